@@ -3,8 +3,6 @@ package actions
 import (
 	"fmt"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gobuffalo/buffalo"
@@ -12,6 +10,7 @@ import (
 	"github.com/pkg/errors"
 
 	"avrnpo.org/models"
+	"avrnpo.org/pkg/errortracking"
 	"avrnpo.org/pkg/logging"
 )
 
@@ -103,11 +102,8 @@ func AdminDashboard(c buffalo.Context) error {
 		return errors.WithStack(err)
 	}
 
-	// Load authors for each post
-	for i := range posts {
-		if err := tx.Load(&posts[i], "User"); err != nil {
-			return errors.WithStack(err)
-		}
+	if err := models.LoadPostAuthors(tx, posts); err != nil {
+		return err
 	}
 
 	c.Set("userCount", userCount)
@@ -118,6 +114,30 @@ func AdminDashboard(c buffalo.Context) error {
 	c.Set("draftPosts", draftPosts)
 	c.Set("recentPosts", recentPosts)
 	c.Set("posts", posts)
+	pendingReviews, err := tx.Where("status IN (?, ?)", models.DonationStatusPendingReview, models.DonationStatusFlagged).Count("donations")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.Set("pendingReviews", pendingReviews)
+	blockedReceipts, err := models.BlockedDonationReceipts(tx, nil)
+	if err != nil {
+		return err
+	}
+	c.Set("blockedReceipts", blockedReceipts)
+	contactSLA, err := loadContactSLAStats(tx, contactReplySLA(), time.Now())
+	if err != nil {
+		return err
+	}
+	c.Set("contactSLA", contactSLA)
+	analytics, err := loadDonationAnalytics(tx, time.Now())
+	if err != nil {
+		return err
+	}
+	c.Set("donationAnalytics", analytics)
+	c.Set("recentErrors", errortracking.Recent(5))
+	c.Set("recentActivity", adminActivityFeed.Recent())
+	c.Set("activityLimit", recentActivityLimit)
+	c.Set("errorTrackingEnabled", errortracking.Default().Enabled())
 
 	return c.Render(http.StatusOK, r.HTML("admin/index.plush.html"))
 }
@@ -156,7 +176,7 @@ func AdminUserShow(c buffalo.Context) error {
 		{"value": "admin", "label": "Administrator"},
 	}
 	c.Set("roleOptions", roleOptions)
-	return c.Render(http.StatusOK, r.HTML("admin/user_edit.plush.html"))
+	return c.Render(http.StatusOK, r.HTML("admin/users/edit.plush.html"))
 }
 
 // AdminUserUpdate updates a user as admin
@@ -183,6 +203,10 @@ func AdminUserUpdate(c buffalo.Context) error {
 	updatedUser.CreatedAt = user.CreatedAt
 	updatedUser.Password = ""
 	updatedUser.PasswordConfirmation = ""
+	updatedUser.DeactivatedAt = user.DeactivatedAt
+	updatedUser.DeactivationReason = user.DeactivationReason
+	updatedUser.InvitationTokenHash = user.InvitationTokenHash
+	updatedUser.InvitedAt = user.InvitedAt
 
 	verrs, err := tx.ValidateAndUpdate(updatedUser)
 	if err != nil {
@@ -200,7 +224,7 @@ func AdminUserUpdate(c buffalo.Context) error {
 		}
 		c.Set("roleOptions", roleOptions)
 
-		return c.Render(http.StatusOK, r.HTML("admin/user_edit.plush.html"))
+		return c.Render(http.StatusOK, r.HTML("admin/users/edit.plush.html"))
 	}
 
 	// Log admin user update
@@ -254,27 +278,6 @@ func AdminUserDelete(c buffalo.Context) error {
 // ADMIN BLOG POST HANDLERS
 // ============================================================================
 
-// AdminPostsIndex shows all posts for admin management
-func AdminPostsIndex(c buffalo.Context) error {
-	tx := c.Value("tx").(*pop.Connection)
-
-	posts := []models.Post{}
-	if err := tx.Order("created_at desc").All(&posts); err != nil {
-		return errors.WithStack(err)
-	}
-
-	// Load authors for each post
-	for i := range posts {
-		if err := tx.Load(&posts[i], "User"); err != nil {
-			return errors.WithStack(err)
-		}
-	}
-
-	c.Set("posts", posts)
-
-	return c.Render(http.StatusOK, r.HTML("admin/posts/index.plush.html"))
-}
-
 // AdminPostsNew shows the new post creation form
 func AdminPostsNew(c buffalo.Context) error {
 	post := &models.Post{}
@@ -318,12 +321,10 @@ func AdminPostsCreate(c buffalo.Context) error {
 		shouldPublish = true
 	}
 	
-	post.Published = shouldPublish
 	if shouldPublish {
-		now := time.Now()
-		post.PublishedAt = &now
+		post.Publish(time.Now())
 	} else {
-		post.PublishedAt = nil
+		post.Unpublish()
 	}
 
 	// Validate and save
@@ -382,16 +383,6 @@ func AdminPostsUpdate(c buffalo.Context) error {
 		return errors.WithStack(err)
 	}
 
-	// Handle published status changes
-	if post.Published && post.PublishedAt == nil {
-		// Post is being published for the first time
-		now := time.Now()
-		post.PublishedAt = &now
-	} else if !post.Published {
-		// Post is being unpublished
-		post.PublishedAt = nil
-	}
-
 	// Generate slug if changed
 	if post.Slug == "" {
 		post.GenerateSlug()
@@ -497,280 +488,3 @@ func AdminPostsDelete(c buffalo.Context) error {
 
 	return c.Redirect(http.StatusFound, "/admin/posts")
 }
-
-// AdminPostsBulk handles bulk operations on posts
-func AdminPostsBulk(c buffalo.Context) error {
-	tx, ok := c.Value("tx").(*pop.Connection)
-	if !ok {
-		return fmt.Errorf("no transaction found")
-	}
-
-	action := c.Param("action")
-	postIDsParam := c.Request().Form["post_ids"]
-
-	if len(postIDsParam) == 0 {
-		c.Flash().Add("error", "Please select at least one post")
-		return c.Render(200, r.HTML("admin/posts/index.plush.html"))
-	}
-
-	// Parse post IDs
-	postIDInts := make([]int, 0, len(postIDsParam))
-	for _, idStr := range postIDsParam {
-		if id, err := strconv.Atoi(strings.TrimSpace(idStr)); err == nil {
-			postIDInts = append(postIDInts, id)
-		}
-	}
-
-	if len(postIDInts) == 0 {
-		c.Flash().Add("error", "No valid posts selected")
-		return c.Render(200, r.HTML("admin/posts/index.plush.html"))
-	}
-
-	currentUser := c.Value("current_user").(*models.User)
-
-	switch action {
-	case "publish":
-		now := time.Now()
-		err := tx.RawQuery("UPDATE posts SET published = true, published_at = ? WHERE id IN (?)", now, postIDInts).Exec()
-		if err != nil {
-			return err
-		}
-		logging.UserAction(c, currentUser.ID.String(), "posts_bulk_published", "Bulk published posts", logging.Fields{
-			"post_count": len(postIDInts),
-		})
-		c.Flash().Add("success", fmt.Sprintf("Published %d post(s)", len(postIDInts)))
-
-	case "unpublish":
-		err := tx.RawQuery("UPDATE posts SET published = false, published_at = NULL WHERE id IN (?)", postIDInts).Exec()
-		if err != nil {
-			return err
-		}
-		logging.UserAction(c, currentUser.ID.String(), "posts_bulk_unpublished", "Bulk unpublished posts", logging.Fields{
-			"post_count": len(postIDInts),
-		})
-		c.Flash().Add("success", fmt.Sprintf("Unpublished %d post(s)", len(postIDInts)))
-
-	case "delete":
-		// For delete action, require confirmation
-		if c.Param("confirm_delete") != "true" {
-			c.Flash().Add("warning", fmt.Sprintf("Are you sure you want to delete %d post(s)? This action cannot be undone.", len(postIDInts)))
-			// Return the current page with confirmation message
-			return c.Render(200, r.HTML("admin/posts/index.plush.html"))
-		}
-
-		err := tx.RawQuery("DELETE FROM posts WHERE id IN (?)", postIDInts).Exec()
-		if err != nil {
-			return err
-		}
-		logging.UserAction(c, currentUser.ID.String(), "posts_bulk_deleted", "Bulk deleted posts", logging.Fields{
-			"post_count": len(postIDInts),
-		})
-		c.Flash().Add("success", fmt.Sprintf("Deleted %d post(s)", len(postIDInts)))
-
-	default:
-		c.Flash().Add("error", "Invalid bulk action")
-	}
-
-	return c.Render(200, r.HTML("admin/posts/index.plush.html"))
-}
-
-// AdminDonationsIndex shows the donations management page
-func AdminDonationsIndex(c buffalo.Context) error {
-	// Get current user
-	currentUser, ok := c.Value("current_user").(*models.User)
-	if !ok || currentUser == nil {
-		return c.Redirect(http.StatusFound, "/")
-	}
-
-	// Get database connection
-	tx, ok := c.Value("tx").(*pop.Connection)
-	if !ok {
-		return fmt.Errorf("no transaction found")
-	}
-
-	// Parse query parameters
-	page := 1
-	if p := c.Param("page"); p != "" {
-		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
-			page = parsed
-		}
-	}
-
-	status := c.Param("status")
-	search := c.Param("search")
-
-	// Build query
-	query := tx.Q()
-
-	if status != "" && status != "all" {
-		query = query.Where("status = ?", status)
-	}
-
-	if search != "" {
-		query = query.Where("donor_name ILIKE ? OR donor_email ILIKE ?",
-			"%"+search+"%", "%"+search+"%")
-	}
-
-	// Get total count for pagination
-	totalCount, err := query.Count(&models.Donation{})
-	if err != nil {
-		c.Logger().Errorf("Error counting donations: %v", err)
-		c.Flash().Add("error", "Error loading donations")
-		return c.Redirect(http.StatusFound, "/admin")
-	}
-
-	// Calculate pagination
-	perPage := 20
-	totalPages := (totalCount + perPage - 1) / perPage
-
-	// Get donations for current page
-	donations := []models.Donation{}
-	err = query.Order("created_at desc").
-		Paginate(page, perPage).
-		All(&donations)
-	if err != nil {
-		c.Logger().Errorf("Error fetching donations: %v", err)
-		c.Flash().Add("error", "Error loading donations")
-		return c.Redirect(http.StatusFound, "/admin")
-	}
-
-	// Calculate summary statistics
-	stats, err := getDonationStats(tx)
-	if err != nil {
-		c.Logger().Errorf("Error getting donation stats: %v", err)
-		// Continue without stats
-		stats = DonationStats{}
-	}
-
-	// Set template data
-	c.Set("donations", donations)
-	c.Set("stats", stats)
-	c.Set("currentPage", page)
-	c.Set("totalPages", totalPages)
-	c.Set("totalCount", totalCount)
-	c.Set("currentStatus", status)
-	c.Set("currentSearch", search)
-	c.Set("user", currentUser)
-
-	// TODO: Admin donations templates not implemented yet
-	// Redirect to main admin dashboard for now
-	c.Flash().Add("info", "Donation management interface coming soon!")
-	return c.Redirect(http.StatusSeeOther, "/admin")
-}
-
-// DonationStats holds donation statistics
-type DonationStats struct {
-	TotalDonations  int     `json:"total_donations"`
-	CompletedCount  int     `json:"completed_count"`
-	PendingCount    int     `json:"pending_count"`
-	FailedCount     int     `json:"failed_count"`
-	TotalAmount     float64 `json:"total_amount"`
-	CompletedAmount float64 `json:"completed_amount"`
-	AverageAmount   float64 `json:"average_amount"`
-	MonthlyTotal    float64 `json:"monthly_total"`
-	RecurringCount  int     `json:"recurring_count"`
-}
-
-// getDonationStats calculates donation statistics
-func getDonationStats(tx *pop.Connection) (DonationStats, error) {
-	stats := DonationStats{}
-
-	// Total donations count
-	totalCount, err := tx.Count(&models.Donation{})
-	if err != nil {
-		return stats, err
-	}
-	stats.TotalDonations = totalCount
-
-	// Count by status
-	completed, _ := tx.Where("status = ?", "completed").Count(&models.Donation{})
-	pending, _ := tx.Where("status = ?", "pending").Count(&models.Donation{})
-	failed, _ := tx.Where("status = ?", "failed").Count(&models.Donation{})
-
-	stats.CompletedCount = completed
-	stats.PendingCount = pending
-	stats.FailedCount = failed
-
-	// Amount calculations
-	var totalAmountResult struct {
-		TotalAmount     float64 `db:"total_amount"`
-		CompletedAmount float64 `db:"completed_amount"`
-		AverageAmount   float64 `db:"average_amount"`
-	}
-
-	err = tx.RawQuery(`
-		SELECT 
-			COALESCE(SUM(amount), 0) as total_amount,
-			COALESCE(SUM(CASE WHEN status = 'completed' THEN amount ELSE 0 END), 0) as completed_amount,
-			COALESCE(AVG(CASE WHEN status = 'completed' THEN amount ELSE NULL END), 0) as average_amount
-		FROM donations
-	`).First(&totalAmountResult)
-
-	if err == nil {
-		stats.TotalAmount = totalAmountResult.TotalAmount
-		stats.CompletedAmount = totalAmountResult.CompletedAmount
-		stats.AverageAmount = totalAmountResult.AverageAmount
-	}
-
-	// Monthly total (current month)
-	var monthlyResult struct {
-		MonthlyTotal float64 `db:"monthly_total"`
-	}
-
-	err = tx.RawQuery(`
-		SELECT COALESCE(SUM(amount), 0) as monthly_total
-		FROM donations 
-		WHERE status = 'completed' 
-		AND created_at >= date_trunc('month', now())
-	`).First(&monthlyResult)
-
-	if err == nil {
-		stats.MonthlyTotal = monthlyResult.MonthlyTotal
-	}
-
-	// Recurring donations count
-	recurringCount, _ := tx.Where("donation_type = ?", "monthly").Count(&models.Donation{})
-	stats.RecurringCount = recurringCount
-
-	return stats, nil
-}
-
-// AdminDonationShow shows a single donation details
-func AdminDonationShow(c buffalo.Context) error {
-	// Get current user
-	currentUser, ok := c.Value("current_user").(*models.User)
-	if !ok || currentUser == nil {
-		return c.Redirect(http.StatusFound, "/")
-	}
-
-	// Get database connection
-	tx, ok := c.Value("tx").(*pop.Connection)
-	if !ok {
-		return fmt.Errorf("no transaction found")
-	}
-
-	// Get donation ID from URL
-	donationID := c.Param("donation_id")
-	if donationID == "" {
-		c.Flash().Add("error", "Donation ID is required")
-		return c.Redirect(http.StatusFound, "/admin/donations")
-	}
-
-	// Find the donation
-	donation := &models.Donation{}
-	err := tx.Find(donation, donationID)
-	if err != nil {
-		c.Logger().Errorf("Error finding donation %s: %v", donationID, err)
-		c.Flash().Add("error", "Donation not found")
-		return c.Redirect(http.StatusFound, "/admin/donations")
-	}
-
-	// Set template data
-	c.Set("donation", donation)
-	c.Set("user", currentUser)
-
-	// TODO: Admin donation show template not implemented yet
-	// Redirect to main admin dashboard for now
-	c.Flash().Add("info", "Donation details view coming soon!")
-	return c.Redirect(http.StatusSeeOther, "/admin")
-}
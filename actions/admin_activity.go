@@ -0,0 +1,203 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+)
+
+// Kinds of activity pushed to the admin dashboard
+const (
+	activityDonation      = "donation"
+	activityReview        = "review"
+	activityContact       = "contact"
+	activityWebhookFailed = "webhook_failed"
+)
+
+// recentActivityLimit is how much activity the dashboard shows when it's
+// first opened
+const recentActivityLimit = 20
+
+// adminActivity is an event shown in the dashboard's live activity feed
+type adminActivity struct {
+	Kind   string    `json:"kind"`
+	Title  string    `json:"title"`
+	Detail string    `json:"detail,omitempty"`
+	URL    string    `json:"url,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// activityHub fans activity out to open admin dashboards and keeps the most
+// recent events for dashboards opened later. It's in memory, so each
+// instance only sees the activity it handled.
+type activityHub struct {
+	mu          sync.Mutex
+	subscribers map[chan adminActivity]struct{}
+	recent      []adminActivity
+}
+
+var adminActivityFeed = &activityHub{subscribers: map[chan adminActivity]struct{}{}}
+
+func (h *activityHub) subscribe() chan adminActivity {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan adminActivity, recentActivityLimit)
+	h.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (h *activityHub) unsubscribe(ch chan adminActivity) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, ch)
+}
+
+// publish sends the activity to every open dashboard. A dashboard that has
+// fallen that far behind misses the event rather than holding up the request
+// that caused it.
+func (h *activityHub) publish(activity adminActivity) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.recent = append(h.recent, activity)
+	if len(h.recent) > recentActivityLimit {
+		h.recent = h.recent[len(h.recent)-recentActivityLimit:]
+	}
+	for ch := range h.subscribers {
+		select {
+		case ch <- activity:
+		default:
+		}
+	}
+}
+
+// Recent returns the latest activity, newest first
+func (h *activityHub) Recent() []adminActivity {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	recent := make([]adminActivity, len(h.recent))
+	for i, activity := range h.recent {
+		recent[len(h.recent)-1-i] = activity
+	}
+	return recent
+}
+
+// publishAdminActivity pushes an event to the admin dashboard feed
+func publishAdminActivity(kind, title, detail, url string) {
+	adminActivityFeed.publish(adminActivity{Kind: kind, Title: title, Detail: detail, URL: url, At: time.Now()})
+}
+
+// publishDonationActivity announces a completed donation, or one held for
+// review or flagged by fraud screening, on the admin dashboard
+func publishDonationActivity(donation *models.Donation) {
+	kind, detail := activityDonation, stringOrEmpty(donation.Designation)
+	switch donation.Status {
+	case models.DonationStatusPendingReview:
+		kind, detail = activityReview, "Held for review: "+stringOrEmpty(donation.ReviewReason)
+	case models.DonationStatusFlagged:
+		kind, detail = activityReview, "Flagged: "+stringOrEmpty(donation.ReviewReason)
+	}
+	donor := donation.DonorName
+	if donor == "" {
+		donor = "Anonymous"
+	}
+	title := fmt.Sprintf("%s from %s", donationTitle(*donation), donor)
+	publishAdminActivity(kind, title, detail, fmt.Sprintf("/admin/donations/%s", donation.ID))
+}
+
+// webhookSources names the payment webhooks whose failures are pushed to
+// the dashboard, by path
+var webhookSources = map[string]string{
+	"/api/donations/webhook":                    "Helcim",
+	"/api/donations/crypto/webhook":             "Crypto",
+	"/api/donations/paypal-giving-fund/webhook": "PayPal Giving Fund",
+}
+
+// WebhookFailureAlerts tells the admin dashboard when a payment webhook is
+// rejected or fails, since a failed webhook usually means a gift that wasn't
+// recorded.
+func WebhookFailureAlerts(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		// Buffalo adds a trailing slash to request paths
+		source, ok := webhookSources[strings.TrimSuffix(c.Request().URL.Path, "/")]
+		if !ok {
+			return next(c)
+		}
+
+		err := next(c)
+		status := http.StatusInternalServerError
+		if res, ok := c.Response().(*buffalo.Response); ok && err == nil {
+			status = res.Status
+		}
+		if err != nil || status >= http.StatusBadRequest {
+			detail := http.StatusText(status)
+			if err != nil {
+				detail = err.Error()
+			}
+			// Helcim events are kept and can be replayed from the admin
+			link := "/admin/system/logs"
+			if source == "Helcim" {
+				link = "/admin/webhooks?status=failed"
+			}
+			publishAdminActivity(activityWebhookFailed, fmt.Sprintf("%s webhook failed (%d)", source, status), detail, link)
+		}
+		return err
+	}
+}
+
+// writeActivityEvent writes the activity as a server-sent "activity" event
+func writeActivityEvent(w io.Writer, activity adminActivity) error {
+	data, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: activity\ndata: %s\n\n", data)
+	return err
+}
+
+// AdminActivityStream pushes new donations, contact messages and failed
+// webhooks to the admin dashboard as server-sent events. It runs outside
+// the request transaction so an open dashboard doesn't hold one.
+func AdminActivityStream(c buffalo.Context) error {
+	w := c.Response()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return c.Error(http.StatusInternalServerError, errors.New("streaming is not supported"))
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseKeepAlive.Milliseconds())
+	flusher.Flush()
+
+	activity := adminActivityFeed.subscribe()
+	defer adminActivityFeed.unsubscribe(activity)
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case event := <-activity:
+			if err := writeActivityEvent(w, event); err != nil {
+				return nil
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,105 @@
+package actions
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+// withActivityFeed swaps in an empty feed for the length of a test
+func withActivityFeed(t *testing.T) *activityHub {
+	previous := adminActivityFeed
+	adminActivityFeed = &activityHub{subscribers: map[chan adminActivity]struct{}{}}
+	t.Cleanup(func() { adminActivityFeed = previous })
+	return adminActivityFeed
+}
+
+func Test_ActivityHubPublish(t *testing.T) {
+	req := require.New(t)
+	feed := withActivityFeed(t)
+
+	open := feed.subscribe()
+	closed := feed.subscribe()
+	feed.unsubscribe(closed)
+
+	for i := 1; i <= recentActivityLimit+5; i++ {
+		publishAdminActivity(activityContact, fmt.Sprintf("Message %d", i), "", "")
+	}
+
+	recent := feed.Recent()
+	req.Len(recent, recentActivityLimit)
+	req.Equal(fmt.Sprintf("Message %d", recentActivityLimit+5), recent[0].Title, "newest first")
+	req.Equal("Message 6", recent[len(recent)-1].Title)
+
+	req.Len(open, recentActivityLimit, "a dashboard that stops reading misses events instead of blocking")
+	req.Equal("Message 1", (<-open).Title)
+	req.Len(closed, 0)
+}
+
+func Test_PublishDonationActivity(t *testing.T) {
+	req := require.New(t)
+	feed := withActivityFeed(t)
+
+	id := uuid.Must(uuid.NewV4())
+	reason := "Amount $9000.00 is over the $5000.00 review threshold"
+	publishDonationActivity(&models.Donation{ID: id, Amount: 50, DonationType: "monthly", DonorName: "Sam Smith", Status: "active"})
+	publishDonationActivity(&models.Donation{ID: id, Amount: 9000, DonationType: "one-time", Status: models.DonationStatusPendingReview, ReviewReason: &reason})
+
+	recent := feed.Recent()
+	req.Len(recent, 2)
+	req.Equal(activityReview, recent[0].Kind)
+	req.Equal("$9000.00 one-time donation from Anonymous", recent[0].Title)
+	req.Equal("Held for review: "+reason, recent[0].Detail)
+	req.Equal(activityDonation, recent[1].Kind)
+	req.Equal("$50.00 monthly donation from Sam Smith", recent[1].Title)
+	req.Equal("/admin/donations/"+id.String(), recent[1].URL)
+}
+
+func Test_WebhookFailureAlerts(t *testing.T) {
+	req := require.New(t)
+	feed := withActivityFeed(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.Use(WebhookFailureAlerts)
+	app.POST("/api/donations/crypto/webhook", func(c buffalo.Context) error {
+		if c.Param("sig") != "ok" {
+			return c.Render(http.StatusUnauthorized, r.JSON(map[string]string{"error": "Invalid signature"}))
+		}
+		return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "recorded"}))
+	})
+	app.POST("/contact-test", func(c buffalo.Context) error {
+		return c.Render(http.StatusBadRequest, r.JSON(map[string]string{}))
+	})
+
+	for _, path := range []string{"/api/donations/crypto/webhook?sig=ok", "/contact-test", "/api/donations/crypto/webhook"} {
+		w := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("POST", path, nil)
+		app.ServeHTTP(w, httpReq)
+	}
+
+	recent := feed.Recent()
+	req.Len(recent, 1, "only the failed webhook is reported")
+	req.Equal(activityWebhookFailed, recent[0].Kind)
+	req.Equal("Crypto webhook failed (401)", recent[0].Title)
+}
+
+func Test_WriteActivityEvent(t *testing.T) {
+	req := require.New(t)
+
+	var buf bytes.Buffer
+	req.NoError(writeActivityEvent(&buf, adminActivity{Kind: activityContact, Title: "Message from Sam: Volunteering"}))
+
+	out := buf.String()
+	req.True(strings.HasPrefix(out, "event: activity\ndata: {"), out)
+	req.Contains(out, `"title":"Message from Sam: Volunteering"`)
+	req.True(strings.HasSuffix(out, "}\n\n"), out)
+}
@@ -0,0 +1,43 @@
+package actions
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+
+	"avrnpo.org/pkg/diagnostics"
+	"avrnpo.org/pkg/logging"
+)
+
+// AdminDiagnostics shows module versions and known advisories for the
+// running build
+func AdminDiagnostics(c buffalo.Context) error {
+	c.Set("report", diagnostics.Build())
+	return c.Render(http.StatusOK, r.HTML("admin/diagnostics.plush.html"))
+}
+
+// AdminSystem shows which build is live and how long it has been running
+func AdminSystem(c buffalo.Context) error {
+	c.Set("build", diagnostics.CurrentBuild())
+	c.Set("environment", ENV)
+	return c.Render(http.StatusOK, r.HTML("admin/system.plush.html"))
+}
+
+// AdminSystemLogs shows recent in-memory log entries with level, module and
+// correlation ID (request_id) filters
+func AdminSystemLogs(c buffalo.Context) error {
+	filter := logging.LogFilter{
+		Level:     c.Param("level"),
+		Module:    c.Param("module"),
+		RequestID: strings.TrimSpace(c.Param("request_id")),
+		Query:     strings.TrimSpace(c.Param("q")),
+		Limit:     500,
+	}
+
+	c.Set("entries", logging.Recent.Entries(filter))
+	c.Set("modules", logging.Recent.Modules())
+	c.Set("levels", []string{"debug", "info", "warn", "error"})
+	c.Set("filter", filter)
+	return c.Render(http.StatusOK, r.HTML("admin/system_logs.plush.html"))
+}
@@ -0,0 +1,512 @@
+package actions
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// donationFilter is the admin donation list's filter form
+type donationFilter struct {
+	Status string
+	Type   string
+	Search string
+	From   string
+	To     string
+}
+
+// donationFilterFromParams reads the filter from the query string, dropping
+// values that aren't a known status, type or date
+func donationFilterFromParams(params buffalo.ParamValues) donationFilter {
+	f := donationFilter{
+		Status: params.Get("status"),
+		Type:   params.Get("type"),
+		Search: strings.TrimSpace(params.Get("search")),
+		From:   params.Get("from"),
+		To:     params.Get("to"),
+	}
+	if !models.ValidDonationStatus(f.Status) {
+		f.Status = ""
+	}
+	if !models.DonationType(f.Type).Valid() {
+		f.Type = ""
+	}
+	if _, err := time.Parse("2006-01-02", f.From); err != nil {
+		f.From = ""
+	}
+	if _, err := time.Parse("2006-01-02", f.To); err != nil {
+		f.To = ""
+	}
+	return f
+}
+
+// Apply narrows q to the donations matching the filter
+func (f donationFilter) Apply(q *pop.Query) *pop.Query {
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if f.Type != "" {
+		q = q.Where("donation_type = ?", f.Type)
+	}
+	if f.Search != "" {
+		like := "%" + f.Search + "%"
+		q = q.Where("(donor_name ILIKE ? OR donor_email ILIKE ? OR helcim_transaction_id = ? OR transaction_id = ? OR subscription_id = ?)",
+			like, like, f.Search, f.Search, f.Search)
+	}
+	if f.From != "" {
+		from, _ := time.Parse("2006-01-02", f.From)
+		q = q.Where("created_at >= ?", from)
+	}
+	if f.To != "" {
+		to, _ := time.Parse("2006-01-02", f.To)
+		q = q.Where("created_at < ?", to.AddDate(0, 0, 1))
+	}
+	return q
+}
+
+// Query is the filter as a query string, for pagination links
+func (f donationFilter) Query() string {
+	v := url.Values{}
+	for key, value := range map[string]string{"status": f.Status, "type": f.Type, "search": f.Search, "from": f.From, "to": f.To} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	return v.Encode()
+}
+
+// DonationStats holds donation statistics
+type DonationStats struct {
+	TotalDonations  int     `json:"total_donations"`
+	CompletedCount  int     `json:"completed_count"`
+	PendingCount    int     `json:"pending_count"`
+	FailedCount     int     `json:"failed_count"`
+	TotalAmount     float64 `json:"total_amount"`
+	CompletedAmount float64 `json:"completed_amount"`
+	AverageAmount   float64 `json:"average_amount"`
+	MonthlyTotal    float64 `json:"monthly_total"`
+	RecurringCount  int     `json:"recurring_count"`
+}
+
+// getDonationStats calculates donation statistics
+func getDonationStats(tx *pop.Connection) (DonationStats, error) {
+	stats := DonationStats{}
+
+	// Total donations count
+	totalCount, err := tx.Count(&models.Donation{})
+	if err != nil {
+		return stats, err
+	}
+	stats.TotalDonations = totalCount
+
+	// Count by status
+	completed, _ := tx.Where("status = ?", "completed").Count(&models.Donation{})
+	pending, _ := tx.Where("status = ?", "pending").Count(&models.Donation{})
+	failed, _ := tx.Where("status = ?", "failed").Count(&models.Donation{})
+
+	stats.CompletedCount = completed
+	stats.PendingCount = pending
+	stats.FailedCount = failed
+
+	// Amount calculations
+	var totalAmountResult struct {
+		TotalAmount     float64 `db:"total_amount"`
+		CompletedAmount float64 `db:"completed_amount"`
+		AverageAmount   float64 `db:"average_amount"`
+	}
+
+	err = tx.RawQuery(`
+		SELECT
+			COALESCE(SUM(amount), 0) as total_amount,
+			COALESCE(SUM(CASE WHEN status = 'completed' THEN amount ELSE 0 END), 0) as completed_amount,
+			COALESCE(AVG(CASE WHEN status = 'completed' THEN amount ELSE NULL END), 0) as average_amount
+		FROM donations
+	`).First(&totalAmountResult)
+
+	if err == nil {
+		stats.TotalAmount = totalAmountResult.TotalAmount
+		stats.CompletedAmount = totalAmountResult.CompletedAmount
+		stats.AverageAmount = totalAmountResult.AverageAmount
+	}
+
+	// Monthly total (current month)
+	var monthlyResult struct {
+		MonthlyTotal float64 `db:"monthly_total"`
+	}
+
+	err = tx.RawQuery(`
+		SELECT COALESCE(SUM(amount), 0) as monthly_total
+		FROM donations
+		WHERE status = 'completed'
+		AND created_at >= date_trunc('month', now())
+	`).First(&monthlyResult)
+
+	if err == nil {
+		stats.MonthlyTotal = monthlyResult.MonthlyTotal
+	}
+
+	// Recurring donations count
+	recurringCount, _ := tx.Where("donation_type = ?", models.DonationTypeMonthly).Count(&models.Donation{})
+	stats.RecurringCount = recurringCount
+
+	return stats, nil
+}
+
+// AdminDonationsIndex lists donations, filtered by status, type, date and
+// donor or transaction search
+func AdminDonationsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	filter := donationFilterFromParams(c.Params())
+
+	q := filter.Apply(tx.PaginateFromParams(c.Params()))
+	donations := models.Donations{}
+	if err := q.Order("created_at desc").All(&donations); err != nil {
+		return errors.WithStack(err)
+	}
+
+	stats, err := getDonationStats(tx)
+	if err != nil {
+		c.Logger().Errorf("Error getting donation stats: %v", err)
+		stats = DonationStats{}
+	}
+
+	emails := make([]string, len(donations))
+	for i, d := range donations {
+		emails[i] = d.DonorEmail
+	}
+	flags, err := loadDonorFlagIndex(tx, emails)
+	if err != nil {
+		return err
+	}
+
+	c.Set("donations", donations)
+	c.Set("flags", flags)
+	c.Set("stats", stats)
+	c.Set("filter", filter)
+	c.Set("pagination", q.Paginator)
+	c.Set("receiptYear", time.Now().Year()-1)
+	setDonationStatusContext(c)
+	return c.Render(http.StatusOK, r.HTML("admin/donations/index.plush.html"))
+}
+
+// donationExportBatch is how many donations the export loads at a time, so
+// a year of gifts is streamed rather than held in memory
+const donationExportBatch = 500
+
+// AdminDonationsExport downloads the donations matching the list's filter as
+// CSV, with the donor addresses finance needs for year-end tax paperwork
+func AdminDonationsExport(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+	filter := donationFilterFromParams(c.Params())
+
+	logging.UserAction(c, user.Email, "donations_exported", "Exported donations", logging.Fields{
+		"filter": filter.Query(),
+	})
+
+	filename := fmt.Sprintf("donations-%s.csv", time.Now().Format(dateInputLayout))
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	return c.Render(http.StatusOK, r.Func("text/csv", func(w io.Writer, d render.Data) error {
+		return writeDonationCSV(w, func(page int) (models.Donations, error) {
+			batch := models.Donations{}
+			q := filter.Apply(tx.Q()).Order("created_at asc, id asc").Paginate(page, donationExportBatch)
+			if err := q.All(&batch); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return batch, nil
+		})
+	}))
+}
+
+// writeDonationCSV writes donations as CSV, one row per donation. Pages are
+// fetched from 1 until one comes back short.
+func writeDonationCSV(w io.Writer, fetch func(page int) (models.Donations, error)) error {
+	out := csv.NewWriter(w)
+	header := []string{"Date", "Donation ID", "Donor", "Email", "Phone", "Address Line 1", "Address Line 2", "City", "State", "Zip",
+		"Type", "Status", "Amount", "Refunded", "Currency", "Transaction ID", "Subscription ID"}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for page := 1; ; page++ {
+		donations, err := fetch(page)
+		if err != nil {
+			return err
+		}
+		for _, d := range donations {
+			row := []string{
+				d.CreatedAt.Format(dateInputLayout),
+				d.ID.String(),
+				csvCell(d.DonorName),
+				csvCell(d.DonorEmail),
+				csvCell(stringOrEmpty(d.DonorPhone)),
+				csvCell(stringOrEmpty(d.AddressLine1)),
+				csvCell(stringOrEmpty(d.AddressLine2)),
+				csvCell(stringOrEmpty(d.City)),
+				csvCell(stringOrEmpty(d.State)),
+				csvCell(stringOrEmpty(d.Zip)),
+				d.TypeLabel(),
+				d.Status,
+				fmt.Sprintf("%.2f", d.Amount),
+				fmt.Sprintf("%.2f", d.RefundedAmount),
+				d.Currency,
+				d.ChargeReference(),
+				stringOrEmpty(d.SubscriptionID),
+			}
+			if err := out.Write(row); err != nil {
+				return err
+			}
+		}
+		if len(donations) < donationExportBatch {
+			break
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// csvCell prefixes a quote to text that a spreadsheet would otherwise run as
+// a formula, so donor-entered fields like "=HYPERLINK(...)" export as text
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// csvCellValue undoes csvCell for a cell read back from one of our exports
+func csvCellValue(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
+
+// setDonationStatusContext sets the status and type choices for the
+// donation filter and status forms
+func setDonationStatusContext(c buffalo.Context) {
+	c.Set("donationStatuses", models.DonationStatuses)
+	c.Set("donationTypes", models.DonationTypes)
+}
+
+// findAdminDonation loads the donation named in the URL
+func findAdminDonation(c buffalo.Context) (*models.Donation, error) {
+	tx := c.Value("tx").(*pop.Connection)
+	donation := &models.Donation{}
+	if err := tx.Find(donation, c.Param("donation_id")); err != nil {
+		return nil, err
+	}
+	return donation, nil
+}
+
+// AdminDonationShow shows a donation with its Helcim transaction or live
+// subscription details, installments and dunning history
+func AdminDonationShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	donation, err := findAdminDonation(c)
+	if err != nil {
+		c.Flash().Add("error", "Donation not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/donations")
+	}
+
+	installments := models.PledgeInstallments{}
+	if err := tx.Where("donation_id = ?", donation.ID).Order("sequence").All(&installments); err != nil {
+		return errors.WithStack(err)
+	}
+	failures := models.PaymentFailures{}
+	if err := tx.Where("donation_id = ?", donation.ID).Order("created_at desc").All(&failures); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Helcim is asked for the subscription's current state rather than
+	// trusting our copy, which only changes on webhooks
+	var subscription *services.SubscriptionResponse
+	subscriptionError := ""
+	if donation.IsRecurring() {
+		subscription, err = services.NewHelcimClient().GetSubscription(c, *donation.SubscriptionID)
+		if err != nil {
+			c.Logger().Warnf("[AdminDonations] Loading subscription %s failed: %v", *donation.SubscriptionID, err)
+			subscriptionError = err.Error()
+			subscription = nil
+		}
+	}
+
+	blockedReceipts, err := models.BlockedDonationReceipts(tx, &donation.ID)
+	if err != nil {
+		return err
+	}
+
+	c.Set("donation", donation)
+	c.Set("installments", installments)
+	c.Set("blockedReceipts", blockedReceipts)
+	c.Set("failures", failures)
+	c.Set("subscription", subscription)
+	c.Set("subscriptionError", subscriptionError)
+	setDonationStatusContext(c)
+	return c.Render(http.StatusOK, r.HTML("admin/donations/show.plush.html"))
+}
+
+// AdminDonationUpdateStatus sets a donation's status by hand, e.g. to mark a
+// gift failed after the processor reports a chargeback
+func AdminDonationUpdateStatus(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	donation, err := findAdminDonation(c)
+	if err != nil {
+		c.Flash().Add("error", "Donation not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/donations")
+	}
+	back := fmt.Sprintf("/admin/donations/%s", donation.ID)
+
+	status := c.Param("status")
+	if !models.ValidDonationStatus(status) {
+		c.Flash().Add("danger", "Choose a valid status.")
+		return c.Redirect(http.StatusSeeOther, back)
+	}
+	if status == donation.Status {
+		return c.Redirect(http.StatusSeeOther, back)
+	}
+
+	previous := donation.Status
+	donation.Status = status
+	if err := tx.Update(donation); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.UserAction(c, user.Email, "donation_status_changed", "Changed donation status", logging.Fields{
+		"donation_id": donation.ID.String(),
+		"from":        previous,
+		"to":          status,
+	})
+	c.Flash().Add("success", fmt.Sprintf("Status changed from %s to %s.", previous, status))
+	return c.Redirect(http.StatusSeeOther, back)
+}
+
+// AdminDonationResendReceipt emails the donor their receipt again
+func AdminDonationResendReceipt(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	donation, err := findAdminDonation(c)
+	if err != nil {
+		c.Flash().Add("error", "Donation not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/donations")
+	}
+	back := fmt.Sprintf("/admin/donations/%s", donation.ID)
+
+	if donation.Status != "completed" && donation.Status != "active" {
+		c.Flash().Add("warning", "Only completed gifts and active subscriptions have a receipt to send.")
+		return c.Redirect(http.StatusSeeOther, back)
+	}
+
+	receipt := donationReceiptData(tx, donation, donation.ChargeReference())
+	addReceiptNumber(tx, donation, &receipt)
+	if err := services.NewEmailService().SendDonationReceipt(donation.DonorEmail, receipt); err != nil {
+		if blocked, ok := services.AsReceiptComplianceError(err); ok {
+			flagBlockedReceipt(tx, donation.ID.String(), receipt, blocked)
+			c.Flash().Add("danger", "The receipt wasn't sent because it's missing "+strings.Join(blocked.Problems, ", ")+". Organization details are set on the Organization page.")
+			return c.Redirect(http.StatusSeeOther, back)
+		}
+		c.Logger().Errorf("[AdminDonations] Resending receipt for donation %s failed: %v", donation.ID.String(), err)
+		c.Flash().Add("danger", "The receipt couldn't be sent: "+err.Error())
+		return c.Redirect(http.StatusSeeOther, back)
+	}
+	clearBlockedReceipt(tx, receipt)
+	recordReceiptSent(tx, donation)
+
+	logging.UserAction(c, user.Email, "donation_receipt_resent", "Resent donation receipt", logging.Fields{
+		"donation_id": donation.ID.String(),
+	})
+	c.Flash().Add("success", "Receipt sent to "+donation.DonorEmail+".")
+	return c.Redirect(http.StatusSeeOther, back)
+}
+
+// parseRefundAmount reads the refund amount from the form, defaulting to
+// everything still refundable
+func parseRefundAmount(s string, refundable float64) (float64, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "$"))
+	if s == "" {
+		return refundable, nil
+	}
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil || amount <= 0 {
+		return 0, errors.New("Enter the amount to refund")
+	}
+	if amount > refundable {
+		return 0, errors.Errorf("At most $%.2f can be refunded", refundable)
+	}
+	return amount, nil
+}
+
+// AdminDonationRefund refunds all or part of a one-time card gift through
+// Helcim. It runs behind SensitiveAdminAction.
+func AdminDonationRefund(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	donation, err := findAdminDonation(c)
+	if err != nil {
+		c.Flash().Add("error", "Donation not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/donations")
+	}
+	back := fmt.Sprintf("/admin/donations/%s", donation.ID)
+
+	refundable := donation.Refundable()
+	if refundable <= 0 {
+		c.Flash().Add("warning", "This donation can't be refunded here.")
+		return c.Redirect(http.StatusSeeOther, back)
+	}
+	amount, err := parseRefundAmount(c.Param("amount"), refundable)
+	if err != nil {
+		c.Flash().Add("danger", err.Error())
+		return c.Redirect(http.StatusSeeOther, back)
+	}
+	transactionID, err := strconv.Atoi(donation.ChargeReference())
+	if err != nil {
+		c.Flash().Add("danger", "The donation's Helcim transaction ID isn't valid.")
+		return c.Redirect(http.StatusSeeOther, back)
+	}
+
+	resp, err := services.NewHelcimClient().RefundPayment(c, services.RefundRequest{
+		OriginalTransactionID: transactionID,
+		Amount:                amount,
+		IPAddress:             getClientIP(c),
+	})
+	if err == nil && paymentDeclined(resp.Status) {
+		err = errors.Errorf("Helcim returned %s", resp.Status)
+	}
+	if err != nil {
+		c.Logger().Errorf("[AdminDonations] Refund of donation %s failed: %v", donation.ID.String(), err)
+		c.Flash().Add("danger", "The refund failed: "+err.Error())
+		return c.Redirect(http.StatusSeeOther, back)
+	}
+
+	donation.RecordRefund(amount, time.Now())
+	if err := tx.Update(donation); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.UserAction(c, user.Email, "donation_refunded", "Refunded donation", logging.Fields{
+		"donation_id":           donation.ID.String(),
+		"amount":                amount,
+		"refund_transaction_id": resp.TransactionID,
+	})
+	c.Flash().Add("success", fmt.Sprintf("Refunded $%.2f to %s.", amount, donation.DonorName))
+	return c.Redirect(http.StatusSeeOther, back)
+}
@@ -0,0 +1,206 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+)
+
+func Test_DonationFilterFromParams(t *testing.T) {
+	r := require.New(t)
+
+	f := donationFilterFromParams(url.Values{
+		"status": {"completed"},
+		"type":   {"monthly"},
+		"search": {"  jane@example.com "},
+		"from":   {"2026-10-01"},
+		"to":     {"October"},
+	})
+	r.Equal("completed", f.Status)
+	r.Equal("monthly", f.Type)
+	r.Equal("jane@example.com", f.Search)
+	r.Equal("2026-10-01", f.From)
+	r.Equal("", f.To)
+	r.Equal("from=2026-10-01&search=jane%40example.com&status=completed&type=monthly", f.Query())
+
+	f = donationFilterFromParams(url.Values{"status": {"everything"}, "type": {"weekly"}})
+	r.Equal(donationFilter{}, f)
+}
+
+func Test_ParseRefundAmount(t *testing.T) {
+	r := require.New(t)
+
+	amount, err := parseRefundAmount("", 50)
+	r.NoError(err)
+	r.Equal(50.0, amount)
+
+	amount, err = parseRefundAmount("$20.50", 50)
+	r.NoError(err)
+	r.Equal(20.5, amount)
+
+	_, err = parseRefundAmount("60", 50)
+	r.EqualError(err, "At most $50.00 can be refunded")
+	_, err = parseRefundAmount("-5", 50)
+	r.Error(err)
+}
+
+func Test_WriteDonationCSV(t *testing.T) {
+	r := require.New(t)
+
+	txn := "12345"
+	street := "1 Main St"
+	city := "Springfield"
+	first := models.Donations{}
+	for i := 0; i < donationExportBatch; i++ {
+		first = append(first, models.Donation{
+			ID:                  uuid.Must(uuid.NewV4()),
+			DonorName:           "Jane Doe",
+			DonorEmail:          "jane@example.com",
+			AddressLine1:        &street,
+			City:                &city,
+			Amount:              100,
+			Currency:            "USD",
+			DonationType:        models.DonationTypeOneTime,
+			Status:              "completed",
+			HelcimTransactionID: &txn,
+			RefundedAmount:      25,
+			CreatedAt:           time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC),
+		})
+	}
+	phone := "+1 555 010 2000"
+	second := models.Donations{{DonorName: "=HYPERLINK(\"https://evil.example\",\"Click\")", DonorPhone: &phone, Amount: 10, Currency: "USD", DonationType: models.DonationTypeMonthly, Status: "active"}}
+
+	var pages []int
+	var buf bytes.Buffer
+	r.NoError(writeDonationCSV(&buf, func(page int) (models.Donations, error) {
+		pages = append(pages, page)
+		if page == 1 {
+			return first, nil
+		}
+		return second, nil
+	}))
+	r.Equal([]int{1, 2}, pages)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	r.NoError(err)
+	r.Len(rows, donationExportBatch+2)
+	r.Equal("Address Line 1", rows[0][5])
+	r.Equal([]string{"2026-10-01", first[0].ID.String(), "Jane Doe", "jane@example.com", "", "1 Main St", "", "Springfield", "", "",
+		"One-time", "completed", "100.00", "25.00", "USD", "12345", ""}, rows[1])
+	// Donor-entered cells can't run as spreadsheet formulas
+	r.Equal("'=HYPERLINK(\"https://evil.example\",\"Click\")", rows[len(rows)-1][2])
+	r.Equal("'+1 555 010 2000", rows[len(rows)-1][4])
+
+	// A failed page stops the export
+	r.EqualError(writeDonationCSV(&bytes.Buffer{}, func(int) (models.Donations, error) {
+		return nil, errors.New("connection lost")
+	}), "connection lost")
+}
+
+func Test_CSVCell(t *testing.T) {
+	r := require.New(t)
+
+	for _, formula := range []string{"=1+1", "+1 555", "-2", "@SUM(A1)", "\tx", "\rx"} {
+		r.Equal("'"+formula, csvCell(formula))
+		r.Equal(formula, csvCellValue(csvCell(formula)))
+	}
+	r.Equal("Jane Doe", csvCell("Jane Doe"))
+	r.Equal("", csvCell(""))
+	r.Equal("'quoted", csvCellValue("'quoted"))
+}
+
+func Test_AdminDonationTemplatesRendering(t *testing.T) {
+	req := require.New(t)
+
+	txn := "12345"
+	donation := models.Donation{
+		ID:                  uuid.Must(uuid.NewV4()),
+		DonorName:           "Jane Doe",
+		DonorEmail:          "jane@example.com",
+		Amount:              100,
+		Currency:            "USD",
+		DonationType:        models.DonationTypeOneTime,
+		Status:              "completed",
+		HelcimTransactionID: &txn,
+		RefundedAmount:      25,
+		CreatedAt:           time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC),
+	}
+	subscriptionID := "sub-1"
+	monthly := donation
+	monthly.ID = uuid.Must(uuid.NewV4())
+	monthly.DonationType = models.DonationTypeMonthly
+	monthly.Status = "active"
+	monthly.SubscriptionID = &subscriptionID
+	monthly.RefundedAmount = 0
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/donations-test", func(c buffalo.Context) error {
+		c.Set("donations", models.Donations{donation})
+		c.Set("flags", donorFlagIndex{})
+		c.Set("stats", DonationStats{TotalDonations: 1, CompletedCount: 1, CompletedAmount: 100})
+		c.Set("filter", donationFilter{Status: "completed"})
+		c.Set("pagination", &pop.Paginator{Page: 1, PerPage: 20, TotalPages: 1})
+		c.Set("receiptYear", 2025)
+		setDonationStatusContext(c)
+		return c.Render(http.StatusOK, r.HTML("admin/donations/index.plush.html"))
+	})
+	app.GET("/donation-test", func(c buffalo.Context) error {
+		c.Set("donation", &donation)
+		c.Set("installments", models.PledgeInstallments{})
+		c.Set("failures", models.PaymentFailures{})
+		c.Set("blockedReceipts", models.DonationReceipts{{ReceiptNumber: "AVR-2026-000012", ComplianceProblems: "organization EIN", BlockedAt: &donation.CreatedAt}})
+		c.Set("subscription", nil)
+		c.Set("subscriptionError", "")
+		setDonationStatusContext(c)
+		return c.Render(http.StatusOK, r.HTML("admin/donations/show.plush.html"))
+	})
+	app.GET("/subscription-test", func(c buffalo.Context) error {
+		retry := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+		c.Set("donation", &monthly)
+		c.Set("installments", models.PledgeInstallments{})
+		c.Set("failures", models.PaymentFailures{{Attempts: 1, NoticesSent: 1, Status: models.PaymentFailureOpen, NextRetryAt: &retry}})
+		c.Set("blockedReceipts", models.DonationReceipts{})
+		c.Set("subscription", &services.SubscriptionResponse{Status: "active", Amount: 100, NextBillingDate: retry})
+		c.Set("subscriptionError", "")
+		setDonationStatusContext(c)
+		return c.Render(http.StatusOK, r.HTML("admin/donations/show.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/donations-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "Jane Doe")
+	req.Contains(w.Body.String(), "$25.00 refunded")
+	req.Contains(w.Body.String(), `<option value="completed" selected>completed</option>`)
+	req.Contains(w.Body.String(), `href="/admin/donations/export?status=completed"`)
+	req.Contains(w.Body.String(), `name="year" value="2025"`)
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/donation-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "12345")
+	req.Contains(w.Body.String(), "Leave blank to refund the full $75.00.")
+	req.Contains(w.Body.String(), "Receipt AVR-2026-000012 wasn't sent")
+	req.Contains(w.Body.String(), "It's missing organization EIN.")
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/subscription-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "Subscription sub-1")
+	req.Contains(w.Body.String(), "Oct 17, 2026")
+	req.NotContains(w.Body.String(), "/refund")
+	req.NotContains(w.Body.String(), "wasn't sent")
+}
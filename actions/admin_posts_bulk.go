@@ -0,0 +1,145 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// postBulkActions are the bulk actions on the posts list, by the past tense
+// used in their flash messages
+var postBulkActions = map[string]string{
+	"publish":   "Published",
+	"unpublish": "Unpublished",
+	"delete":    "Deleted",
+}
+
+// postBulkSelection loads the posts ticked on the posts list
+func postBulkSelection(c buffalo.Context, tx *pop.Connection) ([]models.Post, error) {
+	ids := []int{}
+	for _, s := range c.Request().Form["post_ids"] {
+		if id, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	posts := []models.Post{}
+	if len(ids) == 0 {
+		return posts, nil
+	}
+	if err := tx.Where("id IN (?)", ids).Order("created_at desc").All(&posts); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return posts, nil
+}
+
+// postsListURL is the page of the posts list the bulk form was sent from,
+// with its filter, so the list's script can swap in the changed rows
+func postsListURL(c buffalo.Context) string {
+	values, _ := url.ParseQuery(c.Param("return_query"))
+	if query := postFilterFromParams(values).PageQuery(); query != "" {
+		return "/admin/posts?" + query
+	}
+	return "/admin/posts"
+}
+
+// postBulkApplies reports whether action changes post, so posts already in
+// that state aren't saved or counted again
+func postBulkApplies(action string, post models.Post) bool {
+	switch action {
+	case "publish":
+		return !post.Published
+	case "unpublish":
+		return post.Published
+	}
+	return true
+}
+
+// AdminPostsBulk publishes, unpublishes or deletes the selected posts. Each
+// post is saved through the model, so publishing a post that wouldn't pass
+// the edit form's validations is refused and reported rather than saved.
+// The posts list submits this in the background and swaps in the changed
+// rows from the page it redirects to.
+func AdminPostsBulk(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	action := c.Param("action")
+	if _, ok := postBulkActions[action]; !ok {
+		c.Flash().Add("danger", "Invalid bulk action")
+		return c.Redirect(http.StatusSeeOther, postsListURL(c))
+	}
+	posts, err := postBulkSelection(c, tx)
+	if err != nil {
+		return err
+	}
+	if len(posts) == 0 {
+		c.Flash().Add("danger", "Please select at least one post")
+		return c.Redirect(http.StatusSeeOther, postsListURL(c))
+	}
+
+	if action == "delete" {
+		if c.Param("confirm_delete") != "true" {
+			c.Flash().Add("warning", fmt.Sprintf("Confirm deleting %d post(s). This can't be undone.", len(posts)))
+			return c.Redirect(http.StatusSeeOther, postsListURL(c))
+		}
+		if ok, err := sensitiveActionAllowed(c, "posts_bulk_delete"); !ok {
+			return err
+		}
+	}
+
+	now := time.Now()
+	changed := 0
+	var problems []string
+	for i := range posts {
+		post := &posts[i]
+		if !postBulkApplies(action, *post) {
+			continue
+		}
+		switch action {
+		case "publish":
+			post.Publish(now)
+		case "unpublish":
+			post.Unpublish()
+		case "delete":
+			if err := tx.Destroy(post); err != nil {
+				return errors.WithStack(err)
+			}
+			changed++
+			continue
+		}
+		verrs, err := tx.ValidateAndUpdate(post)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if verrs.HasAny() {
+			problems = append(problems, fmt.Sprintf("%q: %s", post.Title, verrs.Error()))
+			continue
+		}
+		changed++
+	}
+
+	logging.UserAction(c, currentUser.ID.String(), "posts_bulk_"+action, fmt.Sprintf("Bulk %s posts", action), logging.Fields{
+		"post_count": changed,
+		"failed":     len(problems),
+	})
+	if changed > 0 {
+		c.Flash().Add("success", fmt.Sprintf("%s %d post(s)", postBulkActions[action], changed))
+	}
+	if len(problems) > 0 {
+		c.Flash().Add("danger", fmt.Sprintf("Couldn't %s %s", action, strings.Join(problems, "; ")))
+	}
+	if changed == 0 && len(problems) == 0 {
+		c.Flash().Add("info", "The selected posts were already in that state.")
+	}
+	return c.Redirect(http.StatusSeeOther, postsListURL(c))
+}
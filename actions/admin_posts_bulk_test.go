@@ -0,0 +1,87 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_PostBulkApplies(t *testing.T) {
+	req := require.New(t)
+
+	draft := models.Post{}
+	live := models.Post{Published: true}
+	req.True(postBulkApplies("publish", draft))
+	req.False(postBulkApplies("publish", live))
+	req.True(postBulkApplies("unpublish", live))
+	req.False(postBulkApplies("unpublish", draft))
+	req.True(postBulkApplies("delete", live))
+}
+
+func Test_PostsIndexBulkRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/posts-test", func(c buffalo.Context) error {
+		c.Set("posts", []models.Post{
+			{ID: 7, Title: "Spring Build Day", Slug: "spring-build-day", Published: true, CreatedAt: time.Now(), UpdatedAt: time.Now(), User: &models.User{ID: uuid.Must(uuid.NewV4()), FirstName: "Pat"}},
+		})
+		c.Set("anyPosts", true)
+		c.Set("filter", postFilterFromParams(url.Values{"status": {"published"}}))
+		c.Set("filtered", true)
+		c.Set("nextQuery", "")
+		c.Set("authors", []models.User{})
+		c.Set("postSorts", postSorts)
+		return c.Render(http.StatusOK, r.HTML("admin/posts/index.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/posts-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	body := w.Body.String()
+	req.Contains(body, `id="posts-bulk-form"`)
+	req.Contains(body, `<tr id="post-row-7">`)
+	req.Contains(body, `name="post_ids" value="7" form="posts-bulk-form"`)
+	req.Contains(body, `name="return_query" value="status=published"`)
+}
+
+func (as *ActionSuite) Test_AdminPostsBulk_Publish() {
+	admin := &models.User{Email: "admin@test.com", FirstName: "Admin", LastName: "User", Role: "admin"}
+	admin.Password = "password"
+	admin.PasswordConfirmation = "password"
+	verrs, err := admin.Create(as.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+	as.Session.Set("current_user_id", admin.ID)
+
+	ready := &models.Post{Title: "Ready", Content: "Ready to go", AuthorID: admin.ID}
+	as.NoError(as.DB.Create(ready))
+	empty := &models.Post{Title: "Empty", Content: "Placeholder", AuthorID: admin.ID}
+	as.NoError(as.DB.Create(empty))
+	// A draft saved before the content was written
+	as.NoError(as.DB.RawQuery("UPDATE posts SET content = '' WHERE id = ?", empty.ID).Exec())
+
+	res := as.HTML("/admin/posts/bulk").Post(url.Values{
+		"action":   {"publish"},
+		"post_ids": {strconv.Itoa(ready.ID), strconv.Itoa(empty.ID)},
+	})
+	as.Equal(http.StatusSeeOther, res.Code)
+
+	as.NoError(as.DB.Reload(ready))
+	as.True(ready.Published)
+	as.NotNil(ready.PublishedAt)
+	as.NoError(as.DB.Reload(empty))
+	as.False(empty.Published)
+	as.Nil(empty.PublishedAt)
+}
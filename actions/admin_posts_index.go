@@ -0,0 +1,254 @@
+package actions
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+)
+
+// postsPageSize is how many posts the admin posts list shows at a time
+const postsPageSize = 25
+
+// postSearchVector is the text the posts list searches. It must match the
+// expression indexed by the add_post_search_index migration, or the search
+// can't use the index.
+const postSearchVector = "to_tsvector('english', coalesce(title, '') || ' ' || coalesce(excerpt, '') || ' ' || coalesce(content, ''))"
+
+// postSort is one way of ordering the posts list. Posts sharing a value are
+// ordered by ID, so every post has a fixed place to page from.
+type postSort struct {
+	Key    string
+	Label  string
+	Column string
+	Desc   bool
+}
+
+// postSorts are the posts list's orderings, in the order the sort menu
+// offers them. The first is the default.
+var postSorts = []postSort{
+	{Key: "newest", Label: "Newest first", Column: "created_at", Desc: true},
+	{Key: "oldest", Label: "Oldest first", Column: "created_at"},
+	{Key: "updated", Label: "Recently updated", Column: "updated_at", Desc: true},
+	{Key: "title", Label: "Title A–Z", Column: "title"},
+}
+
+// postSortFor is the ordering named key in the query string, or the
+// default
+func postSortFor(key string) postSort {
+	for _, sort := range postSorts {
+		if sort.Key == key {
+			return sort
+		}
+	}
+	return postSorts[0]
+}
+
+// postFilter is the admin posts list's filter form. After is the cursor of
+// the last post on the previous page.
+type postFilter struct {
+	Search string
+	Status string
+	Author string
+	From   string
+	To     string
+	Sort   string
+	After  string
+}
+
+// postFilterFromParams reads the filter from the query string, dropping
+// values that aren't a known status, author ID, date, ordering or cursor
+func postFilterFromParams(params buffalo.ParamValues) postFilter {
+	f := postFilter{
+		Search: strings.TrimSpace(params.Get("search")),
+		Status: params.Get("status"),
+		Author: params.Get("author"),
+		From:   params.Get("from"),
+		To:     params.Get("to"),
+		Sort:   params.Get("sort"),
+		After:  params.Get("after"),
+	}
+	if f.Status != "published" && f.Status != "draft" {
+		f.Status = ""
+	}
+	if _, err := uuid.FromString(f.Author); err != nil {
+		f.Author = ""
+	}
+	if _, err := time.Parse(dateInputLayout, f.From); err != nil {
+		f.From = ""
+	}
+	if _, err := time.Parse(dateInputLayout, f.To); err != nil {
+		f.To = ""
+	}
+	f.Sort = postSortFor(f.Sort).Key
+	if _, ok := f.cursor(); !ok {
+		f.After = ""
+	}
+	return f
+}
+
+// Apply narrows q to the posts matching the filter, ordered and starting
+// after the cursor
+func (f postFilter) Apply(q *pop.Query) *pop.Query {
+	if f.Search != "" {
+		like := "%" + f.Search + "%"
+		q = q.Where("("+postSearchVector+" @@ websearch_to_tsquery('english', ?) OR title ILIKE ? OR slug ILIKE ?)", f.Search, like, like)
+	}
+	switch f.Status {
+	case "published":
+		q = q.Where("published = ?", true)
+	case "draft":
+		q = q.Where("published = ?", false)
+	}
+	if f.Author != "" {
+		q = q.Where("author_id = ?", f.Author)
+	}
+	if f.From != "" {
+		from, _ := time.Parse(dateInputLayout, f.From)
+		q = q.Where("created_at >= ?", from)
+	}
+	if f.To != "" {
+		to, _ := time.Parse(dateInputLayout, f.To)
+		q = q.Where("created_at < ?", to.AddDate(0, 0, 1))
+	}
+
+	sort := postSortFor(f.Sort)
+	direction, compare := "asc", ">"
+	if sort.Desc {
+		direction, compare = "desc", "<"
+	}
+	if cursor, ok := f.cursor(); ok && f.After != "" {
+		q = q.Where("("+sort.Column+", id) "+compare+" (?, ?)", cursor.value, cursor.id)
+	}
+	return q.Order(sort.Column + " " + direction + ", id " + direction)
+}
+
+// Query is the filter as a query string, for links that keep it. The
+// cursor is left off, so links start from the first page.
+func (f postFilter) Query() string {
+	v := url.Values{}
+	for key, value := range map[string]string{"search": f.Search, "status": f.Status, "author": f.Author, "from": f.From, "to": f.To} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	if f.Sort != postSorts[0].Key {
+		v.Set("sort", f.Sort)
+	}
+	return v.Encode()
+}
+
+// PageQuery is the filter as a query string including the cursor, for
+// coming back to the same page
+func (f postFilter) PageQuery() string {
+	v, _ := url.ParseQuery(f.Query())
+	if f.After != "" {
+		v.Set("after", f.After)
+	}
+	return v.Encode()
+}
+
+// NextQuery is the query string for the page after the one ending at post
+func (f postFilter) NextQuery(post models.Post) string {
+	v, _ := url.ParseQuery(f.Query())
+	v.Set("after", encodePostCursor(f.Sort, post))
+	return v.Encode()
+}
+
+// postCursor is a post's place in an ordering of the posts list
+type postCursor struct {
+	value interface{}
+	id    int
+}
+
+// encodePostCursor is the cursor for post in the named ordering
+func encodePostCursor(sort string, post models.Post) string {
+	var value string
+	switch postSortFor(sort).Column {
+	case "created_at":
+		value = post.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated_at":
+		value = post.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		value = post.Title
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(post.ID) + "|" + value))
+}
+
+// cursor decodes the filter's cursor. It reports false when there's one
+// but it doesn't belong to the filter's ordering.
+func (f postFilter) cursor() (postCursor, bool) {
+	if f.After == "" {
+		return postCursor{}, true
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(f.After)
+	if err != nil {
+		return postCursor{}, false
+	}
+	idText, value, found := strings.Cut(string(raw), "|")
+	id, err := strconv.Atoi(idText)
+	if !found || err != nil {
+		return postCursor{}, false
+	}
+	if column := postSortFor(f.Sort).Column; column == "created_at" || column == "updated_at" {
+		at, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return postCursor{}, false
+		}
+		return postCursor{value: at, id: id}, true
+	}
+	return postCursor{value: value, id: id}, true
+}
+
+// AdminPostsIndex lists blog posts for admin management, a page at a time,
+// with search, status, author and date filters. Pages run on from the last
+// post shown rather than an offset, so they stay quick however many posts
+// there are.
+func AdminPostsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	filter := postFilterFromParams(c.Params())
+
+	posts := []models.Post{}
+	if err := filter.Apply(tx.Q()).Limit(postsPageSize + 1).All(&posts); err != nil {
+		return errors.WithStack(err)
+	}
+	nextQuery := ""
+	if len(posts) > postsPageSize {
+		posts = posts[:postsPageSize]
+		nextQuery = filter.NextQuery(posts[len(posts)-1])
+	}
+	if err := models.LoadPostAuthors(tx, posts); err != nil {
+		return err
+	}
+
+	authors := []models.User{}
+	if err := tx.Where("id IN (SELECT author_id FROM posts)").Order("first_name, last_name").All(&authors); err != nil {
+		return errors.WithStack(err)
+	}
+	anyPosts := len(posts) > 0
+	if !anyPosts {
+		count, err := tx.Count(&models.Post{})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		anyPosts = count > 0
+	}
+
+	c.Set("posts", posts)
+	c.Set("anyPosts", anyPosts)
+	c.Set("filter", filter)
+	c.Set("filtered", filter.Query() != "" || filter.After != "")
+	c.Set("nextQuery", nextQuery)
+	c.Set("authors", authors)
+	c.Set("postSorts", postSorts)
+	return c.Render(http.StatusOK, r.HTML("admin/posts/index.plush.html"))
+}
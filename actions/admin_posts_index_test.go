@@ -0,0 +1,120 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_PostFilterFromParams(t *testing.T) {
+	req := require.New(t)
+
+	author := uuid.Must(uuid.NewV4()).String()
+	f := postFilterFromParams(url.Values{
+		"search": {" build day "},
+		"status": {"draft"},
+		"author": {author},
+		"from":   {"2026-01-01"},
+		"to":     {"not a date"},
+		"sort":   {"title"},
+		"after":  {"not a cursor"},
+	})
+	req.Equal(postFilter{Search: "build day", Status: "draft", Author: author, From: "2026-01-01", Sort: "title"}, f)
+	req.Equal("author="+author+"&from=2026-01-01&search=build+day&sort=title&status=draft", f.Query())
+
+	f = postFilterFromParams(url.Values{"status": {"archived"}, "author": {"pat"}, "sort": {"random"}})
+	req.Equal(postFilter{Sort: "newest"}, f)
+	req.Empty(f.Query())
+}
+
+func Test_PostCursor(t *testing.T) {
+	req := require.New(t)
+
+	created := time.Date(2026, 10, 14, 9, 30, 0, 123456000, time.UTC)
+	post := models.Post{ID: 42, Title: "Spring | Build Day", CreatedAt: created, UpdatedAt: created.Add(time.Hour)}
+
+	next, err := url.ParseQuery(postFilter{Status: "published", Sort: "newest"}.NextQuery(post))
+	req.NoError(err)
+	req.Equal("published", next.Get("status"))
+	f := postFilterFromParams(next)
+	cursor, ok := f.cursor()
+	req.True(ok)
+	req.Equal(postCursor{value: created, id: 42}, cursor)
+	req.Equal("after="+url.QueryEscape(f.After)+"&status=published", f.PageQuery())
+
+	f = postFilterFromParams(url.Values{"sort": {"title"}, "after": {encodePostCursor("title", post)}})
+	cursor, ok = f.cursor()
+	req.True(ok)
+	req.Equal(postCursor{value: "Spring | Build Day", id: 42}, cursor)
+
+	// A date cursor doesn't fit the title ordering, and the other way round
+	f = postFilterFromParams(url.Values{"sort": {"oldest"}, "after": {encodePostCursor("title", post)}})
+	req.Empty(f.After)
+}
+
+func Test_PostsIndexFilterRendering(t *testing.T) {
+	req := require.New(t)
+
+	author := models.User{ID: uuid.Must(uuid.NewV4()), FirstName: "Pat", LastName: "Writer"}
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/posts-test", func(c buffalo.Context) error {
+		c.Set("posts", []models.Post{})
+		c.Set("anyPosts", true)
+		c.Set("filter", postFilterFromParams(url.Values{"author": {author.ID.String()}, "sort": {"updated"}}))
+		c.Set("filtered", true)
+		c.Set("nextQuery", "")
+		c.Set("authors", []models.User{author})
+		c.Set("postSorts", postSorts)
+		return c.Render(http.StatusOK, r.HTML("admin/posts/index.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/posts-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	body := w.Body.String()
+	req.Contains(body, fmt.Sprintf(`<option value="%s" selected>Pat Writer</option>`, author.ID))
+	req.Contains(body, `<option value="updated" selected>Recently updated</option>`)
+	req.Contains(body, "No posts match.")
+	req.NotContains(body, "No Posts Yet")
+}
+
+func (as *ActionSuite) Test_AdminPostsIndex_FiltersAndPages() {
+	admin := &models.User{Email: "admin@test.com", FirstName: "Admin", LastName: "User", Role: "admin"}
+	admin.Password = "password"
+	admin.PasswordConfirmation = "password"
+	verrs, err := admin.Create(as.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+	as.Session.Set("current_user_id", admin.ID)
+
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < postsPageSize+2; i++ {
+		post := &models.Post{Title: fmt.Sprintf("Update %02d", i), Slug: fmt.Sprintf("update-%02d", i), Content: "Progress on the build", AuthorID: admin.ID}
+		as.NoError(as.DB.Create(post))
+		as.NoError(as.DB.RawQuery("UPDATE posts SET created_at = ? WHERE id = ?", start.AddDate(0, 0, i), post.ID).Exec())
+	}
+	story := &models.Post{Title: "Veteran Story", Slug: "veteran-story", Content: "Carpentry apprentices finished the roof", AuthorID: admin.ID}
+	as.NoError(as.DB.Create(story))
+
+	res := as.HTML("/admin/posts?sort=oldest").Get()
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "Update 00")
+	as.NotContains(res.Body.String(), "Update 26")
+	as.Contains(res.Body.String(), "Next</a>")
+
+	res = as.HTML("/admin/posts?search=apprentice").Get()
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "Veteran Story")
+	as.NotContains(res.Body.String(), "Update 00")
+}
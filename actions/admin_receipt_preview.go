@@ -0,0 +1,149 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// donationReceiptData builds the receipt for a donation's charge as it's
+// resent: with the gift's thank-you and any store order it paid for. It
+// doesn't number the receipt.
+func donationReceiptData(tx *pop.Connection, donation *models.Donation, transactionID string) services.DonationReceiptData {
+	receipt := webhookReceiptData(donation, transactionID)
+	receipt.NextBillingDate = donation.NextBillingDate
+	addThankYouToReceipt(tx, donation, &receipt)
+	addStoreOrderToReceipt(tx, donation, &receipt)
+	return receipt
+}
+
+// selectedReceipt is the issued receipt ?receipt= names, or the latest one.
+// It's nil when none has been issued for the donation.
+func selectedReceipt(issued models.DonationReceipts, number string) *models.DonationReceipt {
+	for i := range issued {
+		if strings.EqualFold(issued[i].ReceiptNumber, strings.TrimSpace(number)) {
+			return &issued[i]
+		}
+	}
+	if len(issued) == 0 {
+		return nil
+	}
+	return &issued[0]
+}
+
+// previewReceiptData builds the receipt a donor was sent for one charge of
+// donation without changing anything: unlike a resend, no receipt number
+// is issued. Later charges of a recurring gift or pledge show the date they
+// were receipted. With no receipt issued yet, it's the receipt a resend
+// would send.
+func previewReceiptData(tx *pop.Connection, donation *models.Donation, receipt *models.DonationReceipt) services.DonationReceiptData {
+	if receipt == nil {
+		return donationReceiptData(tx, donation, donation.ChargeReference())
+	}
+	data := donationReceiptData(tx, donation, receipt.TransactionID)
+	data.ReceiptNumber = receipt.ReceiptNumber
+	if receipt.TransactionID != donation.ChargeReference() {
+		data.DonationDate = receipt.IssuedAt
+	}
+	return data
+}
+
+// loadReceiptPreview finds the donation and the receipt being previewed
+func loadReceiptPreview(c buffalo.Context) (*models.Donation, models.DonationReceipts, *models.DonationReceipt, error) {
+	tx := c.Value("tx").(*pop.Connection)
+	donation, err := findAdminDonation(c)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	issued := models.DonationReceipts{}
+	if err := tx.Where("donation_id = ?", donation.ID).Order("issued_at desc").All(&issued); err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+	return donation, issued, selectedReceipt(issued, c.Param("receipt")), nil
+}
+
+// AdminDonationReceiptPreview shows staff exactly what the donor was sent
+// for a donation, rendered from their real data: the receipt email's
+// subject, HTML and plain text, and the year-end receipt they'd download
+// from their account. It's read-only, so formatting complaints can be
+// checked without digging through mail logs or sending the receipt again.
+// Viewing it is logged, since it shows the donor's details.
+func AdminDonationReceiptPreview(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	donation, issued, receipt, err := loadReceiptPreview(c)
+	if err != nil {
+		c.Flash().Add("error", "Donation not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/donations")
+	}
+
+	emailService := services.NewEmailService()
+	preview, err := emailService.PreviewDonationReceipt(donation.DonorEmail, previewReceiptData(tx, donation, receipt))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	year := donation.CreatedAt.Year()
+	if receipt != nil {
+		year = receipt.IssuedAt.Year()
+	}
+	yearEndHTML := ""
+	yearEnd, err := loadYearEndReceipts(tx, year, donation.DonorEmail)
+	if err != nil {
+		return err
+	}
+	if len(yearEnd) > 0 {
+		if yearEndHTML, err = emailService.GenerateYearEndReceiptHTML(yearEnd[0]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	receiptNumber := ""
+	if receipt != nil {
+		receiptNumber = receipt.ReceiptNumber
+	}
+	logging.UserAction(c, user.Email, "donation_receipt_previewed", "Previewed donation receipt", logging.Fields{
+		"donation_id":    donation.ID.String(),
+		"receipt_number": receiptNumber,
+	})
+
+	c.Set("donation", donation)
+	c.Set("issuedReceipts", issued)
+	c.Set("receiptNumber", receiptNumber)
+	c.Set("preview", preview)
+	c.Set("blockedProblems", strings.Join(preview.Blocked, ", "))
+	c.Set("yearEndYear", year)
+	c.Set("yearEndHTML", yearEndHTML)
+	return c.Render(http.StatusOK, r.HTML("admin/donations/receipt_preview.plush.html"))
+}
+
+// AdminDonationReceiptPDF downloads the PDF copy attached to the receipt
+// AdminDonationReceiptPreview shows
+func AdminDonationReceiptPDF(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	donation, _, receipt, err := loadReceiptPreview(c)
+	if err != nil {
+		c.Flash().Add("error", "Donation not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/donations")
+	}
+	data := previewReceiptData(tx, donation, receipt)
+	pdf := services.NewEmailService().GenerateReceiptPDF(data)
+
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", services.ReceiptPDFFilename(data)))
+	return c.Render(http.StatusOK, r.Func("application/pdf", func(w io.Writer, d render.Data) error {
+		_, err := w.Write(pdf)
+		return err
+	}))
+}
@@ -0,0 +1,81 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+)
+
+func Test_SelectedReceipt(t *testing.T) {
+	req := require.New(t)
+
+	issued := models.DonationReceipts{
+		{ReceiptNumber: "AVR-2026-000031"},
+		{ReceiptNumber: "AVR-2026-000012"},
+	}
+	req.Equal("AVR-2026-000031", selectedReceipt(issued, "").ReceiptNumber, "the latest receipt by default")
+	req.Equal("AVR-2026-000012", selectedReceipt(issued, " avr-2026-000012 ").ReceiptNumber)
+	req.Equal("AVR-2026-000031", selectedReceipt(issued, "AVR-1999-000001").ReceiptNumber)
+	req.Nil(selectedReceipt(models.DonationReceipts{}, ""))
+}
+
+func Test_AdminDonationReceiptPreviewTemplate(t *testing.T) {
+	req := require.New(t)
+
+	donation := models.Donation{
+		ID:         uuid.Must(uuid.NewV4()),
+		DonorName:  "Jane Doe",
+		DonorEmail: "jane@example.com",
+		Amount:     75,
+		CreatedAt:  time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC),
+	}
+	render := func(c buffalo.Context, blocked string, yearEnd string) error {
+		c.Set("donation", &donation)
+		c.Set("issuedReceipts", models.DonationReceipts{{ReceiptNumber: "AVR-2026-000031"}, {ReceiptNumber: "AVR-2026-000012"}})
+		c.Set("receiptNumber", "AVR-2026-000031")
+		c.Set("preview", services.DonationReceiptPreview{
+			To:          donation.DonorEmail,
+			Subject:     "Thank you for your donation to Test Charity",
+			HTML:        `<p class="greeting">Dear Jane Doe,</p>`,
+			Text:        "Dear Jane Doe,",
+			PDFFilename: "donation-receipt-2026-10-01.pdf",
+		})
+		c.Set("blockedProblems", blocked)
+		c.Set("yearEndYear", 2026)
+		c.Set("yearEndHTML", yearEnd)
+		return c.Render(http.StatusOK, r.HTML("admin/donations/receipt_preview.plush.html"))
+	}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/preview-test", func(c buffalo.Context) error {
+		return render(c, "", "<h1>2026 Tax Receipt</h1>")
+	})
+	app.GET("/blocked-test", func(c buffalo.Context) error {
+		return render(c, "organization EIN", "")
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/preview-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	body := w.Body.String()
+	req.Contains(body, "Thank you for your donation to Test Charity")
+	req.Contains(body, `srcdoc="&lt;p class=&#34;greeting&#34;&gt;Dear Jane Doe,&lt;/p&gt;"`, "the receipt HTML is escaped into a sandboxed frame")
+	req.Contains(body, `/receipt?receipt=AVR-2026-000012`)
+	req.Contains(body, `/receipt/pdf?receipt=AVR-2026-000031`)
+	req.Contains(body, "My Tax Receipts")
+	req.NotContains(body, "would be held back")
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/blocked-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "It's missing organization EIN.")
+	req.Contains(w.Body.String(), "isn't on a 2026 tax receipt")
+}
@@ -0,0 +1,249 @@
+package actions
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/pkg/ratelimit"
+)
+
+const (
+	stepUpSessionKey       = "admin_step_up_at"
+	stepUpReturnSessionKey = "admin_step_up_return"
+	defaultStepUpWindow    = 10 * time.Minute
+)
+
+// parseIPAllowlist turns a comma separated list of IPs and CIDR ranges into
+// networks. Bare IPs are treated as single-host ranges; invalid entries are
+// skipped so a typo can't lock every admin out.
+func parseIPAllowlist(raw string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				continue
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			entry = ip.String() + "/" + strconv.Itoa(bits)
+		}
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+// ipAllowed reports whether ip falls within the allowlist. An empty allowlist
+// allows everything.
+func ipAllowed(allowlist []*net.IPNet, ip string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range allowlist {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// stepUpEnabled reports whether destructive admin actions require a fresh
+// password re-prompt. Enabled by default outside the test environment.
+func stepUpEnabled() bool {
+	switch strings.ToLower(envy.Get("ADMIN_STEP_UP_ENABLED", "")) {
+	case "true", "1", "yes":
+		return true
+	case "false", "0", "no":
+		return false
+	}
+	return envy.Get("GO_ENV", "development") != "test"
+}
+
+// stepUpWindow is how long a successful re-authentication stays valid.
+func stepUpWindow() time.Duration {
+	if d, err := time.ParseDuration(envy.Get("ADMIN_STEP_UP_WINDOW", "")); err == nil && d > 0 {
+		return d
+	}
+	return defaultStepUpWindow
+}
+
+// stepUpSatisfied reports whether the current session re-authenticated
+// recently enough to perform a sensitive action.
+func stepUpSatisfied(c buffalo.Context) bool {
+	if !stepUpEnabled() {
+		return true
+	}
+	at, ok := c.Session().Get(stepUpSessionKey).(int64)
+	if !ok {
+		return false
+	}
+	return time.Since(time.Unix(at, 0)) < stepUpWindow()
+}
+
+// sensitiveActionAllowed checks the admin IP allowlist and step-up state for
+// a destructive action. When it returns false the accompanying error (or
+// redirect) is the response and the action must not run.
+func sensitiveActionAllowed(c buffalo.Context, action string) (bool, error) {
+	user, _ := c.Value("current_user").(*models.User)
+	ip := getClientIP(c)
+
+	allowlist := parseIPAllowlist(envy.Get("ADMIN_IP_ALLOWLIST", ""))
+	if !ipAllowed(allowlist, ip) {
+		fields := logging.Fields{"action": action, "ip": ip}
+		if user != nil {
+			fields["user_id"] = user.ID.String()
+			fields["email"] = user.Email
+		}
+		logging.SecurityEvent(c, "admin_sensitive_action", "failure", "ip_not_allowlisted", fields)
+		return false, c.Error(http.StatusForbidden, errors.New("admin action not permitted from this address"))
+	}
+
+	if stepUpSatisfied(c) {
+		return true, nil
+	}
+
+	// Only ever send the admin back to a local admin page.
+	returnTo := "/admin"
+	if ref, err := url.Parse(c.Request().Referer()); err == nil && strings.HasPrefix(ref.Path, "/admin") {
+		returnTo = ref.Path
+	}
+	c.Session().Set(stepUpReturnSessionKey, returnTo)
+	c.Flash().Add("warning", "Please confirm your password to continue, then repeat the action.")
+	return false, c.Redirect(http.StatusFound, "/admin/step-up")
+}
+
+// SensitiveAdminAction wraps a handler that performs a destructive admin
+// operation (user deletion, refunds, ...) with the IP allowlist and step-up
+// re-authentication checks.
+func SensitiveAdminAction(action string, next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		ok, err := sensitiveActionAllowed(c, action)
+		if !ok {
+			return err
+		}
+		return next(c)
+	}
+}
+
+// stepUpThrottle holds each admin account to the login limit on password
+// re-prompts. It's keyed by account rather than IP, so a hijacked session
+// can't spread its guesses over many addresses.
+type stepUpThrottle struct {
+	Limiter *ratelimit.Limiter
+	Rule    ratelimit.Rule
+}
+
+// adminStepUpThrottle throttles step-up attempts; nil when rate limits or
+// the login limit are off
+var adminStepUpThrottle *stepUpThrottle
+
+// newStepUpThrottle reuses the login limit (RATE_LIMIT_LOGIN), counting
+// attempts with limiter
+func newStepUpThrottle(limiter *ratelimit.Limiter) *stepUpThrottle {
+	login, ok := rateLimitsFromEnv()[rateLimitKey(http.MethodPost, "/auth")]
+	if !ok {
+		return nil
+	}
+	return &stepUpThrottle{Limiter: limiter, Rule: login.Rule}
+}
+
+// Allow counts a step-up attempt by user and reports whether it may go
+// ahead, and if not, how long until it can. A store error lets the attempt
+// through, as the request limits do. A nil throttle allows everything.
+func (t *stepUpThrottle) Allow(user *models.User) (bool, time.Duration) {
+	if t == nil {
+		return true, 0
+	}
+	result, err := t.Limiter.Allow("step_up_account:"+user.ID.String(), t.Rule)
+	if err != nil {
+		logging.Error("Step-up throttle check failed", err, logging.Fields{"user_id": user.ID.String()})
+		return true, 0
+	}
+	if result.Allowed {
+		return true, 0
+	}
+	return false, result.RetryAfter(t.Limiter.Now())
+}
+
+// AdminStepUpNew shows the password re-prompt for sensitive admin actions
+func AdminStepUpNew(c buffalo.Context) error {
+	return c.Render(http.StatusOK, r.HTML("admin/step_up.plush.html"))
+}
+
+// AdminStepUpCreate verifies the admin's password and records the step-up
+func AdminStepUpCreate(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+
+	if ok, retryAfter := adminStepUpThrottle.Allow(user); !ok {
+		logging.SecurityEvent(c, "admin_step_up", "blocked", "rate_limited", logging.Fields{
+			"user_id": user.ID.String(),
+			"email":   user.Email,
+			"ip":      getClientIP(c),
+		})
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+
+		verrs := validate.NewErrors()
+		verrs.Add("password", "Too many attempts. Please wait a few minutes and try again.")
+		c.Set("errors", verrs)
+		return c.Render(http.StatusTooManyRequests, r.HTML("admin/step_up.plush.html"))
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(c.Param("password"))); err != nil {
+		logging.SecurityEvent(c, "admin_step_up", "failure", "invalid_password", logging.Fields{
+			"user_id": user.ID.String(),
+			"email":   user.Email,
+			"ip":      getClientIP(c),
+		})
+
+		verrs := validate.NewErrors()
+		verrs.Add("password", "Password is incorrect")
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnauthorized, r.HTML("admin/step_up.plush.html"))
+	}
+
+	c.Session().Set(stepUpSessionKey, time.Now().Unix())
+
+	logging.Audit("admin_step_up", logging.Fields{
+		"user_id": user.ID.String(),
+		"email":   user.Email,
+		"ip":      getClientIP(c),
+		"window":  stepUpWindow().String(),
+	})
+	logging.SecurityEvent(c, "admin_step_up", "success", "password_confirmed", logging.Fields{
+		"user_id": user.ID.String(),
+	})
+
+	returnTo, _ := c.Session().Get(stepUpReturnSessionKey).(string)
+	c.Session().Delete(stepUpReturnSessionKey)
+	if returnTo == "" {
+		returnTo = "/admin"
+	}
+
+	c.Flash().Add("success", "Identity confirmed. You can now complete the action.")
+	return c.Redirect(http.StatusFound, returnTo)
+}
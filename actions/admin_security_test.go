@@ -0,0 +1,78 @@
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/gofrs/uuid"
+
+	"github.com/stretchr/testify/assert"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/ratelimit"
+)
+
+func Test_ParseIPAllowlist(t *testing.T) {
+	nets := parseIPAllowlist(" 10.0.0.0/8, 192.168.1.5 ,not-an-ip,, ::1")
+	assert.Len(t, nets, 3)
+
+	assert.True(t, ipAllowed(nets, "10.2.3.4"))
+	assert.True(t, ipAllowed(nets, "192.168.1.5"))
+	assert.True(t, ipAllowed(nets, "::1"))
+	assert.False(t, ipAllowed(nets, "192.168.1.6"))
+	assert.False(t, ipAllowed(nets, "garbage"))
+}
+
+func Test_IPAllowed_EmptyAllowlist(t *testing.T) {
+	assert.True(t, ipAllowed(nil, "203.0.113.7"))
+	assert.True(t, ipAllowed(parseIPAllowlist(""), "203.0.113.7"))
+}
+
+func Test_StepUpEnabled(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("ADMIN_STEP_UP_ENABLED", "true")
+		assert.True(t, stepUpEnabled())
+
+		envy.Set("ADMIN_STEP_UP_ENABLED", "false")
+		assert.False(t, stepUpEnabled())
+
+		envy.Set("ADMIN_STEP_UP_ENABLED", "")
+		envy.Set("GO_ENV", "test")
+		assert.False(t, stepUpEnabled())
+
+		envy.Set("GO_ENV", "production")
+		assert.True(t, stepUpEnabled())
+	})
+}
+
+func Test_StepUpThrottle(t *testing.T) {
+	var throttle *stepUpThrottle
+	envy.Temp(func() {
+		envy.Set("RATE_LIMIT_LOGIN", "2/5m")
+		throttle = newStepUpThrottle(ratelimit.New(ratelimit.NewMemoryStore()))
+	})
+	assert.Equal(t, "2/5m0s", throttle.Rule.String())
+
+	admin := &models.User{ID: uuid.Must(uuid.NewV4())}
+	other := &models.User{ID: uuid.Must(uuid.NewV4())}
+	for i := 0; i < 2; i++ {
+		ok, _ := throttle.Allow(admin)
+		assert.True(t, ok)
+	}
+	ok, retryAfter := throttle.Allow(admin)
+	assert.False(t, ok)
+	assert.Greater(t, retryAfter, time.Duration(0))
+
+	ok, _ = throttle.Allow(other)
+	assert.True(t, ok, "each account has its own count")
+
+	var off *stepUpThrottle
+	ok, _ = off.Allow(admin)
+	assert.True(t, ok)
+
+	envy.Temp(func() {
+		envy.Set("RATE_LIMIT_LOGIN", "off")
+		assert.Nil(t, newStepUpThrottle(ratelimit.New(ratelimit.NewMemoryStore())))
+	})
+}
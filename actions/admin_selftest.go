@@ -0,0 +1,253 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+const (
+	selfTestPass = "pass"
+	selfTestFail = "fail"
+	selfTestSkip = "skip"
+
+	selfTestAmount = 1.00
+)
+
+// errSelfTestRollback ends the self-test transaction so the synthetic
+// donation is never committed.
+var errSelfTestRollback = errors.New("self-test complete")
+
+// SelfTestStep is the outcome of one stage of the donation flow.
+type SelfTestStep struct {
+	Name     string
+	Status   string
+	Detail   string
+	Duration time.Duration
+}
+
+// SelfTestRun is one synthetic pass through initialize → verify → charge →
+// webhook → receipt.
+type SelfTestRun struct {
+	StartedAt time.Time
+	Gateway   string
+	Steps     []SelfTestStep
+	Duration  time.Duration
+}
+
+// Passed reports whether every step passed.
+func (r *SelfTestRun) Passed() bool {
+	for _, s := range r.Steps {
+		if s.Status != selfTestPass {
+			return false
+		}
+	}
+	return len(r.Steps) > 0
+}
+
+// step times fn and records the result. Once a step fails the remaining
+// steps are recorded as skipped, since each one depends on the last.
+func (r *SelfTestRun) step(name string, fn func() (string, error)) {
+	for _, s := range r.Steps {
+		if s.Status != selfTestPass {
+			r.Steps = append(r.Steps, SelfTestStep{Name: name, Status: selfTestSkip, Detail: "skipped after earlier failure"})
+			return
+		}
+	}
+
+	start := time.Now()
+	detail, err := fn()
+	s := SelfTestStep{Name: name, Status: selfTestPass, Detail: detail, Duration: time.Since(start)}
+	if err != nil {
+		s.Status = selfTestFail
+		s.Detail = err.Error()
+	}
+	r.Steps = append(r.Steps, s)
+}
+
+var (
+	lastSelfTestMu sync.RWMutex
+	lastSelfTest   *SelfTestRun
+)
+
+// runDonationSelfTest drives a synthetic donation through the payment flow
+// inside a transaction that is always rolled back. When receiptTo is set the
+// receipt is emailed there; otherwise it is only rendered.
+func runDonationSelfTest(db *pop.Connection, c buffalo.Context, receiptTo string) *SelfTestRun {
+	// The charge is always mocked: the run is rolled back, so a real charge
+	// would leave no donation to refund it from
+	client := services.NewMockHelcimClient()
+	run := &SelfTestRun{StartedAt: time.Now(), Gateway: "mock"}
+
+	err := db.Transaction(func(tx *pop.Connection) error {
+		donation := &models.Donation{
+			Amount:       selfTestAmount,
+			Currency:     getCurrency(),
+			DonorName:    "Self-Test Donor",
+			DonorEmail:   "selftest@avrnpo.org",
+			DonationType: models.DonationTypeOneTime,
+			Status:       "pending",
+			Comments:     stringPointer("synthetic admin self-test"),
+		}
+		var transactionID string
+
+		run.step("Initialize", func() (string, error) {
+			verrs, err := tx.ValidateAndCreate(donation)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			if verrs.HasAny() {
+				return "", fmt.Errorf("donation failed validation: %s", verrs.String())
+			}
+			return "pending donation " + donation.ID.String(), nil
+		})
+
+		run.step("Verify", func() (string, error) {
+			resp, err := callHelcimVerifyAPI(HelcimPayVerifyRequest{
+				PaymentType: "verify",
+				Amount:      donation.Amount,
+				Currency:    donation.Currency,
+			})
+			if err != nil {
+				return "", err
+			}
+			if resp.CheckoutToken == "" || resp.SecretToken == "" {
+				return "", errors.New("Helcim returned an empty checkout token")
+			}
+			donation.CheckoutToken = resp.CheckoutToken
+			donation.SecretToken = resp.SecretToken
+			return "checkout token issued", errors.WithStack(tx.Update(donation))
+		})
+
+		run.step("Charge", func() (string, error) {
+			resp, err := client.ProcessPayment(c, services.PaymentAPIRequest{
+				PaymentType:   "purchase",
+				Amount:        donation.Amount,
+				Currency:      donation.Currency,
+				CustomerCode:  "SELFTEST",
+				CardData:      services.CardData{CardToken: "selftest_card_token"},
+				IPAddress:     getClientIP(c),
+				Description:   "Admin self-test",
+				CustomerEmail: donation.DonorEmail,
+				CustomerName:  donation.DonorName,
+			})
+			if err != nil {
+				return "", err
+			}
+			if !strings.EqualFold(resp.Status, "APPROVED") {
+				return "", fmt.Errorf("charge was %s", resp.Status)
+			}
+			transactionID = fmt.Sprintf("selftest_%d", resp.TransactionID)
+			donation.TransactionID = &transactionID
+			return fmt.Sprintf("%s approved %s", run.Gateway, transactionID), errors.WithStack(tx.Update(donation))
+		})
+
+		run.step("Webhook", func() (string, error) {
+			body, err := json.Marshal(HelcimWebhookEvent{
+				ID:   transactionID,
+				Type: "cardTransaction",
+				Data: map[string]interface{}{"transactionId": transactionID},
+			})
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			signature := "sha256=" + generateHMACSignature(body, os.Getenv("HELCIM_WEBHOOK_VERIFIER_TOKEN"))
+			if !verifyWebhookSignature(body, signature) {
+				return "", errors.New("signed webhook was rejected; check HELCIM_WEBHOOK_VERIFIER_TOKEN")
+			}
+
+			completed, err := completeWebhookDonation(tx, transactionID, c)
+			if err != nil {
+				return "", err
+			}
+			if completed == nil || completed.Status != "completed" {
+				return "", errors.New("webhook did not complete the donation")
+			}
+			donation = completed
+			return "signature verified, donation completed", nil
+		})
+
+		run.step("Receipt", func() (string, error) {
+			data := webhookReceiptData(donation, transactionID)
+			emailService := services.NewEmailService()
+			if receiptTo == "" {
+				// Sending would run the same check
+				if err := services.CheckReceiptCompliance(data); err != nil {
+					return "", err
+				}
+				html, err := emailService.GenerateReceiptHTMLForTool(data)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("rendered %d bytes (not sent)", len(html)), nil
+			}
+			if err := emailService.SendDonationReceipt(receiptTo, data); err != nil {
+				return "", err
+			}
+			return "sent to " + receiptTo, nil
+		})
+
+		return errSelfTestRollback
+	})
+	if err != nil && !errors.Is(err, errSelfTestRollback) {
+		run.Steps = append(run.Steps, SelfTestStep{Name: "Database", Status: selfTestFail, Detail: err.Error()})
+	}
+
+	run.Duration = time.Since(run.StartedAt)
+	return run
+}
+
+// AdminSelfTest shows the most recent synthetic donation run
+func AdminSelfTest(c buffalo.Context) error {
+	lastSelfTestMu.RLock()
+	c.Set("run", lastSelfTest)
+	lastSelfTestMu.RUnlock()
+	return c.Render(http.StatusOK, r.HTML("admin/self_test.plush.html"))
+}
+
+// AdminSelfTestRun runs the synthetic donation flow so a deploy can be
+// validated before a campaign. Nothing is committed to the database.
+func AdminSelfTestRun(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+
+	receiptTo := ""
+	if c.Param("send_receipt") == "true" {
+		receiptTo = user.Email
+	}
+
+	run := runDonationSelfTest(models.DB, c, receiptTo)
+
+	lastSelfTestMu.Lock()
+	lastSelfTest = run
+	lastSelfTestMu.Unlock()
+
+	fields := logging.Fields{
+		"admin_id": user.ID.String(),
+		"gateway":  run.Gateway,
+		"passed":   run.Passed(),
+		"duration": run.Duration.String(),
+	}
+	for _, s := range run.Steps {
+		fields["step_"+strings.ToLower(s.Name)] = s.Status
+	}
+	logging.Audit("admin_self_test", fields)
+
+	if run.Passed() {
+		c.Flash().Add("success", "Donation self-test passed.")
+	} else {
+		c.Flash().Add("danger", "Donation self-test failed. See the step results below.")
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/self-test")
+}
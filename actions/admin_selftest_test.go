@@ -0,0 +1,33 @@
+package actions
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SelfTestRunStep(t *testing.T) {
+	r := require.New(t)
+
+	run := &SelfTestRun{}
+	run.step("first", func() (string, error) { return "ok", nil })
+	r.True(run.Passed())
+
+	run.step("second", func() (string, error) { return "", errors.New("gateway down") })
+	called := false
+	run.step("third", func() (string, error) { called = true; return "", nil })
+
+	r.False(called)
+	r.False(run.Passed())
+	r.Len(run.Steps, 3)
+	r.Equal(selfTestPass, run.Steps[0].Status)
+	r.Equal(selfTestFail, run.Steps[1].Status)
+	r.Equal("gateway down", run.Steps[1].Detail)
+	r.Equal(selfTestSkip, run.Steps[2].Status)
+}
+
+func Test_SelfTestRunPassedEmpty(t *testing.T) {
+	r := require.New(t)
+	r.False((&SelfTestRun{}).Passed())
+}
@@ -0,0 +1,56 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// AdminSessionsIndex shows the session backend and active session count
+func AdminSessionsIndex(c buffalo.Context) error {
+	c.Set("serverSide", serverSessions != nil)
+	c.Set("activeSessions", 0)
+
+	if serverSessions != nil {
+		count, err := serverSessions.ActiveCount()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		c.Set("activeSessions", count)
+	}
+
+	return c.Render(http.StatusOK, r.HTML("admin/sessions.plush.html"))
+}
+
+// AdminSessionsInvalidate deletes every server-side session, signing out all
+// users including the admin performing the action
+func AdminSessionsInvalidate(c buffalo.Context) error {
+	if serverSessions == nil {
+		c.Flash().Add("danger", "Sessions are stored in cookies. Rotate SESSION_SECRET to invalidate them.")
+		return c.Redirect(http.StatusFound, "/admin/sessions")
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+
+	count, err := serverSessions.InvalidateAll()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.Audit("sessions_invalidated", logging.Fields{
+		"user_id":  currentUser.ID.String(),
+		"email":    currentUser.Email,
+		"sessions": count,
+		"ip":       getClientIP(c),
+	})
+	logging.UserAction(c, currentUser.ID.String(), "sessions_invalidated", fmt.Sprintf("Invalidated %d session(s)", count), logging.Fields{})
+
+	c.Session().Clear()
+	c.Flash().Add("success", fmt.Sprintf("Invalidated %d session(s). Please sign in again.", count))
+	return c.Redirect(http.StatusFound, "/auth/new")
+}
@@ -0,0 +1,113 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// deactivationReasonMaxLength caps the reason staff give for deactivating
+// an account
+const deactivationReasonMaxLength = 1000
+
+// deactivationReasonParam reads the reason given for deactivating accounts,
+// returning a message for staff when it's missing or too long
+func deactivationReasonParam(c buffalo.Context) (string, string) {
+	reason := strings.TrimSpace(c.Param("reason"))
+	switch {
+	case reason == "":
+		return "", "Give a reason for deactivating the account."
+	case len(reason) > deactivationReasonMaxLength:
+		return "", fmt.Sprintf("Keep the reason under %d characters.", deactivationReasonMaxLength)
+	}
+	return reason, ""
+}
+
+// AdminUserDeactivate suspends a user's account. They're signed out and
+// can't sign back in, but nothing tied to the account is removed.
+func AdminUserDeactivate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if user.ID == currentUser.ID {
+		c.Flash().Add("danger", "You cannot deactivate your own account.")
+		return c.Redirect(http.StatusSeeOther, "/admin/users/%s", user.ID)
+	}
+	if !user.Active() {
+		c.Flash().Add("info", fmt.Sprintf("%s is already deactivated.", user.Email))
+		return c.Redirect(http.StatusSeeOther, "/admin/users/%s", user.ID)
+	}
+	reason, problem := deactivationReasonParam(c)
+	if problem != "" {
+		c.Flash().Add("danger", problem)
+		return c.Redirect(http.StatusSeeOther, "/admin/users/%s", user.ID)
+	}
+
+	// Their sessions stop working on the next request (see SetCurrentUser)
+	user.Deactivate(reason, time.Now())
+	if err := tx.UpdateColumns(user, "deactivated_at", "deactivation_reason", "updated_at"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.Audit("user_deactivated", logging.Fields{
+		"admin_email":    currentUser.Email,
+		"target_user_id": user.ID.String(),
+		"target_email":   user.Email,
+		"reason":         reason,
+		"ip":             getClientIP(c),
+	})
+	logging.UserAction(c, currentUser.ID.String(), "admin_deactivate_user", fmt.Sprintf("Admin deactivated user %s", user.Email), logging.Fields{
+		"target_user_id": user.ID.String(),
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Deactivated %s. They've been signed out.", user.Email))
+	return c.Redirect(http.StatusSeeOther, "/admin/users/%s", user.ID)
+}
+
+// AdminUserReactivate lets a deactivated user sign in again with their
+// existing password
+func AdminUserReactivate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	user := &models.User{}
+	if err := tx.Find(user, c.Param("user_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if user.Active() {
+		c.Flash().Add("info", fmt.Sprintf("%s is already active.", user.Email))
+		return c.Redirect(http.StatusSeeOther, "/admin/users/%s", user.ID)
+	}
+
+	previousReason := user.DeactivationReasonText()
+	user.Reactivate()
+	if err := tx.UpdateColumns(user, "deactivated_at", "deactivation_reason", "updated_at"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.Audit("user_reactivated", logging.Fields{
+		"admin_email":         currentUser.Email,
+		"target_user_id":      user.ID.String(),
+		"target_email":        user.Email,
+		"deactivation_reason": previousReason,
+		"ip":                  getClientIP(c),
+	})
+	logging.UserAction(c, currentUser.ID.String(), "admin_reactivate_user", fmt.Sprintf("Admin reactivated user %s", user.Email), logging.Fields{
+		"target_user_id": user.ID.String(),
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Reactivated %s.", user.Email))
+	return c.Redirect(http.StatusSeeOther, "/admin/users/%s", user.ID)
+}
@@ -0,0 +1,68 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_DeactivationReasonParam(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.POST("/reason-test", func(c buffalo.Context) error {
+		reason, problem := deactivationReasonParam(c)
+		return c.Render(http.StatusOK, r.String(reason+"|"+problem))
+	})
+	post := func(reason string) string {
+		w := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("POST", "/reason-test", strings.NewReader(url.Values{"reason": {reason}}.Encode()))
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		app.ServeHTTP(w, httpReq)
+		return w.Body.String()
+	}
+
+	req.Equal("Left the organization|", post("  Left the organization "))
+	req.Equal("|Give a reason for deactivating the account.", post("   "))
+	req.Contains(post(strings.Repeat("x", deactivationReasonMaxLength+1)), "Keep the reason under")
+}
+
+func Test_UserEditDeactivationRendering(t *testing.T) {
+	req := require.New(t)
+
+	admin := &models.User{ID: uuid.Must(uuid.NewV4()), FirstName: "Pat", Email: "pat@example.com", Role: "admin"}
+	member := &models.User{ID: uuid.Must(uuid.NewV4()), FirstName: "Jo", Email: "jo@example.com", Role: "user"}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/user-edit-test", func(c buffalo.Context) error {
+		c.Set("current_user", admin)
+		c.Set("user", member)
+		c.Set("roleOptions", []map[string]interface{}{{"value": "user", "label": "User"}})
+		return c.Render(http.StatusOK, r.HTML("admin/users/edit.plush.html"))
+	})
+	render := func() string {
+		w := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("GET", "/user-edit-test", nil)
+		app.ServeHTTP(w, httpReq)
+		req.Equal(http.StatusOK, w.Code, w.Body.String())
+		return w.Body.String()
+	}
+
+	body := render()
+	req.Contains(body, `action="/admin/users/`+member.ID.String()+`/deactivate"`)
+
+	member.Deactivate("Volunteer term ended", time.Date(2026, 9, 30, 12, 0, 0, 0, time.Local))
+	body = render()
+	req.Contains(body, "since Sep 30, 2026")
+	req.Contains(body, "Volunteer term ended")
+	req.Contains(body, `action="/admin/users/`+member.ID.String()+`/reactivate"`)
+}
@@ -0,0 +1,288 @@
+package actions
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// userBulkActions are the bulk actions on the users list, by the label on
+// their confirmation page. Everything but the export is confirmed first.
+var userBulkActions = map[string]string{
+	"deactivate": "Deactivate",
+	"reactivate": "Reactivate",
+	"role":       "Change role",
+	"invite":     "Resend invitation",
+	"export":     "Export",
+}
+
+// userBulkSelection loads the users ticked on the users list, in the order
+// the list shows them
+func userBulkSelection(c buffalo.Context, tx *pop.Connection) ([]models.User, error) {
+	ids := []uuid.UUID{}
+	for _, s := range c.Request().Form["user_ids"] {
+		if id, err := uuid.FromString(strings.TrimSpace(s)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	users := []models.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := tx.Where("id IN (?)", ids).Order("created_at desc").All(&users); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return users, nil
+}
+
+// userBulkSkipReason says why action leaves user alone, or "" when it
+// applies. Admins can't lock themselves out by deactivating or demoting
+// their own account.
+func userBulkSkipReason(action string, user, currentUser models.User, role string) string {
+	switch action {
+	case "deactivate":
+		if user.ID == currentUser.ID {
+			return "That's your account"
+		}
+		if !user.Active() {
+			return "Already deactivated"
+		}
+	case "reactivate":
+		if user.Active() {
+			return "Already active"
+		}
+	case "role":
+		if user.ID == currentUser.ID {
+			return "That's your account"
+		}
+		if user.Role == role {
+			return "Already has that role"
+		}
+	case "invite":
+		if !user.Active() {
+			return "Deactivated"
+		}
+	}
+	return ""
+}
+
+// AdminUsersBulk handles bulk operations on users. Deactivating,
+// reactivating, changing roles and resending invitations show the selected
+// users on a confirmation page first; the export downloads straight away.
+func AdminUsersBulk(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	action := c.Param("action")
+	if _, ok := userBulkActions[action]; !ok {
+		c.Flash().Add("danger", "Invalid bulk action")
+		return c.Redirect(http.StatusSeeOther, "/admin/users")
+	}
+	role := c.Param("role")
+	if action == "role" && !slices.Contains(models.UserRoles, role) {
+		c.Flash().Add("danger", "Choose the role to give the selected users.")
+		return c.Redirect(http.StatusSeeOther, "/admin/users")
+	}
+
+	users, err := userBulkSelection(c, tx)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		c.Flash().Add("danger", "Please select at least one user")
+		return c.Redirect(http.StatusSeeOther, "/admin/users")
+	}
+
+	if action == "export" {
+		return exportUsers(c, currentUser, users)
+	}
+
+	applies := []models.User{}
+	skipped := map[string]string{}
+	for _, user := range users {
+		if reason := userBulkSkipReason(action, user, *currentUser, role); reason != "" {
+			skipped[user.ID.String()] = reason
+			continue
+		}
+		applies = append(applies, user)
+	}
+
+	reason := ""
+	if action == "deactivate" && c.Param("confirm") == "true" {
+		var problem string
+		if reason, problem = deactivationReasonParam(c); problem != "" {
+			c.Flash().Add("danger", problem)
+			return c.Redirect(http.StatusSeeOther, "/admin/users")
+		}
+	}
+
+	if c.Param("confirm") != "true" {
+		c.Set("action", action)
+		c.Set("actionLabel", userBulkActions[action])
+		c.Set("role", role)
+		c.Set("users", users)
+		c.Set("skipped", skipped)
+		c.Set("applyCount", len(applies))
+		return c.Render(http.StatusOK, r.HTML("admin/users/bulk_confirm.plush.html"))
+	}
+
+	if action == "deactivate" || action == "role" {
+		if ok, err := sensitiveActionAllowed(c, "users_bulk_"+action); !ok {
+			return err
+		}
+	}
+	if len(applies) == 0 {
+		c.Flash().Add("danger", "None of the selected users could be changed.")
+		return c.Redirect(http.StatusSeeOther, "/admin/users")
+	}
+
+	ids := make([]uuid.UUID, len(applies))
+	emails := make([]string, len(applies))
+	for i, user := range applies {
+		ids[i] = user.ID
+		emails[i] = user.Email
+	}
+	now := time.Now()
+	fields := logging.Fields{
+		"admin_email": currentUser.Email,
+		"user_count":  len(applies),
+		"emails":      strings.Join(emails, ","),
+		"ip":          getClientIP(c),
+	}
+
+	switch action {
+	case "deactivate":
+		// Their sessions stop working on the next request (see SetCurrentUser)
+		err := tx.RawQuery("UPDATE users SET deactivated_at = ?, deactivation_reason = ?, updated_at = ? WHERE id IN (?)", now, reason, now, ids).Exec()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		fields["reason"] = reason
+		c.Flash().Add("success", fmt.Sprintf("Deactivated %d user(s)", len(applies)))
+
+	case "reactivate":
+		err := tx.RawQuery("UPDATE users SET deactivated_at = NULL, deactivation_reason = NULL, updated_at = ? WHERE id IN (?)", now, ids).Exec()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		c.Flash().Add("success", fmt.Sprintf("Reactivated %d user(s)", len(applies)))
+
+	case "role":
+		err := tx.RawQuery("UPDATE users SET role = ?, updated_at = ? WHERE id IN (?)", role, now, ids).Exec()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		fields["role"] = role
+		c.Flash().Add("success", fmt.Sprintf("Changed %d user(s) to %s", len(applies), role))
+
+	case "invite":
+		sent, failed, err := sendUserInvitations(tx, applies, currentUser, now)
+		if err != nil {
+			return err
+		}
+		fields["failed"] = failed
+		if failed > 0 {
+			c.Flash().Add("danger", fmt.Sprintf("%d invitation(s) couldn't be sent. Check the email settings and try again.", failed))
+		}
+		if sent > 0 {
+			c.Flash().Add("success", fmt.Sprintf("Sent %d invitation(s)", sent))
+		}
+	}
+
+	logging.Audit("users_bulk_"+action, fields)
+	logging.UserAction(c, currentUser.ID.String(), "users_bulk_"+action, fmt.Sprintf("Bulk %s users", strings.ToLower(userBulkActions[action])), logging.Fields{
+		"user_count": len(applies),
+		"skipped":    len(skipped),
+	})
+	return c.Redirect(http.StatusSeeOther, "/admin/users")
+}
+
+// sendUserInvitations emails each user a fresh invitation link, replacing
+// any they were sent before, and reports how many were sent and failed
+func sendUserInvitations(tx *pop.Connection, users []models.User, invitedBy *models.User, now time.Time) (sent int, failed int, err error) {
+	emailService := services.NewEmailService()
+	for i := range users {
+		user := &users[i]
+		token, err := user.Invite(now)
+		if err != nil {
+			return sent, failed, err
+		}
+		err = emailService.SendUserInvitation(user.Email, services.UserInvitationData{
+			FirstName:        user.FirstName,
+			InvitedBy:        strings.TrimSpace(invitedBy.FirstName + " " + invitedBy.LastName),
+			SetupURL:         siteURL() + "/invitations/" + token,
+			ExpiresAt:        now.Add(models.InvitationTTL),
+			OrganizationName: organization().Name,
+		})
+		if err != nil {
+			logging.Error("user_invitation_failed", err, logging.Fields{"user_id": user.ID.String()})
+			failed++
+			continue
+		}
+		if err := tx.UpdateColumns(user, "invitation_token_hash", "invited_at", "updated_at"); err != nil {
+			return sent, failed, errors.WithStack(err)
+		}
+		sent++
+	}
+	return sent, failed, nil
+}
+
+// exportUsers downloads the selected users as CSV
+func exportUsers(c buffalo.Context, currentUser *models.User, users []models.User) error {
+	logging.Audit("users_bulk_export", logging.Fields{
+		"admin_email": currentUser.Email,
+		"user_count":  len(users),
+		"ip":          getClientIP(c),
+	})
+	logging.UserAction(c, currentUser.ID.String(), "users_bulk_export", "Exported users", logging.Fields{
+		"user_count": len(users),
+	})
+
+	filename := fmt.Sprintf("users-%s.csv", time.Now().Format(dateInputLayout))
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Render(http.StatusOK, r.Func("text/csv", func(w io.Writer, d render.Data) error {
+		return writeUserCSV(w, users)
+	}))
+}
+
+// writeUserCSV writes users as CSV, one row per user
+func writeUserCSV(w io.Writer, users []models.User) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"First Name", "Last Name", "Email", "Role", "Status", "Created", "Deactivated"}); err != nil {
+		return err
+	}
+	for _, user := range users {
+		deactivated := ""
+		if user.DeactivatedAt != nil {
+			deactivated = user.DeactivatedAt.Format(dateInputLayout)
+		}
+		err := out.Write([]string{
+			csvCell(user.FirstName),
+			csvCell(user.LastName),
+			csvCell(user.Email),
+			user.Role,
+			user.StatusLabel(),
+			user.CreatedAt.Format(dateInputLayout),
+			deactivated,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
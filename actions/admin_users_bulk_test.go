@@ -0,0 +1,133 @@
+package actions
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_UserBulkSkipReason(t *testing.T) {
+	req := require.New(t)
+
+	deactivatedAt := time.Now()
+	admin := models.User{ID: uuid.Must(uuid.NewV4()), Role: "admin"}
+	member := models.User{ID: uuid.Must(uuid.NewV4()), Role: "user"}
+	former := models.User{ID: uuid.Must(uuid.NewV4()), Role: "user", DeactivatedAt: &deactivatedAt}
+
+	req.Equal("That's your account", userBulkSkipReason("deactivate", admin, admin, ""))
+	req.Equal("That's your account", userBulkSkipReason("role", admin, admin, "user"))
+	req.Empty(userBulkSkipReason("deactivate", member, admin, ""))
+	req.Equal("Already deactivated", userBulkSkipReason("deactivate", former, admin, ""))
+	req.Empty(userBulkSkipReason("reactivate", former, admin, ""))
+	req.Equal("Already active", userBulkSkipReason("reactivate", member, admin, ""))
+	req.Equal("Already has that role", userBulkSkipReason("role", member, admin, "user"))
+	req.Empty(userBulkSkipReason("role", member, admin, "admin"))
+	req.Equal("Deactivated", userBulkSkipReason("invite", former, admin, ""))
+}
+
+func Test_WriteUserCSV(t *testing.T) {
+	req := require.New(t)
+
+	deactivatedAt := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	req.NoError(writeUserCSV(&buf, []models.User{
+		{FirstName: "Jo", LastName: "Rivera", Email: "jo@example.com", Role: "user", CreatedAt: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)},
+		{FirstName: "@Sam", LastName: "Lee", Email: "sam@example.com", Role: "admin", CreatedAt: time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC), DeactivatedAt: &deactivatedAt},
+	}))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	req.Len(lines, 3)
+	req.Equal("First Name,Last Name,Email,Role,Status,Created,Deactivated", lines[0])
+	req.Equal("Jo,Rivera,jo@example.com,user,Active,2026-09-01,", lines[1])
+	req.Equal("'@Sam,Lee,sam@example.com,admin,Deactivated,2026-08-01,2026-10-02", lines[2])
+}
+
+func Test_UserBulkConfirmTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	admin := models.User{ID: uuid.Must(uuid.NewV4()), FirstName: "Pat", Email: "pat@example.com", Role: "admin"}
+	member := models.User{ID: uuid.Must(uuid.NewV4()), FirstName: "Jo", Email: "jo@example.com", Role: "user"}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/users-bulk-test", func(c buffalo.Context) error {
+		c.Set("current_user", &admin)
+		c.Set("action", "deactivate")
+		c.Set("actionLabel", userBulkActions["deactivate"])
+		c.Set("role", "")
+		c.Set("users", []models.User{admin, member})
+		c.Set("skipped", map[string]string{admin.ID.String(): "That's your account"})
+		c.Set("applyCount", 1)
+		return c.Render(http.StatusOK, r.HTML("admin/users/bulk_confirm.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/users-bulk-test", nil)
+	app.ServeHTTP(w, httpReq)
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	body := w.Body.String()
+	req.Contains(body, "Deactivate 1 user(s)")
+	req.Contains(body, "Skipped: That&#39;s your account")
+	req.Contains(body, `name="user_ids" value="`+member.ID.String()+`"`)
+	req.NotContains(body, `name="user_ids" value="`+admin.ID.String()+`"`)
+	req.Contains(body, `name="reason"`)
+}
+
+func Test_InvitationTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	var user *models.User
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/invitation-test", func(c buffalo.Context) error {
+		c.Set("user", user)
+		c.Set("token", "abc123")
+		return c.Render(http.StatusOK, r.HTML("auth/invitation.plush.html"))
+	})
+	render := func() string {
+		w := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("GET", "/invitation-test", nil)
+		app.ServeHTTP(w, httpReq)
+		req.Equal(http.StatusOK, w.Code, w.Body.String())
+		return w.Body.String()
+	}
+
+	req.Contains(render(), "This invitation link has expired")
+
+	user = &models.User{FirstName: "Jo", Email: "jo@example.com"}
+	body := render()
+	req.Contains(body, "Welcome, Jo.")
+	req.Contains(body, `action="/invitations/abc123"`)
+}
+
+func Test_AdminUsersTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	admin := models.User{ID: uuid.Must(uuid.NewV4()), FirstName: "Pat", Email: "pat@example.com", Role: "admin"}
+	hash := "x"
+	invited := models.User{ID: uuid.Must(uuid.NewV4()), FirstName: "Jo", Email: "jo@example.com", Role: "user", InvitationTokenHash: &hash}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/users-test", func(c buffalo.Context) error {
+		c.Set("current_user", &admin)
+		c.Set("users", []models.User{admin, invited})
+		c.Set("pagination", &pop.Paginator{Page: 1, TotalPages: 1})
+		return c.Render(http.StatusOK, r.HTML("admin/users.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/users-test", nil)
+	app.ServeHTTP(w, httpReq)
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	body := w.Body.String()
+	req.Contains(body, `action="/admin/users/bulk"`)
+	req.Contains(body, `name="user_ids" value="`+invited.ID.String()+`"`)
+	req.Contains(body, "Invited")
+}
@@ -159,6 +159,10 @@ func (aur AdminUsersResource) Update(c buffalo.Context) error {
 	updatedUser.CreatedAt = user.CreatedAt
 	updatedUser.Password = ""
 	updatedUser.PasswordConfirmation = ""
+	updatedUser.DeactivatedAt = user.DeactivatedAt
+	updatedUser.DeactivationReason = user.DeactivationReason
+	updatedUser.InvitationTokenHash = user.InvitationTokenHash
+	updatedUser.InvitedAt = user.InvitedAt
 
 	verrs, err := tx.ValidateAndUpdate(updatedUser)
 	if err != nil {
@@ -197,6 +201,10 @@ func (aur AdminUsersResource) Update(c buffalo.Context) error {
 
 // Destroy deletes a user (DELETE /admin/users/{user_id})
 func (aur AdminUsersResource) Destroy(c buffalo.Context) error {
+	if ok, err := sensitiveActionAllowed(c, "user_delete"); !ok {
+		return err
+	}
+
 	tx := c.Value("tx").(*pop.Connection)
 
 	user := &models.User{}
@@ -0,0 +1,775 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// apiTriggerLimit is how many records a polling trigger returns. Zapier
+// only looks at the newest and remembers the IDs it has seen.
+const apiTriggerLimit = 50
+
+// apiKeyFromRequest reads the API key from an "Authorization: Bearer" or
+// X-API-Key header
+func apiKeyFromRequest(req *http.Request) string {
+	if auth := req.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return strings.TrimSpace(req.Header.Get("X-API-Key"))
+}
+
+// lookupKeyRoutes are the only routes a lookup-only key can call, by method
+// and route path
+var lookupKeyRoutes = map[string]bool{
+	rateLimitKey(http.MethodGet, "/api/v1/donations/lookup"): true,
+}
+
+// apiKeyAllows reports whether key's scope covers the route
+func apiKeyAllows(key *models.APIKey, method, routePath string) bool {
+	return !key.LookupOnly() || lookupKeyRoutes[rateLimitKey(method, routePath)]
+}
+
+// APIKeyRequired lets through requests carrying an unrevoked API key whose
+// scope covers the route, and notes when each key was last used
+func APIKeyRequired(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		tx := c.Value("tx").(*pop.Connection)
+
+		key, err := models.FindAPIKey(tx, apiKeyFromRequest(c.Request()))
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			logging.SecurityEvent(c, "api_key_auth", "failure", "invalid_key", logging.Fields{
+				"ip":   getClientIP(c),
+				"path": c.Request().URL.Path,
+			})
+			return jsonError(c, http.StatusUnauthorized, codeUnauthorized, "Invalid API key")
+		}
+		route, _ := c.Value("current_route").(buffalo.RouteInfo)
+		if !apiKeyAllows(key, c.Request().Method, route.Path) {
+			logging.SecurityEvent(c, "api_key_auth", "blocked", "out_of_scope", logging.Fields{
+				"api_key_id": key.ID.String(),
+				"path":       c.Request().URL.Path,
+			})
+			return jsonError(c, http.StatusForbidden, codeForbidden, "This API key can only look up donations")
+		}
+		if err := tx.RawQuery("UPDATE api_keys SET last_used_at = ? WHERE id = ?", time.Now(), key.ID).Exec(); err != nil {
+			return errors.WithStack(err)
+		}
+		c.Set("api_key", key)
+		return next(c)
+	}
+}
+
+// apiDonation is a donation as the API and its hooks send it
+type apiDonation struct {
+	ID            string    `json:"id"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	DonorName     string    `json:"donor_name"`
+	DonorEmail    string    `json:"donor_email"`
+	DonationType  string    `json:"donation_type"`
+	PaymentMethod string    `json:"payment_method"`
+	Designation   string    `json:"designation"`
+	CreatedAt     time.Time `json:"created_at"`
+	AdminURL      string    `json:"admin_url"`
+}
+
+func apiDonationFrom(d models.Donation) apiDonation {
+	method := stringOrEmpty(d.PaymentMethod)
+	if method == "" {
+		method = donationPaymentCard
+	}
+	return apiDonation{
+		ID:            d.ID.String(),
+		Amount:        d.Amount,
+		Currency:      d.Currency,
+		DonorName:     d.DonorName,
+		DonorEmail:    d.DonorEmail,
+		DonationType:  string(d.DonationType),
+		PaymentMethod: method,
+		Designation:   stringOrEmpty(d.Designation),
+		CreatedAt:     d.CreatedAt,
+		AdminURL:      fmt.Sprintf("%s/admin/donations/%s", siteURL(), d.ID),
+	}
+}
+
+// apiReceipt is a receipt issued for a donation
+type apiReceipt struct {
+	ReceiptNumber string    `json:"receipt_number"`
+	TransactionID string    `json:"transaction_id"`
+	Amount        float64   `json:"amount"`
+	Email         string    `json:"email"`
+	IssuedAt      time.Time `json:"issued_at"`
+}
+
+// apiDonationDetail is a donation with what a bookkeeper needs to reconcile
+// it: its charges, deductible amount, refunds, fees and receipts
+type apiDonationDetail struct {
+	apiDonation
+	Status              string       `json:"status"`
+	TransactionID       string       `json:"transaction_id"`
+	SubscriptionID      string       `json:"subscription_id"`
+	CustomerCode        string       `json:"customer_code"`
+	TaxDeductibleAmount float64      `json:"tax_deductible_amount"`
+	FairMarketValue     float64      `json:"fair_market_value"`
+	GoodsDescription    string       `json:"goods_description"`
+	RefundedAmount      float64      `json:"refunded_amount"`
+	RefundedAt          *time.Time   `json:"refunded_at"`
+	ProcessorFee        *float64     `json:"processor_fee"`
+	PayoutID            string       `json:"payout_id"`
+	DonorAddress        string       `json:"donor_address"`
+	Receipts            []apiReceipt `json:"receipts"`
+}
+
+func apiDonationDetailFrom(d models.Donation, receipts models.DonationReceipts) apiDonationDetail {
+	address := models.DonorAddress{
+		Line1: stringOrEmpty(d.AddressLine1),
+		Line2: stringOrEmpty(d.AddressLine2),
+		City:  stringOrEmpty(d.City),
+		State: stringOrEmpty(d.State),
+		Zip:   stringOrEmpty(d.Zip),
+	}
+	detail := apiDonationDetail{
+		apiDonation:         apiDonationFrom(d),
+		Status:              d.Status,
+		TransactionID:       d.ChargeReference(),
+		SubscriptionID:      stringOrEmpty(d.SubscriptionID),
+		CustomerCode:        stringOrEmpty(d.CustomerID),
+		TaxDeductibleAmount: d.TaxDeductibleAmount(),
+		FairMarketValue:     d.GoodsValue(),
+		GoodsDescription:    stringOrEmpty(d.GoodsDescription),
+		RefundedAmount:      d.RefundedAmount,
+		RefundedAt:          d.RefundedAt,
+		ProcessorFee:        d.ProcessorFee,
+		PayoutID:            stringOrEmpty(d.PayoutID),
+		DonorAddress:        address.String(),
+		Receipts:            make([]apiReceipt, len(receipts)),
+	}
+	for i, r := range receipts {
+		detail.Receipts[i] = apiReceipt{
+			ReceiptNumber: r.ReceiptNumber,
+			TransactionID: r.TransactionID,
+			Amount:        r.Amount,
+			Email:         r.Email,
+			IssuedAt:      r.IssuedAt,
+		}
+	}
+	return detail
+}
+
+// apiContactMessage is a contact form message as the API and its hooks send
+// it
+type apiContactMessage struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Subject   string    `json:"subject"`
+	Message   string    `json:"message"`
+	Topic     string    `json:"topic"`
+	CreatedAt time.Time `json:"created_at"`
+	AdminURL  string    `json:"admin_url"`
+}
+
+func apiContactMessageFrom(m models.ContactMessage) apiContactMessage {
+	return apiContactMessage{
+		ID:        m.ID.String(),
+		Name:      m.Name,
+		Email:     m.Email,
+		Subject:   m.Subject,
+		Message:   m.Message,
+		Topic:     m.TopicLabel(),
+		CreatedAt: m.CreatedAt,
+		AdminURL:  fmt.Sprintf("%s/admin/messages/%s", siteURL(), m.ID),
+	}
+}
+
+// apiVolunteer is someone signed up for a volunteer day, as the API and its
+// hooks send them. Its ID is the volunteer's ticket.
+type apiVolunteer struct {
+	ID         string    `json:"id" db:"id"`
+	Name       string    `json:"name" db:"holder_name"`
+	Email      string    `json:"email" db:"holder_email"`
+	EventID    string    `json:"event_id" db:"event_id"`
+	EventTitle string    `json:"event_title" db:"event_title"`
+	StartsAt   time.Time `json:"starts_at" db:"starts_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+func apiVolunteerFrom(ticket models.EventTicket, event models.Event) apiVolunteer {
+	return apiVolunteer{
+		ID:         ticket.ID.String(),
+		Name:       ticket.HolderName,
+		Email:      ticket.HolderEmail,
+		EventID:    event.ID.String(),
+		EventTitle: event.Title,
+		StartsAt:   event.StartsAt,
+		CreatedAt:  ticket.CreatedAt,
+	}
+}
+
+// publishAPIEvent posts payload to every REST hook subscribed to event. Each
+// delivery is its own background job, so a subscriber that's down is
+// retried without holding up the others.
+func publishAPIEvent(tx *pop.Connection, event string, payload interface{}) {
+	hooks := models.APIHooks{}
+	if err := tx.Where("event = ?", event).All(&hooks); err != nil {
+		logging.Error("api_hooks_load_failed", err, logging.Fields{"event": event})
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logging.Error("api_hook_encode_failed", err, logging.Fields{"event": event})
+		return
+	}
+	for _, hook := range hooks {
+		queueJob(tx, jobAPIHookDelivery, worker.Args{
+			"hook_id": hook.ID.String(),
+			"payload": string(data),
+		})
+	}
+}
+
+// deliverAPIHookJob posts a queued event to its subscriber. A subscriber
+// that answers 410 Gone is unsubscribed, as REST hooks expect.
+func deliverAPIHookJob(args worker.Args) error {
+	hook := &models.APIHook{}
+	if err := models.DB.Find(hook, jobArg(args, "hook_id")); err != nil {
+		// Unsubscribed since the event was queued
+		return nil
+	}
+	err := services.NewHookSender().Send(hook.TargetURL, []byte(jobArg(args, "payload")))
+	if errors.Is(err, services.ErrHookGone) {
+		logging.Audit("api_hook_gone", logging.Fields{
+			"hook_id": hook.ID.String(),
+			"event":   hook.Event,
+		})
+		return errors.WithStack(models.DB.Destroy(hook))
+	}
+	return err
+}
+
+// APIMe identifies the API key a request was made with. Zapier calls it to
+// test the connection.
+func APIMe(c buffalo.Context) error {
+	key := c.Value("api_key").(*models.APIKey)
+	return c.Render(http.StatusOK, r.JSON(map[string]string{
+		"id":   key.ID.String(),
+		"name": key.Name,
+	}))
+}
+
+// APIDonationsIndex is the new donation trigger: completed donations, newest
+// first
+func APIDonationsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	donations := models.Donations{}
+	if err := tx.Where("status = ?", "completed").Order("created_at desc").Limit(apiTriggerLimit).All(&donations); err != nil {
+		return errors.WithStack(err)
+	}
+	items := make([]apiDonation, len(donations))
+	for i, d := range donations {
+		items[i] = apiDonationFrom(d)
+	}
+	return c.Render(http.StatusOK, r.JSON(items))
+}
+
+// APIDonationLookup finds one donation by the receipt_number on its receipt
+// or a transaction_id from the payment processor, for the bookkeeper to
+// reconcile gifts without the admin pages. Each lookup is audited, since it
+// returns the donor's details.
+func APIDonationLookup(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	key := c.Value("api_key").(*models.APIKey)
+
+	number := strings.TrimSpace(c.Param("receipt_number"))
+	transactionID := strings.TrimSpace(c.Param("transaction_id"))
+	if number == "" && transactionID == "" {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "receipt_number or transaction_id is required")
+	}
+
+	var donation *models.Donation
+	if number != "" {
+		receipt, err := models.FindDonationReceipt(tx, number)
+		if err != nil {
+			return err
+		}
+		if receipt != nil {
+			donation = &models.Donation{}
+			if err := tx.Find(donation, receipt.DonationID); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	} else {
+		found, err := models.FindDonationByTransaction(tx, transactionID)
+		if err != nil {
+			return err
+		}
+		donation = found
+	}
+	if donation == nil {
+		return jsonError(c, http.StatusNotFound, codeNotFound, "Donation not found")
+	}
+
+	receipts := models.DonationReceipts{}
+	if err := tx.Where("donation_id = ?", donation.ID).Order("issued_at").All(&receipts); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.Audit("api_donation_lookup", logging.Fields{
+		"donation_id":    donation.ID.String(),
+		"api_key_id":     key.ID.String(),
+		"receipt_number": number,
+		"transaction_id": transactionID,
+	})
+	return c.Render(http.StatusOK, r.JSON(apiDonationDetailFrom(*donation, receipts)))
+}
+
+// APIContactMessagesIndex is the new contact message trigger: messages from
+// the contact and press forms, newest first
+func APIContactMessagesIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	messages := models.ContactMessages{}
+	if err := tx.Order("created_at desc").Limit(apiTriggerLimit).All(&messages); err != nil {
+		return errors.WithStack(err)
+	}
+	items := make([]apiContactMessage, len(messages))
+	for i, m := range messages {
+		items[i] = apiContactMessageFrom(m)
+	}
+	return c.Render(http.StatusOK, r.JSON(items))
+}
+
+// APIVolunteersIndex is the new volunteer trigger: people issued tickets to
+// volunteer days, newest first
+func APIVolunteersIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	items := []apiVolunteer{}
+	err := tx.RawQuery(`SELECT t.id, t.holder_name, t.holder_email, t.event_id, e.title AS event_title, e.starts_at, t.created_at
+		FROM event_tickets t JOIN events e ON e.id = t.event_id
+		WHERE e.kind = ?
+		ORDER BY t.created_at DESC LIMIT ?`, models.EventKindVolunteerDay, apiTriggerLimit).All(&items)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return c.Render(http.StatusOK, r.JSON(items))
+}
+
+// apiOfflineDonationRequest is a gift received outside the site, such as a
+// check or cash at an event
+type apiOfflineDonationRequest struct {
+	Amount      float64 `json:"amount" form:"amount"`
+	Currency    string  `json:"currency" form:"currency"`
+	DonorName   string  `json:"donor_name" form:"donor_name"`
+	DonorEmail  string  `json:"donor_email" form:"donor_email"`
+	DonatedAt   string  `json:"donated_at" form:"donated_at"`
+	Reference   string  `json:"reference" form:"reference"` // e.g. a check number; repeats are ignored
+	Designation string  `json:"designation" form:"designation"`
+	Comments    string  `json:"comments" form:"comments"`
+	SendReceipt bool    `json:"send_receipt" form:"send_receipt"`
+}
+
+// parseAPIDate reads an RFC 3339 time or a plain date. Blank is now.
+func parseAPIDate(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(dateInputLayout, s, time.Local)
+}
+
+// APIDonationsCreate records an offline donation as completed, emailing the
+// donor a receipt when send_receipt is set. A repeated reference returns the
+// gift already recorded, so retried Zaps don't count a gift twice.
+func APIDonationsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	key := c.Value("api_key").(*models.APIKey)
+
+	req := apiOfflineDonationRequest{}
+	if err := c.Bind(&req); err != nil {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid request body")
+	}
+	reference := strings.TrimSpace(req.Reference)
+	if reference != "" {
+		existing := &models.Donation{}
+		if err := tx.Where("payment_method = ? AND external_id = ?", models.PaymentMethodOffline, reference).First(existing); err == nil {
+			return c.Render(http.StatusOK, r.JSON(apiDonationFrom(*existing)))
+		}
+	}
+
+	donatedAt, err := parseAPIDate(req.DonatedAt, time.Now())
+	if err != nil {
+		verrs := validate.NewErrors()
+		verrs.Add("donated_at", "donated_at must be a date (YYYY-MM-DD) or RFC 3339 time")
+		return jsonValidationError(c, verrs.Errors)
+	}
+	if req.Amount <= 0 {
+		verrs := validate.NewErrors()
+		verrs.Add("amount", "amount must be greater than zero")
+		return jsonValidationError(c, verrs.Errors)
+	}
+	currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+	if currency == "" {
+		currency = "USD"
+	}
+	method := models.PaymentMethodOffline
+	donation := &models.Donation{
+		Amount:        req.Amount,
+		Currency:      currency,
+		DonorName:     strings.TrimSpace(req.DonorName),
+		DonorEmail:    models.NormalizeDonorEmail(req.DonorEmail),
+		DonationType:  models.DonationTypeOneTime,
+		Status:        "completed",
+		PaymentMethod: &method,
+		ExternalID:    stringPointer(reference),
+		Designation:   stringPointer(strings.TrimSpace(req.Designation)),
+		Comments:      stringPointer(strings.TrimSpace(req.Comments)),
+		CreatedAt:     donatedAt,
+	}
+	user := &models.User{}
+	if err := tx.Where("LOWER(email) = ?", donation.DonorEmail).First(user); err == nil {
+		donation.UserID = &user.ID
+	}
+
+	verrs, err := tx.ValidateAndCreate(donation)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		return jsonValidationError(c, verrs.Errors)
+	}
+	notifyDonationCompleted(tx, donation)
+	if req.SendReceipt {
+		receipt := webhookReceiptData(donation, reference)
+		addThankYouToReceipt(tx, donation, &receipt)
+		queueReceipt(tx, donation, receipt, receiptSummary(donation))
+	}
+
+	logging.Audit("api_donation_recorded", logging.Fields{
+		"donation_id": donation.ID.String(),
+		"api_key_id":  key.ID.String(),
+		"amount":      donation.Amount,
+		"reference":   reference,
+	})
+	return c.Render(http.StatusCreated, r.JSON(apiDonationFrom(*donation)))
+}
+
+// APINewsletterSubscribersCreate adds someone to the newsletter list.
+// Subscribing an address already on the list returns it unchanged, and
+// addresses on the do-not-contact list aren't added.
+func APINewsletterSubscribersCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	key := c.Value("api_key").(*models.APIKey)
+
+	req := struct {
+		Email string `json:"email" form:"email"`
+		Name  string `json:"name" form:"name"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid request body")
+	}
+	email := models.NormalizeDonorEmail(req.Email)
+
+	suppression, err := models.FindSuppression(tx, models.SuppressEmail, email)
+	if err != nil {
+		return err
+	}
+	if suppression != nil {
+		return c.Render(http.StatusOK, r.JSON(map[string]string{"email": email, "status": "suppressed"}))
+	}
+
+	subscriber := &models.NewsletterSubscriber{}
+	if err := tx.Where("email = ?", email).First(subscriber); err == nil {
+		return c.Render(http.StatusOK, r.JSON(subscriber))
+	}
+	subscriber = &models.NewsletterSubscriber{
+		Email:  email,
+		Name:   stringPointer(strings.TrimSpace(req.Name)),
+		Source: models.NewsletterSourceAPI,
+	}
+	verrs, err := tx.ValidateAndCreate(subscriber)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		return jsonValidationError(c, verrs.Errors)
+	}
+
+	logging.Audit("newsletter_subscriber_added", logging.Fields{
+		"subscriber_id": subscriber.ID.String(),
+		"api_key_id":    key.ID.String(),
+	})
+	return c.Render(http.StatusCreated, r.JSON(subscriber))
+}
+
+// APIHooksIndex lists the REST hooks subscribed with the API key, so an
+// integration can check what it's registered before adding more
+func APIHooksIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	key := c.Value("api_key").(*models.APIKey)
+
+	hooks := models.APIHooks{}
+	if err := tx.Where("api_key_id = ?", key.ID).Order("created_at").All(&hooks); err != nil {
+		return errors.WithStack(err)
+	}
+	return c.Render(http.StatusOK, r.JSON(hooks))
+}
+
+// APIHooksCreate subscribes a target URL to an event (a REST hook)
+func APIHooksCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	key := c.Value("api_key").(*models.APIKey)
+
+	req := struct {
+		Event     string `json:"event" form:"event"`
+		TargetURL string `json:"target_url" form:"target_url"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid request body")
+	}
+	hook := &models.APIHook{
+		APIKeyID:  key.ID,
+		Event:     strings.TrimSpace(req.Event),
+		TargetURL: strings.TrimSpace(req.TargetURL),
+	}
+	verrs, err := tx.ValidateAndCreate(hook)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		return jsonValidationError(c, verrs.Errors)
+	}
+
+	logging.Audit("api_hook_subscribed", logging.Fields{
+		"hook_id":    hook.ID.String(),
+		"api_key_id": key.ID.String(),
+		"event":      hook.Event,
+	})
+	return c.Render(http.StatusCreated, r.JSON(hook))
+}
+
+// APIHooksDestroy unsubscribes one of the API key's REST hooks
+func APIHooksDestroy(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	key := c.Value("api_key").(*models.APIKey)
+
+	hook := &models.APIHook{}
+	if err := tx.Where("id = ? AND api_key_id = ?", c.Param("hook_id"), key.ID).First(hook); err != nil {
+		return jsonError(c, http.StatusNotFound, codeNotFound, "Hook not found")
+	}
+	if err := tx.Destroy(hook); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.Audit("api_hook_unsubscribed", logging.Fields{
+		"hook_id":    hook.ID.String(),
+		"api_key_id": key.ID.String(),
+		"event":      hook.Event,
+	})
+	return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "unsubscribed"}))
+}
+
+// apiUsageDays is how many days of API key usage the admin page totals
+const apiUsageDays = 30
+
+// apiKeyUsageRow is an API key with its usage and the limits it's held to,
+// for the admin API keys page
+type apiKeyUsageRow struct {
+	models.APIKey
+	Usage  models.APIKeyUsageSummary
+	Limits apiRateLimits
+}
+
+// QuotaPercent is how much of today's quota the key has used
+func (k apiKeyUsageRow) QuotaPercent() int {
+	if k.Limits.Quota.Limit == 0 {
+		return 0
+	}
+	return min(100, k.Usage.Today*100/k.Limits.Quota.Limit)
+}
+
+// AdminAPIKeysIndex lists API keys and the REST hooks subscribed with them
+func AdminAPIKeysIndex(c buffalo.Context) error {
+	return renderAPIKeys(c, "", "")
+}
+
+// renderAPIKeys shows the API keys page, with a key that's just been
+// created when newKey is given
+func renderAPIKeys(c buffalo.Context, newKey, newKeyName string) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	keys := models.APIKeys{}
+	if err := tx.Order("revoked_at desc, created_at desc").All(&keys); err != nil {
+		return errors.WithStack(err)
+	}
+	hooks := models.APIHooks{}
+	if err := tx.Order("created_at desc").All(&hooks); err != nil {
+		return errors.WithStack(err)
+	}
+	keyNames := map[string]string{}
+	for _, k := range keys {
+		keyNames[k.ID.String()] = k.Name
+	}
+	now := time.Now()
+	usage, err := models.LoadAPIKeyUsage(tx, now.AddDate(0, 0, -apiUsageDays), now)
+	if err != nil {
+		return err
+	}
+	limits := apiRateLimitsFromEnv()
+	keyUsage := make([]apiKeyUsageRow, len(keys))
+	for i, k := range keys {
+		keyUsage[i] = apiKeyUsageRow{APIKey: k, Usage: usage[k.ID.String()], Limits: limits.forKey(&k)}
+	}
+
+	c.Set("apiKeys", keyUsage)
+	c.Set("apiLimits", limits)
+	c.Set("apiUsageDays", apiUsageDays)
+	c.Set("rateLimitsEnabled", rateLimitsEnabled())
+	c.Set("apiHooks", hooks)
+	c.Set("keyNames", keyNames)
+	c.Set("apiBaseURL", siteURL()+"/api/v1")
+	c.Set("apiEvents", models.APIEvents)
+	c.Set("newKey", newKey)
+	c.Set("newKeyName", newKeyName)
+	return c.Render(http.StatusOK, r.HTML("admin/api_keys/index.plush.html"))
+}
+
+// AdminAPIKeysCreate generates an API key. The key is only ever shown on
+// the page this renders.
+func AdminAPIKeysCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	apiKey, plain, err := models.NewAPIKey(strings.TrimSpace(c.Param("Name")), &currentUser.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if c.Param("Scope") == models.APIKeyScopeLookup {
+		apiKey.Scope = models.APIKeyScopeLookup
+	}
+	verrs, err := tx.ValidateAndCreate(apiKey)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+		return c.Redirect(http.StatusSeeOther, "/admin/api-keys")
+	}
+
+	logging.UserAction(c, currentUser.ID.String(), "api_key_created", fmt.Sprintf("Created API key: %s", apiKey.Name), logging.Fields{
+		"api_key_id": apiKey.ID.String(),
+		"scope":      apiKey.Scope,
+	})
+
+	return renderAPIKeys(c, plain, apiKey.Name)
+}
+
+// AdminAPIKeysRevoke turns off an API key, dropping its REST hooks
+func AdminAPIKeysRevoke(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	apiKey := &models.APIKey{}
+	if err := tx.Find(apiKey, c.Param("key_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if !apiKey.Revoked() {
+		now := time.Now()
+		apiKey.RevokedAt = &now
+		if err := tx.Update(apiKey); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := tx.RawQuery("DELETE FROM api_hooks WHERE api_key_id = ?", apiKey.ID).Exec(); err != nil {
+			return errors.WithStack(err)
+		}
+		logging.UserAction(c, currentUser.ID.String(), "api_key_revoked", fmt.Sprintf("Revoked API key: %s", apiKey.Name), logging.Fields{
+			"api_key_id": apiKey.ID.String(),
+		})
+	}
+
+	c.Flash().Add("success", fmt.Sprintf("Revoked %s. Integrations using it will stop working.", apiKey.Name))
+	return c.Redirect(http.StatusSeeOther, "/admin/api-keys")
+}
+
+// AdminAPIKeysLimits sets a key's own burst limit and quota; 0 puts either
+// back to the default
+func AdminAPIKeysLimits(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	apiKey := &models.APIKey{}
+	if err := tx.Find(apiKey, c.Param("key_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	for field, param := range map[*int]string{&apiKey.BurstLimit: "BurstLimit", &apiKey.DailyQuota: "DailyQuota"} {
+		value := strings.TrimSpace(c.Param(param))
+		if value == "" {
+			*field = 0
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			c.Flash().Add("danger", fmt.Sprintf("%q isn't a whole number of requests.", value))
+			return c.Redirect(http.StatusSeeOther, "/admin/api-keys")
+		}
+		*field = n
+	}
+	verrs, err := tx.ValidateAndUpdate(apiKey)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+		return c.Redirect(http.StatusSeeOther, "/admin/api-keys")
+	}
+
+	logging.UserAction(c, currentUser.ID.String(), "api_key_limits_updated", fmt.Sprintf("Changed limits for API key: %s", apiKey.Name), logging.Fields{
+		"api_key_id":  apiKey.ID.String(),
+		"burst_limit": apiKey.BurstLimit,
+		"daily_quota": apiKey.DailyQuota,
+	})
+	c.Flash().Add("success", fmt.Sprintf("Saved the limits for %s.", apiKey.Name))
+	return c.Redirect(http.StatusSeeOther, "/admin/api-keys")
+}
+
+// AdminNewsletterIndex lists newsletter subscribers, newest first
+func AdminNewsletterIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	subscribers := models.NewsletterSubscribers{}
+	if err := tx.Order("created_at desc").Limit(200).All(&subscribers); err != nil {
+		return errors.WithStack(err)
+	}
+	total, err := tx.Count(&models.NewsletterSubscriber{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("subscribers", subscribers)
+	c.Set("total", total)
+	return c.Render(http.StatusOK, r.HTML("admin/newsletter/index.plush.html"))
+}
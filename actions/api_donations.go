@@ -0,0 +1,250 @@
+package actions
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// apiDonationInitializeRequest is a card or bank gift started by an
+// integration, such as the mobile app or a partner site, instead of the
+// donate form
+type apiDonationInitializeRequest struct {
+	Amount       float64                  `json:"amount"`
+	DonationType models.DonationType      `json:"donation_type"` // one-time when blank
+	Frequency    models.DonationFrequency `json:"frequency"`     // monthly gifts; monthly when blank
+	Installments int                      `json:"installments"`  // installment pledges
+	// "card" (the default) or "bank" for a bank transfer (ACH)
+	PaymentMethod string `json:"payment_method"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	DonorEmail    string `json:"donor_email"`
+	DonorPhone    string `json:"donor_phone"`
+	AddressLine1  string `json:"address_line1"`
+	AddressLine2  string `json:"address_line2"`
+	City          string `json:"city"`
+	State         string `json:"state"`
+	Zip           string `json:"zip_code"`
+	Designation   string `json:"designation"`
+	Comments      string `json:"comments"`
+}
+
+// donationRequest is the request as the donate form would have sent it, for
+// the checks and payment method handling the two share
+func (req apiDonationInitializeRequest) donationRequest() DonationRequest {
+	return DonationRequest{
+		DonationType:  req.DonationType,
+		PaymentMethod: req.PaymentMethod,
+	}
+}
+
+// apiDonationInitializeErrors checks a gift started through the API the way
+// the donate form checks one, returning the amount to charge each time
+func apiDonationInitializeErrors(req *apiDonationInitializeRequest) (*validate.Errors, float64) {
+	verrs := validate.NewErrors()
+	if req.DonationType == "" {
+		req.DonationType = models.DonationTypeOneTime
+	}
+	req.Frequency = models.NormalizeDonationFrequency(string(req.Frequency))
+
+	required := []struct{ field, value, message string }{
+		{"first_name", req.FirstName, "first_name is required"},
+		{"last_name", req.LastName, "last_name is required"},
+		{"donor_email", req.DonorEmail, "donor_email is required"},
+		{"address_line1", req.AddressLine1, "address_line1 is required"},
+		{"city", req.City, "city is required"},
+		{"state", req.State, "state is required"},
+		{"zip_code", req.Zip, "zip_code is required"},
+	}
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			verrs.Add(r.field, r.message)
+		}
+	}
+	if email := strings.TrimSpace(req.DonorEmail); email != "" && (!strings.Contains(email, "@") || !strings.Contains(email, ".")) {
+		verrs.Add("donor_email", "donor_email must be a valid email address")
+	}
+	if !req.DonationType.Valid() {
+		verrs.Add("donation_type", "donation_type must be one of one-time, monthly or installment")
+	}
+
+	amount, err := parseDonationAmount(strconv.FormatFloat(req.Amount, 'f', -1, 64), getCurrency())
+	if err != nil {
+		verrs.Add("amount", err.Error())
+	}
+	if req.DonationType == models.DonationTypeMonthly && !req.Frequency.Valid() {
+		verrs.Add("frequency", "frequency must be one of monthly, quarterly or annual")
+	}
+	if req.DonationType == models.DonationTypeInstallment {
+		if _, ok := parseInstallmentCount(strconv.Itoa(req.Installments)); !ok {
+			verrs.Add("installments", "installments must be one of "+strings.Join(installmentOptions(), ", "))
+		} else if err == nil {
+			if msg := installmentMinimumError(amount, req.Installments, getCurrency()); msg != "" {
+				verrs.Add("amount", msg)
+			}
+		}
+	}
+	for field, msg := range paymentMethodErrors(req.donationRequest()) {
+		verrs.Add(field, msg)
+	}
+	return verrs, amount
+}
+
+// apiDonationStatus is a donation started through the API, as its status
+// endpoint reports it
+type apiDonationStatus struct {
+	apiDonation
+	Status           string    `json:"status"`
+	Frequency        string    `json:"frequency,omitempty"`
+	InstallmentCount int       `json:"installment_count,omitempty"`
+	PledgeTotal      float64   `json:"pledge_total,omitempty"`
+	TransactionID    string    `json:"transaction_id"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func apiDonationStatusFrom(d models.Donation) apiDonationStatus {
+	status := apiDonationStatus{
+		apiDonation:      apiDonationFrom(d),
+		Status:           d.Status,
+		InstallmentCount: d.InstallmentCount,
+		PledgeTotal:      d.PledgeTotal,
+		TransactionID:    d.ChargeReference(),
+		UpdatedAt:        d.UpdatedAt,
+	}
+	if d.DonationType == models.DonationTypeMonthly {
+		status.Frequency = string(d.ChargeFrequency())
+	}
+	return status
+}
+
+// APIDonationsInitialize starts a card or bank gift for an integration. It
+// saves the donation as pending and returns the HelcimPay.js checkout token
+// to collect the donor's payment details with; the integration then
+// charges it through process_url, as the donate page does. The donation is
+// kept against the API key, so only that key can read its status.
+func APIDonationsInitialize(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	key := c.Value("api_key").(*models.APIKey)
+
+	req := apiDonationInitializeRequest{}
+	if err := c.Bind(&req); err != nil {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid request body")
+	}
+	verrs, amount := apiDonationInitializeErrors(&req)
+	if verrs.HasAny() {
+		return jsonValidationError(c, verrs.Errors)
+	}
+
+	donorName := strings.TrimSpace(req.FirstName + " " + req.LastName)
+	helcimReq := HelcimPayVerifyRequest{
+		PaymentType: "verify",
+		Amount:      0,
+		Currency:    getCurrency(),
+		CustomerRequest: &services.CustomerRequest{
+			ContactName: donorName,
+			Email:       req.DonorEmail,
+			BillingAddress: services.BillingAddress{
+				Name:       donorName,
+				Street1:    req.AddressLine1,
+				City:       req.City,
+				Province:   req.State,
+				Country:    "USA",
+				PostalCode: req.Zip,
+			},
+		},
+	}
+	donation := &models.Donation{
+		DonorName:    donorName,
+		DonorEmail:   models.NormalizeDonorEmail(req.DonorEmail),
+		DonorPhone:   stringPointer(req.DonorPhone),
+		AddressLine1: stringPointer(req.AddressLine1),
+		AddressLine2: stringPointer(req.AddressLine2),
+		City:         stringPointer(req.City),
+		State:        stringPointer(req.State),
+		Zip:          stringPointer(req.Zip),
+		Amount:       amount,
+		Currency:     getCurrency(),
+		DonationType: req.DonationType,
+		Frequency:    models.FrequencyMonthly,
+		Status:       "pending",
+		Designation:  stringPointer(strings.TrimSpace(req.Designation)),
+		Comments:     stringPointer(strings.TrimSpace(req.Comments)),
+		APIKeyID:     &key.ID,
+	}
+	applyPaymentMethod(req.donationRequest(), donation, &helcimReq)
+	if req.DonationType == models.DonationTypeMonthly {
+		donation.Frequency = req.Frequency
+	}
+	if req.DonationType == models.DonationTypeInstallment {
+		donation.InstallmentCount = req.Installments
+		donation.Amount, donation.PledgeTotal = splitPledge(amount, req.Installments)
+	}
+	user := &models.User{}
+	if err := tx.Where("LOWER(email) = ?", donation.DonorEmail).First(user); err == nil {
+		donation.UserID = &user.ID
+	}
+
+	verrs, err := tx.ValidateAndCreate(donation)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		return jsonValidationError(c, verrs.Errors)
+	}
+
+	helcimResponse, err := callHelcimVerifyAPI(helcimReq)
+	if err != nil {
+		logging.Error("api_donation_checkout_failed", err, logging.Fields{
+			"donation_id": donation.ID.String(),
+			"api_key_id":  key.ID.String(),
+		})
+		return jsonError(c, http.StatusBadGateway, codePaymentUnavailable, "Payment system unavailable. Please try again later.")
+	}
+	donation.CheckoutToken = helcimResponse.CheckoutToken
+	donation.SecretToken = helcimResponse.SecretToken
+	if err := tx.UpdateColumns(donation, "checkout_token", "secret_token", "updated_at"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.Audit("api_donation_initialized", logging.Fields{
+		"donation_id":   donation.ID.String(),
+		"api_key_id":    key.ID.String(),
+		"amount":        donation.Amount,
+		"donation_type": string(donation.DonationType),
+	})
+	return c.Render(http.StatusCreated, r.JSON(map[string]interface{}{
+		"donation":       apiDonationStatusFrom(*donation),
+		"checkout_token": helcimResponse.CheckoutToken,
+		"secret_token":   helcimResponse.SecretToken,
+		"process_url":    siteURL() + "/api/donations/process",
+		"status_url":     siteURL() + "/api/v1/donations/" + donation.ID.String(),
+	}))
+}
+
+// APIDonationStatus reports where a donation the API key started has got
+// to, for integrations that poll rather than subscribe to donation.created
+func APIDonationStatus(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	key := c.Value("api_key").(*models.APIKey)
+
+	id, err := uuid.FromString(c.Param("donation_id"))
+	if err != nil {
+		return jsonError(c, http.StatusNotFound, codeNotFound, "Donation not found")
+	}
+	donation := &models.Donation{}
+	if err := tx.Where("id = ? AND api_key_id = ?", id, key.ID).First(donation); err != nil {
+		return jsonError(c, http.StatusNotFound, codeNotFound, "Donation not found")
+	}
+	return c.Render(http.StatusOK, r.JSON(apiDonationStatusFrom(*donation)))
+}
@@ -0,0 +1,97 @@
+package actions
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func validAPIDonationRequest() apiDonationInitializeRequest {
+	return apiDonationInitializeRequest{
+		Amount:       50,
+		FirstName:    "Jane",
+		LastName:     "Doe",
+		DonorEmail:   "jane@example.com",
+		AddressLine1: "1 Main St",
+		City:         "Austin",
+		State:        "TX",
+		Zip:          "78701",
+	}
+}
+
+func Test_APIDonationInitializeErrors(t *testing.T) {
+	req := require.New(t)
+
+	// A blank donation type is a one-time gift
+	donation := validAPIDonationRequest()
+	verrs, amount := apiDonationInitializeErrors(&donation)
+	req.False(verrs.HasAny(), verrs.String())
+	req.Equal(50.0, amount)
+	req.Equal(models.DonationTypeOneTime, donation.DonationType)
+
+	missing := apiDonationInitializeRequest{Amount: 50}
+	verrs, _ = apiDonationInitializeErrors(&missing)
+	for _, field := range []string{"first_name", "last_name", "donor_email", "address_line1", "city", "state", "zip_code"} {
+		req.NotEmpty(verrs.Get(field), field)
+	}
+
+	zero := validAPIDonationRequest()
+	zero.Amount = 0
+	verrs, _ = apiDonationInitializeErrors(&zero)
+	req.NotEmpty(verrs.Get("amount"))
+
+	quarterly := validAPIDonationRequest()
+	quarterly.DonationType = models.DonationTypeMonthly
+	quarterly.Frequency = "quarterly"
+	verrs, _ = apiDonationInitializeErrors(&quarterly)
+	req.False(verrs.HasAny(), verrs.String())
+
+	weekly := validAPIDonationRequest()
+	weekly.DonationType = models.DonationTypeMonthly
+	weekly.Frequency = "weekly"
+	verrs, _ = apiDonationInitializeErrors(&weekly)
+	req.NotEmpty(verrs.Get("frequency"))
+
+	pledge := validAPIDonationRequest()
+	pledge.DonationType = models.DonationTypeInstallment
+	pledge.Installments = 5
+	verrs, _ = apiDonationInitializeErrors(&pledge)
+	req.NotEmpty(verrs.Get("installments"))
+	pledge.Installments = 6
+	verrs, _ = apiDonationInitializeErrors(&pledge)
+	req.False(verrs.HasAny(), verrs.String())
+
+	// Bank transfers are one-time only, as on the donate form
+	bank := validAPIDonationRequest()
+	bank.DonationType = models.DonationTypeMonthly
+	bank.PaymentMethod = donationPaymentBank
+	verrs, _ = apiDonationInitializeErrors(&bank)
+	req.NotEmpty(verrs.Get("payment_method"))
+}
+
+func Test_APIDonationStatusFrom(t *testing.T) {
+	req := require.New(t)
+
+	txn := "TXN-88"
+	donation := models.Donation{
+		ID: uuid.Must(uuid.NewV4()), Amount: 30, Currency: "USD", DonorName: "Jane Doe",
+		DonationType: models.DonationTypeMonthly, Frequency: models.FrequencyAnnual,
+		Status: "active", TransactionID: &txn,
+	}
+	status := apiDonationStatusFrom(donation)
+	req.Equal("active", status.Status)
+	req.Equal("annual", status.Frequency)
+	req.Equal("TXN-88", status.TransactionID)
+
+	body, err := json.Marshal(status)
+	req.NoError(err)
+	req.Contains(string(body), `"id":"`+donation.ID.String()+`"`)
+	req.NotContains(string(body), "installment_count")
+
+	donation.DonationType = models.DonationTypeOneTime
+	req.Equal("", apiDonationStatusFrom(donation).Frequency)
+}
@@ -0,0 +1,145 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v6"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/pkg/ratelimit"
+)
+
+// apiRateLimits are the limits every API key is held to unless it has its
+// own: a burst limit against runaway loops, and a quota on total use. Each
+// is counted per key, apart from the per-IP limits on the public forms.
+type apiRateLimits struct {
+	Burst ratelimit.Rule
+	Quota ratelimit.Rule
+}
+
+// apiRateLimitsFromEnv reads the default API limits from API_RATE_LIMIT_BURST
+// (default 60/1m) and API_RATE_LIMIT_QUOTA (default 10000/24h). Limits
+// that fail to parse keep their default.
+func apiRateLimitsFromEnv() apiRateLimits {
+	rule := func(name, def string) ratelimit.Rule {
+		value := strings.TrimSpace(envy.Get(name, ""))
+		rule, err := ratelimit.ParseRule(value)
+		if value == "" || err != nil {
+			if err != nil {
+				logging.Warn("Invalid API rate limit, using the default", logging.Fields{
+					"limit":   name,
+					"value":   value,
+					"default": def,
+				})
+			}
+			rule, _ = ratelimit.ParseRule(def)
+		}
+		return rule
+	}
+	return apiRateLimits{
+		Burst: rule("API_RATE_LIMIT_BURST", "60/1m"),
+		Quota: rule("API_RATE_LIMIT_QUOTA", "10000/24h"),
+	}
+}
+
+// forKey is the limits for key, with its own burst limit and quota in place
+// of the defaults when they're set
+func (l apiRateLimits) forKey(key *models.APIKey) apiRateLimits {
+	if key.BurstLimit > 0 {
+		l.Burst.Limit = key.BurstLimit
+	}
+	if key.DailyQuota > 0 {
+		l.Quota.Limit = key.DailyQuota
+	}
+	return l
+}
+
+// BurstWindow names the burst limit's window, e.g. "minute"
+func (l apiRateLimits) BurstWindow() string {
+	return rateWindowLabel(l.Burst.Window)
+}
+
+// QuotaWindow names the quota's window, e.g. "day"
+func (l apiRateLimits) QuotaWindow() string {
+	return rateWindowLabel(l.Quota.Window)
+}
+
+// rateWindowLabel names a limit's window for the admin pages
+func rateWindowLabel(d time.Duration) string {
+	switch d {
+	case time.Second:
+		return "second"
+	case time.Minute:
+		return "minute"
+	case time.Hour:
+		return "hour"
+	case 24 * time.Hour:
+		return "day"
+	}
+	return d.String()
+}
+
+// APIRateLimits returns middleware holding each API key to its burst limit
+// and quota, and counting its requests for the admin API keys page. It runs
+// after APIKeyRequired. Responses carry X-RateLimit-* headers for the
+// quota; a key over either limit gets a 429 with Retry-After. If the store
+// can't be reached the request is let through.
+func APIRateLimits(limiter *ratelimit.Limiter, limits apiRateLimits) buffalo.MiddlewareFunc {
+	return func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			key, ok := c.Value("api_key").(*models.APIKey)
+			if !ok {
+				return next(c)
+			}
+			result, name, err := allowAPIRequest(limiter, key, limits.forKey(key))
+			if err != nil {
+				logging.Error("API rate limit check failed", err, logging.Fields{"api_key_id": key.ID.String()})
+				return next(c)
+			}
+			if tx, ok := c.Value("tx").(*pop.Connection); ok {
+				if err := models.RecordAPIKeyUsage(tx, key.ID, limiter.Now(), !result.Allowed); err != nil {
+					logging.Error("Failed to record API key usage", err, logging.Fields{"api_key_id": key.ID.String()})
+				}
+			}
+
+			header := c.Response().Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("X-RateLimit-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+			if result.Allowed {
+				return next(c)
+			}
+
+			retryAfter := int(result.RetryAfter(limiter.Now()) / time.Second)
+			header.Set("Retry-After", strconv.Itoa(retryAfter))
+			logging.SecurityEvent(c, "api_rate_limited", "blocked", name, logging.Fields{
+				"api_key_id": key.ID.String(),
+				"limit":      result.Limit,
+				"path":       c.Request().URL.Path,
+			})
+			body := jsonErrorEnvelope(c, codeRateLimited, fmt.Sprintf("API %s exceeded; try again in %d seconds", name, retryAfter), nil)
+			body["retry_after"] = retryAfter
+			return c.Render(http.StatusTooManyRequests, r.JSON(body))
+		}
+	}
+}
+
+// allowAPIRequest counts a request against the key's burst limit and then,
+// if it's within that, its quota. It returns the result to report and which
+// limit it came from: the quota's when the request is allowed, so clients
+// can see how much they have left.
+func allowAPIRequest(limiter *ratelimit.Limiter, key *models.APIKey, rules apiRateLimits) (ratelimit.Result, string, error) {
+	burst, err := limiter.Allow("api_burst:"+key.ID.String(), rules.Burst)
+	if err != nil || !burst.Allowed {
+		return burst, "burst limit", err
+	}
+	quota, err := limiter.Allow("api_quota:"+key.ID.String(), rules.Quota)
+	return quota, "quota", err
+}
@@ -0,0 +1,93 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/ratelimit"
+)
+
+func Test_APIRateLimitsFromEnv(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("API_RATE_LIMIT_BURST", "5/1s")
+		envy.Set("API_RATE_LIMIT_QUOTA", "plenty")
+
+		limits := apiRateLimitsFromEnv()
+		assert.Equal(t, ratelimit.Rule{Limit: 5, Window: time.Second}, limits.Burst)
+		assert.Equal(t, ratelimit.Rule{Limit: 10000, Window: 24 * time.Hour}, limits.Quota)
+		assert.Equal(t, "second", limits.BurstWindow())
+		assert.Equal(t, "day", limits.QuotaWindow())
+
+		own := limits.forKey(&models.APIKey{DailyQuota: 50})
+		assert.Equal(t, 5, own.Burst.Limit)
+		assert.Equal(t, ratelimit.Rule{Limit: 50, Window: 24 * time.Hour}, own.Quota)
+	})
+}
+
+func Test_APIRateLimits_BurstAndQuotaPerKey(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	limiter := ratelimit.New(ratelimit.NewMemoryStore())
+	limiter.Now = func() time.Time { return now }
+	limits := apiRateLimits{
+		Burst: ratelimit.Rule{Limit: 2, Window: time.Minute},
+		Quota: ratelimit.Rule{Limit: 3, Window: 24 * time.Hour},
+	}
+	keys := map[string]*models.APIKey{
+		"zapier":     {ID: uuid.Must(uuid.NewV4())},
+		"bookkeeper": {ID: uuid.Must(uuid.NewV4()), DailyQuota: 10},
+	}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.Use(func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			c.Set("api_key", keys[c.Request().Header.Get("X-API-Key")])
+			return next(c)
+		}
+	})
+	app.Use(APIRateLimits(limiter, limits))
+	app.GET("/api/v1/me", func(c buffalo.Context) error { return c.Render(http.StatusOK, nil) })
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+		req.Header.Set("X-API-Key", key)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	res := send("zapier")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "3", res.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "2", res.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, http.StatusOK, send("zapier").Code)
+
+	// A third request inside the minute is over the burst limit
+	res = send("zapier")
+	require.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "60", res.Header().Get("Retry-After"))
+	assert.Contains(t, res.Body.String(), "API burst limit exceeded")
+
+	// Other keys have their own counts
+	assert.Equal(t, http.StatusOK, send("bookkeeper").Code)
+
+	// The next minute has burst to spare, but only one request left today
+	now = now.Add(time.Minute)
+	assert.Equal(t, http.StatusOK, send("zapier").Code)
+	res = send("zapier")
+	require.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "43140", res.Header().Get("Retry-After"))
+	assert.Contains(t, res.Body.String(), "API quota exceeded")
+	assert.Contains(t, res.Body.String(), `"retry_after":43140`)
+
+	// A key with its own quota is held to that
+	assert.Equal(t, "10", send("bookkeeper").Header().Get("X-RateLimit-Limit"))
+}
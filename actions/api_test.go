@@ -0,0 +1,148 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/ratelimit"
+)
+
+func Test_APIKeyFromRequest(t *testing.T) {
+	req := require.New(t)
+
+	httpReq := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+	req.Equal("", apiKeyFromRequest(httpReq))
+
+	httpReq.Header.Set("X-API-Key", "avr_header")
+	req.Equal("avr_header", apiKeyFromRequest(httpReq))
+
+	// A bearer token wins over X-API-Key
+	httpReq.Header.Set("Authorization", "Bearer avr_bearer")
+	req.Equal("avr_bearer", apiKeyFromRequest(httpReq))
+
+	httpReq.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	req.Equal("avr_header", apiKeyFromRequest(httpReq))
+}
+
+func Test_APIKeyAllows(t *testing.T) {
+	req := require.New(t)
+
+	full := &models.APIKey{Scope: models.APIKeyScopeFull}
+	lookup := &models.APIKey{Scope: models.APIKeyScopeLookup}
+
+	// Buffalo gives group routes a trailing slash
+	req.True(apiKeyAllows(lookup, http.MethodGet, "/api/v1/donations/lookup/"))
+	req.False(apiKeyAllows(lookup, http.MethodPost, "/api/v1/donations/"))
+	req.False(apiKeyAllows(lookup, http.MethodPost, "/api/v1/hooks/"))
+	req.False(apiKeyAllows(lookup, http.MethodGet, "/api/v1/triggers/donations/"))
+	req.True(apiKeyAllows(full, http.MethodPost, "/api/v1/donations/"))
+	req.True(apiKeyAllows(full, http.MethodGet, "/api/v1/donations/lookup/"))
+}
+
+func Test_ParseAPIDate(t *testing.T) {
+	req := require.New(t)
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	got, err := parseAPIDate("", now)
+	req.NoError(err)
+	req.Equal(now, got)
+
+	got, err = parseAPIDate("2026-10-12T15:04:05Z", now)
+	req.NoError(err)
+	req.Equal(time.Date(2026, 10, 12, 15, 4, 5, 0, time.UTC), got)
+
+	got, err = parseAPIDate("2026-10-12", now)
+	req.NoError(err)
+	req.Equal("2026-10-12", got.Format(dateInputLayout))
+
+	_, err = parseAPIDate("last tuesday", now)
+	req.Error(err)
+}
+
+func Test_APIDonationFrom(t *testing.T) {
+	req := require.New(t)
+
+	donation := models.Donation{ID: uuid.Must(uuid.NewV4()), Amount: 50, Currency: "USD", DonorName: "Jane Doe", DonationType: models.DonationTypeOneTime}
+	item := apiDonationFrom(donation)
+	req.Equal(donationPaymentCard, item.PaymentMethod)
+	req.Contains(item.AdminURL, "/admin/donations/"+donation.ID.String())
+
+	method := models.PaymentMethodOffline
+	donation.PaymentMethod = &method
+	req.Equal(models.PaymentMethodOffline, apiDonationFrom(donation).PaymentMethod)
+}
+
+func Test_APIDonationDetailFrom(t *testing.T) {
+	req := require.New(t)
+
+	txn, line1, city, state, zip := "TXN-55", "1 Main St", "Austin", "TX", "78701"
+	refundedAt := time.Date(2026, 10, 13, 9, 0, 0, 0, time.UTC)
+	donation := models.Donation{
+		ID: uuid.Must(uuid.NewV4()), Amount: 100, Currency: "USD", DonorName: "Jane Doe", DonationType: models.DonationTypeOneTime,
+		Status: "completed", HelcimTransactionID: &txn, AddressLine1: &line1, City: &city, State: &state, Zip: &zip,
+		RefundedAmount: 25, RefundedAt: &refundedAt,
+	}
+	issuedAt := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	receipts := models.DonationReceipts{{ReceiptNumber: "AVR-2026-000007", TransactionID: txn, Amount: 100, Email: "jane@example.com", IssuedAt: issuedAt}}
+
+	detail := apiDonationDetailFrom(donation, receipts)
+	req.Equal(donation.ID.String(), detail.ID)
+	req.Equal("TXN-55", detail.TransactionID)
+	req.Equal(100.0, detail.TaxDeductibleAmount)
+	req.Equal(25.0, detail.RefundedAmount)
+	req.Equal("1 Main St, Austin, TX 78701", detail.DonorAddress)
+	req.Len(detail.Receipts, 1)
+	req.Equal("AVR-2026-000007", detail.Receipts[0].ReceiptNumber)
+
+	// The donation's own fields sit alongside the detail in the JSON
+	body, err := json.Marshal(detail)
+	req.NoError(err)
+	req.Contains(string(body), `"donor_name":"Jane Doe"`)
+	req.Contains(string(body), `"receipt_number":"AVR-2026-000007"`)
+}
+
+func Test_AdminAPIKeysTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	keyID := uuid.Must(uuid.NewV4())
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/admin-api-keys-test", func(c buffalo.Context) error {
+		limits := apiRateLimits{Burst: ratelimit.Rule{Limit: 60, Window: time.Minute}, Quota: ratelimit.Rule{Limit: 1000, Window: 24 * time.Hour}}
+		c.Set("apiKeys", []apiKeyUsageRow{{
+			APIKey: models.APIKey{ID: keyID, Name: "Zapier", Prefix: "avr_abc123", Scope: models.APIKeyScopeLookup, DailyQuota: 500, CreatedAt: time.Now()},
+			Usage:  models.APIKeyUsageSummary{Today: 125, ThrottledToday: 3, Requests: 2400, Throttled: 7},
+			Limits: apiRateLimits{Burst: limits.Burst, Quota: ratelimit.Rule{Limit: 500, Window: 24 * time.Hour}},
+		}})
+		c.Set("apiLimits", limits)
+		c.Set("apiUsageDays", apiUsageDays)
+		c.Set("rateLimitsEnabled", true)
+		c.Set("apiHooks", models.APIHooks{{APIKeyID: keyID, Event: models.APIEventDonationCreated, TargetURL: "https://hooks.zapier.com/abc"}})
+		c.Set("keyNames", map[string]string{keyID.String(): "Zapier"})
+		c.Set("apiBaseURL", "https://avrnpo.org/api/v1")
+		c.Set("apiEvents", models.APIEvents)
+		c.Set("newKey", "avr_abc123secret")
+		c.Set("newKeyName", "Zapier")
+		return c.Render(http.StatusOK, r.HTML("admin/api_keys/index.plush.html"))
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/admin-api-keys-test", nil))
+	req.Equal(http.StatusOK, res.Code, res.Body.String())
+	req.Contains(res.Body.String(), "avr_abc123secret")
+	req.Contains(res.Body.String(), "Never")
+	req.Contains(res.Body.String(), "https://hooks.zapier.com/abc")
+	req.Contains(res.Body.String(), "New donation")
+	req.Contains(res.Body.String(), "60 requests a minute and 1000 a day")
+	req.Contains(res.Body.String(), "125 / 500")
+	req.Contains(res.Body.String(), "25% of quota, 3 throttled")
+	req.Contains(res.Body.String(), `name="DailyQuota" min="0" value="500" placeholder="1000"`)
+	req.Contains(res.Body.String(), "Donation lookup only")
+}
@@ -3,8 +3,13 @@ package actions
 import (
 	"avrnpo.org/locales"
 	"avrnpo.org/models"
+	"avrnpo.org/pkg/diagnostics"
+	"avrnpo.org/pkg/errortracking"
+	"avrnpo.org/pkg/jobqueue"
 	"avrnpo.org/pkg/logging"
+	"avrnpo.org/pkg/ratelimit"
 	"avrnpo.org/public"
+	"avrnpo.org/services"
 	"fmt"
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/buffalo-pop/v3/pop/popmw"
@@ -14,7 +19,7 @@ import (
 	"github.com/gobuffalo/middleware/i18n"
 	"github.com/gobuffalo/mw-csrf"
 	"github.com/gobuffalo/pop/v6"
-	"github.com/gorilla/sessions"
+	"github.com/sirupsen/logrus"
 	"github.com/unrolled/secure"
 	"io/fs"
 	"net/http"
@@ -46,6 +51,10 @@ func ValidateContactForm(c buffalo.Context) error {
 	email := SanitizeInput(c.Param("email"))
 	subject := SanitizeInput(c.Param("subject"))
 	message := SanitizeInput(c.Param("message"))
+	topic := SanitizeInput(c.Param("topic"))
+	if topic == "" {
+		topic = models.ContactTopicGeneral
+	}
 
 	if err := ValidateRequiredString(name, "Name", 100); err != nil {
 		return err
@@ -63,11 +72,72 @@ func ValidateContactForm(c buffalo.Context) error {
 		return err
 	}
 
+	if !models.ValidContactTopic(topic) {
+		return fmt.Errorf("please choose a topic from the list")
+	}
+
 	// Store sanitized values back in context for processing
 	c.Set("name", name)
 	c.Set("email", email)
 	c.Set("subject", subject)
 	c.Set("message", message)
+	c.Set("topic", topic)
+
+	return nil
+}
+
+// ValidatePressInquiry validates the press page's inquiry form. The outlet
+// and deadline are optional; a deadline must be a date that hasn't passed.
+func ValidatePressInquiry(c buffalo.Context) error {
+	if err := ValidateBotProtection(c); err != nil {
+		return err
+	}
+
+	name := SanitizeInput(c.Param("name"))
+	email := SanitizeInput(c.Param("email"))
+	outlet := SanitizeInput(c.Param("outlet"))
+	subject := SanitizeInput(c.Param("subject"))
+	message := SanitizeInput(c.Param("message"))
+
+	if err := ValidateRequiredString(name, "Name", 100); err != nil {
+		return err
+	}
+
+	if err := ValidateEmail(email); err != nil {
+		return err
+	}
+
+	if len(outlet) > 200 {
+		return fmt.Errorf("outlet must be less than 200 characters")
+	}
+
+	if err := ValidateRequiredString(subject, "Subject", 200); err != nil {
+		return err
+	}
+
+	if err := ValidateRequiredString(message, "Message", 2000); err != nil {
+		return err
+	}
+
+	var deadline *time.Time
+	if s := strings.TrimSpace(c.Param("deadline")); s != "" {
+		d, err := time.Parse(dateInputLayout, s)
+		if err != nil {
+			return fmt.Errorf("please enter your deadline as a date")
+		}
+		// Allow a day's slack, since the journalist may be behind UTC
+		if d.Before(time.Now().AddDate(0, 0, -1).Truncate(24 * time.Hour)) {
+			return fmt.Errorf("your deadline has already passed")
+		}
+		deadline = &d
+	}
+
+	c.Set("name", name)
+	c.Set("email", email)
+	c.Set("outlet", outlet)
+	c.Set("subject", subject)
+	c.Set("message", message)
+	c.Set("deadline", deadline)
 
 	return nil
 }
@@ -82,30 +152,38 @@ func ValidateBotProtection(c buffalo.Context) error {
 		return fmt.Errorf("invalid form submission detected")
 	}
 
-	// Check form submission timing (3 seconds minimum, 10 minutes maximum)
-	timestampStr := c.Param("form_timestamp")
-	if timestampStr != "" {
-		if timestamp, err := strconv.ParseInt(timestampStr, 10, 64); err == nil {
-			submissionTime := time.Now().Unix()
-			timeDiff := submissionTime - timestamp
-
-			// Too fast (less than 3 seconds) - likely a bot
-			if timeDiff < 3 {
-				c.Logger().Infof("BOT_PROTECTION - Form submitted too quickly (%d seconds) from IP %s", timeDiff, getClientIP(c))
-				return fmt.Errorf("form submission was too quick, please try again")
-			}
-
-			// Too slow (more than 10 minutes) - could be a stale form or bot
-			if timeDiff > 600 {
-				c.Logger().Infof("BOT_PROTECTION - Form submitted too slowly (%d seconds) from IP %s", timeDiff, getClientIP(c))
-				return fmt.Errorf("form session expired, please refresh and try again")
-			}
-		}
+	// Check form submission timing
+	if elapsed, err := formTimingError(c.Param("form_timestamp"), time.Now()); err != nil {
+		c.Logger().Infof("BOT_PROTECTION - Form submitted after %d seconds from IP %s", elapsed, getClientIP(c))
+		return err
 	}
 
 	return nil
 }
 
+// formTimingError checks how long a form was open before it was sent, from
+// the form_timestamp (unix seconds) it was rendered with: 3 seconds minimum,
+// 10 minutes maximum. Forms sent without one pass. It returns the seconds
+// elapsed for logging.
+func formTimingError(timestampStr string, now time.Time) (int64, error) {
+	timestamp, err := strconv.ParseInt(strings.TrimSpace(timestampStr), 10, 64)
+	if err != nil {
+		return 0, nil
+	}
+	timeDiff := now.Unix() - timestamp
+
+	// Too fast (less than 3 seconds) - likely a bot
+	if timeDiff < 3 {
+		return timeDiff, fmt.Errorf("form submission was too quick, please try again")
+	}
+
+	// Too slow (more than 10 minutes) - could be a stale form or bot
+	if timeDiff > 600 {
+		return timeDiff, fmt.Errorf("form session expired, please refresh and try again")
+	}
+	return timeDiff, nil
+}
+
 // ValidateEmail performs secure email validation
 func ValidateEmail(email string) error {
 	if len(email) == 0 {
@@ -199,6 +277,7 @@ func App() *buffalo.App {
 
 		// Initialize logging first
 		logging.MustInit(nil)
+		logging.SetAuditRecorder(recordAuditLog)
 
 		// Set Buffalo to use our logrus-based logger for all request logs
 		// Use Buffalo's built-in logger with multi-writer (terminal + file)
@@ -250,23 +329,36 @@ func App() *buffalo.App {
 		app = buffalo.New(buffalo.Options{
 			Env:           ENV,
 			SessionName:   "_avrnpo.org_session",
-			SessionStore:  sessions.NewCookieStore([]byte(sessionSecret)),
+			SessionStore:  newSessionStore(sessionSecret, ENV),
 			CompressFiles: true, // Enable gzip compression for static files
 			Addr:          addr, // Listen on all interfaces for container access
+			// Cap request bodies before the form is first read
+			MethodOverride: bodyLimitsFromEnv().MethodOverride,
 		})
 
 		// Create logger with the specified level and set it
 		buffaloLogger := logger.NewLogger(logLevel)
 		app.Logger = buffaloLogger
 
+		// Capture Buffalo request logs for the admin log viewer
+		if lr, ok := buffaloLogger.(logger.Logrus); ok {
+			if l, ok := lr.FieldLogger.(*logrus.Logger); ok {
+				l.AddHook(logging.Recent.ForModule("http"))
+			}
+		}
+
+		// Receipts and webhook side effects such as Helcim syncs run in the
+		// background on a Postgres-backed queue that retries failed jobs
+		queue := jobqueue.New(models.DB)
+		queue.Logger = buffaloLogger
+		if err := registerBackgroundJobs(queue); err != nil {
+			app.Logger.Fatal(err)
+		}
+		app.Worker = queue
+
 		// Debug environment variables (after app is initialized)
 		app.Logger.Infof("Environment check - GO_ENV: %s, SESSION_SECRET length: %d", ENV, len(sessionSecret))
 		app.Logger.Infof("Application configured to listen on: %s", addr)
-		if len(sessionSecret) > 10 {
-			app.Logger.Infof("SESSION_SECRET value starts with: %s...", sessionSecret[:10])
-		} else {
-			app.Logger.Infof("SESSION_SECRET value: %s", sessionSecret)
-		}
 
 		if ENV == "production" && sessionSecret == "development-session-secret-change-in-production" {
 			app.Logger.Warn("SESSION_SECRET not set in production! Using insecure default.")
@@ -274,15 +366,91 @@ func App() *buffalo.App {
 
 		app.Logger.Info("App initialization completed")
 
+		build := diagnostics.CurrentBuild()
+		logging.Info("Starting avrnpo.org", logging.Fields{
+			"version":    build.Version,
+			"commit":     build.Commit,
+			"build_date": build.BuildDate,
+			"go_version": build.GoVersion,
+			"env":        ENV,
+		})
+
+		// Report server errors and panics to a Sentry-compatible endpoint (SENTRY_DSN)
+		if err := errortracking.Init(ENV, build.Version+"+"+build.ShortSHA); err != nil {
+			logging.Error("Invalid SENTRY_DSN, errors will only be kept locally", err)
+		}
+		// Uploads fail until a misconfigured bucket (STORAGE_DRIVER=s3) is fixed
+		if err := services.CheckStorage(); err != nil {
+			logging.Error("Upload storage is not configured, uploads will fail", err)
+		}
+		// Branded error pages showing the incident reference (request_id)
+		for _, status := range []int{http.StatusForbidden, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusTooManyRequests, http.StatusInternalServerError} {
+			app.ErrorHandlers[status] = friendlyErrorHandler(app.ErrorHandlers.Get(status))
+		}
+		app.ErrorHandlers[http.StatusInternalServerError] = reportingErrorHandler(app.ErrorHandlers[http.StatusInternalServerError])
+
+		// Render the page templates with their handlers' context so a template
+		// referencing a missing key stops the dev server instead of a request
+		if ENV == "development" {
+			if err := preflightTemplates(); err != nil {
+				app.Logger.Fatal(err)
+			}
+		}
+
+		// Verify pinned third-party scripts (HelcimPay.js) haven't changed upstream
+		if ENV != "test" {
+			go func() {
+				defer errortracking.Recover("script-integrity")
+				verifyPinnedScripts(&http.Client{Timeout: 10 * time.Second})
+			}()
+		}
+
+		// Share rate limits, payment plans and webhook claims between
+		// instances through Redis when REDIS_URL is set
+		if sharedRedis = connectRedis(); sharedRedis != nil {
+			services.GetPaymentPlanCache().UseShared(sharedRedis)
+		}
+
 		// Use Buffalo's built-in request logging middleware
 		app.Use(buffalo.RequestLoggerFunc)
 
 		// Inject i18n translations middleware for all requests (early in stack)
 		app.Use(translations())
 
+		// Limit how often one IP can post donations, contact messages and
+		// logins (RATE_LIMIT_*), before a transaction is opened for it. Admin
+		// step-up passwords are held to the login limit per account too.
+		if rateLimitsEnabled() {
+			app.Use(RateLimits(ratelimit.New(rateLimitStore()), rateLimitsFromEnv()))
+			adminStepUpThrottle = newStepUpThrottle(ratelimit.New(rateLimitStore()))
+		}
+
+		// Screen donation charges for card testing (FRAUD_*), counting
+		// attempts in the same store as the rate limits
+		if fraudScreeningEnabled() {
+			donationFraudScreen = newFraudScreen(ratelimit.New(rateLimitStore()))
+		}
+
+		// Turn away request bodies over their size limit (REQUEST_LIMIT_*)
+		app.Use(BodyLimits)
+
 		// Inject DB transaction middleware for all requests
 		app.Use(popmw.Transaction(models.DB))
 
+		// Log requests that run the same query once per row of a list
+		if ENV == "development" {
+			app.Use(DetectNPlusOne(app))
+		}
+
+		// Push failed payment webhooks to the admin dashboard
+		app.Use(WebhookFailureAlerts)
+
+		// Send the Content Security Policy (report-only unless CSP_ENFORCE=true)
+		app.Use(ContentSecurityPolicy)
+
+		// Expire logged-in sessions past their absolute lifetime
+		app.Use(EnforceSessionLifetime)
+
 		// Set current user for all requests (after DB transactions)
 		app.Use(SetCurrentUser)
 
@@ -294,13 +462,31 @@ func App() *buffalo.App {
 		// app.Use(secure.New(secure.Options{...}).Handler)
 
 		// Skip CSRF protection only for legitimate API endpoints (webhooks, payment callbacks)
-		app.Middleware.Skip(csrf.New, HelcimWebhookHandler, debugFilesHandler, DebugFlashHandler, DonationInitializeHandler, ProcessPaymentHandler)
+		app.Middleware.Skip(csrf.New, HelcimWebhookHandler, debugFilesHandler, DebugFlashHandler, DonationInitializeHandler, ProcessPaymentHandler, CSPReportHandler, CryptoWebhookHandler, PayPalGivingFundWebhookHandler, EmailEventsWebhookHandler)
+
+		// The live stats stream stays open for minutes, so it doesn't hold a transaction
+		app.Middleware.Skip(popmw.Transaction(models.DB), PublicStatsStreamHandler)
+		app.Middleware.Skip(SetCurrentUser, PublicStatsStreamHandler)
 		app.GET("/debug/files", debugFilesHandler)
 
 		// Public routes
 		app.GET("/", HomeHandler)
 		app.GET("/contact", ContactHandler)
 		app.POST("/contact", ContactHandler)
+		app.GET("/press", PressHandler)
+		app.POST("/press", PressHandler)
+		app.GET("/media/{asset_id}", MediaDownload)
+		app.GET("/jobs", JobsIndex)
+		app.GET("/jobs/new", JobPostingHandler)
+		app.POST("/jobs/new", JobPostingHandler)
+		app.GET("/jobs/feed.xml", JobsFeed)
+		app.GET("/jobs/{job_id}", JobShow)
+		app.GET("/events", EventsIndex)
+		app.GET("/events/calendar.ics", EventsCalendarFeed)
+		app.GET("/events/{event_id}.ics", EventCalendarDownload)
+		app.GET("/mentoring", MentoringIndex)
+		app.GET("/resources", ResourcesIndex)
+		app.GET("/resources/{resource_id}/visit", ResourceVisit)
 		app.GET("/team", TeamHandler)
 		app.GET("/projects", ProjectsHandler)
 		app.GET("/donate", DonateHandler)
@@ -308,10 +494,70 @@ func App() *buffalo.App {
 		app.GET("/donate/payment", DonatePaymentHandler)
 		app.GET("/donate/success", DonationSuccessHandler)
 		app.GET("/donate/failed", DonationFailedHandler)
+		app.GET("/donate/crypto", DonateCryptoHandler)
+		app.POST("/donate/crypto", DonateCryptoCreateHandler)
+		app.GET("/donate/vehicle", VehicleDonationHandler)
+		app.POST("/donate/vehicle", VehicleDonationHandler)
+		app.POST("/drafts/{form}", FormDraftSave)
+		app.POST("/drafts/{form}/discard", FormDraftDiscard)
+		app.GET("/give/{partner_slug}", GivePartnerHandler)
+		app.GET("/gift-cards", GiftCardsHandler)
+		app.GET("/gift-cards/redeem", GiftCardRedeemHandler)
+		app.POST("/gift-cards/redeem", GiftCardRedeemHandler)
+		app.GET("/surveys/{token}", SurveyHandler)
+		app.POST("/surveys/{token}", SurveyHandler)
+		app.GET("/tickets/{token}", TicketHandler)
+		app.GET("/tickets/{token}/qr.svg", TicketQRHandler)
+		app.GET("/auctions", AuctionsIndex)
+		app.GET("/auctions/pay/{donation_id}", AuctionPaymentHandler)
+		app.GET("/auctions/{item_id}", AuctionItemHandler)
+		app.POST("/auctions/{item_id}/bids", AuctionBidCreate)
+		app.POST("/auctions/{item_id}/tickets", RaffleTicketsCreate)
+		app.GET("/store", StoreIndex)
+		app.POST("/store/checkout", StoreCheckout)
 		app.POST("/api/donations/initialize", DonationInitializeHandler)
 		app.POST("/api/donations/process", ProcessPaymentHandler)
 		app.Logger.Info("Registered POST /api/donations/process route")
 		app.POST("/api/donations/webhook", HelcimWebhookHandler)
+		app.POST("/api/donations/crypto/webhook", CryptoWebhookHandler)
+		app.POST("/api/donations/paypal-giving-fund/webhook", PayPalGivingFundWebhookHandler)
+		app.POST("/api/email/events", EmailEventsWebhookHandler)
+
+		// REST API for integrations like Zapier, authenticated by API key
+		apiGroup := app.Group("/api/v1")
+		apiGroup.Use(APIKeyRequired)
+		// Hold each key to its burst limit and quota (API_RATE_LIMIT_*)
+		if rateLimitsEnabled() {
+			apiGroup.Use(APIRateLimits(ratelimit.New(rateLimitStore()), apiRateLimitsFromEnv()))
+		}
+		apiGroup.Middleware.Remove(csrf.New)
+		apiGroup.GET("/me", APIMe)
+		apiGroup.GET("/triggers/donations", APIDonationsIndex)
+		apiGroup.GET("/triggers/contact-messages", APIContactMessagesIndex)
+		apiGroup.GET("/triggers/volunteers", APIVolunteersIndex)
+		apiGroup.POST("/donations", APIDonationsCreate)
+		apiGroup.GET("/donations/lookup", APIDonationLookup)
+		apiGroup.POST("/donations/initialize", APIDonationsInitialize)
+		apiGroup.GET("/donations/{donation_id}", APIDonationStatus)
+		apiGroup.POST("/newsletter-subscribers", APINewsletterSubscribersCreate)
+		apiGroup.GET("/hooks", APIHooksIndex)
+		apiGroup.POST("/hooks", APIHooksCreate)
+		apiGroup.DELETE("/hooks/{hook_id}", APIHooksDestroy)
+
+		// Synthetic donation load for checking capacity before a campaign.
+		// Staging only; see loadTestModeEnabled.
+		if loadTestModeEnabled() {
+			app.Logger.Warn("Load test mode is on: /load-test creates synthetic donations")
+			loadTestGroup := app.Group("/load-test")
+			loadTestGroup.Use(LoadTestTokenRequired)
+			loadTestGroup.Middleware.Remove(csrf.New)
+			loadTestGroup.POST("/donations", LoadTestDonationsCreate)
+			loadTestGroup.GET("/status", LoadTestStatus)
+		}
+
+		// Browser CSP violation reports
+		app.POST("/csp-report", CSPReportHandler)
+
 		app.GET("/debug/user", func(c buffalo.Context) error {
 			tx := c.Value("tx").(*pop.Connection)
 			user := &models.User{}
@@ -328,36 +574,196 @@ func App() *buffalo.App {
 		app.POST("/auth", AuthCreate)
 		app.DELETE("/auth", AuthDestroy)
 		app.GET("/auth/logout", AuthDestroy)
+		app.GET("/invitations/{token}", InvitationShow)
+		app.POST("/invitations/{token}", InvitationAccept)
 		app.GET("/api/blog/load-more/{page}", BlogLoadMore)
+		app.GET("/api/stats", PublicStatsHandler)
+		app.GET("/api/stats/stream", PublicStatsStreamHandler)
 		app.GET("/dashboard", Authorize(DashboardHandler))
 		app.GET("/profile", Authorize(ProfileSettings))
 		app.POST("/profile", Authorize(ProfileUpdate))
 		app.GET("/account", Authorize(AccountSettings))
 		app.POST("/account", Authorize(AccountUpdate))
 		app.GET("/account/subscriptions", Authorize(SubscriptionsList))
+		app.GET("/account/receipts", Authorize(AccountReceipts))
+		app.POST("/account/job-alerts", Authorize(AccountJobAlerts))
+		app.GET("/account/mentoring", Authorize(AccountMentoring))
+		app.POST("/account/mentoring", Authorize(AccountMentoring))
+		app.GET("/account/receipts/{year}", Authorize(AccountReceiptDownload))
 		app.GET("/account/subscriptions/{subscriptionId}", Authorize(SubscriptionDetails))
 		app.POST("/account/subscriptions/{subscriptionId}/cancel", Authorize(CancelSubscription))
+		app.POST("/account/subscriptions/{subscriptionId}/pause", Authorize(PauseSubscription))
+		app.POST("/account/subscriptions/{subscriptionId}/resume", Authorize(ResumeSubscription))
+		app.POST("/account/subscriptions/{subscriptionId}/amount", Authorize(UpdateSubscriptionAmount))
+		app.POST("/account/subscriptions/{subscriptionId}/upgrade/{prompt_id}/accept", Authorize(AcceptUpgradePrompt))
+		app.POST("/account/subscriptions/{subscriptionId}/upgrade/{prompt_id}/dismiss", Authorize(DismissUpgradePrompt))
+		app.GET("/account/subscriptions/{subscriptionId}/payment-method", Authorize(SubscriptionPaymentMethod))
+		app.POST("/account/subscriptions/{subscriptionId}/payment-method", Authorize(UpdateSubscriptionPaymentMethod))
 		app.Resource("/blog", blogResource) // Admin routes
 		adminGroup := app.Group("/admin")
 		adminGroup.Use(AdminRequired)
+		adminGroup.Middleware.Skip(popmw.Transaction(models.DB), AdminActivityStream)
 		adminGroup.GET("/", AdminDashboard)
 		adminGroup.GET("/dashboard", AdminDashboard)
+		adminGroup.GET("/activity/stream", AdminActivityStream)
+		adminGroup.GET("/step-up", AdminStepUpNew)
+		adminGroup.POST("/step-up", AdminStepUpCreate)
+		adminGroup.GET("/sessions", AdminSessionsIndex)
+		adminGroup.GET("/csp-reports", AdminCSPReportsIndex)
+		adminGroup.GET("/diagnostics", AdminDiagnostics)
+		adminGroup.GET("/system", AdminSystem)
+		adminGroup.GET("/system/logs", AdminSystemLogs)
+		adminGroup.GET("/audit-log", AdminAuditLog)
+		adminGroup.GET("/self-test", AdminSelfTest)
+		adminGroup.POST("/self-test", AdminSelfTestRun)
+		adminGroup.POST("/sessions/invalidate", SensitiveAdminAction("sessions_invalidate", AdminSessionsInvalidate))
 		adminGroup.GET("/users", AdminUsers)
+		adminGroup.POST("/users/bulk", AdminUsersBulk)
 		adminGroup.GET("/users/{user_id}", AdminUserShow)
 		adminGroup.POST("/users/{user_id}", AdminUserUpdate)
-		adminGroup.DELETE("/users/{user_id}", AdminUserDelete)
+		adminGroup.POST("/users/{user_id}/deactivate", SensitiveAdminAction("user_deactivate", AdminUserDeactivate))
+		adminGroup.POST("/users/{user_id}/reactivate", AdminUserReactivate)
+		adminGroup.DELETE("/users/{user_id}", SensitiveAdminAction("user_delete", AdminUserDelete))
 		adminGroup.Resource("/users", adminUsersResource)
 		adminGroup.GET("/posts", AdminPostsIndex)
 		adminGroup.GET("/posts/new", AdminPostsNew)
 		adminGroup.POST("/posts", AdminPostsCreate)
+		adminGroup.POST("/posts/bulk", AdminPostsBulk)
 		adminGroup.GET("/posts/{post_id}", AdminPostsShow)
 		adminGroup.GET("/posts/{post_id}/edit", AdminPostsEdit)
 		adminGroup.POST("/posts/{post_id}", AdminPostsUpdate)
 		adminGroup.DELETE("/posts/{post_id}", AdminPostsDestroy)
-		adminGroup.POST("/posts/bulk", AdminPostsBulk)
 		adminGroup.Resource("/posts", postsResource)
 		adminGroup.GET("/donations", AdminDonationsIndex)
+		adminGroup.GET("/donations/review", AdminDonationReviews)
+		adminGroup.GET("/donations/export", AdminDonationsExport)
+		adminGroup.POST("/donations/year-end-receipts", AdminSendYearEndReceipts)
+		adminGroup.POST("/donations/{donation_id}/approve", SensitiveAdminAction("donation_approve", AdminDonationApprove))
+		adminGroup.POST("/donations/{donation_id}/decline", AdminDonationDecline)
 		adminGroup.GET("/donations/{donation_id}", AdminDonationShow)
+		adminGroup.POST("/donations/{donation_id}/status", AdminDonationUpdateStatus)
+		adminGroup.GET("/donations/{donation_id}/receipt", AdminDonationReceiptPreview)
+		adminGroup.GET("/donations/{donation_id}/receipt/pdf", AdminDonationReceiptPDF)
+		adminGroup.POST("/donations/{donation_id}/receipt", AdminDonationResendReceipt)
+		adminGroup.POST("/donations/{donation_id}/refund", SensitiveAdminAction("donation_refund", AdminDonationRefund))
+		adminGroup.GET("/donors", AdminDonorsIndex)
+		adminGroup.GET("/donors/discrepancies", AdminDonorDiscrepancies)
+		adminGroup.POST("/donors/discrepancies/{discrepancy_id}/resolve", AdminDonorDiscrepancyResolve)
+		adminGroup.GET("/donors/{email}", AdminDonorShow)
+		adminGroup.POST("/donors/{email}/notes", AdminDonorNoteCreate)
+		adminGroup.POST("/donors/{email}/flags", AdminDonorFlagCreate)
+		adminGroup.POST("/donors/{email}/flags/{flag_id}/delete", AdminDonorFlagDelete)
+		adminGroup.GET("/finance", AdminFinanceReport)
+		adminGroup.GET("/finance/cohorts", AdminDonorCohorts)
+		adminGroup.GET("/public-stats", AdminPublicStatsIndex)
+		adminGroup.POST("/public-stats", AdminPublicStatsUpdate)
+		adminGroup.GET("/upgrade-prompts", AdminUpgradePrompts)
+		adminGroup.GET("/suppressions", AdminSuppressionsIndex)
+		adminGroup.POST("/suppressions", AdminSuppressionsCreate)
+		adminGroup.POST("/suppressions/import", AdminSuppressionsImport)
+		adminGroup.GET("/suppressions/export", AdminSuppressionsExport)
+		adminGroup.POST("/suppressions/{suppression_id}/delete", AdminSuppressionsDelete)
+		adminGroup.GET("/gift-codes", AdminGiftCodesIndex)
+		adminGroup.GET("/payouts", AdminPayoutsIndex)
+		adminGroup.POST("/payouts/import", AdminPayoutsImport)
+		adminGroup.GET("/partners", AdminPartnersIndex)
+		adminGroup.GET("/partners/new", AdminPartnersNew)
+		adminGroup.POST("/partners", AdminPartnersCreate)
+		adminGroup.GET("/partners/{partner_id}/edit", AdminPartnersEdit)
+		adminGroup.POST("/partners/{partner_id}", AdminPartnersUpdate)
+		adminGroup.GET("/form-fields", AdminFormFieldsIndex)
+		adminGroup.GET("/form-fields/new", AdminFormFieldsNew)
+		adminGroup.POST("/form-fields", AdminFormFieldsCreate)
+		adminGroup.GET("/form-fields/{field_id}/edit", AdminFormFieldsEdit)
+		adminGroup.POST("/form-fields/{field_id}", AdminFormFieldsUpdate)
+		adminGroup.GET("/surveys", AdminSurveysIndex)
+		adminGroup.GET("/surveys/new", AdminSurveysNew)
+		adminGroup.POST("/surveys", AdminSurveysCreate)
+		adminGroup.GET("/surveys/{survey_id}", AdminSurveysShow)
+		adminGroup.POST("/surveys/{survey_id}", AdminSurveysUpdate)
+		adminGroup.GET("/surveys/{survey_id}/export", AdminSurveyExport)
+		adminGroup.POST("/surveys/{survey_id}/questions", AdminSurveyQuestionsCreate)
+		adminGroup.POST("/surveys/{survey_id}/questions/{question_id}/delete", AdminSurveyQuestionsDelete)
+		adminGroup.POST("/surveys/{survey_id}/links", AdminSurveyLinksCreate)
+		adminGroup.GET("/email-campaigns", AdminEmailCampaignsIndex)
+		adminGroup.GET("/email-campaigns/new", AdminEmailCampaignsNew)
+		adminGroup.POST("/email-campaigns", AdminEmailCampaignsCreate)
+		adminGroup.GET("/email-campaigns/{campaign_id}", AdminEmailCampaignsShow)
+		adminGroup.POST("/email-campaigns/{campaign_id}", AdminEmailCampaignsUpdate)
+		adminGroup.POST("/email-campaigns/{campaign_id}/start", AdminEmailCampaignsStart)
+		adminGroup.GET("/events", AdminEventsIndex)
+		adminGroup.GET("/events/new", AdminEventsNew)
+		adminGroup.POST("/events", AdminEventsCreate)
+		adminGroup.GET("/events/{event_id}", AdminEventsShow)
+		adminGroup.GET("/events/{event_id}/edit", AdminEventsEdit)
+		adminGroup.POST("/events/{event_id}", AdminEventsUpdate)
+		adminGroup.POST("/events/{event_id}/tickets", AdminEventTicketsCreate)
+		adminGroup.GET("/events/{event_id}/tickets/search", AdminEventTicketSearch)
+		adminGroup.GET("/events/{event_id}/checkin", AdminEventCheckin)
+		adminGroup.POST("/events/{event_id}/checkin", AdminEventCheckinCreate)
+		adminGroup.GET("/events/{event_id}/attendance", AdminEventAttendance)
+		adminGroup.GET("/auctions", AdminAuctionsIndex)
+		adminGroup.GET("/auctions/new", AdminAuctionsNew)
+		adminGroup.POST("/auctions", AdminAuctionsCreate)
+		adminGroup.GET("/auctions/{item_id}", AdminAuctionsShow)
+		adminGroup.POST("/auctions/{item_id}/award", AdminAuctionsAward)
+		adminGroup.GET("/store", AdminStoreIndex)
+		adminGroup.GET("/store/products/new", AdminProductsNew)
+		adminGroup.POST("/store/products", AdminProductsCreate)
+		adminGroup.GET("/store/products/{product_id}/edit", AdminProductsEdit)
+		adminGroup.POST("/store/products/{product_id}", AdminProductsUpdate)
+		adminGroup.GET("/store/orders", AdminStoreOrdersIndex)
+		adminGroup.GET("/store/orders/{order_id}", AdminStoreOrderShow)
+		adminGroup.POST("/store/orders/{order_id}/fulfillment", AdminStoreOrderFulfillment)
+		adminGroup.GET("/store/orders/{order_id}/packing-slip", AdminStoreOrderPackingSlip)
+		adminGroup.GET("/in-kind", AdminInKindIndex)
+		adminGroup.GET("/in-kind/new", AdminInKindNew)
+		adminGroup.GET("/in-kind/export", AdminInKindExport)
+		adminGroup.POST("/in-kind", AdminInKindCreate)
+		adminGroup.GET("/in-kind/{gift_id}", AdminInKindShow)
+		adminGroup.GET("/in-kind/{gift_id}/letter", AdminInKindLetter)
+		adminGroup.POST("/in-kind/{gift_id}/acknowledge", AdminInKindAcknowledge)
+		adminGroup.GET("/messages", AdminContactMessagesIndex)
+		adminGroup.GET("/messages/{message_id}", AdminContactMessageShow)
+		adminGroup.POST("/messages/{message_id}/replied", AdminContactMessageReplied)
+		adminGroup.GET("/jobs", AdminJobsIndex)
+		adminGroup.POST("/jobs/{job_id}/status", AdminJobStatus)
+		adminGroup.GET("/resources", AdminResourcesIndex)
+		adminGroup.GET("/resources/new", AdminResourcesNew)
+		adminGroup.POST("/resources", AdminResourcesCreate)
+		adminGroup.POST("/resources/check-links", AdminResourcesCheckLinks)
+		adminGroup.GET("/resources/{resource_id}/edit", AdminResourcesEdit)
+		adminGroup.POST("/resources/{resource_id}", AdminResourcesUpdate)
+		adminGroup.GET("/mentoring", AdminMentoringIndex)
+		adminGroup.POST("/mentoring/profiles/{profile_id}/status", AdminMentorProfileStatus)
+		adminGroup.GET("/mentoring/matches", AdminMentoringMatches)
+		adminGroup.POST("/mentoring/introductions", AdminMentorIntroductionCreate)
+		adminGroup.POST("/mentoring/introductions/{introduction_id}/status", AdminMentorIntroductionStatus)
+		adminGroup.GET("/organization", AdminOrganization)
+		adminGroup.POST("/organization", AdminOrganizationUpdate)
+		adminGroup.GET("/campaign", AdminCampaignMode)
+		adminGroup.POST("/campaign", AdminCampaignModeUpdate)
+		adminGroup.GET("/api-keys", AdminAPIKeysIndex)
+		adminGroup.POST("/api-keys", SensitiveAdminAction("api_key_create", AdminAPIKeysCreate))
+		adminGroup.POST("/api-keys/{key_id}/revoke", AdminAPIKeysRevoke)
+		adminGroup.POST("/api-keys/{key_id}/limits", AdminAPIKeysLimits)
+		adminGroup.GET("/newsletter", AdminNewsletterIndex)
+		adminGroup.GET("/webhooks", AdminWebhookEventsIndex)
+		adminGroup.GET("/webhooks/{webhook_event_id}", AdminWebhookEventShow)
+		adminGroup.POST("/webhooks/{webhook_event_id}/replay", AdminWebhookEventReplay)
+		adminGroup.GET("/media", AdminMediaIndex)
+		adminGroup.POST("/media", AdminMediaCreate)
+		adminGroup.POST("/media/{asset_id}/press-kit", AdminMediaPressKit)
+		adminGroup.POST("/media/{asset_id}/delete", AdminMediaDelete)
+		adminGroup.GET("/quarantine", AdminQuarantineIndex)
+		adminGroup.POST("/quarantine/{kind}/{upload_id}/rescan", AdminQuarantineRescan)
+		adminGroup.POST("/quarantine/{kind}/{upload_id}/delete", AdminQuarantineDelete)
+		adminGroup.GET("/vehicles", AdminVehiclesIndex)
+		adminGroup.GET("/vehicles/{vehicle_id}", AdminVehicleShow)
+		adminGroup.GET("/vehicles/{vehicle_id}/photos/{photo_id}", AdminVehiclePhoto)
+		adminGroup.POST("/vehicles/{vehicle_id}/status", AdminVehicleStatus)
+		adminGroup.GET("/vehicles/{vehicle_id}/letter", AdminVehicleLetter)
+		adminGroup.POST("/vehicles/{vehicle_id}/acknowledge", AdminVehicleAcknowledge)
 
 		// Serve assets from /assets path
 		if ENV == "production" {
@@ -0,0 +1,573 @@
+package actions
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// maxRaffleTicketsPerPurchase caps how many raffle tickets one checkout buys
+const maxRaffleTicketsPerPurchase = 100
+
+// beginCheckout starts a HelcimPay checkout for a pending one-time donation
+// and sends the donor to the payment page, the same way the donation form
+// does.
+func beginCheckout(c buffalo.Context, tx *pop.Connection, donation *models.Donation) error {
+	helcimResponse, err := callHelcimVerifyAPI(HelcimPayVerifyRequest{
+		PaymentType: "verify",
+		Amount:      0,
+		Currency:    getCurrency(),
+		CustomerRequest: &services.CustomerRequest{
+			ContactName: donation.DonorName,
+			Email:       donation.DonorEmail,
+			BillingAddress: services.BillingAddress{
+				Name:    donation.DonorName,
+				Country: "USA",
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	donation.CheckoutToken = helcimResponse.CheckoutToken
+	donation.SecretToken = helcimResponse.SecretToken
+	if err := tx.Update(donation); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Redirect(http.StatusSeeOther, donationPaymentPath(donation))
+}
+
+// completeAuctionPayment records a charged raffle ticket purchase or winning
+// bid payment. Other donations are left alone.
+func completeAuctionPayment(c buffalo.Context, tx *pop.Connection, donation *models.Donation) {
+	entry := &models.RaffleEntry{}
+	if err := tx.Where("donation_id = ? AND status = ?", donation.ID, models.RaffleEntryPending).First(entry); err == nil {
+		entry.Status = models.RaffleEntryPaid
+		if err := tx.Update(entry); err != nil {
+			c.Logger().Errorf("[Auction] Failed to mark raffle entry %s paid: %v", entry.ID.String(), err)
+			return
+		}
+		logging.Audit("raffle_tickets_paid", logging.Fields{
+			"item_id":     entry.ItemID.String(),
+			"entry_id":    entry.ID.String(),
+			"donation_id": donation.ID.String(),
+			"tickets":     entry.Tickets,
+		})
+		return
+	}
+
+	item := &models.AuctionItem{}
+	if err := tx.Where("donation_id = ? AND status = ?", donation.ID, models.AuctionStatusAwarded).First(item); err == nil {
+		item.Status = models.AuctionStatusPaid
+		if err := tx.Update(item); err != nil {
+			c.Logger().Errorf("[Auction] Failed to mark auction item %s paid: %v", item.ID.String(), err)
+			return
+		}
+		logging.Audit("auction_item_paid", logging.Fields{
+			"item_id":     item.ID.String(),
+			"donation_id": donation.ID.String(),
+			"amount":      donation.Amount,
+		})
+	}
+}
+
+// highBid returns the leading bid on an auction item, or nil if there are
+// no bids yet.
+func highBid(tx *pop.Connection, item *models.AuctionItem) (*models.AuctionBid, error) {
+	bid := &models.AuctionBid{}
+	err := tx.Where("item_id = ?", item.ID).Order("amount desc, created_at asc").First(bid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return bid, nil
+}
+
+// setAuctionItemContext exposes an item and its bidding state to the public
+// item page.
+func setAuctionItemContext(c buffalo.Context, tx *pop.Connection, item *models.AuctionItem) error {
+	c.Set("title", item.Title)
+	c.Set("item", item)
+	c.Set("itemOpen", item.OpenAt(time.Now()))
+	c.Set("maxTickets", maxRaffleTicketsPerPurchase)
+	c.Set("highBid", 0.0)
+	c.Set("minimumBid", item.StartingBid)
+	if item.IsRaffle() {
+		return nil
+	}
+
+	bid, err := highBid(tx, item)
+	if err != nil {
+		return err
+	}
+	if bid != nil {
+		c.Set("highBid", bid.Amount)
+	}
+	c.Set("minimumBid", item.MinimumBid(bid))
+	return nil
+}
+
+// findOpenAuctionItem loads the item named in the route if it still takes
+// bids or ticket purchases, flashing why not otherwise.
+func findOpenAuctionItem(c buffalo.Context, tx *pop.Connection) (*models.AuctionItem, bool, error) {
+	item := &models.AuctionItem{}
+	if err := tx.Find(item, c.Param("item_id")); err != nil {
+		return nil, false, c.Error(http.StatusNotFound, err)
+	}
+	if !item.OpenAt(time.Now()) {
+		c.Flash().Add("warning", fmt.Sprintf("%s is closed.", item.Title))
+		return item, false, c.Redirect(http.StatusSeeOther, "/auctions/%s", item.ID)
+	}
+	return item, true, nil
+}
+
+// AuctionsIndex lists the auction items and raffles that are open
+func AuctionsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	items := models.AuctionItems{}
+	if err := tx.Where("status = ? AND closes_at > ?", models.AuctionStatusOpen, time.Now()).Order("closes_at asc").All(&items); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var rows []struct {
+		ItemID string  `db:"item_id"`
+		High   float64 `db:"high"`
+	}
+	if err := tx.RawQuery("SELECT item_id, MAX(amount) as high FROM auction_bids GROUP BY item_id").All(&rows); err != nil {
+		return errors.WithStack(err)
+	}
+	highBids := map[string]float64{}
+	for _, row := range rows {
+		highBids[row.ItemID] = row.High
+	}
+
+	c.Set("title", "Auction & Raffle")
+	c.Set("items", items)
+	c.Set("highBids", highBids)
+	return c.Render(http.StatusOK, r.HTML("pages/auctions.plush.html"))
+}
+
+// AuctionItemHandler shows an auction item with its bid form, or a raffle
+// with its ticket form
+func AuctionItemHandler(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	item := &models.AuctionItem{}
+	if err := tx.Find(item, c.Param("item_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if err := setAuctionItemContext(c, tx, item); err != nil {
+		return err
+	}
+	return c.Render(http.StatusOK, r.HTML("pages/auction_item.plush.html"))
+}
+
+// AuctionBidCreate places a bid on an auction item
+func AuctionBidCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	item, open, err := findOpenAuctionItem(c, tx)
+	if !open {
+		return err
+	}
+	if item.IsRaffle() {
+		return c.Redirect(http.StatusSeeOther, "/auctions/%s", item.ID)
+	}
+
+	leading, err := highBid(tx, item)
+	if err != nil {
+		return err
+	}
+	minimum := item.MinimumBid(leading)
+	amount, err := strconv.ParseFloat(strings.TrimSpace(c.Param("amount")), 64)
+	if err != nil || amount < minimum {
+		c.Flash().Add("danger", fmt.Sprintf("Bids must be at least $%.2f.", minimum))
+		return c.Redirect(http.StatusSeeOther, "/auctions/%s", item.ID)
+	}
+
+	bid := &models.AuctionBid{
+		ItemID:      item.ID,
+		BidderName:  strings.TrimSpace(c.Param("name")),
+		BidderEmail: strings.ToLower(strings.TrimSpace(c.Param("email"))),
+		Amount:      amount,
+	}
+	if currentUser, ok := c.Value("current_user").(*models.User); ok && currentUser != nil {
+		bid.UserID = &currentUser.ID
+	}
+	verrs, err := tx.ValidateAndCreate(bid)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", "Please enter your name and a valid email address so we can reach you if you win.")
+		return c.Redirect(http.StatusSeeOther, "/auctions/%s", item.ID)
+	}
+
+	c.Logger().Infof("[Auction] Bid of $%.2f placed on item %s", bid.Amount, item.ID.String())
+	c.Flash().Add("success", fmt.Sprintf("Your bid of $%.2f is in. We'll email you if you win when bidding closes.", bid.Amount))
+	return c.Redirect(http.StatusSeeOther, "/auctions/%s", item.ID)
+}
+
+// RaffleTicketsCreate buys raffle tickets through the payment page. The
+// tickets count in the draw once the payment goes through.
+func RaffleTicketsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	item, open, err := findOpenAuctionItem(c, tx)
+	if !open {
+		return err
+	}
+	if !item.IsRaffle() {
+		return c.Redirect(http.StatusSeeOther, "/auctions/%s", item.ID)
+	}
+
+	tickets, err := strconv.Atoi(c.Param("tickets"))
+	if err != nil || tickets < 1 || tickets > maxRaffleTicketsPerPurchase {
+		c.Flash().Add("danger", fmt.Sprintf("Choose between 1 and %d tickets.", maxRaffleTicketsPerPurchase))
+		return c.Redirect(http.StatusSeeOther, "/auctions/%s", item.ID)
+	}
+	name := strings.TrimSpace(c.Param("name"))
+	email := strings.ToLower(strings.TrimSpace(c.Param("email")))
+	if name == "" || !strings.Contains(email, "@") {
+		c.Flash().Add("danger", "Please enter your name and a valid email address so we can reach you if you win.")
+		return c.Redirect(http.StatusSeeOther, "/auctions/%s", item.ID)
+	}
+
+	// Raffle tickets are a purchase of a chance to win, so none of the price
+	// is deductible
+	amount := float64(tickets) * item.TicketPrice
+	donation := &models.Donation{
+		DonorName:        name,
+		DonorEmail:       email,
+		Amount:           amount,
+		Currency:         getCurrency(),
+		DonationType:     models.DonationTypeOneTime,
+		Status:           "pending",
+		Comments:         stringPointer(fmt.Sprintf("%d raffle tickets: %s", tickets, item.Title)),
+		FairMarketValue:  &amount,
+		GoodsDescription: stringPointer(fmt.Sprintf("%d raffle tickets for %s", tickets, item.Title)),
+	}
+	if currentUser, ok := c.Value("current_user").(*models.User); ok && currentUser != nil {
+		donation.UserID = &currentUser.ID
+	}
+	if err := tx.Create(donation); err != nil {
+		return errors.WithStack(err)
+	}
+
+	entry := &models.RaffleEntry{
+		ItemID:     item.ID,
+		Name:       name,
+		Email:      email,
+		UserID:     donation.UserID,
+		Tickets:    tickets,
+		DonationID: donation.ID,
+		Status:     models.RaffleEntryPending,
+	}
+	if err := tx.Create(entry); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := beginCheckout(c, tx, donation); err != nil {
+		c.Logger().Errorf("[Auction] Failed to start checkout for raffle entry %s: %v", entry.ID.String(), err)
+		c.Flash().Add("error", "Payment system unavailable. Please try again later.")
+		return c.Redirect(http.StatusSeeOther, "/auctions/%s", item.ID)
+	}
+	return nil
+}
+
+// AuctionPaymentHandler takes a winning bidder to the payment page for the
+// item they won
+func AuctionPaymentHandler(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	item := &models.AuctionItem{}
+	if err := tx.Where("donation_id = ?", c.Param("donation_id")).First(item); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if item.Status == models.AuctionStatusPaid {
+		c.Flash().Add("success", fmt.Sprintf("Your payment for %s has already been received. Thank you!", item.Title))
+		return c.Redirect(http.StatusSeeOther, "/auctions")
+	}
+	donation := &models.Donation{}
+	if err := tx.Find(donation, item.DonationID); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	if err := beginCheckout(c, tx, donation); err != nil {
+		c.Logger().Errorf("[Auction] Failed to start checkout for auction item %s: %v", item.ID.String(), err)
+		c.Flash().Add("error", "Payment system unavailable. Please try again later.")
+		return c.Redirect(http.StatusSeeOther, "/auctions")
+	}
+	return nil
+}
+
+// bindAuctionItem copies the admin item form onto item.
+func bindAuctionItem(c buffalo.Context, item *models.AuctionItem) {
+	money := func(name string) float64 {
+		v, _ := strconv.ParseFloat(strings.TrimSpace(c.Param(name)), 64)
+		return v
+	}
+	item.Title = strings.TrimSpace(c.Param("Title"))
+	item.Description = stringPointer(strings.TrimSpace(c.Param("Description")))
+	item.Kind = c.Param("Kind")
+	item.FairMarketValue = money("FairMarketValue")
+	item.StartingBid = money("StartingBid")
+	item.BidIncrement = money("BidIncrement")
+	item.TicketPrice = money("TicketPrice")
+	item.ClosesAt, _ = time.ParseInLocation(datetimeLocalLayout, c.Param("ClosesAt"), time.Local)
+}
+
+// setAuctionFormContext exposes an item to the admin form. Plush can't print
+// the optional *string fields directly.
+func setAuctionFormContext(c buffalo.Context, item *models.AuctionItem) {
+	closesAt := ""
+	if !item.ClosesAt.IsZero() {
+		closesAt = item.ClosesAt.Format(datetimeLocalLayout)
+	}
+	c.Set("item", item)
+	c.Set("itemDescription", item.DescriptionText())
+	c.Set("itemClosesAt", closesAt)
+	c.Set("auctionKinds", models.AuctionKinds)
+}
+
+// AdminAuctionsIndex lists auction and raffle items with their bids and
+// tickets sold
+func AdminAuctionsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	items := models.AuctionItems{}
+	if err := tx.Order("closes_at desc").All(&items); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var bids []struct {
+		ItemID string  `db:"item_id"`
+		Count  int     `db:"count"`
+		High   float64 `db:"high"`
+	}
+	if err := tx.RawQuery("SELECT item_id, COUNT(*) as count, MAX(amount) as high FROM auction_bids GROUP BY item_id").All(&bids); err != nil {
+		return errors.WithStack(err)
+	}
+	var tickets []struct {
+		ItemID string `db:"item_id"`
+		Count  int    `db:"count"`
+	}
+	err := tx.RawQuery("SELECT item_id, SUM(tickets) as count FROM raffle_entries WHERE status = ? GROUP BY item_id", models.RaffleEntryPaid).All(&tickets)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// One summary per item: bid count and high bid, or paid tickets sold
+	summaries := map[string]string{}
+	for _, b := range bids {
+		summaries[b.ItemID] = fmt.Sprintf("%d bids, high $%.2f", b.Count, b.High)
+	}
+	for _, t := range tickets {
+		summaries[t.ItemID] = fmt.Sprintf("%d tickets sold", t.Count)
+	}
+
+	c.Set("items", items)
+	c.Set("summaries", summaries)
+	return c.Render(http.StatusOK, r.HTML("admin/auctions/index.plush.html"))
+}
+
+// AdminAuctionsNew shows the form for adding an auction item or raffle
+func AdminAuctionsNew(c buffalo.Context) error {
+	setAuctionFormContext(c, &models.AuctionItem{Kind: models.AuctionKindAuction})
+	return c.Render(http.StatusOK, r.HTML("admin/auctions/new.plush.html"))
+}
+
+// AdminAuctionsCreate saves a new auction item or raffle
+func AdminAuctionsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	item := &models.AuctionItem{Status: models.AuctionStatusOpen}
+	bindAuctionItem(c, item)
+
+	verrs, err := tx.ValidateAndCreate(item)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		setAuctionFormContext(c, item)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/auctions/new.plush.html"))
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "auction_item_created", fmt.Sprintf("Created %s item: %s", item.Kind, item.Title), logging.Fields{
+		"item_id":           item.ID.String(),
+		"fair_market_value": item.FairMarketValue,
+	})
+
+	opening := "bidding"
+	if item.IsRaffle() {
+		opening = "ticket sales"
+	}
+	c.Flash().Add("success", fmt.Sprintf("\"%s\" is open for %s.", item.Title, opening))
+	return c.Redirect(http.StatusSeeOther, "/admin/auctions/%s", item.ID)
+}
+
+// AdminAuctionsShow shows an item's bids or raffle entries and its winner
+func AdminAuctionsShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	item := &models.AuctionItem{}
+	if err := tx.Find(item, c.Param("item_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	bids := models.AuctionBids{}
+	entries := models.RaffleEntries{}
+	winnerName := ""
+	if item.IsRaffle() {
+		if err := tx.Where("item_id = ?", item.ID).Order("created_at asc").All(&entries); err != nil {
+			return errors.WithStack(err)
+		}
+		for _, e := range entries {
+			if item.WinnerID != nil && e.ID == *item.WinnerID {
+				winnerName = fmt.Sprintf("%s (%s)", e.Name, e.Email)
+			}
+		}
+	} else {
+		if err := tx.Where("item_id = ?", item.ID).Order("amount desc, created_at asc").All(&bids); err != nil {
+			return errors.WithStack(err)
+		}
+		for _, b := range bids {
+			if item.WinnerID != nil && b.ID == *item.WinnerID {
+				winnerName = fmt.Sprintf("%s (%s), $%.2f", b.BidderName, b.BidderEmail, b.Amount)
+			}
+		}
+	}
+
+	c.Set("item", item)
+	c.Set("itemDescription", item.DescriptionText())
+	c.Set("bids", bids)
+	c.Set("entries", entries)
+	c.Set("winnerName", winnerName)
+	return c.Render(http.StatusOK, r.HTML("admin/auctions/show.plush.html"))
+}
+
+// AdminAuctionsAward closes an item and picks its winner: the highest bid
+// for an auction, or a random ticket among those paid for in a raffle. The
+// winner is emailed; auction winners get a link to pay for the item.
+func AdminAuctionsAward(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	item := &models.AuctionItem{}
+	if err := tx.Find(item, c.Param("item_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if item.Status != models.AuctionStatusOpen {
+		c.Flash().Add("warning", "A winner has already been chosen for this item.")
+		return c.Redirect(http.StatusSeeOther, "/admin/auctions/%s", item.ID)
+	}
+
+	var winnerName, winnerEmail string
+	var donation *models.Donation
+	if item.IsRaffle() {
+		entries := models.RaffleEntries{}
+		if err := tx.Where("item_id = ? AND status = ?", item.ID, models.RaffleEntryPaid).Order("created_at asc").All(&entries); err != nil {
+			return errors.WithStack(err)
+		}
+		total := 0
+		for _, e := range entries {
+			total += e.Tickets
+		}
+		if total == 0 {
+			c.Flash().Add("danger", "No paid raffle tickets to draw from.")
+			return c.Redirect(http.StatusSeeOther, "/admin/auctions/%s", item.ID)
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(total)))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		winner := models.PickRaffleWinner(entries, int(n.Int64()))
+		item.WinnerID = &winner.ID
+		winnerName, winnerEmail = winner.Name, winner.Email
+	} else {
+		bid, err := highBid(tx, item)
+		if err != nil {
+			return err
+		}
+		if bid == nil {
+			c.Flash().Add("danger", "No bids have been placed on this item.")
+			return c.Redirect(http.StatusSeeOther, "/admin/auctions/%s", item.ID)
+		}
+
+		// The winner's payment is a donation less the item's fair market value
+		fmv := item.FairMarketValue
+		donation = &models.Donation{
+			DonorName:        bid.BidderName,
+			DonorEmail:       bid.BidderEmail,
+			UserID:           bid.UserID,
+			Amount:           bid.Amount,
+			Currency:         getCurrency(),
+			DonationType:     models.DonationTypeOneTime,
+			Status:           "pending",
+			Comments:         stringPointer("Winning auction bid: " + item.Title),
+			FairMarketValue:  &fmv,
+			GoodsDescription: stringPointer(item.Title + " (auction item)"),
+		}
+		if err := tx.Create(donation); err != nil {
+			return errors.WithStack(err)
+		}
+		item.WinnerID = &bid.ID
+		item.DonationID = &donation.ID
+		winnerName, winnerEmail = bid.BidderName, bid.BidderEmail
+	}
+
+	item.Status = models.AuctionStatusAwarded
+	if err := tx.Update(item); err != nil {
+		return errors.WithStack(err)
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "auction_item_awarded", fmt.Sprintf("Awarded %s to %s", item.Title, winnerName), logging.Fields{
+		"item_id":   item.ID.String(),
+		"winner_id": item.WinnerID.String(),
+		"kind":      item.Kind,
+	})
+
+	data := services.AuctionWinnerData{
+		WinnerName:       winnerName,
+		ItemTitle:        item.Title,
+		Raffle:           item.IsRaffle(),
+		FairMarketValue:  item.FairMarketValue,
+		OrganizationName: organization().Name,
+	}
+	if donation != nil {
+		req := c.Request()
+		scheme := "http"
+		if req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		data.Amount = donation.Amount
+		data.PaymentURL = scheme + "://" + req.Host + "/auctions/pay/" + donation.ID.String()
+	}
+	if err := services.NewEmailService().SendAuctionWinner(winnerEmail, data); err != nil {
+		c.Logger().Errorf("[Auction] Failed to email winner of item %s: %v", item.ID.String(), err)
+		c.Flash().Add("warning", fmt.Sprintf("%s won, but the email could not be sent. Please contact them at %s.", winnerName, winnerEmail))
+	} else {
+		c.Flash().Add("success", fmt.Sprintf("%s won and has been emailed.", winnerName))
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/auctions/%s", item.ID)
+}
@@ -0,0 +1,134 @@
+package actions
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// recordAuditLog is the audit recorder that keeps every UserAction and
+// Audit event in the audit_logs table, with who did it and from where. It
+// writes through models.DB rather than the request's transaction so a
+// failed insert can't abort the action being logged; a failure is only
+// logged.
+func recordAuditLog(c buffalo.Context, entry logging.AuditEntry) {
+	record := models.NewAuditLog(entry.Actor, entry.Action, entry.Details, entry.Fields)
+	if c != nil {
+		if user, ok := c.Value("current_user").(*models.User); ok && user != nil {
+			record.ActorEmail = stringPointer(user.Email)
+		}
+		if c.Request() != nil {
+			record.IPAddress = stringPointer(getClientIP(c))
+		}
+		if id, ok := c.Value("request_id").(string); ok && id != "" {
+			record.RequestID = stringPointer(id)
+		}
+	}
+	if models.DB == nil {
+		return
+	}
+	if err := models.DB.Create(record); err != nil {
+		logging.Error("audit_log_record_failed", err, logging.Fields{
+			"audit_action": entry.Action,
+		})
+	}
+}
+
+// auditLogFilter is the audit trail's search form
+type auditLogFilter struct {
+	Actor  string
+	Action string
+	Target string
+	Search string
+	From   string
+	To     string
+}
+
+// auditLogFilterFromParams reads the filter from the query string, dropping
+// dates that don't parse
+func auditLogFilterFromParams(params buffalo.ParamValues) auditLogFilter {
+	f := auditLogFilter{
+		Actor:  strings.TrimSpace(params.Get("actor")),
+		Action: strings.TrimSpace(params.Get("action")),
+		Target: strings.TrimSpace(params.Get("target")),
+		Search: strings.TrimSpace(params.Get("search")),
+		From:   params.Get("from"),
+		To:     params.Get("to"),
+	}
+	if _, err := time.Parse("2006-01-02", f.From); err != nil {
+		f.From = ""
+	}
+	if _, err := time.Parse("2006-01-02", f.To); err != nil {
+		f.To = ""
+	}
+	return f
+}
+
+// Apply narrows q to the audit entries matching the filter
+func (f auditLogFilter) Apply(q *pop.Query) *pop.Query {
+	if f.Actor != "" {
+		like := "%" + f.Actor + "%"
+		q = q.Where("(actor ILIKE ? OR actor_email ILIKE ?)", like, like)
+	}
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if f.Target != "" {
+		q = q.Where("(target_id = ? OR target_type = ?)", f.Target, f.Target)
+	}
+	if f.Search != "" {
+		like := "%" + f.Search + "%"
+		q = q.Where("(details ILIKE ? OR fields ILIKE ? OR ip_address = ?)", like, like, f.Search)
+	}
+	if f.From != "" {
+		from, _ := time.Parse("2006-01-02", f.From)
+		q = q.Where("created_at >= ?", from)
+	}
+	if f.To != "" {
+		to, _ := time.Parse("2006-01-02", f.To)
+		q = q.Where("created_at < ?", to.AddDate(0, 0, 1))
+	}
+	return q
+}
+
+// Query is the filter as a query string, for pagination links
+func (f auditLogFilter) Query() string {
+	v := url.Values{}
+	for key, value := range map[string]string{"actor": f.Actor, "action": f.Action, "target": f.Target, "search": f.Search, "from": f.From, "to": f.To} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	return v.Encode()
+}
+
+// AdminAuditLog lists the audit trail, newest first, filtered by actor,
+// action, target, text and date
+func AdminAuditLog(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	filter := auditLogFilterFromParams(c.Params())
+
+	q := filter.Apply(tx.PaginateFromParams(c.Params()))
+	entries := models.AuditLogs{}
+	if err := q.Order("created_at desc").All(&entries); err != nil {
+		return errors.WithStack(err)
+	}
+	actions, err := models.AuditActions(tx)
+	if err != nil {
+		return err
+	}
+
+	c.Set("entries", entries)
+	c.Set("auditActions", actions)
+	c.Set("filter", filter)
+	c.Set("pagination", q.Paginator)
+	return c.Render(http.StatusOK, r.HTML("admin/audit_log.plush.html"))
+}
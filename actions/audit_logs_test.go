@@ -0,0 +1,62 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_AuditLogFilterFromParams(t *testing.T) {
+	params := url.Values{
+		"actor":  {" admin@example.com "},
+		"action": {"donation_refunded"},
+		"from":   {"2026-10-01"},
+		"to":     {"not a date"},
+	}
+	f := auditLogFilterFromParams(params)
+	assert.Equal(t, "admin@example.com", f.Actor)
+	assert.Equal(t, "donation_refunded", f.Action)
+	assert.Equal(t, "2026-10-01", f.From)
+	assert.Empty(t, f.To)
+	assert.Equal(t, "action=donation_refunded&actor=admin%40example.com&from=2026-10-01", f.Query())
+}
+
+func Test_AdminAuditLogTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/admin-audit-log-test", func(c buffalo.Context) error {
+		entry := models.NewAuditLog("admin-id", "donation_status_changed", "Changed donation status", map[string]interface{}{
+			"donation_id": "d1",
+			"from":        "pending",
+			"to":          "completed",
+		})
+		entry.ActorEmail = stringPointer("treasurer@example.com")
+		entry.IPAddress = stringPointer("203.0.113.9")
+		entry.CreatedAt = time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+		c.Set("entries", models.AuditLogs{*entry})
+		c.Set("auditActions", []string{"donation_refunded", "donation_status_changed"})
+		c.Set("filter", auditLogFilter{Action: "donation_status_changed"})
+		c.Set("pagination", pop.NewPaginator(1, 25))
+		return c.Render(http.StatusOK, r.HTML("admin/audit_log.plush.html"))
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/admin-audit-log-test", nil))
+	req.Equal(http.StatusOK, res.Code, res.Body.String())
+	body := res.Body.String()
+	req.Contains(body, "treasurer@example.com")
+	req.Contains(body, `<option value="donation_status_changed" selected>`)
+	req.Contains(body, `href="/admin/audit-log?target=d1"`)
+	req.Contains(body, "status: pending → completed")
+	req.Contains(body, "203.0.113.9")
+}
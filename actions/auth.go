@@ -83,6 +83,16 @@ func AuthCreate(c buffalo.Context) error {
 	if err != nil {
 		return bad()
 	}
+	if !u.Active() {
+		logging.SecurityEvent(c, "login_failed", "failure", "user_deactivated", logging.Fields{
+			"user_id": u.ID.String(),
+		})
+		verrs := validate.NewErrors()
+		verrs.Add("email", "This account has been deactivated. Please contact us if you need access.")
+		c.Set("errors", verrs)
+		c.Set("user", &models.User{})
+		return c.Render(http.StatusUnauthorized, r.HTML("auth/new.plush.html"))
+	}
 
 	// Log successful login
 	logging.UserAction(c, u.Email, "login", "User logged in successfully", logging.Fields{
@@ -90,9 +100,6 @@ func AuthCreate(c buffalo.Context) error {
 		"user_role": u.Role,
 	})
 
-	c.Session().Set("current_user_id", u.ID)
-	c.Flash().Add("success", "Welcome Back!")
-
 	// Default redirect based on user role
 	redirectURL := "/"
 	if u.Role == "admin" {
@@ -104,8 +111,11 @@ func AuthCreate(c buffalo.Context) error {
 	if redir, ok := c.Session().Get("redirectURL").(string); ok && redir != "" {
 		redirectURL = redir
 	}
-	// Always clear the redirect URL after use, even if empty
-	c.Session().Delete("redirectURL")
+
+	// Start a fresh session on login to prevent session fixation. This also
+	// drops the redirect URL.
+	rotateSession(c, u.ID, u.Role)
+	c.Flash().Add("success", "Welcome Back!")
 
 	return c.Redirect(http.StatusFound, redirectURL)
 }
@@ -0,0 +1,196 @@
+package actions
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/jobqueue"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// Background job handlers. Their jobs run on the app's worker, which keeps
+// them in the background_jobs table and retries failures with backoff.
+const (
+	jobAPIHookDelivery        = "api_hook_delivery"
+	jobDonationReceipt        = "donation_receipt"
+	jobEmailCampaignSend      = "email_campaign_send"
+	jobEventReminder          = "event_reminder"
+	jobGoogleCalendarSync     = "google_calendar_sync"
+	jobHelcimSubscriptionSync = "helcim_subscription_sync"
+	jobMentorIntroduction     = "mentor_introduction"
+	jobResourceLinkCheck      = "resource_link_check"
+	jobUploadScan             = "upload_scan"
+)
+
+// registerBackgroundJobs maps the app's background jobs to their handlers
+func registerBackgroundJobs(w worker.Worker) error {
+	handlers := map[string]worker.Handler{
+		jobAPIHookDelivery:        deliverAPIHookJob,
+		jobDonationReceipt:        sendDonationReceiptJob,
+		jobEmailCampaignSend:      sendEmailCampaignJob,
+		jobEventReminder:          sendEventReminderJob,
+		jobGoogleCalendarSync:     syncGoogleCalendarJob,
+		jobHelcimSubscriptionSync: syncHelcimSubscriptionJob,
+		jobMentorIntroduction:     sendMentorIntroductionJob,
+		jobResourceLinkCheck:      checkResourceLinksJob,
+		jobUploadScan:             scanUploadJob,
+	}
+	for name, h := range handlers {
+		if err := w.Register(name, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queueJob adds a background job in tx, so it only runs once the changes
+// that called for it are committed. The request itself has gone through by
+// then, so a failure is logged rather than returned.
+func queueJob(tx *pop.Connection, handler string, args worker.Args) {
+	if err := jobqueue.Enqueue(tx, worker.Job{Handler: handler, Args: args}, time.Now()); err != nil {
+		logging.Error("background_job_enqueue_failed", err, logging.Fields{
+			"handler": handler,
+		})
+	}
+}
+
+// jobArg reads a string argument. Job args are stored as JSON, so handlers
+// are only ever given strings.
+func jobArg(args worker.Args, key string) string {
+	s, _ := args[key].(string)
+	return s
+}
+
+// addReceiptNumber numbers the receipt for the charge it's for, keeping the
+// number it was first sent with. A failure only leaves the number off, so
+// the receipt still goes out.
+func addReceiptNumber(tx *pop.Connection, donation *models.Donation, receipt *services.DonationReceiptData) {
+	issued, err := models.IssueDonationReceipt(tx, donation, receipt.TransactionID, receipt.DonationAmount, time.Now())
+	if err != nil {
+		logging.Error("receipt_number_failed", err, logging.Fields{
+			"donation_id": donation.ID.String(),
+		})
+		return
+	}
+	receipt.ReceiptNumber = issued.ReceiptNumber
+}
+
+// flagBlockedReceipt logs a receipt the compliance check held back and
+// marks it blocked, so admins see it on the dashboard and the donation
+func flagBlockedReceipt(tx *pop.Connection, donationID string, receipt services.DonationReceiptData, blocked *services.ReceiptComplianceError) {
+	logging.Error("receipt_compliance_failed", blocked, logging.Fields{
+		"donation_id":    donationID,
+		"receipt_number": receipt.ReceiptNumber,
+		"problems":       strings.Join(blocked.Problems, "; "),
+	})
+	if err := models.SetDonationReceiptCompliance(tx, receipt.ReceiptNumber, blocked.Problems, time.Now()); err != nil {
+		logging.Error("receipt_compliance_flag_failed", err, logging.Fields{
+			"donation_id":    donationID,
+			"receipt_number": receipt.ReceiptNumber,
+		})
+	}
+}
+
+// clearBlockedReceipt clears the blocked mark from a receipt that has now
+// been sent
+func clearBlockedReceipt(tx *pop.Connection, receipt services.DonationReceiptData) {
+	if err := models.SetDonationReceiptCompliance(tx, receipt.ReceiptNumber, nil, time.Now()); err != nil {
+		logging.Error("receipt_compliance_flag_failed", err, logging.Fields{
+			"receipt_number": receipt.ReceiptNumber,
+		})
+	}
+}
+
+// queueReceipt numbers the donor's receipt for donation and queues it to be
+// emailed. Once it's sent it's noted on the donor's timeline as summary.
+func queueReceipt(tx *pop.Connection, donation *models.Donation, receipt services.DonationReceiptData, summary string) {
+	addReceiptNumber(tx, donation, &receipt)
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		logging.Error("receipt_encode_failed", err, logging.Fields{
+			"donation_id": donation.ID.String(),
+		})
+		return
+	}
+	queueJob(tx, jobDonationReceipt, worker.Args{
+		"donation_id": donation.ID.String(),
+		"email":       donation.DonorEmail,
+		"summary":     summary,
+		"receipt":     string(data),
+	})
+}
+
+// sendDonationReceiptJob emails a queued receipt
+func sendDonationReceiptJob(args worker.Args) error {
+	var receipt services.DonationReceiptData
+	if err := json.Unmarshal([]byte(jobArg(args, "receipt")), &receipt); err != nil {
+		return errors.Wrap(err, "decoding receipt")
+	}
+
+	email := jobArg(args, "email")
+	if err := services.NewEmailService().SendDonationReceipt(email, receipt); err != nil {
+		if blocked, ok := services.AsReceiptComplianceError(err); ok {
+			// Trying again won't help until the missing details are filled
+			// in, after which the receipt is resent from the donation's admin
+			// page
+			flagBlockedReceipt(models.DB, jobArg(args, "donation_id"), receipt, blocked)
+			return nil
+		}
+		return err
+	}
+	clearBlockedReceipt(models.DB, receipt)
+
+	var donationID *uuid.UUID
+	if id, err := uuid.FromString(jobArg(args, "donation_id")); err == nil {
+		donationID = &id
+	}
+	recordCommunication(models.DB, email, models.CommunicationReceipt, jobArg(args, "summary"), nil, donationID)
+	return nil
+}
+
+// queueSubscriptionSync queues a refresh of the subscription's status and
+// next billing date from Helcim
+func queueSubscriptionSync(tx *pop.Connection, subscriptionID string) {
+	queueJob(tx, jobHelcimSubscriptionSync, worker.Args{"subscription_id": subscriptionID})
+}
+
+// syncHelcimSubscriptionJob copies a subscription's current state from
+// Helcim onto its donation, see syncSubscriptionStatus. A failed lookup is
+// recorded on the donation before the job is retried.
+func syncHelcimSubscriptionJob(args worker.Args) error {
+	subscriptionID := jobArg(args, "subscription_id")
+	donation := &models.Donation{}
+	err := models.DB.Where("subscription_id = ?", subscriptionID).First(donation)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Not one of ours, e.g. set up directly in Helcim
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "finding donation for subscription %s", subscriptionID)
+	}
+
+	_, err = syncSubscriptionStatus(context.Background(), models.DB, services.NewHelcimClient(), donation, time.Now())
+	return err
+}
+
+// applySubscriptionSync sets the donation's copy of the subscription's state
+// from what Helcim reported
+func applySubscriptionSync(donation *models.Donation, subscription *services.SubscriptionResponse, now time.Time) {
+	donation.SubscriptionStatus = stringPointer(subscription.Status)
+	if !subscription.NextBillingDate.IsZero() {
+		next := subscription.NextBillingDate
+		donation.NextBillingDate = &next
+	}
+	donation.LastStatusSync = &now
+	donation.SyncError = nil
+}
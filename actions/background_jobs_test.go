@@ -0,0 +1,56 @@
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+)
+
+func Test_RegisterBackgroundJobs(t *testing.T) {
+	req := require.New(t)
+
+	w := worker.NewSimple()
+	req.NoError(registerBackgroundJobs(w))
+	// Every handler is registered once, so registering again clashes
+	req.Error(registerBackgroundJobs(w))
+}
+
+func Test_JobArg(t *testing.T) {
+	req := require.New(t)
+
+	args := worker.Args{"donation_id": "abc", "count": 3.0}
+	req.Equal("abc", jobArg(args, "donation_id"))
+	req.Equal("", jobArg(args, "count"))
+	req.Equal("", jobArg(args, "missing"))
+}
+
+func Test_SendDonationReceiptJobRejectsBadReceipt(t *testing.T) {
+	err := sendDonationReceiptJob(worker.Args{"email": "donor@example.com", "receipt": "not json"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "decoding receipt")
+}
+
+func Test_ApplySubscriptionSync(t *testing.T) {
+	req := require.New(t)
+
+	failure := "helcim timeout"
+	donation := &models.Donation{SyncError: &failure}
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	next := time.Date(2026, 11, 14, 0, 0, 0, 0, time.UTC)
+
+	applySubscriptionSync(donation, &services.SubscriptionResponse{Status: "active", NextBillingDate: next}, now)
+	req.Equal("active", *donation.SubscriptionStatus)
+	req.Equal(next, *donation.NextBillingDate)
+	req.Equal(now, *donation.LastStatusSync)
+	req.Nil(donation.SyncError)
+
+	// A zero billing date leaves the one we have
+	applySubscriptionSync(donation, &services.SubscriptionResponse{Status: "paused"}, now)
+	req.Equal("paused", *donation.SubscriptionStatus)
+	req.Equal(next, *donation.NextBillingDate)
+}
@@ -0,0 +1,169 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// Payment methods a donor can pick on the donation form
+const (
+	donationPaymentCard = "card"
+	donationPaymentBank = "bank"
+)
+
+// payByBank reports whether the donor chose to pay by bank transfer (ACH)
+func payByBank(req DonationRequest) bool {
+	return req.PaymentMethod == donationPaymentBank
+}
+
+// paymentMethodErrors returns field errors for the payment method on the
+// donation form. Bank transfers are only taken for one-time gifts, since
+// subscriptions and gift cards are set up to charge a card.
+func paymentMethodErrors(req DonationRequest) map[string]string {
+	errs := map[string]string{}
+	switch req.PaymentMethod {
+	case "", donationPaymentCard:
+	case donationPaymentBank:
+		if req.DonationType != models.DonationTypeOneTime || req.GiftCard == "true" {
+			errs["payment_method"] = "Bank transfers can only be used for one-time donations"
+		}
+	default:
+		errs["payment_method"] = "Please choose how you'd like to pay"
+	}
+	return errs
+}
+
+// applyPaymentMethod records a bank transfer gift on the donation and asks
+// HelcimPay.js for bank account details rather than a card
+func applyPaymentMethod(req DonationRequest, donation *models.Donation, helcimReq *HelcimPayVerifyRequest) {
+	if !payByBank(req) {
+		return
+	}
+	method := models.PaymentMethodBank
+	donation.PaymentMethod = &method
+	helcimReq.PaymentMethod = "ach"
+}
+
+// setPaymentSource puts the token HelcimPay.js saved on a purchase: the bank
+// account for bank transfer gifts, otherwise the card
+func setPaymentSource(req *services.PaymentAPIRequest, donation *models.Donation, token string) {
+	if donation.PaysByBank() {
+		req.BankData = services.BankData{BankToken: token}
+		return
+	}
+	req.CardData = services.CardData{CardToken: token}
+}
+
+// paymentMethodLabel names how a donation is paid on the payment page
+func paymentMethodLabel(donation *models.Donation) string {
+	if donation.PaysByBank() {
+		return "Bank Transfer (ACH)"
+	}
+	return "Credit Card"
+}
+
+// bankSettlement reads the status of a bankTransaction webhook. An ACH debit
+// is settled once the funds arrive and failed if the bank returns or rejects
+// it; anything else means it's still in flight.
+func bankSettlement(status string) (settled bool, failed bool) {
+	switch strings.ToUpper(strings.TrimSpace(status)) {
+	case "SETTLED", "APPROVED", "COMPLETED":
+		return true, false
+	case "RETURNED", "DECLINED", "REJECTED", "FAILED", "CANCELLED", "VOIDED":
+		return false, true
+	}
+	return false, false
+}
+
+// handleBankTransaction moves a bank transfer gift along as Helcim reports
+// on its settlement. A settled gift is completed and receipted; a returned
+// one is marked failed and flagged on the admin dashboard. Gifts that have
+// already settled or failed are left alone, so late or repeated events don't
+// send a second receipt.
+func handleBankTransaction(tx *pop.Connection, data HelcimWebhookData, transactionID string, c buffalo.Context) (string, string, error) {
+	donation := &models.Donation{}
+	err := tx.Where("transaction_id = ? AND payment_method = ?", transactionID, models.PaymentMethodBank).First(donation)
+	if err != nil {
+		c.Logger().Warnf("[Webhook] No bank transfer donation for transaction %s", transactionID)
+		return models.WebhookIgnored, "no matching bank transfer", nil
+	}
+	if donation.Status != models.DonationStatusSettling {
+		return models.WebhookIgnored, "bank transfer already " + donation.Status, nil
+	}
+
+	settled, failed := bankSettlement(data.Status)
+	switch {
+	case settled:
+		donation.Status = "completed"
+		donation.HelcimTransactionID = &transactionID
+		if err := tx.Update(donation); err != nil {
+			return "", "", errors.WithStack(err)
+		}
+		notifyDonationCompleted(tx, donation)
+
+		receipt := webhookReceiptData(donation, transactionID)
+		addThankYouToReceipt(tx, donation, &receipt)
+		queueReceipt(tx, donation, receipt, receiptSummary(donation))
+		logging.Audit("bank_transfer_settled", logging.Fields{
+			"donation_id":    donation.ID.String(),
+			"transaction_id": transactionID,
+			"amount":         donation.Amount,
+		})
+	case failed:
+		reason := fmt.Sprintf("Bank transfer %s", strings.ToLower(strings.TrimSpace(data.Status)))
+		now := time.Now()
+		donation.Status = "failed"
+		donation.PaymentFailureReason = &reason
+		donation.LastPaymentAttempt = &now
+		if err := tx.Update(donation); err != nil {
+			return "", "", errors.WithStack(err)
+		}
+		logging.Warn("bank_transfer_failed", logging.Fields{
+			"donation_id":    donation.ID.String(),
+			"transaction_id": transactionID,
+			"status":         data.Status,
+		})
+		publishAdminActivity(activityReview, fmt.Sprintf("%s from %s", donationTitle(*donation), donation.DonorName), reason, fmt.Sprintf("/admin/donations/%s", donation.ID))
+	default:
+		return models.WebhookIgnored, "bank transfer still settling", nil
+	}
+	return models.WebhookProcessed, "", nil
+}
+
+// startBankTransfer records a submitted bank transfer gift as settling. It
+// isn't receipted, and gift codes and orders it pays for aren't released,
+// until the bankTransaction webhook reports it settled.
+func startBankTransfer(c buffalo.Context, donation *models.Donation, transactionID, customerCode string) error {
+	donation.TransactionID = &transactionID
+	donation.CustomerID = &customerCode
+	donation.Status = models.DonationStatusSettling
+
+	tx := c.Value("tx").(*pop.Connection)
+	if err := tx.Update(donation); err != nil {
+		c.Logger().Errorf("[BankTransfer] Failed to update donation %s: %v", donation.ID.String(), err)
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to update donation")
+	}
+	logging.Audit("bank_transfer_submitted", logging.Fields{
+		"donation_id":    donation.ID.String(),
+		"transaction_id": transactionID,
+		"amount":         donation.Amount,
+	})
+
+	return c.Render(http.StatusOK, r.JSON(map[string]interface{}{
+		"success":       true,
+		"status":        models.DonationStatusSettling,
+		"transactionId": transactionID,
+		"type":          "one-time",
+		"message":       "Thank you! Your bank transfer has been submitted and usually settles in 3-5 business days. We'll email your receipt once it does.",
+	}))
+}
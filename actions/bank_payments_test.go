@@ -0,0 +1,80 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+)
+
+func Test_PaymentMethodErrors(t *testing.T) {
+	r := require.New(t)
+
+	r.Empty(paymentMethodErrors(DonationRequest{DonationType: "monthly"}))
+	r.Empty(paymentMethodErrors(DonationRequest{DonationType: "one-time", PaymentMethod: "bank"}))
+
+	r.Contains(paymentMethodErrors(DonationRequest{DonationType: "monthly", PaymentMethod: "bank"}), "payment_method")
+	r.Contains(paymentMethodErrors(DonationRequest{DonationType: "one-time", PaymentMethod: "bank", GiftCard: "true"}), "payment_method")
+	r.Contains(paymentMethodErrors(DonationRequest{DonationType: "one-time", PaymentMethod: "cheque"}), "payment_method")
+}
+
+func Test_ApplyPaymentMethod(t *testing.T) {
+	r := require.New(t)
+
+	donation := &models.Donation{}
+	helcimReq := HelcimPayVerifyRequest{}
+	applyPaymentMethod(DonationRequest{PaymentMethod: "card"}, donation, &helcimReq)
+	r.False(donation.PaysByBank())
+	r.Empty(helcimReq.PaymentMethod)
+
+	applyPaymentMethod(DonationRequest{PaymentMethod: "bank"}, donation, &helcimReq)
+	r.True(donation.PaysByBank())
+	r.Equal("ach", helcimReq.PaymentMethod)
+	r.Equal("Bank Transfer (ACH)", paymentMethodLabel(donation))
+
+	paymentReq := services.PaymentAPIRequest{}
+	setPaymentSource(&paymentReq, donation, "bank_tok")
+	r.Equal("bank_tok", paymentReq.BankData.BankToken)
+	r.Empty(paymentReq.CardData.CardToken)
+}
+
+func Test_BankSettlement(t *testing.T) {
+	r := require.New(t)
+
+	settled, failed := bankSettlement("SETTLED")
+	r.True(settled)
+	r.False(failed)
+
+	settled, failed = bankSettlement("returned")
+	r.False(settled)
+	r.True(failed)
+
+	settled, failed = bankSettlement("PENDING")
+	r.False(settled)
+	r.False(failed)
+}
+
+func Test_DonationSuccessSettlingNotice(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/donate/success", func(c buffalo.Context) error {
+		setThankYouContext(c)
+		return c.Render(http.StatusOK, r.HTML("pages/donation_success.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/donate/success?status=settling", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Equal(1, strings.Count(w.Body.String(), "Your Bank Transfer Is On Its Way"))
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/donate/success", nil))
+	req.NotContains(w.Body.String(), "Your Bank Transfer Is On Its Way")
+}
@@ -0,0 +1,189 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/pkg/logging"
+)
+
+// multipartMemory is how much of a multipart body is held in memory while
+// it's parsed; files beyond it are streamed to temporary files, which the
+// server removes once the request is done
+const multipartMemory = 1 << 20
+
+// Request bodies are limited by kind unless their route has its own limit.
+// Each can be changed with REQUEST_LIMIT_<KIND>, e.g. REQUEST_LIMIT_JSON=2MB.
+var bodyLimitDefaults = map[string]int64{
+	"json":   1 << 20,
+	"form":   1 << 20,
+	"upload": 10 << 20,
+}
+
+// bodyLimitedRoute is a route whose request body is held to its own limit:
+// a small one for the public donation and contact forms, and room for the
+// files on upload forms. Its limit can be changed with REQUEST_LIMIT_<NAME>,
+// e.g. REQUEST_LIMIT_MEDIA=50MB.
+type bodyLimitedRoute struct {
+	Name    string
+	Method  string
+	Path    string
+	Default int64
+}
+
+var bodyLimitedRoutes = []bodyLimitedRoute{
+	{Name: "donations", Method: http.MethodPost, Path: "/api/donations/initialize", Default: 64 << 10},
+	{Name: "payments", Method: http.MethodPost, Path: "/api/donations/process", Default: 64 << 10},
+	{Name: "donate", Method: http.MethodPost, Path: "/donate", Default: 64 << 10},
+	{Name: "contact", Method: http.MethodPost, Path: "/contact", Default: 64 << 10},
+	{Name: "csp_report", Method: http.MethodPost, Path: "/csp-report", Default: 64 << 10},
+	{Name: "vehicle", Method: http.MethodPost, Path: "/donate/vehicle", Default: maxVehiclePhotos*maxVehiclePhotoSize + 1<<20},
+	{Name: "media", Method: http.MethodPost, Path: "/admin/media", Default: maxMediaSize + 1<<20},
+}
+
+// bodyLimits are the request body limits by kind and by route, the routes
+// keyed as rateLimitKey does
+type bodyLimits struct {
+	Kinds  map[string]int64
+	Routes map[string]int64
+}
+
+// bodyLimitsFromEnv reads the body limits, keeping the default for any that
+// fail to parse
+func bodyLimitsFromEnv() bodyLimits {
+	limit := func(name string, def int64) int64 {
+		value := strings.TrimSpace(envy.Get("REQUEST_LIMIT_"+strings.ToUpper(name), ""))
+		if value == "" {
+			return def
+		}
+		n, err := parseByteSize(value)
+		if err != nil {
+			logging.Warn("Invalid request size limit, using the default", logging.Fields{
+				"limit":   name,
+				"value":   value,
+				"default": def,
+			})
+			return def
+		}
+		return n
+	}
+
+	limits := bodyLimits{Kinds: map[string]int64{}, Routes: map[string]int64{}}
+	for kind, def := range bodyLimitDefaults {
+		limits.Kinds[kind] = limit(kind, def)
+	}
+	for _, route := range bodyLimitedRoutes {
+		limits.Routes[rateLimitKey(route.Method, route.Path)] = limit(route.Name, route.Default)
+	}
+	return limits
+}
+
+// parseByteSize reads a size such as "512KB", "25MB" or a plain number of
+// bytes
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		Suffix string
+		Bytes  int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.Suffix) {
+			s, multiplier = strings.TrimSpace(strings.TrimSuffix(s, unit.Suffix)), unit.Bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
+
+// limitFor is the most a request may send: its route's limit if it has one,
+// otherwise the limit for its kind of body
+func (l bodyLimits) limitFor(req *http.Request) int64 {
+	if n, ok := l.Routes[rateLimitKey(req.Method, req.URL.Path)]; ok {
+		return n
+	}
+	contentType := req.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "multipart/"):
+		return l.Kinds["upload"]
+	case strings.Contains(contentType, "json"):
+		return l.Kinds["json"]
+	}
+	return l.Kinds["form"]
+}
+
+// limitedBody is a request body cut off at its limit, which remembers
+// whether the client tried to send more
+type limitedBody struct {
+	io.ReadCloser
+	limit    int64
+	declared int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// MethodOverride caps the request body before Buffalo's method override
+// reads the form, which happens before routing and so before any
+// middleware. Requests that declare a larger Content-Length aren't read at
+// all. Multipart bodies are parsed with files past multipartMemory streamed
+// to disk, so nothing after (CSRF checks, Bind, c.File) reads them into
+// memory whole. BodyLimits turns an oversized body into a 413.
+func (l bodyLimits) MethodOverride(res http.ResponseWriter, req *http.Request) {
+	if req.Body == nil || req.Body == http.NoBody {
+		buffalo.MethodOverride(res, req)
+		return
+	}
+	limit := l.limitFor(req)
+	body := &limitedBody{ReadCloser: http.MaxBytesReader(res, req.Body, limit), limit: limit, declared: req.ContentLength}
+	req.Body = body
+	if req.ContentLength > limit {
+		body.exceeded = true
+		return
+	}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		// An error here is reading the body, which the handler will hit too
+		_ = req.ParseMultipartForm(multipartMemory)
+	}
+	buffalo.MethodOverride(res, req)
+}
+
+// BodyLimits answers requests whose body went over the limit
+// bodyLimits.MethodOverride put on it with a 413, whether that was found
+// before the handler ran or while it read the body
+func BodyLimits(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		body, ok := c.Request().Body.(*limitedBody)
+		if !ok {
+			return next(c)
+		}
+		if !body.exceeded {
+			if err := next(c); !body.exceeded {
+				return err
+			}
+		}
+		logging.SecurityEvent(c, "request_too_large", "blocked", "body_limit", logging.Fields{
+			"limit":          body.limit,
+			"content_length": body.declared,
+			"path":           c.Request().URL.Path,
+		})
+		return c.Error(http.StatusRequestEntityTooLarge, fmt.Errorf("request body over %d bytes", body.limit))
+	}
+}
@@ -0,0 +1,107 @@
+package actions
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseByteSize(t *testing.T) {
+	for in, want := range map[string]int64{"512": 512, "64KB": 64 << 10, "25 mb": 25 << 20, "1GB": 1 << 30, "100B": 100} {
+		n, err := parseByteSize(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, n, in)
+	}
+	for _, in := range []string{"", "lots", "-1MB", "0"} {
+		_, err := parseByteSize(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func Test_BodyLimitsFromEnv(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("REQUEST_LIMIT_JSON", "2MB")
+		envy.Set("REQUEST_LIMIT_CONTACT", "a lot")
+
+		limits := bodyLimitsFromEnv()
+		assert.Equal(t, int64(2<<20), limits.Kinds["json"])
+		assert.Equal(t, int64(10<<20), limits.Kinds["upload"])
+		assert.Equal(t, int64(64<<10), limits.Routes["POST /contact"])
+		assert.Equal(t, int64(maxMediaSize+1<<20), limits.Routes["POST /admin/media"])
+	})
+}
+
+func Test_BodyLimits(t *testing.T) {
+	limits := bodyLimits{
+		Kinds:  map[string]int64{"json": 100, "form": 100, "upload": 2 << 20},
+		Routes: map[string]int64{"POST /contact": 20},
+	}
+	app := buffalo.New(buffalo.Options{Env: "test", MethodOverride: limits.MethodOverride})
+	app.Use(BodyLimits)
+	app.POST("/contact", func(c buffalo.Context) error {
+		_, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		return c.Render(http.StatusOK, nil)
+	})
+	app.POST("/api/echo", func(c buffalo.Context) error {
+		var body map[string]string
+		if err := c.Bind(&body); err != nil {
+			return err
+		}
+		return c.Render(http.StatusOK, r.JSON(body))
+	})
+	app.POST("/upload", func(c buffalo.Context) error {
+		f, err := c.File("file")
+		if err != nil {
+			return err
+		}
+		return c.Render(http.StatusOK, r.String(f.Filename))
+	})
+
+	send := func(path, contentType string, body io.Reader, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, body)
+		req.Header.Set("Content-Type", contentType)
+		if chunked {
+			// A client that doesn't declare its length is cut off as it reads
+			req.ContentLength = -1
+		}
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	assert.Equal(t, http.StatusOK, send("/contact", "application/x-www-form-urlencoded", strings.NewReader("Name=Jo"), false).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send("/contact", "application/x-www-form-urlencoded", strings.NewReader(strings.Repeat("a", 21)), false).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send("/contact", "application/x-www-form-urlencoded", strings.NewReader(strings.Repeat("a", 21)), true).Code)
+
+	assert.Equal(t, http.StatusOK, send("/api/echo", "application/json", strings.NewReader(`{"a":"b"}`), false).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send("/api/echo", "application/json", strings.NewReader(`{"a":"`+strings.Repeat("b", 200)+`"}`), true).Code)
+
+	upload := func(size int) (*bytes.Buffer, string) {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		part, err := w.CreateFormFile("file", "scan.pdf")
+		require.NoError(t, err)
+		_, err = part.Write(bytes.Repeat([]byte("x"), size))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return &buf, w.FormDataContentType()
+	}
+	body, contentType := upload(3 << 19)
+	res := send("/upload", contentType, body, true)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "scan.pdf", res.Body.String())
+	body, contentType = upload(3 << 20)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send("/upload", contentType, body, true).Code)
+}
@@ -0,0 +1,207 @@
+package actions
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// campaignModeTTL is how long campaign mode is kept in memory before it's
+// read again. It's short so a scheduled campaign starts and ends on time and
+// other instances pick up a change made on one.
+const campaignModeTTL = time.Minute
+
+// campaignCacheFactor is how many times longer cached pages and stats are
+// kept while a campaign is live, when traffic spikes and they change little
+const campaignCacheFactor = 4
+
+// campaignModeCache holds campaign mode for the site layout, which checks it
+// on every page
+var campaignModeCache struct {
+	mu       sync.RWMutex
+	mode     models.CampaignMode
+	loadedAt time.Time
+	live     bool
+}
+
+// campaignMode is the saved campaign mode. When it can't be read campaign
+// mode is off, so pages still render.
+func campaignMode() models.CampaignMode {
+	campaignModeCache.mu.RLock()
+	if !campaignModeCache.loadedAt.IsZero() && time.Since(campaignModeCache.loadedAt) < campaignModeTTL {
+		defer campaignModeCache.mu.RUnlock()
+		return campaignModeCache.mode
+	}
+	campaignModeCache.mu.RUnlock()
+
+	mode, err := models.LoadCampaignMode(models.DB)
+	if err != nil {
+		logging.Error("campaign_mode_load_failed", err)
+	}
+	if setCampaignMode(mode, time.Now()) {
+		// A scheduled campaign just started
+		prewarmCampaignCaches(models.DB, mode, time.Now())
+	}
+	return mode
+}
+
+// setCampaignMode replaces campaign mode held in memory, reporting whether
+// that made the campaign go live
+func setCampaignMode(mode models.CampaignMode, now time.Time) bool {
+	campaignModeCache.mu.Lock()
+	defer campaignModeCache.mu.Unlock()
+	wasLive := campaignModeCache.live
+	campaignModeCache.mode = mode
+	campaignModeCache.loadedAt = now
+	campaignModeCache.live = mode.Live(now)
+	return campaignModeCache.live && !wasLive
+}
+
+// liveCampaign is the campaign showing now, or nil outside campaign mode.
+// Templates use it to swap in the campaign hero and banner.
+func liveCampaign() *models.CampaignMode {
+	mode := campaignMode()
+	if !mode.Live(time.Now()) {
+		return nil
+	}
+	return &mode
+}
+
+// cacheTTL is how long something normally cached for ttl is kept, stretched
+// by campaignCacheFactor while a campaign is live. Call it before taking a
+// cache's lock, as the first check after a campaign starts pre-warms the
+// caches.
+func cacheTTL(ttl time.Duration) time.Duration {
+	if liveCampaign() != nil {
+		return ttl * campaignCacheFactor
+	}
+	return ttl
+}
+
+// prewarmCampaignCaches loads what every campaign visitor's first page
+// needs, so the rush as a campaign starts is served from memory rather than
+// all landing on the database at once. Failures are logged; the caches fill
+// on first use instead.
+func prewarmCampaignCaches(tx *pop.Connection, mode models.CampaignMode, now time.Time) {
+	profile, err := models.LoadOrganizationProfile(tx)
+	if err != nil {
+		logging.Error("campaign_prewarm_organization_failed", err)
+	} else {
+		setOrganization(profile)
+	}
+	clearPublicStatsCache()
+	if _, err := loadPublicStats(tx, now); err != nil {
+		logging.Error("campaign_prewarm_public_stats_failed", err)
+	}
+	logging.Audit("campaign_caches_prewarmed", logging.Fields{
+		"campaign": mode.Name,
+	})
+}
+
+// setCampaignModeFormContext exposes campaign mode to the admin form. Plush
+// can't print the optional *time.Time fields directly.
+func setCampaignModeFormContext(c buffalo.Context, mode models.CampaignMode) {
+	startsAt := ""
+	if mode.StartsAt != nil {
+		startsAt = mode.StartsAt.Format(datetimeLocalLayout)
+	}
+	endsAt := ""
+	if mode.EndsAt != nil {
+		endsAt = mode.EndsAt.Format(datetimeLocalLayout)
+	}
+	c.Set("campaign", mode)
+	c.Set("campaignStatus", mode.StatusText(time.Now()))
+	c.Set("campaignStartsAt", startsAt)
+	c.Set("campaignEndsAt", endsAt)
+}
+
+// bindCampaignMode copies the admin form's fields onto mode
+func bindCampaignMode(c buffalo.Context, mode *models.CampaignMode) {
+	mode.Enabled = c.Param("Enabled") == "true"
+	mode.Name = strings.TrimSpace(c.Param("Name"))
+	mode.Headline = strings.TrimSpace(c.Param("Headline"))
+	mode.Message = strings.TrimSpace(c.Param("Message"))
+	mode.BannerText = strings.TrimSpace(c.Param("BannerText"))
+	mode.ButtonText = strings.TrimSpace(c.Param("ButtonText"))
+	mode.DonateURL = strings.TrimSpace(c.Param("DonateURL"))
+	if mode.ButtonText == "" {
+		mode.ButtonText = models.DefaultCampaignMode().ButtonText
+	}
+	if mode.DonateURL == "" {
+		mode.DonateURL = models.DefaultCampaignMode().DonateURL
+	}
+	mode.StartsAt = nil
+	if startsAt, err := time.ParseInLocation(datetimeLocalLayout, c.Param("StartsAt"), time.Local); err == nil {
+		mode.StartsAt = &startsAt
+	}
+	mode.EndsAt = nil
+	if endsAt, err := time.ParseInLocation(datetimeLocalLayout, c.Param("EndsAt"), time.Local); err == nil {
+		mode.EndsAt = &endsAt
+	}
+}
+
+// AdminCampaignMode shows the campaign mode form
+func AdminCampaignMode(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	mode, err := models.LoadCampaignMode(tx)
+	if err != nil {
+		return err
+	}
+	setCampaignModeFormContext(c, mode)
+	return c.Render(http.StatusOK, r.HTML("admin/campaign.plush.html"))
+}
+
+// AdminCampaignModeUpdate saves campaign mode. The site switches over
+// straight away on this instance, and within campaignModeTTL on others.
+// Switching a campaign live pre-warms the caches it leans on.
+func AdminCampaignModeUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	mode, err := models.LoadCampaignMode(tx)
+	if err != nil {
+		return err
+	}
+	wasEnabled := mode.Enabled
+	bindCampaignMode(c, &mode)
+	mode.UpdatedBy = &currentUser.ID
+
+	verrs, err := tx.ValidateAndSave(&mode)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		setCampaignModeFormContext(c, mode)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/campaign.plush.html"))
+	}
+	now := time.Now()
+	if setCampaignMode(mode, now) {
+		prewarmCampaignCaches(tx, mode, now)
+	}
+
+	logging.UserAction(c, currentUser.Email, "campaign_mode_updated", "Updated campaign mode", logging.Fields{
+		"campaign": mode.Name,
+		"from":     wasEnabled,
+		"to":       mode.Enabled,
+		"status":   mode.StatusText(now),
+	})
+	switch mode.StatusText(now) {
+	case "Live":
+		c.Flash().Add("success", "Campaign mode is live. The homepage and banner now show "+mode.Name+".")
+	case "Scheduled":
+		c.Flash().Add("success", "Campaign mode is scheduled and will switch on at "+mode.StartsAt.Format("Jan 2 at 3:04 PM")+".")
+	default:
+		c.Flash().Add("success", "Campaign mode saved. The site is showing its usual homepage.")
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/campaign")
+}
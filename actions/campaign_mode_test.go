@@ -0,0 +1,90 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+// givingTuesday is a campaign that's live now
+func givingTuesday() models.CampaignMode {
+	mode := models.DefaultCampaignMode()
+	mode.Enabled = true
+	mode.Name = "Giving Tuesday"
+	mode.Headline = "Double your impact today"
+	mode.Message = "Every gift is matched up to $10,000."
+	mode.BannerText = "Gifts are matched today only"
+	return mode
+}
+
+func Test_CampaignModeCacheTTL(t *testing.T) {
+	req := require.New(t)
+	defer setCampaignMode(models.DefaultCampaignMode(), time.Now())
+
+	setCampaignMode(models.DefaultCampaignMode(), time.Now())
+	req.Nil(liveCampaign())
+	req.Equal(10*time.Minute, cacheTTL(10*time.Minute))
+
+	// Going live is reported once, so caches are pre-warmed once
+	req.True(setCampaignMode(givingTuesday(), time.Now()))
+	req.False(setCampaignMode(givingTuesday(), time.Now()))
+	req.NotNil(liveCampaign())
+	req.Equal(40*time.Minute, cacheTTL(10*time.Minute))
+
+	// A campaign scheduled for later isn't live yet
+	later := time.Now().Add(time.Hour)
+	scheduled := givingTuesday()
+	scheduled.StartsAt = &later
+	req.False(setCampaignMode(scheduled, time.Now()))
+	req.Nil(liveCampaign())
+}
+
+func Test_CampaignModeTemplatesRendering(t *testing.T) {
+	req := require.New(t)
+	defer setCampaignMode(models.DefaultCampaignMode(), time.Now())
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/home-test", func(c buffalo.Context) error {
+		c.Set("impactStats", []publicStatValue{})
+		return c.Render(http.StatusOK, r.HTML("home/index.plush.html"))
+	})
+	app.GET("/admin-campaign-test", func(c buffalo.Context) error {
+		setCampaignModeFormContext(c, givingTuesday())
+		return c.Render(http.StatusOK, r.HTML("admin/campaign.plush.html"))
+	})
+
+	setCampaignMode(models.DefaultCampaignMode(), time.Now())
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/home-test", nil))
+	body := res.Body.String()
+	req.Equal(http.StatusOK, res.Code, body)
+	req.Contains(body, "THE AVR MISSION")
+	req.NotContains(body, `class="campaign-banner"`)
+
+	setCampaignMode(givingTuesday(), time.Now())
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/home-test", nil))
+	body = res.Body.String()
+	req.Equal(http.StatusOK, res.Code, body)
+	req.NotContains(body, "THE AVR MISSION")
+	req.Contains(body, "Double your impact today")
+	req.Contains(body, "Every gift is matched up to $10,000.")
+	req.Contains(body, `class="campaign-banner"`)
+	req.Contains(body, "Gifts are matched today only")
+	req.Contains(body, `<a href="/donate" role="button">Donate now</a>`)
+
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/admin-campaign-test", nil))
+	body = res.Body.String()
+	req.Equal(http.StatusOK, res.Code, body)
+	req.Contains(body, "<strong>Status:</strong> Live")
+	req.Contains(body, `name="Headline" value="Double your impact today"`)
+	req.Contains(body, `name="Enabled" value="true" checked`)
+	req.Contains(body, "4× longer")
+}
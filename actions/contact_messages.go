@@ -0,0 +1,285 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/helpers"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// defaultContactReplySLA is how long a contact message can wait for a reply
+// before staff are reminded about it
+const defaultContactReplySLA = 48 * time.Hour
+
+// contactReplySLA is the reply deadline for contact messages, configurable
+// with CONTACT_REPLY_SLA (e.g. "24h"). Messages unanswered for twice as long
+// are escalated.
+func contactReplySLA() time.Duration {
+	if d, err := time.ParseDuration(envy.Get("CONTACT_REPLY_SLA", "")); err == nil && d > 0 {
+		return d
+	}
+	return defaultContactReplySLA
+}
+
+// contactEscalationRecipients is who hears about messages still unanswered
+// after the reminder: the comma-separated CONTACT_ESCALATION_EMAIL, or the
+// general contact address when that's unset
+func contactEscalationRecipients(emailService *services.EmailService) []string {
+	var recipients []string
+	for _, addr := range strings.Split(envy.Get("CONTACT_ESCALATION_EMAIL", ""), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			recipients = append(recipients, addr)
+		}
+	}
+	if len(recipients) == 0 {
+		recipients = []string{emailService.ContactEmail}
+	}
+	return recipients
+}
+
+// siteURL is the site's public address, for links in emails sent outside a
+// request
+func siteURL() string {
+	return strings.TrimSuffix(envy.Get("SITE_URL", "https://avrnpo.org"), "/")
+}
+
+// waitingLabel describes how long a message has waited, in days once it's
+// been a day or more
+func waitingLabel(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return helpers.Pluralize(int(d/(24*time.Hour)), "day")
+	}
+	return helpers.Pluralize(int(d/time.Hour), "hour")
+}
+
+// contactSLAStats are the reply SLA figures on the admin dashboard
+type contactSLAStats struct {
+	SLA        time.Duration
+	Unanswered int
+	Overdue    int
+	Answered   int     `db:"answered"`
+	WithinSLA  int     `db:"within_sla"`
+	AvgSeconds float64 `db:"avg_seconds"`
+}
+
+// contactSLAWindow is how far back the dashboard's reply figures look
+const contactSLAWindow = 30 * 24 * time.Hour
+
+// SLALabel describes the reply deadline
+func (s contactSLAStats) SLALabel() string {
+	return waitingLabel(s.SLA)
+}
+
+// WithinSLAPercent is the share of recent replies sent within the SLA
+func (s contactSLAStats) WithinSLAPercent() int {
+	if s.Answered == 0 {
+		return 100
+	}
+	return s.WithinSLA * 100 / s.Answered
+}
+
+// AverageResponse describes the average time to reply to recent messages
+func (s contactSLAStats) AverageResponse() string {
+	if s.Answered == 0 {
+		return "—"
+	}
+	return waitingLabel(time.Duration(s.AvgSeconds) * time.Second)
+}
+
+// loadContactSLAStats counts unanswered and overdue messages and summarizes
+// replies sent over the last 30 days
+func loadContactSLAStats(tx *pop.Connection, sla time.Duration, now time.Time) (contactSLAStats, error) {
+	stats := contactSLAStats{}
+	err := tx.RawQuery(`SELECT COUNT(*) AS answered,
+			COUNT(*) FILTER (WHERE replied_at <= created_at + (? * interval '1 second')) AS within_sla,
+			COALESCE(AVG(EXTRACT(EPOCH FROM replied_at - created_at)), 0) AS avg_seconds
+		FROM contact_messages WHERE replied_at >= ?`, sla.Seconds(), now.Add(-contactSLAWindow)).First(&stats)
+	if err != nil {
+		return stats, errors.WithStack(err)
+	}
+	stats.SLA = sla
+	if stats.Unanswered, err = tx.Where("replied_at IS NULL").Count(&models.ContactMessage{}); err != nil {
+		return stats, errors.WithStack(err)
+	}
+	if stats.Overdue, err = tx.Where("replied_at IS NULL AND created_at < ?", now.Add(-sla)).Count(&models.ContactMessage{}); err != nil {
+		return stats, errors.WithStack(err)
+	}
+	return stats, nil
+}
+
+// EscalateUnansweredMessages reminds a message's recipients once it goes
+// unanswered past the reply SLA, and tells the escalation contact once it
+// goes twice as long. It's run from cron through the messages:escalate task
+// and returns how many reminders and escalations were sent.
+func EscalateUnansweredMessages(tx *pop.Connection, now time.Time) (reminded, escalated int, err error) {
+	sla := contactReplySLA()
+	messages := models.ContactMessages{}
+	err = tx.Where("replied_at IS NULL AND ((reminded_at IS NULL AND created_at < ?) OR (escalated_at IS NULL AND created_at < ?))",
+		now.Add(-sla), now.Add(-2*sla)).Order("created_at").All(&messages)
+	if err != nil {
+		return 0, 0, errors.WithStack(err)
+	}
+
+	emailService := services.NewEmailService()
+	for i := range messages {
+		msg := &messages[i]
+		data := services.ContactReminderData{
+			Name:       msg.Name,
+			Email:      msg.Email,
+			Subject:    msg.Subject,
+			TopicLabel: msg.TopicLabel(),
+			ReceivedAt: msg.CreatedAt,
+			Waiting:    waitingLabel(now.Sub(msg.CreatedAt)),
+			InboxURL:   fmt.Sprintf("%s/admin/messages/%s", siteURL(), msg.ID),
+		}
+
+		// A message that's already due for escalation skips the reminder,
+		// so a missed run doesn't send both at once
+		recipients := emailService.ContactRecipients(msg.Topic)
+		if msg.EscalationDue(sla, now) {
+			data.Escalation = true
+			recipients = contactEscalationRecipients(emailService)
+		}
+
+		if err := emailService.SendContactReminder(recipients, data); err != nil {
+			logging.Error("contact_reminder_failed", err, logging.Fields{
+				"message_id": msg.ID.String(),
+				"escalation": data.Escalation,
+			})
+			continue
+		}
+		if msg.RemindedAt == nil {
+			msg.RemindedAt = &now
+		}
+		if data.Escalation {
+			msg.EscalatedAt = &now
+			escalated++
+		} else {
+			reminded++
+		}
+		if err := tx.Update(msg); err != nil {
+			return reminded, escalated, errors.WithStack(err)
+		}
+	}
+	return reminded, escalated, nil
+}
+
+// AdminContactMessagesIndex is the inbox of contact form messages, newest
+// first, optionally narrowed to one topic or to messages awaiting a reply
+func AdminContactMessagesIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	topic := c.Param("topic")
+	if !models.ValidContactTopic(topic) {
+		topic = ""
+	}
+	unanswered := c.Param("unanswered") == "1"
+
+	q := tx.PaginateFromParams(c.Params())
+	if topic != "" {
+		q = q.Where("topic = ?", topic)
+	}
+	if unanswered {
+		q = q.Where("replied_at IS NULL")
+	}
+	messages := models.ContactMessages{}
+	if err := q.Order("created_at desc").All(&messages); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var rows []struct {
+		Topic string `db:"topic"`
+		Count int    `db:"count"`
+	}
+	if err := tx.RawQuery("SELECT topic, COUNT(*) as count FROM contact_messages GROUP BY topic").All(&rows); err != nil {
+		return errors.WithStack(err)
+	}
+	counts := map[string]int{}
+	for _, t := range models.ContactTopics {
+		counts[t] = 0
+	}
+	for _, row := range rows {
+		counts[row.Topic] = row.Count
+	}
+
+	c.Set("messages", messages)
+	c.Set("topic", topic)
+	c.Set("unanswered", unanswered)
+	c.Set("topicCounts", counts)
+	c.Set("pagination", q.Paginator)
+	setContactInboxContext(c)
+	return c.Render(http.StatusOK, r.HTML("admin/messages/index.plush.html"))
+}
+
+// setContactInboxContext sets the topic choices and reply SLA for the inbox
+// templates
+func setContactInboxContext(c buffalo.Context) {
+	setContactFormContext(c)
+	c.Set("replySLA", contactReplySLA())
+	c.Set("waitingLabel", waitingLabel)
+	c.Set("now", time.Now())
+}
+
+// findContactMessage loads the message named in the URL
+func findContactMessage(c buffalo.Context) (*models.ContactMessage, error) {
+	tx := c.Value("tx").(*pop.Connection)
+	msg := &models.ContactMessage{}
+	if err := tx.Find(msg, c.Param("message_id")); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// AdminContactMessageShow shows a contact message, marking it read
+func AdminContactMessageShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	msg, err := findContactMessage(c)
+	if err != nil {
+		c.Flash().Add("error", "Message not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/messages")
+	}
+	if msg.ReadAt == nil {
+		msg.MarkRead(time.Now())
+		if err := tx.Update(msg); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	c.Set("message", msg)
+	setContactInboxContext(c)
+	return c.Render(http.StatusOK, r.HTML("admin/messages/show.plush.html"))
+}
+
+// AdminContactMessageReplied records that staff answered a contact message,
+// which stops its reminders
+func AdminContactMessageReplied(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	msg, err := findContactMessage(c)
+	if err != nil {
+		c.Flash().Add("error", "Message not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/messages")
+	}
+	msg.MarkReplied(time.Now())
+	if err := tx.Update(msg); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.UserAction(c, user.Email, "contact_message_replied", "Marked contact message replied", logging.Fields{
+		"message_id": msg.ID.String(),
+	})
+	c.Flash().Add("success", fmt.Sprintf("Marked the message from %s as replied.", msg.Name))
+	return c.Redirect(http.StatusSeeOther, "/admin/messages/%s", msg.ID)
+}
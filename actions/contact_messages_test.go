@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_ContactTemplatesRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/contact-test", func(c buffalo.Context) error {
+		setContactFormContext(c)
+		return c.Render(http.StatusOK, r.HTML("pages/contact.plush.html"))
+	})
+	app.GET("/messages-test", func(c buffalo.Context) error {
+		c.Set("messages", models.ContactMessages{{
+			Name:      "Sam Reporter",
+			Email:     "sam@example.com",
+			Subject:   "Interview request",
+			Message:   "Could we talk about the build program?",
+			Topic:     models.ContactTopicPress,
+			CreatedAt: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC),
+		}})
+		c.Set("topic", models.ContactTopicPress)
+		c.Set("unanswered", false)
+		c.Set("topicCounts", map[string]int{models.ContactTopicPress: 1, models.ContactTopicPrograms: 0})
+		c.Set("pagination", &pop.Paginator{Page: 1, PerPage: 20, TotalPages: 1})
+		setContactInboxContext(c)
+		return c.Render(http.StatusOK, r.HTML("admin/messages/index.plush.html"))
+	})
+	app.GET("/message-test", func(c buffalo.Context) error {
+		c.Set("message", &models.ContactMessage{
+			Name:      "Sam Reporter",
+			Email:     "sam@example.com",
+			Subject:   "Interview request",
+			Message:   "Could we talk about the build program?",
+			Topic:     models.ContactTopicPress,
+			CreatedAt: time.Now().Add(-72 * time.Hour),
+		})
+		setContactInboxContext(c)
+		return c.Render(http.StatusOK, r.HTML("admin/messages/show.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/contact-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `<option value="volunteering">Volunteering</option>`)
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/messages-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "<strong>Press (1)</strong>")
+	req.Contains(w.Body.String(), `<a href="/admin/messages?topic=programs">Programs</a> (0)`)
+	req.Contains(w.Body.String(), "Interview request")
+	req.Contains(w.Body.String(), "<strong>Overdue</strong>")
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/message-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "waiting 3 days")
+	req.Contains(w.Body.String(), "Mark Replied")
+}
+
+func Test_ContactReplySLA(t *testing.T) {
+	r := require.New(t)
+
+	envy.Set("CONTACT_REPLY_SLA", "")
+	r.Equal(defaultContactReplySLA, contactReplySLA())
+	envy.Set("CONTACT_REPLY_SLA", "24h")
+	r.Equal(24*time.Hour, contactReplySLA())
+	envy.Set("CONTACT_REPLY_SLA", "soon")
+	r.Equal(defaultContactReplySLA, contactReplySLA())
+	envy.Set("CONTACT_REPLY_SLA", "")
+
+	r.Equal("5 hours", waitingLabel(5*time.Hour+30*time.Minute))
+	r.Equal("1 day", waitingLabel(30*time.Hour))
+
+	stats := contactSLAStats{SLA: 48 * time.Hour}
+	r.Equal(100, stats.WithinSLAPercent())
+	r.Equal("—", stats.AverageResponse())
+	stats.Answered, stats.WithinSLA, stats.AvgSeconds = 4, 3, 7200
+	r.Equal(75, stats.WithinSLAPercent())
+	r.Equal("2 hours", stats.AverageResponse())
+	r.Equal("2 days", stats.SLALabel())
+}
@@ -0,0 +1,215 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// cryptoAssetName returns the display name for a supported currency code.
+func cryptoAssetName(code string) (string, bool) {
+	for _, a := range services.CryptoAssets {
+		if a.Code == code {
+			return a.Name, true
+		}
+	}
+	return "", false
+}
+
+// setCryptoFormContext sets the crypto donation form defaults
+func setCryptoFormContext(c buffalo.Context) {
+	c.Set("title", "Donate Cryptocurrency")
+	c.Set("assets", services.CryptoAssets)
+	c.Set("errors", map[string]string{})
+}
+
+// DonateCryptoHandler shows the cryptocurrency donation form
+func DonateCryptoHandler(c buffalo.Context) error {
+	if !services.CryptoEnabled() {
+		return c.Redirect(http.StatusSeeOther, "/donate")
+	}
+	setCryptoFormContext(c)
+	return c.Render(http.StatusOK, r.HTML("pages/donate_crypto.plush.html"))
+}
+
+// DonateCryptoCreateHandler records a crypto pledge and shows the donor the
+// deposit address issued by the processor
+func DonateCryptoCreateHandler(c buffalo.Context) error {
+	processor, err := services.NewCryptoProcessor()
+	if err != nil {
+		c.Logger().Errorf("[Crypto] %v", err)
+		c.Flash().Add("error", "Cryptocurrency donations are unavailable right now. Please try again later.")
+		return c.Redirect(http.StatusSeeOther, "/donate")
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(c.Param("currency")))
+	name := strings.TrimSpace(c.Param("donor_name"))
+	email := strings.TrimSpace(c.Param("donor_email"))
+	cryptoAmount, amountErr := strconv.ParseFloat(strings.TrimSpace(c.Param("crypto_amount")), 64)
+
+	errs := map[string]string{}
+	if _, ok := cryptoAssetName(currency); !ok {
+		errs["currency"] = "Please choose a supported cryptocurrency"
+	}
+	if amountErr != nil || cryptoAmount <= 0 {
+		errs["crypto_amount"] = "Please enter the amount you plan to send"
+	}
+	if name == "" {
+		errs["donor_name"] = "Name is required for your receipt"
+	}
+	if !strings.Contains(email, "@") || !strings.Contains(email, ".") {
+		errs["donor_email"] = "Please enter a valid email address for your receipt"
+	}
+	if len(errs) > 0 {
+		setCryptoFormContext(c)
+		c.Set("errors", errs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("pages/donate_crypto.plush.html"))
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	method := models.PaymentMethodCrypto
+	donation := &models.Donation{
+		DonorName:      name,
+		DonorEmail:     email,
+		Currency:       getCurrency(),
+		DonationType:   models.DonationTypeOneTime,
+		Status:         "pending",
+		PaymentMethod:  &method,
+		CryptoCurrency: &currency,
+		CryptoAmount:   &cryptoAmount,
+	}
+	if currentUser, ok := c.Value("current_user").(*models.User); ok && currentUser != nil {
+		donation.UserID = &currentUser.ID
+	}
+	if err := tx.Create(donation); err != nil {
+		return errors.WithStack(err)
+	}
+
+	deposit, err := processor.CreateDeposit(services.CryptoDepositRequest{
+		ExternalID:   donation.ID.String(),
+		Currency:     currency,
+		CryptoAmount: cryptoAmount,
+		DonorName:    name,
+		DonorEmail:   email,
+	})
+	if err != nil {
+		c.Logger().Errorf("[Crypto] Deposit address request failed for donation %s: %v", donation.ID.String(), err)
+		c.Flash().Add("error", "We couldn't create a deposit address. Please try again later.")
+		return c.Redirect(http.StatusSeeOther, "/donate/crypto")
+	}
+
+	donation.CryptoPledgeID = &deposit.PledgeID
+	donation.DepositAddress = &deposit.DepositAddress
+	donation.Amount = deposit.EstimatedUSD
+	if err := tx.Update(donation); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Logger().Infof("[Crypto] Pledge %s created for donation %s (%f %s)", deposit.PledgeID, donation.ID.String(), cryptoAmount, currency)
+
+	assetName, _ := cryptoAssetName(currency)
+	c.Set("title", "Send Your Cryptocurrency")
+	c.Set("donation", donation)
+	c.Set("deposit", deposit)
+	c.Set("currency", currency)
+	c.Set("assetName", assetName)
+	c.Set("cryptoAmount", strconv.FormatFloat(cryptoAmount, 'f', -1, 64))
+	return c.Render(http.StatusOK, r.HTML("pages/donate_crypto_deposit.plush.html"))
+}
+
+// CryptoWebhookHandler receives conversion notices from the crypto
+// processor, records the USD value, and sends the donor's receipt
+func CryptoWebhookHandler(c buffalo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid request body")
+	}
+
+	processor, err := services.NewCryptoProcessor()
+	if err != nil {
+		c.Logger().Errorf("[CryptoWebhook] %v", err)
+		return jsonError(c, http.StatusServiceUnavailable, codeServiceUnavailable, "Crypto processor not configured")
+	}
+	if !processor.VerifyWebhook(body, c.Request().Header.Get("X-Signature")) {
+		logging.SecurityEvent(c, "crypto_webhook", "failure", "invalid_signature")
+		return jsonError(c, http.StatusUnauthorized, codeInvalidSignature, "Invalid signature")
+	}
+
+	var event services.CryptoWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
+	}
+	if event.EventType != services.CryptoEventConverted {
+		c.Logger().Infof("[CryptoWebhook] Ignoring %s event for pledge %s", event.EventType, event.PledgeID)
+		return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "ignored"}))
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	donation, err := completeCryptoDonation(tx, event)
+	if err != nil {
+		c.Logger().Errorf("[CryptoWebhook] Failed to record pledge %s: %v", event.PledgeID, err)
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to record donation")
+	}
+	if donation == nil {
+		c.Logger().Warnf("[CryptoWebhook] No pending donation for pledge %s - already recorded or unknown", event.PledgeID)
+		return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "ignored"}))
+	}
+
+	logging.Audit("crypto_donation_received", logging.Fields{
+		"donation_id": donation.ID.String(),
+		"currency":    event.Currency,
+		"value_usd":   event.ValueUSD,
+	})
+
+	err = services.NewEmailService().SendCryptoReceipt(donation.DonorEmail, services.CryptoReceiptData{
+		DonorName:        donation.DonorName,
+		Currency:         event.Currency,
+		CryptoAmount:     event.CryptoAmount,
+		ConvertedUSD:     event.ValueUSD,
+		TransactionHash:  event.TransactionHash,
+		ReceivedDate:     event.ReceivedAt,
+		OrganizationName: organization().Name,
+		OrganizationEIN:  organization().EIN,
+	})
+	if err != nil {
+		c.Logger().Errorf("[CryptoWebhook] Failed to send receipt for donation %s: %v", donation.ID.String(), err)
+	} else {
+		recordCommunication(tx, donation.DonorEmail, models.CommunicationReceipt, fmt.Sprintf("Receipt for %s donation worth $%.2f", event.Currency, event.ValueUSD), nil, &donation.ID)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "recorded"}))
+}
+
+// completeCryptoDonation records the converted value on the pending donation
+// for a pledge. It returns nil when there is no pending donation, so
+// redelivered webhooks don't send a second receipt.
+func completeCryptoDonation(tx *pop.Connection, event services.CryptoWebhookEvent) (*models.Donation, error) {
+	donation := &models.Donation{}
+	err := tx.Where("crypto_pledge_id = ? AND status = ?", event.PledgeID, "pending").First(donation)
+	if err != nil {
+		return nil, nil
+	}
+
+	currency := strings.ToUpper(event.Currency)
+	donation.Amount = event.ValueUSD
+	donation.CryptoCurrency = &currency
+	donation.CryptoAmount = &event.CryptoAmount
+	donation.TransactionID = stringPointer(event.TransactionHash)
+	donation.Status = "completed"
+	if err := tx.Update(donation); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	notifyDonationCompleted(tx, donation)
+	return donation, nil
+}
@@ -0,0 +1,185 @@
+package actions
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// defaultCSPPolicy covers what the site loads today: local assets, inline
+// scripts/styles in templates, the HelcimPay.js loader and iframe, and the
+// Turnstile CAPTCHA the donate form can fall back to.
+const defaultCSPPolicy = "default-src 'self'; " +
+	"script-src 'self' 'unsafe-inline' https://secure.helcim.app https://challenges.cloudflare.com; " +
+	"style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data: https:; " +
+	"font-src 'self' data:; " +
+	"frame-src https://secure.helcim.app https://challenges.cloudflare.com; " +
+	"connect-src 'self' https://secure.helcim.app https://api.helcim.com"
+
+// maxCSPReportBytes caps report bodies; browsers send well under this.
+const maxCSPReportBytes = 64 * 1024
+
+// ContentSecurityPolicy sets the CSP header on every response. The policy
+// comes from CSP_POLICY and is sent as Report-Only unless CSP_ENFORCE=true,
+// so violations can be collected at /csp-report before tightening.
+func ContentSecurityPolicy(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		policy := strings.TrimSpace(envy.Get("CSP_POLICY", defaultCSPPolicy))
+		if policy != "" {
+			header := "Content-Security-Policy-Report-Only"
+			if envBool("CSP_ENFORCE", false) {
+				header = "Content-Security-Policy"
+			}
+			c.Response().Header().Set(header, policy+"; report-uri /csp-report")
+		}
+		return next(c)
+	}
+}
+
+// legacyCSPReport is the report-uri format: {"csp-report": {...}}
+type legacyCSPReport struct {
+	Body struct {
+		DocumentURI        string `json:"document-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		BlockedURI         string `json:"blocked-uri"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		Disposition        string `json:"disposition"`
+	} `json:"csp-report"`
+}
+
+// reportingAPIReport is the Reporting API format: [{"type": "csp-violation", "body": {...}}]
+type reportingAPIReport struct {
+	Type      string `json:"type"`
+	UserAgent string `json:"user_agent"`
+	Body      struct {
+		DocumentURL        string `json:"documentURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		BlockedURL         string `json:"blockedURL"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Disposition        string `json:"disposition"`
+	} `json:"body"`
+}
+
+// parseCSPReports decodes either report format into CSPReport records.
+func parseCSPReports(body []byte) ([]models.CSPReport, error) {
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		var batch []reportingAPIReport
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		reports := make([]models.CSPReport, 0, len(batch))
+		for _, rep := range batch {
+			if rep.Type != "csp-violation" {
+				continue
+			}
+			raw, _ := json.Marshal(rep)
+			reports = append(reports, models.CSPReport{
+				DocumentURI:        rep.Body.DocumentURL,
+				ViolatedDirective:  rep.Body.EffectiveDirective,
+				EffectiveDirective: rep.Body.EffectiveDirective,
+				BlockedURI:         rep.Body.BlockedURL,
+				SourceFile:         rep.Body.SourceFile,
+				LineNumber:         rep.Body.LineNumber,
+				Disposition:        rep.Body.Disposition,
+				UserAgent:          rep.UserAgent,
+				Raw:                string(raw),
+			})
+		}
+		return reports, nil
+	}
+
+	var legacy legacyCSPReport
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	effective := legacy.Body.EffectiveDirective
+	if effective == "" {
+		// Older browsers only send violated-directive, e.g. "script-src 'self'"
+		fields := strings.Fields(legacy.Body.ViolatedDirective)
+		if len(fields) == 0 {
+			// Not a violation of anything, so there's nothing to keep
+			return []models.CSPReport{}, nil
+		}
+		effective = fields[0]
+	}
+	return []models.CSPReport{{
+		DocumentURI:        legacy.Body.DocumentURI,
+		ViolatedDirective:  legacy.Body.ViolatedDirective,
+		EffectiveDirective: effective,
+		BlockedURI:         legacy.Body.BlockedURI,
+		SourceFile:         legacy.Body.SourceFile,
+		LineNumber:         legacy.Body.LineNumber,
+		Disposition:        legacy.Body.Disposition,
+		Raw:                trimmed,
+	}}, nil
+}
+
+// CSPReportHandler stores CSP violation reports sent by browsers. It's public,
+// so it's held to the csp_report rate limit like the other open forms.
+func CSPReportHandler(c buffalo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxCSPReportBytes))
+	if err != nil {
+		return c.Error(http.StatusBadRequest, err)
+	}
+
+	reports, err := parseCSPReports(body)
+	if err != nil {
+		logging.Debug("Ignoring malformed CSP report", logging.Fields{"error": err.Error()})
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "invalid report")
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	for i := range reports {
+		report := &reports[i]
+		if report.UserAgent == "" {
+			report.UserAgent = c.Request().UserAgent()
+		}
+		// Reports without a directive fail validation and are dropped
+		if _, err := tx.ValidateAndCreate(report); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
+
+// AdminCSPReportsIndex lists collected CSP violations, grouped and raw
+func AdminCSPReportsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	summary := []models.CSPViolationSummary{}
+	err := tx.RawQuery(`SELECT effective_directive, blocked_uri, COUNT(*) AS count, MAX(created_at) AS last_seen
+		FROM csp_reports GROUP BY effective_directive, blocked_uri ORDER BY count DESC LIMIT 50`).All(&summary)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	reports := &models.CSPReports{}
+	q := tx.PaginateFromParams(c.Params())
+	if directive := c.Param("directive"); directive != "" {
+		q = q.Where("effective_directive = ?", directive)
+	}
+	if err := q.Order("created_at desc").All(reports); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("summary", summary)
+	c.Set("reports", reports)
+	c.Set("pagination", q.Paginator)
+	c.Set("directive", c.Param("directive"))
+	return c.Render(http.StatusOK, r.HTML("admin/csp_reports.plush.html"))
+}
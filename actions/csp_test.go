@@ -0,0 +1,52 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseCSPReports_Legacy(t *testing.T) {
+	body := []byte(`{"csp-report": {
+		"document-uri": "https://avrnpo.org/donate/payment",
+		"violated-directive": "script-src 'self'",
+		"blocked-uri": "https://evil.example/x.js",
+		"line-number": 12,
+		"disposition": "report"
+	}}`)
+
+	reports, err := parseCSPReports(body)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, "script-src", reports[0].EffectiveDirective)
+	assert.Equal(t, "https://evil.example/x.js", reports[0].BlockedURI)
+	assert.Equal(t, 12, reports[0].LineNumber)
+}
+
+func Test_ParseCSPReports_ReportingAPI(t *testing.T) {
+	body := []byte(`[
+		{"type": "csp-violation", "user_agent": "UA", "body": {"documentURL": "https://avrnpo.org/", "effectiveDirective": "style-src-elem", "blockedURL": "inline"}},
+		{"type": "deprecation", "body": {}}
+	]`)
+
+	reports, err := parseCSPReports(body)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, "style-src-elem", reports[0].EffectiveDirective)
+	assert.Equal(t, "UA", reports[0].UserAgent)
+}
+
+func Test_ParseCSPReports_Invalid(t *testing.T) {
+	_, err := parseCSPReports([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func Test_ParseCSPReports_NoDirective(t *testing.T) {
+	for _, directive := range []string{"", "   "} {
+		body := []byte(`{"csp-report": {"document-uri": "https://avrnpo.org/", "violated-directive": "` + directive + `"}}`)
+		reports, err := parseCSPReports(body)
+		require.NoError(t, err)
+		assert.Empty(t, reports, "violated-directive %q", directive)
+	}
+}
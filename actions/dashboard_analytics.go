@@ -0,0 +1,283 @@
+package actions
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+)
+
+const dashboardTrendMonths = 12
+
+// Size of the dashboard's donation trend chart, in SVG units
+const (
+	trendChartWidth  = 600
+	trendChartHeight = 180
+	trendBarGap      = 8
+)
+
+// donationMonth is one calendar month of gifts on the admin dashboard
+type donationMonth struct {
+	Month     time.Time
+	OneTime   float64
+	Recurring float64
+	Gifts     int
+}
+
+// Total is everything given in the month
+func (m donationMonth) Total() float64 {
+	return m.OneTime + m.Recurring
+}
+
+// donationAnalytics are the dashboard's donation widgets: the last twelve
+// months of giving, oldest first and ending with the current month, and
+// this month's donors split by whether it's their first gift
+type donationAnalytics struct {
+	Months          []donationMonth
+	NewDonors       int
+	ReturningDonors int
+}
+
+// Current is this month's giving so far
+func (a donationAnalytics) Current() donationMonth {
+	if len(a.Months) == 0 {
+		return donationMonth{}
+	}
+	return a.Months[len(a.Months)-1]
+}
+
+// Previous is last month's giving
+func (a donationAnalytics) Previous() donationMonth {
+	if len(a.Months) < 2 {
+		return donationMonth{}
+	}
+	return a.Months[len(a.Months)-2]
+}
+
+// AverageGift is this month's average gift, each monthly charge counting as
+// a gift
+func (a donationAnalytics) AverageGift() float64 {
+	current := a.Current()
+	if current.Gifts == 0 {
+		return 0
+	}
+	return math.Round(current.Total()/float64(current.Gifts)*100) / 100
+}
+
+// RecurringPercent is the share of this month's giving from monthly gifts
+// and installments
+func (a donationAnalytics) RecurringPercent() int {
+	current := a.Current()
+	if current.Total() == 0 {
+		return 0
+	}
+	return int(math.Round(current.Recurring / current.Total() * 100))
+}
+
+// OneTimePercent is the share of this month's giving from one-time gifts
+func (a donationAnalytics) OneTimePercent() int {
+	if a.Current().Total() == 0 {
+		return 0
+	}
+	return 100 - a.RecurringPercent()
+}
+
+// Change is how this month compares with last month so far, e.g. "+12%",
+// or "" when there's nothing to compare with
+func (a donationAnalytics) Change() string {
+	previous := a.Previous().Total()
+	if previous == 0 {
+		return ""
+	}
+	change := int(math.Round((a.Current().Total() - previous) / previous * 100))
+	if change > 0 {
+		return fmt.Sprintf("+%d%%", change)
+	}
+	return fmt.Sprintf("%d%%", change)
+}
+
+// trendBar is one month's stacked bar on the trend chart, recurring giving
+// at the bottom and one-time gifts on top
+type trendBar struct {
+	X               int
+	Width           int
+	RecurringY      int
+	RecurringHeight int
+	OneTimeY        int
+	OneTimeHeight   int
+	Label           string
+	Title           string
+}
+
+// LabelX is where the bar's month label is centered
+func (b trendBar) LabelX() int {
+	return b.X + b.Width/2
+}
+
+// Bars lays out the trend chart, scaled to the best month
+func (a donationAnalytics) Bars() []trendBar {
+	if len(a.Months) == 0 {
+		return nil
+	}
+	highest := 0.0
+	for _, m := range a.Months {
+		highest = math.Max(highest, m.Total())
+	}
+	slot := trendChartWidth / len(a.Months)
+	height := func(amount float64) int {
+		if highest == 0 {
+			return 0
+		}
+		return int(math.Round(amount / highest * trendChartHeight))
+	}
+
+	bars := make([]trendBar, len(a.Months))
+	for i, m := range a.Months {
+		recurring := height(m.Recurring)
+		oneTime := height(m.Total()) - recurring
+		bars[i] = trendBar{
+			X:               i*slot + trendBarGap/2,
+			Width:           slot - trendBarGap,
+			RecurringY:      trendChartHeight - recurring,
+			RecurringHeight: recurring,
+			OneTimeY:        trendChartHeight - recurring - oneTime,
+			OneTimeHeight:   oneTime,
+			Label:           m.Month.Format("Jan"),
+			Title:           fmt.Sprintf("%s: $%.2f (one-time $%.2f, recurring $%.2f)", m.Month.Format("January 2006"), m.Total(), m.OneTime, m.Recurring),
+		}
+	}
+	return bars
+}
+
+// ChartHeight is the SVG height of the trend chart, leaving room under the
+// bars for the month labels
+func (a donationAnalytics) ChartHeight() int {
+	return trendChartHeight + 20
+}
+
+// ChartWidth is the SVG width of the trend chart
+func (a donationAnalytics) ChartWidth() int {
+	return trendChartWidth
+}
+
+// loadDonationAnalytics totals the last twelve months of gifts for the
+// dashboard
+func loadDonationAnalytics(tx *pop.Connection, now time.Time) (donationAnalytics, error) {
+	start := monthStart(now).AddDate(0, -(dashboardTrendMonths - 1), 0)
+	donations := models.Donations{}
+	if err := tx.Where("(status = 'completed' AND created_at >= ?) OR (status IN ('active', 'cancelled', 'paused') AND subscription_id IS NOT NULL)", start).All(&donations); err != nil {
+		return donationAnalytics{}, errors.WithStack(err)
+	}
+
+	var rows []struct {
+		Email     string    `db:"email"`
+		FirstGift time.Time `db:"first_gift"`
+	}
+	err := tx.RawQuery(`SELECT LOWER(TRIM(donor_email)) AS email, MIN(created_at) AS first_gift
+		FROM donations
+		WHERE status IN ('completed', 'active', 'cancelled') AND donor_email <> ''
+		GROUP BY LOWER(TRIM(donor_email))`).All(&rows)
+	if err != nil {
+		return donationAnalytics{}, errors.WithStack(err)
+	}
+	firstGifts := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		firstGifts[row.Email] = row.FirstGift
+	}
+	return buildDonationAnalytics(donations, firstGifts, now, dashboardTrendMonths), nil
+}
+
+// monthStart is midnight on the first of t's month
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// buildDonationAnalytics totals gifts into the months up to now. Monthly
+// gifts don't record each charge, so as with Donation.ReceivedToDate they
+// count a charge at activation and each billing period after until
+// cancelled or paused, and installment pledges count the installments paid. A donor is
+// new this month when their first gift (firstGifts, keyed by normalized
+// email) was.
+func buildDonationAnalytics(donations models.Donations, firstGifts map[string]time.Time, now time.Time, months int) donationAnalytics {
+	start := monthStart(now).AddDate(0, -(months - 1), 0)
+	analytics := donationAnalytics{Months: make([]donationMonth, months)}
+	for i := range analytics.Months {
+		analytics.Months[i].Month = start.AddDate(0, i, 0)
+	}
+	bucket := func(t time.Time) *donationMonth {
+		t = t.In(now.Location())
+		if t.Before(start) || t.After(now) {
+			return nil
+		}
+		i := (t.Year()-start.Year())*12 + int(t.Month()-start.Month())
+		return &analytics.Months[i]
+	}
+
+	current := monthStart(now)
+	givingNow := map[string]bool{}
+	for _, d := range donations {
+		email := models.NormalizeDonorEmail(d.DonorEmail)
+		if !d.IsRecurring() {
+			if d.Status != "completed" {
+				continue
+			}
+			if m := bucket(d.CreatedAt); m != nil {
+				m.OneTime += d.Amount
+				m.Gifts++
+				if !d.CreatedAt.Before(current) {
+					givingNow[email] = true
+				}
+			}
+			continue
+		}
+
+		if d.Status != "active" && d.Status != "cancelled" && d.Status != models.DonationStatusPaused {
+			continue
+		}
+		first := d.CreatedAt
+		if d.ActivationDate != nil {
+			first = *d.ActivationDate
+		}
+		end := now
+		if d.Status == "cancelled" || d.Status == models.DonationStatusPaused {
+			end = d.UpdatedAt
+		}
+		for k := 0; ; k++ {
+			if d.IsInstallmentPledge() && k >= d.InstallmentsPaid {
+				break
+			}
+			charge := first.AddDate(0, k*d.ChargeFrequency().Months(), 0)
+			if charge.After(end) || charge.After(now) {
+				break
+			}
+			if m := bucket(charge); m != nil {
+				m.Recurring += d.Amount
+				m.Gifts++
+				if !charge.Before(current) {
+					givingNow[email] = true
+				}
+			}
+		}
+	}
+
+	for email := range givingNow {
+		if email == "" {
+			continue
+		}
+		if first, ok := firstGifts[email]; ok && first.Before(current) {
+			analytics.ReturningDonors++
+		} else {
+			analytics.NewDonors++
+		}
+	}
+	for i := range analytics.Months {
+		m := &analytics.Months[i]
+		m.OneTime = math.Round(m.OneTime*100) / 100
+		m.Recurring = math.Round(m.Recurring*100) / 100
+	}
+	return analytics
+}
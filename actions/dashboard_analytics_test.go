@@ -0,0 +1,124 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_BuildDonationAnalytics(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	sub := "sub_1"
+	activated := time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC)
+	pledge := "sub_2"
+	cancelled := "sub_3"
+
+	donations := models.Donations{
+		{DonorEmail: "jo@example.com", Amount: 100, Status: "completed", CreatedAt: time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)},
+		{DonorEmail: "new@example.com", Amount: 40, Status: "completed", CreatedAt: time.Date(2026, 10, 9, 0, 0, 0, 0, time.UTC)},
+		{DonorEmail: "jo@example.com", Amount: 60, Status: "completed", CreatedAt: time.Date(2026, 9, 20, 0, 0, 0, 0, time.UTC)},
+		{DonorEmail: "old@example.com", Amount: 500, Status: "completed", CreatedAt: time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC)},
+		{DonorEmail: "pending@example.com", Amount: 75, Status: "pending", CreatedAt: time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)},
+		// Charged Aug 3, Sep 3 and Oct 3
+		{DonorEmail: " Monthly@Example.com", Amount: 20, Status: "active", SubscriptionID: &sub, ActivationDate: &activated, CreatedAt: activated},
+		// Two of four installments paid, in July and August
+		{DonorEmail: "pledge@example.com", Amount: 50, Status: "active", SubscriptionID: &pledge, DonationType: models.DonationTypeInstallment,
+			InstallmentCount: 4, InstallmentsPaid: 2, CreatedAt: time.Date(2026, 7, 15, 0, 0, 0, 0, time.UTC)},
+		// Charged in May and June before being cancelled
+		{DonorEmail: "gone@example.com", Amount: 10, Status: "cancelled", SubscriptionID: &cancelled,
+			CreatedAt: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2026, 6, 20, 0, 0, 0, 0, time.UTC)},
+	}
+	firstGifts := map[string]time.Time{
+		"jo@example.com":      time.Date(2026, 9, 20, 0, 0, 0, 0, time.UTC),
+		"new@example.com":     time.Date(2026, 10, 9, 0, 0, 0, 0, time.UTC),
+		"monthly@example.com": activated,
+	}
+
+	analytics := buildDonationAnalytics(donations, firstGifts, now, 12)
+	require.Len(t, analytics.Months, 12)
+	assert.Equal(t, time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC), analytics.Months[0].Month)
+
+	current := analytics.Current()
+	assert.Equal(t, 140.0, current.OneTime)
+	assert.Equal(t, 20.0, current.Recurring)
+	assert.Equal(t, 3, current.Gifts)
+	assert.Equal(t, 53.33, analytics.AverageGift())
+	assert.Equal(t, 13, analytics.RecurringPercent())
+	assert.Equal(t, 87, analytics.OneTimePercent())
+
+	assert.Equal(t, donationMonth{Month: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), OneTime: 60, Recurring: 20, Gifts: 2}, analytics.Previous())
+	assert.Equal(t, "+100%", analytics.Change())
+	assert.Equal(t, 70.0, analytics.Months[9].Recurring, "August")
+	assert.Equal(t, 50.0, analytics.Months[8].Recurring, "July")
+	assert.Equal(t, 10.0, analytics.Months[7].Recurring, "June")
+	assert.Equal(t, 10.0, analytics.Months[6].Recurring, "May")
+
+	// jo and monthly gave before October; new@ is giving for the first time
+	assert.Equal(t, 1, analytics.NewDonors)
+	assert.Equal(t, 2, analytics.ReturningDonors)
+
+	bars := analytics.Bars()
+	require.Len(t, bars, 12)
+	october := bars[11]
+	assert.Equal(t, 180, october.RecurringHeight+october.OneTimeHeight, "the best month fills the chart")
+	assert.Equal(t, 23, october.RecurringHeight)
+	assert.Equal(t, 0, october.OneTimeY)
+	assert.Equal(t, "Oct", october.Label)
+	assert.Equal(t, 0, bars[0].RecurringHeight+bars[0].OneTimeHeight)
+}
+
+func Test_DonationAnalyticsEmpty(t *testing.T) {
+	analytics := buildDonationAnalytics(nil, nil, time.Now(), 12)
+	assert.Zero(t, analytics.AverageGift())
+	assert.Zero(t, analytics.RecurringPercent())
+	assert.Zero(t, analytics.OneTimePercent())
+	assert.Empty(t, analytics.Change())
+	for _, bar := range analytics.Bars() {
+		assert.Zero(t, bar.RecurringHeight+bar.OneTimeHeight)
+	}
+}
+
+func Test_AdminDashboardTemplateRendering(t *testing.T) {
+	req := require.New(t)
+	now := time.Now()
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/admin-dashboard-test", func(c buffalo.Context) error {
+		c.Set("userCount", 3)
+		c.Set("adminCount", 1)
+		c.Set("regularUserCount", 2)
+		c.Set("totalPosts", 0)
+		c.Set("publishedPosts", 0)
+		c.Set("draftPosts", 0)
+		c.Set("recentPosts", 0)
+		c.Set("posts", []models.Post{})
+		c.Set("pendingReviews", 0)
+		c.Set("blockedReceipts", models.DonationReceipts{})
+		c.Set("contactSLA", contactSLAStats{})
+		c.Set("donationAnalytics", buildDonationAnalytics(models.Donations{
+			{DonorEmail: "jo@example.com", Amount: 125, Status: "completed", CreatedAt: now},
+		}, nil, now, dashboardTrendMonths))
+		c.Set("recentErrors", nil)
+		c.Set("recentActivity", []adminActivity{})
+		c.Set("activityLimit", recentActivityLimit)
+		c.Set("errorTrackingEnabled", false)
+		return c.Render(http.StatusOK, r.HTML("admin/index.plush.html"))
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/admin-dashboard-test", nil))
+	req.Equal(http.StatusOK, res.Code, res.Body.String())
+	body := res.Body.String()
+	req.Contains(body, "$125.00")
+	req.Contains(body, "0% / 100%")
+	req.Contains(body, "1 / 0")
+	req.Contains(body, `class="trend-one-time"`)
+	req.Contains(body, now.Format("Jan"))
+}
@@ -0,0 +1,82 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_HostedPaymentURL(t *testing.T) {
+	req := require.New(t)
+
+	envy.Temp(func() {
+		envy.Set("HELCIM_HOSTED_PAYMENT_URL", "")
+		req.Empty(hostedPaymentURL(&models.Donation{Amount: 50, DonationType: "one-time"}))
+
+		envy.Set("HELCIM_HOSTED_PAYMENT_URL", "https://avrnpo.myhelcim.com/hosted/?token=abc")
+		req.Equal("https://avrnpo.myhelcim.com/hosted/?amount=50.00&token=abc",
+			hostedPaymentURL(&models.Donation{Amount: 50, DonationType: "one-time"}))
+		req.Empty(hostedPaymentURL(&models.Donation{Amount: 50, DonationType: "monthly"}))
+	})
+}
+
+func Test_DonatePaymentNoScriptFallback(t *testing.T) {
+	req := require.New(t)
+
+	render := func(hosted string) string {
+		app := buffalo.New(buffalo.Options{Env: "test"})
+		app.GET("/payment-test", func(c buffalo.Context) error {
+			c.Set("donationId", "d1")
+			c.Set("flowToken", "flow")
+			c.Set("checkoutToken", "tok")
+			c.Set("amount", "50.00")
+			c.Set("donorName", "Test Donor")
+			c.Set("donorEmail", "donor@example.com")
+			c.Set("donationType", "one-time")
+			c.Set("installmentCount", 0)
+			c.Set("pledgeTotal", 0.0)
+			c.Set("paymentMethod", "Credit Card")
+			c.Set("hostedPaymentURL", hosted)
+			return c.Render(http.StatusOK, r.HTML("pages/donate_payment.plush.html"))
+		})
+		w := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("GET", "/payment-test", nil)
+		app.ServeHTTP(w, httpReq)
+		req.Equal(http.StatusOK, w.Code, w.Body.String())
+		return w.Body.String()
+	}
+
+	body := render("https://avrnpo.myhelcim.com/hosted/?amount=50.00")
+	req.Contains(body, `id="open-payment-modal" class="payment-submit" hidden`)
+	req.Contains(body, `<a href="https://avrnpo.myhelcim.com/hosted/?amount=50.00" role="button" class="payment-submit">Pay $50.00`)
+
+	body = render("")
+	req.NotContains(body, "Secure Payment Page")
+	req.Contains(body, `<a href="/contact">contact us</a>`)
+}
+
+func Test_DonateFormNoScriptPresets(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/donate-form-test", func(c buffalo.Context) error {
+		setDonateContext(c, &DonateContextOptions{Amount: "100"})
+		return c.Render(http.StatusOK, r.HTML("pages/_donate_form.plush.html"))
+	})
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/donate-form-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	body := w.Body.String()
+	req.Contains(body, `id="amount-buttons" class="amount-grid" hidden`)
+	req.Contains(body, `<input type="radio" name="preset_amount" value="100" checked>`)
+	req.Contains(body, `<input type="radio" name="preset_amount" value="25">`)
+	req.Contains(body, `<span id="submit-text">Donate $100.00 Now</span>`)
+}
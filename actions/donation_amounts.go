@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"avrnpo.org/pkg/helpers"
+)
+
+// zeroDecimalCurrencies have no minor unit, so gifts in them are whole
+// amounts. Every other currency is rounded to the cent.
+var zeroDecimalCurrencies = map[string]bool{
+	"CLP": true,
+	"ISK": true,
+	"JPY": true,
+	"KRW": true,
+	"VND": true,
+}
+
+// dollarCurrencies are shown with a "$" rather than their code
+var dollarCurrencies = map[string]bool{
+	"AUD": true,
+	"CAD": true,
+	"NZD": true,
+	"USD": true,
+}
+
+// currencyDecimals is how many decimal places amounts in currency have
+func currencyDecimals(currency string) int {
+	if zeroDecimalCurrencies[strings.ToUpper(currency)] {
+		return 0
+	}
+	return 2
+}
+
+// roundDonationAmount rounds amount to currency's smallest unit, halves
+// away from zero
+func roundDonationAmount(amount float64, currency string) float64 {
+	scale := math.Pow10(currencyDecimals(currency))
+	return math.Round(amount*scale) / scale
+}
+
+// formatDonationAmount shows amount in currency for validation messages,
+// e.g. "$10,000.00" or "JPY 5,000"
+func formatDonationAmount(amount float64, currency string) string {
+	currency = strings.ToUpper(currency)
+	s := helpers.Money(amount)
+	if dollarCurrencies[currency] {
+		return s
+	}
+	s = strings.TrimPrefix(s, "$")
+	if currencyDecimals(currency) == 0 {
+		s = strings.TrimSuffix(s, ".00")
+	}
+	return currency + " " + s
+}
+
+// parseDonationAmount reads a gift amount typed by a donor, allowing "$" and
+// thousands separators. The amount is rounded for currency and held to the
+// configured minimum and maximum, so every donation flow accepts the same
+// gifts.
+func parseDonationAmount(s, currency string) (float64, error) {
+	s = strings.NewReplacer("$", "", ",", "").Replace(strings.TrimSpace(s))
+	if s == "" {
+		return 0, errors.New("Donation amount is required")
+	}
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, errors.New("Please enter a valid donation amount")
+	}
+	amount = roundDonationAmount(amount, currency)
+	if amount <= 0 {
+		return 0, errors.New("Donation amount must be greater than zero")
+	}
+	if minimum := donationMinimum(); amount < minimum {
+		return 0, fmt.Errorf("The minimum donation is %s", formatDonationAmount(minimum, currency))
+	}
+	if maximum := donationMaximum(); maximum > 0 && amount > maximum {
+		return 0, fmt.Errorf("The maximum online donation is %s. Please contact us about larger gifts.", formatDonationAmount(maximum, currency))
+	}
+	return amount, nil
+}
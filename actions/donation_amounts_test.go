@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RoundDonationAmount(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(25.13, roundDonationAmount(25.125, "USD"))
+	r.Equal(10.0, roundDonationAmount(9.999, "CAD"))
+	r.Equal(5001.0, roundDonationAmount(5000.5, "JPY"))
+	r.Equal(5000.0, roundDonationAmount(5000.4, "jpy"))
+}
+
+func Test_FormatDonationAmount(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("$10,000.00", formatDonationAmount(10000, "USD"))
+	r.Equal("$1.00", formatDonationAmount(1, "CAD"))
+	r.Equal("EUR 1,250.50", formatDonationAmount(1250.5, "EUR"))
+	r.Equal("JPY 5,000", formatDonationAmount(5000, "JPY"))
+}
+
+func Test_ParseDonationAmount(t *testing.T) {
+	r := require.New(t)
+
+	envy.Temp(func() {
+		envy.Set("DONATION_MIN_AMOUNT", "1")
+		envy.Set("DONATION_MAX_AMOUNT", "")
+
+		amount, err := parseDonationAmount(" $1,250.505 ", "USD")
+		r.NoError(err)
+		r.Equal(1250.51, amount)
+
+		_, err = parseDonationAmount("", "USD")
+		r.EqualError(err, "Donation amount is required")
+		_, err = parseDonationAmount("ten", "USD")
+		r.EqualError(err, "Please enter a valid donation amount")
+		_, err = parseDonationAmount("NaN", "USD")
+		r.Error(err)
+		_, err = parseDonationAmount("-5", "USD")
+		r.EqualError(err, "Donation amount must be greater than zero")
+		// Rounds down to nothing
+		_, err = parseDonationAmount("0.004", "USD")
+		r.EqualError(err, "Donation amount must be greater than zero")
+		_, err = parseDonationAmount("0.50", "USD")
+		r.EqualError(err, "The minimum donation is $1.00")
+
+		amount, err = parseDonationAmount("100000", "USD")
+		r.NoError(err)
+		r.Equal(defaultDonationMaximum, amount)
+		_, err = parseDonationAmount("100000.01", "USD")
+		r.EqualError(err, "The maximum online donation is $100,000.00. Please contact us about larger gifts.")
+
+		envy.Set("DONATION_MAX_AMOUNT", "10000")
+		_, err = parseDonationAmount("10000.01", "USD")
+		r.EqualError(err, "The maximum online donation is $10,000.00. Please contact us about larger gifts.")
+
+		envy.Set("DONATION_MAX_AMOUNT", "0")
+		_, err = parseDonationAmount("2500000", "USD")
+		r.NoError(err)
+
+		envy.Set("DONATION_MIN_AMOUNT", "100")
+		amount, err = parseDonationAmount("1500.4", "JPY")
+		r.NoError(err)
+		r.Equal(1500.0, amount)
+		_, err = parseDonationAmount("99.4", "JPY")
+		r.EqualError(err, "The minimum donation is JPY 100")
+	})
+}
+
+func Test_DonationMaximum(t *testing.T) {
+	r := require.New(t)
+
+	envy.Temp(func() {
+		envy.Set("DONATION_MAX_AMOUNT", "")
+		r.Equal(defaultDonationMaximum, donationMaximum())
+		// Gifts the review queue holds are still accepted
+		r.Greater(donationMaximum(), donationReviewThreshold())
+
+		envy.Set("DONATION_MAX_AMOUNT", "10000")
+		r.Equal(10000.0, donationMaximum())
+
+		envy.Set("DONATION_MAX_AMOUNT", "-1")
+		r.Equal(defaultDonationMaximum, donationMaximum())
+	})
+}
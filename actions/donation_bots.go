@@ -0,0 +1,84 @@
+package actions
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// captchaErrorKey is the form error the donate form shows the Turnstile
+// widget under
+const captchaErrorKey = "captcha"
+
+// donationBotCheck is what the bot checks made of a donation form post
+type donationBotCheck struct {
+	// Blocked posts filled in the honeypot, which people never see
+	Blocked bool
+	// Message is shown to the donor when the post looked automated but
+	// might not have been; it's empty when the post passed
+	Message string
+}
+
+// screenDonationBot runs the contact form's bot checks on a donation, to
+// slow down card-testing bots trying stolen cards through the donate form.
+// A filled honeypot is blocked outright. A form sent too soon after it was
+// rendered, or too long after, is sent back to the donor; when a CAPTCHA is
+// configured (verifier isn't nil) solving it lets the donation through
+// instead. Requests without a form_timestamp, such as older clients of the
+// JSON endpoint, only get the honeypot check.
+func screenDonationBot(c buffalo.Context, req DonationRequest, verifier services.CaptchaVerifier, now time.Time) donationBotCheck {
+	fields := logging.Fields{
+		"ip":   getClientIP(c),
+		"path": c.Request().URL.Path,
+	}
+	if strings.TrimSpace(req.Website) != "" {
+		logging.SecurityEvent(c, "donation_bot_check", "blocked", "honeypot_filled", fields)
+		return donationBotCheck{Blocked: true}
+	}
+
+	elapsed, timingErr := formTimingError(req.FormTimestamp, now)
+	if timingErr == nil {
+		return donationBotCheck{}
+	}
+	fields["elapsed_seconds"] = elapsed
+	if verifier == nil {
+		logging.SecurityEvent(c, "donation_bot_check", "rejected", "form_timing", fields)
+		return donationBotCheck{Message: "Your donation " + timingErr.Error()}
+	}
+
+	solved, err := verifier.Verify(c.Request().Context(), req.CaptchaToken, getClientIP(c))
+	if err != nil {
+		// Don't turn donors away because Turnstile is down; the timing
+		// check failing alone isn't enough to call it a bot
+		logging.Error("captcha_verify_failed", err, fields)
+		return donationBotCheck{}
+	}
+	if solved {
+		return donationBotCheck{}
+	}
+	if req.CaptchaToken != "" {
+		logging.SecurityEvent(c, "donation_bot_check", "rejected", "captcha_failed", fields)
+	}
+	return donationBotCheck{Message: "Please confirm you're not a robot to complete your donation"}
+}
+
+// rejectDonationBot answers a donation blocked by screenDonationBot. Bots
+// aren't told why.
+func rejectDonationBot(c buffalo.Context) error {
+	if isAPIRequest(c) {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid form submission")
+	}
+	c.Flash().Add("error", "We couldn't process that donation. Please try again.")
+	return c.Redirect(http.StatusSeeOther, "/donate")
+}
+
+// formTimestamp is the form_timestamp a form is rendered with, for the bot
+// timing check when it's sent
+func formTimestamp() int64 {
+	return time.Now().Unix()
+}
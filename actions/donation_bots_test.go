@@ -0,0 +1,83 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/services"
+)
+
+// stubCaptcha accepts one token, or fails every check when err is set
+type stubCaptcha struct {
+	valid string
+	err   error
+}
+
+func (s stubCaptcha) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	return token != "" && token == s.valid, s.err
+}
+
+func Test_FormTimingError(t *testing.T) {
+	req := require.New(t)
+	now := time.Unix(1_800_000_000, 0)
+	ago := func(d time.Duration) string { return strconv.FormatInt(now.Add(-d).Unix(), 10) }
+
+	_, err := formTimingError("", now)
+	req.NoError(err, "forms without a timestamp pass")
+	_, err = formTimingError("not-a-time", now)
+	req.NoError(err)
+	_, err = formTimingError(ago(30*time.Second), now)
+	req.NoError(err)
+
+	elapsed, err := formTimingError(ago(time.Second), now)
+	req.Error(err)
+	req.EqualValues(1, elapsed)
+	_, err = formTimingError(ago(11*time.Minute), now)
+	req.Error(err)
+}
+
+func Test_ScreenDonationBot(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	tooQuick := strconv.FormatInt(now.Unix(), 10)
+	human := strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name     string
+		req      DonationRequest
+		verifier services.CaptchaVerifier
+		blocked  bool
+		message  bool
+	}{
+		{"human", DonationRequest{FormTimestamp: human}, nil, false, false},
+		{"no timestamp", DonationRequest{}, nil, false, false},
+		{"honeypot", DonationRequest{Website: "http://spam.example", FormTimestamp: human}, stubCaptcha{valid: "ok"}, true, false},
+		{"too quick without a captcha", DonationRequest{FormTimestamp: tooQuick}, nil, false, true},
+		{"too quick asks for the captcha", DonationRequest{FormTimestamp: tooQuick}, stubCaptcha{valid: "ok"}, false, true},
+		{"too quick with a bad captcha", DonationRequest{FormTimestamp: tooQuick, CaptchaToken: "reused"}, stubCaptcha{valid: "ok"}, false, true},
+		{"too quick with the captcha solved", DonationRequest{FormTimestamp: tooQuick, CaptchaToken: "ok"}, stubCaptcha{valid: "ok"}, false, false},
+		{"captcha unreachable", DonationRequest{FormTimestamp: tooQuick}, stubCaptcha{err: errors.New("timeout")}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+			var check donationBotCheck
+			app := buffalo.New(buffalo.Options{Env: "test"})
+			app.POST("/donate", func(c buffalo.Context) error {
+				check = screenDonationBot(c, tt.req, tt.verifier, now)
+				return c.Render(http.StatusOK, r.String("ok"))
+			})
+			app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/donate", nil))
+
+			req.Equal(tt.blocked, check.Blocked)
+			req.Equal(tt.message, check.Message != "", "message %q", check.Message)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package actions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+)
+
+// donationFlowTTL is how long a donor has to finish paying after filling in
+// the donation form
+const donationFlowTTL = 2 * time.Hour
+
+var (
+	errFlowMalformed = errors.New("malformed donation flow token")
+	errFlowSignature = errors.New("donation flow token signature mismatch")
+	errFlowExpired   = errors.New("donation flow token expired")
+)
+
+// donationFlowSecret signs flow tokens. It's the session secret, so rotating
+// that also invalidates payment links still in flight.
+func donationFlowSecret() []byte {
+	return []byte(envy.Get("SESSION_SECRET", "development-session-secret-change-in-production"))
+}
+
+func donationFlowMAC(payload string) string {
+	h := hmac.New(sha256.New, donationFlowSecret())
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// signDonationFlow returns a tamper-proof token naming the pending donation a
+// payment page is for. The payment page is driven by the token rather than
+// the session, so donors with several tabs open each pay for the gift they
+// filled in.
+func signDonationFlow(donationID uuid.UUID, now time.Time) string {
+	payload := fmt.Sprintf("%s.%d", donationID, now.Add(donationFlowTTL).Unix())
+	return payload + "." + donationFlowMAC(payload)
+}
+
+// verifyDonationFlow checks a token made by signDonationFlow and returns the
+// donation it names
+func verifyDonationFlow(token string, now time.Time) (uuid.UUID, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return uuid.Nil, errFlowMalformed
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(donationFlowMAC(payload))) {
+		return uuid.Nil, errFlowSignature
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return uuid.Nil, errFlowMalformed
+	}
+	if now.Unix() > expires {
+		return uuid.Nil, errFlowExpired
+	}
+	id, err := uuid.FromString(parts[0])
+	if err != nil {
+		return uuid.Nil, errFlowMalformed
+	}
+	return id, nil
+}
+
+// donationPaymentPath is the payment page for a pending donation
+func donationPaymentPath(donation *models.Donation) string {
+	return "/donate/payment?flow=" + url.QueryEscape(signDonationFlow(donation.ID, time.Now()))
+}
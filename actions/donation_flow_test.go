@@ -0,0 +1,33 @@
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DonationFlowToken(t *testing.T) {
+	req := require.New(t)
+
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	id := uuid.Must(uuid.NewV4())
+	token := signDonationFlow(id, now)
+
+	got, err := verifyDonationFlow(token, now.Add(time.Hour))
+	req.NoError(err)
+	req.Equal(id, got)
+
+	_, err = verifyDonationFlow(token, now.Add(donationFlowTTL+time.Minute))
+	req.ErrorIs(err, errFlowExpired)
+
+	// Pointing a token at another donation breaks the signature
+	other := uuid.Must(uuid.NewV4())
+	tampered := other.String() + token[len(id.String()):]
+	_, err = verifyDonationFlow(tampered, now)
+	req.ErrorIs(err, errFlowSignature)
+
+	_, err = verifyDonationFlow("", now)
+	req.ErrorIs(err, errFlowMalformed)
+}
@@ -0,0 +1,292 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+const (
+	defaultDonationMinimum         = 1.00
+	defaultDonationMaximum         = 100000.00
+	defaultDonationReviewThreshold = 10000.00
+)
+
+// envFloat reads a numeric env var, falling back to def when unset or invalid.
+func envFloat(key string, def float64) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(envy.Get(key, "")), 64)
+	if err != nil || v < 0 {
+		return def
+	}
+	return v
+}
+
+// donationMinimum is the smallest gift accepted (DONATION_MIN_AMOUNT).
+func donationMinimum() float64 {
+	return envFloat("DONATION_MIN_AMOUNT", defaultDonationMinimum)
+}
+
+// donationMaximum is the largest gift accepted (DONATION_MAX_AMOUNT). Gifts
+// between the review threshold and this are held for review rather than
+// refused. Zero removes the limit.
+func donationMaximum() float64 {
+	return envFloat("DONATION_MAX_AMOUNT", defaultDonationMaximum)
+}
+
+// donationReviewThreshold is the soft cap above which gifts are held for an
+// admin to approve before the card is charged (DONATION_REVIEW_THRESHOLD).
+// Zero turns the review queue off.
+func donationReviewThreshold() float64 {
+	return envFloat("DONATION_REVIEW_THRESHOLD", defaultDonationReviewThreshold)
+}
+
+// requiresManualReview reports whether amount is over the soft cap.
+func requiresManualReview(amount float64) bool {
+	threshold := donationReviewThreshold()
+	return threshold > 0 && amount > threshold
+}
+
+// holdDonationForReview keeps the verified card on the donation and parks it
+// in the review queue instead of charging it.
+func holdDonationForReview(c buffalo.Context, tx *pop.Connection, donation *models.Donation, customerCode, cardToken string) error {
+	reason := fmt.Sprintf("Amount $%.2f is over the $%.2f review threshold", donation.PledgeAmount(), donationReviewThreshold())
+
+	donation.Status = models.DonationStatusPendingReview
+	donation.CustomerID = &customerCode
+	donation.CardToken = &cardToken
+	donation.ReviewReason = &reason
+
+	if err := tx.Update(donation); err != nil {
+		c.Logger().Errorf("[DonationReview] Failed to hold donation %s for review: %v", donation.ID.String(), err)
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to update donation")
+	}
+
+	logging.Audit("donation_held_for_review", logging.Fields{
+		"donation_id": donation.ID.String(),
+		"amount":      donation.PledgeAmount(),
+		"type":        donation.DonationType,
+	})
+	publishDonationActivity(donation)
+
+	return c.Render(http.StatusOK, donationReviewResponse())
+}
+
+func donationReviewResponse() render.Renderer {
+	return r.JSON(map[string]interface{}{
+		"success": true,
+		"status":  models.DonationStatusPendingReview,
+		"message": "Thank you! Because of its size, your gift will be reviewed by our team before your card is charged.",
+	})
+}
+
+// chargeReviewedDonation charges an approved donation with the card that was
+// verified at checkout and returns the Helcim transaction or subscription ID.
+func chargeReviewedDonation(ctx context.Context, tx *pop.Connection, donation *models.Donation, ip string) (string, error) {
+	customerCode := stringOrEmpty(donation.CustomerID)
+	cardToken := stringOrEmpty(donation.CardToken)
+	if customerCode == "" || cardToken == "" {
+		return "", errors.New("donation has no verified card on file")
+	}
+
+	client := services.NewHelcimClient()
+	var reference string
+
+	if donation.DonationType == models.DonationTypeMonthly || donation.IsInstallmentPledge() {
+		planID, err := recurringPlanFor(ctx, client, donation)
+		if err != nil {
+			return "", errors.Wrap(err, "setting up payment plan")
+		}
+		subscription, err := client.CreateSubscription(ctx, services.SubscriptionRequest{
+			CustomerID:    customerCode,
+			PaymentPlanID: planID,
+			Amount:        donation.Amount,
+			PaymentMethod: "card",
+		})
+		if err != nil {
+			return "", errors.Wrap(err, "creating subscription")
+		}
+
+		reference = fmt.Sprintf("%d", subscription.ID)
+		planIDStr := fmt.Sprintf("%d", planID)
+		donation.SubscriptionID = &reference
+		donation.PaymentPlanID = &planIDStr
+		donation.NextBillingDate = &subscription.NextBillingDate
+		donation.Status = "active"
+	} else {
+		paymentReq := services.PaymentAPIRequest{
+			PaymentType:   "purchase",
+			Amount:        donation.Amount,
+			Currency:      getCurrency(),
+			CustomerCode:  customerCode,
+			IPAddress:     ip,
+			Description:   "Donation to " + organization().Name,
+			CustomerEmail: donation.DonorEmail,
+			CustomerName:  donation.DonorName,
+			BillingAddress: &services.BillingAddress{
+				Name:       donation.DonorName,
+				Street1:    stringOrEmpty(donation.AddressLine1),
+				City:       stringOrEmpty(donation.City),
+				Province:   stringOrEmpty(donation.State),
+				Country:    "USA",
+				PostalCode: stringOrEmpty(donation.Zip),
+			},
+		}
+		setPaymentSource(&paymentReq, donation, cardToken)
+		transaction, err := client.ProcessPayment(ctx, paymentReq)
+		if err != nil {
+			return "", errors.Wrap(err, "processing payment")
+		}
+
+		reference = fmt.Sprintf("%d", transaction.TransactionID)
+		donation.TransactionID = &reference
+		donation.Status = "completed"
+		if donation.PaysByBank() {
+			donation.Status = models.DonationStatusSettling
+		}
+	}
+
+	donation.CardToken = nil
+	if err := tx.Update(donation); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if donation.IsInstallmentPledge() {
+		if _, err := recordInstallment(tx, donation, 1, ""); err != nil {
+			return reference, err
+		}
+	}
+	return reference, nil
+}
+
+// AdminDonationReviews lists gifts waiting for an approve/decline decision
+func AdminDonationReviews(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	donations := models.Donations{}
+	if err := tx.Where("status IN (?, ?)", models.DonationStatusPendingReview, models.DonationStatusFlagged).Order("created_at asc").All(&donations); err != nil {
+		return errors.WithStack(err)
+	}
+
+	emails := make([]string, len(donations))
+	for i, d := range donations {
+		emails[i] = d.DonorEmail
+	}
+	flags, err := loadDonorFlagIndex(tx, emails)
+	if err != nil {
+		return err
+	}
+
+	c.Set("donations", donations)
+	c.Set("flags", flags)
+	c.Set("threshold", donationReviewThreshold())
+	return c.Render(http.StatusOK, r.HTML("admin/donation_reviews.plush.html"))
+}
+
+// findReviewDonation loads a donation that is still awaiting review.
+func findReviewDonation(c buffalo.Context) (*models.Donation, error) {
+	tx := c.Value("tx").(*pop.Connection)
+	donation := &models.Donation{}
+	if err := tx.Find(donation, c.Param("donation_id")); err != nil {
+		return nil, err
+	}
+	if !donation.AwaitingReview() {
+		return nil, errors.Errorf("donation %s is not awaiting review", donation.ID)
+	}
+	return donation, nil
+}
+
+// markReviewed stamps the reviewing admin and note on a donation.
+func markReviewed(c buffalo.Context, donation *models.Donation) *models.User {
+	user := c.Value("current_user").(*models.User)
+	now := time.Now()
+	donation.ReviewedBy = &user.ID
+	donation.ReviewedAt = &now
+	if note := strings.TrimSpace(c.Param("note")); note != "" {
+		donation.ReviewNote = &note
+	}
+	return user
+}
+
+// AdminDonationApprove charges a held donation and sends the donor's receipt
+func AdminDonationApprove(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	donation, err := findReviewDonation(c)
+	if err != nil {
+		c.Flash().Add("warning", "That donation is no longer awaiting review.")
+		return c.Redirect(http.StatusSeeOther, "/admin/donations/review")
+	}
+	user := markReviewed(c, donation)
+
+	reference, err := chargeReviewedDonation(c, tx, donation, getClientIP(c))
+	if err != nil {
+		c.Logger().Errorf("[DonationReview] Charging approved donation %s failed: %v", donation.ID.String(), err)
+		c.Flash().Add("danger", "The charge failed: "+err.Error())
+		return c.Redirect(http.StatusSeeOther, "/admin/donations/review")
+	}
+
+	logging.UserAction(c, user.Email, "donation_review_approved", "Approved held donation", logging.Fields{
+		"donation_id": donation.ID.String(),
+		"amount":      donation.Amount,
+		"reference":   reference,
+	})
+
+	// Bank transfers are receipted by the webhook once they settle
+	if donation.PaysByBank() {
+		c.Flash().Add("success", fmt.Sprintf("Approved $%.2f from %s. The bank transfer has been submitted and will be receipted once it settles.", donation.Amount, donation.DonorName))
+		return c.Redirect(http.StatusSeeOther, "/admin/donations/review")
+	}
+
+	activateGiftCode(c, tx, donation)
+	completeAuctionPayment(c, tx, donation)
+	completeStoreOrder(c, tx, donation)
+	notifyDonationCompleted(tx, donation)
+
+	receipt := paymentReceiptData(tx, donation, reference)
+	receipt.NextBillingDate = donation.NextBillingDate
+	if donation.IsInstallmentPledge() {
+		receipt.DonationType = recurringReceiptLabel(donation, 1)
+	}
+	queueReceipt(tx, donation, receipt, receiptSummary(donation))
+
+	c.Flash().Add("success", fmt.Sprintf("Approved and charged $%.2f from %s.", donation.Amount, donation.DonorName))
+	return c.Redirect(http.StatusSeeOther, "/admin/donations/review")
+}
+
+// AdminDonationDecline declines a held donation without charging it
+func AdminDonationDecline(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	donation, err := findReviewDonation(c)
+	if err != nil {
+		c.Flash().Add("warning", "That donation is no longer awaiting review.")
+		return c.Redirect(http.StatusSeeOther, "/admin/donations/review")
+	}
+	user := markReviewed(c, donation)
+
+	donation.Status = models.DonationStatusDeclined
+	donation.CardToken = nil
+	if err := tx.Update(donation); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.UserAction(c, user.Email, "donation_review_declined", "Declined held donation", logging.Fields{
+		"donation_id": donation.ID.String(),
+		"amount":      donation.Amount,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Declined the $%.2f gift from %s. The card was not charged.", donation.Amount, donation.DonorName))
+	return c.Redirect(http.StatusSeeOther, "/admin/donations/review")
+}
@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RequiresManualReview(t *testing.T) {
+	r := require.New(t)
+
+	envy.Temp(func() {
+		envy.Set("DONATION_REVIEW_THRESHOLD", "")
+		r.False(requiresManualReview(10000))
+		r.True(requiresManualReview(10000.01))
+
+		envy.Set("DONATION_REVIEW_THRESHOLD", "500")
+		r.False(requiresManualReview(500))
+		r.True(requiresManualReview(750))
+
+		envy.Set("DONATION_REVIEW_THRESHOLD", "0")
+		r.False(requiresManualReview(1000000))
+	})
+}
+
+func Test_DonationMinimum(t *testing.T) {
+	r := require.New(t)
+
+	envy.Temp(func() {
+		envy.Set("DONATION_MIN_AMOUNT", "")
+		r.Equal(defaultDonationMinimum, donationMinimum())
+
+		envy.Set("DONATION_MIN_AMOUNT", "5")
+		r.Equal(5.0, donationMinimum())
+
+		envy.Set("DONATION_MIN_AMOUNT", "not-a-number")
+		r.Equal(defaultDonationMinimum, donationMinimum())
+	})
+}
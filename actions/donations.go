@@ -2,12 +2,14 @@ package actions
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"net/http"
 	"os"
@@ -16,10 +18,12 @@ import (
 	"time"
 
 	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
 	"avrnpo.org/services"
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate"
+	"github.com/pkg/errors"
 )
 
 // getCurrency returns the configured currency with a fallback to USD
@@ -175,20 +179,40 @@ type HelcimPayResponse struct {
 
 // DonationRequest represents the donation form data
 type DonationRequest struct {
-	Amount       interface{} `json:"amount" form:"amount"`
-	CustomAmount string      `json:"custom_amount" form:"custom_amount"`
-	DonationType string      `json:"donation_type" form:"donation_type"`
-	FirstName    string      `json:"first_name" form:"first_name"`
-	LastName     string      `json:"last_name" form:"last_name"`
-	DonorName    string      `json:"donor_name" form:"donor_name"`
-	DonorEmail   string      `json:"donor_email" form:"donor_email"`
-	DonorPhone   string      `json:"donor_phone" form:"donor_phone"`
-	AddressLine1 string      `json:"address_line1" form:"address_line1"`
-	AddressLine2 string      `json:"address_line2" form:"address_line2"`
-	City         string      `json:"city" form:"city"`
-	State        string      `json:"state" form:"state"`
-	Zip          string      `json:"zip_code" form:"zip_code"`
-	Comments     string      `json:"comments" form:"comments"`
+	Amount       interface{}              `json:"amount" form:"amount"`
+	CustomAmount string                   `json:"custom_amount" form:"custom_amount"`
+	PresetAmount string                   `json:"preset_amount" form:"preset_amount"`
+	DonationType models.DonationType      `json:"donation_type" form:"donation_type"`
+	FirstName    string                   `json:"first_name" form:"first_name"`
+	LastName     string                   `json:"last_name" form:"last_name"`
+	DonorName    string                   `json:"donor_name" form:"donor_name"`
+	DonorEmail   string                   `json:"donor_email" form:"donor_email"`
+	DonorPhone   string                   `json:"donor_phone" form:"donor_phone"`
+	AddressLine1 string                   `json:"address_line1" form:"address_line1"`
+	AddressLine2 string                   `json:"address_line2" form:"address_line2"`
+	City         string                   `json:"city" form:"city"`
+	State        string                   `json:"state" form:"state"`
+	Zip          string                   `json:"zip_code" form:"zip_code"`
+	Comments     string                   `json:"comments" form:"comments"`
+	Installments string                   `json:"installments" form:"installments"`
+	Frequency    models.DonationFrequency `json:"frequency" form:"frequency"`
+	PartnerSlug  string                   `json:"partner_slug" form:"partner_slug"`
+	// "card" (the default) or "bank" for a bank transfer (ACH)
+	PaymentMethod string `json:"payment_method" form:"payment_method"`
+	// Gift card purchases
+	GiftCard           string `json:"gift_card" form:"gift_card"`
+	GiftRecipientName  string `json:"gift_recipient_name" form:"gift_recipient_name"`
+	GiftRecipientEmail string `json:"gift_recipient_email" form:"gift_recipient_email"`
+	GiftMessage        string `json:"gift_message" form:"gift_message"`
+	// Answers to admin-defined form fields, keyed by field input name. Form
+	// posts send these as custom_* parameters instead.
+	CustomFields map[string]string `json:"custom_fields" form:"-"`
+	// Bot protection: Website is the hidden honeypot, FormTimestamp when
+	// the form was rendered, and CaptchaToken the Turnstile response when
+	// the donor was asked to solve one
+	Website       string `json:"website" form:"website"`
+	FormTimestamp string `json:"form_timestamp" form:"form_timestamp"`
+	CaptchaToken  string `json:"cf-turnstile-response" form:"cf-turnstile-response"`
 }
 
 // HelcimPayVerifyRequest represents a verify request to Helcim (unified approach)
@@ -196,7 +220,12 @@ type HelcimPayVerifyRequest struct {
 	PaymentType     string                    `json:"paymentType"`
 	Amount          float64                   `json:"amount"`
 	Currency        string                    `json:"currency"`
-	CustomerRequest *services.CustomerRequest `json:"customerRequest"`
+	CustomerRequest *services.CustomerRequest `json:"customerRequest,omitempty"`
+	// CustomerCode saves the verified card to an existing Helcim customer
+	CustomerCode string `json:"customerCode,omitempty"`
+	// PaymentMethod is "ach" for bank transfers; HelcimPay.js asks for a
+	// card when it's empty
+	PaymentMethod string `json:"paymentMethod,omitempty"`
 }
 
 // Webhook event structures for Helcim's actual format
@@ -252,9 +281,7 @@ func DonationInitializeHandler(c buffalo.Context) error {
 		// If CSRF token is missing/invalid, mw-csrf will already have returned 403 before reaching here.
 		// For API requests with malformed JSON, return 400.
 		if isAPIRequest(c) {
-			return c.Render(http.StatusBadRequest, r.JSON(map[string]string{
-				"error": "Invalid request data",
-			}))
+			return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid request data")
 		}
 		// For form submissions, redirect back with error
 		c.Flash().Add("error", "Invalid form data submitted")
@@ -268,6 +295,14 @@ func DonationInitializeHandler(c buffalo.Context) error {
 	// Use Buffalo's validate.Errors for field-specific error collection
 	errors := validate.NewErrors()
 
+	bot := screenDonationBot(c, req, services.NewCaptchaVerifier(), time.Now())
+	if bot.Blocked {
+		return rejectDonationBot(c)
+	}
+	if bot.Message != "" {
+		errors.Add(captchaErrorKey, bot.Message)
+	}
+
 	if strings.TrimSpace(req.FirstName) == "" {
 		errors.Add("first_name", "First name is required")
 	}
@@ -293,8 +328,11 @@ func DonationInitializeHandler(c buffalo.Context) error {
 	if strings.TrimSpace(req.Zip) == "" {
 		errors.Add("zip_code", "ZIP Code is required")
 	}
+	if !req.DonationType.Valid() {
+		errors.Add("donation_type", "Invalid donation frequency selected")
+	}
 
-	// Determine donation amount - check both form and session
+	// Determine donation amount from the form
 	var amount float64
 	var err error
 
@@ -308,46 +346,55 @@ func DonationInitializeHandler(c buffalo.Context) error {
 		}
 	}
 
-	// If still no amount, check session (fallback)
-	if amountStr == "" {
-		if sessionAmount := c.Session().Get("donation_amount"); sessionAmount != nil {
-			if s, ok := sessionAmount.(string); ok {
-				amountStr = s
-			}
+	if strings.TrimSpace(amountStr) == "" {
+		errors.Add("amount", "Donation amount is required")
+	} else {
+		amount, err = parseDonationAmount(amountStr, getCurrency())
+		if err != nil {
+			errors.Add("amount", err.Error())
 		}
 	}
 
-	// Normalize money strings like "$25.00" or "25,00" -> "25.00"
-	if amountStr != "" {
-		// Remove currency symbols and commas
-		amountStr = strings.ReplaceAll(amountStr, "$", "")
-		amountStr = strings.ReplaceAll(amountStr, ",", "")
+	for field, msg := range giftRequestErrors(req) {
+		errors.Add(field, msg)
+	}
+	for field, msg := range paymentMethodErrors(req) {
+		errors.Add(field, msg)
 	}
 
-	if strings.TrimSpace(amountStr) == "" {
-		errors.Add("amount", "Donation amount is required")
-	} else {
-		amount, err = strconv.ParseFloat(amountStr, 64)
-		if err != nil || amount <= 0 {
-			errors.Add("amount", "Donation amount must be greater than zero")
+	customFields := donationFormFields(c, req.PartnerSlug)
+	customAnswers, customErrs := customFieldAnswers(customFields, customFieldValues(c, req))
+	for field, msg := range customErrs {
+		errors.Add(field, msg)
+	}
+
+	// Installment pledges split the entered amount into equal monthly payments
+	installmentCount := 0
+	if req.DonationType == models.DonationTypeInstallment {
+		n, ok := parseInstallmentCount(req.Installments)
+		if !ok {
+			errors.Add("donation_type", "Please choose how many monthly installments to split your pledge into")
+		} else if amount > 0 {
+			if msg := installmentMinimumError(amount, n, getCurrency()); msg != "" {
+				errors.Add("amount", msg)
+			}
 		}
+		installmentCount = n
+	}
+
+	// Open-ended recurring gifts can be charged monthly, quarterly or annually
+	frequency := models.NormalizeDonationFrequency(string(req.Frequency))
+	if req.DonationType == models.DonationTypeMonthly && !frequency.Valid() {
+		errors.Add("frequency", "Please choose how often you'd like to give")
 	}
 
 	// If there are any errors, render the form with errors and user input
 	if errors.HasAny() {
 		c.Logger().Warnf("[DonationInitialize] Validation failed - Errors: %v", errors.Errors)
+		if isAPIRequest(c) {
+			return jsonValidationError(c, errors.Errors)
+		}
 		c.Set("errors", errors)
-		c.Set("hasAnyErrors", errors.HasAny())
-		c.Set("hasCommentsError", errors.Get("comments") != nil)
-		c.Set("hasAmountError", errors.Get("amount") != nil)
-		c.Set("hasFirstNameError", errors.Get("first_name") != nil)
-		c.Set("hasLastNameError", errors.Get("last_name") != nil)
-		c.Set("hasDonorEmailError", errors.Get("donor_email") != nil)
-		c.Set("hasDonorPhoneError", errors.Get("donor_phone") != nil)
-		c.Set("hasAddressLine1Error", errors.Get("address_line1") != nil)
-		c.Set("hasCityError", errors.Get("city") != nil)
-		c.Set("hasStateError", errors.Get("state") != nil)
-		c.Set("hasZipError", errors.Get("zip_code") != nil)
 		c.Set("comments", req.Comments)
 
 		// Convert amount to string to avoid template rendering issues
@@ -428,10 +475,22 @@ func DonationInitializeHandler(c buffalo.Context) error {
 		Zip:          stringPointer(req.Zip),
 		Amount:       amount,
 		Currency:     getCurrency(),
-		DonationType: req.DonationType, // "one-time" or "monthly"
+		DonationType: req.DonationType,
+		Frequency:    models.FrequencyMonthly,
 		Status:       "pending",
 		Comments:     stringPointer(req.Comments),
 	}
+	donation.SetCustomAnswers(customAnswers)
+	applyPaymentMethod(req, donation, &helcimReq)
+
+	if req.DonationType == models.DonationTypeMonthly {
+		donation.Frequency = frequency
+	}
+	if installmentCount > 0 {
+		donation.InstallmentCount = installmentCount
+		donation.Amount, donation.PledgeTotal = splitPledge(amount, installmentCount)
+		amount = donation.Amount
+	}
 
 	// Link to user account if logged in
 	if currentUser, ok := c.Value("current_user").(*models.User); ok && currentUser != nil {
@@ -441,7 +500,7 @@ func DonationInitializeHandler(c buffalo.Context) error {
 	// Ensure amount is valid before saving - extra safeguard
 	if amount <= 0 {
 		if isAPIRequest(c) {
-			return c.Render(http.StatusBadRequest, r.JSON(map[string]string{"error": "Invalid donation amount"}))
+			return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid donation amount")
 		}
 		c.Flash().Add("error", "Invalid donation amount. Please try again.")
 		setDonateContext(c, nil)
@@ -451,18 +510,26 @@ func DonationInitializeHandler(c buffalo.Context) error {
 	// Save to database
 	c.Logger().Infof("[DonationInitialize] Saving donation to database - ID will be generated")
 	tx := c.Value("tx").(*pop.Connection)
+	attachPartner(c, tx, donation, req.PartnerSlug)
 	if err := tx.Create(donation); err != nil {
 		c.Logger().Errorf("[DonationInitialize] Failed to create donation record: %v", err)
 		if isAPIRequest(c) {
-			return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{
-				"error": "Failed to create donation record",
-			}))
+			return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to create donation record")
 		}
 		c.Flash().Add("error", "System error occurred. Please try again.")
 		ensureDonateContext(c)
 		return c.Redirect(http.StatusSeeOther, "/donate")
 	}
 	c.Logger().Infof("[DonationInitialize] Donation record created successfully - ID: %s", donation.ID.String())
+	if err := createPendingGiftCode(tx, donation, req); err != nil {
+		c.Logger().Errorf("[DonationInitialize] Failed to create gift code for donation %s: %v", donation.ID.String(), err)
+		if isAPIRequest(c) {
+			return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to create gift code")
+		}
+		c.Flash().Add("error", "System error occurred. Please try again.")
+		ensureDonateContext(c)
+		return c.Redirect(http.StatusSeeOther, "/donate")
+	}
 
 	// Call Helcim API with verify request
 	c.Logger().Infof("[DonationInitialize] Calling Helcim verify API for donation %s", donation.ID.String())
@@ -470,9 +537,7 @@ func DonationInitializeHandler(c buffalo.Context) error {
 	if err != nil {
 		c.Logger().Errorf("[DonationInitialize] Helcim API error for donation %s: %v", donation.ID.String(), err)
 		if isAPIRequest(c) {
-			return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{
-				"error": "Payment system unavailable. Please try again later.",
-			}))
+			return jsonError(c, http.StatusInternalServerError, codePaymentUnavailable, "Payment system unavailable. Please try again later.")
 		}
 		c.Flash().Add("error", "Payment system unavailable. Please try again later.")
 		ensureDonateContext(c)
@@ -489,9 +554,7 @@ func DonationInitializeHandler(c buffalo.Context) error {
 	if err := tx.Update(donation); err != nil {
 		c.Logger().Errorf("[DonationInitialize] Database error updating donation %s: %v", donation.ID.String(), err)
 		if isAPIRequest(c) {
-			return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{
-				"error": "Failed to update donation record",
-			}))
+			return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to update donation record")
 		}
 		c.Flash().Add("error", "System error occurred. Please try again.")
 		ensureDonateContext(c)
@@ -514,14 +577,8 @@ func DonationInitializeHandler(c buffalo.Context) error {
 
 	// For form submissions, redirect to payment processing page
 	c.Logger().Infof("[DonationInitialize] Redirecting to payment page for donation %s", donation.ID.String())
-	// Store checkout data in session for the payment page
-	// Store amount as formatted string to avoid template rendering issues
-	c.Session().Set("donation_id", donation.ID.String())
-	c.Session().Set("checkout_token", helcimResponse.CheckoutToken)
-	c.Session().Set("amount", fmt.Sprintf("%.2f", amount))
-	c.Session().Set("donor_name", donorName)
 	ensureDonateContext(c)
-	return c.Redirect(http.StatusSeeOther, "/donate/payment")
+	return c.Redirect(http.StatusSeeOther, donationPaymentPath(donation))
 }
 
 // DonationCompleteHandler handles successful donation completion
@@ -535,25 +592,19 @@ func DonationCompleteHandler(c buffalo.Context) error {
 	}
 
 	if err := c.Bind(&completionData); err != nil {
-		return c.Render(http.StatusBadRequest, r.JSON(map[string]string{
-			"error": "Invalid completion data",
-		}))
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid completion data")
 	}
 
 	// Get database connection
 	tx, ok := c.Value("tx").(*pop.Connection)
 	if !ok {
-		return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{
-			"error": "Database connection error",
-		}))
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Database connection error")
 	}
 
 	// Find donation record
 	donation := &models.Donation{}
 	if err := tx.Find(donation, donationID); err != nil {
-		return c.Render(http.StatusNotFound, r.JSON(map[string]string{
-			"error": "Donation not found",
-		}))
+		return jsonError(c, http.StatusNotFound, codeNotFound, "Donation not found")
 	}
 	// Update donation with transaction details
 	donation.HelcimTransactionID = &completionData.TransactionID
@@ -562,33 +613,24 @@ func DonationCompleteHandler(c buffalo.Context) error {
 
 	if err := tx.Update(donation); err != nil {
 		c.Logger().Errorf("Error updating donation: %v", err)
-		return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{
-			"error": "Failed to update donation record",
-		}))
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to update donation record")
 	}
 
 	// Send donation receipt email if payment was successful
 	if completionData.Status == "APPROVED" {
-		emailService := services.NewEmailService()
 		// Prepare receipt data
-		// Map stored donation type to display label
-		displayType := donation.DonationType
-		if displayType == "monthly" {
-			displayType = "Monthly"
-		} else {
-			displayType = "One-time"
-		}
-
 		receiptData := services.DonationReceiptData{
 			DonorName:           donation.DonorName,
 			DonationAmount:      donation.Amount,
-			DonationType:        displayType,
+			DonationType:        donation.TypeLabel(),
 			TransactionID:       *donation.HelcimTransactionID, // Dereference pointer
 			DonationDate:        donation.CreatedAt,
-			TaxDeductibleAmount: donation.Amount, // Full amount is tax deductible
-			OrganizationEIN:     os.Getenv("ORGANIZATION_EIN"),
-			OrganizationName:    "American Veterans Rebuilding",
-			OrganizationAddress: os.Getenv("ORGANIZATION_ADDRESS"),
+			TaxDeductibleAmount: donation.TaxDeductibleAmount(),
+			FairMarketValue:     donation.GoodsValue(),
+			GoodsProvided:       stringOrEmpty(donation.GoodsDescription),
+			OrganizationEIN:     organization().EIN,
+			OrganizationName:    organization().Name,
+			OrganizationAddress: organization().AddressText(),
 			DonorAddressLine1:   stringOrEmpty(donation.AddressLine1),
 			DonorAddressLine2:   stringOrEmpty(donation.AddressLine2),
 			DonorCity:           stringOrEmpty(donation.City),
@@ -597,14 +639,11 @@ func DonationCompleteHandler(c buffalo.Context) error {
 		}
 
 		// stringOrEmpty safely dereferences a *string, returning "" if nil
+		addThankYouToReceipt(tx, donation, &receiptData)
 
-		// Send receipt email
-		if err := emailService.SendDonationReceipt(donation.DonorEmail, receiptData); err != nil {
-			// Log error but don't fail the request - donation was still successful
-			c.Logger().Errorf("Failed to send donation receipt email: %v", err)
-		} else {
-			c.Logger().Infof("Donation receipt sent to %s for transaction %s", donation.DonorEmail, *donation.HelcimTransactionID)
-		}
+		// Queue the receipt email; it's sent in the background once this commits
+		queueReceipt(tx, donation, receiptData, receiptSummary(donation))
+		c.Logger().Infof("Donation receipt queued for %s for transaction %s", donation.DonorEmail, *donation.HelcimTransactionID)
 	}
 
 	return c.Render(http.StatusOK, r.JSON(map[string]interface{}{
@@ -620,17 +659,13 @@ func DonationStatusHandler(c buffalo.Context) error {
 	// Get database connection
 	tx, ok := c.Value("tx").(*pop.Connection)
 	if !ok {
-		return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{
-			"error": "Database connection error",
-		}))
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Database connection error")
 	}
 
 	// Find donation record
 	donation := &models.Donation{}
 	if err := tx.Find(donation, donationID); err != nil {
-		return c.Render(http.StatusNotFound, r.JSON(map[string]string{
-			"error": "Donation not found",
-		}))
+		return jsonError(c, http.StatusNotFound, codeNotFound, "Donation not found")
 	}
 
 	// Return donation status (without sensitive tokens)
@@ -656,7 +691,7 @@ func HelcimWebhookHandler(c buffalo.Context) error {
 	body, err := io.ReadAll(c.Request().Body)
 	if err != nil {
 		c.Logger().Errorf("[Webhook] Failed to read webhook body: %v", err)
-		return c.Render(http.StatusBadRequest, r.JSON(map[string]string{"error": "Invalid request body"}))
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid request body")
 	}
 
 	c.Logger().Debugf("[Webhook] Raw body length: %d bytes", len(body))
@@ -671,7 +706,7 @@ func HelcimWebhookHandler(c buffalo.Context) error {
 
 	if !verifyWebhookSignature(body, signature) {
 		c.Logger().Errorf("[Webhook] Invalid webhook signature - rejecting request")
-		return c.Render(http.StatusUnauthorized, r.JSON(map[string]string{"error": "Invalid signature"}))
+		return jsonError(c, http.StatusUnauthorized, codeInvalidSignature, "Invalid signature")
 	}
 
 	c.Logger().Infof("[Webhook] Signature verification successful")
@@ -680,7 +715,7 @@ func HelcimWebhookHandler(c buffalo.Context) error {
 	var event HelcimWebhookEvent
 	if err := json.Unmarshal(body, &event); err != nil {
 		c.Logger().Errorf("[Webhook] Failed to parse webhook event: %v", err)
-		return c.Render(http.StatusBadRequest, r.JSON(map[string]string{"error": "Invalid JSON"}))
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
 	}
 
 	// Log the webhook event for debugging (signature verified)
@@ -691,62 +726,46 @@ func HelcimWebhookHandler(c buffalo.Context) error {
 	tx, ok := c.Value("tx").(*pop.Connection)
 	if !ok {
 		c.Logger().Errorf("No database transaction found")
-		return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{"error": "Database error"}))
-	}
-
-	// Process based on event type - Helcim only sends cardTransaction and terminalCancel events
-	switch event.Type {
-	case "cardTransaction":
-		// For cardTransaction events, parse the detailed data from the Data field
-		var webhookData HelcimWebhookData
-		if event.Data != nil {
-			// Convert the map to JSON and then unmarshal to structured data
-			dataJSON, err := json.Marshal(event.Data)
-			if err != nil {
-				c.Logger().Errorf("[Webhook] Failed to marshal event data: %v", err)
-				return c.Render(http.StatusBadRequest, r.JSON(map[string]string{"error": "Invalid event data"}))
-			}
-
-			if err := json.Unmarshal(dataJSON, &webhookData); err != nil {
-				c.Logger().Errorf("[Webhook] Failed to parse webhook data: %v", err)
-				return c.Render(http.StatusBadRequest, r.JSON(map[string]string{"error": "Invalid webhook data format"}))
-			}
-
-			// Log subscription information for recurring payments
-			if webhookData.SubscriptionID != "" {
-				c.Logger().Infof("[Webhook] Recurring payment detected - SubscriptionID: %s, PaymentPlanID: %s, PaymentNumber: %d, NextBillingDate: %s",
-					webhookData.SubscriptionID, webhookData.PaymentPlanID, webhookData.PaymentNumber, webhookData.NextBillingDate)
-			} else {
-				c.Logger().Infof("[Webhook] One-time payment detected - TransactionID: %s", webhookData.TransactionID)
-			}
-
-			// Log detailed transaction information
-			c.Logger().Infof("[Webhook] Transaction details - Amount: $%.2f %s, Status: %s, Customer: %s %s (%s)",
-				webhookData.Amount, webhookData.Currency, webhookData.Status,
-				webhookData.Customer.FirstName, webhookData.Customer.LastName, webhookData.CustomerCode)
-		}
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Database error")
+	}
 
-		// Use transaction ID from webhook data if available, otherwise fall back to event.ID
-		transactionID := event.ID
-		if webhookData.TransactionID != "" {
-			transactionID = webhookData.TransactionID
-		}
+	// Leave the event to the instance already handling it; the provider
+	// retries, and by then it's been recorded as handled
+	claimed, release := claimWebhookEvent(models.WebhookProviderHelcim, event.ID, body)
+	if !claimed {
+		c.Logger().Infof("[Webhook] Event %s is being handled by another instance", event.ID)
+		return c.Render(http.StatusConflict, r.JSON(map[string]string{"status": "in_progress"}))
+	}
+	defer release()
 
-		err = handleCardTransaction(tx, transactionID, c)
-	case "terminalCancel":
-		// Terminal cancellation events - handle if needed
-		c.Logger().Infof("Received terminal cancel event - ignoring for donation system")
-		return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "ignored", "reason": "terminal cancel not applicable"}))
-	default:
-		c.Logger().Warnf("Unknown webhook event type: %s", event.Type)
-		return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "ignored", "reason": "unknown event type"}))
+	// Keep the event so a redelivery isn't processed twice. It's stored
+	// outside the request transaction, which is rolled back if processing
+	// fails, so failed events are still there to inspect and replay.
+	stored, duplicate, err := recordWebhookEvent(models.DB, models.WebhookProviderHelcim, event.ID, event.Type, body, signature)
+	if err != nil {
+		c.Logger().Errorf("[Webhook] Failed to store webhook event %s: %v", event.ID, err)
+	}
+	if duplicate {
+		c.Logger().Infof("[Webhook] Event %s was already processed - skipping redelivery", stored.EventID)
+		return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "duplicate"}))
 	}
 
+	// A handled event is marked in the request transaction, so it only
+	// counts as processed once that commits. A failure rolls the transaction
+	// back, so it's recorded directly to stay visible for replay.
+	status, reason, err := processHelcimEvent(tx, event, c)
 	if err != nil {
+		finishWebhookEvent(models.DB, stored, status, err)
 		c.Logger().Errorf("Error processing webhook event: %v", err)
-		return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{"error": "Processing failed"}))
+		if errors.Is(err, errInvalidWebhookData) {
+			return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid webhook data format")
+		}
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Processing failed")
+	}
+	finishWebhookEvent(tx, stored, status, nil)
+	if status == models.WebhookIgnored {
+		return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "ignored", "reason": reason}))
 	}
-
 	return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "processed"}))
 }
 
@@ -781,6 +800,23 @@ func generateHMACSignature(body []byte, secret string) string {
 
 // handleCardTransaction processes cardTransaction webhook events from Helcim
 func handleCardTransaction(tx *pop.Connection, transactionID string, c buffalo.Context) error {
+	donation, err := completeWebhookDonation(tx, transactionID, c)
+	if err != nil || donation == nil {
+		return err
+	}
+
+	receipt := webhookReceiptData(donation, transactionID)
+	addThankYouToReceipt(tx, donation, &receipt)
+	queueReceipt(tx, donation, receipt, receiptSummary(donation))
+	c.Logger().Infof("Donation receipt queued for transaction %s to %s", transactionID, donation.DonorEmail)
+
+	c.Logger().Infof("Successfully processed cardTransaction webhook for transaction %s", transactionID)
+	return nil
+}
+
+// completeWebhookDonation marks the donation for transactionID completed. It
+// returns a nil donation when the transaction isn't one of ours.
+func completeWebhookDonation(tx *pop.Connection, transactionID string, c buffalo.Context) (*models.Donation, error) {
 	c.Logger().Infof("[Webhook] Processing cardTransaction webhook for transaction ID: %s", transactionID)
 
 	// Find the donation record by Helcim transaction ID
@@ -793,15 +829,22 @@ func handleCardTransaction(tx *pop.Connection, transactionID string, c buffalo.C
 		if err2 != nil {
 			// If we can't find the donation, log it but don't fail the webhook
 			c.Logger().Warnf("[Webhook] Could not find donation for transaction ID: %s - may be external transaction", transactionID)
-			return nil
+			return nil, nil
 		}
 	}
 
 	c.Logger().Infof("[Webhook] Found donation record for transaction %s - ID: %s, Donor: %s, Amount: $%.2f, Type: %s",
 		transactionID, donation.ID.String(), donation.DonorEmail, donation.Amount, donation.DonationType)
 
+	// Already completed, on the payment page or by transaction polling when
+	// this webhook ran late, so it isn't completed or receipted again
+	if donation.Status == "completed" {
+		c.Logger().Infof("[Webhook] Donation %s is already completed - skipping", donation.ID.String())
+		return nil, nil
+	}
+
 	// Enhanced logging for recurring donations
-	if donation.DonationType == "monthly" {
+	if donation.DonationType == models.DonationTypeMonthly {
 		if donation.SubscriptionID != nil {
 			c.Logger().Infof("[Webhook] Recurring donation details - SubscriptionID: %s, PaymentPlanID: %s, Status: %s",
 				*donation.SubscriptionID,
@@ -830,31 +873,28 @@ func handleCardTransaction(tx *pop.Connection, transactionID string, c buffalo.C
 	if err := tx.Save(donation); err != nil {
 		c.Logger().Errorf("[Webhook] Failed to update donation %s status for transaction %s: %v",
 			donation.ID.String(), transactionID, err)
-		return fmt.Errorf("failed to update donation status: %v", err)
+		return nil, fmt.Errorf("failed to update donation status: %v", err)
 	}
 	c.Logger().Infof("[Webhook] Donation %s status updated successfully", donation.ID.String())
+	notifyDonationCompleted(tx, donation)
 
-	// Send receipt email for completed payments
-	emailService := services.NewEmailService()
-
-	// Determine display type for receipt
-	displayType := donation.DonationType
-	if displayType == "monthly" {
-		displayType = "Monthly"
-	} else {
-		displayType = "One-time"
-	}
+	return donation, nil
+}
 
+// webhookReceiptData builds the receipt for a donation completed by a webhook
+func webhookReceiptData(donation *models.Donation, transactionID string) services.DonationReceiptData {
 	receiptData := services.DonationReceiptData{
 		DonorName:           donation.DonorName,
 		DonationAmount:      donation.Amount,
-		DonationType:        displayType,
+		DonationType:        donation.TypeLabel(),
 		TransactionID:       transactionID,
 		DonationDate:        donation.CreatedAt,
-		TaxDeductibleAmount: donation.Amount,
-		OrganizationEIN:     os.Getenv("ORGANIZATION_EIN"),
-		OrganizationName:    "American Veterans Rebuilding",
-		OrganizationAddress: os.Getenv("ORGANIZATION_ADDRESS"),
+		TaxDeductibleAmount: donation.TaxDeductibleAmount(),
+		FairMarketValue:     donation.GoodsValue(),
+		GoodsProvided:       stringOrEmpty(donation.GoodsDescription),
+		OrganizationEIN:     organization().EIN,
+		OrganizationName:    organization().Name,
+		OrganizationAddress: organization().AddressText(),
 		DonorAddressLine1:   stringOrEmpty(donation.AddressLine1),
 		DonorAddressLine2:   stringOrEmpty(donation.AddressLine2),
 		DonorCity:           stringOrEmpty(donation.City),
@@ -867,15 +907,27 @@ func handleCardTransaction(tx *pop.Connection, transactionID string, c buffalo.C
 		receiptData.SubscriptionID = *donation.SubscriptionID
 	}
 
-	if err := emailService.SendDonationReceipt(donation.DonorEmail, receiptData); err != nil {
-		c.Logger().Errorf("Failed to send donation receipt for transaction %s: %v", transactionID, err)
-		// Don't fail the webhook for email issues
-	} else {
-		c.Logger().Infof("Donation receipt sent successfully for transaction %s to %s", transactionID, donation.DonorEmail)
-	}
+	return receiptData
+}
 
-	c.Logger().Infof("Successfully processed cardTransaction webhook for transaction %s", transactionID)
-	return nil
+// paymentReceiptData builds the receipt for a gift charged on the site, with
+// any store order it paid for and the partner page's thank-you
+func paymentReceiptData(tx *pop.Connection, donation *models.Donation, transactionID string) services.DonationReceiptData {
+	receiptData := webhookReceiptData(donation, transactionID)
+	addStoreOrderToReceipt(tx, donation, &receiptData)
+	addThankYouToReceipt(tx, donation, &receiptData)
+	return receiptData
+}
+
+// subscriptionReceiptData builds the receipt sent when a recurring gift or
+// pledge is set up, before any one-time transaction exists
+func subscriptionReceiptData(tx *pop.Connection, donation *models.Donation, subscriptionID string, nextBilling *time.Time) services.DonationReceiptData {
+	receiptData := webhookReceiptData(donation, "")
+	receiptData.DonationType = recurringReceiptLabel(donation, 1)
+	receiptData.SubscriptionID = subscriptionID
+	receiptData.NextBillingDate = nextBilling
+	addThankYouToReceipt(tx, donation, &receiptData)
+	return receiptData
 }
 
 // callHelcimVerifyAPI calls the Helcim API with verify mode for unified payment collection
@@ -962,6 +1014,7 @@ func ProcessPaymentHandler(c buffalo.Context) error {
 	var req struct {
 		CustomerCode  string `json:"customerCode"`
 		CardToken     string `json:"cardToken"`
+		BankToken     string `json:"bankToken"`
 		DonationID    string `json:"donationId"`
 		TransactionID string `json:"transactionId"`
 		Amount        string `json:"amount"` // Accept as string from JavaScript
@@ -969,18 +1022,14 @@ func ProcessPaymentHandler(c buffalo.Context) error {
 
 	if err := c.Bind(&req); err != nil {
 		c.Logger().Errorf("[ProcessPayment] Failed to bind request: %v", err)
-		return c.Render(http.StatusBadRequest, r.JSON(map[string]string{
-			"error": "Invalid request data",
-		}))
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid request data")
 	}
 
 	// Parse amount string to float64
 	amount, err := strconv.ParseFloat(req.Amount, 64)
 	if err != nil {
 		c.Logger().Errorf("[ProcessPayment] Failed to parse amount '%s': %v", req.Amount, err)
-		return c.Render(http.StatusBadRequest, r.JSON(map[string]string{
-			"error": "Invalid amount format",
-		}))
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid amount format")
 	}
 
 	c.Logger().Infof("[ProcessPayment] Request parsed - CustomerCode: %s, DonationID: %s, Amount: $%.2f",
@@ -991,16 +1040,14 @@ func ProcessPaymentHandler(c buffalo.Context) error {
 	// Validate required fields for payment processing
 	if req.CustomerCode == "" {
 		// Generate a customer code if missing (fallback for HelcimPay.js response issues)
-		req.CustomerCode = fmt.Sprintf("DON_%s_%d", req.DonationID, time.Now().Unix())
+		req.CustomerCode = fmt.Sprintf("%s%s_%d", models.FallbackCustomerCodePrefix, req.DonationID, time.Now().Unix())
 		c.Logger().Warnf("[ProcessPayment] Missing customerCode - generated fallback: %s for DonationID: %s",
 			req.CustomerCode, req.DonationID)
 	}
 
 	if req.DonationID == "" {
 		c.Logger().Errorf("[ProcessPayment] Missing donation ID")
-		return c.Render(http.StatusBadRequest, r.JSON(map[string]string{
-			"error": "Missing donation ID",
-		}))
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Missing donation ID")
 	}
 
 	c.Logger().Infof("[ProcessPayment] Validation passed - proceeding with payment for donation %s", req.DonationID)
@@ -1010,14 +1057,43 @@ func ProcessPaymentHandler(c buffalo.Context) error {
 	donation := &models.Donation{}
 	if err := tx.Find(donation, req.DonationID); err != nil {
 		c.Logger().Errorf("[ProcessPayment] Donation not found: %s - Error: %v", req.DonationID, err)
-		return c.Render(http.StatusNotFound, r.JSON(map[string]string{
-			"error": "Donation not found",
-		}))
+		return jsonError(c, http.StatusNotFound, codeNotFound, "Donation not found")
 	}
 
 	c.Logger().Infof("[ProcessPayment] Donation found - ID: %s, Type: %s, Amount: $%.2f, Donor: %s",
 		donation.ID.String(), donation.DonationType, donation.Amount, donation.DonorEmail)
 
+	// Bank transfer gifts are paid with the bank account HelcimPay.js saved
+	if donation.PaysByBank() && req.BankToken != "" {
+		req.CardToken = req.BankToken
+	}
+
+	// Large gifts and ones flagged as likely card testing wait for an admin
+	// to approve them before the card is charged
+	if donation.Status == models.DonationStatusFlagged {
+		return c.Render(http.StatusOK, donationFlaggedResponse())
+	}
+	if donation.AwaitingReview() {
+		return c.Render(http.StatusOK, donationReviewResponse())
+	}
+	if donation.Status == models.DonationStatusDeclined {
+		return jsonError(c, http.StatusConflict, codeDonationClosed, "This donation can no longer be processed")
+	}
+	if reason := donationFraudScreen.Screen(fraudAttempt{
+		IP:        getClientIP(c),
+		Email:     donation.DonorEmail,
+		CardToken: req.CardToken,
+		Amount:    donation.Amount,
+	}); reason != "" {
+		c.Logger().Warnf("[ProcessPayment] Donation %s flagged by fraud screening: %s", donation.ID.String(), reason)
+		return flagDonationForFraud(c, tx, donation, req.CustomerCode, req.CardToken, reason)
+	}
+	if requiresManualReview(donation.PledgeAmount()) {
+		c.Logger().Infof("[ProcessPayment] Donation %s ($%.2f) is over the review threshold - holding for manual review",
+			donation.ID.String(), donation.Amount)
+		return holdDonationForReview(c, tx, donation, req.CustomerCode, req.CardToken)
+	}
+
 	// Create payment request struct with parsed amount
 	var paymentReq = struct {
 		CustomerCode string  `json:"customerCode"`
@@ -1031,7 +1107,7 @@ func ProcessPaymentHandler(c buffalo.Context) error {
 		Amount:       amount,
 	}
 
-	if donation.DonationType == "monthly" {
+	if donation.DonationType == models.DonationTypeMonthly || donation.IsInstallmentPledge() {
 		c.Logger().Infof("[ProcessPayment] Routing to recurring payment handler for donation %s", donation.ID.String())
 		// RECURRING DONATION: Create subscription
 		return handleRecurringPayment(c, paymentReq, donation)
@@ -1042,6 +1118,11 @@ func ProcessPaymentHandler(c buffalo.Context) error {
 	}
 }
 
+// sameAmount reports whether two dollar amounts are equal to the cent
+func sameAmount(a, b float64) bool {
+	return math.Round(a*100) == math.Round(b*100)
+}
+
 // handleOneTimePayment processes a one-time donation using Payment API
 func handleOneTimePayment(c buffalo.Context, req struct {
 	CustomerCode string  `json:"customerCode"`
@@ -1058,16 +1139,22 @@ func handleOneTimePayment(c buffalo.Context, req struct {
 	if donation.Amount <= 0 {
 		c.Logger().Errorf("[OneTimePayment] Refusing to process payment: stored donation amount invalid (%.2f). req.Amount=%.2f donation.ID=%s",
 			donation.Amount, req.Amount, donation.ID.String())
-		return c.Render(http.StatusBadRequest, r.JSON(map[string]interface{}{
-			"success": false,
-			"error":   "Invalid donation amount on server",
-		}))
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid donation amount on server")
 	}
 
-	// Log a mismatch if client-supplied amount differs significantly from stored amount
-	if req.Amount > 0 && (fmt.Sprintf("%.2f", req.Amount) != fmt.Sprintf("%.2f", donation.Amount)) {
-		c.Logger().Warnf("[OneTimePayment] Client amount (%.2f) differs from stored donation amount (%.2f) for donation ID %s",
+	// The stored amount is the one charged, so a page sending any other
+	// amount has been tampered with or is stale; refuse it rather than charge
+	// the donor something they didn't see
+	if !sameAmount(req.Amount, donation.Amount) {
+		c.Logger().Warnf("[OneTimePayment] Refusing payment: client amount (%.2f) differs from stored donation amount (%.2f) for donation ID %s",
 			req.Amount, donation.Amount, donation.ID.String())
+		logging.SecurityEvent(c, "payment_amount_mismatch", "blocked", "client_amount_differs", logging.Fields{
+			"donation_id":   donation.ID.String(),
+			"client_amount": req.Amount,
+			"stored_amount": donation.Amount,
+			"ip":            getClientIP(c),
+		})
+		return jsonError(c, http.StatusConflict, codeAmountMismatch, "The payment amount doesn't match your donation. Please reload the page and try again.")
 	}
 
 	c.Logger().Infof("[OneTimePayment] Amount validation passed - proceeding with payment for $%.2f", donation.Amount)
@@ -1084,6 +1171,9 @@ func handleOneTimePayment(c buffalo.Context, req struct {
 		// Generate a fake transaction ID
 		transactionID := fmt.Sprintf("dev_txn_%d", time.Now().Unix())
 		c.Logger().Debugf("[OneTimePayment] Generated dev transaction ID: %s", transactionID)
+		if donation.PaysByBank() {
+			return startBankTransfer(c, donation, transactionID, req.CustomerCode)
+		}
 
 		// Update donation record
 		donation.TransactionID = &transactionID
@@ -1093,45 +1183,19 @@ func handleOneTimePayment(c buffalo.Context, req struct {
 		tx := c.Value("tx").(*pop.Connection)
 		if err := tx.Update(donation); err != nil {
 			c.Logger().Errorf("[OneTimePayment] Failed to update donation %s: %v", donation.ID.String(), err)
-			return c.Render(http.StatusInternalServerError, r.JSON(map[string]interface{}{
-				"success": false,
-				"error":   "Failed to update donation",
-			}))
+			return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to update donation")
 		}
 		c.Logger().Infof("[OneTimePayment] Donation %s updated successfully with dev transaction", donation.ID.String())
+		activateGiftCode(c, tx, donation)
+		completeAuctionPayment(c, tx, donation)
+		completeStoreOrder(c, tx, donation)
+		notifyDonationCompleted(tx, donation)
 
-		// Send donation receipt email in development
-		emailService := services.NewEmailService()
-		// Map stored donation type to display label for receipt
-		displayType := donation.DonationType
-		if displayType == "monthly" {
-			displayType = "Monthly"
-		} else {
-			displayType = "One-time"
-		}
+		// Queue donation receipt email in development
+		receiptData := paymentReceiptData(tx, donation, transactionID)
 
-		receiptData := services.DonationReceiptData{
-			DonorName:           donation.DonorName,
-			DonationAmount:      donation.Amount,
-			DonationType:        displayType,
-			TransactionID:       transactionID,
-			DonationDate:        donation.CreatedAt,
-			TaxDeductibleAmount: donation.Amount,
-			OrganizationEIN:     os.Getenv("ORGANIZATION_EIN"),
-			OrganizationName:    "American Veterans Rebuilding",
-			OrganizationAddress: os.Getenv("ORGANIZATION_ADDRESS"),
-			DonorAddressLine1:   stringOrEmpty(donation.AddressLine1),
-			DonorAddressLine2:   stringOrEmpty(donation.AddressLine2),
-			DonorCity:           stringOrEmpty(donation.City),
-			DonorState:          stringOrEmpty(donation.State),
-			DonorZip:            stringOrEmpty(donation.Zip),
-		}
-
-		if err := emailService.SendDonationReceipt(donation.DonorEmail, receiptData); err != nil {
-			c.Logger().Errorf("[OneTimePayment] Failed to send donation receipt email for %s: %v", donation.DonorEmail, err)
-		} else {
-			c.Logger().Infof("[OneTimePayment] Development: Donation receipt sent to %s for transaction %s", donation.DonorEmail, transactionID)
-		}
+		queueReceipt(tx, donation, receiptData, receiptSummary(donation))
+		c.Logger().Infof("[OneTimePayment] Development: Donation receipt queued for %s for transaction %s", donation.DonorEmail, transactionID)
 
 		response := map[string]interface{}{
 			"success":       true,
@@ -1150,15 +1214,12 @@ func handleOneTimePayment(c buffalo.Context, req struct {
 	// Generate unique idempotency key for this payment (UUID format)
 	// Use Payment API to charge the card token
 	paymentReq := services.PaymentAPIRequest{
-		PaymentType:  "purchase",
-		Amount:       donation.Amount,
-		Currency:     getCurrency(),
-		CustomerCode: req.CustomerCode,
-		CardData: services.CardData{
-			CardToken: req.CardToken,
-		},
+		PaymentType:   "purchase",
+		Amount:        donation.Amount,
+		Currency:      getCurrency(),
+		CustomerCode:  req.CustomerCode,
 		IPAddress:     getClientIP(c),
-		Description:   "Donation to American Veterans Rebuilding",
+		Description:   "Donation to " + organization().Name,
 		CustomerEmail: donation.DonorEmail,
 		CustomerName:  donation.DonorName,
 		BillingAddress: &services.BillingAddress{
@@ -1171,23 +1232,25 @@ func handleOneTimePayment(c buffalo.Context, req struct {
 		},
 	}
 
-	c.Logger().Debugf("[OneTimePayment] Payment request - Amount: $%.2f, Currency: %s, CustomerCode: %s, CardToken: %s",
-		paymentReq.Amount, paymentReq.Currency, paymentReq.CustomerCode, safePrefix(paymentReq.CardData.CardToken, 8)+"...")
+	setPaymentSource(&paymentReq, donation, req.CardToken)
+
+	c.Logger().Debugf("[OneTimePayment] Payment request - Amount: $%.2f, Currency: %s, CustomerCode: %s, Token: %s",
+		paymentReq.Amount, paymentReq.Currency, paymentReq.CustomerCode, safePrefix(req.CardToken, 8)+"...")
 
-	transaction, err := helcimClient.ProcessPayment(paymentReq)
+	transaction, err := helcimClient.ProcessPayment(c, paymentReq)
 	if err != nil {
 		c.Logger().Errorf("[OneTimePayment] Payment processing failed for donation %s: %v", donation.ID.String(), err)
-		c.Logger().Errorf("[OneTimePayment] Payment request data: Amount=$%.2f, Currency=%s, CustomerCode=%s, CardToken=%s",
-			paymentReq.Amount, paymentReq.Currency, paymentReq.CustomerCode, safePrefix(paymentReq.CardData.CardToken, 8)+"...")
-		return c.Render(http.StatusInternalServerError, r.JSON(map[string]interface{}{
-			"success": false,
-			"error":   "Payment processing failed: " + err.Error(),
-		}))
+		c.Logger().Errorf("[OneTimePayment] Payment request data: Amount=$%.2f, Currency=%s, CustomerCode=%s, Token=%s",
+			paymentReq.Amount, paymentReq.Currency, paymentReq.CustomerCode, safePrefix(req.CardToken, 8)+"...")
+		return helcimFailureJSON(c, err)
 	}
 
 	transactionIDStr := fmt.Sprintf("%d", transaction.TransactionID)
 	c.Logger().Infof("[OneTimePayment] Payment successful - TransactionID: %s, Status: %s",
 		transactionIDStr, transaction.Status)
+	if donation.PaysByBank() {
+		return startBankTransfer(c, donation, transactionIDStr, req.CustomerCode)
+	}
 
 	// Update donation record
 	donation.TransactionID = &transactionIDStr
@@ -1197,47 +1260,21 @@ func handleOneTimePayment(c buffalo.Context, req struct {
 	tx := c.Value("tx").(*pop.Connection)
 	if err := tx.Update(donation); err != nil {
 		c.Logger().Errorf("[OneTimePayment] Failed to update donation %s: %v", donation.ID.String(), err)
-		return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{
-			"error": "Failed to update donation",
-		}))
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to update donation")
 	}
 
 	c.Logger().Infof("[OneTimePayment] Donation %s completed successfully - TransactionID: %s",
 		donation.ID.String(), transaction.TransactionID)
+	activateGiftCode(c, tx, donation)
+	completeAuctionPayment(c, tx, donation)
+	completeStoreOrder(c, tx, donation)
+	notifyDonationCompleted(tx, donation)
 
-	// Send donation receipt email
-	emailService := services.NewEmailService()
-
-	// Map stored donation type to display label for receipt
-	displayType := donation.DonationType
-	if displayType == "monthly" {
-		displayType = "Monthly"
-	} else {
-		displayType = "One-time"
-	}
-
-	receiptData := services.DonationReceiptData{
-		DonorName:           donation.DonorName,
-		DonationAmount:      donation.Amount,
-		DonationType:        displayType,
-		TransactionID:       transactionIDStr,
-		DonationDate:        donation.CreatedAt,
-		TaxDeductibleAmount: donation.Amount,
-		OrganizationEIN:     os.Getenv("ORGANIZATION_EIN"),
-		OrganizationName:    "American Veterans Rebuilding",
-		OrganizationAddress: os.Getenv("ORGANIZATION_ADDRESS"),
-		DonorAddressLine1:   stringOrEmpty(donation.AddressLine1),
-		DonorAddressLine2:   stringOrEmpty(donation.AddressLine2),
-		DonorCity:           stringOrEmpty(donation.City),
-		DonorState:          stringOrEmpty(donation.State),
-		DonorZip:            stringOrEmpty(donation.Zip),
-	}
+	// Queue donation receipt email
+	receiptData := paymentReceiptData(tx, donation, transactionIDStr)
 
-	if err := emailService.SendDonationReceipt(donation.DonorEmail, receiptData); err != nil {
-		c.Logger().Errorf("[OneTimePayment] Failed to send donation receipt email for %s: %v", donation.DonorEmail, err)
-	} else {
-		c.Logger().Infof("[OneTimePayment] Donation receipt sent to %s for transaction %s", donation.DonorEmail, transactionIDStr)
-	}
+	queueReceipt(tx, donation, receiptData, receiptSummary(donation))
+	c.Logger().Infof("[OneTimePayment] Donation receipt queued for %s for transaction %s", donation.DonorEmail, transactionIDStr)
 
 	response := map[string]interface{}{
 		"success":       true,
@@ -1274,7 +1311,7 @@ func handleRecurringPayment(c buffalo.Context, req struct {
 		// Create fake IDs and next billing date
 		subscriptionID := fmt.Sprintf("dev_sub_%d", time.Now().Unix())
 		paymentPlanID := fmt.Sprintf("dev_plan_%.0f", donation.Amount)
-		nextBilling := time.Now().AddDate(0, 1, 0)
+		nextBilling := time.Now().AddDate(0, donation.ChargeFrequency().Months(), 0)
 
 		c.Logger().Infof("[RecurringPayment] Generated development subscription: subscription_id=%s, plan_id=%s, next_billing=%s",
 			subscriptionID, paymentPlanID, nextBilling.Format("2006-01-02"))
@@ -1288,38 +1325,22 @@ func handleRecurringPayment(c buffalo.Context, req struct {
 		tx := c.Value("tx").(*pop.Connection)
 		if err := tx.Update(donation); err != nil {
 			c.Logger().Errorf("[RecurringPayment] Failed to update donation %s: %v", donation.ID.String(), err)
-			return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{
-				"error": "Failed to update donation",
-			}))
+			return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to update donation")
 		}
 		c.Logger().Infof("[RecurringPayment] Donation %s updated successfully with dev subscription", donation.ID.String())
+		recordMonthlyConversion(tx, donation)
 
-		// Send simulated receipt email for subscription creation
-		emailService := services.NewEmailService()
-		receiptData := services.DonationReceiptData{
-			DonorName:           donation.DonorName,
-			DonationAmount:      donation.Amount,
-			DonationType:        "Monthly",
-			SubscriptionID:      subscriptionID,
-			NextBillingDate:     &nextBilling,
-			TransactionID:       "",
-			DonationDate:        donation.CreatedAt,
-			TaxDeductibleAmount: donation.Amount,
-			OrganizationEIN:     os.Getenv("ORGANIZATION_EIN"),
-			OrganizationName:    "American Veterans Rebuilding",
-			OrganizationAddress: os.Getenv("ORGANIZATION_ADDRESS"),
-			DonorAddressLine1:   stringOrEmpty(donation.AddressLine1),
-			DonorAddressLine2:   stringOrEmpty(donation.AddressLine2),
-			DonorCity:           stringOrEmpty(donation.City),
-			DonorState:          stringOrEmpty(donation.State),
-			DonorZip:            stringOrEmpty(donation.Zip),
+		if donation.IsInstallmentPledge() {
+			if _, err := recordInstallment(tx, donation, 1, ""); err != nil {
+				c.Logger().Errorf("[RecurringPayment] Failed to record first installment for pledge %s: %v", donation.ID.String(), err)
+			}
 		}
 
-		if err := emailService.SendDonationReceipt(donation.DonorEmail, receiptData); err != nil {
-			c.Logger().Errorf("[RecurringPayment] Failed to send subscription receipt email for %s: %v", donation.DonorEmail, err)
-		} else {
-			c.Logger().Infof("[RecurringPayment] Development: Subscription receipt sent to %s for subscription %s", donation.DonorEmail, subscriptionID)
-		}
+		// Queue simulated receipt email for subscription creation
+		receiptData := subscriptionReceiptData(tx, donation, subscriptionID, &nextBilling)
+
+		queueReceipt(tx, donation, receiptData, receiptSummary(donation))
+		c.Logger().Infof("[RecurringPayment] Development: Subscription receipt queued for %s for subscription %s", donation.DonorEmail, subscriptionID)
 
 		c.Logger().Infof("[RecurringPayment] Development simulation completed successfully for donation %s", donation.ID.String())
 		return c.Render(http.StatusOK, r.JSON(map[string]interface{}{
@@ -1337,20 +1358,18 @@ func handleRecurringPayment(c buffalo.Context, req struct {
 	// Create or get payment plan
 	c.Logger().Infof("[RecurringPayment] Creating payment plan for recurring donation - donation_id=%s, amount=%.2f, donor=%s",
 		donation.ID.String(), donation.Amount, donation.DonorEmail)
-	paymentPlanID, err := getOrCreateMonthlyDonationPlan(helcimClient, donation.Amount)
+	paymentPlanID, err := recurringPlanFor(c, helcimClient, donation)
 	if err != nil {
 		c.Logger().Errorf("[RecurringPayment] Failed to setup payment plan for donation_id=%s, amount=%.2f: %v",
 			donation.ID.String(), donation.Amount, err)
-		return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{
-			"error": "Failed to setup payment plan",
-		}))
+		return helcimFailureJSON(c, err)
 	}
 	c.Logger().Infof("[RecurringPayment] Payment plan created successfully - plan_id=%d, donation_id=%s", paymentPlanID, donation.ID.String())
 
 	// Create subscription using Recurring API
 	c.Logger().Infof("[RecurringPayment] Creating Helcim subscription - customer_code=%s, plan_id=%d, amount=%.2f",
 		req.CustomerCode, paymentPlanID, donation.Amount)
-	subscription, err := helcimClient.CreateSubscription(services.SubscriptionRequest{
+	subscription, err := helcimClient.CreateSubscription(c, services.SubscriptionRequest{
 		CustomerID:    req.CustomerCode,
 		PaymentPlanID: paymentPlanID,
 		Amount:        donation.Amount, // Use actual donation amount for subscription
@@ -1359,9 +1378,7 @@ func handleRecurringPayment(c buffalo.Context, req struct {
 	if err != nil {
 		c.Logger().Errorf("[RecurringPayment] Failed to create Helcim subscription - donation_id=%s, customer_code=%s, plan_id=%d: %v",
 			donation.ID.String(), req.CustomerCode, paymentPlanID, err)
-		return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{
-			"error": "Failed to create subscription",
-		}))
+		return helcimFailureJSON(c, err)
 	}
 	c.Logger().Infof("[RecurringPayment] Helcim subscription created successfully - subscription_id=%d, next_billing=%s, donation_id=%s",
 		subscription.ID, subscription.NextBillingDate.Format("2006-01-02"), donation.ID.String())
@@ -1381,39 +1398,24 @@ func handleRecurringPayment(c buffalo.Context, req struct {
 	tx := c.Value("tx").(*pop.Connection)
 	if err := tx.Update(donation); err != nil {
 		c.Logger().Errorf("[RecurringPayment] Failed to update donation %s: %v", donation.ID.String(), err)
-		return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{
-			"error": "Failed to update donation",
-		}))
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to update donation")
 	}
 	c.Logger().Infof("[RecurringPayment] Donation %s updated successfully with subscription details", donation.ID.String())
+	recordMonthlyConversion(tx, donation)
 
-	// Send receipt email for subscription creation (recurring donation)
-	c.Logger().Infof("[RecurringPayment] Sending subscription receipt email to %s", donation.DonorEmail)
-	emailService := services.NewEmailService()
-	receiptData := services.DonationReceiptData{
-		DonorName:           donation.DonorName,
-		DonationAmount:      donation.Amount,
-		DonationType:        "Monthly",
-		SubscriptionID:      subscriptionIDStr,
-		NextBillingDate:     &subscription.NextBillingDate,
-		TransactionID:       "", // No one-time transaction ID for subscriptions on create
-		DonationDate:        donation.CreatedAt,
-		TaxDeductibleAmount: donation.Amount,
-		OrganizationEIN:     os.Getenv("ORGANIZATION_EIN"),
-		OrganizationName:    "American Veterans Rebuilding",
-		OrganizationAddress: os.Getenv("ORGANIZATION_ADDRESS"),
-		DonorAddressLine1:   stringOrEmpty(donation.AddressLine1),
-		DonorAddressLine2:   stringOrEmpty(donation.AddressLine2),
-		DonorCity:           stringOrEmpty(donation.City),
-		DonorState:          stringOrEmpty(donation.State),
-		DonorZip:            stringOrEmpty(donation.Zip),
+	// The first installment of a pledge bills on sign-up
+	if donation.IsInstallmentPledge() {
+		if _, err := recordInstallment(tx, donation, 1, ""); err != nil {
+			c.Logger().Errorf("[RecurringPayment] Failed to record first installment for pledge %s: %v", donation.ID.String(), err)
+		}
 	}
 
-	if err := emailService.SendDonationReceipt(donation.DonorEmail, receiptData); err != nil {
-		c.Logger().Errorf("[RecurringPayment] Failed to send subscription receipt email to %s: %v", donation.DonorEmail, err)
-	} else {
-		c.Logger().Infof("[RecurringPayment] Subscription receipt sent successfully to %s for subscription %s", donation.DonorEmail, subscriptionIDStr)
-	}
+	// Send receipt email for subscription creation (recurring donation)
+	c.Logger().Infof("[RecurringPayment] Queueing subscription receipt email to %s", donation.DonorEmail)
+	receiptData := subscriptionReceiptData(tx, donation, subscriptionIDStr, &subscription.NextBillingDate)
+
+	queueReceipt(tx, donation, receiptData, receiptSummary(donation))
+	c.Logger().Infof("[RecurringPayment] Subscription receipt queued for %s for subscription %s", donation.DonorEmail, subscriptionIDStr)
 
 	c.Logger().Infof("[RecurringPayment] Recurring payment processing completed successfully for donation %s - SubscriptionID: %s",
 		donation.ID.String(), subscriptionIDStr)
@@ -1426,8 +1428,31 @@ func handleRecurringPayment(c buffalo.Context, req struct {
 	}))
 }
 
-// getOrCreateMonthlyDonationPlan creates or reuses standardized payment plans for monthly donations
-func getOrCreateMonthlyDonationPlan(client services.HelcimAPI, amount float64) (int, error) {
+// billingPeriods maps each donation frequency to how its Helcim plan bills
+var billingPeriods = map[models.DonationFrequency]services.BillingPeriod{
+	models.FrequencyMonthly:   services.BillMonthly,
+	models.FrequencyQuarterly: services.BillQuarterly,
+	models.FrequencyAnnual:    services.BillAnnually,
+}
+
+// recurringPlanCacheKey is the plan cache key for amount billed at frequency.
+// Monthly plans keep the key they had before other frequencies were offered,
+// so plans already in the cache are still reused.
+func recurringPlanCacheKey(amount float64, frequency models.DonationFrequency) string {
+	if frequency == models.FrequencyMonthly {
+		return fmt.Sprintf("plan_%.0f_%s", amount, getCurrency())
+	}
+	return fmt.Sprintf("plan_%s_%.0f_%s", frequency, amount, getCurrency())
+}
+
+// getOrCreateRecurringPlan creates or reuses standardized payment plans for
+// open-ended recurring donations, one per amount and frequency
+func getOrCreateRecurringPlan(ctx context.Context, client services.HelcimAPI, amount float64, frequency models.DonationFrequency) (int, error) {
+	billing, ok := billingPeriods[frequency]
+	if !ok {
+		return 0, fmt.Errorf("no billing period for donation frequency %q", frequency)
+	}
+
 	// Standardized donation amounts to reduce plan proliferation
 	// Note: The subscription amount can override the plan amount, so we can use standardized plans
 	// while still charging the exact requested amount per Helcim documentation
@@ -1444,12 +1469,12 @@ func getOrCreateMonthlyDonationPlan(client services.HelcimAPI, amount float64) (
 	}
 
 	// Create a standardized plan name and cache key
-	planName := fmt.Sprintf("Monthly Donation - $%.0f", standardAmount)
-	cacheKey := fmt.Sprintf("plan_%.0f_%s", standardAmount, getCurrency())
+	planName := fmt.Sprintf("%s Donation - $%.0f", frequency.Label(), standardAmount)
+	cacheKey := recurringPlanCacheKey(standardAmount, frequency)
 
 	// Check if we have a cached plan first
 	if cachedPlan, found := services.GetPaymentPlanCache().Get(cacheKey); found {
-		fmt.Printf("[PaymentPlan] Using cached plan ID %d for $%.2f\n", cachedPlan.ID, standardAmount)
+		fmt.Printf("[PaymentPlan] Using cached %s plan ID %d for $%.2f\n", frequency, cachedPlan.ID, standardAmount)
 		if standardAmount != amount {
 			fmt.Printf("[PaymentPlan] Using standardized plan amount $%.2f instead of exact $%.2f\n", standardAmount, amount)
 		}
@@ -1457,14 +1482,14 @@ func getOrCreateMonthlyDonationPlan(client services.HelcimAPI, amount float64) (
 	}
 
 	// Create new payment plan if not cached
-	plan, err := client.CreatePaymentPlan(standardAmount, planName)
+	plan, err := client.CreatePaymentPlan(ctx, standardAmount, planName, billing)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create payment plan for $%.2f: %w", standardAmount, err)
+		return 0, fmt.Errorf("failed to create %s payment plan for $%.2f: %w", frequency, standardAmount, err)
 	}
 
 	// Cache the newly created plan
 	services.GetPaymentPlanCache().Set(cacheKey, plan)
-	fmt.Printf("[PaymentPlan] Created and cached new plan ID %d for $%.2f\n", plan.ID, standardAmount)
+	fmt.Printf("[PaymentPlan] Created and cached new %s plan ID %d for $%.2f\n", frequency, plan.ID, standardAmount)
 
 	// Log if we're using a different amount than requested (for monitoring)
 	if standardAmount != amount {
@@ -3,8 +3,11 @@ package actions
 import (
 	"net/http"
 	"strings"
+	"testing"
 	"time"
 
+	"github.com/gofrs/uuid"
+
 	"avrnpo.org/models"
 )
 
@@ -57,6 +60,47 @@ func (as *ActionSuite) Test_ProcessPayment_RejectsZeroStoredAmount() {
 	as.Equal(http.StatusBadRequest, res.Code)
 }
 
+func TestSameAmount(t *testing.T) {
+	if !sameAmount(25, 25.001) || !sameAmount(0.1+0.2, 0.3) {
+		t.Error("amounts equal to the cent should match")
+	}
+	if sameAmount(100, 100.01) || sameAmount(100, 1) || sameAmount(100, 0) {
+		t.Error("amounts that differ by a cent or more should not match")
+	}
+}
+
+func (as *ActionSuite) Test_ProcessPayment_RejectsTamperedAmount() {
+	donation := &models.Donation{
+		DonorName:     "Tamper Donor",
+		DonorEmail:    "tamper@example.com",
+		CheckoutToken: "tkn_tamper",
+		SecretToken:   "s",
+		Amount:        100,
+		Currency:      "USD",
+		DonationType:  "one-time",
+		Status:        "pending",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	as.NoError(as.DB.Create(donation))
+
+	for _, amount := range []string{"1.00", "100.01", "1000", "0"} {
+		res := as.JSON("/api/donations/process").Post(map[string]interface{}{
+			"customerCode": "cust_1",
+			"cardToken":    "card_1",
+			"donationId":   donation.ID.String(),
+			"amount":       amount,
+		})
+		as.Equal(http.StatusConflict, res.Code, amount)
+		as.Contains(res.Body.String(), `"success":false`)
+	}
+
+	// Nothing was charged, so the donation is still waiting for payment
+	as.NoError(as.DB.Reload(donation))
+	as.Equal("pending", donation.Status)
+	as.Nil(donation.TransactionID)
+}
+
 func (as *ActionSuite) Test_DonateUpdateAmount_HTMXSwapBehavior() {
 	// Test that the update amount endpoint returns a fragment suitable for innerHTML swap
 
@@ -223,3 +267,36 @@ func (as *ActionSuite) Test_ProcessPayment_SuccessResponseFormat() {
 	as.Contains(body, `"type":"one-time"`)
 	as.Contains(body, `"message"`)
 }
+
+func (as *ActionSuite) Test_PaymentReceiptData_AddsThankYou() {
+	partnerID := uuid.Must(uuid.NewV4())
+	video := "https://youtu.be/thanks"
+	message := "Thank you, {name}!"
+	as.NoError(as.DB.Create(&models.ThankYouRule{PartnerID: partnerID, MinAmount: 20, VideoURL: &video, Message: &message}))
+
+	donation := &models.Donation{
+		DonorName:     "Pat Donor",
+		DonorEmail:    "pat@example.com",
+		CheckoutToken: "tkn_thanks",
+		SecretToken:   "secret_thanks",
+		Amount:        25.00,
+		Currency:      "USD",
+		DonationType:  "one-time",
+		Status:        "completed",
+		PartnerID:     &partnerID,
+	}
+	as.NoError(as.DB.Create(donation))
+
+	// The receipt the live card path and review approval send
+	receipt := paymentReceiptData(as.DB, donation, "12345")
+	as.Equal("12345", receipt.TransactionID)
+	as.Equal("Thank you, Pat!", receipt.ThankYouMessage)
+	as.Equal(video, receipt.ThankYouVideoURL)
+
+	donation.DonationType = "monthly"
+	nextBilling := time.Now().AddDate(0, 1, 0)
+	receipt = subscriptionReceiptData(as.DB, donation, "sub_1", &nextBilling)
+	as.Equal("sub_1", receipt.SubscriptionID)
+	as.Equal("Thank you, Pat!", receipt.ThankYouMessage)
+	as.Equal(video, receipt.ThankYouVideoURL)
+}
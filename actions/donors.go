@@ -0,0 +1,502 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// timelineEntry is one touchpoint on a donor's timeline
+type timelineEntry struct {
+	At     time.Time
+	Kind   string
+	Title  string
+	Detail string
+	Link   string
+	Flags  models.DonorFlags
+}
+
+// timelineKindLabels names each kind of timeline entry
+var timelineKindLabels = map[string]string{
+	"donation":                         "Donation",
+	models.CommunicationReceipt:        "Receipt sent",
+	models.CommunicationYearEndReceipt: "Year-end receipt sent",
+	models.CommunicationAcknowledgment: "Acknowledgment sent",
+	models.CommunicationContactMessage: "Contact message",
+	"note":                             "Note",
+	"in_kind":                          "In-kind gift",
+	"vehicle":                          "Vehicle",
+	"event":                            "Event ticket",
+}
+
+// timelineKindLabel is the display name for a kind of timeline entry
+func timelineKindLabel(kind string) string {
+	if label, ok := timelineKindLabels[kind]; ok {
+		return label
+	}
+	return kind
+}
+
+// donorRecords is everything we hold for one donor email
+type donorRecords struct {
+	Donations      []models.Donation
+	Communications models.DonorCommunications
+	Notes          models.DonorNotes
+	Flags          models.DonorFlags
+	NoteAuthors    map[uuid.UUID]string
+	InKindGifts    models.InKindGifts
+	Vehicles       models.VehicleDonations
+	Tickets        []donorTicket
+}
+
+// donorTicket is an event ticket held by the donor, with its event's title
+type donorTicket struct {
+	ID         uuid.UUID `db:"id"`
+	EventID    uuid.UUID `db:"event_id"`
+	EventTitle string    `db:"event_title"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// recordCommunication adds an email we sent, or a message we received, to
+// the donor's timeline. The donor's gift or message has already gone through
+// by the time this runs, so a failure here is logged rather than returned.
+func recordCommunication(tx *pop.Connection, email, kind, summary string, body *string, referenceID *uuid.UUID) {
+	communication := &models.DonorCommunication{
+		DonorEmail:  models.NormalizeDonorEmail(email),
+		Kind:        kind,
+		Summary:     summary,
+		Body:        body,
+		ReferenceID: referenceID,
+	}
+	if err := tx.Create(communication); err != nil {
+		logging.Error("failed to record donor communication", err, logging.Fields{
+			"kind": kind,
+		})
+	}
+}
+
+// recordReceiptSent notes on the donor's timeline that their receipt for
+// donation went out
+func recordReceiptSent(tx *pop.Connection, donation *models.Donation) {
+	recordCommunication(tx, donation.DonorEmail, models.CommunicationReceipt, receiptSummary(donation), nil, &donation.ID)
+}
+
+// receiptSummary is how a donation's receipt is listed on the donor's
+// timeline
+func receiptSummary(donation *models.Donation) string {
+	return fmt.Sprintf("Receipt for $%.2f %s donation", donation.Amount, donation.DonationType)
+}
+
+// loadDonorRecords gathers the gifts, messages and notes tied to a donor
+// email. Gifts the donor record was matched to under another email, by
+// their Helcim customer code, are included too.
+func loadDonorRecords(tx *pop.Connection, email string) (donorRecords, error) {
+	records := donorRecords{NoteAuthors: map[uuid.UUID]string{}}
+	err := tx.Where("LOWER(donor_email) = ? OR donor_id IN (SELECT id FROM donors WHERE email = ?)", email, email).
+		Order("created_at desc").All(&records.Donations)
+	if err != nil {
+		return records, errors.WithStack(err)
+	}
+	queries := []struct {
+		query string
+		dest  interface{}
+	}{
+		{"donor_email = ?", &records.Communications},
+		{"donor_email = ?", &records.Notes},
+		{"donor_email = ?", &records.Flags},
+		{"donor_email = ?", &records.InKindGifts},
+		{"donor_email = ?", &records.Vehicles},
+	}
+	for _, q := range queries {
+		if err := tx.Where(q.query, email).Order("created_at desc").All(q.dest); err != nil {
+			return records, errors.WithStack(err)
+		}
+	}
+
+	err = tx.RawQuery(`SELECT t.id, t.event_id, e.title AS event_title, t.created_at
+		FROM event_tickets t JOIN events e ON e.id = t.event_id
+		WHERE LOWER(t.holder_email) = ? ORDER BY t.created_at DESC`, email).All(&records.Tickets)
+	if err != nil {
+		return records, errors.WithStack(err)
+	}
+
+	var authorIDs []uuid.UUID
+	for _, note := range records.Notes {
+		if note.AuthorID != nil {
+			authorIDs = append(authorIDs, *note.AuthorID)
+		}
+	}
+	authors, err := models.UsersByID(tx, authorIDs)
+	if err != nil {
+		return records, err
+	}
+	for id, author := range authors {
+		records.NoteAuthors[id] = strings.TrimSpace(author.FirstName + " " + author.LastName)
+	}
+	return records, nil
+}
+
+// donationTitle names a donation on the donor's profile, e.g.
+// "$50.00 monthly donation"
+func donationTitle(d models.Donation) string {
+	return fmt.Sprintf("$%.2f %s donation", d.Amount, d.DonationType)
+}
+
+// donorFlagIndex holds the flags on a page's worth of donors, keyed by
+// normalized email, so list templates can badge each row
+type donorFlagIndex map[string]models.DonorFlags
+
+// loadDonorFlagIndex loads the flags on the donors with the given emails
+func loadDonorFlagIndex(tx *pop.Connection, emails []string) (donorFlagIndex, error) {
+	index := donorFlagIndex{}
+	if len(emails) == 0 {
+		return index, nil
+	}
+	args := make([]interface{}, len(emails))
+	for i, email := range emails {
+		args[i] = models.NormalizeDonorEmail(email)
+	}
+	flags := models.DonorFlags{}
+	if err := tx.Where("donor_email IN (?)", args...).Order("created_at asc").All(&flags); err != nil {
+		return index, errors.WithStack(err)
+	}
+	for _, flag := range flags {
+		index[flag.DonorEmail] = append(index[flag.DonorEmail], flag)
+	}
+	return index, nil
+}
+
+// ForDonor is the flags set on the donor themselves
+func (index donorFlagIndex) ForDonor(email string) models.DonorFlags {
+	flags := models.DonorFlags{}
+	for _, flag := range index[models.NormalizeDonorEmail(email)] {
+		if flag.DonationID == nil {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// ForDonation is the flags on the donor plus any set on this donation
+func (index donorFlagIndex) ForDonation(d models.Donation) models.DonorFlags {
+	return append(index.ForDonor(d.DonorEmail), flagsOnDonation(index[models.NormalizeDonorEmail(d.DonorEmail)], d.ID)...)
+}
+
+// flagsOnDonation picks out the flags set on one donation
+func flagsOnDonation(flags models.DonorFlags, donationID uuid.UUID) models.DonorFlags {
+	var onDonation models.DonorFlags
+	for _, flag := range flags {
+		if flag.DonationID != nil && *flag.DonationID == donationID {
+			onDonation = append(onDonation, flag)
+		}
+	}
+	return onDonation
+}
+
+// donorTimeline merges a donor's records into one timeline, newest first
+func donorTimeline(records donorRecords) []timelineEntry {
+	var entries []timelineEntry
+	donations := map[uuid.UUID]models.Donation{}
+	for _, d := range records.Donations {
+		donations[d.ID] = d
+		entries = append(entries, timelineEntry{
+			At:     d.CreatedAt,
+			Kind:   "donation",
+			Title:  donationTitle(d),
+			Detail: d.Status,
+			Flags:  flagsOnDonation(records.Flags, d.ID),
+		})
+	}
+	for _, comm := range records.Communications {
+		entries = append(entries, timelineEntry{
+			At:     comm.CreatedAt,
+			Kind:   comm.Kind,
+			Title:  comm.Summary,
+			Detail: comm.BodyText(),
+		})
+	}
+	for _, note := range records.Notes {
+		author := "Staff"
+		if note.AuthorID != nil && records.NoteAuthors[*note.AuthorID] != "" {
+			author = records.NoteAuthors[*note.AuthorID]
+		}
+		title := fmt.Sprintf("Note from %s", author)
+		if note.DonationID != nil {
+			if d, ok := donations[*note.DonationID]; ok {
+				title = fmt.Sprintf("Note from %s on the %s of %s", author, donationTitle(d), d.CreatedAt.Format("Jan 2, 2006"))
+			}
+		}
+		entries = append(entries, timelineEntry{
+			At:     note.CreatedAt,
+			Kind:   "note",
+			Title:  title,
+			Detail: note.Body,
+		})
+	}
+	for _, gift := range records.InKindGifts {
+		entries = append(entries, timelineEntry{
+			At:    gift.ReceivedAt,
+			Kind:  "in_kind",
+			Title: fmt.Sprintf("In-kind gift: %s", gift.Description),
+			Link:  fmt.Sprintf("/admin/in-kind/%s", gift.ID),
+		})
+	}
+	for _, vehicle := range records.Vehicles {
+		entries = append(entries, timelineEntry{
+			At:     vehicle.CreatedAt,
+			Kind:   "vehicle",
+			Title:  fmt.Sprintf("Vehicle donation: %s", vehicle.Title()),
+			Detail: vehicle.StatusLabel(),
+			Link:   fmt.Sprintf("/admin/vehicles/%s", vehicle.ID),
+		})
+	}
+	for _, ticket := range records.Tickets {
+		entries = append(entries, timelineEntry{
+			At:    ticket.CreatedAt,
+			Kind:  "event",
+			Title: fmt.Sprintf("Ticket to %s", ticket.EventTitle),
+			Link:  fmt.Sprintf("/admin/events/%s", ticket.EventID),
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].At.After(entries[j].At) })
+	return entries
+}
+
+// donorListRow is a donor on the donor list with their giving
+type donorListRow struct {
+	Donor  models.Donor
+	Giving models.DonorGiving
+}
+
+// donorListRows tallies the giving of a page of donors from their donations
+func donorListRows(donors models.Donors, donations []models.Donation, now time.Time) []donorListRow {
+	byDonor := map[uuid.UUID][]models.Donation{}
+	for _, d := range donations {
+		if d.DonorID != nil {
+			byDonor[*d.DonorID] = append(byDonor[*d.DonorID], d)
+		}
+	}
+	rows := make([]donorListRow, len(donors))
+	for i, donor := range donors {
+		rows[i] = donorListRow{Donor: donor, Giving: models.TallyDonorGiving(byDonor[donor.ID], now)}
+	}
+	return rows
+}
+
+// AdminDonorsIndex lists donors, most recent gift first, optionally
+// narrowed by a search on name, email or Helcim customer code
+func AdminDonorsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	search := strings.TrimSpace(c.Param("search"))
+	q := tx.PaginateFromParams(c.Params())
+	if search != "" {
+		like := "%" + search + "%"
+		q = q.Where("(name ILIKE ? OR email ILIKE ? OR customer_code = ?)", like, like, search)
+	}
+	donors := models.Donors{}
+	if err := q.Order("(SELECT MAX(created_at) FROM donations WHERE donations.donor_id = donors.id) DESC NULLS LAST, email").All(&donors); err != nil {
+		return errors.WithStack(err)
+	}
+
+	donations := []models.Donation{}
+	if len(donors) > 0 {
+		ids := make([]uuid.UUID, len(donors))
+		for i, donor := range donors {
+			ids[i] = donor.ID
+		}
+		if err := tx.Where("donor_id IN (?)", ids).All(&donations); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	c.Set("rows", donorListRows(donors, donations, time.Now()))
+	c.Set("search", search)
+	c.Set("pagination", q.Paginator)
+	return c.Render(http.StatusOK, r.HTML("admin/donors/index.plush.html"))
+}
+
+// AdminDonorShow shows a donor's profile: their giving and a timeline of
+// every touchpoint we have with them
+func AdminDonorShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	email := models.NormalizeDonorEmail(c.Param("email"))
+	if !strings.Contains(email, "@") {
+		return c.Error(http.StatusNotFound, fmt.Errorf("invalid donor email %q", email))
+	}
+	records, err := loadDonorRecords(tx, email)
+	if err != nil {
+		return err
+	}
+
+	donor, err := models.MatchDonor(tx, "", email)
+	if err != nil {
+		return err
+	}
+	name := ""
+	if donor != nil {
+		name = donor.Name
+	}
+	for _, d := range records.Donations {
+		if name == "" {
+			name = d.DonorName
+		}
+	}
+	if name == "" && len(records.InKindGifts) > 0 {
+		name = records.InKindGifts[0].DonorName
+	}
+	if name == "" && len(records.Vehicles) > 0 {
+		name = records.Vehicles[0].DonorName
+	}
+
+	user := &models.User{}
+	if err := tx.Where("LOWER(email) = ?", email).First(user); err != nil {
+		user = nil
+	}
+
+	c.Set("email", email)
+	c.Set("donorName", name)
+	c.Set("account", user)
+	c.Set("donor", donor)
+	c.Set("giving", models.TallyDonorGiving(records.Donations, time.Now()))
+	c.Set("timeline", donorTimeline(records))
+	c.Set("timelineKindLabel", timelineKindLabel)
+	c.Set("donations", records.Donations)
+	c.Set("donationTitle", donationTitle)
+	c.Set("flags", donorFlagIndex{email: records.Flags}.ForDonor(email))
+	c.Set("flagKinds", models.DonorFlagKinds)
+	c.Set("flagLabel", models.DonorFlagLabel)
+	return c.Render(http.StatusOK, r.HTML("admin/donors/show.plush.html"))
+}
+
+// AdminDonorNoteCreate adds a free-form note to a donor's timeline
+func AdminDonorNoteCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	email := models.NormalizeDonorEmail(c.Param("email"))
+	donationID, err := donorDonationParam(tx, email, c.Param("DonationID"))
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	note := &models.DonorNote{
+		DonorEmail: email,
+		DonationID: donationID,
+		Body:       strings.TrimSpace(c.Param("Body")),
+		AuthorID:   &currentUser.ID,
+	}
+	verrs, err := tx.ValidateAndCreate(note)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+		return c.Redirect(http.StatusSeeOther, "/admin/donors/%s", note.DonorEmail)
+	}
+
+	logging.UserAction(c, currentUser.ID.String(), "donor_note_added", fmt.Sprintf("Added a note for %s", note.DonorEmail), logging.Fields{
+		"note_id": note.ID.String(),
+	})
+
+	c.Flash().Add("success", "Note added.")
+	return c.Redirect(http.StatusSeeOther, "/admin/donors/%s", note.DonorEmail)
+}
+
+// donorDonationParam parses the optional donation a note or flag is about,
+// making sure it's one of this donor's
+func donorDonationParam(tx *pop.Connection, email, param string) (*uuid.UUID, error) {
+	if strings.TrimSpace(param) == "" {
+		return nil, nil
+	}
+	donation := &models.Donation{}
+	err := tx.Where("id = ? AND (LOWER(donor_email) = ? OR donor_id IN (SELECT id FROM donors WHERE email = ?))", param, email, email).First(donation)
+	if err != nil {
+		return nil, err
+	}
+	return &donation.ID, nil
+}
+
+// AdminDonorFlagCreate flags a donor, or one of their donations
+func AdminDonorFlagCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	email := models.NormalizeDonorEmail(c.Param("email"))
+	donationID, err := donorDonationParam(tx, email, c.Param("DonationID"))
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	flag := &models.DonorFlag{
+		DonorEmail: email,
+		DonationID: donationID,
+		Flag:       c.Param("Flag"),
+		AuthorID:   &currentUser.ID,
+	}
+	q := tx.Where("donor_email = ? AND flag = ?", flag.DonorEmail, flag.Flag)
+	if donationID != nil {
+		q = q.Where("donation_id = ?", *donationID)
+	} else {
+		q = q.Where("donation_id IS NULL")
+	}
+	exists, err := q.Exists(&models.DonorFlag{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if exists {
+		c.Flash().Add("info", fmt.Sprintf("Already flagged %s.", flag.Label()))
+		return c.Redirect(http.StatusSeeOther, "/admin/donors/%s", email)
+	}
+
+	verrs, err := tx.ValidateAndCreate(flag)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+		return c.Redirect(http.StatusSeeOther, "/admin/donors/%s", email)
+	}
+
+	logging.UserAction(c, currentUser.ID.String(), "donor_flag_added", fmt.Sprintf("Flagged %s as %s", email, flag.Label()), logging.Fields{
+		"flag_id": flag.ID.String(),
+		"flag":    flag.Flag,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Flagged %s.", flag.Label()))
+	return c.Redirect(http.StatusSeeOther, "/admin/donors/%s", email)
+}
+
+// AdminDonorFlagDelete clears a flag from a donor or donation
+func AdminDonorFlagDelete(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	email := models.NormalizeDonorEmail(c.Param("email"))
+	flag := &models.DonorFlag{}
+	if err := tx.Where("id = ? AND donor_email = ?", c.Param("flag_id"), email).First(flag); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if err := tx.Destroy(flag); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.UserAction(c, currentUser.ID.String(), "donor_flag_removed", fmt.Sprintf("Cleared %s from %s", flag.Label(), email), logging.Fields{
+		"flag_id": flag.ID.String(),
+		"flag":    flag.Flag,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Cleared %s.", flag.Label()))
+	return c.Redirect(http.StatusSeeOther, "/admin/donors/%s", email)
+}
@@ -0,0 +1,159 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_DonorTimeline(t *testing.T) {
+	req := require.New(t)
+
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 9, 0, 0, 0, time.Local) }
+	author := uuid.Must(uuid.NewV4())
+	message := "Can I volunteer on the next build?"
+	donationID := uuid.Must(uuid.NewV4())
+	records := donorRecords{
+		Donations: []models.Donation{
+			{ID: donationID, Amount: 50, DonationType: "monthly", Status: "completed", CreatedAt: day(1)},
+		},
+		Communications: models.DonorCommunications{
+			{Kind: models.CommunicationReceipt, Summary: "Receipt for $50.00 monthly donation", CreatedAt: day(2)},
+			{Kind: models.CommunicationContactMessage, Summary: "Volunteering", Body: &message, CreatedAt: day(5)},
+		},
+		Notes: models.DonorNotes{
+			{Body: "Called to say thanks", AuthorID: &author, CreatedAt: day(3)},
+			{Body: "Prefers email", DonationID: &donationID, CreatedAt: day(4)},
+		},
+		Flags: models.DonorFlags{
+			{Flag: models.FlagBoardMember},
+			{Flag: models.FlagChargebackRisk, DonationID: &donationID},
+		},
+		NoteAuthors: map[uuid.UUID]string{author: "Pat Staff"},
+		InKindGifts: models.InKindGifts{
+			{Description: "Lumber", ReceivedAt: day(6)},
+		},
+	}
+
+	entries := donorTimeline(records)
+	req.Len(entries, 6)
+	req.Equal("in_kind", entries[0].Kind, "newest first")
+	req.Equal(models.CommunicationContactMessage, entries[1].Kind)
+	req.Equal(message, entries[1].Detail)
+	req.Equal("Note from Staff on the $50.00 monthly donation of Oct 1, 2026", entries[2].Title)
+	req.Equal("Note from Pat Staff", entries[3].Title)
+	req.Equal("Receipt sent", timelineKindLabel(entries[4].Kind))
+	req.Equal("$50.00 monthly donation", entries[5].Title)
+	req.Equal("completed", entries[5].Detail)
+	req.Len(entries[5].Flags, 1)
+	req.Equal(models.FlagChargebackRisk, entries[5].Flags[0].Flag)
+}
+
+func Test_DonorFlagIndex(t *testing.T) {
+	req := require.New(t)
+
+	flagged := uuid.Must(uuid.NewV4())
+	index := donorFlagIndex{"sam@example.com": {
+		{DonorEmail: "sam@example.com", Flag: models.FlagDoNotSolicit},
+		{DonorEmail: "sam@example.com", Flag: models.FlagChargebackRisk, DonationID: &flagged},
+	}}
+
+	req.Len(index.ForDonor("Sam@Example.com"), 1)
+	req.Len(index.ForDonation(models.Donation{ID: flagged, DonorEmail: "sam@example.com"}), 2)
+	req.Len(index.ForDonation(models.Donation{ID: uuid.Must(uuid.NewV4()), DonorEmail: "sam@example.com"}), 1)
+	req.Empty(index.ForDonor("lee@example.com"))
+}
+
+func Test_DonorShowTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/donor-test", func(c buffalo.Context) error {
+		c.Set("email", "sam@example.com")
+		c.Set("donorName", "Sam Donor")
+		c.Set("account", nil)
+		code := "CST1001"
+		lastGift := time.Date(2026, 10, 1, 9, 0, 0, 0, time.Local)
+		city := "Austin"
+		c.Set("donor", &models.Donor{Email: "sam@example.com", Name: "Sam Donor", CustomerCode: &code, City: &city})
+		c.Set("giving", models.DonorGiving{Lifetime: 150, Gifts: 3, Recurring: true, LastGift: &lastGift})
+		c.Set("timeline", []timelineEntry{
+			{At: time.Now(), Kind: "vehicle", Title: "Vehicle donation: 2013 Ford F-150", Link: "/admin/vehicles/abc"},
+			{At: time.Now(), Kind: "note", Title: "Note from Pat Staff", Detail: "Prefers email"},
+		})
+		c.Set("timelineKindLabel", timelineKindLabel)
+		c.Set("donations", []models.Donation{{Amount: 50, DonationType: "monthly", CreatedAt: time.Date(2026, 10, 1, 9, 0, 0, 0, time.Local)}})
+		c.Set("donationTitle", donationTitle)
+		c.Set("flags", models.DonorFlags{{Flag: models.FlagDoNotSolicit}})
+		c.Set("flagKinds", models.DonorFlagKinds)
+		c.Set("flagLabel", models.DonorFlagLabel)
+		return c.Render(http.StatusOK, r.HTML("admin/donors/show.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/donor-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "Sam Donor")
+	req.Contains(w.Body.String(), `<a href="/admin/vehicles/abc">Vehicle donation: 2013 Ford F-150</a>`)
+	req.Contains(w.Body.String(), "Prefers email")
+	req.Contains(w.Body.String(), `action="/admin/donors/sam@example.com/notes"`)
+	req.Contains(w.Body.String(), `<span class="donor-flag donor-flag-red">Do not solicit</span>`)
+	req.Contains(w.Body.String(), "The $50.00 monthly donation of Oct 1, 2026")
+	req.Contains(w.Body.String(), "Helcim customer CST1001")
+	req.Contains(w.Body.String(), "<p>Austin</p>")
+	req.Contains(w.Body.String(), "$150.00")
+}
+
+func Test_DonorListRows(t *testing.T) {
+	req := require.New(t)
+
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	sam := models.Donor{ID: uuid.Must(uuid.NewV4()), Email: "sam@example.com"}
+	lee := models.Donor{ID: uuid.Must(uuid.NewV4()), Email: "lee@example.com"}
+	rows := donorListRows(models.Donors{sam, lee}, []models.Donation{
+		{DonorID: &sam.ID, Amount: 40, Status: "completed", CreatedAt: now},
+		{DonorID: &sam.ID, Amount: 60, Status: "completed", CreatedAt: now},
+		{Amount: 1000, Status: "completed", CreatedAt: now},
+	}, now)
+
+	req.Len(rows, 2)
+	req.Equal(100.0, rows[0].Giving.Lifetime)
+	req.Equal(2, rows[0].Giving.Gifts)
+	req.Equal(models.DonorGiving{}, rows[1].Giving)
+}
+
+func Test_DonorsIndexTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/donors-test", func(c buffalo.Context) error {
+		lastGift := time.Date(2026, 9, 15, 9, 0, 0, 0, time.Local)
+		c.Set("rows", []donorListRow{
+			{Donor: models.Donor{Email: "sam@example.com", Name: "Sam Donor"}, Giving: models.DonorGiving{Lifetime: 250, Gifts: 4, Recurring: true, LastGift: &lastGift}},
+			{Donor: models.Donor{Email: "lee@example.com"}},
+		})
+		c.Set("search", "")
+		c.Set("pagination", &pop.Paginator{Page: 1, TotalPages: 1})
+		return c.Render(http.StatusOK, r.HTML("admin/donors/index.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/donors-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `<a href="/admin/donors/sam@example.com">Sam Donor</a>`)
+	req.Contains(w.Body.String(), `<a href="/admin/donors/lee@example.com">lee@example.com</a>`)
+	req.Contains(w.Body.String(), "$250.00")
+	req.Contains(w.Body.String(), "Sep 15, 2026")
+}
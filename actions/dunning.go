@@ -0,0 +1,232 @@
+package actions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// paymentDeclined reports whether a Helcim transaction status is a failed
+// charge. Helcim reports successful charges as APPROVED.
+func paymentDeclined(status string) bool {
+	status = strings.TrimSpace(status)
+	return status != "" && !strings.EqualFold(status, "APPROVED")
+}
+
+// openPaymentFailure returns the donation's payment failure still in
+// dunning, or nil when there isn't one
+func openPaymentFailure(tx *pop.Connection, donationID uuid.UUID) (*models.PaymentFailure, error) {
+	failure := &models.PaymentFailure{}
+	err := tx.Where("donation_id = ? AND status = ?", donationID, models.PaymentFailureOpen).First(failure)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return failure, nil
+}
+
+// handleFailedSubscriptionPayment starts or advances dunning for a declined
+// subscription charge reported by webhook
+func handleFailedSubscriptionPayment(tx *pop.Connection, data HelcimWebhookData, c buffalo.Context) error {
+	donation := &models.Donation{}
+	if err := tx.Where("subscription_id = ?", data.SubscriptionID).First(donation); err != nil {
+		c.Logger().Warnf("[Dunning] No donation for failed payment on subscription %s - may be external", data.SubscriptionID)
+		return nil
+	}
+	c.Logger().Warnf("[Dunning] Payment %s for subscription %s failed with status %s", data.TransactionID, data.SubscriptionID, data.Status)
+	return recordPaymentFailure(c, tx, services.NewHelcimClient(), donation, data.TransactionID, data.Status, time.Now())
+}
+
+// recordPaymentFailure counts a failed charge against the donation's open
+// payment failure, opening one for the first failure. The donor is sent the
+// notice for this failure, and once every retry has failed the subscription
+// is cancelled. A transaction already recorded is ignored, so webhook
+// retries don't advance dunning twice.
+func recordPaymentFailure(ctx context.Context, tx *pop.Connection, client services.HelcimAPI, donation *models.Donation, transactionID, reason string, now time.Time) error {
+	failure, err := openPaymentFailure(tx, donation.ID)
+	if err != nil {
+		return err
+	}
+	if failure == nil {
+		failure = &models.PaymentFailure{
+			DonationID:     donation.ID,
+			SubscriptionID: stringOrEmpty(donation.SubscriptionID),
+			Status:         models.PaymentFailureOpen,
+		}
+	} else if transactionID != "" && stringOrEmpty(failure.LastTransactionID) == transactionID {
+		return nil
+	}
+
+	failure.RecordAttempt(transactionID, reason, now)
+
+	if failure.Exhausted() {
+		if err := cancelDunnedSubscription(ctx, tx, client, donation, failure, now); err != nil {
+			return err
+		}
+	} else if failure.NoticeDue() {
+		sendPaymentFailureNotice(tx, donation, failure)
+	}
+
+	verrs, err := tx.ValidateAndSave(failure)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		return errors.New(verrs.String())
+	}
+	return nil
+}
+
+// sendPaymentFailureNotice emails the donor the notice for the failure's
+// latest attempt
+func sendPaymentFailureNotice(tx *pop.Connection, donation *models.Donation, failure *models.PaymentFailure) {
+	err := services.NewEmailService().SendPaymentFailureNotice(donation.DonorEmail, services.PaymentFailureNoticeData{
+		DonorName:        donation.DonorName,
+		Amount:           donation.Amount,
+		Notice:           failure.Attempts,
+		FinalNotice:      failure.FinalNotice(),
+		NextRetryDate:    *failure.NextRetryAt,
+		OrganizationName: organization().Name,
+	})
+	if err != nil {
+		logging.Error("payment_failure_notice_failed", err, logging.Fields{
+			"donation_id": donation.ID.String(),
+			"notice":      failure.Attempts,
+		})
+		return
+	}
+	failure.NoticesSent = failure.Attempts
+	recordCommunication(tx, donation.DonorEmail, models.CommunicationPaymentNotice, fmt.Sprintf("Payment failure notice %d for $%.2f monthly donation", failure.Attempts, donation.Amount), nil, &donation.ID)
+}
+
+// cancelDunnedSubscription cancels a subscription whose retries all failed
+func cancelDunnedSubscription(ctx context.Context, tx *pop.Connection, client services.HelcimAPI, donation *models.Donation, failure *models.PaymentFailure, now time.Time) error {
+	if err := client.CancelSubscription(ctx, failure.SubscriptionID); err != nil {
+		return errors.Wrapf(err, "cancelling subscription %s after failed retries", failure.SubscriptionID)
+	}
+	failure.Resolve(models.PaymentFailureCancelled, now)
+	donation.Status = "cancelled"
+	if err := tx.Update(donation); err != nil {
+		return errors.WithStack(err)
+	}
+	logging.Audit("subscription_cancelled_after_dunning", logging.Fields{
+		"donation_id":     donation.ID.String(),
+		"subscription_id": failure.SubscriptionID,
+		"attempts":        failure.Attempts,
+	})
+	return nil
+}
+
+// resolveRecoveredPayment closes the donation's open payment failure after
+// a subscription charge goes through
+func resolveRecoveredPayment(tx *pop.Connection, subscriptionID string, now time.Time) error {
+	donation := &models.Donation{}
+	if err := tx.Where("subscription_id = ?", subscriptionID).First(donation); err != nil {
+		return nil
+	}
+	failure, err := openPaymentFailure(tx, donation.ID)
+	if err != nil || failure == nil {
+		return err
+	}
+	failure.Resolve(models.PaymentFailureRecovered, now)
+	return errors.WithStack(tx.Update(failure))
+}
+
+// RetryFailedPayments charges every subscription whose failed payment is due
+// for a retry, recovering it or moving it to the next dunning stage. It's run
+// from cron through the dunning:retry task and returns how many were retried.
+// Retries Helcim can't take right now are left for the next run.
+func RetryFailedPayments(ctx context.Context, tx *pop.Connection, client services.HelcimAPI, now time.Time) (int, error) {
+	failures := models.PaymentFailures{}
+	err := tx.Where("status = ? AND next_retry_at <= ?", models.PaymentFailureOpen, now).Order("next_retry_at").All(&failures)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	ids := make([]uuid.UUID, len(failures))
+	for i, failure := range failures {
+		ids[i] = failure.DonationID
+	}
+	donations, err := models.DonationsByID(tx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	retried := 0
+	for i := range failures {
+		failure := &failures[i]
+		donation := donations[failure.DonationID]
+		if donation == nil {
+			return retried, errors.Errorf("payment failure %s has no donation %s", failure.ID, failure.DonationID)
+		}
+		if donation.Status == models.DonationStatusPaused {
+			// The donor paused their gift, so the retry waits until it's resumed
+			continue
+		}
+
+		resp, err := client.ProcessSubscriptionPayment(ctx, failure.SubscriptionID)
+		switch {
+		case services.IsHelcimRetryable(err):
+			// Helcim is busy or down, which says nothing about the donor's
+			// card, so the retry stays due for the next run without using up
+			// an attempt
+			logging.Warn("payment_retry_deferred", logging.Fields{
+				"donation_id":     donation.ID.String(),
+				"subscription_id": failure.SubscriptionID,
+				"error":           err.Error(),
+			})
+			continue
+		case err != nil:
+			reason := err.Error()
+			if helcimErr, ok := services.AsHelcimError(err); ok {
+				reason = helcimErr.Message
+			}
+			err = recordPaymentFailure(ctx, tx, client, donation, "", reason, now)
+		case paymentDeclined(resp.Status):
+			err = recordPaymentFailure(ctx, tx, client, donation, strconv.Itoa(resp.TransactionID), resp.Status, now)
+		default:
+			err = settleRetriedPayment(tx, donation, failure, strconv.Itoa(resp.TransactionID), now)
+		}
+		if err != nil {
+			return retried, err
+		}
+		retried++
+	}
+	return retried, nil
+}
+
+// settleRetriedPayment records a retry that went through: the failure is
+// recovered, a pledge gets its installment, and the donor gets a receipt
+func settleRetriedPayment(tx *pop.Connection, donation *models.Donation, failure *models.PaymentFailure, transactionID string, now time.Time) error {
+	failure.Resolve(models.PaymentFailureRecovered, now)
+	if err := tx.Update(failure); err != nil {
+		return errors.WithStack(err)
+	}
+
+	receipt := webhookReceiptData(donation, transactionID)
+	receipt.DonationDate = now
+	if donation.IsInstallmentPledge() {
+		sequence := donation.InstallmentsPaid + 1
+		if _, err := recordInstallment(tx, donation, sequence, transactionID); err != nil {
+			return err
+		}
+		receipt.DonationType = recurringReceiptLabel(donation, sequence)
+	}
+
+	queueReceipt(tx, donation, receipt, receiptSummary(donation))
+	return nil
+}
@@ -0,0 +1,20 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PaymentDeclined(t *testing.T) {
+	r := require.New(t)
+
+	r.False(paymentDeclined("APPROVED"))
+	r.False(paymentDeclined("approved"))
+	// Webhooks without a status are treated as the successful
+	// notifications they were before dunning
+	r.False(paymentDeclined(""))
+
+	r.True(paymentDeclined("DECLINED"))
+	r.True(paymentDeclined("ERROR"))
+}
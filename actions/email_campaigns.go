@@ -0,0 +1,410 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// emailProviderEvent is one event in a mail provider's webhook. Only opens
+// are acted on; send_id is the CampaignSendIDHeader the email went out with.
+type emailProviderEvent struct {
+	Event     string    `json:"event"`
+	SendID    string    `json:"send_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// setEmailCampaignFormContext sets what the campaign form needs
+func setEmailCampaignFormContext(c buffalo.Context, campaign *models.EmailCampaign) {
+	c.Set("emailCampaign", campaign)
+	c.Set("campaignKinds", models.EmailCampaignKinds)
+	c.Set("maxSubjects", models.MaxEmailCampaignSubjects)
+}
+
+// bindEmailCampaign copies the admin form's fields onto campaign
+func bindEmailCampaign(c buffalo.Context, campaign *models.EmailCampaign) {
+	campaign.Name = strings.TrimSpace(c.Param("Name"))
+	campaign.Kind = c.Param("Kind")
+	campaign.Subjects = strings.Join(models.EmailCampaign{Subjects: c.Param("Subjects")}.SubjectVariants(), "\n")
+	campaign.Body = strings.TrimSpace(c.Param("Body"))
+	campaign.TestPercent, _ = strconv.Atoi(strings.TrimSpace(c.Param("TestPercent")))
+	campaign.TestHours, _ = strconv.Atoi(strings.TrimSpace(c.Param("TestHours")))
+}
+
+// emailCampaignRecipients is the campaign's remaining audience, less donors
+// flagged "do not solicit" or on the suppression list
+func emailCampaignRecipients(tx *pop.Connection, campaign *models.EmailCampaign, now time.Time) ([]models.EmailCampaignRecipient, error) {
+	audience, err := models.FindEmailCampaignAudience(tx, campaign, now)
+	if err != nil {
+		return nil, err
+	}
+	emails := make([]string, len(audience))
+	for i, recipient := range audience {
+		emails[i] = recipient.Email
+	}
+	allowed, err := models.ExcludeDoNotSolicit(tx, emails)
+	if err != nil {
+		return nil, err
+	}
+	solicit := map[string]bool{}
+	for _, email := range allowed {
+		solicit[email] = true
+	}
+
+	var recipients []models.EmailCampaignRecipient
+	for _, recipient := range audience {
+		if !solicit[recipient.Email] || contactSuppressed(tx, models.SuppressEmail, recipient.Email, "Email campaign: "+campaign.Name) {
+			continue
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// queueEmailCampaignSends records a send for each recipient with the
+// subject line variant picks, and queues the emails to go out
+func queueEmailCampaignSends(tx *pop.Connection, campaign *models.EmailCampaign, recipients []models.EmailCampaignRecipient, inTest bool, variant func(i int) int, now time.Time) error {
+	for i, recipient := range recipients {
+		name, _ := splitName(recipient.Name)
+		send := &models.EmailCampaignSend{
+			CampaignID: campaign.ID,
+			DonorEmail: recipient.Email,
+			DonorName:  name,
+			Variant:    variant(i),
+			InTest:     inTest,
+		}
+		if campaign.Kind == models.EmailCampaignAnniversary {
+			send.YearsGiving = recipient.YearsGiving(now)
+		}
+		if err := tx.Create(send); err != nil {
+			return errors.WithStack(err)
+		}
+		queueJob(tx, jobEmailCampaignSend, worker.Args{"send_id": send.ID.String()})
+	}
+	return nil
+}
+
+// StartEmailCampaignTest sends a draft campaign's subject lines to its test
+// group: TestPercent of the audience, picked at random and split evenly
+// between the variants. The rest get the winner once the test has run, see
+// FinishEmailCampaignTests. It returns how many test emails were queued.
+func StartEmailCampaignTest(tx *pop.Connection, campaign *models.EmailCampaign, now time.Time) (int, error) {
+	if campaign.Status != models.EmailCampaignDraft {
+		return 0, errors.Errorf("campaign is already %s", campaign.Status)
+	}
+	recipients, err := emailCampaignRecipients(tx, campaign, now)
+	if err != nil {
+		return 0, err
+	}
+	if len(recipients) == 0 {
+		return 0, errors.New("campaign has no one to send to")
+	}
+
+	rand.Shuffle(len(recipients), func(i, j int) {
+		recipients[i], recipients[j] = recipients[j], recipients[i]
+	})
+	testGroup := recipients[:campaign.TestGroupSize(len(recipients))]
+	variants := len(campaign.SubjectVariants())
+	err = queueEmailCampaignSends(tx, campaign, testGroup, true, func(i int) int { return i % variants }, now)
+	if err != nil {
+		return 0, err
+	}
+
+	campaign.Status = models.EmailCampaignTesting
+	campaign.TestStartedAt = &now
+	if err := tx.UpdateColumns(campaign, "status", "test_started_at", "updated_at"); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return len(testGroup), nil
+}
+
+// FinishEmailCampaignTests picks the winning subject line of each campaign
+// whose test has run its course, by the open rates the mail provider has
+// reported, and queues it to the rest of the campaign's audience. It's run
+// hourly from cron through the donors:campaign_winners task and returns how
+// many campaigns it finished.
+func FinishEmailCampaignTests(tx *pop.Connection, now time.Time) (int, error) {
+	campaigns := models.EmailCampaigns{}
+	if err := tx.Where("status = ?", models.EmailCampaignTesting).All(&campaigns); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	finished := 0
+	for i := range campaigns {
+		campaign := &campaigns[i]
+		if !campaign.WinnerDue(now) {
+			continue
+		}
+		stats, err := models.LoadEmailCampaignStats(tx, campaign.ID, true)
+		if err != nil {
+			return finished, err
+		}
+		winner := models.PickSubjectWinner(stats)
+
+		recipients, err := emailCampaignRecipients(tx, campaign, now)
+		if err != nil {
+			return finished, err
+		}
+		if err := queueEmailCampaignSends(tx, campaign, recipients, false, func(int) int { return winner }, now); err != nil {
+			return finished, err
+		}
+
+		campaign.Status = models.EmailCampaignSent
+		campaign.WinningVariant = &winner
+		campaign.SentAt = &now
+		if err := tx.UpdateColumns(campaign, "status", "winning_variant", "sent_at", "updated_at"); err != nil {
+			return finished, errors.WithStack(err)
+		}
+		logging.Audit("email_campaign_winner_picked", logging.Fields{
+			"campaign_id": campaign.ID.String(),
+			"variant":     campaign.VariantLabel(winner),
+			"recipients":  len(recipients),
+		})
+		finished++
+	}
+	return finished, nil
+}
+
+// sendEmailCampaignJob emails one donor their copy of a campaign, queued by
+// StartEmailCampaignTest or FinishEmailCampaignTests
+func sendEmailCampaignJob(args worker.Args) error {
+	send := &models.EmailCampaignSend{}
+	if err := models.DB.Find(send, jobArg(args, "send_id")); err != nil {
+		return errors.Wrap(err, "loading campaign send")
+	}
+	if send.SentAt != nil {
+		return nil
+	}
+	campaign := &models.EmailCampaign{}
+	if err := models.DB.Find(campaign, send.CampaignID); err != nil {
+		return errors.Wrap(err, "loading email campaign")
+	}
+
+	org := organization()
+	err := services.NewEmailService().SendCampaignEmail(send.DonorEmail, services.CampaignEmailData{
+		Name:             send.DonorName,
+		Subject:          campaign.Subject(send.Variant),
+		Body:             campaign.Body,
+		YearsGiving:      send.YearsGiving,
+		DonateURL:        siteURL() + "/donate",
+		ContactEmail:     org.Email,
+		OrganizationName: org.Name,
+		SendID:           send.ID.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	send.SentAt = &now
+	return errors.WithStack(models.DB.UpdateColumns(send, "sent_at", "updated_at"))
+}
+
+// EmailEventsWebhookHandler records open events reported by the mail
+// provider, which pick the winning subject line of a campaign's test. The
+// provider signs the body with EMAIL_EVENTS_WEBHOOK_SECRET.
+func EmailEventsWebhookHandler(c buffalo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid request body")
+	}
+
+	secret := os.Getenv("EMAIL_EVENTS_WEBHOOK_SECRET")
+	if !services.VerifyHMACSignature(secret, body, c.Request().Header.Get("X-Signature")) {
+		logging.SecurityEvent(c, "email_events_webhook", "failure", "invalid_signature")
+		return jsonError(c, http.StatusUnauthorized, codeInvalidSignature, "Invalid signature")
+	}
+
+	var events []emailProviderEvent
+	if err := json.Unmarshal(body, &events); err != nil {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	opens := 0
+	for _, event := range events {
+		if event.Event != "open" {
+			continue
+		}
+		sendID, err := uuid.FromString(event.SendID)
+		if err != nil {
+			continue
+		}
+		at := event.Timestamp
+		if at.IsZero() {
+			at = time.Now()
+		}
+		opened, err := models.RecordEmailCampaignOpen(tx, sendID, at)
+		if err != nil {
+			c.Logger().Errorf("[EmailEvents] Failed to record open for send %s: %v", sendID, err)
+			return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to record event")
+		}
+		if opened {
+			opens++
+		}
+	}
+	return c.Render(http.StatusOK, r.JSON(map[string]int{"opens": opens}))
+}
+
+// AdminEmailCampaignsIndex lists appeal and anniversary email campaigns
+func AdminEmailCampaignsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	campaigns := models.EmailCampaigns{}
+	if err := tx.Order("created_at desc").All(&campaigns); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var rows []struct {
+		CampaignID string `db:"campaign_id"`
+		Sent       int    `db:"sent"`
+	}
+	if err := tx.RawQuery("SELECT campaign_id, COUNT(sent_at) AS sent FROM email_campaign_sends GROUP BY campaign_id").All(&rows); err != nil {
+		return errors.WithStack(err)
+	}
+	sent := map[string]int{}
+	for _, row := range rows {
+		sent[row.CampaignID] = row.Sent
+	}
+
+	c.Set("emailCampaigns", campaigns)
+	c.Set("sentCounts", sent)
+	return c.Render(http.StatusOK, r.HTML("admin/email_campaigns/index.plush.html"))
+}
+
+// AdminEmailCampaignsNew shows the form for drafting a campaign
+func AdminEmailCampaignsNew(c buffalo.Context) error {
+	setEmailCampaignFormContext(c, &models.EmailCampaign{
+		Kind:        models.EmailCampaignAppeal,
+		TestPercent: models.DefaultEmailCampaignTestPercent,
+		TestHours:   models.DefaultEmailCampaignTestHours,
+	})
+	c.Set("errors", nil)
+	return c.Render(http.StatusOK, r.HTML("admin/email_campaigns/new.plush.html"))
+}
+
+// AdminEmailCampaignsCreate saves a draft campaign
+func AdminEmailCampaignsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	campaign := &models.EmailCampaign{Status: models.EmailCampaignDraft}
+	bindEmailCampaign(c, campaign)
+	verrs, err := tx.ValidateAndCreate(campaign)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		setEmailCampaignFormContext(c, campaign)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/email_campaigns/new.plush.html"))
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "email_campaign_created", fmt.Sprintf("Created email campaign: %s", campaign.Name), logging.Fields{
+		"campaign_id": campaign.ID.String(),
+	})
+
+	c.Flash().Add("success", "Campaign saved as a draft.")
+	return c.Redirect(http.StatusSeeOther, "/admin/email-campaigns/%s", campaign.ID)
+}
+
+// AdminEmailCampaignsShow shows a campaign and how its subject lines did
+func AdminEmailCampaignsShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	campaign := &models.EmailCampaign{}
+	if err := tx.Find(campaign, c.Param("campaign_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	testStats, err := models.LoadEmailCampaignStats(tx, campaign.ID, true)
+	if err != nil {
+		return err
+	}
+	rolloutStats, err := models.LoadEmailCampaignStats(tx, campaign.ID, false)
+	if err != nil {
+		return err
+	}
+	audience := 0
+	if campaign.Editable() {
+		recipients, err := models.FindEmailCampaignAudience(tx, campaign, time.Now())
+		if err != nil {
+			return err
+		}
+		audience = len(recipients)
+	}
+
+	setEmailCampaignFormContext(c, campaign)
+	c.Set("errors", nil)
+	c.Set("testStats", testStats)
+	c.Set("rolloutStats", rolloutStats)
+	c.Set("audience", audience)
+	return c.Render(http.StatusOK, r.HTML("admin/email_campaigns/show.plush.html"))
+}
+
+// AdminEmailCampaignsUpdate saves changes to a draft campaign
+func AdminEmailCampaignsUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	campaign := &models.EmailCampaign{}
+	if err := tx.Find(campaign, c.Param("campaign_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if !campaign.Editable() {
+		c.Flash().Add("warning", "This campaign has started sending, so it can't be changed.")
+		return c.Redirect(http.StatusSeeOther, "/admin/email-campaigns/%s", campaign.ID)
+	}
+
+	bindEmailCampaign(c, campaign)
+	verrs, err := tx.ValidateAndUpdate(campaign)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+	} else {
+		c.Flash().Add("success", "Campaign updated.")
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/email-campaigns/%s", campaign.ID)
+}
+
+// AdminEmailCampaignsStart sends a draft campaign's subject lines to its
+// test group
+func AdminEmailCampaignsStart(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	campaign := &models.EmailCampaign{}
+	if err := tx.Find(campaign, c.Param("campaign_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	queued, err := StartEmailCampaignTest(tx, campaign, time.Now())
+	if err != nil {
+		c.Flash().Add("danger", fmt.Sprintf("The test couldn't be started: %v", err))
+		return c.Redirect(http.StatusSeeOther, "/admin/email-campaigns/%s", campaign.ID)
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "email_campaign_test_started", fmt.Sprintf("Started subject line test for email campaign: %s", campaign.Name), logging.Fields{
+		"campaign_id": campaign.ID.String(),
+		"recipients":  queued,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Sending the test to %d donors. The winning subject line goes to everyone else after %d hours.", queued, campaign.TestHours))
+	return c.Redirect(http.StatusSeeOther, "/admin/email-campaigns/%s", campaign.ID)
+}
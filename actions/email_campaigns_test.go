@@ -0,0 +1,98 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_EmailCampaignShowTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/email-campaign-test", func(c buffalo.Context) error {
+		started := time.Date(2026, 10, 14, 9, 0, 0, 0, time.Local)
+		setEmailCampaignFormContext(c, &models.EmailCampaign{
+			ID:            uuid.Must(uuid.NewV4()),
+			Name:          "Winter appeal",
+			Kind:          models.EmailCampaignAppeal,
+			Subjects:      "Build a home this winter\nVeterans need you",
+			Body:          "Please give.",
+			TestPercent:   20,
+			TestHours:     24,
+			Status:        models.EmailCampaignTesting,
+			TestStartedAt: &started,
+		})
+		c.Set("errors", nil)
+		c.Set("audience", 0)
+		c.Set("testStats", []models.EmailCampaignVariantStats{
+			{Variant: 0, Queued: 10, Sent: 10, Opened: 2},
+			{Variant: 1, Queued: 10, Sent: 8, Opened: 4},
+		})
+		c.Set("rolloutStats", []models.EmailCampaignVariantStats{})
+		return c.Render(http.StatusOK, r.HTML("admin/email_campaigns/show.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/email-campaign-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "Appeal · Testing subject lines")
+	req.Contains(w.Body.String(), "The test ends Oct 15, 2026 9:00 AM")
+	req.Contains(w.Body.String(), "<td>Veterans need you</td>")
+	req.Contains(w.Body.String(), "<td>8 of 10</td>")
+	req.Contains(w.Body.String(), "<td>50%</td>")
+	req.NotContains(w.Body.String(), "Start Subject Line Test")
+}
+
+func (as *ActionSuite) Test_EmailCampaignSubjectTest() {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"} {
+		as.NoError(as.DB.Create(&models.Donation{DonorName: "Pat Giver", DonorEmail: email, Amount: 50, Currency: "USD", DonationType: models.DonationTypeOneTime, Status: "completed", CreatedAt: now.AddDate(-1, 0, 0)}))
+	}
+
+	campaign := &models.EmailCampaign{Name: "Winter appeal", Kind: models.EmailCampaignAppeal, Subjects: "One\nTwo", Body: "Please give.", TestPercent: 50, TestHours: 24, Status: models.EmailCampaignDraft}
+	as.NoError(as.DB.Create(campaign))
+
+	queued, err := StartEmailCampaignTest(as.DB, campaign, now)
+	as.NoError(err)
+	as.Equal(2, queued)
+	as.Equal(models.EmailCampaignTesting, campaign.Status)
+
+	_, err = StartEmailCampaignTest(as.DB, campaign, now)
+	as.Error(err, "a campaign's test only starts once")
+
+	sends := []models.EmailCampaignSend{}
+	as.NoError(as.DB.Where("campaign_id = ?", campaign.ID).Order("variant").All(&sends))
+	as.Len(sends, 2)
+	as.Equal(0, sends[0].Variant)
+	as.Equal(1, sends[1].Variant)
+	as.NoError(as.DB.RawQuery("UPDATE email_campaign_sends SET sent_at = ? WHERE campaign_id = ?", now, campaign.ID).Exec())
+	opened, err := models.RecordEmailCampaignOpen(as.DB, sends[1].ID, now.Add(time.Hour))
+	as.NoError(err)
+	as.True(opened)
+
+	finished, err := FinishEmailCampaignTests(as.DB, now.Add(23*time.Hour))
+	as.NoError(err)
+	as.Equal(0, finished, "the test hasn't run its course yet")
+
+	finished, err = FinishEmailCampaignTests(as.DB, now.Add(24*time.Hour))
+	as.NoError(err)
+	as.Equal(1, finished)
+
+	as.NoError(as.DB.Reload(campaign))
+	as.Equal(models.EmailCampaignSent, campaign.Status)
+	as.Equal("Two", campaign.WinnerSubject())
+
+	rollout, err := models.LoadEmailCampaignStats(as.DB, campaign.ID, false)
+	as.NoError(err)
+	as.Equal([]models.EmailCampaignVariantStats{{Variant: 1, Queued: 2}}, rollout)
+}
@@ -0,0 +1,74 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+
+	"avrnpo.org/pkg/logging"
+)
+
+// incidentReference is the correlation ID shown to visitors on error pages,
+// the same request_id that appears in logs and error tracking.
+func incidentReference(c buffalo.Context) string {
+	if rid, ok := c.Value("request_id").(string); ok {
+		return rid
+	}
+	return ""
+}
+
+// errorTemplateFor picks the branded template for a status code.
+func errorTemplateFor(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return "errors/404.plush.html"
+	case http.StatusRequestEntityTooLarge:
+		return "errors/413.plush.html"
+	case http.StatusTooManyRequests:
+		return "errors/429.plush.html"
+	case http.StatusInternalServerError:
+		return "errors/500.plush.html"
+	default:
+		return "errors/error.plush.html"
+	}
+}
+
+// friendlyErrorHandler renders branded error pages with an incident reference
+// instead of Buffalo's defaults. Development keeps Buffalo's debug page, and
+// anything that fails to render falls back to it as well.
+func friendlyErrorHandler(fallback buffalo.ErrorHandler) buffalo.ErrorHandler {
+	return func(status int, err error, c buffalo.Context) error {
+		incident := incidentReference(c)
+
+		fields := logging.Fields{
+			"status":   status,
+			"incident": incident,
+			"path":     c.Request().URL.Path,
+			"method":   c.Request().Method,
+		}
+		if status >= http.StatusInternalServerError {
+			logging.Error("Request failed", err, fields)
+		} else {
+			fields["error"] = err.Error()
+			logging.Warn("Request error", fields)
+		}
+
+		if ENV == "development" {
+			return fallback(status, err, c)
+		}
+
+		if isAPIRequest(c) {
+			status, code, message := jsonErrorFor(status, err)
+			return jsonError(c, status, code, message)
+		}
+
+		c.Set("incident", incident)
+		c.Set("status", status)
+		c.Set("statusText", http.StatusText(status))
+		if rerr := c.Render(status, r.HTML(errorTemplateFor(status))); rerr != nil {
+			logging.Error("Failed to render error page", rerr, fields)
+			return fallback(status, err, c)
+		}
+		return nil
+	}
+}
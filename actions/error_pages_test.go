@@ -0,0 +1,16 @@
+package actions
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ErrorTemplateFor(t *testing.T) {
+	assert.Equal(t, "errors/404.plush.html", errorTemplateFor(http.StatusNotFound))
+	assert.Equal(t, "errors/500.plush.html", errorTemplateFor(http.StatusInternalServerError))
+	assert.Equal(t, "errors/429.plush.html", errorTemplateFor(http.StatusTooManyRequests))
+	assert.Equal(t, "errors/413.plush.html", errorTemplateFor(http.StatusRequestEntityTooLarge))
+	assert.Equal(t, "errors/error.plush.html", errorTemplateFor(http.StatusForbidden))
+}
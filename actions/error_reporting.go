@@ -0,0 +1,43 @@
+package actions
+
+import (
+	"strconv"
+
+	"github.com/gobuffalo/buffalo"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/errortracking"
+)
+
+// errorRequestInfo builds the request context sent with error reports. Only
+// the path, method, correlation ID and user ID are included; query strings,
+// form bodies and donor details never leave the server.
+func errorRequestInfo(c buffalo.Context) *errortracking.RequestInfo {
+	info := &errortracking.RequestInfo{}
+	if req := c.Request(); req != nil {
+		info.Method = req.Method
+		info.UserAgent = req.UserAgent()
+		if req.URL != nil {
+			info.Path = req.URL.Path
+		}
+	}
+	if rid, ok := c.Value("request_id").(string); ok {
+		info.RequestID = rid
+	}
+	if user, ok := c.Value("current_user").(*models.User); ok && user != nil {
+		info.UserID = user.ID.String()
+	}
+	return info
+}
+
+// reportingErrorHandler sends server errors (including recovered panics) to
+// error tracking before handing off to the next handler.
+func reportingErrorHandler(next buffalo.ErrorHandler) buffalo.ErrorHandler {
+	return func(status int, err error, c buffalo.Context) error {
+		eventID := errortracking.Capture(err, errorRequestInfo(c), map[string]string{
+			"status": strconv.Itoa(status),
+		})
+		c.Set("error_event_id", eventID)
+		return next(status, err, c)
+	}
+}
@@ -0,0 +1,688 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/ical"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/pkg/qrcode"
+	"avrnpo.org/services"
+)
+
+// datetimeLocalLayout is the format of datetime-local form inputs
+const datetimeLocalLayout = "2006-01-02T15:04"
+
+// EventAttendance counts an event's tickets and arrivals
+type EventAttendance struct {
+	Issued    int `json:"issued"`
+	CheckedIn int `json:"checked_in"`
+	Capacity  int `json:"capacity"`
+}
+
+// eventAttendance counts the tickets issued for an event and how many holders
+// have checked in.
+func eventAttendance(tx *pop.Connection, event *models.Event) (EventAttendance, error) {
+	issued, err := tx.Where("event_id = ?", event.ID).Count(&models.EventTicket{})
+	if err != nil {
+		return EventAttendance{}, errors.WithStack(err)
+	}
+	checkedIn, err := tx.Where("event_id = ? AND checked_in_at IS NOT NULL", event.ID).Count(&models.EventTicket{})
+	if err != nil {
+		return EventAttendance{}, errors.WithStack(err)
+	}
+	return EventAttendance{Issued: issued, CheckedIn: checkedIn, Capacity: event.Capacity}, nil
+}
+
+// ticketURL is the public address of a ticket, which its QR code encodes.
+func ticketURL(req *http.Request, token string) string {
+	scheme := "http"
+	if req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host + "/tickets/" + token
+}
+
+// ticketTokenFromScan pulls the ticket token out of whatever the scanner
+// read: the ticket URL from a QR code, or a token typed in by hand.
+func ticketTokenFromScan(scanned string) string {
+	token := strings.TrimSpace(scanned)
+	if i := strings.LastIndex(token, "/tickets/"); i >= 0 {
+		token = token[i+len("/tickets/"):]
+	}
+	if i := strings.IndexAny(token, "/?#"); i >= 0 {
+		token = token[:i]
+	}
+	return token
+}
+
+// setEventFormContext exposes an event to the admin form. Plush can't print
+// the optional *string and *time.Time fields directly.
+func setEventFormContext(c buffalo.Context, event *models.Event) {
+	startsAt := ""
+	if !event.StartsAt.IsZero() {
+		startsAt = event.StartsAt.Format(datetimeLocalLayout)
+	}
+	endsAt := ""
+	if event.EndsAt != nil {
+		endsAt = event.EndsAt.Format(datetimeLocalLayout)
+	}
+	c.Set("event", event)
+	c.Set("eventDescription", event.DescriptionText())
+	c.Set("eventLocation", event.LocationText())
+	c.Set("eventStartsAt", startsAt)
+	c.Set("eventEndsAt", endsAt)
+	c.Set("eventKinds", models.EventKinds)
+	c.Set("eventReminders", models.EventReminders)
+	c.Set("eventReminderMessage", stringOrEmpty(event.ReminderMessage))
+}
+
+// bindEvent copies the admin form's fields onto event
+func bindEvent(c buffalo.Context, event *models.Event) {
+	event.Title = strings.TrimSpace(c.Param("Title"))
+	event.Kind = c.Param("Kind")
+	event.Description = stringPointer(strings.TrimSpace(c.Param("Description")))
+	event.Location = stringPointer(strings.TrimSpace(c.Param("Location")))
+	event.StartsAt, _ = time.ParseInLocation(datetimeLocalLayout, c.Param("StartsAt"), time.Local)
+	event.EndsAt = nil
+	if endsAt, err := time.ParseInLocation(datetimeLocalLayout, c.Param("EndsAt"), time.Local); err == nil {
+		event.EndsAt = &endsAt
+	}
+	event.Capacity, _ = strconv.Atoi(c.Param("Capacity"))
+	event.Published = c.Param("Published") == "true"
+	event.SetReminders(c.Request().Form["Reminders"])
+	event.ReminderMessage = stringPointer(strings.TrimSpace(c.Param("ReminderMessage")))
+}
+
+// AdminEventsIndex lists events with their ticket and attendance counts
+func AdminEventsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	events := models.Events{}
+	if err := tx.Order("starts_at desc").All(&events); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var rows []struct {
+		EventID   string `db:"event_id"`
+		Issued    int    `db:"issued"`
+		CheckedIn int    `db:"checked_in"`
+	}
+	err := tx.RawQuery(`
+		SELECT event_id, COUNT(*) as issued, COUNT(checked_in_at) as checked_in
+		FROM event_tickets
+		GROUP BY event_id
+	`).All(&rows)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	issued := map[string]int{}
+	checkedIn := map[string]int{}
+	for _, row := range rows {
+		issued[row.EventID] = row.Issued
+		checkedIn[row.EventID] = row.CheckedIn
+	}
+
+	c.Set("events", events)
+	c.Set("issuedCounts", issued)
+	c.Set("checkedInCounts", checkedIn)
+	return c.Render(http.StatusOK, r.HTML("admin/events/index.plush.html"))
+}
+
+// AdminEventsNew shows the form for creating an event
+func AdminEventsNew(c buffalo.Context) error {
+	setEventFormContext(c, &models.Event{Active: true, Reminders: models.DefaultEventReminders})
+	return c.Render(http.StatusOK, r.HTML("admin/events/new.plush.html"))
+}
+
+// AdminEventsCreate saves a new event
+func AdminEventsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	event := &models.Event{Active: true}
+	bindEvent(c, event)
+
+	verrs, err := tx.ValidateAndCreate(event)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		setEventFormContext(c, event)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/events/new.plush.html"))
+	}
+	queueGoogleCalendarSync(tx, event)
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "event_created", fmt.Sprintf("Created event: %s", event.Title), logging.Fields{
+		"event_id":  event.ID.String(),
+		"kind":      event.Kind,
+		"published": event.Published,
+	})
+
+	c.Flash().Add("success", "Event created. Issue tickets below.")
+	return c.Redirect(http.StatusSeeOther, "/admin/events/%s", event.ID)
+}
+
+// AdminEventsEdit shows the form for editing an event
+func AdminEventsEdit(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	event := &models.Event{}
+	if err := tx.Find(event, c.Param("event_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	setEventFormContext(c, event)
+	return c.Render(http.StatusOK, r.HTML("admin/events/edit.plush.html"))
+}
+
+// AdminEventsUpdate saves changes to an event
+func AdminEventsUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	event := &models.Event{}
+	if err := tx.Find(event, c.Param("event_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	bindEvent(c, event)
+
+	verrs, err := tx.ValidateAndUpdate(event)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		setEventFormContext(c, event)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/events/edit.plush.html"))
+	}
+	queueGoogleCalendarSync(tx, event)
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "event_updated", fmt.Sprintf("Updated event: %s", event.Title), logging.Fields{
+		"event_id":  event.ID.String(),
+		"kind":      event.Kind,
+		"published": event.Published,
+	})
+
+	c.Flash().Add("success", "Event updated.")
+	return c.Redirect(http.StatusSeeOther, "/admin/events/%s", event.ID)
+}
+
+// AdminEventsShow shows an event's tickets and the form for issuing more
+func AdminEventsShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	event := &models.Event{}
+	if err := tx.Find(event, c.Param("event_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	tickets := models.EventTickets{}
+	if err := tx.Where("event_id = ?", event.ID).Order("holder_name asc").All(&tickets); err != nil {
+		return errors.WithStack(err)
+	}
+	attendance, err := eventAttendance(tx, event)
+	if err != nil {
+		return err
+	}
+
+	c.Set("event", event)
+	c.Set("eventLocation", event.LocationText())
+	c.Set("tickets", tickets)
+	c.Set("attendance", attendance)
+	return c.Render(http.StatusOK, r.HTML("admin/events/show.plush.html"))
+}
+
+// AdminEventTicketsCreate issues a ticket and emails it to the holder
+func AdminEventTicketsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	event := &models.Event{}
+	if err := tx.Find(event, c.Param("event_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	attendance, err := eventAttendance(tx, event)
+	if err != nil {
+		return err
+	}
+	if event.Capacity > 0 && attendance.Issued >= event.Capacity {
+		c.Flash().Add("danger", fmt.Sprintf("This event is at capacity (%d tickets).", event.Capacity))
+		return c.Redirect(http.StatusSeeOther, "/admin/events/%s", event.ID)
+	}
+
+	token, err := models.GenerateTicketToken()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	ticket := &models.EventTicket{
+		EventID:     event.ID,
+		Token:       token,
+		HolderName:  strings.TrimSpace(c.Param("HolderName")),
+		HolderEmail: strings.ToLower(strings.TrimSpace(c.Param("HolderEmail"))),
+	}
+	verrs, err := tx.ValidateAndCreate(ticket)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+		return c.Redirect(http.StatusSeeOther, "/admin/events/%s", event.ID)
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "event_ticket_issued", fmt.Sprintf("Issued ticket for %s to %s", event.Title, ticket.HolderName), logging.Fields{
+		"event_id":  event.ID.String(),
+		"ticket_id": ticket.ID.String(),
+	})
+	if event.Kind == models.EventKindVolunteerDay {
+		publishAPIEvent(tx, models.APIEventVolunteerCreated, apiVolunteerFrom(*ticket, *event))
+	}
+
+	err = services.NewEmailService().SendEventTicket(ticket.HolderEmail, services.EventTicketData{
+		HolderName:       ticket.HolderName,
+		EventTitle:       event.Title,
+		StartsAt:         event.StartsAt,
+		Location:         event.LocationText(),
+		TicketURL:        ticketURL(c.Request(), ticket.Token),
+		OrganizationName: organization().Name,
+	})
+	if err != nil {
+		c.Logger().Errorf("[Events] Failed to email ticket %s: %v", ticket.ID.String(), err)
+		c.Flash().Add("warning", fmt.Sprintf("Ticket issued to %s, but the email could not be sent. Share the ticket link from the list below.", ticket.HolderName))
+	} else {
+		c.Flash().Add("success", fmt.Sprintf("Ticket issued and emailed to %s.", ticket.HolderEmail))
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/events/%s", event.ID)
+}
+
+// AdminEventCheckin shows the mobile check-in page staff use at the door
+func AdminEventCheckin(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	event := &models.Event{}
+	if err := tx.Find(event, c.Param("event_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	attendance, err := eventAttendance(tx, event)
+	if err != nil {
+		return err
+	}
+
+	c.Set("event", event)
+	c.Set("attendance", attendance)
+	return c.Render(http.StatusOK, r.HTML("admin/events/checkin.plush.html"))
+}
+
+// AdminEventCheckinCreate checks in the ticket with the scanned code or the
+// ticket_id picked from a search. Scanning a ticket twice is safe; the
+// response says when it was already used.
+func AdminEventCheckinCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	event := &models.Event{}
+	if err := tx.Find(event, c.Param("event_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	ticket := &models.EventTicket{}
+	var err error
+	if id := c.Param("ticket_id"); id != "" {
+		err = tx.Where("event_id = ? AND id = ?", event.ID, id).First(ticket)
+	} else {
+		err = tx.Where("event_id = ? AND token = ?", event.ID, ticketTokenFromScan(c.Param("code"))).First(ticket)
+	}
+	if err != nil {
+		return c.Render(http.StatusNotFound, r.JSON(map[string]string{
+			"status":  "not_found",
+			"message": "No ticket for this event matches that code.",
+		}))
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	status := "already_checked_in"
+	if ticket.CheckIn(currentUser.ID, time.Now()) {
+		if err := tx.Update(ticket); err != nil {
+			return errors.WithStack(err)
+		}
+		status = "checked_in"
+		logging.UserAction(c, currentUser.ID.String(), "event_ticket_checked_in", fmt.Sprintf("Checked in %s to %s", ticket.HolderName, event.Title), logging.Fields{
+			"event_id":  event.ID.String(),
+			"ticket_id": ticket.ID.String(),
+		})
+	}
+
+	attendance, err := eventAttendance(tx, event)
+	if err != nil {
+		return err
+	}
+	return c.Render(http.StatusOK, r.JSON(map[string]interface{}{
+		"status":        status,
+		"holder_name":   ticket.HolderName,
+		"checked_in_at": ticket.CheckedInAt,
+		"attendance":    attendance,
+	}))
+}
+
+// AdminEventTicketSearch finds an event's tickets by holder name, email or
+// ticket code, for guests who can't show their QR code
+func AdminEventTicketSearch(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	q := strings.TrimSpace(c.Param("q"))
+	tickets := models.EventTickets{}
+	if len(q) >= 2 {
+		like := "%" + strings.ToLower(q) + "%"
+		err := tx.Where("event_id = ?", c.Param("event_id")).
+			Where("(LOWER(holder_name) LIKE ? OR LOWER(holder_email) LIKE ? OR token = ?)", like, like, ticketTokenFromScan(q)).
+			Order("holder_name asc").Limit(20).All(&tickets)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return c.Render(http.StatusOK, r.JSON(tickets))
+}
+
+// AdminEventAttendance returns live attendance counts for the check-in page
+func AdminEventAttendance(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	event := &models.Event{}
+	if err := tx.Find(event, c.Param("event_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	attendance, err := eventAttendance(tx, event)
+	if err != nil {
+		return err
+	}
+	return c.Render(http.StatusOK, r.JSON(attendance))
+}
+
+// TicketHandler shows a ticket holder their ticket and its QR code
+func TicketHandler(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	ticket := &models.EventTicket{}
+	if err := tx.Where("token = ?", c.Param("token")).First(ticket); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	event := &models.Event{}
+	if err := tx.Find(event, ticket.EventID); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	c.Set("title", event.Title)
+	c.Set("event", event)
+	c.Set("eventLocation", event.LocationText())
+	c.Set("ticket", ticket)
+	return c.Render(http.StatusOK, r.HTML("pages/ticket.plush.html"))
+}
+
+// TicketQRHandler serves a ticket's QR code as SVG
+func TicketQRHandler(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	ticket := &models.EventTicket{}
+	if err := tx.Where("token = ?", c.Param("token")).First(ticket); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	code, err := qrcode.Encode(ticketURL(c.Request(), ticket.Token))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Response().Header().Set("Cache-Control", "private, max-age=86400")
+	return c.Render(http.StatusOK, r.Func("image/svg+xml", func(w io.Writer, d render.Data) error {
+		_, err := io.WriteString(w, code.SVG(320))
+		return err
+	}))
+}
+
+// calendarFeedWindow is how far back the calendar feed goes, so subscribers
+// keep recent events rather than seeing them vanish once they've passed
+const calendarFeedWindow = 90 * 24 * time.Hour
+
+// eventCalendarEntry is an event as it's written to the calendar feed and
+// .ics downloads. Its UID stays the same so calendar apps update the event
+// when it changes.
+func eventCalendarEntry(event models.Event) ical.Event {
+	return ical.Event{
+		UID:         event.ID.String() + "@avrnpo.org",
+		Summary:     event.Title,
+		Description: event.DescriptionText(),
+		Location:    event.LocationText(),
+		URL:         siteURL() + "/events",
+		Start:       event.StartsAt,
+		End:         event.EndTime(),
+		Updated:     event.UpdatedAt,
+		Cancelled:   !event.Active,
+	}
+}
+
+// eventCalendar is the site's calendar holding events
+func eventCalendar(events models.Events) ical.Calendar {
+	cal := ical.Calendar{
+		ProdID:          "-//American Veterans Rebuilding//Events//EN",
+		Name:            organization().Name,
+		Description:     "Events and volunteer days from " + organization().Name,
+		RefreshInterval: 12 * time.Hour,
+	}
+	for _, event := range events {
+		cal.Events = append(cal.Events, eventCalendarEntry(event))
+	}
+	return cal
+}
+
+// calendarCacheTTL is how long browsers and calendar apps may keep a public
+// calendar feed
+const calendarCacheTTL = 15 * time.Minute
+
+// renderCalendar writes cal as an iCalendar file, as an attachment named
+// filename when one is given
+func renderCalendar(c buffalo.Context, cal ical.Calendar, filename string) error {
+	if filename != "" {
+		c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	}
+	c.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(cacheTTL(calendarCacheTTL).Seconds())))
+	return c.Render(http.StatusOK, r.Func(ical.ContentType, func(w io.Writer, d render.Data) error {
+		_, err := cal.WriteTo(w)
+		return err
+	}))
+}
+
+// EventsIndex lists upcoming published events and volunteer days
+func EventsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	now := time.Now()
+	events := models.Events{}
+	err := tx.Where("active = ? AND published = ?", true, true).
+		Where("(ends_at > ? OR (ends_at IS NULL AND starts_at > ?))", now, now.Add(-models.DefaultEventLength)).
+		Order("starts_at asc").All(&events)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("title", "Events")
+	c.Set("events", events)
+	c.Set("calendarFeedURL", siteURL()+"/events/calendar.ics")
+	return c.Render(http.StatusOK, r.HTML("pages/events.plush.html"))
+}
+
+// EventsCalendarFeed serves published events as a calendar feed people can
+// subscribe to. Published events that have been called off stay in the feed
+// marked cancelled, so subscribers' calendars drop them.
+func EventsCalendarFeed(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	events := models.Events{}
+	err := tx.Where("published = ? AND starts_at > ?", true, time.Now().Add(-calendarFeedWindow)).
+		Order("starts_at asc").All(&events)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return renderCalendar(c, eventCalendar(events), "")
+}
+
+// EventCalendarDownload serves one published event as an .ics file to add
+// to a calendar
+func EventCalendarDownload(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	event := &models.Event{}
+	if err := tx.Find(event, c.Param("event_id")); err != nil || !event.Listed() {
+		return c.Error(http.StatusNotFound, fmt.Errorf("event not found"))
+	}
+	return renderCalendar(c, eventCalendar(models.Events{*event}), "avr-event.ics")
+}
+
+// queueGoogleCalendarSync copies an event to the staff Google Calendar in
+// the background, when sync is set up
+func queueGoogleCalendarSync(tx *pop.Connection, event *models.Event) {
+	if !services.GoogleCalendarEnabled() {
+		return
+	}
+	queueJob(tx, jobGoogleCalendarSync, worker.Args{"event_id": event.ID.String()})
+}
+
+// syncGoogleCalendarJob adds a listed event to the staff Google Calendar or
+// updates it there, and removes events that are no longer listed
+func syncGoogleCalendarJob(args worker.Args) error {
+	if !services.GoogleCalendarEnabled() {
+		return nil
+	}
+	event := &models.Event{}
+	if err := models.DB.Find(event, jobArg(args, "event_id")); err != nil {
+		return errors.Wrap(err, "loading event")
+	}
+	client, err := services.NewGoogleCalendarClient()
+	if err != nil {
+		return err
+	}
+
+	googleID := ""
+	if event.GoogleEventID != nil {
+		googleID = *event.GoogleEventID
+	}
+	if !event.Listed() {
+		if googleID == "" {
+			return nil
+		}
+		if err := client.DeleteEvent(googleID); err != nil {
+			return err
+		}
+		return errors.WithStack(models.DB.RawQuery("UPDATE events SET google_event_id = NULL WHERE id = ?", event.ID).Exec())
+	}
+
+	savedID, err := client.SaveEvent(googleID, services.CalendarEvent{
+		Summary:     event.Title,
+		Description: event.DescriptionText(),
+		Location:    event.LocationText(),
+		Start:       event.StartsAt,
+		End:         event.EndTime(),
+	})
+	if err != nil {
+		return err
+	}
+	if savedID == googleID {
+		return nil
+	}
+	return errors.WithStack(models.DB.RawQuery("UPDATE events SET google_event_id = ? WHERE id = ?", savedID, event.ID).Exec())
+}
+
+// QueueEventReminders queues a reminder email for every ticket holder whose
+// event has a reminder due, as set by the event's reminder schedule. Each
+// reminder is recorded as it's queued so it goes out once however often
+// this runs. It's run from cron through the events:reminders task and
+// returns how many reminders were queued.
+func QueueEventReminders(tx *pop.Connection, now time.Time) (int, error) {
+	events := models.Events{}
+	err := tx.Where("active = ? AND reminders <> '' AND starts_at > ? AND starts_at <= ?", true, now, now.Add(models.EventReminders[0].Before)).All(&events)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	queued := 0
+	for _, event := range events {
+		tickets := models.EventTickets{}
+		if err := tx.Where("event_id = ?", event.ID).All(&tickets); err != nil {
+			return queued, errors.WithStack(err)
+		}
+		sent := models.EventTicketReminders{}
+		err := tx.Where("ticket_id IN (SELECT id FROM event_tickets WHERE event_id = ?)", event.ID).All(&sent)
+		if err != nil {
+			return queued, errors.WithStack(err)
+		}
+		alreadySent := map[string]bool{}
+		for _, s := range sent {
+			alreadySent[s.TicketID.String()+"/"+s.Reminder] = true
+		}
+
+		for _, ticket := range tickets {
+			reminder, ok := event.DueReminder(ticket.CreatedAt, now)
+			if !ok || alreadySent[ticket.ID.String()+"/"+reminder.Key] {
+				continue
+			}
+			if err := tx.Create(&models.EventTicketReminder{TicketID: ticket.ID, Reminder: reminder.Key}); err != nil {
+				return queued, errors.WithStack(err)
+			}
+			queueJob(tx, jobEventReminder, worker.Args{
+				"ticket_id": ticket.ID.String(),
+				"reminder":  reminder.Key,
+			})
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// sendEventReminderJob emails a ticket holder a reminder queued by
+// QueueEventReminders. Reminders for events that have since been called off
+// or have started are dropped.
+func sendEventReminderJob(args worker.Args) error {
+	ticket := &models.EventTicket{}
+	if err := models.DB.Find(ticket, jobArg(args, "ticket_id")); err != nil {
+		return errors.Wrap(err, "loading event ticket")
+	}
+	event := &models.Event{}
+	if err := models.DB.Find(event, ticket.EventID); err != nil {
+		return errors.Wrap(err, "loading event")
+	}
+	if !event.Active || !time.Now().Before(event.StartsAt) {
+		return nil
+	}
+
+	calendarURL := event.GoogleCalendarURL()
+	if event.Listed() {
+		calendarURL = fmt.Sprintf("%s/events/%s.ics", siteURL(), event.ID)
+	}
+	err := services.NewEmailService().SendEventReminder(ticket.HolderEmail, services.EventReminderData{
+		HolderName:       ticket.HolderName,
+		EventTitle:       event.Title,
+		StartsAt:         event.StartsAt,
+		Location:         event.LocationText(),
+		Message:          event.ReminderMessageFor(ticket.HolderName),
+		TicketURL:        siteURL() + "/tickets/" + ticket.Token,
+		CalendarURL:      calendarURL,
+		OrganizationName: organization().Name,
+	})
+	if err != nil {
+		return err
+	}
+	logging.Audit("event_reminder_sent", logging.Fields{
+		"event_id":  event.ID.String(),
+		"ticket_id": ticket.ID.String(),
+		"reminder":  jobArg(args, "reminder"),
+	})
+	return nil
+}
@@ -0,0 +1,93 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/ical"
+)
+
+func Test_TicketTokenFromScan(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("abc123", ticketTokenFromScan("https://avrnpo.org/tickets/abc123"))
+	r.Equal("abc123", ticketTokenFromScan("https://avrnpo.org/tickets/abc123/qr.svg"))
+	r.Equal("abc123", ticketTokenFromScan("http://localhost:3000/tickets/abc123?utm_source=email"))
+	r.Equal("abc123", ticketTokenFromScan("  abc123\n"))
+	r.Equal("", ticketTokenFromScan(""))
+}
+
+func Test_EventCalendarEntry(t *testing.T) {
+	req := require.New(t)
+
+	location := "AVR Workshop, 12 Main St"
+	startsAt := time.Date(2026, 11, 14, 9, 0, 0, 0, time.UTC)
+	event := models.Event{ID: uuid.Must(uuid.NewV4()), Title: "Build Day", Kind: models.EventKindVolunteerDay, Location: &location, StartsAt: startsAt, Active: true, Published: true}
+
+	entry := eventCalendarEntry(event)
+	req.Equal(event.ID.String()+"@avrnpo.org", entry.UID)
+	req.Equal("AVR Workshop, 12 Main St", entry.Location)
+	req.Equal(startsAt.Add(models.DefaultEventLength), entry.End)
+	req.False(entry.Cancelled)
+
+	event.Active = false
+	req.True(eventCalendarEntry(event).Cancelled)
+}
+
+func Test_EventCalendarDownloadRendering(t *testing.T) {
+	req := require.New(t)
+
+	event := models.Event{ID: uuid.Must(uuid.NewV4()), Title: "Veterans Day Dinner", StartsAt: time.Date(2026, 11, 11, 23, 0, 0, 0, time.UTC), Active: true, Published: true, Reminders: "1d"}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/calendar-test", func(c buffalo.Context) error {
+		return renderCalendar(c, eventCalendar(models.Events{event}), "avr-event.ics")
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/calendar-test", nil))
+	req.Equal(http.StatusOK, res.Code)
+	req.Equal(ical.ContentType, res.Header().Get("Content-Type"))
+	req.Contains(res.Header().Get("Content-Disposition"), "avr-event.ics")
+	req.Contains(res.Body.String(), "BEGIN:VEVENT\r\n")
+	req.Contains(res.Body.String(), "SUMMARY:Veterans Day Dinner\r\n")
+	req.Contains(res.Body.String(), "DTSTART:20261111T230000Z\r\n")
+}
+
+func Test_EventAdminTemplatesRendering(t *testing.T) {
+	req := require.New(t)
+
+	endsAt := time.Date(2026, 11, 14, 15, 0, 0, 0, time.Local)
+	event := models.Event{ID: uuid.Must(uuid.NewV4()), Title: "Build Day", Kind: models.EventKindVolunteerDay, StartsAt: endsAt.Add(-6 * time.Hour), EndsAt: &endsAt, Active: true, Published: true, Reminders: "1d"}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/admin-event-edit-test", func(c buffalo.Context) error {
+		setEventFormContext(c, &event)
+		return c.Render(http.StatusOK, r.HTML("admin/events/edit.plush.html"))
+	})
+	app.GET("/admin-event-new-test", func(c buffalo.Context) error {
+		setEventFormContext(c, &models.Event{Active: true})
+		return c.Render(http.StatusOK, r.HTML("admin/events/new.plush.html"))
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/admin-event-edit-test", nil))
+	req.Equal(http.StatusOK, res.Code, res.Body.String())
+	req.Contains(res.Body.String(), `value="2026-11-14T15:00"`)
+	req.Contains(res.Body.String(), `<option value="volunteer_day" selected>Volunteer day</option>`)
+	req.Contains(res.Body.String(), `name="Published" value="true" checked`)
+	req.Contains(res.Body.String(), `name="Reminders" value="1d" checked`)
+	req.Contains(res.Body.String(), `name="Reminders" value="2h">`)
+
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/admin-event-new-test", nil))
+	req.Equal(http.StatusOK, res.Code, res.Body.String())
+	req.Contains(res.Body.String(), "Create Event")
+}
@@ -0,0 +1,348 @@
+package actions
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+)
+
+const (
+	forecastMonths      = 12
+	churnWindowMonths   = 6
+	staffDigestLookback = 7 * 24 * time.Hour
+)
+
+// recurringForecast is the 12-month projection of recurring revenue shown on
+// the finance report and in the weekly staff digest
+type recurringForecast struct {
+	ActiveMonthly  int
+	ActivePledges  int
+	MonthlyRevenue float64
+	ChurnRate      float64
+	Months         []services.ForecastMonth
+	Total          float64
+}
+
+// ChurnPercent is the monthly churn rate as a percentage, e.g. 2.5
+func (f recurringForecast) ChurnPercent() float64 {
+	return math.Round(f.ChurnRate*1000) / 10
+}
+
+// BarHeight is how tall a month's bar is on the forecast chart, as a
+// percentage of the tallest month
+func (f recurringForecast) BarHeight(month services.ForecastMonth) int {
+	highest := 0.0
+	for _, m := range f.Months {
+		highest = math.Max(highest, m.Expected())
+	}
+	if highest == 0 {
+		return 0
+	}
+	return int(math.Round(month.Expected() / highest * 100))
+}
+
+// loadRecurringForecast projects the next year of recurring revenue from
+// active monthly gifts and installment pledges, discounted by the churn seen
+// over the last six months
+func loadRecurringForecast(tx *pop.Connection, now time.Time) (recurringForecast, error) {
+	forecast := recurringForecast{}
+
+	active := models.Donations{}
+	if err := tx.Where("status = ? AND subscription_id IS NOT NULL", "active").All(&active); err != nil {
+		return forecast, errors.WithStack(err)
+	}
+	gifts := make([]services.RecurringGift, 0, len(active))
+	for _, d := range active {
+		// Quarterly and annual gifts are spread evenly over the months they cover
+		gift := services.RecurringGift{Amount: d.MonthlyEquivalent()}
+		if d.IsInstallmentPledge() {
+			gift.RemainingPayments = d.InstallmentCount - d.InstallmentsPaid
+			if gift.RemainingPayments <= 0 {
+				continue
+			}
+			forecast.ActivePledges++
+		} else {
+			forecast.ActiveMonthly++
+			forecast.MonthlyRevenue += d.MonthlyEquivalent()
+		}
+		gifts = append(gifts, gift)
+	}
+
+	since := now.AddDate(0, -churnWindowMonths, 0)
+	cancelled, err := tx.Where("status = ? AND subscription_id IS NOT NULL AND updated_at >= ?", "cancelled", since).Count(&models.Donation{})
+	if err != nil {
+		return forecast, errors.WithStack(err)
+	}
+
+	forecast.ChurnRate = services.MonthlyChurnRate(cancelled, len(gifts), churnWindowMonths)
+	forecast.Months = services.ForecastRecurringRevenue(gifts, forecast.ChurnRate, now, forecastMonths)
+	forecast.Total = services.ForecastTotal(forecast.Months)
+	return forecast, nil
+}
+
+// AdminFinanceReport shows the recurring revenue forecast
+func AdminFinanceReport(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	forecast, err := loadRecurringForecast(tx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	nudges, err := models.LoadMonthlyNudgeStats(tx, time.Now().AddDate(-1, 0, 0))
+	if err != nil {
+		return err
+	}
+
+	c.Set("forecast", forecast)
+	c.Set("nudges", nudges)
+	c.Set("nudgeMinGifts", models.MonthlyNudgeMinGifts)
+	return c.Render(http.StatusOK, r.HTML("admin/finance.plush.html"))
+}
+
+// Ways donors can be grouped into acquisition cohorts
+const (
+	cohortByMonth    = "month"
+	cohortByCampaign = "campaign"
+	cohortByChannel  = "channel"
+)
+
+var cohortGroupings = []string{cohortByMonth, cohortByCampaign, cohortByChannel}
+
+// giftChannelLabels names the payment methods gifts arrive through. Helcim
+// card and bank gifts don't always record a payment method.
+var giftChannelLabels = map[string]string{
+	"":                                  "Online (card)",
+	"card":                              "Online (card)",
+	models.PaymentMethodCrypto:          "Crypto",
+	services.GiftSourcePayPalGivingFund: "PayPal Giving Fund",
+	services.GiftSourceVenmo:            "Venmo",
+}
+
+// giftChannelLabel is the display name for the channel a gift came through
+func giftChannelLabel(method string) string {
+	if label, ok := giftChannelLabels[method]; ok {
+		return label
+	}
+	return method
+}
+
+// donorCohort totals the giving of the donors whose first gift fell in the
+// same month, came through the same campaign page, or used the same channel
+type donorCohort struct {
+	Key        string
+	Label      string
+	Donors     int
+	FirstGifts float64
+	Total      float64
+	Repeat     int // gave again, or set up a recurring gift
+	Recurring  int // still giving monthly
+}
+
+// LifetimeValue is the average amount each donor in the cohort has given
+func (c donorCohort) LifetimeValue() float64 {
+	if c.Donors == 0 {
+		return 0
+	}
+	return math.Round(c.Total/float64(c.Donors)*100) / 100
+}
+
+// AverageFirstGift is the average size of the cohort's first gifts, counting
+// a single payment of a recurring gift
+func (c donorCohort) AverageFirstGift() float64 {
+	if c.Donors == 0 {
+		return 0
+	}
+	return math.Round(c.FirstGifts/float64(c.Donors)*100) / 100
+}
+
+// RepeatPercent is the share of the cohort who gave more than once
+func (c donorCohort) RepeatPercent() int {
+	if c.Donors == 0 {
+		return 0
+	}
+	return int(math.Round(float64(c.Repeat) / float64(c.Donors) * 100))
+}
+
+// RecurringPercent is the share of the cohort still giving monthly
+func (c donorCohort) RecurringPercent() int {
+	if c.Donors == 0 {
+		return 0
+	}
+	return int(math.Round(float64(c.Recurring) / float64(c.Donors) * 100))
+}
+
+// buildDonorCohorts groups donors by their first gift and totals what each
+// cohort has given since. Campaigns are the corporate partner pages gifts
+// are made from, named in partners.
+func buildDonorCohorts(donations []models.Donation, by string, partners map[uuid.UUID]string, now time.Time) []donorCohort {
+	sorted := make([]models.Donation, 0, len(donations))
+	for _, d := range donations {
+		if d.DonorEmail != "" && d.ReceivedToDate(now) > 0 {
+			sorted = append(sorted, d)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.Before(sorted[j].CreatedAt) })
+
+	type donorGiving struct {
+		first     models.Donation
+		gifts     int
+		total     float64
+		recurring bool
+		active    bool
+	}
+	donors := map[string]*donorGiving{}
+	for _, d := range sorted {
+		email := models.NormalizeDonorEmail(d.DonorEmail)
+		giving, ok := donors[email]
+		if !ok {
+			giving = &donorGiving{first: d}
+			donors[email] = giving
+		}
+		giving.gifts++
+		giving.total += d.ReceivedToDate(now)
+		if d.IsRecurring() && !d.IsInstallmentPledge() {
+			giving.recurring = true
+			giving.active = giving.active || d.Status == "active"
+		}
+	}
+
+	cohorts := map[string]*donorCohort{}
+	for _, giving := range donors {
+		first := giving.first
+		var key, label string
+		switch by {
+		case cohortByCampaign:
+			key, label = "", "Main donation page"
+			if first.PartnerID != nil {
+				key = first.PartnerID.String()
+				label = partners[*first.PartnerID]
+			}
+		case cohortByChannel:
+			key = stringOrEmpty(first.PaymentMethod)
+			label = giftChannelLabel(key)
+		default:
+			key = first.CreatedAt.Format("2006-01")
+			label = first.CreatedAt.Format("January 2006")
+		}
+		cohort, ok := cohorts[label]
+		if !ok {
+			cohort = &donorCohort{Key: key, Label: label}
+			cohorts[label] = cohort
+		}
+		cohort.Donors++
+		cohort.FirstGifts += first.Amount
+		cohort.Total += giving.total
+		if giving.gifts > 1 || giving.recurring {
+			cohort.Repeat++
+		}
+		if giving.active {
+			cohort.Recurring++
+		}
+	}
+
+	rows := make([]donorCohort, 0, len(cohorts))
+	for _, cohort := range cohorts {
+		cohort.Total = math.Round(cohort.Total*100) / 100
+		rows = append(rows, *cohort)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if by == cohortByMonth || by == "" {
+			return rows[i].Key > rows[j].Key
+		}
+		if rows[i].LifetimeValue() != rows[j].LifetimeValue() {
+			return rows[i].LifetimeValue() > rows[j].LifetimeValue()
+		}
+		return rows[i].Label < rows[j].Label
+	})
+	return rows
+}
+
+// AdminDonorCohorts shows donor lifetime value by acquisition cohort
+// (?by=month, campaign or channel)
+func AdminDonorCohorts(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	by := strings.TrimSpace(c.Param("by"))
+	if by != cohortByCampaign && by != cohortByChannel {
+		by = cohortByMonth
+	}
+
+	donations := []models.Donation{}
+	err := tx.Where("status IN (?, ?, ?) AND donor_email <> ''", "completed", "active", "cancelled").
+		Order("created_at asc").All(&donations)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	partners := models.CorporatePartners{}
+	if err := tx.All(&partners); err != nil {
+		return errors.WithStack(err)
+	}
+	partnerNames := map[uuid.UUID]string{}
+	for _, p := range partners {
+		partnerNames[p.ID] = p.Name
+	}
+
+	c.Set("cohorts", buildDonorCohorts(donations, by, partnerNames, time.Now()))
+	c.Set("by", by)
+	c.Set("groupings", cohortGroupings)
+	return c.Render(http.StatusOK, r.HTML("admin/cohorts.plush.html"))
+}
+
+// SendWeeklyStaffDigest emails staff the past week's giving and the
+// recurring revenue forecast. It's run weekly by the digest:weekly task, and
+// goes to STAFF_DIGEST_EMAIL, or the contact address when that's unset.
+func SendWeeklyStaffDigest(tx *pop.Connection, now time.Time) error {
+	since := now.Add(-staffDigestLookback)
+
+	var week struct {
+		Gifts int     `db:"gifts"`
+		Total float64 `db:"total"`
+	}
+	err := tx.RawQuery(`SELECT COUNT(*) AS gifts, COALESCE(SUM(amount), 0) AS total
+		FROM donations WHERE status IN (?, ?) AND created_at >= ?`, "completed", "active", since).First(&week)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	newRecurring, err := tx.Where("status = ? AND subscription_id IS NOT NULL AND created_at >= ?", "active", since).Count(&models.Donation{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	cancelled, err := tx.Where("status = ? AND subscription_id IS NOT NULL AND updated_at >= ?", "cancelled", since).Count(&models.Donation{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	forecast, err := loadRecurringForecast(tx, now)
+	if err != nil {
+		return err
+	}
+
+	emailService := services.NewEmailService()
+	to := envy.Get("STAFF_DIGEST_EMAIL", emailService.ContactEmail)
+	return emailService.SendStaffDigest(to, services.StaffDigestData{
+		WeekStart:        since,
+		WeekEnd:          now,
+		GiftCount:        week.Gifts,
+		GiftTotal:        week.Total,
+		NewRecurring:     newRecurring,
+		Cancelled:        cancelled,
+		ActiveMonthly:    forecast.ActiveMonthly,
+		MonthlyRevenue:   forecast.MonthlyRevenue,
+		ChurnPercent:     forecast.ChurnPercent(),
+		Forecast:         forecast.Months,
+		ForecastTotal:    forecast.Total,
+		OrganizationName: organization().Name,
+	})
+}
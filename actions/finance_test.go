@@ -0,0 +1,122 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+)
+
+func Test_FinanceReportTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	months := services.ForecastRecurringRevenue([]services.RecurringGift{
+		{Amount: 100},
+		{Amount: 100, RemainingPayments: 1},
+	}, 0, time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), 12)
+	forecast := recurringForecast{
+		ActiveMonthly:  1,
+		ActivePledges:  1,
+		MonthlyRevenue: 100,
+		ChurnRate:      0.025,
+		Months:         months,
+		Total:          services.ForecastTotal(months),
+	}
+	req.Equal(100, forecast.BarHeight(months[0]))
+	req.Equal(50, forecast.BarHeight(months[1]))
+	req.Equal(2.5, forecast.ChurnPercent())
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/finance-test", func(c buffalo.Context) error {
+		c.Set("forecast", forecast)
+		c.Set("nudges", models.MonthlyNudgeStats{Sent: 8, Converted: 2, AddedMonthly: 40})
+		c.Set("nudgeMinGifts", models.MonthlyNudgeMinGifts)
+		return c.Render(http.StatusOK, r.HTML("admin/finance.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/finance-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "November 2026")
+	req.Contains(w.Body.String(), `style="height: 50%;"`)
+	req.Contains(w.Body.String(), "$1,300.00")
+	req.Contains(w.Body.String(), "2.5% monthly churn")
+	req.Contains(w.Body.String(), "<h3>25%</h3>")
+}
+
+func Test_BuildDonorCohorts(t *testing.T) {
+	req := require.New(t)
+
+	now := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time { return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC) }
+	partner := uuid.Must(uuid.NewV4())
+	subscription := "sub_1"
+	crypto := models.PaymentMethodCrypto
+	donations := []models.Donation{
+		// Sam gives once from a partner page, then again in September
+		{DonorEmail: "sam@example.com", Amount: 100, Status: "completed", PartnerID: &partner, CreatedAt: day(8, 3)},
+		{DonorEmail: "Sam@Example.com", Amount: 50, Status: "completed", CreatedAt: day(9, 9)},
+		// Pat starts giving $20 a month in August: August, September and October
+		{DonorEmail: "pat@example.com", Amount: 20, DonationType: "monthly", Status: "active", SubscriptionID: &subscription, CreatedAt: day(8, 10)},
+		// Lee gives crypto once in September; Kim's card was declined
+		{DonorEmail: "lee@example.com", Amount: 500, Status: "completed", PaymentMethod: &crypto, CreatedAt: day(9, 20)},
+		{DonorEmail: "kim@example.com", Amount: 40, Status: "failed", CreatedAt: day(9, 21)},
+	}
+
+	byMonth := buildDonorCohorts(donations, cohortByMonth, nil, now)
+	req.Len(byMonth, 2)
+	req.Equal("September 2026", byMonth[0].Label, "newest cohort first")
+	req.Equal(1, byMonth[0].Donors)
+	req.Equal(500.0, byMonth[0].LifetimeValue())
+	august := byMonth[1]
+	req.Equal(2, august.Donors)
+	req.Equal(210.0, august.Total)
+	req.Equal(105.0, august.LifetimeValue())
+	req.Equal(60.0, august.AverageFirstGift())
+	req.Equal(100, august.RepeatPercent())
+	req.Equal(50, august.RecurringPercent())
+
+	byCampaign := buildDonorCohorts(donations, cohortByCampaign, map[uuid.UUID]string{partner: "Acme Builders"}, now)
+	req.Len(byCampaign, 2)
+	req.Equal("Main donation page", byCampaign[0].Label, "highest lifetime value first")
+	req.Equal(2, byCampaign[0].Donors)
+	req.Equal(280.0, byCampaign[0].LifetimeValue())
+	req.Equal("Acme Builders", byCampaign[1].Label)
+	req.Equal(150.0, byCampaign[1].LifetimeValue(), "later gifts count toward the first gift's campaign")
+
+	byChannel := buildDonorCohorts(donations, cohortByChannel, nil, now)
+	req.Len(byChannel, 2)
+	req.Equal("Crypto", byChannel[0].Label)
+	req.Equal("Online (card)", byChannel[1].Label)
+}
+
+func Test_CohortsTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/cohorts-test", func(c buffalo.Context) error {
+		c.Set("cohorts", []donorCohort{{Label: "Acme Builders", Donors: 4, FirstGifts: 200, Total: 1000, Repeat: 2, Recurring: 1}})
+		c.Set("by", cohortByCampaign)
+		c.Set("groupings", cohortGroupings)
+		return c.Render(http.StatusOK, r.HTML("admin/cohorts.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/cohorts-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "<th>Campaign</th>")
+	req.Contains(w.Body.String(), "<strong>$250.00</strong>")
+	req.Contains(w.Body.String(), "<td>50%</td>")
+	req.Contains(w.Body.String(), `<a href="/admin/finance/cohorts?by=channel">channel</a>`)
+}
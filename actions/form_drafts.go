@@ -0,0 +1,170 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+
+	"avrnpo.org/models"
+)
+
+// Long forms that are saved as they're typed, with the fields each keeps.
+// Photos can't be saved with a draft, so only the listed text fields are
+// stored and anything else posted is ignored.
+const (
+	draftFormMentoring = "mentoring"
+	draftFormVehicle   = "vehicle"
+)
+
+var draftFormFields = map[string][]string{
+	draftFormMentoring: {"Role", "DisplayName", "Trade", "Skills", "Region", "Bio"},
+	draftFormVehicle: {
+		"Year", "Make", "Model", "VIN", "Mileage", "Condition", "ConditionNotes",
+		"DonorName", "DonorEmail", "DonorPhone", "AddressLine1", "City", "State", "Zip",
+	},
+}
+
+// formDraftSessionKey holds the random key a visitor who isn't signed in
+// keeps their drafts under
+const formDraftSessionKey = "form_draft_key"
+
+// formDraftOwner is who drafts typed in this request belong to: the
+// signed-in account, or otherwise this browser's session. A session is only
+// given a key when create is set, so just viewing a form doesn't start one;
+// without a key there's no owner and "" is returned.
+func formDraftOwner(c buffalo.Context, create bool) (string, error) {
+	if user, ok := c.Value("current_user").(*models.User); ok && user != nil {
+		return "user:" + user.ID.String(), nil
+	}
+	key, _ := c.Session().Get(formDraftSessionKey).(string)
+	if key == "" {
+		if !create {
+			return "", nil
+		}
+		var err error
+		if key, err = models.GenerateFormDraftKey(); err != nil {
+			return "", err
+		}
+		c.Session().Set(formDraftSessionKey, key)
+	}
+	return "session:" + key, nil
+}
+
+// formDraftView is what a form's template needs to autosave: where to save
+// to and, when the form is first shown, the answers to restore
+type formDraftView struct {
+	Form    string
+	Values  map[string]string
+	SavedAt time.Time
+}
+
+// URL is where the form's draft is saved
+func (v formDraftView) URL() string {
+	return "/drafts/" + v.Form
+}
+
+// DiscardURL is where the form's draft is thrown away
+func (v formDraftView) DiscardURL() string {
+	return v.URL() + "/discard"
+}
+
+// Restoring reports whether there are saved answers to put back in the form
+func (v formDraftView) Restoring() bool {
+	return len(v.Values) > 0
+}
+
+// JSON is the answers to restore, for the autosave script
+func (v formDraftView) JSON() string {
+	if !v.Restoring() {
+		return "{}"
+	}
+	js, _ := json.Marshal(v.Values)
+	return string(js)
+}
+
+// SavedText is when the restored answers were last saved
+func (v formDraftView) SavedText() string {
+	return v.SavedAt.Format("Jan 2 at 3:04 PM")
+}
+
+// restoreFormDraft puts the answers saved on form back into its template's
+// formDraft, for when it's first shown. A form shown again with errors
+// already has the latest answers in it, so is left as it is.
+func restoreFormDraft(c buffalo.Context, form string) error {
+	owner, err := formDraftOwner(c, false)
+	if err != nil || owner == "" {
+		return err
+	}
+	draft, err := models.FindFormDraft(c.Value("tx").(*pop.Connection), form, owner)
+	if err != nil || draft == nil {
+		return err
+	}
+	c.Set("formDraft", formDraftView{Form: form, Values: draft.Values(), SavedAt: draft.UpdatedAt})
+	return nil
+}
+
+// discardFormDraft throws away form's draft once it's been submitted
+func discardFormDraft(c buffalo.Context, tx *pop.Connection, form string) error {
+	owner, err := formDraftOwner(c, false)
+	if err != nil || owner == "" {
+		return err
+	}
+	return models.DeleteFormDraft(tx, form, owner)
+}
+
+// FormDraftSave saves the answers typed so far on a long form. The autosave
+// script posts the form here every few seconds while it's being filled in
+// and when the page is hidden, so answers survive a dropped connection or
+// a reload. A form with nothing filled in has its draft discarded.
+func FormDraftSave(c buffalo.Context) error {
+	form := c.Param("form")
+	fields, ok := draftFormFields[form]
+	if !ok {
+		return jsonError(c, http.StatusNotFound, codeNotFound, "Unknown form")
+	}
+	tx := c.Value("tx").(*pop.Connection)
+	owner, err := formDraftOwner(c, true)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]string{}
+	for _, field := range fields {
+		if value := c.Param(field); value != "" {
+			values[field] = value
+		}
+	}
+	if len(values) == 0 {
+		if err := models.DeleteFormDraft(tx, form, owner); err != nil {
+			return err
+		}
+		return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "empty"}))
+	}
+
+	draft, verrs, err := models.SaveFormDraft(tx, form, owner, values)
+	if err != nil {
+		return err
+	}
+	if verrs.HasAny() {
+		return jsonValidationError(c, verrs.Errors)
+	}
+	return c.Render(http.StatusOK, r.JSON(map[string]interface{}{
+		"status":   "saved",
+		"saved_at": draft.UpdatedAt,
+	}))
+}
+
+// FormDraftDiscard throws away a form's draft when the person filling it in
+// chooses to start over
+func FormDraftDiscard(c buffalo.Context) error {
+	if _, ok := draftFormFields[c.Param("form")]; !ok {
+		return jsonError(c, http.StatusNotFound, codeNotFound, "Unknown form")
+	}
+	if err := discardFormDraft(c, c.Value("tx").(*pop.Connection), c.Param("form")); err != nil {
+		return err
+	}
+	return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "discarded"}))
+}
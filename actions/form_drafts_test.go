@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_FormDraftOwner(t *testing.T) {
+	req := require.New(t)
+	user := &models.User{ID: uuid.Must(uuid.NewV4())}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/owner", func(c buffalo.Context) error {
+		if c.Param("signed_in") != "" {
+			c.Set("current_user", user)
+		}
+		viewing, err := formDraftOwner(c, false)
+		if err != nil {
+			return err
+		}
+		saving, err := formDraftOwner(c, true)
+		if err != nil {
+			return err
+		}
+		again, _ := formDraftOwner(c, false)
+		return c.Render(http.StatusOK, r.String(viewing+"|"+saving+"|"+again))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/owner?signed_in=1", nil))
+	owner := "user:" + user.ID.String()
+	req.Equal(owner+"|"+owner+"|"+owner, w.Body.String())
+
+	// Visitors only get a session key once there's something to save
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/owner", nil))
+	parts := strings.Split(w.Body.String(), "|")
+	req.Len(parts, 3)
+	req.Equal("", parts[0])
+	req.True(strings.HasPrefix(parts[1], "session:"))
+	req.Greater(len(parts[1]), len("session:"))
+	req.Equal(parts[1], parts[2])
+}
+
+func Test_FormDraftSave_UnknownForm(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.POST("/drafts/{form}", FormDraftSave)
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("POST", "/drafts/passwords", strings.NewReader("Password=hunter2")))
+	req.Equal(http.StatusNotFound, w.Code)
+	req.Contains(w.Body.String(), `"code":"not_found"`)
+}
+
+func Test_FormDraftTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/mentoring-test", func(c buffalo.Context) error {
+		setMentorFormContext(c, &models.MentorProfile{Role: models.MentorRoleMentee}, false)
+		if c.Param("restore") != "" {
+			c.Set("formDraft", formDraftView{
+				Form:    draftFormMentoring,
+				Values:  map[string]string{"Bio": `Framer, 20 "years"`},
+				SavedAt: time.Date(2026, 10, 14, 15, 4, 0, 0, time.UTC),
+			})
+		}
+		return c.Render(http.StatusOK, r.HTML("users/mentoring.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/mentoring-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `data-autosave="/drafts/mentoring"`)
+	req.Contains(w.Body.String(), `data-autosave-draft="{}"`)
+	req.NotContains(w.Body.String(), "Start over")
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/mentoring-test?restore=1", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `data-autosave-draft="{&#34;Bio&#34;:&#34;Framer, 20 \&#34;years\&#34;&#34;}"`)
+	req.Contains(w.Body.String(), "answers you saved Oct 14 at 3:04 PM")
+	req.Contains(w.Body.String(), `data-autosave-discard="/drafts/mentoring/discard"`)
+}
@@ -0,0 +1,248 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// donationFormFields returns the active custom fields for a donation form:
+// fields shown on every form plus those for the partner page, if any.
+func donationFormFields(c buffalo.Context, partnerSlug string) models.DonationFormFields {
+	fields := models.DonationFormFields{}
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return fields
+	}
+
+	q := tx.Where("active = ?", true)
+	var partner *models.CorporatePartner
+	if strings.TrimSpace(partnerSlug) != "" {
+		partner, _ = findActivePartner(tx, partnerSlug)
+	}
+	if partner != nil {
+		q = q.Where("(partner_id IS NULL OR partner_id = ?)", partner.ID)
+	} else {
+		q = q.Where("partner_id IS NULL")
+	}
+	if err := q.Order("position asc, created_at asc").All(&fields); err != nil {
+		c.Logger().Errorf("[FormFields] Failed to load custom form fields: %v", err)
+	}
+	return fields
+}
+
+// customFieldValues collects custom field answers from the request: the
+// custom_fields object of a JSON request, or custom_* parameters of a form
+// post.
+func customFieldValues(c buffalo.Context, req DonationRequest) map[string]string {
+	if len(req.CustomFields) > 0 {
+		return req.CustomFields
+	}
+	values := map[string]string{}
+	if err := c.Request().ParseForm(); err != nil {
+		return values
+	}
+	for key := range c.Request().Form {
+		if strings.HasPrefix(key, "custom_") {
+			values[key] = c.Request().Form.Get(key)
+		}
+	}
+	return values
+}
+
+// customFieldAnswers checks submitted values against the form's custom fields.
+// values is keyed by each field's InputName.
+func customFieldAnswers(fields models.DonationFormFields, values map[string]string) ([]models.CustomFieldAnswer, map[string]string) {
+	var answers []models.CustomFieldAnswer
+	errs := map[string]string{}
+	for _, f := range fields {
+		value := strings.TrimSpace(values[f.InputName()])
+		switch f.FieldType {
+		case models.FormFieldCheckbox:
+			checked := value == "true" || value == "on"
+			if f.Required && !checked {
+				errs[f.InputName()] = fmt.Sprintf("Please check \"%s\"", f.Label)
+				continue
+			}
+			value = "No"
+			if checked {
+				value = "Yes"
+			}
+		case models.FormFieldDropdown:
+			if value != "" && !f.HasOption(value) {
+				errs[f.InputName()] = fmt.Sprintf("Please choose a valid option for \"%s\"", f.Label)
+				continue
+			}
+		default:
+			if len(value) > 500 {
+				value = value[:500]
+			}
+		}
+		if f.Required && value == "" {
+			errs[f.InputName()] = fmt.Sprintf("\"%s\" is required", f.Label)
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		answers = append(answers, models.CustomFieldAnswer{FieldID: f.ID, Label: f.Label, Value: value})
+	}
+	return answers, errs
+}
+
+// bindFormField copies the admin form field form onto field.
+func bindFormField(c buffalo.Context, field *models.DonationFormField) {
+	field.Label = strings.TrimSpace(c.Param("Label"))
+	field.FieldType = c.Param("FieldType")
+	field.Options = stringPointer(strings.TrimSpace(c.Param("Options")))
+	field.Required = c.Param("Required") == "true"
+	field.Active = c.Param("Active") == "true"
+	field.Position, _ = strconv.Atoi(c.Param("Position"))
+	field.PartnerID = nil
+	if id, err := uuid.FromString(c.Param("PartnerID")); err == nil {
+		field.PartnerID = &id
+	}
+}
+
+// setFormFieldContext exposes a field and the partner choices to the admin
+// form. Plush can't print the optional *string fields directly.
+func setFormFieldContext(c buffalo.Context, field *models.DonationFormField) error {
+	partners := models.CorporatePartners{}
+	if err := c.Value("tx").(*pop.Connection).Order("name asc").All(&partners); err != nil {
+		return errors.WithStack(err)
+	}
+	partnerID := ""
+	if field.PartnerID != nil {
+		partnerID = field.PartnerID.String()
+	}
+	c.Set("field", field)
+	c.Set("fieldOptions", stringOrEmpty(field.Options))
+	c.Set("fieldPartnerID", partnerID)
+	c.Set("fieldTypes", models.FormFieldTypes)
+	c.Set("partners", partners)
+	return nil
+}
+
+// AdminFormFieldsIndex lists the custom donation form fields
+func AdminFormFieldsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	fields := models.DonationFormFields{}
+	if err := tx.Order("position asc, created_at asc").All(&fields); err != nil {
+		return errors.WithStack(err)
+	}
+
+	partners := models.CorporatePartners{}
+	if err := tx.All(&partners); err != nil {
+		return errors.WithStack(err)
+	}
+	partnerNames := map[string]string{}
+	for _, p := range partners {
+		partnerNames[p.ID.String()] = p.Name
+	}
+	scopes := map[string]string{}
+	for _, f := range fields {
+		scopes[f.ID.String()] = "All forms"
+		if f.PartnerID != nil {
+			scopes[f.ID.String()] = partnerNames[f.PartnerID.String()]
+		}
+	}
+
+	c.Set("fields", fields)
+	c.Set("scopes", scopes)
+	return c.Render(http.StatusOK, r.HTML("admin/form_fields/index.plush.html"))
+}
+
+// AdminFormFieldsNew shows the form for adding a custom field
+func AdminFormFieldsNew(c buffalo.Context) error {
+	if err := setFormFieldContext(c, &models.DonationFormField{FieldType: models.FormFieldText, Active: true}); err != nil {
+		return err
+	}
+	return c.Render(http.StatusOK, r.HTML("admin/form_fields/new.plush.html"))
+}
+
+// AdminFormFieldsCreate saves a new custom field
+func AdminFormFieldsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	field := &models.DonationFormField{}
+	bindFormField(c, field)
+
+	verrs, err := tx.ValidateAndCreate(field)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		if err := setFormFieldContext(c, field); err != nil {
+			return err
+		}
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/form_fields/new.plush.html"))
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "form_field_created", fmt.Sprintf("Created donation form field: %s", field.Label), logging.Fields{
+		"field_id":   field.ID.String(),
+		"field_type": field.FieldType,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Field \"%s\" added to the donation form.", field.Label))
+	return c.Redirect(http.StatusSeeOther, "/admin/form-fields")
+}
+
+// AdminFormFieldsEdit shows the form for editing a custom field
+func AdminFormFieldsEdit(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	field := &models.DonationFormField{}
+	if err := tx.Find(field, c.Param("field_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	if err := setFormFieldContext(c, field); err != nil {
+		return err
+	}
+	return c.Render(http.StatusOK, r.HTML("admin/form_fields/edit.plush.html"))
+}
+
+// AdminFormFieldsUpdate saves changes to a custom field. Answers already
+// given keep the label they were asked with.
+func AdminFormFieldsUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	field := &models.DonationFormField{}
+	if err := tx.Find(field, c.Param("field_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	bindFormField(c, field)
+
+	verrs, err := tx.ValidateAndUpdate(field)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		if err := setFormFieldContext(c, field); err != nil {
+			return err
+		}
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/form_fields/edit.plush.html"))
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "form_field_updated", fmt.Sprintf("Updated donation form field: %s", field.Label), logging.Fields{
+		"field_id": field.ID.String(),
+		"active":   field.Active,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Field \"%s\" updated.", field.Label))
+	return c.Redirect(http.StatusSeeOther, "/admin/form-fields")
+}
@@ -0,0 +1,36 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_CustomFieldAnswers(t *testing.T) {
+	r := require.New(t)
+
+	opts := "Army\nNavy\nMarines"
+	veteran := models.DonationFormField{ID: uuid.Must(uuid.NewV4()), Label: "Are you a veteran?", FieldType: models.FormFieldCheckbox}
+	branch := models.DonationFormField{ID: uuid.Must(uuid.NewV4()), Label: "Branch", FieldType: models.FormFieldDropdown, Options: &opts, Required: true}
+	note := models.DonationFormField{ID: uuid.Must(uuid.NewV4()), Label: "Unit", FieldType: models.FormFieldText}
+	fields := models.DonationFormFields{veteran, branch, note}
+
+	answers, errs := customFieldAnswers(fields, map[string]string{
+		veteran.InputName(): "true",
+		branch.InputName():  "Navy",
+	})
+	r.Empty(errs)
+	r.Len(answers, 2)
+	r.Equal("Yes", answers[0].Value)
+	r.Equal("Navy", answers[1].Value)
+	r.Equal("Branch", answers[1].Label)
+
+	_, errs = customFieldAnswers(fields, map[string]string{branch.InputName(): "Coast Guard"})
+	r.Contains(errs, branch.InputName())
+
+	_, errs = customFieldAnswers(fields, map[string]string{})
+	r.Contains(errs, branch.InputName())
+}
@@ -0,0 +1,201 @@
+package actions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v6"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/pkg/ratelimit"
+	"avrnpo.org/services"
+)
+
+const defaultFraudSmallAmount = 5.00
+
+// fraudCheck is a card-testing velocity check: how many charge attempts one
+// IP address, email or card may make in a window, or for "small", how many
+// small gifts the whole site may take. Its limit can be changed with
+// FRAUD_LIMIT_<NAME>, e.g. FRAUD_LIMIT_IP=3/1h, or turned off with
+// FRAUD_LIMIT_<NAME>=off. Going over it flags the attempt rather than
+// refusing it.
+type fraudCheck struct {
+	Name    string
+	Default string
+}
+
+var fraudChecks = []fraudCheck{
+	{Name: "ip", Default: "5/1h"},
+	{Name: "email", Default: "3/1h"},
+	{Name: "card", Default: "3/1h"},
+	{Name: "small", Default: "10/10m"},
+}
+
+// fraudAttempt is what's known about a charge when it's screened
+type fraudAttempt struct {
+	IP        string
+	Email     string
+	CardToken string
+	Amount    float64
+}
+
+// fraudScreen screens charges for card testing before they reach Helcim.
+// Attempts are counted in the rate limit store, so the counts are shared
+// between instances when Redis is configured.
+type fraudScreen struct {
+	Limiter *ratelimit.Limiter
+	Rules   map[string]ratelimit.Rule
+	// SmallAmount is the largest gift counted towards the small-gift burst
+	SmallAmount float64
+	// DisposableEmails flags donors using a throwaway mailbox provider
+	DisposableEmails bool
+}
+
+// donationFraudScreen screens donation charges; nil when screening is off
+var donationFraudScreen *fraudScreen
+
+// fraudScreeningEnabled reports whether charges are screened for card
+// testing (FRAUD_SCREENING_ENABLED). Like rate limits, it's on by default
+// outside the test environment.
+func fraudScreeningEnabled() bool {
+	return envBool("FRAUD_SCREENING_ENABLED", envy.Get("GO_ENV", "development") != "test")
+}
+
+// newFraudScreen builds the screen from FRAUD_* env vars, counting attempts
+// with limiter. Limits that fail to parse keep their default.
+func newFraudScreen(limiter *ratelimit.Limiter) *fraudScreen {
+	rules := map[string]ratelimit.Rule{}
+	for _, check := range fraudChecks {
+		value := strings.TrimSpace(envy.Get("FRAUD_LIMIT_"+strings.ToUpper(check.Name), ""))
+		if strings.EqualFold(value, "off") {
+			continue
+		}
+		rule, err := ratelimit.ParseRule(value)
+		if value == "" || err != nil {
+			if err != nil {
+				logging.Warn("Invalid fraud screening limit, using the default", logging.Fields{
+					"limit":   check.Name,
+					"value":   value,
+					"default": check.Default,
+				})
+			}
+			rule, _ = ratelimit.ParseRule(check.Default)
+		}
+		rules[check.Name] = rule
+	}
+	return &fraudScreen{
+		Limiter:          limiter,
+		Rules:            rules,
+		SmallAmount:      envFloat("FRAUD_SMALL_AMOUNT", defaultFraudSmallAmount),
+		DisposableEmails: envBool("FRAUD_FLAG_DISPOSABLE_EMAILS", true),
+	}
+}
+
+// fraudCardKey identifies a card token in the store without keeping the
+// token itself there
+func fraudCardKey(cardToken string) string {
+	sum := sha256.Sum256([]byte(cardToken))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Screen counts a charge attempt and returns why it looks like card testing,
+// or "" if it doesn't. Every check counts the attempt, including ones after
+// the first that trips. A store error skips the check rather than blocking
+// the gift. A nil screen lets everything through.
+func (s *fraudScreen) Screen(attempt fraudAttempt) string {
+	if s == nil {
+		return ""
+	}
+
+	reasons := []string{}
+	if s.DisposableEmails && services.IsDisposableEmail(attempt.Email) {
+		reasons = append(reasons, "disposable email address")
+	}
+
+	keys := map[string]string{
+		"ip":    attempt.IP,
+		"email": strings.ToLower(strings.TrimSpace(attempt.Email)),
+	}
+	if attempt.CardToken != "" {
+		keys["card"] = fraudCardKey(attempt.CardToken)
+	}
+	if s.SmallAmount > 0 && attempt.Amount <= s.SmallAmount {
+		keys["small"] = "site"
+	}
+
+	for _, check := range fraudChecks {
+		rule, ok := s.Rules[check.Name]
+		key := keys[check.Name]
+		if !ok || key == "" {
+			continue
+		}
+		result, err := s.Limiter.Allow("fraud:"+check.Name+":"+key, rule)
+		if err != nil {
+			logging.Error("Fraud screening check failed", err, logging.Fields{"check": check.Name})
+			continue
+		}
+		if result.Allowed {
+			continue
+		}
+		switch check.Name {
+		case "small":
+			reasons = append(reasons, fmt.Sprintf("over %d gifts of $%.2f or less site-wide in %s", rule.Limit, s.SmallAmount, rule.Window))
+		default:
+			reasons = append(reasons, fmt.Sprintf("over %d charge attempts from this %s in %s", rule.Limit, fraudCheckSubject(check.Name), rule.Window))
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// fraudCheckSubject names what a velocity check counts attempts from
+func fraudCheckSubject(name string) string {
+	switch name {
+	case "ip":
+		return "IP address"
+	case "email":
+		return "email address"
+	}
+	return name
+}
+
+// flagDonationForFraud quarantines a suspicious donation in the review
+// queue, keeping the verified card so an admin can still approve it.
+func flagDonationForFraud(c buffalo.Context, tx *pop.Connection, donation *models.Donation, customerCode, cardToken, reason string) error {
+	reason = "Possible card testing: " + reason
+
+	donation.Status = models.DonationStatusFlagged
+	donation.CustomerID = &customerCode
+	donation.CardToken = &cardToken
+	donation.ReviewReason = &reason
+
+	if err := tx.Update(donation); err != nil {
+		c.Logger().Errorf("[FraudScreening] Failed to flag donation %s: %v", donation.ID.String(), err)
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to update donation")
+	}
+
+	logging.SecurityEvent(c, "donation_fraud_check", "flagged", reason, logging.Fields{
+		"donation_id": donation.ID.String(),
+		"amount":      donation.Amount,
+		"ip":          getClientIP(c),
+	})
+	publishDonationActivity(donation)
+
+	return c.Render(http.StatusOK, donationFlaggedResponse())
+}
+
+// donationFlaggedResponse doesn't say why the gift was flagged, so card
+// testers don't learn which check they tripped
+func donationFlaggedResponse() render.Renderer {
+	return r.JSON(map[string]interface{}{
+		"success": true,
+		"status":  models.DonationStatusFlagged,
+		"message": "Thank you! Your gift will be reviewed by our team before your card is charged.",
+	})
+}
@@ -0,0 +1,138 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/ratelimit"
+)
+
+// testFraudScreen screens with the default limits and a fixed clock
+func testFraudScreen() *fraudScreen {
+	var screen *fraudScreen
+	envy.Temp(func() {
+		for _, check := range fraudChecks {
+			envy.Set("FRAUD_LIMIT_"+strings.ToUpper(check.Name), "")
+		}
+		envy.Set("FRAUD_SMALL_AMOUNT", "")
+		envy.Set("FRAUD_FLAG_DISPOSABLE_EMAILS", "")
+		limiter := ratelimit.New(ratelimit.NewMemoryStore())
+		now := time.Unix(1_800_000_000, 0)
+		limiter.Now = func() time.Time { return now }
+		screen = newFraudScreen(limiter)
+	})
+	return screen
+}
+
+func Test_FraudScreen(t *testing.T) {
+	t.Run("ordinary gifts pass", func(t *testing.T) {
+		req := require.New(t)
+		screen := testFraudScreen()
+		req.Empty(screen.Screen(fraudAttempt{IP: "203.0.113.7", Email: "donor@example.com", CardToken: "tok-1", Amount: 50}))
+	})
+
+	t.Run("nil screen", func(t *testing.T) {
+		var screen *fraudScreen
+		require.Empty(t, screen.Screen(fraudAttempt{Email: "x@mailinator.com"}))
+	})
+
+	t.Run("disposable email", func(t *testing.T) {
+		req := require.New(t)
+		screen := testFraudScreen()
+		req.Equal("disposable email address", screen.Screen(fraudAttempt{IP: "203.0.113.7", Email: "x@mailinator.com", Amount: 50}))
+
+		screen.DisposableEmails = false
+		req.Empty(screen.Screen(fraudAttempt{IP: "203.0.113.8", Email: "y@mailinator.com", Amount: 50}))
+	})
+
+	t.Run("one IP trying many cards", func(t *testing.T) {
+		req := require.New(t)
+		screen := testFraudScreen()
+		emails := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+		for i, email := range emails {
+			req.Empty(screen.Screen(fraudAttempt{IP: "203.0.113.7", Email: email, CardToken: email, Amount: 50}), "attempt %d", i+1)
+		}
+		reason := screen.Screen(fraudAttempt{IP: "203.0.113.7", Email: "f@example.com", CardToken: "f", Amount: 50})
+		req.Equal("over 5 charge attempts from this IP address in 1h0m0s", reason)
+	})
+
+	t.Run("one card or email from many IPs", func(t *testing.T) {
+		req := require.New(t)
+		screen := testFraudScreen()
+		for _, ip := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+			req.Empty(screen.Screen(fraudAttempt{IP: ip, Email: "Donor@Example.com", CardToken: "tok-1", Amount: 50}))
+		}
+		reason := screen.Screen(fraudAttempt{IP: "203.0.113.4", Email: "donor@example.com ", CardToken: "tok-1", Amount: 50})
+		req.Contains(reason, "from this email address")
+		req.Contains(reason, "from this card")
+	})
+
+	t.Run("burst of small gifts", func(t *testing.T) {
+		req := require.New(t)
+		screen := testFraudScreen()
+		screen.Rules["small"] = ratelimit.Rule{Limit: 2, Window: 10 * time.Minute}
+		req.Empty(screen.Screen(fraudAttempt{IP: "203.0.113.1", Email: "a@example.com", Amount: 1}))
+		req.Empty(screen.Screen(fraudAttempt{IP: "203.0.113.2", Email: "b@example.com", Amount: 50}), "larger gifts aren't counted")
+		req.Empty(screen.Screen(fraudAttempt{IP: "203.0.113.3", Email: "c@example.com", Amount: 5}))
+		req.Equal("over 2 gifts of $5.00 or less site-wide in 10m0s", screen.Screen(fraudAttempt{IP: "203.0.113.4", Email: "d@example.com", Amount: 2}))
+	})
+}
+
+func Test_NewFraudScreen(t *testing.T) {
+	req := require.New(t)
+
+	envy.Temp(func() {
+		envy.Set("FRAUD_LIMIT_IP", "2/10m")
+		envy.Set("FRAUD_LIMIT_EMAIL", "off")
+		envy.Set("FRAUD_LIMIT_CARD", "lots")
+		envy.Set("FRAUD_LIMIT_SMALL", "")
+		envy.Set("FRAUD_SMALL_AMOUNT", "3")
+		envy.Set("FRAUD_FLAG_DISPOSABLE_EMAILS", "false")
+
+		screen := newFraudScreen(ratelimit.New(ratelimit.NewMemoryStore()))
+		req.Equal(ratelimit.Rule{Limit: 2, Window: 10 * time.Minute}, screen.Rules["ip"])
+		req.NotContains(screen.Rules, "email")
+		req.Equal("3/1h0m0s", screen.Rules["card"].String(), "invalid limits keep the default")
+		req.Equal("10/10m0s", screen.Rules["small"].String())
+		req.Equal(3.0, screen.SmallAmount)
+		req.False(screen.DisposableEmails)
+	})
+}
+
+func Test_AdminDonationReviewsFlaggedTemplate(t *testing.T) {
+	req := require.New(t)
+
+	reason := "Possible card testing: disposable email address"
+	donations := models.Donations{{
+		ID:           uuid.Must(uuid.NewV4()),
+		DonorName:    "Card Tester",
+		DonorEmail:   "x@mailinator.com",
+		Amount:       1,
+		DonationType: models.DonationTypeOneTime,
+		Status:       models.DonationStatusFlagged,
+		ReviewReason: &reason,
+		CreatedAt:    time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC),
+	}}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/review-test", func(c buffalo.Context) error {
+		c.Set("donations", donations)
+		c.Set("flags", donorFlagIndex{})
+		c.Set("threshold", 10000.0)
+		return c.Render(http.StatusOK, r.HTML("admin/donation_reviews.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/review-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `<span class="donor-flag donor-flag-red">Flagged</span> <small>Possible card testing: disposable email address</small>`)
+}
@@ -0,0 +1,339 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+const defaultGiftCodeExpiryMonths = 12
+
+// giftPrograms are the programs a gift code recipient can direct funds to.
+var giftPrograms = []string{"Housing Projects", "Skills Training", "Community Support", "Program Operations"}
+
+// giftCodeExpiryMonths is how long a code can be redeemed after purchase
+// (GIFT_CODE_EXPIRY_MONTHS). Zero means codes never expire.
+func giftCodeExpiryMonths() int {
+	n, err := strconv.Atoi(strings.TrimSpace(envy.Get("GIFT_CODE_EXPIRY_MONTHS", "")))
+	if err != nil || n < 0 {
+		return defaultGiftCodeExpiryMonths
+	}
+	return n
+}
+
+// isGiftProgram reports whether program is one recipients can choose.
+func isGiftProgram(program string) bool {
+	for _, p := range giftPrograms {
+		if p == program {
+			return true
+		}
+	}
+	return false
+}
+
+// giftRequestErrors returns field errors for a gift card purchase made
+// through the donation form. Gift cards are always one-time gifts.
+func giftRequestErrors(req DonationRequest) map[string]string {
+	errs := map[string]string{}
+	if req.GiftCard != "true" {
+		return errs
+	}
+	if req.DonationType != models.DonationTypeOneTime {
+		errs["donation_type"] = "Gift cards can only be purchased as a one-time donation"
+	}
+	email := strings.TrimSpace(req.GiftRecipientEmail)
+	if email != "" && (!strings.Contains(email, "@") || !strings.Contains(email, ".")) {
+		errs["gift_recipient_email"] = "Please enter a valid email address for the recipient"
+	}
+	return errs
+}
+
+// createPendingGiftCode attaches a gift code to a purchase donation. The code
+// stays pending until the purchase is charged.
+func createPendingGiftCode(tx *pop.Connection, donation *models.Donation, req DonationRequest) error {
+	if req.GiftCard != "true" {
+		return nil
+	}
+	code, err := models.GenerateGiftCode()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	message := strings.TrimSpace(req.GiftMessage)
+	if len(message) > 500 {
+		message = message[:500]
+	}
+
+	gift := &models.GiftCode{
+		Code:           code,
+		Amount:         donation.Amount,
+		Currency:       donation.Currency,
+		DonationID:     donation.ID,
+		PurchaserName:  donation.DonorName,
+		PurchaserEmail: donation.DonorEmail,
+		RecipientName:  stringPointer(strings.TrimSpace(req.GiftRecipientName)),
+		RecipientEmail: stringPointer(strings.TrimSpace(req.GiftRecipientEmail)),
+		Message:        stringPointer(message),
+		Status:         models.GiftCodeStatusPending,
+	}
+	verrs, err := tx.ValidateAndCreate(gift)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		return errors.New(verrs.String())
+	}
+	return nil
+}
+
+// activateGiftCode makes a purchased gift code redeemable once its donation
+// has been charged, and emails it out. Donations without a gift code, or
+// whose code is already active, are left alone.
+func activateGiftCode(c buffalo.Context, tx *pop.Connection, donation *models.Donation) {
+	gift := &models.GiftCode{}
+	if err := tx.Where("donation_id = ? AND status = ?", donation.ID, models.GiftCodeStatusPending).First(gift); err != nil {
+		return
+	}
+
+	gift.Status = models.GiftCodeStatusActive
+	if months := giftCodeExpiryMonths(); months > 0 {
+		expires := time.Now().AddDate(0, months, 0)
+		gift.ExpiresAt = &expires
+	}
+	if err := tx.Update(gift); err != nil {
+		c.Logger().Errorf("[GiftCode] Failed to activate gift code for donation %s: %v", donation.ID.String(), err)
+		return
+	}
+
+	logging.Audit("gift_code_activated", logging.Fields{
+		"gift_code_id": gift.ID.String(),
+		"donation_id":  donation.ID.String(),
+		"amount":       gift.Amount,
+	})
+
+	// A recipient who asked not to be contacted doesn't get the code; it goes
+	// to the purchaser to pass on instead
+	to := stringOrEmpty(gift.RecipientEmail)
+	if to == "" || contactSuppressed(tx, models.SuppressEmail, to, "Gift code for "+gift.PurchaserName) {
+		to = gift.PurchaserEmail
+	}
+
+	req := c.Request()
+	scheme := "http"
+	if req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	err := services.NewEmailService().SendGiftCode(to, services.GiftCodeData{
+		RecipientName:    stringOrEmpty(gift.RecipientName),
+		PurchaserName:    gift.PurchaserName,
+		Message:          stringOrEmpty(gift.Message),
+		Code:             gift.Code,
+		Amount:           gift.Amount,
+		ExpiresAt:        gift.ExpiresAt,
+		RedeemURL:        scheme + "://" + req.Host + "/gift-cards/redeem?code=" + gift.Code,
+		OrganizationName: organization().Name,
+	})
+	if err != nil {
+		c.Logger().Errorf("[GiftCode] Failed to email gift code for donation %s: %v", donation.ID.String(), err)
+		return
+	}
+	c.Logger().Infof("[GiftCode] Gift code for donation %s emailed to %s", donation.ID.String(), to)
+}
+
+// expireGiftCodes marks active codes past their expiry date as expired. The
+// purchase stays with AVR as an undesignated gift.
+func expireGiftCodes(tx *pop.Connection) error {
+	err := tx.RawQuery("UPDATE gift_codes SET status = ?, updated_at = ? WHERE status = ? AND expires_at IS NOT NULL AND expires_at < ?",
+		models.GiftCodeStatusExpired, time.Now(), models.GiftCodeStatusActive, time.Now()).Exec()
+	return errors.WithStack(err)
+}
+
+// giftCodeUnavailable explains why a code can't be redeemed now, or returns
+// "" if it can
+func giftCodeUnavailable(gift *models.GiftCode, now time.Time) string {
+	if gift.IsExpired(now) {
+		return "This gift code has expired. The gift still supports AVR's general fund - thank you!"
+	}
+	switch gift.Status {
+	case models.GiftCodeStatusRedeemed:
+		return fmt.Sprintf("This gift code was already redeemed for %s.", stringOrEmpty(gift.Program))
+	case models.GiftCodeStatusPending:
+		return "This gift code isn't active yet. Please try again once the purchase has been processed."
+	case models.GiftCodeStatusActive:
+		return ""
+	}
+	return "This gift code can't be redeemed."
+}
+
+// redeemGiftCode redeems an active, unexpired code for program. The update
+// only applies while the code is still active, so two redemptions at once
+// can't both go through; it reports whether this one did.
+func redeemGiftCode(tx *pop.Connection, gift *models.GiftCode, program, name, email string, now time.Time) (bool, error) {
+	n, err := tx.RawQuery(`UPDATE gift_codes SET status = ?, redeemed_at = ?, program = ?, redeemed_by_name = ?, redeemed_by_email = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND (expires_at IS NULL OR expires_at >= ?)`,
+		models.GiftCodeStatusRedeemed, now, program, stringPointer(name), stringPointer(email), now,
+		gift.ID, models.GiftCodeStatusActive, now).ExecWithCount()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+	gift.Status = models.GiftCodeStatusRedeemed
+	gift.RedeemedAt = &now
+	gift.Program = &program
+	gift.RedeemedByName = stringPointer(name)
+	gift.RedeemedByEmail = stringPointer(email)
+	return true, nil
+}
+
+// GiftCardsHandler shows the form for buying a donation gift card
+func GiftCardsHandler(c buffalo.Context) error {
+	setupDonateFormContext(c)
+	c.Set("csrf", c.Value("authenticity_token"))
+	c.Set("title", "Donation Gift Cards")
+	c.Set("giftCard", true)
+	c.Set("expiryMonths", giftCodeExpiryMonths())
+	return c.Render(http.StatusOK, r.HTML("pages/gift_cards.plush.html"))
+}
+
+// GiftCardRedeemHandler shows (GET) and processes (POST) gift code redemption
+func GiftCardRedeemHandler(c buffalo.Context) error {
+	c.Set("title", "Redeem a Gift Card")
+	c.Set("programs", giftPrograms)
+	c.Set("code", models.NormalizeGiftCode(c.Param("code")))
+	c.Set("redeemed", nil)
+
+	if c.Request().Method == "GET" {
+		return c.Render(http.StatusOK, r.HTML("pages/gift_card_redeem.plush.html"))
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	code := models.NormalizeGiftCode(c.Param("code"))
+	program := c.Param("program")
+	name := strings.TrimSpace(c.Param("name"))
+	email := strings.TrimSpace(c.Param("email"))
+
+	fail := func(msg string) error {
+		c.Flash().Add("danger", msg)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("pages/gift_card_redeem.plush.html"))
+	}
+
+	if code == "" {
+		return fail("Please enter the code from your gift card, e.g. AVR-ABCD-2345.")
+	}
+	if !isGiftProgram(program) {
+		return fail("Please choose which program your gift should support.")
+	}
+
+	gift := &models.GiftCode{}
+	if err := tx.Where("code = ?", code).First(gift); err != nil {
+		logging.SecurityEvent(c, "gift_code_redeem", "failure", "unknown_code", logging.Fields{
+			"ip": getClientIP(c),
+		})
+		return fail("We couldn't find that gift code. Please check it and try again.")
+	}
+
+	now := time.Now()
+	if gift.IsExpired(now) && gift.Status != models.GiftCodeStatusExpired {
+		// The error page rolls back this request's transaction, so the
+		// sweep runs outside it
+		if err := expireGiftCodes(models.DB); err != nil {
+			logging.Error("gift_code_expire_failed", err, logging.Fields{"gift_code_id": gift.ID.String()})
+		}
+	}
+	if msg := giftCodeUnavailable(gift, now); msg != "" {
+		return fail(msg)
+	}
+
+	redeemed, err := redeemGiftCode(tx, gift, program, name, email, now)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !redeemed {
+		// Another request redeemed or expired the code since it was loaded
+		if err := tx.Reload(gift); err != nil {
+			return errors.WithStack(err)
+		}
+		return fail(giftCodeUnavailable(gift, now))
+	}
+
+	// The purchase donation now counts toward the chosen program
+	donation := &models.Donation{}
+	if err := tx.Find(donation, gift.DonationID); err == nil {
+		donation.Designation = &program
+		if err := tx.Update(donation); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	logging.Audit("gift_code_redeemed", logging.Fields{
+		"gift_code_id": gift.ID.String(),
+		"donation_id":  gift.DonationID.String(),
+		"amount":       gift.Amount,
+		"program":      program,
+	})
+
+	c.Set("redeemed", gift)
+	return c.Render(http.StatusOK, r.HTML("pages/gift_card_redeem.plush.html"))
+}
+
+// GiftCodeStats summarizes gift codes by status for the admin page
+type GiftCodeStats struct {
+	Outstanding      int
+	OutstandingValue float64
+	Redeemed         int
+	RedeemedValue    float64
+	Expired          int
+	ExpiredValue     float64
+}
+
+// AdminGiftCodesIndex lists gift codes with redemption tracking
+func AdminGiftCodesIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	if err := expireGiftCodes(tx); err != nil {
+		return err
+	}
+
+	codes := models.GiftCodes{}
+	if err := tx.Where("status != ?", models.GiftCodeStatusPending).Order("created_at desc").Limit(200).All(&codes); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var rows []struct {
+		Status string  `db:"status"`
+		Count  int     `db:"count"`
+		Value  float64 `db:"value"`
+	}
+	if err := tx.RawQuery("SELECT status, COUNT(*) as count, COALESCE(SUM(amount), 0) as value FROM gift_codes GROUP BY status").All(&rows); err != nil {
+		return errors.WithStack(err)
+	}
+	stats := GiftCodeStats{}
+	for _, row := range rows {
+		switch row.Status {
+		case models.GiftCodeStatusActive:
+			stats.Outstanding, stats.OutstandingValue = row.Count, row.Value
+		case models.GiftCodeStatusRedeemed:
+			stats.Redeemed, stats.RedeemedValue = row.Count, row.Value
+		case models.GiftCodeStatusExpired:
+			stats.Expired, stats.ExpiredValue = row.Count, row.Value
+		}
+	}
+
+	c.Set("codes", codes)
+	c.Set("stats", stats)
+	return c.Render(http.StatusOK, r.HTML("admin/gift_codes.plush.html"))
+}
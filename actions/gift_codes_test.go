@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_GiftRequestErrors(t *testing.T) {
+	r := require.New(t)
+
+	r.Empty(giftRequestErrors(DonationRequest{DonationType: "monthly"}))
+	r.Empty(giftRequestErrors(DonationRequest{GiftCard: "true", DonationType: "one-time", GiftRecipientEmail: "friend@example.com"}))
+
+	errs := giftRequestErrors(DonationRequest{GiftCard: "true", DonationType: "monthly", GiftRecipientEmail: "not-an-email"})
+	r.Contains(errs, "donation_type")
+	r.Contains(errs, "gift_recipient_email")
+}
+
+func Test_GiftCodeExpiryMonths(t *testing.T) {
+	r := require.New(t)
+
+	envy.Temp(func() {
+		envy.Set("GIFT_CODE_EXPIRY_MONTHS", "")
+		r.Equal(defaultGiftCodeExpiryMonths, giftCodeExpiryMonths())
+
+		envy.Set("GIFT_CODE_EXPIRY_MONTHS", "0")
+		r.Equal(0, giftCodeExpiryMonths())
+
+		envy.Set("GIFT_CODE_EXPIRY_MONTHS", "-3")
+		r.Equal(defaultGiftCodeExpiryMonths, giftCodeExpiryMonths())
+	})
+}
+
+func Test_IsGiftProgram(t *testing.T) {
+	r := require.New(t)
+	r.True(isGiftProgram("Housing Projects"))
+	r.False(isGiftProgram("housing projects"))
+	r.False(isGiftProgram(""))
+}
+
+func Test_GiftCodeUnavailable(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	program := "Skills Training"
+
+	r.Empty(giftCodeUnavailable(&models.GiftCode{Status: models.GiftCodeStatusActive}, now))
+	r.Contains(giftCodeUnavailable(&models.GiftCode{Status: models.GiftCodeStatusActive, ExpiresAt: &past}, now), "has expired")
+	r.Contains(giftCodeUnavailable(&models.GiftCode{Status: models.GiftCodeStatusRedeemed, Program: &program}, now), "already redeemed for Skills Training")
+	r.Contains(giftCodeUnavailable(&models.GiftCode{Status: models.GiftCodeStatusPending}, now), "isn't active yet")
+}
+
+func (as *ActionSuite) Test_RedeemGiftCode_OnlyOnce() {
+	gift := &models.GiftCode{
+		Code:           "AVR-TEST-2345",
+		Amount:         50,
+		Currency:       "USD",
+		DonationID:     uuid.Must(uuid.NewV4()),
+		PurchaserName:  "Pat Buyer",
+		PurchaserEmail: "pat@example.com",
+		Status:         models.GiftCodeStatusActive,
+	}
+	as.NoError(as.DB.Create(gift))
+
+	now := time.Now()
+	redeemed, err := redeemGiftCode(as.DB, gift, "Housing Projects", "Sam", "", now)
+	as.NoError(err)
+	as.True(redeemed)
+
+	// A second redemption loaded before the first committed loses the race
+	stale := &models.GiftCode{ID: gift.ID, Status: models.GiftCodeStatusActive}
+	redeemed, err = redeemGiftCode(as.DB, stale, "Skills Training", "Alex", "", now)
+	as.NoError(err)
+	as.False(redeemed)
+
+	as.NoError(as.DB.Reload(gift))
+	as.Equal(models.GiftCodeStatusRedeemed, gift.Status)
+	as.Equal("Housing Projects", gift.ProgramName())
+}
@@ -0,0 +1,303 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// HelcimSyncResult counts what a Helcim customer sync did
+type HelcimSyncResult struct {
+	Checked int
+	// Updated is how many donors took an address from Helcim because they
+	// had none of their own
+	Updated int
+	// Discrepancies is how many new differences were held for review
+	Discrepancies int
+	Failed        int
+}
+
+// helcimCustomerAddress is the billing address Helcim holds for a customer
+func helcimCustomerAddress(customer *services.HelcimCustomer) models.DonorAddress {
+	return models.DonorAddress{
+		Line1: customer.BillingAddress.Street1,
+		Line2: customer.BillingAddress.Street2,
+		City:  customer.BillingAddress.City,
+		State: customer.BillingAddress.Province,
+		Zip:   customer.BillingAddress.PostalCode,
+	}
+}
+
+// helcimCardProblem describes what's wrong with the card Helcim would
+// charge a monthly gift to, or "" when it's fine
+func helcimCardProblem(customer *services.HelcimCustomer, now time.Time) string {
+	card := customer.DefaultCard()
+	if card == nil {
+		if len(customer.Cards) == 0 {
+			return "No card saved"
+		}
+		return "No default card"
+	}
+	if card.Expired(now) {
+		return fmt.Sprintf("Default card %s expired %s", cardLastFour(card.CardF6L4), card.CardExpiry)
+	}
+	return ""
+}
+
+// cardLastFour is the "ending 4242" form of a card's first six and last
+// four digits
+func cardLastFour(f6l4 string) string {
+	if len(f6l4) < 4 {
+		return "card"
+	}
+	return "ending " + f6l4[len(f6l4)-4:]
+}
+
+// donorHasActiveMonthlyGift reports whether the donor has a monthly gift
+// Helcim is still charging
+func donorHasActiveMonthlyGift(tx *pop.Connection, donor *models.Donor) (bool, error) {
+	exists, err := tx.Where("donor_id = ? AND status = ? AND subscription_id IS NOT NULL AND subscription_id <> ''", donor.ID, "active").Exists(&models.Donation{})
+	return exists, errors.WithStack(err)
+}
+
+// SyncHelcimCustomers checks every donor with a Helcim customer code against
+// their Helcim customer. A donor with no address of their own takes
+// Helcim's; otherwise differing addresses, missing customers and missing or
+// expired cards behind an active monthly gift are held for staff to review on
+// the donor discrepancies page. It's run nightly through the
+// donors:sync_helcim task.
+func SyncHelcimCustomers(ctx context.Context, tx *pop.Connection, client services.HelcimAPI, now time.Time) (HelcimSyncResult, error) {
+	result := HelcimSyncResult{}
+	donors := models.Donors{}
+	if err := tx.Where("customer_code IS NOT NULL").Order("created_at").All(&donors); err != nil {
+		return result, errors.WithStack(err)
+	}
+
+	for i := range donors {
+		donor := &donors[i]
+		customer, err := client.GetCustomer(ctx, donor.CustomerCodeText())
+		if err != nil {
+			// Left for tomorrow's run rather than recorded, since Helcim
+			// being unreachable says nothing about the donor
+			logging.Error("helcim_customer_sync_failed", err, logging.Fields{
+				"donor_id":      donor.ID.String(),
+				"customer_code": donor.CustomerCodeText(),
+			})
+			result.Failed++
+			continue
+		}
+		result.Checked++
+
+		updated, found, err := reconcileHelcimCustomer(tx, donor, customer, now)
+		if err != nil {
+			return result, err
+		}
+		if updated {
+			result.Updated++
+		}
+		result.Discrepancies += found
+
+		donor.HelcimSyncedAt = &now
+		if err := tx.UpdateColumns(donor, "helcim_synced_at"); err != nil {
+			return result, errors.WithStack(err)
+		}
+	}
+
+	if result.Discrepancies > 0 {
+		publishAdminActivity(activityReview, fmt.Sprintf("%d Helcim discrepancies", result.Discrepancies), "Donor records that don't match Helcim", "/admin/donors/discrepancies")
+	}
+	return result, nil
+}
+
+// reconcileHelcimCustomer compares one donor with their Helcim customer,
+// which is nil when Helcim has none. It reports whether the donor's address
+// was filled in from Helcim and how many new discrepancies were found.
+func reconcileHelcimCustomer(tx *pop.Connection, donor *models.Donor, customer *services.HelcimCustomer, now time.Time) (bool, int, error) {
+	found := 0
+	record := func(kind, local, helcim string) error {
+		isNew, err := models.RecordDonorDiscrepancy(tx, donor.ID, kind, local, helcim)
+		if isNew {
+			found++
+			logging.Warn("helcim_customer_discrepancy", logging.Fields{
+				"donor_id":      donor.ID.String(),
+				"customer_code": donor.CustomerCodeText(),
+				"kind":          kind,
+			})
+		}
+		return err
+	}
+
+	if customer == nil {
+		err := record(models.DiscrepancyMissingCustomer, donor.CustomerCodeText(), "")
+		return false, found, err
+	}
+	if err := models.ClearDonorDiscrepancy(tx, donor.ID, models.DiscrepancyMissingCustomer, now); err != nil {
+		return false, found, err
+	}
+
+	updated := false
+	local, helcim := donor.Address(), helcimCustomerAddress(customer)
+	switch {
+	case helcim.IsZero() || local.Matches(helcim):
+		if err := models.ClearDonorDiscrepancy(tx, donor.ID, models.DiscrepancyAddress, now); err != nil {
+			return false, found, err
+		}
+	case local.IsZero():
+		donor.SetAddress(helcim)
+		if err := tx.UpdateColumns(donor, "address_line1", "address_line2", "city", "state", "zip", "updated_at"); err != nil {
+			return false, found, errors.WithStack(err)
+		}
+		updated = true
+	default:
+		if err := record(models.DiscrepancyAddress, local.String(), helcim.String()); err != nil {
+			return updated, found, err
+		}
+	}
+
+	problem := ""
+	monthly, err := donorHasActiveMonthlyGift(tx, donor)
+	if err != nil {
+		return updated, found, err
+	}
+	if monthly {
+		problem = helcimCardProblem(customer, now)
+	}
+	if problem == "" {
+		err = models.ClearDonorDiscrepancy(tx, donor.ID, models.DiscrepancyCard, now)
+	} else {
+		err = record(models.DiscrepancyCard, "Active monthly gift", problem)
+	}
+	return updated, found, err
+}
+
+// donorDiscrepancyRow is an open discrepancy with the donor it's about
+type donorDiscrepancyRow struct {
+	Discrepancy models.DonorDiscrepancy
+	Donor       models.Donor
+}
+
+// AdminDonorDiscrepancies lists the open differences between donor records
+// and Helcim for staff to settle
+func AdminDonorDiscrepancies(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	discrepancies := models.DonorDiscrepancies{}
+	if err := tx.Where("resolved_at IS NULL").Order("created_at desc").All(&discrepancies); err != nil {
+		return errors.WithStack(err)
+	}
+	ids := make([]uuid.UUID, len(discrepancies))
+	for i, d := range discrepancies {
+		ids[i] = d.DonorID
+	}
+	donors, err := models.DonorsByID(tx, ids)
+	if err != nil {
+		return err
+	}
+	rows := make([]donorDiscrepancyRow, 0, len(discrepancies))
+	for _, d := range discrepancies {
+		row := donorDiscrepancyRow{Discrepancy: d}
+		if donor := donors[d.DonorID]; donor != nil {
+			row.Donor = *donor
+		}
+		rows = append(rows, row)
+	}
+
+	c.Set("rows", rows)
+	c.Set("addressKind", models.DiscrepancyAddress)
+	return c.Render(http.StatusOK, r.HTML("admin/donors/discrepancies.plush.html"))
+}
+
+// AdminDonorDiscrepancyResolve settles a discrepancy. An address difference
+// can be settled by keeping Helcim's address or sending ours to Helcim; any
+// discrepancy can be dismissed once staff have dealt with it.
+func AdminDonorDiscrepancyResolve(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	discrepancy := &models.DonorDiscrepancy{}
+	if err := tx.Find(discrepancy, c.Param("discrepancy_id")); err != nil || discrepancy.ResolvedAt != nil {
+		c.Flash().Add("danger", "That discrepancy has already been settled.")
+		return c.Redirect(http.StatusSeeOther, "/admin/donors/discrepancies")
+	}
+	donor := &models.Donor{}
+	if err := tx.Find(donor, discrepancy.DonorID); err != nil {
+		return errors.WithStack(err)
+	}
+
+	resolution := c.Param("resolution")
+	switch resolution {
+	case models.ResolutionDismissed:
+	case models.ResolutionKeptHelcim, models.ResolutionSentLocal:
+		if discrepancy.Kind != models.DiscrepancyAddress {
+			c.Flash().Add("danger", "Only address differences can be copied between Helcim and our records.")
+			return c.Redirect(http.StatusSeeOther, "/admin/donors/discrepancies")
+		}
+		if err := settleAddressDiscrepancy(c, tx, services.NewHelcimClient(), donor, resolution); err != nil {
+			logging.Error("helcim_discrepancy_resolve_failed", err, logging.Fields{
+				"donor_id":   donor.ID.String(),
+				"resolution": resolution,
+			})
+			c.Flash().Add("danger", "Helcim couldn't be updated. Please try again.")
+			return c.Redirect(http.StatusSeeOther, "/admin/donors/discrepancies")
+		}
+	default:
+		c.Flash().Add("danger", "Invalid resolution")
+		return c.Redirect(http.StatusSeeOther, "/admin/donors/discrepancies")
+	}
+
+	discrepancy.Resolve(resolution, &currentUser.ID, time.Now())
+	if err := tx.Update(discrepancy); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.UserAction(c, currentUser.Email, "helcim_discrepancy_resolved", "Settled Helcim discrepancy", logging.Fields{
+		"donor_id":   donor.ID.String(),
+		"kind":       discrepancy.Kind,
+		"resolution": resolution,
+	})
+	c.Flash().Add("success", fmt.Sprintf("Settled the %s for %s.", discrepancy.Label(), donor.DisplayName()))
+	return c.Redirect(http.StatusSeeOther, "/admin/donors/discrepancies")
+}
+
+// settleAddressDiscrepancy copies the address one way or the other between
+// the donor and their Helcim customer
+func settleAddressDiscrepancy(ctx context.Context, tx *pop.Connection, client services.HelcimAPI, donor *models.Donor, resolution string) error {
+	customer, err := client.GetCustomer(ctx, donor.CustomerCodeText())
+	if err != nil {
+		return err
+	}
+	if customer == nil {
+		return fmt.Errorf("helcim has no customer %s", donor.CustomerCodeText())
+	}
+
+	if resolution == models.ResolutionKeptHelcim {
+		donor.SetAddress(helcimCustomerAddress(customer))
+		return errors.WithStack(tx.UpdateColumns(donor, "address_line1", "address_line2", "city", "state", "zip", "updated_at"))
+	}
+
+	address := donor.Address()
+	billing := customer.BillingAddress
+	billing.Street1 = address.Line1
+	billing.Street2 = address.Line2
+	billing.City = address.City
+	billing.Province = address.State
+	billing.PostalCode = address.Zip
+	_, err = client.UpdateCustomer(ctx, customer.ID, services.CustomerRequest{
+		ContactName:    customer.ContactName,
+		Email:          donor.Email,
+		CellPhone:      customer.CellPhone,
+		CustomerCode:   customer.CustomerCode,
+		BillingAddress: billing,
+	})
+	return err
+}
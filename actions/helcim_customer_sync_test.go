@@ -0,0 +1,126 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+)
+
+// fakeHelcimCustomers answers customer lookups from a map. Any other Helcim
+// call panics.
+type fakeHelcimCustomers struct {
+	services.HelcimAPI
+	customers map[string]*services.HelcimCustomer
+}
+
+func (f fakeHelcimCustomers) GetCustomer(ctx context.Context, customerCode string) (*services.HelcimCustomer, error) {
+	return f.customers[customerCode], nil
+}
+
+func Test_HelcimCardProblem(t *testing.T) {
+	req := require.New(t)
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	req.Equal("No card saved", helcimCardProblem(&services.HelcimCustomer{}, now))
+	req.Equal("No default card", helcimCardProblem(&services.HelcimCustomer{
+		Cards: []services.HelcimCard{{CardF6L4: "4242424242", CardExpiry: "1228"}},
+	}, now))
+	req.Equal("Default card ending 4242 expired 0926", helcimCardProblem(&services.HelcimCustomer{
+		Cards: []services.HelcimCard{{CardF6L4: "4242424242", CardExpiry: "0926", Default: true}},
+	}, now))
+	req.Empty(helcimCardProblem(&services.HelcimCustomer{
+		Cards: []services.HelcimCard{{CardF6L4: "4242424242", CardExpiry: "1026", Default: true}},
+	}, now))
+}
+
+func Test_DonorDiscrepanciesTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/discrepancies-test", func(c buffalo.Context) error {
+		c.Set("rows", []donorDiscrepancyRow{
+			{
+				Discrepancy: models.DonorDiscrepancy{ID: uuid.Must(uuid.NewV4()), Kind: models.DiscrepancyAddress, LocalValue: "1 Main St, Austin, TX 78701", HelcimValue: "9 Oak Ave, Austin, TX 78702"},
+				Donor:       models.Donor{Email: "sam@example.com", Name: "Sam Donor"},
+			},
+			{
+				Discrepancy: models.DonorDiscrepancy{ID: uuid.Must(uuid.NewV4()), Kind: models.DiscrepancyCard, LocalValue: "Active monthly gift", HelcimValue: "No card saved"},
+				Donor:       models.Donor{Email: "lee@example.com"},
+			},
+		})
+		c.Set("addressKind", models.DiscrepancyAddress)
+		return c.Render(http.StatusOK, r.HTML("admin/donors/discrepancies.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/discrepancies-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	body := w.Body.String()
+	req.Equal(http.StatusOK, w.Code, body)
+	req.Contains(body, `<a href="/admin/donors/sam@example.com">Sam Donor</a>`)
+	req.Contains(body, "9 Oak Ave, Austin, TX 78702")
+	req.Contains(body, "Card problem")
+	// Only the address difference can be copied either way
+	req.Equal(1, strings.Count(body, `value="sent_local"`))
+	req.Equal(2, strings.Count(body, `value="dismissed"`))
+}
+
+func (as *ActionSuite) Test_SyncHelcimCustomers() {
+	now := time.Date(2026, 10, 14, 2, 0, 0, 0, time.UTC)
+	moved := &models.Donor{Email: "sam@example.com", CustomerCode: stringPointer("CST1")}
+	moved.SetAddress(models.DonorAddress{Line1: "1 Main St", City: "Austin", State: "TX", Zip: "78701"})
+	as.NoError(as.DB.Create(moved))
+	blank := &models.Donor{Email: "lee@example.com", CustomerCode: stringPointer("CST2")}
+	as.NoError(as.DB.Create(blank))
+	gone := &models.Donor{Email: "pat@example.com", CustomerCode: stringPointer("CST3")}
+	as.NoError(as.DB.Create(gone))
+	as.NoError(as.DB.Create(&models.Donor{Email: "kim@example.com"}))
+
+	monthly := &models.Donation{DonorEmail: "sam@example.com", DonorName: "Sam", Amount: 25, Currency: "USD", Status: "active", DonationType: models.DonationTypeMonthly, SubscriptionID: stringPointer("sub_1"), CustomerID: stringPointer("CST1")}
+	as.NoError(as.DB.Create(monthly))
+
+	client := fakeHelcimCustomers{customers: map[string]*services.HelcimCustomer{
+		"CST1": {ID: 1, CustomerCode: "CST1", BillingAddress: services.BillingAddress{Street1: "9 Oak Ave", City: "Austin", Province: "TX", PostalCode: "78702"}},
+		"CST2": {ID: 2, CustomerCode: "CST2", BillingAddress: services.BillingAddress{Street1: "5 Elm St", City: "Waco", Province: "TX", PostalCode: "76701"}},
+	}}
+
+	result, err := SyncHelcimCustomers(context.Background(), as.DB, client, now)
+	as.NoError(err)
+	as.Equal(HelcimSyncResult{Checked: 3, Updated: 1, Discrepancies: 3}, result)
+
+	as.NoError(as.DB.Reload(blank))
+	as.Equal("5 Elm St, Waco, TX 76701", blank.Address().String())
+	as.NotNil(blank.HelcimSyncedAt)
+
+	open := models.DonorDiscrepancies{}
+	as.NoError(as.DB.Where("resolved_at IS NULL").Order("kind").All(&open))
+	as.Len(open, 3)
+	as.Equal(models.DiscrepancyAddress, open[0].Kind)
+	as.Equal("9 Oak Ave, Austin, TX 78702", open[0].HelcimValue)
+	as.Equal(models.DiscrepancyCard, open[1].Kind)
+	as.Equal(moved.ID, open[1].DonorID)
+	as.Equal(models.DiscrepancyMissingCustomer, open[2].Kind)
+	as.Equal(gone.ID, open[2].DonorID)
+
+	// Running again finds nothing new; once Helcim matches, the address
+	// difference clears itself
+	client.customers["CST1"].BillingAddress.Street1 = "1 Main St"
+	client.customers["CST1"].BillingAddress.PostalCode = "78701"
+	result, err = SyncHelcimCustomers(context.Background(), as.DB, client, now)
+	as.NoError(err)
+	as.Equal(0, result.Discrepancies)
+	count, err := as.DB.Where("resolved_at IS NULL").Count(&models.DonorDiscrepancy{})
+	as.NoError(err)
+	as.Equal(2, count)
+}
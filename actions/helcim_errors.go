@@ -0,0 +1,36 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+
+	"avrnpo.org/services"
+)
+
+// helcimFailure is the status, error code and message to give a donor whose
+// payment Helcim didn't take. Declines and rejected details are theirs to
+// fix; rate limits and outages, including Helcim being skipped while it's
+// down, are worth trying again in a few minutes; anything else, such as our
+// API token being refused, is ours.
+func helcimFailure(err error) (int, errorCode, string) {
+	helcimErr, ok := services.AsHelcimError(err)
+	switch {
+	case ok && helcimErr.Code == services.HelcimDeclined:
+		return http.StatusPaymentRequired, codePaymentDeclined, "Your payment was declined. Please check your card details or try a different card."
+	case ok && helcimErr.Code == services.HelcimInvalidRequest:
+		return http.StatusUnprocessableEntity, codePaymentInvalid, "We couldn't process those payment details. Please check them and try again."
+	case ok && helcimErr.Code == services.HelcimCircuitOpen:
+		return http.StatusServiceUnavailable, codePaymentUnavailable, "Our payment system is temporarily unavailable. Please try again in a few minutes."
+	case services.IsHelcimRetryable(err):
+		return http.StatusServiceUnavailable, codePaymentUnavailable, "Our payment processor is busy right now. Please wait a few minutes and try again."
+	}
+	return http.StatusBadGateway, codePaymentFailed, "We couldn't process your payment right now. Please try again later or contact us."
+}
+
+// helcimFailureJSON renders the JSON error for a payment Helcim didn't take,
+// which the payment page shows the donor
+func helcimFailureJSON(c buffalo.Context, err error) error {
+	status, code, message := helcimFailure(err)
+	return jsonError(c, status, code, message)
+}
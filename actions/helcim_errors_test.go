@@ -0,0 +1,44 @@
+package actions
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/services"
+)
+
+func Test_HelcimFailure(t *testing.T) {
+	req := require.New(t)
+
+	status, code, message := helcimFailure(&services.HelcimError{StatusCode: 400, Code: services.HelcimDeclined, Message: "Transaction Declined"})
+	req.Equal(http.StatusPaymentRequired, status)
+	req.Equal(codePaymentDeclined, code)
+	req.Contains(message, "declined")
+
+	status, code, _ = helcimFailure(&services.HelcimError{StatusCode: 400, Code: services.HelcimInvalidRequest})
+	req.Equal(http.StatusUnprocessableEntity, status)
+	req.Equal(codePaymentInvalid, code)
+
+	status, code, message = helcimFailure(&services.HelcimError{StatusCode: 429, Code: services.HelcimRateLimited, Retryable: true})
+	req.Equal(http.StatusServiceUnavailable, status)
+	req.Equal(codePaymentUnavailable, code)
+	req.Contains(message, "try again")
+
+	status, code, message = helcimFailure(&services.HelcimError{StatusCode: 503, Code: services.HelcimCircuitOpen, Retryable: true})
+	req.Equal(http.StatusServiceUnavailable, status)
+	req.Equal(codePaymentUnavailable, code)
+	req.Contains(message, "temporarily unavailable")
+
+	// Our own credentials being refused isn't the donor's to fix, and the
+	// details stay out of the message
+	status, code, message = helcimFailure(&services.HelcimError{StatusCode: 401, Code: services.HelcimAuthFailed, Message: "Invalid api-token"})
+	req.Equal(http.StatusBadGateway, status)
+	req.Equal(codePaymentFailed, code)
+	req.NotContains(message, "api-token")
+
+	status, _, _ = helcimFailure(errors.New("failed to decode response"))
+	req.Equal(http.StatusBadGateway, status)
+}
@@ -4,6 +4,7 @@ import (
 	"avrnpo.org/models"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/pop/v6"
@@ -42,6 +43,15 @@ func HomeHandler(c buffalo.Context) error {
 	}
 	c.Set("recentPosts", posts)
 
+	// Impact counters show whatever stats staff have published
+	impactStats := []publicStatValue{}
+	if payload, err := loadPublicStats(tx, time.Now()); err != nil {
+		c.Logger().Errorf("Error loading public stats for homepage: %v", err)
+	} else {
+		impactStats = payload.Stats
+	}
+	c.Set("impactStats", impactStats)
+
 	// Render the home page (using application layout for consistency)
 	c.Logger().Info("Rendering home page")
 	return c.Render(http.StatusOK, r.HTML("home/index.plush.html"))
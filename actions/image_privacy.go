@@ -0,0 +1,111 @@
+package actions
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"strings"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/imagescrub"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// cleanImageUpload reads an uploaded file the way it should be stored.
+// Photos lose their metadata, GPS position included, since many are taken
+// at veterans' homes, and any areas in blur are pixelated. Other files are
+// returned as uploaded.
+func cleanImageUpload(header *multipart.FileHeader, contentType string, blur []imagescrub.Region) ([]byte, error) {
+	f, err := header.Open()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if !strings.HasPrefix(contentType, "image/") {
+		return data, nil
+	}
+	if len(blur) > 0 {
+		return imagescrub.Blur(data, contentType, blur)
+	}
+	return imagescrub.Strip(data, contentType)
+}
+
+// scrubStoredImage strips metadata from an image already in storage,
+// returning its new size and whether it changed
+func scrubStoredImage(storage services.Storage, key, contentType string) (int64, bool, error) {
+	f, err := storage.Open(key)
+	if err != nil {
+		return 0, false, err
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return 0, false, err
+	}
+	cleaned, err := imagescrub.Strip(data, contentType)
+	if err != nil {
+		return 0, false, err
+	}
+	if bytes.Equal(cleaned, data) {
+		return int64(len(data)), false, nil
+	}
+	if err := storage.Save(key, bytes.NewReader(cleaned)); err != nil {
+		return 0, false, err
+	}
+	return int64(len(cleaned)), true, nil
+}
+
+// ScrubStoredImages strips metadata from media library images and vehicle
+// photos uploaded before it was done on upload, returning how many files
+// changed. Files that can't be read are logged and skipped, and running it
+// again leaves cleaned files alone.
+func ScrubStoredImages(db *pop.Connection) (int, error) {
+	storage := services.NewStorage()
+	scrubbed := 0
+
+	assets := models.MediaAssets{}
+	if err := db.Where("content_type LIKE ?", "image/%").All(&assets); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	for i := range assets {
+		asset := &assets[i]
+		size, changed, err := scrubStoredImage(storage, asset.StorageKey, asset.ContentType)
+		if err != nil {
+			logging.Warn("image_scrub_failed", logging.Fields{"asset_id": asset.ID.String(), "error": err.Error()})
+			continue
+		}
+		if !changed {
+			continue
+		}
+		asset.Size = size
+		if err := db.UpdateColumns(asset, "size", "updated_at"); err != nil {
+			return scrubbed, errors.WithStack(err)
+		}
+		scrubbed++
+	}
+
+	photos := models.VehicleDonationPhotos{}
+	if err := db.All(&photos); err != nil {
+		return scrubbed, errors.WithStack(err)
+	}
+	for _, photo := range photos {
+		_, changed, err := scrubStoredImage(storage, photo.StorageKey, photo.ContentType)
+		if err != nil {
+			logging.Warn("image_scrub_failed", logging.Fields{"vehicle_photo_id": photo.ID.String(), "error": err.Error()})
+			continue
+		}
+		if changed {
+			scrubbed++
+		}
+	}
+	return scrubbed, nil
+}
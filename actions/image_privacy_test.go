@@ -0,0 +1,59 @@
+package actions
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/pkg/imagescrub"
+)
+
+// uploadedFile is data as it arrives in a multipart form field
+func uploadedFile(t *testing.T, name string, data []byte) *multipart.FileHeader {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("File", name)
+	require.NoError(t, err)
+	part.Write(data)
+	require.NoError(t, form.Close())
+
+	req, _ := http.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	require.NoError(t, req.ParseMultipartForm(1<<20))
+	return req.MultipartForm.File["File"][0]
+}
+
+func Test_CleanImageUpload(t *testing.T) {
+	req := require.New(t)
+
+	var encoded bytes.Buffer
+	req.NoError(jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 30, 30)), nil))
+	comment := append([]byte{0xFF, 0xFE, 0x00, 0x10}, "123 Oak Street"...)
+	photo := append([]byte{0xFF, 0xD8}, comment...)
+	photo = append(photo, encoded.Bytes()[2:]...)
+	req.Contains(string(photo), "Oak Street")
+
+	data, err := cleanImageUpload(uploadedFile(t, "porch.jpg", photo), "image/jpeg", nil)
+	req.NoError(err)
+	req.NotContains(string(data), "Oak Street")
+	_, err = jpeg.Decode(bytes.NewReader(data))
+	req.NoError(err)
+
+	data, err = cleanImageUpload(uploadedFile(t, "porch.jpg", photo), "image/jpeg", []imagescrub.Region{{X: 0, Y: 0, W: 0.5, H: 0.5}})
+	req.NoError(err)
+	req.NotContains(string(data), "Oak Street")
+
+	// Documents are stored as uploaded
+	pdf := []byte("%PDF-1.7 fact sheet")
+	data, err = cleanImageUpload(uploadedFile(t, "facts.pdf", pdf), "application/pdf", nil)
+	req.NoError(err)
+	req.Equal(pdf, data)
+
+	_, err = cleanImageUpload(uploadedFile(t, "broken.jpg", []byte("not really a jpeg")), "image/jpeg", nil)
+	req.Error(err)
+}
@@ -0,0 +1,274 @@
+package actions
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// dateInputLayout is the format of date form inputs
+const dateInputLayout = "2006-01-02"
+
+// inKindYear is the calendar year requested for the in-kind log, defaulting
+// to the current year
+func inKindYear(c buffalo.Context) int {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || year < 2000 || year > 9999 {
+		return time.Now().Year()
+	}
+	return year
+}
+
+// inKindGiftsForYear loads the in-kind gifts received during a calendar year
+func inKindGiftsForYear(tx *pop.Connection, year int) (models.InKindGifts, error) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	gifts := models.InKindGifts{}
+	err := tx.Where("received_at >= ? AND received_at < ?", start, start.AddDate(1, 0, 0)).
+		Order("received_at asc").All(&gifts)
+	return gifts, errors.WithStack(err)
+}
+
+// inKindAcknowledgement builds the acknowledgment letter for a gift
+func inKindAcknowledgement(gift *models.InKindGift) services.InKindAcknowledgementData {
+	return services.InKindAcknowledgementData{
+		DonorName:        gift.DonorName,
+		Description:      gift.Description,
+		Quantity:         gift.Quantity,
+		Category:         gift.CategoryLabel(),
+		ReceivedDate:     gift.ReceivedAt,
+		OrganizationName: organization().Name,
+		OrganizationEIN:  organization().EIN,
+	}
+}
+
+// bindInKindGift copies the admin in-kind gift form onto gift.
+func bindInKindGift(c buffalo.Context, gift *models.InKindGift) {
+	gift.DonorName = strings.TrimSpace(c.Param("DonorName"))
+	gift.DonorEmail = stringPointer(strings.ToLower(strings.TrimSpace(c.Param("DonorEmail"))))
+	gift.Category = c.Param("Category")
+	gift.Description = strings.TrimSpace(c.Param("Description"))
+	gift.Quantity, _ = strconv.Atoi(strings.TrimSpace(c.Param("Quantity")))
+	gift.EstimatedValue, _ = strconv.ParseFloat(strings.TrimSpace(c.Param("EstimatedValue")), 64)
+	gift.ReceivedAt, _ = time.ParseInLocation(dateInputLayout, c.Param("ReceivedAt"), time.Local)
+	gift.Notes = stringPointer(strings.TrimSpace(c.Param("Notes")))
+}
+
+// setInKindFormContext exposes a gift to the admin in-kind form. Plush can't
+// print the optional fields or format a zero date directly.
+func setInKindFormContext(c buffalo.Context, gift *models.InKindGift) {
+	receivedAt := ""
+	if !gift.ReceivedAt.IsZero() {
+		receivedAt = gift.ReceivedAt.Format(dateInputLayout)
+	}
+	c.Set("gift", gift)
+	c.Set("giftDonorEmail", gift.DonorEmailText())
+	c.Set("giftNotes", gift.NotesText())
+	c.Set("giftReceivedAt", receivedAt)
+	c.Set("inKindCategories", models.InKindCategories)
+	c.Set("categoryLabel", models.InKindCategoryLabel)
+}
+
+// AdminInKindIndex lists the in-kind gifts received in a year with their
+// estimated value by category
+func AdminInKindIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	year := inKindYear(c)
+	gifts, err := inKindGiftsForYear(tx, year)
+	if err != nil {
+		return err
+	}
+
+	totals := map[string]float64{}
+	var emails []string
+	for _, gift := range gifts {
+		totals[gift.Category] += gift.EstimatedValue
+		if email := gift.DonorEmailText(); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	flags, err := loadDonorFlagIndex(tx, emails)
+	if err != nil {
+		return err
+	}
+
+	c.Set("gifts", gifts)
+	c.Set("flags", flags)
+	c.Set("year", year)
+	c.Set("totals", totals)
+	c.Set("totalValue", gifts.TotalEstimatedValue())
+	c.Set("inKindCategories", models.InKindCategories)
+	c.Set("categoryLabel", models.InKindCategoryLabel)
+	return c.Render(http.StatusOK, r.HTML("admin/in_kind/index.plush.html"))
+}
+
+// AdminInKindNew shows the form for logging an in-kind gift
+func AdminInKindNew(c buffalo.Context) error {
+	setInKindFormContext(c, &models.InKindGift{Category: models.InKindTools, Quantity: 1, ReceivedAt: time.Now()})
+	return c.Render(http.StatusOK, r.HTML("admin/in_kind/new.plush.html"))
+}
+
+// AdminInKindCreate logs an in-kind gift, crediting it to the donor's
+// account when their email matches one
+func AdminInKindCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	gift := &models.InKindGift{RecordedBy: &currentUser.ID}
+	bindInKindGift(c, gift)
+	if gift.DonorEmail != nil {
+		user := &models.User{}
+		if err := tx.Where("LOWER(email) = ?", *gift.DonorEmail).First(user); err == nil {
+			gift.UserID = &user.ID
+		}
+	}
+
+	verrs, err := tx.ValidateAndCreate(gift)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		setInKindFormContext(c, gift)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/in_kind/new.plush.html"))
+	}
+
+	logging.UserAction(c, currentUser.ID.String(), "in_kind_gift_logged", fmt.Sprintf("Logged in-kind gift from %s", gift.DonorName), logging.Fields{
+		"gift_id":         gift.ID.String(),
+		"category":        gift.Category,
+		"estimated_value": gift.EstimatedValue,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Gift from %s logged.", gift.DonorName))
+	return c.Redirect(http.StatusSeeOther, "/admin/in-kind/%s", gift.ID)
+}
+
+// AdminInKindShow shows an in-kind gift with its acknowledgment status
+func AdminInKindShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	gift := &models.InKindGift{}
+	if err := tx.Find(gift, c.Param("gift_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	c.Set("gift", gift)
+	return c.Render(http.StatusOK, r.HTML("admin/in_kind/show.plush.html"))
+}
+
+// AdminInKindLetter renders the acknowledgment letter on its own for printing
+func AdminInKindLetter(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	gift := &models.InKindGift{}
+	if err := tx.Find(gift, c.Param("gift_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	letter, err := services.NewEmailService().GenerateInKindAcknowledgementHTML(inKindAcknowledgement(gift))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return c.Render(http.StatusOK, r.Func("text/html", func(w io.Writer, d render.Data) error {
+		_, err := io.WriteString(w, letter)
+		return err
+	}))
+}
+
+// AdminInKindAcknowledge emails the donor their acknowledgment letter and
+// records when it went out
+func AdminInKindAcknowledge(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	gift := &models.InKindGift{}
+	if err := tx.Find(gift, c.Param("gift_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if gift.DonorEmail == nil {
+		c.Flash().Add("warning", "There's no email address for this donor. Print the letter and mail it instead.")
+		return c.Redirect(http.StatusSeeOther, "/admin/in-kind/%s", gift.ID)
+	}
+
+	if err := services.NewEmailService().SendInKindAcknowledgement(*gift.DonorEmail, inKindAcknowledgement(gift)); err != nil {
+		c.Logger().Errorf("[InKind] Failed to send acknowledgment for gift %s: %v", gift.ID.String(), err)
+		c.Flash().Add("error", "The acknowledgment letter couldn't be sent. Please try again later.")
+		return c.Redirect(http.StatusSeeOther, "/admin/in-kind/%s", gift.ID)
+	}
+
+	now := time.Now()
+	gift.AcknowledgedAt = &now
+	if err := tx.Update(gift); err != nil {
+		return errors.WithStack(err)
+	}
+	recordCommunication(tx, *gift.DonorEmail, models.CommunicationAcknowledgment, fmt.Sprintf("Acknowledgment letter for %s", gift.Description), nil, &gift.ID)
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "in_kind_gift_acknowledged", fmt.Sprintf("Sent in-kind acknowledgment to %s", gift.DonorName), logging.Fields{
+		"gift_id": gift.ID.String(),
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Acknowledgment letter emailed to %s.", *gift.DonorEmail))
+	return c.Redirect(http.StatusSeeOther, "/admin/in-kind/%s", gift.ID)
+}
+
+// AdminInKindExport downloads a year's in-kind gifts as CSV, the non-cash
+// contributions to list on donors' year-end statements
+func AdminInKindExport(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	year := inKindYear(c)
+	gifts, err := inKindGiftsForYear(tx, year)
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("in-kind-gifts-%d.csv", year)
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	return c.Render(http.StatusOK, r.Func("text/csv", func(w io.Writer, d render.Data) error {
+		return writeInKindCSV(w, gifts)
+	}))
+}
+
+// writeInKindCSV writes in-kind gifts as CSV, one row per gift.
+func writeInKindCSV(w io.Writer, gifts models.InKindGifts) error {
+	out := csv.NewWriter(w)
+	header := []string{"Received", "Donor", "Email", "Category", "Quantity", "Description", "Estimated Value", "Acknowledged"}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, gift := range gifts {
+		acknowledged := ""
+		if gift.AcknowledgedAt != nil {
+			acknowledged = gift.AcknowledgedAt.Format(dateInputLayout)
+		}
+		row := []string{
+			gift.ReceivedAt.Format(dateInputLayout),
+			csvCell(gift.DonorName),
+			csvCell(gift.DonorEmailText()),
+			gift.CategoryLabel(),
+			strconv.Itoa(gift.Quantity),
+			csvCell(gift.Description),
+			fmt.Sprintf("%.2f", gift.EstimatedValue),
+			acknowledged,
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
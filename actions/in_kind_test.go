@@ -0,0 +1,80 @@
+package actions
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_WriteInKindCSV(t *testing.T) {
+	r := require.New(t)
+
+	email := "ops@hardware.example"
+	acknowledged := time.Date(2026, 10, 3, 9, 0, 0, 0, time.Local)
+	gifts := models.InKindGifts{
+		{
+			DonorName:      "Hardware Co.",
+			DonorEmail:     &email,
+			Category:       models.InKindTools,
+			Description:    "Cordless drill kits, new in box",
+			Quantity:       4,
+			EstimatedValue: 596,
+			ReceivedAt:     time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local),
+			AcknowledgedAt: &acknowledged,
+		},
+		{
+			DonorName:   "Jane Builder",
+			Category:    models.InKindBuildingMaterials,
+			Description: "Lumber",
+			Quantity:    1,
+			ReceivedAt:  time.Date(2026, 10, 5, 0, 0, 0, 0, time.Local),
+		},
+	}
+
+	var buf bytes.Buffer
+	r.NoError(writeInKindCSV(&buf, gifts))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	r.Len(lines, 3)
+	r.Equal("Received,Donor,Email,Category,Quantity,Description,Estimated Value,Acknowledged", lines[0])
+	r.Equal(`2026-10-01,Hardware Co.,ops@hardware.example,Tools,4,"Cordless drill kits, new in box",596.00,2026-10-03`, lines[1])
+	r.Equal("2026-10-05,Jane Builder,,Building materials,1,Lumber,0.00,", lines[2])
+}
+
+func Test_InKindIndexTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	email := "ops@hardware.example"
+	gifts := models.InKindGifts{
+		{Category: models.InKindTools, DonorName: "Hardware Co.", DonorEmail: &email, Description: "Drill kits", Quantity: 4, EstimatedValue: 596, ReceivedAt: time.Now()},
+	}
+	flags := donorFlagIndex{email: {{DonorEmail: email, Flag: models.FlagBoardMember}}}
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/in-kind-test", func(c buffalo.Context) error {
+		c.Set("gifts", gifts)
+		c.Set("flags", flags)
+		c.Set("year", 2026)
+		c.Set("totals", map[string]float64{models.InKindTools: 596})
+		c.Set("totalValue", gifts.TotalEstimatedValue())
+		c.Set("inKindCategories", models.InKindCategories)
+		c.Set("categoryLabel", models.InKindCategoryLabel)
+		return c.Render(http.StatusOK, r.HTML("admin/in_kind/index.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/in-kind-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "4 × Drill kits")
+	req.Contains(w.Body.String(), "?year=2025")
+	req.Contains(w.Body.String(), `<span class="donor-flag donor-flag-blue">Board member</span>`)
+}
@@ -0,0 +1,194 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+)
+
+// installmentChoices are the pledge terms offered on the donation form.
+var installmentChoices = []int{2, 3, 4, 6, 12}
+
+// installmentOptions is the template helper listing installment choices.
+func installmentOptions() []string {
+	opts := make([]string, len(installmentChoices))
+	for i, n := range installmentChoices {
+		opts[i] = strconv.Itoa(n)
+	}
+	return opts
+}
+
+// parseInstallmentCount validates the number of installments chosen on the form.
+func parseInstallmentCount(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	for _, choice := range installmentChoices {
+		if n == choice {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// splitPledge divides a pledge into equal monthly installments, rounded to
+// the cent. Helcim bills the same amount every period, so the pledge total is
+// what those installments actually add up to.
+func splitPledge(total float64, installments int) (perInstallment, pledgeTotal float64) {
+	perInstallment = math.Round(total/float64(installments)*100) / 100
+	pledgeTotal = math.Round(perInstallment*float64(installments)*100) / 100
+	return perInstallment, pledgeTotal
+}
+
+// installmentMinimumError checks that each installment of a pledge still
+// meets the donation minimum once it's split, and returns the form error if
+// it doesn't.
+func installmentMinimumError(total float64, installments int, currency string) string {
+	perInstallment, _ := splitPledge(total, installments)
+	if minimum := donationMinimum(); perInstallment < minimum {
+		return fmt.Sprintf("Each installment must be at least %s. Please choose fewer installments or a larger pledge.", formatDonationAmount(minimum, currency))
+	}
+	return ""
+}
+
+// recurringPlanFor returns the Helcim payment plan for a recurring donation:
+// a fixed-term plan for installment pledges, otherwise an open-ended plan
+// billing at the gift's frequency.
+func recurringPlanFor(ctx context.Context, client services.HelcimAPI, donation *models.Donation) (int, error) {
+	if !donation.IsInstallmentPledge() {
+		return getOrCreateRecurringPlan(ctx, client, donation.Amount, donation.ChargeFrequency())
+	}
+
+	cacheKey := fmt.Sprintf("installment_%d_%.2f_%s", donation.InstallmentCount, donation.Amount, getCurrency())
+	if cachedPlan, found := services.GetPaymentPlanCache().Get(cacheKey); found {
+		return cachedPlan.ID, nil
+	}
+
+	planName := fmt.Sprintf("Pledge - %d x $%.2f", donation.InstallmentCount, donation.Amount)
+	plan, err := client.CreateInstallmentPlan(ctx, donation.Amount, planName, donation.InstallmentCount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create installment plan for %d x $%.2f: %w", donation.InstallmentCount, donation.Amount, err)
+	}
+	services.GetPaymentPlanCache().Set(cacheKey, plan)
+	return plan.ID, nil
+}
+
+// recurringReceiptLabel is the donation type shown on a recurring receipt.
+func recurringReceiptLabel(donation *models.Donation, sequence int) string {
+	if donation.IsInstallmentPledge() {
+		return fmt.Sprintf("Installment %d of %d", sequence, donation.InstallmentCount)
+	}
+	return donation.TypeLabel()
+}
+
+// recordInstallment stores one installment payment and refreshes the pledge's
+// paid count. It returns false when the installment was already recorded, so
+// webhook retries don't send duplicate receipts.
+func recordInstallment(tx *pop.Connection, donation *models.Donation, sequence int, transactionID string) (bool, error) {
+	q := tx.Where("donation_id = ? AND sequence = ?", donation.ID, sequence)
+	if transactionID != "" {
+		q = tx.Where("(donation_id = ? AND sequence = ?) OR transaction_id = ?", donation.ID, sequence, transactionID)
+	}
+	exists, err := q.Exists(&models.PledgeInstallment{})
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	installment := &models.PledgeInstallment{
+		DonationID: donation.ID,
+		Sequence:   sequence,
+		Amount:     donation.Amount,
+		PaidAt:     time.Now(),
+	}
+	if transactionID != "" {
+		installment.TransactionID = &transactionID
+	}
+	verrs, err := tx.ValidateAndCreate(installment)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		return false, errors.New(verrs.String())
+	}
+
+	paid, err := tx.Where("donation_id = ?", donation.ID).Count(&models.PledgeInstallment{})
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	donation.InstallmentsPaid = paid
+	if donation.PledgeComplete() {
+		donation.Status = "completed"
+	}
+	return true, errors.WithStack(tx.Update(donation))
+}
+
+// handleInstallmentWebhook records a subscription payment against an
+// installment pledge, sends its receipt, and sends the completion email after
+// the final installment. It reports false when the payment isn't for a pledge.
+func handleInstallmentWebhook(tx *pop.Connection, data HelcimWebhookData, c buffalo.Context) (bool, error) {
+	donation := &models.Donation{}
+	err := tx.Where("subscription_id = ? AND installment_count > 0", data.SubscriptionID).First(donation)
+	if err != nil {
+		return false, nil
+	}
+
+	sequence := data.PaymentNumber
+	if sequence <= 0 {
+		sequence = donation.InstallmentsPaid + 1
+	}
+
+	wasComplete := donation.PledgeComplete()
+	created, err := recordInstallment(tx, donation, sequence, data.TransactionID)
+	if err != nil {
+		return true, err
+	}
+	if !created {
+		c.Logger().Infof("[Webhook] Installment %d for pledge %s already recorded - skipping", sequence, donation.ID.String())
+		return true, nil
+	}
+
+	c.Logger().Infof("[Webhook] Recorded installment %d of %d for pledge %s", sequence, donation.InstallmentCount, donation.ID.String())
+
+	emailService := services.NewEmailService()
+	receipt := webhookReceiptData(donation, data.TransactionID)
+	receipt.DonationType = recurringReceiptLabel(donation, sequence)
+	receipt.DonationDate = time.Now()
+	queueReceipt(tx, donation, receipt, fmt.Sprintf("Receipt for installment %d of %d", sequence, donation.InstallmentCount))
+
+	if donation.PledgeComplete() && !wasComplete {
+		sendPledgeCompletion(c, tx, emailService, donation)
+	}
+	return true, nil
+}
+
+// sendPledgeCompletion emails the donor once their pledge is fully paid.
+func sendPledgeCompletion(c buffalo.Context, tx *pop.Connection, emailService *services.EmailService, donation *models.Donation) {
+	err := emailService.SendPledgeCompletion(donation.DonorEmail, services.PledgeCompletionData{
+		DonorName:        donation.DonorName,
+		PledgeTotal:      donation.PledgeTotal,
+		InstallmentCount: donation.InstallmentCount,
+		FirstPaymentDate: donation.CreatedAt,
+		CompletedDate:    time.Now(),
+		OrganizationName: organization().Name,
+		OrganizationEIN:  organization().EIN,
+	})
+	if err != nil {
+		c.Logger().Errorf("[Pledge] Failed to send completion email for pledge %s: %v", donation.ID.String(), err)
+		return
+	}
+	c.Logger().Infof("[Pledge] Completion email sent to %s for pledge %s", donation.DonorEmail, donation.ID.String())
+	recordCommunication(tx, donation.DonorEmail, models.CommunicationAcknowledgment, fmt.Sprintf("Pledge of $%.2f fulfilled", donation.PledgeTotal), nil, &donation.ID)
+}
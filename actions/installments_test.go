@@ -0,0 +1,70 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_SplitPledge(t *testing.T) {
+	r := require.New(t)
+
+	per, total := splitPledge(1200, 12)
+	r.Equal(100.0, per)
+	r.Equal(1200.0, total)
+
+	// Uneven pledges round each installment to the cent
+	per, total = splitPledge(1000, 3)
+	r.Equal(333.33, per)
+	r.Equal(999.99, total)
+}
+
+func Test_InstallmentMinimumError(t *testing.T) {
+	r := require.New(t)
+
+	envy.Temp(func() {
+		envy.Set("DONATION_MIN_AMOUNT", "5")
+
+		r.Empty(installmentMinimumError(60, 12, "USD"))
+		// $30 clears the minimum as a total but not as twelve $2.50 installments
+		r.Equal("Each installment must be at least $5.00. Please choose fewer installments or a larger pledge.", installmentMinimumError(30, 12, "USD"))
+	})
+}
+
+func Test_ParseInstallmentCount(t *testing.T) {
+	r := require.New(t)
+
+	n, ok := parseInstallmentCount("6")
+	r.True(ok)
+	r.Equal(6, n)
+
+	for _, bad := range []string{"", "1", "5", "24", "abc"} {
+		_, ok := parseInstallmentCount(bad)
+		r.False(ok, bad)
+	}
+}
+
+func Test_RecurringReceiptLabel(t *testing.T) {
+	r := require.New(t)
+
+	pledge := &models.Donation{DonationType: models.DonationTypeInstallment, InstallmentCount: 4}
+	r.Equal("Installment 2 of 4", recurringReceiptLabel(pledge, 2))
+	r.Equal("Monthly", recurringReceiptLabel(&models.Donation{DonationType: "monthly"}, 1))
+	r.Equal("Annual", recurringReceiptLabel(&models.Donation{DonationType: "monthly", Frequency: models.FrequencyAnnual}, 1))
+}
+
+func Test_RecurringPlanCacheKey(t *testing.T) {
+	r := require.New(t)
+
+	// Monthly plans keep the key already in the cache
+	r.Equal("plan_25_USD", recurringPlanCacheKey(25, models.FrequencyMonthly))
+	r.Equal("plan_quarterly_25_USD", recurringPlanCacheKey(25, models.FrequencyQuarterly))
+	r.Equal("plan_annual_25_USD", recurringPlanCacheKey(25, models.FrequencyAnnual))
+	for _, f := range models.DonationFrequencies {
+		_, ok := billingPeriods[f]
+		r.True(ok, f)
+	}
+}
@@ -0,0 +1,87 @@
+package actions
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// findInvitedUser loads the user whose invitation link was opened, showing
+// the expired-link page when there's none
+func findInvitedUser(c buffalo.Context) (*models.User, error) {
+	tx := c.Value("tx").(*pop.Connection)
+	user, err := models.FindUserByInvitation(tx, c.Param("token"), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		logging.SecurityEvent(c, "invitation_rejected", "failure", "invalid_or_expired_token", logging.Fields{})
+		c.Set("user", nil)
+		return nil, c.Render(http.StatusNotFound, r.HTML("auth/invitation.plush.html"))
+	}
+	return user, nil
+}
+
+// InvitationShow shows the form for an invited user to choose a password
+func InvitationShow(c buffalo.Context) error {
+	user, err := findInvitedUser(c)
+	if user == nil {
+		return err
+	}
+	c.Set("user", user)
+	c.Set("token", c.Param("token"))
+	return c.Render(http.StatusOK, r.HTML("auth/invitation.plush.html"))
+}
+
+// InvitationAccept sets the invited user's password and signs them in. The
+// link stops working once it's been used.
+func InvitationAccept(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user, err := findInvitedUser(c)
+	if user == nil {
+		return err
+	}
+
+	password := c.Param("password")
+	verrs := validate.NewErrors()
+	if len(password) < 8 || len(password) > 128 {
+		verrs.Add("password", "Password must be between 8 and 128 characters")
+	} else if password != c.Param("password_confirmation") {
+		verrs.Add("password", "Password does not match confirmation")
+	}
+	if verrs.HasAny() {
+		c.Set("user", user)
+		c.Set("token", c.Param("token"))
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("auth/invitation.plush.html"))
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	user.PasswordHash = string(hash)
+	user.InvitationTokenHash = nil
+	if err := tx.UpdateColumns(user, "password_hash", "invitation_token_hash", "updated_at"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.UserAction(c, user.Email, "invitation_accepted", "Accepted account invitation", logging.Fields{
+		"user_id": user.ID.String(),
+	})
+
+	rotateSession(c, user.ID, user.Role)
+	c.Flash().Add("success", "Your password is set. Welcome!")
+	if user.Role == "admin" {
+		return c.Redirect(http.StatusFound, "/admin")
+	}
+	return c.Redirect(http.StatusFound, "/dashboard")
+}
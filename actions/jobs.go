@@ -0,0 +1,392 @@
+package actions
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// jobDigestLookback is how far back the job digest looks for newly approved
+// postings. It matches the weekly cron schedule.
+const jobDigestLookback = 7 * 24 * time.Hour
+
+// parseJobExpiry reads the expiry date an employer chose for their posting.
+// Postings run through the end of that day; a blank date means
+// models.JobDefaultDays from now.
+func parseJobExpiry(s string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if strings.TrimSpace(s) == "" {
+		return today.AddDate(0, 0, models.JobDefaultDays+1), nil
+	}
+	date, err := time.ParseInLocation(dateInputLayout, strings.TrimSpace(s), now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Enter the expiry date as a date")
+	}
+	if date.Before(today) {
+		return time.Time{}, fmt.Errorf("The expiry date has already passed")
+	}
+	if date.After(today.AddDate(0, 0, models.JobMaxDays)) {
+		return time.Time{}, fmt.Errorf("Postings can run for at most %d days", models.JobMaxDays)
+	}
+	return date.AddDate(0, 0, 1), nil
+}
+
+// liveJobs is the query for postings shown on the board
+func liveJobs(tx *pop.Connection, now time.Time) *pop.Query {
+	return tx.Where("status = ? AND expires_at > ?", models.JobApproved, now)
+}
+
+// jobURL is a posting's public address
+func jobURL(job models.JobPosting) string {
+	return fmt.Sprintf("%s/jobs/%s", siteURL(), job.ID)
+}
+
+// JobsIndex is the public job board: approved postings that haven't
+// expired, newest first
+func JobsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	jobs := models.JobPostings{}
+	if err := liveJobs(tx, time.Now()).Order("created_at desc").All(&jobs); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("title", "Job Board")
+	c.Set("jobs", jobs)
+	return c.Render(http.StatusOK, r.HTML("pages/jobs.plush.html"))
+}
+
+// JobShow shows one posting on the board. Postings that are awaiting
+// review, rejected or expired aren't found.
+func JobShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	job := &models.JobPosting{}
+	if err := liveJobs(tx, time.Now()).Find(job, c.Param("job_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	c.Set("title", job.Title)
+	c.Set("job", job)
+	return c.Render(http.StatusOK, r.HTML("pages/job.plush.html"))
+}
+
+// bindJobPosting copies the employer's job posting form onto job
+func bindJobPosting(c buffalo.Context, job *models.JobPosting) {
+	job.EmployerName = strings.TrimSpace(c.Param("EmployerName"))
+	job.ContactEmail = strings.ToLower(strings.TrimSpace(c.Param("ContactEmail")))
+	job.Title = strings.TrimSpace(c.Param("Title"))
+	job.Location = strings.TrimSpace(c.Param("Location"))
+	job.Remote = c.Param("Remote") == "true"
+	job.Description = strings.TrimSpace(c.Param("Description"))
+	job.ApplyURL = strings.TrimSpace(c.Param("ApplyURL"))
+}
+
+// setJobFormContext exposes a posting to the employer's job posting form
+func setJobFormContext(c buffalo.Context, job *models.JobPosting, partner *models.CorporatePartner) {
+	partnerSlug := ""
+	if partner != nil {
+		partnerSlug = partner.Slug
+	}
+	c.Set("title", "Post a Job")
+	c.Set("job", job)
+	c.Set("jobExpires", c.Param("ExpiresAt"))
+	c.Set("partnerSlug", partnerSlug)
+	c.Set("maxJobDays", models.JobMaxDays)
+	c.Set("defaultJobDays", models.JobDefaultDays)
+	c.Set("form_timestamp", time.Now().Unix())
+}
+
+// JobPostingHandler shows (GET) and submits (POST) the form employers use
+// to post a job. Partners can link to it with ?partner={slug} so their
+// postings are credited to them. Postings wait for an admin to approve them.
+func JobPostingHandler(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	job := &models.JobPosting{Status: models.JobPending}
+	var partner *models.CorporatePartner
+	if slug := c.Param("partner"); slug != "" {
+		if p, err := findActivePartner(tx, slug); err == nil {
+			partner = p
+			job.PartnerID = &p.ID
+			job.EmployerName = p.Name
+		}
+	}
+	c.Set("submitted", false)
+	if c.Request().Method == "GET" {
+		setJobFormContext(c, job, partner)
+		return c.Render(http.StatusOK, r.HTML("pages/job_new.plush.html"))
+	}
+
+	bindJobPosting(c, job)
+	verrs, err := job.Validate(tx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ValidateBotProtection(c); err != nil {
+		verrs.Add("form", err.Error())
+	}
+	expires, expiryErr := parseJobExpiry(c.Param("ExpiresAt"), time.Now())
+	if expiryErr != nil {
+		verrs.Add("expires_at", expiryErr.Error())
+	}
+	job.ExpiresAt = expires
+	if verrs.HasAny() {
+		setJobFormContext(c, job, partner)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("pages/job_new.plush.html"))
+	}
+
+	if err := tx.Create(job); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.Audit("job_posting_submitted", logging.Fields{
+		"job_id":     job.ID.String(),
+		"employer":   job.EmployerName,
+		"partner_id": job.PartnerID,
+	})
+	publishAdminActivity(activityReview, fmt.Sprintf("Job posting to review: %s at %s", job.Title, job.EmployerName), job.ContactEmail, "/admin/jobs")
+
+	setJobFormContext(c, job, partner)
+	c.Set("submitted", true)
+	return c.Render(http.StatusOK, r.HTML("pages/job_new.plush.html"))
+}
+
+// RSS 2.0 document for the job board feed
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description"`
+}
+
+// jobsFeed renders the job board's postings as an RSS feed
+func jobsFeed(jobs models.JobPostings) ([]byte, error) {
+	feed := rssFeed{Version: "2.0", Channel: rssChannel{
+		Title:       organization().Name + " Job Board",
+		Link:        siteURL() + "/jobs",
+		Description: "Veteran-friendly jobs posted by employers and partners of " + organization().Name,
+	}}
+	for _, job := range jobs {
+		published := job.CreatedAt
+		if job.ReviewedAt != nil {
+			published = *job.ReviewedAt
+		}
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       fmt.Sprintf("%s at %s", job.Title, job.EmployerName),
+			Link:        jobURL(job),
+			GUID:        jobURL(job),
+			PubDate:     published.Format(time.RFC1123Z),
+			Description: fmt.Sprintf("%s. %s", job.LocationLabel(), job.Description),
+		})
+	}
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// JobsFeed is the job board's RSS feed
+func JobsFeed(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	jobs := models.JobPostings{}
+	if err := liveJobs(tx, time.Now()).Order("created_at desc").All(&jobs); err != nil {
+		return errors.WithStack(err)
+	}
+	feed, err := jobsFeed(jobs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return c.Render(http.StatusOK, r.Func("application/rss+xml", func(w io.Writer, d render.Data) error {
+		_, err := w.Write(feed)
+		return err
+	}))
+}
+
+// AccountJobAlerts turns the signed-in user's job board digest on or off
+func AccountJobAlerts(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	user.JobAlerts = c.Param("JobAlerts") == "true"
+	if err := tx.UpdateColumns(user, "job_alerts", "updated_at"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if user.JobAlerts {
+		c.Flash().Add("success", "Job alerts are on. We'll email you each week when new jobs are posted.")
+	} else {
+		c.Flash().Add("success", "Job alerts are off.")
+	}
+	return c.Redirect(http.StatusFound, "/account")
+}
+
+// AdminJobsIndex lists job postings in one review state, with a count of
+// the postings in each
+func AdminJobsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	status := c.Param("status")
+	valid := false
+	for _, s := range models.JobStatuses {
+		valid = valid || s == status
+	}
+	if !valid {
+		status = models.JobPending
+	}
+
+	jobs := models.JobPostings{}
+	if err := tx.Where("status = ?", status).Order("created_at desc").All(&jobs); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := tx.RawQuery("SELECT status, COUNT(*) as count FROM job_postings GROUP BY status").All(&rows); err != nil {
+		return errors.WithStack(err)
+	}
+	counts := map[string]int{}
+	for _, s := range models.JobStatuses {
+		counts[s] = 0
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	c.Set("jobs", jobs)
+	c.Set("jobStatus", status)
+	c.Set("statusCounts", counts)
+	c.Set("jobStatuses", models.JobStatuses)
+	c.Set("statusLabel", models.JobStatusLabel)
+	c.Set("now", time.Now())
+	return c.Render(http.StatusOK, r.HTML("admin/jobs/index.plush.html"))
+}
+
+// AdminJobStatus approves or rejects a job posting
+func AdminJobStatus(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	job := &models.JobPosting{}
+	if err := tx.Find(job, c.Param("job_id")); err != nil {
+		c.Flash().Add("danger", "Job posting not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/jobs")
+	}
+	status := c.Param("Status")
+	if status != models.JobApproved && status != models.JobRejected {
+		c.Flash().Add("danger", "Choose to approve or reject the posting.")
+		return c.Redirect(http.StatusSeeOther, "/admin/jobs")
+	}
+
+	now := time.Now()
+	previous := job.Status
+	job.Status = status
+	job.ReviewedAt = &now
+	if err := tx.Update(job); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.UserAction(c, user.Email, "job_posting_reviewed", "Reviewed job posting", logging.Fields{
+		"job_id": job.ID.String(),
+		"from":   previous,
+		"to":     status,
+	})
+	switch {
+	case status == models.JobRejected:
+		c.Flash().Add("success", fmt.Sprintf("Rejected %s at %s.", job.Title, job.EmployerName))
+	case job.Expired(now):
+		c.Flash().Add("warning", fmt.Sprintf("Approved %s at %s, but it has already expired so it won't be listed.", job.Title, job.EmployerName))
+	default:
+		c.Flash().Add("success", fmt.Sprintf("Approved %s at %s. It's listed until %s.", job.Title, job.EmployerName, job.LastDay().Format("Jan 2, 2006")))
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/jobs?status=%s", previous)
+}
+
+// SendJobDigest emails every user who turned on job alerts the postings
+// approved over the past week that are still open. It's run weekly from
+// cron through the jobs:digest task and returns how many digests it sent.
+func SendJobDigest(tx *pop.Connection, now time.Time) (int, error) {
+	jobs := models.JobPostings{}
+	err := liveJobs(tx, now).Where("reviewed_at >= ?", now.Add(-jobDigestLookback)).Order("reviewed_at").All(&jobs)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if len(jobs) == 0 {
+		return 0, nil
+	}
+
+	users := []models.User{}
+	if err := tx.Where("job_alerts = ?", true).All(&users); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	postings := make([]services.JobDigestPosting, len(jobs))
+	for i, job := range jobs {
+		postings[i] = services.JobDigestPosting{
+			Title:     job.Title,
+			Employer:  job.EmployerName,
+			Location:  job.LocationLabel(),
+			URL:       jobURL(job),
+			ExpiresAt: job.LastDay(),
+		}
+	}
+
+	emailService := services.NewEmailService()
+	sent := 0
+	for _, user := range users {
+		if contactSuppressed(tx, models.SuppressEmail, user.Email, "Job board digest") {
+			continue
+		}
+		err := emailService.SendJobDigest(user.Email, services.JobDigestData{
+			Name:             user.FirstName,
+			Postings:         postings,
+			BoardURL:         siteURL() + "/jobs",
+			AccountURL:       siteURL() + "/account",
+			OrganizationName: organization().Name,
+		})
+		if err != nil {
+			logging.Error("job_digest_failed", err, logging.Fields{
+				"user_id": user.ID.String(),
+			})
+			continue
+		}
+		sent++
+	}
+
+	logging.Audit("job_digest_sent", logging.Fields{
+		"jobs":       len(jobs),
+		"recipients": sent,
+	})
+	return sent, nil
+}
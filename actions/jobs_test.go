@@ -0,0 +1,126 @@
+package actions
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_ParseJobExpiry(t *testing.T) {
+	req := require.New(t)
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+
+	expires, err := parseJobExpiry("", now)
+	req.NoError(err)
+	req.Equal(time.Date(2026, 11, 14, 0, 0, 0, 0, time.UTC), expires)
+
+	// A posting runs through the end of the chosen day
+	expires, err = parseJobExpiry("2026-10-14", now)
+	req.NoError(err)
+	req.Equal(time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), expires)
+
+	_, err = parseJobExpiry("2026-10-13", now)
+	req.EqualError(err, "The expiry date has already passed")
+	_, err = parseJobExpiry("2027-01-13", now)
+	req.EqualError(err, "Postings can run for at most 90 days")
+	_, err = parseJobExpiry("next month", now)
+	req.Error(err)
+}
+
+func Test_JobsFeed(t *testing.T) {
+	req := require.New(t)
+	t.Setenv("SITE_URL", "https://avrnpo.org/")
+
+	reviewed := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	job := models.JobPosting{
+		ID:           uuid.Must(uuid.NewV4()),
+		EmployerName: "Acme Builders",
+		Title:        "Site Foreman",
+		Location:     "Austin, TX",
+		Remote:       true,
+		Description:  "Lead a residential build crew & mentor apprentices.",
+		ReviewedAt:   &reviewed,
+	}
+	out, err := jobsFeed(models.JobPostings{job})
+	req.NoError(err)
+
+	feed := rssFeed{}
+	req.NoError(xml.Unmarshal(out, &feed))
+	req.Equal("2.0", feed.Version)
+	req.Equal("https://avrnpo.org/jobs", feed.Channel.Link)
+	req.Len(feed.Channel.Items, 1)
+	item := feed.Channel.Items[0]
+	req.Equal("Site Foreman at Acme Builders", item.Title)
+	req.Equal("https://avrnpo.org/jobs/"+job.ID.String(), item.Link)
+	req.Equal("Mon, 12 Oct 2026 09:00:00 +0000", item.PubDate)
+	req.Equal("Austin, TX (remote OK). Lead a residential build crew & mentor apprentices.", item.Description)
+}
+
+func Test_JobTemplatesRendering(t *testing.T) {
+	req := require.New(t)
+
+	job := models.JobPosting{
+		ID:           uuid.Must(uuid.NewV4()),
+		EmployerName: "Acme Builders",
+		ContactEmail: "hr@acme.example",
+		Title:        "Site Foreman",
+		Location:     "Austin, TX",
+		Description:  "Lead a residential build crew.",
+		ApplyURL:     "https://acme.example/jobs/1",
+		Status:       models.JobPending,
+		ExpiresAt:    time.Date(2026, 11, 14, 0, 0, 0, 0, time.UTC),
+		CreatedAt:    time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC),
+	}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/jobs-test", func(c buffalo.Context) error {
+		c.Set("jobs", models.JobPostings{job})
+		return c.Render(http.StatusOK, r.HTML("pages/jobs.plush.html"))
+	})
+	app.GET("/job-new-test", func(c buffalo.Context) error {
+		setJobFormContext(c, &job, &models.CorporatePartner{Slug: "acme"})
+		c.Set("submitted", false)
+		verrs := validate.NewErrors()
+		verrs.Add("expires_at", "The expiry date has already passed")
+		c.Set("errors", verrs)
+		return c.Render(http.StatusOK, r.HTML("pages/job_new.plush.html"))
+	})
+	app.GET("/admin-jobs-test", func(c buffalo.Context) error {
+		c.Set("jobs", models.JobPostings{job})
+		c.Set("jobStatus", models.JobPending)
+		c.Set("statusCounts", map[string]int{models.JobPending: 1, models.JobApproved: 4, models.JobRejected: 0})
+		c.Set("jobStatuses", models.JobStatuses)
+		c.Set("statusLabel", models.JobStatusLabel)
+		c.Set("now", time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+		return c.Render(http.StatusOK, r.HTML("admin/jobs/index.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/jobs-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `<a href="/jobs/`+job.ID.String()+`">Site Foreman</a>`)
+	req.Contains(w.Body.String(), "Open until November 13, 2026")
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/job-new-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `<input type="hidden" name="partner" value="acme">`)
+	req.Contains(w.Body.String(), "The expiry date has already passed")
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/admin-jobs-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "<strong>Awaiting review (1)</strong>")
+	req.Contains(w.Body.String(), `<a href="/admin/jobs?status=approved">Approved</a> (4)`)
+	req.Contains(w.Body.String(), `value="approved">Approve</button>`)
+	req.Contains(w.Body.String(), "Listed until November 13, 2026")
+}
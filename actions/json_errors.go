@@ -0,0 +1,103 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+
+	"avrnpo.org/services"
+)
+
+// errorCode is the machine-readable code on a JSON error response. The
+// donation page and API clients branch on these rather than on messages,
+// which are written for people and may change, so a code is never renamed
+// once it's in use.
+type errorCode string
+
+const (
+	codeInvalidRequest     errorCode = "invalid_request"
+	codeValidationFailed   errorCode = "validation_failed"
+	codeUnauthorized       errorCode = "unauthorized"
+	codeInvalidSignature   errorCode = "invalid_signature"
+	codeForbidden          errorCode = "forbidden"
+	codeNotFound           errorCode = "not_found"
+	codeConflict           errorCode = "conflict"
+	codeDonationClosed     errorCode = "donation_closed"
+	codeAmountMismatch     errorCode = "amount_mismatch"
+	codePaymentDeclined    errorCode = "payment_declined"
+	codePaymentInvalid     errorCode = "payment_details_invalid"
+	codePaymentUnavailable errorCode = "payment_unavailable"
+	codePaymentFailed      errorCode = "payment_failed"
+	codeRequestTooLarge    errorCode = "request_too_large"
+	codeRateLimited        errorCode = "rate_limited"
+	codeServiceUnavailable errorCode = "service_unavailable"
+	codeInternal           errorCode = "internal_error"
+)
+
+// jsonErrorBody is the error in a JSON error response, which is always
+// {"error": {...}}. field_errors is keyed by form field and only present
+// for validation errors; request_id is the incident reference from the logs.
+type jsonErrorBody struct {
+	Code        errorCode           `json:"code"`
+	Message     string              `json:"message"`
+	FieldErrors map[string][]string `json:"field_errors,omitempty"`
+	RequestID   string              `json:"request_id,omitempty"`
+}
+
+// jsonErrorEnvelope is the body of a JSON error response. Handlers that send
+// more, such as retry_after, add it alongside "error".
+func jsonErrorEnvelope(c buffalo.Context, code errorCode, message string, fieldErrors map[string][]string) map[string]interface{} {
+	body := jsonErrorBody{Code: code, Message: message, FieldErrors: fieldErrors, RequestID: incidentReference(c)}
+	return map[string]interface{}{"error": body}
+}
+
+// jsonError renders a JSON error response
+func jsonError(c buffalo.Context, status int, code errorCode, message string) error {
+	return c.Render(status, r.JSON(jsonErrorEnvelope(c, code, message, nil)))
+}
+
+// jsonValidationError renders a 422 listing each field's problems, as in
+// validate.Errors.Errors
+func jsonValidationError(c buffalo.Context, fieldErrors map[string][]string) error {
+	return c.Render(http.StatusUnprocessableEntity, r.JSON(jsonErrorEnvelope(c, codeValidationFailed, "Some fields need attention", fieldErrors)))
+}
+
+// errorCodeForStatus is the code for an error known only by its status, as
+// when Buffalo's error handler renders one for a JSON request
+func errorCodeForStatus(status int) errorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return codeInvalidRequest
+	case http.StatusUnauthorized:
+		return codeUnauthorized
+	case http.StatusForbidden:
+		return codeForbidden
+	case http.StatusNotFound:
+		return codeNotFound
+	case http.StatusConflict:
+		return codeConflict
+	case http.StatusRequestEntityTooLarge:
+		return codeRequestTooLarge
+	case http.StatusUnprocessableEntity:
+		return codeValidationFailed
+	case http.StatusTooManyRequests:
+		return codeRateLimited
+	case http.StatusServiceUnavailable:
+		return codeServiceUnavailable
+	}
+	if status < http.StatusInternalServerError {
+		return codeInvalidRequest
+	}
+	return codeInternal
+}
+
+// jsonErrorFor is the status, code and message for an error a handler
+// returned to a JSON request: a payment Helcim didn't take as helcimFailure
+// describes it, and anything else by its status, the details staying in
+// the logs
+func jsonErrorFor(status int, err error) (int, errorCode, string) {
+	if _, ok := services.AsHelcimError(err); ok {
+		return helcimFailure(err)
+	}
+	return status, errorCodeForStatus(status), http.StatusText(status)
+}
@@ -0,0 +1,81 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/services"
+)
+
+func Test_ErrorCodeForStatus(t *testing.T) {
+	assert.Equal(t, codeInvalidRequest, errorCodeForStatus(http.StatusBadRequest))
+	assert.Equal(t, codeForbidden, errorCodeForStatus(http.StatusForbidden))
+	assert.Equal(t, codeNotFound, errorCodeForStatus(http.StatusNotFound))
+	assert.Equal(t, codeRequestTooLarge, errorCodeForStatus(http.StatusRequestEntityTooLarge))
+	assert.Equal(t, codeRateLimited, errorCodeForStatus(http.StatusTooManyRequests))
+	assert.Equal(t, codeInvalidRequest, errorCodeForStatus(http.StatusMethodNotAllowed))
+	assert.Equal(t, codeInternal, errorCodeForStatus(http.StatusBadGateway))
+}
+
+func Test_JSONErrorFor(t *testing.T) {
+	status, code, _ := jsonErrorFor(http.StatusInternalServerError, fmt.Errorf("charging: %w", &services.HelcimError{StatusCode: 400, Code: services.HelcimDeclined}))
+	assert.Equal(t, http.StatusPaymentRequired, status)
+	assert.Equal(t, codePaymentDeclined, code)
+
+	// Anything else keeps its status, and its details stay out of the message
+	status, code, message := jsonErrorFor(http.StatusInternalServerError, fmt.Errorf("pq: relation \"donations\" does not exist"))
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, codeInternal, code)
+	assert.NotContains(t, message, "pq")
+}
+
+func Test_JSONErrorEnvelope(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.Use(func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			c.Set("request_id", "req-123")
+			return next(c)
+		}
+	})
+	app.GET("/missing", func(c buffalo.Context) error {
+		return jsonError(c, http.StatusNotFound, codeNotFound, "Donation not found")
+	})
+	app.GET("/invalid", func(c buffalo.Context) error {
+		verrs := validate.NewErrors()
+		verrs.Add("amount", "amount must be greater than zero")
+		return jsonValidationError(c, verrs.Errors)
+	})
+
+	var body struct {
+		Error jsonErrorBody `json:"error"`
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		body.Error = jsonErrorBody{}
+		req.NoError(json.Unmarshal(res.Body.Bytes(), &body))
+		return res
+	}
+
+	res := get("/missing")
+	req.Equal(http.StatusNotFound, res.Code)
+	req.Equal(codeNotFound, body.Error.Code)
+	req.Equal("Donation not found", body.Error.Message)
+	req.Equal("req-123", body.Error.RequestID)
+	req.NotContains(res.Body.String(), "field_errors")
+
+	res = get("/invalid")
+	req.Equal(http.StatusUnprocessableEntity, res.Code)
+	req.Equal(codeValidationFailed, body.Error.Code)
+	req.Equal([]string{"amount must be greater than zero"}, body.Error.FieldErrors["amount"])
+}
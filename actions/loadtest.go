@@ -0,0 +1,215 @@
+package actions
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// loadTestMaxBatch is the most synthetic donations one request makes. Load
+// comes from many requests in parallel, as it would from donors.
+const loadTestMaxBatch = 25
+
+// loadTestAmounts are the gifts synthetic donors choose from, weighted
+// toward the smaller presets the way real campaign traffic is
+var loadTestAmounts = []float64{25, 25, 25, 50, 50, 100, 250}
+
+// loadTestLikePattern matches the email of every synthetic donor
+var loadTestLikePattern = "%@" + services.LoadTestEmailDomain
+
+// loadTestModeEnabled reports whether the load test endpoints are mounted:
+// only on staging (DEPLOY_ENV=staging) with LOAD_TEST_MODE=true and a
+// LOAD_TEST_TOKEN for the load generator to send. They fill the database with
+// synthetic donors, so they're never available in production.
+func loadTestModeEnabled() bool {
+	return os.Getenv("LOAD_TEST_MODE") == "true" &&
+		os.Getenv("DEPLOY_ENV") == "staging" &&
+		strings.TrimSpace(os.Getenv("LOAD_TEST_TOKEN")) != ""
+}
+
+// LoadTestTokenRequired only lets through requests carrying LOAD_TEST_TOKEN
+// in the X-Load-Test-Token header
+func LoadTestTokenRequired(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		want := strings.TrimSpace(os.Getenv("LOAD_TEST_TOKEN"))
+		got := c.Request().Header.Get("X-Load-Test-Token")
+		if want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			logging.SecurityEvent(c, "load_test_auth", "failure", "invalid_token", logging.Fields{
+				"ip":   getClientIP(c),
+				"path": c.Request().URL.Path,
+			})
+			return jsonError(c, http.StatusUnauthorized, codeUnauthorized, "Invalid load test token")
+		}
+		return next(c)
+	}
+}
+
+// runLoadTestDonation takes one synthetic one-time gift through the same
+// steps as a real one: the donation is saved and filed under a donor, charged
+// on the mock gateway, completed by the card transaction webhook handler
+// (which publishes the live stats and API hooks), and has its receipt
+// numbered and queued. Only the charge and the email delivery are fake.
+func runLoadTestDonation(tx *pop.Connection, c buffalo.Context, client services.HelcimAPI, n int) (*models.Donation, error) {
+	tag := strings.ReplaceAll(uuid.Must(uuid.NewV4()).String(), "-", "")[:12]
+	donation := &models.Donation{
+		Amount:       loadTestAmounts[rand.IntN(len(loadTestAmounts))],
+		Currency:     getCurrency(),
+		DonorName:    fmt.Sprintf("Load Test Donor %d", n),
+		DonorEmail:   "loadtest-" + tag + "@" + services.LoadTestEmailDomain,
+		DonationType: models.DonationTypeOneTime,
+		Status:       "pending",
+		Comments:     stringPointer("synthetic load test"),
+	}
+	verrs, err := tx.ValidateAndCreate(donation)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		return nil, fmt.Errorf("synthetic donation failed validation: %s", verrs.String())
+	}
+
+	resp, err := client.ProcessPayment(c, services.PaymentAPIRequest{
+		PaymentType:   "purchase",
+		Amount:        donation.Amount,
+		Currency:      donation.Currency,
+		CustomerCode:  "LOADTEST",
+		CardData:      services.CardData{CardToken: "loadtest_card_token"},
+		IPAddress:     getClientIP(c),
+		Description:   "Load test",
+		CustomerEmail: donation.DonorEmail,
+		CustomerName:  donation.DonorName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(resp.Status, "APPROVED") {
+		return nil, fmt.Errorf("charge was %s", resp.Status)
+	}
+	transactionID := "loadtest_" + donation.ID.String()
+	donation.TransactionID = &transactionID
+	if err := tx.Update(donation); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := handleCardTransaction(tx, transactionID, c); err != nil {
+		return nil, err
+	}
+	return donation, nil
+}
+
+// LoadTestDonationsCreate makes ?count= synthetic donations (default 1, at
+// most loadTestMaxBatch) and reports how long they took, for a load
+// generator verifying capacity before a campaign launches
+func LoadTestDonationsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	count := 1
+	if s := c.Param("count"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > loadTestMaxBatch {
+			return jsonError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("count must be between 1 and %d", loadTestMaxBatch))
+		}
+		count = n
+	}
+
+	client := services.NewMockHelcimClient()
+	start := time.Now()
+	ids := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		donation, err := runLoadTestDonation(tx, c, client, i+1)
+		if err != nil {
+			logging.Error("load_test_donation_failed", err, logging.Fields{
+				"created": len(ids),
+			})
+			return jsonError(c, http.StatusInternalServerError, codeInternal, "Synthetic donation failed: "+err.Error())
+		}
+		ids = append(ids, donation.ID.String())
+	}
+
+	return c.Render(http.StatusCreated, r.JSON(map[string]interface{}{
+		"created":      len(ids),
+		"donation_ids": ids,
+		"duration_ms":  time.Since(start).Milliseconds(),
+	}))
+}
+
+// loadTestCounts is how much synthetic data there is
+type loadTestCounts struct {
+	Donations       int `json:"donations"`
+	Donors          int `json:"donors"`
+	PendingReceipts int `json:"pending_receipts"`
+}
+
+// countLoadTestData counts the synthetic donations and donors, and the
+// receipts for them still waiting in the email queue, so a load generator
+// can watch the queue drain once it stops
+func countLoadTestData(tx *pop.Connection) (loadTestCounts, error) {
+	counts := loadTestCounts{}
+	var err error
+	if counts.Donations, err = tx.Where("donor_email LIKE ?", loadTestLikePattern).Count(&models.Donation{}); err != nil {
+		return counts, errors.WithStack(err)
+	}
+	if counts.Donors, err = tx.Where("email LIKE ?", loadTestLikePattern).Count(&models.Donor{}); err != nil {
+		return counts, errors.WithStack(err)
+	}
+	err = tx.RawQuery("SELECT COUNT(*) FROM background_jobs WHERE handler = ? AND status = ? AND args LIKE ?",
+		jobDonationReceipt, "pending", "%"+loadTestLikePattern+"%").First(&counts.PendingReceipts)
+	return counts, errors.WithStack(err)
+}
+
+// LoadTestStatus reports how much synthetic data there is
+func LoadTestStatus(c buffalo.Context) error {
+	counts, err := countLoadTestData(c.Value("tx").(*pop.Connection))
+	if err != nil {
+		return err
+	}
+	return c.Render(http.StatusOK, r.JSON(counts))
+}
+
+// TearDownLoadTest deletes every synthetic donor and donation and what was
+// recorded for them: queued receipt jobs, timeline entries, notes and flags.
+// Receipts and upgrade prompts go with their donations. Only rows with the
+// synthetic email domain are touched, so it's safe to run anywhere. It's run
+// by the loadtest:teardown grift once a load test is done.
+func TearDownLoadTest(tx *pop.Connection) (loadTestCounts, error) {
+	removed, err := countLoadTestData(tx)
+	if err != nil {
+		return removed, err
+	}
+	statements := []struct {
+		sql  string
+		args []interface{}
+	}{
+		{"DELETE FROM background_jobs WHERE handler = ? AND args LIKE ?", []interface{}{jobDonationReceipt, "%" + loadTestLikePattern + "%"}},
+		{"DELETE FROM donor_communications WHERE donor_email LIKE ?", []interface{}{loadTestLikePattern}},
+		{"DELETE FROM donor_notes WHERE donor_email LIKE ?", []interface{}{loadTestLikePattern}},
+		{"DELETE FROM donor_flags WHERE donor_email LIKE ?", []interface{}{loadTestLikePattern}},
+		{"DELETE FROM donations WHERE donor_email LIKE ?", []interface{}{loadTestLikePattern}},
+		{"DELETE FROM donors WHERE email LIKE ?", []interface{}{loadTestLikePattern}},
+	}
+	for _, s := range statements {
+		if err := tx.RawQuery(s.sql, s.args...).Exec(); err != nil {
+			return removed, errors.WithStack(err)
+		}
+	}
+	clearPublicStatsCache()
+	logging.Audit("load_test_torn_down", logging.Fields{
+		"donations": removed.Donations,
+		"donors":    removed.Donors,
+	})
+	return removed, nil
+}
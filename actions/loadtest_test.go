@@ -0,0 +1,54 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LoadTestModeEnabled(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		deployEnv string
+		token     string
+		want      bool
+	}{
+		{"on in staging", "true", "staging", "secret", true},
+		{"off by default", "", "staging", "secret", false},
+		{"never in production", "true", "production", "secret", false},
+		{"never without a deploy env", "true", "", "secret", false},
+		{"needs a token", "true", "staging", " ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LOAD_TEST_MODE", tt.mode)
+			t.Setenv("DEPLOY_ENV", tt.deployEnv)
+			t.Setenv("LOAD_TEST_TOKEN", tt.token)
+			require.Equal(t, tt.want, loadTestModeEnabled())
+		})
+	}
+}
+
+func Test_LoadTestTokenRequired(t *testing.T) {
+	req := require.New(t)
+	t.Setenv("LOAD_TEST_TOKEN", "secret")
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/load-test/status", LoadTestTokenRequired(func(c buffalo.Context) error {
+		return c.Render(http.StatusOK, r.String("ok"))
+	}))
+
+	for token, status := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "secret": http.StatusOK} {
+		request := httptest.NewRequest("GET", "/load-test/status", nil)
+		if token != "" {
+			request.Header.Set("X-Load-Test-Token", token)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, request)
+		req.Equal(status, w.Code, "token %q", token)
+	}
+}
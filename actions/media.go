@@ -0,0 +1,240 @@
+package actions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/imagescrub"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// maxMediaSize is the largest file the media library accepts, in bytes
+const maxMediaSize = 25 << 20
+
+// mediaTypes maps the file types the media library accepts to their file
+// extension
+var mediaTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+}
+
+// mediaUpload checks the file attached to the media library form, sniffing
+// its type rather than trusting the browser
+func mediaUpload(c buffalo.Context) (*multipart.FileHeader, string, string) {
+	req := c.Request()
+	if err := req.ParseMultipartForm(multipartMemory); err != nil && err != http.ErrNotMultipart {
+		return nil, "", "The file couldn't be uploaded. Please try again with a smaller file."
+	}
+	if req.MultipartForm == nil || len(req.MultipartForm.File["File"]) == 0 || req.MultipartForm.File["File"][0].Size == 0 {
+		return nil, "", "Choose a file to upload."
+	}
+
+	header := req.MultipartForm.File["File"][0]
+	if header.Size > maxMediaSize {
+		return nil, "", fmt.Sprintf("%s is too large. Files must be under %d MB.", header.Filename, maxMediaSize>>20)
+	}
+	f, err := header.Open()
+	if err != nil {
+		return nil, "", "The file couldn't be uploaded. Please try again."
+	}
+	sniff := make([]byte, 512)
+	n, _ := io.ReadFull(f, sniff)
+	f.Close()
+	contentType := http.DetectContentType(sniff[:n])
+	if _, ok := mediaTypes[contentType]; !ok {
+		return nil, "", fmt.Sprintf("%s isn't a JPEG, PNG, WebP, PDF or ZIP file.", header.Filename)
+	}
+	return header, contentType, ""
+}
+
+// setMediaContext sets the asset kinds for the media library and press
+// page templates
+func setMediaContext(c buffalo.Context) {
+	c.Set("mediaKinds", models.MediaKinds)
+	c.Set("mediaKindLabel", models.MediaKindLabel)
+	c.Set("scanStatusLabel", models.ScanStatusLabel)
+}
+
+// AdminMediaIndex lists the media library, newest first, with the upload form
+func AdminMediaIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	assets := models.MediaAssets{}
+	if err := tx.Order("created_at desc").All(&assets); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("assets", assets)
+	setMediaContext(c)
+	return c.Render(http.StatusOK, r.HTML("admin/media/index.plush.html"))
+}
+
+// AdminMediaCreate uploads a file to the media library
+func AdminMediaCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	header, contentType, uploadErr := mediaUpload(c)
+	if uploadErr != "" {
+		c.Flash().Add("danger", uploadErr)
+		return c.Redirect(http.StatusSeeOther, "/admin/media")
+	}
+
+	asset := &models.MediaAsset{
+		ID:          uuid.Must(uuid.NewV4()),
+		Title:       strings.TrimSpace(c.Param("Title")),
+		Description: stringPointer(strings.TrimSpace(c.Param("Description"))),
+		Kind:        c.Param("Kind"),
+		Filename:    header.Filename,
+		ContentType: contentType,
+		Size:        header.Size,
+		PressKit:    c.Param("PressKit") == "true",
+		ScanStatus:  initialScanStatus(),
+	}
+	asset.StorageKey = fmt.Sprintf("media/%s%s", asset.ID, mediaTypes[contentType])
+
+	blur, err := imagescrub.ParseRegions(c.Param("BlurAreas"))
+	if err != nil {
+		c.Flash().Add("danger", "Areas to blur: "+err.Error())
+		return c.Redirect(http.StatusSeeOther, "/admin/media")
+	}
+	if len(blur) > 0 && !imagescrub.CanBlur(contentType) {
+		c.Flash().Add("danger", "Areas can only be blurred in JPEG and PNG images.")
+		return c.Redirect(http.StatusSeeOther, "/admin/media")
+	}
+
+	verrs, err := asset.Validate(tx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+		return c.Redirect(http.StatusSeeOther, "/admin/media")
+	}
+
+	data, err := cleanImageUpload(header, contentType, blur)
+	if err != nil {
+		c.Flash().Add("danger", fmt.Sprintf("%s couldn't be read as an image.", header.Filename))
+		return c.Redirect(http.StatusSeeOther, "/admin/media")
+	}
+	asset.Size = int64(len(data))
+	if err := services.NewStorage().Save(asset.StorageKey, bytes.NewReader(data)); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := tx.Create(asset); err != nil {
+		return errors.WithStack(err)
+	}
+	queueUploadScan(tx, uploadKindMedia, asset.ID, asset.ScanStatus)
+
+	logging.UserAction(c, user.Email, "media_uploaded", "Uploaded media asset", logging.Fields{
+		"asset_id":      asset.ID.String(),
+		"press_kit":     asset.PressKit,
+		"blurred_areas": len(blur),
+	})
+	if asset.Released() {
+		c.Flash().Add("success", fmt.Sprintf("Uploaded %s.", asset.Title))
+	} else {
+		c.Flash().Add("success", fmt.Sprintf("Uploaded %s. It can be downloaded once its virus scan is done.", asset.Title))
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/media")
+}
+
+// findMediaAsset loads the asset named in the URL
+func findMediaAsset(c buffalo.Context) (*models.MediaAsset, error) {
+	tx := c.Value("tx").(*pop.Connection)
+	asset := &models.MediaAsset{}
+	if err := tx.Find(asset, c.Param("asset_id")); err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+// AdminMediaPressKit adds an asset to the press kit or takes it out
+func AdminMediaPressKit(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	asset, err := findMediaAsset(c)
+	if err != nil {
+		c.Flash().Add("danger", "File not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/media")
+	}
+	asset.PressKit = c.Param("PressKit") == "true"
+	if err := tx.Update(asset); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if asset.PressKit {
+		c.Flash().Add("success", fmt.Sprintf("Added %s to the press kit.", asset.Title))
+	} else {
+		c.Flash().Add("success", fmt.Sprintf("Removed %s from the press kit.", asset.Title))
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/media")
+}
+
+// AdminMediaDelete removes an asset and its file from the media library
+func AdminMediaDelete(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	asset, err := findMediaAsset(c)
+	if err != nil {
+		c.Flash().Add("danger", "File not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/media")
+	}
+	if err := tx.Destroy(asset); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := services.NewStorage().Delete(asset.StorageKey); err != nil {
+		logging.Error("media_file_delete_failed", err, logging.Fields{
+			"asset_id": asset.ID.String(),
+		})
+	}
+
+	logging.UserAction(c, user.Email, "media_deleted", "Deleted media asset", logging.Fields{
+		"asset_id": asset.ID.String(),
+	})
+	c.Flash().Add("success", fmt.Sprintf("Deleted %s.", asset.Title))
+	return c.Redirect(http.StatusSeeOther, "/admin/media")
+}
+
+// MediaDownload serves a media library file. Images are shown inline unless
+// ?download=1 asks for an attachment; other files always download. Files
+// held for a virus scan or quarantined aren't served to anyone.
+func MediaDownload(c buffalo.Context) error {
+	asset, err := findMediaAsset(c)
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if !asset.Released() {
+		return c.Error(http.StatusNotFound, fmt.Errorf("media asset %s is %s", asset.ID, asset.ScanStatus))
+	}
+	f, err := services.NewStorage().Open(asset.StorageKey)
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	defer f.Close()
+
+	if !asset.IsImage() || c.Param("download") == "1" {
+		c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", asset.Filename))
+	}
+	c.Response().Header().Set("Cache-Control", "public, max-age=86400")
+	return c.Render(http.StatusOK, r.Func(asset.ContentType, func(w io.Writer, d render.Data) error {
+		_, err := io.Copy(w, f)
+		return err
+	}))
+}
@@ -0,0 +1,416 @@
+package actions
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// mentorSuggestionsPerMentee is how many mentors are suggested for each
+// mentee waiting for a match
+const mentorSuggestionsPerMentee = 3
+
+// MentoringIndex is the public page for the mentoring program, listing the
+// approved mentors, optionally in one trade
+func MentoringIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	trade := c.Param("trade")
+	q := tx.Where("status = ? AND role = ?", models.MentorApproved, models.MentorRoleMentor)
+	if trade != "" {
+		q = q.Where("trade = ?", trade)
+	}
+	mentors := models.MentorProfiles{}
+	if err := q.Order("display_name").All(&mentors); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("title", "Mentoring")
+	c.Set("mentors", mentors)
+	c.Set("trades", models.MentorTrades)
+	c.Set("trade", trade)
+	return c.Render(http.StatusOK, r.HTML("pages/mentoring.plush.html"))
+}
+
+// bindMentorProfile copies the mentoring profile form onto profile
+func bindMentorProfile(c buffalo.Context, profile *models.MentorProfile) {
+	profile.Role = c.Param("Role")
+	profile.DisplayName = strings.TrimSpace(c.Param("DisplayName"))
+	profile.Trade = c.Param("Trade")
+	profile.Skills = strings.Join((&models.MentorProfile{Skills: c.Param("Skills")}).SkillList(), ", ")
+	profile.Region = strings.TrimSpace(c.Param("Region"))
+	profile.Bio = strings.TrimSpace(c.Param("Bio"))
+}
+
+// setMentorFormContext exposes a profile to the mentoring profile form
+func setMentorFormContext(c buffalo.Context, profile *models.MentorProfile, saved bool) {
+	c.Set("title", "Mentoring Profile")
+	c.Set("profile", profile)
+	c.Set("hasProfile", saved)
+	c.Set("trades", models.MentorTrades)
+	c.Set("formDraft", formDraftView{Form: draftFormMentoring})
+}
+
+// AccountMentoring shows (GET) and saves (POST) the signed-in user's
+// mentoring profile. New and edited profiles wait for an admin to approve
+// them before they're listed or matched.
+func AccountMentoring(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	profile := &models.MentorProfile{}
+	saved := true
+	err := tx.Where("user_id = ?", user.ID).First(profile)
+	if errors.Is(err, sql.ErrNoRows) {
+		saved = false
+		profile = &models.MentorProfile{
+			UserID:      user.ID,
+			Role:        models.MentorRoleMentee,
+			DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+		}
+	} else if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if c.Request().Method == "GET" {
+		setMentorFormContext(c, profile, saved)
+		if err := restoreFormDraft(c, draftFormMentoring); err != nil {
+			return err
+		}
+		return c.Render(http.StatusOK, r.HTML("users/mentoring.plush.html"))
+	}
+
+	bindMentorProfile(c, profile)
+	profile.Status = models.MentorPending
+	profile.ReviewedAt = nil
+	verrs, err := tx.ValidateAndSave(profile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		setMentorFormContext(c, profile, saved)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("users/mentoring.plush.html"))
+	}
+
+	if err := discardFormDraft(c, tx, draftFormMentoring); err != nil {
+		return err
+	}
+
+	logging.UserAction(c, user.Email, "mentor_profile_submitted", "Submitted mentoring profile", logging.Fields{
+		"mentor_profile_id": profile.ID.String(),
+		"role":              profile.Role,
+	})
+	publishAdminActivity(activityReview, fmt.Sprintf("%s profile to review: %s", profile.RoleLabel(), profile.DisplayName), user.Email, "/admin/mentoring")
+
+	c.Flash().Add("success", "Thanks! Our team will review your profile and be in touch when we've found you a match.")
+	return c.Redirect(http.StatusFound, "/account/mentoring")
+}
+
+// mentorProfileRow is a profile on the admin mentoring page with its
+// owner's email
+type mentorProfileRow struct {
+	Profile models.MentorProfile
+	Email   string
+}
+
+// mentorIntroductionRow is an introduction on the admin matches page with
+// the pair's profiles
+type mentorIntroductionRow struct {
+	Introduction models.MentorIntroduction
+	Mentor       models.MentorProfile
+	Mentee       models.MentorProfile
+}
+
+// mentorProfileEmails maps the profiles' user IDs to their sign-in emails
+func mentorProfileEmails(tx *pop.Connection, profiles models.MentorProfiles) (map[uuid.UUID]string, error) {
+	emails := map[uuid.UUID]string{}
+	if len(profiles) == 0 {
+		return emails, nil
+	}
+	ids := make([]uuid.UUID, len(profiles))
+	for i, p := range profiles {
+		ids[i] = p.UserID
+	}
+	users, err := models.UsersByID(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, u := range users {
+		emails[id] = u.Email
+	}
+	return emails, nil
+}
+
+// AdminMentoringIndex lists mentoring profiles in one review state, with a
+// count of the profiles in each
+func AdminMentoringIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	status := c.Param("status")
+	valid := false
+	for _, s := range models.MentorStatuses {
+		valid = valid || s == status
+	}
+	if !valid {
+		status = models.MentorPending
+	}
+
+	profiles := models.MentorProfiles{}
+	if err := tx.Where("status = ?", status).Order("created_at").All(&profiles); err != nil {
+		return errors.WithStack(err)
+	}
+	emails, err := mentorProfileEmails(tx, profiles)
+	if err != nil {
+		return err
+	}
+	rows := make([]mentorProfileRow, len(profiles))
+	for i, p := range profiles {
+		rows[i] = mentorProfileRow{Profile: p, Email: emails[p.UserID]}
+	}
+
+	var counted []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := tx.RawQuery("SELECT status, COUNT(*) as count FROM mentor_profiles GROUP BY status").All(&counted); err != nil {
+		return errors.WithStack(err)
+	}
+	counts := map[string]int{}
+	for _, s := range models.MentorStatuses {
+		counts[s] = 0
+	}
+	for _, row := range counted {
+		counts[row.Status] = row.Count
+	}
+
+	c.Set("profiles", rows)
+	c.Set("mentorStatus", status)
+	c.Set("statusCounts", counts)
+	c.Set("mentorStatuses", models.MentorStatuses)
+	c.Set("statusLabel", models.MentorStatusLabel)
+	return c.Render(http.StatusOK, r.HTML("admin/mentoring/index.plush.html"))
+}
+
+// AdminMentorProfileStatus approves or rejects a mentoring profile
+func AdminMentorProfileStatus(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	profile := &models.MentorProfile{}
+	if err := tx.Find(profile, c.Param("profile_id")); err != nil {
+		c.Flash().Add("danger", "Mentoring profile not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/mentoring")
+	}
+	status := c.Param("Status")
+	if status != models.MentorApproved && status != models.MentorRejected {
+		c.Flash().Add("danger", "Choose to approve or reject the profile.")
+		return c.Redirect(http.StatusSeeOther, "/admin/mentoring")
+	}
+
+	now := time.Now()
+	previous := profile.Status
+	profile.Status = status
+	profile.ReviewedAt = &now
+	if err := tx.UpdateColumns(profile, "status", "reviewed_at", "updated_at"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.UserAction(c, user.Email, "mentor_profile_reviewed", "Reviewed mentoring profile", logging.Fields{
+		"mentor_profile_id": profile.ID.String(),
+		"from":              previous,
+		"to":                status,
+	})
+	if status == models.MentorApproved {
+		c.Flash().Add("success", fmt.Sprintf("Approved %s as a %s.", profile.DisplayName, strings.ToLower(profile.RoleLabel())))
+	} else {
+		c.Flash().Add("success", fmt.Sprintf("Rejected %s's profile.", profile.DisplayName))
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/mentoring?status=%s", previous)
+}
+
+// AdminMentoringMatches suggests mentors for the approved mentees who are
+// waiting for one, and tracks the introductions already made
+func AdminMentoringMatches(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	profiles := models.MentorProfiles{}
+	if err := tx.Order("created_at").All(&profiles); err != nil {
+		return errors.WithStack(err)
+	}
+	introductions := models.MentorIntroductions{}
+	if err := tx.Order("created_at desc").All(&introductions); err != nil {
+		return errors.WithStack(err)
+	}
+
+	byID := map[uuid.UUID]models.MentorProfile{}
+	mentors, mentees := models.MentorProfiles{}, models.MentorProfiles{}
+	for _, p := range profiles {
+		byID[p.ID] = p
+		if p.Status != models.MentorApproved {
+			continue
+		}
+		if p.IsMentor() {
+			mentors = append(mentors, p)
+		} else {
+			mentees = append(mentees, p)
+		}
+	}
+
+	rows := make([]mentorIntroductionRow, len(introductions))
+	for i, intro := range introductions {
+		rows[i] = mentorIntroductionRow{Introduction: intro, Mentor: byID[intro.MentorID], Mentee: byID[intro.MenteeID]}
+	}
+
+	c.Set("suggestions", models.SuggestMentorMatches(mentors, mentees, introductions, mentorSuggestionsPerMentee))
+	c.Set("introductions", rows)
+	c.Set("introductionStatuses", models.IntroductionStatuses)
+	c.Set("introductionStatusLabel", models.IntroductionStatusLabel)
+	c.Set("mentorCount", len(mentors))
+	c.Set("menteeCount", len(mentees))
+	return c.Render(http.StatusOK, r.HTML("admin/mentoring/matches.plush.html"))
+}
+
+// approvedMentorProfile loads an approved profile in role
+func approvedMentorProfile(tx *pop.Connection, id, role string) (*models.MentorProfile, error) {
+	profile := &models.MentorProfile{}
+	if err := tx.Where("status = ? AND role = ?", models.MentorApproved, role).Find(profile, id); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// AdminMentorIntroductionCreate introduces a mentor and mentee. Each is
+// emailed the other's profile and contact details in the background.
+func AdminMentorIntroductionCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	mentor, err := approvedMentorProfile(tx, c.Param("MentorID"), models.MentorRoleMentor)
+	if err != nil {
+		c.Flash().Add("danger", "That mentor isn't approved.")
+		return c.Redirect(http.StatusSeeOther, "/admin/mentoring/matches")
+	}
+	mentee, err := approvedMentorProfile(tx, c.Param("MenteeID"), models.MentorRoleMentee)
+	if err != nil {
+		c.Flash().Add("danger", "That mentee isn't approved.")
+		return c.Redirect(http.StatusSeeOther, "/admin/mentoring/matches")
+	}
+
+	exists, err := tx.Where("mentor_id = ? AND mentee_id = ?", mentor.ID, mentee.ID).Exists(&models.MentorIntroduction{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if exists {
+		c.Flash().Add("warning", fmt.Sprintf("%s and %s have already been introduced.", mentor.DisplayName, mentee.DisplayName))
+		return c.Redirect(http.StatusSeeOther, "/admin/mentoring/matches")
+	}
+
+	intro := &models.MentorIntroduction{
+		MentorID:     mentor.ID,
+		MenteeID:     mentee.ID,
+		Status:       models.IntroductionIntroduced,
+		IntroducedBy: &user.ID,
+	}
+	if err := tx.Create(intro); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, role := range []string{models.MentorRoleMentor, models.MentorRoleMentee} {
+		queueJob(tx, jobMentorIntroduction, worker.Args{"introduction_id": intro.ID.String(), "recipient": role})
+	}
+
+	logging.UserAction(c, user.Email, "mentor_introduction_created", "Introduced mentor and mentee", logging.Fields{
+		"mentor_introduction_id": intro.ID.String(),
+		"mentor_profile_id":      mentor.ID.String(),
+		"mentee_profile_id":      mentee.ID.String(),
+	})
+	c.Flash().Add("success", fmt.Sprintf("Introduced %s to %s. They'll each get an email with the other's details.", mentee.DisplayName, mentor.DisplayName))
+	return c.Redirect(http.StatusSeeOther, "/admin/mentoring/matches")
+}
+
+// AdminMentorIntroductionStatus records staff's follow-up on an
+// introduction
+func AdminMentorIntroductionStatus(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	intro := &models.MentorIntroduction{}
+	if err := tx.Find(intro, c.Param("introduction_id")); err != nil {
+		c.Flash().Add("danger", "Introduction not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/mentoring/matches")
+	}
+
+	previous := intro.Status
+	intro.SetStatus(c.Param("Status"), time.Now())
+	intro.Notes = stringPointer(strings.TrimSpace(c.Param("Notes")))
+	verrs, err := tx.ValidateAndUpdate(intro)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", "Choose a status for the introduction.")
+		return c.Redirect(http.StatusSeeOther, "/admin/mentoring/matches")
+	}
+
+	logging.UserAction(c, user.Email, "mentor_introduction_updated", "Updated mentoring introduction", logging.Fields{
+		"mentor_introduction_id": intro.ID.String(),
+		"from":                   previous,
+		"to":                     intro.Status,
+	})
+	c.Flash().Add("success", "Introduction updated.")
+	return c.Redirect(http.StatusSeeOther, "/admin/mentoring/matches")
+}
+
+// mentorIntroductionEmail is the introduction email for the pair member in
+// recipient's role, describing the other member
+func mentorIntroductionEmail(recipient string, self, other models.MentorProfile, otherEmail string) services.MentorIntroductionData {
+	return services.MentorIntroductionData{
+		Name:             self.DisplayName,
+		Role:             recipient,
+		OtherName:        other.DisplayName,
+		OtherEmail:       otherEmail,
+		OtherTrade:       other.Trade,
+		OtherRegion:      other.Region,
+		OtherSkills:      strings.Join(other.SkillList(), ", "),
+		OtherBio:         other.Bio,
+		OrganizationName: organization().Name,
+	}
+}
+
+// sendMentorIntroductionJob emails one member of an introduced pair
+func sendMentorIntroductionJob(args worker.Args) error {
+	intro := &models.MentorIntroduction{}
+	if err := models.DB.Find(intro, jobArg(args, "introduction_id")); err != nil {
+		return errors.Wrap(err, "finding introduction")
+	}
+	mentor, mentee := &models.MentorProfile{}, &models.MentorProfile{}
+	if err := models.DB.Find(mentor, intro.MentorID); err != nil {
+		return errors.Wrap(err, "finding mentor")
+	}
+	if err := models.DB.Find(mentee, intro.MenteeID); err != nil {
+		return errors.Wrap(err, "finding mentee")
+	}
+	emails, err := mentorProfileEmails(models.DB, models.MentorProfiles{*mentor, *mentee})
+	if err != nil {
+		return err
+	}
+
+	self, other := *mentee, *mentor
+	if jobArg(args, "recipient") == models.MentorRoleMentor {
+		self, other = *mentor, *mentee
+	}
+	data := mentorIntroductionEmail(self.Role, self, other, emails[other.UserID])
+	return services.NewEmailService().SendMentorIntroduction(emails[self.UserID], data)
+}
@@ -0,0 +1,72 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_MentorIntroductionEmail(t *testing.T) {
+	req := require.New(t)
+
+	mentor := models.MentorProfile{DisplayName: "Dana Cole", Role: models.MentorRoleMentor, Trade: "Electrical", Region: "Austin, TX", Skills: "Conduit, conduit, Code prep", Bio: "Master electrician."}
+	mentee := models.MentorProfile{DisplayName: "Sam Rivera", Role: models.MentorRoleMentee, Trade: "Electrical", Region: "Austin, TX"}
+
+	data := mentorIntroductionEmail(mentee.Role, mentee, mentor, "dana@example.com")
+	req.Equal("Sam Rivera", data.Name)
+	req.Equal(models.MentorRoleMentee, data.Role)
+	req.Equal("Dana Cole", data.OtherName)
+	req.Equal("dana@example.com", data.OtherEmail)
+	req.Equal("Conduit, Code prep", data.OtherSkills)
+	req.Equal("Master electrician.", data.OtherBio)
+}
+
+func Test_MentoringAdminTemplatesRendering(t *testing.T) {
+	req := require.New(t)
+
+	mentor := models.MentorProfile{ID: uuid.Must(uuid.NewV4()), DisplayName: "Dana Cole", Role: models.MentorRoleMentor, Trade: "Electrical", Region: "Austin, TX", Status: models.MentorApproved}
+	mentee := models.MentorProfile{ID: uuid.Must(uuid.NewV4()), DisplayName: "Sam Rivera", Role: models.MentorRoleMentee, Trade: "Electrical", Region: "Austin, TX", Skills: "Wiring", Status: models.MentorPending, UpdatedAt: time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)}
+	intro := models.MentorIntroduction{ID: uuid.Must(uuid.NewV4()), MentorID: mentor.ID, MenteeID: mentee.ID, Status: models.IntroductionMeeting}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/admin-mentoring-test", func(c buffalo.Context) error {
+		c.Set("profiles", []mentorProfileRow{{Profile: mentee, Email: "sam@example.com"}})
+		c.Set("mentorStatus", models.MentorPending)
+		c.Set("statusCounts", map[string]int{models.MentorPending: 1, models.MentorApproved: 4, models.MentorRejected: 0})
+		c.Set("mentorStatuses", models.MentorStatuses)
+		c.Set("statusLabel", models.MentorStatusLabel)
+		return c.Render(http.StatusOK, r.HTML("admin/mentoring/index.plush.html"))
+	})
+	app.GET("/admin-mentoring-matches-test", func(c buffalo.Context) error {
+		c.Set("suggestions", []models.MentorMatch{models.MatchMentor(mentor, mentee)})
+		c.Set("introductions", []mentorIntroductionRow{{Introduction: intro, Mentor: mentor, Mentee: mentee}})
+		c.Set("introductionStatuses", models.IntroductionStatuses)
+		c.Set("introductionStatusLabel", models.IntroductionStatusLabel)
+		c.Set("mentorCount", 1)
+		c.Set("menteeCount", 1)
+		return c.Render(http.StatusOK, r.HTML("admin/mentoring/matches.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/admin-mentoring-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "<strong>Awaiting review (1)</strong>")
+	req.Contains(w.Body.String(), `<a href="/admin/mentoring?status=approved">Approved</a> (4)`)
+	req.Contains(w.Body.String(), `action="/admin/mentoring/profiles/`+mentee.ID.String()+`/status"`)
+	req.Contains(w.Body.String(), "sam@example.com")
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/admin-mentoring-matches-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `name="MentorID" value="`+mentor.ID.String()+`"`)
+	req.Contains(w.Body.String(), "Same trade (Electrical)")
+	req.Contains(w.Body.String(), `action="/admin/mentoring/introductions/`+intro.ID.String()+`/status"`)
+	req.Contains(w.Body.String(), `<option value="meeting" selected>Meeting</option>`)
+}
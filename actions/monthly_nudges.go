@@ -0,0 +1,126 @@
+package actions
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// monthlyNudgeURL is the donate page with the donor's suggested monthly
+// gift filled in
+func monthlyNudgeURL(amount float64) string {
+	query := url.Values{}
+	query.Set("amount", strconv.FormatFloat(amount, 'f', -1, 64))
+	query.Set("donation_type", string(models.DonationTypeMonthly))
+	return siteURL() + "/donate?" + query.Encode()
+}
+
+// SendMonthlyNudges emails donors who made several one-time gifts over the
+// past year asking them to give monthly, skipping donors flagged "do not
+// solicit" or on the suppression list. It's run weekly from cron through
+// the donors:monthly_nudges task and returns how many nudges it sent.
+func SendMonthlyNudges(tx *pop.Connection, now time.Time) (int, error) {
+	candidates, err := models.FindMonthlyNudgeCandidates(tx, now)
+	if err != nil {
+		return 0, err
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	emails := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		emails[i] = candidate.Email
+	}
+	allowed, err := models.ExcludeDoNotSolicit(tx, emails)
+	if err != nil {
+		return 0, err
+	}
+	solicit := map[string]bool{}
+	for _, email := range allowed {
+		solicit[email] = true
+	}
+
+	org := organization()
+	emailService := services.NewEmailService()
+	sent := 0
+	for _, candidate := range candidates {
+		if !solicit[candidate.Email] || contactSuppressed(tx, models.SuppressEmail, candidate.Email, "Monthly giving nudge") {
+			continue
+		}
+		amount := candidate.SuggestedMonthlyAmount()
+		name, _ := splitName(candidate.Name)
+		err := emailService.SendMonthlyNudge(candidate.Email, services.MonthlyNudgeData{
+			Name:             name,
+			Gifts:            candidate.Gifts,
+			SuggestedAmount:  amount,
+			DonateURL:        monthlyNudgeURL(amount),
+			ContactEmail:     org.Email,
+			OrganizationName: org.Name,
+		})
+		if err != nil {
+			logging.Error("monthly_nudge_failed", err, logging.Fields{
+				"gifts": candidate.Gifts,
+			})
+			continue
+		}
+		nudge := &models.MonthlyNudge{
+			DonorEmail:      candidate.Email,
+			GiftCount:       candidate.Gifts,
+			SuggestedAmount: amount,
+			SentAt:          now,
+		}
+		if err := tx.Create(nudge); err != nil {
+			return sent, errors.WithStack(err)
+		}
+		sent++
+	}
+
+	logging.Audit("monthly_nudges_sent", logging.Fields{
+		"candidates": len(candidates),
+		"recipients": sent,
+	})
+	return sent, nil
+}
+
+// recordMonthlyConversion credits a new monthly gift to the nudge that
+// asked for it. A failure is only logged; the gift itself has gone through.
+func recordMonthlyConversion(tx *pop.Connection, donation *models.Donation) {
+	converted, err := models.RecordMonthlyConversion(tx, donation, time.Now())
+	if err != nil {
+		logging.Error("monthly_conversion_record_failed", err, logging.Fields{
+			"donation_id": donation.ID.String(),
+		})
+		return
+	}
+	if converted {
+		logging.Audit("monthly_nudge_converted", logging.Fields{
+			"donation_id":     donation.ID.String(),
+			"donation_amount": donation.Amount,
+		})
+	}
+}
+
+// prefillDonateForm fills in the gift amount and frequency from links like
+// the monthly nudge's /donate?amount=15&donation_type=monthly. They only
+// prefill the form, which carries them on to the payment step. Values that
+// wouldn't be accepted on submit are ignored.
+func prefillDonateForm(c buffalo.Context) {
+	if donationType := models.NormalizeDonationType(c.Param("donation_type")); donationType.Valid() {
+		c.Set("donationType", donationType.String())
+	}
+	if c.Param("amount") == "" {
+		return
+	}
+	if amount, err := parseDonationAmount(c.Param("amount"), getCurrency()); err == nil {
+		c.Set("amount", strconv.FormatFloat(amount, 'f', -1, 64))
+	}
+}
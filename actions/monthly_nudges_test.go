@@ -0,0 +1,43 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MonthlyNudgeURL(t *testing.T) {
+	require.Equal(t, siteURL()+"/donate?amount=15&donation_type=monthly", monthlyNudgeURL(15))
+	require.Equal(t, siteURL()+"/donate?amount=12.5&donation_type=monthly", monthlyNudgeURL(12.5))
+}
+
+func Test_PrefillDonateForm(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/prefill-test", func(c buffalo.Context) error {
+		c.Set("amount", "")
+		c.Set("donationType", "one-time")
+		prefillDonateForm(c)
+		if c.Session().Get("donation_amount") != nil || c.Session().Get("donation_type") != nil {
+			return c.Error(http.StatusInternalServerError, fmt.Errorf("prefill wrote to the session"))
+		}
+		return c.Render(http.StatusOK, r.String("<%= amount %>|<%= donationType %>"))
+	})
+
+	for query, want := range map[string]string{
+		"?amount=15&donation_type=monthly": "15|monthly",
+		"?amount=abc&donation_type=weekly": "|one-time",
+		"?amount=0":                        "|one-time",
+		"":                                 "|one-time",
+	} {
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/prefill-test"+query, nil))
+		req.Equal(http.StatusOK, res.Code, res.Body.String())
+		req.Equal(want, res.Body.String(), query)
+	}
+}
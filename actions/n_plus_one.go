@@ -0,0 +1,118 @@
+package actions
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gobuffalo/buffalo"
+	pp "github.com/gobuffalo/buffalo-pop/v3/pop"
+	"github.com/gobuffalo/pop/v6"
+	poplogging "github.com/gobuffalo/pop/v6/logging"
+
+	"avrnpo.org/pkg/logging"
+)
+
+// nPlusOneThreshold is how many times one request may run the same SELECT
+// before it's reported. Loading something per row of a list shows up as the
+// same statement run once for each row.
+const nPlusOneThreshold = 5
+
+// queryCounter counts the SELECT statements run in each request's
+// transaction, by transaction ID
+type queryCounter struct {
+	mu   sync.Mutex
+	byTx map[int]map[string]int
+}
+
+func newQueryCounter() *queryCounter {
+	return &queryCounter{byTx: map[int]map[string]int{}}
+}
+
+// start begins counting for a transaction
+func (q *queryCounter) start(txID int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.byTx[txID] = map[string]int{}
+}
+
+// record counts a statement if its transaction is being watched
+func (q *queryCounter) record(txID int, statement string) {
+	if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(statement)), "SELECT") {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if counts, ok := q.byTx[txID]; ok {
+		counts[statement]++
+	}
+}
+
+// finish stops counting for a transaction and returns the statements it
+// ran at least threshold times
+func (q *queryCounter) finish(txID int, threshold int) []repeatedQuery {
+	q.mu.Lock()
+	counts := q.byTx[txID]
+	delete(q.byTx, txID)
+	q.mu.Unlock()
+
+	repeated := []repeatedQuery{}
+	for statement, n := range counts {
+		if n >= threshold {
+			repeated = append(repeated, repeatedQuery{Statement: statement, Count: n})
+		}
+	}
+	sort.Slice(repeated, func(i, j int) bool { return repeated[i].Count > repeated[j].Count })
+	return repeated
+}
+
+// repeatedQuery is a statement a request ran over and over
+type repeatedQuery struct {
+	Statement string
+	Count     int
+}
+
+// logger wraps pop's transaction logger so statements are counted as well
+// as logged
+func (q *queryCounter) logger(next func(poplogging.Level, interface{}, string, ...interface{})) func(poplogging.Level, interface{}, string, ...interface{}) {
+	return func(lvl poplogging.Level, conn interface{}, s string, args ...interface{}) {
+		if lvl == poplogging.SQL {
+			if c, ok := conn.(*pop.Connection); ok && c.TX != nil {
+				q.record(c.TX.ID, s)
+			}
+		}
+		next(lvl, conn, s, args...)
+	}
+}
+
+// DetectNPlusOne logs requests that run the same query many times, which
+// usually means something is being loaded per row of a list instead of with
+// one of the models' ...ByID helpers. It's only used in development, after
+// popmw.Transaction so the request's queries share a transaction to count.
+func DetectNPlusOne(app *buffalo.App) buffalo.MiddlewareFunc {
+	counter := newQueryCounter()
+	var install sync.Once
+	return func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			// popmw sets its logger when the app starts, so ours goes on top
+			// of it at the first request
+			install.Do(func() { pop.SetTxLogger(counter.logger(pp.TxLogger(app))) })
+
+			tx, ok := c.Value("tx").(*pop.Connection)
+			if !ok || tx.TX == nil {
+				return next(c)
+			}
+			counter.start(tx.TX.ID)
+			err := next(c)
+			for _, repeated := range counter.finish(tx.TX.ID, nPlusOneThreshold) {
+				logging.Warn("n_plus_one_query", logging.Fields{
+					"method": c.Request().Method,
+					"path":   c.Request().URL.Path,
+					"count":  repeated.Count,
+					"query":  repeated.Statement,
+				})
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryCounter(t *testing.T) {
+	counter := newQueryCounter()
+	author := "SELECT users.id FROM users AS users WHERE users.id = $1 LIMIT 1"
+
+	counter.start(1)
+	counter.record(1, "SELECT posts.id FROM posts AS posts LIMIT 5")
+	for i := 0; i < 5; i++ {
+		counter.record(1, author)
+		counter.record(1, "UPDATE posts SET published = $1 WHERE id = $2")
+	}
+	// Another request's transaction, and one that isn't watched
+	counter.start(2)
+	counter.record(2, author)
+	counter.record(3, author)
+
+	repeated := counter.finish(1, nPlusOneThreshold)
+	require.Len(t, repeated, 1)
+	assert.Equal(t, author, repeated[0].Statement)
+	assert.Equal(t, 5, repeated[0].Count)
+
+	assert.Empty(t, counter.finish(2, nPlusOneThreshold))
+	assert.Empty(t, counter.finish(1, nPlusOneThreshold), "a finished transaction is no longer counted")
+}
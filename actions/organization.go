@@ -0,0 +1,122 @@
+package actions
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// organizationTTL is how long the organization profile is kept in memory
+// before it's read again, so other instances pick up a change made on one.
+// It's stretched while a campaign is live, see cacheTTL.
+const organizationTTL = 5 * time.Minute
+
+// organizationCache holds the organization profile for receipts, emails and
+// the site layout, which would otherwise read it for every page and message
+var organizationCache struct {
+	mu       sync.RWMutex
+	profile  models.OrganizationProfile
+	loadedAt time.Time
+}
+
+// organization is the organization profile. When it can't be read the
+// default is used, so receipts and pages still go out.
+func organization() models.OrganizationProfile {
+	ttl := cacheTTL(organizationTTL)
+	organizationCache.mu.RLock()
+	if !organizationCache.loadedAt.IsZero() && time.Since(organizationCache.loadedAt) < ttl {
+		defer organizationCache.mu.RUnlock()
+		return organizationCache.profile
+	}
+	organizationCache.mu.RUnlock()
+
+	profile, err := models.LoadOrganizationProfile(models.DB)
+	if err != nil {
+		logging.Error("organization_profile_load_failed", err)
+	}
+	setOrganization(profile)
+	return profile
+}
+
+// setOrganization replaces the profile held in memory
+func setOrganization(profile models.OrganizationProfile) {
+	organizationCache.mu.Lock()
+	defer organizationCache.mu.Unlock()
+	organizationCache.profile = profile
+	organizationCache.loadedAt = time.Now()
+}
+
+// organizationStructuredData is the organization's JSON-LD for the site
+// layout
+func organizationStructuredData() string {
+	return organization().StructuredData(siteURL())
+}
+
+// AdminOrganization shows the organization profile form
+func AdminOrganization(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	profile, err := models.LoadOrganizationProfile(tx)
+	if err != nil {
+		return err
+	}
+	c.Set("profile", profile)
+	return c.Render(http.StatusOK, r.HTML("admin/organization.plush.html"))
+}
+
+// AdminOrganizationUpdate saves the organization profile. Receipts, emails
+// and the site use the change straight away on this instance, and within
+// organizationTTL on others.
+func AdminOrganizationUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	profile, err := models.LoadOrganizationProfile(tx)
+	if err != nil {
+		return err
+	}
+	for field, value := range map[*string]string{
+		&profile.Name:         "Name",
+		&profile.EIN:          "EIN",
+		&profile.AddressLine1: "AddressLine1",
+		&profile.AddressLine2: "AddressLine2",
+		&profile.City:         "City",
+		&profile.State:        "State",
+		&profile.Zip:          "Zip",
+		&profile.Phone:        "Phone",
+		&profile.Email:        "Email",
+		&profile.FacebookURL:  "FacebookURL",
+		&profile.XURL:         "XURL",
+		&profile.DiscordURL:   "DiscordURL",
+		&profile.SpotifyURL:   "SpotifyURL",
+	} {
+		*field = strings.TrimSpace(c.Param(value))
+	}
+	profile.UpdatedBy = &currentUser.ID
+
+	verrs, err := tx.ValidateAndSave(&profile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Set("profile", profile)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/organization.plush.html"))
+	}
+	setOrganization(profile)
+
+	logging.UserAction(c, currentUser.Email, "organization_profile_updated", "Updated the organization profile", logging.Fields{
+		"name": profile.Name,
+		"ein":  profile.EIN,
+	})
+	c.Flash().Add("success", "Organization profile saved.")
+	return c.Redirect(http.StatusSeeOther, "/admin/organization")
+}
@@ -0,0 +1,44 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_OrganizationTemplatesRendering(t *testing.T) {
+	req := require.New(t)
+
+	profile := models.DefaultOrganizationProfile()
+	profile.Name = "Veterans Rebuilding Test"
+	profile.EIN = "12-3456789"
+	profile.Phone = "512-555-0100"
+	profile.City = "Austin"
+	profile.DiscordURL = ""
+	setOrganization(profile)
+	defer setOrganization(models.DefaultOrganizationProfile())
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/admin-organization-test", func(c buffalo.Context) error {
+		c.Set("profile", profile)
+		return c.Render(http.StatusOK, r.HTML("admin/organization.plush.html"))
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/admin-organization-test", nil))
+	body := res.Body.String()
+	req.Equal(http.StatusOK, res.Code, body)
+	req.Contains(body, `name="EIN" value="12-3456789"`)
+	// the layout and footer come from the profile too
+	req.Contains(body, "<title>\n            Veterans Rebuilding Test")
+	req.Contains(body, `"taxID":"12-3456789"`)
+	req.Contains(body, `class="site-footer`)
+	req.Contains(body, `href="tel:512-555-0100"`)
+	req.Contains(body, "https://x.com/avrnpo")
+	req.NotContains(body, "discord.com/invite")
+}
@@ -3,15 +3,19 @@ package actions
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"avrnpo.org/models"
+	"avrnpo.org/pkg/helpers"
 	"avrnpo.org/services"
 	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
 )
@@ -23,30 +27,19 @@ var presetAmounts = []string{"25", "50", "100", "250", "500", "1000"}
 
 // DonateContextOptions holds options for setting up donation form context
 type DonateContextOptions struct {
-	Amount               string
-	DonationType         string
-	FirstName            string
-	LastName             string
-	DonorEmail           string
-	DonorPhone           string
-	AddressLine1         string
-	AddressLine2         string
-	City                 string
-	State                string
-	Zip                  string
-	Comments             string
-	Errors               *validate.Errors
-	HasAnyErrors         bool
-	HasAmountError       bool
-	HasFirstNameError    bool
-	HasLastNameError     bool
-	HasDonorEmailError   bool
-	HasDonorPhoneError   bool
-	HasAddressLine1Error bool
-	HasCityError         bool
-	HasStateError        bool
-	HasZipError          bool
-	HasCommentsError     bool
+	Amount       string
+	DonationType string
+	FirstName    string
+	LastName     string
+	DonorEmail   string
+	DonorPhone   string
+	AddressLine1 string
+	AddressLine2 string
+	City         string
+	State        string
+	Zip          string
+	Comments     string
+	Errors       *validate.Errors
 }
 
 // generateSecureToken creates a cryptographically secure CSRF token
@@ -63,32 +56,25 @@ func generateSecureToken() string {
 func setupDonateFormContext(c buffalo.Context) {
 	// Page metadata
 	c.Set("title", "Make a Donation")
-	c.Set("description", "Support American Veterans Rebuilding with your tax-deductible donation")
+	c.Set("description", "Support "+organization().Name+" with your tax-deductible donation")
 	c.Set("current_path", c.Request().URL.Path)
 
 	// Form model and errors
 	donation := &DonationRequest{}
 	c.Set("donation", donation)
 	c.Set("errors", nil)
-	c.Set("hasAnyErrors", false)
-	c.Set("hasCommentsError", false)
-	c.Set("hasAmountError", false)
-	c.Set("hasFirstNameError", false)
-	c.Set("hasLastNameError", false)
-	c.Set("hasDonorEmailError", false)
-	c.Set("hasDonorPhoneError", false)
-	c.Set("hasAddressLine1Error", false)
-	c.Set("hasCityError", false)
-	c.Set("hasStateError", false)
-	c.Set("hasZipError", false)
 	c.Set("comments", "")
 
 	// Amount and donation type
 	c.Set("amount", "")
 	c.Set("customAmount", "")
 	c.Set("donationType", "one-time")
+	c.Set("installments", c.Param("installments"))
+	c.Set("frequency", c.Param("frequency"))
 	c.Set("presets", []string{"25", "50", "100", "250", "500", "1000"})
 	c.Set("presetAmounts", []string{"25", "50", "100", "250", "500", "1000"})
+	c.Set("cryptoEnabled", services.CryptoEnabled())
+	c.Set("customFields", donationFormFields(c, ""))
 
 	// Donor information fields
 	c.Set("firstName", "")
@@ -101,10 +87,6 @@ func setupDonateFormContext(c buffalo.Context) {
 	c.Set("state", "")
 	c.Set("zip", "")
 
-	// Session defaults
-	c.Session().Set("donation_amount", "")
-	c.Session().Set("donation_type", "one-time")
-
 	// Ensure CSRF token - set a dummy token for testing
 	if c.Value("authenticity_token") == nil {
 		c.Set("authenticity_token", "test-csrf-token-for-debugging")
@@ -119,6 +101,12 @@ func ensureDonateContext(c buffalo.Context) {
 	if c.Value("donationType") == nil {
 		c.Set("donationType", "one-time")
 	}
+	if c.Value("installments") == nil {
+		c.Set("installments", c.Param("installments"))
+	}
+	if c.Value("frequency") == nil {
+		c.Set("frequency", c.Param("frequency"))
+	}
 
 	// Ensure the CSRF token identifier exists in the template context.
 	// Buffalo's CSRF middleware should have set authenticity_token.
@@ -132,7 +120,7 @@ func ensureDonateContext(c buffalo.Context) {
 func setDonateContext(c buffalo.Context, opts *DonateContextOptions) {
 	// Page metadata
 	c.Set("title", "Make a Donation")
-	c.Set("description", "Support American Veterans Rebuilding with your tax-deductible donation")
+	c.Set("description", "Support "+organization().Name+" with your tax-deductible donation")
 	c.Set("current_path", c.Request().URL.Path)
 
 	// Form model
@@ -152,10 +140,14 @@ func setDonateContext(c buffalo.Context, opts *DonateContextOptions) {
 		donationType = opts.DonationType
 	}
 	c.Set("donationType", donationType)
+	c.Set("installments", c.Param("installments"))
+	c.Set("frequency", c.Param("frequency"))
 
 	// Preset amounts
 	c.Set("presets", presetAmounts)
 	c.Set("presetAmounts", presetAmounts)
+	c.Set("cryptoEnabled", services.CryptoEnabled())
+	c.Set("customFields", donationFormFields(c, ""))
 
 	// Donor information fields - use provided values or defaults
 	firstName := ""
@@ -221,43 +213,57 @@ func setDonateContext(c buffalo.Context, opts *DonateContextOptions) {
 	// Error handling
 	if opts != nil && opts.Errors != nil {
 		c.Set("errors", opts.Errors)
-		c.Set("hasAnyErrors", opts.HasAnyErrors)
-		c.Set("hasAmountError", opts.HasAmountError)
-		c.Set("hasFirstNameError", opts.HasFirstNameError)
-		c.Set("hasLastNameError", opts.HasLastNameError)
-		c.Set("hasDonorEmailError", opts.HasDonorEmailError)
-		c.Set("hasDonorPhoneError", opts.HasDonorPhoneError)
-		c.Set("hasAddressLine1Error", opts.HasAddressLine1Error)
-		c.Set("hasCityError", opts.HasCityError)
-		c.Set("hasStateError", opts.HasStateError)
-		c.Set("hasZipError", opts.HasZipError)
-		c.Set("hasCommentsError", opts.HasCommentsError)
 	} else {
-		// Default error states
 		c.Set("errors", nil)
-		c.Set("hasAnyErrors", false)
-		c.Set("hasCommentsError", false)
-		c.Set("hasAmountError", false)
-		c.Set("hasFirstNameError", false)
-		c.Set("hasLastNameError", false)
-		c.Set("hasDonorEmailError", false)
-		c.Set("hasDonorPhoneError", false)
-		c.Set("hasAddressLine1Error", false)
-		c.Set("hasCityError", false)
-		c.Set("hasStateError", false)
-		c.Set("hasZipError", false)
 	}
 
 	// CSRF token should be provided by Buffalo's CSRF middleware
 	// Don't override it if it's already set
 }
 
-// getDonateButtonText generates the appropriate button text based on amount and donation type
-func getDonateButtonText(amountStr, donationType string) string {
-	if donationType == "monthly" {
-		return fmt.Sprintf("Donate $%s Monthly", amountStr)
+// donateButtonLabel is the donate button's wording for one donation type,
+// with {amount} and {installments} filled in when an amount is chosen
+type donateButtonLabel struct {
+	WithAmount    string `json:"withAmount"`
+	WithoutAmount string `json:"withoutAmount"`
+}
+
+// donateButtonLabels is the wording for every donate button. The server
+// renders it through donateButtonText and the form script gets the same
+// labels from donateButtonLabelsJSON, so the two can't disagree.
+var donateButtonLabels = map[models.DonationType]donateButtonLabel{
+	models.DonationTypeOneTime:     {WithAmount: "Donate {amount} Now", WithoutAmount: "Donate Now"},
+	models.DonationTypeMonthly:     {WithAmount: "Donate {amount} {frequency}", WithoutAmount: "Donate {frequency}"},
+	models.DonationTypeInstallment: {WithAmount: "Pledge {amount} over {installments} Months", WithoutAmount: "Donate Now"},
+}
+
+// donateButtonText is the donate button's label for the chosen amount,
+// donation type, installment count and frequency. With no count or
+// frequency chosen it names the form's first option, which is the one the
+// select shows.
+func donateButtonText(amount, donationType, installments, frequency string) string {
+	label, ok := donateButtonLabels[models.NormalizeDonationType(donationType)]
+	if !ok {
+		label = donateButtonLabels[models.DonationTypeOneTime]
+	}
+	every := models.NormalizeDonationFrequency(frequency)
+	if !every.Valid() {
+		every = models.DonationFrequencies[0]
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+	if err != nil || value <= 0 {
+		return strings.ReplaceAll(label.WithoutAmount, "{frequency}", every.Adverb())
 	}
-	return fmt.Sprintf("Donate $%s Now", amountStr)
+	if installments == "" {
+		installments = installmentOptions()[0]
+	}
+	return strings.NewReplacer("{amount}", helpers.Money(value), "{installments}", installments, "{frequency}", every.Adverb()).Replace(label.WithAmount)
+}
+
+// donateButtonLabelsJSON is donateButtonLabels for the form script
+func donateButtonLabelsJSON() string {
+	labels, _ := json.Marshal(donateButtonLabels)
+	return string(labels)
 }
 
 // TeamHandler shows the team page
@@ -270,9 +276,17 @@ func ProjectsHandler(c buffalo.Context) error {
 	return c.Render(http.StatusOK, r.HTML("pages/projects.plush.html"))
 }
 
+// setContactFormContext exposes the topic choices to the contact form
+func setContactFormContext(c buffalo.Context) {
+	c.Set("contactTopics", models.ContactTopics)
+	c.Set("contactTopicLabel", models.ContactTopicLabel)
+}
+
 // ContactHandler shows the contact form
 // ContactHandler handles both GET (show form) and POST (process form) for the contact page
 func ContactHandler(c buffalo.Context) error {
+	setContactFormContext(c)
+
 	// Handle GET request - show the contact form
 	if c.Request().Method == "GET" {
 		// Set form timestamp for bot protection
@@ -293,6 +307,7 @@ func ContactHandler(c buffalo.Context) error {
 	email := c.Value("email").(string)
 	subject := c.Value("subject").(string)
 	message := c.Value("message").(string)
+	topic := c.Value("topic").(string)
 
 	// Prepare contact form data
 	contactData := services.ContactFormData{
@@ -300,6 +315,8 @@ func ContactHandler(c buffalo.Context) error {
 		Email:          email,
 		Subject:        subject,
 		Message:        message,
+		Topic:          topic,
+		TopicLabel:     models.ContactTopicLabel(topic),
 		SubmissionDate: time.Now(),
 	}
 
@@ -315,6 +332,16 @@ func ContactHandler(c buffalo.Context) error {
 
 	// Success
 	c.Logger().Infof("CONTACT_FORM_EMAIL_SUCCESS - Contact form submission from %s (%s): %s", name, email, subject)
+	if tx, ok := c.Value("tx").(*pop.Connection); ok {
+		stored := &models.ContactMessage{Name: name, Email: email, Subject: subject, Message: message, Topic: topic}
+		if err := tx.Create(stored); err != nil {
+			c.Logger().Errorf("CONTACT_FORM_STORE_FAILED - Failed to store contact message from %s (%s): %v", name, email, err)
+		} else {
+			publishAPIEvent(tx, models.APIEventContactMessageCreated, apiContactMessageFrom(*stored))
+		}
+		recordCommunication(tx, email, models.CommunicationContactMessage, subject, &message, &stored.ID)
+	}
+	publishAdminActivity(activityContact, fmt.Sprintf("Message from %s: %s", name, subject), email, fmt.Sprintf("/admin/donors/%s", url.PathEscape(models.NormalizeDonorEmail(email))))
 	c.Flash().Add("success", "Thank you for your message! We'll get back to you soon.")
 	return c.Render(http.StatusOK, r.HTML("pages/contact.plush.html"))
 }
@@ -359,6 +386,7 @@ func DonateHandler(c buffalo.Context) error {
 	if c.Request().Method == "GET" {
 		// Set up all context variables for the donation form
 		setupDonateFormContext(c)
+		prefillDonateForm(c)
 
 		// Ensure CSRF token is available
 		c.Set("csrf", c.Value("authenticity_token"))
@@ -424,6 +452,14 @@ func DonateHandler(c buffalo.Context) error {
 	// Use Buffalo's validate.Errors for field-specific error collection
 	errors := validate.NewErrors()
 
+	bot := screenDonationBot(c, req, services.NewCaptchaVerifier(), time.Now())
+	if bot.Blocked {
+		return rejectDonationBot(c)
+	}
+	if bot.Message != "" {
+		errors.Add(captchaErrorKey, bot.Message)
+	}
+
 	if strings.TrimSpace(req.FirstName) == "" {
 		errors.Add("first_name", "First name is required")
 	}
@@ -451,63 +487,73 @@ func DonateHandler(c buffalo.Context) error {
 	}
 
 	// Validate donation type
-	if strings.TrimSpace(req.DonationType) == "" {
+	if req.DonationType == "" {
 		errors.Add("donation_type", "Please select a donation frequency")
-	} else if req.DonationType != "one-time" && req.DonationType != "monthly" {
+	} else if !req.DonationType.Valid() {
 		errors.Add("donation_type", "Invalid donation frequency selected")
 	}
 
-	// Determine donation amount - check both form and session
+	// Determine donation amount from the form
 	var amount float64
 	var err error
 
-	// First try to get amount from form submission
+	// First try to get amount from form submission. Without JavaScript the
+	// preset amounts are radio buttons instead of filling in custom_amount.
 	amountStr := strings.TrimSpace(req.CustomAmount)
-
-	// If no amount in form, check session (from preset button selections)
 	if amountStr == "" {
-		if sessionAmount := c.Session().Get("donation_amount"); sessionAmount != nil {
-			if s, ok := sessionAmount.(string); ok {
-				amountStr = s
-			}
-		}
-	}
-
-	// Normalize money strings like "$25.00" or "25,00" -> "25.00"
-	if amountStr != "" {
-		// Remove currency symbols and commas
-		amountStr = strings.ReplaceAll(amountStr, "$", "")
-		amountStr = strings.ReplaceAll(amountStr, ",", "")
+		amountStr = strings.TrimSpace(req.PresetAmount)
 	}
 
 	if strings.TrimSpace(amountStr) == "" {
 		errors.Add("amount", "Donation amount is required")
 	} else {
-		amount, err = strconv.ParseFloat(amountStr, 64)
-		if err != nil || amount <= 0 {
-			errors.Add("amount", "Donation amount must be greater than zero")
+		amount, err = parseDonationAmount(amountStr, getCurrency())
+		if err != nil {
+			errors.Add("amount", err.Error())
+		}
+	}
+
+	for field, msg := range giftRequestErrors(req) {
+		errors.Add(field, msg)
+	}
+	for field, msg := range paymentMethodErrors(req) {
+		errors.Add(field, msg)
+	}
+
+	customFields := donationFormFields(c, req.PartnerSlug)
+	customAnswers, customErrs := customFieldAnswers(customFields, customFieldValues(c, req))
+	for field, msg := range customErrs {
+		errors.Add(field, msg)
+	}
+
+	// Installment pledges split the entered amount into equal monthly payments
+	installmentCount := 0
+	if req.DonationType == models.DonationTypeInstallment {
+		n, ok := parseInstallmentCount(req.Installments)
+		if !ok {
+			errors.Add("donation_type", "Please choose how many monthly installments to split your pledge into")
+		} else if amount > 0 {
+			if msg := installmentMinimumError(amount, n, getCurrency()); msg != "" {
+				errors.Add("amount", msg)
+			}
 		}
+		installmentCount = n
+	}
+
+	// Open-ended recurring gifts can be charged monthly, quarterly or annually
+	frequency := models.NormalizeDonationFrequency(string(req.Frequency))
+	if req.DonationType == models.DonationTypeMonthly && !frequency.Valid() {
+		errors.Add("frequency", "Please choose how often you'd like to give")
 	}
+
 	// If there are any errors, render the form with errors and user input
 	if errors.HasAny() {
 		// Set error context for template
 		c.Set("errors", errors)
-		c.Set("hasAnyErrors", errors.HasAny())
-		c.Set("hasCommentsError", errors.Get("comments") != nil)
-		c.Set("hasAmountError", errors.Get("amount") != nil)
-		c.Set("hasFirstNameError", errors.Get("first_name") != nil)
-		c.Set("hasLastNameError", errors.Get("last_name") != nil)
-		c.Set("hasDonorEmailError", errors.Get("donor_email") != nil)
-		c.Set("hasDonorPhoneError", errors.Get("donor_phone") != nil)
-		c.Set("hasAddressLine1Error", errors.Get("address_line1") != nil)
-		c.Set("hasCityError", errors.Get("city") != nil)
-		c.Set("hasStateError", errors.Get("state") != nil)
-		c.Set("hasZipError", errors.Get("zip_code") != nil)
-		c.Set("hasDonationTypeError", errors.Get("donation_type") != nil)
 
 		// Preserve all submitted form data for template re-rendering
 		c.Set("amount", amountStr)
-		c.Set("donationType", req.DonationType)
+		c.Set("donationType", req.DonationType.String())
 		c.Set("firstName", req.FirstName)
 		c.Set("lastName", req.LastName)
 		c.Set("donorEmail", req.DonorEmail)
@@ -522,6 +568,21 @@ func DonateHandler(c buffalo.Context) error {
 		// Set up additional context variables
 		ensureDonateContext(c)
 		c.Set("presets", []string{"25", "50", "100", "250", "500", "1000"})
+		c.Set("customFields", customFields)
+
+		if req.GiftCard == "true" {
+			c.Set("giftCard", true)
+			c.Set("expiryMonths", giftCodeExpiryMonths())
+			return c.Render(http.StatusOK, r.HTML("pages/gift_cards.plush.html"))
+		}
+
+		// Keep partner gifts on the partner's branded page
+		if req.PartnerSlug != "" {
+			if partner, err := findActivePartner(c.Value("tx").(*pop.Connection), req.PartnerSlug); err == nil {
+				setPartnerContext(c, partner)
+				return c.Render(http.StatusOK, r.HTML("pages/give.plush.html"))
+			}
+		}
 
 		return c.Render(http.StatusOK, r.HTML("pages/donate.plush.html"))
 	}
@@ -559,10 +620,25 @@ func DonateHandler(c buffalo.Context) error {
 		Zip:          stringPointer(req.Zip),
 		Amount:       amount,
 		Currency:     getCurrency(),
-		DonationType: req.DonationType, // "one-time" or "monthly"
+		DonationType: req.DonationType,
+		Frequency:    models.FrequencyMonthly,
 		Status:       "pending",
 		Comments:     stringPointer(req.Comments),
 	}
+	donation.SetCustomAnswers(customAnswers)
+	applyPaymentMethod(req, donation, &helcimReq)
+
+	if req.DonationType == models.DonationTypeMonthly {
+		donation.Frequency = frequency
+	}
+	if installmentCount > 0 {
+		donation.InstallmentCount = installmentCount
+		donation.Amount, donation.PledgeTotal = splitPledge(amount, installmentCount)
+		amount = donation.Amount
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	attachPartner(c, tx, donation, req.PartnerSlug)
 
 	// Link to user account if logged in
 	if currentUser, ok := c.Value("current_user").(*models.User); ok && currentUser != nil {
@@ -580,7 +656,6 @@ func DonateHandler(c buffalo.Context) error {
 	}
 
 	// Save to database
-	tx := c.Value("tx").(*pop.Connection)
 	if err := tx.Create(donation); err != nil {
 		c.Flash().Add("error", "System error occurred. Please try again.")
 		c.Set("presetAmounts", []string{"25", "50", "100", "250", "500", "1000"})
@@ -588,6 +663,12 @@ func DonateHandler(c buffalo.Context) error {
 		ensureDonateContext(c)
 		return c.Render(http.StatusOK, r.HTML("pages/donate.plush.html"))
 	}
+	if err := createPendingGiftCode(tx, donation, req); err != nil {
+		c.Logger().Errorf("Failed to create gift code for donation %s: %v", donation.ID.String(), err)
+		c.Flash().Add("error", "System error occurred. Please try again.")
+		ensureDonateContext(c)
+		return c.Redirect(http.StatusSeeOther, "/gift-cards")
+	}
 
 	// Call Helcim API with verify request
 	helcimResponse, err := callHelcimVerifyAPI(helcimReq)
@@ -615,15 +696,7 @@ func DonateHandler(c buffalo.Context) error {
 		return c.Render(http.StatusOK, r.HTML("pages/donate.plush.html"))
 	}
 
-	// Store checkout data in session for the payment page
-	c.Session().Set("donation_id", donation.ID.String())
-	c.Session().Set("checkout_token", helcimResponse.CheckoutToken)
-	c.Session().Set("amount", fmt.Sprintf("%.2f", amount))
-	c.Session().Set("donor_name", donorName)
-	c.Session().Set("donation_type", donation.DonationType)
-	c.Session().Set("donor_email", donation.DonorEmail)
-
-	return c.Redirect(http.StatusSeeOther, "/donate/payment")
+	return c.Redirect(http.StatusSeeOther, donationPaymentPath(donation))
 }
 
 // DonatePaymentHandler shows the payment processing page after form submission
@@ -632,15 +705,10 @@ func DonatePaymentHandler(c buffalo.Context) error {
 	ensureDonateContext(c)
 	c.Set("csrf", c.Value("authenticity_token"))
 
-	// Get session data from the donation initialization
-	donationID := c.Session().Get("donation_id")
-	checkoutToken := c.Session().Get("checkout_token")
-	amount := c.Session().Get("amount")
-	donorName := c.Session().Get("donor_name")
-
-	// If no session data, redirect back to donate page
-	if donationID == nil || checkoutToken == nil {
-		c.Flash().Add("error", "Session expired. Please start your donation again.")
+	// The signed flow token names the pending donation this page is for
+	donationID, err := verifyDonationFlow(c.Param("flow"), time.Now())
+	if err != nil {
+		c.Flash().Add("error", "Your payment link has expired. Please start your donation again.")
 		return c.Redirect(http.StatusSeeOther, "/donate")
 	}
 
@@ -651,49 +719,73 @@ func DonatePaymentHandler(c buffalo.Context) error {
 		c.Flash().Add("error", "Donation record not found. Please start your donation again.")
 		return c.Redirect(http.StatusSeeOther, "/donate")
 	}
+	if donation.Status != "pending" || donation.CheckoutToken == "" {
+		c.Flash().Add("info", "This donation has already been processed. Thank you!")
+		return c.Redirect(http.StatusSeeOther, "/donate")
+	}
 
 	// Set template variables for payment processing
-	// Ensure amount is a safe, formatted string for template rendering
-	amountStr := ""
-	if amount != nil {
-		switch v := amount.(type) {
-		case string:
-			amountStr = v
-		case float64:
-			amountStr = fmt.Sprintf("%.2f", v)
-		case int:
-			amountStr = fmt.Sprintf("%d", v)
-		default:
-			amountStr = fmt.Sprintf("%v", v)
-		}
-	}
+	amountStr := fmt.Sprintf("%.2f", donation.Amount)
 
-	c.Set("donationId", donationID)
-	c.Set("checkoutToken", checkoutToken)
+	c.Set("donationId", donation.ID.String())
+	c.Set("flowToken", c.Param("flow"))
+	c.Set("checkoutToken", donation.CheckoutToken)
 	c.Set("amount", amountStr)
-	c.Set("donorName", donorName)
-	c.Set("donationType", donation.DonationType) // "one-time" or "recurring"
+	c.Set("donorName", donation.DonorName)
+	c.Set("donationType", donation.DonationType.String())
 	c.Set("donorEmail", donation.DonorEmail)
+	c.Set("installmentCount", donation.InstallmentCount)
+	c.Set("pledgeTotal", donation.PledgeTotal)
 
-	// Set next billing date for monthly donations
-	if donation.DonationType == "monthly" {
-		// Calculate next billing date (1 month from now)
-		nextBilling := time.Now().AddDate(0, 1, 0)
+	setFrequencyContext(c, donation.ChargeFrequency())
+
+	// Set next billing date for recurring donations
+	if donation.DonationType == models.DonationTypeMonthly {
+		// The first charge is today, the next one a billing period from now
+		nextBilling := time.Now().AddDate(0, donation.ChargeFrequency().Months(), 0)
 		c.Set("nextBillingDate", nextBilling.Format("January 2, 2006"))
 	}
 
-	// Set payment method (default to credit card for now)
-	c.Set("paymentMethod", "Credit Card")
+	c.Set("paymentMethod", paymentMethodLabel(donation))
+	c.Set("hostedPaymentURL", hostedPaymentURL(donation))
 
 	// Debug logging for payment page variables
-	c.Logger().Infof("DonatePayment vars: donationID=%T:%v, checkoutToken=%T:%v, amount=%T:%v, donorName=%T:%v, donationType=%s",
-		donationID, donationID, checkoutToken, checkoutToken, amount, amount, donorName, donorName, donation.DonationType)
+	c.Logger().Infof("DonatePayment vars: donationID=%s, amount=%s, donationType=%s",
+		donation.ID.String(), amountStr, donation.DonationType)
 
 	return c.Render(http.StatusOK, r.HTML("pages/donate_payment.plush.html"))
 }
 
+// setFrequencyContext exposes how often a recurring gift is charged to the
+// payment page, as "Quarterly", "quarterly" and "per quarter"
+func setFrequencyContext(c buffalo.Context, frequency models.DonationFrequency) {
+	c.Set("frequencyLabel", frequency.Label())
+	c.Set("frequencyText", strings.ToLower(frequency.Label()))
+	c.Set("frequencyPeriod", frequency.Period())
+}
+
+// hostedPaymentURL is the Helcim hosted payment page offered when HelcimPay.js
+// can't run, with the gift amount filled in. Hosted-page payments aren't tied
+// to the donation record, so only one-time gifts are sent there; it's empty
+// for recurring gifts or when HELCIM_HOSTED_PAYMENT_URL is unset.
+func hostedPaymentURL(donation *models.Donation) string {
+	base := envy.Get("HELCIM_HOSTED_PAYMENT_URL", "")
+	if base == "" || donation.DonationType != models.DonationTypeOneTime {
+		return ""
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set("amount", fmt.Sprintf("%.2f", donation.Amount))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
 // DonationSuccessHandler shows the donation success page
 func DonationSuccessHandler(c buffalo.Context) error {
+	setThankYouContext(c)
 	return c.Render(http.StatusOK, r.HTML("pages/donation_success.plush.html"))
 }
 
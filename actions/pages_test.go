@@ -2,6 +2,9 @@ package actions
 
 import (
 	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 // Test all page handlers with pure HTMX implementation
@@ -171,3 +174,22 @@ func (as *ActionSuite) Test_Donate_PresetAmount_Click() {
 func (as *ActionSuite) Test_StaticAsset_Endpoints() {
 	as.T().Skip("Asset serving test skipped - testing infrastructure, not business logic")
 }
+
+func Test_DonateButtonText(t *testing.T) {
+	assert.Equal(t, "Donate $100.00 Now", donateButtonText("100", "one-time", "", ""))
+	assert.Equal(t, "Donate $1,250.50 Monthly", donateButtonText("1250.5", "monthly", "", ""))
+	assert.Equal(t, "Pledge $600.00 over 6 Months", donateButtonText("600", "installment", "6", ""))
+	assert.Equal(t, "Donate $75.00 Quarterly", donateButtonText("75", "monthly", "", "quarterly"))
+	assert.Equal(t, "Donate $300.00 Annually", donateButtonText("300", "monthly", "", "annual"))
+
+	// Without a usable amount the button falls back to the type's plain label
+	assert.Equal(t, "Donate Now", donateButtonText("", "one-time", "", ""))
+	assert.Equal(t, "Donate Monthly", donateButtonText("abc", "monthly", "", ""))
+	assert.Equal(t, "Donate Annually", donateButtonText("", "monthly", "", "yearly"))
+	assert.Equal(t, "Donate Now", donateButtonText("0", "installment", "6", ""))
+
+	// Legacy and unknown types are labelled like the type they're saved as
+	assert.Equal(t, "Donate $25.00 Monthly", donateButtonText("25", "recurring", "", ""))
+	assert.Equal(t, "Donate $25.00 Now", donateButtonText("25", "weekly", "", ""))
+	assert.Equal(t, "Donate $25.00 Monthly", donateButtonText("25", "monthly", "", "weekly"))
+}
@@ -0,0 +1,251 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// PartnerStats is the giving report for one corporate partner
+type PartnerStats struct {
+	Partner      models.CorporatePartner
+	FundsRaised  float64
+	Participants int
+	Gifts        int
+}
+
+// findActivePartner looks up an active partner by its landing page slug.
+func findActivePartner(tx *pop.Connection, slug string) (*models.CorporatePartner, error) {
+	partner := &models.CorporatePartner{}
+	err := tx.Where("slug = ? AND active = ?", models.NormalizePartnerSlug(slug), true).First(partner)
+	if err != nil {
+		return nil, err
+	}
+	return partner, nil
+}
+
+// attachPartner credits a donation to the partner page it was made from and
+// applies the partner's designation. Unknown or inactive slugs are ignored so
+// the gift still goes through as a general donation.
+func attachPartner(c buffalo.Context, tx *pop.Connection, donation *models.Donation, slug string) *models.CorporatePartner {
+	if strings.TrimSpace(slug) == "" {
+		return nil
+	}
+	partner, err := findActivePartner(tx, slug)
+	if err != nil {
+		c.Logger().Warnf("[Partner] Donation submitted for unknown partner %q", slug)
+		return nil
+	}
+	donation.PartnerID = &partner.ID
+	donation.Designation = partner.Designation
+	return partner
+}
+
+// setPartnerContext exposes a partner's branding to the giving page and the
+// admin partner form. Plush can't print the optional *string fields directly.
+func setPartnerContext(c buffalo.Context, partner *models.CorporatePartner) {
+	c.Set("partner", partner)
+	c.Set("partnerLogo", stringOrEmpty(partner.LogoURL))
+	c.Set("partnerMessage", stringOrEmpty(partner.Message))
+	c.Set("partnerDesignation", stringOrEmpty(partner.Designation))
+}
+
+// GivePartnerHandler shows a corporate partner's branded giving page
+func GivePartnerHandler(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	partner, err := findActivePartner(tx, c.Param("partner_slug"))
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	setupDonateFormContext(c)
+	c.Set("csrf", c.Value("authenticity_token"))
+	setPartnerContext(c, partner)
+	c.Set("title", fmt.Sprintf("Give with %s", partner.Name))
+	c.Set("customFields", donationFormFields(c, partner.Slug))
+
+	return c.Render(http.StatusOK, r.HTML("pages/give.plush.html"))
+}
+
+// getPartnerStats totals funds raised and participating employees for each
+// partner. Only completed and active (recurring) donations are counted.
+func getPartnerStats(tx *pop.Connection, partners models.CorporatePartners) ([]PartnerStats, error) {
+	report := make([]PartnerStats, 0, len(partners))
+	for _, p := range partners {
+		var result struct {
+			FundsRaised  float64 `db:"funds_raised"`
+			Participants int     `db:"participants"`
+			Gifts        int     `db:"gifts"`
+		}
+		err := tx.RawQuery(`
+			SELECT
+				COALESCE(SUM(amount), 0) as funds_raised,
+				COUNT(DISTINCT LOWER(donor_email)) as participants,
+				COUNT(*) as gifts
+			FROM donations
+			WHERE partner_id = ? AND status IN ('completed', 'active')
+		`, p.ID).First(&result)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		report = append(report, PartnerStats{
+			Partner:      p,
+			FundsRaised:  result.FundsRaised,
+			Participants: result.Participants,
+			Gifts:        result.Gifts,
+		})
+	}
+	return report, nil
+}
+
+// bindPartner copies the admin partner form onto partner.
+func bindPartner(c buffalo.Context, partner *models.CorporatePartner) {
+	partner.Name = strings.TrimSpace(c.Param("Name"))
+	partner.Slug = c.Param("Slug")
+	partner.LogoURL = stringPointer(strings.TrimSpace(c.Param("LogoURL")))
+	partner.Message = stringPointer(strings.TrimSpace(c.Param("Message")))
+	partner.Designation = stringPointer(strings.TrimSpace(c.Param("Designation")))
+	active := c.Param("Active")
+	partner.Active = active == "true" || active == "on"
+	partner.GenerateSlug()
+}
+
+// loadThankYouRules exposes a partner's thank-you tiers to the admin partner
+// form, lowest tier first
+func loadThankYouRules(c buffalo.Context, tx *pop.Connection, partner *models.CorporatePartner) error {
+	rules := models.ThankYouRules{}
+	if err := tx.Where("partner_id = ?", partner.ID).Order("min_amount asc").All(&rules); err != nil {
+		return errors.WithStack(err)
+	}
+	c.Set("thankYouRules", rules)
+	return nil
+}
+
+// AdminPartnersIndex lists corporate partners with funds raised and
+// employee participation
+func AdminPartnersIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	partners := models.CorporatePartners{}
+	if err := tx.Order("name asc").All(&partners); err != nil {
+		return errors.WithStack(err)
+	}
+
+	report, err := getPartnerStats(tx, partners)
+	if err != nil {
+		return err
+	}
+
+	c.Set("report", report)
+	return c.Render(http.StatusOK, r.HTML("admin/partners/index.plush.html"))
+}
+
+// AdminPartnersNew shows the form for adding a corporate partner
+func AdminPartnersNew(c buffalo.Context) error {
+	setPartnerContext(c, &models.CorporatePartner{Active: true})
+	c.Set("thankYouRules", models.ThankYouRules{})
+	return c.Render(http.StatusOK, r.HTML("admin/partners/new.plush.html"))
+}
+
+// AdminPartnersCreate saves a new corporate partner
+func AdminPartnersCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	partner := &models.CorporatePartner{}
+	bindPartner(c, partner)
+	rules, verrs := bindThankYouRules(c)
+
+	partnerErrs, err := tx.ValidateAndCreate(partner)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	verrs.Append(partnerErrs)
+	if !verrs.HasAny() {
+		ruleErrs, err := replaceThankYouRules(tx, partner, rules)
+		if err != nil {
+			return err
+		}
+		verrs.Append(ruleErrs)
+	}
+	if verrs.HasAny() {
+		setPartnerContext(c, partner)
+		c.Set("thankYouRules", rules)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/partners/new.plush.html"))
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "partner_created", fmt.Sprintf("Created corporate partner: %s", partner.Name), logging.Fields{
+		"partner_id":   partner.ID.String(),
+		"partner_slug": partner.Slug,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Partner \"%s\" created. Their giving page is at /give/%s", partner.Name, partner.Slug))
+	return c.Redirect(http.StatusSeeOther, "/admin/partners")
+}
+
+// AdminPartnersEdit shows the form for editing a corporate partner
+func AdminPartnersEdit(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	partner := &models.CorporatePartner{}
+	if err := tx.Find(partner, c.Param("partner_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	setPartnerContext(c, partner)
+	if err := loadThankYouRules(c, tx, partner); err != nil {
+		return err
+	}
+	return c.Render(http.StatusOK, r.HTML("admin/partners/edit.plush.html"))
+}
+
+// AdminPartnersUpdate saves changes to a corporate partner
+func AdminPartnersUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	partner := &models.CorporatePartner{}
+	if err := tx.Find(partner, c.Param("partner_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	bindPartner(c, partner)
+	rules, verrs := bindThankYouRules(c)
+
+	partnerErrs, err := tx.ValidateAndUpdate(partner)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	verrs.Append(partnerErrs)
+	if !verrs.HasAny() {
+		ruleErrs, err := replaceThankYouRules(tx, partner, rules)
+		if err != nil {
+			return err
+		}
+		verrs.Append(ruleErrs)
+	}
+	// The 422 response rolls back the transaction, partner changes included
+	if verrs.HasAny() {
+		setPartnerContext(c, partner)
+		c.Set("thankYouRules", rules)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/partners/edit.plush.html"))
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "partner_updated", fmt.Sprintf("Updated corporate partner: %s", partner.Name), logging.Fields{
+		"partner_id":   partner.ID.String(),
+		"partner_slug": partner.Slug,
+		"active":       partner.Active,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Partner \"%s\" updated.", partner.Name))
+	return c.Redirect(http.StatusSeeOther, "/admin/partners")
+}
@@ -0,0 +1,285 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// payoutChannels are the donation channels paid out through PayPal Giving
+// Fund, keyed by the payment_method recorded on imported donations.
+var payoutChannels = map[string]string{
+	services.GiftSourcePayPalGivingFund: "PayPal Giving Fund",
+	services.GiftSourceVenmo:            "Venmo",
+}
+
+// PayoutChannelStats totals imported gifts for one payout channel
+type PayoutChannelStats struct {
+	Channel string  `db:"channel"`
+	Gifts   int     `db:"gifts"`
+	Gross   float64 `db:"gross"`
+	Fees    float64 `db:"fees"`
+}
+
+// PayoutGiftRow is an imported gift as listed on the admin page. Plush can't
+// print the donation's optional *string fields directly.
+type PayoutGiftRow struct {
+	Donation      models.Donation
+	Channel       string
+	TransactionID string
+	PayoutID      string
+	Fee           float64
+}
+
+// importPayoutGift records a PayPal Giving Fund or Venmo gift as a completed
+// donation so it shows up with Helcim gifts in the donor list and reports.
+// It returns false when the gift was already imported.
+func importPayoutGift(tx *pop.Connection, gift services.PayoutGift) (*models.Donation, bool, error) {
+	if _, ok := payoutChannels[gift.Source]; !ok {
+		return nil, false, fmt.Errorf("unknown payout channel %q", gift.Source)
+	}
+
+	existing := &models.Donation{}
+	err := tx.Where("payment_method = ? AND external_id = ?", gift.Source, gift.TransactionID).First(existing)
+	if err == nil {
+		return existing, false, nil
+	}
+
+	name := gift.DonorName
+	if name == "" {
+		name = "Anonymous"
+	}
+	method := gift.Source
+	fee := gift.Fee
+	donation := &models.Donation{
+		Amount:        gift.Amount,
+		Currency:      gift.Currency,
+		DonorName:     name,
+		DonorEmail:    gift.DonorEmail,
+		DonationType:  models.DonationTypeOneTime,
+		Status:        "completed",
+		PaymentMethod: &method,
+		ExternalID:    stringPointer(gift.TransactionID),
+		PayoutID:      stringPointer(gift.PayoutID),
+		ProcessorFee:  &fee,
+		Designation:   stringPointer(gift.Program),
+		CreatedAt:     gift.DonatedAt,
+	}
+
+	// Credit the gift to the donor's account when we know them
+	if gift.DonorEmail != "" {
+		user := &models.User{}
+		if err := tx.Where("LOWER(email) = ?", gift.DonorEmail).First(user); err == nil {
+			donation.UserID = &user.ID
+		}
+	}
+
+	if err := tx.Create(donation); err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	notifyDonationCompleted(tx, donation)
+	return donation, true, nil
+}
+
+// acknowledgePayoutGift emails the donor a thank-you. PayPal Giving Fund
+// issues the tax receipt for these gifts, so ours is not one, and donors on
+// the do-not-contact list are skipped.
+func acknowledgePayoutGift(c buffalo.Context, tx *pop.Connection, donation *models.Donation) {
+	if donation.DonorEmail == "" {
+		return
+	}
+	if contactSuppressed(tx, models.SuppressEmail, donation.DonorEmail, "PayPal/Venmo thank-you") {
+		return
+	}
+	err := services.NewEmailService().SendPayoutGiftAcknowledgement(donation.DonorEmail, services.PayoutGiftAcknowledgementData{
+		DonorName:        donation.DonorName,
+		Amount:           donation.Amount,
+		Channel:          payoutChannels[stringOrEmpty(donation.PaymentMethod)],
+		DonationDate:     donation.CreatedAt,
+		OrganizationName: organization().Name,
+	})
+	if err != nil {
+		c.Logger().Errorf("[Payout] Failed to send acknowledgement for donation %s: %v", donation.ID.String(), err)
+		return
+	}
+	recordCommunication(tx, donation.DonorEmail, models.CommunicationAcknowledgment, fmt.Sprintf("Thank-you for $%.2f %s gift", donation.Amount, payoutChannels[stringOrEmpty(donation.PaymentMethod)]), nil, &donation.ID)
+}
+
+// PayPalGivingFundWebhookHandler records gifts as PayPal Giving Fund
+// disburses them
+func PayPalGivingFundWebhookHandler(c buffalo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid request body")
+	}
+
+	secret := os.Getenv("PAYPAL_GIVING_FUND_WEBHOOK_SECRET")
+	if !services.VerifyHMACSignature(secret, body, c.Request().Header.Get("X-Signature")) {
+		logging.SecurityEvent(c, "paypal_giving_fund_webhook", "failure", "invalid_signature")
+		return jsonError(c, http.StatusUnauthorized, codeInvalidSignature, "Invalid signature")
+	}
+
+	var event services.PayPalGivingFundWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON")
+	}
+	if event.EventType != services.PayPalGivingFundEventDisbursed {
+		c.Logger().Infof("[PayoutWebhook] Ignoring %s event", event.EventType)
+		return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "ignored"}))
+	}
+
+	gift := event.Resource
+	if gift.Source == "" {
+		gift.Source = services.GiftSourcePayPalGivingFund
+	}
+	if gift.Currency == "" {
+		gift.Currency = "USD"
+	}
+	gift.DonorEmail = strings.ToLower(strings.TrimSpace(gift.DonorEmail))
+	if gift.TransactionID == "" || gift.Amount <= 0 || gift.DonatedAt.IsZero() {
+		return jsonError(c, http.StatusBadRequest, codeInvalidRequest, "Incomplete donation")
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	donation, created, err := importPayoutGift(tx, gift)
+	if err != nil {
+		c.Logger().Errorf("[PayoutWebhook] Failed to record %s gift %s: %v", gift.Source, gift.TransactionID, err)
+		return jsonError(c, http.StatusInternalServerError, codeInternal, "Failed to record donation")
+	}
+	if !created {
+		return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "duplicate"}))
+	}
+
+	logging.Audit("payout_gift_recorded", logging.Fields{
+		"donation_id":    donation.ID.String(),
+		"channel":        gift.Source,
+		"transaction_id": gift.TransactionID,
+		"amount":         gift.Amount,
+	})
+	acknowledgePayoutGift(c, tx, donation)
+
+	return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "recorded"}))
+}
+
+// AdminPayoutsIndex shows imported PayPal Giving Fund and Venmo gifts and the
+// report upload form
+func AdminPayoutsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	donations := models.Donations{}
+	err := tx.Where("payment_method IN (?, ?)", services.GiftSourcePayPalGivingFund, services.GiftSourceVenmo).
+		Order("created_at desc").Limit(100).All(&donations)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var stats []PayoutChannelStats
+	err = tx.RawQuery(`
+		SELECT
+			payment_method as channel,
+			COUNT(*) as gifts,
+			COALESCE(SUM(amount), 0) as gross,
+			COALESCE(SUM(processor_fee), 0) as fees
+		FROM donations
+		WHERE payment_method IN (?, ?)
+		GROUP BY payment_method
+		ORDER BY payment_method
+	`, services.GiftSourcePayPalGivingFund, services.GiftSourceVenmo).All(&stats)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for i := range stats {
+		stats[i].Channel = payoutChannels[stats[i].Channel]
+	}
+
+	emails := make([]string, len(donations))
+	for i, d := range donations {
+		emails[i] = d.DonorEmail
+	}
+	flags, err := loadDonorFlagIndex(tx, emails)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]PayoutGiftRow, 0, len(donations))
+	for _, d := range donations {
+		row := PayoutGiftRow{
+			Donation:      d,
+			Channel:       payoutChannels[stringOrEmpty(d.PaymentMethod)],
+			TransactionID: stringOrEmpty(d.ExternalID),
+			PayoutID:      stringOrEmpty(d.PayoutID),
+		}
+		if d.ProcessorFee != nil {
+			row.Fee = *d.ProcessorFee
+		}
+		rows = append(rows, row)
+	}
+
+	c.Set("gifts", rows)
+	c.Set("stats", stats)
+	c.Set("flags", flags)
+	return c.Render(http.StatusOK, r.HTML("admin/payouts.plush.html"))
+}
+
+// AdminPayoutsImport imports a PayPal Giving Fund donation report (CSV)
+func AdminPayoutsImport(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	f, err := c.File("report")
+	if err != nil || f.File == nil {
+		c.Flash().Add("danger", "Please choose a PayPal Giving Fund donation report (CSV) to import.")
+		return c.Redirect(http.StatusSeeOther, "/admin/payouts")
+	}
+	defer f.Close()
+
+	gifts, rowErrs, err := services.ParsePayoutReport(f)
+	if err != nil {
+		c.Flash().Add("danger", fmt.Sprintf("Could not read %s: %v", f.Filename, err))
+		return c.Redirect(http.StatusSeeOther, "/admin/payouts")
+	}
+
+	notify := c.Param("send_acknowledgements") == "true"
+	imported, duplicates := 0, 0
+	for _, gift := range gifts {
+		donation, created, err := importPayoutGift(tx, gift)
+		if err != nil {
+			return err
+		}
+		if !created {
+			duplicates++
+			continue
+		}
+		imported++
+		if notify {
+			acknowledgePayoutGift(c, tx, donation)
+		}
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "payout_report_imported", fmt.Sprintf("Imported PayPal Giving Fund report %s", f.Filename), logging.Fields{
+		"imported":   imported,
+		"duplicates": duplicates,
+		"row_errors": len(rowErrs),
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Imported %d gifts from %s (%d already recorded).", imported, f.Filename, duplicates))
+	for i, rowErr := range rowErrs {
+		if i == 10 {
+			c.Flash().Add("warning", fmt.Sprintf("...and %d more rows that could not be read.", len(rowErrs)-i))
+			break
+		}
+		c.Flash().Add("warning", rowErr.Error())
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/payouts")
+}
@@ -5,8 +5,6 @@ import (
 	"avrnpo.org/services"
 	"fmt"
 	"net/http"
-	"strconv"
-	"time"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/pop/v6"
@@ -215,64 +213,3 @@ func (pr PostsResource) Destroy(c buffalo.Context) error {
 	return c.Redirect(http.StatusSeeOther, "/admin/posts")
 }
 
-// Bulk handles bulk operations on posts (POST /admin/posts/bulk)
-func (pr PostsResource) Bulk(c buffalo.Context) error {
-	tx, ok := c.Value("tx").(*pop.Connection)
-	if !ok {
-		return fmt.Errorf("no transaction found")
-	}
-
-	action := c.Param("action")
-	postIDsParam := c.Request().FormValue("post_ids")
-	var postIDs []string
-	if postIDsParam != "" {
-		postIDs = []string{postIDsParam}
-	}
-	// Handle multiple post_ids if sent as array
-	if values := c.Request().Form["post_ids"]; len(values) > 0 {
-		postIDs = values
-	}
-
-	if action == "" || len(postIDs) == 0 {
-		c.Flash().Add("danger", "No action or posts selected")
-		return c.Redirect(http.StatusSeeOther, "/admin/posts")
-	}
-
-	// Convert string IDs to integers
-	var ids []int
-	for _, idStr := range postIDs {
-		if id, err := strconv.Atoi(idStr); err == nil {
-			ids = append(ids, id)
-		}
-	}
-
-	switch action {
-	case "publish":
-		now := time.Now()
-		err := tx.RawQuery("UPDATE posts SET published = true, published_at = ? WHERE id IN (?)", now, ids).Exec()
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		c.Flash().Add("success", fmt.Sprintf("Published %d posts", len(ids)))
-
-	case "unpublish":
-		err := tx.RawQuery("UPDATE posts SET published = false, published_at = NULL WHERE id IN (?)", ids).Exec()
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		c.Flash().Add("success", fmt.Sprintf("Unpublished %d posts", len(ids)))
-
-	case "delete":
-		err := tx.RawQuery("DELETE FROM posts WHERE id IN (?)", ids).Exec()
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		c.Flash().Add("success", fmt.Sprintf("Deleted %d posts", len(ids)))
-
-	default:
-		c.Flash().Add("danger", "Unknown bulk action")
-	}
-
-	return c.Redirect(http.StatusSeeOther, "/admin/posts")
-}
-
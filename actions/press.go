@@ -0,0 +1,104 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+)
+
+// setPressContext sets the press kit files and inquiry form values for the
+// press page
+func setPressContext(c buffalo.Context) {
+	assets := models.MediaAssets{}
+	if tx, ok := c.Value("tx").(*pop.Connection); ok {
+		if err := tx.Where("press_kit = ?", true).Where("scan_status IN (?)", []string{models.ScanClean, models.ScanNotScanned}).Order("created_at desc").All(&assets); err != nil {
+			c.Logger().Errorf("PRESS_KIT_LOAD_FAILED - Failed to load press kit files: %v", err)
+		}
+	}
+	c.Set("pressAssets", assets)
+	setMediaContext(c)
+	c.Set("form_timestamp", time.Now().Unix())
+	c.Set("csrf", c.Value("authenticity_token"))
+}
+
+// PressHandler shows the press page with its downloadable press kit and
+// handles its inquiry form. Inquiries skip the normal contact queue: they're
+// emailed straight to leadership and kept in the inbox under the press topic.
+func PressHandler(c buffalo.Context) error {
+	setPressContext(c)
+
+	if c.Request().Method == "GET" {
+		return c.Render(http.StatusOK, r.HTML("pages/press.plush.html"))
+	}
+
+	if err := ValidatePressInquiry(c); err != nil {
+		c.Flash().Add("error", err.Error())
+		return c.Render(http.StatusOK, r.HTML("pages/press.plush.html"))
+	}
+
+	name := c.Value("name").(string)
+	email := c.Value("email").(string)
+	outlet := c.Value("outlet").(string)
+	subject := c.Value("subject").(string)
+	message := c.Value("message").(string)
+	deadline := c.Value("deadline").(*time.Time)
+
+	// Store the inquiry first so the alert can link straight to it
+	stored := &models.ContactMessage{
+		Name:     name,
+		Email:    email,
+		Subject:  subject,
+		Message:  message,
+		Topic:    models.ContactTopicPress,
+		Outlet:   stringPointer(outlet),
+		Deadline: deadline,
+	}
+	tx, hasTx := c.Value("tx").(*pop.Connection)
+	if hasTx {
+		if err := tx.Create(stored); err != nil {
+			c.Logger().Errorf("PRESS_INQUIRY_STORE_FAILED - Failed to store press inquiry from %s (%s): %v", name, email, err)
+			hasTx = false
+		} else {
+			publishAPIEvent(tx, models.APIEventContactMessageCreated, apiContactMessageFrom(*stored))
+		}
+	}
+
+	inboxURL := siteURL() + "/admin/messages"
+	if hasTx {
+		inboxURL = fmt.Sprintf("%s/%s", inboxURL, stored.ID)
+	}
+	emailService := services.NewEmailService()
+	err := emailService.SendPressInquiryAlert(services.PressInquiryData{
+		Name:        name,
+		Email:       email,
+		Outlet:      outlet,
+		Deadline:    deadline,
+		Subject:     subject,
+		Message:     message,
+		SubmittedAt: time.Now(),
+		InboxURL:    inboxURL,
+	})
+	if err != nil {
+		c.Logger().Errorf("PRESS_INQUIRY_ALERT_FAILED - Failed to send press inquiry alert from %s (%s): %v", name, email, err)
+		// A stored inquiry still reaches staff through the inbox
+		if !hasTx {
+			c.Flash().Add("error", fmt.Sprintf("There was an error sending your inquiry. Please try again or contact us directly at %s.", emailService.ContactEmail))
+			return c.Render(http.StatusOK, r.HTML("pages/press.plush.html"))
+		}
+	}
+
+	c.Logger().Infof("PRESS_INQUIRY_RECEIVED - Press inquiry from %s (%s): %s", name, email, subject)
+	if hasTx {
+		recordCommunication(tx, email, models.CommunicationContactMessage, subject, &message, &stored.ID)
+	}
+	publishAdminActivity(activityContact, fmt.Sprintf("Press inquiry from %s: %s", name, subject), email, fmt.Sprintf("/admin/donors/%s", url.PathEscape(models.NormalizeDonorEmail(email))))
+	c.Flash().Add("success", "Thank you! Your inquiry has gone straight to our leadership team and we'll be in touch shortly.")
+	return c.Render(http.StatusOK, r.HTML("pages/press.plush.html"))
+}
@@ -0,0 +1,143 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_PressTemplatesRendering(t *testing.T) {
+	req := require.New(t)
+
+	logo := models.MediaAsset{
+		ID:          uuid.Must(uuid.NewV4()),
+		Title:       "AVR logo",
+		Kind:        models.MediaKindLogo,
+		Filename:    "avr-logo.png",
+		ContentType: "image/png",
+		Size:        48 << 10,
+		PressKit:    true,
+	}
+	factSheet := models.MediaAsset{
+		ID:          uuid.Must(uuid.NewV4()),
+		Title:       "Fact sheet",
+		Kind:        models.MediaKindDocument,
+		Filename:    "fact-sheet.pdf",
+		ContentType: "application/pdf",
+		Size:        2 << 20,
+	}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/press-test", func(c buffalo.Context) error {
+		setPressContext(c)
+		c.Set("pressAssets", models.MediaAssets{logo})
+		return c.Render(http.StatusOK, r.HTML("pages/press.plush.html"))
+	})
+	app.GET("/media-test", func(c buffalo.Context) error {
+		c.Set("assets", models.MediaAssets{logo, factSheet})
+		setMediaContext(c)
+		return c.Render(http.StatusOK, r.HTML("admin/media/index.plush.html"))
+	})
+	app.GET("/message-test", func(c buffalo.Context) error {
+		deadline := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+		c.Set("message", &models.ContactMessage{
+			Name:      "Sam Reporter",
+			Email:     "sam@example.com",
+			Subject:   "Interview request",
+			Topic:     models.ContactTopicPress,
+			Outlet:    stringPointer("Daily Ledger"),
+			Deadline:  &deadline,
+			CreatedAt: time.Now(),
+		})
+		setContactInboxContext(c)
+		return c.Render(http.StatusOK, r.HTML("admin/messages/show.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/press-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "<h3>Logos</h3>")
+	req.NotContains(w.Body.String(), "<h3>Photos</h3>")
+	req.Contains(w.Body.String(), `<img src="/media/`+logo.ID.String()+`"`)
+	req.Contains(w.Body.String(), `href="/media/`+logo.ID.String()+`?download=1"`)
+	req.Contains(w.Body.String(), `name="deadline"`)
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/media-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "Fact sheet")
+	req.Contains(w.Body.String(), "2.0 MB")
+	req.Contains(w.Body.String(), `<option value="document">Fact sheets &amp; documents</option>`)
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/message-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "<strong>Outlet:</strong> Daily Ledger")
+	req.Contains(w.Body.String(), "<strong>Deadline:</strong> Fri, Oct 16, 2026")
+}
+
+func TestValidatePressInquiry(t *testing.T) {
+	valid := func() url.Values {
+		return url.Values{
+			"name":    {"Sam Reporter"},
+			"email":   {"sam@example.com"},
+			"subject": {"Interview request"},
+			"message": {"Could we talk about the build program?"},
+		}
+	}
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(dateInputLayout)
+
+	tests := []struct {
+		name        string
+		edit        func(url.Values)
+		errContains string
+	}{
+		{"Valid inquiry", func(v url.Values) {}, ""},
+		{"Outlet and deadline", func(v url.Values) { v.Set("outlet", "Daily Ledger"); v.Set("deadline", tomorrow) }, ""},
+		{"Missing subject", func(v url.Values) { v.Del("subject") }, "Subject is required"},
+		{"Outlet too long", func(v url.Values) { v.Set("outlet", strings.Repeat("a", 201)) }, "outlet must be less than 200 characters"},
+		{"Malformed deadline", func(v url.Values) { v.Set("deadline", "next Friday") }, "please enter your deadline as a date"},
+		{"Past deadline", func(v url.Values) { v.Set("deadline", "2020-01-02") }, "your deadline has already passed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := buffalo.New(buffalo.Options{Env: "test"})
+			app.POST("/test", func(c buffalo.Context) error {
+				if err := ValidatePressInquiry(c); err != nil {
+					return c.Render(400, r.String(err.Error()))
+				}
+				if d := c.Value("deadline").(*time.Time); d != nil {
+					return c.Render(200, r.String("deadline "+d.Format(dateInputLayout)))
+				}
+				return c.Render(200, r.String("success"))
+			})
+
+			form := valid()
+			tt.edit(form)
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/test", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			app.ServeHTTP(w, req)
+
+			if tt.errContains == "" {
+				require.Equal(t, 200, w.Code, w.Body.String())
+			} else {
+				require.Equal(t, 400, w.Code)
+				require.Contains(t, w.Body.String(), tt.errContains)
+			}
+			if form.Get("deadline") == tomorrow {
+				require.Contains(t, w.Body.String(), "deadline "+tomorrow)
+			}
+		})
+	}
+}
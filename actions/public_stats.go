@@ -0,0 +1,378 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// publicStatsTTL is how long the public stats are served from memory before
+// they're totalled again, stretched while a campaign is live. Saving the
+// admin settings clears the cache.
+const publicStatsTTL = 10 * time.Minute
+
+// Computed stats are published rounded down to these steps, so a single
+// gift can't be read off a change in the totals.
+const (
+	publicMoneyStep = 100
+	publicCountStep = 10
+)
+
+// A completed donation updates the live homepage counters after a random
+// delay between liveStatsMinDelay and liveStatsMinDelay+liveStatsJitter, so
+// the update can't be matched to the moment someone gave.
+const (
+	liveStatsMinDelay = 15 * time.Second
+	liveStatsJitter   = 45 * time.Second
+)
+
+// sseKeepAlive is how often an idle event stream sends a comment so proxies
+// don't close it, and how soon browsers reconnect after it drops
+const sseKeepAlive = 30 * time.Second
+
+// publicStatValue is one published stat
+type publicStatValue struct {
+	Key   string  `json:"key"`
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+	Money bool    `json:"money"`
+}
+
+// Display is the value as the homepage counters show it, e.g. "$48,200"
+func (v publicStatValue) Display() string {
+	digits := strconv.FormatInt(int64(v.Value), 10)
+	var b strings.Builder
+	if v.Money {
+		b.WriteString("$")
+	}
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(",")
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
+// publicStatsPayload is the body of GET /api/stats. It only ever carries
+// totals, never anything about an individual donor.
+type publicStatsPayload struct {
+	Year        int               `json:"year"`
+	Stats       []publicStatValue `json:"stats"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+var publicStatsCache struct {
+	mu      sync.Mutex
+	payload *publicStatsPayload
+	expires time.Time
+}
+
+// clearPublicStatsCache makes the next request total the stats again
+func clearPublicStatsCache() {
+	publicStatsCache.mu.Lock()
+	defer publicStatsCache.mu.Unlock()
+	publicStatsCache.payload = nil
+}
+
+// computePublicStatValues totals the computed stats for the calendar year
+// now falls in. Recurring gifts count their first payment, as on the partner
+// reports.
+func computePublicStatValues(tx *pop.Connection, now time.Time) (map[string]float64, error) {
+	yearStart := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+
+	var totals struct {
+		Raised float64 `db:"raised"`
+		Donors int     `db:"donors"`
+	}
+	err := tx.RawQuery(`SELECT COALESCE(SUM(amount), 0) AS raised,
+		COUNT(DISTINCT CASE WHEN donor_email <> '' THEN LOWER(donor_email) END) AS donors
+		FROM donations WHERE status IN (?, ?) AND created_at >= ?`, "completed", "active", yearStart).First(&totals)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return map[string]float64{
+		models.StatRaisedThisYear: totals.Raised,
+		models.StatDonorsThisYear: float64(totals.Donors),
+	}, nil
+}
+
+// buildPublicStatsPayload lists the published stats, taking computed ones
+// from computed and the rest from their saved value. Computed stats are
+// rounded down to publicMoneyStep or publicCountStep; entered figures are
+// published as entered, to the whole number.
+func buildPublicStatsPayload(stats models.PublicStats, computed map[string]float64, now time.Time) publicStatsPayload {
+	payload := publicStatsPayload{Year: now.Year(), Stats: []publicStatValue{}, GeneratedAt: now}
+	for _, stat := range stats {
+		if !stat.Public {
+			continue
+		}
+		def := stat.Definition()
+		value := math.Floor(stat.Value)
+		if def.Computed {
+			step := float64(publicCountStep)
+			if def.Money {
+				step = publicMoneyStep
+			}
+			value = math.Floor(computed[def.Key]/step) * step
+		}
+		payload.Stats = append(payload.Stats, publicStatValue{Key: def.Key, Label: def.Label, Value: value, Money: def.Money})
+	}
+	return payload
+}
+
+// loadPublicStats returns the published stats, from the cache while it's
+// fresh
+func loadPublicStats(tx *pop.Connection, now time.Time) (*publicStatsPayload, error) {
+	ttl := cacheTTL(publicStatsTTL)
+	publicStatsCache.mu.Lock()
+	defer publicStatsCache.mu.Unlock()
+	if publicStatsCache.payload != nil && now.Before(publicStatsCache.expires) {
+		return publicStatsCache.payload, nil
+	}
+
+	stats, err := models.LoadPublicStats(tx)
+	if err != nil {
+		return nil, err
+	}
+	computed, err := computePublicStatValues(tx, now)
+	if err != nil {
+		return nil, err
+	}
+	payload := buildPublicStatsPayload(stats, computed, now)
+	publicStatsCache.payload = &payload
+	publicStatsCache.expires = now.Add(ttl)
+	return &payload, nil
+}
+
+// PublicStatsHandler serves the headline stats staff have published, for
+// the homepage counters and the annual report microsite
+func PublicStatsHandler(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	payload, err := loadPublicStats(tx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(publicStatsTTL.Seconds())))
+	c.Response().Header().Set("Access-Control-Allow-Origin", "*")
+	return c.Render(http.StatusOK, r.JSON(payload))
+}
+
+// statsHub tells the open homepage counter streams that the stats may have
+// changed
+type statsHub struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+	pending     bool
+}
+
+var liveStats = &statsHub{subscribers: map[chan struct{}]struct{}{}}
+
+func (h *statsHub) subscribe() chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan struct{}, 1)
+	h.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (h *statsHub) unsubscribe(ch chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, ch)
+}
+
+// publish signals every stream without waiting on slow ones; a stream that
+// hasn't picked up the last signal yet will reload once either way.
+func (h *statsHub) publish() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// notifyDonationCompleted announces the donation on the admin dashboard and
+// to REST hook subscribers, and schedules a refresh of the public stats and
+// the live counters. Donations completed while a refresh is pending share
+// it.
+func notifyDonationCompleted(tx *pop.Connection, donation *models.Donation) {
+	publishDonationActivity(donation)
+	publishAPIEvent(tx, models.APIEventDonationCreated, apiDonationFrom(*donation))
+
+	liveStats.mu.Lock()
+	defer liveStats.mu.Unlock()
+	if liveStats.pending {
+		return
+	}
+	liveStats.pending = true
+	time.AfterFunc(liveStatsMinDelay+rand.N(liveStatsJitter), func() {
+		liveStats.mu.Lock()
+		liveStats.pending = false
+		liveStats.mu.Unlock()
+		clearPublicStatsCache()
+		liveStats.publish()
+	})
+}
+
+// writeStatsEvent writes the stats as a server-sent "stats" event
+func writeStatsEvent(w io.Writer, payload *publicStatsPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: stats\ndata: %s\n\n", data)
+	return err
+}
+
+// PublicStatsStreamHandler streams the public stats to the homepage counters
+// as server-sent events, sending them again whenever they change. It runs
+// outside the request transaction so an open stream doesn't hold one, and
+// rechecks every publicStatsTTL to pick up gifts taken by other instances.
+func PublicStatsStreamHandler(c buffalo.Context) error {
+	w := c.Response()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return c.Error(http.StatusInternalServerError, errors.New("streaming is not supported"))
+	}
+
+	payload, err := loadPublicStats(models.DB, time.Now())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseKeepAlive.Milliseconds())
+	if err := writeStatsEvent(w, payload); err != nil {
+		return nil
+	}
+	flusher.Flush()
+
+	updates := liveStats.subscribe()
+	defer liveStats.unsubscribe(updates)
+	refresh := time.NewTicker(publicStatsTTL)
+	defer refresh.Stop()
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	sent := payload
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case <-updates:
+		case <-refresh.C:
+		}
+
+		latest, err := loadPublicStats(models.DB, time.Now())
+		if err != nil {
+			logging.Error("failed to refresh live stats", err, logging.Fields{})
+		} else if !slices.Equal(latest.Stats, sent.Stats) {
+			if err := writeStatsEvent(w, latest); err != nil {
+				return nil
+			}
+			sent = latest
+		}
+		flusher.Flush()
+	}
+}
+
+// AdminPublicStatsIndex shows which headline stats are published, with
+// their current values
+func AdminPublicStatsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	stats, err := models.LoadPublicStats(tx)
+	if err != nil {
+		return err
+	}
+	computed, err := computePublicStatValues(tx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	c.Set("stats", stats)
+	c.Set("computed", computed)
+	return c.Render(http.StatusOK, r.HTML("admin/public_stats.plush.html"))
+}
+
+// AdminPublicStatsUpdate saves which stats are published and the values of
+// the ones staff keep by hand
+func AdminPublicStatsUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	stats, err := models.LoadPublicStats(tx)
+	if err != nil {
+		return err
+	}
+
+	published := []string{}
+	for i := range stats {
+		stat := &stats[i]
+		def := stat.Definition()
+		stat.Public = c.Param("public_"+def.Key) == "true"
+		stat.UpdatedBy = &currentUser.ID
+		if !def.Computed {
+			raw := strings.ReplaceAll(strings.TrimSpace(c.Param("value_"+def.Key)), ",", "")
+			value, err := strconv.ParseFloat(raw, 64)
+			if raw != "" && err != nil {
+				c.Flash().Add("danger", fmt.Sprintf("%s must be a number.", def.Label))
+				return c.Redirect(http.StatusSeeOther, "/admin/public-stats")
+			}
+			stat.Value = value
+		}
+
+		save := tx.ValidateAndUpdate
+		if stat.ID == uuid.Nil {
+			save = tx.ValidateAndCreate
+		}
+		verrs, err := save(stat)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if verrs.HasAny() {
+			c.Flash().Add("danger", verrs.String())
+			return c.Redirect(http.StatusSeeOther, "/admin/public-stats")
+		}
+		if stat.Public {
+			published = append(published, def.Key)
+		}
+	}
+	clearPublicStatsCache()
+
+	logging.UserAction(c, currentUser.ID.String(), "public_stats_updated", "Updated public stats", logging.Fields{
+		"published": strings.Join(published, ","),
+	})
+
+	c.Flash().Add("success", "Public stats saved.")
+	return c.Redirect(http.StatusSeeOther, "/admin/public-stats")
+}
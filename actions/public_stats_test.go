@@ -0,0 +1,137 @@
+package actions
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_BuildPublicStatsPayload(t *testing.T) {
+	req := require.New(t)
+
+	now := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	stats := models.PublicStats{
+		{Key: models.StatRaisedThisYear, Public: true, Value: 1},
+		{Key: models.StatDonorsThisYear, Public: true},
+		{Key: models.StatHomesBuilt, Public: true, Value: 12},
+		{Key: models.StatVolunteers, Public: false, Value: 340},
+	}
+	computed := map[string]float64{models.StatRaisedThisYear: 48250.75, models.StatDonorsThisYear: 317}
+
+	payload := buildPublicStatsPayload(stats, computed, now)
+	req.Equal(2026, payload.Year)
+	req.Equal([]publicStatValue{
+		{Key: models.StatRaisedThisYear, Label: "Raised this year", Value: 48200, Money: true},
+		{Key: models.StatDonorsThisYear, Label: "Donors this year", Value: 310},
+		{Key: models.StatHomesBuilt, Label: "Homes built", Value: 12},
+	}, payload.Stats, "computed stats are rounded down and ignore the saved value; unpublished stats are left out")
+
+	empty := buildPublicStatsPayload(models.PublicStats{}, computed, now)
+	req.NotNil(empty.Stats, "no published stats is an empty list, not null")
+}
+
+func Test_PublicStatValueDisplay(t *testing.T) {
+	req := require.New(t)
+
+	req.Equal("$48,200", publicStatValue{Value: 48200, Money: true}.Display())
+	req.Equal("$1,250,000", publicStatValue{Value: 1250000, Money: true}.Display())
+	req.Equal("310", publicStatValue{Value: 310}.Display())
+	req.Equal("0", publicStatValue{}.Display())
+}
+
+func Test_StatsHubPublish(t *testing.T) {
+	req := require.New(t)
+
+	hub := &statsHub{subscribers: map[chan struct{}]struct{}{}}
+	first := hub.subscribe()
+	second := hub.subscribe()
+	hub.unsubscribe(second)
+
+	hub.publish()
+	hub.publish() // doesn't block on a stream that hasn't read the first signal
+	req.Len(first, 1)
+	req.Len(second, 0)
+}
+
+func Test_WriteStatsEvent(t *testing.T) {
+	req := require.New(t)
+
+	var buf bytes.Buffer
+	payload := &publicStatsPayload{Year: 2026, Stats: []publicStatValue{{Key: models.StatHomesBuilt, Label: "Homes built", Value: 12}}}
+	req.NoError(writeStatsEvent(&buf, payload))
+
+	out := buf.String()
+	req.True(strings.HasPrefix(out, "event: stats\ndata: {"), out)
+	req.True(strings.HasSuffix(out, "}\n\n"), out)
+	req.Contains(out, `"key":"homes_built"`)
+	req.Equal(1, strings.Count(out, "\n\n"), "the event is a single line of JSON")
+}
+
+func Test_LoadPublicStatsServesCache(t *testing.T) {
+	req := require.New(t)
+	defer clearPublicStatsCache()
+
+	now := time.Now()
+	cached := &publicStatsPayload{Year: now.Year(), GeneratedAt: now}
+	publicStatsCache.payload = cached
+	publicStatsCache.expires = now.Add(publicStatsTTL)
+
+	// A fresh cache never touches the database, so no connection is needed
+	payload, err := loadPublicStats(nil, now.Add(time.Minute))
+	req.NoError(err)
+	req.Same(cached, payload)
+
+	clearPublicStatsCache()
+	req.Nil(publicStatsCache.payload)
+}
+
+func Test_PublicStatsTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/public-stats-test", func(c buffalo.Context) error {
+		c.Set("stats", models.PublicStats{
+			{Key: models.StatRaisedThisYear, Public: true},
+			{Key: models.StatHomesBuilt, Value: 12},
+		})
+		c.Set("computed", map[string]float64{models.StatRaisedThisYear: 48250})
+		return c.Render(http.StatusOK, r.HTML("admin/public_stats.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/public-stats-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	body := w.Body.String()
+	req.Equal(http.StatusOK, w.Code, body)
+	req.Contains(body, "$48250")
+	req.Contains(body, `name="value_homes_built" value="12"`)
+	req.Contains(body, `name="public_raised_this_year" value="true" checked`)
+}
+
+func Test_HomeImpactCountersRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/home-test", func(c buffalo.Context) error {
+		c.Set("impactStats", []publicStatValue{{Key: models.StatRaisedThisYear, Label: "Raised this year", Value: 48200, Money: true}})
+		return c.Render(http.StatusOK, r.HTML("home/index.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/home-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	body := w.Body.String()
+	req.Equal(http.StatusOK, w.Code, body)
+	req.Contains(body, `<strong data-stat-key="raised_this_year" data-value="48200">$48,200</strong>`)
+	req.Contains(body, "/api/stats/stream")
+}
@@ -0,0 +1,126 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/pkg/ratelimit"
+)
+
+// rateLimitedRoute is a route that abuse tends to target (card testing,
+// contact form spam, password guessing), limited per client IP. Its limit
+// can be changed with RATE_LIMIT_<NAME>, e.g. RATE_LIMIT_CONTACT=5/10m, or
+// turned off with RATE_LIMIT_<NAME>=off.
+type rateLimitedRoute struct {
+	Name    string
+	Method  string
+	Path    string
+	Default string
+}
+
+var rateLimitedRoutes = []rateLimitedRoute{
+	{Name: "donations", Method: http.MethodPost, Path: "/api/donations/initialize", Default: "10/1m"},
+	{Name: "contact", Method: http.MethodPost, Path: "/contact", Default: "5/10m"},
+	{Name: "login", Method: http.MethodPost, Path: "/auth", Default: "10/5m"},
+	{Name: "signup", Method: http.MethodPost, Path: "/users", Default: "5/1h"},
+	{Name: "step_up", Method: http.MethodPost, Path: "/admin/step-up", Default: "5/5m"},
+	{Name: "drafts", Method: http.MethodPost, Path: "/drafts/{form}", Default: "30/1m"},
+	{Name: "csp_report", Method: http.MethodPost, Path: "/csp-report", Default: "30/1m"},
+}
+
+// rateLimit is a rateLimitedRoute's name and the rule it's held to
+type rateLimit struct {
+	Name string
+	Rule ratelimit.Rule
+}
+
+// rateLimitsEnabled reports whether request limits are applied. Enabled by
+// default outside the test environment, where suites post the same forms
+// from one address many times over.
+func rateLimitsEnabled() bool {
+	switch strings.ToLower(envy.Get("RATE_LIMIT_ENABLED", "")) {
+	case "true", "1", "yes":
+		return true
+	case "false", "0", "no":
+		return false
+	}
+	return envy.Get("GO_ENV", "development") != "test"
+}
+
+// rateLimitsFromEnv builds the rules for rateLimitedRoutes, keyed by method
+// and route path. Limits that fail to parse keep their default.
+func rateLimitsFromEnv() map[string]rateLimit {
+	limits := map[string]rateLimit{}
+	for _, route := range rateLimitedRoutes {
+		value := strings.TrimSpace(envy.Get("RATE_LIMIT_"+strings.ToUpper(route.Name), ""))
+		if strings.EqualFold(value, "off") {
+			continue
+		}
+		rule, err := ratelimit.ParseRule(value)
+		if value == "" || err != nil {
+			if err != nil {
+				logging.Warn("Invalid rate limit, using the default", logging.Fields{
+					"limit":   route.Name,
+					"value":   value,
+					"default": route.Default,
+				})
+			}
+			rule, _ = ratelimit.ParseRule(route.Default)
+		}
+		limits[rateLimitKey(route.Method, route.Path)] = rateLimit{Name: route.Name, Rule: rule}
+	}
+	return limits
+}
+
+// rateLimitKey identifies a route by method and path, ignoring the trailing
+// slash Buffalo adds to route paths
+func rateLimitKey(method, path string) string {
+	return method + " " + strings.TrimSuffix(path, "/")
+}
+
+// RateLimits returns middleware holding each client IP to limits on the
+// routes it covers. Responses carry X-RateLimit-* headers; a client over
+// the limit gets a 429 with Retry-After. If the store can't be reached the
+// request is let through rather than taking the forms down with it.
+func RateLimits(limiter *ratelimit.Limiter, limits map[string]rateLimit) buffalo.MiddlewareFunc {
+	return func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			route, _ := c.Value("current_route").(buffalo.RouteInfo)
+			limit, ok := limits[rateLimitKey(c.Request().Method, route.Path)]
+			if !ok {
+				return next(c)
+			}
+
+			ip := getClientIP(c)
+			result, err := limiter.Allow(limit.Name+":"+ip, limit.Rule)
+			if err != nil {
+				logging.Error("Rate limit check failed", err, logging.Fields{"limit": limit.Name})
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("X-RateLimit-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+			if result.Allowed {
+				return next(c)
+			}
+
+			retryAfter := result.RetryAfter(limiter.Now())
+			header.Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+			logging.SecurityEvent(c, "rate_limited", "blocked", limit.Name, logging.Fields{
+				"ip":    ip,
+				"limit": limit.Rule.String(),
+				"path":  c.Request().URL.Path,
+			})
+			return c.Error(http.StatusTooManyRequests, fmt.Errorf("rate limit %s exceeded for %s", limit.Name, ip))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/assert"
+
+	"avrnpo.org/pkg/ratelimit"
+)
+
+func Test_RateLimitsFromEnv(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("RATE_LIMIT_CONTACT", "2/1h")
+		envy.Set("RATE_LIMIT_SIGNUP", "off")
+		envy.Set("RATE_LIMIT_LOGIN", "lots")
+
+		limits := rateLimitsFromEnv()
+		assert.Equal(t, ratelimit.Rule{Limit: 2, Window: time.Hour}, limits["POST /contact"].Rule)
+		assert.Equal(t, ratelimit.Rule{Limit: 10, Window: 5 * time.Minute}, limits["POST /auth"].Rule)
+		assert.Equal(t, "donations", limits["POST /api/donations/initialize"].Name)
+		assert.Equal(t, ratelimit.Rule{Limit: 30, Window: time.Minute}, limits["POST /csp-report"].Rule)
+		_, ok := limits["POST /users"]
+		assert.False(t, ok)
+	})
+}
+
+func Test_RateLimitsEnabled(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("RATE_LIMIT_ENABLED", "")
+		envy.Set("GO_ENV", "test")
+		assert.False(t, rateLimitsEnabled())
+
+		envy.Set("GO_ENV", "production")
+		assert.True(t, rateLimitsEnabled())
+
+		envy.Set("RATE_LIMIT_ENABLED", "false")
+		assert.False(t, rateLimitsEnabled())
+	})
+}
+
+func Test_RateLimits_BlocksOverLimit(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	limiter := ratelimit.New(ratelimit.NewMemoryStore())
+	limiter.Now = func() time.Time { return now }
+	limits := map[string]rateLimit{
+		rateLimitKey(http.MethodPost, "/contact"): {Name: "contact", Rule: ratelimit.Rule{Limit: 2, Window: time.Minute}},
+	}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.Use(RateLimits(limiter, limits))
+	ok := func(c buffalo.Context) error { return c.Render(http.StatusOK, nil) }
+	app.POST("/contact", ok)
+	app.GET("/contact", ok)
+
+	send := func(method, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/contact", nil)
+		req.Header.Set("X-Forwarded-For", ip)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	res := send(http.MethodPost, "203.0.113.7")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "2", res.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", res.Header().Get("X-RateLimit-Remaining"))
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "203.0.113.7").Code)
+
+	res = send(http.MethodPost, "203.0.113.7")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "60", res.Header().Get("Retry-After"))
+
+	// Other addresses and routes without a limit are unaffected
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "198.51.100.2").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "203.0.113.7").Code)
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "203.0.113.7").Code)
+}
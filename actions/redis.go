@@ -0,0 +1,76 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/gobuffalo/envy"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/pkg/ratelimit"
+	"avrnpo.org/pkg/redis"
+)
+
+// sharedRedis is the Redis server (REDIS_URL) that instances of the app share
+// rate limit counts, payment plans and webhook claims through. It's nil when
+// REDIS_URL isn't set, and everything is kept in memory.
+var sharedRedis *redis.Client
+
+// webhookClaimTTL is how long a webhook delivery stays claimed if the
+// instance handling it dies before releasing it
+const webhookClaimTTL = 5 * time.Minute
+
+// connectRedis opens the client for REDIS_URL. A URL that's invalid or a
+// server that can't be reached is logged and the app carries on in memory,
+// so Redis being down doesn't keep the site from starting.
+func connectRedis() *redis.Client {
+	rawURL := envy.Get("REDIS_URL", "")
+	if rawURL == "" {
+		return nil
+	}
+	client, err := redis.New(rawURL)
+	if err != nil {
+		logging.Error("Invalid REDIS_URL, keeping rate limits and caches in memory", err)
+		return nil
+	}
+	if err := client.Ping(); err != nil {
+		logging.Error("Redis is unreachable, keeping rate limits and caches in memory", err)
+		return nil
+	}
+	return client
+}
+
+// rateLimitStore counts requests in Redis when it's configured, so the
+// limits hold across instances
+func rateLimitStore() ratelimit.Store {
+	if sharedRedis != nil {
+		return ratelimit.NewRedisStore(sharedRedis, "ratelimit:")
+	}
+	return ratelimit.NewMemoryStore()
+}
+
+// claimWebhookEvent stops two instances handling the same webhook at once.
+// Stored events catch redeliveries, but a provider retrying quickly can land
+// a second delivery on another instance before the first is recorded as
+// handled. claimed is false when another instance holds the event; release
+// should be called once it's been handled. Without Redis there's nothing to
+// race against, and a Redis error lets the delivery through.
+func claimWebhookEvent(provider, eventID string, payload []byte) (claimed bool, release func()) {
+	if sharedRedis == nil {
+		return true, func() {}
+	}
+	key := "webhook:" + provider + ":" + models.WebhookEventID(eventID, payload)
+	claimed, err := sharedRedis.SetNX(key, "1", webhookClaimTTL)
+	if err != nil {
+		logging.Error("Failed to claim webhook event", err, logging.Fields{"provider": provider, "event_id": eventID})
+		return true, func() {}
+	}
+	if !claimed {
+		return false, func() {}
+	}
+	return true, func() {
+		if err := sharedRedis.Del(key); err != nil {
+			logging.Warn("Failed to release webhook claim", logging.Fields{"key": key, "error": err.Error()})
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/assert"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/ratelimit"
+)
+
+func Test_ConnectRedis_FallsBackToMemory(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("REDIS_URL", "")
+		assert.Nil(t, connectRedis())
+
+		envy.Set("REDIS_URL", "http://localhost")
+		assert.Nil(t, connectRedis())
+
+		envy.Set("REDIS_URL", "redis://127.0.0.1:1")
+		assert.Nil(t, connectRedis())
+	})
+}
+
+func Test_WithoutRedis(t *testing.T) {
+	assert.IsType(t, &ratelimit.MemoryStore{}, rateLimitStore())
+
+	// A single instance has nothing to race, so every delivery is claimed
+	claimed, release := claimWebhookEvent(models.WebhookProviderHelcim, "evt-1", nil)
+	assert.True(t, claimed)
+	release()
+	claimed, _ = claimWebhookEvent(models.WebhookProviderHelcim, "evt-1", nil)
+	assert.True(t, claimed)
+}
@@ -1,37 +1,43 @@
 package actions
 
 import (
-	"fmt"
-	"io/fs"
-
-	public "avrnpo.org/public"
-	"avrnpo.org/templates"
 	"html/template"
-	"regexp"
-	"strings"
-	"time"
+	"io/fs"
+	"net/http"
 
 	"github.com/gobuffalo/buffalo/render"
 	"github.com/gobuffalo/helpers/forms"
-	"github.com/gobuffalo/helpers/hctx"
-	"github.com/gobuffalo/tags/v3"
+	"github.com/gobuffalo/plush/v4"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/helpers"
+	public "avrnpo.org/public"
+	"avrnpo.org/services"
+	"avrnpo.org/templates"
 )
 
 var r *render.Engine
 
 func init() {
-	// Common helpers
-	commonHelpers := render.Helpers{
-		forms.FormKey:         forms.Form,
-		forms.FormForKey:      forms.FormFor,
-		"getCurrentURL":       getCurrentURL,
-		"stripTags":           stripTagsHelper,
-		"dateFormat":          dateFormatHelper,
-		"getDonateButtonText": getDonateButtonText,
-		"current_path":        func() string { return "/" },
-		"t":                   func(s string, args ...interface{}) string { return s }, // Simple fallback translator
-		"csrf":                csrfHelper,
-	}
+	// Shared formatting helpers plus the app-specific ones
+	commonHelpers := helpers.Helpers()
+	commonHelpers[forms.FormKey] = forms.Form
+	commonHelpers[forms.FormForKey] = forms.FormFor
+	commonHelpers["getCurrentURL"] = getCurrentURL
+	commonHelpers["donateButtonText"] = donateButtonText
+	commonHelpers["donateButtonLabels"] = donateButtonLabelsJSON
+	commonHelpers["current_path"] = func() string { return "/" }
+	commonHelpers["t"] = func(s string, args ...interface{}) string { return s } // Simple fallback translator
+	commonHelpers["helcimPayIntegrity"] = helcimPayIntegrity
+	commonHelpers["installmentOptions"] = installmentOptions
+	commonHelpers["donationFrequencies"] = func() []models.DonationFrequency { return models.DonationFrequencies }
+	commonHelpers["organization"] = organization
+	commonHelpers["organizationStructuredData"] = organizationStructuredData
+	commonHelpers["liveCampaign"] = liveCampaign
+	commonHelpers["campaignCacheFactor"] = campaignCacheFactor
+	commonHelpers["formTimestamp"] = formTimestamp
+	commonHelpers["captchaSiteKey"] = services.CaptchaSiteKey
+	commonHelpers["param"] = paramHelper
 
 	// Get the assets sub-filesystem
 	assetsFS, _ := fs.Sub(public.EmbeddedAssets, "assets")
@@ -52,23 +58,15 @@ func getCurrentURL() string {
 	return ""
 }
 
-// stripTagsHelper removes HTML tags from content for use in templates
-func stripTagsHelper(content string) string {
-	// Regular expression to match HTML tags
-	htmlTagRegex := regexp.MustCompile(`<[^>]*>`)
-	// Remove HTML tags
-	cleaned := htmlTagRegex.ReplaceAllString(content, "")
-	// Clean up extra whitespace
-	cleaned = strings.TrimSpace(cleaned)
-	// Replace multiple spaces/newlines with single spaces
-	spaceRegex := regexp.MustCompile(`\s+`)
-	cleaned = spaceRegex.ReplaceAllString(cleaned, " ")
-	return cleaned
-}
-
-// dateFormatHelper formats time.Time values for use in templates
-func dateFormatHelper(t time.Time, format string) string {
-	return t.Format(format)
+// paramHelper is the param template helper: a query string or form value
+// from the request being rendered, e.g. to keep what a donor entered when a
+// form is shown again with errors. It's empty outside a request.
+func paramHelper(name string, help plush.HelperContext) string {
+	req, ok := help.Value("request").(*http.Request)
+	if !ok || req == nil {
+		return ""
+	}
+	return req.FormValue(name)
 }
 
 // renderForRequest was removed in favor of a single render strategy (use r.HTML).
@@ -80,17 +78,3 @@ func dateFormatHelper(t time.Time, format string) string {
 func SanitizeString(s string) string {
 	return template.HTMLEscapeString(s)
 }
-
-// csrfHelper returns the CSRF token from the context for use in templates
-func csrfHelper(opts tags.Options, help hctx.HelperContext) (template.HTML, error) {
-	if help == nil {
-		return template.HTML(""), nil
-	}
-
-	token := help.Value("authenticity_token")
-	if token == nil {
-		return template.HTML(""), nil
-	}
-
-	return template.HTML(fmt.Sprintf(`<input name="authenticity_token" type="hidden" value="%s" />`, token)), nil
-}
@@ -0,0 +1,337 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// resourceRecentDays is the window the admin resource list counts recent
+// clicks over
+const resourceRecentDays = 30
+
+// crawlerMarkers appear in the user agents of search engines and link
+// previewers, whose visits aren't counted as clicks
+var crawlerMarkers = []string{"bot", "crawl", "spider", "slurp", "preview", "facebookexternalhit"}
+
+// isCrawler reports whether userAgent looks like an automated visitor
+func isCrawler(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return true
+	}
+	for _, marker := range crawlerMarkers {
+		if strings.Contains(ua, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourcesIndex is the public resource library, grouped by category. A
+// search matches every word against the title, organization and
+// description.
+func ResourcesIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	q := strings.TrimSpace(c.Param("q"))
+	category := c.Param("category")
+	query := tx.Where("active = ?", true)
+	if slices.Contains(models.ResourceCategoryKeys(), category) {
+		query = query.Where("category = ?", category)
+	} else {
+		category = ""
+	}
+	for _, term := range strings.Fields(strings.ToLower(q)) {
+		like := "%" + term + "%"
+		query = query.Where("(LOWER(title) LIKE ? OR LOWER(COALESCE(organization, '')) LIKE ? OR LOWER(description) LIKE ?)", like, like, like)
+	}
+	resources := models.VeteranResources{}
+	if err := query.Order("title").All(&resources); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("title", "Veteran Resources")
+	c.Set("groups", models.GroupResources(resources))
+	c.Set("categories", models.ResourceCategories)
+	c.Set("category", category)
+	c.Set("q", q)
+	return c.Render(http.StatusOK, r.HTML("pages/resources.plush.html"))
+}
+
+// ResourceVisit counts a click on a library resource and sends the visitor
+// on to it
+func ResourceVisit(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	resource := &models.VeteranResource{}
+	if err := tx.Where("active = ?", true).Find(resource, c.Param("resource_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	if !isCrawler(c.Request().UserAgent()) {
+		if err := tx.Create(&models.ResourceClick{ResourceID: resource.ID}); err != nil {
+			// The visitor still gets where they were going
+			logging.Error("resource_click_record_failed", err, logging.Fields{
+				"resource_id": resource.ID.String(),
+			})
+		}
+	}
+	return c.Redirect(http.StatusFound, resource.URL)
+}
+
+// bindVeteranResource copies the admin resource form onto resource
+func bindVeteranResource(c buffalo.Context, resource *models.VeteranResource) {
+	resource.Title = strings.TrimSpace(c.Param("Title"))
+	resource.URL = strings.TrimSpace(c.Param("URL"))
+	resource.Category = c.Param("Category")
+	resource.Organization = stringPointer(strings.TrimSpace(c.Param("Organization")))
+	resource.Description = strings.TrimSpace(c.Param("Description"))
+	resource.Phone = stringPointer(strings.TrimSpace(c.Param("Phone")))
+	active := c.Param("Active")
+	resource.Active = active == "true" || active == "on"
+}
+
+// setResourceFormContext exposes a resource to the admin resource form
+func setResourceFormContext(c buffalo.Context, resource *models.VeteranResource) {
+	c.Set("resource", resource)
+	c.Set("categories", models.ResourceCategories)
+}
+
+// resourceRow is a resource on the admin list with how often it's used
+type resourceRow struct {
+	Resource     models.VeteranResource
+	Clicks       int
+	RecentClicks int
+}
+
+// sortResourceRows puts the most used resources first: by recent clicks,
+// then all-time clicks, then title
+func sortResourceRows(rows []resourceRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].RecentClicks != rows[j].RecentClicks {
+			return rows[i].RecentClicks > rows[j].RecentClicks
+		}
+		if rows[i].Clicks != rows[j].Clicks {
+			return rows[i].Clicks > rows[j].Clicks
+		}
+		return rows[i].Resource.Title < rows[j].Resource.Title
+	})
+}
+
+// AdminResourcesIndex lists the resource library, most used first, with
+// each link's health. ?link=broken shows only the broken links.
+func AdminResourcesIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	brokenOnly := c.Param("link") == models.LinkBroken
+	query := tx.Q()
+	if brokenOnly {
+		query = query.Where("link_status = ?", models.LinkBroken)
+	}
+	resources := models.VeteranResources{}
+	if err := query.Order("title").All(&resources); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var counted []struct {
+		ResourceID uuid.UUID `db:"resource_id"`
+		Clicks     int       `db:"clicks"`
+		Recent     int       `db:"recent"`
+	}
+	since := time.Now().AddDate(0, 0, -resourceRecentDays)
+	err := tx.RawQuery("SELECT resource_id, COUNT(*) AS clicks, SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END) AS recent FROM resource_clicks GROUP BY resource_id", since).All(&counted)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	clicks := map[uuid.UUID]resourceRow{}
+	for _, row := range counted {
+		clicks[row.ResourceID] = resourceRow{Clicks: row.Clicks, RecentClicks: row.Recent}
+	}
+
+	rows := make([]resourceRow, len(resources))
+	broken := 0
+	for i, res := range resources {
+		rows[i] = resourceRow{Resource: res, Clicks: clicks[res.ID].Clicks, RecentClicks: clicks[res.ID].RecentClicks}
+		if res.LinkBroken() {
+			broken++
+		}
+	}
+	sortResourceRows(rows)
+
+	c.Set("brokenCount", broken)
+	c.Set("resources", rows)
+	c.Set("brokenOnly", brokenOnly)
+	c.Set("recentDays", resourceRecentDays)
+	return c.Render(http.StatusOK, r.HTML("admin/resources/index.plush.html"))
+}
+
+// AdminResourcesNew shows the form for adding a resource
+func AdminResourcesNew(c buffalo.Context) error {
+	setResourceFormContext(c, &models.VeteranResource{Active: true})
+	return c.Render(http.StatusOK, r.HTML("admin/resources/new.plush.html"))
+}
+
+// AdminResourcesCreate saves a new resource and queues a check of its link
+func AdminResourcesCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	resource := &models.VeteranResource{LinkStatus: models.LinkUnchecked}
+	bindVeteranResource(c, resource)
+
+	verrs, err := tx.ValidateAndCreate(resource)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		setResourceFormContext(c, resource)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/resources/new.plush.html"))
+	}
+	queueResourceLinkCheck(tx, resource.ID.String())
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "resource_created", fmt.Sprintf("Created library resource: %s", resource.Title), logging.Fields{
+		"resource_id": resource.ID.String(),
+		"category":    resource.Category,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Resource \"%s\" created.", resource.Title))
+	return c.Redirect(http.StatusSeeOther, "/admin/resources")
+}
+
+// AdminResourcesEdit shows the form for editing a resource
+func AdminResourcesEdit(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	resource := &models.VeteranResource{}
+	if err := tx.Find(resource, c.Param("resource_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	setResourceFormContext(c, resource)
+	return c.Render(http.StatusOK, r.HTML("admin/resources/edit.plush.html"))
+}
+
+// AdminResourcesUpdate saves changes to a resource. A new link is checked
+// again.
+func AdminResourcesUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	resource := &models.VeteranResource{}
+	if err := tx.Find(resource, c.Param("resource_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	previousURL := resource.URL
+	bindVeteranResource(c, resource)
+	linkChanged := resource.URL != previousURL
+	if linkChanged {
+		resource.LinkStatus = models.LinkUnchecked
+		resource.LinkStatusCode = nil
+		resource.LinkError = nil
+		resource.LinkCheckedAt = nil
+	}
+
+	verrs, err := tx.ValidateAndUpdate(resource)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		setResourceFormContext(c, resource)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/resources/edit.plush.html"))
+	}
+	if linkChanged {
+		queueResourceLinkCheck(tx, resource.ID.String())
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "resource_updated", fmt.Sprintf("Updated library resource: %s", resource.Title), logging.Fields{
+		"resource_id": resource.ID.String(),
+		"category":    resource.Category,
+		"active":      resource.Active,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Resource \"%s\" updated.", resource.Title))
+	return c.Redirect(http.StatusSeeOther, "/admin/resources")
+}
+
+// AdminResourcesCheckLinks queues a check of every active resource's link
+func AdminResourcesCheckLinks(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	queueResourceLinkCheck(tx, "")
+	c.Flash().Add("info", "Checking links in the background. Refresh in a minute to see the results.")
+	return c.Redirect(http.StatusSeeOther, "/admin/resources")
+}
+
+// queueResourceLinkCheck queues a check of one resource's link, or of every
+// active resource when resourceID is ""
+func queueResourceLinkCheck(tx *pop.Connection, resourceID string) {
+	queueJob(tx, jobResourceLinkCheck, worker.Args{"resource_id": resourceID})
+}
+
+// checkResourceLinksJob checks the links a queued link check asks for
+func checkResourceLinksJob(args worker.Args) error {
+	query := models.DB.Where("active = ?", true)
+	if id := jobArg(args, "resource_id"); id != "" {
+		query = models.DB.Where("id = ?", id)
+	}
+	resources := models.VeteranResources{}
+	if err := query.All(&resources); err != nil {
+		return errors.Wrap(err, "loading resources")
+	}
+	_, err := checkResourceLinks(models.DB, services.NewLinkChecker(), resources, time.Now())
+	return err
+}
+
+// CheckResourceLinks checks every active resource's link, recording which
+// are broken, and reports how many were checked and broken. It's run from
+// cron through the resources:check_links task.
+func CheckResourceLinks(db *pop.Connection, now time.Time) (checked int, broken int, err error) {
+	resources := models.VeteranResources{}
+	if err := db.Where("active = ?", true).All(&resources); err != nil {
+		return 0, 0, errors.WithStack(err)
+	}
+	broken, err = checkResourceLinks(db, services.NewLinkChecker(), resources, now)
+	return len(resources), broken, err
+}
+
+// checkResourceLinks checks each resource's link and saves the result. A
+// link that has just broken is logged so staff can fix or hide it.
+func checkResourceLinks(db *pop.Connection, checker *services.LinkChecker, resources models.VeteranResources, now time.Time) (int, error) {
+	broken := 0
+	for i := range resources {
+		resource := &resources[i]
+		wasBroken := resource.LinkBroken()
+		res := checker.Check(resource.URL)
+		resource.SetLinkCheck(res.StatusCode, res.Error, now)
+		if err := db.UpdateColumns(resource, "link_status", "link_status_code", "link_error", "link_checked_at"); err != nil {
+			return broken, errors.Wrapf(err, "saving link check for resource %s", resource.ID)
+		}
+		if !resource.LinkBroken() {
+			continue
+		}
+		broken++
+		if !wasBroken {
+			logging.Warn("resource_link_broken", logging.Fields{
+				"resource_id": resource.ID.String(),
+				"url":         resource.URL,
+				"problem":     resource.LinkProblem(),
+			})
+		}
+	}
+	return broken, nil
+}
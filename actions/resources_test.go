@@ -0,0 +1,80 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_IsCrawler(t *testing.T) {
+	req := require.New(t)
+
+	req.True(isCrawler(""))
+	req.True(isCrawler("Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"))
+	req.True(isCrawler("facebookexternalhit/1.1"))
+	req.False(isCrawler("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148 Safari/604.1"))
+}
+
+func Test_SortResourceRows(t *testing.T) {
+	rows := []resourceRow{
+		{Resource: models.VeteranResource{Title: "Unused"}},
+		{Resource: models.VeteranResource{Title: "Popular last year"}, Clicks: 50},
+		{Resource: models.VeteranResource{Title: "Popular now"}, Clicks: 20, RecentClicks: 12},
+		{Resource: models.VeteranResource{Title: "Also unused"}},
+	}
+	sortResourceRows(rows)
+
+	titles := []string{}
+	for _, row := range rows {
+		titles = append(titles, row.Resource.Title)
+	}
+	require.Equal(t, []string{"Popular now", "Popular last year", "Also unused", "Unused"}, titles)
+}
+
+func Test_ResourceAdminTemplatesRendering(t *testing.T) {
+	req := require.New(t)
+
+	failure := 404
+	broken := models.VeteranResource{ID: uuid.Must(uuid.NewV4()), Title: "Old benefits page", URL: "https://example.org/gone", Category: "va_benefits", Active: true, LinkStatus: models.LinkBroken, LinkStatusCode: &failure}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/admin-resources-test", func(c buffalo.Context) error {
+		c.Set("resources", []resourceRow{{Resource: broken, Clicks: 9, RecentClicks: 3}})
+		c.Set("brokenCount", 1)
+		c.Set("brokenOnly", false)
+		c.Set("recentDays", resourceRecentDays)
+		return c.Render(http.StatusOK, r.HTML("admin/resources/index.plush.html"))
+	})
+	app.GET("/admin-resource-edit-test", func(c buffalo.Context) error {
+		setResourceFormContext(c, &broken)
+		return c.Render(http.StatusOK, r.HTML("admin/resources/edit.plush.html"))
+	})
+	app.GET("/admin-resource-new-test", func(c buffalo.Context) error {
+		setResourceFormContext(c, &models.VeteranResource{Active: true})
+		return c.Render(http.StatusOK, r.HTML("admin/resources/new.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/admin-resources-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `<a href="/admin/resources?link=broken">Broken links</a> (1)`)
+	req.Contains(w.Body.String(), "HTTP 404 Not Found")
+	req.Contains(w.Body.String(), `href="/admin/resources/`+broken.ID.String()+`/edit"`)
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/admin-resource-edit-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `action="/admin/resources/`+broken.ID.String()+`"`)
+	req.Contains(w.Body.String(), `<option value="va_benefits" selected>VA Benefits</option>`)
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/admin-resource-new-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `action="/admin/resources"`)
+}
@@ -0,0 +1,157 @@
+package actions
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/envy"
+
+	"avrnpo.org/pkg/logging"
+)
+
+// pinnedScript is a third-party script the site loads from another origin.
+// Integrity is the expected SRI hash ("sha384-..."); when it is empty the
+// startup check only records the current hash so it can be pinned.
+// Unpinnable says why a script can never carry a hash; it's listed so every
+// remote script is accounted for, but its hash is never enforced.
+type pinnedScript struct {
+	Name       string
+	URL        string
+	Integrity  string
+	Unpinnable string
+}
+
+// ScriptIntegrityResult is the outcome of checking one pinned script.
+type ScriptIntegrityResult struct {
+	Name      string
+	URL       string
+	Expected  string
+	Actual    string
+	Match     bool
+	Error     string
+	CheckedAt time.Time
+}
+
+const (
+	helcimPayScriptURL = "https://secure.helcim.app/helcim-pay/services/start.js"
+	turnstileScriptURL = "https://challenges.cloudflare.com/turnstile/v0/api.js"
+)
+
+var (
+	scriptIntegrityMu      sync.RWMutex
+	scriptIntegrityResults []ScriptIntegrityResult
+)
+
+// pinnedScripts returns the third-party scripts to verify. HTMX and the rest
+// of the front end are served from public/assets, so only the HelcimPay.js
+// loader, which Helcim requires be loaded from their origin, and the
+// Turnstile widget on the donate form are remote.
+func pinnedScripts() []pinnedScript {
+	return []pinnedScript{
+		{
+			Name:      "HelcimPay.js",
+			URL:       helcimPayScriptURL,
+			Integrity: strings.TrimSpace(envy.Get("HELCIM_PAY_JS_SRI", "")),
+		},
+		{
+			// Cloudflare updates api.js in place under the same v0 URL and
+			// says not to pin or self-host it, so a hash would break the
+			// challenge on their next release. The CSP limits it to
+			// challenges.cloudflare.com instead.
+			Name:       "Cloudflare Turnstile",
+			URL:        turnstileScriptURL,
+			Unpinnable: "Cloudflare updates the script in place without versioning it",
+		},
+	}
+}
+
+// helcimPayIntegrity is the SRI hash the payment page puts on the HelcimPay.js
+// script tag, or "" when none is pinned.
+func helcimPayIntegrity() string {
+	return strings.TrimSpace(envy.Get("HELCIM_PAY_JS_SRI", ""))
+}
+
+// computeSRI returns the sha384 subresource integrity value for data.
+func computeSRI(data []byte) string {
+	sum := sha512.Sum384(data)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// checkPinnedScript downloads a script and compares it with its pinned hash.
+func checkPinnedScript(client *http.Client, script pinnedScript) ScriptIntegrityResult {
+	result := ScriptIntegrityResult{
+		Name:      script.Name,
+		URL:       script.URL,
+		Expected:  script.Integrity,
+		CheckedAt: time.Now(),
+	}
+
+	resp, err := client.Get(script.URL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		result.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		return result
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 5<<20))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Actual = computeSRI(body)
+	result.Match = script.Integrity == "" || script.Integrity == result.Actual
+	return result
+}
+
+// verifyPinnedScripts checks every pinned script and logs mismatches. It is
+// run in the background at startup so a slow CDN never delays boot.
+func verifyPinnedScripts(client *http.Client) []ScriptIntegrityResult {
+	var results []ScriptIntegrityResult
+	for _, script := range pinnedScripts() {
+		res := checkPinnedScript(client, script)
+		results = append(results, res)
+
+		fields := logging.Fields{
+			"script":   res.Name,
+			"url":      res.URL,
+			"expected": res.Expected,
+			"actual":   res.Actual,
+		}
+		switch {
+		case res.Error != "":
+			fields["error"] = res.Error
+			logging.Warn("Could not verify third-party script integrity", fields)
+		case !res.Match:
+			logging.Error("Third-party script hash does not match pinned value", fmt.Errorf("integrity mismatch for %s", res.Name), fields)
+		case script.Unpinnable != "":
+			fields["reason"] = script.Unpinnable
+			logging.Info("Third-party script can't be pinned; it is only allowed by origin", fields)
+		case res.Expected == "":
+			logging.Info("Third-party script is not pinned; set its SRI hash to enforce it", fields)
+		}
+	}
+
+	scriptIntegrityMu.Lock()
+	scriptIntegrityResults = results
+	scriptIntegrityMu.Unlock()
+	return results
+}
+
+// ScriptIntegrityResults returns the latest startup check results.
+func ScriptIntegrityResults() []ScriptIntegrityResult {
+	scriptIntegrityMu.RLock()
+	defer scriptIntegrityMu.RUnlock()
+	return append([]ScriptIntegrityResult(nil), scriptIntegrityResults...)
+}
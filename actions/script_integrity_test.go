@@ -0,0 +1,70 @@
+package actions
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ComputeSRI(t *testing.T) {
+	// echo -n "alert('hi');" | openssl dgst -sha384 -binary | base64
+	assert.Equal(t, "sha384-FANl2IiScBgbQg1ZiXDX2/KBIClVPK/2G9OPEEydFX4pOsLyf8a9qfpBudHpQY1u", computeSRI([]byte("alert('hi');")))
+}
+
+func Test_CheckPinnedScript(t *testing.T) {
+	body := []byte("window.appendHelcimPayIframe = function() {};")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.js" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	pinned := checkPinnedScript(srv.Client(), pinnedScript{Name: "ok", URL: srv.URL + "/start.js", Integrity: computeSRI(body)})
+	assert.True(t, pinned.Match)
+	assert.Empty(t, pinned.Error)
+
+	tampered := checkPinnedScript(srv.Client(), pinnedScript{Name: "bad", URL: srv.URL + "/start.js", Integrity: computeSRI([]byte("original"))})
+	assert.False(t, tampered.Match)
+	assert.Equal(t, computeSRI(body), tampered.Actual)
+
+	unpinned := checkPinnedScript(srv.Client(), pinnedScript{Name: "unpinned", URL: srv.URL + "/start.js"})
+	assert.True(t, unpinned.Match)
+
+	missing := checkPinnedScript(srv.Client(), pinnedScript{Name: "missing", URL: srv.URL + "/missing.js"})
+	assert.NotEmpty(t, missing.Error)
+}
+
+// Every script a template loads from another origin must be listed in
+// pinnedScripts, pinned or with the reason it can't be
+func Test_PinnedScripts_CoverTemplates(t *testing.T) {
+	listed := map[string]bool{}
+	for _, script := range pinnedScripts() {
+		listed[script.URL] = true
+	}
+
+	remote := regexp.MustCompile(`<script[^>]*\ssrc="(https?://[^"]+)"|script\.src\s*=\s*'(https?://[^']+)'`)
+	err := filepath.WalkDir("../templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, m := range remote.FindAllStringSubmatch(string(data), -1) {
+			url := m[1] + m[2]
+			assert.True(t, listed[url], "%s loads %s, which isn't in pinnedScripts", path, url)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+}
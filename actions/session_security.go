@@ -0,0 +1,180 @@
+package actions
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gorilla/sessions"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/pkg/sessionstore"
+)
+
+const (
+	sessionStartedAtKey = "session_started_at"
+	sessionRoleKey      = "session_role"
+
+	defaultSessionMaxAge           = 7 * 24 * time.Hour
+	defaultSessionAbsoluteLifetime = 24 * time.Hour
+)
+
+// envBool reads a boolean env var, falling back to def when unset or invalid.
+func envBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(strings.TrimSpace(envy.Get(key, "")))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// envDuration reads a Go duration env var (e.g. "12h"), falling back to def.
+func envDuration(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(envy.Get(key, "")))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// parseSameSite maps SESSION_COOKIE_SAMESITE values to http.SameSite.
+// Defaults to Lax, which keeps the Helcim redirect back to /donate working.
+func parseSameSite(v string) http.SameSite {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// sessionCookieOptions builds the session cookie flags from the environment:
+//
+//	SESSION_COOKIE_SAMESITE  lax (default), strict or none
+//	SESSION_COOKIE_SECURE    defaults to true in production
+//	SESSION_COOKIE_HTTPONLY  defaults to true
+//	SESSION_MAX_AGE          cookie lifetime, defaults to 7 days
+func sessionCookieOptions(env string) *sessions.Options {
+	opts := &sessions.Options{
+		Path:     "/",
+		MaxAge:   int(envDuration("SESSION_MAX_AGE", defaultSessionMaxAge).Seconds()),
+		Secure:   envBool("SESSION_COOKIE_SECURE", env == "production"),
+		HttpOnly: envBool("SESSION_COOKIE_HTTPONLY", true),
+		SameSite: parseSameSite(envy.Get("SESSION_COOKIE_SAMESITE", "lax")),
+	}
+	// Browsers reject SameSite=None cookies that aren't Secure.
+	if opts.SameSite == http.SameSiteNoneMode {
+		opts.Secure = true
+	}
+	return opts
+}
+
+// serverSessions is set when sessions are kept server-side (SESSION_STORE=postgres)
+// and is nil with the default cookie store.
+var serverSessions *sessionstore.Store
+
+// newSessionStore creates the session store selected by SESSION_STORE with
+// hardened cookie options in place of Buffalo's defaults:
+//
+//	cookie (default)  values live in a signed cookie
+//	postgres          values are encrypted at rest in the http_sessions table,
+//	                  keyed by SESSION_ENCRYPTION_KEY (base64, 32 bytes) or a
+//	                  key derived from the session secret
+func newSessionStore(secret, env string) sessions.Store {
+	opts := sessionCookieOptions(env)
+
+	if strings.EqualFold(envy.Get("SESSION_STORE", "cookie"), "postgres") {
+		var key []byte
+		if raw := envy.Get("SESSION_ENCRYPTION_KEY", ""); raw != "" {
+			decoded, err := base64.StdEncoding.DecodeString(raw)
+			if err != nil {
+				logging.Error("SESSION_ENCRYPTION_KEY is not valid base64, falling back to cookie sessions", err)
+				return newCookieSessionStore(secret, opts)
+			}
+			key = decoded
+		}
+
+		store, err := sessionstore.New(models.DB, []byte(secret), key)
+		if err != nil {
+			logging.Error("Failed to create server-side session store, falling back to cookie sessions", err)
+			return newCookieSessionStore(secret, opts)
+		}
+		store.Options = opts
+		serverSessions = store
+		return store
+	}
+
+	return newCookieSessionStore(secret, opts)
+}
+
+func newCookieSessionStore(secret string, opts *sessions.Options) *sessions.CookieStore {
+	store := sessions.NewCookieStore([]byte(secret))
+	store.Options = opts
+	return store
+}
+
+// sessionAbsoluteLifetime is the maximum age of a logged-in session,
+// regardless of activity (SESSION_ABSOLUTE_LIFETIME, default 24h).
+func sessionAbsoluteLifetime() time.Duration {
+	return envDuration("SESSION_ABSOLUTE_LIFETIME", defaultSessionAbsoluteLifetime)
+}
+
+// sessionExpired reports whether a session started at startedAt (unix
+// seconds) has outlived the absolute lifetime.
+func sessionExpired(startedAt int64, now time.Time, lifetime time.Duration) bool {
+	return now.Sub(time.Unix(startedAt, 0)) > lifetime
+}
+
+// rotateSession discards everything stored in the session and starts a fresh
+// one for the given user. Called on login and whenever the user's privileges
+// change so nothing granted under the old identity (step-up, redirects,
+// donation state) carries over.
+func rotateSession(c buffalo.Context, userID interface{}, role string) {
+	sess := c.Session()
+	sess.Clear()
+	if serverSessions != nil {
+		if err := serverSessions.Rotate(sess.Session); err != nil {
+			logging.Error("Failed to rotate server-side session", err)
+		}
+	}
+	sess.Set("current_user_id", userID)
+	sess.Set(sessionRoleKey, role)
+	sess.Set(sessionStartedAtKey, time.Now().Unix())
+}
+
+// EnforceSessionLifetime logs users out once their session passes the
+// absolute lifetime. Sessions created before this was introduced get a start
+// time stamped on first sight.
+func EnforceSessionLifetime(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		sess := c.Session()
+		uid := sess.Get("current_user_id")
+		if uid == nil {
+			return next(c)
+		}
+
+		startedAt, ok := sess.Get(sessionStartedAtKey).(int64)
+		if !ok {
+			sess.Set(sessionStartedAtKey, time.Now().Unix())
+			return next(c)
+		}
+
+		if sessionExpired(startedAt, time.Now(), sessionAbsoluteLifetime()) {
+			logging.SecurityEvent(c, "session_expired", "success", "absolute_lifetime_exceeded", logging.Fields{
+				"user_id": uid,
+			})
+			sess.Clear()
+			c.Flash().Add("warning", "Your session has expired. Please sign in again.")
+			return c.Redirect(http.StatusFound, "/auth/new")
+		}
+
+		return next(c)
+	}
+}
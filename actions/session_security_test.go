@@ -0,0 +1,51 @@
+package actions
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SessionCookieOptions_Defaults(t *testing.T) {
+	envy.Temp(func() {
+		for _, k := range []string{"SESSION_COOKIE_SAMESITE", "SESSION_COOKIE_SECURE", "SESSION_COOKIE_HTTPONLY", "SESSION_MAX_AGE"} {
+			envy.Set(k, "")
+		}
+
+		opts := sessionCookieOptions("production")
+		assert.True(t, opts.Secure)
+		assert.True(t, opts.HttpOnly)
+		assert.Equal(t, http.SameSiteLaxMode, opts.SameSite)
+		assert.Equal(t, int(defaultSessionMaxAge.Seconds()), opts.MaxAge)
+
+		assert.False(t, sessionCookieOptions("development").Secure)
+	})
+}
+
+func Test_SessionCookieOptions_Overrides(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("SESSION_COOKIE_SAMESITE", "Strict")
+		envy.Set("SESSION_COOKIE_SECURE", "false")
+		envy.Set("SESSION_COOKIE_HTTPONLY", "false")
+		envy.Set("SESSION_MAX_AGE", "2h")
+
+		opts := sessionCookieOptions("production")
+		assert.Equal(t, http.SameSiteStrictMode, opts.SameSite)
+		assert.False(t, opts.Secure)
+		assert.False(t, opts.HttpOnly)
+		assert.Equal(t, 7200, opts.MaxAge)
+
+		// SameSite=None always forces Secure
+		envy.Set("SESSION_COOKIE_SAMESITE", "none")
+		assert.True(t, sessionCookieOptions("development").Secure)
+	})
+}
+
+func Test_SessionExpired(t *testing.T) {
+	now := time.Now()
+	assert.False(t, sessionExpired(now.Add(-time.Hour).Unix(), now, 24*time.Hour))
+	assert.True(t, sessionExpired(now.Add(-25*time.Hour).Unix(), now, 24*time.Hour))
+}
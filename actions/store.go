@@ -0,0 +1,497 @@
+package actions
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// maxStoreQuantity caps how many of one product a single order can hold
+const maxStoreQuantity = 20
+
+// FulfillmentCounts is the number of paid store orders at each fulfillment
+// step, for the store dashboard
+type FulfillmentCounts struct {
+	Unfulfilled int
+	Packed      int
+	Shipped     int
+}
+
+// storeOrderItems reads the quantity fields of the store form, one per
+// product, into order lines. It reports the first product the request can't
+// be filled for.
+func storeOrderItems(c buffalo.Context, products models.Products) (models.StoreOrderItems, string) {
+	items := models.StoreOrderItems{}
+	for _, product := range products {
+		raw := strings.TrimSpace(c.Param("qty_" + product.ID.String()))
+		if raw == "" || raw == "0" {
+			continue
+		}
+		quantity, err := strconv.Atoi(raw)
+		if err != nil || quantity < 0 || quantity > maxStoreQuantity {
+			return nil, fmt.Sprintf("Choose up to %d of %s.", maxStoreQuantity, product.Name)
+		}
+		if quantity > product.Inventory {
+			return nil, fmt.Sprintf("Only %d of %s left in stock.", product.Inventory, product.Name)
+		}
+		items = append(items, models.StoreOrderItem{
+			ProductID:   product.ID,
+			ProductName: product.Name,
+			Quantity:    quantity,
+			UnitPrice:   product.Price,
+		})
+	}
+	return items, ""
+}
+
+// orderLines converts store order items for the shipping, tax and receipt
+// services
+func orderLines(items models.StoreOrderItems) []services.OrderLine {
+	lines := make([]services.OrderLine, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, services.OrderLine{
+			ID:          item.ProductID.String(),
+			Description: item.ProductName,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+		})
+	}
+	return lines
+}
+
+// priceStoreOrder fills in the order's subtotal, shipping, sales tax and
+// total using the configured shipping and tax calculators.
+func priceStoreOrder(order *models.StoreOrder, items models.StoreOrderItems) error {
+	taxes, err := services.NewTaxCalculator()
+	if err != nil {
+		return err
+	}
+	to := services.ShippingAddress{Line1: order.AddressLine1, City: order.City, State: order.State, Zip: order.Zip}
+	lines := orderLines(items)
+
+	shipping, err := services.NewShippingCalculator().ShippingRate(to, lines)
+	if err != nil {
+		return err
+	}
+	tax, err := taxes.SalesTax(to, lines, shipping)
+	if err != nil {
+		return err
+	}
+
+	order.Subtotal = items.Subtotal()
+	order.Shipping = shipping
+	order.SalesTax = tax
+	order.Total = math.Round((order.Subtotal+shipping+tax)*100) / 100
+	return nil
+}
+
+// addStoreOrderToReceipt itemizes a store order's lines, shipping and tax
+// on the receipt for the donation that paid for it. Other donations are
+// left alone.
+func addStoreOrderToReceipt(tx *pop.Connection, donation *models.Donation, receipt *services.DonationReceiptData) {
+	order := &models.StoreOrder{}
+	if err := tx.Where("donation_id = ?", donation.ID).First(order); err != nil {
+		return
+	}
+	items := models.StoreOrderItems{}
+	if err := tx.Where("order_id = ?", order.ID).Order("product_name asc").All(&items); err != nil {
+		return
+	}
+	receipt.OrderItems = orderLines(items)
+	receipt.Shipping = order.Shipping
+	receipt.SalesTax = order.SalesTax
+}
+
+// completeStoreOrder marks a charged store order paid and takes its items
+// out of inventory. Other donations are left alone.
+func completeStoreOrder(c buffalo.Context, tx *pop.Connection, donation *models.Donation) {
+	order := &models.StoreOrder{}
+	if err := tx.Where("donation_id = ? AND status = ?", donation.ID, models.OrderStatusPending).First(order); err != nil {
+		return
+	}
+	order.Status = models.OrderStatusPaid
+	if err := tx.Update(order); err != nil {
+		c.Logger().Errorf("[Store] Failed to mark order %s paid: %v", order.ID.String(), err)
+		return
+	}
+
+	items := models.StoreOrderItems{}
+	if err := tx.Where("order_id = ?", order.ID).All(&items); err != nil {
+		c.Logger().Errorf("[Store] Failed to load items for order %s: %v", order.ID.String(), err)
+		return
+	}
+	for _, item := range items {
+		// Stock is checked at checkout but not held, so two buyers can race
+		// for the last one; never count below zero
+		err := tx.RawQuery("UPDATE products SET inventory = GREATEST(inventory - ?, 0), updated_at = ? WHERE id = ?",
+			item.Quantity, time.Now(), item.ProductID).Exec()
+		if err != nil {
+			c.Logger().Errorf("[Store] Failed to update inventory for product %s: %v", item.ProductID.String(), err)
+		}
+	}
+
+	logging.Audit("store_order_paid", logging.Fields{
+		"order_id":    order.ID.String(),
+		"donation_id": donation.ID.String(),
+		"total":       order.Total,
+		"items":       items.Summary(),
+	})
+}
+
+// StoreIndex shows the merchandise for sale with the order form
+func StoreIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	products := models.Products{}
+	if err := tx.Where("active = ?", true).Order("name asc").All(&products); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("title", "Store")
+	c.Set("products", products)
+	c.Set("maxQuantity", maxStoreQuantity)
+	return c.Render(http.StatusOK, r.HTML("pages/store.plush.html"))
+}
+
+// StoreCheckout records an order from the store form and sends the buyer to
+// the payment page. Merchandise is bought at its full price, so none of the
+// payment is deductible.
+func StoreCheckout(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	products := models.Products{}
+	if err := tx.Where("active = ?", true).All(&products); err != nil {
+		return errors.WithStack(err)
+	}
+	items, problem := storeOrderItems(c, products)
+	if problem != "" {
+		c.Flash().Add("danger", problem)
+		return c.Redirect(http.StatusSeeOther, "/store")
+	}
+	if len(items) == 0 {
+		c.Flash().Add("danger", "Choose at least one item.")
+		return c.Redirect(http.StatusSeeOther, "/store")
+	}
+
+	order := &models.StoreOrder{
+		Name:              strings.TrimSpace(c.Param("name")),
+		Email:             strings.ToLower(strings.TrimSpace(c.Param("email"))),
+		AddressLine1:      strings.TrimSpace(c.Param("address_line1")),
+		AddressLine2:      stringPointer(strings.TrimSpace(c.Param("address_line2"))),
+		City:              strings.TrimSpace(c.Param("city")),
+		State:             strings.TrimSpace(c.Param("state")),
+		Zip:               strings.TrimSpace(c.Param("zip")),
+		Status:            models.OrderStatusPending,
+		FulfillmentStatus: models.FulfillmentUnfulfilled,
+	}
+	verrs, err := order.Validate(tx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", "Please enter your name, email and complete shipping address.")
+		return c.Redirect(http.StatusSeeOther, "/store")
+	}
+	if err := priceStoreOrder(order, items); err != nil {
+		c.Logger().Errorf("[Store] Failed to price order for %s: %v", order.State, err)
+		c.Flash().Add("error", "We couldn't calculate shipping and tax for that address. Please check it and try again.")
+		return c.Redirect(http.StatusSeeOther, "/store")
+	}
+
+	summary := items.Summary()
+	donation := &models.Donation{
+		DonorName:        order.Name,
+		DonorEmail:       order.Email,
+		Amount:           order.Total,
+		Currency:         getCurrency(),
+		DonationType:     models.DonationTypeOneTime,
+		Status:           "pending",
+		AddressLine1:     stringPointer(order.AddressLine1),
+		AddressLine2:     order.AddressLine2,
+		City:             stringPointer(order.City),
+		State:            stringPointer(order.State),
+		Zip:              stringPointer(order.Zip),
+		Comments:         stringPointer("Store order: " + summary),
+		FairMarketValue:  &order.Total,
+		GoodsDescription: stringPointer(summary),
+	}
+	if currentUser, ok := c.Value("current_user").(*models.User); ok && currentUser != nil {
+		donation.UserID = &currentUser.ID
+	}
+	if err := tx.Create(donation); err != nil {
+		return errors.WithStack(err)
+	}
+
+	order.DonationID = donation.ID
+	if err := tx.Create(order); err != nil {
+		return errors.WithStack(err)
+	}
+	for i := range items {
+		items[i].OrderID = order.ID
+		if err := tx.Create(&items[i]); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := beginCheckout(c, tx, donation); err != nil {
+		c.Logger().Errorf("[Store] Failed to start checkout for order %s: %v", order.ID.String(), err)
+		c.Flash().Add("error", "Payment system unavailable. Please try again later.")
+		return c.Redirect(http.StatusSeeOther, "/store")
+	}
+	return nil
+}
+
+// bindProduct copies the admin product form onto product.
+func bindProduct(c buffalo.Context, product *models.Product) {
+	product.Name = strings.TrimSpace(c.Param("Name"))
+	product.Description = stringPointer(strings.TrimSpace(c.Param("Description")))
+	product.Price, _ = strconv.ParseFloat(strings.TrimSpace(c.Param("Price")), 64)
+	product.Inventory, _ = strconv.Atoi(strings.TrimSpace(c.Param("Inventory")))
+	active := c.Param("Active")
+	product.Active = active == "true" || active == "on"
+}
+
+// setProductFormContext exposes a product to the admin product form
+func setProductFormContext(c buffalo.Context, product *models.Product) {
+	c.Set("product", product)
+	c.Set("productDescription", product.DescriptionText())
+}
+
+// loadStoreOrder finds the order named in the route with its items.
+func loadStoreOrder(c buffalo.Context, tx *pop.Connection) (*models.StoreOrder, models.StoreOrderItems, error) {
+	order := &models.StoreOrder{}
+	if err := tx.Find(order, c.Param("order_id")); err != nil {
+		return nil, nil, c.Error(http.StatusNotFound, err)
+	}
+	items := models.StoreOrderItems{}
+	if err := tx.Where("order_id = ?", order.ID).Order("product_name asc").All(&items); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return order, items, nil
+}
+
+// AdminStoreIndex lists store products with their stock and how many paid
+// orders are waiting on volunteers
+func AdminStoreIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	products := models.Products{}
+	if err := tx.Order("name asc").All(&products); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var rows []struct {
+		FulfillmentStatus string `db:"fulfillment_status"`
+		Orders            int    `db:"orders"`
+	}
+	err := tx.RawQuery("SELECT fulfillment_status, COUNT(*) as orders FROM store_orders WHERE status = ? GROUP BY fulfillment_status",
+		models.OrderStatusPaid).All(&rows)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	counts := FulfillmentCounts{}
+	for _, row := range rows {
+		switch row.FulfillmentStatus {
+		case models.FulfillmentUnfulfilled:
+			counts.Unfulfilled = row.Orders
+		case models.FulfillmentPacked:
+			counts.Packed = row.Orders
+		case models.FulfillmentShipped:
+			counts.Shipped = row.Orders
+		}
+	}
+
+	c.Set("products", products)
+	c.Set("counts", counts)
+	return c.Render(http.StatusOK, r.HTML("admin/store/index.plush.html"))
+}
+
+// AdminProductsNew shows the form for adding a product
+func AdminProductsNew(c buffalo.Context) error {
+	setProductFormContext(c, &models.Product{Active: true})
+	return c.Render(http.StatusOK, r.HTML("admin/store/new.plush.html"))
+}
+
+// AdminProductsCreate saves a new product
+func AdminProductsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	product := &models.Product{}
+	bindProduct(c, product)
+
+	verrs, err := tx.ValidateAndCreate(product)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		setProductFormContext(c, product)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/store/new.plush.html"))
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "product_created", fmt.Sprintf("Created store product: %s", product.Name), logging.Fields{
+		"product_id": product.ID.String(),
+		"price":      product.Price,
+		"inventory":  product.Inventory,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Product \"%s\" created.", product.Name))
+	return c.Redirect(http.StatusSeeOther, "/admin/store")
+}
+
+// AdminProductsEdit shows the form for editing a product
+func AdminProductsEdit(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	product := &models.Product{}
+	if err := tx.Find(product, c.Param("product_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	setProductFormContext(c, product)
+	return c.Render(http.StatusOK, r.HTML("admin/store/edit.plush.html"))
+}
+
+// AdminProductsUpdate saves changes to a product, including restocks
+func AdminProductsUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	product := &models.Product{}
+	if err := tx.Find(product, c.Param("product_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	bindProduct(c, product)
+
+	verrs, err := tx.ValidateAndUpdate(product)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		setProductFormContext(c, product)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/store/edit.plush.html"))
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "product_updated", fmt.Sprintf("Updated store product: %s", product.Name), logging.Fields{
+		"product_id": product.ID.String(),
+		"price":      product.Price,
+		"inventory":  product.Inventory,
+		"active":     product.Active,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Product \"%s\" updated.", product.Name))
+	return c.Redirect(http.StatusSeeOther, "/admin/store")
+}
+
+// AdminStoreOrdersIndex lists paid store orders, filtered to one
+// fulfillment status (unfulfilled by default)
+func AdminStoreOrdersIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	fulfillment := c.Param("fulfillment")
+	valid := false
+	for _, status := range models.FulfillmentStatuses {
+		valid = valid || status == fulfillment
+	}
+	if !valid {
+		fulfillment = models.FulfillmentUnfulfilled
+	}
+
+	orders := models.StoreOrders{}
+	err := tx.Where("status = ? AND fulfillment_status = ?", models.OrderStatusPaid, fulfillment).
+		Order("created_at asc").All(&orders)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("orders", orders)
+	c.Set("fulfillment", fulfillment)
+	c.Set("fulfillmentStatuses", models.FulfillmentStatuses)
+	return c.Render(http.StatusOK, r.HTML("admin/store/orders.plush.html"))
+}
+
+// AdminStoreOrderShow shows a store order with its fulfillment form
+func AdminStoreOrderShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	order, items, err := loadStoreOrder(c, tx)
+	if err != nil {
+		return err
+	}
+
+	c.Set("order", order)
+	c.Set("items", items)
+	c.Set("fulfillmentStatuses", models.FulfillmentStatuses)
+	return c.Render(http.StatusOK, r.HTML("admin/store/order.plush.html"))
+}
+
+// AdminStoreOrderFulfillment moves an order to a new fulfillment status,
+// recording the tracking number and ship date when it goes out
+func AdminStoreOrderFulfillment(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	order := &models.StoreOrder{}
+	if err := tx.Find(order, c.Param("order_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if order.Status != models.OrderStatusPaid {
+		c.Flash().Add("warning", "This order hasn't been paid for yet.")
+		return c.Redirect(http.StatusSeeOther, "/admin/store/orders/%s", order.ID)
+	}
+
+	previous := order.FulfillmentStatus
+	order.FulfillmentStatus = c.Param("FulfillmentStatus")
+	order.TrackingNumber = stringPointer(strings.TrimSpace(c.Param("TrackingNumber")))
+	if order.FulfillmentStatus == models.FulfillmentShipped && order.ShippedAt == nil {
+		now := time.Now()
+		order.ShippedAt = &now
+	}
+
+	verrs, err := tx.ValidateAndUpdate(order)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", "Choose a valid fulfillment status.")
+		return c.Redirect(http.StatusSeeOther, "/admin/store/orders/%s", order.ID)
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "store_order_fulfillment", fmt.Sprintf("Marked order %s %s", order.OrderNumber(), order.FulfillmentStatus), logging.Fields{
+		"order_id":        order.ID.String(),
+		"previous_status": previous,
+		"status":          order.FulfillmentStatus,
+		"tracking_number": order.TrackingNumberText(),
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Order %s marked %s.", order.OrderNumber(), order.FulfillmentStatus))
+	return c.Redirect(http.StatusSeeOther, "/admin/store/orders/%s", order.ID)
+}
+
+// AdminStoreOrderPackingSlip shows a printable packing slip for volunteers
+// to put in the box
+func AdminStoreOrderPackingSlip(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	order, items, err := loadStoreOrder(c, tx)
+	if err != nil {
+		return err
+	}
+
+	c.Set("order", order)
+	c.Set("items", items)
+	return c.Render(http.StatusOK, r.HTML("admin/store/packing_slip.plush.html"))
+}
@@ -0,0 +1,141 @@
+package actions
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// subscriptionReconciliation is what a reconciliation run found
+type subscriptionReconciliation struct {
+	Checked   int
+	Cancelled int
+	Paused    int
+	Resumed   int
+	Failed    int
+}
+
+// donationStatusForSubscription is the donation status matching a Helcim
+// subscription status, or "" for one we don't act on
+func donationStatusForSubscription(helcimStatus string) string {
+	switch strings.ToLower(strings.TrimSpace(helcimStatus)) {
+	case "active":
+		return "active"
+	case "paused", "on_hold", "suspended":
+		return models.DonationStatusPaused
+	case "cancelled", "canceled", "inactive", "expired":
+		return "cancelled"
+	}
+	return ""
+}
+
+// reconcilable reports whether a donation in status follows its
+// subscription's status in Helcim
+func reconcilable(status string) bool {
+	return status == "active" || status == models.DonationStatusPaused
+}
+
+// syncSubscriptionStatus copies a subscription's current state from Helcim
+// onto its donation, and corrects the donation's status when the
+// subscription was cancelled, paused or resumed in the Helcim dashboard
+// rather than here. Only active and paused gifts have their status
+// changed; one still being set up or already cancelled here is left as it
+// is. It returns the donation's status before the sync. A failure to reach
+// Helcim is kept on the donation as its sync error.
+func syncSubscriptionStatus(ctx context.Context, tx *pop.Connection, client services.HelcimAPI, donation *models.Donation, now time.Time) (string, error) {
+	previous := donation.Status
+	subscription, err := client.GetSubscription(ctx, *donation.SubscriptionID)
+	if err != nil {
+		donation.SyncError = stringPointer(err.Error())
+		if uerr := tx.UpdateColumns(donation, "sync_error", "updated_at"); uerr != nil {
+			logging.Error("subscription_sync_error_update_failed", uerr, logging.Fields{
+				"donation_id": donation.ID.String(),
+			})
+		}
+		return previous, err
+	}
+
+	applySubscriptionSync(donation, subscription, now)
+	columns := []string{"subscription_status", "next_billing_date", "last_status_sync", "sync_error", "updated_at"}
+	status := donationStatusForSubscription(subscription.Status)
+	if reconcilable(previous) && status != "" && status != previous {
+		donation.Status = status
+		columns = append(columns, "status")
+	}
+	if err := tx.UpdateColumns(donation, columns...); err != nil {
+		return previous, errors.WithStack(err)
+	}
+	if donation.Status == previous {
+		return previous, nil
+	}
+
+	logging.Audit("subscription_status_reconciled", logging.Fields{
+		"donation_id":     donation.ID.String(),
+		"subscription_id": *donation.SubscriptionID,
+		"from":            previous,
+		"to":              donation.Status,
+		"helcim_status":   subscription.Status,
+	})
+	if donation.Status == "cancelled" {
+		// Nothing is left to retry on a subscription cancelled in Helcim
+		failure, err := openPaymentFailure(tx, donation.ID)
+		if err != nil {
+			return previous, err
+		}
+		if failure != nil {
+			failure.Resolve(models.PaymentFailureCancelled, now)
+			if err := tx.Update(failure); err != nil {
+				return previous, errors.WithStack(err)
+			}
+		}
+	}
+	return previous, nil
+}
+
+// ReconcileSubscriptions checks every active or paused monthly gift against
+// its Helcim subscription, so ones cancelled or paused in the Helcim
+// dashboard stop showing as active here and ones resumed there show as
+// active again. Helcim failing for one subscription is logged and the rest
+// are still checked. It's run from cron by the subscriptions:reconcile
+// grift.
+func ReconcileSubscriptions(ctx context.Context, tx *pop.Connection, client services.HelcimAPI, now time.Time) (subscriptionReconciliation, error) {
+	result := subscriptionReconciliation{}
+	donations := models.Donations{}
+	err := tx.Where("subscription_id IS NOT NULL AND subscription_id <> '' AND status IN (?, ?)", "active", models.DonationStatusPaused).
+		Order("last_status_sync ASC NULLS FIRST").All(&donations)
+	if err != nil {
+		return result, errors.WithStack(err)
+	}
+
+	for i := range donations {
+		donation := &donations[i]
+		result.Checked++
+		previous, err := syncSubscriptionStatus(ctx, tx, client, donation, now)
+		if err != nil {
+			result.Failed++
+			logging.Warn("subscription_reconcile_failed", logging.Fields{
+				"donation_id":     donation.ID.String(),
+				"subscription_id": *donation.SubscriptionID,
+				"error":           err.Error(),
+			})
+			continue
+		}
+		switch {
+		case donation.Status == previous:
+		case donation.Status == "cancelled":
+			result.Cancelled++
+		case donation.Status == models.DonationStatusPaused:
+			result.Paused++
+		case donation.Status == "active":
+			result.Resumed++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,34 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_DonationStatusForSubscription(t *testing.T) {
+	req := require.New(t)
+
+	req.Equal("active", donationStatusForSubscription("active"))
+	req.Equal("active", donationStatusForSubscription(" Active "))
+	req.Equal(models.DonationStatusPaused, donationStatusForSubscription("paused"))
+	req.Equal(models.DonationStatusPaused, donationStatusForSubscription("on_hold"))
+	req.Equal("cancelled", donationStatusForSubscription("cancelled"))
+	req.Equal("cancelled", donationStatusForSubscription("canceled"))
+	req.Equal("cancelled", donationStatusForSubscription("expired"))
+	// Statuses we don't recognise leave the donation alone
+	req.Equal("", donationStatusForSubscription(""))
+	req.Equal("", donationStatusForSubscription("pending"))
+}
+
+func Test_Reconcilable(t *testing.T) {
+	req := require.New(t)
+
+	req.True(reconcilable("active"))
+	req.True(reconcilable(models.DonationStatusPaused))
+	req.False(reconcilable("pending"))
+	req.False(reconcilable("cancelled"))
+	req.False(reconcilable("completed"))
+}
@@ -0,0 +1,298 @@
+package actions
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// contactSuppressed reports whether recipient is on the suppression list,
+// and records the skipped send when it is. Sends we're obliged to make, like
+// receipts, don't check. If the list can't be read the send is treated as
+// suppressed, since contacting someone who opted out is worse than a missed
+// thank-you.
+func contactSuppressed(tx *pop.Connection, channel, recipient, purpose string) bool {
+	suppression, err := models.FindSuppression(tx, channel, recipient)
+	if err != nil {
+		logging.Error("failed to check suppression list", err, logging.Fields{
+			"channel": channel,
+			"purpose": purpose,
+		})
+		return true
+	}
+	if suppression == nil {
+		return false
+	}
+
+	send := &models.SuppressedSend{
+		SuppressionID: suppression.ID,
+		Channel:       channel,
+		Recipient:     suppression.Value,
+		Purpose:       purpose,
+	}
+	if err := tx.Create(send); err != nil {
+		logging.Error("failed to record suppressed send", err, logging.Fields{
+			"suppression_id": suppression.ID.String(),
+		})
+	}
+	logging.Audit("send_suppressed", logging.Fields{
+		"suppression_id": suppression.ID.String(),
+		"channel":        channel,
+		"purpose":        purpose,
+	})
+	return true
+}
+
+// parseSuppressionCSV reads a suppression list with a header row. Only the
+// value column is required; kind defaults to email, and a reason column is
+// kept when present. Rows that can't be used are returned as errors.
+func parseSuppressionCSV(r io.Reader) (models.Suppressions, []error, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	index := map[string]int{}
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	if _, ok := index["value"]; !ok {
+		return nil, nil, errors.New("the file is missing the value column")
+	}
+
+	var suppressions models.Suppressions
+	var rowErrs []error
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			rowErrs = append(rowErrs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		get := func(field string) string {
+			i, ok := index[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return csvCellValue(strings.TrimSpace(record[i]))
+		}
+
+		kind := strings.ToLower(get("kind"))
+		if kind == "" {
+			kind = models.SuppressEmail
+		}
+		suppression := models.Suppression{
+			Kind:   kind,
+			Value:  models.NormalizeSuppressionValue(kind, get("value")),
+			Reason: stringPointer(get("reason")),
+			Source: models.SuppressionImport,
+		}
+		verrs, _ := suppression.Validate(nil)
+		if verrs.HasAny() {
+			rowErrs = append(rowErrs, fmt.Errorf("line %d: %s", line, strings.TrimSpace(verrs.String())))
+			continue
+		}
+		suppressions = append(suppressions, suppression)
+	}
+	return suppressions, rowErrs, nil
+}
+
+// writeSuppressionCSV writes the suppression list as CSV in the format
+// parseSuppressionCSV reads back.
+func writeSuppressionCSV(w io.Writer, suppressions models.Suppressions) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"kind", "value", "reason", "source", "added"}); err != nil {
+		return err
+	}
+	for _, s := range suppressions {
+		row := []string{s.Kind, csvCell(s.Value), csvCell(s.ReasonText()), s.Source, s.CreatedAt.Format(dateInputLayout)}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// AdminSuppressionsIndex lists suppressed contact details and the sends they
+// have stopped
+func AdminSuppressionsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	q := tx.Order("created_at desc")
+	search := strings.TrimSpace(c.Param("q"))
+	if search != "" {
+		q = q.Where("value LIKE ?", "%"+strings.ToLower(search)+"%")
+	}
+	suppressions := models.Suppressions{}
+	if err := q.Limit(200).All(&suppressions); err != nil {
+		return errors.WithStack(err)
+	}
+	total, err := tx.Count(&models.Suppression{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	sends := models.SuppressedSends{}
+	if err := tx.Order("created_at desc").Limit(50).All(&sends); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("suppressions", suppressions)
+	c.Set("total", total)
+	c.Set("search", search)
+	c.Set("sends", sends)
+	c.Set("suppressionKinds", models.SuppressionKinds)
+	c.Set("kindLabel", models.SuppressionKindLabel)
+	return c.Render(http.StatusOK, r.HTML("admin/suppressions/index.plush.html"))
+}
+
+// AdminSuppressionsCreate adds one contact detail to the suppression list
+func AdminSuppressionsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	kind := c.Param("Kind")
+	suppression := &models.Suppression{
+		Kind:      kind,
+		Value:     models.NormalizeSuppressionValue(kind, c.Param("Value")),
+		Reason:    stringPointer(strings.TrimSpace(c.Param("Reason"))),
+		Source:    models.SuppressionManual,
+		CreatedBy: &currentUser.ID,
+	}
+
+	existing, err := models.FindSuppression(tx, suppression.Kind, suppression.Value)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		c.Flash().Add("info", fmt.Sprintf("%s is already suppressed.", existing.Value))
+		return c.Redirect(http.StatusSeeOther, "/admin/suppressions")
+	}
+
+	verrs, err := tx.ValidateAndCreate(suppression)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+		return c.Redirect(http.StatusSeeOther, "/admin/suppressions")
+	}
+
+	logging.UserAction(c, currentUser.ID.String(), "suppression_added", fmt.Sprintf("Suppressed %s %s", suppression.Kind, suppression.Value), logging.Fields{
+		"suppression_id": suppression.ID.String(),
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("%s will no longer be contacted.", suppression.Value))
+	return c.Redirect(http.StatusSeeOther, "/admin/suppressions")
+}
+
+// AdminSuppressionsImport adds the contact details in an uploaded CSV to the
+// suppression list, skipping ones already on it
+func AdminSuppressionsImport(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	f, err := c.File("file")
+	if err != nil || f.File == nil {
+		c.Flash().Add("danger", "Please choose a CSV file to import.")
+		return c.Redirect(http.StatusSeeOther, "/admin/suppressions")
+	}
+	defer f.Close()
+
+	suppressions, rowErrs, err := parseSuppressionCSV(f)
+	if err != nil {
+		c.Flash().Add("danger", fmt.Sprintf("Could not read %s: %v", f.Filename, err))
+		return c.Redirect(http.StatusSeeOther, "/admin/suppressions")
+	}
+
+	imported, duplicates := 0, 0
+	for i := range suppressions {
+		suppression := &suppressions[i]
+		existing, err := models.FindSuppression(tx, suppression.Kind, suppression.Value)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			duplicates++
+			continue
+		}
+		suppression.CreatedBy = &currentUser.ID
+		if err := tx.Create(suppression); err != nil {
+			return errors.WithStack(err)
+		}
+		imported++
+	}
+
+	logging.UserAction(c, currentUser.ID.String(), "suppressions_imported", fmt.Sprintf("Imported suppression list %s", f.Filename), logging.Fields{
+		"imported":   imported,
+		"duplicates": duplicates,
+		"row_errors": len(rowErrs),
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Suppressed %d contact details from %s (%d already on the list).", imported, f.Filename, duplicates))
+	for i, rowErr := range rowErrs {
+		if i == 10 {
+			c.Flash().Add("warning", fmt.Sprintf("...and %d more rows that could not be read.", len(rowErrs)-i))
+			break
+		}
+		c.Flash().Add("warning", rowErr.Error())
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/suppressions")
+}
+
+// AdminSuppressionsExport downloads the whole suppression list as CSV
+func AdminSuppressionsExport(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	suppressions := models.Suppressions{}
+	if err := tx.Order("kind asc, value asc").All(&suppressions); err != nil {
+		return errors.WithStack(err)
+	}
+
+	filename := fmt.Sprintf("suppressions-%s.csv", time.Now().Format(dateInputLayout))
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	return c.Render(http.StatusOK, r.Func("text/csv", func(w io.Writer, d render.Data) error {
+		return writeSuppressionCSV(w, suppressions)
+	}))
+}
+
+// AdminSuppressionsDelete takes a contact detail off the suppression list
+func AdminSuppressionsDelete(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	currentUser := c.Value("current_user").(*models.User)
+
+	suppression := &models.Suppression{}
+	if err := tx.Find(suppression, c.Param("suppression_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if err := tx.Destroy(suppression); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logging.UserAction(c, currentUser.ID.String(), "suppression_removed", fmt.Sprintf("Removed %s %s from the suppression list", suppression.Kind, suppression.Value), logging.Fields{
+		"suppression_id": suppression.ID.String(),
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("%s can be contacted again.", suppression.Value))
+	return c.Redirect(http.StatusSeeOther, "/admin/suppressions")
+}
@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_ParseSuppressionCSV(t *testing.T) {
+	req := require.New(t)
+
+	input := "Value,Kind,Reason\n" +
+		"Sam@Example.com,,Unsubscribed by phone\n" +
+		"(555) 010-2000,phone,\n" +
+		"not-an-email,email,\n" +
+		"12 Oak St.,address,Returned mail\n"
+
+	suppressions, rowErrs, err := parseSuppressionCSV(strings.NewReader(input))
+	req.NoError(err)
+	req.Len(suppressions, 3)
+	req.Len(rowErrs, 1)
+	req.Contains(rowErrs[0].Error(), "line 4")
+
+	req.Equal(models.SuppressEmail, suppressions[0].Kind)
+	req.Equal("sam@example.com", suppressions[0].Value)
+	req.Equal("Unsubscribed by phone", suppressions[0].ReasonText())
+	req.Equal("5550102000", suppressions[1].Value)
+	req.Nil(suppressions[1].Reason)
+	req.Equal("12 oak st", suppressions[2].Value)
+	req.Equal(models.SuppressionImport, suppressions[2].Source)
+
+	_, _, err = parseSuppressionCSV(strings.NewReader("email\nsam@example.com\n"))
+	req.Error(err)
+}
+
+func Test_WriteSuppressionCSVRoundTrip(t *testing.T) {
+	req := require.New(t)
+
+	reason := "Returned mail"
+	added := time.Date(2026, 10, 14, 0, 0, 0, 0, time.Local)
+	var buf bytes.Buffer
+	req.NoError(writeSuppressionCSV(&buf, models.Suppressions{
+		{Kind: models.SuppressAddress, Value: "12 oak st", Reason: &reason, Source: models.SuppressionManual, CreatedAt: added},
+		{Kind: models.SuppressEmail, Value: "=sam@example.com", Source: models.SuppressionManual, CreatedAt: added},
+	}))
+	req.Equal("kind,value,reason,source,added\naddress,12 oak st,Returned mail,manual,2026-10-14\nemail,'=sam@example.com,,manual,2026-10-14\n", buf.String())
+
+	suppressions, rowErrs, err := parseSuppressionCSV(&buf)
+	req.NoError(err)
+	req.Empty(rowErrs)
+	req.Len(suppressions, 2)
+	req.Equal("12 oak st", suppressions[0].Value)
+	req.Equal("=sam@example.com", suppressions[1].Value)
+}
+
+func Test_SuppressionsTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/suppressions-test", func(c buffalo.Context) error {
+		c.Set("suppressions", models.Suppressions{
+			{Kind: models.SuppressPhone, Value: "5550102000", Source: models.SuppressionImport, CreatedAt: time.Now()},
+		})
+		c.Set("total", 1)
+		c.Set("search", "")
+		c.Set("sends", models.SuppressedSends{
+			{Recipient: "5550102000", Purpose: "PayPal/Venmo thank-you", CreatedAt: time.Now()},
+		})
+		c.Set("suppressionKinds", models.SuppressionKinds)
+		c.Set("kindLabel", models.SuppressionKindLabel)
+		return c.Render(http.StatusOK, r.HTML("admin/suppressions/index.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/suppressions-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "<code>5550102000</code>")
+	req.Contains(w.Body.String(), "imported")
+	req.Contains(w.Body.String(), "PayPal/Venmo thank-you")
+	req.Contains(w.Body.String(), `<option value="address">Mailing address</option>`)
+}
@@ -0,0 +1,378 @@
+package actions
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// maxSurveyLinksPerBatch caps how many links an admin can generate at once
+const maxSurveyLinksPerBatch = 500
+
+// loadSurvey finds the survey named in the route and its questions.
+func loadSurvey(tx *pop.Connection, id string) (*models.Survey, models.SurveyQuestions, error) {
+	survey := &models.Survey{}
+	if err := tx.Find(survey, id); err != nil {
+		return nil, nil, err
+	}
+	questions := models.SurveyQuestions{}
+	if err := tx.Where("survey_id = ?", survey.ID).Order("position asc, created_at asc").All(&questions); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return survey, questions, nil
+}
+
+// surveyAnswers checks a submission against the survey's questions. values is
+// keyed by each question's InputName.
+func surveyAnswers(questions models.SurveyQuestions, values map[string]string) ([]models.SurveyAnswer, map[string]string) {
+	var answers []models.SurveyAnswer
+	errs := map[string]string{}
+	for _, q := range questions {
+		value := strings.TrimSpace(values[q.InputName()])
+		if len(value) > 2000 {
+			value = value[:2000]
+		}
+		if value == "" {
+			if q.Required {
+				errs[q.InputName()] = "This question is required"
+			}
+			continue
+		}
+		if q.QuestionType != models.SurveyQuestionText && !q.IsChoice(value) {
+			errs[q.InputName()] = "Please choose one of the options"
+			continue
+		}
+		answers = append(answers, models.SurveyAnswer{QuestionID: q.ID, Prompt: q.Prompt, Value: value})
+	}
+	return answers, errs
+}
+
+// SurveyHandler shows (GET) and records (POST) a response through a
+// single-use survey link
+func SurveyHandler(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	link := &models.SurveyLink{}
+	if err := tx.Where("token = ?", c.Param("token")).First(link); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	survey, questions, err := loadSurvey(tx, link.SurveyID.String())
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	c.Set("title", survey.Title)
+	c.Set("survey", survey)
+	c.Set("questions", questions)
+	c.Set("token", link.Token)
+	c.Set("errors", map[string]string{})
+	c.Set("completed", false)
+	c.Set("closed", false)
+
+	if link.Used() {
+		c.Set("completed", true)
+		return c.Render(http.StatusOK, r.HTML("pages/survey.plush.html"))
+	}
+	if !survey.Active {
+		c.Set("closed", true)
+		return c.Render(http.StatusOK, r.HTML("pages/survey.plush.html"))
+	}
+	if c.Request().Method == "GET" {
+		return c.Render(http.StatusOK, r.HTML("pages/survey.plush.html"))
+	}
+
+	values := map[string]string{}
+	for _, q := range questions {
+		values[q.InputName()] = c.Param(q.InputName())
+	}
+	answers, errs := surveyAnswers(questions, values)
+	if len(errs) > 0 {
+		c.Set("errors", errs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("pages/survey.plush.html"))
+	}
+
+	response := &models.SurveyResponse{SurveyID: survey.ID, LinkID: link.ID}
+	response.SetAnswers(answers)
+	if err := tx.Create(response); err != nil {
+		return errors.WithStack(err)
+	}
+	now := time.Now()
+	link.UsedAt = &now
+	if err := tx.Update(link); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("completed", true)
+	return c.Render(http.StatusOK, r.HTML("pages/survey.plush.html"))
+}
+
+// AdminSurveysIndex lists surveys with their response counts
+func AdminSurveysIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	surveys := models.Surveys{}
+	if err := tx.Order("created_at desc").All(&surveys); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var rows []struct {
+		SurveyID  string `db:"survey_id"`
+		Responses int    `db:"responses"`
+	}
+	if err := tx.RawQuery("SELECT survey_id, COUNT(*) as responses FROM survey_responses GROUP BY survey_id").All(&rows); err != nil {
+		return errors.WithStack(err)
+	}
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[row.SurveyID] = row.Responses
+	}
+
+	c.Set("surveys", surveys)
+	c.Set("responseCounts", counts)
+	return c.Render(http.StatusOK, r.HTML("admin/surveys/index.plush.html"))
+}
+
+// AdminSurveysNew shows the form for starting a survey
+func AdminSurveysNew(c buffalo.Context) error {
+	c.Set("survey", &models.Survey{Active: true})
+	c.Set("surveyDescription", "")
+	return c.Render(http.StatusOK, r.HTML("admin/surveys/new.plush.html"))
+}
+
+// AdminSurveysCreate saves a new survey and takes the admin to add questions
+func AdminSurveysCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	survey := &models.Survey{
+		Title:       strings.TrimSpace(c.Param("Title")),
+		Description: stringPointer(strings.TrimSpace(c.Param("Description"))),
+		Active:      true,
+	}
+	verrs, err := tx.ValidateAndCreate(survey)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Set("survey", survey)
+		c.Set("surveyDescription", survey.DescriptionText())
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("admin/surveys/new.plush.html"))
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "survey_created", fmt.Sprintf("Created survey: %s", survey.Title), logging.Fields{
+		"survey_id": survey.ID.String(),
+	})
+
+	c.Flash().Add("success", "Survey created. Add your questions below.")
+	return c.Redirect(http.StatusSeeOther, "/admin/surveys/%s", survey.ID)
+}
+
+// AdminSurveysShow shows a survey's questions, links and responses
+func AdminSurveysShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	survey, questions, err := loadSurvey(tx, c.Param("survey_id"))
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	links := models.SurveyLinks{}
+	if err := tx.Where("survey_id = ?", survey.ID).Order("created_at desc").All(&links); err != nil {
+		return errors.WithStack(err)
+	}
+	responses := models.SurveyResponses{}
+	if err := tx.Where("survey_id = ?", survey.ID).Order("created_at desc").All(&responses); err != nil {
+		return errors.WithStack(err)
+	}
+
+	req := c.Request()
+	scheme := "http"
+	if req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	c.Set("survey", survey)
+	c.Set("surveyDescription", survey.DescriptionText())
+	c.Set("questions", questions)
+	c.Set("questionTypes", models.SurveyQuestionTypes)
+	c.Set("links", links)
+	c.Set("responses", responses)
+	c.Set("surveyBaseURL", scheme+"://"+req.Host+"/surveys/")
+	return c.Render(http.StatusOK, r.HTML("admin/surveys/show.plush.html"))
+}
+
+// AdminSurveysUpdate saves a survey's title, introduction and open/closed state
+func AdminSurveysUpdate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	survey := &models.Survey{}
+	if err := tx.Find(survey, c.Param("survey_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	survey.Title = strings.TrimSpace(c.Param("Title"))
+	survey.Description = stringPointer(strings.TrimSpace(c.Param("Description")))
+	survey.Active = c.Param("Active") == "true"
+
+	verrs, err := tx.ValidateAndUpdate(survey)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+	} else {
+		c.Flash().Add("success", "Survey updated.")
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/surveys/%s", survey.ID)
+}
+
+// AdminSurveyQuestionsCreate adds a question to a survey
+func AdminSurveyQuestionsCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	survey, questions, err := loadSurvey(tx, c.Param("survey_id"))
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	question := &models.SurveyQuestion{
+		SurveyID:     survey.ID,
+		Prompt:       strings.TrimSpace(c.Param("Prompt")),
+		QuestionType: c.Param("QuestionType"),
+		Options:      stringPointer(strings.TrimSpace(c.Param("Options"))),
+		Required:     c.Param("Required") == "true",
+		Position:     len(questions) + 1,
+	}
+	verrs, err := tx.ValidateAndCreate(question)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+	} else {
+		c.Flash().Add("success", "Question added.")
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/surveys/%s", survey.ID)
+}
+
+// AdminSurveyQuestionsDelete removes a question. Answers already given keep
+// the prompt they were asked with.
+func AdminSurveyQuestionsDelete(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	question := &models.SurveyQuestion{}
+	err := tx.Where("id = ? AND survey_id = ?", c.Param("question_id"), c.Param("survey_id")).First(question)
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if err := tx.Destroy(question); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Question removed.")
+	return c.Redirect(http.StatusSeeOther, "/admin/surveys/%s", question.SurveyID)
+}
+
+// AdminSurveyLinksCreate generates single-use links for a survey, e.g. one per
+// attendee of an event
+func AdminSurveyLinksCreate(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	survey := &models.Survey{}
+	if err := tx.Find(survey, c.Param("survey_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	count, err := strconv.Atoi(c.Param("count"))
+	if err != nil || count < 1 || count > maxSurveyLinksPerBatch {
+		c.Flash().Add("danger", fmt.Sprintf("Enter a number of links between 1 and %d.", maxSurveyLinksPerBatch))
+		return c.Redirect(http.StatusSeeOther, "/admin/surveys/%s", survey.ID)
+	}
+	label := stringPointer(strings.TrimSpace(c.Param("label")))
+
+	for i := 0; i < count; i++ {
+		token, err := models.GenerateSurveyToken()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := tx.Create(&models.SurveyLink{SurveyID: survey.ID, Token: token, Label: label}); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "survey_links_created", fmt.Sprintf("Generated %d links for survey: %s", count, survey.Title), logging.Fields{
+		"survey_id": survey.ID.String(),
+		"count":     count,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Generated %d survey links.", count))
+	return c.Redirect(http.StatusSeeOther, "/admin/surveys/%s", survey.ID)
+}
+
+// AdminSurveyExport downloads a survey's responses as CSV, one row per
+// response and one column per question
+func AdminSurveyExport(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	survey, questions, err := loadSurvey(tx, c.Param("survey_id"))
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	responses := models.SurveyResponses{}
+	if err := tx.Where("survey_id = ?", survey.ID).Order("created_at asc").All(&responses); err != nil {
+		return errors.WithStack(err)
+	}
+	links := models.SurveyLinks{}
+	if err := tx.Where("survey_id = ?", survey.ID).All(&links); err != nil {
+		return errors.WithStack(err)
+	}
+	labels := map[string]string{}
+	for _, l := range links {
+		labels[l.ID.String()] = l.LabelText()
+	}
+
+	filename := fmt.Sprintf("survey-responses-%s.csv", time.Now().Format("2006-01-02"))
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	return c.Render(http.StatusOK, r.Func("text/csv", func(w io.Writer, d render.Data) error {
+		return writeSurveyCSV(w, questions, responses, labels)
+	}))
+}
+
+// writeSurveyCSV writes survey responses as CSV. labels maps link IDs to the
+// label the link was generated with.
+func writeSurveyCSV(w io.Writer, questions models.SurveyQuestions, responses models.SurveyResponses, labels map[string]string) error {
+	out := csv.NewWriter(w)
+	header := []string{"Submitted At", "Link Label"}
+	for _, q := range questions {
+		header = append(header, csvCell(q.Prompt))
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, resp := range responses {
+		row := []string{resp.CreatedAt.Format(time.RFC3339), csvCell(labels[resp.LinkID.String()])}
+		for _, q := range questions {
+			row = append(row, csvCell(resp.AnswerFor(q.ID)))
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,45 @@
+package actions
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_SurveyAnswers(t *testing.T) {
+	r := require.New(t)
+
+	rating := models.SurveyQuestion{ID: uuid.Must(uuid.NewV4()), Prompt: "Rate the training", QuestionType: models.SurveyQuestionRating, Required: true}
+	comment := models.SurveyQuestion{ID: uuid.Must(uuid.NewV4()), Prompt: "Anything else?", QuestionType: models.SurveyQuestionText}
+	questions := models.SurveyQuestions{rating, comment}
+
+	answers, errs := surveyAnswers(questions, map[string]string{rating.InputName(): "4"})
+	r.Empty(errs)
+	r.Len(answers, 1)
+	r.Equal("Rate the training", answers[0].Prompt)
+
+	_, errs = surveyAnswers(questions, map[string]string{rating.InputName(): "7"})
+	r.Contains(errs, rating.InputName())
+
+	_, errs = surveyAnswers(questions, map[string]string{comment.InputName(): "Great"})
+	r.Contains(errs, rating.InputName())
+}
+
+func Test_WriteSurveyCSV(t *testing.T) {
+	r := require.New(t)
+
+	q := models.SurveyQuestion{ID: uuid.Must(uuid.NewV4()), Prompt: "Rate the event", QuestionType: models.SurveyQuestionRating}
+	linkID := uuid.Must(uuid.NewV4())
+	resp := models.SurveyResponse{LinkID: linkID, CreatedAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}
+	resp.SetAnswers([]models.SurveyAnswer{{QuestionID: q.ID, Prompt: q.Prompt, Value: "5"}})
+
+	var buf bytes.Buffer
+	err := writeSurveyCSV(&buf, models.SurveyQuestions{q}, models.SurveyResponses{resp}, map[string]string{linkID.String(): "=Cookout"})
+	r.NoError(err)
+	r.Equal("Submitted At,Link Label,Rate the event\n2026-10-01T12:00:00Z,'=Cookout,5\n", buf.String())
+}
@@ -1,50 +1,196 @@
 package actions
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
-	"os"
-	"path/filepath"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
 
+	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/plush/v4"
-	"strings"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+	"avrnpo.org/templates"
 )
 
-// prewarmTemplates parses all .plush.html templates at startup in dev/test
-func prewarmTemplates() error {
-	if ENV == "production" {
-		return nil
-	}
+// templatePreflightCase is a page template and the context its handler
+// renders it with. Setup should call the same context helpers the handler
+// does, so a key the template needs but the handler forgets shows up here.
+type templatePreflightCase struct {
+	Template string
+	Setup    func(c buffalo.Context)
+}
+
+// templatePreflightCases are rendered at boot in development. Add a case
+// when a template starts depending on context a handler sets.
+var templatePreflightCases = []templatePreflightCase{
+	{Template: "pages/donate.plush.html", Setup: func(c buffalo.Context) {
+		setupDonateFormContext(c)
+		ensureDonateContext(c)
+	}},
+	{Template: "pages/donate_payment.plush.html", Setup: func(c buffalo.Context) {
+		ensureDonateContext(c)
+		c.Set("donationId", "00000000-0000-0000-0000-000000000000")
+		c.Set("flowToken", "preflight")
+		c.Set("checkoutToken", "preflight")
+		c.Set("amount", "50.00")
+		c.Set("donorName", "Preflight Donor")
+		c.Set("donationType", models.DonationTypeOneTime.String())
+		c.Set("donorEmail", "donor@example.com")
+		c.Set("installmentCount", 0)
+		c.Set("pledgeTotal", 0.0)
+		setFrequencyContext(c, models.FrequencyMonthly)
+		c.Set("paymentMethod", "Credit Card")
+		c.Set("hostedPaymentURL", "")
+	}},
+	{Template: "pages/donation_success.plush.html", Setup: setThankYouContext},
+	{Template: "pages/donation_failed.plush.html", Setup: func(c buffalo.Context) {}},
+	{Template: "pages/give.plush.html", Setup: func(c buffalo.Context) {
+		setupDonateFormContext(c)
+		setPartnerContext(c, &models.CorporatePartner{Name: "Preflight Partner", Slug: "preflight", Active: true})
+		c.Set("customFields", models.DonationFormFields{})
+	}},
+	{Template: "pages/contact.plush.html", Setup: func(c buffalo.Context) {
+		setContactFormContext(c)
+		c.Set("form_timestamp", int64(0))
+	}},
+	{Template: "pages/press.plush.html", Setup: func(c buffalo.Context) {
+		setPressContext(c)
+	}},
+	{Template: "pages/jobs.plush.html", Setup: func(c buffalo.Context) {
+		c.Set("jobs", models.JobPostings{})
+	}},
+	{Template: "pages/mentoring.plush.html", Setup: func(c buffalo.Context) {
+		c.Set("mentors", models.MentorProfiles{{Role: models.MentorRoleMentor, Skills: "Framing"}})
+		c.Set("trades", models.MentorTrades)
+		c.Set("trade", "")
+	}},
+	{Template: "pages/resources.plush.html", Setup: func(c buffalo.Context) {
+		c.Set("groups", models.GroupResources(models.VeteranResources{{Category: "housing", URL: "https://www.va.gov/housing-assistance/"}}))
+		c.Set("categories", models.ResourceCategories)
+		c.Set("category", "")
+		c.Set("q", "")
+	}},
+	{Template: "pages/events.plush.html", Setup: func(c buffalo.Context) {
+		c.Set("events", models.Events{{Title: "Build Day", Kind: models.EventKindVolunteerDay, StartsAt: time.Now()}})
+		c.Set("calendarFeedURL", "https://avrnpo.org/events/calendar.ics")
+	}},
+	{Template: "pages/job_new.plush.html", Setup: func(c buffalo.Context) {
+		setJobFormContext(c, &models.JobPosting{Status: models.JobPending}, nil)
+		c.Set("submitted", false)
+	}},
+	{Template: "pages/team.plush.html", Setup: func(c buffalo.Context) {}},
+	{Template: "pages/projects.plush.html", Setup: func(c buffalo.Context) {}},
+	{Template: "users/subscriptions_list.plush.html", Setup: func(c buffalo.Context) {
+		c.Set("subscriptions", []*models.Donation{})
+	}},
+	{Template: "users/mentoring.plush.html", Setup: func(c buffalo.Context) {
+		setMentorFormContext(c, &models.MentorProfile{Role: models.MentorRoleMentee, Status: models.MentorPending}, false)
+	}},
+	{Template: "users/receipts.plush.html", Setup: func(c buffalo.Context) {
+		c.Set("receipts", []services.YearEndReceiptData{})
+		c.Set("currentYear", 0)
+	}},
+	{Template: "users/subscription_details.plush.html", Setup: func(c buffalo.Context) {
+		c.Set("donation", &models.Donation{Amount: 25, DonationType: models.DonationTypeMonthly, Status: "active"})
+		c.Set("subscription", nil)
+	}},
+	{Template: "users/subscription_payment_method.plush.html", Setup: func(c buffalo.Context) {
+		c.Set("donation", &models.Donation{Amount: 25, DonationType: models.DonationTypeMonthly, Status: "active"})
+		c.Set("subscriptionID", "preflight")
+		c.Set("checkoutToken", "preflight")
+	}},
+	{Template: "admin/campaign.plush.html", Setup: func(c buffalo.Context) {
+		setCampaignModeFormContext(c, models.DefaultCampaignMode())
+	}},
+	{Template: "admin/partners/new.plush.html", Setup: func(c buffalo.Context) {
+		setPartnerContext(c, &models.CorporatePartner{Active: true})
+		c.Set("thankYouRules", models.ThankYouRules{})
+	}},
+	{Template: "admin/email_campaigns/index.plush.html", Setup: func(c buffalo.Context) {
+		c.Set("emailCampaigns", models.EmailCampaigns{})
+		c.Set("sentCounts", map[string]int{})
+	}},
+	{Template: "admin/email_campaigns/new.plush.html", Setup: func(c buffalo.Context) {
+		setEmailCampaignFormContext(c, &models.EmailCampaign{Kind: models.EmailCampaignAppeal, TestPercent: models.DefaultEmailCampaignTestPercent, TestHours: models.DefaultEmailCampaignTestHours})
+		c.Set("errors", nil)
+	}},
+	{Template: "admin/email_campaigns/show.plush.html", Setup: func(c buffalo.Context) {
+		setEmailCampaignFormContext(c, &models.EmailCampaign{Kind: models.EmailCampaignAppeal, Status: models.EmailCampaignDraft})
+		c.Set("errors", nil)
+		c.Set("audience", 0)
+		c.Set("testStats", []models.EmailCampaignVariantStats{})
+		c.Set("rolloutStats", []models.EmailCampaignVariantStats{})
+	}},
+}
 
-	// Hard-coded absolute templates path to ensure consistent behavior
-	tmplDir := "/home/josh/avrnpo.org/templates"
-	err := filepath.WalkDir(tmplDir, func(path string, d fs.DirEntry, err error) error {
+// preflightTemplates parses every template, then renders each preflight
+// case with its handler's context. Plush fails on identifiers that aren't in
+// the context, so a template referencing a missing key is reported here
+// instead of on the first request that hits it.
+func preflightTemplates() error {
+	var problems []string
 
+	err := fs.WalkDir(templates.FS(), ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if d.IsDir() {
-			return nil
-		}
-		if filepath.Ext(path) != ".html" {
+		if d.IsDir() || !strings.HasSuffix(path, ".plush.html") {
 			return nil
 		}
-		if !strings.HasSuffix(path, ".plush.html") {
-			return nil
-		}
-		data, err := os.ReadFile(path)
+		data, err := fs.ReadFile(templates.FS(), path)
 		if err != nil {
 			return fmt.Errorf("reading template %s: %w", path, err)
 		}
-		// Try parsing with plush to catch syntax errors
-		_, err = plush.Parse(string(data))
-		if err != nil {
-			return fmt.Errorf("template parse error %s: %w", path, err)
+		if _, err := plush.Parse(string(data)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
 		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
+
+	for _, tc := range templatePreflightCases {
+		if err := renderPreflightCase(tc); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", tc.Template, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("template preflight failed:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// renderPreflightCase renders tc through a bare Buffalo app, so the layout,
+// partials, flash and params are all in play as they are for a real request
+func renderPreflightCase(tc templatePreflightCase) error {
+	var renderErr error
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/preflight", func(c buffalo.Context) error {
+		tc.Setup(c)
+		renderErr = c.Render(http.StatusOK, r.HTML(tc.Template))
+		return renderErr
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/preflight", nil)
+	app.ServeHTTP(w, req)
+
+	if renderErr != nil {
+		var httpErr buffalo.HTTPError
+		if errors.As(renderErr, &httpErr) && httpErr.Cause != nil {
+			return httpErr.Cause
+		}
+		return renderErr
+	}
+	if w.Code != http.StatusOK {
+		return fmt.Errorf("rendered with status %d", w.Code)
+	}
 	return nil
 }
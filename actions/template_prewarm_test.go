@@ -0,0 +1,21 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PreflightTemplates(t *testing.T) {
+	require.NoError(t, preflightTemplates())
+}
+
+func Test_RenderPreflightCase_MissingKey(t *testing.T) {
+	err := renderPreflightCase(templatePreflightCase{
+		Template: "pages/donate_payment.plush.html",
+		Setup:    func(c buffalo.Context) {},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown identifier")
+}
@@ -25,6 +25,8 @@ func Test_DonateTemplateRendering(t *testing.T) {
 		// Set default values for form fields
 		c.Set("amount", "")
 		c.Set("donationType", "one-time")
+		c.Set("installments", "")
+		c.Set("frequency", "")
 		c.Set("firstName", "")
 		c.Set("lastName", "")
 		c.Set("donorEmail", "")
@@ -52,6 +54,7 @@ func Test_DonateTemplateRendering(t *testing.T) {
 	req.Contains(body, "first_name", "Template should contain first name field")
 	req.Contains(body, "donor_email", "Template should contain email field")
 	req.Contains(body, "Donate Now", "Template should contain submit button")
+	req.Contains(body, `<option value="quarterly" data-adverb="Quarterly">Quarterly</option>`, "Template should offer recurring frequencies")
 }
 
 // Test_DonateFormTemplateRendering tests the donation form partial specifically
@@ -63,6 +66,8 @@ func Test_DonateFormTemplateRendering(t *testing.T) {
 		// Set up context variables for the form
 		c.Set("amount", "25")
 		c.Set("donationType", "one-time")
+		c.Set("installments", "")
+		c.Set("frequency", "")
 		c.Set("firstName", "John")
 		c.Set("lastName", "Doe")
 		c.Set("donorEmail", "john@example.com")
@@ -121,9 +126,6 @@ func Test_DonateTemplateWithErrors(t *testing.T) {
 		errs.Add("first_name", "First name is required")
 		errs.Add("donor_email", "Email address is required")
 		c.Set("errors", errs)
-		c.Set("hasAnyErrors", true)
-		c.Set("hasFirstNameError", true)
-		c.Set("hasDonorEmailError", true)
 
 		// Try to render the template with errors - this should not panic or error
 		return c.Render(http.StatusOK, r.HTML("pages/donate.plush.html"))
@@ -0,0 +1,128 @@
+package actions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// addThankYouToReceipt adds the partner page's thank-you video and message
+// for the gift's tier to its receipt. A lookup failure only costs the
+// personal touch, so the receipt still goes out.
+func addThankYouToReceipt(tx *pop.Connection, donation *models.Donation, receipt *services.DonationReceiptData) {
+	rule, err := models.FindThankYouRule(tx, donation)
+	if err != nil {
+		logging.Error("failed to load thank-you rule", err, logging.Fields{
+			"donation_id": donation.ID.String(),
+		})
+		return
+	}
+	if rule == nil {
+		return
+	}
+	receipt.ThankYouMessage = rule.PersonalizedMessage(donation)
+	receipt.ThankYouVideoURL = rule.VideoLink()
+}
+
+// setThankYouContext shows the thank-you video and message on the thank-you
+// page when the signed flow token names a gift that has gone through
+func setThankYouContext(c buffalo.Context) {
+	c.Set("thankYouMessage", "")
+	c.Set("thankYouVideo", "")
+
+	donationID, err := verifyDonationFlow(c.Param("flow"), time.Now())
+	if err != nil {
+		return
+	}
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return
+	}
+	donation := &models.Donation{}
+	if err := tx.Find(donation, donationID); err != nil {
+		return
+	}
+	if donation.Status != "completed" && donation.Status != "active" {
+		return
+	}
+	rule, err := models.FindThankYouRule(tx, donation)
+	if err != nil || rule == nil {
+		return
+	}
+	c.Set("thankYouMessage", rule.PersonalizedMessage(donation))
+	c.Set("thankYouVideo", rule.VideoLink())
+}
+
+// bindThankYouRules reads the thank-you tiers from the partner form, one per
+// row of ThankYouMinAmount, ThankYouVideoURL and ThankYouMessage fields. Rows
+// left blank are dropped.
+func bindThankYouRules(c buffalo.Context) (models.ThankYouRules, *validate.Errors) {
+	verrs := validate.NewErrors()
+	if err := c.Request().ParseForm(); err != nil {
+		verrs.Add("thank_you", "Could not read the thank-you tiers")
+		return nil, verrs
+	}
+	form := c.Request().Form
+	mins, videos, messages := form["ThankYouMinAmount"], form["ThankYouVideoURL"], form["ThankYouMessage"]
+	field := func(values []string, i int) string {
+		if i >= len(values) {
+			return ""
+		}
+		return strings.TrimSpace(values[i])
+	}
+
+	rules := models.ThankYouRules{}
+	for i := 0; i < max(len(mins), len(videos), len(messages)); i++ {
+		minAmount, video, message := field(mins, i), field(videos, i), field(messages, i)
+		if video == "" && message == "" {
+			continue
+		}
+		rule := models.ThankYouRule{VideoURL: stringPointer(video), Message: stringPointer(message)}
+		if minAmount != "" {
+			amount, err := strconv.ParseFloat(strings.NewReplacer("$", "", ",", "").Replace(minAmount), 64)
+			if err != nil {
+				verrs.Add("min_amount", fmt.Sprintf("%q is not a valid thank-you tier amount", minAmount))
+				continue
+			}
+			rule.MinAmount = amount
+		}
+		rules = append(rules, rule)
+	}
+	return rules, verrs
+}
+
+// replaceThankYouRules swaps a partner's thank-you tiers for rules
+func replaceThankYouRules(tx *pop.Connection, partner *models.CorporatePartner, rules models.ThankYouRules) (*validate.Errors, error) {
+	verrs := validate.NewErrors()
+	for i := range rules {
+		rules[i].PartnerID = partner.ID
+		ruleErrs, err := rules[i].Validate(tx)
+		if err != nil {
+			return verrs, err
+		}
+		verrs.Append(ruleErrs)
+	}
+	if verrs.HasAny() {
+		return verrs, nil
+	}
+
+	if err := tx.RawQuery("DELETE FROM thank_you_rules WHERE partner_id = ?", partner.ID).Exec(); err != nil {
+		return verrs, errors.WithStack(err)
+	}
+	for i := range rules {
+		if err := tx.Create(&rules[i]); err != nil {
+			return verrs, errors.WithStack(err)
+		}
+	}
+	return verrs, nil
+}
@@ -0,0 +1,150 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// defaultTransactionPollAfter is how long a charged donation waits for its
+// cardTransaction webhook before Helcim is asked about it directly
+const defaultTransactionPollAfter = 15 * time.Minute
+
+// transactionPollAfter is defaultTransactionPollAfter, or
+// TRANSACTION_POLL_AFTER (e.g. "30m") when it's set
+func transactionPollAfter() time.Duration {
+	return envDuration("TRANSACTION_POLL_AFTER", defaultTransactionPollAfter)
+}
+
+// transactionPoll is what a transaction polling run found
+type transactionPoll struct {
+	Checked    int
+	Completed  int
+	Failed     int
+	Unresolved int
+}
+
+// pollReference is the Helcim transaction a stuck donation was charged with
+func pollReference(donation models.Donation) string {
+	if ref := stringOrEmpty(donation.HelcimTransactionID); ref != "" {
+		return ref
+	}
+	return stringOrEmpty(donation.TransactionID)
+}
+
+// completePolledDonation completes and receipts a donation Helcim reports
+// approved. The update only applies while the donation is still pending, so
+// a webhook that lands at the same time can't receipt it twice. It reports
+// whether this run completed it rather than the webhook.
+func completePolledDonation(tx *pop.Connection, donation *models.Donation, transactionID string, now time.Time) (bool, error) {
+	n, err := tx.RawQuery(`UPDATE donations SET status = ?, helcim_transaction_id = COALESCE(helcim_transaction_id, ?),
+		transaction_id = COALESCE(transaction_id, ?), updated_at = ? WHERE id = ? AND status = ?`,
+		"completed", transactionID, transactionID, now, donation.ID, "pending").ExecWithCount()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+	if err := tx.Reload(donation); err != nil {
+		return false, errors.WithStack(err)
+	}
+	notifyDonationCompleted(tx, donation)
+
+	receipt := webhookReceiptData(donation, transactionID)
+	addThankYouToReceipt(tx, donation, &receipt)
+	queueReceipt(tx, donation, receipt, receiptSummary(donation))
+	logging.Audit("donation_completed_by_poll", logging.Fields{
+		"donation_id":    donation.ID.String(),
+		"transaction_id": transactionID,
+		"amount":         donation.Amount,
+	})
+	return true, nil
+}
+
+// failPolledDonation marks a donation failed when Helcim reports its charge
+// declined, and flags it on the admin dashboard
+func failPolledDonation(tx *pop.Connection, donation *models.Donation, transactionID, status string, now time.Time) (bool, error) {
+	reason := fmt.Sprintf("Payment %s", strings.ToLower(strings.TrimSpace(status)))
+	n, err := tx.RawQuery(`UPDATE donations SET status = ?, payment_failure_reason = ?, last_payment_attempt = ?, updated_at = ?
+		WHERE id = ? AND status = ?`, "failed", reason, now, now, donation.ID, "pending").ExecWithCount()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+	logging.Warn("donation_failed_by_poll", logging.Fields{
+		"donation_id":    donation.ID.String(),
+		"transaction_id": transactionID,
+		"status":         status,
+	})
+	publishAdminActivity(activityReview, fmt.Sprintf("%s from %s", donationTitle(*donation), donation.DonorName), reason, fmt.Sprintf("/admin/donations/%s", donation.ID))
+	return true, nil
+}
+
+// PollStuckTransactions settles donations that were charged but are still
+// pending because their cardTransaction webhook never arrived, or arrived
+// while webhooks were misconfigured. Each one pending for longer than
+// transactionPollAfter is looked up in Helcim: approved charges are
+// completed and receipted as the webhook would have, declined ones marked
+// failed. Those Helcim can't answer for are left for the next run. Bank
+// transfers take days to settle and wait on their own webhook. It's run
+// from cron by the donations:poll_transactions grift.
+func PollStuckTransactions(ctx context.Context, tx *pop.Connection, client services.HelcimAPI, now time.Time) (transactionPoll, error) {
+	result := transactionPoll{}
+	donations := models.Donations{}
+	err := tx.Where("status = ? AND COALESCE(NULLIF(helcim_transaction_id, ''), NULLIF(transaction_id, '')) IS NOT NULL AND updated_at < ?",
+		"pending", now.Add(-transactionPollAfter())).Order("updated_at ASC").All(&donations)
+	if err != nil {
+		return result, errors.WithStack(err)
+	}
+
+	for i := range donations {
+		donation := &donations[i]
+		ref := pollReference(*donation)
+		result.Checked++
+
+		transaction, err := client.GetTransaction(ctx, ref)
+		if err != nil {
+			result.Unresolved++
+			logging.Warn("transaction_poll_failed", logging.Fields{
+				"donation_id":    donation.ID.String(),
+				"transaction_id": ref,
+				"error":          err.Error(),
+			})
+			continue
+		}
+
+		switch {
+		case strings.EqualFold(strings.TrimSpace(transaction.Status), "APPROVED"):
+			completed, err := completePolledDonation(tx, donation, ref, now)
+			if err != nil {
+				return result, err
+			}
+			if completed {
+				result.Completed++
+			}
+		case paymentDeclined(transaction.Status):
+			failed, err := failPolledDonation(tx, donation, ref, transaction.Status, now)
+			if err != nil {
+				return result, err
+			}
+			if failed {
+				result.Failed++
+			}
+		default:
+			// No status yet; try again next run
+			result.Unresolved++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,35 @@
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_PollReference(t *testing.T) {
+	req := require.New(t)
+
+	helcim, ours, blank := "5551", "txn_ours", ""
+	req.Equal("5551", pollReference(models.Donation{HelcimTransactionID: &helcim, TransactionID: &ours}))
+	req.Equal("txn_ours", pollReference(models.Donation{HelcimTransactionID: &blank, TransactionID: &ours}))
+	req.Equal("", pollReference(models.Donation{}))
+}
+
+func Test_TransactionPollAfter(t *testing.T) {
+	req := require.New(t)
+
+	envy.Temp(func() {
+		envy.Set("TRANSACTION_POLL_AFTER", "")
+		req.Equal(defaultTransactionPollAfter, transactionPollAfter())
+
+		envy.Set("TRANSACTION_POLL_AFTER", "45m")
+		req.Equal(45*time.Minute, transactionPollAfter())
+
+		envy.Set("TRANSACTION_POLL_AFTER", "soon")
+		req.Equal(defaultTransactionPollAfter, transactionPollAfter())
+	})
+}
@@ -0,0 +1,163 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// upgradePromptIncrement is how much more a month donors are asked to give,
+// from UPGRADE_PROMPT_INCREMENT (default $5, 0 turns the prompts off)
+func upgradePromptIncrement() float64 {
+	if v := os.Getenv("UPGRADE_PROMPT_INCREMENT"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return 5
+}
+
+// upgradePromptFor is the upgrade prompt to show on a user's subscription,
+// or nil. Donors whose last payment failed aren't asked for more. A failure
+// only hides the prompt, so the page still loads.
+func upgradePromptFor(tx *pop.Connection, user *models.User, donation *models.Donation) *models.UpgradePrompt {
+	failure, err := openPaymentFailure(tx, donation.ID)
+	if err != nil || failure != nil {
+		return nil
+	}
+	prompt, err := models.ShowUpgradePrompt(tx, donation, user.ID, upgradePromptIncrement(), time.Now())
+	if err != nil {
+		logging.Error("upgrade_prompt_failed", err, logging.Fields{
+			"donation_id": donation.ID.String(),
+		})
+		return nil
+	}
+	return prompt
+}
+
+// findUpgradePrompt loads the user's subscription and the open upgrade
+// prompt on it named in the URL, redirecting with a message when either
+// isn't there
+func findUpgradePrompt(c buffalo.Context) (*models.Donation, *models.UpgradePrompt, error) {
+	user := c.Value("current_user").(*models.User)
+	tx := c.Value("tx").(*pop.Connection)
+	subscriptionID := c.Param("subscriptionId")
+
+	donation, err := findUserSubscription(tx, user, subscriptionID)
+	if err != nil {
+		c.Flash().Add("danger", "Subscription not found")
+		return nil, nil, c.Redirect(http.StatusFound, "/account/subscriptions")
+	}
+	var prompt *models.UpgradePrompt
+	if id, err := uuid.FromString(c.Param("prompt_id")); err == nil {
+		if prompt, err = models.FindOpenUpgradePrompt(tx, id, donation.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if prompt == nil {
+		c.Flash().Add("info", "That suggestion has already been answered")
+		return nil, nil, c.Redirect(http.StatusFound, fmt.Sprintf("/account/subscriptions/%s", subscriptionID))
+	}
+	return donation, prompt, nil
+}
+
+// AcceptUpgradePrompt raises a user's monthly gift to the amount an upgrade
+// prompt suggested
+func AcceptUpgradePrompt(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+	tx := c.Value("tx").(*pop.Connection)
+	donation, prompt, err := findUpgradePrompt(c)
+	if donation == nil {
+		return err
+	}
+	detailsURL := fmt.Sprintf("/account/subscriptions/%s", stringOrEmpty(donation.SubscriptionID))
+
+	if donation.Status != "active" || donation.Amount != prompt.CurrentAmount {
+		c.Flash().Add("warning", "Your monthly gift has changed since this suggestion was made")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+	if err := changeSubscriptionAmount(c, tx, user, donation, prompt.SuggestedAmount); err != nil {
+		c.Flash().Add("danger", "Unable to change your monthly amount. Please try again later.")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+
+	prompt.Respond(models.UpgradePromptAccepted, time.Now())
+	if err := tx.UpdateColumns(prompt, "status", "responded_at", "updated_at"); err != nil {
+		return err
+	}
+	logging.UserAction(c, user.Email, "accept_upgrade_prompt", "User accepted a monthly gift upgrade", logging.Fields{
+		"subscription_id": stringOrEmpty(donation.SubscriptionID),
+		"previous_amount": prompt.CurrentAmount,
+		"donation_amount": prompt.SuggestedAmount,
+		"reason":          prompt.Reason,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Thank you! Your monthly donation is now $%.2f, starting with your next billing date", prompt.SuggestedAmount))
+	return c.Redirect(http.StatusFound, detailsURL)
+}
+
+// DismissUpgradePrompt hides an upgrade prompt the user doesn't want
+func DismissUpgradePrompt(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	donation, prompt, err := findUpgradePrompt(c)
+	if donation == nil {
+		return err
+	}
+
+	prompt.Respond(models.UpgradePromptDismissed, time.Now())
+	if err := tx.UpdateColumns(prompt, "status", "responded_at", "updated_at"); err != nil {
+		return err
+	}
+	c.Flash().Add("info", "No problem. Thank you for your monthly gift!")
+	return c.Redirect(http.StatusFound, fmt.Sprintf("/account/subscriptions/%s", stringOrEmpty(donation.SubscriptionID)))
+}
+
+// upgradeReasons are the prompt reasons in the order the admin page lists
+// them, with their labels
+var upgradeReasons = []struct {
+	Key   string
+	Label string
+}{
+	{models.UpgradeReasonAnniversary, "Giving 12+ months"},
+	{models.UpgradeReasonHalfYear, "Giving 6–11 months"},
+	{models.UpgradeReasonSteady, "Giving 3–5 months"},
+}
+
+// upgradePromptRow is one line of the admin upgrade prompts table
+type upgradePromptRow struct {
+	Label string
+	models.UpgradePromptStats
+}
+
+// AdminUpgradePrompts shows how often donors see and accept upgrade prompts
+func AdminUpgradePrompts(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	days := 90
+	if n, err := strconv.Atoi(c.Param("days")); err == nil && n > 0 {
+		days = n
+	}
+	stats, err := models.LoadUpgradePromptStats(tx, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return err
+	}
+	rows := []upgradePromptRow{}
+	for _, reason := range upgradeReasons {
+		rows = append(rows, upgradePromptRow{Label: reason.Label, UpgradePromptStats: stats[reason.Key]})
+	}
+
+	c.Set("days", days)
+	c.Set("total", stats[""])
+	c.Set("rows", rows)
+	c.Set("increment", upgradePromptIncrement())
+	return c.Render(http.StatusOK, r.HTML("admin/upgrade_prompts.plush.html"))
+}
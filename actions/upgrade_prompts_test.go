@@ -0,0 +1,51 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_UpgradePromptTemplatesRendering(t *testing.T) {
+	req := require.New(t)
+
+	subscriptionID := "sub_123"
+	donation := &models.Donation{Amount: 25, DonationType: models.DonationTypeMonthly, Status: "active", SubscriptionID: &subscriptionID}
+	prompt := &models.UpgradePrompt{ID: uuid.Must(uuid.NewV4()), Reason: models.UpgradeReasonAnniversary, CurrentAmount: 25, SuggestedAmount: 30}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/subscription-test", func(c buffalo.Context) error {
+		c.Set("csrf", "")
+		c.Set("donation", donation)
+		c.Set("subscription", nil)
+		c.Set("upgradePrompt", prompt)
+		return c.Render(http.StatusOK, r.HTML("users/subscription_details.plush.html"))
+	})
+	app.GET("/admin-upgrade-prompts-test", func(c buffalo.Context) error {
+		c.Set("days", 90)
+		c.Set("total", models.UpgradePromptStats{Prompts: 4, Impressions: 9, Accepted: 1, AddedMonthly: 5})
+		c.Set("rows", []upgradePromptRow{{Label: "Giving 12+ months", UpgradePromptStats: models.UpgradePromptStats{Prompts: 4, Accepted: 1}}})
+		c.Set("increment", 5.0)
+		return c.Render(http.StatusOK, r.HTML("admin/upgrade_prompts.plush.html"))
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/subscription-test", nil))
+	body := res.Body.String()
+	req.Equal(http.StatusOK, res.Code, body)
+	req.Contains(body, "Increase your monthly gift by $5?")
+	req.Contains(body, `action="/account/subscriptions/sub_123/upgrade/`+prompt.ID.String()+`/accept"`)
+
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/admin-upgrade-prompts-test", nil))
+	body = res.Body.String()
+	req.Equal(http.StatusOK, res.Code, body)
+	req.Contains(body, "25%")
+	req.Contains(body, "Giving 12+ months")
+}
@@ -0,0 +1,273 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// Kinds of upload that are virus scanned, as they appear in quarantine URLs
+const (
+	uploadKindMedia        = "media"
+	uploadKindVehiclePhoto = "vehicle_photo"
+)
+
+// quarantineStatuses are the scan statuses that keep a file out of reach
+var quarantineStatuses = []string{models.ScanPending, models.ScanInfected, models.ScanFailed}
+
+// scannedUpload is a media asset or vehicle photo as the quarantine page
+// shows it
+type scannedUpload struct {
+	Kind       string
+	ID         uuid.UUID
+	Title      string
+	Filename   string
+	StorageKey string
+	ScanStatus string
+	ScanDetail string
+	UploadedAt time.Time
+	// URL is the admin page the file belongs to
+	URL string
+}
+
+func mediaScannedUpload(asset models.MediaAsset) scannedUpload {
+	return scannedUpload{
+		Kind:       uploadKindMedia,
+		ID:         asset.ID,
+		Title:      asset.Title,
+		Filename:   asset.Filename,
+		StorageKey: asset.StorageKey,
+		ScanStatus: asset.ScanStatus,
+		ScanDetail: asset.ScanDetailText(),
+		UploadedAt: asset.CreatedAt,
+		URL:        "/admin/media",
+	}
+}
+
+func vehiclePhotoScannedUpload(photo models.VehicleDonationPhoto) scannedUpload {
+	return scannedUpload{
+		Kind:       uploadKindVehiclePhoto,
+		ID:         photo.ID,
+		Title:      "Vehicle donation photo",
+		Filename:   photo.Filename,
+		StorageKey: photo.StorageKey,
+		ScanStatus: photo.ScanStatus,
+		ScanDetail: photo.ScanDetailText(),
+		UploadedAt: photo.CreatedAt,
+		URL:        fmt.Sprintf("/admin/vehicles/%s", photo.VehicleDonationID),
+	}
+}
+
+// initialScanStatus is the status a new upload starts with: held for a scan
+// when a scanner is configured, otherwise released as not scanned
+func initialScanStatus() string {
+	if services.VirusScanningEnabled() {
+		return models.ScanPending
+	}
+	return models.ScanNotScanned
+}
+
+// queueUploadScan queues a virus scan of a new upload if it's waiting for one
+func queueUploadScan(tx *pop.Connection, kind string, id uuid.UUID, status string) {
+	if status != models.ScanPending {
+		return
+	}
+	queueJob(tx, jobUploadScan, worker.Args{"kind": kind, "upload_id": id.String()})
+}
+
+// scanUploadJob scans a queued upload
+func scanUploadJob(args worker.Args) error {
+	scanner := services.NewVirusScanner()
+	if scanner == nil {
+		// Scanning was switched off after the file was queued. It stays in
+		// quarantine until staff rescan or delete it.
+		logging.Warn("upload_scan_skipped", logging.Fields{
+			"kind":      jobArg(args, "kind"),
+			"upload_id": jobArg(args, "upload_id"),
+			"reason":    "CLAMAV_ADDRESS is not set",
+		})
+		return nil
+	}
+	return scanUpload(models.DB, scanner, services.NewStorage(), jobArg(args, "kind"), jobArg(args, "upload_id"), time.Now())
+}
+
+// scanStoredFile runs the scanner over an upload in storage and returns the
+// status to record for it, with the signature found or why the scan failed.
+// An error is returned when the scanner itself failed, so the scan can be
+// tried again.
+func scanStoredFile(scanner services.VirusScanner, storage services.Storage, key string) (string, string, error) {
+	f, err := storage.Open(key)
+	if err != nil {
+		return models.ScanFailed, "The file couldn't be read from storage: " + err.Error(), nil
+	}
+	defer f.Close()
+
+	result, err := scanner.Scan(f)
+	if err != nil {
+		return models.ScanFailed, err.Error(), err
+	}
+	if result.Infected {
+		return models.ScanInfected, result.Signature, nil
+	}
+	return models.ScanClean, "", nil
+}
+
+// findScannedUpload loads the upload named by kind and id
+func findScannedUpload(db *pop.Connection, kind, id string) (scannedUpload, error) {
+	switch kind {
+	case uploadKindMedia:
+		asset := models.MediaAsset{}
+		if err := db.Find(&asset, id); err != nil {
+			return scannedUpload{}, err
+		}
+		return mediaScannedUpload(asset), nil
+	case uploadKindVehiclePhoto:
+		photo := models.VehicleDonationPhoto{}
+		if err := db.Find(&photo, id); err != nil {
+			return scannedUpload{}, err
+		}
+		return vehiclePhotoScannedUpload(photo), nil
+	}
+	return scannedUpload{}, fmt.Errorf("unknown upload kind %q", kind)
+}
+
+// uploadTables are the tables that hold each kind of upload
+var uploadTables = map[string]string{
+	uploadKindMedia:        "media_assets",
+	uploadKindVehiclePhoto: "vehicle_donation_photos",
+}
+
+// setScanStatus records an upload's scan status. scannedAt is nil while
+// it's waiting for a scan.
+func setScanStatus(db *pop.Connection, upload scannedUpload, status, detail string, scannedAt *time.Time) error {
+	err := db.RawQuery(
+		fmt.Sprintf("UPDATE %s SET scan_status = ?, scan_detail = ?, scanned_at = ?, updated_at = ? WHERE id = ?", uploadTables[upload.Kind]),
+		status, stringPointer(detail), scannedAt, time.Now(), upload.ID,
+	).Exec()
+	return errors.WithStack(err)
+}
+
+// scanUpload scans an upload and records the result. Infected files are
+// reported on the admin dashboard.
+func scanUpload(db *pop.Connection, scanner services.VirusScanner, storage services.Storage, kind, id string, now time.Time) error {
+	upload, err := findScannedUpload(db, kind, id)
+	if err != nil {
+		// Deleted before it was scanned
+		logging.Warn("upload_scan_not_found", logging.Fields{"kind": kind, "upload_id": id})
+		return nil
+	}
+
+	status, detail, scanErr := scanStoredFile(scanner, storage, upload.StorageKey)
+	if err := setScanStatus(db, upload, status, detail, &now); err != nil {
+		return err
+	}
+
+	switch status {
+	case models.ScanInfected:
+		logging.Warn("upload_infected", logging.Fields{
+			"kind":      kind,
+			"upload_id": id,
+			"signature": detail,
+		})
+		publishAdminActivity(activityReview, fmt.Sprintf("Quarantined %s", upload.Filename), "Virus scan found "+detail, "/admin/quarantine")
+	case models.ScanFailed:
+		logging.Warn("upload_scan_failed", logging.Fields{
+			"kind":      kind,
+			"upload_id": id,
+			"error":     detail,
+		})
+	}
+	return scanErr
+}
+
+// AdminQuarantineIndex lists uploads held back from download: waiting for
+// a scan, infected, or the scan failed
+func AdminQuarantineIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	assets := models.MediaAssets{}
+	if err := tx.Where("scan_status IN (?)", quarantineStatuses).All(&assets); err != nil {
+		return errors.WithStack(err)
+	}
+	photos := models.VehicleDonationPhotos{}
+	if err := tx.Where("scan_status IN (?)", quarantineStatuses).All(&photos); err != nil {
+		return errors.WithStack(err)
+	}
+
+	uploads := make([]scannedUpload, 0, len(assets)+len(photos))
+	for _, asset := range assets {
+		uploads = append(uploads, mediaScannedUpload(asset))
+	}
+	for _, photo := range photos {
+		uploads = append(uploads, vehiclePhotoScannedUpload(photo))
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].UploadedAt.After(uploads[j].UploadedAt) })
+
+	c.Set("uploads", uploads)
+	c.Set("scanningEnabled", services.VirusScanningEnabled())
+	c.Set("scanStatusLabel", models.ScanStatusLabel)
+	return c.Render(http.StatusOK, r.HTML("admin/quarantine/index.plush.html"))
+}
+
+// AdminQuarantineRescan queues another scan of a quarantined upload
+func AdminQuarantineRescan(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	if !services.VirusScanningEnabled() {
+		c.Flash().Add("danger", "Virus scanning isn't set up. Set CLAMAV_ADDRESS to rescan files.")
+		return c.Redirect(http.StatusSeeOther, "/admin/quarantine")
+	}
+	upload, err := findScannedUpload(tx, c.Param("kind"), c.Param("upload_id"))
+	if err != nil {
+		c.Flash().Add("danger", "File not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/quarantine")
+	}
+	if err := setScanStatus(tx, upload, models.ScanPending, "", nil); err != nil {
+		return err
+	}
+	queueUploadScan(tx, upload.Kind, upload.ID, models.ScanPending)
+
+	c.Flash().Add("success", fmt.Sprintf("%s will be scanned again shortly.", upload.Filename))
+	return c.Redirect(http.StatusSeeOther, "/admin/quarantine")
+}
+
+// AdminQuarantineDelete removes a quarantined upload and its file
+func AdminQuarantineDelete(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	upload, err := findScannedUpload(tx, c.Param("kind"), c.Param("upload_id"))
+	if err != nil {
+		c.Flash().Add("danger", "File not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/quarantine")
+	}
+	err = tx.RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id = ?", uploadTables[upload.Kind]), upload.ID).Exec()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := services.NewStorage().Delete(upload.StorageKey); err != nil {
+		logging.Error("quarantined_file_delete_failed", err, logging.Fields{
+			"kind":      upload.Kind,
+			"upload_id": upload.ID.String(),
+		})
+	}
+
+	logging.UserAction(c, user.Email, "quarantined_upload_deleted", "Deleted quarantined upload", logging.Fields{
+		"kind":        upload.Kind,
+		"upload_id":   upload.ID.String(),
+		"scan_status": upload.ScanStatus,
+	})
+	c.Flash().Add("success", fmt.Sprintf("Deleted %s.", upload.Filename))
+	return c.Redirect(http.StatusSeeOther, "/admin/quarantine")
+}
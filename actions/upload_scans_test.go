@@ -0,0 +1,100 @@
+package actions
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+)
+
+// stubScanner flags files containing "EICAR", or fails with err
+type stubScanner struct {
+	err error
+}
+
+func (s stubScanner) Scan(r io.Reader) (services.ScanResult, error) {
+	if s.err != nil {
+		return services.ScanResult{}, s.err
+	}
+	data, _ := io.ReadAll(r)
+	if strings.Contains(string(data), "EICAR") {
+		return services.ScanResult{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	}
+	return services.ScanResult{}, nil
+}
+
+func Test_ScanStoredFile(t *testing.T) {
+	req := require.New(t)
+	storage := &services.LocalStorage{Root: t.TempDir()}
+	req.NoError(storage.Save("media/clean.pdf", strings.NewReader("%PDF-1.7")))
+	req.NoError(storage.Save("media/bad.zip", strings.NewReader("EICAR test file")))
+
+	status, detail, err := scanStoredFile(stubScanner{}, storage, "media/clean.pdf")
+	req.NoError(err)
+	req.Equal(models.ScanClean, status)
+	req.Empty(detail)
+
+	status, detail, err = scanStoredFile(stubScanner{}, storage, "media/bad.zip")
+	req.NoError(err)
+	req.Equal(models.ScanInfected, status)
+	req.Equal("Eicar-Test-Signature", detail)
+
+	// A scanner outage is retried; a missing file isn't
+	status, _, err = scanStoredFile(stubScanner{err: errors.New("connecting to clamd: refused")}, storage, "media/clean.pdf")
+	req.Error(err)
+	req.Equal(models.ScanFailed, status)
+	status, detail, err = scanStoredFile(stubScanner{}, storage, "media/gone.pdf")
+	req.NoError(err)
+	req.Equal(models.ScanFailed, status)
+	req.Contains(detail, "couldn't be read")
+}
+
+func Test_InitialScanStatus(t *testing.T) {
+	t.Setenv("CLAMAV_ADDRESS", "")
+	require.Equal(t, models.ScanNotScanned, initialScanStatus())
+	t.Setenv("CLAMAV_ADDRESS", "clamav:3310")
+	require.Equal(t, models.ScanPending, initialScanStatus())
+}
+
+func Test_AdminQuarantineTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	uploads := []scannedUpload{{
+		Kind:       uploadKindMedia,
+		ID:         uuid.Must(uuid.NewV4()),
+		Title:      "Volunteer packet",
+		Filename:   "packet.zip",
+		ScanStatus: models.ScanInfected,
+		ScanDetail: "Eicar-Test-Signature",
+		UploadedAt: time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC),
+		URL:        "/admin/media",
+	}}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/quarantine-test", func(c buffalo.Context) error {
+		c.Set("uploads", uploads)
+		c.Set("scanningEnabled", true)
+		c.Set("scanStatusLabel", models.ScanStatusLabel)
+		return c.Render(http.StatusOK, r.HTML("admin/quarantine/index.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/quarantine-test", nil)
+	app.ServeHTTP(w, httpReq)
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	body := w.Body.String()
+	req.Contains(body, "Quarantined: infected")
+	req.Contains(body, "Eicar-Test-Signature")
+	req.Contains(body, "/admin/quarantine/media/"+uploads[0].ID.String()+"/rescan")
+	req.NotContains(body, "Virus scanning isn&#39;t set up")
+}
@@ -1,8 +1,15 @@
 package actions
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/pop/v6"
@@ -66,7 +73,7 @@ func UsersCreate(c buffalo.Context) error {
 	})
 
 	c.Session().Set("current_user_id", u.ID)
-	c.Flash().Add("success", "Welcome to American Veterans Rebuilding!")
+	c.Flash().Add("success", "Welcome to "+organization().Name+"!")
 
 	return c.Redirect(http.StatusFound, "/")
 }
@@ -234,6 +241,7 @@ func SubscriptionsList(c buffalo.Context) error {
 	}
 
 	c.Set("subscriptions", subscriptions)
+	c.Set("title", "My Subscriptions")
 	return c.Render(http.StatusOK, r.HTML("users/subscriptions_list.plush.html"))
 }
 
@@ -244,8 +252,7 @@ func SubscriptionDetails(c buffalo.Context) error {
 	tx := c.Value("tx").(*pop.Connection)
 
 	// Find the donation record for this subscription
-	donation := &models.Donation{}
-	err := tx.Where("user_id = ? AND subscription_id = ?", user.ID, subscriptionID).First(donation)
+	donation, err := findUserSubscription(tx, user, subscriptionID)
 	if err != nil {
 		c.Flash().Add("danger", "Subscription not found")
 		return c.Redirect(http.StatusFound, "/account/subscriptions")
@@ -253,7 +260,7 @@ func SubscriptionDetails(c buffalo.Context) error {
 
 	// Get subscription details from Helcim
 	helcimClient := services.NewHelcimClient()
-	subscription, err := helcimClient.GetSubscription(subscriptionID)
+	subscription, err := helcimClient.GetSubscription(c, subscriptionID)
 	if err != nil {
 		c.Flash().Add("warning", "Unable to load current subscription status from payment processor")
 		// Still show the page with limited info
@@ -262,7 +269,9 @@ func SubscriptionDetails(c buffalo.Context) error {
 
 	c.Set("donation", donation)
 	c.Set("subscription", subscription)
+	c.Set("upgradePrompt", upgradePromptFor(tx, user, donation))
 	c.Set("csrf", c.Value("authenticity_token"))
+	c.Set("title", "Subscription Details")
 	return c.Render(http.StatusOK, r.HTML("users/subscription_details.plush.html"))
 }
 
@@ -273,8 +282,7 @@ func CancelSubscription(c buffalo.Context) error {
 	tx := c.Value("tx").(*pop.Connection)
 
 	// Verify this subscription belongs to the user
-	donation := &models.Donation{}
-	err := tx.Where("user_id = ? AND subscription_id = ?", user.ID, subscriptionID).First(donation)
+	donation, err := findUserSubscription(tx, user, subscriptionID)
 	if err != nil {
 		c.Flash().Add("danger", "Subscription not found")
 		return c.Redirect(http.StatusFound, "/account/subscriptions")
@@ -288,7 +296,7 @@ func CancelSubscription(c buffalo.Context) error {
 
 	// Cancel the subscription with Helcim
 	helcimClient := services.NewHelcimClient()
-	err = helcimClient.CancelSubscription(subscriptionID)
+	err = helcimClient.CancelSubscription(c, subscriptionID)
 	if err != nil {
 		// Log the error but don't expose internal details
 		logging.Error("subscription_cancellation_failed", err, logging.Fields{
@@ -320,6 +328,328 @@ func CancelSubscription(c buffalo.Context) error {
 	return c.Redirect(http.StatusFound, "/account/subscriptions")
 }
 
+// PauseSubscription stops a user's recurring donation from being charged
+// until they resume it
+func PauseSubscription(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+	subscriptionID := c.Param("subscriptionId")
+	tx := c.Value("tx").(*pop.Connection)
+	detailsURL := fmt.Sprintf("/account/subscriptions/%s", subscriptionID)
+
+	donation, err := findUserSubscription(tx, user, subscriptionID)
+	if err != nil {
+		c.Flash().Add("danger", "Subscription not found")
+		return c.Redirect(http.StatusFound, "/account/subscriptions")
+	}
+	if donation.Status != "active" {
+		c.Flash().Add("warning", "Only active subscriptions can be paused")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+
+	subscription, err := services.NewHelcimClient().PauseSubscription(c, subscriptionID)
+	if err != nil {
+		logging.Error("subscription_pause_failed", err, logging.Fields{
+			"subscription_id": subscriptionID,
+			"user_id":         user.ID.String(),
+		})
+		c.Flash().Add("danger", "Unable to pause your subscription. Please try again later.")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+	recordSubscriptionStatus(tx, donation, subscription, models.DonationStatusPaused)
+
+	logging.UserAction(c, user.Email, "pause_subscription", "User paused recurring donation", logging.Fields{
+		"subscription_id": subscriptionID,
+		"donation_amount": donation.Amount,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Your %s donation is paused. You won't be charged until you resume it.", recurringLabel(donation)))
+	return c.Redirect(http.StatusFound, detailsURL)
+}
+
+// ResumeSubscription starts charging a user's paused recurring donation
+// again from its next billing date
+func ResumeSubscription(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+	subscriptionID := c.Param("subscriptionId")
+	tx := c.Value("tx").(*pop.Connection)
+	detailsURL := fmt.Sprintf("/account/subscriptions/%s", subscriptionID)
+
+	donation, err := findUserSubscription(tx, user, subscriptionID)
+	if err != nil {
+		c.Flash().Add("danger", "Subscription not found")
+		return c.Redirect(http.StatusFound, "/account/subscriptions")
+	}
+	if donation.Status != models.DonationStatusPaused {
+		c.Flash().Add("warning", "Only paused subscriptions can be resumed")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+
+	subscription, err := services.NewHelcimClient().ResumeSubscription(c, subscriptionID)
+	if err != nil {
+		logging.Error("subscription_resume_failed", err, logging.Fields{
+			"subscription_id": subscriptionID,
+			"user_id":         user.ID.String(),
+		})
+		c.Flash().Add("danger", "Unable to resume your subscription. Please try again later.")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+	recordSubscriptionStatus(tx, donation, subscription, "active")
+
+	logging.UserAction(c, user.Email, "resume_subscription", "User resumed recurring donation", logging.Fields{
+		"subscription_id": subscriptionID,
+		"donation_amount": donation.Amount,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Welcome back! Your %s donation will be charged again from your next billing date.", recurringLabel(donation)))
+	return c.Redirect(http.StatusFound, detailsURL)
+}
+
+// recordSubscriptionStatus updates our copy of a donation after its
+// subscription was paused or resumed with Helcim
+func recordSubscriptionStatus(tx *pop.Connection, donation *models.Donation, subscription *services.SubscriptionResponse, status string) {
+	applySubscriptionSync(donation, subscription, time.Now())
+	donation.Status = status
+	if err := tx.Update(donation); err != nil {
+		// Helcim already has the new status, and the nightly reconcile
+		// will bring ours in line, so only log the mismatch
+		logging.Error("donation_status_update_failed", err, logging.Fields{
+			"donation_id":     donation.ID.String(),
+			"subscription_id": stringOrEmpty(donation.SubscriptionID),
+		})
+	}
+}
+
+// recurringLabel is how often a donation is charged, for donor-facing copy
+// like "your quarterly donation"
+func recurringLabel(donation *models.Donation) string {
+	return strings.ToLower(donation.ChargeFrequency().Label())
+}
+
+// findUserSubscription loads the donation that started one of the user's
+// subscriptions, so donors can only manage their own
+func findUserSubscription(tx *pop.Connection, user *models.User, subscriptionID string) (*models.Donation, error) {
+	donation := &models.Donation{}
+	if err := tx.Where("user_id = ? AND subscription_id = ?", user.ID, subscriptionID).First(donation); err != nil {
+		return nil, err
+	}
+	return donation, nil
+}
+
+// parseSubscriptionAmount reads a new recurring amount typed by the donor,
+// allowing "$" and thousands separators, and holds it to the same limits as
+// the donation form
+func parseSubscriptionAmount(s string) (float64, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, errors.New("Please enter a new amount")
+	}
+	return parseDonationAmount(s, getCurrency())
+}
+
+// UpdateSubscriptionAmount changes the recurring amount of a user's
+// subscription with Helcim
+func UpdateSubscriptionAmount(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+	subscriptionID := c.Param("subscriptionId")
+	tx := c.Value("tx").(*pop.Connection)
+	detailsURL := fmt.Sprintf("/account/subscriptions/%s", subscriptionID)
+
+	donation, err := findUserSubscription(tx, user, subscriptionID)
+	if err != nil {
+		c.Flash().Add("danger", "Subscription not found")
+		return c.Redirect(http.StatusFound, "/account/subscriptions")
+	}
+	if donation.Status != "active" {
+		c.Flash().Add("warning", "Only active subscriptions can be changed")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+
+	amount, err := parseSubscriptionAmount(c.Param("amount"))
+	if err != nil {
+		c.Flash().Add("danger", err.Error())
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+	previous := donation.Amount
+	if amount == previous {
+		c.Flash().Add("info", fmt.Sprintf("Your %s donation is already $%.2f", recurringLabel(donation), amount))
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+
+	if err := changeSubscriptionAmount(c, tx, user, donation, amount); err != nil {
+		c.Flash().Add("danger", "Unable to change your donation amount. Please try again later.")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+
+	logging.UserAction(c, user.Email, "update_subscription_amount", "User changed recurring donation amount", logging.Fields{
+		"subscription_id": subscriptionID,
+		"previous_amount": previous,
+		"donation_amount": amount,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Your %s donation is now $%.2f, starting with your next billing date", recurringLabel(donation), amount))
+	return c.Redirect(http.StatusFound, detailsURL)
+}
+
+// changeSubscriptionAmount has Helcim bill a user's subscription a new
+// recurring amount and updates our copy of the donation. The error is logged
+// and left for the caller to tell the donor about.
+func changeSubscriptionAmount(c buffalo.Context, tx *pop.Connection, user *models.User, donation *models.Donation, amount float64) error {
+	subscriptionID := stringOrEmpty(donation.SubscriptionID)
+	helcimClient := services.NewHelcimClient()
+	if _, err := helcimClient.UpdateSubscription(c, subscriptionID, map[string]interface{}{
+		"recurringAmount": amount,
+	}); err != nil {
+		logging.Error("subscription_amount_update_failed", err, logging.Fields{
+			"subscription_id": subscriptionID,
+			"user_id":         user.ID.String(),
+		})
+		return err
+	}
+
+	donation.Amount = amount
+	if err := tx.Update(donation); err != nil {
+		// Helcim already bills the new amount, so only log the mismatch
+		logging.Error("donation_amount_update_failed", err, logging.Fields{
+			"donation_id":     donation.ID.String(),
+			"subscription_id": subscriptionID,
+		})
+	}
+	return nil
+}
+
+// SubscriptionPaymentMethod shows HelcimPay.js so a user can put a new card
+// on file for their subscription. The card is saved to the Helcim customer
+// the subscription bills.
+func SubscriptionPaymentMethod(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+	subscriptionID := c.Param("subscriptionId")
+	tx := c.Value("tx").(*pop.Connection)
+	detailsURL := fmt.Sprintf("/account/subscriptions/%s", subscriptionID)
+
+	donation, err := findUserSubscription(tx, user, subscriptionID)
+	if err != nil {
+		c.Flash().Add("danger", "Subscription not found")
+		return c.Redirect(http.StatusFound, "/account/subscriptions")
+	}
+	if donation.Status != "active" || donation.CustomerID == nil {
+		c.Flash().Add("warning", "The payment method can only be changed on an active subscription")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+
+	helcimResponse, err := callHelcimVerifyAPI(HelcimPayVerifyRequest{
+		PaymentType:  "verify",
+		Amount:       0,
+		Currency:     getCurrency(),
+		CustomerCode: *donation.CustomerID,
+	})
+	if err != nil {
+		logging.Error("subscription_card_checkout_failed", err, logging.Fields{
+			"subscription_id": subscriptionID,
+			"user_id":         user.ID.String(),
+		})
+		c.Flash().Add("danger", "Unable to start the card update. Please try again later.")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+
+	// Kept on the server so the HelcimPay.js response posted back can be
+	// checked against this checkout
+	donation.SecretToken = helcimResponse.SecretToken
+	if err := tx.UpdateColumns(donation, "secret_token", "updated_at"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("donation", donation)
+	c.Set("subscriptionID", subscriptionID)
+	c.Set("checkoutToken", helcimResponse.CheckoutToken)
+	c.Set("csrf", c.Value("authenticity_token"))
+	c.Set("title", "Update Payment Method")
+	return c.Render(http.StatusOK, r.HTML("users/subscription_payment_method.plush.html"))
+}
+
+// UpdateSubscriptionPaymentMethod switches a user's subscription to the card
+// they just verified through HelcimPay.js
+func UpdateSubscriptionPaymentMethod(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+	subscriptionID := c.Param("subscriptionId")
+	tx := c.Value("tx").(*pop.Connection)
+	detailsURL := fmt.Sprintf("/account/subscriptions/%s", subscriptionID)
+
+	donation, err := findUserSubscription(tx, user, subscriptionID)
+	if err != nil {
+		c.Flash().Add("danger", "Subscription not found")
+		return c.Redirect(http.StatusFound, "/account/subscriptions")
+	}
+	if donation.Status != "active" {
+		c.Flash().Add("warning", "The payment method can only be changed on an active subscription")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+	card, err := verifyHelcimPayResponse(c.Param("helcim_data"), c.Param("helcim_hash"), donation.SecretToken)
+	if err == nil && card.CustomerCode != "" && card.CustomerCode != stringOrEmpty(donation.CustomerID) {
+		err = errors.New("card saved to a different customer")
+	}
+	if err != nil {
+		logging.SecurityEvent(c, "subscription_card_update", "blocked", err.Error(), logging.Fields{
+			"subscription_id": subscriptionID,
+			"user_id":         user.ID.String(),
+		})
+		c.Flash().Add("danger", "Your new card wasn't verified. Please try again.")
+		return c.Redirect(http.StatusFound, detailsURL+"/payment-method")
+	}
+
+	if err := switchSubscriptionCard(c, services.NewHelcimClient(), subscriptionID, card.CardToken); err != nil {
+		logging.Error("subscription_payment_method_update_failed", err, logging.Fields{
+			"subscription_id": subscriptionID,
+			"user_id":         user.ID.String(),
+		})
+		c.Flash().Add("danger", "Unable to update your payment method. Please try again later.")
+		return c.Redirect(http.StatusFound, detailsURL)
+	}
+
+	logging.UserAction(c, user.Email, "update_subscription_payment_method", "User updated recurring donation card", logging.Fields{
+		"subscription_id": subscriptionID,
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Your new card will be used for future %s donations", recurringLabel(donation)))
+	return c.Redirect(http.StatusFound, detailsURL)
+}
+
+// helcimPayCard is the card HelcimPay.js saved, from its response data
+type helcimPayCard struct {
+	CardToken    string `json:"cardToken"`
+	CustomerCode string `json:"customerCode"`
+}
+
+// verifyHelcimPayResponse checks that a HelcimPay.js response came from
+// Helcim for the checkout with secretToken: its hash is the SHA-256 of the
+// response data followed by the checkout's secret token. It returns the
+// card that was saved.
+func verifyHelcimPayResponse(data, hash, secretToken string) (helcimPayCard, error) {
+	card := helcimPayCard{}
+	if data == "" || secretToken == "" {
+		return card, errors.New("missing HelcimPay.js response")
+	}
+	sum := sha256.Sum256([]byte(data + secretToken))
+	if !hmac.Equal([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(strings.TrimSpace(hash)))) {
+		return card, errors.New("HelcimPay.js response hash doesn't match")
+	}
+	if err := json.Unmarshal([]byte(data), &card); err != nil {
+		return card, errors.Wrap(err, "reading HelcimPay.js response")
+	}
+	if card.CardToken == "" {
+		return card, errors.New("HelcimPay.js response has no card token")
+	}
+	return card, nil
+}
+
+// switchSubscriptionCard has Helcim bill a subscription to the card saved
+// as cardToken from now on
+func switchSubscriptionCard(ctx context.Context, client services.HelcimAPI, subscriptionID, cardToken string) error {
+	_, err := client.UpdateSubscription(ctx, subscriptionID, map[string]interface{}{
+		"paymentMethod": "card",
+		"cardToken":     cardToken,
+	})
+	return err
+}
+
 // SetCurrentUser attempts to find a user based on the current_user_id
 // in the session. If one is found it is set on the context.
 func SetCurrentUser(next buffalo.Handler) buffalo.Handler {
@@ -328,14 +658,37 @@ func SetCurrentUser(next buffalo.Handler) buffalo.Handler {
 		if sessionUID != nil {
 			c.Logger().Infof("Found current_user_id in session: %v", sessionUID)
 			u := &models.User{}
-			tx := c.Value("tx").(*pop.Connection)
+			// Long-lived streams run without a request transaction
+			tx, ok := c.Value("tx").(*pop.Connection)
+			if !ok {
+				tx = models.DB
+			}
 			err := tx.Find(u, sessionUID)
 			if err != nil {
 				c.Logger().Infof("User not found for ID %v, clearing session", sessionUID)
 				// If user not found, clear the session and continue
 				c.Session().Delete("current_user_id")
 				c.Set("current_user", nil)
+			} else if !u.Active() {
+				// Deactivated by staff since signing in
+				logging.SecurityEvent(c, "session_ended", "success", "user_deactivated", logging.Fields{
+					"user_id": u.ID.String(),
+				})
+				c.Session().Clear()
+				c.Set("current_user", nil)
 			} else {
+				// Rotate the session when the user's role changed since it was
+				// issued (e.g. an admin demoted or promoted them).
+				if role, ok := c.Session().Get(sessionRoleKey).(string); ok && role != u.Role {
+					logging.SecurityEvent(c, "session_rotated", "success", "role_changed", logging.Fields{
+						"user_id":       u.ID.String(),
+						"previous_role": role,
+						"role":          u.Role,
+					})
+					rotateSession(c, u.ID, u.Role)
+				} else if !ok {
+					c.Session().Set(sessionRoleKey, u.Role)
+				}
 				c.Logger().Infof("Setting current_user: %s (%s)", u.Email, u.Role)
 				c.Set("current_user", u)
 			}
@@ -1,12 +1,21 @@
 package actions
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"testing"
 	"time"
 
+	"github.com/gobuffalo/buffalo"
+	"github.com/stretchr/testify/require"
+
 	"avrnpo.org/models"
+	"avrnpo.org/services"
 )
 
 func (as *ActionSuite) Test_Users_New() {
@@ -173,6 +182,145 @@ func (as *ActionSuite) Test_AccountSettings_RequiresAuth() {
 	as.Equal(http.StatusFound, res.Code) // Should redirect to signin
 }
 
+func (as *ActionSuite) Test_SubscriptionPaymentMethod_RequiresAuth() {
+	res := as.HTML("/account/subscriptions/123/payment-method").Get()
+	as.Equal(http.StatusFound, res.Code) // Should redirect to signin
+}
+
+func (as *ActionSuite) Test_PauseResumeSubscription_RequiresAuth() {
+	res := as.HTML("/account/subscriptions/123/pause").Post(nil)
+	as.Equal(http.StatusFound, res.Code) // Should redirect to signin
+	res = as.HTML("/account/subscriptions/123/resume").Post(nil)
+	as.Equal(http.StatusFound, res.Code)
+}
+
+func Test_SubscriptionTemplatesPauseResume(t *testing.T) {
+	req := require.New(t)
+
+	subscriptionID := "sub_123"
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/subscription-test", func(c buffalo.Context) error {
+		c.Set("csrf", "")
+		c.Set("donation", &models.Donation{Amount: 25, DonationType: models.DonationTypeMonthly, Frequency: models.NormalizeDonationFrequency(c.Param("frequency")), Status: c.Param("status"), SubscriptionID: &subscriptionID})
+		c.Set("subscription", nil)
+		c.Set("upgradePrompt", nil)
+		return c.Render(http.StatusOK, r.HTML("users/subscription_details.plush.html"))
+	})
+	app.GET("/subscriptions-test", func(c buffalo.Context) error {
+		c.Set("subscriptions", []*models.Donation{{Amount: 25, DonationType: models.DonationTypeMonthly, Status: models.DonationStatusPaused, SubscriptionID: &subscriptionID}})
+		return c.Render(http.StatusOK, r.HTML("users/subscriptions_list.plush.html"))
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/subscription-test?status=active", nil))
+	body := res.Body.String()
+	req.Equal(http.StatusOK, res.Code, body)
+	req.Contains(body, "✅ Active")
+	req.Contains(body, `action="/account/subscriptions/sub_123/pause"`)
+	req.NotContains(body, `action="/account/subscriptions/sub_123/resume"`)
+	req.Contains(body, `action="/account/subscriptions/sub_123/cancel"`)
+	req.Contains(body, "Amount per month")
+
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/subscription-test?status=active&frequency=annual", nil))
+	body = res.Body.String()
+	req.Equal(http.StatusOK, res.Code, body)
+	req.Contains(body, "Amount per year")
+	req.NotContains(body, "onthly")
+
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/subscription-test?status=paused", nil))
+	body = res.Body.String()
+	req.Equal(http.StatusOK, res.Code, body)
+	req.Contains(body, "⏸ Paused")
+	req.Contains(body, `action="/account/subscriptions/sub_123/resume"`)
+	req.NotContains(body, `action="/account/subscriptions/sub_123/pause"`)
+	req.NotContains(body, `action="/account/subscriptions/sub_123/amount"`)
+	req.Contains(body, `action="/account/subscriptions/sub_123/cancel"`)
+
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/subscription-test?status=cancelled", nil))
+	body = res.Body.String()
+	req.Equal(http.StatusOK, res.Code, body)
+	req.Contains(body, "❌ Cancelled")
+	req.NotContains(body, "⚙️ Actions")
+
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/subscriptions-test", nil))
+	body = res.Body.String()
+	req.Equal(http.StatusOK, res.Code, body)
+	req.Contains(body, "⏸ Paused")
+	req.Contains(body, `href="/account/subscriptions/sub_123"`)
+}
+
+func Test_RecurringLabel(t *testing.T) {
+	require.Equal(t, "monthly", recurringLabel(&models.Donation{DonationType: models.DonationTypeMonthly}))
+	require.Equal(t, "annual", recurringLabel(&models.Donation{DonationType: models.DonationTypeMonthly, Frequency: models.FrequencyAnnual}))
+	require.Equal(t, "quarterly", recurringLabel(&models.Donation{DonationType: models.DonationTypeMonthly, Frequency: models.FrequencyQuarterly}))
+}
+
+func Test_ParseSubscriptionAmount(t *testing.T) {
+	amount, err := parseSubscriptionAmount(" $1,250.50 ")
+	require.NoError(t, err)
+	require.Equal(t, 1250.50, amount)
+
+	_, err = parseSubscriptionAmount("")
+	require.Error(t, err)
+	_, err = parseSubscriptionAmount("ten")
+	require.Error(t, err)
+	_, err = parseSubscriptionAmount("-5")
+	require.Error(t, err)
+	_, err = parseSubscriptionAmount("0.50")
+	require.EqualError(t, err, fmt.Sprintf("The minimum donation is $%.2f", donationMinimum()))
+}
+
+func Test_VerifyHelcimPayResponse(t *testing.T) {
+	req := require.New(t)
+
+	data := `{"cardToken":"tok_new","customerCode":"CST1001"}`
+	sum := sha256.Sum256([]byte(data + "secret"))
+	hash := hex.EncodeToString(sum[:])
+
+	card, err := verifyHelcimPayResponse(data, hash, "secret")
+	req.NoError(err)
+	req.Equal(helcimPayCard{CardToken: "tok_new", CustomerCode: "CST1001"}, card)
+
+	_, err = verifyHelcimPayResponse(data, hash, "another checkout")
+	req.Error(err, "a response from another checkout")
+	_, err = verifyHelcimPayResponse(`{"cardToken":"tok_forged"}`, hash, "secret")
+	req.Error(err, "a tampered response")
+	_, err = verifyHelcimPayResponse(data, "", "secret")
+	req.Error(err)
+	_, err = verifyHelcimPayResponse("", "", "")
+	req.Error(err, "no response posted")
+
+	empty := `{"customerCode":"CST1001"}`
+	sum = sha256.Sum256([]byte(empty + "secret"))
+	_, err = verifyHelcimPayResponse(empty, hex.EncodeToString(sum[:]), "secret")
+	req.Error(err, "no card saved")
+}
+
+// recordingHelcimSubscriptions records subscription updates. Any other
+// Helcim call panics.
+type recordingHelcimSubscriptions struct {
+	services.HelcimAPI
+	updates map[string]map[string]interface{}
+}
+
+func (r *recordingHelcimSubscriptions) UpdateSubscription(ctx context.Context, subscriptionID string, updates map[string]interface{}) (*services.SubscriptionResponse, error) {
+	r.updates[subscriptionID] = updates
+	return &services.SubscriptionResponse{}, nil
+}
+
+func Test_SwitchSubscriptionCard(t *testing.T) {
+	req := require.New(t)
+
+	client := &recordingHelcimSubscriptions{updates: map[string]map[string]interface{}{}}
+	req.NoError(switchSubscriptionCard(context.Background(), client, "sub_123", "tok_new"))
+	req.Equal("tok_new", client.updates["sub_123"]["cardToken"], "the verified card is sent to Helcim")
+	req.Equal("card", client.updates["sub_123"]["paymentMethod"])
+}
+
 func (as *ActionSuite) Test_AccountSettings_ProgressiveEnhancement() {
 	timestamp := time.Now().UnixNano()
 
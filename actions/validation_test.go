@@ -189,6 +189,18 @@ func TestValidateContactForm(t *testing.T) {
 			false,
 			"Message must be less than 2000 characters",
 		},
+		{
+			"Unknown topic",
+			map[string]string{
+				"name":    "John Doe",
+				"email":   "john@example.com",
+				"subject": "Test Subject",
+				"message": "This is a test message",
+				"topic":   "billing",
+			},
+			false,
+			"please choose a topic from the list",
+		},
 	}
 
 	for _, tt := range tests {
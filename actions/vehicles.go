@@ -0,0 +1,474 @@
+package actions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// maxVehiclePhotos caps how many photos a donor can attach to a vehicle
+const maxVehiclePhotos = 6
+
+// maxVehiclePhotoSize is the largest photo we accept, in bytes
+const maxVehiclePhotoSize = 10 << 20
+
+// vehiclePhotoTypes maps the image types we accept to their file extension
+var vehiclePhotoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// vehiclePhotoUpload is a checked photo from the donation form, with its
+// metadata stripped
+type vehiclePhotoUpload struct {
+	header      *multipart.FileHeader
+	contentType string
+	data        []byte
+}
+
+// vehiclePhotoUploads checks the photos attached to the vehicle donation
+// form, sniffing each file's type rather than trusting the browser
+func vehiclePhotoUploads(c buffalo.Context) ([]vehiclePhotoUpload, string) {
+	req := c.Request()
+	if err := req.ParseMultipartForm(multipartMemory); err != nil && err != http.ErrNotMultipart {
+		return nil, "Your photos couldn't be uploaded. Please try again with smaller files."
+	}
+	if req.MultipartForm == nil {
+		return nil, ""
+	}
+
+	var uploads []vehiclePhotoUpload
+	for _, header := range req.MultipartForm.File["Photos"] {
+		if header.Size == 0 {
+			continue
+		}
+		if len(uploads) == maxVehiclePhotos {
+			return nil, fmt.Sprintf("Please attach no more than %d photos.", maxVehiclePhotos)
+		}
+		if header.Size > maxVehiclePhotoSize {
+			return nil, fmt.Sprintf("%s is too large. Photos must be under %d MB.", header.Filename, maxVehiclePhotoSize>>20)
+		}
+		f, err := header.Open()
+		if err != nil {
+			return nil, "Your photos couldn't be uploaded. Please try again."
+		}
+		sniff := make([]byte, 512)
+		n, _ := io.ReadFull(f, sniff)
+		f.Close()
+		contentType := http.DetectContentType(sniff[:n])
+		if _, ok := vehiclePhotoTypes[contentType]; !ok {
+			return nil, fmt.Sprintf("%s isn't a JPEG, PNG or WebP image.", header.Filename)
+		}
+		data, err := cleanImageUpload(header, contentType, nil)
+		if err != nil {
+			return nil, fmt.Sprintf("%s couldn't be read as an image. Please try another photo.", header.Filename)
+		}
+		uploads = append(uploads, vehiclePhotoUpload{header: header, contentType: contentType, data: data})
+	}
+	return uploads, ""
+}
+
+// saveVehiclePhotos stores the donor's photos and records them against the
+// vehicle
+func saveVehiclePhotos(tx *pop.Connection, vehicle *models.VehicleDonation, uploads []vehiclePhotoUpload) error {
+	storage := services.NewStorage()
+	for _, upload := range uploads {
+		photo := &models.VehicleDonationPhoto{
+			ID:                uuid.Must(uuid.NewV4()),
+			VehicleDonationID: vehicle.ID,
+			Filename:          upload.header.Filename,
+			ContentType:       upload.contentType,
+			ScanStatus:        initialScanStatus(),
+		}
+		photo.StorageKey = fmt.Sprintf("vehicles/%s/%s%s", vehicle.ID, photo.ID, vehiclePhotoTypes[upload.contentType])
+
+		if err := storage.Save(photo.StorageKey, bytes.NewReader(upload.data)); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := tx.Create(photo); err != nil {
+			return errors.WithStack(err)
+		}
+		queueUploadScan(tx, uploadKindVehiclePhoto, photo.ID, photo.ScanStatus)
+	}
+	return nil
+}
+
+// bindVehicleDonation copies the public vehicle donation form onto vehicle.
+func bindVehicleDonation(c buffalo.Context, vehicle *models.VehicleDonation) {
+	vehicle.DonorName = strings.TrimSpace(c.Param("DonorName"))
+	vehicle.DonorEmail = strings.ToLower(strings.TrimSpace(c.Param("DonorEmail")))
+	vehicle.DonorPhone = stringPointer(strings.TrimSpace(c.Param("DonorPhone")))
+	vehicle.AddressLine1 = strings.TrimSpace(c.Param("AddressLine1"))
+	vehicle.City = strings.TrimSpace(c.Param("City"))
+	vehicle.State = strings.ToUpper(strings.TrimSpace(c.Param("State")))
+	vehicle.Zip = strings.TrimSpace(c.Param("Zip"))
+	vehicle.Year, _ = strconv.Atoi(strings.TrimSpace(c.Param("Year")))
+	vehicle.Make = strings.TrimSpace(c.Param("Make"))
+	vehicle.Model = strings.TrimSpace(c.Param("Model"))
+	vehicle.VIN = models.NormalizeVIN(c.Param("VIN"))
+	vehicle.Mileage, _ = strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(c.Param("Mileage")), ",", ""))
+	vehicle.Condition = c.Param("Condition")
+	vehicle.ConditionNotes = stringPointer(strings.TrimSpace(c.Param("ConditionNotes")))
+}
+
+// setVehicleFormContext exposes a vehicle to the public donation form
+func setVehicleFormContext(c buffalo.Context, vehicle *models.VehicleDonation) {
+	year := ""
+	if vehicle.Year > 0 {
+		year = strconv.Itoa(vehicle.Year)
+	}
+	mileage := ""
+	if vehicle.Mileage > 0 {
+		mileage = strconv.Itoa(vehicle.Mileage)
+	}
+	c.Set("title", "Donate a Vehicle")
+	c.Set("vehicle", vehicle)
+	c.Set("vehicleYear", year)
+	c.Set("vehicleMileage", mileage)
+	c.Set("vehicleDonorPhone", vehicle.DonorPhoneText())
+	c.Set("vehicleConditionNotes", vehicle.ConditionNotesText())
+	c.Set("vehicleConditions", models.VehicleConditions)
+	c.Set("conditionLabel", models.VehicleConditionLabel)
+	c.Set("maxPhotos", maxVehiclePhotos)
+	c.Set("formDraft", formDraftView{Form: draftFormVehicle})
+}
+
+// VehicleDonationHandler shows (GET) and submits (POST) the vehicle
+// donation form
+func VehicleDonationHandler(c buffalo.Context) error {
+	vehicle := &models.VehicleDonation{Status: models.VehicleSubmitted}
+	c.Set("submitted", false)
+	if c.Request().Method == "GET" {
+		setVehicleFormContext(c, vehicle)
+		if err := restoreFormDraft(c, draftFormVehicle); err != nil {
+			return err
+		}
+		return c.Render(http.StatusOK, r.HTML("pages/vehicle_donation.plush.html"))
+	}
+
+	tx := c.Value("tx").(*pop.Connection)
+	bindVehicleDonation(c, vehicle)
+	uploads, photoErr := vehiclePhotoUploads(c)
+
+	verrs, err := vehicle.Validate(tx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if photoErr != "" {
+		verrs.Add("photos", photoErr)
+	}
+	if verrs.HasAny() {
+		setVehicleFormContext(c, vehicle)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusUnprocessableEntity, r.HTML("pages/vehicle_donation.plush.html"))
+	}
+
+	user := &models.User{}
+	if err := tx.Where("LOWER(email) = ?", vehicle.DonorEmail).First(user); err == nil {
+		vehicle.UserID = &user.ID
+	}
+	if err := tx.Create(vehicle); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := saveVehiclePhotos(tx, vehicle, uploads); err != nil {
+		return err
+	}
+	if err := discardFormDraft(c, tx, draftFormVehicle); err != nil {
+		return err
+	}
+
+	logging.Audit("vehicle_donation_submitted", logging.Fields{
+		"vehicle_id": vehicle.ID.String(),
+		"vehicle":    vehicle.Title(),
+		"photos":     len(uploads),
+	})
+
+	setVehicleFormContext(c, vehicle)
+	c.Set("submitted", true)
+	return c.Render(http.StatusOK, r.HTML("pages/vehicle_donation.plush.html"))
+}
+
+// vehicleAcknowledgement builds the 1098-C style acknowledgment for a
+// vehicle that has been sold or put to use
+func vehicleAcknowledgement(vehicle *models.VehicleDonation) services.VehicleAcknowledgementData {
+	contributed := vehicle.CreatedAt
+	if vehicle.PickedUpAt != nil {
+		contributed = *vehicle.PickedUpAt
+	}
+	data := services.VehicleAcknowledgementData{
+		DonorName:        vehicle.DonorName,
+		DonorAddress:     fmt.Sprintf("%s, %s, %s %s", vehicle.AddressLine1, vehicle.City, vehicle.State, vehicle.Zip),
+		ContributionDate: contributed,
+		Year:             vehicle.Year,
+		Make:             vehicle.Make,
+		Model:            vehicle.Model,
+		VIN:              vehicle.VIN,
+		Mileage:          vehicle.Mileage,
+		Sold:             vehicle.Status == models.VehicleSold,
+		ArmsLengthSale:   vehicle.ArmsLengthSale,
+		GrossProceeds:    vehicle.GrossProceeds,
+		UseDescription:   vehicle.UseDescriptionText(),
+		OrganizationName: organization().Name,
+		OrganizationEIN:  organization().EIN,
+	}
+	if vehicle.DisposedAt != nil {
+		data.SaleDate = *vehicle.DisposedAt
+	}
+	return data
+}
+
+// acknowledgeVehicle emails the donor their acknowledgment and records when
+// it went out
+func acknowledgeVehicle(tx *pop.Connection, vehicle *models.VehicleDonation) error {
+	if err := services.NewEmailService().SendVehicleAcknowledgement(vehicle.DonorEmail, vehicleAcknowledgement(vehicle)); err != nil {
+		return err
+	}
+	now := time.Now()
+	vehicle.AcknowledgedAt = &now
+	if err := tx.Update(vehicle); err != nil {
+		return errors.WithStack(err)
+	}
+	recordCommunication(tx, vehicle.DonorEmail, models.CommunicationAcknowledgment, fmt.Sprintf("Vehicle acknowledgment for %s", vehicle.Title()), nil, &vehicle.ID)
+	return nil
+}
+
+// loadVehicleDonation finds the vehicle named in the route
+func loadVehicleDonation(c buffalo.Context, tx *pop.Connection) (*models.VehicleDonation, error) {
+	vehicle := &models.VehicleDonation{}
+	if err := tx.Find(vehicle, c.Param("vehicle_id")); err != nil {
+		return nil, c.Error(http.StatusNotFound, err)
+	}
+	return vehicle, nil
+}
+
+// AdminVehiclesIndex lists vehicle donations at one workflow stage, with a
+// count of the vehicles at each
+func AdminVehiclesIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	status := c.Param("status")
+	valid := false
+	for _, s := range models.VehicleStatuses {
+		valid = valid || s == status
+	}
+	if !valid {
+		status = models.VehicleSubmitted
+	}
+
+	vehicles := models.VehicleDonations{}
+	if err := tx.Where("status = ?", status).Order("created_at asc").All(&vehicles); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := tx.RawQuery("SELECT status, COUNT(*) as count FROM vehicle_donations GROUP BY status").All(&rows); err != nil {
+		return errors.WithStack(err)
+	}
+	counts := map[string]int{}
+	for _, s := range models.VehicleStatuses {
+		counts[s] = 0
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	emails := make([]string, len(vehicles))
+	for i, v := range vehicles {
+		emails[i] = v.DonorEmail
+	}
+	flags, err := loadDonorFlagIndex(tx, emails)
+	if err != nil {
+		return err
+	}
+
+	c.Set("vehicles", vehicles)
+	c.Set("flags", flags)
+	c.Set("status", status)
+	c.Set("statusCounts", counts)
+	c.Set("vehicleStatuses", models.VehicleStatuses)
+	c.Set("statusLabel", models.VehicleStatusLabel)
+	return c.Render(http.StatusOK, r.HTML("admin/vehicles/index.plush.html"))
+}
+
+// AdminVehicleShow shows a vehicle donation with its photos and the form for
+// moving it to the next stage
+func AdminVehicleShow(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	vehicle, err := loadVehicleDonation(c, tx)
+	if err != nil {
+		return err
+	}
+	photos := models.VehicleDonationPhotos{}
+	if err := tx.Where("vehicle_donation_id = ?", vehicle.ID).Order("created_at asc").All(&photos); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("vehicle", vehicle)
+	c.Set("photos", photos)
+	c.Set("statusLabel", models.VehicleStatusLabel)
+	c.Set("scanStatusLabel", models.ScanStatusLabel)
+	c.Set("today", time.Now().Format(dateInputLayout))
+	return c.Render(http.StatusOK, r.HTML("admin/vehicles/show.plush.html"))
+}
+
+// AdminVehiclePhoto serves one of a vehicle's photos. Photos aren't public
+// since they can show the donor's home and plates, and staff only see them
+// once they've passed their virus scan.
+func AdminVehiclePhoto(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	photo := &models.VehicleDonationPhoto{}
+	err := tx.Where("id = ? AND vehicle_donation_id = ?", c.Param("photo_id"), c.Param("vehicle_id")).First(photo)
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if !photo.Released() {
+		return c.Error(http.StatusForbidden, fmt.Errorf("vehicle photo %s is %s", photo.ID, photo.ScanStatus))
+	}
+	f, err := services.NewStorage().Open(photo.StorageKey)
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	defer f.Close()
+
+	return c.Render(http.StatusOK, r.Func(photo.ContentType, func(w io.Writer, d render.Data) error {
+		_, err := io.Copy(w, f)
+		return err
+	}))
+}
+
+// AdminVehicleStatus moves a vehicle to its next workflow stage. Recording
+// the sale or use of the vehicle emails the donor their acknowledgment.
+func AdminVehicleStatus(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	vehicle, err := loadVehicleDonation(c, tx)
+	if err != nil {
+		return err
+	}
+	status := c.Param("Status")
+	if !vehicle.CanMoveTo(status) {
+		c.Flash().Add("danger", fmt.Sprintf("A %s vehicle can't be marked %s.", strings.ToLower(vehicle.StatusLabel()), strings.ToLower(models.VehicleStatusLabel(status))))
+		return c.Redirect(http.StatusSeeOther, "/admin/vehicles/%s", vehicle.ID)
+	}
+
+	date, err := time.ParseInLocation(dateInputLayout, c.Param("Date"), time.Local)
+	if err != nil {
+		date = time.Now()
+	}
+	previous := vehicle.Status
+	vehicle.Status = status
+	switch status {
+	case models.VehicleInspected:
+		vehicle.InspectedAt = &date
+	case models.VehiclePickedUp:
+		vehicle.PickedUpAt = &date
+	case models.VehicleSold:
+		vehicle.DisposedAt = &date
+		vehicle.GrossProceeds, _ = strconv.ParseFloat(strings.TrimSpace(c.Param("GrossProceeds")), 64)
+		vehicle.ArmsLengthSale = c.Param("ArmsLengthSale") == "true"
+	case models.VehicleUsed:
+		vehicle.DisposedAt = &date
+		vehicle.UseDescription = stringPointer(strings.TrimSpace(c.Param("UseDescription")))
+	}
+
+	verrs, err := tx.ValidateAndUpdate(vehicle)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+		return c.Redirect(http.StatusSeeOther, "/admin/vehicles/%s", vehicle.ID)
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "vehicle_donation_status", fmt.Sprintf("Marked %s %s", vehicle.Title(), vehicle.StatusLabel()), logging.Fields{
+		"vehicle_id":      vehicle.ID.String(),
+		"previous_status": previous,
+		"status":          vehicle.Status,
+	})
+
+	if vehicle.Disposed() {
+		if err := acknowledgeVehicle(tx, vehicle); err != nil {
+			c.Logger().Errorf("[Vehicles] Failed to send acknowledgment for vehicle %s: %v", vehicle.ID.String(), err)
+			c.Flash().Add("warning", "The vehicle was updated, but the donor's acknowledgment couldn't be emailed. Resend it below; it's due within 30 days.")
+			return c.Redirect(http.StatusSeeOther, "/admin/vehicles/%s", vehicle.ID)
+		}
+		c.Flash().Add("success", fmt.Sprintf("%s marked %s and the acknowledgment was emailed to %s.", vehicle.Title(), strings.ToLower(vehicle.StatusLabel()), vehicle.DonorEmail))
+		return c.Redirect(http.StatusSeeOther, "/admin/vehicles/%s", vehicle.ID)
+	}
+
+	c.Flash().Add("success", fmt.Sprintf("%s marked %s.", vehicle.Title(), strings.ToLower(vehicle.StatusLabel())))
+	return c.Redirect(http.StatusSeeOther, "/admin/vehicles/%s", vehicle.ID)
+}
+
+// AdminVehicleLetter renders a vehicle's acknowledgment on its own for printing
+func AdminVehicleLetter(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	vehicle, err := loadVehicleDonation(c, tx)
+	if err != nil {
+		return err
+	}
+	if !vehicle.Disposed() {
+		c.Flash().Add("warning", "The acknowledgment is available once the vehicle has been sold or put to use.")
+		return c.Redirect(http.StatusSeeOther, "/admin/vehicles/%s", vehicle.ID)
+	}
+
+	letter, err := services.NewEmailService().GenerateVehicleAcknowledgementHTML(vehicleAcknowledgement(vehicle))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return c.Render(http.StatusOK, r.Func("text/html", func(w io.Writer, d render.Data) error {
+		_, err := io.WriteString(w, letter)
+		return err
+	}))
+}
+
+// AdminVehicleAcknowledge resends a vehicle donor's acknowledgment
+func AdminVehicleAcknowledge(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	vehicle, err := loadVehicleDonation(c, tx)
+	if err != nil {
+		return err
+	}
+	if !vehicle.Disposed() {
+		c.Flash().Add("warning", "The acknowledgment is available once the vehicle has been sold or put to use.")
+		return c.Redirect(http.StatusSeeOther, "/admin/vehicles/%s", vehicle.ID)
+	}
+
+	if err := acknowledgeVehicle(tx, vehicle); err != nil {
+		c.Logger().Errorf("[Vehicles] Failed to send acknowledgment for vehicle %s: %v", vehicle.ID.String(), err)
+		c.Flash().Add("error", "The acknowledgment couldn't be sent. Please try again later.")
+		return c.Redirect(http.StatusSeeOther, "/admin/vehicles/%s", vehicle.ID)
+	}
+
+	currentUser := c.Value("current_user").(*models.User)
+	logging.UserAction(c, currentUser.ID.String(), "vehicle_donation_acknowledged", fmt.Sprintf("Sent vehicle acknowledgment to %s", vehicle.DonorName), logging.Fields{
+		"vehicle_id": vehicle.ID.String(),
+	})
+
+	c.Flash().Add("success", fmt.Sprintf("Acknowledgment emailed to %s.", vehicle.DonorEmail))
+	return c.Redirect(http.StatusSeeOther, "/admin/vehicles/%s", vehicle.ID)
+}
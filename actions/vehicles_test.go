@@ -0,0 +1,153 @@
+package actions
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+// vehiclePhotoRequest builds a multipart vehicle form post with the given
+// files attached as Photos
+func vehiclePhotoRequest(t *testing.T, files map[string][]byte) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, data := range files {
+		part, err := form.CreateFormFile("Photos", name)
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, form.Close())
+
+	req, _ := http.NewRequest("POST", "/photos-test", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func Test_VehiclePhotoUploads(t *testing.T) {
+	req := require.New(t)
+
+	var pngData bytes.Buffer
+	req.NoError(png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 2, 2))))
+
+	var uploads []vehiclePhotoUpload
+	var message string
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.POST("/photos-test", func(c buffalo.Context) error {
+		uploads, message = vehiclePhotoUploads(c)
+		return c.Render(http.StatusOK, r.String("ok"))
+	})
+
+	app.ServeHTTP(httptest.NewRecorder(), vehiclePhotoRequest(t, map[string][]byte{"truck.png": pngData.Bytes()}))
+	req.Empty(message)
+	req.Len(uploads, 1)
+	req.Equal("image/png", uploads[0].contentType)
+
+	app.ServeHTTP(httptest.NewRecorder(), vehiclePhotoRequest(t, map[string][]byte{
+		"truck.png":  pngData.Bytes(),
+		"title.html": []byte("<html><script>alert(1)</script></html>"),
+	}))
+	req.Nil(uploads)
+	req.Contains(message, "title.html isn't a JPEG, PNG or WebP image")
+}
+
+func Test_VehicleDonationTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	vehicle := &models.VehicleDonation{Make: "Ford", Model: "F-150", Year: 2013, Condition: models.VehicleNeedsRepair}
+	verrs := validate.NewErrors()
+	verrs.Add("vin", "123 is not a valid VIN")
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/vehicle-test", func(c buffalo.Context) error {
+		setVehicleFormContext(c, vehicle)
+		c.Set("submitted", false)
+		c.Set("errors", verrs)
+		return c.Render(http.StatusOK, r.HTML("pages/vehicle_donation.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("GET", "/vehicle-test", nil)
+	app.ServeHTTP(w, httpReq)
+
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `enctype="multipart/form-data"`)
+	req.Contains(w.Body.String(), `data-autosave="/drafts/vehicle"`)
+	req.Contains(w.Body.String(), `value="2013"`)
+	req.Contains(w.Body.String(), `<option value="needs_repair" selected>`)
+	req.Contains(w.Body.String(), "is not a valid VIN")
+}
+
+func Test_AdminVehicleShowTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	pickedUp := time.Date(2026, 9, 2, 0, 0, 0, 0, time.Local)
+	vehicle := &models.VehicleDonation{
+		DonorName:  "Sam Donor",
+		DonorEmail: "sam@example.com",
+		Year:       2013,
+		Make:       "Ford",
+		Model:      "F-150",
+		VIN:        "1FTFW1ET5DFC10312",
+		Condition:  models.VehicleRunsWell,
+		Status:     models.VehiclePickedUp,
+		PickedUpAt: &pickedUp,
+	}
+
+	photos := models.VehicleDonationPhotos{}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/vehicle-admin-test", func(c buffalo.Context) error {
+		c.Set("vehicle", vehicle)
+		c.Set("photos", photos)
+		c.Set("statusLabel", models.VehicleStatusLabel)
+		c.Set("scanStatusLabel", models.ScanStatusLabel)
+		c.Set("today", "2026-10-14")
+		return c.Render(http.StatusOK, r.HTML("admin/vehicles/show.plush.html"))
+	})
+
+	render := func() string {
+		w := httptest.NewRecorder()
+		httpReq, _ := http.NewRequest("GET", "/vehicle-admin-test", nil)
+		app.ServeHTTP(w, httpReq)
+		req.Equal(http.StatusOK, w.Code, w.Body.String())
+		return w.Body.String()
+	}
+
+	body := render()
+	req.Contains(body, "Picked up September 2, 2026")
+	req.Contains(body, "Mark Sold")
+	req.Contains(body, "Mark Used by AVR")
+	req.NotContains(body, "View &amp; Print Acknowledgment")
+
+	disposed := time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)
+	vehicle.Status = models.VehicleSold
+	vehicle.DisposedAt = &disposed
+	vehicle.GrossProceeds = 3250
+	body = render()
+	req.Contains(body, "Sold October 1, 2026 for $3,250.00")
+	req.Contains(body, "View &amp; Print Acknowledgment")
+	req.Contains(body, "Not sent yet.")
+	req.NotContains(body, "Mark Sold")
+
+	photos = models.VehicleDonationPhotos{
+		{ID: uuid.Must(uuid.NewV4()), Filename: "front.jpg", ScanStatus: models.ScanClean},
+		{ID: uuid.Must(uuid.NewV4()), Filename: "plates.jpg", ScanStatus: models.ScanInfected},
+	}
+	body = render()
+	req.Contains(body, "/photos/"+photos[0].ID.String())
+	req.NotContains(body, "/photos/"+photos[1].ID.String(), "quarantined photos aren't linked")
+	req.Contains(body, "Quarantined: infected")
+}
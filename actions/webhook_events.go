@@ -0,0 +1,268 @@
+package actions
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/logging"
+)
+
+// errInvalidWebhookData is returned when a webhook's data can't be read as
+// the event it claims to be
+var errInvalidWebhookData = errors.New("invalid webhook data format")
+
+// recordWebhookEvent stores a delivered webhook, or counts the delivery
+// against the event when it was delivered before. duplicate is true when
+// that earlier delivery was already handled, in which case this one should
+// be skipped.
+func recordWebhookEvent(db *pop.Connection, provider, eventID, eventType string, payload []byte, signature string) (*models.WebhookEvent, bool, error) {
+	id := models.WebhookEventID(eventID, payload)
+
+	existing := &models.WebhookEvent{}
+	err := db.Where("provider = ? AND event_id = ?", provider, id).First(existing)
+	if err == nil {
+		existing.Deliveries++
+		if err := db.UpdateColumns(existing, "deliveries", "updated_at"); err != nil {
+			return existing, existing.Handled(), errors.WithStack(err)
+		}
+		return existing, existing.Handled(), nil
+	}
+	if errors.Cause(err) != sql.ErrNoRows {
+		return nil, false, errors.WithStack(err)
+	}
+
+	event := &models.WebhookEvent{
+		Provider:   provider,
+		EventID:    id,
+		EventType:  eventType,
+		Payload:    string(payload),
+		Signature:  stringPointer(signature),
+		Status:     models.WebhookReceived,
+		Deliveries: 1,
+	}
+	if err := db.Create(event); err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	return event, false, nil
+}
+
+// finishWebhookEvent records how processing a stored event went. Events
+// that couldn't be stored are skipped, and a failure to record the outcome
+// is only logged since the event itself was handled.
+func finishWebhookEvent(db *pop.Connection, event *models.WebhookEvent, status string, processErr error) {
+	if event == nil {
+		return
+	}
+	event.Finish(status, processErr, time.Now())
+	if err := db.UpdateColumns(event, "status", "error", "attempts", "processed_at", "updated_at"); err != nil {
+		logging.Error("webhook_event_update_failed", err, logging.Fields{
+			"webhook_event_id": event.ID.String(),
+			"status":           event.Status,
+		})
+	}
+}
+
+// processHelcimEvent acts on a Helcim webhook event. It returns
+// models.WebhookIgnored and why for events the donation system doesn't act
+// on, and models.WebhookProcessed otherwise.
+func processHelcimEvent(tx *pop.Connection, event HelcimWebhookEvent, c buffalo.Context) (string, string, error) {
+	// Helcim sends cardTransaction and terminalCancel events, and
+	// bankTransaction events as bank transfers (ACH) settle or are returned
+	switch event.Type {
+	case "cardTransaction", "bankTransaction":
+	case "terminalCancel":
+		c.Logger().Infof("Received terminal cancel event - ignoring for donation system")
+		return models.WebhookIgnored, "terminal cancel not applicable", nil
+	default:
+		c.Logger().Warnf("Unknown webhook event type: %s", event.Type)
+		return models.WebhookIgnored, "unknown event type", nil
+	}
+
+	// For cardTransaction events, parse the detailed data from the Data field
+	var webhookData HelcimWebhookData
+	if event.Data != nil {
+		// Convert the map to JSON and then unmarshal to structured data
+		dataJSON, err := json.Marshal(event.Data)
+		if err != nil {
+			return "", "", errors.Wrap(errInvalidWebhookData, err.Error())
+		}
+		if err := json.Unmarshal(dataJSON, &webhookData); err != nil {
+			return "", "", errors.Wrap(errInvalidWebhookData, err.Error())
+		}
+
+		// Log subscription information for recurring payments
+		if webhookData.SubscriptionID != "" {
+			c.Logger().Infof("[Webhook] Recurring payment detected - SubscriptionID: %s, PaymentPlanID: %s, PaymentNumber: %d, NextBillingDate: %s",
+				webhookData.SubscriptionID, webhookData.PaymentPlanID, webhookData.PaymentNumber, webhookData.NextBillingDate)
+		} else {
+			c.Logger().Infof("[Webhook] One-time payment detected - TransactionID: %s", webhookData.TransactionID)
+		}
+
+		// Log detailed transaction information
+		c.Logger().Infof("[Webhook] Transaction details - Amount: $%.2f %s, Status: %s, Customer: %s %s (%s)",
+			webhookData.Amount, webhookData.Currency, webhookData.Status,
+			webhookData.Customer.FirstName, webhookData.Customer.LastName, webhookData.CustomerCode)
+	}
+
+	// Use transaction ID from webhook data if available, otherwise fall back to event.ID
+	transactionID := event.ID
+	if webhookData.TransactionID != "" {
+		transactionID = webhookData.TransactionID
+	}
+
+	if event.Type == "bankTransaction" {
+		return handleBankTransaction(tx, webhookData, transactionID, c)
+	}
+
+	if webhookData.SubscriptionID != "" && paymentDeclined(webhookData.Status) {
+		if err := handleFailedSubscriptionPayment(tx, webhookData, c); err != nil {
+			return "", "", errors.Wrap(err, "recording failed subscription payment")
+		}
+		queueSubscriptionSync(tx, webhookData.SubscriptionID)
+		return models.WebhookProcessed, "", nil
+	}
+
+	if webhookData.SubscriptionID != "" {
+		if err := resolveRecoveredPayment(tx, webhookData.SubscriptionID, time.Now()); err != nil {
+			return "", "", errors.Wrap(err, "resolving recovered subscription payment")
+		}
+		// Our copy of the subscription's status and next billing date only
+		// changes here, so it's refreshed from Helcim after each charge
+		queueSubscriptionSync(tx, webhookData.SubscriptionID)
+
+		handled, err := handleInstallmentWebhook(tx, webhookData, c)
+		if err != nil {
+			return "", "", errors.Wrap(err, "recording installment payment")
+		}
+		if handled {
+			return models.WebhookProcessed, "", nil
+		}
+	}
+
+	if err := handleCardTransaction(tx, transactionID, c); err != nil {
+		return "", "", err
+	}
+	return models.WebhookProcessed, "", nil
+}
+
+// AdminWebhookEventsIndex lists stored webhook events in one processing
+// state, newest first, with a count of the events in each
+func AdminWebhookEventsIndex(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+
+	status := c.Param("status")
+	valid := false
+	for _, s := range models.WebhookStatuses {
+		valid = valid || s == status
+	}
+	if !valid {
+		status = models.WebhookFailed
+	}
+
+	q := tx.PaginateFromParams(c.Params())
+	events := models.WebhookEvents{}
+	if err := q.Where("status = ?", status).Order("created_at desc").All(&events); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := tx.RawQuery("SELECT status, COUNT(*) as count FROM webhook_events GROUP BY status").All(&rows); err != nil {
+		return errors.WithStack(err)
+	}
+	counts := map[string]int{}
+	for _, s := range models.WebhookStatuses {
+		counts[s] = 0
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	c.Set("events", events)
+	c.Set("webhookStatus", status)
+	c.Set("statusCounts", counts)
+	c.Set("webhookStatuses", models.WebhookStatuses)
+	c.Set("statusLabel", models.WebhookStatusLabel)
+	c.Set("pagination", q.Paginator)
+	return c.Render(http.StatusOK, r.HTML("admin/webhooks/index.plush.html"))
+}
+
+// findWebhookEvent loads the stored event named in the URL
+func findWebhookEvent(c buffalo.Context) (*models.WebhookEvent, error) {
+	tx := c.Value("tx").(*pop.Connection)
+	event := &models.WebhookEvent{}
+	if err := tx.Find(event, c.Param("webhook_event_id")); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// AdminWebhookEventShow shows a stored webhook event and its payload
+func AdminWebhookEventShow(c buffalo.Context) error {
+	event, err := findWebhookEvent(c)
+	if err != nil {
+		c.Flash().Add("danger", "Webhook event not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/webhooks")
+	}
+
+	c.Set("event", event)
+	return c.Render(http.StatusOK, r.HTML("admin/webhooks/show.plush.html"))
+}
+
+// AdminWebhookEventReplay processes a failed or interrupted webhook event
+// again from its stored payload. The processing runs in its own
+// transaction so a replay that fails again leaves nothing half done, while
+// the attempt is still recorded against the event.
+func AdminWebhookEventReplay(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	event, err := findWebhookEvent(c)
+	if err != nil {
+		c.Flash().Add("danger", "Webhook event not found")
+		return c.Redirect(http.StatusSeeOther, "/admin/webhooks")
+	}
+	if !event.Replayable() {
+		c.Flash().Add("warning", fmt.Sprintf("This event was already %s, so it wasn't replayed.", event.Status))
+		return c.Redirect(http.StatusSeeOther, "/admin/webhooks/%s", event.ID)
+	}
+
+	var status, reason string
+	var helcimEvent HelcimWebhookEvent
+	processErr := json.Unmarshal([]byte(event.Payload), &helcimEvent)
+	if processErr != nil {
+		processErr = errors.Wrap(errInvalidWebhookData, processErr.Error())
+	} else {
+		processErr = models.DB.Transaction(func(replay *pop.Connection) error {
+			var err error
+			status, reason, err = processHelcimEvent(replay, helcimEvent, c)
+			return err
+		})
+	}
+	finishWebhookEvent(tx, event, status, processErr)
+
+	logging.UserAction(c, user.Email, "webhook_event_replayed", "Replayed webhook event", logging.Fields{
+		"webhook_event_id": event.ID.String(),
+		"event_id":         event.EventID,
+		"status":           event.Status,
+	})
+	switch {
+	case processErr != nil:
+		c.Flash().Add("danger", fmt.Sprintf("Replay failed: %v", processErr))
+	case status == models.WebhookIgnored:
+		c.Flash().Add("info", fmt.Sprintf("Replayed; the event was ignored (%s).", reason))
+	default:
+		c.Flash().Add("success", "Replayed the event successfully.")
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/webhooks/%s", event.ID)
+}
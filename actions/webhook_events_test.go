@@ -0,0 +1,93 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+)
+
+func Test_ProcessHelcimEventIgnoresOtherEvents(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/process-test", func(c buffalo.Context) error {
+		status, reason, err := processHelcimEvent(nil, HelcimWebhookEvent{ID: "evt-1", Type: "terminalCancel"}, c)
+		req.NoError(err)
+		req.Equal(models.WebhookIgnored, status)
+		req.Equal("terminal cancel not applicable", reason)
+
+		status, reason, err = processHelcimEvent(nil, HelcimWebhookEvent{ID: "evt-2", Type: "refund"}, c)
+		req.NoError(err)
+		req.Equal(models.WebhookIgnored, status)
+		req.Equal("unknown event type", reason)
+		return c.Render(http.StatusOK, r.String("ok"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/process-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+}
+
+func Test_FinishWebhookEventWithoutStoredEvent(t *testing.T) {
+	// Processing goes ahead when the event couldn't be stored, so there's
+	// nothing to record the outcome against
+	require.NotPanics(t, func() {
+		finishWebhookEvent((*pop.Connection)(nil), nil, models.WebhookProcessed, nil)
+	})
+}
+
+func Test_WebhookEventTemplatesRendering(t *testing.T) {
+	req := require.New(t)
+
+	failure := "recording installment payment: connection reset"
+	event := models.WebhookEvent{
+		ID:         uuid.Must(uuid.NewV4()),
+		Provider:   models.WebhookProviderHelcim,
+		EventID:    "evt-123",
+		EventType:  "cardTransaction",
+		Payload:    `{"id":"evt-123","type":"cardTransaction"}`,
+		Status:     models.WebhookFailed,
+		Error:      &failure,
+		Deliveries: 2,
+		Attempts:   2,
+		CreatedAt:  time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC),
+	}
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/admin-webhooks-test", func(c buffalo.Context) error {
+		c.Set("events", models.WebhookEvents{event})
+		c.Set("webhookStatus", models.WebhookFailed)
+		c.Set("statusCounts", map[string]int{models.WebhookFailed: 1, models.WebhookReceived: 0, models.WebhookProcessed: 12, models.WebhookIgnored: 3})
+		c.Set("webhookStatuses", models.WebhookStatuses)
+		c.Set("statusLabel", models.WebhookStatusLabel)
+		c.Set("pagination", &pop.Paginator{Page: 1, PerPage: 20, TotalPages: 1})
+		return c.Render(http.StatusOK, r.HTML("admin/webhooks/index.plush.html"))
+	})
+	app.GET("/admin-webhook-test", func(c buffalo.Context) error {
+		c.Set("event", &event)
+		return c.Render(http.StatusOK, r.HTML("admin/webhooks/show.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/admin-webhooks-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "<strong>Failed (1)</strong>")
+	req.Contains(w.Body.String(), `<a href="/admin/webhooks?status=processed">Processed</a> (12)`)
+	req.Contains(w.Body.String(), `<a href="/admin/webhooks/`+event.ID.String()+`">`)
+	req.Contains(w.Body.String(), "connection reset")
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/admin-webhook-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `action="/admin/webhooks/`+event.ID.String()+`/replay"`)
+	req.Contains(w.Body.String(), "Replay Event")
+	req.Contains(w.Body.String(), "&#34;type&#34;: &#34;cardTransaction&#34;")
+}
@@ -0,0 +1,299 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/helpers"
+	"avrnpo.org/pkg/logging"
+	"avrnpo.org/services"
+)
+
+// yearBounds is the start of year and of the year after it
+func yearBounds(year int) (time.Time, time.Time) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(1, 0, 0)
+}
+
+// parseReceiptYear reads a receipt year, which can't be in the future
+func parseReceiptYear(s string, now time.Time) (int, error) {
+	year, err := strconv.Atoi(s)
+	if err != nil || year < 2000 || year > now.Year() {
+		return 0, fmt.Errorf("%q is not a year we can issue receipts for", s)
+	}
+	return year, nil
+}
+
+// loadYearEndReceipts builds the year-end receipt of every donor who gave in
+// year, or only the donor with email when it's set. Installment pledges are
+// counted by the installments paid that year. Fully refunded gifts are left
+// out, and PayPal Giving Fund and Venmo gifts are too since PayPal Giving
+// Fund receipts those itself.
+func loadYearEndReceipts(tx *pop.Connection, year int, email string) ([]services.YearEndReceiptData, error) {
+	start, end := yearBounds(year)
+	email = models.NormalizeDonorEmail(email)
+
+	q := tx.Where("created_at >= ? AND created_at < ? AND installment_count = 0 AND donor_email <> ''", start, end).
+		Where("(status = ? OR (subscription_id IS NOT NULL AND status IN (?, ?)))", "completed", "active", "cancelled").
+		Where("(payment_method IS NULL OR payment_method NOT IN (?, ?))", services.GiftSourcePayPalGivingFund, services.GiftSourceVenmo)
+	if email != "" {
+		q = q.Where("LOWER(donor_email) = ?", email)
+	}
+	donations := models.Donations{}
+	if err := q.Order("created_at").All(&donations); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	iq := tx.Where("paid_at >= ? AND paid_at < ?", start, end)
+	if email != "" {
+		iq = iq.Where("donation_id IN (SELECT id FROM donations WHERE LOWER(donor_email) = ?)", email)
+	}
+	installments := models.PledgeInstallments{}
+	if err := iq.Order("paid_at").All(&installments); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	pledges := models.Donations{}
+	if len(installments) > 0 {
+		ids := make([]interface{}, len(installments))
+		for i, inst := range installments {
+			ids[i] = inst.DonationID
+		}
+		if err := tx.Where("id IN (?) AND status <> ? AND donor_email <> ''", append(ids, models.DonationStatusRefunded)...).All(&pledges); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return buildYearEndReceipts(year, donations, installments, pledges), nil
+}
+
+// buildYearEndReceipts groups a year's gifts into one receipt per donor,
+// ordered by email. The donor's name and address are taken from their
+// latest gift.
+func buildYearEndReceipts(year int, donations models.Donations, installments models.PledgeInstallments, pledges models.Donations) []services.YearEndReceiptData {
+	receipts := map[string]*services.YearEndReceiptData{}
+	latest := map[string]time.Time{}
+	add := func(d *models.Donation, line services.YearEndReceiptLine) {
+		key := models.NormalizeDonorEmail(d.DonorEmail)
+		receipt, ok := receipts[key]
+		if !ok {
+			receipt = &services.YearEndReceiptData{
+				Year:                year,
+				DonorEmail:          key,
+				OrganizationName:    organization().Name,
+				OrganizationEIN:     organization().EIN,
+				OrganizationAddress: organization().AddressText(),
+			}
+			receipts[key] = receipt
+		}
+		if !line.Date.Before(latest[key]) {
+			latest[key] = line.Date
+			receipt.DonorName = d.DonorName
+			receipt.DonorAddressLine1 = stringOrEmpty(d.AddressLine1)
+			receipt.DonorAddressLine2 = stringOrEmpty(d.AddressLine2)
+			receipt.DonorCity = stringOrEmpty(d.City)
+			receipt.DonorState = stringOrEmpty(d.State)
+			receipt.DonorZip = stringOrEmpty(d.Zip)
+		}
+		receipt.Lines = append(receipt.Lines, line)
+	}
+
+	for i := range donations {
+		d := &donations[i]
+		amount := d.Amount - d.RefundedAmount
+		if amount <= 0 {
+			continue
+		}
+		line := services.YearEndReceiptLine{
+			Date:          d.CreatedAt,
+			Description:   yearEndGiftDescription(d),
+			Amount:        amount,
+			GoodsValue:    math.Min(d.GoodsValue(), amount),
+			GoodsProvided: stringOrEmpty(d.GoodsDescription),
+		}
+		add(d, line)
+	}
+
+	byID := map[uuid.UUID]*models.Donation{}
+	for i := range pledges {
+		byID[pledges[i].ID] = &pledges[i]
+	}
+	for _, inst := range installments {
+		pledge, ok := byID[inst.DonationID]
+		if !ok {
+			continue
+		}
+		add(pledge, services.YearEndReceiptLine{
+			Date:        inst.PaidAt,
+			Description: "Pledge " + recurringReceiptLabel(pledge, inst.Sequence),
+			Amount:      inst.Amount,
+		})
+	}
+
+	out := make([]services.YearEndReceiptData, 0, len(receipts))
+	for _, receipt := range receipts {
+		sort.SliceStable(receipt.Lines, func(i, j int) bool {
+			return receipt.Lines[i].Date.Before(receipt.Lines[j].Date)
+		})
+		out = append(out, *receipt)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DonorEmail < out[j].DonorEmail })
+	return out
+}
+
+// yearEndGiftDescription describes a gift on the year-end receipt
+func yearEndGiftDescription(d *models.Donation) string {
+	switch {
+	case d.IsCrypto():
+		return fmt.Sprintf("Cryptocurrency gift (%s)", stringOrEmpty(d.CryptoCurrency))
+	case d.SubscriptionID != nil:
+		return "Monthly gift"
+	default:
+		return "One-time gift"
+	}
+}
+
+// yearEndReceiptSent reports whether the donor was already emailed their
+// receipt for year
+func yearEndReceiptSent(tx *pop.Connection, email string, year int) (bool, error) {
+	exists, err := tx.Where("donor_email = ? AND kind = ? AND summary LIKE ?",
+		models.NormalizeDonorEmail(email), models.CommunicationYearEndReceipt, fmt.Sprintf("%d %%", year)).
+		Exists(&models.DonorCommunication{})
+	return exists, errors.WithStack(err)
+}
+
+// AdminSendYearEndReceipts emails every donor who gave in the chosen year
+// their consolidated receipt. Donors already sent that year's receipt are
+// skipped, so the send can be run again after a partial failure.
+func AdminSendYearEndReceipts(c buffalo.Context) error {
+	tx := c.Value("tx").(*pop.Connection)
+	user := c.Value("current_user").(*models.User)
+
+	year, err := parseReceiptYear(c.Param("year"), time.Now())
+	if err != nil {
+		c.Flash().Add("danger", "Choose a past or current year to send receipts for.")
+		return c.Redirect(http.StatusSeeOther, "/admin/donations")
+	}
+
+	receipts, err := loadYearEndReceipts(tx, year, "")
+	if err != nil {
+		return err
+	}
+
+	emailService := services.NewEmailService()
+	sent, skipped, failed := 0, 0, 0
+	for _, receipt := range receipts {
+		already, err := yearEndReceiptSent(tx, receipt.DonorEmail, year)
+		if err != nil {
+			return err
+		}
+		if already {
+			skipped++
+			continue
+		}
+		if err := emailService.SendYearEndReceipt(receipt.DonorEmail, receipt); err != nil {
+			logging.Error("year_end_receipt_failed", err, logging.Fields{
+				"year":        year,
+				"donor_email": receipt.DonorEmail,
+			})
+			failed++
+			continue
+		}
+		recordCommunication(tx, receipt.DonorEmail, models.CommunicationYearEndReceipt,
+			fmt.Sprintf("%d year-end receipt for $%.2f in %s", year, receipt.Total(), helpers.Pluralize(len(receipt.Lines), "gift")), nil, nil)
+		sent++
+	}
+
+	logging.UserAction(c, user.Email, "year_end_receipts_sent", "Sent year-end receipts", logging.Fields{
+		"year":    year,
+		"sent":    sent,
+		"skipped": skipped,
+		"failed":  failed,
+	})
+
+	message := fmt.Sprintf("Sent %s for %d.", helpers.Pluralize(sent, "year-end receipt"), year)
+	if skipped > 0 {
+		message += fmt.Sprintf(" Skipped %s already sent theirs.", helpers.Pluralize(skipped, "donor"))
+	}
+	if failed > 0 {
+		c.Flash().Add("warning", fmt.Sprintf("%s %s couldn't be sent; send again to retry them.", message, helpers.Pluralize(failed, "receipt")))
+	} else {
+		c.Flash().Add("success", message)
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/donations")
+}
+
+// AccountReceipts lists the signed-in donor's year-end receipts, one for
+// each year they gave
+func AccountReceipts(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	var years []struct {
+		Year int `db:"year"`
+	}
+	err := tx.RawQuery(`SELECT DISTINCT EXTRACT(YEAR FROM created_at)::int AS year FROM donations WHERE LOWER(donor_email) = ?
+		UNION SELECT DISTINCT EXTRACT(YEAR FROM pi.paid_at)::int AS year FROM pledge_installments pi
+			JOIN donations d ON d.id = pi.donation_id WHERE LOWER(d.donor_email) = ?
+		ORDER BY year DESC`, models.NormalizeDonorEmail(user.Email), models.NormalizeDonorEmail(user.Email)).All(&years)
+	if err != nil {
+		c.Flash().Add("danger", "Unable to load your receipts")
+		return c.Redirect(http.StatusFound, "/account")
+	}
+
+	receipts := []services.YearEndReceiptData{}
+	for _, y := range years {
+		yearReceipts, err := loadYearEndReceipts(tx, y.Year, user.Email)
+		if err != nil {
+			return err
+		}
+		receipts = append(receipts, yearReceipts...)
+	}
+
+	c.Set("receipts", receipts)
+	c.Set("currentYear", time.Now().Year())
+	c.Set("title", "My Tax Receipts")
+	return c.Render(http.StatusOK, r.HTML("users/receipts.plush.html"))
+}
+
+// AccountReceiptDownload downloads the signed-in donor's year-end receipt
+func AccountReceiptDownload(c buffalo.Context) error {
+	user := c.Value("current_user").(*models.User)
+	tx := c.Value("tx").(*pop.Connection)
+
+	year, err := parseReceiptYear(c.Param("year"), time.Now())
+	if err != nil {
+		c.Flash().Add("danger", "Receipt not found")
+		return c.Redirect(http.StatusFound, "/account/receipts")
+	}
+	receipts, err := loadYearEndReceipts(tx, year, user.Email)
+	if err != nil {
+		return err
+	}
+	if len(receipts) == 0 {
+		c.Flash().Add("danger", fmt.Sprintf("We don't have any gifts from you in %d.", year))
+		return c.Redirect(http.StatusFound, "/account/receipts")
+	}
+
+	receipt, err := services.NewEmailService().GenerateYearEndReceiptHTML(receipts[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("avr-tax-receipt-%d.html", year)))
+	return c.Render(http.StatusOK, r.Func("text/html", func(w io.Writer, d render.Data) error {
+		_, err := io.WriteString(w, receipt)
+		return err
+	}))
+}
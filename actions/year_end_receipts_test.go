@@ -0,0 +1,114 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+)
+
+func Test_ParseReceiptYear(t *testing.T) {
+	r := require.New(t)
+	now := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	year, err := parseReceiptYear("2025", now)
+	r.NoError(err)
+	r.Equal(2025, year)
+
+	for _, s := range []string{"", "next", "2027", "1999"} {
+		_, err := parseReceiptYear(s, now)
+		r.Error(err, s)
+	}
+}
+
+func Test_BuildYearEndReceipts(t *testing.T) {
+	r := require.New(t)
+
+	oldAddress, newAddress := "1 Old Rd", "2 New St"
+	subscriptionID := "sub-1"
+	fmv, goods := 40.0, "Gala dinner"
+	crypto := models.PaymentMethodCrypto
+	btc := "BTC"
+	donations := models.Donations{
+		{DonorName: "Jane Doe", DonorEmail: "Jane@Example.com", Amount: 100, AddressLine1: &oldAddress,
+			CreatedAt: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{DonorName: "Jane Doe", DonorEmail: "jane@example.com", Amount: 25, SubscriptionID: &subscriptionID, AddressLine1: &newAddress,
+			CreatedAt: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{DonorName: "Jane Doe", DonorEmail: "jane@example.com", Amount: 150, FairMarketValue: &fmv, GoodsDescription: &goods,
+			RefundedAmount: 50, CreatedAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{DonorName: "Al Roe", DonorEmail: "al@example.com", Amount: 60, PaymentMethod: &crypto, CryptoCurrency: &btc,
+			CreatedAt: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		// Refunded in full
+		{DonorName: "Al Roe", DonorEmail: "al@example.com", Amount: 10, RefundedAmount: 10,
+			CreatedAt: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	pledge := models.Donation{ID: uuid.Must(uuid.NewV4()), DonorName: "Al Roe", DonorEmail: "al@example.com", Amount: 30,
+		DonationType: models.DonationTypeInstallment, InstallmentCount: 3}
+	installments := models.PledgeInstallments{
+		{DonationID: pledge.ID, Sequence: 2, Amount: 30, PaidAt: time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)},
+		// Its pledge was refunded, so it isn't loaded
+		{DonationID: uuid.Must(uuid.NewV4()), Sequence: 1, Amount: 80, PaidAt: time.Date(2025, 9, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	receipts := buildYearEndReceipts(2025, donations, installments, models.Donations{pledge})
+	r.Len(receipts, 2)
+
+	al := receipts[0]
+	r.Equal("al@example.com", al.DonorEmail)
+	r.Equal(2025, al.Year)
+	r.Len(al.Lines, 2)
+	r.Equal("Cryptocurrency gift (BTC)", al.Lines[0].Description)
+	r.Equal("Pledge Installment 2 of 3", al.Lines[1].Description)
+	r.Equal(90.0, al.Total())
+
+	jane := receipts[1]
+	r.Equal("jane@example.com", jane.DonorEmail)
+	r.Equal("2 New St", jane.DonorAddressLine1, "address comes from the latest gift")
+	r.Len(jane.Lines, 3)
+	r.Equal([]string{"One-time gift", "One-time gift", "Monthly gift"},
+		[]string{jane.Lines[0].Description, jane.Lines[1].Description, jane.Lines[2].Description})
+	r.Equal(100.0, jane.Lines[1].Amount)
+	r.Equal("Gala dinner", jane.Lines[1].GoodsProvided)
+	r.Equal(225.0, jane.Total())
+	r.Equal(185.0, jane.TotalDeductible())
+	r.Equal("American Veterans Rebuilding", jane.OrganizationName)
+}
+
+func Test_AccountReceiptsTemplateRendering(t *testing.T) {
+	req := require.New(t)
+
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.GET("/receipts-test", func(c buffalo.Context) error {
+		c.Set("receipts", []services.YearEndReceiptData{
+			{Year: 2026, Lines: []services.YearEndReceiptLine{{Amount: 50}}},
+			{Year: 2025, Lines: []services.YearEndReceiptLine{{Amount: 100, GoodsValue: 20}, {Amount: 25}}},
+		})
+		c.Set("currentYear", 2026)
+		return c.Render(http.StatusOK, r.HTML("users/receipts.plush.html"))
+	})
+	app.GET("/no-receipts-test", func(c buffalo.Context) error {
+		c.Set("receipts", []services.YearEndReceiptData{})
+		c.Set("currentYear", 2026)
+		return c.Render(http.StatusOK, r.HTML("users/receipts.plush.html"))
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/receipts-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), `href="/account/receipts/2025"`)
+	req.Contains(w.Body.String(), "<strong>2026</strong> <small>(so far)</small>")
+	req.Contains(w.Body.String(), "$125.00")
+	req.Contains(w.Body.String(), "$105.00")
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/no-receipts-test", nil))
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+	req.Contains(w.Body.String(), "We don't have any gifts on record")
+}
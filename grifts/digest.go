@@ -0,0 +1,23 @@
+package grifts
+
+import (
+	"fmt"
+	"time"
+
+	"avrnpo.org/actions"
+	"avrnpo.org/models"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("digest", func() {
+
+	grift.Desc("weekly", "Emails staff the past week's giving and the recurring revenue forecast (run weekly from cron)")
+	grift.Add("weekly", func(c *grift.Context) error {
+		if err := actions.SendWeeklyStaffDigest(models.DB, time.Now()); err != nil {
+			return err
+		}
+		fmt.Println("Sent the weekly staff digest")
+		return nil
+	})
+})
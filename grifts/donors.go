@@ -0,0 +1,56 @@
+package grifts
+
+import (
+	"fmt"
+	"time"
+
+	"avrnpo.org/actions"
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("donors", func() {
+
+	grift.Desc("backfill", "Matches donations made before donor records existed to their donors (run once; safe to run again)")
+	grift.Add("backfill", func(c *grift.Context) error {
+		linked, err := models.BackfillDonors(models.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Linked %d donations to donors\n", linked)
+		return nil
+	})
+
+	grift.Desc("sync_helcim", "Checks donor records against their Helcim customers and holds differences for review (run nightly from cron)")
+	grift.Add("sync_helcim", func(c *grift.Context) error {
+		result, err := actions.SyncHelcimCustomers(c, models.DB, services.NewHelcimClient(), time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Checked %d donors: %d addresses filled in, %d new discrepancies, %d failed\n",
+			result.Checked, result.Updated, result.Discrepancies, result.Failed)
+		return nil
+	})
+
+	grift.Desc("monthly_nudges", "Asks donors with three or more one-time gifts this past year to give monthly (run weekly from cron)")
+	grift.Add("monthly_nudges", func(c *grift.Context) error {
+		sent, err := actions.SendMonthlyNudges(models.DB, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Sent %d monthly giving nudges\n", sent)
+		return nil
+	})
+
+	grift.Desc("campaign_winners", "Picks the winning subject line of email campaigns whose test has run and sends it to the rest of their audience (run hourly from cron)")
+	grift.Add("campaign_winners", func(c *grift.Context) error {
+		finished, err := actions.FinishEmailCampaignTests(models.DB, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Finished %d email campaign tests\n", finished)
+		return nil
+	})
+})
@@ -0,0 +1,23 @@
+package grifts
+
+import (
+	"fmt"
+	"time"
+
+	"avrnpo.org/models"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("drafts", func() {
+
+	grift.Desc("prune", "Deletes saved form drafts nobody has touched in 30 days (run daily from cron)")
+	grift.Add("prune", func(c *grift.Context) error {
+		n, err := models.PruneFormDrafts(models.DB, time.Now().Add(-models.FormDraftRetention))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Pruned %d form draft(s)\n", n)
+		return nil
+	})
+})
@@ -0,0 +1,25 @@
+package grifts
+
+import (
+	"fmt"
+	"time"
+
+	"avrnpo.org/actions"
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("dunning", func() {
+
+	grift.Desc("retry", "Retries failed recurring donation payments that are due and emails donors the next notice (run daily from cron)")
+	grift.Add("retry", func(c *grift.Context) error {
+		retried, err := actions.RetryFailedPayments(c, models.DB, services.NewHelcimClient(), time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Retried %d failed recurring payments\n", retried)
+		return nil
+	})
+})
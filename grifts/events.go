@@ -0,0 +1,24 @@
+package grifts
+
+import (
+	"fmt"
+	"time"
+
+	"avrnpo.org/actions"
+	"avrnpo.org/models"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("events", func() {
+
+	grift.Desc("reminders", "Queues reminder emails for event ticket holders on each event's reminder schedule (run every 15 minutes from cron)")
+	grift.Add("reminders", func(c *grift.Context) error {
+		queued, err := actions.QueueEventReminders(models.DB, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Queued %d event reminders\n", queued)
+		return nil
+	})
+})
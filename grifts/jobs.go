@@ -0,0 +1,24 @@
+package grifts
+
+import (
+	"fmt"
+	"time"
+
+	"avrnpo.org/actions"
+	"avrnpo.org/models"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("jobs", func() {
+
+	grift.Desc("digest", "Emails veterans who turned on job alerts the job postings approved this week (run weekly from cron)")
+	grift.Add("digest", func(c *grift.Context) error {
+		sent, err := actions.SendJobDigest(models.DB, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Sent %d job board digests\n", sent)
+		return nil
+	})
+})
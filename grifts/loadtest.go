@@ -0,0 +1,24 @@
+package grifts
+
+import (
+	"fmt"
+
+	"avrnpo.org/actions"
+	"avrnpo.org/models"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("loadtest", func() {
+
+	grift.Desc("teardown", "Deletes the synthetic donors and donations made in load test mode, with their queued receipts and timeline entries")
+	grift.Add("teardown", func(c *grift.Context) error {
+		removed, err := actions.TearDownLoadTest(models.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d synthetic donations, %d synthetic donors and %d queued receipts\n",
+			removed.Donations, removed.Donors, removed.PendingReceipts)
+		return nil
+	})
+})
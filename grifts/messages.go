@@ -0,0 +1,24 @@
+package grifts
+
+import (
+	"fmt"
+	"time"
+
+	"avrnpo.org/actions"
+	"avrnpo.org/models"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("messages", func() {
+
+	grift.Desc("escalate", "Reminds staff about contact messages past the reply SLA and escalates those unanswered for twice as long (run hourly from cron)")
+	grift.Add("escalate", func(c *grift.Context) error {
+		reminded, escalated, err := actions.EscalateUnansweredMessages(models.DB, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Sent %d contact message reminders and %d escalations\n", reminded, escalated)
+		return nil
+	})
+})
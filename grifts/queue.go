@@ -0,0 +1,46 @@
+package grifts
+
+import (
+	"fmt"
+	"time"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/jobqueue"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("queue", func() {
+
+	grift.Desc("status", "Shows how many background jobs are in each state")
+	grift.Add("status", func(c *grift.Context) error {
+		counts, err := jobqueue.Counts(models.DB)
+		if err != nil {
+			return err
+		}
+		for _, s := range jobqueue.Statuses {
+			fmt.Printf("%-8s %d\n", s, counts[s])
+		}
+		return nil
+	})
+
+	grift.Desc("retry_dead", "Puts background jobs that ran out of attempts back in the queue")
+	grift.Add("retry_dead", func(c *grift.Context) error {
+		n, err := jobqueue.RetryDead(models.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Requeued %d dead job(s)\n", n)
+		return nil
+	})
+
+	grift.Desc("prune", "Deletes background jobs that finished more than a week ago")
+	grift.Add("prune", func(c *grift.Context) error {
+		n, err := jobqueue.Prune(models.DB, time.Now().AddDate(0, 0, -7))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Pruned %d finished job(s)\n", n)
+		return nil
+	})
+})
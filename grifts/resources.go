@@ -0,0 +1,24 @@
+package grifts
+
+import (
+	"fmt"
+	"time"
+
+	"avrnpo.org/actions"
+	"avrnpo.org/models"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("resources", func() {
+
+	grift.Desc("check_links", "Checks that every active resource library link still works (run daily from cron)")
+	grift.Add("check_links", func(c *grift.Context) error {
+		checked, broken, err := actions.CheckResourceLinks(models.DB, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Checked %d resource links, %d broken\n", checked, broken)
+		return nil
+	})
+})
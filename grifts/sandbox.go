@@ -0,0 +1,89 @@
+package grifts
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"avrnpo.org/models"
+
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/grift/grift"
+)
+
+// defaultSandboxFile is where donations:anonymize writes, and
+// donations:load_anonymized reads, when no file is given
+const defaultSandboxFile = "tmp/donations_anonymized.jsonl"
+
+// sandboxFile is the file named on the command line, or defaultSandboxFile
+func sandboxFile(c *grift.Context) string {
+	if len(c.Args) > 0 && c.Args[0] != "" {
+		return c.Args[0]
+	}
+	return defaultSandboxFile
+}
+
+var _ = grift.Namespace("donations", func() {
+
+	grift.Desc("anonymize", "Writes an anonymized copy of every donation for a staging database: grift donations:anonymize [file] (ANONYMIZE_SALT keeps pseudonyms stable between runs, ANONYMIZE_JITTER sets how far amounts move, default 0.1)")
+	grift.Add("anonymize", func(c *grift.Context) error {
+		// Without a salt a random one is used and thrown away, so nobody can
+		// work back from a pseudonym
+		salt := []byte(os.Getenv("ANONYMIZE_SALT"))
+		if len(salt) == 0 {
+			salt = make([]byte, 32)
+			if _, err := rand.Read(salt); err != nil {
+				return err
+			}
+		}
+		jitter := 0.1
+		if s := os.Getenv("ANONYMIZE_JITTER"); s != "" {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil || v < 0 || v >= 1 {
+				return fmt.Errorf("ANONYMIZE_JITTER must be a fraction between 0 and 1, like 0.1")
+			}
+			jitter = v
+		}
+
+		path := sandboxFile(c)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		anonymizer := models.NewDonationAnonymizer(salt, jitter, time.Now().UnixNano())
+		written, err := models.ExportAnonymizedDonations(models.DB, anonymizer, f)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d anonymized donations to %s\n", written, path)
+		return nil
+	})
+
+	grift.Desc("load_anonymized", "Loads a copy from donations:anonymize into this database, which must not be production: grift donations:load_anonymized [file]")
+	grift.Add("load_anonymized", func(c *grift.Context) error {
+		if env := envy.Get("GO_ENV", "development"); env == "production" {
+			return fmt.Errorf("refusing to load anonymized donations into the %s database", env)
+		}
+		path := sandboxFile(c)
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		loaded, err := models.LoadAnonymizedDonations(models.DB, f)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Loaded %d anonymized donations from %s\n", loaded, path)
+		return nil
+	})
+})
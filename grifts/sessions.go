@@ -0,0 +1,33 @@
+package grifts
+
+import (
+	"fmt"
+
+	"avrnpo.org/models"
+	"avrnpo.org/pkg/sessionstore"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("sessions", func() {
+
+	grift.Desc("prune", "Deletes expired server-side sessions")
+	grift.Add("prune", func(c *grift.Context) error {
+		n, err := sessionstore.Prune(models.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Pruned %d expired session(s)\n", n)
+		return nil
+	})
+
+	grift.Desc("invalidate_all", "Deletes every server-side session, signing out all users")
+	grift.Add("invalidate_all", func(c *grift.Context) error {
+		n, err := sessionstore.InvalidateAll(models.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Invalidated %d session(s)\n", n)
+		return nil
+	})
+})
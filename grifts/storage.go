@@ -0,0 +1,43 @@
+package grifts
+
+import (
+	"fmt"
+
+	"avrnpo.org/actions"
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("storage", func() {
+
+	grift.Desc("migrate", "Copies existing uploads from UPLOADS_DIR to the S3 bucket (set STORAGE_DRIVER=s3 first; safe to run again)")
+	grift.Add("migrate", func(c *grift.Context) error {
+		if err := services.CheckStorage(); err != nil {
+			return err
+		}
+		to, ok := services.NewStorage().(*services.S3Storage)
+		if !ok {
+			return fmt.Errorf("STORAGE_DRIVER must be s3 to migrate uploads")
+		}
+		from := services.NewLocalStorage()
+		copied, skipped, err := services.CopyUploads(from, to, func(key string, copied bool) {
+			if copied {
+				fmt.Printf("Copied %s\n", key)
+			}
+		})
+		fmt.Printf("Copied %d uploads to %s, %d were already there\n", copied, to.Bucket, skipped)
+		return err
+	})
+
+	grift.Desc("scrub_images", "Strips location and camera metadata from photos uploaded before it was done on upload (run once)")
+	grift.Add("scrub_images", func(c *grift.Context) error {
+		scrubbed, err := actions.ScrubStoredImages(models.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed metadata from %d stored images\n", scrubbed)
+		return nil
+	})
+})
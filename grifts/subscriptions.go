@@ -0,0 +1,26 @@
+package grifts
+
+import (
+	"fmt"
+	"time"
+
+	"avrnpo.org/actions"
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("subscriptions", func() {
+
+	grift.Desc("reconcile", "Checks active and paused monthly gifts against Helcim and updates ones cancelled, paused or resumed there (run daily from cron)")
+	grift.Add("reconcile", func(c *grift.Context) error {
+		result, err := actions.ReconcileSubscriptions(c, models.DB, services.NewHelcimClient(), time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Checked %d subscriptions: %d cancelled, %d paused, %d resumed, %d couldn't be checked\n",
+			result.Checked, result.Cancelled, result.Paused, result.Resumed, result.Failed)
+		return nil
+	})
+})
@@ -0,0 +1,26 @@
+package grifts
+
+import (
+	"fmt"
+	"time"
+
+	"avrnpo.org/actions"
+	"avrnpo.org/models"
+	"avrnpo.org/services"
+
+	"github.com/gobuffalo/grift/grift"
+)
+
+var _ = grift.Namespace("donations", func() {
+
+	grift.Desc("poll_transactions", "Asks Helcim about charged donations still pending with no webhook and completes or fails them (run every few minutes from cron)")
+	grift.Add("poll_transactions", func(c *grift.Context) error {
+		result, err := actions.PollStuckTransactions(c, models.DB, services.NewHelcimClient(), time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Checked %d pending donations: %d completed, %d failed, %d still unresolved\n",
+			result.Checked, result.Completed, result.Failed, result.Unresolved)
+		return nil
+	})
+})
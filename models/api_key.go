@@ -0,0 +1,275 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// APIKeyPrefix starts every API key, so a leaked key is easy to recognise
+const APIKeyPrefix = "avr_"
+
+// API key scopes: a full key can use the whole API, while a lookup key,
+// e.g. the bookkeeper's, can only look donations up
+const (
+	APIKeyScopeFull   = "full"
+	APIKeyScopeLookup = "lookup"
+)
+
+// APIKeyScopes are the scopes a key can be issued with
+var APIKeyScopes = []string{APIKeyScopeFull, APIKeyScopeLookup}
+
+// APIKey lets an integration such as Zapier call the REST API. Only a hash
+// of the key is stored; the key itself is shown once, when it's created.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"` // the key's first characters, to tell keys apart
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Scope      string     `json:"scope" db:"scope"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	// BurstLimit and DailyQuota override the default request limits for the
+	// key; 0 uses the default
+	BurstLimit int       `json:"burst_limit" db:"burst_limit"`
+	DailyQuota int       `json:"daily_quota" db:"daily_quota"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (k APIKey) String() string {
+	jk, _ := json.Marshal(k)
+	return string(jk)
+}
+
+// APIKeys is not required by pop and may be deleted
+type APIKeys []APIKey
+
+// NewAPIKey generates a key named name, returning the record to save and
+// the key to hand to the integration
+func NewAPIKey(name string, createdBy *uuid.UUID) (*APIKey, string, error) {
+	token, err := randomURLToken(24)
+	if err != nil {
+		return nil, "", err
+	}
+	key := APIKeyPrefix + token
+	return &APIKey{
+		Name:      name,
+		Prefix:    key[:len(APIKeyPrefix)+6],
+		KeyHash:   HashAPIKey(key),
+		Scope:     APIKeyScopeFull,
+		CreatedBy: createdBy,
+	}, key, nil
+}
+
+// HashAPIKey is the hash an API key is stored and looked up by
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// FindAPIKey looks up the unrevoked API key matching key. It returns nil
+// when there is none.
+func FindAPIKey(tx *pop.Connection, key string) (*APIKey, error) {
+	if key == "" {
+		return nil, nil
+	}
+	apiKey := &APIKey{}
+	err := tx.Where("key_hash = ? AND revoked_at IS NULL", HashAPIKey(key)).First(apiKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return apiKey, nil
+}
+
+// Revoked reports whether the key has been turned off
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// LookupOnly reports whether the key can only look donations up
+func (k APIKey) LookupOnly() bool {
+	return k.Scope == APIKeyScopeLookup
+}
+
+// ScopeLabel says what the key can do
+func (k APIKey) ScopeLabel() string {
+	if k.LookupOnly() {
+		return "Donation lookup only"
+	}
+	return "Full access"
+}
+
+// LastUsedLabel says when the key was last used, or "Never"
+func (k APIKey) LastUsedLabel() string {
+	if k.LastUsedAt == nil {
+		return "Never"
+	}
+	return k.LastUsedAt.Format("Jan 2, 2006 3:04 PM")
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (k *APIKey) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: k.Name, Name: "Name", Message: "Name is required"},
+		&validators.StringLengthInRange{Field: k.Name, Name: "Name", Max: 100, Message: "Name must be 100 characters or less"},
+		&validators.StringIsPresent{Field: k.KeyHash, Name: "KeyHash"},
+		&validators.StringInclusion{Field: k.Scope, Name: "Scope", List: APIKeyScopes, Message: "Scope must be full access or donation lookup"},
+		&validators.IntIsGreaterThan{Field: k.BurstLimit, Name: "BurstLimit", Compared: -1, Message: "Burst limit can't be negative"},
+		&validators.IntIsGreaterThan{Field: k.DailyQuota, Name: "DailyQuota", Compared: -1, Message: "Daily quota can't be negative"},
+	), nil
+}
+
+// APIKeyUsage counts one key's API requests on one day (UTC), including
+// those turned away for going over the key's limits
+type APIKeyUsage struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	APIKeyID  uuid.UUID `json:"api_key_id" db:"api_key_id"`
+	Day       time.Time `json:"day" db:"day"`
+	Requests  int       `json:"requests" db:"requests"`
+	Throttled int       `json:"throttled" db:"throttled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RecordAPIKeyUsage counts a request made with a key at now, and whether it
+// was throttled
+func RecordAPIKeyUsage(tx *pop.Connection, keyID uuid.UUID, now time.Time, throttled bool) error {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	turnedAway := 0
+	if throttled {
+		turnedAway = 1
+	}
+	day := now.UTC().Truncate(24 * time.Hour)
+	err = tx.RawQuery(`INSERT INTO api_key_usages (id, api_key_id, day, requests, throttled, created_at, updated_at)
+		VALUES (?, ?, ?, 1, ?, ?, ?)
+		ON CONFLICT (api_key_id, day) DO UPDATE SET requests = api_key_usages.requests + 1,
+			throttled = api_key_usages.throttled + EXCLUDED.throttled, updated_at = EXCLUDED.updated_at`,
+		id, keyID, day, turnedAway, now, now).Exec()
+	return errors.WithStack(err)
+}
+
+// APIKeyUsageSummary is a key's requests today and over a longer period
+type APIKeyUsageSummary struct {
+	Today          int `db:"today"`
+	ThrottledToday int `db:"throttled_today"`
+	Requests       int `db:"requests"`
+	Throttled      int `db:"throttled"`
+}
+
+// LoadAPIKeyUsage sums each key's usage on the days since the given time,
+// keyed by key ID. Keys that haven't been used are left out.
+func LoadAPIKeyUsage(tx *pop.Connection, since, now time.Time) (map[string]APIKeyUsageSummary, error) {
+	rows := []struct {
+		APIKeyID uuid.UUID `db:"api_key_id"`
+		APIKeyUsageSummary
+	}{}
+	today := now.UTC().Truncate(24 * time.Hour)
+	err := tx.RawQuery(`SELECT api_key_id,
+			COALESCE(SUM(requests) FILTER (WHERE day = ?), 0) AS today,
+			COALESCE(SUM(throttled) FILTER (WHERE day = ?), 0) AS throttled_today,
+			SUM(requests) AS requests,
+			SUM(throttled) AS throttled
+		FROM api_key_usages WHERE day >= ?
+		GROUP BY api_key_id`, today, today, since.UTC().Truncate(24*time.Hour)).All(&rows)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	usage := map[string]APIKeyUsageSummary{}
+	for _, row := range rows {
+		usage[row.APIKeyID.String()] = row.APIKeyUsageSummary
+	}
+	return usage, nil
+}
+
+// Events REST hook subscribers can be sent
+const (
+	APIEventDonationCreated       = "donation.created"
+	APIEventContactMessageCreated = "contact_message.created"
+	APIEventVolunteerCreated      = "volunteer.created"
+)
+
+// APIEvent is an event integrations can subscribe to, with its display name
+type APIEvent struct {
+	Key   string
+	Label string
+}
+
+// APIEvents lists the events integrations can subscribe to
+var APIEvents = []APIEvent{
+	{Key: APIEventDonationCreated, Label: "New donation"},
+	{Key: APIEventContactMessageCreated, Label: "New contact message"},
+	{Key: APIEventVolunteerCreated, Label: "New volunteer"},
+}
+
+// APIEventLabel is the display name for an event key
+func APIEventLabel(key string) string {
+	for _, e := range APIEvents {
+		if e.Key == key {
+			return e.Label
+		}
+	}
+	return key
+}
+
+// APIHook is a REST hook subscription: events of its kind are posted to
+// TargetURL as they happen. Zapier subscribes when a Zap is turned on and
+// unsubscribes when it's turned off.
+type APIHook struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	APIKeyID  uuid.UUID `json:"api_key_id" db:"api_key_id"`
+	Event     string    `json:"event" db:"event"`
+	TargetURL string    `json:"target_url" db:"target_url"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// APIHooks is not required by pop and may be deleted
+type APIHooks []APIHook
+
+// EventLabel is the display name of the event the hook receives
+func (h APIHook) EventLabel() string {
+	return APIEventLabel(h.Event)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (h *APIHook) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: h.APIKeyID, Name: "APIKeyID"},
+		&validators.FuncValidator{
+			Field:   h.Event,
+			Name:    "Event",
+			Message: "%s is not an event that can be subscribed to",
+			Fn: func() bool {
+				return slices.ContainsFunc(APIEvents, func(e APIEvent) bool { return e.Key == h.Event })
+			},
+		},
+		&validators.FuncValidator{
+			Field:   h.TargetURL,
+			Name:    "TargetURL",
+			Message: "%s is not an https link",
+			Fn: func() bool {
+				u, err := url.Parse(h.TargetURL)
+				return err == nil && u.Scheme == "https" && u.Host != ""
+			},
+		},
+	), nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	apiKey, plain, err := NewAPIKey("Zapier", nil)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(plain, apiKey.Prefix))
+	assert.Equal(t, HashAPIKey(plain), apiKey.KeyHash)
+	assert.NotContains(t, apiKey.String(), plain)
+
+	_, other, err := NewAPIKey("Zapier", nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, plain, other)
+
+	verrs, err := apiKey.Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+
+	verrs, err = (&APIKey{KeyHash: apiKey.KeyHash}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("name"))
+
+	verrs, err = (&APIKey{Name: "Zapier", KeyHash: apiKey.KeyHash, BurstLimit: -1, DailyQuota: 500}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("burst_limit"))
+	assert.Empty(t, verrs.Get("daily_quota"))
+
+	assert.Equal(t, APIKeyScopeFull, apiKey.Scope)
+	assert.False(t, apiKey.LookupOnly())
+	apiKey.Scope = "admin"
+	verrs, err = apiKey.Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("scope"))
+	apiKey.Scope = APIKeyScopeLookup
+	assert.True(t, apiKey.LookupOnly())
+	assert.Equal(t, "Donation lookup only", apiKey.ScopeLabel())
+}
+
+func TestAPIHook_Validate(t *testing.T) {
+	keyID := uuid.Must(uuid.NewV4())
+
+	verrs, err := (&APIHook{APIKeyID: keyID, Event: APIEventDonationCreated, TargetURL: "https://hooks.zapier.com/hooks/standard/1/abc"}).Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+
+	verrs, err = (&APIHook{APIKeyID: keyID, Event: "donation.deleted", TargetURL: "http://example.com/hook"}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("event"))
+	assert.NotEmpty(t, verrs.Get("target_url"))
+}
+
+func TestAPIEventLabel(t *testing.T) {
+	assert.Equal(t, "New volunteer", APIHook{Event: APIEventVolunteerCreated}.EventLabel())
+	assert.Equal(t, "custom.event", APIEventLabel("custom.event"))
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Auction item kinds
+const (
+	AuctionKindAuction = "auction"
+	AuctionKindRaffle  = "raffle"
+)
+
+// AuctionKinds lists the item kinds admins can choose from
+var AuctionKinds = []string{AuctionKindAuction, AuctionKindRaffle}
+
+// Auction item statuses. An item is awarded once a winner is chosen and paid
+// once the winning auction bid has been charged.
+const (
+	AuctionStatusOpen    = "open"
+	AuctionStatusAwarded = "awarded"
+	AuctionStatusPaid    = "paid"
+)
+
+// Raffle entry statuses
+const (
+	RaffleEntryPending = "pending"
+	RaffleEntryPaid    = "paid"
+)
+
+// AuctionItem is an item offered in a silent auction or raffle
+type AuctionItem struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	Title           string     `json:"title" db:"title"`
+	Description     *string    `json:"description,omitempty" db:"description"`
+	Kind            string     `json:"kind" db:"kind"`
+	FairMarketValue float64    `json:"fair_market_value" db:"fair_market_value"`
+	StartingBid     float64    `json:"starting_bid" db:"starting_bid"`
+	BidIncrement    float64    `json:"bid_increment" db:"bid_increment"`
+	TicketPrice     float64    `json:"ticket_price" db:"ticket_price"`
+	ClosesAt        time.Time  `json:"closes_at" db:"closes_at"`
+	Status          string     `json:"status" db:"status"`
+	WinnerID        *uuid.UUID `json:"winner_id,omitempty" db:"winner_id"`     // winning bid or raffle entry
+	DonationID      *uuid.UUID `json:"donation_id,omitempty" db:"donation_id"` // payment for the winning bid
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (a AuctionItem) String() string {
+	js, _ := json.Marshal(a)
+	return string(js)
+}
+
+// AuctionItems is not required by pop and may be deleted
+type AuctionItems []AuctionItem
+
+// DescriptionText is the item's description, if any
+func (a AuctionItem) DescriptionText() string {
+	if a.Description == nil {
+		return ""
+	}
+	return *a.Description
+}
+
+// IsRaffle reports whether the item is raffled rather than auctioned
+func (a AuctionItem) IsRaffle() bool {
+	return a.Kind == AuctionKindRaffle
+}
+
+// OpenAt reports whether the item still takes bids or ticket purchases
+func (a AuctionItem) OpenAt(now time.Time) bool {
+	return a.Status == AuctionStatusOpen && now.Before(a.ClosesAt)
+}
+
+// MinimumBid is the lowest bid accepted given the current high bid, if any
+func (a AuctionItem) MinimumBid(highBid *AuctionBid) float64 {
+	if highBid == nil {
+		return a.StartingBid
+	}
+	return highBid.Amount + a.BidIncrement
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (a *AuctionItem) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: a.Title, Name: "Title"},
+		&validators.StringInclusion{Field: a.Kind, Name: "Kind", List: AuctionKinds},
+		&validators.TimeIsPresent{Field: a.ClosesAt, Name: "ClosesAt"},
+		&validators.FuncValidator{
+			Field:   a.Kind,
+			Name:    "FairMarketValue",
+			Message: "Fair market value can't be negative (%s)",
+			Fn:      func() bool { return a.FairMarketValue >= 0 },
+		},
+		&validators.FuncValidator{
+			Field:   a.Kind,
+			Name:    "StartingBid",
+			Message: "Auction items need a starting bid above zero (%s)",
+			Fn:      func() bool { return a.IsRaffle() || a.StartingBid > 0 },
+		},
+		&validators.FuncValidator{
+			Field:   a.Kind,
+			Name:    "BidIncrement",
+			Message: "Bid increment can't be negative (%s)",
+			Fn:      func() bool { return a.BidIncrement >= 0 },
+		},
+		&validators.FuncValidator{
+			Field:   a.Kind,
+			Name:    "TicketPrice",
+			Message: "Raffles need a ticket price above zero (%s)",
+			Fn:      func() bool { return !a.IsRaffle() || a.TicketPrice > 0 },
+		},
+	), nil
+}
+
+// AuctionBid is a bid on an auction item. Bids aren't charged; the winner
+// pays once bidding closes.
+type AuctionBid struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	ItemID      uuid.UUID  `json:"item_id" db:"item_id"`
+	BidderName  string     `json:"bidder_name" db:"bidder_name"`
+	BidderEmail string     `json:"bidder_email" db:"bidder_email"`
+	UserID      *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	Amount      float64    `json:"amount" db:"amount"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// AuctionBids is not required by pop and may be deleted
+type AuctionBids []AuctionBid
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (b *AuctionBid) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: b.ItemID, Name: "ItemID"},
+		&validators.StringIsPresent{Field: b.BidderName, Name: "BidderName"},
+		&validators.EmailIsPresent{Field: b.BidderEmail, Name: "BidderEmail"},
+	), nil
+}
+
+// RaffleEntry is a purchase of one or more tickets for a raffle item. The
+// entry counts in the draw once its donation has been charged.
+type RaffleEntry struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	ItemID     uuid.UUID  `json:"item_id" db:"item_id"`
+	Name       string     `json:"name" db:"name"`
+	Email      string     `json:"email" db:"email"`
+	UserID     *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	Tickets    int        `json:"tickets" db:"tickets"`
+	DonationID uuid.UUID  `json:"donation_id" db:"donation_id"`
+	Status     string     `json:"status" db:"status"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// RaffleEntries is not required by pop and may be deleted
+type RaffleEntries []RaffleEntry
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (e *RaffleEntry) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: e.ItemID, Name: "ItemID"},
+		&validators.StringIsPresent{Field: e.Name, Name: "Name"},
+		&validators.EmailIsPresent{Field: e.Email, Name: "Email"},
+		&validators.IntIsGreaterThan{Field: e.Tickets, Name: "Tickets", Compared: 0},
+	), nil
+}
+
+// PickRaffleWinner returns the entry holding ticket number n of all tickets
+// sold, counting through the entries in order, so each ticket has an equal
+// chance when n is drawn uniformly from [0, total tickets). It returns nil if
+// n is out of range.
+func PickRaffleWinner(entries RaffleEntries, n int) *RaffleEntry {
+	if n < 0 {
+		return nil
+	}
+	for i := range entries {
+		if n < entries[i].Tickets {
+			return &entries[i]
+		}
+		n -= entries[i].Tickets
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickRaffleWinner(t *testing.T) {
+	entries := RaffleEntries{
+		{Name: "Ann", Tickets: 2},
+		{Name: "Ben", Tickets: 1},
+		{Name: "Cal", Tickets: 3},
+	}
+
+	assert.Equal(t, "Ann", PickRaffleWinner(entries, 0).Name)
+	assert.Equal(t, "Ann", PickRaffleWinner(entries, 1).Name)
+	assert.Equal(t, "Ben", PickRaffleWinner(entries, 2).Name)
+	assert.Equal(t, "Cal", PickRaffleWinner(entries, 5).Name)
+	assert.Nil(t, PickRaffleWinner(entries, 6))
+	assert.Nil(t, PickRaffleWinner(entries, -1))
+	assert.Nil(t, PickRaffleWinner(nil, 0))
+}
+
+func TestAuctionItem_MinimumBid(t *testing.T) {
+	item := AuctionItem{Kind: AuctionKindAuction, StartingBid: 50, BidIncrement: 5}
+	assert.Equal(t, 50.0, item.MinimumBid(nil))
+	assert.Equal(t, 80.0, item.MinimumBid(&AuctionBid{Amount: 75}))
+}
+
+func TestAuctionItem_OpenAt(t *testing.T) {
+	now := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+	item := AuctionItem{Status: AuctionStatusOpen, ClosesAt: now.Add(time.Hour)}
+	assert.True(t, item.OpenAt(now))
+	assert.False(t, item.OpenAt(now.Add(2*time.Hour)))
+
+	item.Status = AuctionStatusAwarded
+	assert.False(t, item.OpenAt(now))
+}
+
+func TestDonation_TaxDeductibleAmount(t *testing.T) {
+	fmv := 150.0
+	assert.Equal(t, 100.0, (&Donation{Amount: 100}).TaxDeductibleAmount())
+	assert.Equal(t, 250.0, (&Donation{Amount: 400, FairMarketValue: &fmv}).TaxDeductibleAmount())
+	assert.Equal(t, 0.0, (&Donation{Amount: 100, FairMarketValue: &fmv}).TaxDeductibleAmount())
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// AuditLog is one admin, account or payment action in the audit trail. The
+// same actions go to the audit log files; this copy is kept in the database
+// so the finance committee can search it.
+type AuditLog struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	Actor        string    `json:"actor" db:"actor"`
+	ActorEmail   *string   `json:"actor_email,omitempty" db:"actor_email"`
+	Action       string    `json:"action" db:"action"`
+	Details      string    `json:"details" db:"details"`
+	TargetType   string    `json:"target_type" db:"target_type"`
+	TargetID     string    `json:"target_id" db:"target_id"`
+	BeforeValues *string   `json:"before_values,omitempty" db:"before_values"`
+	AfterValues  *string   `json:"after_values,omitempty" db:"after_values"`
+	Fields       string    `json:"fields" db:"fields"`
+	IPAddress    *string   `json:"ip_address,omitempty" db:"ip_address"`
+	RequestID    *string   `json:"request_id,omitempty" db:"request_id"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (a AuditLog) String() string {
+	ja, _ := json.Marshal(a)
+	return string(ja)
+}
+
+// AuditLogs is not required by pop and may be deleted
+type AuditLogs []AuditLog
+
+// auditChanges are the field pairs actions log a value's before and after
+// under, with the name the change is recorded as
+var auditChanges = []struct {
+	Name, Before, After string
+}{
+	{"status", "from", "to"},
+	{"status", "previous_status", "status"},
+	{"role", "previous_role", "updated_role"},
+	{"amount", "previous_amount", "donation_amount"},
+}
+
+// NewAuditLog builds the audit trail entry for an action logged with the
+// given fields. The target is the first ID field, preferring target_user_id,
+// and before and after values come from the fields in auditChanges.
+func NewAuditLog(actor, action, details string, fields map[string]interface{}) *AuditLog {
+	entry := &AuditLog{
+		Actor:   actor,
+		Action:  action,
+		Details: details,
+		Fields:  "{}",
+	}
+	if len(fields) == 0 {
+		return entry
+	}
+	if js, err := json.Marshal(fields); err == nil {
+		entry.Fields = string(js)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range append([]string{"target_user_id"}, keys...) {
+		value, ok := fields[key]
+		if !ok || !strings.HasSuffix(key, "_id") || key == "request_id" {
+			continue
+		}
+		entry.TargetType = strings.TrimSuffix(strings.TrimPrefix(key, "target_"), "_id")
+		entry.TargetID = fmt.Sprint(value)
+		break
+	}
+
+	before, after := map[string]interface{}{}, map[string]interface{}{}
+	for _, change := range auditChanges {
+		if value, ok := fields[change.Before]; ok {
+			before[change.Name] = value
+			after[change.Name] = fields[change.After]
+		}
+	}
+	if len(before) > 0 {
+		entry.BeforeValues = auditJSON(before)
+		entry.AfterValues = auditJSON(after)
+	}
+	return entry
+}
+
+func auditJSON(values map[string]interface{}) *string {
+	js, err := json.Marshal(values)
+	if err != nil {
+		return nil
+	}
+	s := string(js)
+	return &s
+}
+
+// Changes lists what the action changed as "name: before → after"
+func (a AuditLog) Changes() []string {
+	if a.BeforeValues == nil {
+		return nil
+	}
+	before, after := map[string]interface{}{}, map[string]interface{}{}
+	if json.Unmarshal([]byte(*a.BeforeValues), &before) != nil {
+		return nil
+	}
+	if a.AfterValues != nil {
+		_ = json.Unmarshal([]byte(*a.AfterValues), &after)
+	}
+	changes := make([]string, 0, len(before))
+	for name, value := range before {
+		changes = append(changes, fmt.Sprintf("%s: %v → %v", name, value, auditValue(after[name])))
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func auditValue(value interface{}) interface{} {
+	if value == nil {
+		return "—"
+	}
+	return value
+}
+
+// ActorName is the actor's email when it's known, and what was logged
+// otherwise
+func (a AuditLog) ActorName() string {
+	if a.ActorEmail != nil && *a.ActorEmail != "" {
+		return *a.ActorEmail
+	}
+	return a.Actor
+}
+
+// Target is the target as "type id", or "" when there wasn't one
+func (a AuditLog) Target() string {
+	if a.TargetID == "" {
+		return ""
+	}
+	return a.TargetType + " " + a.TargetID
+}
+
+// IPText is the IP address the action came from, or "" for system events
+func (a AuditLog) IPText() string {
+	if a.IPAddress == nil {
+		return ""
+	}
+	return *a.IPAddress
+}
+
+// FieldList is the logged fields as sorted "key=value" strings for display
+func (a AuditLog) FieldList() []string {
+	fields := map[string]interface{}{}
+	if json.Unmarshal([]byte(a.Fields), &fields) != nil {
+		return nil
+	}
+	list := make([]string, 0, len(fields))
+	for key, value := range fields {
+		list = append(list, fmt.Sprintf("%s=%v", key, value))
+	}
+	sort.Strings(list)
+	return list
+}
+
+// AuditActions lists the actions in the audit trail, for filtering on
+func AuditActions(tx *pop.Connection) ([]string, error) {
+	var rows []struct {
+		Action string `db:"action"`
+	}
+	if err := tx.RawQuery("SELECT DISTINCT action FROM audit_logs ORDER BY action").All(&rows); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	actions := make([]string, len(rows))
+	for i, row := range rows {
+		actions[i] = row.Action
+	}
+	return actions, nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuditLog(t *testing.T) {
+	entry := NewAuditLog("admin-id", "admin_update_user", "Admin updated user jo@example.com", map[string]interface{}{
+		"admin_email":    "admin@example.com",
+		"target_user_id": "u1",
+		"target_email":   "jo@example.com",
+		"updated_role":   "admin",
+		"previous_role":  "user",
+	})
+	assert.Equal(t, "user", entry.TargetType)
+	assert.Equal(t, "u1", entry.TargetID)
+	assert.Equal(t, "user u1", entry.Target())
+	assert.Equal(t, `{"role":"user"}`, *entry.BeforeValues)
+	assert.Equal(t, `{"role":"admin"}`, *entry.AfterValues)
+	assert.Equal(t, []string{"role: user → admin"}, entry.Changes())
+	assert.Contains(t, entry.FieldList(), "target_email=jo@example.com")
+
+	entry = NewAuditLog("admin@example.com", "donation_status_changed", "Changed donation status", map[string]interface{}{
+		"donation_id": "d1",
+		"from":        "pending",
+		"to":          "completed",
+	})
+	assert.Equal(t, "donation", entry.TargetType)
+	assert.Equal(t, "d1", entry.TargetID)
+	assert.Equal(t, []string{"status: pending → completed"}, entry.Changes())
+
+	entry = NewAuditLog("system", "store_order_paid", "", nil)
+	assert.Equal(t, "{}", entry.Fields)
+	assert.Empty(t, entry.Target())
+	assert.Nil(t, entry.BeforeValues)
+	assert.Empty(t, entry.Changes())
+	assert.Equal(t, "system", entry.ActorName())
+}
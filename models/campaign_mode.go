@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// CampaignMode is the switch staff flip before a big fundraising push like
+// Giving Tuesday. While it's live the homepage hero is replaced by the
+// campaign's, a donation banner is pinned to every page, and cached pages
+// and stats are kept longer to ride out the traffic. It can be turned on
+// straight away or scheduled between StartsAt and EndsAt. There's one,
+// edited on the admin campaign page; until it's first saved
+// DefaultCampaignMode is used.
+type CampaignMode struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	Enabled    bool       `json:"enabled" db:"enabled"`
+	Name       string     `json:"name" db:"name"`
+	Headline   string     `json:"headline" db:"headline"`
+	Message    string     `json:"message" db:"message"`
+	BannerText string     `json:"banner_text" db:"banner_text"`
+	ButtonText string     `json:"button_text" db:"button_text"`
+	DonateURL  string     `json:"donate_url" db:"donate_url"`
+	StartsAt   *time.Time `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	UpdatedBy  *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (m CampaignMode) String() string {
+	jm, _ := json.Marshal(m)
+	return string(jm)
+}
+
+// DefaultCampaignMode is campaign mode before it's been saved: switched off
+func DefaultCampaignMode() CampaignMode {
+	return CampaignMode{ButtonText: "Donate now", DonateURL: "/donate"}
+}
+
+// LoadCampaignMode loads the saved campaign mode, or the default when none
+// has been saved
+func LoadCampaignMode(tx *pop.Connection) (CampaignMode, error) {
+	mode := CampaignMode{}
+	err := tx.Order("created_at").First(&mode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultCampaignMode(), nil
+	}
+	if err != nil {
+		return DefaultCampaignMode(), errors.WithStack(err)
+	}
+	return mode, nil
+}
+
+// Live reports whether the campaign is showing at now: it's switched on and
+// now is inside its schedule, if it has one
+func (m CampaignMode) Live(now time.Time) bool {
+	if !m.Enabled {
+		return false
+	}
+	if m.StartsAt != nil && now.Before(*m.StartsAt) {
+		return false
+	}
+	return m.EndsAt == nil || now.Before(*m.EndsAt)
+}
+
+// StatusText describes where the campaign is at now, for the admin page
+func (m CampaignMode) StatusText(now time.Time) string {
+	switch {
+	case !m.Enabled:
+		return "Off"
+	case m.Live(now):
+		return "Live"
+	case m.EndsAt != nil && !now.Before(*m.EndsAt):
+		return "Ended"
+	default:
+		return "Scheduled"
+	}
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// A campaign that's switched on needs the wording it shows; one that's off
+// may be left half filled in while it's drafted.
+func (m *CampaignMode) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	checks := []validate.Validator{
+		&validators.StringLengthInRange{Field: m.Headline, Name: "Headline", Max: 150, Message: "Headline must be 150 characters or less"},
+		&validators.StringLengthInRange{Field: m.BannerText, Name: "BannerText", Max: 200, Message: "Banner text must be 200 characters or less"},
+		&validators.FuncValidator{
+			Field:   m.DonateURL,
+			Name:    "DonateURL",
+			Message: "%s should be a page on this site, like /donate, or a full link",
+			Fn: func() bool {
+				if m.DonateURL == "" || (strings.HasPrefix(m.DonateURL, "/") && !strings.HasPrefix(m.DonateURL, "//")) {
+					return true
+				}
+				u, err := url.Parse(m.DonateURL)
+				return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+			},
+		},
+		&validators.FuncValidator{
+			Field:   "End time",
+			Name:    "EndsAt",
+			Message: "%s must be after the start",
+			Fn: func() bool {
+				return m.StartsAt == nil || m.EndsAt == nil || m.EndsAt.After(*m.StartsAt)
+			},
+		},
+	}
+	if m.Enabled {
+		checks = append(checks,
+			&validators.StringIsPresent{Field: m.Name, Name: "Name", Message: "Name is required to switch campaign mode on"},
+			&validators.StringIsPresent{Field: m.Headline, Name: "Headline", Message: "Headline is required to switch campaign mode on"},
+			&validators.StringIsPresent{Field: m.BannerText, Name: "BannerText", Message: "Banner text is required to switch campaign mode on"},
+		)
+	}
+	return validate.Validate(checks...), nil
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCampaignMode_Live(t *testing.T) {
+	now := time.Date(2026, 12, 1, 12, 0, 0, 0, time.UTC)
+	starts := now.Add(-time.Hour)
+	ends := now.Add(time.Hour)
+
+	assert.False(t, CampaignMode{}.Live(now))
+	assert.True(t, CampaignMode{Enabled: true}.Live(now))
+	assert.True(t, CampaignMode{Enabled: true, StartsAt: &starts, EndsAt: &ends}.Live(now))
+	assert.False(t, CampaignMode{Enabled: true, StartsAt: &ends}.Live(now))
+	assert.False(t, CampaignMode{Enabled: true, EndsAt: &starts}.Live(now))
+	// ends exactly on the hour
+	assert.False(t, CampaignMode{Enabled: true, EndsAt: &now}.Live(now))
+}
+
+func TestCampaignMode_StatusText(t *testing.T) {
+	now := time.Date(2026, 12, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	assert.Equal(t, "Off", CampaignMode{StartsAt: &past}.StatusText(now))
+	assert.Equal(t, "Live", CampaignMode{Enabled: true}.StatusText(now))
+	assert.Equal(t, "Scheduled", CampaignMode{Enabled: true, StartsAt: &future}.StatusText(now))
+	assert.Equal(t, "Ended", CampaignMode{Enabled: true, EndsAt: &past}.StatusText(now))
+}
+
+func TestCampaignMode_Validate(t *testing.T) {
+	// A campaign that's off can be saved half written
+	draft := DefaultCampaignMode()
+	draft.Name = "Giving Tuesday"
+	verrs, err := draft.Validate(nil)
+	require.NoError(t, err)
+	assert.False(t, verrs.HasAny(), verrs.String())
+
+	draft.Enabled = true
+	verrs, _ = draft.Validate(nil)
+	assert.NotEmpty(t, verrs.Get("headline"))
+	assert.NotEmpty(t, verrs.Get("banner_text"))
+
+	now := time.Now()
+	before := now.Add(-time.Hour)
+	live := CampaignMode{Enabled: true, Name: "Giving Tuesday", Headline: "Double your impact", BannerText: "Gifts matched today", DonateURL: "//evil.example", StartsAt: &now, EndsAt: &before}
+	verrs, _ = live.Validate(nil)
+	assert.NotEmpty(t, verrs.Get("donate_url"))
+	assert.Equal(t, []string{"End time must be after the start"}, verrs.Get("ends_at"))
+
+	live.DonateURL = "https://givingtuesday.example/avr"
+	live.EndsAt = nil
+	verrs, _ = live.Validate(nil)
+	assert.False(t, verrs.HasAny(), verrs.String())
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Contact form topics. Each is routed to its own recipients, and messages
+// without one go to the general contact address.
+const (
+	ContactTopicGeneral      = "general"
+	ContactTopicDonations    = "donations"
+	ContactTopicPrograms     = "programs"
+	ContactTopicPress        = "press"
+	ContactTopicVolunteering = "volunteering"
+)
+
+// ContactTopics lists the topics offered on the contact form
+var ContactTopics = []string{ContactTopicGeneral, ContactTopicDonations, ContactTopicPrograms, ContactTopicPress, ContactTopicVolunteering}
+
+var contactTopicLabels = map[string]string{
+	ContactTopicGeneral:      "General question",
+	ContactTopicDonations:    "Donations",
+	ContactTopicPrograms:     "Programs",
+	ContactTopicPress:        "Press",
+	ContactTopicVolunteering: "Volunteering",
+}
+
+// ContactTopicLabel is the display name for a contact form topic
+func ContactTopicLabel(topic string) string {
+	if label, ok := contactTopicLabels[topic]; ok {
+		return label
+	}
+	return topic
+}
+
+// ValidContactTopic reports whether topic is one of ContactTopics
+func ValidContactTopic(topic string) bool {
+	_, ok := contactTopicLabels[topic]
+	return ok
+}
+
+// ContactMessage is a message sent through the contact form, kept for the
+// admin inbox. Staff are reminded of messages nobody has replied to within
+// the reply SLA, and a second recipient is told when one goes twice as long.
+// Press inquiries from the press page also record the journalist's outlet
+// and deadline.
+type ContactMessage struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Email       string     `json:"email" db:"email"`
+	Subject     string     `json:"subject" db:"subject"`
+	Message     string     `json:"message" db:"message"`
+	Topic       string     `json:"topic" db:"topic"`
+	Outlet      *string    `json:"outlet,omitempty" db:"outlet"`
+	Deadline    *time.Time `json:"deadline,omitempty" db:"deadline"`
+	ReadAt      *time.Time `json:"read_at,omitempty" db:"read_at"`
+	RepliedAt   *time.Time `json:"replied_at,omitempty" db:"replied_at"`
+	RemindedAt  *time.Time `json:"reminded_at,omitempty" db:"reminded_at"`
+	EscalatedAt *time.Time `json:"escalated_at,omitempty" db:"escalated_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (m ContactMessage) String() string {
+	jm, _ := json.Marshal(m)
+	return string(jm)
+}
+
+// ContactMessages is not required by pop and may be deleted
+type ContactMessages []ContactMessage
+
+// TopicLabel is the display name for the message's topic
+func (m ContactMessage) TopicLabel() string {
+	return ContactTopicLabel(m.Topic)
+}
+
+// OutletName is the press outlet the message came from, if any
+func (m ContactMessage) OutletName() string {
+	if m.Outlet == nil {
+		return ""
+	}
+	return *m.Outlet
+}
+
+// HasDeadline reports whether a press inquiry gave a deadline
+func (m ContactMessage) HasDeadline() bool {
+	return m.Deadline != nil
+}
+
+// Read reports whether staff have opened the message
+func (m ContactMessage) Read() bool {
+	return m.ReadAt != nil
+}
+
+// Replied reports whether staff have answered the message
+func (m ContactMessage) Replied() bool {
+	return m.RepliedAt != nil
+}
+
+// Overdue reports whether the message has gone unanswered longer than sla
+func (m ContactMessage) Overdue(sla time.Duration, now time.Time) bool {
+	return !m.Replied() && now.Sub(m.CreatedAt) > sla
+}
+
+// ReminderDue reports whether staff should be reminded about the message
+func (m ContactMessage) ReminderDue(sla time.Duration, now time.Time) bool {
+	return m.RemindedAt == nil && m.Overdue(sla, now)
+}
+
+// EscalationDue reports whether the message has gone unanswered for twice
+// the SLA without being escalated
+func (m ContactMessage) EscalationDue(sla time.Duration, now time.Time) bool {
+	return m.EscalatedAt == nil && m.Overdue(2*sla, now)
+}
+
+// MarkRead records that staff opened the message, the first time only
+func (m *ContactMessage) MarkRead(now time.Time) {
+	if m.ReadAt == nil {
+		m.ReadAt = &now
+	}
+}
+
+// MarkReplied records that staff answered the message. A message can't be
+// replied to unread, so it's marked read too.
+func (m *ContactMessage) MarkReplied(now time.Time) {
+	m.MarkRead(now)
+	if m.RepliedAt == nil {
+		m.RepliedAt = &now
+	}
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (m *ContactMessage) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: m.Name, Name: "Name"},
+		&validators.EmailIsPresent{Field: m.Email, Name: "Email"},
+		&validators.StringIsPresent{Field: m.Subject, Name: "Subject"},
+		&validators.StringIsPresent{Field: m.Message, Name: "Message"},
+		&validators.StringInclusion{Field: m.Topic, Name: "Topic", List: ContactTopics},
+	), nil
+}
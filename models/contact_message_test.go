@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContactTopicLabel(t *testing.T) {
+	assert.Equal(t, "Press", ContactTopicLabel(ContactTopicPress))
+	assert.Equal(t, "billing", ContactTopicLabel("billing"))
+	assert.True(t, ValidContactTopic(ContactTopicVolunteering))
+	assert.False(t, ValidContactTopic(""))
+}
+
+func TestContactMessage_Validate(t *testing.T) {
+	msg := &ContactMessage{Name: "Sam", Email: "sam@example.com", Subject: "Hi", Message: "Hello", Topic: ContactTopicPrograms}
+	verrs, err := msg.Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+
+	msg.Topic = "billing"
+	verrs, _ = msg.Validate(nil)
+	assert.NotEmpty(t, verrs.Get("topic"))
+}
+
+func TestContactMessage_SLA(t *testing.T) {
+	received := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	sla := 48 * time.Hour
+	msg := ContactMessage{CreatedAt: received}
+
+	assert.False(t, msg.ReminderDue(sla, received.Add(47*time.Hour)))
+	assert.True(t, msg.ReminderDue(sla, received.Add(49*time.Hour)))
+	assert.False(t, msg.EscalationDue(sla, received.Add(49*time.Hour)))
+	assert.True(t, msg.EscalationDue(sla, received.Add(97*time.Hour)))
+
+	reminded := received.Add(49 * time.Hour)
+	msg.RemindedAt = &reminded
+	assert.False(t, msg.ReminderDue(sla, received.Add(50*time.Hour)))
+
+	msg.MarkReplied(received.Add(98 * time.Hour))
+	assert.True(t, msg.Replied())
+	assert.Equal(t, received.Add(98*time.Hour), *msg.ReadAt)
+	assert.False(t, msg.Overdue(sla, received.Add(200*time.Hour)))
+	assert.False(t, msg.EscalationDue(sla, received.Add(200*time.Hour)))
+}
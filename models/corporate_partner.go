@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+var partnerSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// CorporatePartner is an employer or workplace giving partner with its own
+// branded landing page at /give/{slug}
+type CorporatePartner struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	Slug        string    `json:"slug" db:"slug"`
+	Name        string    `json:"name" db:"name"`
+	LogoURL     *string   `json:"logo_url,omitempty" db:"logo_url"`
+	Message     *string   `json:"message,omitempty" db:"message"`
+	Designation *string   `json:"designation,omitempty" db:"designation"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (p CorporatePartner) String() string {
+	jp, _ := json.Marshal(p)
+	return string(jp)
+}
+
+// CorporatePartners is not required by pop and may be deleted
+type CorporatePartners []CorporatePartner
+
+// NormalizePartnerSlug turns a partner name or typed slug into a URL slug
+func NormalizePartnerSlug(s string) string {
+	slug := regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(s), "-")
+	return strings.Trim(slug, "-")
+}
+
+// GenerateSlug fills in the slug from the partner name when left blank
+func (p *CorporatePartner) GenerateSlug() {
+	if strings.TrimSpace(p.Slug) == "" {
+		p.Slug = p.Name
+	}
+	p.Slug = NormalizePartnerSlug(p.Slug)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (p *CorporatePartner) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: p.Name, Name: "Name"},
+		&validators.StringIsPresent{Field: p.Slug, Name: "Slug"},
+		&validators.RegexMatch{Field: p.Slug, Name: "Slug", Expr: partnerSlugPattern.String(), Message: "Slug may only contain lowercase letters, numbers and hyphens"},
+		&validators.FuncValidator{
+			Field:   p.Slug,
+			Name:    "Slug",
+			Message: "%s is already used by another partner",
+			Fn: func() bool {
+				q := tx.Where("slug = ?", p.Slug)
+				if p.ID != uuid.Nil {
+					q = q.Where("id != ?", p.ID)
+				}
+				exists, err := q.Exists(&CorporatePartner{})
+				return err == nil && !exists
+			},
+		},
+	), nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePartnerSlug(t *testing.T) {
+	assert.Equal(t, "acme-construction", NormalizePartnerSlug("Acme Construction"))
+	assert.Equal(t, "b-q-co", NormalizePartnerSlug("  B&Q Co. "))
+	assert.Equal(t, "acme", NormalizePartnerSlug("acme"))
+	assert.Equal(t, "", NormalizePartnerSlug("!!!"))
+}
+
+func TestCorporatePartner_GenerateSlug(t *testing.T) {
+	p := &CorporatePartner{Name: "Gulf Coast Builders"}
+	p.GenerateSlug()
+	assert.Equal(t, "gulf-coast-builders", p.Slug)
+
+	p = &CorporatePartner{Name: "Gulf Coast Builders", Slug: "GCB Team"}
+	p.GenerateSlug()
+	assert.Equal(t, "gcb-team", p.Slug)
+}
+
+func TestPartnerSlugPattern(t *testing.T) {
+	assert.True(t, partnerSlugPattern.MatchString("acme-2024"))
+	assert.False(t, partnerSlugPattern.MatchString("Acme"))
+	assert.False(t, partnerSlugPattern.MatchString("-acme"))
+	assert.False(t, partnerSlugPattern.MatchString("acme--co"))
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// CSPReport is a Content Security Policy violation reported by a browser
+type CSPReport struct {
+	ID                 uuid.UUID `json:"id" db:"id"`
+	DocumentURI        string    `json:"document_uri" db:"document_uri"`
+	ViolatedDirective  string    `json:"violated_directive" db:"violated_directive"`
+	EffectiveDirective string    `json:"effective_directive" db:"effective_directive"`
+	BlockedURI         string    `json:"blocked_uri" db:"blocked_uri"`
+	SourceFile         string    `json:"source_file" db:"source_file"`
+	LineNumber         int       `json:"line_number" db:"line_number"`
+	Disposition        string    `json:"disposition" db:"disposition"`
+	UserAgent          string    `json:"user_agent" db:"user_agent"`
+	Raw                string    `json:"raw" db:"raw"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (r CSPReport) String() string {
+	jr, _ := json.Marshal(r)
+	return string(jr)
+}
+
+// CSPReports is not required by pop and may be deleted
+type CSPReports []CSPReport
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (r *CSPReport) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: r.EffectiveDirective, Name: "EffectiveDirective"},
+	), nil
+}
+
+// CSPViolationSummary groups reports by directive and blocked resource
+type CSPViolationSummary struct {
+	EffectiveDirective string    `db:"effective_directive"`
+	BlockedURI         string    `db:"blocked_uri"`
+	Count              int       `db:"count"`
+	LastSeen           time.Time `db:"last_seen"`
+}
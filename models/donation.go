@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -12,26 +13,89 @@ import (
 	"github.com/gofrs/uuid"
 )
 
+// Donation statuses used by the manual review queue. Flagged gifts are ones
+// fraud screening quarantined as likely card testing.
+const (
+	DonationStatusPendingReview = "pending_review"
+	DonationStatusFlagged       = "flagged"
+	DonationStatusDeclined      = "declined"
+)
+
+// DonationStatusRefunded marks a gift refunded in full
+const DonationStatusRefunded = "refunded"
+
+// DonationStatusSettling marks a bank transfer (ACH) gift that has been
+// submitted but not yet settled. ACH takes a few business days, and the gift
+// is only receipted once Helcim reports it settled.
+const DonationStatusSettling = "settling"
+
+// DonationStatusPaused marks a monthly gift whose subscription is paused in
+// Helcim: it isn't being charged, but can be resumed
+const DonationStatusPaused = "paused"
+
+// DonationStatuses are the statuses admins can filter donations by and set
+// on one by hand
+var DonationStatuses = []string{
+	"pending",
+	DonationStatusPendingReview,
+	DonationStatusFlagged,
+	DonationStatusSettling,
+	"active",
+	DonationStatusPaused,
+	"completed",
+	"failed",
+	"cancelled",
+	DonationStatusDeclined,
+	DonationStatusRefunded,
+}
+
+// ValidDonationStatus reports whether status is one of DonationStatuses
+func ValidDonationStatus(status string) bool {
+	for _, s := range DonationStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// FallbackCustomerCodePrefix starts the customer codes we make up when
+// HelcimPay.js doesn't report one. They're unique to the gift, so they
+// don't identify the donor.
+const FallbackCustomerCodePrefix = "DON_"
+
+// PaymentMethodCrypto marks gifts received through the crypto processor
+const PaymentMethodCrypto = "crypto"
+
+// PaymentMethodBank marks gifts paid by bank transfer (ACH) through Helcim.
+// Helcim card gifts leave PaymentMethod empty.
+const PaymentMethodBank = "bank"
+
+// PaymentMethodOffline marks gifts received outside the site, like checks
+// and cash, and recorded through the REST API
+const PaymentMethodOffline = "offline"
+
 // Donation represents a donation transaction
 type Donation struct {
-	ID                  uuid.UUID  `json:"id" db:"id"`
-	UserID              *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
-	HelcimTransactionID *string    `json:"helcim_transaction_id,omitempty" db:"helcim_transaction_id"`
-	CheckoutToken       string     `json:"checkout_token" db:"checkout_token"`
-	SecretToken         string     `json:"secret_token" db:"secret_token"`
-	Amount              float64    `json:"amount" db:"amount"`
-	Currency            string     `json:"currency" db:"currency"`
-	DonorName           string     `json:"donor_name" db:"donor_name"`
-	DonorEmail          string     `json:"donor_email" db:"donor_email"`
-	DonorPhone          *string    `json:"donor_phone,omitempty" db:"donor_phone"`
-	AddressLine1        *string    `json:"address_line1,omitempty" db:"address_line1"`
-	AddressLine2        *string    `json:"address_line2,omitempty" db:"address_line2"`
-	City                *string    `json:"city,omitempty" db:"city"`
-	State               *string    `json:"state,omitempty" db:"state"`
-	Zip                 *string    `json:"zip,omitempty" db:"zip"`
-	DonationType        string     `json:"donation_type" db:"donation_type"`
-	Status              string     `json:"status" db:"status"`
-	Comments            *string    `json:"comments,omitempty" db:"comments"`
+	ID                  uuid.UUID    `json:"id" db:"id"`
+	UserID              *uuid.UUID   `json:"user_id,omitempty" db:"user_id"`
+	DonorID             *uuid.UUID   `json:"donor_id,omitempty" db:"donor_id"`
+	HelcimTransactionID *string      `json:"helcim_transaction_id,omitempty" db:"helcim_transaction_id"`
+	CheckoutToken       string       `json:"checkout_token" db:"checkout_token"`
+	SecretToken         string       `json:"secret_token" db:"secret_token"`
+	Amount              float64      `json:"amount" db:"amount"`
+	Currency            string       `json:"currency" db:"currency"`
+	DonorName           string       `json:"donor_name" db:"donor_name"`
+	DonorEmail          string       `json:"donor_email" db:"donor_email"`
+	DonorPhone          *string      `json:"donor_phone,omitempty" db:"donor_phone"`
+	AddressLine1        *string      `json:"address_line1,omitempty" db:"address_line1"`
+	AddressLine2        *string      `json:"address_line2,omitempty" db:"address_line2"`
+	City                *string      `json:"city,omitempty" db:"city"`
+	State               *string      `json:"state,omitempty" db:"state"`
+	Zip                 *string      `json:"zip,omitempty" db:"zip"`
+	DonationType        DonationType `json:"donation_type" db:"donation_type"`
+	Status              string       `json:"status" db:"status"`
+	Comments            *string      `json:"comments,omitempty" db:"comments"`
 	// Recurring payment fields
 	SubscriptionID *string `json:"subscription_id,omitempty" db:"subscription_id"`
 	CustomerID     *string `json:"customer_id,omitempty" db:"customer_id"`
@@ -57,10 +121,118 @@ type Donation struct {
 	LastStatusSync *time.Time `json:"last_status_sync,omitempty" db:"last_status_sync"`
 	SyncError      *string    `json:"sync_error,omitempty" db:"sync_error"`
 
+	// Frequency is how often an open-ended recurring gift is charged.
+	// Installment pledges are always charged monthly.
+	Frequency DonationFrequency `json:"frequency" db:"frequency"`
+
+	// Installment pledges: Amount is charged monthly until InstallmentCount
+	// payments totalling PledgeTotal have been made.
+	InstallmentCount int     `json:"installment_count" db:"installment_count"`
+	InstallmentsPaid int     `json:"installments_paid" db:"installments_paid"`
+	PledgeTotal      float64 `json:"pledge_total" db:"pledge_total"`
+
+	// Manual review of large gifts. CardToken is only held until the gift is
+	// approved or declined.
+	CardToken    *string    `json:"-" db:"card_token"`
+	ReviewReason *string    `json:"review_reason,omitempty" db:"review_reason"`
+	ReviewedBy   *uuid.UUID `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	ReviewNote   *string    `json:"review_note,omitempty" db:"review_note"`
+
+	// Workplace giving: the corporate partner page the gift came from and
+	// the designation that partner pre-selected.
+	PartnerID   *uuid.UUID `json:"partner_id,omitempty" db:"partner_id"`
+	Designation *string    `json:"designation,omitempty" db:"designation"`
+
+	// APIKeyID is the integration that started the gift through the
+	// donation API, such as the mobile app or a partner site
+	APIKeyID *uuid.UUID `json:"api_key_id,omitempty" db:"api_key_id"`
+
+	// Crypto gifts: the donor sends CryptoAmount of CryptoCurrency to
+	// DepositAddress; Amount is the USD value once the processor converts it.
+	CryptoCurrency *string  `json:"crypto_currency,omitempty" db:"crypto_currency"`
+	CryptoAmount   *float64 `json:"crypto_amount,omitempty" db:"crypto_amount"`
+	CryptoPledgeID *string  `json:"crypto_pledge_id,omitempty" db:"crypto_pledge_id"`
+	DepositAddress *string  `json:"deposit_address,omitempty" db:"deposit_address"`
+
+	// Gifts paid out by PayPal Giving Fund (including Venmo): PaymentMethod
+	// is the channel and ExternalID its transaction ID, which is unique per
+	// channel so re-imported reports and webhooks don't duplicate gifts.
+	ExternalID   *string  `json:"external_id,omitempty" db:"external_id"`
+	PayoutID     *string  `json:"payout_id,omitempty" db:"payout_id"`
+	ProcessorFee *float64 `json:"processor_fee,omitempty" db:"processor_fee"`
+
+	// Answers to admin-defined form fields, JSON-encoded []CustomFieldAnswer
+	CustomFields *string `json:"custom_fields,omitempty" db:"custom_fields"`
+
+	// Fair market value of goods or services the donor received in return
+	// (an auction item, raffle tickets), which isn't tax deductible, and
+	// what they received as it's described on the receipt
+	FairMarketValue  *float64 `json:"fair_market_value,omitempty" db:"fair_market_value"`
+	GoodsDescription *string  `json:"goods_description,omitempty" db:"goods_description"`
+
+	// Refunds issued from the admin donation page, which may be partial
+	RefundedAmount float64    `json:"refunded_amount" db:"refunded_amount"`
+	RefundedAt     *time.Time `json:"refunded_at,omitempty" db:"refunded_at"`
+
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// IsCrypto reports whether the gift was made in cryptocurrency
+func (d *Donation) IsCrypto() bool {
+	return d.PaymentMethod != nil && *d.PaymentMethod == PaymentMethodCrypto
+}
+
+// PaysByBank reports whether the gift is paid by bank transfer (ACH)
+func (d *Donation) PaysByBank() bool {
+	return d.PaymentMethod != nil && *d.PaymentMethod == PaymentMethodBank
+}
+
+// GoodsValue is the fair market value of what the donor received, or zero
+// when the gift bought nothing
+func (d *Donation) GoodsValue() float64 {
+	if d.FairMarketValue == nil {
+		return 0
+	}
+	return *d.FairMarketValue
+}
+
+// TaxDeductibleAmount is the part of the payment the donor can deduct: the
+// amount less the fair market value of anything they received
+func (d *Donation) TaxDeductibleAmount() float64 {
+	if d.FairMarketValue == nil {
+		return d.Amount
+	}
+	if deductible := d.Amount - *d.FairMarketValue; deductible > 0 {
+		return deductible
+	}
+	return 0
+}
+
+// CustomAnswers decodes the donor's answers to custom form fields
+func (d Donation) CustomAnswers() []CustomFieldAnswer {
+	if d.CustomFields == nil || *d.CustomFields == "" {
+		return nil
+	}
+	var answers []CustomFieldAnswer
+	if err := json.Unmarshal([]byte(*d.CustomFields), &answers); err != nil {
+		return nil
+	}
+	return answers
+}
+
+// SetCustomAnswers stores answers to custom form fields on the donation
+func (d *Donation) SetCustomAnswers(answers []CustomFieldAnswer) {
+	if len(answers) == 0 {
+		d.CustomFields = nil
+		return
+	}
+	encoded, _ := json.Marshal(answers)
+	s := string(encoded)
+	d.CustomFields = &s
+}
+
 // String is not required by pop and may be deleted
 func (d Donation) String() string {
 	jd, _ := json.Marshal(d)
@@ -84,7 +256,18 @@ func (d *Donation) Validate(tx *pop.Connection) (*validate.Errors, error) {
 		&validators.StringIsPresent{Field: d.DonorEmail, Name: "DonorEmail"},
 		&validators.EmailIsPresent{Field: d.DonorEmail, Name: "DonorEmail"},
 		&validators.StringIsPresent{Field: d.Currency, Name: "Currency"},
-		&validators.StringIsPresent{Field: d.DonationType, Name: "DonationType"},
+		&validators.FuncValidator{
+			Field:   string(d.DonationType),
+			Name:    "DonationType",
+			Message: "%q is not a known donation type",
+			Fn:      d.DonationType.Valid,
+		},
+		&validators.FuncValidator{
+			Field:   string(d.Frequency),
+			Name:    "Frequency",
+			Message: "%q is not a known donation frequency",
+			Fn:      NormalizeDonationFrequency(string(d.Frequency)).Valid,
+		},
 		&validators.StringIsPresent{Field: d.Status, Name: "Status"},
 	), nil
 }
@@ -185,6 +368,139 @@ func (d *Donation) IsRecurring() bool {
 	return d.SubscriptionID != nil && *d.SubscriptionID != ""
 }
 
+// HelcimCustomerCode is the Helcim customer the gift was charged to, or
+// blank when there's none or only a fallback code
+func (d *Donation) HelcimCustomerCode() string {
+	if d.CustomerID == nil || strings.HasPrefix(*d.CustomerID, FallbackCustomerCodePrefix) {
+		return ""
+	}
+	return strings.TrimSpace(*d.CustomerID)
+}
+
+// AfterSave files the gift under its donor record
+func (d *Donation) AfterSave(tx *pop.Connection) error {
+	return LinkDonor(tx, d)
+}
+
+// IsInstallmentPledge returns true if this donation is a pledge paid in a
+// fixed number of monthly installments
+func (d *Donation) IsInstallmentPledge() bool {
+	return d.DonationType == DonationTypeInstallment && d.InstallmentCount > 0
+}
+
+// ChargeFrequency is how often the gift is charged: monthly for installment
+// pledges and for gifts saved before other frequencies were offered
+func (d *Donation) ChargeFrequency() DonationFrequency {
+	frequency := NormalizeDonationFrequency(string(d.Frequency))
+	if d.IsInstallmentPledge() || !frequency.Valid() {
+		return FrequencyMonthly
+	}
+	return frequency
+}
+
+// ChargePeriod is the stretch of time one charge covers, for templates, as
+// in "per quarter"
+func (d *Donation) ChargePeriod() string {
+	return d.ChargeFrequency().Period()
+}
+
+// TypeLabel is how the gift's type is shown on receipts and admin pages,
+// naming the frequency of open-ended recurring gifts, e.g. "Quarterly"
+func (d Donation) TypeLabel() string {
+	if d.DonationType == DonationTypeMonthly {
+		return d.ChargeFrequency().Label()
+	}
+	return d.DonationType.Label()
+}
+
+// MonthlyEquivalent is what the gift brings in per month on average, so
+// quarterly and annual gifts can be totalled alongside monthly ones
+func (d *Donation) MonthlyEquivalent() float64 {
+	return d.Amount / float64(d.ChargeFrequency().Months())
+}
+
+// PledgeAmount is the full amount committed: the pledge total for
+// installment pledges, otherwise the donation amount
+func (d *Donation) PledgeAmount() float64 {
+	if d.IsInstallmentPledge() {
+		return d.PledgeTotal
+	}
+	return d.Amount
+}
+
+// PledgeComplete returns true once every installment has been paid
+func (d *Donation) PledgeComplete() bool {
+	return d.IsInstallmentPledge() && d.InstallmentsPaid >= d.InstallmentCount
+}
+
+// ReceivedToDate estimates how much the donation has brought in as of now.
+// Installment pledges count the installments paid. Open-ended recurring
+// gifts don't record each charge, so they count one payment at activation
+// plus one per full billing period since, ending when the subscription was
+// cancelled or paused.
+func (d *Donation) ReceivedToDate(now time.Time) float64 {
+	if d.IsInstallmentPledge() {
+		return d.Amount * float64(d.InstallmentsPaid)
+	}
+	if d.IsRecurring() && (d.Status == "active" || d.Status == "cancelled" || d.Status == DonationStatusPaused) {
+		start := d.CreatedAt
+		if d.ActivationDate != nil {
+			start = *d.ActivationDate
+		}
+		end := now
+		if d.Status == "cancelled" || d.Status == DonationStatusPaused {
+			end = d.UpdatedAt
+		}
+		months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+		if end.Day() < start.Day() {
+			months--
+		}
+		if months < 0 {
+			months = 0
+		}
+		return d.Amount * float64(months/d.ChargeFrequency().Months()+1)
+	}
+	if d.Status == "completed" {
+		return d.Amount
+	}
+	return 0
+}
+
+// AwaitingReview returns true if the donation is held for an admin decision
+func (d *Donation) AwaitingReview() bool {
+	return d.Status == DonationStatusPendingReview || d.Status == DonationStatusFlagged
+}
+
+// ChargeReference is the Helcim transaction ID for a one-time card gift, or
+// blank before it has been charged
+func (d *Donation) ChargeReference() string {
+	for _, id := range []*string{d.HelcimTransactionID, d.TransactionID} {
+		if id != nil && *id != "" {
+			return *id
+		}
+	}
+	return ""
+}
+
+// Refundable is how much of a one-time card gift can still be refunded.
+// Recurring gifts are refunded per charge in Helcim, so they report zero.
+func (d *Donation) Refundable() float64 {
+	if d.IsRecurring() || d.IsCrypto() || d.Status != "completed" || d.ChargeReference() == "" {
+		return 0
+	}
+	return math.Round((d.Amount-d.RefundedAmount)*100) / 100
+}
+
+// RecordRefund adds a refund to the donation, marking it refunded once the
+// whole gift has been returned
+func (d *Donation) RecordRefund(amount float64, now time.Time) {
+	d.RefundedAmount = math.Round((d.RefundedAmount+amount)*100) / 100
+	d.RefundedAt = &now
+	if d.RefundedAmount >= d.Amount {
+		d.Status = DonationStatusRefunded
+	}
+}
+
 // CanRetryPayment returns true if payment can be retried
 func (d *Donation) CanRetryPayment() bool {
 	return d.PaymentRetryCount < 3 && d.IsRecurring()
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+)
+
+// anonymizeBatchSize is how many donations are read at a time when a copy
+// is exported
+const anonymizeBatchSize = 500
+
+// DonationAnonymizer turns real donations into ones safe to load into a
+// staging database for report and performance work. Donors are replaced by
+// pseudonyms derived from their email with a keyed hash, so one donor's
+// gifts still belong together and repeat-giving reports hold up, but nobody
+// without the salt can match a pseudonym back to a person. Names, contact
+// details, notes and payment tokens are dropped, processor IDs are
+// pseudonymized so staging can't act on real subscriptions, and amounts are
+// jittered so individual gifts can't be looked up by value.
+type DonationAnonymizer struct {
+	salt   []byte
+	jitter float64
+	rand   *rand.Rand
+}
+
+// NewDonationAnonymizer returns an anonymizer keyed by salt that moves each
+// amount by up to jitter (0.1 is ±10%). seed fixes the jitter, so the same
+// salt and seed give the same copy.
+func NewDonationAnonymizer(salt []byte, jitter float64, seed int64) *DonationAnonymizer {
+	return &DonationAnonymizer{salt: salt, jitter: jitter, rand: rand.New(rand.NewSource(seed))}
+}
+
+// pseudonym is a stable stand-in for value, different for each kind of value
+func (a *DonationAnonymizer) pseudonym(kind, value string) string {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(kind + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// pseudonymize replaces an optional processor ID with a stand-in, keeping
+// IDs that were shared shared and unique ones unique
+func (a *DonationAnonymizer) pseudonymize(kind string, value *string) *string {
+	if value == nil || *value == "" {
+		return value
+	}
+	anon := "anon_" + a.pseudonym(kind, *value)
+	return &anon
+}
+
+// Anonymize returns an anonymized copy of d. Each money field is scaled by
+// the same random factor, so refunds, fees and pledge totals stay in
+// proportion to the gift.
+func (a *DonationAnonymizer) Anonymize(d Donation) Donation {
+	if email := NormalizeDonorEmail(d.DonorEmail); email != "" {
+		donor := a.pseudonym("email", email)
+		d.DonorEmail = "donor-" + donor + "@example.invalid"
+		d.DonorName = "Donor " + donor[:8]
+	} else {
+		d.DonorName = "Anonymous donor"
+	}
+	d.DonorPhone = nil
+	d.AddressLine1 = nil
+	d.AddressLine2 = nil
+	d.City = nil
+	if d.Zip != nil && len(*d.Zip) > 3 {
+		// The first three digits are kept for regional reports
+		prefix := (*d.Zip)[:3]
+		d.Zip = &prefix
+	}
+
+	// Free text may name the donor, and tokens could be replayed
+	d.Comments = nil
+	d.CustomFields = nil
+	d.GoodsDescription = nil
+	d.ReviewNote = nil
+	d.PaymentFailureReason = nil
+	d.SyncError = nil
+	d.CheckoutToken = ""
+	d.SecretToken = ""
+	d.CardToken = nil
+
+	d.HelcimTransactionID = a.pseudonymize("helcim_transaction", d.HelcimTransactionID)
+	d.TransactionID = a.pseudonymize("transaction", d.TransactionID)
+	d.SubscriptionID = a.pseudonymize("subscription", d.SubscriptionID)
+	d.CustomerID = a.pseudonymize("customer", d.CustomerID)
+	d.PaymentPlanID = a.pseudonymize("payment_plan", d.PaymentPlanID)
+	d.ExternalID = a.pseudonymize("external", d.ExternalID)
+	d.PayoutID = a.pseudonymize("payout", d.PayoutID)
+	d.CryptoPledgeID = a.pseudonymize("crypto_pledge", d.CryptoPledgeID)
+	d.DepositAddress = a.pseudonymize("deposit_address", d.DepositAddress)
+
+	// Accounts and donor records aren't copied; loading the copy files each
+	// gift under a donor record made from its pseudonym
+	d.UserID = nil
+	d.DonorID = nil
+	d.ReviewedBy = nil
+	d.APIKeyID = nil
+
+	factor := 1 + a.jitter*(2*a.rand.Float64()-1)
+	scale := func(v float64) float64 { return math.Round(v*factor*100) / 100 }
+	d.Amount = math.Max(scale(d.Amount), 1)
+	if d.IsInstallmentPledge() {
+		d.PledgeTotal = math.Round(d.Amount*float64(d.InstallmentCount)*100) / 100
+	} else {
+		d.PledgeTotal = scale(d.PledgeTotal)
+	}
+	d.RefundedAmount = math.Min(scale(d.RefundedAmount), d.Amount)
+	for _, v := range []**float64{&d.ProcessorFee, &d.FairMarketValue, &d.CryptoAmount} {
+		if *v != nil {
+			scaled := scale(**v)
+			*v = &scaled
+		}
+	}
+	if d.AddonAmounts != nil && *d.AddonAmounts != "" {
+		amounts := strings.Split(*d.AddonAmounts, ",")
+		for i, s := range amounts {
+			if v, err := strconv.ParseFloat(s, 64); err == nil {
+				amounts[i] = strconv.FormatFloat(scale(v), 'f', 2, 64)
+			}
+		}
+		joined := strings.Join(amounts, ",")
+		d.AddonAmounts = &joined
+	}
+	return d
+}
+
+// ExportAnonymizedDonations writes an anonymized copy of every donation to
+// w, oldest first, as one JSON object per line. It returns how many were
+// written.
+func ExportAnonymizedDonations(tx *pop.Connection, a *DonationAnonymizer, w io.Writer) (int, error) {
+	enc := json.NewEncoder(w)
+	written := 0
+	for page := 1; ; page++ {
+		batch := Donations{}
+		if err := tx.Order("created_at asc, id asc").Paginate(page, anonymizeBatchSize).All(&batch); err != nil {
+			return written, errors.WithStack(err)
+		}
+		for _, d := range batch {
+			if err := enc.Encode(a.Anonymize(d)); err != nil {
+				return written, errors.WithStack(err)
+			}
+			written++
+		}
+		if len(batch) < anonymizeBatchSize {
+			return written, nil
+		}
+	}
+}
+
+// LoadAnonymizedDonations loads a copy written by ExportAnonymizedDonations
+// and returns how many donations it added. Donations already loaded are
+// skipped, so a partly loaded copy can be loaded again.
+func LoadAnonymizedDonations(tx *pop.Connection, r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	loaded := 0
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		d := Donation{}
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			return loaded, errors.Wrapf(err, "line %d", line)
+		}
+		if !strings.HasSuffix(d.DonorEmail, "@example.invalid") && d.DonorEmail != "" {
+			return loaded, errors.Errorf("line %d isn't anonymized; refusing to load donation %s", line, d.ID)
+		}
+		exists, err := tx.Where("id = ?", d.ID).Exists(&Donation{})
+		if err != nil {
+			return loaded, errors.WithStack(err)
+		}
+		if exists {
+			continue
+		}
+		updatedAt := d.UpdatedAt
+		if err := tx.Create(&d); err != nil {
+			return loaded, errors.Wrapf(err, "line %d", line)
+		}
+		// Create stamps updated_at with now; cancellation reports need the original
+		if err := tx.RawQuery("UPDATE donations SET updated_at = ? WHERE id = ?", updatedAt, d.ID).Exec(); err != nil {
+			return loaded, errors.WithStack(err)
+		}
+		loaded++
+	}
+	return loaded, errors.WithStack(scanner.Err())
+}
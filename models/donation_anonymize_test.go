@@ -0,0 +1,90 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDonationAnonymizer_Anonymize(t *testing.T) {
+	phone := "555-0100"
+	street := "12 Main St"
+	zip := "94110"
+	comments := "In memory of my husband Frank"
+	subscription := "sub_123"
+	customer := "CST1001"
+	fee := 3.2
+	userID := uuid.Must(uuid.NewV4())
+	original := Donation{
+		ID:             uuid.Must(uuid.NewV4()),
+		UserID:         &userID,
+		DonorName:      "Jane Veteran",
+		DonorEmail:     "Jane@Example.com",
+		DonorPhone:     &phone,
+		AddressLine1:   &street,
+		Zip:            &zip,
+		Comments:       &comments,
+		SubscriptionID: &subscription,
+		CustomerID:     &customer,
+		CheckoutToken:  "checkout",
+		SecretToken:    "secret",
+		Amount:         100,
+		RefundedAmount: 40,
+		ProcessorFee:   &fee,
+		DonationType:   DonationTypeMonthly,
+		CreatedAt:      time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	a := NewDonationAnonymizer([]byte("salt"), 0.1, 1)
+	anon := a.Anonymize(original)
+
+	assert.Equal(t, original.ID, anon.ID)
+	assert.Equal(t, original.CreatedAt, anon.CreatedAt)
+	assert.True(t, strings.HasSuffix(anon.DonorEmail, "@example.invalid"))
+	assert.NotContains(t, anon.DonorName, "Jane")
+	assert.Nil(t, anon.DonorPhone)
+	assert.Nil(t, anon.AddressLine1)
+	assert.Nil(t, anon.Comments)
+	assert.Nil(t, anon.UserID)
+	assert.Equal(t, "941", *anon.Zip)
+	assert.Empty(t, anon.CheckoutToken)
+	assert.Empty(t, anon.SecretToken)
+	assert.NotEqual(t, subscription, *anon.SubscriptionID)
+	assert.True(t, strings.HasPrefix(*anon.SubscriptionID, "anon_"))
+	assert.Equal(t, "Jane@Example.com", original.DonorEmail, "the original is left alone")
+
+	// Amounts move by no more than the jitter, and together
+	assert.InDelta(t, 100, anon.Amount, 10)
+	assert.InDelta(t, 0.4, anon.RefundedAmount/anon.Amount, 0.001)
+	assert.InDelta(t, 0.032, *anon.ProcessorFee/anon.Amount, 0.001)
+
+	// The same donor gets the same pseudonym, however their email was typed
+	again := a.Anonymize(Donation{DonorEmail: " jane@example.com", Amount: 25})
+	assert.Equal(t, anon.DonorEmail, again.DonorEmail)
+	assert.Equal(t, anon.DonorName, again.DonorName)
+
+	// A different salt gives different pseudonyms
+	other := NewDonationAnonymizer([]byte("other"), 0.1, 1).Anonymize(original)
+	assert.NotEqual(t, anon.DonorEmail, other.DonorEmail)
+
+	// The copy round-trips through the export format
+	var buf bytes.Buffer
+	assert.NoError(t, json.NewEncoder(&buf).Encode(anon))
+	loaded := Donation{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &loaded))
+	assert.Equal(t, anon.DonorEmail, loaded.DonorEmail)
+	assert.Equal(t, anon.Amount, loaded.Amount)
+}
+
+func TestDonationAnonymizer_Pledge(t *testing.T) {
+	pledge := Donation{DonationType: DonationTypeInstallment, InstallmentCount: 6, Amount: 250, PledgeTotal: 1500}
+	anon := NewDonationAnonymizer([]byte("salt"), 0.2, 7).Anonymize(pledge)
+	assert.Equal(t, 6, anon.InstallmentCount)
+	assert.InDelta(t, anon.Amount*6, anon.PledgeTotal, 0.001)
+	assert.Equal(t, "Anonymous donor", anon.DonorName)
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Custom donation form field types
+const (
+	FormFieldDropdown = "dropdown"
+	FormFieldText     = "text"
+	FormFieldCheckbox = "checkbox"
+)
+
+// FormFieldTypes lists the field types admins can choose from
+var FormFieldTypes = []string{FormFieldDropdown, FormFieldText, FormFieldCheckbox}
+
+// DonationFormField is an admin-defined question on the donation form. Fields
+// without a partner appear on every form; partner fields only on that
+// partner's giving page.
+type DonationFormField struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Label     string     `json:"label" db:"label"`
+	FieldType string     `json:"field_type" db:"field_type"`
+	Options   *string    `json:"options,omitempty" db:"options"`
+	Required  bool       `json:"required" db:"required"`
+	Active    bool       `json:"active" db:"active"`
+	Position  int        `json:"position" db:"position"`
+	PartnerID *uuid.UUID `json:"partner_id,omitempty" db:"partner_id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (f DonationFormField) String() string {
+	jf, _ := json.Marshal(f)
+	return string(jf)
+}
+
+// DonationFormFields is not required by pop and may be deleted
+type DonationFormFields []DonationFormField
+
+// InputName is the form parameter the field's answer is posted as
+func (f DonationFormField) InputName() string {
+	return "custom_" + f.ID.String()
+}
+
+// OptionList returns the dropdown choices, one per line of Options.
+func (f DonationFormField) OptionList() []string {
+	if f.Options == nil {
+		return nil
+	}
+	var opts []string
+	for _, line := range strings.Split(*f.Options, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			opts = append(opts, line)
+		}
+	}
+	return opts
+}
+
+// HasOption reports whether value is one of the dropdown choices
+func (f DonationFormField) HasOption(value string) bool {
+	for _, opt := range f.OptionList() {
+		if opt == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (f *DonationFormField) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: f.Label, Name: "Label"},
+		&validators.StringInclusion{Field: f.FieldType, Name: "FieldType", List: FormFieldTypes},
+		&validators.FuncValidator{
+			Field:   f.Label,
+			Name:    "Options",
+			Message: "Dropdown fields need at least two options (%s)",
+			Fn: func() bool {
+				return f.FieldType != FormFieldDropdown || len(f.OptionList()) >= 2
+			},
+		},
+	), nil
+}
+
+// CustomFieldAnswer is a donor's answer to a custom form field. The label is
+// stored with the answer so exports still read correctly if the field is
+// later renamed or removed.
+type CustomFieldAnswer struct {
+	FieldID uuid.UUID `json:"field_id"`
+	Label   string    `json:"label"`
+	Value   string    `json:"value"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDonationFormField_OptionList(t *testing.T) {
+	opts := " Yes \n\nNo\r\nPrefer not to say\n"
+	f := DonationFormField{FieldType: FormFieldDropdown, Options: &opts}
+	assert.Equal(t, []string{"Yes", "No", "Prefer not to say"}, f.OptionList())
+	assert.True(t, f.HasOption("No"))
+	assert.False(t, f.HasOption("Maybe"))
+	assert.Nil(t, DonationFormField{}.OptionList())
+}
+
+func TestDonation_CustomAnswers(t *testing.T) {
+	d := &Donation{}
+	assert.Nil(t, d.CustomAnswers())
+
+	answers := []CustomFieldAnswer{{FieldID: uuid.Must(uuid.NewV4()), Label: "Are you a veteran?", Value: "Yes"}}
+	d.SetCustomAnswers(answers)
+	assert.NotNil(t, d.CustomFields)
+	assert.Equal(t, answers, d.CustomAnswers())
+
+	d.SetCustomAnswers(nil)
+	assert.Nil(t, d.CustomFields)
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"database/sql/driver"
+	"strings"
+)
+
+// DonationFrequency is how often a recurring donation is charged. Open-ended
+// recurring gifts (DonationTypeMonthly) may be charged monthly, quarterly or
+// annually; installment pledges are always monthly.
+type DonationFrequency string
+
+const (
+	FrequencyMonthly   DonationFrequency = "monthly"
+	FrequencyQuarterly DonationFrequency = "quarterly"
+	FrequencyAnnual    DonationFrequency = "annual"
+)
+
+// DonationFrequencies lists the frequencies in the order the donate form
+// offers them
+var DonationFrequencies = []DonationFrequency{FrequencyMonthly, FrequencyQuarterly, FrequencyAnnual}
+
+// donationFrequencyAliases are the other spellings API clients send. Records
+// and requests from before frequencies were offered have none, and are monthly.
+var donationFrequencyAliases = map[string]DonationFrequency{
+	"":         FrequencyMonthly,
+	"quarter":  FrequencyQuarterly,
+	"annually": FrequencyAnnual,
+	"yearly":   FrequencyAnnual,
+}
+
+// NormalizeDonationFrequency maps a submitted frequency onto a
+// DonationFrequency, with no frequency meaning monthly. Unknown values are
+// returned lowercased, for Valid to reject.
+func NormalizeDonationFrequency(s string) DonationFrequency {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if f, ok := donationFrequencyAliases[s]; ok {
+		return f
+	}
+	return DonationFrequency(s)
+}
+
+// UnmarshalText normalizes the frequency when a form or JSON body is bound
+func (f *DonationFrequency) UnmarshalText(text []byte) error {
+	*f = NormalizeDonationFrequency(string(text))
+	return nil
+}
+
+// Valid reports whether f is one of DonationFrequencies
+func (f DonationFrequency) Valid() bool {
+	switch f {
+	case FrequencyMonthly, FrequencyQuarterly, FrequencyAnnual:
+		return true
+	}
+	return false
+}
+
+// Months is how many months apart charges are
+func (f DonationFrequency) Months() int {
+	switch f {
+	case FrequencyQuarterly:
+		return 3
+	case FrequencyAnnual:
+		return 12
+	}
+	return 1
+}
+
+// Label is how the frequency is shown to donors and staff, e.g. "Quarterly"
+func (f DonationFrequency) Label() string {
+	switch f {
+	case FrequencyQuarterly:
+		return "Quarterly"
+	case FrequencyAnnual:
+		return "Annual"
+	}
+	return "Monthly"
+}
+
+// Adverb is the frequency in a sentence, as in "Donate $25.00 Annually"
+func (f DonationFrequency) Adverb() string {
+	if f == FrequencyAnnual {
+		return "Annually"
+	}
+	return f.Label()
+}
+
+// Period is the stretch of time one charge covers, as in "per quarter"
+func (f DonationFrequency) Period() string {
+	switch f {
+	case FrequencyQuarterly:
+		return "quarter"
+	case FrequencyAnnual:
+		return "year"
+	}
+	return "month"
+}
+
+func (f DonationFrequency) String() string {
+	return string(f)
+}
+
+// Value stores the frequency as its plain string
+func (f DonationFrequency) Value() (driver.Value, error) {
+	return string(f), nil
+}
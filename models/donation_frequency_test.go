@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeDonationFrequency(t *testing.T) {
+	assert.Equal(t, FrequencyMonthly, NormalizeDonationFrequency(""))
+	assert.Equal(t, FrequencyQuarterly, NormalizeDonationFrequency(" Quarterly "))
+	assert.Equal(t, FrequencyAnnual, NormalizeDonationFrequency("yearly"))
+	assert.Equal(t, FrequencyAnnual, NormalizeDonationFrequency("Annually"))
+
+	unknown := NormalizeDonationFrequency("Weekly")
+	assert.Equal(t, DonationFrequency("weekly"), unknown)
+	assert.False(t, unknown.Valid())
+}
+
+func TestDonationFrequency_UnmarshalJSON(t *testing.T) {
+	var req struct {
+		Frequency DonationFrequency `json:"frequency"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(`{"frequency":"Yearly"}`), &req))
+	assert.Equal(t, FrequencyAnnual, req.Frequency)
+}
+
+func TestDonationFrequency_Wording(t *testing.T) {
+	assert.Equal(t, 1, FrequencyMonthly.Months())
+	assert.Equal(t, 3, FrequencyQuarterly.Months())
+	assert.Equal(t, 12, FrequencyAnnual.Months())
+
+	assert.Equal(t, "Annual", FrequencyAnnual.Label())
+	assert.Equal(t, "Annually", FrequencyAnnual.Adverb())
+	assert.Equal(t, "Quarterly", FrequencyQuarterly.Adverb())
+	assert.Equal(t, "quarter", FrequencyQuarterly.Period())
+	assert.Equal(t, "month", FrequencyMonthly.Period())
+}
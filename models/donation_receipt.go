@@ -0,0 +1,158 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// DonationReceipt is a receipt issued for a donation. A recurring gift or
+// pledge gets one for each charge, told apart by TransactionID; sending the
+// same receipt again keeps its number. A receipt held back by the
+// compliance check before sending is marked blocked until it goes out.
+type DonationReceipt struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	ReceiptNumber string    `json:"receipt_number" db:"receipt_number"`
+	DonationID    uuid.UUID `json:"donation_id" db:"donation_id"`
+	TransactionID string    `json:"transaction_id" db:"transaction_id"`
+	Amount        float64   `json:"amount" db:"amount"`
+	Email         string    `json:"email" db:"email"`
+	IssuedAt      time.Time `json:"issued_at" db:"issued_at"`
+	// ComplianceProblems lists what the receipt was missing when it was
+	// last blocked, separated by "; "
+	ComplianceProblems string     `json:"compliance_problems" db:"compliance_problems"`
+	BlockedAt          *time.Time `json:"blocked_at,omitempty" db:"blocked_at"`
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (r DonationReceipt) String() string {
+	jr, _ := json.Marshal(r)
+	return string(jr)
+}
+
+// DonationReceipts is not required by pop and may be deleted
+type DonationReceipts []DonationReceipt
+
+// Blocked reports whether the receipt was held back and hasn't been sent
+// since
+func (r DonationReceipt) Blocked() bool {
+	return r.BlockedAt != nil
+}
+
+// receiptSequence reads the next value of the receipt number sequence
+type receiptSequence struct {
+	Next int64 `db:"next"`
+}
+
+// ReceiptNumber formats the nth receipt issued in year, e.g.
+// "AVR-2026-000042". Numbers run on across years, so they never repeat.
+func ReceiptNumber(year int, n int64) string {
+	return fmt.Sprintf("AVR-%d-%06d", year, n)
+}
+
+// IssueDonationReceipt numbers the receipt for one charge of a donation,
+// or returns the receipt already issued for it
+func IssueDonationReceipt(tx *pop.Connection, donation *Donation, transactionID string, amount float64, now time.Time) (*DonationReceipt, error) {
+	transactionID = strings.TrimSpace(transactionID)
+	receipt := &DonationReceipt{}
+	err := tx.Where("donation_id = ? AND transaction_id = ?", donation.ID, transactionID).First(receipt)
+	if err == nil {
+		return receipt, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.WithStack(err)
+	}
+
+	seq := receiptSequence{}
+	if err := tx.RawQuery("SELECT nextval('donation_receipt_numbers') AS next").First(&seq); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	receipt = &DonationReceipt{
+		ReceiptNumber: ReceiptNumber(now.Year(), seq.Next),
+		DonationID:    donation.ID,
+		TransactionID: transactionID,
+		Amount:        amount,
+		Email:         donation.DonorEmail,
+		IssuedAt:      now,
+	}
+	if err := tx.Create(receipt); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return receipt, nil
+}
+
+// FindDonationReceipt looks up a receipt by its number, ignoring case and
+// surrounding space. It returns nil when there is none.
+func FindDonationReceipt(tx *pop.Connection, number string) (*DonationReceipt, error) {
+	receipt := &DonationReceipt{}
+	err := tx.Where("receipt_number = ?", strings.ToUpper(strings.TrimSpace(number))).First(receipt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return receipt, nil
+}
+
+// FindDonationByTransaction looks up the donation a Helcim or other
+// processor transaction ID belongs to, whether it's the gift's own charge
+// or a later charge of a recurring gift. It returns nil when there is none.
+func FindDonationByTransaction(tx *pop.Connection, transactionID string) (*Donation, error) {
+	transactionID = strings.TrimSpace(transactionID)
+	if transactionID == "" {
+		return nil, nil
+	}
+	donation := &Donation{}
+	err := tx.Where("helcim_transaction_id = ? OR transaction_id = ? OR external_id = ? OR id IN (SELECT donation_id FROM donation_receipts WHERE transaction_id = ?)",
+		transactionID, transactionID, transactionID, transactionID).Order("created_at").First(donation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return donation, nil
+}
+
+// SetDonationReceiptCompliance records whether the receipt with the given
+// number was blocked for missing details, clearing the mark when problems
+// is empty. A blank number, left by a failure to number the receipt, is
+// ignored.
+func SetDonationReceiptCompliance(tx *pop.Connection, number string, problems []string, now time.Time) error {
+	receipt, err := FindDonationReceipt(tx, number)
+	if err != nil || receipt == nil {
+		return err
+	}
+	if len(problems) == 0 && !receipt.Blocked() {
+		return nil
+	}
+	receipt.ComplianceProblems = strings.Join(problems, "; ")
+	receipt.BlockedAt = nil
+	if len(problems) > 0 {
+		receipt.BlockedAt = &now
+	}
+	return errors.WithStack(tx.UpdateColumns(receipt, "compliance_problems", "blocked_at", "updated_at"))
+}
+
+// BlockedDonationReceipts are the receipts held back by the compliance
+// check, newest first. With a donation ID only that donation's are loaded.
+func BlockedDonationReceipts(tx *pop.Connection, donationID *uuid.UUID) (DonationReceipts, error) {
+	receipts := DonationReceipts{}
+	q := tx.Where("blocked_at IS NOT NULL")
+	if donationID != nil {
+		q = q.Where("donation_id = ?", *donationID)
+	}
+	if err := q.Order("blocked_at desc").All(&receipts); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return receipts, nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiptNumber(t *testing.T) {
+	assert.Equal(t, "AVR-2026-000042", ReceiptNumber(2026, 42))
+	assert.Equal(t, "AVR-2027-1234567", ReceiptNumber(2027, 1234567))
+}
+
+func (ms *ModelSuite) Test_IssueDonationReceipt() {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	txn := "TXN-1001"
+	donation := &Donation{DonorName: "Pat Giver", DonorEmail: "pat@example.com", Amount: 25, Currency: "USD", DonationType: "monthly", Status: "active", TransactionID: &txn}
+	ms.NoError(ms.DB.Create(donation))
+
+	first, err := IssueDonationReceipt(ms.DB, donation, txn, 25, now)
+	ms.NoError(err)
+	ms.Contains(first.ReceiptNumber, "AVR-2026-")
+
+	// Sending the same receipt again keeps its number
+	again, err := IssueDonationReceipt(ms.DB, donation, " "+txn+" ", 25, now)
+	ms.NoError(err)
+	ms.Equal(first.ReceiptNumber, again.ReceiptNumber)
+
+	// A later monthly charge gets a receipt of its own
+	next, err := IssueDonationReceipt(ms.DB, donation, "TXN-2002", 25, now.AddDate(0, 1, 0))
+	ms.NoError(err)
+	ms.NotEqual(first.ReceiptNumber, next.ReceiptNumber)
+
+	found, err := FindDonationReceipt(ms.DB, " "+next.ReceiptNumber+" ")
+	ms.NoError(err)
+	ms.Equal("TXN-2002", found.TransactionID)
+	missing, err := FindDonationReceipt(ms.DB, "AVR-1999-000001")
+	ms.NoError(err)
+	ms.Nil(missing)
+
+	for _, id := range []string{txn, "TXN-2002"} {
+		byTransaction, err := FindDonationByTransaction(ms.DB, id)
+		ms.NoError(err)
+		ms.Equal(donation.ID, byTransaction.ID)
+	}
+	none, err := FindDonationByTransaction(ms.DB, "TXN-unknown")
+	ms.NoError(err)
+	ms.Nil(none)
+}
+
+func (ms *ModelSuite) Test_SetDonationReceiptCompliance() {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	donation := &Donation{DonorName: "Pat Giver", DonorEmail: "pat@example.com", Amount: 25, Currency: "USD", DonationType: "one-time", Status: "completed"}
+	ms.NoError(ms.DB.Create(donation))
+	receipt, err := IssueDonationReceipt(ms.DB, donation, "TXN-3003", 25, now)
+	ms.NoError(err)
+
+	ms.NoError(SetDonationReceiptCompliance(ms.DB, receipt.ReceiptNumber, []string{"organization EIN", "organization address"}, now))
+	blocked, err := BlockedDonationReceipts(ms.DB, &donation.ID)
+	ms.NoError(err)
+	ms.Len(blocked, 1)
+	ms.True(blocked[0].Blocked())
+	ms.Equal("organization EIN; organization address", blocked[0].ComplianceProblems)
+
+	// Sending it later clears the mark
+	ms.NoError(SetDonationReceiptCompliance(ms.DB, receipt.ReceiptNumber, nil, now))
+	blocked, err = BlockedDonationReceipts(ms.DB, nil)
+	ms.NoError(err)
+	ms.Empty(blocked)
+
+	// A receipt that couldn't be numbered has nothing to mark
+	ms.NoError(SetDonationReceiptCompliance(ms.DB, "", []string{"organization EIN"}, now))
+}
@@ -122,3 +122,100 @@ func TestDonation_RecordPaymentFailure(t *testing.T) {
 	assert.Equal(t, 2, donation.PaymentRetryCount)
 	assert.Equal(t, "Insufficient funds", *donation.PaymentFailureReason)
 }
+
+func TestDonation_InstallmentPledge(t *testing.T) {
+	donation := &Donation{
+		DonationType:     DonationTypeInstallment,
+		Amount:           250,
+		PledgeTotal:      1500,
+		InstallmentCount: 6,
+		InstallmentsPaid: 5,
+	}
+
+	assert.True(t, donation.IsInstallmentPledge())
+	assert.Equal(t, 1500.0, donation.PledgeAmount())
+	assert.False(t, donation.PledgeComplete())
+
+	donation.InstallmentsPaid = 6
+	assert.True(t, donation.PledgeComplete())
+
+	monthly := &Donation{DonationType: "monthly", Amount: 50}
+	assert.False(t, monthly.IsInstallmentPledge())
+	assert.Equal(t, 50.0, monthly.PledgeAmount())
+	assert.False(t, monthly.PledgeComplete())
+}
+
+func TestDonation_ReceivedToDate(t *testing.T) {
+	now := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	subscription := "sub_1"
+
+	oneTime := &Donation{Amount: 75, Status: "completed"}
+	assert.Equal(t, 75.0, oneTime.ReceivedToDate(now))
+	oneTime.Status = "failed"
+	assert.Equal(t, 0.0, oneTime.ReceivedToDate(now))
+
+	activated := time.Date(2026, 7, 20, 0, 0, 0, 0, time.UTC)
+	monthly := &Donation{DonationType: "monthly", Amount: 50, Status: "active", SubscriptionID: &subscription, ActivationDate: &activated}
+	assert.Equal(t, 150.0, monthly.ReceivedToDate(now), "July 20, August 20 and September 20")
+
+	monthly.Status = "cancelled"
+	monthly.UpdatedAt = time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 50.0, monthly.ReceivedToDate(now))
+
+	spring := time.Date(2026, 4, 20, 0, 0, 0, 0, time.UTC)
+	quarterly := &Donation{DonationType: "monthly", Frequency: FrequencyQuarterly, Amount: 90, Status: "active", SubscriptionID: &subscription, ActivationDate: &spring}
+	assert.Equal(t, 180.0, quarterly.ReceivedToDate(now), "April 20 and July 20")
+	quarterly.Frequency = FrequencyAnnual
+	assert.Equal(t, 90.0, quarterly.ReceivedToDate(now))
+
+	pledge := &Donation{DonationType: DonationTypeInstallment, Amount: 250, InstallmentCount: 6, InstallmentsPaid: 2, Status: "active", SubscriptionID: &subscription}
+	assert.Equal(t, 500.0, pledge.ReceivedToDate(now))
+}
+
+func TestDonation_ChargeFrequency(t *testing.T) {
+	legacy := &Donation{DonationType: DonationTypeMonthly, Amount: 30}
+	assert.Equal(t, FrequencyMonthly, legacy.ChargeFrequency())
+	assert.Equal(t, "Monthly", legacy.TypeLabel())
+
+	quarterly := &Donation{DonationType: DonationTypeMonthly, Frequency: FrequencyQuarterly, Amount: 30}
+	assert.Equal(t, "Quarterly", quarterly.TypeLabel())
+	assert.Equal(t, 10.0, quarterly.MonthlyEquivalent())
+
+	annual := &Donation{DonationType: DonationTypeMonthly, Frequency: FrequencyAnnual, Amount: 120}
+	assert.Equal(t, 10.0, annual.MonthlyEquivalent())
+
+	// Pledges are always monthly, whatever was stored
+	pledge := &Donation{DonationType: DonationTypeInstallment, Frequency: FrequencyAnnual, InstallmentCount: 4, Amount: 50}
+	assert.Equal(t, FrequencyMonthly, pledge.ChargeFrequency())
+	assert.Equal(t, "Installment pledge", pledge.TypeLabel())
+	assert.Equal(t, "One-time", (&Donation{DonationType: DonationTypeOneTime}).TypeLabel())
+}
+
+func TestDonation_Refundable(t *testing.T) {
+	txn := "12345"
+	d := &Donation{Amount: 100, Status: "completed", HelcimTransactionID: &txn}
+	assert.Equal(t, 100.0, d.Refundable())
+
+	d.RecordRefund(40, time.Now())
+	assert.Equal(t, 60.0, d.Refundable())
+	assert.Equal(t, "completed", d.Status)
+
+	d.RecordRefund(60, time.Now())
+	assert.Equal(t, DonationStatusRefunded, d.Status)
+	assert.Equal(t, 0.0, d.Refundable())
+
+	sub := "sub-1"
+	assert.Equal(t, 0.0, (&Donation{Amount: 25, Status: "completed", HelcimTransactionID: &txn, SubscriptionID: &sub}).Refundable())
+	assert.Equal(t, 0.0, (&Donation{Amount: 25, Status: "completed"}).Refundable())
+}
+
+func TestDonation_PaysByBank(t *testing.T) {
+	assert.False(t, (&Donation{}).PaysByBank())
+
+	bank := PaymentMethodBank
+	d := &Donation{Amount: 50, Status: DonationStatusSettling, PaymentMethod: &bank}
+	assert.True(t, d.PaysByBank())
+	assert.True(t, ValidDonationStatus(DonationStatusSettling))
+	// A transfer can't be refunded until it has settled
+	assert.Equal(t, 0.0, d.Refundable())
+}
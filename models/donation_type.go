@@ -0,0 +1,84 @@
+package models
+
+import (
+	"database/sql/driver"
+	"strings"
+)
+
+// DonationType is how a donation is charged: once, every month, or as a
+// pledge paid in a fixed number of monthly installments
+type DonationType string
+
+const (
+	DonationTypeOneTime     DonationType = "one-time"
+	DonationTypeMonthly     DonationType = "monthly"
+	DonationTypeInstallment DonationType = "installment"
+)
+
+// DonationTypes lists the donation types in the order the donate form offers
+// them
+var DonationTypes = []DonationType{DonationTypeOneTime, DonationTypeMonthly, DonationTypeInstallment}
+
+// donationTypeAliases are the other spellings that reach us from older
+// records, API clients and display labels
+var donationTypeAliases = map[string]DonationType{
+	"one_time":  DonationTypeOneTime,
+	"onetime":   DonationTypeOneTime,
+	"once":      DonationTypeOneTime,
+	"recurring": DonationTypeMonthly,
+	"pledge":    DonationTypeInstallment,
+}
+
+// NormalizeDonationType maps a submitted donation type onto a DonationType.
+// Unknown values are returned lowercased, for Valid to reject.
+func NormalizeDonationType(s string) DonationType {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if t, ok := donationTypeAliases[s]; ok {
+		return t
+	}
+	return DonationType(s)
+}
+
+// UnmarshalText normalizes the donation type when a form or JSON body is
+// bound, so handlers only ever see the canonical spellings
+func (t *DonationType) UnmarshalText(text []byte) error {
+	*t = NormalizeDonationType(string(text))
+	return nil
+}
+
+// Valid reports whether t is one of DonationTypes
+func (t DonationType) Valid() bool {
+	switch t {
+	case DonationTypeOneTime, DonationTypeMonthly, DonationTypeInstallment:
+		return true
+	}
+	return false
+}
+
+// IsRecurring reports whether the type is charged more than once
+func (t DonationType) IsRecurring() bool {
+	return t == DonationTypeMonthly || t == DonationTypeInstallment
+}
+
+// Label is how the type is shown to donors and staff on receipts, emails,
+// reports and admin pages
+func (t DonationType) Label() string {
+	switch t {
+	case DonationTypeOneTime:
+		return "One-time"
+	case DonationTypeMonthly:
+		return "Monthly"
+	case DonationTypeInstallment:
+		return "Installment pledge"
+	}
+	return string(t)
+}
+
+func (t DonationType) String() string {
+	return string(t)
+}
+
+// Value stores the type as its plain string
+func (t DonationType) Value() (driver.Value, error) {
+	return string(t), nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeDonationType(t *testing.T) {
+	assert.Equal(t, DonationTypeMonthly, NormalizeDonationType(" Monthly "))
+	assert.Equal(t, DonationTypeMonthly, NormalizeDonationType("recurring"))
+	assert.Equal(t, DonationTypeOneTime, NormalizeDonationType("One_Time"))
+	assert.Equal(t, DonationTypeInstallment, NormalizeDonationType("installment"))
+
+	unknown := NormalizeDonationType("Weekly")
+	assert.Equal(t, DonationType("weekly"), unknown)
+	assert.False(t, unknown.Valid())
+	assert.False(t, DonationType("").Valid())
+}
+
+func TestDonationType_UnmarshalJSON(t *testing.T) {
+	var req struct {
+		DonationType DonationType `json:"donation_type"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(`{"donation_type":"Recurring"}`), &req))
+	assert.Equal(t, DonationTypeMonthly, req.DonationType)
+}
+
+func TestDonationType_Label(t *testing.T) {
+	assert.Equal(t, "One-time", DonationTypeOneTime.Label())
+	assert.Equal(t, "Monthly", DonationTypeMonthly.Label())
+	assert.Equal(t, "Installment pledge", DonationTypeInstallment.Label())
+	assert.True(t, DonationTypeInstallment.IsRecurring())
+	assert.False(t, DonationTypeOneTime.IsRecurring())
+}
+
+func TestDonation_ValidateDonationType(t *testing.T) {
+	donation := &Donation{DonorName: "Jo", DonorEmail: "jo@example.com", Currency: "USD", Status: "pending", DonationType: "weekly"}
+	verrs, err := donation.Validate(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, verrs.Get("donation_type"), `"weekly" is not a known donation type`)
+
+	donation.DonationType = DonationTypeMonthly
+	verrs, _ = donation.Validate(nil)
+	assert.False(t, verrs.HasAny())
+}
@@ -0,0 +1,500 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Kinds of donor communication
+const (
+	CommunicationReceipt        = "receipt"
+	CommunicationAcknowledgment = "acknowledgment"
+	CommunicationContactMessage = "contact_message"
+	CommunicationPaymentNotice  = "payment_notice"
+	CommunicationYearEndReceipt = "year_end_receipt"
+)
+
+// NormalizeDonorEmail is the form of an email address donor records are
+// keyed by. Donors don't need an account, so their email is what ties their
+// gifts, messages and notes together.
+func NormalizeDonorEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Donor is one person who gives, however many gifts they've made. Gifts are
+// matched to a donor by their Helcim customer code, then by email, so a
+// donor who changes their email but keeps paying through the same Helcim
+// customer stays one donor.
+type Donor struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	Email        string    `json:"email" db:"email"`
+	Name         string    `json:"name" db:"name"`
+	Phone        *string   `json:"phone,omitempty" db:"phone"`
+	CustomerCode *string   `json:"customer_code,omitempty" db:"customer_code"`
+	AddressLine1 *string   `json:"address_line1,omitempty" db:"address_line1"`
+	AddressLine2 *string   `json:"address_line2,omitempty" db:"address_line2"`
+	City         *string   `json:"city,omitempty" db:"city"`
+	State        *string   `json:"state,omitempty" db:"state"`
+	Zip          *string   `json:"zip,omitempty" db:"zip"`
+	// HelcimSyncedAt is when the donor was last checked against their
+	// Helcim customer
+	HelcimSyncedAt *time.Time `json:"helcim_synced_at,omitempty" db:"helcim_synced_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (d Donor) String() string {
+	js, _ := json.Marshal(d)
+	return string(js)
+}
+
+// Donors is not required by pop and may be deleted
+type Donors []Donor
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (d *Donor) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.EmailIsPresent{Field: d.Email, Name: "Email"},
+	), nil
+}
+
+// DisplayName is the donor's name, or their email when we don't have one
+func (d Donor) DisplayName() string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return d.Email
+}
+
+// CustomerCodeText is the donor's Helcim customer code, or blank
+func (d Donor) CustomerCodeText() string {
+	if d.CustomerCode == nil {
+		return ""
+	}
+	return *d.CustomerCode
+}
+
+// DonorAddress is a donor's mailing address
+type DonorAddress struct {
+	Line1 string
+	Line2 string
+	City  string
+	State string
+	Zip   string
+}
+
+// IsZero reports whether no part of the address is filled in
+func (a DonorAddress) IsZero() bool {
+	return a.normalized() == DonorAddress{}
+}
+
+// Matches reports whether the two addresses are the same, ignoring case and
+// spacing
+func (a DonorAddress) Matches(other DonorAddress) bool {
+	return a.normalized() == other.normalized()
+}
+
+func (a DonorAddress) normalized() DonorAddress {
+	clean := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+	return DonorAddress{
+		Line1: clean(a.Line1),
+		Line2: clean(a.Line2),
+		City:  clean(a.City),
+		State: clean(a.State),
+		Zip:   clean(a.Zip),
+	}
+}
+
+// String is the address on one line
+func (a DonorAddress) String() string {
+	parts := []string{}
+	for _, part := range []string{a.Line1, a.Line2, a.City, strings.TrimSpace(a.State + " " + a.Zip)} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Address is the donor's mailing address
+func (d Donor) Address() DonorAddress {
+	text := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	return DonorAddress{
+		Line1: text(d.AddressLine1),
+		Line2: text(d.AddressLine2),
+		City:  text(d.City),
+		State: text(d.State),
+		Zip:   text(d.Zip),
+	}
+}
+
+// AddressText is the donor's mailing address on one line, or blank
+func (d Donor) AddressText() string {
+	return d.Address().String()
+}
+
+// SetAddress replaces the donor's mailing address
+func (d *Donor) SetAddress(a DonorAddress) {
+	text := func(s string) *string {
+		if s = strings.TrimSpace(s); s == "" {
+			return nil
+		}
+		return &s
+	}
+	d.AddressLine1 = text(a.Line1)
+	d.AddressLine2 = text(a.Line2)
+	d.City = text(a.City)
+	d.State = text(a.State)
+	d.Zip = text(a.Zip)
+}
+
+// MatchDonor finds the donor with the Helcim customer code, or failing that
+// the email. It returns nil when neither has given before.
+func MatchDonor(tx *pop.Connection, customerCode, email string) (*Donor, error) {
+	if customerCode != "" {
+		donor, err := findDonor(tx, "customer_code = ?", customerCode)
+		if donor != nil || err != nil {
+			return donor, err
+		}
+	}
+	email = NormalizeDonorEmail(email)
+	if email == "" {
+		return nil, nil
+	}
+	return findDonor(tx, "email = ?", email)
+}
+
+func findDonor(tx *pop.Connection, query string, arg interface{}) (*Donor, error) {
+	donor := &Donor{}
+	err := tx.Where(query, arg).First(donor)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return donor, nil
+}
+
+// fillFrom copies the details the donor record is missing from one of their
+// gifts and reports whether anything changed. A customer code is only taken
+// when the donor has none, since MatchDonor would have found the donor
+// already holding it.
+func (d *Donor) fillFrom(donation *Donation) bool {
+	changed := false
+	if d.Name == "" && strings.TrimSpace(donation.DonorName) != "" {
+		d.Name = strings.TrimSpace(donation.DonorName)
+		changed = true
+	}
+	if d.Phone == nil && donation.DonorPhone != nil && *donation.DonorPhone != "" {
+		d.Phone = donation.DonorPhone
+		changed = true
+	}
+	if code := donation.HelcimCustomerCode(); d.CustomerCode == nil && code != "" {
+		d.CustomerCode = &code
+		changed = true
+	}
+	if address := donationAddress(donation); d.Address().IsZero() && !address.IsZero() {
+		d.SetAddress(address)
+		changed = true
+	}
+	return changed
+}
+
+func donationAddress(donation *Donation) DonorAddress {
+	return Donor{
+		AddressLine1: donation.AddressLine1,
+		AddressLine2: donation.AddressLine2,
+		City:         donation.City,
+		State:        donation.State,
+		Zip:          donation.Zip,
+	}.Address()
+}
+
+// LinkDonor files a gift under its donor, creating the donor the first time
+// they give
+func LinkDonor(tx *pop.Connection, donation *Donation) error {
+	email := NormalizeDonorEmail(donation.DonorEmail)
+	donor, err := MatchDonor(tx, donation.HelcimCustomerCode(), email)
+	if err != nil {
+		return err
+	}
+	if donor == nil {
+		if email == "" {
+			return nil
+		}
+		donor = &Donor{Email: email}
+	}
+
+	if donor.fillFrom(donation) || donor.ID == uuid.Nil {
+		if err := tx.Save(donor); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if donation.DonorID != nil && *donation.DonorID == donor.ID {
+		return nil
+	}
+	// Set directly rather than saving the donation, which would run this
+	// hook again
+	if err := tx.RawQuery("UPDATE donations SET donor_id = ? WHERE id = ?", donor.ID, donation.ID).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+	donation.DonorID = &donor.ID
+	return nil
+}
+
+// BackfillDonors files the gifts made before donor records existed under
+// their donors, oldest first, and returns how many it linked
+func BackfillDonors(tx *pop.Connection) (int, error) {
+	donations := []Donation{}
+	if err := tx.Where("donor_id IS NULL").Order("created_at asc").All(&donations); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	linked := 0
+	for i := range donations {
+		if err := LinkDonor(tx, &donations[i]); err != nil {
+			return linked, err
+		}
+		if donations[i].DonorID != nil {
+			linked++
+		}
+	}
+	return linked, nil
+}
+
+// DonorGiving sums up a donor's gifts
+type DonorGiving struct {
+	// Lifetime is what the donor's gifts have brought in, counting monthly
+	// gifts as ReceivedToDate estimates them
+	Lifetime  float64
+	Gifts     int
+	FirstGift *time.Time
+	LastGift  *time.Time
+	// Recurring is set while the donor has an active monthly gift
+	Recurring bool
+}
+
+// TallyDonorGiving sums up donations as of now. Only gifts that have
+// brought something in count towards the gift count and dates.
+func TallyDonorGiving(donations []Donation, now time.Time) DonorGiving {
+	giving := DonorGiving{}
+	for i := range donations {
+		d := &donations[i]
+		if d.IsRecurring() && d.Status == "active" {
+			giving.Recurring = true
+		}
+		received := d.ReceivedToDate(now)
+		if received <= 0 {
+			continue
+		}
+		giving.Lifetime += received
+		giving.Gifts++
+		at := d.CreatedAt
+		if giving.FirstGift == nil || at.Before(*giving.FirstGift) {
+			giving.FirstGift = &at
+		}
+		if giving.LastGift == nil || at.After(*giving.LastGift) {
+			giving.LastGift = &at
+		}
+	}
+	return giving
+}
+
+// DonorCommunication records an email we sent a donor, such as a receipt,
+// or a message they sent us through the contact form
+type DonorCommunication struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	DonorEmail  string     `json:"donor_email" db:"donor_email"`
+	Kind        string     `json:"kind" db:"kind"`
+	Summary     string     `json:"summary" db:"summary"`
+	Body        *string    `json:"body,omitempty" db:"body"`
+	ReferenceID *uuid.UUID `json:"reference_id,omitempty" db:"reference_id"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (d DonorCommunication) String() string {
+	js, _ := json.Marshal(d)
+	return string(js)
+}
+
+// DonorCommunications is not required by pop and may be deleted
+type DonorCommunications []DonorCommunication
+
+// BodyText is the text of the communication, when we keep it
+func (d DonorCommunication) BodyText() string {
+	if d.Body == nil {
+		return ""
+	}
+	return *d.Body
+}
+
+// DonorNote is a free-form note staff keep on a donor, or on one of their
+// donations when DonationID is set
+type DonorNote struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	DonorEmail string     `json:"donor_email" db:"donor_email"`
+	DonationID *uuid.UUID `json:"donation_id,omitempty" db:"donation_id"`
+	Body       string     `json:"body" db:"body"`
+	AuthorID   *uuid.UUID `json:"author_id,omitempty" db:"author_id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (n DonorNote) String() string {
+	js, _ := json.Marshal(n)
+	return string(js)
+}
+
+// DonorNotes is not required by pop and may be deleted
+type DonorNotes []DonorNote
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (n *DonorNote) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.EmailIsPresent{Field: n.DonorEmail, Name: "DonorEmail"},
+		&validators.StringIsPresent{Field: n.Body, Name: "Body"},
+		&validators.StringLengthInRange{Field: n.Body, Name: "Body", Max: 5000},
+	), nil
+}
+
+// Donor flags staff can set on a donor or one of their donations
+const (
+	FlagDoNotSolicit   = "do_not_solicit"
+	FlagBoardMember    = "board_member"
+	FlagChargebackRisk = "chargeback_risk"
+	FlagMajorDonor     = "major_donor"
+)
+
+// DonorFlagKinds lists the flags in the order they're offered
+var DonorFlagKinds = []string{FlagDoNotSolicit, FlagBoardMember, FlagChargebackRisk, FlagMajorDonor}
+
+var donorFlagLabels = map[string]string{
+	FlagDoNotSolicit:   "Do not solicit",
+	FlagBoardMember:    "Board member",
+	FlagChargebackRisk: "Chargeback risk",
+	FlagMajorDonor:     "Major donor",
+}
+
+// donorFlagColors is the badge color each flag is shown in
+var donorFlagColors = map[string]string{
+	FlagDoNotSolicit:   "red",
+	FlagBoardMember:    "blue",
+	FlagChargebackRisk: "orange",
+	FlagMajorDonor:     "green",
+}
+
+// DonorFlagLabel is the display name for a donor flag
+func DonorFlagLabel(flag string) string {
+	if label, ok := donorFlagLabels[flag]; ok {
+		return label
+	}
+	return flag
+}
+
+// DonorFlag marks a donor, or one of their donations when DonationID is set,
+// for staff attention. A "do not solicit" flag keeps the donor out of bulk
+// appeals.
+type DonorFlag struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	DonorEmail string     `json:"donor_email" db:"donor_email"`
+	DonationID *uuid.UUID `json:"donation_id,omitempty" db:"donation_id"`
+	Flag       string     `json:"flag" db:"flag"`
+	AuthorID   *uuid.UUID `json:"author_id,omitempty" db:"author_id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (f DonorFlag) String() string {
+	js, _ := json.Marshal(f)
+	return string(js)
+}
+
+// DonorFlags is not required by pop and may be deleted
+type DonorFlags []DonorFlag
+
+// Label is the display name of the flag
+func (f DonorFlag) Label() string {
+	return DonorFlagLabel(f.Flag)
+}
+
+// Color is the badge color the flag is shown in
+func (f DonorFlag) Color() string {
+	if color, ok := donorFlagColors[f.Flag]; ok {
+		return color
+	}
+	return "gray"
+}
+
+// Has reports whether flag is among the flags
+func (f DonorFlags) Has(flag string) bool {
+	for _, existing := range f {
+		if existing.Flag == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (f *DonorFlag) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.EmailIsPresent{Field: f.DonorEmail, Name: "DonorEmail"},
+		&validators.StringInclusion{Field: f.Flag, Name: "Flag", List: DonorFlagKinds},
+	), nil
+}
+
+// ExcludeDoNotSolicit drops donors flagged "do not solicit" from a list of
+// recipient emails. Bulk appeals must run their recipients through this
+// before sending; receipts and acknowledgments are not solicitations and
+// don't need to.
+func ExcludeDoNotSolicit(tx *pop.Connection, emails []string) ([]string, error) {
+	if len(emails) == 0 {
+		return emails, nil
+	}
+	normalized := make([]interface{}, len(emails))
+	for i, email := range emails {
+		normalized[i] = NormalizeDonorEmail(email)
+	}
+	flags := DonorFlags{}
+	if err := tx.Where("flag = ?", FlagDoNotSolicit).Where("donor_email IN (?)", normalized...).All(&flags); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return FilterDoNotSolicit(emails, flags), nil
+}
+
+// FilterDoNotSolicit drops the emails flagged "do not solicit" in flags
+func FilterDoNotSolicit(emails []string, flags DonorFlags) []string {
+	blocked := map[string]bool{}
+	for _, flag := range flags {
+		if flag.Flag == FlagDoNotSolicit {
+			blocked[flag.DonorEmail] = true
+		}
+	}
+	kept := make([]string, 0, len(emails))
+	for _, email := range emails {
+		if !blocked[NormalizeDonorEmail(email)] {
+			kept = append(kept, email)
+		}
+	}
+	return kept
+}
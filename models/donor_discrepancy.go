@@ -0,0 +1,118 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Kinds of difference the nightly Helcim sync finds between a donor and
+// their Helcim customer
+const (
+	DiscrepancyMissingCustomer = "missing_customer"
+	DiscrepancyAddress         = "address"
+	DiscrepancyCard            = "card"
+)
+
+// How a discrepancy was settled
+const (
+	ResolutionKeptHelcim = "kept_helcim"
+	ResolutionSentLocal  = "sent_local"
+	ResolutionDismissed  = "dismissed"
+	// ResolutionCleared is set by the sync when the difference has gone away
+	ResolutionCleared = "cleared"
+)
+
+var discrepancyLabels = map[string]string{
+	DiscrepancyMissingCustomer: "No Helcim customer",
+	DiscrepancyAddress:         "Address differs",
+	DiscrepancyCard:            "Card problem",
+}
+
+// DonorDiscrepancy is a difference between a donor record and their Helcim
+// customer, held for staff to review. Each donor has at most one open
+// discrepancy of each kind.
+type DonorDiscrepancy struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	DonorID     uuid.UUID  `json:"donor_id" db:"donor_id"`
+	Kind        string     `json:"kind" db:"kind"`
+	LocalValue  string     `json:"local_value" db:"local_value"`
+	HelcimValue string     `json:"helcim_value" db:"helcim_value"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty" db:"resolved_at"`
+	Resolution  *string    `json:"resolution,omitempty" db:"resolution"`
+	ResolvedBy  *uuid.UUID `json:"resolved_by,omitempty" db:"resolved_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (d DonorDiscrepancy) String() string {
+	js, _ := json.Marshal(d)
+	return string(js)
+}
+
+// DonorDiscrepancies is not required by pop and may be deleted
+type DonorDiscrepancies []DonorDiscrepancy
+
+// Label is the display name of the discrepancy's kind
+func (d DonorDiscrepancy) Label() string {
+	if label, ok := discrepancyLabels[d.Kind]; ok {
+		return label
+	}
+	return d.Kind
+}
+
+// Resolve closes the discrepancy. resolvedBy is nil when the sync closed it.
+func (d *DonorDiscrepancy) Resolve(resolution string, resolvedBy *uuid.UUID, now time.Time) {
+	d.Resolution = &resolution
+	d.ResolvedBy = resolvedBy
+	d.ResolvedAt = &now
+}
+
+// findOpenDiscrepancy loads the donor's open discrepancy of kind, or nil
+func findOpenDiscrepancy(tx *pop.Connection, donorID uuid.UUID, kind string) (*DonorDiscrepancy, error) {
+	discrepancy := &DonorDiscrepancy{}
+	err := tx.Where("donor_id = ? AND kind = ? AND resolved_at IS NULL", donorID, kind).First(discrepancy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return discrepancy, nil
+}
+
+// RecordDonorDiscrepancy opens a discrepancy for the donor, or brings the
+// values on their open one of the same kind up to date. It reports whether
+// the discrepancy is new.
+func RecordDonorDiscrepancy(tx *pop.Connection, donorID uuid.UUID, kind, localValue, helcimValue string) (bool, error) {
+	discrepancy, err := findOpenDiscrepancy(tx, donorID, kind)
+	if err != nil {
+		return false, err
+	}
+	if discrepancy == nil {
+		discrepancy = &DonorDiscrepancy{DonorID: donorID, Kind: kind, LocalValue: localValue, HelcimValue: helcimValue}
+		return true, errors.WithStack(tx.Create(discrepancy))
+	}
+	if discrepancy.LocalValue == localValue && discrepancy.HelcimValue == helcimValue {
+		return false, nil
+	}
+	discrepancy.LocalValue = localValue
+	discrepancy.HelcimValue = helcimValue
+	return false, errors.WithStack(tx.Update(discrepancy))
+}
+
+// ClearDonorDiscrepancy closes the donor's open discrepancy of kind, if
+// they have one, once the sync no longer finds it
+func ClearDonorDiscrepancy(tx *pop.Connection, donorID uuid.UUID, kind string, now time.Time) error {
+	discrepancy, err := findOpenDiscrepancy(tx, donorID, kind)
+	if err != nil || discrepancy == nil {
+		return err
+	}
+	discrepancy.Resolve(ResolutionCleared, nil, now)
+	return errors.WithStack(tx.Update(discrepancy))
+}
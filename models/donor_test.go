@@ -0,0 +1,123 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDonorNote_Validate(t *testing.T) {
+	verrs, err := (&DonorNote{}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("donor_email"))
+	assert.NotEmpty(t, verrs.Get("body"))
+
+	verrs, _ = (&DonorNote{DonorEmail: "sam@example.com", Body: strings.Repeat("x", 5001)}).Validate(nil)
+	assert.NotEmpty(t, verrs.Get("body"))
+
+	verrs, _ = (&DonorNote{DonorEmail: "sam@example.com", Body: "Prefers email"}).Validate(nil)
+	assert.False(t, verrs.HasAny())
+	assert.Equal(t, "sam@example.com", NormalizeDonorEmail("  Sam@Example.COM "))
+}
+
+func TestDonorFlag_Validate(t *testing.T) {
+	verrs, err := (&DonorFlag{DonorEmail: "sam@example.com", Flag: "vip"}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("flag"))
+
+	flag := DonorFlag{DonorEmail: "sam@example.com", Flag: FlagChargebackRisk}
+	verrs, _ = flag.Validate(nil)
+	assert.False(t, verrs.HasAny())
+	assert.Equal(t, "Chargeback risk", flag.Label())
+	assert.Equal(t, "orange", flag.Color())
+}
+
+func TestFilterDoNotSolicit(t *testing.T) {
+	flags := DonorFlags{
+		{DonorEmail: "pat@example.com", Flag: FlagDoNotSolicit},
+		{DonorEmail: "sam@example.com", Flag: FlagBoardMember},
+	}
+	assert.True(t, flags.Has(FlagBoardMember))
+	assert.False(t, flags.Has(FlagMajorDonor))
+
+	kept := FilterDoNotSolicit([]string{"Pat@Example.com", "sam@example.com", "lee@example.com"}, flags)
+	assert.Equal(t, []string{"sam@example.com", "lee@example.com"}, kept)
+}
+
+func TestTallyDonorGiving(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	subscription := "sub_1"
+	first := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	monthly := time.Date(2026, 8, 1, 9, 0, 0, 0, time.UTC)
+	giving := TallyDonorGiving([]Donation{
+		{Amount: 100, Status: "completed", CreatedAt: first},
+		{Amount: 25, Status: "active", SubscriptionID: &subscription, CreatedAt: monthly},
+		{Amount: 500, Status: "failed", CreatedAt: now},
+	}, now)
+
+	assert.Equal(t, 175.0, giving.Lifetime)
+	assert.Equal(t, 2, giving.Gifts)
+	assert.Equal(t, first, *giving.FirstGift)
+	assert.Equal(t, monthly, *giving.LastGift)
+	assert.True(t, giving.Recurring)
+
+	assert.Equal(t, DonorGiving{}, TallyDonorGiving(nil, now))
+}
+
+func TestDonorAddress(t *testing.T) {
+	donor := &Donor{}
+	assert.True(t, donor.Address().IsZero())
+
+	donor.SetAddress(DonorAddress{Line1: "1 Main St ", City: "Austin", State: "TX", Zip: "78701"})
+	assert.Nil(t, donor.AddressLine2)
+	assert.Equal(t, "1 Main St, Austin, TX 78701", donor.Address().String())
+	assert.True(t, donor.Address().Matches(DonorAddress{Line1: "1  MAIN st", City: "austin", State: "tx", Zip: "78701"}))
+	assert.False(t, donor.Address().Matches(DonorAddress{Line1: "9 Oak Ave", City: "Austin", State: "TX", Zip: "78701"}))
+}
+
+func TestDonor_FillFrom(t *testing.T) {
+	fallback := FallbackCustomerCodePrefix + "123_456"
+	donor := &Donor{Email: "sam@example.com"}
+	assert.Equal(t, "sam@example.com", donor.DisplayName())
+	assert.True(t, donor.fillFrom(&Donation{DonorName: "Sam Donor", CustomerID: &fallback}))
+	assert.Equal(t, "Sam Donor", donor.DisplayName())
+	assert.Empty(t, donor.CustomerCodeText())
+
+	code := "CST1001"
+	assert.True(t, donor.fillFrom(&Donation{DonorName: "Samuel", CustomerID: &code}))
+	assert.Equal(t, "Sam Donor", donor.Name)
+	assert.Equal(t, "CST1001", donor.CustomerCodeText())
+	assert.False(t, donor.fillFrom(&Donation{DonorName: "Samuel", CustomerID: &code}))
+
+	// The first address given is kept; later ones are for the Helcim sync
+	// to compare
+	street, city := "1 Main St", "Austin"
+	assert.True(t, donor.fillFrom(&Donation{AddressLine1: &street, City: &city}))
+	assert.Equal(t, "1 Main St, Austin", donor.Address().String())
+	other := "9 Oak Ave"
+	assert.False(t, donor.fillFrom(&Donation{AddressLine1: &other, City: &city}))
+}
+
+func (ms *ModelSuite) Test_LinkDonor() {
+	code := "CST2002"
+	first := &Donation{DonorName: "Pat Giver", DonorEmail: "Pat@Example.com", Amount: 50, Currency: "USD", DonationType: "one-time", Status: "completed"}
+	ms.NoError(ms.DB.Create(first))
+	ms.NotNil(first.DonorID)
+
+	// A later gift under a new email is matched on the Helcim customer, and
+	// teaches the donor record its customer code
+	second := &Donation{DonorName: "Pat Giver", DonorEmail: "pat@example.com", CustomerID: &code, Amount: 25, Currency: "USD", DonationType: "monthly", Status: "active"}
+	ms.NoError(ms.DB.Create(second))
+	third := &Donation{DonorName: "Pat Giver", DonorEmail: "pat.giver@example.org", CustomerID: &code, Amount: 10, Currency: "USD", DonationType: "one-time", Status: "completed"}
+	ms.NoError(ms.DB.Create(third))
+	ms.Equal(*first.DonorID, *second.DonorID)
+	ms.Equal(*first.DonorID, *third.DonorID)
+
+	donors := Donors{}
+	ms.NoError(ms.DB.All(&donors))
+	ms.Len(donors, 1)
+	ms.Equal("pat@example.com", donors[0].Email)
+	ms.Equal("CST2002", donors[0].CustomerCodeText())
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// The helpers in this file load the records a list of rows refers to in one
+// query, rather than one query per row. IDs may repeat; ones with no record
+// are left out of the result.
+
+// uuidArgs turns ids into query arguments for an IN (?) clause, without
+// repeats
+func uuidArgs(ids []uuid.UUID) []interface{} {
+	seen := map[uuid.UUID]bool{}
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		if id != uuid.Nil && !seen[id] {
+			seen[id] = true
+			args = append(args, id)
+		}
+	}
+	return args
+}
+
+// UsersByID loads the users with the given IDs
+func UsersByID(tx *pop.Connection, ids []uuid.UUID) (map[uuid.UUID]*User, error) {
+	byID := map[uuid.UUID]*User{}
+	args := uuidArgs(ids)
+	if len(args) == 0 {
+		return byID, nil
+	}
+	users := []User{}
+	if err := tx.Where("id IN (?)", args...).All(&users); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+	return byID, nil
+}
+
+// DonorsByID loads the donors with the given IDs
+func DonorsByID(tx *pop.Connection, ids []uuid.UUID) (map[uuid.UUID]*Donor, error) {
+	byID := map[uuid.UUID]*Donor{}
+	args := uuidArgs(ids)
+	if len(args) == 0 {
+		return byID, nil
+	}
+	donors := Donors{}
+	if err := tx.Where("id IN (?)", args...).All(&donors); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := range donors {
+		byID[donors[i].ID] = &donors[i]
+	}
+	return byID, nil
+}
+
+// DonationsByID loads the donations with the given IDs
+func DonationsByID(tx *pop.Connection, ids []uuid.UUID) (map[uuid.UUID]*Donation, error) {
+	byID := map[uuid.UUID]*Donation{}
+	args := uuidArgs(ids)
+	if len(args) == 0 {
+		return byID, nil
+	}
+	donations := Donations{}
+	if err := tx.Where("id IN (?)", args...).All(&donations); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := range donations {
+		byID[donations[i].ID] = &donations[i]
+	}
+	return byID, nil
+}
+
+// LoadPostAuthors sets User on each post, the way Eager("User") would for a
+// query that's already been run
+func LoadPostAuthors(tx *pop.Connection, posts []Post) error {
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.AuthorID
+	}
+	authors, err := UsersByID(tx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].User = authors[posts[i].AuthorID]
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import "github.com/gofrs/uuid"
+
+func (ms *ModelSuite) Test_LoadPostAuthors() {
+	author := &User{Email: "author@example.com", Password: "password", PasswordConfirmation: "password", FirstName: "Ada", LastName: "Writer"}
+	verrs, err := author.Create(ms.DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	for _, title := range []string{"First", "Second"} {
+		ms.NoError(ms.DB.Create(&Post{Title: title, Slug: title, Content: "Body", AuthorID: author.ID}))
+	}
+	posts := []Post{}
+	ms.NoError(ms.DB.All(&posts))
+	ms.Len(posts, 2)
+
+	ms.NoError(LoadPostAuthors(ms.DB, posts))
+	for _, post := range posts {
+		ms.NotNil(post.User)
+		ms.Equal("Ada", post.User.FirstName)
+	}
+}
+
+func (ms *ModelSuite) Test_UsersByID() {
+	user := &User{Email: "one@example.com", Password: "password", PasswordConfirmation: "password", FirstName: "One", LastName: "User"}
+	verrs, err := user.Create(ms.DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	missing := uuid.Must(uuid.NewV4())
+	users, err := UsersByID(ms.DB, []uuid.UUID{user.ID, user.ID, missing, uuid.Nil})
+	ms.NoError(err)
+	ms.Len(users, 1)
+	ms.Equal("one@example.com", users[user.ID].Email)
+	ms.Nil(users[missing])
+
+	none, err := UsersByID(ms.DB, nil)
+	ms.NoError(err)
+	ms.Empty(none)
+}
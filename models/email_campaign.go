@@ -0,0 +1,345 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Email campaign kinds
+const (
+	EmailCampaignAppeal      = "appeal"
+	EmailCampaignAnniversary = "anniversary"
+)
+
+// EmailCampaignKind is a kind of campaign with its display name
+type EmailCampaignKind struct {
+	Key   string
+	Label string
+}
+
+// EmailCampaignKinds lists the kinds of campaign in the order the admin form
+// shows them
+var EmailCampaignKinds = []EmailCampaignKind{
+	{Key: EmailCampaignAppeal, Label: "Appeal"},
+	{Key: EmailCampaignAnniversary, Label: "Giving anniversary"},
+}
+
+// Email campaign states. A campaign is tested on part of its audience, then
+// sent to the rest with the subject line that was opened most.
+const (
+	EmailCampaignDraft   = "draft"
+	EmailCampaignTesting = "testing"
+	EmailCampaignSent    = "sent"
+)
+
+// MaxEmailCampaignSubjects is how many subject lines a campaign can test
+const MaxEmailCampaignSubjects = 4
+
+// Defaults for a new campaign's test: a fifth of the audience, for a day
+const (
+	DefaultEmailCampaignTestPercent = 20
+	DefaultEmailCampaignTestHours   = 24
+)
+
+// EmailCampaign is an appeal or giving anniversary email sent to donors in
+// bulk. Its subject lines are tried on a test share of the audience, split
+// evenly, and once the test has run the one with the best open rate goes to
+// everyone else.
+type EmailCampaign struct {
+	ID   uuid.UUID `json:"id" db:"id"`
+	Name string    `json:"name" db:"name"`
+	Kind string    `json:"kind" db:"kind"`
+	// Subjects is the subject line variants, one per line
+	Subjects       string     `json:"subjects" db:"subjects"`
+	Body           string     `json:"body" db:"body"`
+	TestPercent    int        `json:"test_percent" db:"test_percent"`
+	TestHours      int        `json:"test_hours" db:"test_hours"`
+	Status         string     `json:"status" db:"status"`
+	WinningVariant *int       `json:"winning_variant,omitempty" db:"winning_variant"`
+	TestStartedAt  *time.Time `json:"test_started_at,omitempty" db:"test_started_at"`
+	SentAt         *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (c EmailCampaign) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// EmailCampaigns is not required by pop and may be deleted
+type EmailCampaigns []EmailCampaign
+
+// SubjectVariants returns the campaign's subject lines, skipping blank lines
+func (c EmailCampaign) SubjectVariants() []string {
+	var subjects []string
+	for _, line := range strings.Split(c.Subjects, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	return subjects
+}
+
+// Subject is the subject line for a variant, or the first one if there's
+// no such variant
+func (c EmailCampaign) Subject(variant int) string {
+	subjects := c.SubjectVariants()
+	if len(subjects) == 0 {
+		return ""
+	}
+	if variant < 0 || variant >= len(subjects) {
+		variant = 0
+	}
+	return subjects[variant]
+}
+
+// VariantLabel names a variant by letter: A for the first subject line, B
+// for the second and so on
+func (c EmailCampaign) VariantLabel(variant int) string {
+	return string(rune('A' + variant))
+}
+
+// KindLabel is the display name for the campaign's kind
+func (c EmailCampaign) KindLabel() string {
+	for _, k := range EmailCampaignKinds {
+		if k.Key == c.Kind {
+			return k.Label
+		}
+	}
+	return c.Kind
+}
+
+// StatusLabel describes where the campaign is for admins
+func (c EmailCampaign) StatusLabel() string {
+	switch c.Status {
+	case EmailCampaignTesting:
+		return "Testing subject lines"
+	case EmailCampaignSent:
+		return "Sent"
+	}
+	return "Draft"
+}
+
+// Editable reports whether the campaign can still be changed: only drafts
+// can, since a test compares subject lines sent with the same body
+func (c EmailCampaign) Editable() bool {
+	return c.Status == EmailCampaignDraft
+}
+
+// TestEndsAt is when the subject line test finishes, or the zero time if it
+// hasn't started
+func (c EmailCampaign) TestEndsAt() time.Time {
+	if c.TestStartedAt == nil {
+		return time.Time{}
+	}
+	return c.TestStartedAt.Add(time.Duration(c.TestHours) * time.Hour)
+}
+
+// TestEndsLabel says when the subject line test finishes, for admin pages
+func (c EmailCampaign) TestEndsLabel() string {
+	return c.TestEndsAt().Format("Jan 2, 2006 3:04 PM")
+}
+
+// WinnerDue reports whether the test has run its course and a winner should
+// be picked
+func (c EmailCampaign) WinnerDue(now time.Time) bool {
+	return c.Status == EmailCampaignTesting && !now.Before(c.TestEndsAt())
+}
+
+// WinnerSubject is the subject line that won the test, or "" before the
+// test has finished
+func (c EmailCampaign) WinnerSubject() string {
+	if c.WinningVariant == nil {
+		return ""
+	}
+	return c.Subject(*c.WinningVariant)
+}
+
+// TestGroupSize is how many of an audience of the given size get the test:
+// TestPercent of them rounded up, but at least one per subject line so
+// every variant is tried
+func (c EmailCampaign) TestGroupSize(audience int) int {
+	size := int(math.Ceil(float64(audience) * float64(c.TestPercent) / 100))
+	if variants := len(c.SubjectVariants()); size < variants {
+		size = variants
+	}
+	if size > audience {
+		size = audience
+	}
+	return size
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (c *EmailCampaign) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	subjects := len(c.SubjectVariants())
+	return validate.Validate(
+		&validators.StringIsPresent{Field: c.Name, Name: "Name"},
+		&validators.FuncValidator{
+			Field:   c.Kind,
+			Name:    "Kind",
+			Message: "%q is not a kind of email campaign",
+			Fn: func() bool {
+				return slices.ContainsFunc(EmailCampaignKinds, func(k EmailCampaignKind) bool { return k.Key == c.Kind })
+			},
+		},
+		&validators.StringIsPresent{Field: c.Body, Name: "Body"},
+		&validators.FuncValidator{
+			Field:   strconv.Itoa(subjects),
+			Name:    "Subjects",
+			Message: "Enter two to four subject lines to test, one per line (%s given)",
+			Fn: func() bool {
+				return subjects >= 2 && subjects <= MaxEmailCampaignSubjects
+			},
+		},
+		&validators.FuncValidator{
+			Field:   strconv.Itoa(c.TestPercent),
+			Name:    "TestPercent",
+			Message: "The test share must be between 1 and 100 percent (%s)",
+			Fn: func() bool {
+				return c.TestPercent >= 1 && c.TestPercent <= 100
+			},
+		},
+		&validators.FuncValidator{
+			Field:   strconv.Itoa(c.TestHours),
+			Name:    "TestHours",
+			Message: "The test must run between 1 and 168 hours (%s)",
+			Fn: func() bool {
+				return c.TestHours >= 1 && c.TestHours <= 168
+			},
+		},
+	), nil
+}
+
+// EmailCampaignSend is one donor's copy of a campaign: the subject line they
+// were sent, and when the mail provider reported it opened
+type EmailCampaignSend struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	CampaignID  uuid.UUID  `json:"campaign_id" db:"campaign_id"`
+	DonorEmail  string     `json:"donor_email" db:"donor_email"`
+	DonorName   string     `json:"donor_name" db:"donor_name"`
+	YearsGiving int        `json:"years_giving" db:"years_giving"`
+	Variant     int        `json:"variant" db:"variant"`
+	InTest      bool       `json:"in_test" db:"in_test"`
+	SentAt      *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	OpenedAt    *time.Time `json:"opened_at,omitempty" db:"opened_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// EmailCampaignSends is not required by pop and may be deleted
+type EmailCampaignSends []EmailCampaignSend
+
+// EmailCampaignRecipient is a donor in a campaign's audience
+type EmailCampaignRecipient struct {
+	Email       string    `db:"email"`
+	Name        string    `db:"name"`
+	FirstGiftAt time.Time `db:"first_gift_at"`
+}
+
+// YearsGiving is how many years it's been since the donor's first gift, by
+// calendar year
+func (r EmailCampaignRecipient) YearsGiving(now time.Time) int {
+	return now.Year() - r.FirstGiftAt.Year()
+}
+
+// FindEmailCampaignAudience finds the donors a campaign goes to who haven't
+// been sent it yet. Appeals go to everyone who has given; anniversary emails
+// to donors whose first gift was in an earlier year, in the month of now.
+// Do-not-solicit flags and the suppression list are left to the caller.
+func FindEmailCampaignAudience(tx *pop.Connection, campaign *EmailCampaign, now time.Time) ([]EmailCampaignRecipient, error) {
+	having := ""
+	args := []interface{}{campaign.ID}
+	if campaign.Kind == EmailCampaignAnniversary {
+		having = " AND EXTRACT(MONTH FROM MIN(d.created_at)) = ? AND MIN(d.created_at) < ?"
+		args = append(args, int(now.Month()), time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location()))
+	}
+
+	recipients := []EmailCampaignRecipient{}
+	err := tx.RawQuery(`SELECT LOWER(TRIM(d.donor_email)) AS email,
+			MAX(d.donor_name) AS name,
+			MIN(d.created_at) AS first_gift_at
+		FROM donations d
+		WHERE d.status IN ('completed', 'active') AND TRIM(d.donor_email) <> ''
+		GROUP BY LOWER(TRIM(d.donor_email))
+		HAVING NOT EXISTS (SELECT 1 FROM email_campaign_sends s
+				WHERE s.campaign_id = ? AND s.donor_email = LOWER(TRIM(d.donor_email)))`+having+`
+		ORDER BY email`, args...).All(&recipients)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return recipients, nil
+}
+
+// RecordEmailCampaignOpen marks a send opened, keeping the first open, and
+// reports whether it was a send we know of that hadn't been opened yet
+func RecordEmailCampaignOpen(tx *pop.Connection, sendID uuid.UUID, at time.Time) (bool, error) {
+	count, err := tx.RawQuery(`UPDATE email_campaign_sends SET opened_at = ?, updated_at = ?
+		WHERE id = ? AND opened_at IS NULL`, at, at, sendID).ExecWithCount()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return count > 0, nil
+}
+
+// EmailCampaignVariantStats are how one subject line of a campaign has done
+type EmailCampaignVariantStats struct {
+	Variant int `db:"variant"`
+	Queued  int `db:"queued"`
+	Sent    int `db:"sent"`
+	Opened  int `db:"opened"`
+}
+
+// OpenRate is the percentage of sent emails the provider reported opened
+func (s EmailCampaignVariantStats) OpenRate() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return math.Round(float64(s.Opened)/float64(s.Sent)*1000) / 10
+}
+
+// LoadEmailCampaignStats totals a campaign's sends by subject line, either
+// for the test group or for the rest of the audience sent after it
+func LoadEmailCampaignStats(tx *pop.Connection, campaignID uuid.UUID, inTest bool) ([]EmailCampaignVariantStats, error) {
+	stats := []EmailCampaignVariantStats{}
+	err := tx.RawQuery(`SELECT variant,
+			COUNT(*) AS queued,
+			COUNT(sent_at) AS sent,
+			COUNT(opened_at) AS opened
+		FROM email_campaign_sends
+		WHERE campaign_id = ? AND in_test = ?
+		GROUP BY variant
+		ORDER BY variant`, campaignID, inTest).All(&stats)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return stats, nil
+}
+
+// PickSubjectWinner returns the variant with the highest open rate. A tie
+// goes to the earlier subject line, as does a test where nothing was sent.
+func PickSubjectWinner(stats []EmailCampaignVariantStats) int {
+	winner := 0
+	best := -1.0
+	for _, s := range stats {
+		if s.Sent == 0 {
+			continue
+		}
+		rate := float64(s.Opened) / float64(s.Sent)
+		if rate > best || (rate == best && s.Variant < winner) {
+			winner, best = s.Variant, rate
+		}
+	}
+	return winner
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailCampaign_Subjects(t *testing.T) {
+	campaign := EmailCampaign{Subjects: "Build a home this winter\n\n  Veterans need you  \n"}
+	assert.Equal(t, []string{"Build a home this winter", "Veterans need you"}, campaign.SubjectVariants())
+	assert.Equal(t, "Veterans need you", campaign.Subject(1))
+	assert.Equal(t, "Build a home this winter", campaign.Subject(5))
+	assert.Equal(t, "B", campaign.VariantLabel(1))
+	assert.Equal(t, "", campaign.WinnerSubject())
+
+	winner := 1
+	campaign.WinningVariant = &winner
+	assert.Equal(t, "Veterans need you", campaign.WinnerSubject())
+}
+
+func TestEmailCampaign_TestGroupSize(t *testing.T) {
+	campaign := EmailCampaign{Subjects: "A\nB\nC", TestPercent: 20}
+	assert.Equal(t, 20, campaign.TestGroupSize(100))
+	assert.Equal(t, 21, campaign.TestGroupSize(101))
+	// Every subject line is tried, however small the audience
+	assert.Equal(t, 3, campaign.TestGroupSize(5))
+	assert.Equal(t, 2, campaign.TestGroupSize(2))
+	assert.Equal(t, 0, campaign.TestGroupSize(0))
+}
+
+func TestEmailCampaign_WinnerDue(t *testing.T) {
+	started := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	campaign := EmailCampaign{Status: EmailCampaignTesting, TestHours: 24, TestStartedAt: &started}
+	assert.Equal(t, started.Add(24*time.Hour), campaign.TestEndsAt())
+	assert.False(t, campaign.WinnerDue(started.Add(23*time.Hour)))
+	assert.True(t, campaign.WinnerDue(started.Add(24*time.Hour)))
+
+	campaign.Status = EmailCampaignSent
+	assert.False(t, campaign.WinnerDue(started.Add(48*time.Hour)))
+}
+
+func TestEmailCampaign_Validate(t *testing.T) {
+	campaign := &EmailCampaign{Name: "Winter appeal", Kind: EmailCampaignAppeal, Subjects: "One\nTwo", Body: "Please give.", TestPercent: 20, TestHours: 24}
+	verrs, err := campaign.Validate(nil)
+	require.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+
+	campaign.Subjects = "Only one"
+	campaign.TestPercent = 0
+	campaign.Kind = "newsletter"
+	verrs, err = campaign.Validate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Enter two to four subject lines to test, one per line (1 given)", verrs.Get("subjects")[0])
+	assert.NotEmpty(t, verrs.Get("test_percent"))
+	assert.NotEmpty(t, verrs.Get("kind"))
+}
+
+func TestEmailCampaignVariantStats_OpenRate(t *testing.T) {
+	assert.Equal(t, 0.0, EmailCampaignVariantStats{}.OpenRate())
+	assert.Equal(t, 37.5, EmailCampaignVariantStats{Sent: 8, Opened: 3}.OpenRate())
+}
+
+func TestPickSubjectWinner(t *testing.T) {
+	assert.Equal(t, 1, PickSubjectWinner([]EmailCampaignVariantStats{
+		{Variant: 0, Sent: 50, Opened: 10},
+		{Variant: 1, Sent: 50, Opened: 16},
+		{Variant: 2, Sent: 50, Opened: 12},
+	}))
+	// A tie goes to the earlier subject line
+	assert.Equal(t, 0, PickSubjectWinner([]EmailCampaignVariantStats{
+		{Variant: 0, Sent: 40, Opened: 10},
+		{Variant: 1, Sent: 20, Opened: 5},
+	}))
+	// Rates compare, not counts
+	assert.Equal(t, 1, PickSubjectWinner([]EmailCampaignVariantStats{
+		{Variant: 0, Sent: 100, Opened: 20},
+		{Variant: 1, Sent: 10, Opened: 3},
+	}))
+	assert.Equal(t, 0, PickSubjectWinner(nil))
+	assert.Equal(t, 1, PickSubjectWinner([]EmailCampaignVariantStats{{Variant: 0}, {Variant: 1, Sent: 5}}))
+}
+
+func (ms *ModelSuite) Test_EmailCampaignAudience() {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	gift := func(email, status string, at time.Time) {
+		ms.NoError(ms.DB.Create(&Donation{DonorName: "Pat Giver", DonorEmail: email, Amount: 50, Currency: "USD", DonationType: DonationTypeOneTime, Status: status, CreatedAt: at}))
+	}
+	gift("pat@example.com", "completed", time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC))
+	gift("pat@example.com", "completed", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC))
+	gift("sam@example.com", "completed", time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC))
+	// First gave this October, so there's no anniversary yet
+	gift("lee@example.com", "completed", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	gift("max@example.com", "failed", time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC))
+
+	appeal := &EmailCampaign{Name: "Winter appeal", Kind: EmailCampaignAppeal, Subjects: "A\nB", Body: "Please give.", TestPercent: 20, TestHours: 24, Status: EmailCampaignDraft}
+	ms.NoError(ms.DB.Create(appeal))
+	ms.NoError(ms.DB.Create(&EmailCampaignSend{CampaignID: appeal.ID, DonorEmail: "sam@example.com", InTest: true}))
+
+	recipients, err := FindEmailCampaignAudience(ms.DB, appeal, now)
+	ms.NoError(err)
+	ms.Len(recipients, 2)
+	ms.Equal("lee@example.com", recipients[0].Email)
+	ms.Equal("pat@example.com", recipients[1].Email)
+
+	anniversary := &EmailCampaign{Name: "Anniversaries", Kind: EmailCampaignAnniversary, Subjects: "A\nB", Body: "Thank you.", TestPercent: 20, TestHours: 24, Status: EmailCampaignDraft}
+	ms.NoError(ms.DB.Create(anniversary))
+	recipients, err = FindEmailCampaignAudience(ms.DB, anniversary, now)
+	ms.NoError(err)
+	ms.Len(recipients, 1)
+	ms.Equal("pat@example.com", recipients[0].Email)
+	ms.Equal(3, recipients[0].YearsGiving(now))
+
+	send := &EmailCampaignSend{CampaignID: anniversary.ID, DonorEmail: "pat@example.com", InTest: true, SentAt: &now}
+	ms.NoError(ms.DB.Create(send))
+	opened, err := RecordEmailCampaignOpen(ms.DB, send.ID, now)
+	ms.NoError(err)
+	ms.True(opened)
+	opened, err = RecordEmailCampaignOpen(ms.DB, send.ID, now.Add(time.Hour))
+	ms.NoError(err)
+	ms.False(opened)
+
+	stats, err := LoadEmailCampaignStats(ms.DB, anniversary.ID, true)
+	ms.NoError(err)
+	ms.Equal([]EmailCampaignVariantStats{{Variant: 0, Queued: 1, Sent: 1, Opened: 1}}, stats)
+}
@@ -0,0 +1,341 @@
+package models
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Kinds of event. Volunteer days are listed alongside events in the
+// calendar so volunteers can sign up for build days.
+const (
+	EventKindEvent        = "event"
+	EventKindVolunteerDay = "volunteer_day"
+)
+
+// EventKind is a kind of event with its display name
+type EventKind struct {
+	Key   string
+	Label string
+}
+
+// EventKinds lists the kinds of event in the order the admin form shows them
+var EventKinds = []EventKind{
+	{Key: EventKindEvent, Label: "Event"},
+	{Key: EventKindVolunteerDay, Label: "Volunteer day"},
+}
+
+// EventKindLabel is the display name for a kind of event
+func EventKindLabel(kind string) string {
+	for _, k := range EventKinds {
+		if k.Key == kind {
+			return k.Label
+		}
+	}
+	return kind
+}
+
+// DefaultEventLength is how long an event without an end time is shown as
+// lasting in calendars
+const DefaultEventLength = 2 * time.Hour
+
+// EventReminder is a reminder email ticket holders can be sent ahead of an
+// event
+type EventReminder struct {
+	Key    string
+	Label  string
+	Before time.Duration
+}
+
+// EventReminders lists the reminders an event can send, earliest first
+var EventReminders = []EventReminder{
+	{Key: "1w", Label: "1 week before", Before: 7 * 24 * time.Hour},
+	{Key: "1d", Label: "1 day before", Before: 24 * time.Hour},
+	{Key: "2h", Label: "2 hours before", Before: 2 * time.Hour},
+}
+
+// DefaultEventReminders is the reminder schedule new events start with
+const DefaultEventReminders = "1w,1d,2h"
+
+// FindEventReminder looks up a reminder by its key
+func FindEventReminder(key string) (EventReminder, bool) {
+	for _, r := range EventReminders {
+		if r.Key == key {
+			return r, true
+		}
+	}
+	return EventReminder{}, false
+}
+
+// Event is a ticketed event such as a fundraiser dinner or workshop, or a
+// volunteer day. Published events are listed on the site and in its
+// calendar feed.
+type Event struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	Title         string     `json:"title" db:"title"`
+	Kind          string     `json:"kind" db:"kind"`
+	Description   *string    `json:"description,omitempty" db:"description"`
+	Location      *string    `json:"location,omitempty" db:"location"`
+	StartsAt      time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	Capacity      int        `json:"capacity" db:"capacity"` // 0 means unlimited
+	Active        bool       `json:"active" db:"active"`
+	Published     bool       `json:"published" db:"published"`
+	GoogleEventID *string    `json:"-" db:"google_event_id"`
+	// Reminders is the comma-separated keys of the EventReminders sent to
+	// ticket holders, e.g. "1w,1d,2h"
+	Reminders       string    `json:"reminders" db:"reminders"`
+	ReminderMessage *string   `json:"reminder_message,omitempty" db:"reminder_message"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (e Event) String() string {
+	js, _ := json.Marshal(e)
+	return string(js)
+}
+
+// Events is not required by pop and may be deleted
+type Events []Event
+
+// DescriptionText is the event's description, if any
+func (e Event) DescriptionText() string {
+	if e.Description == nil {
+		return ""
+	}
+	return *e.Description
+}
+
+// LocationText is where the event is held, if given
+func (e Event) LocationText() string {
+	if e.Location == nil {
+		return ""
+	}
+	return *e.Location
+}
+
+// KindLabel is the display name of the event's kind
+func (e Event) KindLabel() string {
+	if e.Kind == "" {
+		return EventKindLabel(EventKindEvent)
+	}
+	return EventKindLabel(e.Kind)
+}
+
+// BeforeSave makes events saved without a kind ordinary events
+func (e *Event) BeforeSave(tx *pop.Connection) error {
+	if e.Kind == "" {
+		e.Kind = EventKindEvent
+	}
+	return nil
+}
+
+// EndTime is when the event ends, or DefaultEventLength after it starts if
+// no end was given
+func (e Event) EndTime() time.Time {
+	if e.EndsAt != nil {
+		return *e.EndsAt
+	}
+	return e.StartsAt.Add(DefaultEventLength)
+}
+
+// Listed reports whether the event is shown on the site and in the calendar
+// feed
+func (e Event) Listed() bool {
+	return e.Active && e.Published
+}
+
+// ReminderSchedule is the reminders the event sends, earliest first
+func (e Event) ReminderSchedule() []EventReminder {
+	keys := strings.Split(e.Reminders, ",")
+	schedule := []EventReminder{}
+	for _, r := range EventReminders {
+		if slices.Contains(keys, r.Key) {
+			schedule = append(schedule, r)
+		}
+	}
+	return schedule
+}
+
+// HasReminder reports whether the event sends the reminder with key
+func (e Event) HasReminder(key string) bool {
+	return slices.ContainsFunc(e.ReminderSchedule(), func(r EventReminder) bool { return r.Key == key })
+}
+
+// ReminderSummary lists how long before the event reminders go out, e.g.
+// "1 week, 1 day and 2 hours". It's "" if the event sends none.
+func (e Event) ReminderSummary() string {
+	labels := []string{}
+	for _, r := range e.ReminderSchedule() {
+		labels = append(labels, strings.TrimSuffix(r.Label, " before"))
+	}
+	switch len(labels) {
+	case 0:
+		return ""
+	case 1:
+		return labels[0]
+	}
+	return strings.Join(labels[:len(labels)-1], ", ") + " and " + labels[len(labels)-1]
+}
+
+// SetReminders sets the event's reminder schedule from reminder keys,
+// dropping any it doesn't know
+func (e *Event) SetReminders(keys []string) {
+	picked := []string{}
+	for _, r := range EventReminders {
+		if slices.Contains(keys, r.Key) {
+			picked = append(picked, r.Key)
+		}
+	}
+	e.Reminders = strings.Join(picked, ",")
+}
+
+// DueReminder is the reminder a ticket issued at issuedAt should have been
+// sent by now: the latest one in the schedule whose time has come. Reminders
+// that came due before the ticket was issued are skipped, since the holder
+// has just been sent their ticket, and so are ones a later reminder has
+// overtaken, so a missed run never sends two at once.
+func (e Event) DueReminder(issuedAt, now time.Time) (EventReminder, bool) {
+	if !e.Active || !now.Before(e.StartsAt) {
+		return EventReminder{}, false
+	}
+	schedule := e.ReminderSchedule()
+	for i := len(schedule) - 1; i >= 0; i-- {
+		sendAt := e.StartsAt.Add(-schedule[i].Before)
+		if sendAt.After(now) {
+			continue
+		}
+		if sendAt.Before(issuedAt) {
+			return EventReminder{}, false
+		}
+		return schedule[i], true
+	}
+	return EventReminder{}, false
+}
+
+// ReminderMessageFor is the event's reminder message written for a ticket
+// holder, with {name} and {event} filled in. It's "" if the event has no
+// message of its own.
+func (e Event) ReminderMessageFor(holderName string) string {
+	if e.ReminderMessage == nil {
+		return ""
+	}
+	return strings.NewReplacer("{name}", holderName, "{event}", e.Title).Replace(*e.ReminderMessage)
+}
+
+// GoogleCalendarURL is a link that opens the event in Google Calendar, ready
+// to add
+func (e Event) GoogleCalendarURL() string {
+	const layout = "20060102T150405Z"
+	q := url.Values{
+		"action":   {"TEMPLATE"},
+		"text":     {e.Title},
+		"dates":    {e.StartsAt.UTC().Format(layout) + "/" + e.EndTime().UTC().Format(layout)},
+		"details":  {e.DescriptionText()},
+		"location": {e.LocationText()},
+	}
+	return "https://calendar.google.com/calendar/render?" + q.Encode()
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (e *Event) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: e.Title, Name: "Title"},
+		&validators.FuncValidator{
+			Field:   e.Kind,
+			Name:    "Kind",
+			Message: "%s is not a kind of event",
+			Fn: func() bool {
+				return e.Kind == "" || slices.ContainsFunc(EventKinds, func(k EventKind) bool { return k.Key == e.Kind })
+			},
+		},
+		&validators.TimeIsPresent{Field: e.StartsAt, Name: "StartsAt"},
+		&validators.FuncValidator{
+			Field:   "End time",
+			Name:    "EndsAt",
+			Message: "%s must be after the start",
+			Fn: func() bool {
+				return e.EndsAt == nil || e.EndsAt.After(e.StartsAt)
+			},
+		},
+		&validators.IntIsGreaterThan{Field: e.Capacity, Name: "Capacity", Compared: -1},
+		&validators.FuncValidator{
+			Field:   e.Reminders,
+			Name:    "Reminders",
+			Message: "%s is not a reminder schedule",
+			Fn: func() bool {
+				return e.Reminders == "" || len(e.ReminderSchedule()) == len(strings.Split(e.Reminders, ","))
+			},
+		},
+	), nil
+}
+
+// EventTicket admits one person to an event. The token is printed in the
+// ticket's QR code and is scanned at the door.
+type EventTicket struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	EventID     uuid.UUID  `json:"event_id" db:"event_id"`
+	Token       string     `json:"token" db:"token"`
+	HolderName  string     `json:"holder_name" db:"holder_name"`
+	HolderEmail string     `json:"holder_email" db:"holder_email"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty" db:"checked_in_at"`
+	CheckedInBy *uuid.UUID `json:"checked_in_by,omitempty" db:"checked_in_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// EventTickets is not required by pop and may be deleted
+type EventTickets []EventTicket
+
+// CheckedIn reports whether the ticket holder has arrived
+func (t EventTicket) CheckedIn() bool {
+	return t.CheckedInAt != nil
+}
+
+// CheckIn marks the ticket as used by staff member by at the given time. It
+// returns false, leaving the original check-in alone, if the ticket was
+// already used.
+func (t *EventTicket) CheckIn(by uuid.UUID, at time.Time) bool {
+	if t.CheckedIn() {
+		return false
+	}
+	t.CheckedInAt = &at
+	t.CheckedInBy = &by
+	return true
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (t *EventTicket) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: t.EventID, Name: "EventID"},
+		&validators.StringIsPresent{Field: t.Token, Name: "Token"},
+		&validators.StringIsPresent{Field: t.HolderName, Name: "HolderName"},
+		&validators.EmailIsPresent{Field: t.HolderEmail, Name: "HolderEmail"},
+	), nil
+}
+
+// EventTicketReminder records that a reminder was queued for a ticket, so
+// it's only ever sent once
+type EventTicketReminder struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	TicketID  uuid.UUID `json:"ticket_id" db:"ticket_id"`
+	Reminder  string    `json:"reminder" db:"reminder"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EventTicketReminders is not required by pop and may be deleted
+type EventTicketReminders []EventTicketReminder
+
+// GenerateTicketToken returns a random URL-safe token for an event ticket.
+func GenerateTicketToken() (string, error) {
+	return randomURLToken(18)
+}
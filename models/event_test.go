@@ -0,0 +1,127 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventTicket_CheckIn(t *testing.T) {
+	staff, other := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	first := time.Date(2026, 11, 11, 18, 0, 0, 0, time.UTC)
+
+	ticket := EventTicket{}
+	assert.False(t, ticket.CheckedIn())
+	assert.True(t, ticket.CheckIn(staff, first))
+	assert.True(t, ticket.CheckedIn())
+
+	// A second scan keeps the original check-in
+	assert.False(t, ticket.CheckIn(other, first.Add(time.Hour)))
+	assert.Equal(t, first, *ticket.CheckedInAt)
+	assert.Equal(t, staff, *ticket.CheckedInBy)
+}
+
+func TestEvent_Validate(t *testing.T) {
+	verrs, err := (&Event{Capacity: -1}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("title"))
+	assert.NotEmpty(t, verrs.Get("starts_at"))
+	assert.NotEmpty(t, verrs.Get("capacity"))
+
+	verrs, err = (&Event{Title: "Veterans Day Dinner", StartsAt: time.Now()}).Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+}
+
+func TestEvent_EndTime(t *testing.T) {
+	startsAt := time.Date(2026, 11, 14, 9, 0, 0, 0, time.UTC)
+	event := Event{StartsAt: startsAt}
+	assert.Equal(t, startsAt.Add(DefaultEventLength), event.EndTime())
+
+	endsAt := startsAt.Add(6 * time.Hour)
+	event.EndsAt = &endsAt
+	assert.Equal(t, endsAt, event.EndTime())
+
+	verrs, err := (&Event{Title: "Build Day", StartsAt: startsAt, EndsAt: &startsAt}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("ends_at"))
+}
+
+func TestEvent_Listed(t *testing.T) {
+	assert.False(t, Event{Active: true}.Listed())
+	assert.False(t, Event{Published: true}.Listed())
+	assert.True(t, Event{Active: true, Published: true}.Listed())
+}
+
+func TestEvent_KindLabel(t *testing.T) {
+	assert.Equal(t, "Event", Event{}.KindLabel())
+	assert.Equal(t, "Volunteer day", Event{Kind: EventKindVolunteerDay}.KindLabel())
+
+	verrs, err := (&Event{Title: "Build Day", Kind: "party", StartsAt: time.Now()}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("kind"))
+}
+
+func TestEvent_GoogleCalendarURL(t *testing.T) {
+	event := Event{Title: "Build Day", StartsAt: time.Date(2026, 11, 14, 9, 0, 0, 0, time.UTC)}
+	link := event.GoogleCalendarURL()
+	assert.Contains(t, link, "action=TEMPLATE")
+	assert.Contains(t, link, "text=Build+Day")
+	assert.Contains(t, link, "dates=20261114T090000Z%2F20261114T110000Z")
+}
+
+func TestEvent_ReminderSchedule(t *testing.T) {
+	event := Event{}
+	event.SetReminders([]string{"2h", "bogus", "1w"})
+	assert.Equal(t, "1w,2h", event.Reminders)
+	assert.True(t, event.HasReminder("1w"))
+	assert.False(t, event.HasReminder("1d"))
+	assert.Equal(t, "1 week and 2 hours", event.ReminderSummary())
+
+	event.SetReminders(nil)
+	assert.Empty(t, event.ReminderSchedule())
+	assert.Equal(t, "", event.ReminderSummary())
+
+	verrs, err := (&Event{Title: "Build Day", StartsAt: time.Now(), Reminders: "1w,3d"}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("reminders"))
+}
+
+func TestEvent_DueReminder(t *testing.T) {
+	startsAt := time.Date(2026, 11, 11, 18, 0, 0, 0, time.UTC)
+	event := Event{Active: true, StartsAt: startsAt, Reminders: DefaultEventReminders}
+	issued := startsAt.AddDate(0, -1, 0)
+
+	_, ok := event.DueReminder(issued, startsAt.Add(-8*24*time.Hour))
+	assert.False(t, ok)
+
+	r, ok := event.DueReminder(issued, startsAt.Add(-6*24*time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, "1w", r.Key)
+
+	// A missed run only sends the latest reminder
+	r, ok = event.DueReminder(issued, startsAt.Add(-time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, "2h", r.Key)
+
+	// Tickets issued after a reminder came due wait for the next one
+	_, ok = event.DueReminder(startsAt.Add(-3*24*time.Hour), startsAt.Add(-2*24*time.Hour))
+	assert.False(t, ok)
+
+	_, ok = event.DueReminder(issued, startsAt)
+	assert.False(t, ok)
+
+	event.Active = false
+	_, ok = event.DueReminder(issued, startsAt.Add(-time.Hour))
+	assert.False(t, ok)
+}
+
+func TestEvent_ReminderMessageFor(t *testing.T) {
+	assert.Equal(t, "", Event{}.ReminderMessageFor("Jane"))
+
+	message := "Hi {name}, parking for {event} is in the north lot."
+	event := Event{Title: "Build Day", ReminderMessage: &message}
+	assert.Equal(t, "Hi Jane, parking for Build Day is in the north lot.", event.ReminderMessageFor("Jane"))
+}
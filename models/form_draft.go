@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// FormDraftMaxSize is the most a draft's answers may take up, a generous
+// allowance for the longest application typed out in full
+const FormDraftMaxSize = 64 << 10
+
+// FormDraftRetention is how long an untouched draft is kept before it's
+// pruned
+const FormDraftRetention = 30 * 24 * time.Hour
+
+// FormDraft is the answers so far on a long form, saved as they're typed so
+// someone whose connection drops or whose phone reloads the page can pick up
+// where they left off. Owner is the account ("user:<id>") or, for visitors
+// who aren't signed in, the session ("session:<key>") it was typed in.
+type FormDraft struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Form      string    `json:"form" db:"form"`
+	Owner     string    `json:"owner" db:"owner"`
+	Data      string    `json:"data" db:"data"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (d FormDraft) String() string {
+	jd, _ := json.Marshal(d)
+	return string(jd)
+}
+
+// FormDrafts is not required by pop and may be deleted
+type FormDrafts []FormDraft
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (d *FormDraft) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	verrs := validate.Validate(
+		&validators.StringIsPresent{Field: d.Form, Name: "Form"},
+		&validators.StringIsPresent{Field: d.Owner, Name: "Owner"},
+	)
+	if len(d.Data) > FormDraftMaxSize {
+		verrs.Add("data", fmt.Sprintf("Draft is over %d KB", FormDraftMaxSize>>10))
+	}
+	return verrs, nil
+}
+
+// Values is the draft's answers by field name
+func (d FormDraft) Values() map[string]string {
+	values := map[string]string{}
+	_ = json.Unmarshal([]byte(d.Data), &values)
+	return values
+}
+
+// FindFormDraft is owner's draft of form, or nil if they don't have one
+func FindFormDraft(tx *pop.Connection, form, owner string) (*FormDraft, error) {
+	draft := &FormDraft{}
+	err := tx.Where("form = ? AND owner = ?", form, owner).First(draft)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return draft, nil
+}
+
+// SaveFormDraft replaces owner's draft of form with values, returning the
+// validation errors if they're too large to keep
+func SaveFormDraft(tx *pop.Connection, form, owner string, values map[string]string) (*FormDraft, *validate.Errors, error) {
+	draft, err := FindFormDraft(tx, form, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+	if draft == nil {
+		draft = &FormDraft{Form: form, Owner: owner}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	draft.Data = string(data)
+	verrs, err := tx.ValidateAndSave(draft)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return draft, verrs, nil
+}
+
+// DeleteFormDraft discards owner's draft of form, once it's been submitted
+// or they've chosen to start over
+func DeleteFormDraft(tx *pop.Connection, form, owner string) error {
+	err := tx.RawQuery("DELETE FROM form_drafts WHERE form = ? AND owner = ?", form, owner).Exec()
+	return errors.WithStack(err)
+}
+
+// PruneFormDrafts deletes drafts nobody has touched since before, returning
+// how many there were
+func PruneFormDrafts(tx *pop.Connection, before time.Time) (int, error) {
+	count, err := tx.Where("updated_at < ?", before).Count(&FormDraft{})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if err := tx.RawQuery("DELETE FROM form_drafts WHERE updated_at < ?", before).Exec(); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return count, nil
+}
+
+// GenerateFormDraftKey returns a random key for a visitor's drafts, kept in
+// their session
+func GenerateFormDraftKey() (string, error) {
+	return randomURLToken(18)
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormDraft(t *testing.T) {
+	draft := FormDraft{Form: "mentoring", Owner: "user:u1", Data: `{"Bio":"Twenty years framing houses"}`}
+	assert.Equal(t, map[string]string{"Bio": "Twenty years framing houses"}, draft.Values())
+
+	verrs, err := draft.Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+
+	draft.Data = `{"Bio":"` + strings.Repeat("x", FormDraftMaxSize) + `"}`
+	verrs, _ = draft.Validate(nil)
+	assert.NotEmpty(t, verrs.Get("data"))
+
+	assert.Empty(t, FormDraft{Data: "not json"}.Values())
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Gift code statuses. A code is pending until the purchase is charged, then
+// active until it is redeemed or passes its expiry date.
+const (
+	GiftCodeStatusPending  = "pending"
+	GiftCodeStatusActive   = "active"
+	GiftCodeStatusRedeemed = "redeemed"
+	GiftCodeStatusExpired  = "expired"
+)
+
+// giftCodeAlphabet leaves out characters that are easy to misread on a card
+// (0/O, 1/I/L).
+const giftCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// GiftCode is a prepaid donation code. The purchaser's payment is the
+// donation; the recipient chooses which program it supports.
+type GiftCode struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	Code            string     `json:"code" db:"code"`
+	Amount          float64    `json:"amount" db:"amount"`
+	Currency        string     `json:"currency" db:"currency"`
+	DonationID      uuid.UUID  `json:"donation_id" db:"donation_id"`
+	PurchaserName   string     `json:"purchaser_name" db:"purchaser_name"`
+	PurchaserEmail  string     `json:"purchaser_email" db:"purchaser_email"`
+	RecipientName   *string    `json:"recipient_name,omitempty" db:"recipient_name"`
+	RecipientEmail  *string    `json:"recipient_email,omitempty" db:"recipient_email"`
+	Message         *string    `json:"message,omitempty" db:"message"`
+	Status          string     `json:"status" db:"status"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	RedeemedAt      *time.Time `json:"redeemed_at,omitempty" db:"redeemed_at"`
+	RedeemedByName  *string    `json:"redeemed_by_name,omitempty" db:"redeemed_by_name"`
+	RedeemedByEmail *string    `json:"redeemed_by_email,omitempty" db:"redeemed_by_email"`
+	Program         *string    `json:"program,omitempty" db:"program"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (g GiftCode) String() string {
+	jg, _ := json.Marshal(g)
+	return string(jg)
+}
+
+// GiftCodes is not required by pop and may be deleted
+type GiftCodes []GiftCode
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (g *GiftCode) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: g.Code, Name: "Code"},
+		&validators.UUIDIsPresent{Field: g.DonationID, Name: "DonationID"},
+		&validators.StringIsPresent{Field: g.PurchaserEmail, Name: "PurchaserEmail"},
+		&validators.StringInclusion{Field: g.Status, Name: "Status", List: []string{
+			GiftCodeStatusPending, GiftCodeStatusActive, GiftCodeStatusRedeemed, GiftCodeStatusExpired,
+		}},
+	), nil
+}
+
+// IsExpired reports whether an unredeemed code is past its expiry date.
+func (g *GiftCode) IsExpired(now time.Time) bool {
+	if g.Status == GiftCodeStatusExpired {
+		return true
+	}
+	return g.Status != GiftCodeStatusRedeemed && g.ExpiresAt != nil && now.After(*g.ExpiresAt)
+}
+
+// Redeemable reports whether the code can be redeemed now.
+func (g *GiftCode) Redeemable(now time.Time) bool {
+	return g.Status == GiftCodeStatusActive && !g.IsExpired(now)
+}
+
+// ProgramName is the program the code was redeemed for, if any.
+func (g GiftCode) ProgramName() string {
+	if g.Program == nil {
+		return ""
+	}
+	return *g.Program
+}
+
+// RecipientLabel is the recipient's name, falling back to their email.
+func (g GiftCode) RecipientLabel() string {
+	if g.RecipientName != nil && *g.RecipientName != "" {
+		return *g.RecipientName
+	}
+	if g.RecipientEmail != nil {
+		return *g.RecipientEmail
+	}
+	return ""
+}
+
+// GenerateGiftCode returns a random code formatted as AVR-XXXX-XXXX.
+func GenerateGiftCode() (string, error) {
+	size := big.NewInt(int64(len(giftCodeAlphabet)))
+	b := make([]byte, 8)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		b[i] = giftCodeAlphabet[n.Int64()]
+	}
+	return "AVR-" + string(b[:4]) + "-" + string(b[4:]), nil
+}
+
+// NormalizeGiftCode formats a code as typed by a recipient ("avr xxxx xxxx",
+// "XXXXXXXX") the way it is stored.
+func NormalizeGiftCode(input string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(input) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	s := b.String()
+	if len(s) == 11 && strings.HasPrefix(s, "AVR") {
+		s = s[3:]
+	}
+	if len(s) != 8 {
+		return ""
+	}
+	return "AVR-" + s[:4] + "-" + s[4:]
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateGiftCode(t *testing.T) {
+	code, err := GenerateGiftCode()
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^AVR-[A-HJKMNP-Z2-9]{4}-[A-HJKMNP-Z2-9]{4}$`), code)
+	assert.Equal(t, code, NormalizeGiftCode(code))
+}
+
+func TestNormalizeGiftCode(t *testing.T) {
+	assert.Equal(t, "AVR-ABCD-2345", NormalizeGiftCode("avr abcd 2345"))
+	assert.Equal(t, "AVR-ABCD-2345", NormalizeGiftCode("ABCD2345"))
+	assert.Equal(t, "AVR-AVRB-CDEF", NormalizeGiftCode("AVRBCDEF"))
+	assert.Equal(t, "", NormalizeGiftCode("AVR-ABC"))
+	assert.Equal(t, "", NormalizeGiftCode(""))
+}
+
+func TestGiftCode_Redeemable(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	assert.True(t, (&GiftCode{Status: GiftCodeStatusActive, ExpiresAt: &future}).Redeemable(now))
+	assert.True(t, (&GiftCode{Status: GiftCodeStatusActive}).Redeemable(now))
+	assert.False(t, (&GiftCode{Status: GiftCodeStatusActive, ExpiresAt: &past}).Redeemable(now))
+	assert.False(t, (&GiftCode{Status: GiftCodeStatusPending}).Redeemable(now))
+	assert.False(t, (&GiftCode{Status: GiftCodeStatusRedeemed}).Redeemable(now))
+
+	// A redeemed code doesn't become expired later
+	assert.False(t, (&GiftCode{Status: GiftCodeStatusRedeemed, ExpiresAt: &past}).IsExpired(now))
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// In-kind gift categories
+const (
+	InKindTools             = "tools"
+	InKindBuildingMaterials = "building_materials"
+	InKindVehicle           = "vehicle"
+	InKindEquipment         = "equipment"
+	InKindOther             = "other"
+)
+
+// InKindCategories lists the categories admins can log gifts under
+var InKindCategories = []string{InKindTools, InKindBuildingMaterials, InKindVehicle, InKindEquipment, InKindOther}
+
+var inKindCategoryLabels = map[string]string{
+	InKindTools:             "Tools",
+	InKindBuildingMaterials: "Building materials",
+	InKindVehicle:           "Vehicle",
+	InKindEquipment:         "Equipment",
+	InKindOther:             "Other",
+}
+
+// InKindCategoryLabel is the display name for an in-kind gift category
+func InKindCategoryLabel(category string) string {
+	if label, ok := inKindCategoryLabels[category]; ok {
+		return label
+	}
+	return category
+}
+
+// InKindGift is a non-cash gift of property, such as tools or building
+// materials. EstimatedValue is our own estimate for reporting; donors value
+// noncash gifts themselves, so acknowledgment letters don't state it.
+type InKindGift struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	DonorName      string     `json:"donor_name" db:"donor_name"`
+	DonorEmail     *string    `json:"donor_email,omitempty" db:"donor_email"`
+	UserID         *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	Category       string     `json:"category" db:"category"`
+	Description    string     `json:"description" db:"description"`
+	Quantity       int        `json:"quantity" db:"quantity"`
+	EstimatedValue float64    `json:"estimated_value" db:"estimated_value"`
+	ReceivedAt     time.Time  `json:"received_at" db:"received_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
+	Notes          *string    `json:"notes,omitempty" db:"notes"`
+	RecordedBy     *uuid.UUID `json:"recorded_by,omitempty" db:"recorded_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (g InKindGift) String() string {
+	js, _ := json.Marshal(g)
+	return string(js)
+}
+
+// InKindGifts is not required by pop and may be deleted
+type InKindGifts []InKindGift
+
+// CategoryLabel is the display name of the gift's category
+func (g InKindGift) CategoryLabel() string {
+	return InKindCategoryLabel(g.Category)
+}
+
+// DonorEmailText is the donor's email address, if we have one
+func (g InKindGift) DonorEmailText() string {
+	if g.DonorEmail == nil {
+		return ""
+	}
+	return *g.DonorEmail
+}
+
+// NotesText is the staff notes on the gift, if any
+func (g InKindGift) NotesText() string {
+	if g.Notes == nil {
+		return ""
+	}
+	return *g.Notes
+}
+
+// Acknowledged reports whether the donor has been sent their letter
+func (g InKindGift) Acknowledged() bool {
+	return g.AcknowledgedAt != nil
+}
+
+// TotalEstimatedValue adds up the estimated value of the gifts
+func (gifts InKindGifts) TotalEstimatedValue() float64 {
+	total := 0.0
+	for _, g := range gifts {
+		total += g.EstimatedValue
+	}
+	return total
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (g *InKindGift) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	checks := []validate.Validator{
+		&validators.StringIsPresent{Field: g.DonorName, Name: "DonorName"},
+		&validators.StringInclusion{Field: g.Category, Name: "Category", List: InKindCategories},
+		&validators.StringIsPresent{Field: g.Description, Name: "Description"},
+		&validators.IntIsGreaterThan{Field: g.Quantity, Name: "Quantity", Compared: 0},
+		&validators.TimeIsPresent{Field: g.ReceivedAt, Name: "ReceivedAt"},
+		&validators.FuncValidator{
+			Field:   g.Description,
+			Name:    "EstimatedValue",
+			Message: "Estimated value can't be negative (%s)",
+			Fn:      func() bool { return g.EstimatedValue >= 0 },
+		},
+	}
+	if g.DonorEmail != nil {
+		checks = append(checks, &validators.EmailIsPresent{Field: *g.DonorEmail, Name: "DonorEmail"})
+	}
+	return validate.Validate(checks...), nil
+}
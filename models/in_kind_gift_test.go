@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInKindGift_Validate(t *testing.T) {
+	verrs, err := (&InKindGift{Category: "furniture", EstimatedValue: -5}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("donor_name"))
+	assert.NotEmpty(t, verrs.Get("category"))
+	assert.NotEmpty(t, verrs.Get("description"))
+	assert.NotEmpty(t, verrs.Get("quantity"))
+	assert.NotEmpty(t, verrs.Get("received_at"))
+	assert.NotEmpty(t, verrs.Get("estimated_value"))
+
+	email := "not-an-email"
+	gift := &InKindGift{
+		DonorName:   "Hardware Co.",
+		DonorEmail:  &email,
+		Category:    InKindTools,
+		Description: "Cordless drill kits",
+		Quantity:    4,
+		ReceivedAt:  time.Now(),
+	}
+	verrs, err = gift.Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("donor_email"))
+
+	gift.DonorEmail = nil
+	verrs, err = gift.Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+}
+
+func TestInKindCategoryLabel(t *testing.T) {
+	assert.Equal(t, "Building materials", InKindCategoryLabel(InKindBuildingMaterials))
+	assert.Equal(t, "furniture", InKindCategoryLabel("furniture"))
+	assert.Equal(t, 350.0, InKindGifts{{EstimatedValue: 100}, {EstimatedValue: 250}}.TotalEstimatedValue())
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Job posting review states. Employers' postings wait for an admin to
+// approve them before they're listed.
+const (
+	JobPending  = "pending"
+	JobApproved = "approved"
+	JobRejected = "rejected"
+)
+
+// JobStatuses lists the review states in the order the admin board shows them
+var JobStatuses = []string{JobPending, JobApproved, JobRejected}
+
+var jobStatusLabels = map[string]string{
+	JobPending:  "Awaiting review",
+	JobApproved: "Approved",
+	JobRejected: "Rejected",
+}
+
+// JobStatusLabel is the display name for a job posting review state
+func JobStatusLabel(status string) string {
+	if label, ok := jobStatusLabels[status]; ok {
+		return label
+	}
+	return status
+}
+
+// How long a posting stays up: employers choose an expiry date up to
+// JobMaxDays out, and postings without one come down after JobDefaultDays
+const (
+	JobDefaultDays = 30
+	JobMaxDays     = 90
+)
+
+// JobPosting is a veteran-friendly job an employer submitted to the job
+// board. It's listed once approved and drops off the board when it expires.
+type JobPosting struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	PartnerID    *uuid.UUID `json:"partner_id,omitempty" db:"partner_id"`
+	EmployerName string     `json:"employer_name" db:"employer_name"`
+	ContactEmail string     `json:"contact_email" db:"contact_email"`
+	Title        string     `json:"title" db:"title"`
+	Location     string     `json:"location" db:"location"`
+	Remote       bool       `json:"remote" db:"remote"`
+	Description  string     `json:"description" db:"description"`
+	ApplyURL     string     `json:"apply_url" db:"apply_url"`
+	Status       string     `json:"status" db:"status"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (j JobPosting) String() string {
+	js, _ := json.Marshal(j)
+	return string(js)
+}
+
+// JobPostings is not required by pop and may be deleted
+type JobPostings []JobPosting
+
+// StatusLabel is the display name of the posting's review state
+func (j JobPosting) StatusLabel() string {
+	return JobStatusLabel(j.Status)
+}
+
+// LocationLabel is where the job is, noting when it can be done remotely
+func (j JobPosting) LocationLabel() string {
+	switch {
+	case j.Remote && j.Location == "":
+		return "Remote"
+	case j.Remote:
+		return j.Location + " (remote OK)"
+	default:
+		return j.Location
+	}
+}
+
+// FromPartner reports whether a corporate partner submitted the posting
+func (j JobPosting) FromPartner() bool {
+	return j.PartnerID != nil
+}
+
+// Live reports whether the posting is approved and not yet expired, which
+// is when it's shown on the board
+func (j JobPosting) Live(now time.Time) bool {
+	return j.Status == JobApproved && now.Before(j.ExpiresAt)
+}
+
+// Expired reports whether the posting's expiry date has passed
+func (j JobPosting) Expired(now time.Time) bool {
+	return !now.Before(j.ExpiresAt)
+}
+
+// LastDay is the last day the posting is listed. Postings expire at the
+// start of the day after the date the employer chose.
+func (j JobPosting) LastDay() time.Time {
+	return j.ExpiresAt.AddDate(0, 0, -1)
+}
+
+// ListedUntil is the posting's last day on the board, e.g. "November 13, 2026"
+func (j JobPosting) ListedUntil() string {
+	return j.LastDay().Format("January 2, 2006")
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (j *JobPosting) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	where := j.Location
+	if j.Remote {
+		where = "remote"
+	}
+	return validate.Validate(
+		&validators.StringIsPresent{Field: j.EmployerName, Name: "EmployerName", Message: "Employer is required"},
+		&validators.EmailIsPresent{Field: j.ContactEmail, Name: "ContactEmail", Message: "A valid contact email is required"},
+		&validators.StringIsPresent{Field: j.Title, Name: "Title", Message: "Job title is required"},
+		&validators.StringLengthInRange{Field: j.Title, Name: "Title", Max: 150, Message: "Job title must be 150 characters or less"},
+		&validators.StringIsPresent{Field: j.Description, Name: "Description", Message: "Description is required"},
+		&validators.StringLengthInRange{Field: j.Description, Name: "Description", Max: 5000, Message: "Description must be 5000 characters or less"},
+		&validators.StringIsPresent{Field: where, Name: "Location", Message: "Give a location or mark the job remote"},
+		&validators.FuncValidator{
+			Field:   j.ApplyURL,
+			Name:    "ApplyURL",
+			Message: "%s is not a valid application link",
+			Fn: func() bool {
+				u, err := url.Parse(j.ApplyURL)
+				return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+			},
+		},
+		&validators.StringInclusion{Field: j.Status, Name: "Status", List: JobStatuses},
+		&validators.TimeIsPresent{Field: j.ExpiresAt, Name: "ExpiresAt", Message: "Expiry date is required"},
+	), nil
+}
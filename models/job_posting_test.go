@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobPosting_LocationLabel(t *testing.T) {
+	assert.Equal(t, "Austin, TX", JobPosting{Location: "Austin, TX"}.LocationLabel())
+	assert.Equal(t, "Austin, TX (remote OK)", JobPosting{Location: "Austin, TX", Remote: true}.LocationLabel())
+	assert.Equal(t, "Remote", JobPosting{Remote: true}.LocationLabel())
+}
+
+func TestJobPosting_Live(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	job := JobPosting{Status: JobApproved, ExpiresAt: now.Add(time.Hour)}
+	assert.True(t, job.Live(now))
+	assert.False(t, job.Expired(now))
+	assert.False(t, job.Live(now.Add(2*time.Hour)))
+	assert.True(t, job.Expired(now.Add(time.Hour)))
+
+	job.Status = JobPending
+	assert.False(t, job.Live(now))
+	assert.Equal(t, "Awaiting review", job.StatusLabel())
+	assert.False(t, job.FromPartner())
+	job.PartnerID = &uuid.Nil
+	assert.True(t, job.FromPartner())
+}
+
+func TestJobPosting_Validate(t *testing.T) {
+	job := &JobPosting{
+		EmployerName: "Acme Builders",
+		ContactEmail: "hr@acme.example",
+		Title:        "Site Foreman",
+		Location:     "Austin, TX",
+		Description:  "Lead a residential build crew.",
+		ApplyURL:     "https://acme.example/jobs/1",
+		Status:       JobPending,
+		ExpiresAt:    time.Now().AddDate(0, 0, 30),
+	}
+	verrs, err := job.Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny(), verrs.String())
+
+	job.Location, job.ApplyURL = "", "javascript:alert(1)"
+	verrs, _ = job.Validate(nil)
+	assert.Equal(t, []string{"Give a location or mark the job remote"}, verrs.Get("location"))
+	assert.NotEmpty(t, verrs.Get("apply_url"))
+
+	job.Remote, job.ApplyURL = true, "https://acme.example/jobs/1"
+	verrs, _ = job.Validate(nil)
+	assert.False(t, verrs.HasAny(), verrs.String())
+}
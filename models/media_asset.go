@@ -0,0 +1,129 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Kinds of media asset
+const (
+	MediaKindLogo     = "logo"
+	MediaKindPhoto    = "photo"
+	MediaKindDocument = "document"
+)
+
+// MediaKinds lists the kinds of asset, in the order the press kit shows them
+var MediaKinds = []string{MediaKindLogo, MediaKindPhoto, MediaKindDocument}
+
+var mediaKindLabels = map[string]string{
+	MediaKindLogo:     "Logos",
+	MediaKindPhoto:    "Photos",
+	MediaKindDocument: "Fact sheets & documents",
+}
+
+// MediaKindLabel is the heading for a kind of asset
+func MediaKindLabel(kind string) string {
+	if label, ok := mediaKindLabels[kind]; ok {
+		return label
+	}
+	return kind
+}
+
+// MediaAsset is a file in the media library, such as a logo or a photo from
+// a build site. Library files are public; those in the press kit are listed
+// for download on the press page.
+type MediaAsset struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Title       string     `json:"title" db:"title"`
+	Description *string    `json:"description,omitempty" db:"description"`
+	Kind        string     `json:"kind" db:"kind"`
+	Filename    string     `json:"filename" db:"filename"`
+	ContentType string     `json:"content_type" db:"content_type"`
+	Size        int64      `json:"size" db:"size"`
+	StorageKey  string     `json:"-" db:"storage_key"`
+	PressKit    bool       `json:"press_kit" db:"press_kit"`
+	ScanStatus  string     `json:"scan_status" db:"scan_status"`
+	ScanDetail  *string    `json:"scan_detail,omitempty" db:"scan_detail"`
+	ScannedAt   *time.Time `json:"scanned_at,omitempty" db:"scanned_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (m MediaAsset) String() string {
+	jm, _ := json.Marshal(m)
+	return string(jm)
+}
+
+// MediaAssets is not required by pop and may be deleted
+type MediaAssets []MediaAsset
+
+// OfKind is the assets of one kind
+func (m MediaAssets) OfKind(kind string) MediaAssets {
+	var out MediaAssets
+	for _, a := range m {
+		if a.Kind == kind {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// DescriptionText is the asset's description, or blank
+func (m MediaAsset) DescriptionText() string {
+	if m.Description == nil {
+		return ""
+	}
+	return *m.Description
+}
+
+// IsImage reports whether the asset can be shown inline as an image
+func (m MediaAsset) IsImage() bool {
+	switch m.ContentType {
+	case "image/jpeg", "image/png", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Released reports whether the file has passed its virus scan and can be
+// downloaded
+func (m MediaAsset) Released() bool {
+	return ScanReleased(m.ScanStatus)
+}
+
+// ScanDetailText is the signature the virus scan found, or why it failed
+func (m MediaAsset) ScanDetailText() string {
+	if m.ScanDetail == nil {
+		return ""
+	}
+	return *m.ScanDetail
+}
+
+// SizeLabel is the file size for display, e.g. "2.4 MB"
+func (m MediaAsset) SizeLabel() string {
+	switch {
+	case m.Size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(m.Size)/(1<<20))
+	case m.Size >= 1<<10:
+		return fmt.Sprintf("%d KB", m.Size>>10)
+	default:
+		return fmt.Sprintf("%d bytes", m.Size)
+	}
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (m *MediaAsset) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: m.Title, Name: "Title"},
+		&validators.StringInclusion{Field: m.Kind, Name: "Kind", List: MediaKinds},
+		&validators.StringIsPresent{Field: m.Filename, Name: "Filename"},
+		&validators.StringIsPresent{Field: m.StorageKey, Name: "StorageKey"},
+	), nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaAsset_SizeLabel(t *testing.T) {
+	assert.Equal(t, "512 bytes", MediaAsset{Size: 512}.SizeLabel())
+	assert.Equal(t, "48 KB", MediaAsset{Size: 48 << 10}.SizeLabel())
+	assert.Equal(t, "2.5 MB", MediaAsset{Size: 5 << 19}.SizeLabel())
+}
+
+func TestMediaAssets_OfKind(t *testing.T) {
+	assets := MediaAssets{{Title: "Logo", Kind: MediaKindLogo}, {Title: "Build", Kind: MediaKindPhoto}, {Title: "Mark", Kind: MediaKindLogo}}
+	logos := assets.OfKind(MediaKindLogo)
+	assert.Len(t, logos, 2)
+	assert.Equal(t, "Mark", logos[1].Title)
+	assert.Empty(t, assets.OfKind(MediaKindDocument))
+	assert.Equal(t, "Photos", MediaKindLabel(MediaKindPhoto))
+}
+
+func TestMediaAsset_Validate(t *testing.T) {
+	asset := &MediaAsset{Title: "Logo", Kind: MediaKindLogo, Filename: "logo.png", StorageKey: "media/x.png"}
+	verrs, err := asset.Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+	assert.False(t, asset.IsImage())
+
+	asset.ContentType = "image/png"
+	assert.True(t, asset.IsImage())
+
+	asset.Kind = "video"
+	verrs, _ = asset.Validate(nil)
+	assert.NotEmpty(t, verrs.Get("kind"))
+}
+
+func TestMediaAsset_Released(t *testing.T) {
+	assert.True(t, MediaAsset{ScanStatus: ScanClean}.Released())
+	assert.True(t, MediaAsset{ScanStatus: ScanNotScanned}.Released(), "files uploaded without a scanner aren't held back")
+	assert.False(t, MediaAsset{ScanStatus: ScanPending}.Released())
+	assert.False(t, MediaAsset{ScanStatus: ScanInfected}.Released())
+	assert.False(t, VehicleDonationPhoto{ScanStatus: ScanFailed}.Released())
+	assert.Equal(t, "Quarantined: infected", ScanStatusLabel(ScanInfected))
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Introduction states. A pair is introduced by email, and staff follow up
+// to note whether they're meeting until the mentoring wraps up.
+const (
+	IntroductionIntroduced = "introduced"
+	IntroductionMeeting    = "meeting"
+	IntroductionClosed     = "closed"
+)
+
+// IntroductionStatuses lists the introduction states in order
+var IntroductionStatuses = []string{IntroductionIntroduced, IntroductionMeeting, IntroductionClosed}
+
+var introductionStatusLabels = map[string]string{
+	IntroductionIntroduced: "Introduced",
+	IntroductionMeeting:    "Meeting",
+	IntroductionClosed:     "Closed",
+}
+
+// IntroductionStatusLabel is the display name for an introduction state
+func IntroductionStatusLabel(status string) string {
+	if label, ok := introductionStatusLabels[status]; ok {
+		return label
+	}
+	return status
+}
+
+// MentorIntroduction is a mentor and mentee an admin introduced to each
+// other, tracked until the mentoring ends
+type MentorIntroduction struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	MentorID     uuid.UUID  `json:"mentor_id" db:"mentor_id"`
+	MenteeID     uuid.UUID  `json:"mentee_id" db:"mentee_id"`
+	Status       string     `json:"status" db:"status"`
+	Notes        *string    `json:"notes,omitempty" db:"notes"`
+	IntroducedBy *uuid.UUID `json:"introduced_by,omitempty" db:"introduced_by"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty" db:"closed_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (m MentorIntroduction) String() string {
+	js, _ := json.Marshal(m)
+	return string(js)
+}
+
+// MentorIntroductions is not required by pop and may be deleted
+type MentorIntroductions []MentorIntroduction
+
+// StatusLabel is the display name of the introduction's state
+func (m MentorIntroduction) StatusLabel() string {
+	return IntroductionStatusLabel(m.Status)
+}
+
+// Open reports whether the pair are still being mentored
+func (m MentorIntroduction) Open() bool {
+	return m.Status != IntroductionClosed
+}
+
+// NotesText is staff's follow-up notes on the introduction
+func (m MentorIntroduction) NotesText() string {
+	if m.Notes == nil {
+		return ""
+	}
+	return *m.Notes
+}
+
+// SetStatus moves the introduction to status, noting when it was closed
+func (m *MentorIntroduction) SetStatus(status string, now time.Time) {
+	m.Status = status
+	if status == IntroductionClosed {
+		if m.ClosedAt == nil {
+			m.ClosedAt = &now
+		}
+		return
+	}
+	m.ClosedAt = nil
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (m *MentorIntroduction) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: m.MentorID, Name: "MentorID"},
+		&validators.UUIDIsPresent{Field: m.MenteeID, Name: "MenteeID"},
+		&validators.StringInclusion{Field: m.Status, Name: "Status", List: IntroductionStatuses},
+	), nil
+}
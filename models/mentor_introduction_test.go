@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMentorIntroduction_SetStatus(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	intro := &MentorIntroduction{Status: IntroductionIntroduced}
+	assert.True(t, intro.Open())
+	assert.Equal(t, "", intro.NotesText())
+
+	intro.SetStatus(IntroductionClosed, now)
+	assert.False(t, intro.Open())
+	assert.Equal(t, now, *intro.ClosedAt)
+	assert.Equal(t, "Closed", intro.StatusLabel())
+
+	// Saving a closed introduction again keeps when it closed
+	intro.SetStatus(IntroductionClosed, now.Add(time.Hour))
+	assert.Equal(t, now, *intro.ClosedAt)
+
+	intro.SetStatus(IntroductionMeeting, now)
+	assert.True(t, intro.Open())
+	assert.Nil(t, intro.ClosedAt)
+}
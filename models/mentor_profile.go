@@ -0,0 +1,245 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Mentoring roles: veterans established in a trade mentor those starting
+// out in one
+const (
+	MentorRoleMentor = "mentor"
+	MentorRoleMentee = "mentee"
+)
+
+// Mentor profile review states. Profiles wait for an admin to approve them
+// before they're listed or matched.
+const (
+	MentorPending  = "pending"
+	MentorApproved = "approved"
+	MentorRejected = "rejected"
+)
+
+// MentorStatuses lists the review states in the order the admin page shows
+// them
+var MentorStatuses = []string{MentorPending, MentorApproved, MentorRejected}
+
+var mentorStatusLabels = map[string]string{
+	MentorPending:  "Awaiting review",
+	MentorApproved: "Approved",
+	MentorRejected: "Rejected",
+}
+
+// MentorStatusLabel is the display name for a mentor profile review state
+func MentorStatusLabel(status string) string {
+	if label, ok := mentorStatusLabels[status]; ok {
+		return label
+	}
+	return status
+}
+
+// MentorTrades are the trades mentors and mentees choose from. A fixed list
+// keeps profiles in the same trade matching each other.
+var MentorTrades = []string{
+	"Carpentry",
+	"Electrical",
+	"Plumbing",
+	"HVAC",
+	"Welding",
+	"Masonry & Concrete",
+	"Roofing",
+	"Painting & Finishing",
+	"Construction Management",
+	"Other",
+}
+
+// MentorProfile is a veteran's listing in the mentoring program, as either
+// a mentor or a mentee. Each user has at most one.
+type MentorProfile struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	Role        string     `json:"role" db:"role"`
+	DisplayName string     `json:"display_name" db:"display_name"`
+	Trade       string     `json:"trade" db:"trade"`
+	Skills      string     `json:"skills" db:"skills"`
+	Region      string     `json:"region" db:"region"`
+	Bio         string     `json:"bio" db:"bio"`
+	Status      string     `json:"status" db:"status"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (m MentorProfile) String() string {
+	js, _ := json.Marshal(m)
+	return string(js)
+}
+
+// MentorProfiles is not required by pop and may be deleted
+type MentorProfiles []MentorProfile
+
+// StatusLabel is the display name of the profile's review state
+func (m MentorProfile) StatusLabel() string {
+	return MentorStatusLabel(m.Status)
+}
+
+// RoleLabel is "Mentor" or "Mentee"
+func (m MentorProfile) RoleLabel() string {
+	if m.Role == MentorRoleMentor {
+		return "Mentor"
+	}
+	return "Mentee"
+}
+
+// IsMentor reports whether the profile offers mentoring
+func (m MentorProfile) IsMentor() bool {
+	return m.Role == MentorRoleMentor
+}
+
+// SkillList is the profile's comma-separated skills, trimmed and without
+// repeats
+func (m MentorProfile) SkillList() []string {
+	skills := []string{}
+	seen := map[string]bool{}
+	for _, s := range strings.Split(m.Skills, ",") {
+		s = strings.TrimSpace(s)
+		key := strings.ToLower(s)
+		if s == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		skills = append(skills, s)
+	}
+	return skills
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (m *MentorProfile) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringInclusion{Field: m.Role, Name: "Role", List: []string{MentorRoleMentor, MentorRoleMentee}, Message: "Choose whether you'd like to mentor or be mentored"},
+		&validators.StringIsPresent{Field: m.DisplayName, Name: "DisplayName", Message: "Name is required"},
+		&validators.StringLengthInRange{Field: m.DisplayName, Name: "DisplayName", Max: 100, Message: "Name must be 100 characters or less"},
+		&validators.StringInclusion{Field: m.Trade, Name: "Trade", List: MentorTrades, Message: "Choose a trade"},
+		&validators.StringIsPresent{Field: m.Region, Name: "Region", Message: "Region is required"},
+		&validators.StringLengthInRange{Field: m.Skills, Name: "Skills", Max: 500, Message: "Skills must be 500 characters or less"},
+		&validators.StringLengthInRange{Field: m.Bio, Name: "Bio", Max: 2000, Message: "About you must be 2000 characters or less"},
+		&validators.StringInclusion{Field: m.Status, Name: "Status", List: MentorStatuses},
+	), nil
+}
+
+// Points a suggested pair scores for what they have in common
+const (
+	mentorMatchTrade  = 3
+	mentorMatchRegion = 2
+	mentorMatchSkill  = 1
+)
+
+// MentorMatch is a mentor suggested for a mentee, with what they have in
+// common
+type MentorMatch struct {
+	Mentor       MentorProfile
+	Mentee       MentorProfile
+	Score        int
+	SameTrade    bool
+	SameRegion   bool
+	SharedSkills []string
+}
+
+// Reasons describes what the pair have in common, e.g. "Same trade (Welding)"
+func (m MentorMatch) Reasons() string {
+	reasons := []string{}
+	if m.SameTrade {
+		reasons = append(reasons, "Same trade ("+m.Mentor.Trade+")")
+	}
+	if m.SameRegion {
+		reasons = append(reasons, "Same region ("+m.Mentor.Region+")")
+	}
+	if len(m.SharedSkills) > 0 {
+		reasons = append(reasons, "Shared skills: "+strings.Join(m.SharedSkills, ", "))
+	}
+	return strings.Join(reasons, " · ")
+}
+
+// MatchMentor scores how well mentor suits mentee: a shared trade counts
+// most, then a shared region, then each skill they have in common
+func MatchMentor(mentor, mentee MentorProfile) MentorMatch {
+	match := MentorMatch{
+		Mentor:     mentor,
+		Mentee:     mentee,
+		SameTrade:  mentor.Trade == mentee.Trade && mentor.Trade != "Other",
+		SameRegion: strings.EqualFold(strings.TrimSpace(mentor.Region), strings.TrimSpace(mentee.Region)),
+	}
+	mentorSkills := map[string]bool{}
+	for _, s := range mentor.SkillList() {
+		mentorSkills[strings.ToLower(s)] = true
+	}
+	for _, s := range mentee.SkillList() {
+		if mentorSkills[strings.ToLower(s)] {
+			match.SharedSkills = append(match.SharedSkills, s)
+		}
+	}
+
+	if match.SameTrade {
+		match.Score += mentorMatchTrade
+	}
+	if match.SameRegion {
+		match.Score += mentorMatchRegion
+	}
+	match.Score += mentorMatchSkill * len(match.SharedSkills)
+	return match
+}
+
+// SuggestMentorMatches suggests up to perMentee mentors for each mentee
+// who isn't already in an open introduction, best matches first. Pairs
+// that were introduced before, and pairs with nothing in common, aren't
+// suggested. Mentees who have waited longest come first among equal
+// matches.
+func SuggestMentorMatches(mentors, mentees MentorProfiles, introductions MentorIntroductions, perMentee int) []MentorMatch {
+	introduced := map[[2]uuid.UUID]bool{}
+	matched := map[uuid.UUID]bool{}
+	for _, intro := range introductions {
+		introduced[[2]uuid.UUID{intro.MentorID, intro.MenteeID}] = true
+		if intro.Open() {
+			matched[intro.MenteeID] = true
+		}
+	}
+
+	suggestions := []MentorMatch{}
+	for _, mentee := range mentees {
+		if matched[mentee.ID] {
+			continue
+		}
+		candidates := []MentorMatch{}
+		for _, mentor := range mentors {
+			if mentor.UserID == mentee.UserID || introduced[[2]uuid.UUID{mentor.ID, mentee.ID}] {
+				continue
+			}
+			if match := MatchMentor(mentor, mentee); match.Score > 0 {
+				candidates = append(candidates, match)
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Score > candidates[j].Score
+		})
+		if len(candidates) > perMentee {
+			candidates = candidates[:perMentee]
+		}
+		suggestions = append(suggestions, candidates...)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Mentee.CreatedAt.Before(suggestions[j].Mentee.CreatedAt)
+	})
+	return suggestions
+}
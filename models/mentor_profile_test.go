@@ -0,0 +1,97 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMentorProfile_SkillList(t *testing.T) {
+	p := MentorProfile{Skills: " Framing, blueprint reading,,framing , Estimating "}
+	assert.Equal(t, []string{"Framing", "blueprint reading", "Estimating"}, p.SkillList())
+	assert.Empty(t, MentorProfile{}.SkillList())
+}
+
+func TestMentorProfile_Labels(t *testing.T) {
+	p := MentorProfile{Role: MentorRoleMentor, Status: MentorPending}
+	assert.True(t, p.IsMentor())
+	assert.Equal(t, "Mentor", p.RoleLabel())
+	assert.Equal(t, "Awaiting review", p.StatusLabel())
+	assert.Equal(t, "Mentee", MentorProfile{Role: MentorRoleMentee}.RoleLabel())
+	assert.Equal(t, "unknown", MentorStatusLabel("unknown"))
+}
+
+func TestMentorProfile_Validate(t *testing.T) {
+	p := &MentorProfile{Role: MentorRoleMentee, DisplayName: "Sam Rivera", Trade: "Welding", Region: "Tulsa, OK", Status: MentorPending}
+	verrs, err := p.Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+
+	p.Role, p.Trade, p.Region = "observer", "Juggling", ""
+	verrs, _ = p.Validate(nil)
+	assert.NotEmpty(t, verrs.Get("role"))
+	assert.NotEmpty(t, verrs.Get("trade"))
+	assert.NotEmpty(t, verrs.Get("region"))
+}
+
+func TestMatchMentor(t *testing.T) {
+	mentor := MentorProfile{Trade: "Welding", Region: "Tulsa, OK", Skills: "TIG, Pipe welding, Blueprint reading"}
+	mentee := MentorProfile{Trade: "Welding", Region: " tulsa, ok", Skills: "tig, Blueprint Reading"}
+
+	match := MatchMentor(mentor, mentee)
+	assert.True(t, match.SameTrade)
+	assert.True(t, match.SameRegion)
+	assert.Equal(t, []string{"tig", "Blueprint Reading"}, match.SharedSkills)
+	assert.Equal(t, 7, match.Score)
+	assert.Equal(t, "Same trade (Welding) · Same region (Tulsa, OK) · Shared skills: tig, Blueprint Reading", match.Reasons())
+
+	// "Other" isn't a trade in common
+	match = MatchMentor(MentorProfile{Trade: "Other", Region: "Austin, TX"}, MentorProfile{Trade: "Other", Region: "Boise, ID"})
+	assert.False(t, match.SameTrade)
+	assert.Equal(t, 0, match.Score)
+}
+
+func TestSuggestMentorMatches(t *testing.T) {
+	newProfile := func(role, trade, region string, created time.Time) MentorProfile {
+		return MentorProfile{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4()), Role: role, Trade: trade, Region: region, CreatedAt: created}
+	}
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	welder := newProfile(MentorRoleMentor, "Welding", "Tulsa, OK", day)
+	localWelder := newProfile(MentorRoleMentor, "Welding", "Austin, TX", day)
+	electrician := newProfile(MentorRoleMentor, "Electrical", "Austin, TX", day)
+
+	waiting := newProfile(MentorRoleMentee, "Welding", "Austin, TX", day)
+	newer := newProfile(MentorRoleMentee, "Welding", "Austin, TX", day.Add(48*time.Hour))
+	paired := newProfile(MentorRoleMentee, "Electrical", "Austin, TX", day)
+	finished := newProfile(MentorRoleMentee, "Electrical", "Austin, TX", day)
+	// The same person can't mentor themselves
+	self := welder
+	self.ID, self.Role = uuid.Must(uuid.NewV4()), MentorRoleMentee
+
+	intros := MentorIntroductions{
+		{MentorID: electrician.ID, MenteeID: paired.ID, Status: IntroductionMeeting},
+		{MentorID: electrician.ID, MenteeID: finished.ID, Status: IntroductionClosed},
+	}
+	suggestions := SuggestMentorMatches(
+		MentorProfiles{welder, localWelder, electrician},
+		MentorProfiles{newer, waiting, paired, finished, self},
+		intros, 2,
+	)
+
+	type pair struct{ mentor, mentee uuid.UUID }
+	got := []pair{}
+	for _, s := range suggestions {
+		got = append(got, pair{s.Mentor.ID, s.Mentee.ID})
+	}
+	assert.Equal(t, []pair{
+		{localWelder.ID, waiting.ID},
+		{localWelder.ID, newer.ID},
+		{welder.ID, waiting.ID},
+		{localWelder.ID, self.ID},
+		{welder.ID, newer.ID},
+		{localWelder.ID, finished.ID},
+	}, got)
+}
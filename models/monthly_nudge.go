@@ -0,0 +1,147 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// MonthlyNudgeMinGifts is how many one-time gifts a donor makes within a
+// year before they're asked to become a monthly supporter
+const MonthlyNudgeMinGifts = 3
+
+// MonthlyNudgeCooldown is how long after a nudge before the same donor is
+// nudged again
+const MonthlyNudgeCooldown = 365 * 24 * time.Hour
+
+// MonthlyNudgeConversionWindow is how long after a nudge a new monthly gift
+// from the donor counts as a conversion
+const MonthlyNudgeConversionWindow = 60 * 24 * time.Hour
+
+// MonthlyNudge is an email asking a donor who keeps giving one-time gifts to
+// give monthly instead, kept to measure how many do
+type MonthlyNudge struct {
+	ID                  uuid.UUID  `json:"id" db:"id"`
+	DonorEmail          string     `json:"donor_email" db:"donor_email"`
+	GiftCount           int        `json:"gift_count" db:"gift_count"`
+	SuggestedAmount     float64    `json:"suggested_amount" db:"suggested_amount"`
+	SentAt              time.Time  `json:"sent_at" db:"sent_at"`
+	ConvertedAt         *time.Time `json:"converted_at,omitempty" db:"converted_at"`
+	ConvertedDonationID *uuid.UUID `json:"converted_donation_id,omitempty" db:"converted_donation_id"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (n MonthlyNudge) String() string {
+	jn, _ := json.Marshal(n)
+	return string(jn)
+}
+
+// MonthlyNudges is not required by pop and may be deleted
+type MonthlyNudges []MonthlyNudge
+
+// MonthlyNudgeCandidate is a donor whose one-time gifts over the past year
+// make them worth asking to give monthly
+type MonthlyNudgeCandidate struct {
+	Email string  `db:"email"`
+	Name  string  `db:"name"`
+	Gifts int     `db:"gifts"`
+	Total float64 `db:"total"`
+}
+
+// SuggestedMonthlyAmount is the monthly gift to pre-fill for the donor:
+// their past year's giving spread over twelve months, rounded up to the
+// next $5, and never less than $10
+func (c MonthlyNudgeCandidate) SuggestedMonthlyAmount() float64 {
+	return math.Max(10, math.Ceil(c.Total/12/5)*5)
+}
+
+// FindMonthlyNudgeCandidates finds donors with at least MonthlyNudgeMinGifts
+// completed one-time gifts in the year before now who don't already give
+// monthly and haven't been nudged within MonthlyNudgeCooldown
+func FindMonthlyNudgeCandidates(tx *pop.Connection, now time.Time) ([]MonthlyNudgeCandidate, error) {
+	candidates := []MonthlyNudgeCandidate{}
+	err := tx.RawQuery(`SELECT LOWER(TRIM(d.donor_email)) AS email,
+			MAX(d.donor_name) AS name,
+			COUNT(*) AS gifts,
+			SUM(d.amount) AS total
+		FROM donations d
+		WHERE d.donation_type = ? AND d.status = 'completed' AND d.created_at >= ?
+		GROUP BY LOWER(TRIM(d.donor_email))
+		HAVING COUNT(*) >= ?
+			AND NOT EXISTS (SELECT 1 FROM donations m
+				WHERE LOWER(TRIM(m.donor_email)) = LOWER(TRIM(d.donor_email))
+				AND m.donation_type = ? AND m.status = 'active')
+			AND NOT EXISTS (SELECT 1 FROM monthly_nudges n
+				WHERE n.donor_email = LOWER(TRIM(d.donor_email)) AND n.sent_at >= ?)
+		ORDER BY email`,
+		DonationTypeOneTime, now.AddDate(-1, 0, 0), MonthlyNudgeMinGifts,
+		DonationTypeMonthly, now.Add(-MonthlyNudgeCooldown)).All(&candidates)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return candidates, nil
+}
+
+// RecordMonthlyConversion credits a new monthly gift to the nudge its donor
+// was sent within MonthlyNudgeConversionWindow, if any, and reports whether
+// there was one
+func RecordMonthlyConversion(tx *pop.Connection, donation *Donation, now time.Time) (bool, error) {
+	if donation.DonationType != DonationTypeMonthly {
+		return false, nil
+	}
+	nudge := &MonthlyNudge{}
+	err := tx.Where("donor_email = ? AND converted_at IS NULL AND sent_at >= ?",
+		NormalizeDonorEmail(donation.DonorEmail), now.Add(-MonthlyNudgeConversionWindow)).
+		Order("sent_at desc").First(nudge)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, errors.WithStack(err)
+	}
+	nudge.ConvertedAt = &now
+	nudge.ConvertedDonationID = &donation.ID
+	if err := tx.UpdateColumns(nudge, "converted_at", "converted_donation_id", "updated_at"); err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// MonthlyNudgeStats are how monthly nudges have done
+type MonthlyNudgeStats struct {
+	Sent         int     `db:"sent"`
+	Converted    int     `db:"converted"`
+	AddedMonthly float64 `db:"added_monthly"`
+}
+
+// ConversionRate is the percentage of nudged donors who went on to give
+// monthly
+func (s MonthlyNudgeStats) ConversionRate() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return math.Round(float64(s.Converted)/float64(s.Sent)*1000) / 10
+}
+
+// LoadMonthlyNudgeStats totals the nudges sent since the given time and the
+// monthly gifts they brought in
+func LoadMonthlyNudgeStats(tx *pop.Connection, since time.Time) (MonthlyNudgeStats, error) {
+	stats := MonthlyNudgeStats{}
+	err := tx.RawQuery(`SELECT COUNT(*) AS sent,
+			COUNT(n.converted_at) AS converted,
+			COALESCE(SUM(d.amount), 0) AS added_monthly
+		FROM monthly_nudges n
+		LEFT JOIN donations d ON d.id = n.converted_donation_id
+		WHERE n.sent_at >= ?`, since).First(&stats)
+	if err != nil {
+		return stats, errors.WithStack(err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonthlyNudgeCandidate_SuggestedMonthlyAmount(t *testing.T) {
+	assert.Equal(t, 15.0, MonthlyNudgeCandidate{Gifts: 3, Total: 150}.SuggestedMonthlyAmount())
+	assert.Equal(t, 45.0, MonthlyNudgeCandidate{Gifts: 4, Total: 500}.SuggestedMonthlyAmount())
+	assert.Equal(t, 10.0, MonthlyNudgeCandidate{Gifts: 3, Total: 30}.SuggestedMonthlyAmount())
+}
+
+func TestMonthlyNudgeStats_ConversionRate(t *testing.T) {
+	assert.Equal(t, 0.0, MonthlyNudgeStats{}.ConversionRate())
+	assert.Equal(t, 12.5, MonthlyNudgeStats{Sent: 8, Converted: 1}.ConversionRate())
+}
+
+func (ms *ModelSuite) Test_MonthlyNudges() {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	gift := func(email string, donationType DonationType, status string, amount float64, at time.Time) *Donation {
+		donation := &Donation{DonorName: "Pat Giver", DonorEmail: email, Amount: amount, Currency: "USD", DonationType: donationType, Status: status, CreatedAt: at}
+		ms.NoError(ms.DB.Create(donation))
+		return donation
+	}
+	for _, months := range []int{1, 4, 8} {
+		gift("pat@example.com", DonationTypeOneTime, "completed", 50, now.AddDate(0, -months, 0))
+		// Already gives monthly, so isn't asked
+		gift("sam@example.com", DonationTypeOneTime, "completed", 50, now.AddDate(0, -months, 0))
+	}
+	gift("sam@example.com", DonationTypeMonthly, "active", 20, now.AddDate(0, -2, 0))
+	// Only two of these gifts fall in the past year
+	gift("lee@example.com", DonationTypeOneTime, "completed", 50, now.AddDate(0, -2, 0))
+	gift("lee@example.com", DonationTypeOneTime, "completed", 50, now.AddDate(0, -3, 0))
+	gift("lee@example.com", DonationTypeOneTime, "completed", 50, now.AddDate(-2, 0, 0))
+
+	candidates, err := FindMonthlyNudgeCandidates(ms.DB, now)
+	ms.NoError(err)
+	ms.Len(candidates, 1)
+	ms.Equal("pat@example.com", candidates[0].Email)
+	ms.Equal(3, candidates[0].Gifts)
+	ms.Equal(15.0, candidates[0].SuggestedMonthlyAmount())
+
+	ms.NoError(ms.DB.Create(&MonthlyNudge{DonorEmail: "pat@example.com", GiftCount: 3, SuggestedAmount: 15, SentAt: now}))
+	candidates, err = FindMonthlyNudgeCandidates(ms.DB, now.AddDate(0, 1, 0))
+	ms.NoError(err)
+	ms.Empty(candidates)
+
+	monthly := gift("Pat@Example.com", DonationTypeMonthly, "active", 15, now.AddDate(0, 0, 10))
+	converted, err := RecordMonthlyConversion(ms.DB, monthly, now.AddDate(0, 0, 10))
+	ms.NoError(err)
+	ms.True(converted)
+
+	stats, err := LoadMonthlyNudgeStats(ms.DB, now.AddDate(0, -1, 0))
+	ms.NoError(err)
+	ms.Equal(1, stats.Sent)
+	ms.Equal(1, stats.Converted)
+	ms.Equal(15.0, stats.AddedMonthly)
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// NewsletterSourceAPI marks subscribers added through the REST API
+const NewsletterSourceAPI = "api"
+
+// NewsletterSubscriber is someone on the newsletter mailing list
+type NewsletterSubscriber struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Email     string    `json:"email" db:"email"`
+	Name      *string   `json:"name,omitempty" db:"name"`
+	Source    string    `json:"source" db:"source"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (s NewsletterSubscriber) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// NewsletterSubscribers is not required by pop and may be deleted
+type NewsletterSubscribers []NewsletterSubscriber
+
+// NameText is the subscriber's name, or "" if not given
+func (s NewsletterSubscriber) NameText() string {
+	if s.Name == nil {
+		return ""
+	}
+	return *s.Name
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (s *NewsletterSubscriber) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.EmailIsPresent{Field: s.Email, Name: "Email", Message: "A valid email is required"},
+		&validators.StringIsPresent{Field: s.Source, Name: "Source"},
+	), nil
+}
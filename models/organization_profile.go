@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// OrganizationProfile is who we are, as shown on receipts, email footers,
+// the site footer and the structured data search engines read. There's one
+// profile, edited on the admin organization page; until it's first saved
+// DefaultOrganizationProfile is used.
+type OrganizationProfile struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	Name         string     `json:"name" db:"name"`
+	EIN          string     `json:"ein" db:"ein"`
+	AddressLine1 string     `json:"address_line1" db:"address_line1"`
+	AddressLine2 string     `json:"address_line2" db:"address_line2"`
+	City         string     `json:"city" db:"city"`
+	State        string     `json:"state" db:"state"`
+	Zip          string     `json:"zip" db:"zip"`
+	Phone        string     `json:"phone" db:"phone"`
+	Email        string     `json:"email" db:"email"`
+	FacebookURL  string     `json:"facebook_url" db:"facebook_url"`
+	XURL         string     `json:"x_url" db:"x_url"`
+	DiscordURL   string     `json:"discord_url" db:"discord_url"`
+	SpotifyURL   string     `json:"spotify_url" db:"spotify_url"`
+	UpdatedBy    *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (o OrganizationProfile) String() string {
+	jo, _ := json.Marshal(o)
+	return string(jo)
+}
+
+// DefaultOrganizationProfile is the profile before one has been saved. The
+// EIN and address come from ORGANIZATION_EIN and ORGANIZATION_ADDRESS,
+// which configured them before there was a profile.
+func DefaultOrganizationProfile() OrganizationProfile {
+	return OrganizationProfile{
+		Name:         "American Veterans Rebuilding",
+		EIN:          strings.TrimSpace(os.Getenv("ORGANIZATION_EIN")),
+		AddressLine1: strings.TrimSpace(os.Getenv("ORGANIZATION_ADDRESS")),
+		Email:        "AmericanVeteransRebuilding@avrnpo.org",
+		FacebookURL:  "https://www.facebook.com/AmericanVeteransRebuilding",
+		XURL:         "https://x.com/avrnpo",
+		DiscordURL:   "https://discord.com/invite/f6TzKart7J",
+		SpotifyURL:   "https://open.spotify.com/show/5BBarK2chVBVPCbqrILO73",
+	}
+}
+
+// LoadOrganizationProfile loads the saved profile, or the default when none
+// has been saved
+func LoadOrganizationProfile(tx *pop.Connection) (OrganizationProfile, error) {
+	profile := OrganizationProfile{}
+	err := tx.Order("created_at").First(&profile)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultOrganizationProfile(), nil
+	}
+	if err != nil {
+		return DefaultOrganizationProfile(), errors.WithStack(err)
+	}
+	return profile, nil
+}
+
+// AddressText is the mailing address on one line, e.g. "1 Main St, Austin,
+// TX 78701"
+func (o OrganizationProfile) AddressText() string {
+	return DonorAddress{Line1: o.AddressLine1, Line2: o.AddressLine2, City: o.City, State: o.State, Zip: o.Zip}.String()
+}
+
+// SocialLink is one of the organization's social media accounts
+type SocialLink struct {
+	Name string
+	URL  string
+	Icon string // the image under public/assets/images
+}
+
+// SocialLinks are the accounts that have been filled in, in the order the
+// site header shows them
+func (o OrganizationProfile) SocialLinks() []SocialLink {
+	links := []SocialLink{}
+	for _, link := range []SocialLink{
+		{Name: "Facebook", URL: o.FacebookURL, Icon: "images/Facebook.svg"},
+		{Name: "X", URL: o.XURL, Icon: "images/X.svg"},
+		{Name: "Discord", URL: o.DiscordURL, Icon: "images/Discord.svg"},
+		{Name: "Spotify", URL: o.SpotifyURL, Icon: "images/Spotify.svg"},
+	} {
+		if link.URL != "" {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// StructuredData is the schema.org NonProfitOrganization JSON-LD for the
+// site layout. siteURL is the site's address, e.g. "https://avrnpo.org".
+func (o OrganizationProfile) StructuredData(siteURL string) string {
+	address := map[string]string{"@type": "PostalAddress", "addressCountry": "US"}
+	for key, value := range map[string]string{
+		"streetAddress":   strings.TrimSpace(o.AddressLine1 + " " + o.AddressLine2),
+		"addressLocality": o.City,
+		"addressRegion":   o.State,
+		"postalCode":      o.Zip,
+	} {
+		if value != "" {
+			address[key] = value
+		}
+	}
+	data := map[string]interface{}{
+		"@context":     "https://schema.org",
+		"@type":        "NonProfitOrganization",
+		"name":         o.Name,
+		"description":  "Dedicated to the improvement of the American Veteran's Self, Family and Community through Technical Training, Occupational Licensing, Home Ownership Options and Professional Networking",
+		"url":          siteURL,
+		"logo":         siteURL + "/assets/images/logo.avif",
+		"foundingDate": "2021",
+		"address":      address,
+	}
+	if o.EIN != "" {
+		data["taxID"] = o.EIN
+	}
+	if o.Phone != "" {
+		data["telephone"] = o.Phone
+	}
+	if o.Email != "" {
+		data["email"] = o.Email
+	}
+	sameAs := []string{}
+	for _, link := range o.SocialLinks() {
+		sameAs = append(sameAs, link.URL)
+	}
+	if len(sameAs) > 0 {
+		data["sameAs"] = sameAs
+	}
+	// json.Marshal escapes <, > and &, so this is safe inside a script tag
+	js, _ := json.Marshal(data)
+	return string(js)
+}
+
+// einPattern is the IRS format for an EIN, e.g. 12-3456789
+var einPattern = regexp.MustCompile(`^\d{2}-\d{7}$`)
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (o *OrganizationProfile) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	checks := []validate.Validator{
+		&validators.StringIsPresent{Field: o.Name, Name: "Name", Message: "Name is required"},
+		&validators.StringLengthInRange{Field: o.Name, Name: "Name", Max: 150, Message: "Name must be 150 characters or less"},
+		&validators.FuncValidator{
+			Field:   o.EIN,
+			Name:    "EIN",
+			Message: "EIN %s should look like 12-3456789",
+			Fn:      func() bool { return o.EIN == "" || einPattern.MatchString(o.EIN) },
+		},
+		&validators.FuncValidator{
+			Field:   o.Email,
+			Name:    "Email",
+			Message: "%s is not a valid email address",
+			Fn:      func() bool { return o.Email == "" || strings.Contains(o.Email, "@") },
+		},
+	}
+	for name, link := range map[string]string{"FacebookURL": o.FacebookURL, "XURL": o.XURL, "DiscordURL": o.DiscordURL, "SpotifyURL": o.SpotifyURL} {
+		checks = append(checks, &validators.FuncValidator{
+			Field:   link,
+			Name:    name,
+			Message: "%s is not a valid link",
+			Fn: func() bool {
+				if link == "" {
+					return true
+				}
+				u, err := url.Parse(link)
+				return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+			},
+		})
+	}
+	return validate.Validate(checks...), nil
+}
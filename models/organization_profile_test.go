@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultOrganizationProfile(t *testing.T) {
+	t.Setenv("ORGANIZATION_EIN", " 12-3456789 ")
+	t.Setenv("ORGANIZATION_ADDRESS", "1 Main St, Austin, TX 78701")
+
+	profile := DefaultOrganizationProfile()
+	assert.Equal(t, "American Veterans Rebuilding", profile.Name)
+	assert.Equal(t, "12-3456789", profile.EIN)
+	assert.Equal(t, "1 Main St, Austin, TX 78701", profile.AddressText())
+	assert.Len(t, profile.SocialLinks(), 4)
+}
+
+func TestOrganizationProfile_AddressText(t *testing.T) {
+	profile := OrganizationProfile{AddressLine1: "1 Main St", AddressLine2: "Suite 2", City: "Austin", State: "TX", Zip: "78701"}
+	assert.Equal(t, "1 Main St, Suite 2, Austin, TX 78701", profile.AddressText())
+	assert.Equal(t, "", OrganizationProfile{}.AddressText())
+}
+
+func TestOrganizationProfile_SocialLinks(t *testing.T) {
+	profile := OrganizationProfile{XURL: "https://x.com/avrnpo", SpotifyURL: "https://open.spotify.com/show/abc"}
+	links := profile.SocialLinks()
+	require.Len(t, links, 2)
+	assert.Equal(t, "X", links[0].Name)
+	assert.Equal(t, "images/X.svg", links[0].Icon)
+	assert.Equal(t, "Spotify", links[1].Name)
+}
+
+func TestOrganizationProfile_StructuredData(t *testing.T) {
+	profile := OrganizationProfile{
+		Name:        "American Veterans Rebuilding",
+		EIN:         "12-3456789",
+		City:        "Austin",
+		State:       "TX",
+		Phone:       "512-555-0100",
+		FacebookURL: "https://www.facebook.com/AmericanVeteransRebuilding",
+	}
+	data := profile.StructuredData("https://avrnpo.org")
+	assert.Contains(t, data, `"@type":"NonProfitOrganization"`)
+	assert.Contains(t, data, `"taxID":"12-3456789"`)
+	assert.Contains(t, data, `"telephone":"512-555-0100"`)
+	assert.Contains(t, data, `"addressLocality":"Austin"`)
+	assert.Contains(t, data, `"sameAs":["https://www.facebook.com/AmericanVeteransRebuilding"]`)
+	assert.NotContains(t, data, `"email"`)
+
+	profile.Name = "</script><script>alert(1)</script>"
+	assert.NotContains(t, profile.StructuredData("https://avrnpo.org"), "</script>")
+}
+
+func TestOrganizationProfile_Validate(t *testing.T) {
+	profile := DefaultOrganizationProfile()
+	profile.EIN = "12-3456789"
+	verrs, err := profile.Validate(nil)
+	require.NoError(t, err)
+	assert.False(t, verrs.HasAny(), verrs.Error())
+
+	profile = OrganizationProfile{EIN: "123456789", Email: "nobody", DiscordURL: "javascript:alert(1)"}
+	verrs, err = profile.Validate(nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("name"))
+	assert.NotEmpty(t, verrs.Get("ein"))
+	assert.NotEmpty(t, verrs.Get("email"))
+	assert.NotEmpty(t, verrs.Get("discord_url"))
+	assert.Empty(t, verrs.Get("facebook_url"))
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Payment failure statuses
+const (
+	PaymentFailureOpen      = "open"
+	PaymentFailureRecovered = "recovered"
+	PaymentFailureCancelled = "cancelled"
+)
+
+// DunningRetryDelays are how long to wait after each failed charge before
+// retrying it. The donor is emailed after each failure, the last of them a
+// cancellation warning, and the subscription is cancelled when the final
+// retry fails too.
+var DunningRetryDelays = []time.Duration{
+	3 * 24 * time.Hour,
+	5 * 24 * time.Hour,
+	7 * 24 * time.Hour,
+}
+
+// PaymentFailure tracks a recurring donation's failed payment through
+// dunning: the retries, the notices sent to the donor, and whether the
+// payment was eventually recovered or the subscription cancelled
+type PaymentFailure struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	DonationID        uuid.UUID  `json:"donation_id" db:"donation_id"`
+	SubscriptionID    string     `json:"subscription_id" db:"subscription_id"`
+	Status            string     `json:"status" db:"status"`
+	Attempts          int        `json:"attempts" db:"attempts"`
+	NoticesSent       int        `json:"notices_sent" db:"notices_sent"`
+	LastTransactionID *string    `json:"last_transaction_id,omitempty" db:"last_transaction_id"`
+	LastError         *string    `json:"last_error,omitempty" db:"last_error"`
+	LastFailedAt      time.Time  `json:"last_failed_at" db:"last_failed_at"`
+	NextRetryAt       *time.Time `json:"next_retry_at,omitempty" db:"next_retry_at"`
+	ResolvedAt        *time.Time `json:"resolved_at,omitempty" db:"resolved_at"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (f PaymentFailure) String() string {
+	jf, _ := json.Marshal(f)
+	return string(jf)
+}
+
+// PaymentFailures is not required by pop and may be deleted
+type PaymentFailures []PaymentFailure
+
+// RecordAttempt counts another failed charge and schedules the next retry,
+// or none once the retries are used up
+func (f *PaymentFailure) RecordAttempt(transactionID, reason string, now time.Time) {
+	f.Attempts++
+	f.LastFailedAt = now
+	if transactionID != "" {
+		f.LastTransactionID = &transactionID
+	}
+	if reason != "" {
+		f.LastError = &reason
+	}
+	f.NextRetryAt = nil
+	if f.Attempts <= len(DunningRetryDelays) {
+		next := now.Add(DunningRetryDelays[f.Attempts-1])
+		f.NextRetryAt = &next
+	}
+}
+
+// Exhausted reports whether every retry has failed
+func (f *PaymentFailure) Exhausted() bool {
+	return f.Attempts > len(DunningRetryDelays)
+}
+
+// FinalNotice reports whether the latest failure leaves one retry before
+// the subscription is cancelled
+func (f *PaymentFailure) FinalNotice() bool {
+	return f.Attempts == len(DunningRetryDelays)
+}
+
+// NoticeDue reports whether the donor hasn't yet been told about the latest
+// failure. Webhook retries for the same failure don't send a second notice.
+func (f *PaymentFailure) NoticeDue() bool {
+	return !f.Exhausted() && f.NoticesSent < f.Attempts
+}
+
+// Resolve closes the failure with status
+func (f *PaymentFailure) Resolve(status string, now time.Time) {
+	f.Status = status
+	f.NextRetryAt = nil
+	f.ResolvedAt = &now
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (f *PaymentFailure) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: f.DonationID, Name: "DonationID"},
+		&validators.StringIsPresent{Field: f.SubscriptionID, Name: "SubscriptionID"},
+		&validators.StringInclusion{Field: f.Status, Name: "Status", List: []string{PaymentFailureOpen, PaymentFailureRecovered, PaymentFailureCancelled}},
+	), nil
+}
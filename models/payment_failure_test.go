@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentFailure_RecordAttempt(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	f := &PaymentFailure{Status: PaymentFailureOpen}
+
+	f.RecordAttempt("txn-1", "DECLINED", now)
+	assert.Equal(t, 1, f.Attempts)
+	assert.Equal(t, now.Add(DunningRetryDelays[0]), *f.NextRetryAt)
+	assert.Equal(t, "txn-1", *f.LastTransactionID)
+	assert.True(t, f.NoticeDue())
+	assert.False(t, f.FinalNotice())
+
+	f.NoticesSent = 1
+	assert.False(t, f.NoticeDue())
+
+	f.RecordAttempt("txn-2", "DECLINED", now)
+	f.RecordAttempt("txn-3", "DECLINED", now)
+	assert.True(t, f.FinalNotice())
+	assert.True(t, f.NoticeDue())
+	assert.Equal(t, now.Add(DunningRetryDelays[2]), *f.NextRetryAt)
+
+	f.RecordAttempt("txn-4", "DECLINED", now)
+	assert.True(t, f.Exhausted())
+	assert.False(t, f.NoticeDue())
+	assert.Nil(t, f.NextRetryAt)
+}
+
+func TestPaymentFailure_Validate(t *testing.T) {
+	verrs, err := (&PaymentFailure{Status: "stuck"}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("donation_id"))
+	assert.NotEmpty(t, verrs.Get("subscription_id"))
+	assert.NotEmpty(t, verrs.Get("status"))
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// PledgeInstallment is one payment toward an installment pledge
+type PledgeInstallment struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	DonationID    uuid.UUID `json:"donation_id" db:"donation_id"`
+	Sequence      int       `json:"sequence" db:"sequence"`
+	Amount        float64   `json:"amount" db:"amount"`
+	TransactionID *string   `json:"transaction_id,omitempty" db:"transaction_id"`
+	PaidAt        time.Time `json:"paid_at" db:"paid_at"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (p PledgeInstallment) String() string {
+	jp, _ := json.Marshal(p)
+	return string(jp)
+}
+
+// PledgeInstallments is not required by pop and may be deleted
+type PledgeInstallments []PledgeInstallment
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (p *PledgeInstallment) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: p.DonationID, Name: "DonationID"},
+		&validators.IntIsGreaterThan{Field: p.Sequence, Name: "Sequence", Compared: 0},
+	), nil
+}
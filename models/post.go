@@ -83,9 +83,35 @@ func (p *Post) GenerateSlug() {
 	}
 }
 
+// Publish puts the post live. Published is what decides whether a post is
+// shown; PublishedAt only records when it went live and follows Published.
+func (p *Post) Publish(now time.Time) {
+	p.Published = true
+	if p.PublishedAt == nil {
+		p.PublishedAt = &now
+	}
+}
+
+// Unpublish takes the post back to a draft
+func (p *Post) Unpublish() {
+	p.Published = false
+	p.PublishedAt = nil
+}
+
+// syncPublishedAt brings PublishedAt in line with Published before a save,
+// however Published was set
+func (p *Post) syncPublishedAt(now time.Time) {
+	if p.Published {
+		p.Publish(now)
+	} else {
+		p.Unpublish()
+	}
+}
+
 // BeforeCreate runs before creating a post
 func (p *Post) BeforeCreate(tx *pop.Connection) error {
 	p.GenerateSlug()
+	p.syncPublishedAt(time.Now())
 	return nil
 }
 
@@ -95,6 +121,7 @@ func (p *Post) BeforeUpdate(tx *pop.Connection) error {
 	if p.Slug == "" {
 		p.GenerateSlug()
 	}
+	p.syncPublishedAt(time.Now())
 	return nil
 }
 
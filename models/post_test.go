@@ -0,0 +1,38 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPost_Publish(t *testing.T) {
+	first := time.Date(2026, 9, 1, 9, 0, 0, 0, time.UTC)
+	post := &Post{}
+	post.Publish(first)
+	assert.True(t, post.Published)
+	assert.Equal(t, first, *post.PublishedAt)
+
+	// Publishing again keeps the original date
+	post.Publish(first.Add(time.Hour))
+	assert.Equal(t, first, *post.PublishedAt)
+
+	post.Unpublish()
+	assert.False(t, post.Published)
+	assert.Nil(t, post.PublishedAt)
+}
+
+func TestPost_SyncPublishedAt(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+
+	// Bound from the edit form's checkbox without a date
+	post := &Post{Published: true}
+	post.syncPublishedAt(now)
+	assert.Equal(t, now, *post.PublishedAt)
+
+	// Unticked, with the date it was first published still set
+	post.Published = false
+	post.syncPublishedAt(now)
+	assert.Nil(t, post.PublishedAt)
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Headline stats that can be published on the public stats API
+const (
+	StatRaisedThisYear = "raised_this_year"
+	StatDonorsThisYear = "donors_this_year"
+	StatHomesBuilt     = "homes_built"
+	StatVolunteers     = "volunteers"
+)
+
+// PublicStatDefinition describes a headline stat. Computed stats are totalled
+// from donations; the rest are figures staff keep up to date by hand.
+type PublicStatDefinition struct {
+	Key      string
+	Label    string
+	Computed bool
+	Money    bool
+}
+
+// PublicStatDefinitions lists the stats in the order they're published
+var PublicStatDefinitions = []PublicStatDefinition{
+	{Key: StatRaisedThisYear, Label: "Raised this year", Computed: true, Money: true},
+	{Key: StatDonorsThisYear, Label: "Donors this year", Computed: true},
+	{Key: StatHomesBuilt, Label: "Homes built"},
+	{Key: StatVolunteers, Label: "Volunteers"},
+}
+
+// FindPublicStatDefinition looks up a stat by key
+func FindPublicStatDefinition(key string) (PublicStatDefinition, bool) {
+	for _, def := range PublicStatDefinitions {
+		if def.Key == key {
+			return def, true
+		}
+	}
+	return PublicStatDefinition{}, false
+}
+
+// PublicStat is the admin setting for one headline stat: whether it's
+// published, and its value when it isn't computed
+type PublicStat struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Key       string     `json:"key" db:"key"`
+	Value     float64    `json:"value" db:"value"`
+	Public    bool       `json:"public" db:"public"`
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (s PublicStat) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// PublicStats is not required by pop and may be deleted
+type PublicStats []PublicStat
+
+// Definition is the stat's definition, with the key as its label when the
+// stat is no longer defined
+func (s PublicStat) Definition() PublicStatDefinition {
+	if def, ok := FindPublicStatDefinition(s.Key); ok {
+		return def
+	}
+	return PublicStatDefinition{Key: s.Key, Label: s.Key}
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (s *PublicStat) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.FuncValidator{
+			Field:   s.Key,
+			Name:    "Key",
+			Message: "%s is not a known stat",
+			Fn: func() bool {
+				_, ok := FindPublicStatDefinition(s.Key)
+				return ok
+			},
+		},
+		&validators.FuncValidator{
+			Field:   s.Key,
+			Name:    "Value",
+			Message: "%s can't be negative",
+			Fn:      func() bool { return s.Value >= 0 },
+		},
+	), nil
+}
+
+// LoadPublicStats returns the setting for every defined stat, in definition
+// order. Stats that have never been saved come back unpublished.
+func LoadPublicStats(tx *pop.Connection) (PublicStats, error) {
+	saved := PublicStats{}
+	if err := tx.All(&saved); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	byKey := map[string]PublicStat{}
+	for _, s := range saved {
+		byKey[s.Key] = s
+	}
+
+	stats := make(PublicStats, 0, len(PublicStatDefinitions))
+	for _, def := range PublicStatDefinitions {
+		stat, ok := byKey[def.Key]
+		if !ok {
+			stat = PublicStat{Key: def.Key}
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicStat_Validate(t *testing.T) {
+	verrs, err := (&PublicStat{Key: StatHomesBuilt, Value: 12}).Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+
+	verrs, _ = (&PublicStat{Key: "meals_served"}).Validate(nil)
+	assert.NotEmpty(t, verrs.Get("key"))
+
+	verrs, _ = (&PublicStat{Key: StatVolunteers, Value: -1}).Validate(nil)
+	assert.NotEmpty(t, verrs.Get("value"))
+}
+
+func TestPublicStat_Definition(t *testing.T) {
+	def := PublicStat{Key: StatRaisedThisYear}.Definition()
+	assert.True(t, def.Computed)
+	assert.True(t, def.Money)
+	assert.Equal(t, "Raised this year", def.Label)
+
+	assert.Equal(t, "retired_stat", PublicStat{Key: "retired_stat"}.Definition().Label)
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// ResourceClick is one visit to a resource library link, kept to see which
+// resources veterans actually use
+type ResourceClick struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	ResourceID uuid.UUID `json:"resource_id" db:"resource_id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
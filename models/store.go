@@ -0,0 +1,178 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Store order payment statuses
+const (
+	OrderStatusPending = "pending"
+	OrderStatusPaid    = "paid"
+)
+
+// Fulfillment statuses, in the order volunteers move an order through them
+const (
+	FulfillmentUnfulfilled = "unfulfilled"
+	FulfillmentPacked      = "packed"
+	FulfillmentShipped     = "shipped"
+)
+
+// FulfillmentStatuses lists the fulfillment statuses admins can choose from
+var FulfillmentStatuses = []string{FulfillmentUnfulfilled, FulfillmentPacked, FulfillmentShipped}
+
+// Product is a piece of merchandise sold in the store
+type Product struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	Price       float64   `json:"price" db:"price"`
+	Inventory   int       `json:"inventory" db:"inventory"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (p Product) String() string {
+	js, _ := json.Marshal(p)
+	return string(js)
+}
+
+// Products is not required by pop and may be deleted
+type Products []Product
+
+// DescriptionText is the product's description, if any
+func (p Product) DescriptionText() string {
+	if p.Description == nil {
+		return ""
+	}
+	return *p.Description
+}
+
+// InStock reports whether the product is on sale and has stock left
+func (p Product) InStock() bool {
+	return p.Active && p.Inventory > 0
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (p *Product) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: p.Name, Name: "Name"},
+		&validators.IntIsGreaterThan{Field: p.Inventory, Name: "Inventory", Compared: -1},
+		&validators.FuncValidator{
+			Field:   p.Name,
+			Name:    "Price",
+			Message: "Price must be above zero (%s)",
+			Fn:      func() bool { return p.Price > 0 },
+		},
+	), nil
+}
+
+// StoreOrder is a merchandise order paid for through the donation payment
+// flow. The buyer pays the donation; the order tracks what to ship and where.
+type StoreOrder struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	DonationID        uuid.UUID  `json:"donation_id" db:"donation_id"`
+	Name              string     `json:"name" db:"name"`
+	Email             string     `json:"email" db:"email"`
+	AddressLine1      string     `json:"address_line1" db:"address_line1"`
+	AddressLine2      *string    `json:"address_line2,omitempty" db:"address_line2"`
+	City              string     `json:"city" db:"city"`
+	State             string     `json:"state" db:"state"`
+	Zip               string     `json:"zip" db:"zip"`
+	Subtotal          float64    `json:"subtotal" db:"subtotal"`
+	Shipping          float64    `json:"shipping" db:"shipping"`
+	SalesTax          float64    `json:"sales_tax" db:"sales_tax"`
+	Total             float64    `json:"total" db:"total"`
+	Status            string     `json:"status" db:"status"`
+	FulfillmentStatus string     `json:"fulfillment_status" db:"fulfillment_status"`
+	TrackingNumber    *string    `json:"tracking_number,omitempty" db:"tracking_number"`
+	ShippedAt         *time.Time `json:"shipped_at,omitempty" db:"shipped_at"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// StoreOrders is not required by pop and may be deleted
+type StoreOrders []StoreOrder
+
+// AddressLine2Text is the second address line, if any
+func (o StoreOrder) AddressLine2Text() string {
+	if o.AddressLine2 == nil {
+		return ""
+	}
+	return *o.AddressLine2
+}
+
+// TrackingNumberText is the shipment's tracking number, if any
+func (o StoreOrder) TrackingNumberText() string {
+	if o.TrackingNumber == nil {
+		return ""
+	}
+	return *o.TrackingNumber
+}
+
+// OrderNumber is the short reference printed on packing slips
+func (o StoreOrder) OrderNumber() string {
+	return strings.ToUpper(o.ID.String()[:8])
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (o *StoreOrder) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: o.Name, Name: "Name"},
+		&validators.EmailIsPresent{Field: o.Email, Name: "Email"},
+		&validators.StringIsPresent{Field: o.AddressLine1, Name: "AddressLine1"},
+		&validators.StringIsPresent{Field: o.City, Name: "City"},
+		&validators.StringIsPresent{Field: o.State, Name: "State"},
+		&validators.StringIsPresent{Field: o.Zip, Name: "Zip"},
+		&validators.StringInclusion{Field: o.FulfillmentStatus, Name: "FulfillmentStatus", List: FulfillmentStatuses},
+	), nil
+}
+
+// StoreOrderItem is one line of a store order. The product name and price
+// are copied at checkout so later catalog edits don't change past orders.
+type StoreOrderItem struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	OrderID     uuid.UUID `json:"order_id" db:"order_id"`
+	ProductID   uuid.UUID `json:"product_id" db:"product_id"`
+	ProductName string    `json:"product_name" db:"product_name"`
+	Quantity    int       `json:"quantity" db:"quantity"`
+	UnitPrice   float64   `json:"unit_price" db:"unit_price"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// StoreOrderItems is not required by pop and may be deleted
+type StoreOrderItems []StoreOrderItem
+
+// LineTotal is the price of the line's quantity
+func (i StoreOrderItem) LineTotal() float64 {
+	return float64(i.Quantity) * i.UnitPrice
+}
+
+// Subtotal adds up the order lines
+func (items StoreOrderItems) Subtotal() float64 {
+	total := 0.0
+	for _, item := range items {
+		total += item.LineTotal()
+	}
+	return total
+}
+
+// Summary lists the items for receipts and the donation record, e.g.
+// "2 × T-Shirt, 1 × Challenge Coin"
+func (items StoreOrderItems) Summary() string {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		parts = append(parts, fmt.Sprintf("%d × %s", item.Quantity, item.ProductName))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreOrderItems_Totals(t *testing.T) {
+	items := StoreOrderItems{
+		{ProductName: "T-Shirt", Quantity: 2, UnitPrice: 20},
+		{ProductName: "Challenge Coin", Quantity: 1, UnitPrice: 12.5},
+	}
+
+	assert.Equal(t, 40.0, items[0].LineTotal())
+	assert.Equal(t, 52.5, items.Subtotal())
+	assert.Equal(t, "2 × T-Shirt, 1 × Challenge Coin", items.Summary())
+}
+
+func TestProduct_InStock(t *testing.T) {
+	assert.True(t, Product{Active: true, Inventory: 3}.InStock())
+	assert.False(t, Product{Active: true, Inventory: 0}.InStock())
+	assert.False(t, Product{Active: false, Inventory: 3}.InStock())
+}
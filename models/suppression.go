@@ -0,0 +1,167 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Kinds of contact detail that can be suppressed
+const (
+	SuppressEmail   = "email"
+	SuppressPhone   = "phone"
+	SuppressAddress = "address"
+)
+
+// SuppressionKinds lists the kinds of contact detail that can be suppressed
+var SuppressionKinds = []string{SuppressEmail, SuppressPhone, SuppressAddress}
+
+var suppressionKindLabels = map[string]string{
+	SuppressEmail:   "Email",
+	SuppressPhone:   "Phone",
+	SuppressAddress: "Mailing address",
+}
+
+// SuppressionKindLabel is the display name for a kind of suppressed contact
+// detail
+func SuppressionKindLabel(kind string) string {
+	if label, ok := suppressionKindLabels[kind]; ok {
+		return label
+	}
+	return kind
+}
+
+// Where a suppression came from
+const (
+	SuppressionManual = "manual"
+	SuppressionImport = "import"
+)
+
+var (
+	nonDigits       = regexp.MustCompile(`[^0-9]`)
+	addressNoise    = regexp.MustCompile(`[^a-z0-9 ]`)
+	addressSpaceRun = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeSuppressionValue puts a contact detail in the form suppressions
+// are matched on, so "(555) 010-2000" matches "+1 555 010 2000" and
+// "12 Oak St." matches "12 oak st"
+func NormalizeSuppressionValue(kind, value string) string {
+	switch kind {
+	case SuppressEmail:
+		return NormalizeDonorEmail(value)
+	case SuppressPhone:
+		digits := nonDigits.ReplaceAllString(value, "")
+		if len(digits) == 11 && strings.HasPrefix(digits, "1") {
+			digits = digits[1:]
+		}
+		return digits
+	case SuppressAddress:
+		address := addressNoise.ReplaceAllString(strings.ToLower(value), " ")
+		return strings.TrimSpace(addressSpaceRun.ReplaceAllString(address, " "))
+	}
+	return strings.TrimSpace(value)
+}
+
+// Suppression is an email, phone number or mailing address we must not
+// contact. Every outbound send that isn't a receipt or acknowledgment the
+// donor is owed checks this list first.
+type Suppression struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Kind      string     `json:"kind" db:"kind"`
+	Value     string     `json:"value" db:"value"`
+	Reason    *string    `json:"reason,omitempty" db:"reason"`
+	Source    string     `json:"source" db:"source"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (s Suppression) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// Suppressions is not required by pop and may be deleted
+type Suppressions []Suppression
+
+// KindLabel is the display name of the suppressed contact detail's kind
+func (s Suppression) KindLabel() string {
+	return SuppressionKindLabel(s.Kind)
+}
+
+// ReasonText is why the contact detail was suppressed, when recorded
+func (s Suppression) ReasonText() string {
+	if s.Reason == nil {
+		return ""
+	}
+	return *s.Reason
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (s *Suppression) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	checks := []validate.Validator{
+		&validators.StringInclusion{Field: s.Kind, Name: "Kind", List: SuppressionKinds},
+		&validators.StringIsPresent{Field: s.Value, Name: "Value"},
+	}
+	switch s.Kind {
+	case SuppressEmail:
+		checks = append(checks, &validators.EmailIsPresent{Field: s.Value, Name: "Value"})
+	case SuppressPhone:
+		checks = append(checks, &validators.FuncValidator{
+			Field:   s.Value,
+			Name:    "Value",
+			Message: "%s is not a valid phone number",
+			Fn:      func() bool { return len(s.Value) >= 10 && len(s.Value) <= 15 },
+		})
+	}
+	return validate.Validate(checks...), nil
+}
+
+// FindSuppression looks up the suppression covering a contact detail. It
+// returns nil when the detail isn't suppressed.
+func FindSuppression(tx *pop.Connection, kind, value string) (*Suppression, error) {
+	value = NormalizeSuppressionValue(kind, value)
+	if value == "" {
+		return nil, nil
+	}
+	suppression := &Suppression{}
+	err := tx.Where("kind = ? AND value = ?", kind, value).First(suppression)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return suppression, nil
+}
+
+// SuppressedSend records a message we didn't send because its recipient is
+// on the suppression list
+type SuppressedSend struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	SuppressionID uuid.UUID `json:"suppression_id" db:"suppression_id"`
+	Channel       string    `json:"channel" db:"channel"`
+	Recipient     string    `json:"recipient" db:"recipient"`
+	Purpose       string    `json:"purpose" db:"purpose"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (s SuppressedSend) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// SuppressedSends is not required by pop and may be deleted
+type SuppressedSends []SuppressedSend
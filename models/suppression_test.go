@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeSuppressionValue(t *testing.T) {
+	assert.Equal(t, "sam@example.com", NormalizeSuppressionValue(SuppressEmail, " Sam@Example.com "))
+	assert.Equal(t, "5550102000", NormalizeSuppressionValue(SuppressPhone, "(555) 010-2000"))
+	assert.Equal(t, "5550102000", NormalizeSuppressionValue(SuppressPhone, "+1 555 010 2000"))
+	assert.Equal(t, "12 oak st apt 4", NormalizeSuppressionValue(SuppressAddress, "12 Oak St.,  Apt #4"))
+}
+
+func TestSuppression_Validate(t *testing.T) {
+	verrs, err := (&Suppression{Kind: "fax", Value: "5550102000"}).Validate(nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verrs.Get("kind"))
+
+	verrs, _ = (&Suppression{Kind: SuppressEmail, Value: "not-an-email"}).Validate(nil)
+	assert.NotEmpty(t, verrs.Get("value"))
+
+	verrs, _ = (&Suppression{Kind: SuppressPhone, Value: "55501"}).Validate(nil)
+	assert.NotEmpty(t, verrs.Get("value"))
+
+	verrs, _ = (&Suppression{Kind: SuppressAddress, Value: "12 oak st"}).Validate(nil)
+	assert.False(t, verrs.HasAny())
+}
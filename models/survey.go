@@ -0,0 +1,215 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Survey question types
+const (
+	SurveyQuestionText   = "text"
+	SurveyQuestionChoice = "choice"
+	SurveyQuestionRating = "rating"
+)
+
+// SurveyQuestionTypes lists the question types admins can choose from
+var SurveyQuestionTypes = []string{SurveyQuestionText, SurveyQuestionChoice, SurveyQuestionRating}
+
+// SurveyRatings are the choices for a rating question, lowest first
+var SurveyRatings = []string{"1", "2", "3", "4", "5"}
+
+// Survey is a feedback questionnaire sent out as single-use links
+type Survey struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	Title       string    `json:"title" db:"title"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (s Survey) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// Surveys is not required by pop and may be deleted
+type Surveys []Survey
+
+// DescriptionText is the survey's introduction, if any
+func (s Survey) DescriptionText() string {
+	if s.Description == nil {
+		return ""
+	}
+	return *s.Description
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (s *Survey) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: s.Title, Name: "Title"},
+	), nil
+}
+
+// SurveyQuestion is one question on a survey
+type SurveyQuestion struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	SurveyID     uuid.UUID `json:"survey_id" db:"survey_id"`
+	Prompt       string    `json:"prompt" db:"prompt"`
+	QuestionType string    `json:"question_type" db:"question_type"`
+	Options      *string   `json:"options,omitempty" db:"options"`
+	Required     bool      `json:"required" db:"required"`
+	Position     int       `json:"position" db:"position"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SurveyQuestions is not required by pop and may be deleted
+type SurveyQuestions []SurveyQuestion
+
+// InputName is the form parameter the question's answer is posted as
+func (q SurveyQuestion) InputName() string {
+	return "q_" + q.ID.String()
+}
+
+// Choices returns the answers a respondent can pick from: one per line of
+// Options for choice questions, 1-5 for ratings.
+func (q SurveyQuestion) Choices() []string {
+	if q.QuestionType == SurveyQuestionRating {
+		return SurveyRatings
+	}
+	if q.Options == nil {
+		return nil
+	}
+	var choices []string
+	for _, line := range strings.Split(*q.Options, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			choices = append(choices, line)
+		}
+	}
+	return choices
+}
+
+// IsChoice reports whether value is one of the question's choices
+func (q SurveyQuestion) IsChoice(value string) bool {
+	for _, c := range q.Choices() {
+		if c == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (q *SurveyQuestion) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: q.SurveyID, Name: "SurveyID"},
+		&validators.StringIsPresent{Field: q.Prompt, Name: "Prompt"},
+		&validators.StringInclusion{Field: q.QuestionType, Name: "QuestionType", List: SurveyQuestionTypes},
+		&validators.FuncValidator{
+			Field:   q.Prompt,
+			Name:    "Options",
+			Message: "Choice questions need at least two options (%s)",
+			Fn: func() bool {
+				return q.QuestionType != SurveyQuestionChoice || len(q.Choices()) >= 2
+			},
+		},
+	), nil
+}
+
+// SurveyLink is a shareable single-use link to a survey
+type SurveyLink struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	SurveyID  uuid.UUID  `json:"survey_id" db:"survey_id"`
+	Token     string     `json:"token" db:"token"`
+	Label     *string    `json:"label,omitempty" db:"label"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SurveyLinks is not required by pop and may be deleted
+type SurveyLinks []SurveyLink
+
+// LabelText is the link's label (e.g. the event it was sent after), if any
+func (l SurveyLink) LabelText() string {
+	if l.Label == nil {
+		return ""
+	}
+	return *l.Label
+}
+
+// Used reports whether the link has already been used to respond
+func (l SurveyLink) Used() bool {
+	return l.UsedAt != nil
+}
+
+// GenerateSurveyToken returns a random URL-safe token for a survey link.
+func GenerateSurveyToken() (string, error) {
+	return randomURLToken(18)
+}
+
+// randomURLToken returns n random bytes encoded for use in a URL.
+func randomURLToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SurveyAnswer is a respondent's answer to one question. The prompt is
+// stored with the answer so exports still read correctly if the question is
+// later edited or removed.
+type SurveyAnswer struct {
+	QuestionID uuid.UUID `json:"question_id"`
+	Prompt     string    `json:"prompt"`
+	Value      string    `json:"value"`
+}
+
+// SurveyResponse is the set of answers submitted through one link
+type SurveyResponse struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	SurveyID  uuid.UUID `json:"survey_id" db:"survey_id"`
+	LinkID    uuid.UUID `json:"link_id" db:"link_id"`
+	Answers   string    `json:"answers" db:"answers"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SurveyResponses is not required by pop and may be deleted
+type SurveyResponses []SurveyResponse
+
+// AnswerList decodes the response's answers
+func (r SurveyResponse) AnswerList() []SurveyAnswer {
+	var answers []SurveyAnswer
+	if err := json.Unmarshal([]byte(r.Answers), &answers); err != nil {
+		return nil
+	}
+	return answers
+}
+
+// AnswerFor returns the answer given to a question, or "" if it was skipped
+func (r SurveyResponse) AnswerFor(questionID uuid.UUID) string {
+	for _, a := range r.AnswerList() {
+		if a.QuestionID == questionID {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// SetAnswers stores answers on the response
+func (r *SurveyResponse) SetAnswers(answers []SurveyAnswer) {
+	encoded, _ := json.Marshal(answers)
+	r.Answers = string(encoded)
+}
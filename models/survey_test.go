@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSurveyQuestion_Choices(t *testing.T) {
+	opts := "Very useful\n Somewhat useful \n\nNot useful"
+	q := SurveyQuestion{QuestionType: SurveyQuestionChoice, Options: &opts}
+	assert.Equal(t, []string{"Very useful", "Somewhat useful", "Not useful"}, q.Choices())
+	assert.True(t, q.IsChoice("Not useful"))
+	assert.False(t, q.IsChoice("Useful"))
+
+	rating := SurveyQuestion{QuestionType: SurveyQuestionRating}
+	assert.Equal(t, SurveyRatings, rating.Choices())
+	assert.Nil(t, SurveyQuestion{QuestionType: SurveyQuestionText}.Choices())
+}
+
+func TestSurveyResponse_Answers(t *testing.T) {
+	q1, q2 := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	r := SurveyResponse{}
+	r.SetAnswers([]SurveyAnswer{{QuestionID: q1, Prompt: "Rate the event", Value: "5"}})
+
+	assert.Len(t, r.AnswerList(), 1)
+	assert.Equal(t, "5", r.AnswerFor(q1))
+	assert.Equal(t, "", r.AnswerFor(q2))
+}
+
+func TestGenerateSurveyToken(t *testing.T) {
+	a, err := GenerateSurveyToken()
+	assert.NoError(t, err)
+	b, err := GenerateSurveyToken()
+	assert.NoError(t, err)
+	assert.Len(t, a, 24)
+	assert.NotEqual(t, a, b)
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// ThankYouRule is a personalized thank-you video or message added to the
+// receipt and thank-you page for gifts made from a partner's giving page.
+// A partner can have several, one per amount tier.
+type ThankYouRule struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	PartnerID uuid.UUID `json:"partner_id" db:"partner_id"`
+	MinAmount float64   `json:"min_amount" db:"min_amount"`
+	VideoURL  *string   `json:"video_url,omitempty" db:"video_url"`
+	Message   *string   `json:"message,omitempty" db:"message"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (r ThankYouRule) String() string {
+	jr, _ := json.Marshal(r)
+	return string(jr)
+}
+
+// ThankYouRules is not required by pop and may be deleted
+type ThankYouRules []ThankYouRule
+
+// VideoLink is the video URL, or blank when the rule has none
+func (r ThankYouRule) VideoLink() string {
+	if r.VideoURL == nil {
+		return ""
+	}
+	return *r.VideoURL
+}
+
+// MessageText is the unpersonalized message, or blank when the rule has none
+func (r ThankYouRule) MessageText() string {
+	if r.Message == nil {
+		return ""
+	}
+	return *r.Message
+}
+
+// Match returns the rule for the highest tier amount reaches, or nil when it
+// doesn't reach any
+func (rules ThankYouRules) Match(amount float64) *ThankYouRule {
+	var match *ThankYouRule
+	for i := range rules {
+		rule := &rules[i]
+		if amount >= rule.MinAmount && (match == nil || rule.MinAmount > match.MinAmount) {
+			match = rule
+		}
+	}
+	return match
+}
+
+// FindThankYouRule returns the thank-you rule for a donation's partner page
+// and amount, or nil when none applies. Pledges are matched on their full
+// pledged amount.
+func FindThankYouRule(tx *pop.Connection, donation *Donation) (*ThankYouRule, error) {
+	if donation.PartnerID == nil {
+		return nil, nil
+	}
+	rules := ThankYouRules{}
+	if err := tx.Where("partner_id = ?", *donation.PartnerID).All(&rules); err != nil {
+		return nil, err
+	}
+	return rules.Match(donation.PledgeAmount()), nil
+}
+
+// PersonalizedMessage fills in the {name} and {amount} placeholders in the
+// rule's message for the donation being thanked
+func (r ThankYouRule) PersonalizedMessage(donation *Donation) string {
+	if r.Message == nil {
+		return ""
+	}
+	name := strings.Fields(donation.DonorName)
+	first := "friend"
+	if len(name) > 0 {
+		first = name[0]
+	}
+	return strings.NewReplacer(
+		"{name}", first,
+		"{amount}", fmt.Sprintf("$%.2f", donation.PledgeAmount()),
+	).Replace(*r.Message)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (r *ThankYouRule) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	videoURL, message := r.VideoLink(), r.MessageText()
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: r.PartnerID, Name: "PartnerID"},
+		&validators.FuncValidator{
+			Field:   fmt.Sprintf("%.2f", r.MinAmount),
+			Name:    "MinAmount",
+			Message: "Thank-you tier minimum $%s can't be negative",
+			Fn:      func() bool { return r.MinAmount >= 0 },
+		},
+		&validators.StringIsPresent{Field: videoURL + message, Name: "Message", Message: "Each thank-you tier needs a video link or a message"},
+		&validators.FuncValidator{
+			Field:   videoURL,
+			Name:    "VideoURL",
+			Message: "%s is not a valid video link",
+			Fn: func() bool {
+				if r.VideoURL == nil {
+					return true
+				}
+				u, err := url.Parse(*r.VideoURL)
+				return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+			},
+		},
+	), nil
+}
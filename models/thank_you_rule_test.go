@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThankYouRules_Match(t *testing.T) {
+	rules := ThankYouRules{
+		{MinAmount: 100},
+		{MinAmount: 0},
+		{MinAmount: 500},
+	}
+	assert.Equal(t, 0.0, rules.Match(25).MinAmount)
+	assert.Equal(t, 100.0, rules.Match(100).MinAmount)
+	assert.Equal(t, 500.0, rules.Match(1000).MinAmount)
+
+	assert.Nil(t, ThankYouRules{{MinAmount: 50}}.Match(10))
+	assert.Nil(t, ThankYouRules{}.Match(10))
+}
+
+func TestThankYouRule_PersonalizedMessage(t *testing.T) {
+	msg := "Thank you, {name}, for your gift of {amount}!"
+	rule := ThankYouRule{Message: &msg}
+
+	assert.Equal(t, "Thank you, Jane, for your gift of $50.00!", rule.PersonalizedMessage(&Donation{DonorName: "Jane Doe", Amount: 50}))
+	assert.Equal(t, "Thank you, friend, for your gift of $50.00!", rule.PersonalizedMessage(&Donation{Amount: 50}))
+	assert.Equal(t, "", ThankYouRule{}.PersonalizedMessage(&Donation{Amount: 50}))
+}
+
+func TestThankYouRule_Validate(t *testing.T) {
+	partnerID := uuid.Must(uuid.NewV4())
+	video := "https://example.com/thanks"
+	rule := &ThankYouRule{PartnerID: partnerID, VideoURL: &video}
+	verrs, err := rule.Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+
+	bad := "javascript:alert(1)"
+	verrs, _ = (&ThankYouRule{PartnerID: partnerID, VideoURL: &bad}).Validate(nil)
+	assert.NotEmpty(t, verrs.Get("video_url"))
+
+	verrs, _ = (&ThankYouRule{PartnerID: partnerID}).Validate(nil)
+	assert.NotEmpty(t, verrs.Get("message"))
+
+	verrs, _ = (&ThankYouRule{PartnerID: partnerID, VideoURL: &video, MinAmount: -5}).Validate(nil)
+	assert.NotEmpty(t, verrs.Get("min_amount"))
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Upgrade prompt states. A prompt stays shown, counting each time the donor
+// sees it, until they accept or dismiss it.
+const (
+	UpgradePromptShown     = "shown"
+	UpgradePromptAccepted  = "accepted"
+	UpgradePromptDismissed = "dismissed"
+)
+
+// Reasons a monthly donor is asked to give a little more, by how long
+// they've been giving
+const (
+	UpgradeReasonAnniversary = "anniversary"
+	UpgradeReasonHalfYear    = "half_year"
+	UpgradeReasonSteady      = "steady"
+)
+
+// UpgradePromptCooldown is how long after a donor answers a prompt before
+// they're asked again
+const UpgradePromptCooldown = 180 * 24 * time.Hour
+
+// upgradePromptMinMonths is how long a monthly gift runs before its donor
+// is asked to raise it
+const upgradePromptMinMonths = 3
+
+// UpgradePrompt is a suggestion, shown in the donor portal, to raise a
+// monthly gift by a small amount
+type UpgradePrompt struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	DonationID      uuid.UUID  `json:"donation_id" db:"donation_id"`
+	UserID          uuid.UUID  `json:"user_id" db:"user_id"`
+	Reason          string     `json:"reason" db:"reason"`
+	CurrentAmount   float64    `json:"current_amount" db:"current_amount"`
+	SuggestedAmount float64    `json:"suggested_amount" db:"suggested_amount"`
+	Status          string     `json:"status" db:"status"`
+	Impressions     int        `json:"impressions" db:"impressions"`
+	LastShownAt     time.Time  `json:"last_shown_at" db:"last_shown_at"`
+	RespondedAt     *time.Time `json:"responded_at,omitempty" db:"responded_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (p UpgradePrompt) String() string {
+	jp, _ := json.Marshal(p)
+	return string(jp)
+}
+
+// UpgradePrompts is not required by pop and may be deleted
+type UpgradePrompts []UpgradePrompt
+
+// Increase is how much more a month the prompt asks for
+func (p UpgradePrompt) Increase() float64 {
+	return math.Round((p.SuggestedAmount-p.CurrentAmount)*100) / 100
+}
+
+// Headline is the prompt's call to action, e.g. "Increase your monthly gift
+// by $5"
+func (p UpgradePrompt) Headline() string {
+	return fmt.Sprintf("Increase your monthly gift by $%s", trimCents(p.Increase()))
+}
+
+// Message is the prompt's reason for asking, fitted to the donor's history
+func (p UpgradePrompt) Message() string {
+	switch p.Reason {
+	case UpgradeReasonAnniversary:
+		return "You've been giving every month for over a year. Thank you for standing with veterans all that time."
+	case UpgradeReasonHalfYear:
+		return "Six months of monthly giving has helped veterans train for new careers and find homes."
+	}
+	return "Your monthly gift is already at work for veterans rebuilding their lives."
+}
+
+// trimCents formats a dollar amount without ".00" when it's whole
+func trimCents(amount float64) string {
+	if amount == math.Trunc(amount) {
+		return fmt.Sprintf("%.0f", amount)
+	}
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// monthsBetween is how many whole months have passed from start to now
+func monthsBetween(start, now time.Time) int {
+	months := (now.Year()-start.Year())*12 + int(now.Month()-start.Month())
+	if now.Day() < start.Day() {
+		months--
+	}
+	return max(months, 0)
+}
+
+// SuggestUpgrade decides whether an active monthly gift's donor should be
+// asked to give increment more, and why. Pledges paid in installments have
+// a set total, and the prompts are written for monthly gifts, so quarterly
+// and annual donors are never asked.
+func SuggestUpgrade(donation *Donation, increment float64, now time.Time) (reason string, suggested float64, ok bool) {
+	if donation.DonationType != DonationTypeMonthly || donation.ChargeFrequency() != FrequencyMonthly || donation.Status != "active" || increment <= 0 {
+		return "", 0, false
+	}
+	months := monthsBetween(donation.CreatedAt, now)
+	switch {
+	case months >= 12:
+		reason = UpgradeReasonAnniversary
+	case months >= 6:
+		reason = UpgradeReasonHalfYear
+	case months >= upgradePromptMinMonths:
+		reason = UpgradeReasonSteady
+	default:
+		return "", 0, false
+	}
+	return reason, math.Round((donation.Amount+increment)*100) / 100, true
+}
+
+// ShowUpgradePrompt returns the upgrade prompt to show on a monthly gift,
+// counting this impression, or nil when the donor shouldn't be asked now.
+// A prompt already showing is reused; a new one is only made once the last
+// answer is older than UpgradePromptCooldown.
+func ShowUpgradePrompt(tx *pop.Connection, donation *Donation, userID uuid.UUID, increment float64, now time.Time) (*UpgradePrompt, error) {
+	reason, suggested, ok := SuggestUpgrade(donation, increment, now)
+	if !ok {
+		return nil, nil
+	}
+
+	last := &UpgradePrompt{}
+	err := tx.Where("donation_id = ?", donation.ID).Order("created_at desc").First(last)
+	switch {
+	case err == nil && last.Status == UpgradePromptShown && last.CurrentAmount == donation.Amount:
+		last.Impressions++
+		last.LastShownAt = now
+		if err := tx.UpdateColumns(last, "impressions", "last_shown_at", "updated_at"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return last, nil
+	case err == nil && last.RespondedAt != nil && now.Sub(*last.RespondedAt) < UpgradePromptCooldown:
+		return nil, nil
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return nil, errors.WithStack(err)
+	}
+	if err == nil && last.Status == UpgradePromptShown {
+		// The donor changed the amount themselves since it was shown, which
+		// answers it
+		last.Respond(UpgradePromptDismissed, now)
+		if err := tx.UpdateColumns(last, "status", "responded_at", "updated_at"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return nil, nil
+	}
+
+	prompt := &UpgradePrompt{
+		DonationID:      donation.ID,
+		UserID:          userID,
+		Reason:          reason,
+		CurrentAmount:   donation.Amount,
+		SuggestedAmount: suggested,
+		Status:          UpgradePromptShown,
+		Impressions:     1,
+		LastShownAt:     now,
+	}
+	if err := tx.Create(prompt); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return prompt, nil
+}
+
+// FindOpenUpgradePrompt loads a prompt still waiting on an answer for the
+// donation. It returns nil when there's none.
+func FindOpenUpgradePrompt(tx *pop.Connection, id uuid.UUID, donationID uuid.UUID) (*UpgradePrompt, error) {
+	prompt := &UpgradePrompt{}
+	err := tx.Where("id = ? AND donation_id = ? AND status = ?", id, donationID, UpgradePromptShown).First(prompt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return prompt, nil
+}
+
+// Respond records the donor accepting or dismissing the prompt
+func (p *UpgradePrompt) Respond(status string, now time.Time) {
+	p.Status = status
+	p.RespondedAt = &now
+}
+
+// UpgradePromptStats are how upgrade prompts have done
+type UpgradePromptStats struct {
+	Prompts      int     `db:"prompts"`
+	Impressions  int     `db:"impressions"`
+	Accepted     int     `db:"accepted"`
+	Dismissed    int     `db:"dismissed"`
+	AddedMonthly float64 `db:"added_monthly"`
+}
+
+// AcceptanceRate is the percentage of prompts donors accepted
+func (s UpgradePromptStats) AcceptanceRate() float64 {
+	if s.Prompts == 0 {
+		return 0
+	}
+	return math.Round(float64(s.Accepted)/float64(s.Prompts)*1000) / 10
+}
+
+// LoadUpgradePromptStats totals the prompts first shown since the given
+// time, by the reason they gave. The "" entry is every reason together.
+func LoadUpgradePromptStats(tx *pop.Connection, since time.Time) (map[string]UpgradePromptStats, error) {
+	rows := []struct {
+		Reason string `db:"reason"`
+		UpgradePromptStats
+	}{}
+	err := tx.RawQuery(`SELECT COALESCE(reason, '') AS reason,
+			COUNT(*) AS prompts,
+			COALESCE(SUM(impressions), 0) AS impressions,
+			COUNT(*) FILTER (WHERE status = ?) AS accepted,
+			COUNT(*) FILTER (WHERE status = ?) AS dismissed,
+			COALESCE(SUM(suggested_amount - current_amount) FILTER (WHERE status = ?), 0) AS added_monthly
+		FROM upgrade_prompts WHERE created_at >= ?
+		GROUP BY ROLLUP (reason)`, UpgradePromptAccepted, UpgradePromptDismissed, UpgradePromptAccepted, since).All(&rows)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	stats := map[string]UpgradePromptStats{}
+	for _, row := range rows {
+		stats[row.Reason] = row.UpgradePromptStats
+	}
+	return stats, nil
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestUpgrade(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	monthly := func(started time.Time) *Donation {
+		return &Donation{Amount: 25, DonationType: DonationTypeMonthly, Status: "active", CreatedAt: started}
+	}
+
+	reason, suggested, ok := SuggestUpgrade(monthly(now.AddDate(-1, 0, 0)), 5, now)
+	assert.True(t, ok)
+	assert.Equal(t, UpgradeReasonAnniversary, reason)
+	assert.Equal(t, 30.0, suggested)
+
+	reason, _, ok = SuggestUpgrade(monthly(now.AddDate(0, -7, 0)), 5, now)
+	assert.True(t, ok)
+	assert.Equal(t, UpgradeReasonHalfYear, reason)
+
+	reason, _, ok = SuggestUpgrade(monthly(now.AddDate(0, -3, 0)), 5, now)
+	assert.True(t, ok)
+	assert.Equal(t, UpgradeReasonSteady, reason)
+
+	// Not three whole months yet
+	_, _, ok = SuggestUpgrade(monthly(now.AddDate(0, -3, 1)), 5, now)
+	assert.False(t, ok)
+
+	cancelled := monthly(now.AddDate(-1, 0, 0))
+	cancelled.Status = "cancelled"
+	_, _, ok = SuggestUpgrade(cancelled, 5, now)
+	assert.False(t, ok)
+
+	pledge := monthly(now.AddDate(-1, 0, 0))
+	pledge.DonationType = DonationTypeInstallment
+	_, _, ok = SuggestUpgrade(pledge, 5, now)
+	assert.False(t, ok)
+
+	_, _, ok = SuggestUpgrade(monthly(now.AddDate(-1, 0, 0)), 0, now)
+	assert.False(t, ok)
+
+	annual := monthly(now.AddDate(-2, 0, 0))
+	annual.Frequency = FrequencyAnnual
+	_, _, ok = SuggestUpgrade(annual, 5, now)
+	assert.False(t, ok, "annual gifts aren't asked to raise a monthly amount")
+}
+
+func TestUpgradePrompt_Headline(t *testing.T) {
+	assert.Equal(t, "Increase your monthly gift by $5", UpgradePrompt{CurrentAmount: 25, SuggestedAmount: 30}.Headline())
+	assert.Equal(t, "Increase your monthly gift by $2.50", UpgradePrompt{CurrentAmount: 10, SuggestedAmount: 12.5}.Headline())
+}
+
+func TestUpgradePromptStats_AcceptanceRate(t *testing.T) {
+	assert.Equal(t, 0.0, UpgradePromptStats{}.AcceptanceRate())
+	assert.Equal(t, 33.3, UpgradePromptStats{Prompts: 3, Accepted: 1}.AcceptanceRate())
+}
+
+func (ms *ModelSuite) Test_ShowUpgradePrompt() {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	userID := uuid.Must(uuid.NewV4())
+	donation := &Donation{DonorName: "Pat Giver", DonorEmail: "pat@example.com", Amount: 25, Currency: "USD", DonationType: DonationTypeMonthly, Status: "active", CreatedAt: now.AddDate(-1, -1, 0)}
+	ms.NoError(ms.DB.Create(donation))
+
+	prompt, err := ShowUpgradePrompt(ms.DB, donation, userID, 5, now)
+	ms.NoError(err)
+	ms.NotNil(prompt)
+	ms.Equal(30.0, prompt.SuggestedAmount)
+
+	// Seeing it again counts another impression of the same prompt
+	again, err := ShowUpgradePrompt(ms.DB, donation, userID, 5, now.Add(time.Hour))
+	ms.NoError(err)
+	ms.Equal(prompt.ID, again.ID)
+	ms.Equal(2, again.Impressions)
+
+	open, err := FindOpenUpgradePrompt(ms.DB, prompt.ID, donation.ID)
+	ms.NoError(err)
+	ms.NotNil(open)
+
+	// Once answered, the donor isn't asked again until the cooldown is over
+	again.Respond(UpgradePromptAccepted, now)
+	ms.NoError(ms.DB.UpdateColumns(again, "status", "responded_at", "updated_at"))
+	open, err = FindOpenUpgradePrompt(ms.DB, prompt.ID, donation.ID)
+	ms.NoError(err)
+	ms.Nil(open)
+
+	later, err := ShowUpgradePrompt(ms.DB, donation, userID, 5, now.AddDate(0, 1, 0))
+	ms.NoError(err)
+	ms.Nil(later)
+	later, err = ShowUpgradePrompt(ms.DB, donation, userID, 5, now.Add(UpgradePromptCooldown+time.Hour))
+	ms.NoError(err)
+	ms.NotNil(later)
+
+	stats, err := LoadUpgradePromptStats(ms.DB, now.AddDate(0, -1, 0))
+	ms.NoError(err)
+	ms.Equal(2, stats[""].Prompts)
+	ms.Equal(1, stats[UpgradeReasonAnniversary].Accepted)
+	ms.Equal(5.0, stats[""].AddedMonthly)
+}
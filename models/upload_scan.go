@@ -0,0 +1,34 @@
+package models
+
+// Virus scan status of an uploaded file. Files are held back from download
+// until they're scanned clean; infected files and those the scanner failed
+// on stay in quarantine for staff to rescan or delete.
+const (
+	ScanPending    = "pending"
+	ScanClean      = "clean"
+	ScanInfected   = "infected"
+	ScanFailed     = "failed"
+	ScanNotScanned = "not_scanned"
+)
+
+var scanStatusLabels = map[string]string{
+	ScanPending:    "Waiting for virus scan",
+	ScanClean:      "Scanned clean",
+	ScanInfected:   "Quarantined: infected",
+	ScanFailed:     "Quarantined: scan failed",
+	ScanNotScanned: "Not scanned",
+}
+
+// ScanStatusLabel describes a scan status for staff
+func ScanStatusLabel(status string) string {
+	if label, ok := scanStatusLabels[status]; ok {
+		return label
+	}
+	return status
+}
+
+// ScanReleased reports whether a file with this status can be downloaded:
+// it was scanned clean, or uploaded while no scanner was set up
+func ScanReleased(status string) bool {
+	return status == ScanClean || status == ScanNotScanned
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"database/sql"
 	"strings"
 	"time"
 
@@ -22,6 +23,13 @@ type User struct {
 	FirstName    string    `json:"first_name" db:"first_name" form:"first_name"`
 	LastName     string    `json:"last_name" db:"last_name" form:"last_name"`
 	Role         string    `json:"role" db:"role"` // Added Role field
+	JobAlerts    bool      `json:"job_alerts" db:"job_alerts"`
+	// DeactivatedAt is set when staff switch the account off. Deactivated
+	// users can't sign in and their sessions stop working.
+	DeactivatedAt       *time.Time `json:"deactivated_at,omitempty" db:"deactivated_at"`
+	DeactivationReason  *string    `json:"deactivation_reason,omitempty" db:"deactivation_reason"`
+	InvitationTokenHash *string    `json:"-" db:"invitation_token_hash"`
+	InvitedAt           *time.Time `json:"invited_at,omitempty" db:"invited_at"`
 
 	Password             string `json:"-" db:"-" form:"password"`
 	PasswordConfirmation string `json:"-" db:"-" form:"password_confirmation"`
@@ -42,6 +50,97 @@ func (u *User) Create(tx *pop.Connection) (*validate.Errors, error) {
 	return tx.ValidateAndCreate(u)
 }
 
+// Permissions checked by currentUserCan in templates
+const (
+	PermissionViewAdmin = "view_admin"
+)
+
+// Can reports whether the user has a permission. Admins have every
+// permission; other roles have none yet.
+func (u *User) Can(permission string) bool {
+	return u != nil && u.Role == "admin"
+}
+
+// UserRoles are the roles an admin can give a user
+var UserRoles = []string{"user", "admin"}
+
+// InvitationTTL is how long an invitation link can be used to set a password
+const InvitationTTL = 7 * 24 * time.Hour
+
+// Active reports whether the user can sign in
+func (u User) Active() bool {
+	return u.DeactivatedAt == nil
+}
+
+// Deactivate suspends the account with the reason staff gave. Unlike
+// deleting it, the user's donations, messages and audit history stay linked
+// to the account, and it can be reactivated.
+func (u *User) Deactivate(reason string, now time.Time) {
+	u.DeactivatedAt = &now
+	u.DeactivationReason = &reason
+}
+
+// Reactivate lets a deactivated user sign in again
+func (u *User) Reactivate() {
+	u.DeactivatedAt = nil
+	u.DeactivationReason = nil
+}
+
+// DeactivationReasonText is why the account was deactivated, or blank
+func (u User) DeactivationReasonText() string {
+	if u.DeactivationReason == nil {
+		return ""
+	}
+	return *u.DeactivationReason
+}
+
+// StatusLabel describes the account's state for staff
+func (u User) StatusLabel() string {
+	switch {
+	case !u.Active():
+		return "Deactivated"
+	case u.InvitationPending():
+		return "Invited"
+	}
+	return "Active"
+}
+
+// InvitationPending reports whether the user has been sent an invitation
+// they haven't used yet
+func (u User) InvitationPending() bool {
+	return u.InvitationTokenHash != nil
+}
+
+// Invite starts a new invitation, replacing any earlier one, and returns the
+// token for the link emailed to the user. Only its hash is stored.
+func (u *User) Invite(now time.Time) (string, error) {
+	token, err := randomURLToken(32)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	hash := HashAPIKey(token)
+	u.InvitationTokenHash = &hash
+	u.InvitedAt = &now
+	return token, nil
+}
+
+// FindUserByInvitation looks up the active user whose unexpired invitation
+// matches token. It returns nil when there is none.
+func FindUserByInvitation(tx *pop.Connection, token string, now time.Time) (*User, error) {
+	if token == "" {
+		return nil, nil
+	}
+	u := &User{}
+	err := tx.Where("invitation_token_hash = ? AND deactivated_at IS NULL AND invited_at > ?", HashAPIKey(token), now.Add(-InvitationTTL)).First(u)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return u, nil
+}
+
 // VerifyPassword compares a plaintext password against the user's hashed password
 func (u *User) VerifyPassword(password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 func (ms *ModelSuite) Test_User_Create() {
 	count, err := ms.DB.Count("users")
 	ms.NoError(err)
@@ -83,3 +85,61 @@ func (ms *ModelSuite) Test_User_Create_UserExists() {
 	ms.NoError(err)
 	ms.Equal(1, count)
 }
+
+func (ms *ModelSuite) Test_User_Invitation() {
+	u := &User{
+		Email:                "jo@example.com",
+		Password:             "password",
+		PasswordConfirmation: "password",
+		FirstName:            "Jo",
+		LastName:             "Rivera",
+	}
+	now := time.Now()
+	token, err := u.Invite(now)
+	ms.NoError(err)
+	ms.NotEqual(token, *u.InvitationTokenHash, "only the hash is stored")
+	verrs, err := u.Create(ms.DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal("Invited", u.StatusLabel())
+
+	found, err := FindUserByInvitation(ms.DB, token, now)
+	ms.NoError(err)
+	ms.Equal(u.ID, found.ID)
+
+	found, err = FindUserByInvitation(ms.DB, token, now.Add(InvitationTTL+time.Minute))
+	ms.NoError(err)
+	ms.Nil(found, "expired")
+
+	deactivated := now
+	u.DeactivatedAt = &deactivated
+	ms.NoError(ms.DB.UpdateColumns(u, "deactivated_at"))
+	found, err = FindUserByInvitation(ms.DB, token, now)
+	ms.NoError(err)
+	ms.Nil(found, "deactivated users can't accept")
+	ms.Equal("Deactivated", u.StatusLabel())
+}
+
+func (ms *ModelSuite) Test_User_Deactivate() {
+	u := &User{
+		Email:                "lee@example.com",
+		Password:             "password",
+		PasswordConfirmation: "password",
+		FirstName:            "Lee",
+		LastName:             "Park",
+	}
+	verrs, err := u.Create(ms.DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	u.Deactivate("Left the organization", time.Now())
+	ms.NoError(ms.DB.UpdateColumns(u, "deactivated_at", "deactivation_reason"))
+	reloaded := &User{}
+	ms.NoError(ms.DB.Find(reloaded, u.ID))
+	ms.False(reloaded.Active())
+	ms.Equal("Left the organization", reloaded.DeactivationReasonText())
+
+	reloaded.Reactivate()
+	ms.True(reloaded.Active())
+	ms.Empty(reloaded.DeactivationReasonText())
+}
@@ -0,0 +1,295 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// Vehicle donation workflow stages. A vehicle is submitted by the donor,
+// inspected, picked up, and then either sold or put to use by AVR.
+const (
+	VehicleSubmitted = "submitted"
+	VehicleInspected = "inspected"
+	VehiclePickedUp  = "picked_up"
+	VehicleSold      = "sold"
+	VehicleUsed      = "used"
+)
+
+// VehicleStatuses lists the workflow stages in order
+var VehicleStatuses = []string{VehicleSubmitted, VehicleInspected, VehiclePickedUp, VehicleSold, VehicleUsed}
+
+var vehicleStatusLabels = map[string]string{
+	VehicleSubmitted: "Submitted",
+	VehicleInspected: "Inspected",
+	VehiclePickedUp:  "Picked up",
+	VehicleSold:      "Sold",
+	VehicleUsed:      "Used by AVR",
+}
+
+// vehicleTransitions is the stages each stage can move on to
+var vehicleTransitions = map[string][]string{
+	VehicleSubmitted: {VehicleInspected},
+	VehicleInspected: {VehiclePickedUp},
+	VehiclePickedUp:  {VehicleSold, VehicleUsed},
+}
+
+// VehicleStatusLabel is the display name for a vehicle workflow stage
+func VehicleStatusLabel(status string) string {
+	if label, ok := vehicleStatusLabels[status]; ok {
+		return label
+	}
+	return status
+}
+
+// Vehicle conditions donors can choose from
+const (
+	VehicleRunsWell    = "runs_well"
+	VehicleNeedsRepair = "needs_repair"
+	VehicleDoesNotRun  = "does_not_run"
+)
+
+// VehicleConditions lists the conditions on the donation form
+var VehicleConditions = []string{VehicleRunsWell, VehicleNeedsRepair, VehicleDoesNotRun}
+
+var vehicleConditionLabels = map[string]string{
+	VehicleRunsWell:    "Runs and drives",
+	VehicleNeedsRepair: "Runs but needs repair",
+	VehicleDoesNotRun:  "Does not run",
+}
+
+// VehicleConditionLabel is the display name for a vehicle condition
+func VehicleConditionLabel(condition string) string {
+	if label, ok := vehicleConditionLabels[condition]; ok {
+		return label
+	}
+	return condition
+}
+
+// NormalizeVIN uppercases a VIN and drops the spaces and dashes donors
+// sometimes type into it
+func NormalizeVIN(vin string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(vin)))
+}
+
+// ValidVIN reports whether vin looks like a vehicle identification number.
+// Vehicles from 1981 on have 17-character VINs, which never use I, O or Q;
+// older vehicles have shorter serial numbers.
+func ValidVIN(vin string, year int) bool {
+	if len(vin) != 17 && (year >= 1981 || len(vin) < 5 || len(vin) > 17) {
+		return false
+	}
+	for _, r := range vin {
+		isDigit := r >= '0' && r <= '9'
+		isLetter := r >= 'A' && r <= 'Z' && r != 'I' && r != 'O' && r != 'Q'
+		if !isDigit && !isLetter {
+			return false
+		}
+	}
+	return true
+}
+
+// VehicleDonation is a car, truck, boat or other vehicle offered to AVR
+// through the vehicle donation form, tracked from submission until it's sold
+// or put to use. Donors of vehicles worth over $500 are owed a Form 1098-C
+// style acknowledgment within 30 days of that disposition.
+type VehicleDonation struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	DonorName      string     `json:"donor_name" db:"donor_name"`
+	DonorEmail     string     `json:"donor_email" db:"donor_email"`
+	DonorPhone     *string    `json:"donor_phone,omitempty" db:"donor_phone"`
+	AddressLine1   string     `json:"address_line1" db:"address_line1"`
+	City           string     `json:"city" db:"city"`
+	State          string     `json:"state" db:"state"`
+	Zip            string     `json:"zip" db:"zip"`
+	UserID         *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	Year           int        `json:"year" db:"year"`
+	Make           string     `json:"make" db:"make"`
+	Model          string     `json:"model" db:"model"`
+	VIN            string     `json:"vin" db:"vin"`
+	Mileage        int        `json:"mileage" db:"mileage"`
+	Condition      string     `json:"condition" db:"condition"`
+	ConditionNotes *string    `json:"condition_notes,omitempty" db:"condition_notes"`
+	Status         string     `json:"status" db:"status"`
+	InspectedAt    *time.Time `json:"inspected_at,omitempty" db:"inspected_at"`
+	PickedUpAt     *time.Time `json:"picked_up_at,omitempty" db:"picked_up_at"`
+	DisposedAt     *time.Time `json:"disposed_at,omitempty" db:"disposed_at"`
+	GrossProceeds  float64    `json:"gross_proceeds" db:"gross_proceeds"`
+	ArmsLengthSale bool       `json:"arms_length_sale" db:"arms_length_sale"`
+	UseDescription *string    `json:"use_description,omitempty" db:"use_description"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (v VehicleDonation) String() string {
+	js, _ := json.Marshal(v)
+	return string(js)
+}
+
+// VehicleDonations is not required by pop and may be deleted
+type VehicleDonations []VehicleDonation
+
+// Title is the vehicle's year, make and model, e.g. "2012 Ford F-150"
+func (v VehicleDonation) Title() string {
+	return fmt.Sprintf("%d %s %s", v.Year, v.Make, v.Model)
+}
+
+// StatusLabel is the display name of the vehicle's workflow stage
+func (v VehicleDonation) StatusLabel() string {
+	return VehicleStatusLabel(v.Status)
+}
+
+// ConditionLabel is the display name of the condition the donor reported
+func (v VehicleDonation) ConditionLabel() string {
+	return VehicleConditionLabel(v.Condition)
+}
+
+// DonorPhoneText is the donor's phone number, if they gave one
+func (v VehicleDonation) DonorPhoneText() string {
+	if v.DonorPhone == nil {
+		return ""
+	}
+	return *v.DonorPhone
+}
+
+// ConditionNotesText is the donor's description of the vehicle's condition
+func (v VehicleDonation) ConditionNotesText() string {
+	if v.ConditionNotes == nil {
+		return ""
+	}
+	return *v.ConditionNotes
+}
+
+// UseDescriptionText is how AVR is using the vehicle, when it isn't sold
+func (v VehicleDonation) UseDescriptionText() string {
+	if v.UseDescription == nil {
+		return ""
+	}
+	return *v.UseDescription
+}
+
+// NextStatuses is the stages the vehicle can move on to from its current one
+func (v VehicleDonation) NextStatuses() []string {
+	return vehicleTransitions[v.Status]
+}
+
+// CanMoveTo reports whether the workflow allows moving on to status
+func (v VehicleDonation) CanMoveTo(status string) bool {
+	for _, next := range v.NextStatuses() {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Disposed reports whether the vehicle has been sold or put to use, which
+// is when its acknowledgment is due
+func (v VehicleDonation) Disposed() bool {
+	return v.Status == VehicleSold || v.Status == VehicleUsed
+}
+
+// Acknowledged reports whether the donor has been sent their acknowledgment
+func (v VehicleDonation) Acknowledged() bool {
+	return v.AcknowledgedAt != nil
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (v *VehicleDonation) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	nextYear := time.Now().Year() + 1
+	checks := []validate.Validator{
+		&validators.StringIsPresent{Field: v.DonorName, Name: "DonorName"},
+		&validators.EmailIsPresent{Field: v.DonorEmail, Name: "DonorEmail"},
+		&validators.StringIsPresent{Field: v.AddressLine1, Name: "AddressLine1"},
+		&validators.StringIsPresent{Field: v.City, Name: "City"},
+		&validators.StringIsPresent{Field: v.State, Name: "State"},
+		&validators.StringIsPresent{Field: v.Zip, Name: "Zip"},
+		&validators.StringIsPresent{Field: v.Make, Name: "Make"},
+		&validators.StringIsPresent{Field: v.Model, Name: "Model"},
+		&validators.StringInclusion{Field: v.Condition, Name: "Condition", List: VehicleConditions},
+		&validators.StringInclusion{Field: v.Status, Name: "Status", List: VehicleStatuses},
+		&validators.FuncValidator{
+			Field:   fmt.Sprint(v.Mileage),
+			Name:    "Mileage",
+			Message: "Mileage can't be negative (%s)",
+			Fn:      func() bool { return v.Mileage >= 0 },
+		},
+	}
+	if v.Year == 0 {
+		checks = append(checks, &validators.IntIsPresent{Field: v.Year, Name: "Year"})
+	} else {
+		checks = append(checks, &validators.FuncValidator{
+			Field:   fmt.Sprint(v.Year),
+			Name:    "Year",
+			Message: "%s is not a valid model year",
+			Fn:      func() bool { return v.Year >= 1900 && v.Year <= nextYear },
+		})
+	}
+	if v.VIN == "" {
+		checks = append(checks, &validators.StringIsPresent{Field: v.VIN, Name: "VIN"})
+	} else {
+		checks = append(checks, &validators.FuncValidator{
+			Field:   v.VIN,
+			Name:    "VIN",
+			Message: "%s is not a valid VIN",
+			Fn:      func() bool { return ValidVIN(v.VIN, v.Year) },
+		})
+	}
+	if v.Status == VehicleSold {
+		checks = append(checks, &validators.FuncValidator{
+			Field:   fmt.Sprintf("%.2f", v.GrossProceeds),
+			Name:    "GrossProceeds",
+			Message: "Enter the gross proceeds from the sale (%s)",
+			Fn:      func() bool { return v.GrossProceeds > 0 },
+		})
+	}
+	if v.Status == VehicleUsed {
+		checks = append(checks, &validators.StringIsPresent{Field: v.UseDescriptionText(), Name: "UseDescription", Message: "Describe how AVR will use the vehicle."})
+	}
+	return validate.Validate(checks...), nil
+}
+
+// VehicleDonationPhoto is a photo the donor uploaded with their vehicle
+// donation. The file itself lives in upload storage under StorageKey.
+type VehicleDonationPhoto struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	VehicleDonationID uuid.UUID  `json:"vehicle_donation_id" db:"vehicle_donation_id"`
+	StorageKey        string     `json:"storage_key" db:"storage_key"`
+	Filename          string     `json:"filename" db:"filename"`
+	ContentType       string     `json:"content_type" db:"content_type"`
+	ScanStatus        string     `json:"scan_status" db:"scan_status"`
+	ScanDetail        *string    `json:"scan_detail,omitempty" db:"scan_detail"`
+	ScannedAt         *time.Time `json:"scanned_at,omitempty" db:"scanned_at"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Released reports whether the photo has passed its virus scan and can be
+// shown to staff
+func (p VehicleDonationPhoto) Released() bool {
+	return ScanReleased(p.ScanStatus)
+}
+
+// ScanDetailText is the signature the virus scan found, or why it failed
+func (p VehicleDonationPhoto) ScanDetailText() string {
+	if p.ScanDetail == nil {
+		return ""
+	}
+	return *p.ScanDetail
+}
+
+// VehicleDonationPhotos is not required by pop and may be deleted
+type VehicleDonationPhotos []VehicleDonationPhoto
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidVIN(t *testing.T) {
+	assert.True(t, ValidVIN("1FTFW1ET5DFC10312", 2013))
+	assert.False(t, ValidVIN("1FTFW1ET5DFC1031", 2013), "too short")
+	assert.False(t, ValidVIN("1FTFW1ET5DFO10312", 2013), "contains O")
+	assert.True(t, ValidVIN("7R01C123456", 1967), "pre-1981 serial")
+	assert.False(t, ValidVIN("7R01", 1967))
+	assert.Equal(t, "1FTFW1ET5DFC10312", NormalizeVIN(" 1ftfw1et5-dfc 10312 "))
+}
+
+func TestVehicleDonation_Workflow(t *testing.T) {
+	v := VehicleDonation{Status: VehicleSubmitted}
+	assert.True(t, v.CanMoveTo(VehicleInspected))
+	assert.False(t, v.CanMoveTo(VehicleSold), "can't skip inspection and pickup")
+
+	v.Status = VehiclePickedUp
+	assert.ElementsMatch(t, []string{VehicleSold, VehicleUsed}, v.NextStatuses())
+	assert.False(t, v.Disposed())
+
+	v.Status = VehicleSold
+	assert.True(t, v.Disposed())
+	assert.Empty(t, v.NextStatuses())
+	assert.Equal(t, "Used by AVR", VehicleStatusLabel(VehicleUsed))
+}
+
+func TestVehicleDonation_Validate(t *testing.T) {
+	verrs, err := (&VehicleDonation{Year: 1850, Mileage: -1}).Validate(nil)
+	assert.NoError(t, err)
+	for _, field := range []string{"donor_name", "donor_email", "address_line1", "make", "model", "condition", "status", "year", "vin", "mileage"} {
+		assert.NotEmpty(t, verrs.Get(field), field)
+	}
+
+	v := &VehicleDonation{
+		DonorName:    "Sam Donor",
+		DonorEmail:   "sam@example.com",
+		AddressLine1: "12 Main St",
+		City:         "Lexington",
+		State:        "KY",
+		Zip:          "40502",
+		Year:         2013,
+		Make:         "Ford",
+		Model:        "F-150",
+		VIN:          "1FTFW1ET5DFC10312",
+		Mileage:      142000,
+		Condition:    VehicleRunsWell,
+		Status:       VehicleSubmitted,
+	}
+	verrs, err = v.Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+	assert.Equal(t, "2013 Ford F-150", v.Title())
+
+	v.Status = VehicleSold
+	verrs, _ = v.Validate(nil)
+	assert.NotEmpty(t, verrs.Get("gross_proceeds"))
+
+	v.Status = VehicleUsed
+	verrs, _ = v.Validate(nil)
+	assert.NotEmpty(t, verrs.Get("use_description"))
+}
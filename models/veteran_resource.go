@@ -0,0 +1,201 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// ResourceCategory is a section of the resource library
+type ResourceCategory struct {
+	Key   string
+	Label string
+}
+
+// ResourceCategories are the resource library's sections in the order the
+// page lists them
+var ResourceCategories = []ResourceCategory{
+	{Key: "crisis", Label: "Crisis Support"},
+	{Key: "va_benefits", Label: "VA Benefits"},
+	{Key: "health_care", Label: "Health Care"},
+	{Key: "mental_health", Label: "Mental Health"},
+	{Key: "housing", Label: "Housing"},
+	{Key: "employment", Label: "Employment & Training"},
+	{Key: "education", Label: "Education"},
+	{Key: "financial", Label: "Financial Help"},
+	{Key: "legal", Label: "Legal Aid"},
+	{Key: "family", Label: "Family & Caregivers"},
+}
+
+// ResourceCategoryKeys lists the keys of ResourceCategories
+func ResourceCategoryKeys() []string {
+	keys := make([]string, len(ResourceCategories))
+	for i, c := range ResourceCategories {
+		keys[i] = c.Key
+	}
+	return keys
+}
+
+// ResourceCategoryLabel is the display name for a category key
+func ResourceCategoryLabel(key string) string {
+	for _, c := range ResourceCategories {
+		if c.Key == key {
+			return c.Label
+		}
+	}
+	return key
+}
+
+// Link health of a resource, from the last time its URL was checked
+const (
+	LinkUnchecked = "unchecked"
+	LinkOK        = "ok"
+	LinkBroken    = "broken"
+)
+
+// VeteranResource is a link in the resource library to an outside service
+// for veterans, like VA benefits help or housing assistance
+type VeteranResource struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	Title          string     `json:"title" db:"title"`
+	URL            string     `json:"url" db:"url"`
+	Category       string     `json:"category" db:"category"`
+	Organization   *string    `json:"organization,omitempty" db:"organization"`
+	Description    string     `json:"description" db:"description"`
+	Phone          *string    `json:"phone,omitempty" db:"phone"`
+	Active         bool       `json:"active" db:"active"`
+	LinkStatus     string     `json:"link_status" db:"link_status"`
+	LinkStatusCode *int       `json:"link_status_code,omitempty" db:"link_status_code"`
+	LinkError      *string    `json:"link_error,omitempty" db:"link_error"`
+	LinkCheckedAt  *time.Time `json:"link_checked_at,omitempty" db:"link_checked_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (v VeteranResource) String() string {
+	jv, _ := json.Marshal(v)
+	return string(jv)
+}
+
+// VeteranResources is not required by pop and may be deleted
+type VeteranResources []VeteranResource
+
+// CategoryLabel is the display name of the resource's category
+func (v VeteranResource) CategoryLabel() string {
+	return ResourceCategoryLabel(v.Category)
+}
+
+// OrganizationText is who runs the resource, or "" if not given
+func (v VeteranResource) OrganizationText() string {
+	if v.Organization == nil {
+		return ""
+	}
+	return *v.Organization
+}
+
+// PhoneText is the resource's phone number, or "" if not given
+func (v VeteranResource) PhoneText() string {
+	if v.Phone == nil {
+		return ""
+	}
+	return *v.Phone
+}
+
+// Host is the site the resource links to, e.g. "www.va.gov"
+func (v VeteranResource) Host() string {
+	u, err := url.Parse(v.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// LinkBroken reports whether the resource's link failed its last check
+func (v VeteranResource) LinkBroken() bool {
+	return v.LinkStatus == LinkBroken
+}
+
+// LinkProblem describes why the last link check failed, e.g.
+// "HTTP 404 Not Found"
+func (v VeteranResource) LinkProblem() string {
+	if !v.LinkBroken() {
+		return ""
+	}
+	if v.LinkError != nil && *v.LinkError != "" {
+		return *v.LinkError
+	}
+	if v.LinkStatusCode != nil {
+		return strings.TrimSpace(fmt.Sprintf("HTTP %d %s", *v.LinkStatusCode, http.StatusText(*v.LinkStatusCode)))
+	}
+	return ""
+}
+
+// SetLinkCheck records the result of checking the resource's link: the
+// HTTP status it answered with (0 if it couldn't be reached) and any error.
+// Anything but a 2xx answer is a broken link.
+func (v *VeteranResource) SetLinkCheck(statusCode int, checkErr string, now time.Time) {
+	v.LinkCheckedAt = &now
+	v.LinkStatusCode = nil
+	if statusCode > 0 {
+		v.LinkStatusCode = &statusCode
+	}
+	v.LinkError = nil
+	if checkErr != "" {
+		v.LinkError = &checkErr
+	}
+	v.LinkStatus = LinkBroken
+	if checkErr == "" && statusCode >= 200 && statusCode < 300 {
+		v.LinkStatus = LinkOK
+	}
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (v *VeteranResource) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: v.Title, Name: "Title", Message: "Title is required"},
+		&validators.StringLengthInRange{Field: v.Title, Name: "Title", Max: 150, Message: "Title must be 150 characters or less"},
+		&validators.FuncValidator{
+			Field:   v.URL,
+			Name:    "URL",
+			Message: "%s is not a valid link",
+			Fn: func() bool {
+				u, err := url.Parse(v.URL)
+				return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+			},
+		},
+		&validators.StringInclusion{Field: v.Category, Name: "Category", List: ResourceCategoryKeys(), Message: "Choose a category"},
+		&validators.StringLengthInRange{Field: v.Description, Name: "Description", Max: 1000, Message: "Description must be 1000 characters or less"},
+		&validators.StringInclusion{Field: v.LinkStatus, Name: "LinkStatus", List: []string{LinkUnchecked, LinkOK, LinkBroken}},
+	), nil
+}
+
+// ResourceGroup is one category's resources on the resource library page
+type ResourceGroup struct {
+	Category  ResourceCategory
+	Resources VeteranResources
+}
+
+// GroupResources sorts resources into their categories, in the order of
+// ResourceCategories. Empty categories are left out.
+func GroupResources(resources VeteranResources) []ResourceGroup {
+	byCategory := map[string]VeteranResources{}
+	for _, r := range resources {
+		byCategory[r.Category] = append(byCategory[r.Category], r)
+	}
+	groups := []ResourceGroup{}
+	for _, c := range ResourceCategories {
+		if len(byCategory[c.Key]) > 0 {
+			groups = append(groups, ResourceGroup{Category: c, Resources: byCategory[c.Key]})
+		}
+	}
+	return groups
+}
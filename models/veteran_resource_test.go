@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVeteranResource_SetLinkCheck(t *testing.T) {
+	now := time.Date(2026, 10, 14, 6, 0, 0, 0, time.UTC)
+	res := &VeteranResource{LinkStatus: LinkUnchecked}
+
+	res.SetLinkCheck(200, "", now)
+	assert.Equal(t, LinkOK, res.LinkStatus)
+	assert.False(t, res.LinkBroken())
+	assert.Equal(t, 200, *res.LinkStatusCode)
+	assert.Equal(t, now, *res.LinkCheckedAt)
+
+	res.SetLinkCheck(404, "", now)
+	assert.True(t, res.LinkBroken())
+	assert.Equal(t, "HTTP 404 Not Found", res.LinkProblem())
+
+	res.SetLinkCheck(0, "dial tcp: no such host", now)
+	assert.True(t, res.LinkBroken())
+	assert.Nil(t, res.LinkStatusCode)
+	assert.Equal(t, "dial tcp: no such host", res.LinkProblem())
+
+	res.SetLinkCheck(200, "", now)
+	assert.Nil(t, res.LinkError)
+	assert.Equal(t, "", res.LinkProblem())
+}
+
+func TestVeteranResource_Validate(t *testing.T) {
+	res := &VeteranResource{Title: "Apply for VA Health Care", URL: "https://www.va.gov/health-care/apply/", Category: "health_care", LinkStatus: LinkUnchecked}
+	verrs, err := res.Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+	assert.Equal(t, "www.va.gov", res.Host())
+	assert.Equal(t, "Health Care", res.CategoryLabel())
+
+	res.URL, res.Category = "javascript:alert(1)", "gardening"
+	verrs, _ = res.Validate(nil)
+	assert.NotEmpty(t, verrs.Get("url"))
+	assert.NotEmpty(t, verrs.Get("category"))
+}
+
+func TestGroupResources(t *testing.T) {
+	groups := GroupResources(VeteranResources{
+		{Title: "HUD-VASH", Category: "housing"},
+		{Title: "Veterans Crisis Line", Category: "crisis"},
+		{Title: "SSVF", Category: "housing"},
+	})
+	assert.Len(t, groups, 2)
+	assert.Equal(t, "Crisis Support", groups[0].Category.Label)
+	assert.Equal(t, "housing", groups[1].Category.Key)
+	assert.Len(t, groups[1].Resources, 2)
+	assert.Equal(t, "Legal Aid", ResourceCategoryLabel("legal"))
+	assert.Equal(t, "unknown", ResourceCategoryLabel("unknown"))
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// WebhookProviderHelcim is the provider name for Helcim payment webhooks
+const WebhookProviderHelcim = "helcim"
+
+// Webhook event processing states. An event is received before it's
+// processed, and is ignored when it's a kind of event we don't act on.
+const (
+	WebhookReceived  = "received"
+	WebhookProcessed = "processed"
+	WebhookIgnored   = "ignored"
+	WebhookFailed    = "failed"
+)
+
+// WebhookStatuses lists the processing states in the order the admin view
+// shows them
+var WebhookStatuses = []string{WebhookFailed, WebhookReceived, WebhookProcessed, WebhookIgnored}
+
+var webhookStatusLabels = map[string]string{
+	WebhookReceived:  "Received",
+	WebhookProcessed: "Processed",
+	WebhookIgnored:   "Ignored",
+	WebhookFailed:    "Failed",
+}
+
+// WebhookStatusLabel is the display name for a webhook processing state
+func WebhookStatusLabel(status string) string {
+	if label, ok := webhookStatusLabels[status]; ok {
+		return label
+	}
+	return status
+}
+
+// WebhookEventID is the ID an event is deduplicated on: the provider's own
+// event ID, or a hash of the payload when the provider didn't send one
+func WebhookEventID(id string, payload []byte) string {
+	if id != "" {
+		return id
+	}
+	sum := sha256.Sum256(payload)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// WebhookEvent is a payment webhook as it was delivered, kept so redelivered
+// events aren't processed twice and failed ones can be inspected and
+// replayed from the admin.
+type WebhookEvent struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Provider    string     `json:"provider" db:"provider"`
+	EventID     string     `json:"event_id" db:"event_id"`
+	EventType   string     `json:"event_type" db:"event_type"`
+	Payload     string     `json:"payload" db:"payload"`
+	Signature   *string    `json:"signature,omitempty" db:"signature"`
+	Status      string     `json:"status" db:"status"`
+	Error       *string    `json:"error,omitempty" db:"error"`
+	Deliveries  int        `json:"deliveries" db:"deliveries"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty" db:"processed_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// String is not required by pop and may be deleted
+func (w WebhookEvent) String() string {
+	js, _ := json.Marshal(w)
+	return string(js)
+}
+
+// WebhookEvents is not required by pop and may be deleted
+type WebhookEvents []WebhookEvent
+
+// StatusLabel is the display name of the event's processing state
+func (w WebhookEvent) StatusLabel() string {
+	return WebhookStatusLabel(w.Status)
+}
+
+// ErrorText is why the event's last processing attempt failed
+func (w WebhookEvent) ErrorText() string {
+	if w.Error == nil {
+		return ""
+	}
+	return *w.Error
+}
+
+// Handled reports whether the event was processed or deliberately ignored,
+// so a redelivery of it can be skipped
+func (w WebhookEvent) Handled() bool {
+	return w.Status == WebhookProcessed || w.Status == WebhookIgnored
+}
+
+// Replayable reports whether an admin can run the event again. Events
+// still marked received were interrupted before they finished.
+func (w WebhookEvent) Replayable() bool {
+	return w.Status == WebhookFailed || w.Status == WebhookReceived
+}
+
+// PrettyPayload is the payload indented for reading, or as delivered when
+// it isn't valid JSON
+func (w WebhookEvent) PrettyPayload() string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(w.Payload), "", "  "); err != nil {
+		return w.Payload
+	}
+	return out.String()
+}
+
+// Finish records the outcome of an attempt to process the event
+func (w *WebhookEvent) Finish(status string, err error, now time.Time) {
+	w.Status = status
+	w.Attempts++
+	if err != nil {
+		w.Status = WebhookFailed
+		msg := err.Error()
+		w.Error = &msg
+		return
+	}
+	w.Error = nil
+	w.ProcessedAt = &now
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (w *WebhookEvent) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: w.Provider, Name: "Provider"},
+		&validators.StringIsPresent{Field: w.EventID, Name: "EventID"},
+		&validators.StringInclusion{Field: w.Status, Name: "Status", List: WebhookStatuses},
+	), nil
+}
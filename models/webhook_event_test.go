@@ -0,0 +1,61 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookEventID(t *testing.T) {
+	assert.Equal(t, "evt-123", WebhookEventID("evt-123", []byte(`{}`)))
+
+	hashed := WebhookEventID("", []byte(`{"type":"cardTransaction"}`))
+	assert.Equal(t, hashed, WebhookEventID("", []byte(`{"type":"cardTransaction"}`)))
+	assert.NotEqual(t, hashed, WebhookEventID("", []byte(`{"type":"terminalCancel"}`)))
+	assert.Len(t, hashed, len("sha256:")+64)
+}
+
+func TestWebhookEvent_Finish(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	event := &WebhookEvent{Status: WebhookReceived}
+	assert.True(t, event.Replayable())
+	assert.False(t, event.Handled())
+
+	event.Finish(WebhookProcessed, errors.New("connection reset"), now)
+	assert.Equal(t, WebhookFailed, event.Status)
+	assert.Equal(t, "connection reset", event.ErrorText())
+	assert.Nil(t, event.ProcessedAt)
+	assert.Equal(t, 1, event.Attempts)
+	assert.True(t, event.Replayable())
+
+	event.Finish(WebhookProcessed, nil, now)
+	assert.Equal(t, WebhookProcessed, event.Status)
+	assert.Equal(t, "", event.ErrorText())
+	assert.Equal(t, now, *event.ProcessedAt)
+	assert.Equal(t, 2, event.Attempts)
+	assert.True(t, event.Handled())
+	assert.False(t, event.Replayable())
+
+	event.Finish(WebhookIgnored, nil, now)
+	assert.True(t, event.Handled())
+	assert.Equal(t, "Ignored", event.StatusLabel())
+}
+
+func TestWebhookEvent_PrettyPayload(t *testing.T) {
+	assert.Equal(t, "{\n  \"id\": \"evt-1\"\n}", WebhookEvent{Payload: `{"id":"evt-1"}`}.PrettyPayload())
+	assert.Equal(t, "not json", WebhookEvent{Payload: "not json"}.PrettyPayload())
+}
+
+func TestWebhookEvent_Validate(t *testing.T) {
+	event := &WebhookEvent{Provider: WebhookProviderHelcim, EventID: "evt-1", Status: WebhookReceived}
+	verrs, err := event.Validate(nil)
+	assert.NoError(t, err)
+	assert.False(t, verrs.HasAny())
+
+	event.Status = "done"
+	event.EventID = ""
+	verrs, _ = event.Validate(nil)
+	assert.True(t, verrs.HasAny())
+}
@@ -0,0 +1,81 @@
+package diagnostics
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Build metadata, set at link time:
+//
+//	go build -ldflags "-X avrnpo.org/pkg/diagnostics.Version=v1.2.3 \
+//	  -X avrnpo.org/pkg/diagnostics.Commit=$(git rev-parse HEAD) \
+//	  -X avrnpo.org/pkg/diagnostics.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// When they are left empty the VCS stamp Go records in the binary is used.
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+var startedAt = time.Now()
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	ShortSHA  string
+	BuildDate string
+	Modified  bool
+	GoVersion string
+	StartedAt time.Time
+}
+
+// Uptime is how long the process has been running.
+func (b BuildInfo) Uptime() time.Duration {
+	return time.Since(b.StartedAt).Round(time.Second)
+}
+
+// CurrentBuild returns the build metadata for the running binary.
+func CurrentBuild() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		StartedAt: startedAt,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	info.ShortSHA = info.Commit
+	if len(info.ShortSHA) > 7 {
+		info.ShortSHA = info.ShortSHA[:7]
+	}
+	return info
+}
@@ -0,0 +1,32 @@
+package diagnostics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrentBuild_LinkerValues(t *testing.T) {
+	oldVersion, oldCommit, oldDate := Version, Commit, BuildDate
+	defer func() { Version, Commit, BuildDate = oldVersion, oldCommit, oldDate }()
+
+	Version = "v1.4.0"
+	Commit = "0123456789abcdef"
+	BuildDate = "2026-10-14T12:00:00Z"
+
+	info := CurrentBuild()
+	assert.Equal(t, "v1.4.0", info.Version)
+	assert.Equal(t, "0123456", info.ShortSHA)
+	assert.Equal(t, "2026-10-14T12:00:00Z", info.BuildDate)
+	assert.NotEmpty(t, info.GoVersion)
+}
+
+func TestCurrentBuild_Defaults(t *testing.T) {
+	oldVersion, oldCommit, oldDate := Version, Commit, BuildDate
+	defer func() { Version, Commit, BuildDate = oldVersion, oldCommit, oldDate }()
+	Version, Commit, BuildDate = "", "", ""
+
+	info := CurrentBuild()
+	assert.NotEmpty(t, info.Version)
+	assert.NotEmpty(t, info.Commit)
+}
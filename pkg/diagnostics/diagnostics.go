@@ -0,0 +1,227 @@
+// Package diagnostics reports what the running binary was built from: build
+// version and commit, Go module versions, and the results of a govulncheck
+// scan embedded at build time (see `make vulncheck`).
+package diagnostics
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VulncheckFile is where `make vulncheck` writes the scan, relative to this
+// package. It's generated, not committed; builds without it report no scan.
+const VulncheckFile = "scan/vulncheck.json"
+
+//go:embed all:scan
+var scanFiles embed.FS
+
+// paymentPathModules are dependencies on the donation/payment request path.
+// Advisories against these should be patched first.
+var paymentPathModules = map[string]bool{
+	"stdlib":                          true,
+	"golang.org/x/crypto":             true,
+	"golang.org/x/net":                true,
+	"github.com/gobuffalo/buffalo":    true,
+	"github.com/gobuffalo/mw-csrf":    true,
+	"github.com/gorilla/sessions":     true,
+	"github.com/gorilla/securecookie": true,
+	"github.com/gofrs/uuid":           true,
+	"github.com/jackc/pgx/v5":         true,
+	"github.com/lib/pq":               true,
+}
+
+// Module is a dependency compiled into the binary.
+type Module struct {
+	Path        string
+	Version     string
+	Replace     string
+	PaymentPath bool
+}
+
+// Advisory is a known vulnerability that affects the build.
+type Advisory struct {
+	ID           string
+	Summary      string
+	Module       string
+	FoundVersion string
+	FixedVersion string
+	Called       bool // true when vulnerable code is reachable, not just imported
+	PaymentPath  bool
+}
+
+// Report is the combined diagnostics view.
+type Report struct {
+	GoVersion         string
+	MainModule        string
+	Modules           []Module
+	Advisories        []Advisory
+	ScanAvailable     bool
+	ScanUnavailable   string // why the build has no scan, when it tried to make one
+	ScannerVersion    string
+	DBLastModified    *time.Time
+	PaymentAdvisories int
+}
+
+// Build returns the diagnostics report for the running binary.
+func Build() Report {
+	report := Report{}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		report.GoVersion = info.GoVersion
+		report.MainModule = info.Main.Path
+		for _, dep := range info.Deps {
+			m := Module{Path: dep.Path, Version: dep.Version, PaymentPath: paymentPathModules[dep.Path]}
+			if dep.Replace != nil {
+				m.Replace = dep.Replace.Path + " " + dep.Replace.Version
+			}
+			report.Modules = append(report.Modules, m)
+		}
+		sort.Slice(report.Modules, func(i, j int) bool { return report.Modules[i].Path < report.Modules[j].Path })
+	}
+
+	embedded, _ := scanFiles.ReadFile(VulncheckFile)
+	scan := ParseVulncheck(embedded)
+	report.ScanAvailable = scan.Available
+	report.ScanUnavailable = scan.Unavailable
+	report.ScannerVersion = scan.ScannerVersion
+	report.DBLastModified = scan.DBLastModified
+	report.Advisories = scan.Advisories
+	for _, a := range scan.Advisories {
+		if a.PaymentPath {
+			report.PaymentAdvisories++
+		}
+	}
+	return report
+}
+
+// VulncheckResult is the parsed `govulncheck -json` output.
+type VulncheckResult struct {
+	Available      bool
+	Unavailable    string
+	ScannerVersion string
+	DBLastModified *time.Time
+	Advisories     []Advisory
+}
+
+type vulncheckMessage struct {
+	// Unavailable is written in place of a scan by `make build` when
+	// govulncheck couldn't run; it isn't part of govulncheck's output
+	Unavailable *struct {
+		Reason string `json:"reason"`
+	} `json:"unavailable"`
+	Config *struct {
+		ScannerVersion string     `json:"scanner_version"`
+		DBLastModified *time.Time `json:"db_last_modified"`
+	} `json:"config"`
+	OSV *struct {
+		ID      string `json:"id"`
+		Summary string `json:"summary"`
+	} `json:"osv"`
+	Finding *struct {
+		OSV          string `json:"osv"`
+		FixedVersion string `json:"fixed_version"`
+		Trace        []struct {
+			Module   string `json:"module"`
+			Version  string `json:"version"`
+			Function string `json:"function"`
+		} `json:"trace"`
+	} `json:"finding"`
+}
+
+// ValidateVulncheck checks that data is a complete `govulncheck -json`
+// stream, so a failed or empty scan isn't embedded as if it were clean.
+func ValidateVulncheck(data []byte) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return errors.New("scan output is empty")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	sawConfig := false
+	for n := 1; ; n++ {
+		var msg vulncheckMessage
+		err := dec.Decode(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("message %d isn't valid JSON: %w", n, err)
+		}
+		sawConfig = sawConfig || msg.Config != nil
+	}
+	if !sawConfig {
+		return errors.New("scan output has no govulncheck config message")
+	}
+	return nil
+}
+
+// ParseVulncheck reads the stream of JSON messages printed by
+// `govulncheck -json`. Only OSV entries with at least one finding are
+// returned, one per advisory and module.
+func ParseVulncheck(data []byte) VulncheckResult {
+	result := VulncheckResult{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return result
+	}
+	result.Available = true
+
+	summaries := map[string]string{}
+	byKey := map[string]*Advisory{}
+	var order []string
+
+	dec := json.NewDecoder(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		var msg vulncheckMessage
+		if err := dec.Decode(&msg); err != nil {
+			break
+		}
+		switch {
+		case msg.Unavailable != nil:
+			return VulncheckResult{Unavailable: msg.Unavailable.Reason}
+		case msg.Config != nil:
+			result.ScannerVersion = msg.Config.ScannerVersion
+			result.DBLastModified = msg.Config.DBLastModified
+		case msg.OSV != nil:
+			summaries[msg.OSV.ID] = msg.OSV.Summary
+		case msg.Finding != nil && len(msg.Finding.Trace) > 0:
+			top := msg.Finding.Trace[0]
+			key := msg.Finding.OSV + "|" + top.Module
+			adv, ok := byKey[key]
+			if !ok {
+				adv = &Advisory{
+					ID:           msg.Finding.OSV,
+					Module:       top.Module,
+					FoundVersion: top.Version,
+					FixedVersion: msg.Finding.FixedVersion,
+					PaymentPath:  paymentPathModules[top.Module],
+				}
+				byKey[key] = adv
+				order = append(order, key)
+			}
+			if top.Function != "" {
+				adv.Called = true
+			}
+		}
+	}
+
+	for _, key := range order {
+		adv := byKey[key]
+		adv.Summary = strings.TrimSpace(summaries[adv.ID])
+		result.Advisories = append(result.Advisories, *adv)
+	}
+	sort.SliceStable(result.Advisories, func(i, j int) bool {
+		a, b := result.Advisories[i], result.Advisories[j]
+		if a.PaymentPath != b.PaymentPath {
+			return a.PaymentPath
+		}
+		return a.Called && !b.Called
+	})
+	return result
+}
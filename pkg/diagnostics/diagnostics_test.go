@@ -0,0 +1,57 @@
+package diagnostics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleVulncheck = `{"config":{"protocol_version":"v1.0.0","scanner_name":"govulncheck","scanner_version":"v1.1.3","db_last_modified":"2026-10-01T00:00:00Z"}}
+{"progress":{"message":"Scanning your code..."}}
+{"osv":{"id":"GO-2026-0001","summary":"Header smuggling in net/http"}}
+{"osv":{"id":"GO-2026-0002","summary":"Unused vuln in a logger"}}
+{"finding":{"osv":"GO-2026-0002","fixed_version":"v1.2.0","trace":[{"module":"github.com/example/logger","version":"v1.1.0"}]}}
+{"finding":{"osv":"GO-2026-0001","fixed_version":"v1.24.9","trace":[{"module":"stdlib","version":"v1.24.1","package":"net/http"}]}}
+{"finding":{"osv":"GO-2026-0001","fixed_version":"v1.24.9","trace":[{"module":"stdlib","version":"v1.24.1","package":"net/http","function":"ReadRequest"}]}}
+`
+
+func TestParseVulncheck(t *testing.T) {
+	res := ParseVulncheck([]byte(sampleVulncheck))
+	assert.True(t, res.Available)
+	assert.Equal(t, "v1.1.3", res.ScannerVersion)
+	require.NotNil(t, res.DBLastModified)
+
+	require.Len(t, res.Advisories, 2)
+	// Payment path advisories sort first
+	first := res.Advisories[0]
+	assert.Equal(t, "GO-2026-0001", first.ID)
+	assert.Equal(t, "Header smuggling in net/http", first.Summary)
+	assert.True(t, first.PaymentPath)
+	assert.True(t, first.Called)
+	assert.Equal(t, "v1.24.9", first.FixedVersion)
+
+	assert.False(t, res.Advisories[1].Called)
+	assert.False(t, res.Advisories[1].PaymentPath)
+}
+
+func TestParseVulncheck_Empty(t *testing.T) {
+	res := ParseVulncheck(nil)
+	assert.False(t, res.Available)
+	assert.Empty(t, res.Advisories)
+}
+
+func TestValidateVulncheck(t *testing.T) {
+	assert.NoError(t, ValidateVulncheck([]byte(sampleVulncheck)))
+	assert.Error(t, ValidateVulncheck(nil))
+	assert.Error(t, ValidateVulncheck([]byte("go: errors parsing go.mod\n")))
+	assert.Error(t, ValidateVulncheck([]byte(`{"progress":{"message":"Scanning your code..."}}`)))
+	assert.Error(t, ValidateVulncheck([]byte(sampleVulncheck+`{"finding":{"osv":`)))
+}
+
+func TestParseVulncheck_Unavailable(t *testing.T) {
+	res := ParseVulncheck([]byte(`{"unavailable":{"reason":"govulncheck could not run when this build was made"}}` + "\n"))
+	assert.False(t, res.Available)
+	assert.Equal(t, "govulncheck could not run when this build was made", res.Unavailable)
+	assert.Error(t, ValidateVulncheck([]byte(`{"unavailable":{"reason":"offline"}}`)))
+}
@@ -0,0 +1,363 @@
+// Package errortracking reports errors and panics to a Sentry-compatible
+// endpoint (Sentry, GlitchTip, ...) using the store API, and keeps the most
+// recent errors in memory for the admin dashboard. Nothing is sent unless
+// SENTRY_DSN is set.
+package errortracking
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+const recentErrorLimit = 50
+
+// DSN is a parsed Sentry DSN: https://<public_key>@<host>/<project_id>
+type DSN struct {
+	PublicKey string
+	StoreURL  string
+}
+
+// ParseDSN parses a Sentry DSN into the store endpoint and key.
+func ParseDSN(raw string) (*DSN, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("errortracking: DSN is missing the public key")
+	}
+	project := strings.Trim(u.Path, "/")
+	if project == "" {
+		return nil, errors.New("errortracking: DSN is missing the project id")
+	}
+
+	// Self-hosted installs can live under a path prefix: /prefix/<project_id>
+	prefix := ""
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix = "/" + project[:i]
+		project = project[i+1:]
+	}
+
+	return &DSN{
+		PublicKey: u.User.Username(),
+		StoreURL:  fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project),
+	}, nil
+}
+
+// RequestInfo is the request context attached to an event. It deliberately
+// carries no query string, body, cookies or donor contact details.
+type RequestInfo struct {
+	Method    string
+	Path      string
+	RequestID string
+	UserID    string
+	UserAgent string
+}
+
+// Event is an error captured by the tracker.
+type Event struct {
+	ID        string
+	Time      time.Time
+	Level     string
+	Message   string
+	Type      string
+	Culprit   string
+	Frames    []Frame
+	Request   *RequestInfo
+	Tags      map[string]string
+	Release   string
+	Delivered bool
+}
+
+// Frame is one stack frame.
+type Frame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// Tracker sends events and remembers recent ones.
+type Tracker struct {
+	dsn         *DSN
+	environment string
+	release     string
+	client      *http.Client
+	queue       chan *Event
+
+	mu     sync.RWMutex
+	recent []Event
+}
+
+// New creates a tracker. dsn may be empty, in which case events are only
+// kept in memory.
+func New(dsn, environment, release string) (*Tracker, error) {
+	t := &Tracker{
+		environment: environment,
+		release:     release,
+		client:      &http.Client{Timeout: 5 * time.Second},
+	}
+	if strings.TrimSpace(dsn) != "" {
+		parsed, err := ParseDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+		t.dsn = parsed
+		t.queue = make(chan *Event, 100)
+		go t.run()
+	}
+	return t, nil
+}
+
+var (
+	defaultTracker *Tracker
+	defaultOnce    sync.Once
+)
+
+// Init configures the default tracker from SENTRY_DSN and SENTRY_ENVIRONMENT.
+// It is safe to call more than once; only the first call takes effect.
+func Init(environment, release string) error {
+	var err error
+	defaultOnce.Do(func() {
+		env := envy.Get("SENTRY_ENVIRONMENT", environment)
+		defaultTracker, err = New(envy.Get("SENTRY_DSN", ""), env, release)
+		if err != nil {
+			// Keep capturing locally even if the DSN is bad
+			defaultTracker, _ = New("", env, release)
+		}
+	})
+	return err
+}
+
+// Default returns the default tracker, creating an in-memory one if Init
+// hasn't been called.
+func Default() *Tracker {
+	if defaultTracker == nil {
+		Init(envy.Get("GO_ENV", "development"), "")
+	}
+	return defaultTracker
+}
+
+// Capture reports err with optional request context and tags.
+func Capture(err error, req *RequestInfo, tags map[string]string) string {
+	return Default().Capture(err, req, tags)
+}
+
+// Recover reports a panic from a background goroutine and swallows it.
+// Use as: defer errortracking.Recover("worker-name")
+func Recover(worker string) {
+	if r := recover(); r != nil {
+		err, ok := r.(error)
+		if !ok {
+			err = fmt.Errorf("%v", r)
+		}
+		Default().capture(err, "fatal", nil, map[string]string{"worker": worker}, 4)
+	}
+}
+
+// Recent returns the most recently captured errors, newest first.
+func Recent(limit int) []Event {
+	return Default().Recent(limit)
+}
+
+// Enabled reports whether events are being sent to a remote endpoint.
+func (t *Tracker) Enabled() bool {
+	return t.dsn != nil
+}
+
+// Capture reports err and returns the event ID.
+func (t *Tracker) Capture(err error, req *RequestInfo, tags map[string]string) string {
+	return t.capture(err, "error", req, tags, 3)
+}
+
+func (t *Tracker) capture(err error, level string, req *RequestInfo, tags map[string]string, skip int) string {
+	if err == nil {
+		return ""
+	}
+
+	ev := Event{
+		ID:      strings.ReplaceAll(uuid.Must(uuid.NewV4()).String(), "-", ""),
+		Time:    time.Now().UTC(),
+		Level:   level,
+		Message: err.Error(),
+		Type:    fmt.Sprintf("%T", errors.Cause(err)),
+		Frames:  stackFrames(err, skip),
+		Request: req,
+		Tags:    tags,
+		Release: t.release,
+	}
+	if len(ev.Frames) > 0 {
+		ev.Culprit = ev.Frames[0].Function
+	}
+
+	if t.queue != nil {
+		select {
+		case t.queue <- &ev:
+		default:
+			// Drop rather than block request handling when the endpoint is slow
+		}
+	}
+
+	t.mu.Lock()
+	t.recent = append([]Event{ev}, t.recent...)
+	if len(t.recent) > recentErrorLimit {
+		t.recent = t.recent[:recentErrorLimit]
+	}
+	t.mu.Unlock()
+
+	return ev.ID
+}
+
+// Recent returns up to limit recent events, newest first.
+func (t *Tracker) Recent(limit int) []Event {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if limit <= 0 || limit > len(t.recent) {
+		limit = len(t.recent)
+	}
+	return append([]Event(nil), t.recent[:limit]...)
+}
+
+func (t *Tracker) run() {
+	for ev := range t.queue {
+		if err := t.send(ev); err == nil {
+			t.markDelivered(ev.ID)
+		}
+	}
+}
+
+func (t *Tracker) markDelivered(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.recent {
+		if t.recent[i].ID == id {
+			t.recent[i].Delivered = true
+			return
+		}
+	}
+}
+
+// payload builds the Sentry store API event body.
+func (t *Tracker) payload(ev *Event) map[string]interface{} {
+	// Sentry wants frames oldest first
+	frames := make([]Frame, len(ev.Frames))
+	for i, f := range ev.Frames {
+		frames[len(ev.Frames)-1-i] = f
+	}
+
+	body := map[string]interface{}{
+		"event_id":    ev.ID,
+		"timestamp":   ev.Time.Format(time.RFC3339),
+		"level":       ev.Level,
+		"platform":    "go",
+		"logger":      "avrnpo.org",
+		"environment": t.environment,
+		"release":     ev.Release,
+		"culprit":     ev.Culprit,
+		"tags":        ev.Tags,
+		"exception": map[string]interface{}{
+			"values": []map[string]interface{}{{
+				"type":       ev.Type,
+				"value":      ev.Message,
+				"stacktrace": map[string]interface{}{"frames": frames},
+			}},
+		},
+	}
+	if ev.Request != nil {
+		body["request"] = map[string]interface{}{
+			"method":  ev.Request.Method,
+			"url":     ev.Request.Path,
+			"headers": map[string]string{"User-Agent": ev.Request.UserAgent},
+		}
+		if ev.Request.UserID != "" {
+			body["user"] = map[string]string{"id": ev.Request.UserID}
+		}
+		if ev.Request.RequestID != "" {
+			tags := map[string]string{"request_id": ev.Request.RequestID}
+			for k, v := range ev.Tags {
+				tags[k] = v
+			}
+			body["tags"] = tags
+		}
+	}
+	return body
+}
+
+func (t *Tracker) send(ev *Event) error {
+	data, err := json.Marshal(t.payload(ev))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.dsn.StoreURL, bytes.NewReader(data))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=avrnpo/1.0, sentry_key=%s", t.dsn.PublicKey))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error tracking endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+// stackFrames uses the pkg/errors stack when err carries one, otherwise the
+// current goroutine's stack. Frames are returned innermost first.
+func stackFrames(err error, skip int) []Frame {
+	var pcs []uintptr
+
+	var st stackTracer
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if s, ok := e.(stackTracer); ok {
+			st = s
+		}
+	}
+	if st != nil {
+		for _, f := range st.StackTrace() {
+			pcs = append(pcs, uintptr(f))
+		}
+	} else {
+		buf := make([]uintptr, 64)
+		n := runtime.Callers(skip, buf)
+		pcs = buf[:n]
+	}
+
+	frames := make([]Frame, 0, len(pcs))
+	iter := runtime.CallersFrames(pcs)
+	for {
+		f, more := iter.Next()
+		if f.Function != "" {
+			frames = append(frames, Frame{
+				Function: f.Function,
+				Filename: f.File,
+				Lineno:   f.Line,
+				InApp:    strings.HasPrefix(f.Function, "avrnpo.org/"),
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	return frames
+}
@@ -0,0 +1,91 @@
+package errortracking
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDSN(t *testing.T) {
+	dsn, err := ParseDSN("https://abc123@o1.ingest.sentry.io/42")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", dsn.PublicKey)
+	assert.Equal(t, "https://o1.ingest.sentry.io/api/42/store/", dsn.StoreURL)
+
+	dsn, err = ParseDSN("https://key@glitchtip.example.org/sentry/7")
+	require.NoError(t, err)
+	assert.Equal(t, "https://glitchtip.example.org/sentry/api/7/store/", dsn.StoreURL)
+
+	_, err = ParseDSN("https://o1.ingest.sentry.io/42")
+	assert.Error(t, err)
+	_, err = ParseDSN("https://key@o1.ingest.sentry.io/")
+	assert.Error(t, err)
+}
+
+func TestCapture_KeepsRecentWithoutDSN(t *testing.T) {
+	tr, err := New("", "test", "v1")
+	require.NoError(t, err)
+	assert.False(t, tr.Enabled())
+
+	id := tr.Capture(errors.New("first"), nil, nil)
+	tr.Capture(errors.New("second"), nil, nil)
+	assert.NotEmpty(t, id)
+
+	recent := tr.Recent(10)
+	require.Len(t, recent, 2)
+	assert.Equal(t, "second", recent[0].Message)
+	assert.NotEmpty(t, recent[0].Frames)
+	assert.Equal(t, "", tr.Capture(nil, nil, nil))
+}
+
+func TestCapture_SendsToEndpoint(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("X-Sentry-Auth")
+		body, _ := io.ReadAll(r.Body)
+		var ev map[string]interface{}
+		json.Unmarshal(body, &ev)
+		received <- ev
+	}))
+	defer srv.Close()
+
+	dsn := strings.Replace(srv.URL, "http://", "http://pubkey@", 1) + "/9"
+	tr, err := New(dsn, "production", "v2.0.0+abc1234")
+	require.NoError(t, err)
+
+	tr.Capture(errors.New("payment declined"), &RequestInfo{Method: "POST", Path: "/api/donations/process", RequestID: "rid-1", UserID: "u-1"}, map[string]string{"component": "payments"})
+
+	select {
+	case ev := <-received:
+		assert.Equal(t, "production", ev["environment"])
+		assert.Equal(t, "v2.0.0+abc1234", ev["release"])
+		tags := ev["tags"].(map[string]interface{})
+		assert.Equal(t, "rid-1", tags["request_id"])
+		assert.Equal(t, "payments", tags["component"])
+		assert.Equal(t, "/api/donations/process", ev["request"].(map[string]interface{})["url"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+	assert.Contains(t, auth, "sentry_key=pubkey")
+}
+
+func TestRecover_CapturesPanic(t *testing.T) {
+	func() {
+		defer Recover("test-worker")
+		panic("boom")
+	}()
+
+	recent := Recent(1)
+	require.Len(t, recent, 1)
+	assert.Equal(t, "boom", recent[0].Message)
+	assert.Equal(t, "test-worker", recent[0].Tags["worker"])
+}
@@ -0,0 +1,170 @@
+// Package helpers is the site's Plush template helper library: money and
+// date formatting, pluralization, per-field validation errors, the CSRF form
+// field and permission checks. Templates should format values with these
+// rather than handlers setting pre-formatted strings.
+package helpers
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/flect"
+	"github.com/gobuffalo/helpers/hctx"
+	"github.com/gobuffalo/tags/v3"
+)
+
+// Helpers returns the helpers under the names templates call them by. csrf
+// is kept as an alias of csrfField for the many forms that already use it.
+func Helpers() render.Helpers {
+	return render.Helpers{
+		"money":          Money,
+		"dateFormat":     DateFormat,
+		"pluralize":      Pluralize,
+		"errorsFor":      ErrorsFor,
+		"csrfField":      CSRFField,
+		"csrf":           CSRFField,
+		"currentUserCan": CurrentUserCan,
+		"stripTags":      StripTags,
+	}
+}
+
+// Money formats an amount as US dollars with cents and thousands
+// separators, e.g. $1,250.00. It takes the float64 amounts on models as well
+// as the numeric strings some handlers still pass; anything else is $0.00.
+func Money(amount interface{}) string {
+	var v float64
+	switch a := amount.(type) {
+	case float64:
+		v = a
+	case float32:
+		v = float64(a)
+	case int:
+		v = float64(a)
+	case int64:
+		v = float64(a)
+	case string:
+		v, _ = strconv.ParseFloat(strings.NewReplacer("$", "", ",", "").Replace(strings.TrimSpace(a)), 64)
+	}
+
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	cents := int64(math.Round(v * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return fmt.Sprintf("%s$%s.%02d", sign, whole, cents%100)
+}
+
+// DateFormat formats a time with a Go layout. Unset optional times (a nil
+// *time.Time) and zero times format as blank.
+func DateFormat(t interface{}, layout string) string {
+	switch v := t.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(layout)
+	case *time.Time:
+		if v == nil || v.IsZero() {
+			return ""
+		}
+		return v.Format(layout)
+	}
+	return ""
+}
+
+// Pluralize prefixes a noun with its count, using plural when the count
+// isn't one. Without plural an "s" is added to singular.
+func Pluralize(count int, singular string, plural ...string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, singular)
+	}
+	word := singular + "s"
+	if len(plural) > 0 {
+		word = plural[0]
+	}
+	return fmt.Sprintf("%d %s", count, word)
+}
+
+// fieldErrors is satisfied by both validate and validate/v3 error sets
+type fieldErrors interface {
+	Get(key string) []string
+}
+
+// ErrorsFor returns the validation messages for one field from the
+// "errors" set in the context, or none when no errors are set. The field may
+// be the error key (donor_email) or the struct field name (DonorEmail).
+func ErrorsFor(field string, help hctx.HelperContext) []string {
+	if help == nil {
+		return nil
+	}
+	errs := help.Value("errors")
+	fe, ok := errs.(fieldErrors)
+	if !ok {
+		return nil
+	}
+	// A nil *validate.Errors would panic on Get
+	if v := reflect.ValueOf(errs); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	if messages := fe.Get(field); len(messages) > 0 {
+		return messages
+	}
+	return fe.Get(flect.Underscore(field))
+}
+
+// CSRFField renders the hidden authenticity_token input for a form, or
+// nothing when CSRF protection isn't running (e.g. in tests)
+func CSRFField(opts tags.Options, help hctx.HelperContext) (template.HTML, error) {
+	if help == nil {
+		return template.HTML(""), nil
+	}
+
+	token := help.Value("authenticity_token")
+	if token == nil {
+		return template.HTML(""), nil
+	}
+
+	return template.HTML(fmt.Sprintf(`<input name="authenticity_token" type="hidden" value="%s" />`, template.HTMLEscapeString(fmt.Sprint(token)))), nil
+}
+
+// permissionChecker is implemented by models.User
+type permissionChecker interface {
+	Can(permission string) bool
+}
+
+// CurrentUserCan reports whether the signed in user has a permission. It's
+// false for visitors.
+func CurrentUserCan(permission string, help hctx.HelperContext) bool {
+	if help == nil {
+		return false
+	}
+	user, ok := help.Value("current_user").(permissionChecker)
+	if !ok {
+		return false
+	}
+	return user.Can(permission)
+}
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// StripTags removes HTML tags from content and collapses whitespace, for
+// excerpts and meta descriptions
+func StripTags(content string) string {
+	cleaned := htmlTagPattern.ReplaceAllString(content, "")
+	return whitespacePattern.ReplaceAllString(strings.TrimSpace(cleaned), " ")
+}
@@ -0,0 +1,83 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/plush/v4"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func renderHelper(t *testing.T, input string, values map[string]interface{}) string {
+	t.Helper()
+	ctx := plush.NewContext()
+	for k, v := range Helpers() {
+		ctx.Set(k, v)
+	}
+	for k, v := range values {
+		ctx.Set(k, v)
+	}
+	out, err := plush.Render(input, ctx)
+	require.NoError(t, err)
+	return out
+}
+
+func TestMoney(t *testing.T) {
+	assert.Equal(t, "$0.00", Money(0.0))
+	assert.Equal(t, "$25.00", Money(25))
+	assert.Equal(t, "$1,250.50", Money(1250.5))
+	assert.Equal(t, "$1,234,567.89", Money(1234567.891))
+	assert.Equal(t, "-$40.00", Money(-40.0))
+	assert.Equal(t, "$1,000.00", Money("1000"))
+	assert.Equal(t, "$0.00", Money(nil))
+}
+
+func TestDateFormat(t *testing.T) {
+	d := time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, "October 1, 2026", DateFormat(d, "January 2, 2006"))
+	assert.Equal(t, "October 1, 2026", DateFormat(&d, "January 2, 2006"))
+	assert.Equal(t, "", DateFormat((*time.Time)(nil), "January 2, 2006"))
+	assert.Equal(t, "", DateFormat(time.Time{}, "January 2, 2006"))
+}
+
+func TestPluralize(t *testing.T) {
+	assert.Equal(t, "1 gift", Pluralize(1, "gift"))
+	assert.Equal(t, "3 gifts", Pluralize(3, "gift"))
+	assert.Equal(t, "0 entries", Pluralize(0, "entry", "entries"))
+}
+
+func TestErrorsFor(t *testing.T) {
+	errs := validate.NewErrors()
+	errs.Add("donor_email", "Email address is required")
+
+	tmpl := `<%= for (msg) in errorsFor("DonorEmail") { %>[<%= msg %>]<% } %>`
+	assert.Equal(t, "[Email address is required]", renderHelper(t, tmpl, map[string]interface{}{"errors": errs}))
+	assert.Equal(t, "", renderHelper(t, `<%= for (msg) in errorsFor("city") { %>[<%= msg %>]<% } %>`, map[string]interface{}{"errors": errs}))
+
+	// No errors in the context, or a nil set, renders nothing
+	assert.Equal(t, "", renderHelper(t, tmpl, nil))
+	assert.Equal(t, "", renderHelper(t, tmpl, map[string]interface{}{"errors": (*validate.Errors)(nil)}))
+}
+
+type fakeUser struct{ admin bool }
+
+func (u fakeUser) Can(permission string) bool { return u.admin }
+
+func TestCurrentUserCan(t *testing.T) {
+	tmpl := `<%= if (currentUserCan("view_admin")) { %>admin<% } else { %>visitor<% } %>`
+	assert.Equal(t, "visitor", renderHelper(t, tmpl, nil))
+	assert.Equal(t, "visitor", renderHelper(t, tmpl, map[string]interface{}{"current_user": fakeUser{}}))
+	assert.Equal(t, "admin", renderHelper(t, tmpl, map[string]interface{}{"current_user": fakeUser{admin: true}}))
+}
+
+func TestCSRFField(t *testing.T) {
+	assert.Equal(t, "", renderHelper(t, `<%= csrfField() %>`, nil))
+	out := renderHelper(t, `<%= csrfField() %>`, map[string]interface{}{"authenticity_token": `a"b`})
+	assert.Equal(t, `<input name="authenticity_token" type="hidden" value="a&#34;b" />`, out)
+}
+
+func TestStripTags(t *testing.T) {
+	assert.Equal(t, "Hello world", StripTags("<p>Hello\n  <b>world</b></p>"))
+}
@@ -0,0 +1,164 @@
+// Package ical writes iCalendar (RFC 5545) files for the site's events, both
+// the subscribable feed and single-event downloads. It only writes the
+// handful of VEVENT properties calendar apps need to show an event.
+package ical
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the MIME type of an iCalendar file
+const ContentType = "text/calendar; charset=utf-8"
+
+// timeLayout is a UTC DATE-TIME value
+const timeLayout = "20060102T150405Z"
+
+// maxLineOctets is the longest a content line may be before it's folded
+const maxLineOctets = 75
+
+// Event is one VEVENT
+type Event struct {
+	// UID must stay the same for an event across downloads so calendar apps
+	// update it rather than adding a copy
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	// Updated is when the event last changed, written as DTSTAMP and
+	// LAST-MODIFIED
+	Updated   time.Time
+	Cancelled bool
+}
+
+// Calendar is a VCALENDAR holding events
+type Calendar struct {
+	// ProdID names the software that wrote the calendar
+	ProdID string
+	// Name is shown by calendar apps that subscribe to a feed
+	Name        string
+	Description string
+	// RefreshInterval hints how often subscribers should fetch the feed.
+	// Zero leaves it to the app.
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// WriteTo writes the calendar to w
+func (c Calendar) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	line := func(name, value string) {
+		writeLine(&buf, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", c.ProdID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", Escape(c.Name))
+	}
+	if c.Description != "" {
+		line("X-WR-CALDESC", Escape(c.Description))
+	}
+	if c.RefreshInterval > 0 {
+		duration := formatDuration(c.RefreshInterval)
+		line("REFRESH-INTERVAL;VALUE=DURATION", duration)
+		line("X-PUBLISHED-TTL", duration)
+	}
+	for _, e := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", formatTime(e.Updated))
+		line("LAST-MODIFIED", formatTime(e.Updated))
+		line("DTSTART", formatTime(e.Start))
+		line("DTEND", formatTime(e.End))
+		line("SUMMARY", Escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", Escape(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION", Escape(e.Location))
+		}
+		if e.URL != "" {
+			line("URL", e.URL)
+		}
+		if e.Cancelled {
+			line("STATUS", "CANCELLED")
+		} else {
+			line("STATUS", "CONFIRMED")
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// Bytes is the calendar as an iCalendar file
+func (c Calendar) Bytes() []byte {
+	var buf bytes.Buffer
+	c.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// Escape escapes a TEXT value: backslashes, semicolons, commas and newlines
+func Escape(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// formatDuration writes d as a DURATION value to the minute, e.g. "PT1H30M"
+func formatDuration(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	s := "PT"
+	if h := minutes / 60; h > 0 {
+		s += strconv.Itoa(h) + "H"
+	}
+	if m := minutes % 60; m > 0 {
+		s += strconv.Itoa(m) + "M"
+	}
+	return s
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// writeLine writes a content line ending in CRLF, folding it onto
+// continuation lines that start with a space so no line is longer than 75
+// octets. Folds never split a UTF-8 character.
+func writeLine(buf *bytes.Buffer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		buf.WriteString(s[:cut])
+		buf.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space counts towards the continuation line's length
+		limit = maxLineOctets - 1
+	}
+	buf.WriteString(s)
+	buf.WriteString("\r\n")
+}
@@ -0,0 +1,68 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `Dinner\, drinks\; and a toast\nBring a friend \\ spouse`, Escape("Dinner, drinks; and a toast\r\nBring a friend \\ spouse"))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "PT1H", formatDuration(time.Hour))
+	assert.Equal(t, "PT10M", formatDuration(10*time.Minute))
+	assert.Equal(t, "PT1H30M", formatDuration(90*time.Minute))
+	assert.Equal(t, "PT1M", formatDuration(time.Second))
+}
+
+func TestCalendar_Bytes(t *testing.T) {
+	start := time.Date(2026, 11, 11, 18, 0, 0, 0, time.FixedZone("CST", -6*3600))
+	cal := Calendar{
+		ProdID:          "-//AVR//Events//EN",
+		Name:            "AVR Events",
+		RefreshInterval: 6 * time.Hour,
+		Events: []Event{{
+			UID:         "abc@avrnpo.org",
+			Summary:     "Veterans Day Dinner",
+			Description: strings.Repeat("Join us for dinner. ", 8),
+			Location:    "VFW Post 9182, Austin, TX",
+			URL:         "https://avrnpo.org/events",
+			Start:       start,
+			End:         start.Add(3 * time.Hour),
+			Updated:     time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+		}},
+	}
+	out := string(cal.Bytes())
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.Contains(t, out, "\r\nDTSTART:20261112T000000Z\r\n")
+	assert.Contains(t, out, "\r\nDTEND:20261112T030000Z\r\n")
+	assert.Contains(t, out, "\r\nLOCATION:VFW Post 9182\\, Austin\\, TX\r\n")
+	assert.Contains(t, out, "\r\nREFRESH-INTERVAL;VALUE=DURATION:PT6H\r\n")
+	assert.Contains(t, out, "\r\nSTATUS:CONFIRMED\r\n")
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), maxLineOctets, line)
+	}
+	// Unfolding gives the description back
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	assert.Contains(t, unfolded, "DESCRIPTION:"+strings.Repeat("Join us for dinner. ", 8)+"\r\n")
+}
+
+func TestWriteLine_KeepsCharactersWhole(t *testing.T) {
+	var c Calendar
+	c.ProdID = "-//AVR//Events//EN"
+	c.Events = []Event{{UID: "x", Summary: strings.Repeat("é", 60)}}
+	out := string(c.Bytes())
+
+	for _, line := range strings.Split(out, "\r\n") {
+		assert.LessOrEqual(t, len(line), maxLineOctets)
+		assert.True(t, strings.ToValidUTF8(line, "?") == line, line)
+	}
+	assert.Contains(t, strings.ReplaceAll(out, "\r\n ", ""), "SUMMARY:"+strings.Repeat("é", 60))
+}
@@ -0,0 +1,386 @@
+// Package imagescrub removes what a photo gives away beyond the picture. Strip
+// drops EXIF (including GPS position), XMP, IPTC and text metadata from JPEG,
+// PNG and WebP files without re-encoding them, and Blur pixelates chosen
+// areas such as faces. Project photos are taken at veterans' homes, so this
+// runs on every image before it's stored.
+package imagescrub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// Image types the package handles
+const (
+	JPEG = "image/jpeg"
+	PNG  = "image/png"
+	WebP = "image/webp"
+)
+
+// jpegQuality is used when a JPEG has to be re-encoded, to bake in its
+// orientation or blur an area
+const jpegQuality = 90
+
+// ErrUnsupported is returned for image types the package can't rewrite
+var ErrUnsupported = errors.New("unsupported image type")
+
+var errMalformed = errors.New("malformed image")
+
+// Strip returns the image without its metadata. A JPEG whose EXIF says it
+// should be shown rotated is re-encoded the right way up first, since the
+// orientation tag goes with the rest of the metadata.
+func Strip(data []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case JPEG:
+		if orientation := jpegOrientation(data); orientation > 1 {
+			img, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			return encode(orient(toRGBA(img), orientation), JPEG)
+		}
+		return stripJPEG(data)
+	case PNG:
+		return stripPNG(data)
+	case WebP:
+		return stripWebP(data)
+	}
+	return nil, ErrUnsupported
+}
+
+// CanBlur reports whether Blur can rewrite images of contentType. WebP
+// can't be re-encoded with the standard library.
+func CanBlur(contentType string) bool {
+	return contentType == JPEG || contentType == PNG
+}
+
+// Blur pixelates regions of a JPEG or PNG so faces, house numbers and the
+// like can't be made out. The result carries no metadata.
+func Blur(data []byte, contentType string, regions []Region) ([]byte, error) {
+	if !CanBlur(contentType) {
+		return nil, ErrUnsupported
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	rgba := toRGBA(img)
+	if contentType == JPEG {
+		rgba = orient(rgba, jpegOrientation(data))
+	}
+	for _, region := range regions {
+		pixelate(rgba, region.Rect(rgba.Bounds()))
+	}
+	return encode(rgba, contentType)
+}
+
+// Region is an area of an image as fractions of its width and height, so
+// it holds whatever the image's size
+type Region struct {
+	X, Y, W, H float64
+}
+
+// Rect is the region within bounds, in pixels
+func (r Region) Rect(bounds image.Rectangle) image.Rectangle {
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	rect := image.Rect(
+		bounds.Min.X+int(r.X*w),
+		bounds.Min.Y+int(r.Y*h),
+		bounds.Min.X+int((r.X+r.W)*w+0.5),
+		bounds.Min.Y+int((r.Y+r.H)*h+0.5),
+	)
+	return rect.Intersect(bounds)
+}
+
+// ParseRegions reads areas written as "left,top,width,height" percentages
+// of the image, one per line or separated by semicolons, e.g. "40,10,20,25"
+func ParseRegions(s string) ([]Region, error) {
+	regions := []Region{}
+	for _, line := range strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == ';' }) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, ",")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("%q should be left,top,width,height", line)
+		}
+		var v [4]float64
+		for i, part := range parts {
+			n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(part), "%")), 64)
+			if err != nil || n < 0 || n > 100 {
+				return nil, fmt.Errorf("%q should be percentages from 0 to 100", line)
+			}
+			v[i] = n / 100
+		}
+		if v[2] == 0 || v[3] == 0 {
+			return nil, fmt.Errorf("%q has no width or height", line)
+		}
+		regions = append(regions, Region{X: v[0], Y: v[1], W: v[2], H: v[3]})
+	}
+	return regions, nil
+}
+
+// pixelate replaces rect with blocks of its average colour, coarse enough
+// (six or so across) that a face can't be recognised
+func pixelate(img *image.RGBA, rect image.Rectangle) {
+	if rect.Empty() {
+		return
+	}
+	block := max(rect.Dx(), rect.Dy()) / 6
+	block = max(block, 6)
+	for by := rect.Min.Y; by < rect.Max.Y; by += block {
+		for bx := rect.Min.X; bx < rect.Max.X; bx += block {
+			cell := image.Rect(bx, by, bx+block, by+block).Intersect(rect)
+			var r, g, b, a, n int
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				for x := cell.Min.X; x < cell.Max.X; x++ {
+					i := img.PixOffset(x, y)
+					r += int(img.Pix[i])
+					g += int(img.Pix[i+1])
+					b += int(img.Pix[i+2])
+					a += int(img.Pix[i+3])
+					n++
+				}
+			}
+			avg := [4]uint8{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)}
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				for x := cell.Min.X; x < cell.Max.X; x++ {
+					copy(img.Pix[img.PixOffset(x, y):], avg[:])
+				}
+			}
+		}
+	}
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == PNG {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	return buf.Bytes(), err
+}
+
+// orient turns an image the way its EXIF orientation (2-8) says it's meant
+// to be seen
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):])
+		}
+	}
+	return dst
+}
+
+// jpegSegments calls fn with each marker segment before the image data
+// (marker, whole segment including its marker), then returns the offset of
+// the start of scan
+func jpegSegments(data []byte, fn func(marker byte, segment []byte)) (int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, errMalformed
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 0, errMalformed
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			// Fill byte before a marker
+			i++
+			continue
+		}
+		if marker == 0xDA {
+			return i, nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 0, errMalformed
+		}
+		fn(marker, data[i:i+2+length])
+		i += 2 + length
+	}
+	return 0, errMalformed
+}
+
+// keepJPEGSegment reports whether a segment is needed to show the image.
+// JFIF, Adobe colour transform and ICC profile segments are kept; EXIF, XMP,
+// IPTC, comments and other application data are dropped.
+func keepJPEGSegment(marker byte, segment []byte) bool {
+	switch {
+	case marker == 0xE0 || marker == 0xEE:
+		return true
+	case marker == 0xE2:
+		return bytes.HasPrefix(segment[4:], []byte("ICC_PROFILE\x00"))
+	case marker >= 0xE1 && marker <= 0xEF, marker == 0xFE:
+		return false
+	}
+	return true
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	scan, err := jpegSegments(data, func(marker byte, segment []byte) {
+		if keepJPEGSegment(marker, segment) {
+			out = append(out, segment...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[scan:]...), nil
+}
+
+// jpegOrientation is the EXIF orientation of a JPEG, or 0 if it has none
+func jpegOrientation(data []byte) int {
+	orientation := 0
+	jpegSegments(data, func(marker byte, segment []byte) {
+		if marker != 0xE1 || orientation != 0 || !bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			return
+		}
+		orientation = exifOrientation(segment[10:])
+	})
+	return orientation
+}
+
+// exifOrientation reads the orientation tag from IFD0 of a TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// droppedPNGChunks hold metadata rather than image data
+var droppedPNGChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+func stripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, errMalformed
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+	for i := len(signature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, errMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errMalformed
+		}
+		if !droppedPNGChunks[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
+
+// VP8X flags saying a WebP carries EXIF or XMP metadata
+const webpMetadataFlags = 0x08 | 0x04
+
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errMalformed
+	}
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, errMalformed
+		}
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2
+		if size < 0 || end > len(data) {
+			return nil, errMalformed
+		}
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if size > 0 {
+				chunk[8] &^= webpMetadataFlags
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
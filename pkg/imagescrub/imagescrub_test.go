@@ -0,0 +1,184 @@
+package imagescrub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testImage is a 40x20 image, red on the left half and blue on the right
+func testImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 20 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// exifSegment is an APP1 segment with an orientation tag and a GPS-looking
+// string that mustn't survive
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, "GPS 38.8977N 77.0365W"...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func jpegWithMetadata(t *testing.T, orientation uint16) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, testImage(), nil))
+	encoded := buf.Bytes()
+
+	comment := []byte{0xFF, 0xFE, 0x00, 0x0C}
+	comment = append(comment, "Home photo"...)
+	data := append([]byte{0xFF, 0xD8}, exifSegment(orientation)...)
+	data = append(data, comment...)
+	return append(data, encoded[2:]...)
+}
+
+func TestStrip_JPEG(t *testing.T) {
+	data := jpegWithMetadata(t, 1)
+	require.Equal(t, 1, jpegOrientation(data))
+
+	stripped, err := Strip(data, JPEG)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "GPS")
+	assert.NotContains(t, string(stripped), "Home photo")
+	assert.Equal(t, 0, jpegOrientation(stripped))
+
+	img, err := jpeg.Decode(bytes.NewReader(stripped))
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(40, 20), img.Bounds().Size())
+}
+
+func TestStrip_JPEGKeepsOrientation(t *testing.T) {
+	stripped, err := Strip(jpegWithMetadata(t, 6), JPEG)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "GPS")
+
+	// Rotated a quarter turn clockwise: red (the left half) ends up on top
+	img, err := jpeg.Decode(bytes.NewReader(stripped))
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(20, 40), img.Bounds().Size())
+	r, _, b, _ := img.At(10, 5).RGBA()
+	assert.Greater(t, r, b)
+	r, _, b, _ = img.At(10, 35).RGBA()
+	assert.Greater(t, b, r)
+}
+
+func pngChunk(kind string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, kind...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestStrip_PNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage()))
+	encoded := buf.Bytes()
+	iend := len(encoded) - 12
+	data := append([]byte(nil), encoded[:iend]...)
+	data = append(data, pngChunk("tEXt", []byte("Location\x00123 Main St"))...)
+	data = append(data, pngChunk("eXIf", []byte("MM\x00*GPS"))...)
+	data = append(data, encoded[iend:]...)
+
+	stripped, err := Strip(data, PNG)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "Main St")
+	assert.NotContains(t, string(stripped), "GPS")
+	_, err = png.Decode(bytes.NewReader(stripped))
+	assert.NoError(t, err)
+}
+
+func TestStrip_WebP(t *testing.T) {
+	chunk := func(fourCC string, data []byte) []byte {
+		c := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+		c = append(c, data...)
+		if len(data)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	body := []byte("WEBP")
+	body = append(body, chunk("VP8X", []byte{0x0C, 0, 0, 0, 39, 0, 0, 19, 0, 0})...)
+	body = append(body, chunk("VP8L", []byte("image data"))...)
+	body = append(body, chunk("EXIF", []byte("GPS 38.8977N"))...)
+	body = append(body, chunk("XMP ", []byte("<x:xmpmeta/>"))...)
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	data = append(data, body...)
+
+	stripped, err := Strip(data, WebP)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "GPS")
+	assert.NotContains(t, string(stripped), "xmpmeta")
+	assert.Contains(t, string(stripped), "image data")
+	assert.Equal(t, uint32(len(stripped)-8), binary.LittleEndian.Uint32(stripped[4:]))
+	assert.Equal(t, byte(0), stripped[20], "metadata flags are cleared")
+}
+
+func TestStrip_Rejects(t *testing.T) {
+	_, err := Strip([]byte("%PDF-1.7"), "application/pdf")
+	assert.ErrorIs(t, err, ErrUnsupported)
+	_, err = Strip([]byte("not a jpeg"), JPEG)
+	assert.Error(t, err)
+}
+
+func TestParseRegions(t *testing.T) {
+	regions, err := ParseRegions("40,10,20,25\n 0, 0, 50%, 50% ; ")
+	require.NoError(t, err)
+	assert.Equal(t, []Region{{X: 0.4, Y: 0.1, W: 0.2, H: 0.25}, {X: 0, Y: 0, W: 0.5, H: 0.5}}, regions)
+
+	regions, err = ParseRegions("")
+	require.NoError(t, err)
+	assert.Empty(t, regions)
+
+	for _, bad := range []string{"1,2,3", "a,b,c,d", "10,10,0,10", "10,10,200,10"} {
+		_, err := ParseRegions(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestBlur(t *testing.T) {
+	var buf bytes.Buffer
+	img := testImage()
+	require.NoError(t, png.Encode(&buf, img))
+
+	// Blurring across the red/blue edge mixes the two
+	blurred, err := Blur(buf.Bytes(), PNG, []Region{{X: 0.25, Y: 0, W: 0.5, H: 1}})
+	require.NoError(t, err)
+	out, err := png.Decode(bytes.NewReader(blurred))
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, color.RGBAModel.Convert(out.At(2, 2)), "outside the region is untouched")
+	r, _, b, _ := out.At(19, 10).RGBA()
+	assert.NotZero(t, r)
+	assert.NotZero(t, b)
+
+	assert.True(t, CanBlur(JPEG))
+	assert.False(t, CanBlur(WebP))
+	_, err = Blur(nil, WebP, nil)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
@@ -0,0 +1,366 @@
+// Package jobqueue provides a Buffalo worker backed by Postgres. Jobs are
+// rows in background_jobs, so they survive restarts and can be enqueued in
+// the same transaction as the change that called for them. A job that fails
+// is retried with exponential backoff until it runs out of attempts, and is
+// then kept as dead so it can be looked at and retried by hand.
+package jobqueue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"avrnpo.org/pkg/errortracking"
+)
+
+// TableName is the table jobs are persisted to.
+const TableName = "background_jobs"
+
+// Job states. A running job whose lease runs out, because the process
+// running it died, is picked up again.
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusDead    = "dead"
+)
+
+// Statuses lists the job states.
+var Statuses = []string{StatusPending, StatusRunning, StatusDone, StatusDead}
+
+// Defaults for a new Queue.
+const (
+	DefaultMaxAttempts  = 8
+	DefaultBaseDelay    = 30 * time.Second
+	DefaultMaxDelay     = 6 * time.Hour
+	DefaultPollInterval = 2 * time.Second
+	DefaultLease        = 10 * time.Minute
+	DefaultWorkers      = 2
+)
+
+var _ worker.Worker = &Queue{}
+
+// Queue is a worker.Worker that stores jobs in Postgres and runs them from
+// a pool of goroutines.
+type Queue struct {
+	db       *pop.Connection
+	handlers map[string]worker.Handler
+	mu       sync.RWMutex
+	wake     chan struct{}
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	// Workers is how many jobs run at once.
+	Workers int
+	// PollInterval is how often idle workers look for due jobs.
+	PollInterval time.Duration
+	// MaxAttempts is how many times a job is tried before it's dead.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry; it doubles with each
+	// failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Lease is how long a job may run before it's presumed lost.
+	Lease time.Duration
+	// Logger, when set, is told about failed and dead jobs.
+	Logger worker.SimpleLogger
+}
+
+// New creates a Queue that keeps its jobs in db.
+func New(db *pop.Connection) *Queue {
+	return &Queue{
+		db:           db,
+		handlers:     map[string]worker.Handler{},
+		wake:         make(chan struct{}, 1),
+		Workers:      DefaultWorkers,
+		PollInterval: DefaultPollInterval,
+		MaxAttempts:  DefaultMaxAttempts,
+		BaseDelay:    DefaultBaseDelay,
+		MaxDelay:     DefaultMaxDelay,
+		Lease:        DefaultLease,
+	}
+}
+
+// Register maps a handler name to the function that runs its jobs.
+func (q *Queue) Register(name string, h worker.Handler) error {
+	if name == "" || h == nil {
+		return errors.New("jobqueue: name and handler are required")
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.handlers[name]; ok {
+		return errors.Errorf("jobqueue: handler already registered for %s", name)
+	}
+	q.handlers[name] = h
+	return nil
+}
+
+// Start runs the worker pool until ctx is done or Stop is called. It
+// returns straight away, as Buffalo expects.
+func (q *Queue) Start(ctx context.Context) error {
+	ctx, q.cancel = context.WithCancel(ctx)
+	for i := 0; i < q.Workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			q.work(ctx)
+		}()
+	}
+	return nil
+}
+
+// Stop stops taking new jobs and waits for running ones to finish.
+func (q *Queue) Stop() error {
+	if q.cancel != nil {
+		q.cancel()
+	}
+	q.wg.Wait()
+	return nil
+}
+
+// Perform enqueues job to run as soon as a worker is free.
+func (q *Queue) Perform(job worker.Job) error {
+	return q.PerformAt(job, time.Now())
+}
+
+// PerformIn enqueues job to run once d has passed.
+func (q *Queue) PerformIn(job worker.Job, d time.Duration) error {
+	return q.PerformAt(job, time.Now().Add(d))
+}
+
+// PerformAt enqueues job to run at t.
+func (q *Queue) PerformAt(job worker.Job, t time.Time) error {
+	q.mu.RLock()
+	_, ok := q.handlers[job.Handler]
+	q.mu.RUnlock()
+	if !ok {
+		return errors.Errorf("jobqueue: no handler registered for %s", job.Handler)
+	}
+	if err := enqueue(q.db, job, t, q.MaxAttempts); err != nil {
+		return err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Enqueue adds job to the queue through db, which may be a transaction: the
+// job is then only run if the transaction commits. It's picked up on the
+// workers' next poll.
+func Enqueue(db *pop.Connection, job worker.Job, runAt time.Time) error {
+	return enqueue(db, job, runAt, DefaultMaxAttempts)
+}
+
+func enqueue(db *pop.Connection, job worker.Job, runAt time.Time, maxAttempts int) error {
+	if job.Handler == "" {
+		return errors.New("jobqueue: job has no handler")
+	}
+	if job.Queue == "" {
+		job.Queue = "default"
+	}
+	args, err := json.Marshal(job.Args)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	now := time.Now()
+	err = db.RawQuery(`INSERT INTO `+TableName+` (id, queue, handler, args, status, attempts, max_attempts, run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, 0, ?, ?, ?, ?)`,
+		id, job.Queue, job.Handler, string(args), StatusPending, maxAttempts, runAt, now, now).Exec()
+	return errors.WithStack(err)
+}
+
+// claimed is a job a worker has taken to run.
+type claimed struct {
+	ID          uuid.UUID `db:"id"`
+	Queue       string    `db:"queue"`
+	Handler     string    `db:"handler"`
+	Args        string    `db:"args"`
+	Attempts    int       `db:"attempts"`
+	MaxAttempts int       `db:"max_attempts"`
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if q.runNext(time.Now()) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-time.After(q.PollInterval):
+		}
+	}
+}
+
+// runNext claims and runs one due job, reporting whether there was one.
+func (q *Queue) runNext(now time.Time) bool {
+	job, err := q.claim(now)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			q.logf("jobqueue: claiming a job failed: %v", err)
+		}
+		return false
+	}
+
+	runErr := q.run(job)
+	if err := q.finish(job, runErr, time.Now()); err != nil {
+		q.logf("jobqueue: recording job %s (%s) failed: %v", job.ID, job.Handler, err)
+	}
+	return true
+}
+
+// claim takes the longest-waiting due job, skipping ones other workers hold
+func (q *Queue) claim(now time.Time) (*claimed, error) {
+	job := &claimed{}
+	err := q.db.RawQuery(`UPDATE `+TableName+`
+		SET status = ?, attempts = attempts + 1, locked_at = ?, updated_at = ?
+		WHERE id = (
+			SELECT id FROM `+TableName+`
+			WHERE (status = ? AND run_at <= ?) OR (status = ? AND locked_at <= ?)
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, queue, handler, args, attempts, max_attempts`,
+		StatusRunning, now, now, StatusPending, now, StatusRunning, now.Add(-q.Lease)).First(job)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// run calls the job's handler, turning a panic into an error that's also
+// reported to error tracking.
+func (q *Queue) run(job *claimed) (err error) {
+	q.mu.RLock()
+	h, ok := q.handlers[job.Handler]
+	q.mu.RUnlock()
+	if !ok {
+		return errors.Errorf("no handler registered for %s", job.Handler)
+	}
+
+	args := worker.Args{}
+	if err := json.Unmarshal([]byte(job.Args), &args); err != nil {
+		return errors.Wrap(err, "decoding job args")
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("panic: %v", r)
+			captureJobError(job, err)
+		}
+	}()
+	return h(args)
+}
+
+// finish marks the job done, or schedules its retry, or gives up on it once
+// it's out of attempts.
+func (q *Queue) finish(job *claimed, runErr error, now time.Time) error {
+	if runErr == nil {
+		return errors.WithStack(q.db.RawQuery(`UPDATE `+TableName+`
+			SET status = ?, locked_at = NULL, last_error = NULL, finished_at = ?, updated_at = ?
+			WHERE id = ?`, StatusDone, now, now, job.ID).Exec())
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		q.logf("jobqueue: job %s (%s) is dead after %d attempts: %v", job.ID, job.Handler, job.Attempts, runErr)
+		captureJobError(job, errors.Wrapf(runErr, "job dead after %d attempts", job.Attempts))
+		return errors.WithStack(q.db.RawQuery(`UPDATE `+TableName+`
+			SET status = ?, locked_at = NULL, last_error = ?, finished_at = ?, updated_at = ?
+			WHERE id = ?`, StatusDead, runErr.Error(), now, now, job.ID).Exec())
+	}
+
+	retryAt := now.Add(Backoff(q.BaseDelay, q.MaxDelay, job.Attempts))
+	q.logf("jobqueue: job %s (%s) failed on attempt %d, retrying at %s: %v", job.ID, job.Handler, job.Attempts, retryAt.Format(time.RFC3339), runErr)
+	return errors.WithStack(q.db.RawQuery(`UPDATE `+TableName+`
+		SET status = ?, locked_at = NULL, last_error = ?, run_at = ?, updated_at = ?
+		WHERE id = ?`, StatusPending, runErr.Error(), retryAt, now, job.ID).Exec())
+}
+
+// captureJobError reports a job failure that needs looking at, tagged with
+// the job it came from.
+func captureJobError(job *claimed, err error) {
+	errortracking.Capture(err, nil, map[string]string{
+		"worker":  "jobqueue",
+		"handler": job.Handler,
+		"job_id":  job.ID.String(),
+	})
+}
+
+func (q *Queue) logf(format string, args ...interface{}) {
+	if q.Logger != nil {
+		q.Logger.Errorf(format, args...)
+	}
+}
+
+// Backoff is how long to wait before retrying a job that has failed
+// attempts times: base, doubling with each further failure, capped at max.
+func Backoff(base, max time.Duration, attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// Counts returns how many jobs are in each state.
+func Counts(db *pop.Connection) (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := db.RawQuery("SELECT status, COUNT(*) AS count FROM " + TableName + " GROUP BY status").All(&rows); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	counts := map[string]int{}
+	for _, s := range Statuses {
+		counts[s] = 0
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// RetryDead puts every dead job back in the queue with a fresh set of
+// attempts.
+func RetryDead(db *pop.Connection) (int, error) {
+	now := time.Now()
+	n, err := db.RawQuery(`UPDATE `+TableName+`
+		SET status = ?, attempts = 0, run_at = ?, finished_at = NULL, updated_at = ?
+		WHERE status = ?`, StatusPending, now, now, StatusDead).ExecWithCount()
+	return n, errors.WithStack(err)
+}
+
+// Prune deletes jobs that finished before cutoff.
+func Prune(db *pop.Connection, cutoff time.Time) (int, error) {
+	n, err := db.RawQuery("DELETE FROM "+TableName+" WHERE status = ? AND finished_at < ?", StatusDone, cutoff).ExecWithCount()
+	return n, errors.WithStack(err)
+}
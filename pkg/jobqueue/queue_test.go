@@ -0,0 +1,70 @@
+package jobqueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"avrnpo.org/pkg/errortracking"
+)
+
+func TestBackoff(t *testing.T) {
+	base, max := 30*time.Second, 6*time.Hour
+	assert.Equal(t, 30*time.Second, Backoff(base, max, 0))
+	assert.Equal(t, 30*time.Second, Backoff(base, max, 1))
+	assert.Equal(t, time.Minute, Backoff(base, max, 2))
+	assert.Equal(t, 4*time.Minute, Backoff(base, max, 4))
+	assert.Equal(t, 6*time.Hour, Backoff(base, max, 20))
+	assert.Equal(t, time.Second, Backoff(time.Minute, time.Second, 1))
+}
+
+func TestRegister(t *testing.T) {
+	q := New(nil)
+	require.NoError(t, q.Register("send_receipt", func(worker.Args) error { return nil }))
+	assert.Error(t, q.Register("send_receipt", func(worker.Args) error { return nil }))
+	assert.Error(t, q.Register("", func(worker.Args) error { return nil }))
+	assert.Error(t, q.Register("sync", nil))
+}
+
+func TestPerform_RequiresRegisteredHandler(t *testing.T) {
+	q := New(nil)
+	assert.Error(t, q.Perform(worker.Job{Handler: "missing"}))
+	assert.Error(t, Enqueue(nil, worker.Job{}, time.Now()))
+}
+
+func TestRun(t *testing.T) {
+	q := New(nil)
+	var got worker.Args
+	require.NoError(t, q.Register("ok", func(args worker.Args) error {
+		got = args
+		return nil
+	}))
+	require.NoError(t, q.Register("panics", func(worker.Args) error {
+		panic("boom")
+	}))
+
+	require.NoError(t, q.run(&claimed{Handler: "ok", Args: `{"donation_id":"abc"}`}))
+	assert.Equal(t, "abc", got["donation_id"])
+
+	err := q.run(&claimed{Handler: "panics", Args: `{}`})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+	recent := errortracking.Recent(1)
+	require.Len(t, recent, 1)
+	assert.Equal(t, "panic: boom", recent[0].Message)
+	assert.Equal(t, "panics", recent[0].Tags["handler"])
+
+	assert.Error(t, q.run(&claimed{Handler: "ok", Args: "not json"}))
+	assert.Error(t, q.run(&claimed{Handler: "gone", Args: `{}`}))
+}
+
+func TestStartStop(t *testing.T) {
+	q := New(nil)
+	q.Workers = 0
+	assert.NoError(t, q.Stop())
+	require.NoError(t, q.Start(t.Context()))
+	assert.NoError(t, q.Stop())
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"sync"
+
+	"github.com/gobuffalo/buffalo"
+)
+
+// SystemActor is the actor recorded for Audit events, which aren't made by a
+// signed-in user (payments settling, scheduled sends and the like)
+const SystemActor = "system"
+
+// AuditEntry is a user action or audit event as it's handed to the audit
+// recorder
+type AuditEntry struct {
+	Actor   string
+	Action  string
+	Details string
+	Fields  Fields
+}
+
+// AuditRecorder keeps audit entries somewhere lasting, such as the database.
+// c is nil for Audit events.
+type AuditRecorder func(c buffalo.Context, entry AuditEntry)
+
+var (
+	auditRecorderMu sync.RWMutex
+	auditRecorder   AuditRecorder
+)
+
+// SetAuditRecorder sends every UserAction and Audit event to record as well
+// as the log files. Passing nil stops recording.
+func SetAuditRecorder(record AuditRecorder) {
+	auditRecorderMu.Lock()
+	defer auditRecorderMu.Unlock()
+	auditRecorder = record
+}
+
+// recordAudit hands an entry to the audit recorder, if one is set
+func recordAudit(c buffalo.Context, entry AuditEntry) {
+	auditRecorderMu.RLock()
+	record := auditRecorder
+	auditRecorderMu.RUnlock()
+	if record != nil {
+		record(c, entry)
+	}
+}
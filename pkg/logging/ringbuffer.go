@@ -0,0 +1,195 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultRingBufferSize is how many entries are kept for the admin log viewer
+// when LOG_BUFFER_SIZE is not set.
+const DefaultRingBufferSize = 2000
+
+// RecordedEntry is a log entry captured in memory for the admin log viewer.
+type RecordedEntry struct {
+	Time      time.Time
+	Level     string
+	Module    string
+	Message   string
+	RequestID string
+	Fields    map[string]string
+}
+
+// FieldList returns the fields as sorted "key=value" strings for display.
+func (e RecordedEntry) FieldList() []string {
+	list := make([]string, 0, len(e.Fields))
+	for k, v := range e.Fields {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// RingBuffer is a logrus hook that keeps the most recent entries in memory.
+type RingBuffer struct {
+	mu      sync.RWMutex
+	entries []RecordedEntry
+	next    int
+	full    bool
+}
+
+// NewRingBuffer creates a ring buffer holding up to size entries.
+func NewRingBuffer(size int) *RingBuffer {
+	if size <= 0 {
+		size = DefaultRingBufferSize
+	}
+	return &RingBuffer{entries: make([]RecordedEntry, size)}
+}
+
+// Recent holds recent entries from the application, audit, and Buffalo
+// request loggers.
+var Recent = NewRingBuffer(envyInt("LOG_BUFFER_SIZE", DefaultRingBufferSize))
+
+func envyInt(key string, def int) int {
+	n, err := strconv.Atoi(envy.Get(key, ""))
+	if err != nil || n <= 0 {
+		return def
+	}
+	return n
+}
+
+// ForModule returns a hook that records into the same buffer but tags
+// entries without a "module" field with the given module name.
+func (b *RingBuffer) ForModule(module string) logrus.Hook {
+	return &moduleHook{buffer: b, module: module}
+}
+
+// Levels implements logrus.Hook.
+func (b *RingBuffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook.
+func (b *RingBuffer) Fire(entry *logrus.Entry) error {
+	b.record(entry, "app")
+	return nil
+}
+
+func (b *RingBuffer) record(entry *logrus.Entry, module string) {
+	rec := RecordedEntry{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Module:  module,
+		Message: entry.Message,
+		Fields:  make(map[string]string, len(entry.Data)),
+	}
+	for k, v := range entry.Data {
+		s := fmt.Sprint(v)
+		switch k {
+		case "module", "component":
+			rec.Module = s
+		case "request_id":
+			rec.RequestID = s
+		default:
+			rec.Fields[k] = s
+		}
+	}
+
+	b.mu.Lock()
+	b.entries[b.next] = rec
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	b.mu.Unlock()
+}
+
+// LogFilter narrows the entries returned by Entries. Empty fields match all.
+type LogFilter struct {
+	Level     string // minimum level, e.g. "warn" returns warn, error, fatal
+	Module    string
+	RequestID string // substring match on the correlation ID
+	Query     string // case-insensitive substring match on the message
+	Limit     int
+}
+
+// Entries returns matching entries, newest first.
+func (b *RingBuffer) Entries(f LogFilter) []RecordedEntry {
+	var minLevel logrus.Level = logrus.TraceLevel
+	if f.Level != "" {
+		if lvl, err := logrus.ParseLevel(f.Level); err == nil {
+			minLevel = lvl
+		}
+	}
+	query := strings.ToLower(f.Query)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	count := b.next
+	if b.full {
+		count = len(b.entries)
+	}
+
+	var out []RecordedEntry
+	for i := 0; i < count; i++ {
+		idx := (b.next - 1 - i + len(b.entries)) % len(b.entries)
+		e := b.entries[idx]
+
+		if lvl, err := logrus.ParseLevel(e.Level); err == nil && lvl > minLevel {
+			continue
+		}
+		if f.Module != "" && e.Module != f.Module {
+			continue
+		}
+		if f.RequestID != "" && !strings.Contains(e.RequestID, f.RequestID) {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(e.Message), query) {
+			continue
+		}
+
+		out = append(out, e)
+		if f.Limit > 0 && len(out) >= f.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// Modules returns the distinct module names currently in the buffer.
+func (b *RingBuffer) Modules() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	seen := map[string]bool{}
+	for _, e := range b.entries {
+		if e.Module != "" {
+			seen[e.Module] = true
+		}
+	}
+	modules := make([]string, 0, len(seen))
+	for m := range seen {
+		modules = append(modules, m)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+type moduleHook struct {
+	buffer *RingBuffer
+	module string
+}
+
+func (h *moduleHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *moduleHook) Fire(entry *logrus.Entry) error {
+	h.buffer.record(entry, h.module)
+	return nil
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBufferedLogger(buf *RingBuffer) *logrus.Logger {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	l.SetLevel(logrus.DebugLevel)
+	l.AddHook(buf)
+	return l
+}
+
+func TestRingBuffer_WrapsAndOrdersNewestFirst(t *testing.T) {
+	buf := NewRingBuffer(3)
+	l := newBufferedLogger(buf)
+
+	for i := 1; i <= 5; i++ {
+		l.Info(fmt.Sprintf("message %d", i))
+	}
+
+	entries := buf.Entries(LogFilter{})
+	require.Len(t, entries, 3)
+	assert.Equal(t, "message 5", entries[0].Message)
+	assert.Equal(t, "message 3", entries[2].Message)
+}
+
+func TestRingBuffer_Filters(t *testing.T) {
+	buf := NewRingBuffer(10)
+	l := newBufferedLogger(buf)
+	audit := logrus.New()
+	audit.SetOutput(io.Discard)
+	audit.AddHook(buf.ForModule("audit"))
+
+	l.WithField("request_id", "abc-123").Debug("loading donation")
+	l.WithFields(logrus.Fields{"request_id": "abc-123", "module": "helcim"}).Error("payment failed")
+	l.WithField("request_id", "zzz-999").Warn("slow request")
+	audit.Info("admin_step_up")
+
+	assert.Len(t, buf.Entries(LogFilter{Level: "warn"}), 2)
+	assert.Len(t, buf.Entries(LogFilter{RequestID: "abc"}), 2)
+	assert.Len(t, buf.Entries(LogFilter{Module: "helcim"}), 1)
+	assert.Len(t, buf.Entries(LogFilter{Module: "audit"}), 1)
+	assert.Len(t, buf.Entries(LogFilter{Query: "PAYMENT"}), 1)
+	assert.Len(t, buf.Entries(LogFilter{Limit: 1}), 1)
+
+	assert.Equal(t, []string{"app", "audit", "helcim"}, buf.Modules())
+
+	entry := buf.Entries(LogFilter{Module: "helcim"})[0]
+	assert.Equal(t, "abc-123", entry.RequestID)
+	assert.Empty(t, entry.FieldList())
+}
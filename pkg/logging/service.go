@@ -37,6 +37,10 @@ func NewService(config *Config) (*Service, error) {
 		return nil, fmt.Errorf("failed to create audit logger: %w", err)
 	}
 
+	// Keep recent entries in memory for the admin log viewer
+	mainLogger.AddHook(Recent)
+	auditLogger.AddHook(Recent.ForModule("audit"))
+
 	service := &Service{
 		config: config,
 		logger: mainLogger,
@@ -262,6 +266,7 @@ func (s *Service) Audit(action string, fields Fields) {
 	}
 
 	s.audit.WithFields(logrusFields).Info("Audit Event")
+	recordAudit(nil, AuditEntry{Actor: SystemActor, Action: action, Fields: fields})
 }
 
 // UserAction logs a user-specific action (e.g., login, logout, item creation)
@@ -293,6 +298,12 @@ func (s *Service) UserAction(c buffalo.Context, actor string, action string, det
 	}
 
 	entry.Info(fmt.Sprintf("UserAction: %s by %s - %s", action, actor, details))
+
+	var extra Fields
+	if len(fields) > 0 {
+		extra = fields[0]
+	}
+	recordAudit(c, AuditEntry{Actor: actor, Action: action, Details: details, Fields: extra})
 }
 
 // SecurityEvent logs a security-relevant event (e.g., auth failure, permission denied)
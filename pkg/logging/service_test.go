@@ -239,6 +239,22 @@ func (s *LoggingServiceTestSuite) TestUserAction() {
 	s.Equal("user_action", entry.Data["log_type"])
 }
 
+func (s *LoggingServiceTestSuite) TestAuditRecorder() {
+	var recorded []AuditEntry
+	SetAuditRecorder(func(c buffalo.Context, entry AuditEntry) {
+		recorded = append(recorded, entry)
+	})
+	defer SetAuditRecorder(nil)
+
+	s.service.UserAction(nil, "admin@example.com", "donation_refunded", "Refunded donation", Fields{"donation_id": "d1"})
+	s.service.Audit("store_order_paid", Fields{"order_id": "o1"})
+	s.service.SecurityEvent(nil, "auth_attempt", "failure", "invalid_credentials")
+
+	s.Require().Len(recorded, 2)
+	s.Equal(AuditEntry{Actor: "admin@example.com", Action: "donation_refunded", Details: "Refunded donation", Fields: Fields{"donation_id": "d1"}}, recorded[0])
+	s.Equal(AuditEntry{Actor: SystemActor, Action: "store_order_paid", Fields: Fields{"order_id": "o1"}}, recorded[1])
+}
+
 func (s *LoggingServiceTestSuite) TestSecurityEvent() {
 	testMsgFormat := "SecurityEvent: %s (%s) - %s"
 	eventType := "auth_attempt"
@@ -0,0 +1,412 @@
+// Package qrcode encodes short strings, such as ticket URLs, as QR codes and
+// renders them as SVG. It supports byte mode at error correction level M for
+// versions 1-6 (up to 106 bytes), which is all the site needs and keeps
+// version information blocks out of the picture.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTooLong is returned when the data doesn't fit in a version 6 code
+var ErrTooLong = errors.New("qrcode: data too long")
+
+// version describes the codeword layout of one QR version at level M. All
+// blocks within these versions are the same size.
+type version struct {
+	dataCodewords int
+	ecPerBlock    int
+	blocks        int
+	align         int // alignment pattern centre, 0 when the version has none
+}
+
+var versions = []version{
+	{},
+	{16, 10, 1, 0},
+	{28, 16, 1, 18},
+	{44, 26, 1, 22},
+	{64, 18, 2, 26},
+	{86, 24, 2, 30},
+	{108, 16, 4, 34},
+}
+
+// eccLevelM is the two format bits for error correction level M
+const eccLevelM = 0
+
+// Code is an encoded QR symbol
+type Code struct {
+	Size     int
+	modules  [][]bool
+	reserved [][]bool
+}
+
+// Encode encodes data as a QR code using the smallest version that fits.
+func Encode(data string) (*Code, error) {
+	ver := 0
+	for v := 1; v < len(versions); v++ {
+		if len(data) <= (versions[v].dataCodewords*8-12)/8 {
+			ver = v
+			break
+		}
+	}
+	if ver == 0 {
+		return nil, ErrTooLong
+	}
+	info := versions[ver]
+
+	codewords := interleave(info, dataCodewords(info, []byte(data)))
+
+	size := 17 + 4*ver
+	c := &Code{Size: size, modules: make([][]bool, size), reserved: make([][]bool, size)}
+	for i := range c.modules {
+		c.modules[i] = make([]bool, size)
+		c.reserved[i] = make([]bool, size)
+	}
+	c.drawFunctionPatterns(info)
+	c.drawCodewords(codewords)
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // masks are their own inverse
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+	return c, nil
+}
+
+// Dark reports whether the module at column x, row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// SVG renders the code with a four-module quiet zone. The image scales to
+// its container; size is the width and height in pixels.
+func (c *Code) SVG(size int) string {
+	const quiet = 4
+	n := c.Size + 2*quiet
+	var path strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+quiet, y+quiet)
+			}
+		}
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges"><rect width="%d" height="%d" fill="#fff"/><path d="%s" fill="#000"/></svg>`,
+		size, size, n, n, n, n, path.String())
+}
+
+// dataCodewords builds the byte mode bit stream, padded to the version's
+// data capacity.
+func dataCodewords(info version, data []byte) []byte {
+	var bits []bool
+	appendBits := func(val, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, (val>>uint(i))&1 == 1)
+		}
+	}
+	appendBits(0x4, 4) // byte mode
+	appendBits(len(data), 8)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+
+	capacity := info.dataCodewords * 8
+	for i := 0; i < 4 && len(bits) < capacity; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+
+	out := make([]byte, 0, info.dataCodewords)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << uint(7-j)
+			}
+		}
+		out = append(out, b)
+	}
+	for pad := byte(0xEC); len(out) < info.dataCodewords; pad ^= 0xEC ^ 0x11 {
+		out = append(out, pad)
+	}
+	return out
+}
+
+// interleave splits data into blocks, appends each block's error correction
+// codewords, and interleaves them in the order they are placed.
+func interleave(info version, data []byte) []byte {
+	perBlock := info.dataCodewords / info.blocks
+	divisor := rsDivisor(info.ecPerBlock)
+	dataBlocks := make([][]byte, info.blocks)
+	ecBlocks := make([][]byte, info.blocks)
+	for b := 0; b < info.blocks; b++ {
+		dataBlocks[b] = data[b*perBlock : (b+1)*perBlock]
+		ecBlocks[b] = rsRemainder(dataBlocks[b], divisor)
+	}
+
+	out := make([]byte, 0, info.dataCodewords+info.blocks*info.ecPerBlock)
+	for i := 0; i < perBlock; i++ {
+		for b := range dataBlocks {
+			out = append(out, dataBlocks[b][i])
+		}
+	}
+	for i := 0; i < info.ecPerBlock; i++ {
+		for b := range ecBlocks {
+			out = append(out, ecBlocks[b][i])
+		}
+	}
+	return out
+}
+
+// setFunction sets a function pattern module, which data and masking skip.
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.reserved[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns(info version) {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+	if info.align > 0 {
+		for dy := -2; dy <= 2; dy++ {
+			for dx := -2; dx <= 2; dx++ {
+				c.setFunction(info.align+dx, info.align+dy, max(abs(dx), abs(dy)) != 1)
+			}
+		}
+	}
+	// Reserve the format areas; the real bits are drawn once a mask is chosen
+	c.drawFormatBits(0)
+}
+
+// drawFinder draws a finder pattern and its separator around centre (cx, cy).
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawFormatBits draws both copies of the format information for mask.
+func (c *Code) drawFormatBits(mask int) {
+	data := eccLevelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true) // dark module
+}
+
+// drawCodewords places codeword bits in the two-column zigzag, skipping
+// function patterns. Leftover remainder bits stay light.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if upward {
+					y = c.Size - 1 - vert
+				}
+				if c.reserved[y][x] {
+					continue
+				}
+				if i < len(codewords)*8 {
+					c.modules[y][x] = (codewords[i/8]>>uint(7-i%8))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.reserved[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol with the four mask evaluation rules; lower is
+// easier to scan.
+func (c *Code) penalty() int {
+	n := c.Size
+	score := 0
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+	for pass := 0; pass < 2; pass++ {
+		at := func(i, j int) bool {
+			if pass == 0 {
+				return c.modules[i][j]
+			}
+			return c.modules[j][i]
+		}
+		for i := 0; i < n; i++ {
+			run := 1
+			for j := 1; j < n; j++ {
+				if at(i, j) == at(i, j-1) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += 3 + run - 5
+				}
+				run = 1
+			}
+			if run >= 5 {
+				score += 3 + run - 5
+			}
+			for j := 0; j+len(finderLike[0]) <= n; j++ {
+				for _, pattern := range finderLike {
+					match := true
+					for k, dark := range pattern {
+						if at(i, j+k) != dark {
+							match = false
+							break
+						}
+					}
+					if match {
+						score += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				v := c.modules[y][x]
+				if c.modules[y][x+1] == v && c.modules[y+1][x] == v && c.modules[y+1][x+1] == v {
+					score += 3
+				}
+			}
+		}
+	}
+	total := n * n
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return score + k*10
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of the given degree.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords for data.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= gfMul(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,89 @@
+package qrcode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// Data and error correction codewords for "HELLO WORLD" as a 1-M symbol
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	assert.Equal(t, want, rsRemainder(data, rsDivisor(10)))
+}
+
+func TestFormatBits(t *testing.T) {
+	c := &Code{Size: 21, modules: make([][]bool, 21), reserved: make([][]bool, 21)}
+	for i := range c.modules {
+		c.modules[i] = make([]bool, 21)
+		c.reserved[i] = make([]bool, 21)
+	}
+	c.drawFormatBits(0)
+
+	// Level M, mask 0 is 101010000010010, most significant bit first along
+	// row 8 from the left edge past the timing column
+	var got strings.Builder
+	for _, x := range []int{0, 1, 2, 3, 4, 5, 7} {
+		got.WriteString(bitString(c.modules[8][x]))
+	}
+	for _, y := range []int{8, 7, 5, 4, 3, 2, 1, 0} {
+		got.WriteString(bitString(c.modules[y][8]))
+	}
+	assert.Equal(t, "101010000010010", got.String())
+}
+
+func TestEncode_ChoosesSmallestVersion(t *testing.T) {
+	tests := []struct {
+		length int
+		size   int
+	}{
+		{1, 21},
+		{14, 21},
+		{15, 25},
+		{62, 33},
+		{106, 41},
+	}
+	for _, tt := range tests {
+		c, err := Encode(strings.Repeat("a", tt.length))
+		require.NoError(t, err)
+		assert.Equal(t, tt.size, c.Size, "length %d", tt.length)
+	}
+
+	_, err := Encode(strings.Repeat("a", 107))
+	assert.ErrorIs(t, err, ErrTooLong)
+}
+
+func TestEncode_FinderPatterns(t *testing.T) {
+	c, err := Encode("https://avrnpo.org/tickets/abcdefghijklmnopqrstuvwx")
+	require.NoError(t, err)
+
+	for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+		for i := 0; i < 7; i++ {
+			assert.True(t, c.Dark(corner[0]+i, corner[1]), "top edge")
+			assert.True(t, c.Dark(corner[0], corner[1]+i), "left edge")
+		}
+		assert.False(t, c.Dark(corner[0]+1, corner[1]+1))
+		assert.True(t, c.Dark(corner[0]+3, corner[1]+3))
+	}
+	assert.True(t, c.Dark(8, c.Size-8), "dark module")
+}
+
+func TestSVG(t *testing.T) {
+	c, err := Encode("ticket")
+	require.NoError(t, err)
+
+	svg := c.SVG(200)
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.Contains(t, svg, `viewBox="0 0 29 29"`)
+	assert.Contains(t, svg, `width="200"`)
+}
+
+func bitString(dark bool) string {
+	if dark {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,153 @@
+// Package ratelimit counts requests against fixed-window limits, e.g. five
+// contact form posts per IP every ten minutes. Counts are kept in a Store:
+// MemoryStore holds them in the process, and RedisStore shares them between
+// instances when the app runs on more than one.
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store counts hits per key within fixed windows.
+type Store interface {
+	// Increment adds a hit to key in the window containing now and returns
+	// the window's count including it, along with when the window ends.
+	Increment(key string, window time.Duration, now time.Time) (count int, resetAt time.Time, err error)
+}
+
+// Rule is how many requests are allowed in each window.
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParseRule reads a rule written as "<limit>/<window>", e.g. "5/10m".
+func ParseRule(s string) (Rule, error) {
+	limit, window, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("rate limit %q should look like 5/10m", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil || n < 1 {
+		return Rule{}, fmt.Errorf("rate limit %q needs a positive request count", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || d <= 0 {
+		return Rule{}, fmt.Errorf("rate limit %q needs a window like 1m or 1h", s)
+	}
+	return Rule{Limit: n, Window: d}, nil
+}
+
+// String writes the rule the way ParseRule reads it.
+func (r Rule) String() string {
+	return fmt.Sprintf("%d/%s", r.Limit, r.Window)
+}
+
+// Result is the outcome of counting one request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	ResetAt   time.Time
+}
+
+// RetryAfter is how long until the window ends, rounded up to the second.
+func (r Result) RetryAfter(now time.Time) time.Duration {
+	wait := r.ResetAt.Sub(now)
+	if wait <= 0 {
+		return 0
+	}
+	if rounded := wait.Truncate(time.Second); rounded < wait {
+		return rounded + time.Second
+	}
+	return wait
+}
+
+// Limiter applies rules to keys using a Store.
+type Limiter struct {
+	Store Store
+	// Now is the clock; tests can replace it.
+	Now func() time.Time
+}
+
+// New returns a Limiter counting in store.
+func New(store Store) *Limiter {
+	return &Limiter{Store: store, Now: time.Now}
+}
+
+// Allow counts a request for key and reports whether it's within rule.
+func (l *Limiter) Allow(key string, rule Rule) (Result, error) {
+	count, resetAt, err := l.Store.Increment(key, rule.Window, l.Now())
+	if err != nil {
+		return Result{}, err
+	}
+	remaining := rule.Limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Result{
+		Allowed:   count <= rule.Limit,
+		Limit:     rule.Limit,
+		Remaining: remaining,
+		ResetAt:   resetAt,
+	}, nil
+}
+
+// sweepInterval is how often MemoryStore drops windows that have ended.
+const sweepInterval = time.Minute
+
+type counter struct {
+	count   int
+	resetAt time.Time
+}
+
+// MemoryStore is a Store in process memory. Counts are lost on restart and
+// aren't shared between instances.
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: map[string]*counter{}}
+}
+
+// Increment implements Store.
+func (m *MemoryStore) Increment(key string, window time.Duration, now time.Time) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+
+	c, ok := m.counters[key]
+	if !ok || !now.Before(c.resetAt) {
+		c = &counter{resetAt: now.Truncate(window).Add(window)}
+		m.counters[key] = c
+	}
+	c.count++
+	return c.count, c.resetAt, nil
+}
+
+// Len is the number of keys with a window still open.
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.counters)
+}
+
+func (m *MemoryStore) sweep(now time.Time) {
+	for key, c := range m.counters {
+		if !now.Before(c.resetAt) {
+			delete(m.counters, key)
+		}
+	}
+	m.lastSweep = now
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("5/10m")
+	require.NoError(t, err)
+	assert.Equal(t, Rule{Limit: 5, Window: 10 * time.Minute}, rule)
+	assert.Equal(t, "5/10m0s", rule.String())
+
+	for _, bad := range []string{"", "5", "0/1m", "-1/1m", "five/1m", "5/soon", "5/0s"} {
+		_, err := ParseRule(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLimiter_AllowsUpToLimitPerWindow(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	l := New(NewMemoryStore())
+	l.Now = func() time.Time { return now }
+	rule := Rule{Limit: 2, Window: time.Minute}
+
+	res, err := l.Allow("contact:1.2.3.4", rule)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, _ = l.Allow("contact:1.2.3.4", rule)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, _ = l.Allow("contact:1.2.3.4", rule)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, now.Add(time.Minute), res.ResetAt)
+	assert.Equal(t, time.Minute, res.RetryAfter(now))
+
+	// Other keys have their own count
+	res, _ = l.Allow("contact:5.6.7.8", rule)
+	assert.True(t, res.Allowed)
+
+	// The next window starts over
+	now = now.Add(time.Minute)
+	res, _ = l.Allow("contact:1.2.3.4", rule)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+}
+
+func TestResult_RetryAfterRoundsUp(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	res := Result{ResetAt: now.Add(1500 * time.Millisecond)}
+	assert.Equal(t, 2*time.Second, res.RetryAfter(now))
+	assert.Equal(t, time.Duration(0), res.RetryAfter(now.Add(time.Hour)))
+}
+
+func TestMemoryStore_SweepsEndedWindows(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	m := NewMemoryStore()
+	m.Increment("a", time.Minute, now)
+	m.Increment("b", time.Hour, now)
+	assert.Equal(t, 2, m.Len())
+
+	m.Increment("c", time.Minute, now.Add(2*time.Minute))
+	assert.Equal(t, 2, m.Len(), "a's window ended and is swept")
+}
+
+// fakeRedis runs the increment script against a map, remembering the TTLs
+// it was asked to set
+type fakeRedis struct {
+	counts map[string]int64
+	ttls   map[string]string
+}
+
+func (f *fakeRedis) Do(args ...string) (interface{}, error) {
+	key := args[3]
+	f.counts[key]++
+	if f.counts[key] == 1 {
+		f.ttls[key] = args[4]
+	}
+	return f.counts[key], nil
+}
+
+func TestRedisStore_CountsPerWindow(t *testing.T) {
+	redis := &fakeRedis{counts: map[string]int64{}, ttls: map[string]string{}}
+	store := NewRedisStore(redis, "ratelimit:")
+	now := time.Date(2026, 10, 14, 12, 0, 30, 0, time.UTC)
+
+	count, resetAt, err := store.Increment("login:1.2.3.4", time.Minute, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, time.Date(2026, 10, 14, 12, 1, 0, 0, time.UTC), resetAt)
+
+	count, _, _ = store.Increment("login:1.2.3.4", time.Minute, now.Add(10*time.Second))
+	assert.Equal(t, 2, count)
+
+	count, _, _ = store.Increment("login:1.2.3.4", time.Minute, now.Add(time.Minute))
+	assert.Equal(t, 1, count)
+
+	key := "ratelimit:login:1.2.3.4:" + strconv.FormatInt(now.Truncate(time.Minute).Unix(), 10)
+	assert.Equal(t, int64(2), redis.counts[key])
+	assert.Equal(t, "60000", redis.ttls[key])
+}
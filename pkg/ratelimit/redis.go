@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Commander runs a Redis command, like *redis.Client from avrnpo.org/pkg/redis
+type Commander interface {
+	Do(args ...string) (interface{}, error)
+}
+
+// incrementScript bumps a window's count and sets it to expire with the
+// window, in one round trip so a counter is never left without a TTL
+const incrementScript = `local n = redis.call('INCR', KEYS[1])
+if n == 1 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end
+return n`
+
+// RedisStore is a Store in Redis, so every instance of the app counts
+// against the same limits. Each window is its own key, which Redis expires
+// once the window ends.
+type RedisStore struct {
+	redis  Commander
+	prefix string
+}
+
+// NewRedisStore returns a Store keeping counts under prefix in Redis
+func NewRedisStore(redis Commander, prefix string) *RedisStore {
+	return &RedisStore{redis: redis, prefix: prefix}
+}
+
+// Increment implements Store.
+func (s *RedisStore) Increment(key string, window time.Duration, now time.Time) (int, time.Time, error) {
+	start := now.Truncate(window)
+	windowKey := fmt.Sprintf("%s%s:%d", s.prefix, key, start.Unix())
+	reply, err := s.redis.Do("EVAL", incrementScript, "1", windowKey, strconv.FormatInt(window.Milliseconds(), 10))
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	count, ok := reply.(int64)
+	if !ok {
+		return 0, time.Time{}, fmt.Errorf("unexpected redis reply %T counting %s", reply, key)
+	}
+	return int(count), start.Add(window), nil
+}
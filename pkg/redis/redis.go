@@ -0,0 +1,287 @@
+// Package redis is a small Redis client covering what the app shares between
+// instances: cached values, rate limit counters and short-lived claims. It
+// speaks RESP2 over a pool of connections and leaves anything fancier
+// (pub/sub, pipelining, cluster) out.
+package redis
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxIdle is how many connections are kept open between commands
+const maxIdle = 8
+
+// Error is an error reply from the server, e.g. "WRONGTYPE ..."
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Client runs commands against one Redis server. It's safe for concurrent
+// use.
+type Client struct {
+	addr     string
+	username string
+	password string
+	db       int
+	useTLS   bool
+	// Timeout bounds dialing and each command's round trip
+	Timeout time.Duration
+
+	idle chan *conn
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// New returns a client for a redis:// or rediss:// (TLS) URL such as
+// redis://:password@localhost:6379/0. Connections are opened on first use.
+func New(rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid redis url")
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("redis url must start with redis:// or rediss://, got %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	c := &Client{
+		addr:    addr,
+		useTLS:  u.Scheme == "rediss",
+		Timeout: 5 * time.Second,
+		idle:    make(chan *conn, maxIdle),
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		if c.db, err = strconv.Atoi(path); err != nil {
+			return nil, fmt.Errorf("redis url database %q is not a number", path)
+		}
+	}
+	return c, nil
+}
+
+// Do runs a command and returns its reply: a string, int64, nil, or
+// []interface{} of those. Error replies are returned as Error.
+func (c *Client) Do(args ...string) (interface{}, error) {
+	cn, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := cn.do(c.Timeout, args...)
+	if _, isReply := err.(Error); err != nil && !isReply {
+		// The connection may be half way through a reply, so it isn't reused
+		cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// Ping checks the server can be reached
+func (c *Client) Ping() error {
+	_, err := c.Do("PING")
+	return err
+}
+
+// Get returns key's value, and false if it isn't set
+func (c *Client) Get(key string) (string, bool, error) {
+	reply, err := c.Do("GET", key)
+	if err != nil || reply == nil {
+		return "", false, err
+	}
+	s, ok := reply.(string)
+	if !ok {
+		return "", false, fmt.Errorf("unexpected redis reply %T for GET", reply)
+	}
+	return s, true, nil
+}
+
+// Set stores value under key, expiring after ttl (zero keeps it until it's
+// deleted)
+func (c *Client) Set(key, value string, ttl time.Duration) error {
+	args := []string{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := c.Do(args...)
+	return err
+}
+
+// SetNX stores value under key only if it isn't already set, reporting
+// whether it was stored
+func (c *Client) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	args := []string{"SET", key, value, "NX"}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	reply, err := c.Do(args...)
+	return reply != nil, err
+}
+
+// Del removes keys
+func (c *Client) Del(keys ...string) error {
+	_, err := c.Do(append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// Close closes the idle connections
+func (c *Client) Close() error {
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) get() (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+		return c.dial()
+	}
+}
+
+func (c *Client) put(cn *conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+func (c *Client) dial() (*conn, error) {
+	dialer := &net.Dialer{Timeout: c.Timeout}
+	var nc net.Conn
+	var err error
+	if c.useTLS {
+		host, _, _ := net.SplitHostPort(c.addr)
+		nc, err = tls.DialWithDialer(dialer, "tcp", c.addr, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+	} else {
+		nc, err = dialer.Dial("tcp", c.addr)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to redis")
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc)}
+
+	if c.password != "" {
+		args := []string{"AUTH", c.password}
+		if c.username != "" {
+			args = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := cn.do(c.Timeout, args...); err != nil {
+			cn.Close()
+			return nil, errors.Wrap(err, "redis auth failed")
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.do(c.Timeout, "SELECT", strconv.Itoa(c.db)); err != nil {
+			cn.Close()
+			return nil, errors.Wrap(err, "redis select failed")
+		}
+	}
+	return cn, nil
+}
+
+func (cn *conn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	if timeout > 0 {
+		cn.SetDeadline(time.Now().Add(timeout))
+	}
+	if _, err := cn.Write(encodeCommand(args)); err != nil {
+		return nil, errors.Wrap(err, "failed to send redis command")
+	}
+	return readReply(cn.r)
+}
+
+// encodeCommand writes a command as a RESP array of bulk strings
+func encodeCommand(args []string) []byte {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		b.WriteString(arg)
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
+
+// readReply reads one RESP reply
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read redis reply")
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid redis integer")
+		}
+		return n, nil
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid redis bulk length")
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, errors.Wrap(err, "failed to read redis bulk string")
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid redis array length")
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			item, err := readReply(r)
+			if reply, isReply := err.(Error); isReply {
+				// Error replies inside an array (e.g. from EXEC) are items
+				item, err = reply, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown redis reply type %q", line[0])
+}
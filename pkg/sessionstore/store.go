@@ -0,0 +1,259 @@
+// Package sessionstore provides a server-side gorilla/sessions store backed
+// by Postgres. Session values are encrypted at rest with AES-GCM and the
+// browser only ever holds a signed, random session ID, which means every
+// session can be revoked by deleting rows.
+package sessionstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/pkg/errors"
+)
+
+// TableName is the table sessions are persisted to.
+const TableName = "http_sessions"
+
+// Store is a sessions.Store that keeps encrypted session data in Postgres.
+type Store struct {
+	db      *pop.Connection
+	codecs  []securecookie.Codec
+	aead    cipher.AEAD
+	Options *sessions.Options
+}
+
+// New creates a Store. secret signs the session ID cookie; encryptionKey is
+// used for encryption at rest and must be 32 bytes. When encryptionKey is
+// empty a key is derived from secret.
+func New(db *pop.Connection, secret, encryptionKey []byte) (*Store, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("sessionstore: secret is required")
+	}
+
+	if len(encryptionKey) == 0 {
+		derived := sha256.Sum256(append([]byte("sessionstore:at-rest:"), secret...))
+		encryptionKey = derived[:]
+	}
+	if len(encryptionKey) != 32 {
+		return nil, errors.Errorf("sessionstore: encryption key must be 32 bytes, got %d", len(encryptionKey))
+	}
+
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	hashKey := sha256.Sum256(append([]byte("sessionstore:cookie:"), secret...))
+
+	return &Store{
+		db:     db,
+		codecs: securecookie.CodecsFromPairs(hashKey[:]),
+		aead:   aead,
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   86400 * 30,
+			HttpOnly: true,
+		},
+	}, nil
+}
+
+// Get returns a cached session for the request, loading it if needed.
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session referenced by the request cookie, or returns a new
+// empty session when there is no valid cookie or stored row.
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var id string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &id, s.codecs...); err != nil {
+		// A tampered or stale cookie just gets a fresh session
+		return session, nil
+	}
+
+	values, err := s.load(id)
+	if err != nil || values == nil {
+		return session, err
+	}
+
+	session.ID = id
+	session.Values = values
+	session.IsNew = false
+	return session, nil
+}
+
+// Save persists the session and writes the ID cookie. A negative MaxAge
+// deletes the session.
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.delete(session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = newID()
+	}
+
+	if err := s.save(session); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Rotate discards the stored row for session and gives it a new ID on the
+// next save. Values on the session are kept, so callers should clear them
+// first when rotating on a privilege change.
+func (s *Store) Rotate(session *sessions.Session) error {
+	if session.ID == "" {
+		return nil
+	}
+	err := s.delete(session.ID)
+	session.ID = ""
+	return err
+}
+
+// InvalidateAll deletes every stored session, logging out all users.
+func (s *Store) InvalidateAll() (int, error) {
+	return InvalidateAll(s.db)
+}
+
+// Prune deletes expired sessions.
+func (s *Store) Prune() (int, error) {
+	return Prune(s.db)
+}
+
+// ActiveCount returns the number of unexpired sessions.
+func (s *Store) ActiveCount() (int, error) {
+	n, err := s.db.Where("expires_at > ?", time.Now()).Count(TableName)
+	return n, errors.WithStack(err)
+}
+
+// InvalidateAll deletes every session row. It doesn't need the store's keys,
+// so it can be run from tasks without the app configuration.
+func InvalidateAll(db *pop.Connection) (int, error) {
+	n, err := db.RawQuery("DELETE FROM " + TableName).ExecWithCount()
+	return n, errors.WithStack(err)
+}
+
+// Prune deletes expired session rows.
+func Prune(db *pop.Connection) (int, error) {
+	n, err := db.RawQuery("DELETE FROM "+TableName+" WHERE expires_at <= ?", time.Now()).ExecWithCount()
+	return n, errors.WithStack(err)
+}
+
+func (s *Store) load(id string) (map[interface{}]interface{}, error) {
+	row := struct {
+		Data string `db:"data"`
+	}{}
+	err := s.db.RawQuery("SELECT data FROM "+TableName+" WHERE id = ? AND expires_at > ?", id, time.Now()).First(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	plain, err := s.open(row.Data)
+	if err != nil {
+		// Undecryptable rows (e.g. after a key change) behave like no session
+		return nil, nil
+	}
+
+	values := make(map[interface{}]interface{})
+	if err := (securecookie.GobEncoder{}).Deserialize(plain, &values); err != nil {
+		return nil, nil
+	}
+	return values, nil
+}
+
+func (s *Store) save(session *sessions.Session) error {
+	plain, err := (securecookie.GobEncoder{}).Serialize(session.Values)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	sealed, err := s.seal(plain)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(session.Options.MaxAge) * time.Second)
+	if session.Options.MaxAge == 0 {
+		// Browser-session cookies still need a server-side bound
+		expiresAt = now.Add(24 * time.Hour)
+	}
+
+	err = s.db.RawQuery(`INSERT INTO `+TableName+` (id, data, expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at, updated_at = EXCLUDED.updated_at`,
+		session.ID, sealed, expiresAt, now, now).Exec()
+	return errors.WithStack(err)
+}
+
+func (s *Store) delete(id string) error {
+	return errors.WithStack(s.db.RawQuery("DELETE FROM "+TableName+" WHERE id = ?", id).Exec())
+}
+
+// seal encrypts plain and returns base64(nonce || ciphertext).
+func (s *Store) seal(plain []byte) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.WithStack(err)
+	}
+	out := s.aead.Seal(nonce, nonce, plain, nil)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// open reverses seal.
+func (s *Store) open(data string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ns := s.aead.NonceSize()
+	if len(raw) < ns {
+		return nil, errors.New("sessionstore: ciphertext too short")
+	}
+	plain, err := s.aead.Open(nil, raw[:ns], raw[ns:], nil)
+	return plain, errors.WithStack(err)
+}
+
+func newID() string {
+	return strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+}
@@ -0,0 +1,54 @@
+package sessionstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_RequiresSecret(t *testing.T) {
+	_, err := New(nil, nil, nil)
+	assert.Error(t, err)
+
+	_, err = New(nil, []byte("secret"), []byte("short"))
+	assert.Error(t, err)
+}
+
+func TestSealOpen_RoundTrip(t *testing.T) {
+	s, err := New(nil, []byte("a-session-secret"), nil)
+	require.NoError(t, err)
+
+	sealed, err := s.seal([]byte("current_user_id=42"))
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "current_user_id")
+
+	plain, err := s.open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "current_user_id=42", string(plain))
+}
+
+func TestOpen_RejectsOtherKey(t *testing.T) {
+	a, err := New(nil, []byte("secret-a"), nil)
+	require.NoError(t, err)
+	b, err := New(nil, []byte("secret-b"), nil)
+	require.NoError(t, err)
+
+	sealed, err := a.seal([]byte("data"))
+	require.NoError(t, err)
+
+	_, err = b.open(sealed)
+	assert.Error(t, err)
+
+	_, err = a.open("not base64!")
+	assert.Error(t, err)
+}
+
+func TestNewID_Unique(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		id := newID()
+		assert.False(t, seen[id])
+		seen[id] = true
+	}
+}
@@ -0,0 +1,119 @@
+// Saves long forms to the server as they're filled in, so an applicant whose
+// connection drops or whose phone reloads the page doesn't lose their answers.
+//
+// A form opts in with data-autosave="<save URL>". data-autosave-draft holds
+// answers saved earlier, which are put back when the page loads, and
+// data-autosave-discard="<URL>" is where "Start over" throws them away.
+// Progress is shown in the form's [data-autosave-status] element.
+
+(function () {
+  const SAVE_DELAY = 3000;
+
+  function fieldsOf(form) {
+    // Files can't be kept with a draft; the server only keeps the text fields
+    const params = new URLSearchParams();
+    new FormData(form).forEach((value, name) => {
+      if (typeof value === 'string') {
+        params.append(name, value);
+      }
+    });
+    return params;
+  }
+
+  function restore(form, values) {
+    Object.keys(values).forEach(name => {
+      const fields = form.querySelectorAll(`[name="${CSS.escape(name)}"]`);
+      fields.forEach(field => {
+        if (field.type === 'radio' || field.type === 'checkbox') {
+          field.checked = field.value === values[name];
+        } else if (field.type !== 'file') {
+          field.value = values[name];
+        }
+      });
+    });
+  }
+
+  function setStatus(form, text) {
+    const status = form.querySelector('[data-autosave-status-text]');
+    if (status) {
+      status.textContent = text;
+    }
+  }
+
+  function setup(form) {
+    const url = form.dataset.autosave;
+    let timer = null;
+    let dirty = false;
+
+    try {
+      const draft = JSON.parse(form.dataset.autosaveDraft || '{}');
+      restore(form, draft);
+    } catch (e) {
+      console.warn('[Autosave] Ignoring unreadable draft:', e);
+    }
+
+    function save(keepalive) {
+      clearTimeout(timer);
+      if (!dirty) {
+        return;
+      }
+      dirty = false;
+      fetch(url, {
+        method: 'POST',
+        body: fieldsOf(form),
+        headers: { 'Accept': 'application/json' },
+        credentials: 'same-origin',
+        keepalive: keepalive
+      })
+        .then(response => response.json().then(result => ({ ok: response.ok, result })))
+        .then(({ ok, result }) => {
+          if (!ok) {
+            throw new Error((result.error && result.error.code) || 'save_failed');
+          }
+          if (result.status === 'saved') {
+            setStatus(form, 'Draft saved at ' + new Date(result.saved_at).toLocaleTimeString([], { hour: 'numeric', minute: '2-digit' }));
+          }
+        })
+        .catch(error => {
+          // Try again with the next change; the answers are still on the page
+          dirty = true;
+          setStatus(form, "Couldn't save your draft. We'll keep trying.");
+          console.warn('[Autosave] Save failed:', error);
+        });
+    }
+
+    function changed() {
+      dirty = true;
+      clearTimeout(timer);
+      timer = setTimeout(() => save(false), SAVE_DELAY);
+    }
+
+    form.addEventListener('input', changed);
+    form.addEventListener('change', changed);
+    form.addEventListener('submit', () => {
+      dirty = false;
+      clearTimeout(timer);
+    });
+    document.addEventListener('visibilitychange', () => {
+      if (document.visibilityState === 'hidden') {
+        save(true);
+      }
+    });
+
+    const discard = form.querySelector('[data-autosave-discard]');
+    if (discard) {
+      discard.addEventListener('click', () => {
+        fetch(discard.dataset.autosaveDiscard, {
+          method: 'POST',
+          body: new URLSearchParams({ authenticity_token: fieldsOf(form).get('authenticity_token') || '' }),
+          headers: { 'Accept': 'application/json' },
+          credentials: 'same-origin'
+        }).finally(() => window.location.reload());
+      });
+    }
+  }
+
+  document.addEventListener('DOMContentLoaded', () => {
+    document.querySelectorAll('form[data-autosave]').forEach(setup);
+  });
+})();
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// turnstileVerifyURL is where Cloudflare Turnstile checks a CAPTCHA response
+const turnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+
+// CaptchaVerifier checks the response a visitor's browser got by solving a
+// CAPTCHA
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// CaptchaEnabled reports whether forms can fall back to a Cloudflare
+// Turnstile CAPTCHA (TURNSTILE_SITE_KEY and TURNSTILE_SECRET_KEY are set)
+func CaptchaEnabled() bool {
+	return CaptchaSiteKey() != "" && strings.TrimSpace(os.Getenv("TURNSTILE_SECRET_KEY")) != ""
+}
+
+// CaptchaSiteKey is the public key the Turnstile widget is rendered with
+func CaptchaSiteKey() string {
+	return strings.TrimSpace(os.Getenv("TURNSTILE_SITE_KEY"))
+}
+
+// NewCaptchaVerifier returns a verifier for TURNSTILE_SECRET_KEY, or nil when
+// the CAPTCHA isn't configured
+func NewCaptchaVerifier() CaptchaVerifier {
+	if !CaptchaEnabled() {
+		return nil
+	}
+	return &TurnstileVerifier{
+		Secret: strings.TrimSpace(os.Getenv("TURNSTILE_SECRET_KEY")),
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// TurnstileVerifier checks responses with Cloudflare's siteverify API.
+// VerifyURL is turnstileVerifyURL unless it's overridden for tests.
+type TurnstileVerifier struct {
+	Secret    string
+	VerifyURL string
+	Client    *http.Client
+}
+
+// turnstileResult is siteverify's answer
+type turnstileResult struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify reports whether token is a fresh, unused response to the CAPTCHA.
+// An error means Turnstile couldn't be asked, not that the token was bad.
+func (v *TurnstileVerifier) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	if strings.TrimSpace(token) == "" {
+		return false, nil
+	}
+	endpoint := v.VerifyURL
+	if endpoint == "" {
+		endpoint = turnstileVerifyURL
+	}
+	form := url.Values{"secret": {v.Secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, fmt.Errorf("building turnstile request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("calling turnstile: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("turnstile answered %d", resp.StatusCode)
+	}
+
+	result := turnstileResult{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("reading turnstile response: %w", err)
+	}
+	return result.Success, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptchaEnabled(t *testing.T) {
+	req := require.New(t)
+
+	t.Setenv("TURNSTILE_SITE_KEY", "")
+	t.Setenv("TURNSTILE_SECRET_KEY", "")
+	req.False(CaptchaEnabled())
+	req.Nil(NewCaptchaVerifier())
+
+	t.Setenv("TURNSTILE_SITE_KEY", "site-key")
+	req.False(CaptchaEnabled(), "the secret is needed to verify responses")
+
+	t.Setenv("TURNSTILE_SECRET_KEY", "secret-key")
+	req.True(CaptchaEnabled())
+	req.NotNil(NewCaptchaVerifier())
+}
+
+func TestTurnstileVerifier_Verify(t *testing.T) {
+	req := require.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req.NoError(r.ParseForm())
+		req.Equal("secret-key", r.PostForm.Get("secret"))
+		req.Equal("203.0.113.7", r.PostForm.Get("remoteip"))
+		switch r.PostForm.Get("response") {
+		case "good":
+			w.Write([]byte(`{"success":true}`))
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+		}
+	}))
+	defer server.Close()
+
+	v := &TurnstileVerifier{Secret: "secret-key", VerifyURL: server.URL, Client: server.Client()}
+	ctx := context.Background()
+
+	ok, err := v.Verify(ctx, "good", "203.0.113.7")
+	req.NoError(err)
+	req.True(ok)
+
+	ok, err = v.Verify(ctx, "reused", "203.0.113.7")
+	req.NoError(err)
+	req.False(ok)
+
+	_, err = v.Verify(ctx, "broken", "203.0.113.7")
+	req.Error(err)
+
+	ok, err = v.Verify(ctx, "", "203.0.113.7")
+	req.NoError(err)
+	req.False(ok, "a blank token is rejected without asking Turnstile")
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// CryptoAsset is a cryptocurrency accepted through the crypto processor
+type CryptoAsset struct {
+	Code string
+	Name string
+}
+
+// CryptoAssets are the currencies offered on the crypto donation page
+var CryptoAssets = []CryptoAsset{
+	{Code: "BTC", Name: "Bitcoin"},
+	{Code: "ETH", Name: "Ethereum"},
+	{Code: "USDC", Name: "USD Coin"},
+	{Code: "LTC", Name: "Litecoin"},
+	{Code: "DOGE", Name: "Dogecoin"},
+}
+
+// CryptoProcessor is a crypto donation processor (The Giving Block style):
+// it issues a deposit address per pledge, liquidates what arrives to USD, and
+// reports the result by webhook.
+type CryptoProcessor interface {
+	CreateDeposit(req CryptoDepositRequest) (*CryptoDeposit, error)
+	VerifyWebhook(body []byte, signature string) bool
+}
+
+// CryptoDepositRequest asks the processor for a deposit address
+type CryptoDepositRequest struct {
+	ExternalID   string  `json:"externalId"`
+	Currency     string  `json:"pledgeCurrency"`
+	CryptoAmount float64 `json:"pledgeAmount"`
+	DonorName    string  `json:"donorName"`
+	DonorEmail   string  `json:"donorEmail"`
+}
+
+// CryptoDeposit is where the donor sends their crypto
+type CryptoDeposit struct {
+	PledgeID       string  `json:"pledgeId"`
+	DepositAddress string  `json:"depositAddress"`
+	DepositTag     string  `json:"depositTag,omitempty"`
+	EstimatedUSD   float64 `json:"estimatedValueUsd"`
+}
+
+// CryptoWebhookEvent reports a deposit received and converted to USD
+type CryptoWebhookEvent struct {
+	EventType       string    `json:"eventType"`
+	PledgeID        string    `json:"pledgeId"`
+	TransactionHash string    `json:"transactionHash"`
+	Currency        string    `json:"currency"`
+	CryptoAmount    float64   `json:"cryptoAmount"`
+	ValueUSD        float64   `json:"valueUsd"`
+	ReceivedAt      time.Time `json:"receivedAt"`
+}
+
+// CryptoEventConverted is sent once a deposit has been liquidated to USD
+const CryptoEventConverted = "transaction.converted"
+
+// CryptoProcessorClient is the HTTP implementation of CryptoProcessor
+type CryptoProcessorClient struct {
+	APIKey         string
+	OrganizationID string
+	WebhookSecret  string
+	BaseURL        string
+	Client         *http.Client
+}
+
+// CryptoEnabled reports whether crypto donations should be offered: a
+// processor API key is configured, or we're in development with the mock.
+func CryptoEnabled() bool {
+	return os.Getenv("CRYPTO_PROCESSOR_API_KEY") != "" || os.Getenv("GO_ENV") == "development"
+}
+
+// NewCryptoProcessor creates the configured crypto processor client. In
+// development without credentials it returns a mock.
+func NewCryptoProcessor() (CryptoProcessor, error) {
+	apiKey := os.Getenv("CRYPTO_PROCESSOR_API_KEY")
+	if apiKey == "" {
+		if os.Getenv("GO_ENV") == "development" {
+			fmt.Printf("[Crypto] development mode: CRYPTO_PROCESSOR_API_KEY not set — returning mockCryptoProcessor\n")
+			return &mockCryptoProcessor{}, nil
+		}
+		return nil, fmt.Errorf("crypto processor is not configured")
+	}
+
+	baseURL := os.Getenv("CRYPTO_PROCESSOR_URL")
+	if baseURL == "" {
+		return nil, fmt.Errorf("CRYPTO_PROCESSOR_URL is not set")
+	}
+
+	return &CryptoProcessorClient{
+		APIKey:         apiKey,
+		OrganizationID: os.Getenv("CRYPTO_PROCESSOR_ORGANIZATION_ID"),
+		WebhookSecret:  os.Getenv("CRYPTO_PROCESSOR_WEBHOOK_SECRET"),
+		BaseURL:        strings.TrimRight(baseURL, "/"),
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// CreateDeposit requests a deposit address for a crypto pledge
+func (p *CryptoProcessorClient) CreateDeposit(req CryptoDepositRequest) (*CryptoDeposit, error) {
+	payload := map[string]interface{}{
+		"organizationId": p.OrganizationID,
+		"externalId":     req.ExternalID,
+		"pledgeCurrency": req.Currency,
+		"pledgeAmount":   req.CryptoAmount,
+		"donorName":      req.DonorName,
+		"donorEmail":     req.DonorEmail,
+		"isAnonymous":    false,
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", p.BaseURL+"/deposit-address", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.APIKey)
+
+	resp, err := p.Client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Printf("[Crypto] Deposit address error response: %s\n", string(body))
+		return nil, fmt.Errorf("crypto processor request failed with status: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data CryptoDeposit `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.Data.DepositAddress == "" {
+		return nil, fmt.Errorf("crypto processor returned no deposit address")
+	}
+	return &result.Data, nil
+}
+
+// VerifyWebhook checks the HMAC-SHA256 signature (hex) of a webhook body
+func (p *CryptoProcessorClient) VerifyWebhook(body []byte, signature string) bool {
+	return VerifyHMACSignature(p.WebhookSecret, body, signature)
+}
+
+// mockCryptoProcessor issues fake deposit addresses for development
+type mockCryptoProcessor struct{}
+
+// NewMockCryptoProcessor returns a processor that never contacts the network
+func NewMockCryptoProcessor() CryptoProcessor {
+	return &mockCryptoProcessor{}
+}
+
+func (m *mockCryptoProcessor) CreateDeposit(req CryptoDepositRequest) (*CryptoDeposit, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	return &CryptoDeposit{
+		PledgeID:       "mock_pledge_" + id.String(),
+		DepositAddress: "mock_" + strings.ToLower(req.Currency) + "_" + strings.ReplaceAll(id.String(), "-", ""),
+	}, nil
+}
+
+func (m *mockCryptoProcessor) VerifyWebhook(body []byte, signature string) bool {
+	return true
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCryptoProcessorClient_CreateDeposit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/deposit-address", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "org-1", body["organizationId"])
+		assert.Equal(t, "ETH", body["pledgeCurrency"])
+		assert.Equal(t, "donation-123", body["externalId"])
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"pledgeId":"pl_1","depositAddress":"0xabc","estimatedValueUsd":1234.5}}`))
+	}))
+	defer server.Close()
+
+	client := &CryptoProcessorClient{APIKey: "test-key", OrganizationID: "org-1", BaseURL: server.URL, Client: server.Client()}
+	deposit, err := client.CreateDeposit(CryptoDepositRequest{ExternalID: "donation-123", Currency: "ETH", CryptoAmount: 0.5})
+	require.NoError(t, err)
+	assert.Equal(t, "pl_1", deposit.PledgeID)
+	assert.Equal(t, "0xabc", deposit.DepositAddress)
+	assert.Equal(t, 1234.5, deposit.EstimatedUSD)
+}
+
+func TestCryptoProcessorClient_CreateDeposit_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := &CryptoProcessorClient{APIKey: "test-key", BaseURL: server.URL, Client: server.Client()}
+	_, err := client.CreateDeposit(CryptoDepositRequest{Currency: "BTC"})
+	assert.Error(t, err)
+}
+
+func TestCryptoProcessorClient_VerifyWebhook(t *testing.T) {
+	body := []byte(`{"eventType":"transaction.converted"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	client := &CryptoProcessorClient{WebhookSecret: "secret"}
+	assert.True(t, client.VerifyWebhook(body, sig))
+	assert.True(t, client.VerifyWebhook(body, "sha256="+sig))
+	assert.False(t, client.VerifyWebhook(body, "deadbeef"))
+	assert.False(t, (&CryptoProcessorClient{}).VerifyWebhook(body, sig))
+}
+
+func TestEmailService_generateCryptoReceiptHTML(t *testing.T) {
+	emailService := &EmailService{}
+
+	html, err := emailService.generateCryptoReceiptHTML(CryptoReceiptData{
+		DonorName:        "Jane Doe",
+		Currency:         "BTC",
+		CryptoAmount:     0.015,
+		ConvertedUSD:     912.34,
+		TransactionHash:  "0xfeed",
+		ReceivedDate:     time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		OrganizationName: "Test Organization",
+		OrganizationEIN:  "12-3456789",
+	})
+	require.NoError(t, err)
+	require.Contains(t, html, "0.01500000 BTC")
+	require.Contains(t, html, "$912.34")
+	require.Contains(t, html, "No goods or services")
+	require.Contains(t, html, "Form 8283")
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"os"
+	"strings"
+)
+
+// disposableEmailDomains are throwaway mailbox providers card testers use to
+// give every attempt a fresh address. Add more with DISPOSABLE_EMAIL_DOMAINS,
+// a comma-separated list.
+var disposableEmailDomains = map[string]bool{
+	"10minutemail.com":  true,
+	"discard.email":     true,
+	"dispostable.com":   true,
+	"emailondeck.com":   true,
+	"fakeinbox.com":     true,
+	"getnada.com":       true,
+	"guerrillamail.com": true,
+	"guerrillamail.net": true,
+	"maildrop.cc":       true,
+	"mailinator.com":    true,
+	"mailnesia.com":     true,
+	"mintemail.com":     true,
+	"mohmal.com":        true,
+	"sharklasers.com":   true,
+	"spamgourmet.com":   true,
+	"temp-mail.org":     true,
+	"tempmail.com":      true,
+	"tempmailo.com":     true,
+	"throwawaymail.com": true,
+	"trashmail.com":     true,
+	"yopmail.com":       true,
+}
+
+// IsDisposableEmail reports whether email is at a throwaway mailbox
+// provider, including subdomains of one
+func IsDisposableEmail(email string) bool {
+	_, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok || domain == "" {
+		return false
+	}
+	extra := map[string]bool{}
+	for _, d := range strings.Split(os.Getenv("DISPOSABLE_EMAIL_DOMAINS"), ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			extra[d] = true
+		}
+	}
+	for {
+		if disposableEmailDomains[domain] || extra[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok || !strings.Contains(parent, ".") {
+			return false
+		}
+		domain = parent
+	}
+}
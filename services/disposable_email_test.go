@@ -0,0 +1,22 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDisposableEmail(t *testing.T) {
+	req := require.New(t)
+
+	t.Setenv("DISPOSABLE_EMAIL_DOMAINS", " Burner.example , ")
+	req.True(IsDisposableEmail("x1@mailinator.com"))
+	req.True(IsDisposableEmail(" X1@YOPMAIL.COM "))
+	req.True(IsDisposableEmail("x1@inbox.guerrillamail.com"), "subdomains of a provider")
+	req.True(IsDisposableEmail("x1@burner.example"), "domains added in the environment")
+	req.False(IsDisposableEmail("donor@gmail.com"))
+	req.False(IsDisposableEmail("donor@mailinator.com.example.org"))
+	req.False(IsDisposableEmail("donor@com"))
+	req.False(IsDisposableEmail("not-an-email"))
+	req.False(IsDisposableEmail(""))
+}
@@ -2,10 +2,13 @@ package services
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"html/template"
 	"net/smtp"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -88,8 +91,14 @@ type DonationReceiptData struct {
 	CustomerID          string // Helcim Customer ID for subscription management
 	NextBillingDate     *time.Time
 	TransactionID       string
+	ReceiptNumber       string // the number the gift's bookkeeping records it under
 	DonationDate        time.Time
 	TaxDeductibleAmount float64
+	FairMarketValue     float64     // value of goods or services provided in return, if any
+	GoodsProvided       string      // description of those goods or services
+	OrderItems          []OrderLine // store order lines, itemized with shipping and sales tax
+	Shipping            float64
+	SalesTax            float64
 	OrganizationEIN     string
 	OrganizationName    string
 	OrganizationAddress string
@@ -99,6 +108,8 @@ type DonationReceiptData struct {
 	DonorState          string
 	DonorZip            string
 	ContactEmail        string // configurable contact email for support
+	ThankYouMessage     string // personalized note from the partner page's thank-you tier
+	ThankYouVideoURL    string
 }
 
 // ContactFormData contains data for contact form submissions
@@ -107,6 +118,8 @@ type ContactFormData struct {
 	Email          string
 	Subject        string
 	Message        string
+	Topic          string // routes the notification; see ContactRecipients
+	TopicLabel     string
 	SubmissionDate time.Time
 }
 
@@ -125,8 +138,15 @@ func (e *EmailService) SendDonationReceipt(toEmail string, data DonationReceiptD
 	data.ContactEmail = e.ContactEmail
 	fmt.Printf("[EMAIL_SERVICE] Contact email injected: %s\n", data.ContactEmail)
 
+	// Receipts missing what the donor needs for their deduction are held
+	// back rather than sent
+	if err := CheckReceiptCompliance(data); err != nil {
+		fmt.Printf("[EMAIL_SERVICE] Donation receipt blocked: %v\n", err)
+		return err
+	}
+
 	// Generate email content with timing
-	subject := fmt.Sprintf("Thank you for your donation to %s", data.OrganizationName)
+	subject := donationReceiptSubject(data)
 	fmt.Printf("[EMAIL_SERVICE] Generated donation receipt subject: %s\n", subject)
 
 	htmlBody, err := e.generateReceiptHTML(data)
@@ -136,6 +156,10 @@ func (e *EmailService) SendDonationReceipt(toEmail string, data DonationReceiptD
 	}
 
 	textBody := e.generateReceiptText(data)
+	if err := checkReceiptStatements(map[string]string{"HTML": htmlBody, "text": textBody}); err != nil {
+		fmt.Printf("[EMAIL_SERVICE] Donation receipt blocked: %v\n", err)
+		return err
+	}
 
 	// Log content metrics
 	htmlSize := len(htmlBody)
@@ -143,11 +167,135 @@ func (e *EmailService) SendDonationReceipt(toEmail string, data DonationReceiptD
 	fmt.Printf("[EMAIL_SERVICE] Generated receipt content - HTML: %d bytes, Text: %d bytes\n",
 		htmlSize, textSize)
 
+	// Attach a PDF copy so the donor has a file to keep for their tax records
+	receiptPDF := EmailAttachment{
+		Filename:    ReceiptPDFFilename(data),
+		ContentType: "application/pdf",
+		Data:        e.GenerateReceiptPDF(data),
+	}
+	fmt.Printf("[EMAIL_SERVICE] Generated receipt PDF %s - %d bytes\n", receiptPDF.Filename, len(receiptPDF.Data))
+
 	// Send email with BCC to michael@avrnpo.org (keep this for now for receipt tracking)
 	bccEmails := []string{"michael@avrnpo.org"}
 	fmt.Printf("[EMAIL_SERVICE] Donation receipt BCC recipients: %v\n", bccEmails)
 
-	return e.sendEmailWithBCC(toEmail, subject, htmlBody, textBody, bccEmails)
+	return e.sendEmailWithBCC(toEmail, subject, htmlBody, textBody, bccEmails, receiptPDF)
+}
+
+// donationReceiptSubject is the subject line of a donation receipt email
+func donationReceiptSubject(data DonationReceiptData) string {
+	return fmt.Sprintf("Thank you for your donation to %s", data.OrganizationName)
+}
+
+// DonationReceiptPreview is a donation receipt email as SendDonationReceipt
+// would send it
+type DonationReceiptPreview struct {
+	To          string
+	Subject     string
+	HTML        string
+	Text        string
+	PDFFilename string
+	// Blocked lists what the compliance check would hold the receipt back
+	// for; it's empty when the receipt would go out
+	Blocked []string
+}
+
+// PreviewDonationReceipt renders the receipt SendDonationReceipt would send
+// toEmail, without sending anything, so staff can see what a donor got.
+// Receipts the compliance check would block are still rendered, with the
+// problems in Blocked. It works without SMTP configured.
+func (e *EmailService) PreviewDonationReceipt(toEmail string, data DonationReceiptData) (DonationReceiptPreview, error) {
+	data.ContactEmail = e.ContactEmail
+	preview := DonationReceiptPreview{
+		To:          toEmail,
+		Subject:     donationReceiptSubject(data),
+		Text:        e.generateReceiptText(data),
+		PDFFilename: ReceiptPDFFilename(data),
+	}
+	html, err := e.generateReceiptHTML(data)
+	if err != nil {
+		return preview, fmt.Errorf("error generating email HTML: %v", err)
+	}
+	preview.HTML = html
+
+	for _, check := range []error{
+		CheckReceiptCompliance(data),
+		checkReceiptStatements(map[string]string{"HTML": preview.HTML, "text": preview.Text}),
+	} {
+		if blocked, ok := AsReceiptComplianceError(check); ok {
+			preview.Blocked = append(preview.Blocked, blocked.Problems...)
+		}
+	}
+	return preview, nil
+}
+
+// ReceiptPDFFilename names a receipt's PDF attachment by the donation date
+func ReceiptPDFFilename(data DonationReceiptData) string {
+	return fmt.Sprintf("donation-receipt-%s.pdf", data.DonationDate.Format("2006-01-02"))
+}
+
+// GenerateReceiptPDF renders a donation receipt as a PDF the donor can keep
+// for their tax records. It carries the receipt's gift and tax details but
+// not the email's thank-you and program information.
+func (e *EmailService) GenerateReceiptPDF(data DonationReceiptData) []byte {
+	if data.ContactEmail == "" {
+		data.ContactEmail = e.ContactEmail
+	}
+
+	lines := []pdfLine{pdfHeading(data.OrganizationName, 16)}
+	if data.OrganizationAddress != "" {
+		lines = append(lines, pdfText("%s", data.OrganizationAddress))
+	}
+	if data.OrganizationEIN != "" {
+		lines = append(lines, pdfText("Tax ID (EIN): %s", data.OrganizationEIN))
+	}
+
+	lines = append(lines, pdfLine{}, pdfHeading("Donation Receipt", 13), pdfText("%s", data.DonorName))
+	for _, l := range []string{data.DonorAddressLine1, data.DonorAddressLine2} {
+		if l != "" {
+			lines = append(lines, pdfText("%s", l))
+		}
+	}
+	if data.DonorCity != "" {
+		lines = append(lines, pdfText("%s, %s %s", data.DonorCity, data.DonorState, data.DonorZip))
+	}
+
+	lines = append(lines,
+		pdfLine{},
+		pdfText("Date: %s", data.DonationDate.Format("January 2, 2006")),
+	)
+	if data.ReceiptNumber != "" {
+		lines = append(lines, pdfText("Receipt Number: %s", data.ReceiptNumber))
+	}
+	lines = append(lines,
+		pdfText("Transaction ID: %s", data.TransactionID),
+		pdfText("Donation Type: %s", data.DonationType),
+		pdfText("Amount: $%.2f", data.DonationAmount),
+	)
+	for _, l := range strings.Split(strings.TrimRight(receiptOrderLines(data)+receiptGoodsLines(data), "\n"), "\n") {
+		if l != "" {
+			lines = append(lines, pdfText("%s", l))
+		}
+	}
+	if data.SubscriptionID != "" {
+		lines = append(lines, pdfText("Subscription ID: %s", data.SubscriptionID))
+	}
+	if data.NextBillingDate != nil && !data.NextBillingDate.IsZero() {
+		lines = append(lines, pdfText("Next Billing Date: %s", data.NextBillingDate.Format("January 2, 2006")))
+	}
+
+	lines = append(lines,
+		pdfLine{},
+		pdfHeading("Tax Information", 13),
+		pdfText("%s is a registered 501(c)(3) non-profit organization.", data.OrganizationName),
+		pdfText("%s", strings.Join(strings.Fields(receiptTaxStatement(data)), " ")),
+	)
+
+	lines = append(lines, pdfLine{}, pdfText("Please keep this receipt for your tax records."))
+	if data.ContactEmail != "" {
+		lines = append(lines, pdfText("Questions? Contact us at %s.", data.ContactEmail))
+	}
+	return renderPDF(data.OrganizationName+" Donation Receipt", lines)
 }
 
 // SendContactNotification sends a contact form notification to the organization
@@ -161,11 +309,14 @@ func (e *EmailService) SendContactNotification(contactData ContactFormData) erro
 	}
 	fmt.Printf("[EMAIL_SERVICE] Configuration validated successfully\n")
 
-	// Send to configured contact email
-	toEmail := e.ContactEmail
-	fmt.Printf("[EMAIL_SERVICE] Contact notification recipient: %s\n", toEmail)
+	// Send to the recipients configured for the message's topic
+	recipients := e.ContactRecipients(contactData.Topic)
+	fmt.Printf("[EMAIL_SERVICE] Contact notification recipients for topic %q: %v\n", contactData.Topic, recipients)
 
 	subject := fmt.Sprintf("New Contact Form Submission: %s", contactData.Subject)
+	if contactData.TopicLabel != "" {
+		subject = fmt.Sprintf("New Contact Form Submission [%s]: %s", contactData.TopicLabel, contactData.Subject)
+	}
 	fmt.Printf("[EMAIL_SERVICE] Generated subject: %s\n", subject)
 
 	// Generate email content with timing
@@ -185,7 +336,25 @@ func (e *EmailService) SendContactNotification(contactData ContactFormData) erro
 
 	// Send email
 	fmt.Printf("[EMAIL_SERVICE] Initiating email send for contact notification\n")
-	return e.sendEmail(toEmail, subject, htmlBody, textBody)
+	return e.sendEmailWithBCC(recipients[0], subject, htmlBody, textBody, recipients[1:])
+}
+
+// ContactRecipients is who gets contact form notifications for topic. Each
+// topic's recipients are a comma-separated list in CONTACT_EMAIL_<TOPIC>,
+// such as CONTACT_EMAIL_PRESS; topics without one go to ContactEmail.
+func (e *EmailService) ContactRecipients(topic string) []string {
+	var recipients []string
+	if topic != "" {
+		for _, addr := range strings.Split(os.Getenv("CONTACT_EMAIL_"+strings.ToUpper(topic)), ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				recipients = append(recipients, addr)
+			}
+		}
+	}
+	if len(recipients) == 0 {
+		recipients = []string{e.ContactEmail}
+	}
+	return recipients
 }
 
 // isConfigured checks if the email service has all required configuration
@@ -225,6 +394,7 @@ func (e *EmailService) generateReceiptHTML(data DonationReceiptData) (string, er
         .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
         .content { padding: 20px; background-color: #f9f9f9; }
         .receipt-details { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; }
+        .thank-you-note { background-color: #f0f7ff; padding: 15px; border-left: 4px solid #2c5aa0; margin: 20px 0; }
         .amount { font-size: 24px; font-weight: bold; color: #dc2626; }
         .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
         .logo { max-width: 150px; height: auto; }
@@ -254,10 +424,22 @@ func (e *EmailService) generateReceiptHTML(data DonationReceiptData) (string, er
             
             <div class="receipt-details">
                 <h3>Donation Receipt</h3>
+                {{if .ReceiptNumber}}<p><strong>Receipt Number:</strong> {{.ReceiptNumber}}</p>{{end}}
                 <p><strong>Transaction ID:</strong> {{.TransactionID}}</p>
                 <p><strong>Date:</strong> {{.DonationDate.Format "January 2, 2006"}}</p>
 				<p><strong>Donation Type:</strong> {{.DonationType}}</p>
 				<p><strong>Amount:</strong> <span class="amount">${{printf "%.2f" .DonationAmount}}</span></p>
+				{{if .OrderItems}}
+				<table style="width: 100%; border-collapse: collapse;">
+					{{range .OrderItems}}
+					<tr><td>{{.Quantity}} × {{.Description}}</td><td style="text-align: right;">${{printf "%.2f" .Total}}</td></tr>
+					{{end}}
+					<tr><td>Shipping</td><td style="text-align: right;">${{printf "%.2f" .Shipping}}</td></tr>
+					{{if .SalesTax}}
+					<tr><td>Sales tax</td><td style="text-align: right;">${{printf "%.2f" .SalesTax}}</td></tr>
+					{{end}}
+				</table>
+				{{end}}
 				{{if .SubscriptionID}}
 				<p><strong>Subscription ID:</strong> {{.SubscriptionID}}</p>
 				{{end}}
@@ -271,26 +453,44 @@ func (e *EmailService) generateReceiptHTML(data DonationReceiptData) (string, er
 				<p><strong>Next Billing Date:</strong> {{.NextBillingDate.Format "January 2, 2006"}}</p>
 				{{end}}
 				{{end}}
-				{{if ne .TaxDeductibleAmount .DonationAmount}}
+				{{if .FairMarketValue}}
+				<p><strong>Goods or Services Provided:</strong> {{if .GoodsProvided}}{{.GoodsProvided}}{{else}}Goods or services{{end}}</p>
+				<p><strong>Estimated Fair Market Value:</strong> ${{printf "%.2f" .FairMarketValue}}</p>
+				<p><strong>Tax Deductible Amount:</strong> ${{printf "%.2f" .TaxDeductibleAmount}}</p>
+				{{else if ne .TaxDeductibleAmount .DonationAmount}}
 				<p><strong>Tax Deductible Amount:</strong> ${{printf "%.2f" .TaxDeductibleAmount}}</p>
 				{{end}}
             </div>
             
-            {{if eq .DonationType "Monthly"}}
+            {{if or .ThankYouMessage .ThankYouVideoURL}}
+            <div class="thank-you-note">
+                {{if .ThankYouMessage}}<p>{{.ThankYouMessage}}</p>{{end}}
+                {{if .ThankYouVideoURL}}<p><a href="{{.ThankYouVideoURL}}">Watch your thank-you video</a></p>{{end}}
+            </div>
+            {{end}}
+            
+            {{if or (eq .DonationType "Monthly") (eq .DonationType "Quarterly") (eq .DonationType "Annual")}}
             <h3>Subscription Management</h3>
             <p>
-                Your monthly recurring donation will automatically process on the same day each month. 
-				To modify the amount, change frequency, or cancel your subscription, please contact us at 
-				<strong>{{.ContactEmail}}</strong> and reference your <strong>Customer ID: {{.CustomerID}}</strong> 
-				in your message.
+                Your {{if eq .DonationType "Monthly"}}monthly{{else if eq .DonationType "Quarterly"}}quarterly{{else}}annual{{end}} recurring donation will automatically process on the same day each {{if eq .DonationType "Monthly"}}month{{else if eq .DonationType "Quarterly"}}quarter{{else}}year{{end}}. 
+				To change the amount, update your card, or cancel, sign in to your account and open
+				<strong>My Subscriptions</strong>. For anything else, contact us at <strong>{{.ContactEmail}}</strong>
+				and reference your <strong>Customer ID: {{.CustomerID}}</strong>.
             </p>
             {{end}}
             
             <h3>Tax Information</h3>
             <p>
                 {{.OrganizationName}} is a registered 501(c)(3) non-profit organization. 
+                {{if .FairMarketValue}}
+                In exchange for this payment you received {{if .GoodsProvided}}{{.GoodsProvided}}{{else}}goods or services{{end}}
+                with an estimated fair market value of ${{printf "%.2f" .FairMarketValue}}. The amount deductible for
+                federal income tax purposes is limited to the excess of your payment over that value:
+                ${{printf "%.2f" .TaxDeductibleAmount}}.
+                {{else}}
                 Your donation is tax-deductible to the full extent allowed by law. 
                 No goods or services were provided in exchange for this donation.
+                {{end}}
             </p>
             {{if .OrganizationEIN}}
             <p><strong>Tax ID (EIN):</strong> {{.OrganizationEIN}}</p>
@@ -355,18 +555,19 @@ Dear %s,
 Thank you for your generous donation to %s!
 
 DONATION RECEIPT
-Transaction ID: %s
+%sTransaction ID: %s
 Date: %s
 Donation Type: %s
 Amount: $%.2f
-
+%s%s%s
 Subscription ID: %s
 Customer ID: %s
 Next Billing Date: %s
 
 RECURRING SUBSCRIPTION MANAGEMENT
-Your subscription Customer ID is %s. Please reference this ID when 
-contacting us to cancel or modify your recurring donation.
+To change the amount, update your card, or cancel, sign in to your
+account and open My Subscriptions. For anything else, contact us and
+reference your Customer ID: %s
 Email: %s
 
 Donor Address:
@@ -376,8 +577,7 @@ Donor Address:
 
 TAX INFORMATION
 %s is a registered 501(c)(3) non-profit organization. 
-Your donation is tax-deductible to the full extent allowed by law. 
-No goods or services were provided in exchange for this donation.
+%s
 %s
 
 HOW YOUR DONATION HELPS
@@ -399,10 +599,14 @@ This is an automated receipt. Please save this for your tax records.
 `,
 		data.DonorName,
 		data.OrganizationName,
+		receiptNumberLine(data),
 		data.TransactionID,
 		data.DonationDate.Format("January 2, 2006"),
 		data.DonationType,
 		data.DonationAmount,
+		receiptOrderLines(data),
+		receiptGoodsLines(data),
+		receiptThankYouLines(data),
 		data.SubscriptionID,
 		data.CustomerID,
 		func() string {
@@ -419,6 +623,7 @@ This is an automated receipt. Please save this for your tax records.
 		data.DonorState,
 		data.DonorZip,
 		data.OrganizationName,
+		receiptTaxStatement(data),
 		func() string {
 			if data.OrganizationEIN != "" {
 				return fmt.Sprintf("Tax ID (EIN): %s", data.OrganizationEIN)
@@ -431,24 +636,111 @@ This is an automated receipt. Please save this for your tax records.
 	)
 }
 
+// receiptGoodsProvided describes what the donor received in return for the
+// payment, for receipts where the FMV is set but no description was recorded
+func receiptGoodsProvided(data DonationReceiptData) string {
+	if data.GoodsProvided != "" {
+		return data.GoodsProvided
+	}
+	return "goods or services"
+}
+
+// receiptOrderLines lists a store order's items, shipping and sales tax in
+// the plain text receipt, or is blank for other payments
+func receiptOrderLines(data DonationReceiptData) string {
+	if len(data.OrderItems) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, item := range data.OrderItems {
+		fmt.Fprintf(&b, "  %d × %s: $%.2f\n", item.Quantity, item.Description, item.Total())
+	}
+	fmt.Fprintf(&b, "  Shipping: $%.2f\n", data.Shipping)
+	if data.SalesTax > 0 {
+		fmt.Fprintf(&b, "  Sales tax: $%.2f\n", data.SalesTax)
+	}
+	return b.String()
+}
+
+// receiptGoodsLines itemizes the quid pro quo portion of a payment in the
+// plain text receipt, or is blank when nothing was provided in return
+func receiptGoodsLines(data DonationReceiptData) string {
+	if data.FairMarketValue <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("Goods or Services Provided: %s\nEstimated Fair Market Value: $%.2f\nTax Deductible Amount: $%.2f\n",
+		receiptGoodsProvided(data), data.FairMarketValue, data.TaxDeductibleAmount)
+}
+
+// receiptThankYouLines is the partner page's thank-you note and video link
+// in the plain text receipt, or is blank when there's no thank-you tier
+// receiptNumberLine is the receipt number line of the text receipt, when
+// the receipt has been numbered
+func receiptNumberLine(data DonationReceiptData) string {
+	if data.ReceiptNumber == "" {
+		return ""
+	}
+	return fmt.Sprintf("Receipt Number: %s\n", data.ReceiptNumber)
+}
+
+func receiptThankYouLines(data DonationReceiptData) string {
+	var b strings.Builder
+	if data.ThankYouMessage != "" {
+		fmt.Fprintf(&b, "\n%s\n", data.ThankYouMessage)
+	}
+	if data.ThankYouVideoURL != "" {
+		fmt.Fprintf(&b, "Watch your thank-you video: %s\n", data.ThankYouVideoURL)
+	}
+	return b.String()
+}
+
+// receiptTaxStatement is the disclosure the IRS requires on a receipt: either
+// that nothing was provided for the gift or, for a quid pro quo contribution
+// over $75, what was provided, its value and the deductible remainder
+func receiptTaxStatement(data DonationReceiptData) string {
+	if data.FairMarketValue <= 0 {
+		return "Your donation is tax-deductible to the full extent allowed by law. \nNo goods or services were provided in exchange for this donation."
+	}
+	return fmt.Sprintf("In exchange for this payment you received %s with an estimated fair market value of $%.2f.\nThe amount deductible for federal income tax purposes is limited to the excess of your payment\nover that value: $%.2f.",
+		receiptGoodsProvided(data), data.FairMarketValue, data.TaxDeductibleAmount)
+}
+
+// LoadTestEmailDomain is the email domain of the synthetic donors created
+// in load test mode. It's reserved, so no real donor can have it.
+const LoadTestEmailDomain = "loadtest.invalid"
+
+// IsLoadTestEmail reports whether email belongs to a synthetic load test donor
+func IsLoadTestEmail(email string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSpace(email)), "@"+LoadTestEmailDomain)
+}
+
 // sendEmail sends an email using SMTP
 func (e *EmailService) sendEmail(toEmail, subject, htmlBody, textBody string) error {
 	return e.sendEmailWithBCC(toEmail, subject, htmlBody, textBody, nil)
 }
 
-// sendEmailWithBCC sends an email using SMTP with BCC recipients
-func (e *EmailService) sendEmailWithBCC(toEmail, subject, htmlBody, textBody string, bccEmails []string) error {
+// EmailAttachment is a file attached to an email
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// sendEmailWithBCC sends an email using SMTP with BCC recipients. With
+// attachments the HTML and text parts are wrapped in a multipart/mixed
+// message alongside the files.
+func (e *EmailService) sendEmailWithBCC(toEmail, subject, htmlBody, textBody string, bccEmails []string, attachments ...EmailAttachment) error {
+	return e.sendMessage(toEmail, subject, htmlBody, textBody, bccEmails, nil, attachments...)
+}
+
+// sendMessage sends an email using SMTP, adding headers to the message's
+// own, e.g. for the mail provider to pass back in its event webhooks
+func (e *EmailService) sendMessage(toEmail, subject, htmlBody, textBody string, bccEmails []string, headers map[string]string, attachments ...EmailAttachment) error {
 	startTime := time.Now()
 	fmt.Printf("[EMAIL_SMTP] Starting email send operation at %s\n", startTime.Format("2006-01-02 15:04:05"))
 
 	// Create message with both HTML and text parts
-	message := fmt.Sprintf(`To: %s
-From: %s <%s>
-Subject: %s
-MIME-Version: 1.0
-Content-Type: multipart/alternative; boundary="boundary123"
-
---boundary123
+	body := fmt.Sprintf(`--boundary123
 Content-Type: text/plain; charset=UTF-8
 
 %s
@@ -459,7 +751,21 @@ Content-Type: text/html; charset=UTF-8
 %s
 
 --boundary123--
-`, toEmail, e.FromName, e.FromEmail, subject, textBody, htmlBody)
+`, textBody, htmlBody)
+	contentType := `multipart/alternative; boundary="boundary123"`
+	if len(attachments) > 0 {
+		body = fmt.Sprintf("--mixed123\nContent-Type: %s\n\n%s%s--mixed123--\n", contentType, body, attachmentParts(attachments))
+		contentType = `multipart/mixed; boundary="mixed123"`
+		fmt.Printf("[EMAIL_SMTP] Attaching %d file(s)\n", len(attachments))
+	}
+
+	message := fmt.Sprintf(`To: %s
+From: %s <%s>
+Subject: %s
+%sMIME-Version: 1.0
+Content-Type: %s
+
+%s`, toEmail, e.FromName, e.FromEmail, subject, extraHeaders(headers), contentType, body)
 
 	// Log message statistics
 	messageSize := len(message)
@@ -477,6 +783,13 @@ Content-Type: text/html; charset=UTF-8
 			toEmail, len(recipients))
 	}
 
+	// Synthetic load test donors exercise the queue and templates, but
+	// nothing is mailed to them or copied to staff
+	if IsLoadTestEmail(toEmail) {
+		fmt.Printf("[EMAIL_LOADTEST] Not sending to synthetic donor %s Subject: %s\n", toEmail, subject)
+		return nil
+	}
+
 	// If email sending is disabled, log and return without sending
 	if !e.EmailEnabled {
 		elapsed := time.Since(startTime)
@@ -523,6 +836,39 @@ Content-Type: text/html; charset=UTF-8
 	return nil
 }
 
+// extraHeaders formats headers as message header lines, sorted by name.
+// Line breaks are dropped from values so they can't start new headers.
+func extraHeaders(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		value := strings.NewReplacer("\r", "", "\n", "").Replace(headers[name])
+		fmt.Fprintf(&b, "%s: %s\n", name, value)
+	}
+	return b.String()
+}
+
+// attachmentParts encodes attachments as the base64 parts of a
+// multipart/mixed message, each opening with the mixed123 boundary
+func attachmentParts(attachments []EmailAttachment) string {
+	var b strings.Builder
+	for _, a := range attachments {
+		fmt.Fprintf(&b, "--mixed123\nContent-Type: %s; name=%q\nContent-Disposition: attachment; filename=%q\nContent-Transfer-Encoding: base64\n\n",
+			a.ContentType, a.Filename, a.Filename)
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76] + "\n")
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded + "\n\n")
+	}
+	return b.String()
+}
+
 // generateContactNotificationHTML creates HTML email content for contact notifications
 func (e *EmailService) generateContactNotificationHTML(data ContactFormData) (string, error) {
 	htmlTemplate := `
@@ -555,6 +901,7 @@ func (e *EmailService) generateContactNotificationHTML(data ContactFormData) (st
                 <p><strong>Name:</strong> {{.Name}}</p>
                 <p><strong>Email:</strong> {{.Email}}</p>
                 <p><strong>Subject:</strong> {{.Subject}}</p>
+                {{if .TopicLabel}}<p><strong>Topic:</strong> {{.TopicLabel}}</p>{{end}}
                 <p><strong>Submitted:</strong> {{.SubmissionDate.Format "January 2, 2006 at 3:04 PM"}}</p>
             </div>
             
@@ -598,6 +945,7 @@ CONTACT DETAILS
 Name: %s
 Email: %s
 Subject: %s
+Topic: %s
 Submitted: %s
 
 MESSAGE
@@ -610,8 +958,2675 @@ This message was sent from the AVRNPO.org contact form.
 		data.Name,
 		data.Email,
 		data.Subject,
+		data.TopicLabel,
 		data.SubmissionDate.Format("January 2, 2006 at 3:04 PM"),
 		data.Message,
 		data.Email,
 	)
 }
+
+// PledgeCompletionData contains data for the email sent when the final
+// installment of a pledge has been paid
+type PledgeCompletionData struct {
+	DonorName        string
+	PledgeTotal      float64
+	InstallmentCount int
+	FirstPaymentDate time.Time
+	CompletedDate    time.Time
+	OrganizationName string
+	OrganizationEIN  string
+	ContactEmail     string
+}
+
+// SendPledgeCompletion thanks a donor for completing an installment pledge
+func (e *EmailService) SendPledgeCompletion(toEmail string, data PledgeCompletionData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+	subject := fmt.Sprintf("Your pledge to %s is complete", data.OrganizationName)
+
+	htmlBody, err := e.generatePledgeCompletionHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generatePledgeCompletionText(data))
+}
+
+// generatePledgeCompletionHTML creates HTML email content for a completed pledge
+func (e *EmailService) generatePledgeCompletionHTML(data PledgeCompletionData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Pledge Complete</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Thank You, {{.DonorName}}!</h1>
+            <p>{{.OrganizationName}}</p>
+        </div>
+
+        <div class="content">
+            <p>Your final installment has been received and your pledge is complete.</p>
+
+            <div class="summary">
+                <h3>Pledge Summary</h3>
+                <p><strong>Total Pledged:</strong> ${{printf "%.2f" .PledgeTotal}}</p>
+                <p><strong>Installments:</strong> {{.InstallmentCount}}</p>
+                <p><strong>First Payment:</strong> {{.FirstPaymentDate.Format "January 2, 2006"}}</p>
+                <p><strong>Completed:</strong> {{.CompletedDate.Format "January 2, 2006"}}</p>
+                {{if .OrganizationEIN}}<p><strong>EIN:</strong> {{.OrganizationEIN}}</p>{{end}}
+            </div>
+
+            <p>You received a receipt for each installment; please keep them for your tax records.</p>
+            <p>Questions? Contact us at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>{{.OrganizationName}} is a 501(c)(3) nonprofit organization</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("pledge_completion").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generatePledgeCompletionText creates plain text email content for a completed pledge
+func (e *EmailService) generatePledgeCompletionText(data PledgeCompletionData) string {
+	return fmt.Sprintf(`
+Thank You, %s!
+%s
+
+Your final installment has been received and your pledge is complete.
+
+PLEDGE SUMMARY
+Total Pledged: $%.2f
+Installments: %d
+First Payment: %s
+Completed: %s
+
+You received a receipt for each installment; please keep them for your tax records.
+
+Questions? Contact us at %s.
+`,
+		data.DonorName,
+		data.OrganizationName,
+		data.PledgeTotal,
+		data.InstallmentCount,
+		data.FirstPaymentDate.Format("January 2, 2006"),
+		data.CompletedDate.Format("January 2, 2006"),
+		data.ContactEmail,
+	)
+}
+
+// PaymentFailureNoticeData contains data for the emails sent when a
+// recurring donation's payment fails. Notice counts up from 1 with each
+// failure; FinalNotice marks the last one before the subscription is cancelled.
+type PaymentFailureNoticeData struct {
+	DonorName        string
+	Amount           float64
+	Notice           int
+	FinalNotice      bool
+	NextRetryDate    time.Time
+	OrganizationName string
+	ContactEmail     string
+}
+
+// paymentFailureSubject is the subject line for each dunning notice
+func paymentFailureSubject(data PaymentFailureNoticeData) string {
+	switch {
+	case data.FinalNotice:
+		return fmt.Sprintf("Final notice: your monthly donation to %s will be cancelled", data.OrganizationName)
+	case data.Notice > 1:
+		return fmt.Sprintf("We still couldn't process your monthly donation to %s", data.OrganizationName)
+	default:
+		return fmt.Sprintf("We couldn't process your monthly donation to %s", data.OrganizationName)
+	}
+}
+
+// paymentFailureNextStep tells the donor what happens on the next retry
+func paymentFailureNextStep(data PaymentFailureNoticeData) string {
+	date := data.NextRetryDate.Format("January 2, 2006")
+	if data.FinalNotice {
+		return fmt.Sprintf("We'll try one last time on %s. If that payment fails, your monthly donation will be cancelled.", date)
+	}
+	return fmt.Sprintf("We'll try again on %s.", date)
+}
+
+// SendPaymentFailureNotice tells a donor their recurring payment failed and
+// when it will be retried
+func (e *EmailService) SendPaymentFailureNotice(toEmail string, data PaymentFailureNoticeData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+
+	htmlBody, err := e.generatePaymentFailureNoticeHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, paymentFailureSubject(data), htmlBody, e.generatePaymentFailureNoticeText(data))
+}
+
+// generatePaymentFailureNoticeHTML creates HTML email content for a failed recurring payment
+func (e *EmailService) generatePaymentFailureNoticeHTML(data PaymentFailureNoticeData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Payment Problem</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Hi {{.DonorName}},</h1>
+            <p>{{.OrganizationName}}</p>
+        </div>
+
+        <div class="content">
+            <p>We weren't able to process your monthly donation of ${{printf "%.2f" .Amount}}. This is usually an expired card or a change at your bank.</p>
+
+            <div class="summary">
+                <p>{{.NextStep}}</p>
+            </div>
+
+            <p>To update your card, sign in to your account and open <strong>My Subscriptions</strong>.</p>
+            <p>Questions? Contact us at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>{{.OrganizationName}} is a 501(c)(3) nonprofit organization</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("payment_failure_notice").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		PaymentFailureNoticeData
+		NextStep string
+	}{data, paymentFailureNextStep(data)})
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generatePaymentFailureNoticeText creates plain text email content for a failed recurring payment
+func (e *EmailService) generatePaymentFailureNoticeText(data PaymentFailureNoticeData) string {
+	return fmt.Sprintf(`
+Hi %s,
+%s
+
+We weren't able to process your monthly donation of $%.2f. This is usually
+an expired card or a change at your bank.
+
+%s
+
+To update your card, sign in to your account and open My Subscriptions.
+
+Questions? Contact us at %s.
+`,
+		data.DonorName,
+		data.OrganizationName,
+		data.Amount,
+		paymentFailureNextStep(data),
+		data.ContactEmail,
+	)
+}
+
+// GiftCodeData contains data for the email that delivers a donation gift code
+type GiftCodeData struct {
+	RecipientName    string
+	PurchaserName    string
+	Message          string
+	Code             string
+	Amount           float64
+	ExpiresAt        *time.Time
+	RedeemURL        string
+	OrganizationName string
+	ContactEmail     string
+}
+
+// SendGiftCode emails a gift code to its recipient (or to the purchaser when
+// no recipient email was given)
+func (e *EmailService) SendGiftCode(toEmail string, data GiftCodeData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+	subject := fmt.Sprintf("%s sent you a gift for %s", data.PurchaserName, data.OrganizationName)
+
+	htmlBody, err := e.generateGiftCodeHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generateGiftCodeText(data))
+}
+
+// generateGiftCodeHTML creates HTML email content for a gift code
+func (e *EmailService) generateGiftCodeHTML(data GiftCodeData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Your Gift Code</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; text-align: center; }
+        .code { font-size: 24px; font-weight: bold; letter-spacing: 2px; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{if .RecipientName}}{{.RecipientName}}, you{{else}}You{{end}} have a gift to give!</h1>
+            <p>{{.OrganizationName}}</p>
+        </div>
+
+        <div class="content">
+            <p>{{.PurchaserName}} donated ${{printf "%.2f" .Amount}} to {{.OrganizationName}} in your name, and you get to choose which of our veteran programs it supports.</p>
+            {{if .Message}}<p><em>"{{.Message}}"</em></p>{{end}}
+
+            <div class="summary">
+                <p>Your gift code</p>
+                <p class="code">{{.Code}}</p>
+                {{if .ExpiresAt}}<p><small>Redeem by {{.ExpiresAt.Format "January 2, 2006"}}</small></p>{{end}}
+            </div>
+
+            <p>Redeem it at <a href="{{.RedeemURL}}">{{.RedeemURL}}</a>.</p>
+            <p>Questions? Contact us at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>{{.OrganizationName}} is a 501(c)(3) nonprofit organization</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("gift_code").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateGiftCodeText creates plain text email content for a gift code
+func (e *EmailService) generateGiftCodeText(data GiftCodeData) string {
+	expiry := ""
+	if data.ExpiresAt != nil {
+		expiry = fmt.Sprintf("Redeem by %s\n", data.ExpiresAt.Format("January 2, 2006"))
+	}
+	message := ""
+	if data.Message != "" {
+		message = fmt.Sprintf("\n\"%s\"\n", data.Message)
+	}
+
+	return fmt.Sprintf(`
+%s donated $%.2f to %s in your name, and you get to choose which of our veteran programs it supports.
+%s
+YOUR GIFT CODE: %s
+%s
+Redeem it at %s
+
+Questions? Contact us at %s.
+`,
+		data.PurchaserName,
+		data.Amount,
+		data.OrganizationName,
+		message,
+		data.Code,
+		expiry,
+		data.RedeemURL,
+		data.ContactEmail,
+	)
+}
+
+// UserInvitationData contains data for the email inviting someone to set up
+// the account staff made for them
+type UserInvitationData struct {
+	FirstName        string
+	InvitedBy        string
+	SetupURL         string
+	ExpiresAt        time.Time
+	OrganizationName string
+	ContactEmail     string
+}
+
+// SendUserInvitation emails a link for the user to choose their password
+func (e *EmailService) SendUserInvitation(toEmail string, data UserInvitationData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+	subject := fmt.Sprintf("You're invited to your %s account", data.OrganizationName)
+
+	htmlBody, err := e.generateUserInvitationHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generateUserInvitationText(data))
+}
+
+// generateUserInvitationHTML creates HTML email content for an account
+// invitation
+func (e *EmailService) generateUserInvitationHTML(data UserInvitationData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Your Account Invitation</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; text-align: center; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Welcome{{if .FirstName}}, {{.FirstName}}{{end}}!</h1>
+            <p>{{.OrganizationName}}</p>
+        </div>
+
+        <div class="content">
+            <p>{{if .InvitedBy}}{{.InvitedBy}} has{{else}}We've{{end}} set up an account for you on the {{.OrganizationName}} website. Choose a password to start using it.</p>
+
+            <div class="summary">
+                <p><a href="{{.SetupURL}}">Set up your account</a></p>
+                <p><small>This link works until {{.ExpiresAt.Format "January 2, 2006"}}.</small></p>
+            </div>
+
+            <p>If you weren't expecting this, you can ignore this email. Questions? Contact us at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>{{.OrganizationName}} is a 501(c)(3) nonprofit organization</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("user_invitation").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateUserInvitationText creates plain text email content for an
+// account invitation
+func (e *EmailService) generateUserInvitationText(data UserInvitationData) string {
+	greeting := "Welcome!"
+	if data.FirstName != "" {
+		greeting = fmt.Sprintf("Welcome, %s!", data.FirstName)
+	}
+	inviter := "We've"
+	if data.InvitedBy != "" {
+		inviter = data.InvitedBy + " has"
+	}
+
+	return fmt.Sprintf(`
+%s
+
+%s set up an account for you on the %s website. Choose a password to start using it:
+
+%s
+
+This link works until %s.
+
+If you weren't expecting this, you can ignore this email. Questions? Contact us at %s.
+`,
+		greeting,
+		inviter,
+		data.OrganizationName,
+		data.SetupURL,
+		data.ExpiresAt.Format("January 2, 2006"),
+		data.ContactEmail,
+	)
+}
+
+// CryptoReceiptData contains data for a cryptocurrency donation receipt.
+// Crypto is noncash property, so the receipt describes what was received
+// rather than stating a deductible dollar amount.
+type CryptoReceiptData struct {
+	DonorName        string
+	Currency         string
+	CryptoAmount     float64
+	ConvertedUSD     float64
+	TransactionHash  string
+	ReceivedDate     time.Time
+	OrganizationName string
+	OrganizationEIN  string
+	ContactEmail     string
+}
+
+// SendCryptoReceipt sends the acknowledgment for a cryptocurrency donation
+func (e *EmailService) SendCryptoReceipt(toEmail string, data CryptoReceiptData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+	subject := fmt.Sprintf("Receipt for your cryptocurrency gift to %s", data.OrganizationName)
+
+	htmlBody, err := e.generateCryptoReceiptHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generateCryptoReceiptText(data))
+}
+
+// generateCryptoReceiptHTML creates HTML email content for a crypto receipt
+func (e *EmailService) generateCryptoReceiptHTML(data CryptoReceiptData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Cryptocurrency Donation Receipt</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; }
+        .tax-info { background-color: #fff; padding: 15px; border-left: 4px solid #666; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Thank You, {{.DonorName}}!</h1>
+            <p>{{.OrganizationName}}</p>
+        </div>
+
+        <div class="content">
+            <p>We received your cryptocurrency donation. Thank you for supporting combat veterans.</p>
+
+            <div class="summary">
+                <h3>Donation Details</h3>
+                <p><strong>Property Received:</strong> {{printf "%.8f" .CryptoAmount}} {{.Currency}}</p>
+                <p><strong>Date Received:</strong> {{.ReceivedDate.Format "January 2, 2006"}}</p>
+                {{if .TransactionHash}}<p><strong>Transaction:</strong> {{.TransactionHash}}</p>{{end}}
+                <p><strong>Proceeds on Conversion:</strong> ${{printf "%.2f" .ConvertedUSD}} (for your reference)</p>
+            </div>
+
+            <div class="tax-info">
+                <h3>Tax Information</h3>
+                <p>{{.OrganizationName}} is a 501(c)(3) tax-exempt organization{{if .OrganizationEIN}} (EIN {{.OrganizationEIN}}){{end}}.
+                No goods or services were provided in exchange for this contribution.</p>
+                <p>Cryptocurrency is treated as noncash property. This receipt describes the property we received; it does not
+                state its fair market value, which you are responsible for determining. Gifts valued over $5,000 generally
+                require a qualified appraisal and IRS Form 8283. Please consult your tax advisor.</p>
+            </div>
+
+            <p>Questions? Contact us at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>Please keep this receipt for your tax records.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("crypto_receipt").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateCryptoReceiptText creates plain text email content for a crypto receipt
+func (e *EmailService) generateCryptoReceiptText(data CryptoReceiptData) string {
+	return fmt.Sprintf(`
+Thank You, %s!
+%s
+
+We received your cryptocurrency donation. Thank you for supporting combat veterans.
+
+DONATION DETAILS
+Property Received: %.8f %s
+Date Received: %s
+Transaction: %s
+Proceeds on Conversion: $%.2f (for your reference)
+
+TAX INFORMATION
+%s is a 501(c)(3) tax-exempt organization. EIN: %s
+No goods or services were provided in exchange for this contribution.
+
+Cryptocurrency is treated as noncash property. This receipt describes the property we received; it does not state its fair market value, which you are responsible for determining. Gifts valued over $5,000 generally require a qualified appraisal and IRS Form 8283. Please consult your tax advisor.
+
+Questions? Contact us at %s.
+`,
+		data.DonorName,
+		data.OrganizationName,
+		data.CryptoAmount,
+		data.Currency,
+		data.ReceivedDate.Format("January 2, 2006"),
+		data.TransactionHash,
+		data.ConvertedUSD,
+		data.OrganizationName,
+		data.OrganizationEIN,
+		data.ContactEmail,
+	)
+}
+
+// PayoutGiftAcknowledgementData contains data for thanking a donor whose gift
+// reached us through PayPal Giving Fund (including Venmo)
+type PayoutGiftAcknowledgementData struct {
+	DonorName        string
+	Amount           float64
+	Channel          string
+	DonationDate     time.Time
+	OrganizationName string
+	ContactEmail     string
+}
+
+// SendPayoutGiftAcknowledgement thanks a PayPal Giving Fund or Venmo donor.
+// PPGF is the donor of record and issues the tax receipt, so this email is an
+// acknowledgement only.
+func (e *EmailService) SendPayoutGiftAcknowledgement(toEmail string, data PayoutGiftAcknowledgementData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+	subject := fmt.Sprintf("Thank you for your gift to %s", data.OrganizationName)
+
+	htmlBody, err := e.generatePayoutGiftAcknowledgementHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generatePayoutGiftAcknowledgementText(data))
+}
+
+// generatePayoutGiftAcknowledgementHTML creates HTML email content for a PPGF gift acknowledgement
+func (e *EmailService) generatePayoutGiftAcknowledgementHTML(data PayoutGiftAcknowledgementData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Thank You</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; }
+        .tax-info { background-color: #fff; padding: 15px; border-left: 4px solid #666; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Thank You, {{.DonorName}}!</h1>
+            <p>{{.OrganizationName}}</p>
+        </div>
+
+        <div class="content">
+            <p>Your gift made through {{.Channel}} has reached us. Thank you for supporting combat veterans.</p>
+
+            <div class="summary">
+                <h3>Gift Details</h3>
+                <p><strong>Amount:</strong> ${{printf "%.2f" .Amount}}</p>
+                <p><strong>Date Given:</strong> {{.DonationDate.Format "January 2, 2006"}}</p>
+                <p><strong>Given Through:</strong> {{.Channel}}</p>
+            </div>
+
+            <div class="tax-info">
+                <h3>Tax Information</h3>
+                <p>Gifts made through PayPal Giving Fund are donations to PayPal Giving Fund, which granted them to
+                {{.OrganizationName}}. PayPal Giving Fund provides your tax receipt; please use it for your records.
+                This email is an acknowledgement and is not a tax receipt.</p>
+            </div>
+
+            <p>Questions? Contact us at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>Thank you for standing with our veterans.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("payout_gift_acknowledgement").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generatePayoutGiftAcknowledgementText creates plain text email content for a PPGF gift acknowledgement
+func (e *EmailService) generatePayoutGiftAcknowledgementText(data PayoutGiftAcknowledgementData) string {
+	return fmt.Sprintf(`
+Thank You, %s!
+%s
+
+Your gift made through %s has reached us. Thank you for supporting combat veterans.
+
+GIFT DETAILS
+Amount: $%.2f
+Date Given: %s
+Given Through: %s
+
+TAX INFORMATION
+Gifts made through PayPal Giving Fund are donations to PayPal Giving Fund, which granted them to %s. PayPal Giving Fund provides your tax receipt; please use it for your records. This email is an acknowledgement and is not a tax receipt.
+
+Questions? Contact us at %s.
+`,
+		data.DonorName,
+		data.OrganizationName,
+		data.Channel,
+		data.Amount,
+		data.DonationDate.Format("January 2, 2006"),
+		data.Channel,
+		data.OrganizationName,
+		data.ContactEmail,
+	)
+}
+
+// EventTicketData contains data for the email that delivers an event ticket
+type EventTicketData struct {
+	HolderName       string
+	EventTitle       string
+	StartsAt         time.Time
+	Location         string
+	TicketURL        string
+	OrganizationName string
+	ContactEmail     string
+}
+
+// SendEventTicket emails a ticket holder the link to their ticket and its QR
+// code
+func (e *EmailService) SendEventTicket(toEmail string, data EventTicketData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+	subject := fmt.Sprintf("Your ticket for %s", data.EventTitle)
+
+	htmlBody, err := e.generateEventTicketHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generateEventTicketText(data))
+}
+
+// generateEventTicketHTML creates HTML email content for an event ticket
+func (e *EmailService) generateEventTicketHTML(data EventTicketData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Your Ticket</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; text-align: center; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>You're registered, {{.HolderName}}!</h1>
+            <p>{{.OrganizationName}}</p>
+        </div>
+
+        <div class="content">
+            <div class="summary">
+                <p><strong>{{.EventTitle}}</strong></p>
+                <p>{{.StartsAt.Format "Monday, January 2, 2006 at 3:04 PM"}}</p>
+                {{if .Location}}<p>{{.Location}}</p>{{end}}
+            </div>
+
+            <p>Your ticket and its QR code are at <a href="{{.TicketURL}}">{{.TicketURL}}</a>. Show the QR code on your phone or a printout when you arrive.</p>
+            <p>Questions? Contact us at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>{{.OrganizationName}} is a 501(c)(3) nonprofit organization</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("event_ticket").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateEventTicketText creates plain text email content for an event ticket
+func (e *EmailService) generateEventTicketText(data EventTicketData) string {
+	location := ""
+	if data.Location != "" {
+		location = data.Location + "\n"
+	}
+
+	return fmt.Sprintf(`
+You're registered, %s!
+
+%s
+%s
+%s
+Your ticket and its QR code are at %s
+Show the QR code on your phone or a printout when you arrive.
+
+Questions? Contact us at %s.
+`,
+		data.HolderName,
+		data.EventTitle,
+		data.StartsAt.Format("Monday, January 2, 2006 at 3:04 PM"),
+		location,
+		data.TicketURL,
+		data.ContactEmail,
+	)
+}
+
+// EventReminderData contains data for a reminder emailed to a ticket holder
+// ahead of an event
+type EventReminderData struct {
+	HolderName       string
+	EventTitle       string
+	StartsAt         time.Time
+	Location         string
+	Message          string // the event's own reminder message, if any
+	TicketURL        string
+	CalendarURL      string
+	OrganizationName string
+	ContactEmail     string
+}
+
+// SendEventReminder emails a ticket holder a reminder that their event is
+// coming up, with links to their ticket and to add the event to a calendar
+func (e *EmailService) SendEventReminder(toEmail string, data EventReminderData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+	subject := fmt.Sprintf("Reminder: %s is coming up", data.EventTitle)
+
+	htmlBody, err := e.generateEventReminderHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generateEventReminderText(data))
+}
+
+// generateEventReminderHTML creates HTML email content for an event reminder
+func (e *EmailService) generateEventReminderHTML(data EventReminderData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Event Reminder</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; text-align: center; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>See you soon, {{.HolderName}}!</h1>
+            <p>{{.OrganizationName}}</p>
+        </div>
+
+        <div class="content">
+            {{if .Message}}<p style="white-space: pre-line;">{{.Message}}</p>{{end}}
+
+            <div class="summary">
+                <p><strong>{{.EventTitle}}</strong></p>
+                <p>{{.StartsAt.Format "Monday, January 2, 2006 at 3:04 PM"}}</p>
+                {{if .Location}}<p>{{.Location}}</p>{{end}}
+            </div>
+
+            <p>Your ticket and its QR code are at <a href="{{.TicketURL}}">{{.TicketURL}}</a>. Show the QR code when you arrive to check in.</p>
+            {{if .CalendarURL}}<p><a href="{{.CalendarURL}}">Add this event to your calendar</a></p>{{end}}
+            <p>Can't make it? Let us know at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a> so we can offer your seat to someone else.</p>
+        </div>
+
+        <div class="footer">
+            <p>{{.OrganizationName}} is a 501(c)(3) nonprofit organization</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("event_reminder").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateEventReminderText creates plain text email content for an event
+// reminder
+func (e *EmailService) generateEventReminderText(data EventReminderData) string {
+	message := ""
+	if data.Message != "" {
+		message = data.Message + "\n\n"
+	}
+	location := ""
+	if data.Location != "" {
+		location = data.Location + "\n"
+	}
+	calendar := ""
+	if data.CalendarURL != "" {
+		calendar = "Add it to your calendar: " + data.CalendarURL + "\n"
+	}
+
+	return fmt.Sprintf(`
+See you soon, %s!
+
+%s%s
+%s
+%s
+Your ticket and its QR code are at %s
+Show the QR code when you arrive to check in.
+%s
+Can't make it? Let us know at %s so we can offer your seat to someone else.
+`,
+		data.HolderName,
+		message,
+		data.EventTitle,
+		data.StartsAt.Format("Monday, January 2, 2006 at 3:04 PM"),
+		location,
+		data.TicketURL,
+		calendar,
+		data.ContactEmail,
+	)
+}
+
+// AuctionWinnerData contains data for the email telling a winning bidder or
+// raffle ticket holder they won
+type AuctionWinnerData struct {
+	WinnerName       string
+	ItemTitle        string
+	Raffle           bool
+	Amount           float64
+	FairMarketValue  float64
+	PaymentURL       string // auctions only; raffle tickets are paid up front
+	OrganizationName string
+	ContactEmail     string
+}
+
+// SendAuctionWinner emails the winner of an auction item or raffle
+func (e *EmailService) SendAuctionWinner(toEmail string, data AuctionWinnerData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+	subject := fmt.Sprintf("You won %s!", data.ItemTitle)
+
+	htmlBody, err := e.generateAuctionWinnerHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generateAuctionWinnerText(data))
+}
+
+// generateAuctionWinnerHTML creates HTML email content for an auction or
+// raffle winner
+func (e *EmailService) generateAuctionWinnerHTML(data AuctionWinnerData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>You Won!</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; text-align: center; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Congratulations, {{.WinnerName}}!</h1>
+            <p>{{.OrganizationName}}</p>
+        </div>
+
+        <div class="content">
+            <div class="summary">
+                <p>You won</p>
+                <p><strong>{{.ItemTitle}}</strong></p>
+                {{if not .Raffle}}<p>Winning bid: ${{printf "%.2f" .Amount}}</p>{{end}}
+            </div>
+
+            {{if .Raffle}}
+            <p>Your raffle ticket was drawn. We'll be in touch to arrange getting your prize to you.</p>
+            {{else}}
+            <p>Please complete your payment at <a href="{{.PaymentURL}}">{{.PaymentURL}}</a>. Your receipt will show the item's fair market value of ${{printf "%.2f" .FairMarketValue}}, which is not tax deductible.</p>
+            {{end}}
+            <p>Questions? Contact us at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>{{.OrganizationName}} is a 501(c)(3) nonprofit organization</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("auction_winner").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateAuctionWinnerText creates plain text email content for an auction
+// or raffle winner
+func (e *EmailService) generateAuctionWinnerText(data AuctionWinnerData) string {
+	next := "Your raffle ticket was drawn. We'll be in touch to arrange getting your prize to you."
+	if !data.Raffle {
+		next = fmt.Sprintf("Winning bid: $%.2f\n\nPlease complete your payment at %s\nYour receipt will show the item's fair market value of $%.2f, which is not tax deductible.",
+			data.Amount, data.PaymentURL, data.FairMarketValue)
+	}
+
+	return fmt.Sprintf(`
+Congratulations, %s!
+
+You won %s.
+
+%s
+
+Questions? Contact us at %s.
+`,
+		data.WinnerName,
+		data.ItemTitle,
+		next,
+		data.ContactEmail,
+	)
+}
+
+// InKindAcknowledgementData contains data for the letter acknowledging a
+// gift of property
+type InKindAcknowledgementData struct {
+	DonorName        string
+	Description      string
+	Quantity         int
+	Category         string
+	ReceivedDate     time.Time
+	OrganizationName string
+	OrganizationEIN  string
+	ContactEmail     string
+}
+
+// SendInKindAcknowledgement emails the acknowledgment letter for an in-kind
+// gift
+func (e *EmailService) SendInKindAcknowledgement(toEmail string, data InKindAcknowledgementData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+	subject := fmt.Sprintf("Acknowledgment of your gift to %s", data.OrganizationName)
+
+	htmlBody, err := e.generateInKindAcknowledgementHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generateInKindAcknowledgementText(data))
+}
+
+// GenerateInKindAcknowledgementHTML renders the acknowledgment letter so
+// staff can print it for donors without an email address
+func (e *EmailService) GenerateInKindAcknowledgementHTML(data InKindAcknowledgementData) (string, error) {
+	if data.ContactEmail == "" {
+		data.ContactEmail = e.ContactEmail
+	}
+	return e.generateInKindAcknowledgementHTML(data)
+}
+
+// generateInKindAcknowledgementHTML creates HTML content for an in-kind gift letter
+func (e *EmailService) generateInKindAcknowledgementHTML(data InKindAcknowledgementData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Gift Acknowledgment</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; }
+        .tax-info { background-color: #fff; padding: 15px; border-left: 4px solid #666; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{.OrganizationName}}</h1>
+            <p>Acknowledgment of Noncash Contribution</p>
+        </div>
+
+        <div class="content">
+            <p>Dear {{.DonorName}},</p>
+            <p>Thank you for your generous gift. Donated property like yours goes straight to work on our housing
+            projects and training programs for combat veterans.</p>
+
+            <div class="summary">
+                <h3>Property Received</h3>
+                <p><strong>Description:</strong> {{if gt .Quantity 1}}{{.Quantity}} × {{end}}{{.Description}}</p>
+                <p><strong>Type:</strong> {{.Category}}</p>
+                <p><strong>Date Received:</strong> {{.ReceivedDate.Format "January 2, 2006"}}</p>
+            </div>
+
+            <div class="tax-info">
+                <h3>Tax Information</h3>
+                <p>{{.OrganizationName}} is a 501(c)(3) tax-exempt organization{{if .OrganizationEIN}} (EIN {{.OrganizationEIN}}){{end}}.
+                No goods or services were provided in exchange for this contribution.</p>
+                <p>This letter describes the property we received; it does not state its value, which you are
+                responsible for determining. Noncash gifts over $500 are reported on IRS Form 8283, and items valued
+                over $5,000 generally require a qualified appraisal. Please consult your tax advisor.</p>
+            </div>
+
+            <p>Questions? Contact us at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>Please keep this letter for your tax records.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("in_kind_acknowledgement").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateInKindAcknowledgementText creates plain text content for an in-kind gift letter
+func (e *EmailService) generateInKindAcknowledgementText(data InKindAcknowledgementData) string {
+	description := data.Description
+	if data.Quantity > 1 {
+		description = fmt.Sprintf("%d × %s", data.Quantity, data.Description)
+	}
+	return fmt.Sprintf(`
+%s
+Acknowledgment of Noncash Contribution
+
+Dear %s,
+
+Thank you for your generous gift. Donated property like yours goes straight to work on our housing projects and training programs for combat veterans.
+
+PROPERTY RECEIVED
+Description: %s
+Type: %s
+Date Received: %s
+
+TAX INFORMATION
+%s is a 501(c)(3) tax-exempt organization. EIN: %s
+No goods or services were provided in exchange for this contribution.
+
+This letter describes the property we received; it does not state its value, which you are responsible for determining. Noncash gifts over $500 are reported on IRS Form 8283, and items valued over $5,000 generally require a qualified appraisal. Please consult your tax advisor.
+
+Questions? Contact us at %s.
+`,
+		data.OrganizationName,
+		data.DonorName,
+		description,
+		data.Category,
+		data.ReceivedDate.Format("January 2, 2006"),
+		data.OrganizationName,
+		data.OrganizationEIN,
+		data.ContactEmail,
+	)
+}
+
+// VehicleAcknowledgementData contains data for the Form 1098-C style
+// acknowledgment of a donated vehicle
+type VehicleAcknowledgementData struct {
+	DonorName        string
+	DonorAddress     string
+	ContributionDate time.Time
+	Year             int
+	Make             string
+	Model            string
+	VIN              string
+	Mileage          int
+	Sold             bool
+	ArmsLengthSale   bool
+	SaleDate         time.Time
+	GrossProceeds    float64
+	UseDescription   string
+	OrganizationName string
+	OrganizationEIN  string
+	ContactEmail     string
+}
+
+// SendVehicleAcknowledgement emails the acknowledgment for a donated vehicle
+// once it has been sold or put to use
+func (e *EmailService) SendVehicleAcknowledgement(toEmail string, data VehicleAcknowledgementData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+	subject := fmt.Sprintf("Tax acknowledgment for your %d %s %s donation", data.Year, data.Make, data.Model)
+
+	htmlBody, err := e.generateVehicleAcknowledgementHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generateVehicleAcknowledgementText(data))
+}
+
+// GenerateVehicleAcknowledgementHTML renders a vehicle acknowledgment so staff
+// can print or mail a copy
+func (e *EmailService) GenerateVehicleAcknowledgementHTML(data VehicleAcknowledgementData) (string, error) {
+	if data.ContactEmail == "" {
+		data.ContactEmail = e.ContactEmail
+	}
+	return e.generateVehicleAcknowledgementHTML(data)
+}
+
+// generateVehicleAcknowledgementHTML creates HTML content for a vehicle acknowledgment
+func (e *EmailService) generateVehicleAcknowledgementHTML(data VehicleAcknowledgementData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Vehicle Donation Acknowledgment</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; }
+        .tax-info { background-color: #fff; padding: 15px; border-left: 4px solid #666; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{.OrganizationName}}</h1>
+            <p>Contemporaneous Written Acknowledgment of Vehicle Donation (Form 1098-C format)</p>
+        </div>
+
+        <div class="content">
+            <p>Dear {{.DonorName}},</p>
+            <p>Thank you for donating your vehicle to support our housing projects and training programs for
+            combat veterans.</p>
+
+            <div class="summary">
+                <h3>Donor</h3>
+                <p>{{.DonorName}}<br>{{.DonorAddress}}</p>
+                <h3>Vehicle</h3>
+                <p><strong>Date of contribution:</strong> {{.ContributionDate.Format "January 2, 2006"}}</p>
+                <p><strong>Year, make, model:</strong> {{.Year}} {{.Make}} {{.Model}}</p>
+                <p><strong>Vehicle identification number:</strong> {{.VIN}}</p>
+                <p><strong>Odometer mileage:</strong> {{.Mileage}}</p>
+            </div>
+
+            <div class="summary">
+                {{if .Sold}}
+                <h3>Sale of Vehicle</h3>
+                <p><strong>Date of sale:</strong> {{.SaleDate.Format "January 2, 2006"}}</p>
+                <p><strong>Gross proceeds from sale:</strong> ${{printf "%.2f" .GrossProceeds}}</p>
+                <p>{{if .ArmsLengthSale}}The vehicle was sold in an arm's length transaction to an unrelated party.{{else}}The vehicle was not sold in an arm's length transaction to an unrelated party.{{end}}</p>
+                {{else}}
+                <h3>Use of Vehicle</h3>
+                <p>{{.OrganizationName}} will not sell the vehicle before it has been put to significant intervening use
+                in our charitable programs. Intended use and duration: {{.UseDescription}}</p>
+                {{end}}
+            </div>
+
+            <div class="tax-info">
+                <h3>Tax Information</h3>
+                <p>{{.OrganizationName}} is a 501(c)(3) tax-exempt organization{{if .OrganizationEIN}} (EIN {{.OrganizationEIN}}){{end}}.
+                No goods or services were provided in exchange for this vehicle.</p>
+                {{if .Sold}}
+                <p>Because the vehicle was sold without significant intervening use or material improvement, your
+                deduction generally cannot exceed the gross proceeds shown above.</p>
+                {{else}}
+                <p>Because the vehicle is being put to significant intervening use, your deduction is generally its
+                fair market value on the date of contribution.</p>
+                {{end}}
+                <p>Attach a copy of this acknowledgment to your tax return if you claim a deduction of more than $500 for
+                this vehicle. Please consult your tax advisor.</p>
+            </div>
+
+            <p>Questions? Contact us at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>Please keep this acknowledgment for your tax records.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("vehicle_acknowledgement").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateVehicleAcknowledgementText creates plain text content for a vehicle acknowledgment
+func (e *EmailService) generateVehicleAcknowledgementText(data VehicleAcknowledgementData) string {
+	var disposition, deduction string
+	if data.Sold {
+		arms := "was"
+		if !data.ArmsLengthSale {
+			arms = "was not"
+		}
+		disposition = fmt.Sprintf("SALE OF VEHICLE\nDate of sale: %s\nGross proceeds from sale: $%.2f\nThe vehicle %s sold in an arm's length transaction to an unrelated party.",
+			data.SaleDate.Format("January 2, 2006"), data.GrossProceeds, arms)
+		deduction = "Because the vehicle was sold without significant intervening use or material improvement, your deduction generally cannot exceed the gross proceeds shown above."
+	} else {
+		disposition = fmt.Sprintf("USE OF VEHICLE\n%s will not sell the vehicle before it has been put to significant intervening use in our charitable programs. Intended use and duration: %s",
+			data.OrganizationName, data.UseDescription)
+		deduction = "Because the vehicle is being put to significant intervening use, your deduction is generally its fair market value on the date of contribution."
+	}
+
+	return fmt.Sprintf(`
+%s
+Contemporaneous Written Acknowledgment of Vehicle Donation (Form 1098-C format)
+
+Dear %s,
+
+Thank you for donating your vehicle to support our housing projects and training programs for combat veterans.
+
+DONOR
+%s
+%s
+
+VEHICLE
+Date of contribution: %s
+Year, make, model: %d %s %s
+Vehicle identification number: %s
+Odometer mileage: %d
+
+%s
+
+TAX INFORMATION
+%s is a 501(c)(3) tax-exempt organization. EIN: %s
+No goods or services were provided in exchange for this vehicle.
+
+%s
+
+Attach a copy of this acknowledgment to your tax return if you claim a deduction of more than $500 for this vehicle. Please consult your tax advisor.
+
+Questions? Contact us at %s.
+`,
+		data.OrganizationName,
+		data.DonorName,
+		data.DonorName,
+		data.DonorAddress,
+		data.ContributionDate.Format("January 2, 2006"),
+		data.Year, data.Make, data.Model,
+		data.VIN,
+		data.Mileage,
+		disposition,
+		data.OrganizationName,
+		data.OrganizationEIN,
+		deduction,
+		data.ContactEmail,
+	)
+}
+
+// YearEndReceiptLine is one gift on a year-end receipt
+type YearEndReceiptLine struct {
+	Date          time.Time
+	Description   string
+	Amount        float64
+	GoodsValue    float64 // fair market value of goods or services received for the gift
+	GoodsProvided string
+}
+
+// Deductible is the part of the gift the donor can deduct
+func (l YearEndReceiptLine) Deductible() float64 {
+	if l.GoodsValue >= l.Amount {
+		return 0
+	}
+	return l.Amount - l.GoodsValue
+}
+
+// YearEndReceiptData contains data for a donor's consolidated receipt of
+// every gift they made in a calendar year
+type YearEndReceiptData struct {
+	Year                int
+	DonorName           string
+	DonorEmail          string
+	DonorAddressLine1   string
+	DonorAddressLine2   string
+	DonorCity           string
+	DonorState          string
+	DonorZip            string
+	Lines               []YearEndReceiptLine
+	OrganizationName    string
+	OrganizationEIN     string
+	OrganizationAddress string
+	ContactEmail        string
+}
+
+// Total is the sum of the year's gifts
+func (d YearEndReceiptData) Total() float64 {
+	total := 0.0
+	for _, l := range d.Lines {
+		total += l.Amount
+	}
+	return total
+}
+
+// TotalGoodsValue is the value of goods or services received for the year's gifts
+func (d YearEndReceiptData) TotalGoodsValue() float64 {
+	total := 0.0
+	for _, l := range d.Lines {
+		total += l.Amount - l.Deductible()
+	}
+	return total
+}
+
+// TotalDeductible is the tax-deductible part of the year's gifts
+func (d YearEndReceiptData) TotalDeductible() float64 {
+	total := 0.0
+	for _, l := range d.Lines {
+		total += l.Deductible()
+	}
+	return total
+}
+
+// GoodsReceived reports whether the donor received anything for any gift
+func (d YearEndReceiptData) GoodsReceived() bool {
+	return d.TotalGoodsValue() > 0
+}
+
+// SendYearEndReceipt emails a donor their consolidated receipt for the year
+func (e *EmailService) SendYearEndReceipt(toEmail string, data YearEndReceiptData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	data.ContactEmail = e.ContactEmail
+	subject := fmt.Sprintf("Your %d tax receipt from %s", data.Year, data.OrganizationName)
+
+	htmlBody, err := e.generateYearEndReceiptHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generateYearEndReceiptText(data))
+}
+
+// GenerateYearEndReceiptHTML renders a year-end receipt for the donor to
+// download from their account
+func (e *EmailService) GenerateYearEndReceiptHTML(data YearEndReceiptData) (string, error) {
+	if data.ContactEmail == "" {
+		data.ContactEmail = e.ContactEmail
+	}
+	return e.generateYearEndReceiptHTML(data)
+}
+
+// generateYearEndReceiptHTML creates HTML content for a year-end receipt
+func (e *EmailService) generateYearEndReceiptHTML(data YearEndReceiptData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Year}} Year-End Tax Receipt</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; }
+        .summary table { width: 100%; border-collapse: collapse; }
+        .summary th, .summary td { text-align: left; padding: 4px 0; border-bottom: 1px solid #eee; }
+        .summary .amount { text-align: right; }
+        .tax-info { background-color: #fff; padding: 15px; border-left: 4px solid #666; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{.OrganizationName}}</h1>
+            <p>{{.Year}} Year-End Tax Receipt</p>
+            {{if .OrganizationAddress}}<p>{{.OrganizationAddress}}</p>{{end}}
+        </div>
+
+        <div class="content">
+            <p>Dear {{.DonorName}},</p>
+            <p>Thank you for your support of our housing projects and training programs for combat veterans
+            in {{.Year}}. This receipt lists every gift you made to us during the year.</p>
+
+            <div class="summary">
+                <h3>Donor</h3>
+                <p>{{.DonorName}}{{if .DonorAddressLine1}}<br>{{.DonorAddressLine1}}{{end}}{{if .DonorAddressLine2}}<br>{{.DonorAddressLine2}}{{end}}{{if .DonorCity}}<br>{{.DonorCity}}, {{.DonorState}} {{.DonorZip}}{{end}}</p>
+            </div>
+
+            <div class="summary">
+                <h3>Gifts</h3>
+                <table>
+                    <thead>
+                        <tr><th>Date</th><th>Gift</th><th class="amount">Amount</th></tr>
+                    </thead>
+                    <tbody>
+                        {{range .Lines}}
+                        <tr>
+                            <td>{{.Date.Format "Jan 2, 2006"}}</td>
+                            <td>{{.Description}}{{if .GoodsValue}}<br><small>Received: {{.GoodsProvided}} (value ${{printf "%.2f" .GoodsValue}})</small>{{end}}</td>
+                            <td class="amount">${{printf "%.2f" .Amount}}</td>
+                        </tr>
+                        {{end}}
+                    </tbody>
+                </table>
+                <p><strong>Total contributions:</strong> ${{printf "%.2f" .Total}}</p>
+                {{if .GoodsReceived}}
+                <p><strong>Value of goods or services received:</strong> ${{printf "%.2f" .TotalGoodsValue}}</p>
+                {{end}}
+                <p><strong>Tax-deductible amount:</strong> ${{printf "%.2f" .TotalDeductible}}</p>
+            </div>
+
+            <div class="tax-info">
+                <h3>Tax Information</h3>
+                <p>{{.OrganizationName}} is a 501(c)(3) tax-exempt organization{{if .OrganizationEIN}} (EIN {{.OrganizationEIN}}){{end}}.</p>
+                {{if .GoodsReceived}}
+                <p>Goods or services were provided in exchange for some of these contributions, as listed above. Only the
+                amount exceeding their fair market value is tax deductible.</p>
+                {{else}}
+                <p>No goods or services were provided in exchange for these contributions.</p>
+                {{end}}
+                <p>Please consult your tax advisor regarding the deductibility of your contributions.</p>
+            </div>
+
+            <p>Questions? Contact us at <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>Please keep this receipt for your tax records.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("year_end_receipt").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateYearEndReceiptText creates plain text content for a year-end receipt
+func (e *EmailService) generateYearEndReceiptText(data YearEndReceiptData) string {
+	var gifts strings.Builder
+	for _, l := range data.Lines {
+		fmt.Fprintf(&gifts, "%s  %s  $%.2f\n", l.Date.Format("Jan 2, 2006"), l.Description, l.Amount)
+		if l.GoodsValue > 0 {
+			fmt.Fprintf(&gifts, "    Received: %s (value $%.2f)\n", l.GoodsProvided, l.GoodsValue)
+		}
+	}
+
+	goods := "No goods or services were provided in exchange for these contributions."
+	if data.GoodsReceived() {
+		goods = fmt.Sprintf("Value of goods or services received: $%.2f\nGoods or services were provided in exchange for some of these contributions, as listed above. Only the amount exceeding their fair market value is tax deductible.",
+			data.TotalGoodsValue())
+	}
+
+	return fmt.Sprintf(`
+%s
+%d Year-End Tax Receipt
+
+Dear %s,
+
+Thank you for your support of our housing projects and training programs for combat veterans in %d. This receipt lists every gift you made to us during the year.
+
+GIFTS
+%s
+Total contributions: $%.2f
+Tax-deductible amount: $%.2f
+
+TAX INFORMATION
+%s is a 501(c)(3) tax-exempt organization. EIN: %s
+%s
+
+Please consult your tax advisor regarding the deductibility of your contributions.
+
+Questions? Contact us at %s.
+
+Please keep this receipt for your tax records.
+`,
+		data.OrganizationName,
+		data.Year,
+		data.DonorName,
+		data.Year,
+		gifts.String(),
+		data.Total(),
+		data.TotalDeductible(),
+		data.OrganizationName,
+		data.OrganizationEIN,
+		goods,
+		data.ContactEmail,
+	)
+}
+
+// StaffDigestData contains data for the weekly staff digest email
+type StaffDigestData struct {
+	WeekStart        time.Time
+	WeekEnd          time.Time
+	GiftCount        int
+	GiftTotal        float64
+	NewRecurring     int
+	Cancelled        int
+	ActiveMonthly    int
+	MonthlyRevenue   float64
+	ChurnPercent     float64
+	Forecast         []ForecastMonth
+	ForecastTotal    float64
+	OrganizationName string
+}
+
+// SendStaffDigest emails staff the weekly summary of giving and the
+// recurring revenue forecast
+func (e *EmailService) SendStaffDigest(toEmail string, data StaffDigestData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	subject := fmt.Sprintf("Weekly digest: %d gifts, $%.2f (%s - %s)", data.GiftCount, data.GiftTotal,
+		data.WeekStart.Format("Jan 2"), data.WeekEnd.Format("Jan 2"))
+
+	htmlBody, err := e.generateStaffDigestHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, subject, htmlBody, e.generateStaffDigestText(data))
+}
+
+// generateStaffDigestHTML creates HTML email content for the weekly staff digest
+func (e *EmailService) generateStaffDigestHTML(data StaffDigestData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Weekly Digest</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .summary { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #eee; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Weekly Digest</h1>
+            <p>{{.OrganizationName}} · {{.WeekStart.Format "January 2"}} - {{.WeekEnd.Format "January 2, 2006"}}</p>
+        </div>
+
+        <div class="content">
+            <div class="summary">
+                <h3>This Week</h3>
+                <p><strong>Gifts:</strong> {{.GiftCount}} totaling ${{printf "%.2f" .GiftTotal}}</p>
+                <p><strong>New recurring gifts:</strong> {{.NewRecurring}}</p>
+                <p><strong>Recurring gifts cancelled:</strong> {{.Cancelled}}</p>
+            </div>
+
+            <div class="summary">
+                <h3>Recurring Revenue Forecast</h3>
+                <p>{{.ActiveMonthly}} active monthly donors giving ${{printf "%.2f" .MonthlyRevenue}} a month, with {{printf "%.1f" .ChurnPercent}}% monthly churn.
+                We expect ${{printf "%.2f" .ForecastTotal}} in recurring revenue over the next 12 months.</p>
+                <table>
+                    <tr><th>Month</th><th>Expected</th></tr>
+                    {{range .Forecast}}<tr><td>{{.Month.Format "January 2006"}}</td><td>${{printf "%.2f" .Expected}}</td></tr>
+                    {{end}}
+                </table>
+            </div>
+        </div>
+
+        <div class="footer">
+            <p>Sent weekly to AVR staff.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("staff_digest").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateStaffDigestText creates plain text email content for the weekly staff digest
+func (e *EmailService) generateStaffDigestText(data StaffDigestData) string {
+	var forecast strings.Builder
+	for _, month := range data.Forecast {
+		fmt.Fprintf(&forecast, "%s: $%.2f\n", month.Month.Format("January 2006"), month.Expected())
+	}
+
+	return fmt.Sprintf(`
+Weekly Digest
+%s - %s to %s
+
+THIS WEEK
+Gifts: %d totaling $%.2f
+New recurring gifts: %d
+Recurring gifts cancelled: %d
+
+RECURRING REVENUE FORECAST
+%d active monthly donors giving $%.2f a month, with %.1f%% monthly churn.
+We expect $%.2f in recurring revenue over the next 12 months.
+
+%s`,
+		data.OrganizationName,
+		data.WeekStart.Format("January 2"),
+		data.WeekEnd.Format("January 2, 2006"),
+		data.GiftCount, data.GiftTotal,
+		data.NewRecurring,
+		data.Cancelled,
+		data.ActiveMonthly, data.MonthlyRevenue, data.ChurnPercent,
+		data.ForecastTotal,
+		forecast.String(),
+	)
+}
+
+// ContactReminderData contains data for the email sent to staff about a
+// contact message nobody has replied to
+type ContactReminderData struct {
+	Name       string
+	Email      string
+	Subject    string
+	TopicLabel string
+	ReceivedAt time.Time
+	Waiting    string // how long the message has gone unanswered, e.g. "2 days"
+	Escalation bool   // sent to the escalation contact after the reminder went unanswered
+	InboxURL   string
+}
+
+// contactReminderSubject is the subject line for a contact message reminder
+func contactReminderSubject(data ContactReminderData) string {
+	if data.Escalation {
+		return fmt.Sprintf("Escalated: contact message from %s unanswered for %s", data.Name, data.Waiting)
+	}
+	return fmt.Sprintf("Reminder: contact message from %s awaiting reply", data.Name)
+}
+
+// SendContactReminder emails recipients about a contact message that has
+// gone unanswered past the reply SLA
+func (e *EmailService) SendContactReminder(recipients []string, data ContactReminderData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("no recipients for contact reminder")
+	}
+
+	htmlBody, err := e.generateContactReminderHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmailWithBCC(recipients[0], contactReminderSubject(data), htmlBody, e.generateContactReminderText(data), recipients[1:])
+}
+
+// generateContactReminderHTML creates HTML email content for a contact
+// message reminder
+func (e *EmailService) generateContactReminderHTML(data ContactReminderData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Contact Message Reminder</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .form-details { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{if .Escalation}}Escalated Contact Message{{else}}Contact Message Awaiting Reply{{end}}</h1>
+        </div>
+
+        <div class="content">
+            {{if .Escalation}}
+            <p>This message has gone unanswered for {{.Waiting}}, and the reminder sent to its recipients hasn't been acted on. Please make sure someone replies.</p>
+            {{else}}
+            <p>This message has been waiting {{.Waiting}} for a reply.</p>
+            {{end}}
+            <div class="form-details">
+                <p><strong>From:</strong> {{.Name}} &lt;{{.Email}}&gt;</p>
+                <p><strong>Subject:</strong> {{.Subject}}</p>
+                <p><strong>Topic:</strong> {{.TopicLabel}}</p>
+                <p><strong>Received:</strong> {{.ReceivedAt.Format "January 2, 2006 at 3:04 PM"}}</p>
+            </div>
+            <p>Reply to <a href="mailto:{{.Email}}">{{.Email}}</a>, then mark the message replied in the <a href="{{.InboxURL}}">admin inbox</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>Sent to AVR staff when a contact message passes the reply deadline.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("contact_reminder").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateContactReminderText creates plain text email content for a
+// contact message reminder
+func (e *EmailService) generateContactReminderText(data ContactReminderData) string {
+	intro := fmt.Sprintf("This message has been waiting %s for a reply.", data.Waiting)
+	if data.Escalation {
+		intro = fmt.Sprintf("This message has gone unanswered for %s, and the reminder sent to its recipients hasn't been acted on. Please make sure someone replies.", data.Waiting)
+	}
+
+	return fmt.Sprintf(`
+%s
+
+From: %s <%s>
+Subject: %s
+Topic: %s
+Received: %s
+
+Reply to %s, then mark the message replied in the admin inbox:
+%s
+`,
+		intro,
+		data.Name, data.Email,
+		data.Subject,
+		data.TopicLabel,
+		data.ReceivedAt.Format("January 2, 2006 at 3:04 PM"),
+		data.Email,
+		data.InboxURL,
+	)
+}
+
+// PressInquiryData contains data for a press inquiry alert
+type PressInquiryData struct {
+	Name        string
+	Email       string
+	Outlet      string
+	Deadline    *time.Time
+	Subject     string
+	Message     string
+	SubmittedAt time.Time
+	InboxURL    string
+}
+
+// PressAlertRecipients is who is alerted to press inquiries: the
+// comma-separated PRESS_ALERT_EMAIL, usually leadership, or the press
+// topic's contact recipients when that's unset
+func (e *EmailService) PressAlertRecipients() []string {
+	var recipients []string
+	for _, addr := range strings.Split(os.Getenv("PRESS_ALERT_EMAIL"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			recipients = append(recipients, addr)
+		}
+	}
+	if len(recipients) == 0 {
+		recipients = e.ContactRecipients("press")
+	}
+	return recipients
+}
+
+// pressInquirySubject is the subject line for a press inquiry alert, which
+// leads with the journalist's deadline when they gave one
+func pressInquirySubject(data PressInquiryData) string {
+	from := data.Name
+	if data.Outlet != "" {
+		from = fmt.Sprintf("%s (%s)", data.Name, data.Outlet)
+	}
+	if data.Deadline != nil {
+		return fmt.Sprintf("Press inquiry, deadline %s: %s from %s", data.Deadline.Format("Jan 2"), data.Subject, from)
+	}
+	return fmt.Sprintf("Press inquiry: %s from %s", data.Subject, from)
+}
+
+// SendPressInquiryAlert emails leadership a press inquiry as soon as it
+// arrives, rather than routing it through the contact inbox
+func (e *EmailService) SendPressInquiryAlert(data PressInquiryData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	recipients := e.PressAlertRecipients()
+	htmlBody, err := e.generatePressInquiryHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmailWithBCC(recipients[0], pressInquirySubject(data), htmlBody, e.generatePressInquiryText(data), recipients[1:])
+}
+
+// generatePressInquiryHTML creates HTML email content for a press inquiry alert
+func (e *EmailService) generatePressInquiryHTML(data PressInquiryData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Press Inquiry</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .form-details { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 20px 0; }
+        .message-content { background-color: #fff; padding: 15px; border-left: 4px solid #666; margin: 20px 0; white-space: pre-wrap; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Press Inquiry</h1>
+            {{if .Deadline}}<p><strong>Deadline: {{.Deadline.Format "Monday, January 2, 2006"}}</strong></p>{{end}}
+        </div>
+
+        <div class="content">
+            <div class="form-details">
+                <p><strong>From:</strong> {{.Name}} &lt;{{.Email}}&gt;</p>
+                {{if .Outlet}}<p><strong>Outlet:</strong> {{.Outlet}}</p>{{end}}
+                <p><strong>Subject:</strong> {{.Subject}}</p>
+                <p><strong>Received:</strong> {{.SubmittedAt.Format "January 2, 2006 at 3:04 PM"}}</p>
+            </div>
+            <div class="message-content">{{.Message}}</div>
+            <p>Reply to <a href="mailto:{{.Email}}">{{.Email}}</a>, then mark the message replied in the <a href="{{.InboxURL}}">admin inbox</a>.</p>
+        </div>
+
+        <div class="footer">
+            <p>Sent to AVR leadership as soon as a press inquiry arrives through the press page.</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("press_inquiry").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generatePressInquiryText creates plain text email content for a press
+// inquiry alert
+func (e *EmailService) generatePressInquiryText(data PressInquiryData) string {
+	deadline := "none given"
+	if data.Deadline != nil {
+		deadline = data.Deadline.Format("Monday, January 2, 2006")
+	}
+	outlet := data.Outlet
+	if outlet == "" {
+		outlet = "not given"
+	}
+
+	return fmt.Sprintf(`
+PRESS INQUIRY
+
+From: %s <%s>
+Outlet: %s
+Deadline: %s
+Subject: %s
+Received: %s
+
+%s
+
+Reply to %s, then mark the message replied in the admin inbox:
+%s
+`,
+		data.Name, data.Email,
+		outlet,
+		deadline,
+		data.Subject,
+		data.SubmittedAt.Format("January 2, 2006 at 3:04 PM"),
+		data.Message,
+		data.Email,
+		data.InboxURL,
+	)
+}
+
+// JobDigestPosting is one job posting in the job board digest
+type JobDigestPosting struct {
+	Title     string
+	Employer  string
+	Location  string
+	URL       string
+	ExpiresAt time.Time
+}
+
+// JobDigestData contains data for the job board digest sent to veterans who
+// opted in to job alerts
+type JobDigestData struct {
+	Name             string
+	Postings         []JobDigestPosting
+	BoardURL         string
+	AccountURL       string
+	OrganizationName string
+}
+
+// jobDigestSubject is the subject line for the job board digest
+func jobDigestSubject(data JobDigestData) string {
+	if len(data.Postings) == 1 {
+		return fmt.Sprintf("New on the job board: %s at %s", data.Postings[0].Title, data.Postings[0].Employer)
+	}
+	return fmt.Sprintf("%d new jobs on the %s job board", len(data.Postings), data.OrganizationName)
+}
+
+// SendJobDigest emails a veteran the postings added to the job board since
+// the last digest
+func (e *EmailService) SendJobDigest(toEmail string, data JobDigestData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	htmlBody, err := e.generateJobDigestHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, jobDigestSubject(data), htmlBody, e.generateJobDigestText(data))
+}
+
+// generateJobDigestHTML creates HTML email content for the job board digest
+func (e *EmailService) generateJobDigestHTML(data JobDigestData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>New Jobs</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .job { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 15px 0; }
+        .job h3 { margin: 0 0 5px 0; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>New on the Job Board</h1>
+        </div>
+
+        <div class="content">
+            <p>Hi {{if .Name}}{{.Name}}{{else}}there{{end}},</p>
+            <p>These veteran-friendly jobs were posted by employers this week:</p>
+            {{range .Postings}}
+            <div class="job">
+                <h3><a href="{{.URL}}">{{.Title}}</a></h3>
+                <p>{{.Employer}} · {{.Location}}<br>
+                <small>Open until {{.ExpiresAt.Format "January 2, 2006"}}</small></p>
+            </div>
+            {{end}}
+            <p><a href="{{.BoardURL}}">See every open job</a></p>
+        </div>
+
+        <div class="footer">
+            <p>You're receiving this because you turned on job alerts. You can turn them off in your <a href="{{.AccountURL}}">account settings</a>.</p>
+            <p>{{.OrganizationName}}</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("job_digest").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateJobDigestText creates plain text email content for the job board
+// digest
+func (e *EmailService) generateJobDigestText(data JobDigestData) string {
+	name := data.Name
+	if name == "" {
+		name = "there"
+	}
+	var jobs strings.Builder
+	for _, p := range data.Postings {
+		fmt.Fprintf(&jobs, "%s\n%s, %s\nOpen until %s\n%s\n\n", p.Title, p.Employer, p.Location, p.ExpiresAt.Format("January 2, 2006"), p.URL)
+	}
+
+	return fmt.Sprintf(`Hi %s,
+
+These veteran-friendly jobs were posted by employers this week:
+
+%sSee every open job: %s
+
+You're receiving this because you turned on job alerts. You can turn them off in your account settings: %s
+
+%s
+`,
+		name,
+		jobs.String(),
+		data.BoardURL,
+		data.AccountURL,
+		data.OrganizationName,
+	)
+}
+
+// MentorIntroductionData contains data for the email introducing a mentor
+// and mentee to each other. Each gets their own copy describing the other.
+type MentorIntroductionData struct {
+	Name             string
+	Role             string // the recipient's role, "mentor" or "mentee"
+	OtherName        string
+	OtherEmail       string
+	OtherTrade       string
+	OtherRegion      string
+	OtherSkills      string
+	OtherBio         string
+	OrganizationName string
+}
+
+// otherRole is the role of the person the recipient is being introduced to
+func (d MentorIntroductionData) otherRole() string {
+	if d.Role == "mentor" {
+		return "mentee"
+	}
+	return "mentor"
+}
+
+// mentorIntroductionSubject is the subject line for a mentoring introduction
+func mentorIntroductionSubject(data MentorIntroductionData) string {
+	return fmt.Sprintf("Meet your %s, %s", data.otherRole(), data.OtherName)
+}
+
+// SendMentorIntroduction introduces a mentor or mentee to the person an
+// admin matched them with
+func (e *EmailService) SendMentorIntroduction(toEmail string, data MentorIntroductionData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	htmlBody, err := e.generateMentorIntroductionHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, mentorIntroductionSubject(data), htmlBody, e.generateMentorIntroductionText(data))
+}
+
+// generateMentorIntroductionHTML creates HTML email content for a mentoring
+// introduction
+func (e *EmailService) generateMentorIntroductionHTML(data MentorIntroductionData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Mentoring Introduction</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .profile { background-color: #fff; padding: 15px; border: 1px solid #ddd; margin: 15px 0; }
+        .profile h3 { margin: 0 0 5px 0; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Meet Your {{if eq .Role "mentor"}}Mentee{{else}}Mentor{{end}}</h1>
+        </div>
+
+        <div class="content">
+            <p>Hi {{.Name}},</p>
+            {{if eq .Role "mentor"}}
+            <p>Thank you for offering to mentor through our program. We'd like to introduce you to {{.OtherName}}, a veteran who is building a career in the trades and could use your experience.</p>
+            {{else}}
+            <p>We've found a mentor for you! {{.OtherName}} is a veteran working in the trades who has offered to share what they've learned.</p>
+            {{end}}
+            <div class="profile">
+                <h3>{{.OtherName}}</h3>
+                <p>{{.OtherTrade}} · {{.OtherRegion}}
+                {{if .OtherSkills}}<br><small>Skills: {{.OtherSkills}}</small>{{end}}</p>
+                {{if .OtherBio}}<p>{{.OtherBio}}</p>{{end}}
+                <p>Email: <a href="mailto:{{.OtherEmail}}">{{.OtherEmail}}</a></p>
+            </div>
+            <p>{{if eq .Role "mentor"}}{{.OtherName}} has your email address too, so look out for a note from them.{{else}}{{.OtherName}} has your email address too, but don't wait for them: reach out and introduce yourself.{{end}} We'll check in with you both in a few weeks to see how it's going.</p>
+        </div>
+
+        <div class="footer">
+            <p>You're receiving this because you joined the {{.OrganizationName}} mentoring program.</p>
+            <p>{{.OrganizationName}}</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("mentor_introduction").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateMentorIntroductionText creates plain text email content for a
+// mentoring introduction
+func (e *EmailService) generateMentorIntroductionText(data MentorIntroductionData) string {
+	intro := fmt.Sprintf("We've found a mentor for you! %s is a veteran working in the trades who has offered to share what they've learned.", data.OtherName)
+	next := fmt.Sprintf("%s has your email address too, but don't wait for them: reach out and introduce yourself.", data.OtherName)
+	if data.Role == "mentor" {
+		intro = fmt.Sprintf("Thank you for offering to mentor through our program. We'd like to introduce you to %s, a veteran who is building a career in the trades and could use your experience.", data.OtherName)
+		next = fmt.Sprintf("%s has your email address too, so look out for a note from them.", data.OtherName)
+	}
+
+	var profile strings.Builder
+	fmt.Fprintf(&profile, "%s\n%s, %s\n", data.OtherName, data.OtherTrade, data.OtherRegion)
+	if data.OtherSkills != "" {
+		fmt.Fprintf(&profile, "Skills: %s\n", data.OtherSkills)
+	}
+	if data.OtherBio != "" {
+		fmt.Fprintf(&profile, "\n%s\n", data.OtherBio)
+	}
+	fmt.Fprintf(&profile, "\nEmail: %s\n", data.OtherEmail)
+
+	return fmt.Sprintf(`Hi %s,
+
+%s
+
+%s
+%s We'll check in with you both in a few weeks to see how it's going.
+
+You're receiving this because you joined the %s mentoring program.
+
+%s
+`,
+		data.Name,
+		intro,
+		profile.String(),
+		next,
+		data.OrganizationName,
+		data.OrganizationName,
+	)
+}
+
+// MonthlyNudgeData contains data for the email asking a donor who has made
+// several one-time gifts to become a monthly supporter
+type MonthlyNudgeData struct {
+	Name             string
+	Gifts            int
+	SuggestedAmount  float64
+	DonateURL        string // the donate page with the monthly amount filled in
+	ContactEmail     string
+	OrganizationName string
+}
+
+// monthlyNudgeSubject is the subject line for a monthly supporter nudge
+func monthlyNudgeSubject(data MonthlyNudgeData) string {
+	return fmt.Sprintf("Become a monthly supporter of %s", data.OrganizationName)
+}
+
+// SendMonthlyNudge asks a repeat one-time donor to give monthly instead
+func (e *EmailService) SendMonthlyNudge(toEmail string, data MonthlyNudgeData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	htmlBody, err := e.generateMonthlyNudgeHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	return e.sendEmail(toEmail, monthlyNudgeSubject(data), htmlBody, e.generateMonthlyNudgeText(data))
+}
+
+// generateMonthlyNudgeHTML creates HTML email content for a monthly
+// supporter nudge
+func (e *EmailService) generateMonthlyNudgeHTML(data MonthlyNudgeData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Become a Monthly Supporter</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #fff; color: #333; padding: 20px; text-align: center; border-bottom: 1px solid #ddd; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .button { display: inline-block; background-color: #1d4ed8; color: #fff; padding: 12px 24px; text-decoration: none; border-radius: 4px; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Thank You for Giving {{.Gifts}} Times This Year</h1>
+        </div>
+
+        <div class="content">
+            <p>Hi {{if .Name}}{{.Name}}{{else}}there{{end}},</p>
+            <p>You've given to {{.OrganizationName}} {{.Gifts}} times over the past year, and every gift has helped veterans train for new careers, find homes and rebuild.</p>
+            <p>Would you consider becoming a monthly supporter? A steady monthly gift lets us plan ahead and commit to veterans for the long run, and you won't need to remember to give.</p>
+            <p style="text-align: center;"><a href="{{.DonateURL}}" class="button">Give {{printf "$%.2f" .SuggestedAmount}} a month</a></p>
+            <p>You can change the amount on the next page, and change or cancel a monthly gift any time from your account.</p>
+        </div>
+
+        <div class="footer">
+            <p>You're receiving this because you've given to {{.OrganizationName}}. If you'd rather we didn't ask, reply to this email{{if .ContactEmail}} or write to <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>{{end}} and we won't.</p>
+            <p>{{.OrganizationName}}</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("monthly_nudge").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateMonthlyNudgeText creates plain text email content for a monthly
+// supporter nudge
+func (e *EmailService) generateMonthlyNudgeText(data MonthlyNudgeData) string {
+	name := data.Name
+	if name == "" {
+		name = "there"
+	}
+	optOut := "reply to this email"
+	if data.ContactEmail != "" {
+		optOut += " or write to " + data.ContactEmail
+	}
+
+	return fmt.Sprintf(`Hi %s,
+
+You've given to %s %d times over the past year, and every gift has helped veterans train for new careers, find homes and rebuild.
+
+Would you consider becoming a monthly supporter? A steady monthly gift lets us plan ahead and commit to veterans for the long run, and you won't need to remember to give.
+
+Give $%.2f a month: %s
+
+You can change the amount on the next page, and change or cancel a monthly gift any time from your account.
+
+You're receiving this because you've given to %s. If you'd rather we didn't ask, %s and we won't.
+
+%s
+`,
+		name,
+		data.OrganizationName,
+		data.Gifts,
+		data.SuggestedAmount,
+		data.DonateURL,
+		data.OrganizationName,
+		optOut,
+		data.OrganizationName,
+	)
+}
+
+// CampaignSendIDHeader carries the ID of a campaign email's send. Mail
+// providers pass custom headers back in their open events, which is how an
+// open is matched to the subject line it was sent with.
+const CampaignSendIDHeader = "X-Campaign-Send-ID"
+
+// CampaignEmailData contains data for an appeal or giving anniversary email
+// sent from the admin email campaigns tool
+type CampaignEmailData struct {
+	Name             string
+	Subject          string
+	Body             string // written by staff; paragraphs separated by blank lines
+	YearsGiving      int    // years since the donor's first gift, for anniversary emails
+	DonateURL        string
+	ContactEmail     string
+	OrganizationName string
+	SendID           string
+}
+
+// Paragraphs splits the body into paragraphs at blank lines
+func (d CampaignEmailData) Paragraphs() []string {
+	var paragraphs []string
+	for _, p := range strings.Split(strings.ReplaceAll(d.Body, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+// YearsText is how long the donor has been giving, e.g. "3 years", or ""
+// when the email isn't for a giving anniversary
+func (d CampaignEmailData) YearsText() string {
+	switch {
+	case d.YearsGiving <= 0:
+		return ""
+	case d.YearsGiving == 1:
+		return "1 year"
+	}
+	return fmt.Sprintf("%d years", d.YearsGiving)
+}
+
+// SendCampaignEmail sends one donor their copy of an email campaign, tagged
+// with the send's ID so the provider's open events can be matched to it
+func (e *EmailService) SendCampaignEmail(toEmail string, data CampaignEmailData) error {
+	if !e.isConfigured() {
+		return fmt.Errorf("email service not configured - missing environment variables")
+	}
+
+	htmlBody, err := e.generateCampaignEmailHTML(data)
+	if err != nil {
+		return fmt.Errorf("error generating email HTML: %v", err)
+	}
+
+	headers := map[string]string{CampaignSendIDHeader: data.SendID}
+	return e.sendMessage(toEmail, data.Subject, htmlBody, e.generateCampaignEmailText(data), nil, headers)
+}
+
+// generateCampaignEmailHTML creates HTML email content for a campaign email
+func (e *EmailService) generateCampaignEmailHTML(data CampaignEmailData) (string, error) {
+	htmlTemplate := `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Subject}}</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .button { display: inline-block; background-color: #1d4ed8; color: #fff; padding: 12px 24px; text-decoration: none; border-radius: 4px; }
+        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="content">
+            <p>Hi {{if .Name}}{{.Name}}{{else}}there{{end}},</p>
+            {{if .YearsText}}<p>It's been {{.YearsText}} since your first gift to {{.OrganizationName}}. Thank you for standing with veterans all this time.</p>{{end}}
+            {{range .Paragraphs}}<p>{{.}}</p>
+            {{end}}
+            <p style="text-align: center;"><a href="{{.DonateURL}}" class="button">Give today</a></p>
+        </div>
+
+        <div class="footer">
+            <p>You're receiving this because you've given to {{.OrganizationName}}. If you'd rather we didn't ask, reply to this email{{if .ContactEmail}} or write to <a href="mailto:{{.ContactEmail}}">{{.ContactEmail}}</a>{{end}} and we won't.</p>
+            <p>{{.OrganizationName}}</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
+	tmpl, err := template.New("campaign_email").Parse(htmlTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateCampaignEmailText creates plain text email content for a campaign
+// email
+func (e *EmailService) generateCampaignEmailText(data CampaignEmailData) string {
+	name := data.Name
+	if name == "" {
+		name = "there"
+	}
+	optOut := "reply to this email"
+	if data.ContactEmail != "" {
+		optOut += " or write to " + data.ContactEmail
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\n", name)
+	if years := data.YearsText(); years != "" {
+		fmt.Fprintf(&b, "It's been %s since your first gift to %s. Thank you for standing with veterans all this time.\n\n", years, data.OrganizationName)
+	}
+	for _, p := range data.Paragraphs() {
+		b.WriteString(p + "\n\n")
+	}
+	fmt.Fprintf(&b, "Give today: %s\n\n", data.DonateURL)
+	fmt.Fprintf(&b, "You're receiving this because you've given to %s. If you'd rather we didn't ask, %s and we won't.\n\n%s\n",
+		data.OrganizationName, optOut, data.OrganizationName)
+	return b.String()
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"encoding/base64"
 	"errors"
 	"net/smtp"
+	"strings"
 	"testing"
 	"time"
 
@@ -42,12 +44,14 @@ func TestSendDonationReceipt_UsesClientAndRespectsEmailEnabled(t *testing.T) {
 	}
 
 	testData := DonationReceiptData{
-		DonorName:        "Mock Donor",
-		DonationAmount:   10.0,
-		DonationType:     "One-time",
-		TransactionID:    "MOCK-1",
-		DonationDate:     time.Now(),
-		OrganizationName: "Test Org",
+		DonorName:           "Mock Donor",
+		DonationAmount:      10.0,
+		DonationType:        "One-time",
+		TransactionID:       "MOCK-1",
+		DonationDate:        time.Now(),
+		OrganizationName:    "Test Org",
+		OrganizationEIN:     "12-3456789",
+		OrganizationAddress: "1 Main St, Austin, TX 78701",
 	}
 
 	err := es.SendDonationReceipt("recipient@test.local", testData)
@@ -55,6 +59,14 @@ func TestSendDonationReceipt_UsesClientAndRespectsEmailEnabled(t *testing.T) {
 	require.True(t, mock.called)
 	require.Contains(t, mock.addr, "smtp.test")
 	require.Contains(t, string(mock.message), "MOCK-1")
+
+	// The receipt goes out with a PDF copy attached
+	message := string(mock.message)
+	require.Contains(t, message, `Content-Type: multipart/mixed; boundary="mixed123"`)
+	require.Contains(t, message, "Content-Type: multipart/alternative; boundary=\"boundary123\"\n\n--boundary123")
+	require.Contains(t, message, `Content-Disposition: attachment; filename="`+ReceiptPDFFilename(testData)+`"`)
+	require.Contains(t, message, base64.StdEncoding.EncodeToString([]byte("%PDF-1.4"))[:8])
+	require.True(t, strings.HasSuffix(message, "--mixed123--\n"))
 }
 
 func TestSendDonationReceipt_WhenDisabled_DoesNotCallClient(t *testing.T) {
@@ -71,12 +83,14 @@ func TestSendDonationReceipt_WhenDisabled_DoesNotCallClient(t *testing.T) {
 	}
 
 	testData := DonationReceiptData{
-		DonorName:        "Mock Donor",
-		DonationAmount:   10.0,
-		DonationType:     "One-time",
-		TransactionID:    "MOCK-2",
-		DonationDate:     time.Now(),
-		OrganizationName: "Test Org",
+		DonorName:           "Mock Donor",
+		DonationAmount:      10.0,
+		DonationType:        "One-time",
+		TransactionID:       "MOCK-2",
+		DonationDate:        time.Now(),
+		OrganizationName:    "Test Org",
+		OrganizationEIN:     "12-3456789",
+		OrganizationAddress: "1 Main St, Austin, TX 78701",
 	}
 
 	err := es.SendDonationReceipt("recipient@test.local", testData)
@@ -98,15 +112,44 @@ func TestSendDonationReceipt_ClientErrorPropagates(t *testing.T) {
 	}
 
 	testData := DonationReceiptData{
-		DonorName:        "Mock Donor",
-		DonationAmount:   10.0,
-		DonationType:     "One-time",
-		TransactionID:    "MOCK-3",
-		DonationDate:     time.Now(),
-		OrganizationName: "Test Org",
+		DonorName:           "Mock Donor",
+		DonationAmount:      10.0,
+		DonationType:        "One-time",
+		TransactionID:       "MOCK-3",
+		DonationDate:        time.Now(),
+		OrganizationName:    "Test Org",
+		OrganizationEIN:     "12-3456789",
+		OrganizationAddress: "1 Main St, Austin, TX 78701",
 	}
 
 	err := es.SendDonationReceipt("recipient@test.local", testData)
 	require.Error(t, err)
 	require.True(t, mock.called)
 }
+
+func TestSendCampaignEmail_TagsSendID(t *testing.T) {
+	mock := &mockSMTPClient{}
+	es := &EmailService{
+		SMTPHost:     "smtp.test",
+		SMTPPort:     "1025",
+		SMTPUsername: "user",
+		SMTPPassword: "pass",
+		FromEmail:    "from@test.local",
+		FromName:     "Test",
+		EmailEnabled: true,
+		client:       mock,
+	}
+
+	err := es.SendCampaignEmail("donor@test.local", CampaignEmailData{
+		Name:             "Alex",
+		Subject:          "Build a home this winter",
+		Body:             "Please give.",
+		DonateURL:        "https://avrnpo.org/donate",
+		OrganizationName: "Test Org",
+		SendID:           "send-1\r\nBcc: attacker@example.com",
+	})
+	require.NoError(t, err)
+	message := string(mock.message)
+	require.Contains(t, message, "Subject: Build a home this winter\nX-Campaign-Send-ID: send-1Bcc: attacker@example.com\nMIME-Version: 1.0\n")
+	require.Equal(t, []string{"donor@test.local"}, mock.to)
+}
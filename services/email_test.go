@@ -188,6 +188,157 @@ func TestEmailService_generateReceiptText(t *testing.T) {
 	require.Contains(t, text, "Somewhere, NY 10001")
 }
 
+func TestEmailService_generateReceipt_ReceiptNumber(t *testing.T) {
+	emailService := &EmailService{}
+
+	testData := DonationReceiptData{
+		DonorName:        "Jane Smith",
+		DonationAmount:   75.50,
+		DonationType:     "One-time",
+		TransactionID:    "TXN-789012",
+		ReceiptNumber:    "AVR-2026-000042",
+		DonationDate:     time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC),
+		OrganizationName: "Test Charity",
+	}
+
+	html, err := emailService.generateReceiptHTML(testData)
+	require.NoError(t, err)
+	require.Contains(t, html, "AVR-2026-000042")
+	require.Contains(t, emailService.generateReceiptText(testData), "Receipt Number: AVR-2026-000042\nTransaction ID: TXN-789012")
+
+	testData.ReceiptNumber = ""
+	require.NotContains(t, emailService.generateReceiptText(testData), "Receipt Number")
+}
+
+func TestEmailService_PreviewDonationReceipt(t *testing.T) {
+	emailService := &EmailService{ContactEmail: "giving@example.org"}
+
+	testData := DonationReceiptData{
+		DonorName:           "Jane Smith",
+		DonationAmount:      75.50,
+		DonationType:        "One-time",
+		TransactionID:       "TXN-789012",
+		ReceiptNumber:       "AVR-2026-000042",
+		DonationDate:        time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC),
+		TaxDeductibleAmount: 75.50,
+		OrganizationEIN:     "12-3456789",
+		OrganizationName:    "Test Charity",
+		OrganizationAddress: "123 Main St, City, ST 12345",
+	}
+
+	preview, err := emailService.PreviewDonationReceipt("jane@example.com", testData)
+	require.NoError(t, err)
+	require.Equal(t, "jane@example.com", preview.To)
+	require.Equal(t, "Thank you for your donation to Test Charity", preview.Subject)
+	require.Equal(t, "donation-receipt-2026-10-14.pdf", preview.PDFFilename)
+	require.Contains(t, preview.HTML, "AVR-2026-000042")
+	require.Contains(t, preview.Text, "Receipt Number: AVR-2026-000042")
+	require.Empty(t, preview.Blocked)
+
+	// A receipt that would be held back is still shown, with why
+	testData.OrganizationEIN = ""
+	preview, err = emailService.PreviewDonationReceipt("jane@example.com", testData)
+	require.NoError(t, err)
+	require.NotEmpty(t, preview.HTML)
+	require.NotEmpty(t, preview.Blocked)
+}
+
+func TestEmailService_generateReceipt_QuidProQuo(t *testing.T) {
+	emailService := &EmailService{}
+
+	testData := DonationReceiptData{
+		DonorName:           "Auction Winner",
+		DonationAmount:      400.00,
+		DonationType:        "One-time",
+		TransactionID:       "TXN-QPQ",
+		DonationDate:        time.Date(2026, 11, 11, 20, 0, 0, 0, time.UTC),
+		TaxDeductibleAmount: 250.00,
+		FairMarketValue:     150.00,
+		GoodsProvided:       "Weekend Cabin Stay (auction item)",
+		OrganizationName:    "Test Charity",
+	}
+
+	html, err := emailService.generateReceiptHTML(testData)
+	require.NoError(t, err)
+	require.Contains(t, html, "Weekend Cabin Stay (auction item)")
+	require.Contains(t, html, "$150.00")
+	require.Contains(t, html, "$250.00")
+	require.NotContains(t, html, "No goods or services were provided")
+
+	text := emailService.generateReceiptText(testData)
+	require.Contains(t, text, "Goods or Services Provided: Weekend Cabin Stay (auction item)")
+	require.Contains(t, text, "Estimated Fair Market Value: $150.00")
+	require.Contains(t, text, "limited to the excess of your payment")
+	require.NotContains(t, text, "No goods or services were provided")
+
+	// Without goods provided the standard statement stays
+	testData.FairMarketValue, testData.GoodsProvided, testData.TaxDeductibleAmount = 0, "", 400.00
+	html, err = emailService.generateReceiptHTML(testData)
+	require.NoError(t, err)
+	require.Contains(t, html, "No goods or services were provided")
+	require.Contains(t, emailService.generateReceiptText(testData), "No goods or services were provided")
+}
+
+func TestEmailService_generateReceipt_StoreOrder(t *testing.T) {
+	emailService := &EmailService{}
+
+	testData := DonationReceiptData{
+		DonorName:        "Store Buyer",
+		DonationAmount:   64.33,
+		DonationType:     "One-time",
+		TransactionID:    "TXN-STORE",
+		DonationDate:     time.Date(2026, 11, 11, 20, 0, 0, 0, time.UTC),
+		FairMarketValue:  64.33,
+		GoodsProvided:    "2 × T-Shirt, 1 × Challenge Coin",
+		OrderItems:       testOrderLines,
+		Shipping:         7.5,
+		SalesTax:         4.33,
+		OrganizationName: "Test Charity",
+	}
+
+	html, err := emailService.generateReceiptHTML(testData)
+	require.NoError(t, err)
+	require.Contains(t, html, "2 × T-Shirt")
+	require.Contains(t, html, "$40.00")
+	require.Contains(t, html, "$7.50")
+	require.Contains(t, html, "$4.33")
+
+	text := emailService.generateReceiptText(testData)
+	require.Contains(t, text, "2 × T-Shirt: $40.00")
+	require.Contains(t, text, "1 × Challenge Coin: $12.50")
+	require.Contains(t, text, "Shipping: $7.50")
+	require.Contains(t, text, "Sales tax: $4.33")
+}
+
+func TestEmailService_generateReceipt_ThankYou(t *testing.T) {
+	emailService := &EmailService{}
+
+	testData := DonationReceiptData{
+		DonorName:        "Partner Donor",
+		DonationAmount:   250,
+		DonationType:     "One-time",
+		TransactionID:    "TXN-THANKS",
+		DonationDate:     time.Date(2026, 11, 11, 20, 0, 0, 0, time.UTC),
+		OrganizationName: "Test Charity",
+		ThankYouMessage:  "Thank you, Partner, for your gift of $250.00!",
+		ThankYouVideoURL: "https://example.com/thanks",
+	}
+
+	html, err := emailService.generateReceiptHTML(testData)
+	require.NoError(t, err)
+	require.Contains(t, html, "Thank you, Partner, for your gift of $250.00!")
+	require.Contains(t, html, `href="https://example.com/thanks"`)
+
+	text := emailService.generateReceiptText(testData)
+	require.Contains(t, text, "Thank you, Partner, for your gift of $250.00!")
+	require.Contains(t, text, "Watch your thank-you video: https://example.com/thanks")
+
+	testData.ThankYouMessage, testData.ThankYouVideoURL = "", ""
+	html, err = emailService.generateReceiptHTML(testData)
+	require.NoError(t, err)
+	require.NotContains(t, html, `class="thank-you-note"`)
+}
+
 func TestEmailService_generateReceipt_IncludesSubscription(t *testing.T) {
 	emailService := &EmailService{}
 
@@ -317,3 +468,476 @@ func TestEmailService_LogoFileExists(t *testing.T) {
 
 	t.Logf("Logo file validation successful: %d bytes", len(logoData))
 }
+
+func TestEmailService_generateEventTicketHTML(t *testing.T) {
+	emailService := &EmailService{}
+
+	html, err := emailService.generateEventTicketHTML(EventTicketData{
+		HolderName:       "Jane Doe",
+		EventTitle:       "Veterans Day Dinner",
+		StartsAt:         time.Date(2026, 11, 11, 18, 30, 0, 0, time.UTC),
+		Location:         "VFW Post 123",
+		TicketURL:        "https://avrnpo.org/tickets/abc123",
+		OrganizationName: "Test Organization",
+	})
+	require.NoError(t, err)
+	require.Contains(t, html, "Veterans Day Dinner")
+	require.Contains(t, html, "Wednesday, November 11, 2026 at 6:30 PM")
+	require.Contains(t, html, "VFW Post 123")
+	require.Contains(t, html, "https://avrnpo.org/tickets/abc123")
+}
+
+func TestEmailService_generateEventReminder(t *testing.T) {
+	emailService := &EmailService{}
+	data := EventReminderData{
+		HolderName:       "Jane Doe",
+		EventTitle:       "Veterans Day Dinner",
+		StartsAt:         time.Date(2026, 11, 11, 18, 30, 0, 0, time.UTC),
+		Message:          "Parking is in the north lot.",
+		TicketURL:        "https://avrnpo.org/tickets/abc123",
+		CalendarURL:      "https://avrnpo.org/events/abc.ics",
+		OrganizationName: "Test Organization",
+	}
+
+	html, err := emailService.generateEventReminderHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "Parking is in the north lot.")
+	require.Contains(t, html, "Wednesday, November 11, 2026 at 6:30 PM")
+	require.Contains(t, html, "https://avrnpo.org/tickets/abc123")
+	require.Contains(t, html, "https://avrnpo.org/events/abc.ics")
+
+	text := emailService.generateEventReminderText(data)
+	require.Contains(t, text, "Parking is in the north lot.")
+	require.Contains(t, text, "Add it to your calendar: https://avrnpo.org/events/abc.ics")
+}
+
+func TestEmailService_generateAuctionWinnerHTML(t *testing.T) {
+	emailService := &EmailService{}
+
+	html, err := emailService.generateAuctionWinnerHTML(AuctionWinnerData{
+		WinnerName:       "Jane Doe",
+		ItemTitle:        "Weekend Cabin Stay",
+		Amount:           600,
+		FairMarketValue:  450,
+		PaymentURL:       "https://avrnpo.org/auctions/pay/abc",
+		OrganizationName: "Test Organization",
+	})
+	require.NoError(t, err)
+	require.Contains(t, html, "Weekend Cabin Stay")
+	require.Contains(t, html, "$600.00")
+	require.Contains(t, html, "$450.00")
+	require.Contains(t, html, "https://avrnpo.org/auctions/pay/abc")
+
+	html, err = emailService.generateAuctionWinnerHTML(AuctionWinnerData{WinnerName: "Sam", ItemTitle: "Quilt", Raffle: true})
+	require.NoError(t, err)
+	require.Contains(t, html, "raffle ticket was drawn")
+	require.NotContains(t, html, "Winning bid")
+}
+
+func TestEmailService_generateUserInvitation(t *testing.T) {
+	emailService := &EmailService{}
+	data := UserInvitationData{
+		FirstName:        "Jo",
+		InvitedBy:        "Pat Admin",
+		SetupURL:         "https://avrnpo.org/invitations/abc",
+		ExpiresAt:        time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC),
+		OrganizationName: "Test Organization",
+		ContactEmail:     "info@example.com",
+	}
+
+	html, err := emailService.generateUserInvitationHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "Welcome, Jo!")
+	require.Contains(t, html, "Pat Admin has set up an account")
+	require.Contains(t, html, "https://avrnpo.org/invitations/abc")
+	require.Contains(t, html, "October 21, 2026")
+
+	text := emailService.generateUserInvitationText(UserInvitationData{SetupURL: data.SetupURL, ExpiresAt: data.ExpiresAt})
+	require.Contains(t, text, "Welcome!")
+	require.Contains(t, text, "We've set up an account")
+	require.Contains(t, text, "https://avrnpo.org/invitations/abc")
+}
+
+func TestEmailService_generateInKindAcknowledgement(t *testing.T) {
+	emailService := &EmailService{}
+	data := InKindAcknowledgementData{
+		DonorName:        "Hardware Co.",
+		Description:      "Cordless drill kits",
+		Quantity:         4,
+		Category:         "Tools",
+		ReceivedDate:     time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		OrganizationName: "Test Charity",
+		OrganizationEIN:  "12-3456789",
+		ContactEmail:     "info@example.org",
+	}
+
+	html, err := emailService.generateInKindAcknowledgementHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "4 × Cordless drill kits")
+	require.Contains(t, html, "October 1, 2026")
+	require.Contains(t, html, "No goods or services were provided")
+	require.Contains(t, html, "does not state its value")
+
+	text := emailService.generateInKindAcknowledgementText(data)
+	require.Contains(t, text, "Description: 4 × Cordless drill kits")
+	require.Contains(t, text, "EIN: 12-3456789")
+	require.Contains(t, text, "Form 8283")
+}
+
+func TestEmailService_generateVehicleAcknowledgement(t *testing.T) {
+	emailService := &EmailService{}
+	data := VehicleAcknowledgementData{
+		DonorName:        "Sam Donor",
+		DonorAddress:     "12 Main St, Lexington, KY 40502",
+		ContributionDate: time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC),
+		Year:             2013,
+		Make:             "Ford",
+		Model:            "F-150",
+		VIN:              "1FTFW1ET5DFC10312",
+		Mileage:          142000,
+		Sold:             true,
+		ArmsLengthSale:   true,
+		SaleDate:         time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		GrossProceeds:    3250,
+		OrganizationName: "Test Charity",
+		OrganizationEIN:  "12-3456789",
+		ContactEmail:     "info@example.org",
+	}
+
+	html, err := emailService.generateVehicleAcknowledgementHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "1FTFW1ET5DFC10312")
+	require.Contains(t, html, "$3250.00")
+	require.Contains(t, html, "October 1, 2026")
+	require.Contains(t, html, "cannot exceed the gross proceeds")
+	require.NotContains(t, html, "significant intervening use in our charitable programs")
+
+	text := emailService.generateVehicleAcknowledgementText(data)
+	require.Contains(t, text, "Gross proceeds from sale: $3250.00")
+	require.Contains(t, text, "Date of contribution: September 2, 2026")
+	require.Contains(t, text, "EIN: 12-3456789")
+
+	data.Sold = false
+	data.UseDescription = "Hauling materials to build sites for two years"
+	html, err = emailService.generateVehicleAcknowledgementHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "Hauling materials to build sites for two years")
+	require.Contains(t, html, "fair market value on the date of contribution")
+	require.NotContains(t, html, "Gross proceeds")
+
+	text = emailService.generateVehicleAcknowledgementText(data)
+	require.Contains(t, text, "USE OF VEHICLE")
+	require.NotContains(t, text, "SALE OF VEHICLE")
+}
+
+func TestEmailService_generateYearEndReceipt(t *testing.T) {
+	emailService := &EmailService{}
+	data := YearEndReceiptData{
+		Year:              2025,
+		DonorName:         "Sam Donor",
+		DonorAddressLine1: "12 Main St",
+		DonorCity:         "Lexington",
+		DonorState:        "KY",
+		DonorZip:          "40502",
+		Lines: []YearEndReceiptLine{
+			{Date: time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC), Description: "One-time gift", Amount: 100},
+			{Date: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), Description: "Pledge Installment 1 of 3", Amount: 50},
+		},
+		OrganizationName: "Test Charity",
+		OrganizationEIN:  "12-3456789",
+		ContactEmail:     "info@example.org",
+	}
+
+	require.Equal(t, 150.0, data.Total())
+	require.Equal(t, 150.0, data.TotalDeductible())
+	require.False(t, data.GoodsReceived())
+
+	html, err := emailService.generateYearEndReceiptHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "2025 Year-End Tax Receipt")
+	require.Contains(t, html, "Lexington, KY 40502")
+	require.Contains(t, html, "Pledge Installment 1 of 3")
+	require.Contains(t, html, "<strong>Total contributions:</strong> $150.00")
+	require.Contains(t, html, "EIN 12-3456789")
+	require.Contains(t, html, "No goods or services were provided")
+
+	text := emailService.generateYearEndReceiptText(data)
+	require.Contains(t, text, "Mar 4, 2025  One-time gift  $100.00")
+	require.Contains(t, text, "Tax-deductible amount: $150.00")
+	require.Contains(t, text, "EIN: 12-3456789")
+
+	// An auction win is only deductible above the item's value
+	data.Lines = append(data.Lines, YearEndReceiptLine{
+		Date: time.Date(2025, 11, 8, 0, 0, 0, 0, time.UTC), Description: "One-time gift", Amount: 300, GoodsValue: 200, GoodsProvided: "Weekend cabin stay",
+	})
+	require.Equal(t, 450.0, data.Total())
+	require.Equal(t, 200.0, data.TotalGoodsValue())
+	require.Equal(t, 250.0, data.TotalDeductible())
+
+	html, err = emailService.generateYearEndReceiptHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "Received: Weekend cabin stay (value $200.00)")
+	require.Contains(t, html, "<strong>Tax-deductible amount:</strong> $250.00")
+	require.NotContains(t, html, "No goods or services were provided")
+
+	text = emailService.generateYearEndReceiptText(data)
+	require.Contains(t, text, "Value of goods or services received: $200.00")
+}
+
+func TestEmailService_generateStaffDigest(t *testing.T) {
+	emailService := &EmailService{}
+	data := StaffDigestData{
+		WeekStart:      time.Date(2026, 10, 7, 0, 0, 0, 0, time.UTC),
+		WeekEnd:        time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+		GiftCount:      12,
+		GiftTotal:      1840,
+		NewRecurring:   2,
+		Cancelled:      1,
+		ActiveMonthly:  40,
+		MonthlyRevenue: 1250,
+		ChurnPercent:   2.5,
+		Forecast: []ForecastMonth{
+			{Month: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), Monthly: 1218.75, Installments: 100},
+		},
+		ForecastTotal:    1318.75,
+		OrganizationName: "Test Charity",
+	}
+
+	html, err := emailService.generateStaffDigestHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "12 totaling $1840.00")
+	require.Contains(t, html, "<td>November 2026</td><td>$1318.75</td>")
+	require.Contains(t, html, "2.5% monthly churn")
+
+	text := emailService.generateStaffDigestText(data)
+	require.Contains(t, text, "Recurring gifts cancelled: 1")
+	require.Contains(t, text, "November 2026: $1318.75")
+	require.Contains(t, text, "October 7 to October 14, 2026")
+}
+
+func TestEmailService_generatePaymentFailureNotice(t *testing.T) {
+	emailService := &EmailService{}
+	data := PaymentFailureNoticeData{
+		DonorName:        "Jane Doe",
+		Amount:           25,
+		Notice:           1,
+		NextRetryDate:    time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		OrganizationName: "Test Charity",
+		ContactEmail:     "help@example.com",
+	}
+
+	require.Equal(t, "We couldn't process your monthly donation to Test Charity", paymentFailureSubject(data))
+	html, err := emailService.generatePaymentFailureNoticeHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "monthly donation of $25.00")
+	require.Contains(t, html, "We&#39;ll try again on October 17, 2026.")
+
+	data.Notice, data.FinalNotice = 3, true
+	require.Equal(t, "Final notice: your monthly donation to Test Charity will be cancelled", paymentFailureSubject(data))
+	text := emailService.generatePaymentFailureNoticeText(data)
+	require.Contains(t, text, "We'll try one last time on October 17, 2026. If that payment fails, your monthly donation will be cancelled.")
+	require.Contains(t, text, "My Subscriptions")
+}
+
+func TestEmailService_ContactRecipients(t *testing.T) {
+	emailService := &EmailService{ContactEmail: "info@example.com"}
+	t.Setenv("CONTACT_EMAIL_PRESS", "press@example.com, media@example.com,")
+
+	require.Equal(t, []string{"press@example.com", "media@example.com"}, emailService.ContactRecipients("press"))
+	require.Equal(t, []string{"info@example.com"}, emailService.ContactRecipients("programs"))
+	require.Equal(t, []string{"info@example.com"}, emailService.ContactRecipients(""))
+
+	html, err := emailService.generateContactNotificationHTML(ContactFormData{Name: "Sam", Subject: "Interview", TopicLabel: "Press"})
+	require.NoError(t, err)
+	require.Contains(t, html, "<strong>Topic:</strong> Press")
+}
+
+func TestEmailService_generateContactReminder(t *testing.T) {
+	emailService := &EmailService{}
+	data := ContactReminderData{
+		Name:       "Sam",
+		Email:      "sam@example.com",
+		Subject:    "Volunteering",
+		TopicLabel: "Volunteering",
+		ReceivedAt: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC),
+		Waiting:    "2 days",
+		InboxURL:   "https://avrnpo.org/admin/messages/1",
+	}
+
+	require.Equal(t, "Reminder: contact message from Sam awaiting reply", contactReminderSubject(data))
+	html, err := emailService.generateContactReminderHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "This message has been waiting 2 days for a reply.")
+	require.Contains(t, html, `<a href="https://avrnpo.org/admin/messages/1">admin inbox</a>`)
+
+	data.Escalation, data.Waiting = true, "4 days"
+	require.Equal(t, "Escalated: contact message from Sam unanswered for 4 days", contactReminderSubject(data))
+	require.Contains(t, emailService.generateContactReminderText(data), "has gone unanswered for 4 days")
+}
+
+func TestEmailService_generatePressInquiry(t *testing.T) {
+	emailService := &EmailService{ContactEmail: "info@example.com"}
+	t.Setenv("CONTACT_EMAIL_PRESS", "press@example.com")
+	require.Equal(t, []string{"press@example.com"}, emailService.PressAlertRecipients())
+	t.Setenv("PRESS_ALERT_EMAIL", "director@example.com, chair@example.com")
+	require.Equal(t, []string{"director@example.com", "chair@example.com"}, emailService.PressAlertRecipients())
+
+	data := PressInquiryData{
+		Name:        "Sam",
+		Email:       "sam@example.com",
+		Subject:     "Interview",
+		Message:     "Could we talk this week?",
+		SubmittedAt: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC),
+		InboxURL:    "https://avrnpo.org/admin/messages/1",
+	}
+	require.Equal(t, "Press inquiry: Interview from Sam", pressInquirySubject(data))
+	require.Contains(t, emailService.generatePressInquiryText(data), "Deadline: none given")
+
+	deadline := time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC)
+	data.Outlet, data.Deadline = "Daily Ledger", &deadline
+	require.Equal(t, "Press inquiry, deadline Oct 3: Interview from Sam (Daily Ledger)", pressInquirySubject(data))
+	html, err := emailService.generatePressInquiryHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "<strong>Deadline: Saturday, October 3, 2026</strong>")
+	require.Contains(t, html, "<strong>Outlet:</strong> Daily Ledger")
+	require.Contains(t, html, `<a href="https://avrnpo.org/admin/messages/1">admin inbox</a>`)
+}
+
+func TestEmailService_generateJobDigest(t *testing.T) {
+	emailService := &EmailService{}
+	data := JobDigestData{
+		Name: "Alex",
+		Postings: []JobDigestPosting{{
+			Title:     "Site Foreman",
+			Employer:  "Acme Builders",
+			Location:  "Austin, TX",
+			URL:       "https://avrnpo.org/jobs/1",
+			ExpiresAt: time.Date(2026, 11, 13, 0, 0, 0, 0, time.UTC),
+		}},
+		BoardURL:         "https://avrnpo.org/jobs",
+		AccountURL:       "https://avrnpo.org/account",
+		OrganizationName: "American Veterans Rebuilding",
+	}
+
+	require.Equal(t, "New on the job board: Site Foreman at Acme Builders", jobDigestSubject(data))
+	html, err := emailService.generateJobDigestHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, `<h3><a href="https://avrnpo.org/jobs/1">Site Foreman</a></h3>`)
+	require.Contains(t, html, "Open until November 13, 2026")
+	require.Contains(t, html, `<a href="https://avrnpo.org/account">account settings</a>`)
+
+	data.Postings = append(data.Postings, JobDigestPosting{Title: "Electrician", Employer: "Volt Co", Location: "Remote"})
+	require.Equal(t, "2 new jobs on the American Veterans Rebuilding job board", jobDigestSubject(data))
+	text := emailService.generateJobDigestText(data)
+	require.Contains(t, text, "Electrician\nVolt Co, Remote\n")
+	require.Contains(t, text, "See every open job: https://avrnpo.org/jobs")
+}
+
+func TestEmailService_generateMentorIntroduction(t *testing.T) {
+	emailService := &EmailService{}
+	data := MentorIntroductionData{
+		Name:             "Sam",
+		Role:             "mentee",
+		OtherName:        "Pat Rivera",
+		OtherEmail:       "pat@example.com",
+		OtherTrade:       "Welding",
+		OtherRegion:      "Central Texas",
+		OtherSkills:      "TIG, pipe welding",
+		OrganizationName: "American Veterans Rebuilding",
+	}
+
+	require.Equal(t, "Meet your mentor, Pat Rivera", mentorIntroductionSubject(data))
+	html, err := emailService.generateMentorIntroductionHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "<h1>Meet Your Mentor</h1>")
+	require.Contains(t, html, `<a href="mailto:pat@example.com">pat@example.com</a>`)
+	require.Contains(t, html, "Skills: TIG, pipe welding")
+	text := emailService.generateMentorIntroductionText(data)
+	require.Contains(t, text, "Pat Rivera\nWelding, Central Texas\nSkills: TIG, pipe welding\n")
+	require.Contains(t, text, "reach out and introduce yourself")
+
+	data.Role = "mentor"
+	data.OtherName = "Sam Lee"
+	require.Equal(t, "Meet your mentee, Sam Lee", mentorIntroductionSubject(data))
+	text = emailService.generateMentorIntroductionText(data)
+	require.Contains(t, text, "Thank you for offering to mentor")
+	require.Contains(t, text, "look out for a note from them")
+}
+
+func TestEmailService_generateMonthlyNudge(t *testing.T) {
+	emailService := &EmailService{}
+	data := MonthlyNudgeData{
+		Name:             "Alex",
+		Gifts:            4,
+		SuggestedAmount:  15,
+		DonateURL:        "https://avrnpo.org/donate?amount=15&donation_type=monthly",
+		ContactEmail:     "info@avrnpo.org",
+		OrganizationName: "American Veterans Rebuilding",
+	}
+
+	require.Equal(t, "Become a monthly supporter of American Veterans Rebuilding", monthlyNudgeSubject(data))
+	html, err := emailService.generateMonthlyNudgeHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "Thank You for Giving 4 Times This Year")
+	require.Contains(t, html, `<a href="https://avrnpo.org/donate?amount=15&amp;donation_type=monthly" class="button">Give $15.00 a month</a>`)
+	require.Contains(t, html, `<a href="mailto:info@avrnpo.org">info@avrnpo.org</a>`)
+
+	text := emailService.generateMonthlyNudgeText(data)
+	require.Contains(t, text, "Give $15.00 a month: https://avrnpo.org/donate?amount=15&donation_type=monthly")
+	require.Contains(t, text, "reply to this email or write to info@avrnpo.org and we won't")
+}
+
+func TestEmailService_generateCampaignEmail(t *testing.T) {
+	emailService := &EmailService{}
+	data := CampaignEmailData{
+		Name:             "Alex",
+		Subject:          "Build a home this winter",
+		Body:             "Winter is hard on veterans without a home.\n\nYour gift <today> puts a roof over one.",
+		YearsGiving:      3,
+		DonateURL:        "https://avrnpo.org/donate",
+		ContactEmail:     "info@avrnpo.org",
+		OrganizationName: "American Veterans Rebuilding",
+	}
+
+	html, err := emailService.generateCampaignEmailHTML(data)
+	require.NoError(t, err)
+	require.Contains(t, html, "<p>It's been 3 years since your first gift to American Veterans Rebuilding.")
+	require.Contains(t, html, "<p>Winter is hard on veterans without a home.</p>")
+	require.Contains(t, html, "<p>Your gift &lt;today&gt; puts a roof over one.</p>")
+	require.Contains(t, html, `<a href="https://avrnpo.org/donate" class="button">Give today</a>`)
+
+	text := emailService.generateCampaignEmailText(data)
+	require.Contains(t, text, "Hi Alex,\n\nIt's been 3 years since your first gift")
+	require.Contains(t, text, "Winter is hard on veterans without a home.\n\nYour gift <today> puts a roof over one.\n\n")
+	require.Contains(t, text, "Give today: https://avrnpo.org/donate")
+
+	// Appeals don't mention an anniversary
+	data.YearsGiving = 0
+	require.NotContains(t, emailService.generateCampaignEmailText(data), "since your first gift")
+	data.YearsGiving = 1
+	require.Equal(t, "1 year", data.YearsText())
+}
+
+func TestIsLoadTestEmail(t *testing.T) {
+	require.True(t, IsLoadTestEmail("loadtest-abc123@loadtest.invalid"))
+	require.True(t, IsLoadTestEmail(" Someone@LOADTEST.invalid "))
+	require.False(t, IsLoadTestEmail("donor@example.com"))
+	require.False(t, IsLoadTestEmail("donor@loadtest.invalid.example.com"))
+	require.False(t, IsLoadTestEmail(""))
+}
+
+func TestEmailService_sendEmail_SkipsLoadTestDonors(t *testing.T) {
+	// Enabled, but with nowhere to deliver to: only the load test guard
+	// stops this from failing
+	service := &EmailService{
+		SMTPHost:     "127.0.0.1",
+		SMTPPort:     "1",
+		SMTPUsername: "test@example.com",
+		SMTPPassword: "password",
+		FromEmail:    "test@example.com",
+		FromName:     "Test",
+		EmailEnabled: true,
+	}
+	err := service.sendEmailWithBCC("loadtest-abc123@loadtest.invalid", "Receipt", "<p>Thanks</p>", "Thanks", []string{"staff@example.com"})
+	require.NoError(t, err)
+}
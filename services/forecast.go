@@ -0,0 +1,74 @@
+package services
+
+import (
+	"math"
+	"time"
+)
+
+// RecurringGift is an active recurring gift as the revenue forecast sees it:
+// a monthly amount and, for installment pledges, how many payments are left.
+type RecurringGift struct {
+	Amount            float64
+	RemainingPayments int // 0 for monthly gifts, which have no end date
+}
+
+// ForecastMonth is the recurring revenue expected in one calendar month
+type ForecastMonth struct {
+	Month        time.Time
+	Monthly      float64
+	Installments float64
+}
+
+// Expected is the month's total expected recurring revenue
+func (m ForecastMonth) Expected() float64 {
+	return m.Monthly + m.Installments
+}
+
+// MonthlyChurnRate estimates the share of recurring gifts cancelled each
+// month from the cancellations seen over the last windowMonths, given how
+// many gifts are still active. It's the monthly rate that would leave the
+// observed number of survivors after windowMonths.
+func MonthlyChurnRate(cancelled, active, windowMonths int) float64 {
+	started := cancelled + active
+	if started == 0 || cancelled == 0 || windowMonths < 1 {
+		return 0
+	}
+	if active == 0 {
+		return 1
+	}
+	survived := float64(active) / float64(started)
+	return 1 - math.Pow(survived, 1/float64(windowMonths))
+}
+
+// ForecastRecurringRevenue projects recurring revenue for the months calendar
+// months after from. Each gift is discounted by the chance it has churned by
+// then; installment pledges stop once their remaining payments are made.
+func ForecastRecurringRevenue(gifts []RecurringGift, monthlyChurn float64, from time.Time, months int) []ForecastMonth {
+	first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location()).AddDate(0, 1, 0)
+	forecast := make([]ForecastMonth, months)
+	for i := range forecast {
+		survival := math.Pow(1-monthlyChurn, float64(i+1))
+		month := ForecastMonth{Month: first.AddDate(0, i, 0)}
+		for _, gift := range gifts {
+			switch {
+			case gift.RemainingPayments == 0:
+				month.Monthly += gift.Amount * survival
+			case i < gift.RemainingPayments:
+				month.Installments += gift.Amount * survival
+			}
+		}
+		month.Monthly = math.Round(month.Monthly*100) / 100
+		month.Installments = math.Round(month.Installments*100) / 100
+		forecast[i] = month
+	}
+	return forecast
+}
+
+// ForecastTotal is the recurring revenue expected over the whole forecast
+func ForecastTotal(forecast []ForecastMonth) float64 {
+	total := 0.0
+	for _, month := range forecast {
+		total += month.Expected()
+	}
+	return math.Round(total*100) / 100
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonthlyChurnRate(t *testing.T) {
+	assert.Equal(t, 0.0, MonthlyChurnRate(0, 10, 6))
+	assert.Equal(t, 0.0, MonthlyChurnRate(0, 0, 6))
+	assert.Equal(t, 1.0, MonthlyChurnRate(4, 0, 6))
+
+	// 1 of 4 lost over 2 months is about 13.4% a month
+	assert.InDelta(t, 0.134, MonthlyChurnRate(1, 3, 2), 0.001)
+}
+
+func TestForecastRecurringRevenue(t *testing.T) {
+	from := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	gifts := []RecurringGift{
+		{Amount: 100},
+		{Amount: 50, RemainingPayments: 2},
+	}
+
+	forecast := ForecastRecurringRevenue(gifts, 0, from, 12)
+	require.Len(t, forecast, 12)
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), forecast[0].Month)
+	assert.Equal(t, time.Date(2027, 10, 1, 0, 0, 0, 0, time.UTC), forecast[11].Month)
+	assert.Equal(t, 150.0, forecast[0].Expected())
+	assert.Equal(t, 150.0, forecast[1].Expected())
+	assert.Equal(t, 100.0, forecast[2].Expected(), "the pledge is paid off after two more payments")
+	assert.Equal(t, 1300.0, ForecastTotal(forecast))
+
+	forecast = ForecastRecurringRevenue(gifts, 0.1, from, 3)
+	assert.Equal(t, 90.0, forecast[0].Monthly)
+	assert.Equal(t, 45.0, forecast[0].Installments)
+	assert.Equal(t, 81.0, forecast[1].Monthly)
+	assert.Equal(t, 0.0, forecast[2].Installments)
+}
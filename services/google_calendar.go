@@ -0,0 +1,266 @@
+package services
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	googleCalendarAPI   = "https://www.googleapis.com/calendar/v3"
+	googleCalendarScope = "https://www.googleapis.com/auth/calendar.events"
+	googleTokenURL      = "https://oauth2.googleapis.com/token"
+)
+
+// CalendarEvent is an event as it's copied to the staff Google Calendar
+type CalendarEvent struct {
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+}
+
+// googleServiceAccount is the part of a Google service account key file the
+// client needs
+type googleServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// GoogleCalendarClient keeps events in a staff Google Calendar, signing in
+// as a service account the calendar has been shared with
+type GoogleCalendarClient struct {
+	CalendarID  string
+	ClientEmail string
+	PrivateKey  *rsa.PrivateKey
+	TokenURL    string
+	BaseURL     string
+	Client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// GoogleCalendarEnabled reports whether events are synced to Google
+// Calendar (GOOGLE_CALENDAR_ID and GOOGLE_CALENDAR_CREDENTIALS are set)
+func GoogleCalendarEnabled() bool {
+	return os.Getenv("GOOGLE_CALENDAR_ID") != "" && os.Getenv("GOOGLE_CALENDAR_CREDENTIALS") != ""
+}
+
+// NewGoogleCalendarClient returns a client for the calendar in
+// GOOGLE_CALENDAR_ID. GOOGLE_CALENDAR_CREDENTIALS is the service account's
+// JSON key, or the path to it.
+func NewGoogleCalendarClient() (*GoogleCalendarClient, error) {
+	calendarID := os.Getenv("GOOGLE_CALENDAR_ID")
+	credentials := strings.TrimSpace(os.Getenv("GOOGLE_CALENDAR_CREDENTIALS"))
+	if calendarID == "" || credentials == "" {
+		return nil, fmt.Errorf("google calendar sync is not configured")
+	}
+
+	key := []byte(credentials)
+	if !strings.HasPrefix(credentials, "{") {
+		data, err := os.ReadFile(credentials)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read google calendar credentials: %w", err)
+		}
+		key = data
+	}
+	return newGoogleCalendarClient(calendarID, key)
+}
+
+func newGoogleCalendarClient(calendarID string, key []byte) (*GoogleCalendarClient, error) {
+	var account googleServiceAccount
+	if err := json.Unmarshal(key, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse google calendar credentials: %w", err)
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("google calendar credentials have no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse google calendar private key: %w", err)
+	}
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("google calendar private key is not an RSA key")
+	}
+	tokenURL := account.TokenURI
+	if tokenURL == "" {
+		tokenURL = googleTokenURL
+	}
+
+	return &GoogleCalendarClient{
+		CalendarID:  calendarID,
+		ClientEmail: account.ClientEmail,
+		PrivateKey:  privateKey,
+		TokenURL:    tokenURL,
+		BaseURL:     googleCalendarAPI,
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// token returns an access token for the service account, signing in again
+// shortly before the current one expires
+func (g *GoogleCalendarClient) token() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.accessToken != "" && time.Now().Before(g.expiresAt.Add(-time.Minute)) {
+		return g.accessToken, nil
+	}
+
+	assertion, err := g.signAssertion(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	resp, err := g.Client.PostForm(g.TokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to request google access token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("google token request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to decode google token response: %w", err)
+	}
+	g.accessToken = result.AccessToken
+	g.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return g.accessToken, nil
+}
+
+// signAssertion is the signed JWT the service account trades for an access
+// token
+func (g *GoogleCalendarClient) signAssertion(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   g.ClientEmail,
+		"scope": googleCalendarScope,
+		"aud":   g.TokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, g.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign google token request: %w", err)
+	}
+	return unsigned + "." + enc.EncodeToString(signature), nil
+}
+
+// do sends a Calendar API request, returning the response status and body
+func (g *GoogleCalendarClient) do(method, path string, payload interface{}) (int, []byte, error) {
+	token, err := g.token()
+	if err != nil {
+		return 0, nil, err
+	}
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, g.BaseURL+path, body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, respBody, nil
+}
+
+func (g *GoogleCalendarClient) eventsPath() string {
+	return "/calendars/" + url.PathEscape(g.CalendarID) + "/events"
+}
+
+// SaveEvent creates the event in the calendar, or updates it when googleID
+// is the ID it was created with. It returns the event's Google ID. An event
+// deleted from the calendar by hand is created again.
+func (g *GoogleCalendarClient) SaveEvent(googleID string, event CalendarEvent) (string, error) {
+	payload := map[string]interface{}{
+		"summary":     event.Summary,
+		"description": event.Description,
+		"location":    event.Location,
+		"start":       map[string]string{"dateTime": event.Start.UTC().Format(time.RFC3339)},
+		"end":         map[string]string{"dateTime": event.End.UTC().Format(time.RFC3339)},
+		"status":      "confirmed",
+	}
+
+	status, body, err := 0, []byte(nil), error(nil)
+	if googleID != "" {
+		status, body, err = g.do(http.MethodPut, g.eventsPath()+"/"+url.PathEscape(googleID), payload)
+		if err != nil {
+			return "", err
+		}
+	}
+	if googleID == "" || status == http.StatusNotFound || status == http.StatusGone {
+		status, body, err = g.do(http.MethodPost, g.eventsPath(), payload)
+		if err != nil {
+			return "", err
+		}
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("google calendar request failed with status %d: %s", status, string(body))
+	}
+
+	var saved struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &saved); err != nil {
+		return "", fmt.Errorf("failed to decode google calendar response: %w", err)
+	}
+	return saved.ID, nil
+}
+
+// DeleteEvent removes an event from the calendar. Events that are already
+// gone are left alone.
+func (g *GoogleCalendarClient) DeleteEvent(googleID string) error {
+	status, body, err := g.do(http.MethodDelete, g.eventsPath()+"/"+url.PathEscape(googleID), nil)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound, http.StatusGone:
+		return nil
+	}
+	return fmt.Errorf("google calendar delete failed with status %d: %s", status, string(body))
+}
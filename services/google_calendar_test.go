@@ -0,0 +1,104 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testGoogleCalendarKey(t *testing.T, tokenURL string) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	data, err := json.Marshal(googleServiceAccount{
+		ClientEmail: "calendar@avr-test.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    tokenURL,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func TestGoogleCalendarClient_SignAssertion(t *testing.T) {
+	req := require.New(t)
+
+	client, err := newGoogleCalendarClient("staff@group.calendar.google.com", testGoogleCalendarKey(t, "https://oauth2.example.com/token"))
+	req.NoError(err)
+
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	assertion, err := client.signAssertion(now)
+	req.NoError(err)
+	parts := strings.Split(assertion, ".")
+	req.Len(parts, 3)
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	req.NoError(err)
+	var claims map[string]interface{}
+	req.NoError(json.Unmarshal(claimsJSON, &claims))
+	req.Equal("calendar@avr-test.iam.gserviceaccount.com", claims["iss"])
+	req.Equal("https://oauth2.example.com/token", claims["aud"])
+	req.Equal(googleCalendarScope, claims["scope"])
+	req.EqualValues(now.Add(time.Hour).Unix(), claims["exp"])
+}
+
+func TestGoogleCalendarClient_SaveAndDelete(t *testing.T) {
+	req := require.New(t)
+
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Write([]byte(`{"access_token":"test-token","expires_in":3600}`))
+			return
+		}
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		req.Equal("Bearer test-token", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/removed"):
+			http.NotFound(w, r)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			body, _ := io.ReadAll(r.Body)
+			req.Contains(string(body), `"summary":"Build Day"`)
+			w.Write([]byte(`{"id":"gcal123"}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := newGoogleCalendarClient("staff@group.calendar.google.com", testGoogleCalendarKey(t, server.URL+"/token"))
+	req.NoError(err)
+	client.BaseURL = server.URL
+
+	event := CalendarEvent{Summary: "Build Day", Start: time.Now(), End: time.Now().Add(time.Hour)}
+
+	id, err := client.SaveEvent("", event)
+	req.NoError(err)
+	req.Equal("gcal123", id)
+
+	// An event removed from the calendar by hand is created again
+	id, err = client.SaveEvent("removed", event)
+	req.NoError(err)
+	req.Equal("gcal123", id)
+
+	req.NoError(client.DeleteEvent("gcal123"))
+
+	events := "/calendars/staff@group.calendar.google.com/events"
+	req.Equal([]string{
+		"POST " + events,
+		"PUT " + events + "/removed",
+		"POST " + events,
+		"DELETE " + events + "/gcal123",
+	}, calls)
+}
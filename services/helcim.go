@@ -2,33 +2,87 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gofrs/uuid"
 )
 
-// PaymentPlanCache provides thread-safe in-memory caching for payment plans
+// SharedCache is a key/value store shared by every instance of the app,
+// such as Redis (see pkg/redis)
+type SharedCache interface {
+	Get(key string) (string, bool, error)
+	Set(key, value string, ttl time.Duration) error
+}
+
+// paymentPlanSharedTTL is how long a plan stays in the shared cache. Plans
+// aren't removed from Helcim, so this only bounds how long a plan deleted by
+// hand keeps being offered.
+const paymentPlanSharedTTL = 7 * 24 * time.Hour
+
+// PaymentPlanCache provides thread-safe in-memory caching for payment plans.
+// With a shared cache set, plans created on one instance are reused by the
+// others instead of each creating its own copy in Helcim.
 type PaymentPlanCache struct {
-	mu    sync.RWMutex
-	items map[string]*PaymentPlan
+	mu     sync.RWMutex
+	items  map[string]*PaymentPlan
+	shared SharedCache
+}
+
+// UseShared keeps plans in a shared cache as well as in memory
+func (c *PaymentPlanCache) UseShared(shared SharedCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shared = shared
 }
 
 // Get retrieves a payment plan from the cache
 func (c *PaymentPlanCache) Get(key string) (*PaymentPlan, bool) {
 	c.mu.RLock()
-	defer c.mu.RUnlock()
 	plan, found := c.items[key]
-	return plan, found
+	shared := c.shared
+	c.mu.RUnlock()
+	if found || shared == nil {
+		return plan, found
+	}
+
+	value, found, err := shared.Get(paymentPlanSharedKey(key))
+	if err != nil || !found {
+		return nil, false
+	}
+	plan = &PaymentPlan{}
+	if err := json.Unmarshal([]byte(value), plan); err != nil {
+		return nil, false
+	}
+	c.setLocal(key, plan)
+	return plan, true
 }
 
 // Set stores a payment plan in the cache
 func (c *PaymentPlanCache) Set(key string, plan *PaymentPlan) {
+	c.setLocal(key, plan)
+
+	c.mu.RLock()
+	shared := c.shared
+	c.mu.RUnlock()
+	if shared == nil {
+		return
+	}
+	if data, err := json.Marshal(plan); err == nil {
+		// A failed write only means other instances create their own plan
+		_ = shared.Set(paymentPlanSharedKey(key), string(data), paymentPlanSharedTTL)
+	}
+}
+
+func (c *PaymentPlanCache) setLocal(key string, plan *PaymentPlan) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.items == nil {
@@ -37,6 +91,10 @@ func (c *PaymentPlanCache) Set(key string, plan *PaymentPlan) {
 	c.items[key] = plan
 }
 
+func paymentPlanSharedKey(key string) string {
+	return "payment_plan:" + key
+}
+
 // Global cache instance
 var paymentPlanCache = &PaymentPlanCache{}
 
@@ -45,15 +103,27 @@ func GetPaymentPlanCache() *PaymentPlanCache {
 	return paymentPlanCache
 }
 
-// HelcimAPI defines the methods used by the application
+// HelcimAPI defines the methods used by the application. Each takes the
+// context of the request or job it's made for: cancelling the context, or
+// passing its deadline, abandons the call, and a request ID carried by the
+// context is sent along to Helcim.
 type HelcimAPI interface {
-	ProcessPayment(req PaymentAPIRequest) (*PaymentAPIResponse, error)
-	CreatePaymentPlan(amount float64, planName string) (*PaymentPlan, error)
-	CreateSubscription(req SubscriptionRequest) (*SubscriptionResponse, error)
-	GetSubscription(subscriptionID string) (*SubscriptionResponse, error)
-	CancelSubscription(subscriptionID string) error
-	UpdateSubscription(subscriptionID string, updates map[string]interface{}) (*SubscriptionResponse, error)
-	ListSubscriptionsByCustomer(customerID string) ([]SubscriptionResponse, error)
+	ProcessPayment(ctx context.Context, req PaymentAPIRequest) (*PaymentAPIResponse, error)
+	GetTransaction(ctx context.Context, transactionID string) (*PaymentAPIResponse, error)
+	CreatePaymentPlan(ctx context.Context, amount float64, planName string, billing BillingPeriod) (*PaymentPlan, error)
+	CreateInstallmentPlan(ctx context.Context, amount float64, planName string, installments int) (*PaymentPlan, error)
+	CreateSubscription(ctx context.Context, req SubscriptionRequest) (*SubscriptionResponse, error)
+	GetSubscription(ctx context.Context, subscriptionID string) (*SubscriptionResponse, error)
+	CancelSubscription(ctx context.Context, subscriptionID string) error
+	UpdateSubscription(ctx context.Context, subscriptionID string, updates map[string]interface{}) (*SubscriptionResponse, error)
+	PauseSubscription(ctx context.Context, subscriptionID string) (*SubscriptionResponse, error)
+	ResumeSubscription(ctx context.Context, subscriptionID string) (*SubscriptionResponse, error)
+	ListSubscriptionsByCustomer(ctx context.Context, customerID string) ([]SubscriptionResponse, error)
+	ProcessSubscriptionPayment(ctx context.Context, subscriptionID string) (*PaymentAPIResponse, error)
+	RefundPayment(ctx context.Context, req RefundRequest) (*PaymentAPIResponse, error)
+	GetCustomer(ctx context.Context, customerCode string) (*HelcimCustomer, error)
+	CreateCustomer(ctx context.Context, req CustomerRequest) (*HelcimCustomer, error)
+	UpdateCustomer(ctx context.Context, customerID int, req CustomerRequest) (*HelcimCustomer, error)
 }
 
 // HelcimClient is the real implementation of HelcimAPI
@@ -61,6 +131,15 @@ type HelcimClient struct {
 	APIToken string
 	BaseURL  string
 	Client   *http.Client
+	// Retry is how requests that fail for a passing reason are tried again.
+	// The zero value sends each request once.
+	Retry HelcimRetryPolicy
+	// Breaker turns requests away while Helcim is down. Nil sends every
+	// request.
+	Breaker *HelcimBreaker
+
+	// sleep waits between retries, replaced in tests
+	sleep func(time.Duration)
 }
 
 // Payment API structures
@@ -69,7 +148,8 @@ type PaymentAPIRequest struct {
 	Amount         float64         `json:"amount"`
 	Currency       string          `json:"currency"`
 	CustomerCode   string          `json:"customerCode"`
-	CardData       CardData        `json:"cardData"`
+	CardData       CardData        `json:"cardData,omitzero"`
+	BankData       BankData        `json:"bankData,omitzero"`
 	IPAddress      string          `json:"ipAddress"`
 	InvoiceNumber  string          `json:"invoiceNumber,omitempty"`
 	Description    string          `json:"description,omitempty"`
@@ -78,10 +158,24 @@ type PaymentAPIRequest struct {
 	CustomerName   string          `json:"customerName,omitempty"`
 }
 
+// RefundRequest refunds all or part of an earlier purchase
+type RefundRequest struct {
+	OriginalTransactionID int     `json:"originalTransactionId"`
+	Amount                float64 `json:"amount"`
+	IPAddress             string  `json:"ipAddress"`
+}
+
 type CardData struct {
 	CardToken string `json:"cardToken"`
 }
 
+// BankData pays with a bank account (ACH) saved by HelcimPay.js. ACH
+// purchases are accepted straight away but take a few business days to
+// settle, which Helcim reports with a bankTransaction webhook.
+type BankData struct {
+	BankToken string `json:"bankToken"`
+}
+
 type PaymentAPIResponse struct {
 	TransactionID int     `json:"transactionId"`
 	Status        string  `json:"status"`
@@ -90,6 +184,19 @@ type PaymentAPIResponse struct {
 	CustomerCode  string  `json:"customerCode"`
 }
 
+// BillingPeriod is how often a payment plan bills: every Increments of Period
+type BillingPeriod struct {
+	Label      string // how the period reads in plan descriptions
+	Period     string // Helcim billingPeriod
+	Increments int    // Helcim billingPeriodIncrements
+}
+
+var (
+	BillMonthly   = BillingPeriod{Label: "Monthly", Period: "monthly", Increments: 1}
+	BillQuarterly = BillingPeriod{Label: "Quarterly", Period: "monthly", Increments: 3}
+	BillAnnually  = BillingPeriod{Label: "Annual", Period: "yearly", Increments: 1}
+)
+
 // Recurring API structures
 type PaymentPlan struct {
 	ID                      int     `json:"id"`
@@ -109,18 +216,62 @@ type PaymentPlan struct {
 type CustomerRequest struct {
 	ContactName    string         `json:"contactName"`
 	Email          string         `json:"email"`
+	CellPhone      string         `json:"cellPhone,omitempty"`
+	CustomerCode   string         `json:"customerCode,omitempty"`
 	BillingAddress BillingAddress `json:"billingAddress"`
 }
 
 type BillingAddress struct {
 	Name       string `json:"name"`
 	Street1    string `json:"street1"`
+	Street2    string `json:"street2,omitempty"`
 	City       string `json:"city"`
 	Province   string `json:"province"`
 	Country    string `json:"country"`
 	PostalCode string `json:"postalCode"`
 }
 
+// HelcimCustomer is a customer record in Helcim, with the cards saved to it
+type HelcimCustomer struct {
+	ID             int            `json:"id"`
+	CustomerCode   string         `json:"customerCode"`
+	ContactName    string         `json:"contactName"`
+	CellPhone      string         `json:"cellPhone"`
+	BillingAddress BillingAddress `json:"billingAddress"`
+	Cards          []HelcimCard   `json:"cards,omitempty"`
+}
+
+// HelcimCard is a card saved to a Helcim customer. CardExpiry is MMYY.
+type HelcimCard struct {
+	ID         int    `json:"id"`
+	CardToken  string `json:"cardToken"`
+	CardF6L4   string `json:"cardF6L4"`
+	CardExpiry string `json:"cardExpiry"`
+	Default    bool   `json:"default"`
+}
+
+// DefaultCard is the card Helcim charges the customer's subscriptions to,
+// or nil when none is set
+func (c HelcimCustomer) DefaultCard() *HelcimCard {
+	for i := range c.Cards {
+		if c.Cards[i].Default {
+			return &c.Cards[i]
+		}
+	}
+	return nil
+}
+
+// Expired reports whether the card's expiry month has passed by now. A card
+// whose expiry can't be read counts as expired.
+func (c HelcimCard) Expired(now time.Time) bool {
+	expiry, err := time.Parse("0106", c.CardExpiry)
+	if err != nil {
+		return true
+	}
+	// Cards are good through the end of their expiry month
+	return !now.Before(expiry.AddDate(0, 1, 0))
+}
+
 type SubscriptionRequest struct {
 	CustomerID    string  `json:"customerId"`
 	PaymentPlanID int     `json:"paymentPlanId"`
@@ -174,11 +325,13 @@ func NewHelcimClient() HelcimAPI {
 		Client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		Retry:   helcimRetryPolicyFromEnv(),
+		Breaker: defaultHelcimBreaker(),
 	}
 }
 
 // ProcessPayment processes a one-time payment using the Payment API
-func (h *HelcimClient) ProcessPayment(req PaymentAPIRequest) (*PaymentAPIResponse, error) {
+func (h *HelcimClient) ProcessPayment(ctx context.Context, req PaymentAPIRequest) (*PaymentAPIResponse, error) {
 	url := fmt.Sprintf("%s/payment/purchase", h.BaseURL)
 
 	// Generate UUID v4 idempotency key as required by Helcim API
@@ -201,7 +354,7 @@ func (h *HelcimClient) ProcessPayment(req PaymentAPIRequest) (*PaymentAPIRespons
 	// Debug: log the JSON being sent to Helcim
 	fmt.Printf("[Helcim] Payment API request JSON: %s\n", string(jsonData))
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -210,7 +363,7 @@ func (h *HelcimClient) ProcessPayment(req PaymentAPIRequest) (*PaymentAPIRespons
 	httpReq.Header.Set("api-token", h.APIToken)
 	httpReq.Header.Set("Idempotency-Key", idempotencyKey) // Required by Helcim API
 
-	resp, err := h.Client.Do(httpReq)
+	resp, err := h.send(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -220,7 +373,7 @@ func (h *HelcimClient) ProcessPayment(req PaymentAPIRequest) (*PaymentAPIRespons
 		// Debug: read and log the error response
 		body, _ := io.ReadAll(resp.Body)
 		fmt.Printf("[Helcim] Payment API error response: %s\n", string(body))
-		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		return nil, newHelcimError(resp.StatusCode, body)
 	}
 
 	var result PaymentAPIResponse
@@ -231,8 +384,58 @@ func (h *HelcimClient) ProcessPayment(req PaymentAPIRequest) (*PaymentAPIRespons
 	return &result, nil
 }
 
-// CreatePaymentPlan creates a new payment plan for recurring donations
-func (h *HelcimClient) CreatePaymentPlan(amount float64, planName string) (*PaymentPlan, error) {
+// GetTransaction looks up a card transaction, for settling a donation
+// whose webhook hasn't arrived
+func (h *HelcimClient) GetTransaction(ctx context.Context, transactionID string) (*PaymentAPIResponse, error) {
+	var result PaymentAPIResponse
+	if err := h.getJSON(ctx, fmt.Sprintf("%s/card-transactions/%s", h.BaseURL, url.PathEscape(transactionID)), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreatePaymentPlan creates a new open-ended payment plan for recurring
+// donations, billing amount every billing period
+func (h *HelcimClient) CreatePaymentPlan(ctx context.Context, amount float64, planName string, billing BillingPeriod) (*PaymentPlan, error) {
+	// Create payment plan request according to Helcim API docs
+	return h.postPaymentPlan(ctx, map[string]interface{}{
+		"name":                    planName,
+		"description":             fmt.Sprintf("%s donation plan for $%.2f", billing.Label, amount),
+		"type":                    "subscription", // Bill on sign-up
+		"currency":                "USD",
+		"recurringAmount":         amount,
+		"billingPeriod":           billing.Period,
+		"billingPeriodIncrements": billing.Increments,
+		"dateBilling":             "Sign-up",
+		"termType":                "forever", // Indefinite billing
+		"paymentMethod":           "card",
+		"taxType":                 "no_tax",
+		"status":                  "active",
+	})
+}
+
+// CreateInstallmentPlan creates a fixed-term plan that bills amount monthly
+// and stops after the given number of installments.
+func (h *HelcimClient) CreateInstallmentPlan(ctx context.Context, amount float64, planName string, installments int) (*PaymentPlan, error) {
+	return h.postPaymentPlan(ctx, map[string]interface{}{
+		"name":                    planName,
+		"description":             fmt.Sprintf("%d monthly installments of $%.2f", installments, amount),
+		"type":                    "subscription", // First installment bills on sign-up
+		"currency":                "USD",
+		"recurringAmount":         amount,
+		"billingPeriod":           "monthly",
+		"billingPeriodIncrements": 1,
+		"dateBilling":             "Sign-up",
+		"termType":                "fixed",
+		"termLength":              installments,
+		"paymentMethod":           "card",
+		"taxType":                 "no_tax",
+		"status":                  "active",
+	})
+}
+
+// postPaymentPlan creates a single payment plan with the Recurring API.
+func (h *HelcimClient) postPaymentPlan(ctx context.Context, plan map[string]interface{}) (*PaymentPlan, error) {
 	url := fmt.Sprintf("%s/payment-plans", h.BaseURL) // BaseURL already includes v2
 
 	// Generate UUID v4 idempotency key as required by Helcim API
@@ -242,24 +445,8 @@ func (h *HelcimClient) CreatePaymentPlan(amount float64, planName string) (*Paym
 	}
 	idempotencyKey := idempotencyUUID.String()
 
-	// Create payment plan request according to Helcim API docs
 	request := map[string]interface{}{
-		"paymentPlans": []map[string]interface{}{
-			{
-				"name":                    planName,
-				"description":             fmt.Sprintf("Monthly donation plan for $%.2f", amount),
-				"type":                    "subscription", // Bill on sign-up
-				"currency":                "USD",
-				"recurringAmount":         amount,
-				"billingPeriod":           "monthly",
-				"billingPeriodIncrements": 1,
-				"dateBilling":             "Sign-up",
-				"termType":                "forever", // Indefinite billing
-				"paymentMethod":           "card",
-				"taxType":                 "no_tax",
-				"status":                  "active",
-			},
-		},
+		"paymentPlans": []map[string]interface{}{plan},
 	}
 
 	// Log the request for debugging
@@ -271,7 +458,7 @@ func (h *HelcimClient) CreatePaymentPlan(amount float64, planName string) (*Paym
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -280,7 +467,7 @@ func (h *HelcimClient) CreatePaymentPlan(amount float64, planName string) (*Paym
 	httpReq.Header.Set("api-token", h.APIToken)
 	httpReq.Header.Set("Idempotency-Key", idempotencyKey) // Required by Helcim API
 
-	resp, err := h.Client.Do(httpReq)
+	resp, err := h.send(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -288,7 +475,7 @@ func (h *HelcimClient) CreatePaymentPlan(amount float64, planName string) (*Paym
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newHelcimError(resp.StatusCode, body)
 	}
 
 	// Parse response - Helcim may return array or single object
@@ -330,7 +517,7 @@ func (h *HelcimClient) CreatePaymentPlan(amount float64, planName string) (*Paym
 }
 
 // CreateSubscription creates a new subscription using the Recurring API
-func (h *HelcimClient) CreateSubscription(req SubscriptionRequest) (*SubscriptionResponse, error) {
+func (h *HelcimClient) CreateSubscription(ctx context.Context, req SubscriptionRequest) (*SubscriptionResponse, error) {
 	url := fmt.Sprintf("%s/subscriptions", h.BaseURL) // BaseURL already includes v2
 
 	// Generate UUID v4 idempotency key as required by Helcim API
@@ -362,7 +549,7 @@ func (h *HelcimClient) CreateSubscription(req SubscriptionRequest) (*Subscriptio
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -371,7 +558,7 @@ func (h *HelcimClient) CreateSubscription(req SubscriptionRequest) (*Subscriptio
 	httpReq.Header.Set("api-token", h.APIToken)
 	httpReq.Header.Set("Idempotency-Key", idempotencyKey) // Required by Helcim API
 
-	resp, err := h.Client.Do(httpReq)
+	resp, err := h.send(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -379,7 +566,7 @@ func (h *HelcimClient) CreateSubscription(req SubscriptionRequest) (*Subscriptio
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newHelcimError(resp.StatusCode, body)
 	}
 
 	// Read response body
@@ -411,10 +598,10 @@ func (h *HelcimClient) CreateSubscription(req SubscriptionRequest) (*Subscriptio
 }
 
 // GetSubscription retrieves a subscription by ID
-func (h *HelcimClient) GetSubscription(subscriptionID string) (*SubscriptionResponse, error) {
+func (h *HelcimClient) GetSubscription(ctx context.Context, subscriptionID string) (*SubscriptionResponse, error) {
 	url := fmt.Sprintf("%s/subscriptions/%s", h.BaseURL, subscriptionID)
 
-	httpReq, err := http.NewRequest("GET", url, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -422,7 +609,7 @@ func (h *HelcimClient) GetSubscription(subscriptionID string) (*SubscriptionResp
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("api-token", h.APIToken)
 
-	resp, err := h.Client.Do(httpReq)
+	resp, err := h.send(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -430,7 +617,7 @@ func (h *HelcimClient) GetSubscription(subscriptionID string) (*SubscriptionResp
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newHelcimError(resp.StatusCode, body)
 	}
 
 	var result SubscriptionResponse
@@ -442,10 +629,10 @@ func (h *HelcimClient) GetSubscription(subscriptionID string) (*SubscriptionResp
 }
 
 // CancelSubscription cancels a subscription by ID
-func (h *HelcimClient) CancelSubscription(subscriptionID string) error {
+func (h *HelcimClient) CancelSubscription(ctx context.Context, subscriptionID string) error {
 	url := fmt.Sprintf("%s/subscriptions/%s", h.BaseURL, subscriptionID)
 
-	httpReq, err := http.NewRequest("DELETE", url, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -453,7 +640,7 @@ func (h *HelcimClient) CancelSubscription(subscriptionID string) error {
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("api-token", h.APIToken)
 
-	resp, err := h.Client.Do(httpReq)
+	resp, err := h.send(httpReq)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -461,14 +648,14 @@ func (h *HelcimClient) CancelSubscription(subscriptionID string) error {
 
 	if resp.StatusCode != http.StatusNoContent {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return newHelcimError(resp.StatusCode, body)
 	}
 
 	return nil
 }
 
 // UpdateSubscription updates a subscription's details
-func (h *HelcimClient) UpdateSubscription(subscriptionID string, updates map[string]interface{}) (*SubscriptionResponse, error) {
+func (h *HelcimClient) UpdateSubscription(ctx context.Context, subscriptionID string, updates map[string]interface{}) (*SubscriptionResponse, error) {
 	url := fmt.Sprintf("%s/subscriptions", h.BaseURL)
 
 	// Add the subscription ID to the updates
@@ -488,7 +675,7 @@ func (h *HelcimClient) UpdateSubscription(subscriptionID string, updates map[str
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -496,7 +683,7 @@ func (h *HelcimClient) UpdateSubscription(subscriptionID string, updates map[str
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("api-token", h.APIToken)
 
-	resp, err := h.Client.Do(httpReq)
+	resp, err := h.send(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -504,7 +691,7 @@ func (h *HelcimClient) UpdateSubscription(subscriptionID string, updates map[str
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newHelcimError(resp.StatusCode, body)
 	}
 
 	// Parse response - Helcim returns array of updated subscriptions
@@ -520,11 +707,22 @@ func (h *HelcimClient) UpdateSubscription(subscriptionID string, updates map[str
 	return &responseData[0], nil
 }
 
+// PauseSubscription stops billing a subscription until it's resumed
+func (h *HelcimClient) PauseSubscription(ctx context.Context, subscriptionID string) (*SubscriptionResponse, error) {
+	return h.UpdateSubscription(ctx, subscriptionID, map[string]interface{}{"status": "paused"})
+}
+
+// ResumeSubscription restarts billing a paused subscription from its next
+// billing date
+func (h *HelcimClient) ResumeSubscription(ctx context.Context, subscriptionID string) (*SubscriptionResponse, error) {
+	return h.UpdateSubscription(ctx, subscriptionID, map[string]interface{}{"status": "active"})
+}
+
 // ListSubscriptionsByCustomer retrieves all subscriptions for a customer
-func (h *HelcimClient) ListSubscriptionsByCustomer(customerID string) ([]SubscriptionResponse, error) {
+func (h *HelcimClient) ListSubscriptionsByCustomer(ctx context.Context, customerID string) ([]SubscriptionResponse, error) {
 	url := fmt.Sprintf("%s/subscriptions?customerId=%s", h.BaseURL, customerID)
 
-	httpReq, err := http.NewRequest("GET", url, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -532,7 +730,7 @@ func (h *HelcimClient) ListSubscriptionsByCustomer(customerID string) ([]Subscri
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("api-token", h.APIToken)
 
-	resp, err := h.Client.Do(httpReq)
+	resp, err := h.send(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -540,7 +738,7 @@ func (h *HelcimClient) ListSubscriptionsByCustomer(customerID string) ([]Subscri
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newHelcimError(resp.StatusCode, body)
 	}
 
 	var result []SubscriptionResponse
@@ -551,10 +749,190 @@ func (h *HelcimClient) ListSubscriptionsByCustomer(customerID string) ([]Subscri
 	return result, nil
 }
 
+// ProcessSubscriptionPayment charges a subscription's stored card for its
+// current recurring amount now, outside the normal billing cycle. Dunning uses
+// it to retry a payment that failed.
+func (h *HelcimClient) ProcessSubscriptionPayment(ctx context.Context, subscriptionID string) (*PaymentAPIResponse, error) {
+	url := fmt.Sprintf("%s/subscriptions/%s/process-payment", h.BaseURL, subscriptionID)
+
+	idempotencyUUID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("api-token", h.APIToken)
+	httpReq.Header.Set("Idempotency-Key", idempotencyUUID.String())
+
+	resp, err := h.send(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newHelcimError(resp.StatusCode, body)
+	}
+
+	var result PaymentAPIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// RefundPayment refunds a purchase through the Payment API
+func (h *HelcimClient) RefundPayment(ctx context.Context, req RefundRequest) (*PaymentAPIResponse, error) {
+	url := fmt.Sprintf("%s/payment/refund", h.BaseURL)
+
+	idempotencyUUID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("api-token", h.APIToken)
+	httpReq.Header.Set("Idempotency-Key", idempotencyUUID.String())
+
+	resp, err := h.send(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newHelcimError(resp.StatusCode, body)
+	}
+
+	var result PaymentAPIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// GetCustomer looks up a customer by their customer code, along with their
+// saved cards. It returns nil when Helcim has no customer with that code.
+func (h *HelcimClient) GetCustomer(ctx context.Context, customerCode string) (*HelcimCustomer, error) {
+	var customers []HelcimCustomer
+	if err := h.getJSON(ctx, fmt.Sprintf("%s/customers?customerCode=%s", h.BaseURL, url.QueryEscape(customerCode)), &customers); err != nil {
+		return nil, err
+	}
+	for i := range customers {
+		// The search is a partial match, so CST12 also finds CST123
+		if customers[i].CustomerCode != customerCode {
+			continue
+		}
+		customer := &customers[i]
+		if err := h.getJSON(ctx, fmt.Sprintf("%s/customers/%d/cards", h.BaseURL, customer.ID), &customer.Cards); err != nil {
+			return nil, err
+		}
+		return customer, nil
+	}
+	return nil, nil
+}
+
+// CreateCustomer adds a customer to Helcim
+func (h *HelcimClient) CreateCustomer(ctx context.Context, req CustomerRequest) (*HelcimCustomer, error) {
+	return h.sendCustomer(ctx, "POST", fmt.Sprintf("%s/customers", h.BaseURL), req)
+}
+
+// UpdateCustomer replaces a Helcim customer's contact details and billing
+// address
+func (h *HelcimClient) UpdateCustomer(ctx context.Context, customerID int, req CustomerRequest) (*HelcimCustomer, error) {
+	return h.sendCustomer(ctx, "PUT", fmt.Sprintf("%s/customers/%d", h.BaseURL, customerID), req)
+}
+
+// getJSON fetches url from the Helcim API and decodes the response into out
+func (h *HelcimClient) getJSON(ctx context.Context, url string, out interface{}) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("api-token", h.APIToken)
+
+	resp, err := h.send(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return newHelcimError(resp.StatusCode, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// sendCustomer creates or updates a customer and returns Helcim's copy
+func (h *HelcimClient) sendCustomer(ctx context.Context, method, url string, req CustomerRequest) (*HelcimCustomer, error) {
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("api-token", h.APIToken)
+
+	resp, err := h.send(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newHelcimError(resp.StatusCode, body)
+	}
+
+	var result HelcimCustomer
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
 // mockHelcimClient implements HelcimAPI for development/testing
 type mockHelcimClient struct{}
 
-func (m *mockHelcimClient) ProcessPayment(req PaymentAPIRequest) (*PaymentAPIResponse, error) {
+// NewMockHelcimClient returns a client that approves everything without
+// calling Helcim, for synthetic checks that must never move money.
+func NewMockHelcimClient() HelcimAPI {
+	return &mockHelcimClient{}
+}
+
+func (m *mockHelcimClient) ProcessPayment(ctx context.Context, req PaymentAPIRequest) (*PaymentAPIResponse, error) {
 	// Simulate an approved transaction
 	return &PaymentAPIResponse{
 		TransactionID: int(time.Now().UnixNano() % 1000000000), // Generate a mock integer ID
@@ -565,20 +943,44 @@ func (m *mockHelcimClient) ProcessPayment(req PaymentAPIRequest) (*PaymentAPIRes
 	}, nil
 }
 
-func (m *mockHelcimClient) CreatePaymentPlan(amount float64, planName string) (*PaymentPlan, error) {
+func (m *mockHelcimClient) GetTransaction(ctx context.Context, transactionID string) (*PaymentAPIResponse, error) {
+	id, _ := strconv.Atoi(transactionID)
+	return &PaymentAPIResponse{
+		TransactionID: id,
+		Status:        "APPROVED",
+		Currency:      "USD",
+	}, nil
+}
+
+func (m *mockHelcimClient) CreatePaymentPlan(ctx context.Context, amount float64, planName string, billing BillingPeriod) (*PaymentPlan, error) {
+	return &PaymentPlan{
+		ID:                      int(time.Now().Unix() % 1000000),
+		Name:                    planName,
+		Description:             fmt.Sprintf("Dev plan for $%.2f", amount),
+		Type:                    "subscription",
+		Currency:                "USD",
+		RecurringAmount:         amount,
+		BillingPeriod:           billing.Period,
+		BillingPeriodIncrements: billing.Increments,
+		Status:                  "active",
+	}, nil
+}
+
+func (m *mockHelcimClient) CreateInstallmentPlan(ctx context.Context, amount float64, planName string, installments int) (*PaymentPlan, error) {
 	return &PaymentPlan{
 		ID:              int(time.Now().Unix() % 1000000),
 		Name:            planName,
-		Description:     fmt.Sprintf("Dev plan for $%.2f", amount),
+		Description:     fmt.Sprintf("Dev plan for %d x $%.2f", installments, amount),
 		Type:            "subscription",
 		Currency:        "USD",
 		RecurringAmount: amount,
 		BillingPeriod:   "monthly",
+		TermType:        "fixed",
 		Status:          "active",
 	}, nil
 }
 
-func (m *mockHelcimClient) CreateSubscription(req SubscriptionRequest) (*SubscriptionResponse, error) {
+func (m *mockHelcimClient) CreateSubscription(ctx context.Context, req SubscriptionRequest) (*SubscriptionResponse, error) {
 	return &SubscriptionResponse{
 		ID:              int(time.Now().Unix() % 1000000),
 		CustomerID:      req.CustomerID,
@@ -591,7 +993,7 @@ func (m *mockHelcimClient) CreateSubscription(req SubscriptionRequest) (*Subscri
 	}, nil
 }
 
-func (m *mockHelcimClient) GetSubscription(subscriptionID string) (*SubscriptionResponse, error) {
+func (m *mockHelcimClient) GetSubscription(ctx context.Context, subscriptionID string) (*SubscriptionResponse, error) {
 	// Return a simulated active subscription
 	now := time.Now()
 	return &SubscriptionResponse{
@@ -606,12 +1008,12 @@ func (m *mockHelcimClient) GetSubscription(subscriptionID string) (*Subscription
 	}, nil
 }
 
-func (m *mockHelcimClient) CancelSubscription(subscriptionID string) error {
+func (m *mockHelcimClient) CancelSubscription(ctx context.Context, subscriptionID string) error {
 	// Simulate success
 	return nil
 }
 
-func (m *mockHelcimClient) UpdateSubscription(subscriptionID string, updates map[string]interface{}) (*SubscriptionResponse, error) {
+func (m *mockHelcimClient) UpdateSubscription(ctx context.Context, subscriptionID string, updates map[string]interface{}) (*SubscriptionResponse, error) {
 	// Simulate returning an updated subscription
 	sub := &SubscriptionResponse{
 		ID:              123456,
@@ -626,7 +1028,17 @@ func (m *mockHelcimClient) UpdateSubscription(subscriptionID string, updates map
 	return sub, nil
 }
 
-func (m *mockHelcimClient) ListSubscriptionsByCustomer(customerID string) ([]SubscriptionResponse, error) {
+func (m *mockHelcimClient) PauseSubscription(ctx context.Context, subscriptionID string) (*SubscriptionResponse, error) {
+	sub, err := m.UpdateSubscription(ctx, subscriptionID, nil)
+	sub.Status = "paused"
+	return sub, err
+}
+
+func (m *mockHelcimClient) ResumeSubscription(ctx context.Context, subscriptionID string) (*SubscriptionResponse, error) {
+	return m.UpdateSubscription(ctx, subscriptionID, nil)
+}
+
+func (m *mockHelcimClient) ListSubscriptionsByCustomer(ctx context.Context, customerID string) ([]SubscriptionResponse, error) {
 	now := time.Now()
 	return []SubscriptionResponse{
 		{
@@ -641,3 +1053,56 @@ func (m *mockHelcimClient) ListSubscriptionsByCustomer(customerID string) ([]Sub
 		},
 	}, nil
 }
+
+func (m *mockHelcimClient) ProcessSubscriptionPayment(ctx context.Context, subscriptionID string) (*PaymentAPIResponse, error) {
+	return &PaymentAPIResponse{
+		TransactionID: int(time.Now().UnixNano() % 1000000000),
+		Status:        "APPROVED",
+		Amount:        10.00,
+		Currency:      "USD",
+	}, nil
+}
+
+func (m *mockHelcimClient) RefundPayment(ctx context.Context, req RefundRequest) (*PaymentAPIResponse, error) {
+	return &PaymentAPIResponse{
+		TransactionID: int(time.Now().UnixNano() % 1000000000),
+		Status:        "APPROVED",
+		Amount:        req.Amount,
+		Currency:      "USD",
+	}, nil
+}
+
+func (m *mockHelcimClient) GetCustomer(ctx context.Context, customerCode string) (*HelcimCustomer, error) {
+	return &HelcimCustomer{
+		ID:           123456,
+		CustomerCode: customerCode,
+		ContactName:  "Dev Customer",
+		Cards: []HelcimCard{
+			{ID: 1, CardToken: "dev_card_token", CardF6L4: "4242424242", CardExpiry: time.Now().AddDate(2, 0, 0).Format("0106"), Default: true},
+		},
+	}, nil
+}
+
+func (m *mockHelcimClient) CreateCustomer(ctx context.Context, req CustomerRequest) (*HelcimCustomer, error) {
+	code := req.CustomerCode
+	if code == "" {
+		code = fmt.Sprintf("CST%d", time.Now().Unix()%1000000)
+	}
+	return &HelcimCustomer{
+		ID:             int(time.Now().Unix() % 1000000),
+		CustomerCode:   code,
+		ContactName:    req.ContactName,
+		CellPhone:      req.CellPhone,
+		BillingAddress: req.BillingAddress,
+	}, nil
+}
+
+func (m *mockHelcimClient) UpdateCustomer(ctx context.Context, customerID int, req CustomerRequest) (*HelcimCustomer, error) {
+	return &HelcimCustomer{
+		ID:             customerID,
+		CustomerCode:   req.CustomerCode,
+		ContactName:    req.ContactName,
+		CellPhone:      req.CellPhone,
+		BillingAddress: req.BillingAddress,
+	}, nil
+}
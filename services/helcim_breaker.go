@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HelcimCircuitOpen is the HelcimError.Code for a request that wasn't sent
+// because Helcim has been failing, so the donor hears straight away instead
+// of waiting out a timeout
+const HelcimCircuitOpen = "circuit_open"
+
+// HelcimBreaker stops calling Helcim after Threshold requests in a row fail
+// with an outage or no response. For Cooldown every request then fails
+// fast; after that one request at a time is let through to see whether
+// Helcim is back, and the first to succeed closes the breaker again.
+type HelcimBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+	now       func() time.Time // replaced in tests
+}
+
+// helcimBreakerOutcome is how a request went, as far as the breaker cares
+type helcimBreakerOutcome int
+
+const (
+	helcimBreakerSuccess helcimBreakerOutcome = iota
+	helcimBreakerFailure
+	// helcimBreakerAbandoned is a request our side gave up on, which says
+	// nothing about Helcim
+	helcimBreakerAbandoned
+)
+
+var (
+	sharedHelcimBreaker     *HelcimBreaker
+	sharedHelcimBreakerOnce sync.Once
+)
+
+// defaultHelcimBreaker is the breaker every client from NewHelcimClient
+// shares, so one request's failures spare the next donor the wait.
+// HELCIM_BREAKER_THRESHOLD sets the failures in a row that trip it
+// (default 5) and HELCIM_BREAKER_COOLDOWN_SECONDS how long it stays open
+// (default 30).
+func defaultHelcimBreaker() *HelcimBreaker {
+	sharedHelcimBreakerOnce.Do(func() {
+		sharedHelcimBreaker = &HelcimBreaker{Threshold: 5, Cooldown: 30 * time.Second}
+		if n, err := strconv.Atoi(os.Getenv("HELCIM_BREAKER_THRESHOLD")); err == nil && n > 0 {
+			sharedHelcimBreaker.Threshold = n
+		}
+		if n, err := strconv.Atoi(os.Getenv("HELCIM_BREAKER_COOLDOWN_SECONDS")); err == nil && n > 0 {
+			sharedHelcimBreaker.Cooldown = time.Duration(n) * time.Second
+		}
+	})
+	return sharedHelcimBreaker
+}
+
+func (b *HelcimBreaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// Open reports whether requests are currently being turned away
+func (b *HelcimBreaker) Open() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.Threshold && (b.clock().Before(b.openUntil) || b.probing)
+}
+
+// allow reports whether a request may be sent. Once the cooldown is over it
+// lets a single request through to test Helcim, and turns the rest away
+// until that request finishes.
+func (b *HelcimBreaker) allow() bool {
+	if b == nil || b.Threshold < 1 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.Threshold {
+		return true
+	}
+	if b.clock().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record counts how a request that allow let through went
+func (b *HelcimBreaker) record(outcome helcimBreakerOutcome) {
+	if b == nil || b.Threshold < 1 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	switch outcome {
+	case helcimBreakerSuccess:
+		if b.failures >= b.Threshold {
+			fmt.Printf("[Helcim] Circuit closed: Helcim is answering again\n")
+		}
+		b.failures = 0
+	case helcimBreakerFailure:
+		b.failures++
+		if b.failures >= b.Threshold {
+			b.openUntil = b.clock().Add(b.Cooldown)
+			fmt.Printf("[Helcim] Circuit open after %d failed requests in a row, failing fast for %s\n", b.failures, b.Cooldown)
+		}
+	}
+}
+
+// errHelcimCircuitOpen is returned in place of a request the breaker
+// turned away
+func errHelcimCircuitOpen() *HelcimError {
+	return &HelcimError{
+		StatusCode: http.StatusServiceUnavailable,
+		Code:       HelcimCircuitOpen,
+		Message:    "payment system temporarily unavailable",
+		Retryable:  true,
+	}
+}
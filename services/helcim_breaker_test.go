@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelcimBreaker_TripsAndRecovers(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	b := &HelcimBreaker{Threshold: 3, Cooldown: 30 * time.Second, now: func() time.Time { return now }}
+
+	for i := 0; i < 2; i++ {
+		require.True(t, b.allow())
+		b.record(helcimBreakerFailure)
+	}
+	// A success in between starts the count again
+	require.True(t, b.allow())
+	b.record(helcimBreakerSuccess)
+	for i := 0; i < 3; i++ {
+		require.True(t, b.allow())
+		b.record(helcimBreakerFailure)
+	}
+	assert.True(t, b.Open())
+	assert.False(t, b.allow())
+
+	// After the cooldown one request tests Helcim while the rest wait
+	now = now.Add(31 * time.Second)
+	assert.True(t, b.allow())
+	assert.False(t, b.allow())
+	b.record(helcimBreakerFailure)
+	assert.False(t, b.allow(), "a failed test reopens the breaker")
+
+	now = now.Add(31 * time.Second)
+	assert.True(t, b.allow())
+	b.record(helcimBreakerSuccess)
+	assert.False(t, b.Open())
+	assert.True(t, b.allow())
+}
+
+func TestHelcimBreaker_AbandonedRequestsDontCount(t *testing.T) {
+	b := &HelcimBreaker{Threshold: 1, Cooldown: time.Minute}
+	require.True(t, b.allow())
+	b.record(helcimBreakerAbandoned)
+	assert.False(t, b.Open())
+
+	var nilBreaker *HelcimBreaker
+	assert.True(t, nilBreaker.allow())
+	assert.False(t, nilBreaker.Open())
+}
+
+func TestHelcimClient_FailsFastWhileBreakerOpen(t *testing.T) {
+	var calls int32
+	down := int32(1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(SubscriptionResponse{ID: 123, Status: "active"})
+	}))
+	defer server.Close()
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	client := &HelcimClient{
+		APIToken: "test-token",
+		BaseURL:  server.URL,
+		Client:   server.Client(),
+		Breaker:  &HelcimBreaker{Threshold: 2, Cooldown: 30 * time.Second, now: func() time.Time { return now }},
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetSubscription(context.Background(), "123")
+		require.Error(t, err)
+	}
+	_, err := client.GetSubscription(context.Background(), "123")
+	helcimErr, ok := AsHelcimError(err)
+	require.True(t, ok)
+	assert.Equal(t, HelcimCircuitOpen, helcimErr.Code)
+	assert.True(t, IsHelcimRetryable(err))
+	assert.EqualValues(t, 2, calls, "no request is sent while the breaker is open")
+
+	atomic.StoreInt32(&down, 0)
+	now = now.Add(time.Minute)
+	sub, err := client.GetSubscription(context.Background(), "123")
+	require.NoError(t, err)
+	assert.Equal(t, "active", sub.Status)
+	assert.False(t, client.Breaker.Open())
+}
+
+func TestHelcimClient_BreakerStopsRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	var waits []time.Duration
+	client := retryingClient(server, 5, &waits)
+	client.Breaker = &HelcimBreaker{Threshold: 2, Cooldown: time.Minute}
+
+	_, err := client.GetSubscription(context.Background(), "123")
+	helcimErr, ok := AsHelcimError(err)
+	require.True(t, ok)
+	assert.Equal(t, HelcimCircuitOpen, helcimErr.Code)
+	assert.EqualValues(t, 2, calls)
+}
@@ -0,0 +1,29 @@
+package services
+
+import "context"
+
+// HelcimRequestIDHeader carries the ID of the request or job a Helcim call
+// was made for, so a call in Helcim's logs can be matched with ours
+const HelcimRequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key WithRequestID stores under
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying id, for calls made outside a
+// Buffalo request, such as from a background job
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext is the request ID carried by ctx: one set with
+// WithRequestID, or the request_id Buffalo gives each request. It's empty
+// when there's neither.
+func RequestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	if id, ok := ctx.Value("request_id").(string); ok {
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Kinds of Helcim API failure, as HelcimError.Code
+const (
+	// HelcimDeclined is a card or bank payment the issuer turned down
+	HelcimDeclined = "declined"
+	// HelcimInvalidRequest is a request Helcim rejected as malformed, such
+	// as a missing field or an unknown customer code
+	HelcimInvalidRequest = "invalid_request"
+	// HelcimAuthFailed means our API token was refused
+	HelcimAuthFailed = "auth_failed"
+	// HelcimNotFound is a subscription, customer or transaction Helcim
+	// doesn't have
+	HelcimNotFound = "not_found"
+	// HelcimRateLimited means we sent too many requests too quickly
+	HelcimRateLimited = "rate_limited"
+	// HelcimUnavailable is an error on Helcim's side
+	HelcimUnavailable = "unavailable"
+)
+
+// HelcimError is an error response from the Helcim API. Retryable is set
+// when the same request may succeed later without changes.
+type HelcimError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Retryable  bool
+}
+
+func (e *HelcimError) Error() string {
+	return fmt.Sprintf("helcim %s (status %d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+// declineWords mark a 4xx response as a payment the issuer refused rather
+// than a request we got wrong
+var declineWords = []string{"declin", "insufficient", "do not honor", "expired card", "card expired", "invalid card", "cvv", "avs"}
+
+// newHelcimError reads Helcim's error payload from body. Helcim sends
+// "errors" as a string, a list, or an object of messages by field.
+func newHelcimError(statusCode int, body []byte) *HelcimError {
+	e := &HelcimError{StatusCode: statusCode, Message: helcimErrorMessage(body)}
+	if e.Message == "" {
+		e.Message = http.StatusText(statusCode)
+	}
+
+	lower := strings.ToLower(e.Message)
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		e.Code = HelcimAuthFailed
+	case statusCode == http.StatusTooManyRequests:
+		e.Code, e.Retryable = HelcimRateLimited, true
+	case statusCode >= 500:
+		e.Code, e.Retryable = HelcimUnavailable, true
+	case statusCode == http.StatusNotFound:
+		e.Code = HelcimNotFound
+	default:
+		e.Code = HelcimInvalidRequest
+		for _, word := range declineWords {
+			if strings.Contains(lower, word) {
+				e.Code = HelcimDeclined
+				break
+			}
+		}
+	}
+	return e
+}
+
+// helcimErrorMessage pulls the messages out of an error payload, or returns
+// the body itself when it isn't one
+func helcimErrorMessage(body []byte) string {
+	var payload struct {
+		Errors  json.RawMessage `json:"errors"`
+		Message string          `json:"message"`
+		Error   string          `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+
+	var text string
+	var list []string
+	var fields map[string]interface{}
+	switch {
+	case json.Unmarshal(payload.Errors, &text) == nil && text != "":
+		return text
+	case json.Unmarshal(payload.Errors, &list) == nil && len(list) > 0:
+		return strings.Join(list, "; ")
+	case json.Unmarshal(payload.Errors, &fields) == nil && len(fields) > 0:
+		messages := make([]string, 0, len(fields))
+		for field, message := range fields {
+			messages = append(messages, fmt.Sprintf("%s: %v", field, message))
+		}
+		sort.Strings(messages)
+		return strings.Join(messages, "; ")
+	case payload.Message != "":
+		return payload.Message
+	}
+	return payload.Error
+}
+
+// AsHelcimError returns the Helcim error response behind err, if there is
+// one
+func AsHelcimError(err error) (*HelcimError, bool) {
+	var helcimErr *HelcimError
+	ok := errors.As(err, &helcimErr)
+	return helcimErr, ok
+}
+
+// IsHelcimRetryable reports whether err is a Helcim failure worth trying
+// again later: a rate limit, an outage, or no response at all
+func IsHelcimRetryable(err error) bool {
+	if helcimErr, ok := AsHelcimError(err); ok {
+		return helcimErr.Retryable
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
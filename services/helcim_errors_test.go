@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHelcimError(t *testing.T) {
+	tests := []struct {
+		status    int
+		body      string
+		code      string
+		message   string
+		retryable bool
+	}{
+		{http.StatusBadRequest, `{"errors":"Transaction Declined: DECLINE"}`, HelcimDeclined, "Transaction Declined: DECLINE", false},
+		{http.StatusBadRequest, `{"errors":["Card expired","CVV mismatch"]}`, HelcimDeclined, "Card expired; CVV mismatch", false},
+		{http.StatusBadRequest, `{"errors":{"customerCode":"is required","amount":"must be positive"}}`, HelcimInvalidRequest, "amount: must be positive; customerCode: is required", false},
+		{http.StatusUnauthorized, `{"errors":"Unauthorized"}`, HelcimAuthFailed, "Unauthorized", false},
+		{http.StatusNotFound, `{"message":"Subscription not found"}`, HelcimNotFound, "Subscription not found", false},
+		{http.StatusTooManyRequests, ``, HelcimRateLimited, "Too Many Requests", true},
+		{http.StatusBadGateway, `<html>Bad Gateway</html>`, HelcimUnavailable, "<html>Bad Gateway</html>", true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d %s", tt.status, tt.code), func(t *testing.T) {
+			err := newHelcimError(tt.status, []byte(tt.body))
+			assert.Equal(t, &HelcimError{StatusCode: tt.status, Code: tt.code, Message: tt.message, Retryable: tt.retryable}, err)
+			assert.Equal(t, tt.retryable, IsHelcimRetryable(fmt.Errorf("wrapped: %w", err)))
+		})
+	}
+}
+
+func TestHelcimClient_ReturnsHelcimError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors":"Transaction Declined: INSUFFICIENT FUNDS"}`))
+	}))
+	defer server.Close()
+	client := &HelcimClient{APIToken: "test-token", BaseURL: server.URL, Client: server.Client()}
+
+	_, err := client.ProcessPayment(context.Background(), PaymentAPIRequest{Amount: 25, Currency: "USD"})
+	helcimErr, ok := AsHelcimError(err)
+	require.True(t, ok, "expected a HelcimError, got %v", err)
+	assert.Equal(t, HelcimDeclined, helcimErr.Code)
+	assert.Equal(t, "helcim declined (status 400): Transaction Declined: INSUFFICIENT FUNDS", err.Error())
+
+	err = client.CancelSubscription(context.Background(), "123")
+	_, ok = AsHelcimError(err)
+	assert.True(t, ok)
+}
+
+func TestIsHelcimRetryable_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	client := &HelcimClient{APIToken: "test-token", BaseURL: "http://" + address, Client: http.DefaultClient}
+	_, err = client.GetSubscription(context.Background(), "123")
+	require.Error(t, err)
+	assert.True(t, IsHelcimRetryable(err))
+	assert.False(t, IsHelcimRetryable(nil))
+	assert.False(t, IsHelcimRetryable(fmt.Errorf("no verified card on file")))
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// HelcimRetryPolicy says how often a Helcim request that hit a rate limit,
+// an outage or a dropped connection is tried again. Delays double from
+// BaseDelay up to MaxDelay, with random jitter so many requests don't retry
+// in step. MaxAttempts includes the first try; below 2 nothing is retried.
+type HelcimRetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// helcimRetryPolicyFromEnv is the retry policy NewHelcimClient uses.
+// HELCIM_RETRY_ATTEMPTS sets the number of attempts (default 3, 1 turns
+// retries off).
+func helcimRetryPolicyFromEnv() HelcimRetryPolicy {
+	policy := HelcimRetryPolicy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 5 * time.Second}
+	if attempts, err := strconv.Atoi(os.Getenv("HELCIM_RETRY_ATTEMPTS")); err == nil && attempts > 0 {
+		policy.MaxAttempts = attempts
+	}
+	return policy
+}
+
+// delay is how long to wait before retry number attempt (1 for the first
+// retry). Retry-After from Helcim is honoured up to MaxDelay.
+func (p HelcimRetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, p.MaxDelay)
+	}
+	backoff := p.BaseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	// Full jitter: anywhere from half the backoff to all of it
+	return backoff/2 + rand.N(backoff/2+1)
+}
+
+// helcimRequestRetryable reports whether req is safe to send twice. Reads,
+// replacements and deletes are. A POST or PATCH could charge a card or
+// change a plan twice, so it's only retried with an Idempotency-Key, which
+// Helcim uses to recognise the repeat and answer it without doing it again.
+func helcimRequestRetryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" && (req.Body == nil || req.GetBody != nil)
+}
+
+// transientHelcimStatus reports whether a response status is worth trying
+// again
+func transientHelcimStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// send makes a request to Helcim, retrying it under the client's retry
+// policy when that's safe and the failure looks temporary. Each retry sends
+// the same headers, including the idempotency key. A retry that wouldn't
+// start before the request's context deadline isn't tried, and cancelling
+// the context stops the wait between tries. While the client's breaker is
+// open the request isn't sent at all.
+func (h *HelcimClient) send(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if id := RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(HelcimRequestIDHeader, id)
+	}
+	attempts := h.Retry.MaxAttempts
+	if attempts < 1 || !helcimRequestRetryable(req) {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		if !h.Breaker.allow() {
+			return nil, errHelcimCircuitOpen()
+		}
+		resp, err := h.Client.Do(req)
+		var netErr net.Error
+		transient := (err != nil && errors.As(err, &netErr)) || (err == nil && transientHelcimStatus(resp.StatusCode))
+		h.Breaker.record(breakerOutcome(ctx, err, resp))
+		if !transient || attempt >= attempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		var retryAfter time.Duration
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = fmt.Sprintf("status %d", resp.StatusCode)
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				retryAfter = time.Duration(seconds) * time.Second
+			}
+		}
+		wait := h.Retry.delay(attempt, retryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			// No time left to try again, so report this failure
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			req.Body = body
+		}
+		fmt.Printf("[Helcim] %s %s failed (%s), retrying in %s (attempt %d of %d)\n", req.Method, req.URL.Path, reason, wait, attempt+1, attempts)
+		if err := h.wait(ctx, wait); err != nil {
+			return nil, fmt.Errorf("gave up retrying after %s: %w", reason, err)
+		}
+	}
+}
+
+// breakerOutcome is what a request's result says about whether Helcim is up.
+// A rate limit is Helcim answering, so only outages and requests that got no
+// answer count against it.
+func breakerOutcome(ctx context.Context, err error, resp *http.Response) helcimBreakerOutcome {
+	switch {
+	case ctx.Err() != nil:
+		return helcimBreakerAbandoned
+	case err != nil || resp.StatusCode >= 500:
+		return helcimBreakerFailure
+	}
+	return helcimBreakerSuccess
+}
+
+// wait pauses before a retry, returning early with the context's error if
+// it's cancelled
+func (h *HelcimClient) wait(ctx context.Context, d time.Duration) error {
+	if h.sleep != nil {
+		h.sleep(d)
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// retryingClient is a client for server that retries without waiting,
+// recording the delays it would have slept for
+func retryingClient(server *httptest.Server, attempts int, waits *[]time.Duration) *HelcimClient {
+	return &HelcimClient{
+		APIToken: "test-token",
+		BaseURL:  server.URL,
+		Client:   server.Client(),
+		Retry:    HelcimRetryPolicy{MaxAttempts: attempts, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second},
+		sleep:    func(d time.Duration) { *waits = append(*waits, d) },
+	}
+}
+
+func TestHelcimClient_RetriesTransientFailures(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(SubscriptionResponse{ID: 123, Status: "active"})
+	}))
+	defer server.Close()
+	var waits []time.Duration
+	client := retryingClient(server, 3, &waits)
+
+	sub, err := client.GetSubscription(context.Background(), "123")
+	require.NoError(t, err)
+	assert.Equal(t, "active", sub.Status)
+	assert.EqualValues(t, 3, calls)
+	assert.Len(t, waits, 2)
+}
+
+func TestHelcimClient_GivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"errors":"Too many requests"}`))
+	}))
+	defer server.Close()
+	var waits []time.Duration
+	client := retryingClient(server, 3, &waits)
+
+	_, err := client.GetSubscription(context.Background(), "123")
+	helcimErr, ok := AsHelcimError(err)
+	require.True(t, ok, "expected a HelcimError, got %v", err)
+	assert.Equal(t, HelcimRateLimited, helcimErr.Code)
+	assert.Equal(t, "Too many requests", helcimErr.Message)
+	assert.EqualValues(t, 3, calls)
+}
+
+func TestHelcimClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors":"Transaction Declined"}`))
+	}))
+	defer server.Close()
+	var waits []time.Duration
+	client := retryingClient(server, 3, &waits)
+
+	_, err := client.ProcessPayment(context.Background(), PaymentAPIRequest{Amount: 25, Currency: "USD"})
+	require.Error(t, err)
+	assert.EqualValues(t, 1, calls)
+	assert.Empty(t, waits)
+}
+
+func TestHelcimClient_RetriesPurchaseWithSameIdempotencyKey(t *testing.T) {
+	var keys, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		bodies = append(bodies, string(body))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(PaymentAPIResponse{TransactionID: 42, Status: "APPROVED"})
+	}))
+	defer server.Close()
+	var waits []time.Duration
+	client := retryingClient(server, 3, &waits)
+
+	resp, err := client.ProcessPayment(context.Background(), PaymentAPIRequest{Amount: 25, Currency: "USD"})
+	require.NoError(t, err)
+	assert.Equal(t, 42, resp.TransactionID)
+	require.Len(t, keys, 2)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.NotEmpty(t, bodies[1])
+}
+
+func TestHelcimClient_DoesNotRetryPostWithoutIdempotencyKey(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	var waits []time.Duration
+	client := retryingClient(server, 3, &waits)
+
+	_, err := client.CreateCustomer(context.Background(), CustomerRequest{ContactName: "Pat Donor", Email: "pat@example.com"})
+	require.Error(t, err)
+	assert.True(t, IsHelcimRetryable(err))
+	assert.EqualValues(t, 1, calls)
+	assert.Empty(t, waits)
+}
+
+func TestHelcimClient_HonoursRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(SubscriptionResponse{ID: 123})
+	}))
+	defer server.Close()
+	var waits []time.Duration
+	client := retryingClient(server, 2, &waits)
+
+	_, err := client.GetSubscription(context.Background(), "123")
+	require.NoError(t, err)
+	// Capped at the policy's longest delay
+	assert.Equal(t, []time.Duration{time.Second}, waits)
+}
+
+func TestHelcimRetryPolicy_Delay(t *testing.T) {
+	policy := HelcimRetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for i := 0; i < 50; i++ {
+		first := policy.delay(1, 0)
+		assert.GreaterOrEqual(t, first, 50*time.Millisecond)
+		assert.LessOrEqual(t, first, 100*time.Millisecond)
+
+		third := policy.delay(3, 0)
+		assert.GreaterOrEqual(t, third, 200*time.Millisecond)
+		assert.LessOrEqual(t, third, 400*time.Millisecond)
+
+		capped := policy.delay(10, 0)
+		assert.GreaterOrEqual(t, capped, 500*time.Millisecond)
+		assert.LessOrEqual(t, capped, time.Second)
+	}
+	assert.Equal(t, time.Duration(0), HelcimRetryPolicy{}.delay(1, 0))
+}
+
+func TestHelcimRetryPolicyFromEnv(t *testing.T) {
+	original := os.Getenv("HELCIM_RETRY_ATTEMPTS")
+	defer os.Setenv("HELCIM_RETRY_ATTEMPTS", original)
+
+	os.Setenv("HELCIM_RETRY_ATTEMPTS", "")
+	assert.Equal(t, 3, helcimRetryPolicyFromEnv().MaxAttempts)
+
+	os.Setenv("HELCIM_RETRY_ATTEMPTS", "5")
+	assert.Equal(t, 5, helcimRetryPolicyFromEnv().MaxAttempts)
+
+	os.Setenv("HELCIM_RETRY_ATTEMPTS", "none")
+	assert.Equal(t, 3, helcimRetryPolicyFromEnv().MaxAttempts)
+}
+
+func TestHelcimClient_StopsRetryingWhenCancelled(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &HelcimClient{
+		APIToken: "test-token",
+		BaseURL:  server.URL,
+		Client:   server.Client(),
+		Retry:    HelcimRetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second},
+		// The caller goes away while the client waits to retry
+		sleep: func(time.Duration) { cancel() },
+	}
+
+	_, err := client.GetSubscription(ctx, "123")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.EqualValues(t, 1, calls)
+}
+
+func TestHelcimClient_DoesNotRetryPastDeadline(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	var waits []time.Duration
+	client := retryingClient(server, 3, &waits)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetSubscription(ctx, "123")
+	require.Error(t, err)
+	assert.True(t, IsHelcimRetryable(err))
+	assert.EqualValues(t, 1, calls)
+	assert.Empty(t, waits)
+}
+
+func TestHelcimClient_SendsRequestID(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(HelcimRequestIDHeader))
+		json.NewEncoder(w).Encode(SubscriptionResponse{ID: 123})
+	}))
+	defer server.Close()
+	var waits []time.Duration
+	client := retryingClient(server, 1, &waits)
+
+	_, err := client.GetSubscription(WithRequestID(context.Background(), "req-42"), "123")
+	require.NoError(t, err)
+	_, err = client.GetSubscription(context.Background(), "123")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"req-42", ""}, got)
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	assert.Equal(t, "", RequestIDFromContext(context.Background()))
+	assert.Equal(t, "job-1", RequestIDFromContext(WithRequestID(context.Background(), "job-1")))
+	// Buffalo stores each request's ID under this plain string key
+	buffaloCtx := context.WithValue(context.Background(), "request_id", "abc123")
+	assert.Equal(t, "abc123", RequestIDFromContext(buffaloCtx))
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -145,7 +146,7 @@ func TestProcessPayment_IdempotencyKeyGeneration(t *testing.T) {
 		},
 	}
 
-	response, err := client.ProcessPayment(req)
+	response, err := client.ProcessPayment(context.Background(), req)
 	require.NoError(t, err)
 	assert.NotNil(t, response)
 	assert.Equal(t, 123456, response.TransactionID)
@@ -203,7 +204,7 @@ func TestCreatePaymentPlan_IdempotencyKeyGeneration(t *testing.T) {
 	}
 
 	// Test CreatePaymentPlan
-	plan, err := client.CreatePaymentPlan(50.0, "Test Plan")
+	plan, err := client.CreatePaymentPlan(context.Background(), 50.0, "Test Plan", BillMonthly)
 	require.NoError(t, err)
 	assert.NotNil(t, plan)
 	assert.Equal(t, 12345, plan.ID)
@@ -211,6 +212,98 @@ func TestCreatePaymentPlan_IdempotencyKeyGeneration(t *testing.T) {
 	assert.Equal(t, 50.0, plan.RecurringAmount)
 }
 
+func TestCreatePaymentPlan_BillingPeriod(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			PaymentPlans []map[string]interface{} `json:"paymentPlans"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		require.Len(t, reqBody.PaymentPlans, 1)
+		assert.Equal(t, "monthly", reqBody.PaymentPlans[0]["billingPeriod"])
+		assert.Equal(t, 3.0, reqBody.PaymentPlans[0]["billingPeriodIncrements"])
+		assert.Equal(t, "forever", reqBody.PaymentPlans[0]["termType"])
+		assert.Equal(t, "Quarterly donation plan for $75.00", reqBody.PaymentPlans[0]["description"])
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "ok",
+			"data":   []PaymentPlan{{ID: 778, Name: "Quarterly Donation - $75", BillingPeriod: "monthly", BillingPeriodIncrements: 3}},
+		})
+	}))
+	defer server.Close()
+
+	client := &HelcimClient{
+		APIToken: "test-api-key",
+		BaseURL:  server.URL,
+		Client:   &http.Client{Timeout: 30 * time.Second},
+	}
+
+	plan, err := client.CreatePaymentPlan(context.Background(), 75, "Quarterly Donation - $75", BillQuarterly)
+	require.NoError(t, err)
+	assert.Equal(t, 778, plan.ID)
+	assert.Equal(t, 3, plan.BillingPeriodIncrements)
+}
+
+func TestCreateInstallmentPlan_FixedTerm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			PaymentPlans []map[string]interface{} `json:"paymentPlans"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		require.Len(t, reqBody.PaymentPlans, 1)
+		assert.Equal(t, "fixed", reqBody.PaymentPlans[0]["termType"])
+		assert.Equal(t, 6.0, reqBody.PaymentPlans[0]["termLength"])
+		assert.Equal(t, 250.0, reqBody.PaymentPlans[0]["recurringAmount"])
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "ok",
+			"data":   []PaymentPlan{{ID: 777, Name: "Pledge - 6 x $250.00", TermType: "fixed"}},
+		})
+	}))
+	defer server.Close()
+
+	client := &HelcimClient{
+		APIToken: "test-api-key",
+		BaseURL:  server.URL,
+		Client:   &http.Client{Timeout: 30 * time.Second},
+	}
+
+	plan, err := client.CreateInstallmentPlan(context.Background(), 250, "Pledge - 6 x $250.00", 6)
+	require.NoError(t, err)
+	assert.Equal(t, 777, plan.ID)
+}
+
+func TestPauseResumeSubscription(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PATCH", r.Method)
+		var reqBody struct {
+			Subscriptions []map[string]interface{} `json:"subscriptions"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		require.Len(t, reqBody.Subscriptions, 1)
+		assert.Equal(t, "789", reqBody.Subscriptions[0]["id"])
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]SubscriptionResponse{{ID: 789, Status: reqBody.Subscriptions[0]["status"].(string)}})
+	}))
+	defer server.Close()
+
+	client := &HelcimClient{
+		APIToken: "test-api-key",
+		BaseURL:  server.URL,
+		Client:   &http.Client{Timeout: 30 * time.Second},
+	}
+
+	paused, err := client.PauseSubscription(context.Background(), "789")
+	require.NoError(t, err)
+	assert.Equal(t, "paused", paused.Status)
+
+	resumed, err := client.ResumeSubscription(context.Background(), "789")
+	require.NoError(t, err)
+	assert.Equal(t, "active", resumed.Status)
+}
+
 func TestCreateSubscription_IdempotencyKeyGeneration(t *testing.T) {
 	// Setup test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -269,7 +362,7 @@ func TestCreateSubscription_IdempotencyKeyGeneration(t *testing.T) {
 		PaymentMethod: "card",
 	}
 
-	response, err := client.CreateSubscription(req)
+	response, err := client.CreateSubscription(context.Background(), req)
 	require.NoError(t, err)
 	assert.NotNil(t, response)
 	assert.Equal(t, 67890, response.ID)
@@ -410,7 +503,7 @@ func TestMockHelcimClient_ProcessPayment(t *testing.T) {
 		},
 	}
 
-	response, err := client.ProcessPayment(req)
+	response, err := client.ProcessPayment(context.Background(), req)
 	require.NoError(t, err)
 	assert.NotNil(t, response)
 	assert.Equal(t, "APPROVED", response.Status)
@@ -423,7 +516,7 @@ func TestMockHelcimClient_ProcessPayment(t *testing.T) {
 func TestMockHelcimClient_CreatePaymentPlan(t *testing.T) {
 	client := &mockHelcimClient{}
 
-	plan, err := client.CreatePaymentPlan(50.0, "Test Plan")
+	plan, err := client.CreatePaymentPlan(context.Background(), 50.0, "Test Plan", BillMonthly)
 	require.NoError(t, err)
 	assert.NotNil(t, plan)
 	assert.Equal(t, "Test Plan", plan.Name)
@@ -442,7 +535,7 @@ func TestMockHelcimClient_CreateSubscription(t *testing.T) {
 		PaymentMethod: "card",
 	}
 
-	response, err := client.CreateSubscription(req)
+	response, err := client.CreateSubscription(context.Background(), req)
 	require.NoError(t, err)
 	assert.NotNil(t, response)
 	assert.Equal(t, "test-customer", response.CustomerID)
@@ -451,3 +544,157 @@ func TestMockHelcimClient_CreateSubscription(t *testing.T) {
 	assert.Equal(t, "active", response.Status)
 	assert.Equal(t, "card", response.PaymentMethod)
 }
+
+func TestPaymentAPIRequest_BankData(t *testing.T) {
+	req := PaymentAPIRequest{
+		PaymentType:  "purchase",
+		Amount:       50.0,
+		Currency:     "USD",
+		CustomerCode: "test-customer",
+		BankData:     BankData{BankToken: "test-bank-token"},
+	}
+
+	data, err := json.Marshal(req)
+	assert.NoError(t, err)
+
+	var parsed map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &parsed))
+
+	// ACH purchases send bankData and leave cardData out
+	_, hasCardData := parsed["cardData"]
+	assert.False(t, hasCardData)
+	if bankData, ok := parsed["bankData"].(map[string]interface{}); ok {
+		assert.Equal(t, "test-bank-token", bankData["bankToken"])
+	} else {
+		t.Error("bankData should be a map")
+	}
+}
+
+// mapCache is a SharedCache in a map, standing in for another instance's view
+// of Redis
+type mapCache map[string]string
+
+func (m mapCache) Get(key string) (string, bool, error) {
+	v, ok := m[key]
+	return v, ok, nil
+}
+
+func (m mapCache) Set(key, value string, ttl time.Duration) error {
+	m[key] = value
+	return nil
+}
+
+func TestPaymentPlanCache_SharedBetweenInstances(t *testing.T) {
+	shared := mapCache{}
+	first := &PaymentPlanCache{}
+	first.UseShared(shared)
+	first.Set("plan_25_USD", &PaymentPlan{ID: 42, Name: "Monthly $25"})
+	assert.Contains(t, shared, "payment_plan:plan_25_USD")
+
+	second := &PaymentPlanCache{}
+	second.UseShared(shared)
+	plan, found := second.Get("plan_25_USD")
+	require.True(t, found)
+	assert.Equal(t, 42, plan.ID)
+
+	// Without a shared cache each instance only sees its own plans
+	_, found = (&PaymentPlanCache{}).Get("plan_25_USD")
+	assert.False(t, found)
+}
+
+func TestGetTransaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-token", r.Header.Get("api-token"))
+		switch r.URL.Path {
+		case "/card-transactions/5551":
+			json.NewEncoder(w).Encode(PaymentAPIResponse{TransactionID: 5551, Status: "APPROVED", Amount: 50, Currency: "USD"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":"Transaction not found"}`))
+		}
+	}))
+	defer server.Close()
+	client := &HelcimClient{APIToken: "test-token", BaseURL: server.URL, Client: server.Client()}
+
+	transaction, err := client.GetTransaction(context.Background(), "5551")
+	require.NoError(t, err)
+	assert.Equal(t, 5551, transaction.TransactionID)
+	assert.Equal(t, "APPROVED", transaction.Status)
+
+	_, err = client.GetTransaction(context.Background(), "404")
+	require.Error(t, err)
+}
+
+func TestGetCustomer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-token", r.Header.Get("api-token"))
+		switch r.URL.Path {
+		case "/customers":
+			if r.URL.Query().Get("customerCode") != "CST12" {
+				json.NewEncoder(w).Encode([]HelcimCustomer{})
+				return
+			}
+			json.NewEncoder(w).Encode([]HelcimCustomer{
+				{ID: 7, CustomerCode: "CST123"},
+				{ID: 8, CustomerCode: "CST12", ContactName: "Sam Donor", BillingAddress: BillingAddress{Street1: "1 Main St", City: "Austin"}},
+			})
+		case "/customers/8/cards":
+			json.NewEncoder(w).Encode([]HelcimCard{
+				{ID: 1, CardF6L4: "4111111111", CardExpiry: "0125"},
+				{ID: 2, CardF6L4: "4242424242", CardExpiry: "1228", Default: true},
+			})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := &HelcimClient{APIToken: "test-token", BaseURL: server.URL, Client: server.Client()}
+
+	customer, err := client.GetCustomer(context.Background(), "CST12")
+	require.NoError(t, err)
+	require.NotNil(t, customer)
+	assert.Equal(t, 8, customer.ID)
+	assert.Equal(t, "1 Main St", customer.BillingAddress.Street1)
+	require.NotNil(t, customer.DefaultCard())
+	assert.Equal(t, "4242424242", customer.DefaultCard().CardF6L4)
+
+	customer, err = client.GetCustomer(context.Background(), "CST9")
+	require.NoError(t, err)
+	assert.Nil(t, customer)
+}
+
+func TestUpdateCustomer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PUT", r.Method)
+		assert.Equal(t, "/customers/8", r.URL.Path)
+		var req CustomerRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		json.NewEncoder(w).Encode(HelcimCustomer{ID: 8, CustomerCode: req.CustomerCode, ContactName: req.ContactName, BillingAddress: req.BillingAddress})
+	}))
+	defer server.Close()
+	client := &HelcimClient{APIToken: "test-token", BaseURL: server.URL, Client: server.Client()}
+
+	customer, err := client.UpdateCustomer(context.Background(), 8, CustomerRequest{
+		ContactName:    "Sam Donor",
+		CustomerCode:   "CST12",
+		BillingAddress: BillingAddress{Street1: "9 Oak Ave", City: "Austin", Province: "TX", Country: "USA", PostalCode: "78702"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "9 Oak Ave", customer.BillingAddress.Street1)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":"customer not found"}`, http.StatusNotFound)
+	}))
+	defer failing.Close()
+	client.BaseURL = failing.URL
+	_, err = client.UpdateCustomer(context.Background(), 8, CustomerRequest{})
+	assert.ErrorContains(t, err, "status 404")
+}
+
+func TestHelcimCard_Expired(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	assert.False(t, HelcimCard{CardExpiry: "1026"}.Expired(now))
+	assert.True(t, HelcimCard{CardExpiry: "0926"}.Expired(now))
+	assert.True(t, HelcimCard{CardExpiry: ""}.Expired(now))
+}
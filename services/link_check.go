@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// linkCheckUserAgent identifies the resource library's link checker to the
+// sites it checks
+const linkCheckUserAgent = "AVRLinkChecker/1.0 (+https://avrnpo.org/resources)"
+
+// LinkCheck is the result of checking one link
+type LinkCheck struct {
+	// StatusCode is the HTTP status the link answered with after
+	// redirects, or 0 if it couldn't be reached
+	StatusCode int
+	// Error says why the link couldn't be reached
+	Error string
+}
+
+// OK reports whether the link answered with a 2xx status
+func (l LinkCheck) OK() bool {
+	return l.Error == "" && l.StatusCode >= 200 && l.StatusCode < 300
+}
+
+// LinkChecker checks that outside links still work
+type LinkChecker struct {
+	Client *http.Client
+}
+
+// NewLinkChecker returns a LinkChecker with a short timeout, so a slow site
+// doesn't hold up the rest
+func NewLinkChecker() *LinkChecker {
+	return &LinkChecker{
+		Client: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+// Check requests url, following redirects. Sites that don't answer HEAD
+// requests properly are tried again with a GET.
+func (l *LinkChecker) Check(url string) LinkCheck {
+	res := l.request(http.MethodHead, url)
+	if !res.OK() {
+		res = l.request(http.MethodGet, url)
+	}
+	return res
+}
+
+func (l *LinkChecker) request(method, url string) LinkCheck {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return LinkCheck{Error: fmt.Sprintf("invalid link: %v", err)}
+	}
+	req.Header.Set("User-Agent", linkCheckUserAgent)
+
+	resp, err := l.Client.Do(req)
+	if err != nil {
+		return LinkCheck{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	// Read a little of the body so the connection can be reused
+	io.CopyN(io.Discard, resp.Body, 64<<10)
+	return LinkCheck{StatusCode: resp.StatusCode}
+}
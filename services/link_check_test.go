@@ -0,0 +1,57 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkChecker_Check(t *testing.T) {
+	req := require.New(t)
+
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		req.Contains(r.UserAgent(), "AVRLinkChecker")
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Write([]byte("hello"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	checker := NewLinkChecker()
+
+	res := checker.Check(server.URL + "/ok")
+	req.True(res.OK())
+	req.Equal(http.StatusOK, res.StatusCode)
+
+	res = checker.Check(server.URL + "/moved")
+	req.True(res.OK())
+
+	methods = nil
+	res = checker.Check(server.URL + "/no-head")
+	req.True(res.OK())
+	req.Equal([]string{"HEAD /no-head", "GET /no-head"}, methods)
+
+	res = checker.Check(server.URL + "/gone")
+	req.False(res.OK())
+	req.Equal(http.StatusNotFound, res.StatusCode)
+
+	res = checker.Check("http://127.0.0.1:1/unreachable")
+	req.False(res.OK())
+	req.Zero(res.StatusCode)
+	req.NotEmpty(res.Error)
+}
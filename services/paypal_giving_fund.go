@@ -0,0 +1,203 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sources for gifts that reach AVR through PayPal Giving Fund payouts rather
+// than our own Helcim checkout.
+const (
+	GiftSourcePayPalGivingFund = "paypal_giving_fund"
+	GiftSourceVenmo            = "venmo"
+)
+
+// PayPalGivingFundEventDisbursed is sent when PPGF pays out a donation
+const PayPalGivingFundEventDisbursed = "GIVING_FUND.DONATION.DISBURSED"
+
+// PayoutGift is one donation from a PayPal Giving Fund disbursement, whether
+// read from the monthly donation report or delivered by webhook.
+type PayoutGift struct {
+	Source        string    `json:"source"`
+	TransactionID string    `json:"transaction_id"`
+	PayoutID      string    `json:"payout_id"`
+	DonorName     string    `json:"donor_name"`
+	DonorEmail    string    `json:"donor_email"`
+	Amount        float64   `json:"gross_amount"`
+	Fee           float64   `json:"fee_amount"`
+	Currency      string    `json:"currency"`
+	Program       string    `json:"program"`
+	DonatedAt     time.Time `json:"donation_date"`
+}
+
+// PayPalGivingFundWebhookEvent is the envelope PPGF webhooks arrive in
+type PayPalGivingFundWebhookEvent struct {
+	EventType string     `json:"event_type"`
+	Resource  PayoutGift `json:"resource"`
+}
+
+// payoutReportColumns maps our fields to the header names used by the PPGF
+// donation report. Venmo gifts appear in the same report.
+var payoutReportColumns = map[string][]string{
+	"transaction_id": {"transaction id", "donation id"},
+	"payout_id":      {"payout transaction id", "disbursement id"},
+	"first_name":     {"donor first name", "first name"},
+	"last_name":      {"donor last name", "last name"},
+	"name":           {"donor name", "name"},
+	"email":          {"donor email", "email", "donor email address"},
+	"amount":         {"gross amount", "donation amount", "amount"},
+	"fee":            {"total fees", "fee amount", "fees"},
+	"currency":       {"currency code", "currency"},
+	"program":        {"program name", "program"},
+	"date":           {"donation date", "date"},
+	"channel":        {"payment method", "channel", "source"},
+}
+
+// payoutDateLayouts are the date formats seen in PPGF exports
+var payoutDateLayouts = []string{"2006-01-02", "01/02/2006", "1/2/2006", "2006-01-02 15:04:05", time.RFC3339}
+
+// ParsePayoutReport reads a PayPal Giving Fund donation report (CSV). Rows
+// that can't be read are returned as errors alongside the gifts that could,
+// so one bad row doesn't block the rest of the import.
+func ParsePayoutReport(r io.Reader) ([]PayoutGift, []error, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read report header: %w", err)
+	}
+
+	index := map[string]int{}
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		for field, aliases := range payoutReportColumns {
+			if _, found := index[field]; found {
+				continue
+			}
+			for _, alias := range aliases {
+				if name == alias {
+					index[field] = i
+				}
+			}
+		}
+	}
+	for _, required := range []string{"transaction_id", "amount", "date"} {
+		if _, ok := index[required]; !ok {
+			return nil, nil, fmt.Errorf("report is missing the %s column", strings.ReplaceAll(required, "_", " "))
+		}
+	}
+
+	var gifts []PayoutGift
+	var rowErrs []error
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			rowErrs = append(rowErrs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+
+		get := func(field string) string {
+			i, ok := index[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		gift, err := payoutGiftFromRow(get)
+		if err != nil {
+			rowErrs = append(rowErrs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		gifts = append(gifts, gift)
+	}
+	return gifts, rowErrs, nil
+}
+
+func payoutGiftFromRow(get func(string) string) (PayoutGift, error) {
+	gift := PayoutGift{
+		Source:        GiftSourcePayPalGivingFund,
+		TransactionID: get("transaction_id"),
+		PayoutID:      get("payout_id"),
+		DonorEmail:    strings.ToLower(get("email")),
+		Currency:      strings.ToUpper(get("currency")),
+		Program:       get("program"),
+	}
+	if gift.TransactionID == "" {
+		return gift, fmt.Errorf("missing transaction id")
+	}
+	if strings.Contains(strings.ToLower(get("channel")), "venmo") {
+		gift.Source = GiftSourceVenmo
+	}
+	if gift.Currency == "" {
+		gift.Currency = "USD"
+	}
+
+	gift.DonorName = get("name")
+	if gift.DonorName == "" {
+		gift.DonorName = strings.TrimSpace(get("first_name") + " " + get("last_name"))
+	}
+
+	amount, err := parsePayoutAmount(get("amount"))
+	if err != nil || amount <= 0 {
+		return gift, fmt.Errorf("invalid amount %q", get("amount"))
+	}
+	gift.Amount = amount
+	if fee := get("fee"); fee != "" {
+		if gift.Fee, err = parsePayoutAmount(fee); err != nil {
+			return gift, fmt.Errorf("invalid fee %q", fee)
+		}
+	}
+
+	date := get("date")
+	for _, layout := range payoutDateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			gift.DonatedAt = t
+			break
+		}
+	}
+	if gift.DonatedAt.IsZero() {
+		return gift, fmt.Errorf("invalid donation date %q", date)
+	}
+	return gift, nil
+}
+
+// parsePayoutAmount accepts "1,234.56", "$25.00" and "-1.50" (fees are
+// sometimes exported as negatives).
+func parsePayoutAmount(s string) (float64, error) {
+	s = strings.NewReplacer("$", "", ",", "").Replace(strings.TrimSpace(s))
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 {
+		v = -v
+	}
+	return v, nil
+}
+
+// VerifyHMACSignature checks a hex HMAC-SHA256 signature of body, with or
+// without a "sha256=" prefix. An empty secret never verifies.
+func VerifyHMACSignature(secret string, body []byte, signature string) bool {
+	if secret == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.TrimPrefix(signature, "sha256=")))
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePayoutReport(t *testing.T) {
+	report := "\ufeffDonation Date,Donor First Name,Donor Last Name,Donor Email,Program Name,Currency Code,Gross Amount,Total Fees,Net Amount,Transaction ID,Payout Transaction ID,Payment Method\n" +
+		"2026-09-03,Jane,Doe,Jane@Example.com,General Fund,USD,\"1,000.00\",-0.00,1000.00,TX-1,PO-9,PayPal\n" +
+		"09/15/2026,Sam,Lee,,,USD,25.00,0.00,25.00,TX-2,PO-9,Venmo\n" +
+		"2026-09-20,Bad,Row,bad@example.com,,USD,abc,0,0,TX-3,PO-9,PayPal\n"
+
+	gifts, rowErrs, err := ParsePayoutReport(strings.NewReader(report))
+	require.NoError(t, err)
+	require.Len(t, gifts, 2)
+	require.Len(t, rowErrs, 1)
+	assert.Contains(t, rowErrs[0].Error(), "line 4")
+
+	assert.Equal(t, GiftSourcePayPalGivingFund, gifts[0].Source)
+	assert.Equal(t, "TX-1", gifts[0].TransactionID)
+	assert.Equal(t, "PO-9", gifts[0].PayoutID)
+	assert.Equal(t, "Jane Doe", gifts[0].DonorName)
+	assert.Equal(t, "jane@example.com", gifts[0].DonorEmail)
+	assert.Equal(t, 1000.0, gifts[0].Amount)
+	assert.Equal(t, "General Fund", gifts[0].Program)
+	assert.Equal(t, time.Date(2026, 9, 3, 0, 0, 0, 0, time.UTC), gifts[0].DonatedAt)
+
+	assert.Equal(t, GiftSourceVenmo, gifts[1].Source)
+	assert.Equal(t, time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC), gifts[1].DonatedAt)
+}
+
+func TestParsePayoutReport_MissingColumns(t *testing.T) {
+	_, _, err := ParsePayoutReport(strings.NewReader("Donor Name,Gross Amount\nJane,10\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transaction id")
+}
+
+func TestVerifyHMACSignature(t *testing.T) {
+	body := []byte(`{"event_type":"GIVING_FUND.DONATION.DISBURSED"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	assert.True(t, VerifyHMACSignature("secret", body, sig))
+	assert.True(t, VerifyHMACSignature("secret", body, "sha256="+sig))
+	assert.False(t, VerifyHMACSignature("other", body, sig))
+	assert.False(t, VerifyHMACSignature("", body, sig))
+}
+
+func TestEmailService_generatePayoutGiftAcknowledgementHTML(t *testing.T) {
+	emailService := &EmailService{}
+
+	html, err := emailService.generatePayoutGiftAcknowledgementHTML(PayoutGiftAcknowledgementData{
+		DonorName:        "Jane Doe",
+		Amount:           25,
+		Channel:          "Venmo",
+		DonationDate:     time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC),
+		OrganizationName: "Test Organization",
+	})
+	require.NoError(t, err)
+	require.Contains(t, html, "$25.00")
+	require.Contains(t, html, "Venmo")
+	require.Contains(t, html, "not a tax receipt")
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// PDF page layout, in points: US Letter with one-inch margins
+const (
+	pdfPageWidth  = 612.0
+	pdfPageHeight = 792.0
+	pdfMargin     = 72.0
+)
+
+// pdfLine is one line of text on a generated PDF. Long lines are wrapped
+// and a blank line leaves a gap.
+type pdfLine struct {
+	Text string
+	Size float64
+	Bold bool
+}
+
+// pdfText is a body-size line
+func pdfText(format string, args ...interface{}) pdfLine {
+	return pdfLine{Text: fmt.Sprintf(format, args...), Size: 11}
+}
+
+// pdfHeading is a bold line at size
+func pdfHeading(text string, size float64) pdfLine {
+	return pdfLine{Text: text, Size: size, Bold: true}
+}
+
+// renderPDF lays lines out top to bottom on as many pages as they need and
+// returns the PDF file. Text is set in the standard Helvetica fonts, so no
+// fonts are embedded, and content streams are left uncompressed.
+func renderPDF(title string, lines []pdfLine) []byte {
+	type placed struct {
+		text string
+		size float64
+		bold bool
+		y    float64
+	}
+	var pages [][]placed
+	var page []placed
+	y := pdfPageHeight - pdfMargin
+	for _, line := range lines {
+		size := line.Size
+		if size == 0 {
+			size = 11
+		}
+		leading := size * 1.4
+		for _, text := range wrapPDFText(line.Text, size, pdfPageWidth-2*pdfMargin) {
+			if y-leading < pdfMargin {
+				pages = append(pages, page)
+				page = nil
+				y = pdfPageHeight - pdfMargin
+			}
+			y -= leading
+			if text != "" {
+				page = append(page, placed{text: text, size: size, bold: line.Bold, y: y})
+			}
+		}
+	}
+	pages = append(pages, page)
+
+	// Objects 1-5 are the catalog, page tree, fonts and document info; each
+	// page then takes two, for the page and its content stream
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) /Producer (American Veterans Rebuilding) >>", pdfString(title)),
+	)
+	for i, lines := range pages {
+		var content bytes.Buffer
+		for _, l := range lines {
+			font := "F1"
+			if l.bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&content, "BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET\n", font, l.size, pdfMargin, l.y, pdfString(l.text))
+		}
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 7+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// wrapPDFText splits text into lines that fit width at size. Helvetica's
+// glyphs average about half the font size, which is close enough for
+// receipt text.
+func wrapPDFText(text string, size, width float64) []string {
+	maxChars := int(width / (size * 0.5))
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		words := strings.Fields(para)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, word := range words[1:] {
+			if len(line)+1+len(word) > maxChars {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line += " " + word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pdfWinAnsi maps the punctuation outside Latin-1 that receipts use to its
+// WinAnsiEncoding byte
+var pdfWinAnsi = map[rune]byte{
+	'€': 0x80, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+}
+
+// pdfString encodes s as the body of a PDF literal string. Characters the
+// standard fonts can't show become "?".
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case pdfWinAnsi[r] != 0:
+			fmt.Fprintf(&b, "\\%03o", pdfWinAnsi[r])
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireValidPDF checks the file's cross-reference table points at each
+// of its objects and returns how many pages it has
+func requireValidPDF(t *testing.T, pdf []byte) int {
+	t.Helper()
+	require.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	require.NotNil(t, m)
+	xref, err := strconv.Atoi(string(m[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(pdf[xref:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(pdf[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+	}
+
+	count := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(pdf)
+	require.NotNil(t, count)
+	pages, _ := strconv.Atoi(string(count[1]))
+	require.Equal(t, pages, bytes.Count(pdf, []byte("/Type /Page /Parent")))
+	return pages
+}
+
+func TestRenderPDF(t *testing.T) {
+	pdf := renderPDF("Test", []pdfLine{pdfHeading("Title (draft)", 16), {}, pdfText("Paid $%.2f — thank you", 25.0)})
+	require.Equal(t, 1, requireValidPDF(t, pdf))
+	require.Contains(t, string(pdf), `(Title \(draft\)) Tj`)
+	require.Contains(t, string(pdf), `(Paid $25.00 \227 thank you) Tj`)
+	require.Contains(t, string(pdf), "/F2 16.0 Tf")
+
+	// Enough lines to need a second page
+	lines := make([]pdfLine, 60)
+	for i := range lines {
+		lines[i] = pdfText("Line %d", i+1)
+	}
+	pdf = renderPDF("Long", lines)
+	require.Equal(t, 2, requireValidPDF(t, pdf))
+	require.Contains(t, string(pdf), "(Line 60) Tj")
+}
+
+func TestWrapPDFText(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	lines := wrapPDFText(long, 11, 468)
+	require.Len(t, lines, 3)
+	for _, l := range lines {
+		require.LessOrEqual(t, len(l), 85)
+	}
+	require.Equal(t, []string{"a", "", "b"}, wrapPDFText("a\n\nb", 11, 468))
+}
+
+func TestPDFString(t *testing.T) {
+	require.Equal(t, `a\(b\)\\`, pdfString(`a(b)\`))
+	require.Equal(t, `2 \327 mugs`, pdfString("2 × mugs"))
+	require.Equal(t, `thanks ?`, pdfString("thanks 🎉"))
+}
+
+func TestEmailService_GenerateReceiptPDF(t *testing.T) {
+	emailService := &EmailService{ContactEmail: "info@example.org"}
+	data := DonationReceiptData{
+		DonorName:           "Sam Donor",
+		DonationAmount:      250,
+		DonationType:        "One-time",
+		TransactionID:       "TXN-42",
+		DonationDate:        time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+		TaxDeductibleAmount: 150,
+		FairMarketValue:     100,
+		GoodsProvided:       "Gala dinner for two",
+		OrganizationName:    "Test Charity",
+		OrganizationEIN:     "12-3456789",
+		DonorAddressLine1:   "12 Main St",
+		DonorCity:           "Lexington",
+		DonorState:          "KY",
+		DonorZip:            "40502",
+	}
+
+	pdf := emailService.GenerateReceiptPDF(data)
+	requireValidPDF(t, pdf)
+	for _, want := range []string{"(Test Charity) Tj", "(Tax ID \\(EIN\\): 12-3456789) Tj", "(Lexington, KY 40502) Tj",
+		"(Transaction ID: TXN-42) Tj", "(Amount: $250.00) Tj", "(Date: October 14, 2026) Tj",
+		"(Estimated Fair Market Value: $100.00) Tj", "(Questions? Contact us at info@example.org.) Tj"} {
+		require.Contains(t, string(pdf), want)
+	}
+	require.Equal(t, "donation-receipt-2026-10-14.pdf", ReceiptPDFFilename(data))
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ReceiptComplianceError is returned instead of sending a donation receipt
+// that's missing something the IRS expects on a written acknowledgment, so
+// the donor never gets a receipt they can't use for their deduction
+type ReceiptComplianceError struct {
+	Problems []string
+}
+
+func (e *ReceiptComplianceError) Error() string {
+	return "receipt is missing required details: " + strings.Join(e.Problems, "; ")
+}
+
+// AsReceiptComplianceError returns the compliance failure behind err, if
+// there is one
+func AsReceiptComplianceError(err error) (*ReceiptComplianceError, bool) {
+	var complianceErr *ReceiptComplianceError
+	ok := errors.As(err, &complianceErr)
+	return complianceErr, ok
+}
+
+// receiptEINPattern is the IRS format for an EIN, e.g. 12-3456789
+var receiptEINPattern = regexp.MustCompile(`^\d{2}-\d{7}$`)
+
+// deductibilityStatements are the ways a receipt can state what's
+// deductible: all of it when nothing was given in return, or the excess
+// over the value of what was
+var deductibilityStatements = []string{
+	"tax-deductible to the full extent allowed by law",
+	"amount deductible for federal income tax purposes",
+}
+
+// CheckReceiptCompliance checks a receipt has the organization's name, EIN
+// and address, the gift's date and amount, and what the donor received in
+// return when they received something. It returns a ReceiptComplianceError
+// listing everything that's missing.
+func CheckReceiptCompliance(data DonationReceiptData) error {
+	problems := []string{}
+	if strings.TrimSpace(data.OrganizationName) == "" {
+		problems = append(problems, "organization name")
+	}
+	switch ein := strings.TrimSpace(data.OrganizationEIN); {
+	case ein == "":
+		problems = append(problems, "organization EIN")
+	case !receiptEINPattern.MatchString(ein):
+		problems = append(problems, fmt.Sprintf("organization EIN %q is not in the form 12-3456789", ein))
+	}
+	if strings.TrimSpace(data.OrganizationAddress) == "" {
+		problems = append(problems, "organization address")
+	}
+	if data.DonationDate.IsZero() {
+		problems = append(problems, "donation date")
+	}
+	if data.DonationAmount <= 0 {
+		problems = append(problems, "donation amount")
+	}
+	if data.TaxDeductibleAmount < 0 || data.TaxDeductibleAmount > data.DonationAmount {
+		problems = append(problems, fmt.Sprintf("deductible amount $%.2f is outside the gift's $%.2f", data.TaxDeductibleAmount, data.DonationAmount))
+	}
+	if data.FairMarketValue > 0 && strings.TrimSpace(data.GoodsProvided) == "" && len(data.OrderItems) == 0 {
+		problems = append(problems, "description of the goods or services provided in return")
+	}
+	if len(problems) > 0 {
+		return &ReceiptComplianceError{Problems: problems}
+	}
+	return nil
+}
+
+// checkReceiptStatements checks each rendered copy of a receipt says the
+// organization is tax-exempt and how much of the gift is deductible
+func checkReceiptStatements(bodies map[string]string) error {
+	problems := []string{}
+	for _, part := range []string{"HTML", "text"} {
+		body, ok := bodies[part]
+		if !ok {
+			continue
+		}
+		if !strings.Contains(body, "501(c)(3)") {
+			problems = append(problems, "501(c)(3) statement in the "+part+" receipt")
+		}
+		if !containsDeductibilityStatement(body) {
+			problems = append(problems, "deductibility statement in the "+part+" receipt")
+		}
+	}
+	if len(problems) > 0 {
+		return &ReceiptComplianceError{Problems: problems}
+	}
+	return nil
+}
+
+// containsDeductibilityStatement reports whether body states what's
+// deductible, however the template has wrapped its lines
+func containsDeductibilityStatement(body string) bool {
+	flat := strings.Join(strings.Fields(body), " ")
+	for _, statement := range deductibilityStatements {
+		if strings.Contains(flat, statement) {
+			return true
+		}
+	}
+	return false
+}
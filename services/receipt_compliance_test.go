@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compliantReceipt() DonationReceiptData {
+	return DonationReceiptData{
+		DonorName:           "Jane Doe",
+		DonationAmount:      100,
+		TaxDeductibleAmount: 100,
+		DonationType:        "One-time",
+		TransactionID:       "TXN-1",
+		DonationDate:        time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+		OrganizationName:    "American Veterans Rebuilding",
+		OrganizationEIN:     "12-3456789",
+		OrganizationAddress: "1 Main St, Austin, TX 78701",
+	}
+}
+
+func TestCheckReceiptCompliance(t *testing.T) {
+	require.NoError(t, CheckReceiptCompliance(compliantReceipt()))
+
+	err := CheckReceiptCompliance(DonationReceiptData{OrganizationEIN: "123456789", DonationAmount: 50, TaxDeductibleAmount: 60})
+	blocked, ok := AsReceiptComplianceError(err)
+	require.True(t, ok)
+	assert.Equal(t, []string{
+		"organization name",
+		`organization EIN "123456789" is not in the form 12-3456789`,
+		"organization address",
+		"donation date",
+		"deductible amount $60.00 is outside the gift's $50.00",
+	}, blocked.Problems)
+	assert.Contains(t, err.Error(), "receipt is missing required details: organization name; ")
+}
+
+func TestCheckReceiptCompliance_QuidProQuo(t *testing.T) {
+	data := compliantReceipt()
+	data.FairMarketValue = 40
+	data.TaxDeductibleAmount = 60
+	blocked, ok := AsReceiptComplianceError(CheckReceiptCompliance(data))
+	require.True(t, ok)
+	assert.Equal(t, []string{"description of the goods or services provided in return"}, blocked.Problems)
+
+	data.GoodsProvided = "Two gala dinner tickets"
+	assert.NoError(t, CheckReceiptCompliance(data))
+}
+
+func TestCheckReceiptStatements(t *testing.T) {
+	es := &EmailService{}
+	data := compliantReceipt()
+	html, err := es.generateReceiptHTML(data)
+	require.NoError(t, err)
+	assert.NoError(t, checkReceiptStatements(map[string]string{"HTML": html, "text": es.generateReceiptText(data)}))
+
+	data.FairMarketValue = 40
+	data.GoodsProvided = "Two gala dinner tickets"
+	data.TaxDeductibleAmount = 60
+	html, err = es.generateReceiptHTML(data)
+	require.NoError(t, err)
+	assert.NoError(t, checkReceiptStatements(map[string]string{"HTML": html, "text": es.generateReceiptText(data)}))
+
+	blocked, ok := AsReceiptComplianceError(checkReceiptStatements(map[string]string{"text": "Thanks for your gift!"}))
+	require.True(t, ok)
+	assert.Equal(t, []string{"501(c)(3) statement in the text receipt", "deductibility statement in the text receipt"}, blocked.Problems)
+}
+
+func TestSendDonationReceipt_BlocksNoncompliantReceipt(t *testing.T) {
+	mock := &mockSMTPClient{}
+	es := &EmailService{
+		SMTPHost:     "smtp.test",
+		SMTPPort:     "1025",
+		SMTPUsername: "user",
+		SMTPPassword: "pass",
+		FromEmail:    "from@test.local",
+		FromName:     "Test",
+		EmailEnabled: true,
+		client:       mock,
+	}
+
+	data := compliantReceipt()
+	data.OrganizationEIN = ""
+	err := es.SendDonationReceipt("recipient@test.local", data)
+	_, ok := AsReceiptComplianceError(err)
+	require.True(t, ok, "expected a compliance error, got %v", err)
+	require.False(t, mock.called, "a blocked receipt must not be sent")
+}
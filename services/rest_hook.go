@@ -0,0 +1,59 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// restHookUserAgent identifies REST hook deliveries to the services that
+// subscribed to them
+const restHookUserAgent = "AVRWebhooks/1.0 (+https://avrnpo.org)"
+
+// ErrHookGone is returned when a hook's target answers 410 Gone, which is
+// how Zapier and similar services say the subscription should be dropped
+var ErrHookGone = errors.New("rest hook target is gone")
+
+// HookSender posts events to REST hook subscribers
+type HookSender struct {
+	Client *http.Client
+}
+
+// NewHookSender returns a HookSender with a short timeout, so a slow
+// subscriber doesn't hold up the worker. Failed deliveries are retried by
+// the job queue.
+func NewHookSender() *HookSender {
+	return &HookSender{
+		Client: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+// Send posts payload, a JSON document, to targetURL. Any answer but a 2xx
+// is an error, and 410 Gone is ErrHookGone.
+func (h *HookSender) Send(targetURL string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid hook target: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", restHookUserAgent)
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send hook: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode == http.StatusGone {
+		return ErrHookGone
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("hook target answered with status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHookSender_Send(t *testing.T) {
+	req := require.New(t)
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req.Equal(http.MethodPost, r.Method)
+		req.Equal("application/json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	sender := NewHookSender()
+	req.NoError(sender.Send(server.URL+"/ok", []byte(`{"id":"1"}`)))
+	req.Equal(`{"id":"1"}`, received)
+	req.ErrorIs(sender.Send(server.URL+"/gone", []byte(`{}`)), ErrHookGone)
+
+	err := sender.Send(server.URL+"/broken", []byte(`{}`))
+	req.Error(err)
+	req.NotErrorIs(err, ErrHookGone)
+	req.Contains(err.Error(), "500")
+}
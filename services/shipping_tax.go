@@ -0,0 +1,228 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShippingAddress is where an order ships, for rate and tax lookups
+type ShippingAddress struct {
+	Line1 string
+	City  string
+	State string
+	Zip   string
+}
+
+// OrderLine is one priced line of an order being quoted
+type OrderLine struct {
+	ID          string
+	Description string
+	Quantity    int
+	UnitPrice   float64
+}
+
+// Total is the price of the line's quantity
+func (l OrderLine) Total() float64 {
+	return float64(l.Quantity) * l.UnitPrice
+}
+
+// ShippingCalculator prices shipping for an order
+type ShippingCalculator interface {
+	ShippingRate(to ShippingAddress, lines []OrderLine) (float64, error)
+}
+
+// TaxCalculator works out the sales tax to collect on an order. Shipping is
+// passed separately because some states tax it and some don't.
+type TaxCalculator interface {
+	SalesTax(to ShippingAddress, lines []OrderLine, shipping float64) (float64, error)
+}
+
+// FlatRateShipping charges a base rate per order plus a rate for each item
+// after the first
+type FlatRateShipping struct {
+	Base           float64
+	AdditionalItem float64
+}
+
+// ShippingRate implements ShippingCalculator
+func (f FlatRateShipping) ShippingRate(to ShippingAddress, lines []OrderLine) (float64, error) {
+	items := 0
+	for _, line := range lines {
+		items += line.Quantity
+	}
+	if items == 0 {
+		return 0, nil
+	}
+	return roundCents(f.Base + float64(items-1)*f.AdditionalItem), nil
+}
+
+// FlatRateTax charges one rate on orders shipped to the states where we
+// collect sales tax. An empty state list means no state collects.
+type FlatRateTax struct {
+	Rate          float64
+	States        []string
+	TaxesShipping bool
+}
+
+// SalesTax implements TaxCalculator
+func (f FlatRateTax) SalesTax(to ShippingAddress, lines []OrderLine, shipping float64) (float64, error) {
+	collects := false
+	for _, state := range f.States {
+		collects = collects || strings.EqualFold(state, strings.TrimSpace(to.State))
+	}
+	if !collects {
+		return 0, nil
+	}
+	taxable := 0.0
+	for _, line := range lines {
+		taxable += line.Total()
+	}
+	if f.TaxesShipping {
+		taxable += shipping
+	}
+	return roundCents(taxable * f.Rate), nil
+}
+
+// TaxJarClient looks up sales tax from a TaxJar-style API, which knows each
+// destination's rates and whether shipping is taxed there
+type TaxJarClient struct {
+	APIKey  string
+	BaseURL string
+	From    ShippingAddress
+	Client  *http.Client
+}
+
+// SalesTax implements TaxCalculator
+func (t *TaxJarClient) SalesTax(to ShippingAddress, lines []OrderLine, shipping float64) (float64, error) {
+	amount := 0.0
+	lineItems := make([]map[string]interface{}, 0, len(lines))
+	for _, line := range lines {
+		amount += line.Total()
+		lineItems = append(lineItems, map[string]interface{}{
+			"id":          line.ID,
+			"description": line.Description,
+			"quantity":    line.Quantity,
+			"unit_price":  line.UnitPrice,
+		})
+	}
+	payload := map[string]interface{}{
+		"from_street":  t.From.Line1,
+		"from_city":    t.From.City,
+		"from_state":   t.From.State,
+		"from_zip":     t.From.Zip,
+		"from_country": "US",
+		"to_street":    to.Line1,
+		"to_city":      to.City,
+		"to_state":     to.State,
+		"to_zip":       to.Zip,
+		"to_country":   "US",
+		"amount":       amount,
+		"shipping":     shipping,
+		"line_items":   lineItems,
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", t.BaseURL+"/v2/taxes", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+t.APIKey)
+
+	resp, err := t.Client.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Printf("[Tax] Sales tax error response: %s\n", string(body))
+		return 0, fmt.Errorf("sales tax request failed with status: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Tax struct {
+			AmountToCollect float64 `json:"amount_to_collect"`
+		} `json:"tax"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return roundCents(result.Tax.AmountToCollect), nil
+}
+
+// NewShippingCalculator returns flat-rate shipping priced from
+// STORE_SHIPPING_FLAT_RATE and STORE_SHIPPING_ADDITIONAL_ITEM
+func NewShippingCalculator() ShippingCalculator {
+	return FlatRateShipping{
+		Base:           envFloat("STORE_SHIPPING_FLAT_RATE", 5),
+		AdditionalItem: envFloat("STORE_SHIPPING_ADDITIONAL_ITEM", 1),
+	}
+}
+
+// NewTaxCalculator returns the configured sales tax calculator: the
+// TaxJar-style API when TAX_PROVIDER=taxjar, otherwise a flat rate applied
+// in the STORE_TAX_STATES we collect for.
+func NewTaxCalculator() (TaxCalculator, error) {
+	if os.Getenv("TAX_PROVIDER") == "taxjar" {
+		apiKey := os.Getenv("TAXJAR_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("TAXJAR_API_KEY is not set")
+		}
+		baseURL := os.Getenv("TAXJAR_API_URL")
+		if baseURL == "" {
+			baseURL = "https://api.taxjar.com"
+		}
+		return &TaxJarClient{
+			APIKey:  apiKey,
+			BaseURL: strings.TrimRight(baseURL, "/"),
+			From: ShippingAddress{
+				Line1: os.Getenv("STORE_SHIP_FROM_STREET"),
+				City:  os.Getenv("STORE_SHIP_FROM_CITY"),
+				State: os.Getenv("STORE_SHIP_FROM_STATE"),
+				Zip:   os.Getenv("STORE_SHIP_FROM_ZIP"),
+			},
+			Client: &http.Client{
+				Timeout: 10 * time.Second,
+			},
+		}, nil
+	}
+
+	var states []string
+	for _, state := range strings.Split(os.Getenv("STORE_TAX_STATES"), ",") {
+		if state = strings.TrimSpace(state); state != "" {
+			states = append(states, state)
+		}
+	}
+	return FlatRateTax{
+		Rate:          envFloat("STORE_TAX_RATE", 0),
+		States:        states,
+		TaxesShipping: os.Getenv("STORE_TAX_SHIPPING") == "true",
+	}, nil
+}
+
+// envFloat reads a numeric setting, falling back when it's unset or invalid
+func envFloat(name string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(name)), 64)
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// roundCents rounds a dollar amount to the nearest cent
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
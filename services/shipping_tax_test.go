@@ -0,0 +1,81 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOrderLines = []OrderLine{
+	{ID: "shirt", Description: "T-Shirt", Quantity: 2, UnitPrice: 20},
+	{ID: "coin", Description: "Challenge Coin", Quantity: 1, UnitPrice: 12.5},
+}
+
+func TestFlatRateShipping(t *testing.T) {
+	shipping := FlatRateShipping{Base: 5, AdditionalItem: 1.25}
+
+	rate, err := shipping.ShippingRate(ShippingAddress{State: "TX"}, testOrderLines)
+	require.NoError(t, err)
+	assert.Equal(t, 7.5, rate)
+
+	rate, err = shipping.ShippingRate(ShippingAddress{State: "TX"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, rate)
+}
+
+func TestFlatRateTax(t *testing.T) {
+	tax := FlatRateTax{Rate: 0.0825, States: []string{"TX"}}
+
+	amount, err := tax.SalesTax(ShippingAddress{State: "tx"}, testOrderLines, 7.5)
+	require.NoError(t, err)
+	assert.Equal(t, 4.33, amount)
+
+	amount, err = tax.SalesTax(ShippingAddress{State: "OK"}, testOrderLines, 7.5)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, amount)
+
+	tax.TaxesShipping = true
+	amount, err = tax.SalesTax(ShippingAddress{State: "TX"}, testOrderLines, 7.5)
+	require.NoError(t, err)
+	assert.Equal(t, 4.95, amount)
+}
+
+func TestTaxJarClient_SalesTax(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/taxes", r.URL.Path)
+		assert.Equal(t, "Bearer tax-key", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "TX", body["to_state"])
+		assert.Equal(t, 52.5, body["amount"])
+		assert.Equal(t, 7.5, body["shipping"])
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"tax":{"amount_to_collect":4.95,"rate":0.0825}}`))
+	}))
+	defer server.Close()
+
+	client := &TaxJarClient{APIKey: "tax-key", BaseURL: server.URL, Client: server.Client()}
+	amount, err := client.SalesTax(ShippingAddress{State: "TX", Zip: "78701"}, testOrderLines, 7.5)
+	require.NoError(t, err)
+	assert.Equal(t, 4.95, amount)
+}
+
+func TestNewTaxCalculator(t *testing.T) {
+	t.Setenv("TAX_PROVIDER", "")
+	t.Setenv("STORE_TAX_RATE", "0.06")
+	t.Setenv("STORE_TAX_STATES", "TX, OK")
+	calc, err := NewTaxCalculator()
+	require.NoError(t, err)
+	assert.Equal(t, FlatRateTax{Rate: 0.06, States: []string{"TX", "OK"}}, calc)
+
+	t.Setenv("TAX_PROVIDER", "taxjar")
+	t.Setenv("TAXJAR_API_KEY", "")
+	_, err = NewTaxCalculator()
+	assert.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Storage keeps uploaded files. Keys are slash-separated relative paths such
+// as "vehicles/<id>/<photo>.jpg".
+type Storage interface {
+	Save(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// LocalStorage stores uploads in a directory on the app host
+type LocalStorage struct {
+	Root string
+}
+
+// NewStorage returns the configured upload storage. STORAGE_DRIVER=s3 keeps
+// uploads in an S3-compatible bucket (see NewS3StorageFromEnv); otherwise
+// they go in a directory named by UPLOADS_DIR (default "uploads").
+func NewStorage() Storage {
+	if os.Getenv("STORAGE_DRIVER") == "s3" {
+		s, err := NewS3StorageFromEnv()
+		if err != nil {
+			return unavailableStorage{err}
+		}
+		return s
+	}
+	return NewLocalStorage()
+}
+
+// NewLocalStorage returns the upload directory named by UPLOADS_DIR
+func NewLocalStorage() *LocalStorage {
+	root := os.Getenv("UPLOADS_DIR")
+	if root == "" {
+		root = "uploads"
+	}
+	return &LocalStorage{Root: root}
+}
+
+// CheckStorage reports why the configured storage can't be used, or nil
+func CheckStorage() error {
+	if s, ok := NewStorage().(unavailableStorage); ok {
+		return s.err
+	}
+	return nil
+}
+
+// unavailableStorage fails every call with the reason the configured
+// storage couldn't be set up, rather than quietly saving to local disk
+type unavailableStorage struct {
+	err error
+}
+
+func (s unavailableStorage) Save(string, io.Reader) error       { return s.err }
+func (s unavailableStorage) Open(string) (io.ReadCloser, error) { return nil, s.err }
+func (s unavailableStorage) Delete(string) error                { return s.err }
+
+// path resolves a key inside the storage root, refusing keys that would
+// escape it
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(clean)), nil
+}
+
+// Save implements Storage
+func (s *LocalStorage) Save(key string, r io.Reader) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write upload: %w", err)
+	}
+	return f.Close()
+}
+
+// Open implements Storage
+func (s *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// Delete implements Storage
+func (s *LocalStorage) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Keys lists every file in the storage directory
+func (s *LocalStorage) Keys() ([]string, error) {
+	keys := []string{}
+	err := filepath.WalkDir(s.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			rel, err := filepath.Rel(s.Root, p)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return keys, nil
+	}
+	return keys, err
+}
+
+// CopyUploads copies every file in a local upload directory to another
+// storage, for moving existing uploads to object storage. Files the
+// destination already has are skipped when it can tell (S3Storage can), so
+// an interrupted copy can be run again. The local files are left in place.
+func CopyUploads(from *LocalStorage, to Storage, progress func(key string, copied bool)) (copied, skipped int, err error) {
+	keys, err := from.Keys()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list uploads: %w", err)
+	}
+	exists, canCheck := to.(interface {
+		Exists(key string) (bool, error)
+	})
+	for _, key := range keys {
+		if canCheck {
+			found, err := exists.Exists(key)
+			if err != nil {
+				return copied, skipped, fmt.Errorf("failed to check %s: %w", key, err)
+			}
+			if found {
+				skipped++
+				if progress != nil {
+					progress(key, false)
+				}
+				continue
+			}
+		}
+
+		f, err := from.Open(key)
+		if err != nil {
+			return copied, skipped, fmt.Errorf("failed to open %s: %w", key, err)
+		}
+		err = to.Save(key, f)
+		f.Close()
+		if err != nil {
+			return copied, skipped, fmt.Errorf("failed to copy %s: %w", key, err)
+		}
+		copied++
+		if progress != nil {
+			progress(key, true)
+		}
+	}
+	return copied, skipped, nil
+}
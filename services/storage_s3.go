@@ -0,0 +1,405 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// s3MinPartSize is the smallest part S3 accepts in a multipart upload,
+	// other than the last
+	s3MinPartSize = 5 << 20
+	// s3DefaultPartSize is how much of an upload is sent per request. Files
+	// smaller than this are sent in one PUT.
+	s3DefaultPartSize = 16 << 20
+
+	s3Algorithm = "AWS4-HMAC-SHA256"
+	s3DateTime  = "20060102T150405Z"
+)
+
+// s3EmptyHash is the SHA-256 of an empty request body
+var s3EmptyHash = hex.EncodeToString(sha256.New().Sum(nil))
+
+// S3Storage stores uploads in an S3-compatible bucket: AWS S3, MinIO,
+// Backblaze B2, Cloudflare R2 and the like. Requests are signed with AWS
+// Signature Version 4, and files larger than PartSize go up as multipart
+// uploads so large videos never have to fit in one request.
+type S3Storage struct {
+	// Endpoint is the service's base URL, e.g. https://s3.us-east-1.amazonaws.com
+	// or https://<account>.r2.cloudflarestorage.com
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle puts the bucket in the path (endpoint/bucket/key) rather than
+	// the host name, which MinIO and most self-hosted services need
+	PathStyle bool
+	PartSize  int64
+	Client    *http.Client
+
+	now func() time.Time
+}
+
+// NewS3StorageFromEnv returns an S3Storage configured by S3_BUCKET,
+// S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY, with S3_ENDPOINT, S3_REGION
+// (default us-east-1; R2 uses "auto"), S3_PATH_STYLE and S3_PART_SIZE_MB
+// for services other than AWS.
+func NewS3StorageFromEnv() (*S3Storage, error) {
+	s := &S3Storage{
+		Endpoint:  strings.TrimRight(os.Getenv("S3_ENDPOINT"), "/"),
+		Region:    os.Getenv("S3_REGION"),
+		Bucket:    os.Getenv("S3_BUCKET"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		PathStyle: os.Getenv("S3_PATH_STYLE") == "true",
+		PartSize:  s3DefaultPartSize,
+		Client:    &http.Client{Timeout: 5 * time.Minute},
+	}
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	if mb, err := strconv.Atoi(os.Getenv("S3_PART_SIZE_MB")); err == nil && mb > 0 {
+		s.PartSize = int64(mb) << 20
+	}
+	if s.Bucket == "" || s.AccessKey == "" || s.SecretKey == "" {
+		return nil, fmt.Errorf("S3 storage needs S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+	}
+	if _, err := url.Parse(s.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT: %w", err)
+	}
+	return s, nil
+}
+
+// objectURL is the URL of key in the bucket, with an optional query
+func (s *S3Storage) objectURL(key string, query url.Values) (*url.URL, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return nil, fmt.Errorf("invalid storage key: %q", key)
+	}
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if s.PathStyle {
+		u.Path = "/" + s.Bucket + clean
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = clean
+	}
+	// Send the path encoded exactly as it's signed
+	u.RawPath = s3EscapePath(u.Path)
+	u.RawQuery = s3Query(query)
+	return u, nil
+}
+
+// do sends a signed request for key and returns the response, which the
+// caller closes
+func (s *S3Storage) do(method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u, err := s.objectURL(key, query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	s.sign(req, body)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach object storage: %w", err)
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req
+func (s *S3Storage) sign(req *http.Request, body []byte) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	amzDate := t.Format(s3DateTime)
+	date := t.Format("20060102")
+
+	payloadHash := s3EmptyHash
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := s3Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := s3HMAC([]byte("AWS4"+s.SecretKey), date)
+	key = s3HMAC(key, s.Region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	signature := hex.EncodeToString(s3HMAC(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.AccessKey, scope, signedHeaders, signature))
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3EscapePath URI-encodes each segment of a path the way SigV4 expects
+func s3EscapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = s3Escape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3Query is a query string sorted by key with SigV4's encoding, which is
+// also what's sent so the signature matches
+func s3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape encodes everything but RFC 3986 unreserved characters
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3Error is the error document S3 answers failed requests with
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// s3ResponseError describes a failed S3 response
+func s3ResponseError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var doc s3Error
+	if xml.Unmarshal(body, &doc) == nil && doc.Code != "" {
+		return fmt.Errorf("object storage %s failed with status %d: %s: %s", op, resp.StatusCode, doc.Code, doc.Message)
+	}
+	return fmt.Errorf("object storage %s failed with status %d", op, resp.StatusCode)
+}
+
+// Save implements Storage. The file is read a part at a time; one that fits
+// in a single part is sent with a plain PUT.
+func (s *S3Storage) Save(key string, r io.Reader) error {
+	partSize := s.PartSize
+	if partSize < s3MinPartSize {
+		partSize = s3MinPartSize
+	}
+	first, err := readPart(r, partSize)
+	if err != nil {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	if int64(len(first)) < partSize {
+		return s.putObject(key, first)
+	}
+	return s.multipartUpload(key, first, r, partSize)
+}
+
+// readPart reads up to size bytes, returning fewer only at the end of r
+func readPart(r io.Reader, size int64) ([]byte, error) {
+	buf := make([]byte, size)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return buf[:n], err
+}
+
+func (s *S3Storage) putObject(key string, body []byte) error {
+	resp, err := s.do(http.MethodPut, key, nil, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3ResponseError("upload", resp)
+	}
+	return nil
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteUpload struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+// multipartUpload sends a file in parts, starting with first and reading
+// the rest from r. An upload that fails part way is aborted so the bucket
+// isn't left holding (and billing for) the parts.
+func (s *S3Storage) multipartUpload(key string, first []byte, r io.Reader, partSize int64) error {
+	resp, err := s.do(http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return s3ResponseError("upload start", resp)
+	}
+	var started struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&started)
+	resp.Body.Close()
+	if err != nil || started.UploadID == "" {
+		return fmt.Errorf("object storage didn't start the upload: %v", err)
+	}
+
+	if err := s.uploadParts(key, started.UploadID, first, r, partSize); err != nil {
+		if resp, abortErr := s.do(http.MethodDelete, key, url.Values{"uploadId": {started.UploadID}}, nil); abortErr == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *S3Storage) uploadParts(key, uploadID string, part []byte, r io.Reader, partSize int64) error {
+	complete := s3CompleteUpload{}
+	for number := 1; len(part) > 0; number++ {
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		resp, err := s.do(http.MethodPut, key, query, part)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return s3ResponseError(fmt.Sprintf("upload of part %d", number), resp)
+		}
+		resp.Body.Close()
+		complete.Parts = append(complete.Parts, s3CompletedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+
+		if int64(len(part)) < partSize {
+			break
+		}
+		if part, err = readPart(r, partSize); err != nil {
+			return fmt.Errorf("failed to read upload: %w", err)
+		}
+	}
+
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// S3 can report a failed completion in the body of a 200 response
+	result, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var doc s3Error
+	if resp.StatusCode != http.StatusOK || (xml.Unmarshal(result, &doc) == nil && doc.Code != "") {
+		return fmt.Errorf("object storage upload completion failed with status %d: %s %s", resp.StatusCode, doc.Code, doc.Message)
+	}
+	return nil
+}
+
+// Open implements Storage. A missing object's error wraps fs.ErrNotExist,
+// the same as LocalStorage.
+func (s *S3Storage) Open(key string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("open %s: %w", key, fs.ErrNotExist)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s3ResponseError("download", resp)
+	}
+	return resp.Body, nil
+}
+
+// Delete implements Storage
+func (s *S3Storage) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+	return s3ResponseError("delete", resp)
+}
+
+// Exists reports whether key is in the bucket
+func (s *S3Storage) Exists(key string) (bool, error) {
+	resp, err := s.do(http.MethodHead, key, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("object storage lookup failed with status %d", resp.StatusCode)
+}
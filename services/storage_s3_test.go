@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is a path-style bucket in memory, answering the requests
+// S3Storage makes
+type fakeS3 struct {
+	t        *testing.T
+	mu       sync.Mutex
+	objects  map[string][]byte
+	parts    map[string]map[string][]byte
+	aborted  []string
+	failPart string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *S3Storage) {
+	f := &fakeS3{t: t, objects: map[string][]byte{}, parts: map[string]map[string][]byte{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, &S3Storage{
+		Endpoint:  server.URL,
+		Region:    "auto",
+		Bucket:    "uploads",
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "secret",
+		PathStyle: true,
+		PartSize:  s3MinPartSize,
+		Client:    server.Client(),
+		now:       func() time.Time { return time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) },
+	}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	assert.Equal(f.t, hex.EncodeToString(sum[:]), r.Header.Get("x-amz-content-sha256"))
+	assert.True(f.t, strings.HasPrefix(r.Header.Get("Authorization"),
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20261014/auto/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="))
+
+	key := strings.TrimPrefix(r.URL.Path, "/uploads/")
+	query := r.URL.Query()
+	uploadID := query.Get("uploadId")
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.parts["upload-1"] = map[string][]byte{}
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && uploadID != "":
+		if query.Get("partNumber") == f.failPart {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `<Error><Code>InternalError</Code><Message>try again</Message></Error>`)
+			return
+		}
+		f.parts[uploadID][query.Get("partNumber")] = body
+		w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+	case r.Method == http.MethodPost && uploadID != "":
+		var complete s3CompleteUpload
+		require.NoError(f.t, xml.Unmarshal(body, &complete))
+		var object []byte
+		for _, part := range complete.Parts {
+			assert.Equal(f.t, fmt.Sprintf(`"etag-%d"`, part.PartNumber), part.ETag)
+			object = append(object, f.parts[uploadID][fmt.Sprint(part.PartNumber)]...)
+		}
+		f.objects[key] = object
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><Key>`+key+`</Key></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete && uploadID != "":
+		f.aborted = append(f.aborted, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(object)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestS3Storage_SaveOpenDelete(t *testing.T) {
+	f, storage := newFakeS3(t)
+
+	require.NoError(t, storage.Save("media/abc photo.jpg", strings.NewReader("jpeg bytes")))
+	assert.Equal(t, "jpeg bytes", string(f.objects["media/abc photo.jpg"]))
+	assert.Empty(t, f.parts, "small files are a single PUT")
+
+	exists, err := storage.Exists("media/abc photo.jpg")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	r, err := storage.Open("media/abc photo.jpg")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "jpeg bytes", string(data))
+
+	require.NoError(t, storage.Delete("media/abc photo.jpg"))
+	_, err = storage.Open("media/abc photo.jpg")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	exists, err = storage.Exists("media/abc photo.jpg")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.Error(t, storage.Save("../outside", strings.NewReader("x")))
+}
+
+func TestS3Storage_MultipartUpload(t *testing.T) {
+	f, storage := newFakeS3(t)
+	video := bytes.Repeat([]byte("0123456789"), (2*s3MinPartSize)/10+1000)
+
+	require.NoError(t, storage.Save("media/video.mp4", bytes.NewReader(video)))
+	assert.Len(t, f.parts["upload-1"], 3)
+	assert.Equal(t, video, f.objects["media/video.mp4"])
+}
+
+func TestS3Storage_AbortsFailedMultipartUpload(t *testing.T) {
+	f, storage := newFakeS3(t)
+	f.failPart = "2"
+	video := bytes.Repeat([]byte("x"), 2*s3MinPartSize)
+
+	err := storage.Save("media/video.mp4", bytes.NewReader(video))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "InternalError")
+	assert.Equal(t, []string{"upload-1"}, f.aborted)
+	assert.NotContains(t, f.objects, "media/video.mp4")
+}
+
+func TestS3Storage_VirtualHostURL(t *testing.T) {
+	storage := &S3Storage{Endpoint: "https://s3.us-east-1.amazonaws.com", Bucket: "avr-uploads"}
+	u, err := storage.objectURL("media/a b.pdf", map[string][]string{"uploadId": {"x/y"}, "partNumber": {"2"}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://avr-uploads.s3.us-east-1.amazonaws.com/media/a%20b.pdf?partNumber=2&uploadId=x%2Fy", u.String())
+}
+
+func TestNewStorage_Driver(t *testing.T) {
+	t.Setenv("STORAGE_DRIVER", "s3")
+	t.Setenv("S3_BUCKET", "")
+	assert.Error(t, CheckStorage())
+	assert.Error(t, NewStorage().Save("media/x", strings.NewReader("x")), "a misconfigured bucket never falls back to local disk")
+
+	t.Setenv("S3_BUCKET", "uploads")
+	t.Setenv("S3_ACCESS_KEY_ID", "key")
+	t.Setenv("S3_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("S3_REGION", "")
+	t.Setenv("S3_ENDPOINT", "")
+	require.NoError(t, CheckStorage())
+	s3, ok := NewStorage().(*S3Storage)
+	require.True(t, ok)
+	assert.Equal(t, "https://s3.us-east-1.amazonaws.com", s3.Endpoint)
+
+	t.Setenv("STORAGE_DRIVER", "")
+	assert.IsType(t, &LocalStorage{}, NewStorage())
+}
+
+func TestCopyUploads_SkipsCopiedFiles(t *testing.T) {
+	local := &LocalStorage{Root: t.TempDir()}
+	require.NoError(t, local.Save("vehicles/1/photo.jpg", strings.NewReader("photo")))
+	require.NoError(t, local.Save("media/kit.pdf", strings.NewReader("pdf")))
+	f, storage := newFakeS3(t)
+	f.objects["media/kit.pdf"] = []byte("pdf")
+
+	copied, skipped, err := CopyUploads(local, storage, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, copied)
+	assert.Equal(t, 1, skipped)
+	assert.Equal(t, "photo", string(f.objects["vehicles/1/photo.jpg"]))
+
+	keys, err := (&LocalStorage{Root: t.TempDir() + "/missing"}).Keys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage_SaveOpenDelete(t *testing.T) {
+	storage := &LocalStorage{Root: t.TempDir()}
+
+	require.NoError(t, storage.Save("vehicles/abc/photo.jpg", strings.NewReader("jpeg bytes")))
+
+	f, err := storage.Open("vehicles/abc/photo.jpg")
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	f.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg bytes", string(data))
+
+	require.NoError(t, storage.Delete("vehicles/abc/photo.jpg"))
+	_, err = storage.Open("vehicles/abc/photo.jpg")
+	assert.Error(t, err)
+	assert.NoError(t, storage.Delete("vehicles/abc/photo.jpg"), "deleting a missing file is not an error")
+}
+
+func TestLocalStorage_RejectsEscapingKeys(t *testing.T) {
+	storage := &LocalStorage{Root: t.TempDir()}
+
+	assert.Error(t, storage.Save("../outside.txt", strings.NewReader("x")))
+	assert.Error(t, storage.Save("vehicles/../../outside.txt", strings.NewReader("x")))
+	assert.Error(t, storage.Save("", strings.NewReader("x")))
+	_, err := storage.Open("../../etc/passwd")
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// VirusScanner checks an uploaded file for malware
+type VirusScanner interface {
+	Scan(r io.Reader) (ScanResult, error)
+}
+
+// ScanResult is what the scanner found. Signature names the match when the
+// file is infected.
+type ScanResult struct {
+	Infected  bool
+	Signature string
+}
+
+// VirusScanningEnabled reports whether uploads are scanned before staff or
+// the public can download them (CLAMAV_ADDRESS is set)
+func VirusScanningEnabled() bool {
+	return os.Getenv("CLAMAV_ADDRESS") != ""
+}
+
+// NewVirusScanner returns a scanner for the clamd daemon at CLAMAV_ADDRESS,
+// or nil when scanning isn't configured
+func NewVirusScanner() VirusScanner {
+	if !VirusScanningEnabled() {
+		return nil
+	}
+	return &ClamAVScanner{Address: os.Getenv("CLAMAV_ADDRESS")}
+}
+
+// clamdChunkSize is how much of the file goes in each INSTREAM chunk. clamd
+// rejects streams over its StreamMaxLength (25MB by default) on its own.
+const clamdChunkSize = 64 * 1024
+
+// ClamAVScanner streams files to clamd with the INSTREAM command. Address is
+// "host:port", "tcp://host:port" or "unix:///path/to/clamd.sock".
+type ClamAVScanner struct {
+	Address string
+	Timeout time.Duration
+}
+
+func (s *ClamAVScanner) dial() (net.Conn, error) {
+	network, address := "tcp", s.Address
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		network, address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		address = strings.TrimPrefix(address, "tcp://")
+	}
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+	conn, err := net.DialTimeout(network, address, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to clamd: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	return conn, nil
+}
+
+// Scan sends the file to clamd and reads its verdict
+func (s *ClamAVScanner) Scan(r io.Reader) (ScanResult, error) {
+	conn, err := s.dial()
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ScanResult{}, fmt.Errorf("sending to clamd: %w", err)
+	}
+	buf := make([]byte, clamdChunkSize)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			chunk := binary.BigEndian.AppendUint32(nil, uint32(n))
+			if _, err := conn.Write(append(chunk, buf[:n]...)); err != nil {
+				return ScanResult{}, fmt.Errorf("sending to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return ScanResult{}, readErr
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return ScanResult{}, fmt.Errorf("sending to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return ScanResult{}, fmt.Errorf("reading clamd reply: %w", err)
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamdReply reads "stream: OK", "stream: <signature> FOUND" or
+// "<reason> ERROR"
+func parseClamdReply(reply string) (ScanResult, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return ScanResult{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return ScanResult{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	case strings.HasSuffix(result, " ERROR"):
+		return ScanResult{}, fmt.Errorf("clamd: %s", strings.TrimSuffix(result, " ERROR"))
+	}
+	return ScanResult{}, fmt.Errorf("unexpected clamd reply %q", reply)
+}
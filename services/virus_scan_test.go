@@ -0,0 +1,103 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eicar is the standard antivirus test string
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd answers INSTREAM commands, flagging streams that contain the
+// EICAR test string
+func fakeClamd(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				command, err := r.ReadString(0)
+				if err != nil || command != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var stream bytes.Buffer
+				for {
+					var size uint32
+					if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err := io.CopyN(&stream, r, int64(size)); err != nil {
+						return
+					}
+				}
+				switch {
+				case strings.Contains(stream.String(), "EICAR-STANDARD"):
+					conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+				case stream.Len() > 100*1024:
+					conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+				default:
+					conn.Write([]byte("stream: OK\x00"))
+				}
+			}(conn)
+		}
+	}()
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestClamAVScanner_Scan(t *testing.T) {
+	scanner := &ClamAVScanner{Address: fakeClamd(t)}
+
+	result, err := scanner.Scan(strings.NewReader("%PDF-1.7 volunteer application"))
+	require.NoError(t, err)
+	assert.False(t, result.Infected)
+
+	// Spread over several chunks
+	infected := append(bytes.Repeat([]byte("a"), clamdChunkSize+10), eicar...)
+	result, err = scanner.Scan(bytes.NewReader(infected))
+	require.NoError(t, err)
+	assert.True(t, result.Infected)
+	assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+
+	_, err = scanner.Scan(bytes.NewReader(make([]byte, 200*1024)))
+	assert.EqualError(t, err, "clamd: INSTREAM size limit exceeded.")
+}
+
+func TestClamAVScanner_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	_, err = (&ClamAVScanner{Address: address}).Scan(strings.NewReader("x"))
+	assert.ErrorContains(t, err, "connecting to clamd")
+}
+
+func TestNewVirusScanner(t *testing.T) {
+	t.Setenv("CLAMAV_ADDRESS", "")
+	assert.False(t, VirusScanningEnabled())
+	assert.Nil(t, NewVirusScanner())
+
+	t.Setenv("CLAMAV_ADDRESS", "clamav:3310")
+	assert.True(t, VirusScanningEnabled())
+	assert.Equal(t, &ClamAVScanner{Address: "clamav:3310"}, NewVirusScanner())
+}
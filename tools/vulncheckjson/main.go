@@ -0,0 +1,27 @@
+// Command vulncheckjson checks a `govulncheck -json` scan before it's
+// embedded for the admin diagnostics page. `make vulncheck` runs it.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"avrnpo.org/pkg/diagnostics"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Println("usage: vulncheckjson <scan.json>")
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		fmt.Println("failed to read scan:", err)
+		os.Exit(1)
+	}
+	if err := diagnostics.ValidateVulncheck(data); err != nil {
+		fmt.Printf("%s is not a usable govulncheck scan: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}